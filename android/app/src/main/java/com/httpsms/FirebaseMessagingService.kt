@@ -150,6 +150,7 @@ class MyFirebaseMessagingService : FirebaseMessagingService() {
             return try {
                 val sentIntents = ArrayList<PendingIntent>()
                 val deliveredIntents = ArrayList<PendingIntent>()
+                val requestsDeliveryReport = message.requestsDeliveryReport()
 
                 for (i in 0 until parts.size) {
                     var id = "${message.id}.$i"
@@ -161,9 +162,11 @@ class MyFirebaseMessagingService : FirebaseMessagingService() {
                     }
 
                     sentIntents.add(createPendingIntent(id, SmsManagerService.sentAction()))
-                    deliveredIntents.add(createPendingIntent(id, SmsManagerService.deliveredAction()))
+                    if (requestsDeliveryReport) {
+                        deliveredIntents.add(createPendingIntent(id, SmsManagerService.deliveredAction()))
+                    }
                 }
-                SmsManagerService().sendMultipartMessage(this.applicationContext,message.contact, parts, message.sim, sentIntents, deliveredIntents)
+                SmsManagerService().sendMultipartMessage(this.applicationContext,message.contact, parts, message.sim, sentIntents, if (requestsDeliveryReport) deliveredIntents else null)
                 Timber.d("sent SMS for message with ID [${message.id}] in [${parts.size}] parts")
                 Result.success()
             } catch (e: Exception) {
@@ -178,7 +181,7 @@ class MyFirebaseMessagingService : FirebaseMessagingService() {
             sendMessage(
                 message,
                 createPendingIntent(message.id, SmsManagerService.sentAction()),
-                createPendingIntent(message.id, SmsManagerService.deliveredAction())
+                if (message.requestsDeliveryReport()) createPendingIntent(message.id, SmsManagerService.deliveredAction()) else null
             )
             return Result.success()
         }
@@ -222,7 +225,7 @@ class MyFirebaseMessagingService : FirebaseMessagingService() {
             return null
         }
 
-        private fun sendMessage(message: Message, sentIntent: PendingIntent, deliveredIntent: PendingIntent) {
+        private fun sendMessage(message: Message, sentIntent: PendingIntent, deliveredIntent: PendingIntent?) {
             Timber.d("sending SMS for message with ID [${message.id}]")
             try {
                 SmsManagerService().sendTextMessage(this.applicationContext,message.contact, message.content, message.sim, sentIntent, deliveredIntent)
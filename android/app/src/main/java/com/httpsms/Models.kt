@@ -37,6 +37,9 @@ data class Message (
     @Json(name = "created_at")
     val createdAt: String,
 
+    @Json(name = "delivery_tracking")
+    val deliveryTracking: String?,
+
     @Json(name = "failure_reason")
     val failureReason: String?,
 
@@ -67,4 +70,9 @@ data class Message (
 
     @Json(name = "updated_at")
     val updatedAt: String
-)
+) {
+    // the delivery report is requested for messages which were created before the delivery tracking
+    fun requestsDeliveryReport(): Boolean {
+        return deliveryTracking != "off"
+    }
+}
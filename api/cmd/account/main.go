@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// exports the account of a user to a bundle or imports a bundle into the account of a user on another installation e.g.
+//
+//	go run ./cmd/account export -user-id WB7DRDWrJZRGbYrv2CKGkqbzvqdC -output account.ndjson.gz
+//	go run ./cmd/account import -user-id 3Ngh1zG0AoTNsT1vHBlF3Zvp4Ri1 -input account.ndjson.gz
+//
+// An import into an account which already has records is refused unless -merge is set, and records whose ID already
+// exists are skipped so an interrupted import can be run again with -merge.
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "export" && os.Args[1] != "import") {
+		log.Fatal("usage: account export -user-id ID -output FILE | account import -user-id ID -input FILE [-merge]")
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	userID := flags.String("user-id", "", "ID of the user who owns the account")
+	output := flags.String("output", "", "path of the bundle which is written by export")
+	input := flags.String("input", "", "path of the bundle which is read by import")
+	merge := flags.Bool("merge", false, "import the bundle into an account which is not empty")
+	_ = flags.Parse(os.Args[2:])
+
+	if *userID == "" {
+		log.Fatal("the -user-id flag is required")
+	}
+
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+	service := container.AccountExportService()
+
+	if os.Args[1] == "export" {
+		manifest, err := export(service, entities.UserID(*userID), *output)
+		if err != nil {
+			logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot export the account of user [%s]", *userID)))
+		}
+		printJSON(manifest)
+		return
+	}
+
+	file, err := os.Open(*input)
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot open bundle [%s]", *input)))
+	}
+	defer func() { _ = file.Close() }()
+
+	result, err := service.Import(context.Background(), services.AccountImportParams{
+		UserID: entities.UserID(*userID),
+		Merge:  *merge,
+		Bundle: file,
+	})
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot import bundle [%s] into the account of user [%s]", *input, *userID)))
+	}
+	printJSON(result)
+}
+
+func export(service *services.AccountExportService, userID entities.UserID, output string) (*entities.AccountExportManifest, error) {
+	if output == "" {
+		return nil, stacktrace.NewError("the -output flag is required")
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot create bundle [%s]", output))
+	}
+
+	manifest, err := service.Export(context.Background(), userID, file)
+	if err != nil {
+		_ = file.Close()
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot write bundle [%s]", output))
+	}
+
+	return manifest, file.Close()
+}
+
+func printJSON(value any) {
+	content, _ := json.MarshalIndent(value, "", "  ")
+	fmt.Println(string(content))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// moves the messages which are older than MESSAGE_ARCHIVE_AFTER into the messages_archive table
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+
+	logger.Info(fmt.Sprintf("archiving messages older than [%s]", container.MessageArchiveAfter()))
+	count, err := container.MessageService().Archive(context.Background())
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, "cannot archive messages"))
+	}
+	logger.Info(fmt.Sprintf("[%d] messages archived successfully", count))
+}
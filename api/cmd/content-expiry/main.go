@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// replaces the content of the received messages whose content expiry rule TTL has passed, it is scheduled to run every minute
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+
+	count, err := container.ContentExpiryService().Apply(context.Background())
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot expire the content of messages after expiring [%d] messages", count)))
+	}
+	logger.Info(fmt.Sprintf("the content of [%d] messages expired successfully", count))
+}
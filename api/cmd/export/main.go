@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// exports the archived messages which are older than MESSAGE_EXPORT_AFTER to the blob store and deletes them from the database.
+// Use -message-id to print an exported message for a support lookup.
+func main() {
+	messageID := flag.String("message-id", "", "ID of an exported message to print instead of exporting messages")
+	flag.Parse()
+
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+	service := container.MessageExportService()
+
+	if *messageID != "" {
+		id, err := uuid.Parse(*messageID)
+		if err != nil {
+			logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("[%s] is not a valid message ID", *messageID)))
+		}
+
+		message, err := service.Load(context.Background(), id)
+		if err != nil {
+			logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot load exported message [%s]", id)))
+		}
+
+		content, _ := json.MarshalIndent(message, "", "  ")
+		fmt.Println(string(content))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("exporting archived messages older than [%s]", container.MessageExportAfter()))
+	count, err := service.Export(context.Background())
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, "cannot export messages"))
+	}
+	logger.Info(fmt.Sprintf("[%d] messages exported successfully", count))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/carlmjohnson/requests"
+	"github.com/palantir/stacktrace"
+)
+
+// apiError is the error envelope which is returned by the API when a request fails
+type apiError struct {
+	StatusCode int
+	Code       string          `json:"code"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Error implements the error interface
+func (err *apiError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("the API responded with status code [%d]", err.StatusCode)
+	}
+	if len(err.Data) != 0 && string(err.Data) != "null" {
+		return fmt.Sprintf("%s [%d]: %s", err.Message, err.StatusCode, err.Data)
+	}
+	return fmt.Sprintf("%s [%d]", err.Message, err.StatusCode)
+}
+
+// messageIndexParams are the filters of the GET /v1/messages endpoint
+type messageIndexParams struct {
+	Owner           string
+	Contact         string
+	Query           string
+	Skip            int
+	Limit           int
+	IncludeArchived bool
+}
+
+// messageSendParams is the payload of the POST /v1/messages/send endpoint
+type messageSendParams struct {
+	From      string `json:"from,omitempty"`
+	To        string `json:"to"`
+	Content   string `json:"content"`
+	RequestID string `json:"request_id,omitempty"`
+	SIM       string `json:"sim,omitempty"`
+}
+
+// twilioExportContentTypes are the content types of the formats of the Twilio exports
+var twilioExportContentTypes = map[string]string{
+	"csv":  "text/csv",
+	"json": "application/json",
+}
+
+// apiClient calls the public HTTP API with an API key
+type apiClient struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+// newAPIClient creates a new apiClient
+func newAPIClient(client *http.Client, baseURL string, apiKey string) *apiClient {
+	return &apiClient{
+		client:  client,
+		baseURL: baseURL,
+		apiKey:  apiKey,
+	}
+}
+
+// Send sends a new entities.Message
+func (api *apiClient) Send(ctx context.Context, params messageSendParams) (*entities.Message, error) {
+	response := new(responses.MessageResponse)
+	err := api.request("/v1/messages/send").
+		BodyJSON(params).
+		ToJSON(response).
+		Fetch(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot send message to [%s]", params.To))
+	}
+	return &response.Data, nil
+}
+
+// Index fetches a page of the messages between an owner and a contact
+func (api *apiClient) Index(ctx context.Context, params messageIndexParams) ([]entities.Message, error) {
+	response := new(responses.MessagesResponse)
+	err := api.request("/v1/messages").
+		Param("owner", params.Owner).
+		Param("contact", params.Contact).
+		Param("query", params.Query).
+		Param("skip", strconv.Itoa(params.Skip)).
+		Param("limit", strconv.Itoa(params.Limit)).
+		Param("include_archived", strconv.FormatBool(params.IncludeArchived)).
+		ToJSON(response).
+		Fetch(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch messages between [%s] and [%s]", params.Owner, params.Contact))
+	}
+	return response.Data, nil
+}
+
+// Get fetches an entities.Message by its ID
+func (api *apiClient) Get(ctx context.Context, messageID string) (*entities.Message, error) {
+	response := new(responses.MessageResponse)
+	err := api.request("/v1/messages/" + messageID).
+		ToJSON(response).
+		Fetch(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch message with ID [%s]", messageID))
+	}
+	return &response.Data, nil
+}
+
+// Delete deletes an entities.Message by its ID
+func (api *apiClient) Delete(ctx context.Context, messageID string) error {
+	err := api.request("/v1/messages/" + messageID).
+		Delete().
+		Fetch(ctx)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot delete message with ID [%s]", messageID))
+	}
+	return nil
+}
+
+// ImportTwilio uploads a Twilio export of the format as a stream and returns the summary of the import
+func (api *apiClient) ImportTwilio(ctx context.Context, export io.Reader, format string, owner string) (*entities.TwilioImportResult, error) {
+	response := new(responses.TwilioImportResponse)
+	builder := api.request("/v1/messages/import/twilio").
+		Post().
+		Param("format", format).
+		ContentType(twilioExportContentTypes[format]).
+		BodyReader(export).
+		ToJSON(response)
+	if owner != "" {
+		builder = builder.Param("owner", owner)
+	}
+
+	if err := builder.Fetch(ctx); err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot import the %s Twilio export", format))
+	}
+	return &response.Data, nil
+}
+
+func (api *apiClient) request(path string) *requests.Builder {
+	return requests.
+		URL(api.baseURL).
+		Path(path).
+		Client(api.client).
+		Header("x-api-key", api.apiKey).
+		AddValidator(checkStatus)
+}
+
+// checkStatus converts the error envelope of a response which is not successful into an apiError
+func checkStatus(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+
+	err := &apiError{StatusCode: response.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	_ = json.Unmarshal(body, err)
+	return err
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/palantir/stacktrace"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// exitCodeSuccess is returned when the command succeeds
+	exitCodeSuccess = 0
+
+	// exitCodeFailure is returned when the command fails e.g. the API rejects the request
+	exitCodeFailure = 1
+
+	// exitCodeUsage is returned when the command is called with invalid arguments or flags
+	exitCodeUsage = 2
+
+	// exportPageSize is the number of messages which are fetched with one request while exporting
+	exportPageSize = 20
+)
+
+// usageError is an error caused by invalid arguments or flags, it is returned with exitCodeUsage
+type usageError struct {
+	error
+}
+
+// newUsageError creates a new usageError
+func newUsageError(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// usageArgs marks the errors of a cobra.PositionalArgs as a usageError
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return usageError{err}
+		}
+		return nil
+	}
+}
+
+// cli holds the global flags and the dependencies of the commands
+type cli struct {
+	stdout     io.Writer
+	httpClient *http.Client
+	flags      config
+	configPath string
+	output     string
+}
+
+// execute runs the command in args and returns the exit code of the process
+func execute(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, httpClient *http.Client) int {
+	app := &cli{stdout: stdout, httpClient: httpClient}
+
+	root := app.rootCommand()
+	root.SetArgs(args)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+
+	err := root.ExecuteContext(ctx)
+	if err == nil {
+		return exitCodeSuccess
+	}
+
+	fmt.Fprintf(stderr, "Error: %s\n", err.Error())
+	if errors.As(err, &usageError{}) {
+		fmt.Fprintf(stderr, "Run '%s --help' for usage.\n", root.Name())
+		return exitCodeUsage
+	}
+	return exitCodeFailure
+}
+
+func (app *cli) rootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "httpsms",
+		Short:         "Send and inspect SMS messages with the httpSMS API",
+		Args:          usageArgs(cobra.NoArgs),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if app.output != outputTable && app.output != outputJSON {
+				return newUsageError("invalid output format [%s], use [%s] or [%s]", app.output, outputTable, outputJSON)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError{err}
+	})
+
+	root.PersistentFlags().StringVar(&app.flags.URL, "url", "", fmt.Sprintf("URL of the API, defaults to $%s or %s", envURL, defaultURL))
+	root.PersistentFlags().StringVar(&app.flags.APIKey, "api-key", "", fmt.Sprintf("API key of your account, defaults to $%s", envAPIKey))
+	root.PersistentFlags().StringVar(&app.configPath, "config", "", fmt.Sprintf("path of the JSON config file with the url and api_key, defaults to $%s or %s", envConfig, defaultConfigPath()))
+	root.PersistentFlags().StringVarP(&app.output, "output", "o", outputTable, fmt.Sprintf("output format [%s] or [%s]", outputTable, outputJSON))
+
+	root.AddCommand(app.sendCommand(), app.messagesCommand(), app.webhooksCommand())
+	return root
+}
+
+func (app *cli) sendCommand() *cobra.Command {
+	params := messageSendParams{}
+	cmd := &cobra.Command{
+		Use:     "send",
+		Short:   "Send a new SMS message",
+		Example: `  httpsms send --to +18005550100 --content "This is a sample text message"`,
+		Args:    usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(params.To) == "" || params.Content == "" {
+				return newUsageError("the --to and --content flags are required")
+			}
+
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			message, err := client.Send(cmd.Context(), params)
+			if err != nil {
+				return err
+			}
+			return app.printer().Message(message)
+		},
+	}
+
+	cmd.Flags().StringVar(&params.From, "from", "", "phone number which sends the message, defaults to the default phone of your account")
+	cmd.Flags().StringVar(&params.To, "to", "", "phone number which receives the message")
+	cmd.Flags().StringVar(&params.Content, "content", "", "content of the message")
+	cmd.Flags().StringVar(&params.RequestID, "request-id", "", "ID which is used to track the message in your system")
+	cmd.Flags().StringVar(&params.SIM, "sim", "", "SIM card which sends the message e.g. SIM1 or SIM2")
+	return cmd
+}
+
+func (app *cli) messagesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "messages",
+		Short: "List, inspect, cancel, requeue, export and import messages",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		app.messagesListCommand(),
+		app.messagesGetCommand(),
+		app.messagesCancelCommand(),
+		app.messagesRequeueCommand(),
+		app.messagesExportCommand(),
+		app.messagesImportTwilioCommand(),
+	)
+	return cmd
+}
+
+func (app *cli) messagesListCommand() *cobra.Command {
+	params := messageIndexParams{}
+	var status string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List the messages between a phone and a contact",
+		Example: `  httpsms messages list --owner +18005550199 --contact +18005550100 --status failed`,
+		Args:    usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateIndexParams(params); err != nil {
+				return err
+			}
+
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			messages, err := client.Index(cmd.Context(), params)
+			if err != nil {
+				return err
+			}
+
+			if status != "" {
+				messages = filterMessages(messages, entities.MessageStatus(strings.ToLower(status)))
+			}
+			return app.printer().Messages(messages)
+		},
+	}
+
+	addIndexFlags(cmd, &params)
+	cmd.Flags().IntVar(&params.Skip, "skip", 0, "number of messages to skip")
+	cmd.Flags().IntVar(&params.Limit, "limit", 20, "number of messages to fetch between 1 and 20")
+	cmd.Flags().StringVar(&status, "status", "", "only print the fetched messages with this status e.g. failed")
+	return cmd
+}
+
+func (app *cli) messagesGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get MESSAGE_ID",
+		Short: "Get a message by its ID",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			message, err := client.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			return app.printer().Message(message)
+		},
+	}
+}
+
+func (app *cli) messagesCancelCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel MESSAGE_ID",
+		Short: "Cancel a pending or scheduled message before it is sent by the phone",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			message, err := client.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			if !message.IsPending() && !message.IsScheduled() {
+				return stacktrace.NewError(fmt.Sprintf("message [%s] cannot be cancelled because it has status [%s]", message.ID, message.Status))
+			}
+
+			if err = client.Delete(cmd.Context(), message.ID.String()); err != nil {
+				return err
+			}
+
+			if app.output == outputJSON {
+				return app.printer().Message(message)
+			}
+			_, err = fmt.Fprintf(app.stdout, "message [%s] to [%s] has been cancelled\n", message.ID, message.Contact)
+			return err
+		},
+	}
+}
+
+func (app *cli) messagesRequeueCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "requeue MESSAGE_ID",
+		Short: "Send a copy of a failed or expired message",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			message, err := client.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+
+			if message.Status != entities.MessageStatusFailed && !message.IsExpired() {
+				return stacktrace.NewError(fmt.Sprintf("message [%s] cannot be requeued because it has status [%s]", message.ID, message.Status))
+			}
+
+			requeued, err := client.Send(cmd.Context(), messageSendParams{
+				From:    message.Owner,
+				To:      message.Contact,
+				Content: message.Content,
+				SIM:     string(message.SIM),
+			})
+			if err != nil {
+				return err
+			}
+			return app.printer().Message(requeued)
+		},
+	}
+}
+
+func (app *cli) messagesExportCommand() *cobra.Command {
+	params := messageIndexParams{Limit: exportPageSize}
+	var format, file string
+
+	cmd := &cobra.Command{
+		Use:     "export",
+		Short:   "Export all the messages between a phone and a contact as JSON lines or CSV",
+		Example: `  httpsms messages export --owner +18005550199 --contact +18005550100 --format csv --file messages.csv`,
+		Args:    usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateIndexParams(params); err != nil {
+				return err
+			}
+			if format != "jsonl" && format != "csv" {
+				return newUsageError("invalid export format [%s], use [jsonl] or [csv]", format)
+			}
+
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			writer := app.stdout
+			if file != "" {
+				output, err := os.Create(file)
+				if err != nil {
+					return stacktrace.Propagate(err, fmt.Sprintf("cannot create export file [%s]", file))
+				}
+				defer output.Close()
+				writer = output
+			}
+
+			count, err := exportMessages(cmd.Context(), client, params, format, writer)
+			if err != nil {
+				return err
+			}
+
+			if file != "" {
+				_, err = fmt.Fprintf(app.stdout, "exported [%d] messages to [%s]\n", count, file)
+			}
+			return err
+		},
+	}
+
+	addIndexFlags(cmd, &params)
+	cmd.Flags().StringVar(&format, "format", "jsonl", "format of the export [jsonl] or [csv]")
+	cmd.Flags().StringVar(&file, "file", "", "path of the export file, the messages are written to stdout when it is empty")
+	return cmd
+}
+
+func (app *cli) messagesImportTwilioCommand() *cobra.Command {
+	var format, owner string
+
+	cmd := &cobra.Command{
+		Use:     "import-twilio FILE",
+		Short:   "Import the history of your messages from a Twilio CSV or JSON export",
+		Example: `  httpsms messages import-twilio messages.csv --owner +18005550199`,
+		Args:    usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := twilioExportContentTypes[format]; !ok {
+				return newUsageError("invalid export format [%s], use [csv] or [json]", format)
+			}
+
+			client, err := app.apiClient()
+			if err != nil {
+				return err
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot open Twilio export [%s]", args[0]))
+			}
+			defer file.Close()
+
+			result, err := client.ImportTwilio(cmd.Context(), file, format, owner)
+			if err != nil {
+				return err
+			}
+			return app.printer().TwilioImport(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "csv", "format of the export [csv] or [json]")
+	cmd.Flags().StringVar(&owner, "owner", "", "phone number which owns all the imported messages instead of the Twilio phone number")
+	return cmd
+}
+
+func (app *cli) webhooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Test the webhooks which receive the events of your account",
+		Args:  usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(app.webhooksTestCommand())
+	return cmd
+}
+
+func (app *cli) webhooksTestCommand() *cobra.Command {
+	var signingKey, eventType string
+
+	cmd := &cobra.Command{
+		Use:     "test WEBHOOK_URL",
+		Short:   "Send a signed sample event to a webhook",
+		Example: `  httpsms webhooks test https://example.com/webhook --signing-key secret --event message.phone.received`,
+		Args:    usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := webhookSamplePayloads[eventType]; !ok {
+				return newUsageError("invalid event [%s], use one of [%s]", eventType, strings.Join(webhookSampleEvents(), ", "))
+			}
+
+			statusCode, err := sendWebhookTest(cmd.Context(), app.httpClient, args[0], signingKey, eventType)
+			if err != nil {
+				return err
+			}
+
+			if app.output == outputJSON {
+				return app.printer().json(map[string]any{"url": args[0], "event": eventType, "status_code": statusCode})
+			}
+			_, err = fmt.Fprintf(app.stdout, "webhook [%s] accepted event [%s] with status code [%d]\n", args[0], eventType, statusCode)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&signingKey, "signing-key", "", "signing key of the webhook which is used to sign the Authorization header")
+	cmd.Flags().StringVar(&eventType, "event", events.EventTypeMessagePhoneReceived, fmt.Sprintf("type of the sample event, one of [%s]", strings.Join(webhookSampleEvents(), ", ")))
+	return cmd
+}
+
+func (app *cli) apiClient() (*apiClient, error) {
+	path, explicit := app.configPath, app.configPath != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+
+	config, err := loadConfig(path, explicit, app.flags)
+	if err != nil {
+		return nil, err
+	}
+	return newAPIClient(app.httpClient, config.URL, config.APIKey), nil
+}
+
+func (app *cli) printer() *printer {
+	return &printer{writer: app.stdout, format: app.output}
+}
+
+// addIndexFlags adds the filters of the GET /v1/messages endpoint to a command
+func addIndexFlags(cmd *cobra.Command, params *messageIndexParams) {
+	cmd.Flags().StringVar(&params.Owner, "owner", "", "phone number of your phone")
+	cmd.Flags().StringVar(&params.Contact, "contact", "", "phone number of the contact")
+	cmd.Flags().StringVar(&params.Query, "query", "", "only fetch the messages which contain this text")
+	cmd.Flags().BoolVar(&params.IncludeArchived, "include-archived", false, "also fetch the messages which have been archived")
+}
+
+func validateIndexParams(params messageIndexParams) error {
+	if strings.TrimSpace(params.Owner) == "" || strings.TrimSpace(params.Contact) == "" {
+		return newUsageError("the --owner and --contact flags are required")
+	}
+	if params.Limit < 1 || params.Limit > 20 {
+		return newUsageError("invalid limit [%d], use a number between 1 and 20", params.Limit)
+	}
+	return nil
+}
+
+func filterMessages(messages []entities.Message, status entities.MessageStatus) []entities.Message {
+	result := make([]entities.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Status == status {
+			result = append(result, message)
+		}
+	}
+	return result
+}
+
+// exportMessages fetches the messages page by page and writes them in the format, it returns the number of exported messages
+func exportMessages(ctx context.Context, client *apiClient, params messageIndexParams, format string, writer io.Writer) (int, error) {
+	csvWriter := csv.NewWriter(writer)
+	jsonEncoder := json.NewEncoder(writer)
+
+	if format == "csv" {
+		_ = csvWriter.Write([]string{"id", "owner", "contact", "type", "status", "sim", "content", "created_at", "sent_at", "delivered_at", "failed_at", "failure_reason"})
+	}
+
+	count := 0
+	for {
+		messages, err := client.Index(ctx, params)
+		if err != nil {
+			return count, stacktrace.Propagate(err, fmt.Sprintf("cannot export messages after [%d] messages", count))
+		}
+
+		for _, message := range messages {
+			if format == "csv" {
+				err = csvWriter.Write([]string{
+					message.ID.String(),
+					message.Owner,
+					message.Contact,
+					string(message.Type),
+					string(message.Status),
+					string(message.SIM),
+					message.Content,
+					message.CreatedAt.Format(time.RFC3339),
+					formatTime(message.SentAt),
+					formatTime(message.DeliveredAt),
+					formatTime(message.FailedAt),
+					formatString(message.FailureReason),
+				})
+			} else {
+				err = jsonEncoder.Encode(message)
+			}
+			if err != nil {
+				return count, stacktrace.Propagate(err, fmt.Sprintf("cannot write message [%s] to the export", message.ID))
+			}
+			count++
+		}
+
+		if len(messages) < params.Limit {
+			break
+		}
+		params.Skip += len(messages)
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return count, stacktrace.Propagate(err, "cannot write the CSV export")
+	}
+	return count, nil
+}
+
+func formatTime(timestamp *time.Time) string {
+	if timestamp == nil {
+		return ""
+	}
+	return timestamp.Format(time.RFC3339)
+}
+
+func formatString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// defaultURL is the URL of the hosted API
+	defaultURL = "https://api.httpsms.com"
+
+	// envURL is the environment variable which overrides the URL in the config file
+	envURL = "HTTPSMS_URL"
+
+	// envAPIKey is the environment variable which overrides the API key in the config file
+	envAPIKey = "HTTPSMS_KEY"
+
+	// envConfig is the environment variable with the path of the config file
+	envConfig = "HTTPSMS_CONFIG"
+)
+
+// config is the connection to the API, the flags take precedence over the environment variables which take
+// precedence over the config file
+type config struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+}
+
+// defaultConfigPath returns the path of the config file when it is not set with a flag or HTTPSMS_CONFIG
+func defaultConfigPath() string {
+	if path := os.Getenv(envConfig); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "httpsms", "config.json")
+}
+
+// loadConfig resolves the config from the flags, the environment and the config file at path.
+// A missing config file is ignored unless the path was set explicitly.
+func loadConfig(path string, explicit bool, flags config) (config, error) {
+	result := config{}
+
+	if path != "" {
+		content, err := os.ReadFile(path)
+		switch {
+		case err == nil && len(bytes.TrimSpace(content)) == 0:
+		case err == nil:
+			if err = json.Unmarshal(content, &result); err != nil {
+				return result, stacktrace.Propagate(err, fmt.Sprintf("cannot decode config file [%s]", path))
+			}
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		default:
+			return result, stacktrace.Propagate(err, fmt.Sprintf("cannot read config file [%s]", path))
+		}
+	}
+
+	result.URL = firstNonEmpty(flags.URL, os.Getenv(envURL), result.URL, defaultURL)
+	result.APIKey = firstNonEmpty(flags.APIKey, os.Getenv(envAPIKey), result.APIKey)
+	result.URL = strings.TrimRight(result.URL, "/")
+
+	if result.APIKey == "" {
+		return result, stacktrace.NewError(fmt.Sprintf("the API key is not set, use the --api-key flag, the %s environment variable or the config file [%s]", envAPIKey, path))
+	}
+
+	return result, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
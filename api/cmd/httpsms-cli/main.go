@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+// sends and inspects messages with the public HTTP API e.g. httpsms send --to +18005550100 --content "hi".
+// The exit code is 0 on success, 1 when a command fails and 2 when it is called with invalid arguments.
+func main() {
+	stacktrace.DefaultFormat = stacktrace.FormatBrief
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := execute(ctx, os.Args[1:], os.Stdout, os.Stderr, &http.Client{Timeout: 30 * time.Second})
+	cancel()
+
+	os.Exit(code)
+}
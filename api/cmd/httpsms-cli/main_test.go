@@ -0,0 +1,524 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAPIKey = "test-api-key"
+
+// fakeAPI implements the endpoints of the API which are used by the CLI
+type fakeAPI struct {
+	mutex    sync.Mutex
+	messages []*entities.Message
+	queries  []url.Values
+	deleted  []string
+	imports  []*http.Request
+	uploads  []string
+}
+
+func newFakeAPI(t *testing.T) (*fakeAPI, string) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return api, server.URL
+}
+
+func (api *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-api-key") != testAPIKey {
+		api.error(w, http.StatusUnauthorized, "You are not authorized to carry out this request.")
+		return
+	}
+
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/send":
+		payload := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		api.respond(w, api.store(payload["from"], payload["to"], payload["content"], entities.MessageStatusPending))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/messages":
+		api.queries = append(api.queries, r.URL.Query())
+		api.respond(w, api.index(r.URL.Query()))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/import/twilio":
+		body, _ := io.ReadAll(r.Body)
+		api.imports = append(api.imports, r)
+		api.uploads = append(api.uploads, string(body))
+		api.respond(w, entities.TwilioImportResult{
+			Imported: 1,
+			Errored:  1,
+			Errors:   []entities.TwilioImportRowError{{Row: 2, SID: "SM4c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7f", Reason: "the status [queued] of an outgoing message is not final"}},
+		})
+	case strings.HasPrefix(r.URL.Path, "/v1/messages/"):
+		message := api.find(strings.TrimPrefix(r.URL.Path, "/v1/messages/"))
+		if message == nil {
+			api.error(w, http.StatusNotFound, fmt.Sprintf("cannot find message with ID [%s]", strings.TrimPrefix(r.URL.Path, "/v1/messages/")))
+			return
+		}
+		if r.Method == http.MethodDelete {
+			api.deleted = append(api.deleted, message.ID.String())
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		api.respond(w, message)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (api *fakeAPI) store(owner string, contact string, content string, status entities.MessageStatus) *entities.Message {
+	message := &entities.Message{
+		ID:        uuid.New(),
+		Owner:     owner,
+		Contact:   contact,
+		Content:   content,
+		Type:      entities.MessageTypeMobileTerminated,
+		Status:    status,
+		SIM:       entities.SIM1,
+		CreatedAt: time.Now().UTC(),
+	}
+	api.messages = append(api.messages, message)
+	return message
+}
+
+func (api *fakeAPI) find(id string) *entities.Message {
+	for _, message := range api.messages {
+		if message.ID.String() == id {
+			return message
+		}
+	}
+	return nil
+}
+
+func (api *fakeAPI) index(query url.Values) []entities.Message {
+	var skip, limit int
+	_, _ = fmt.Sscan(query.Get("skip"), &skip)
+	_, _ = fmt.Sscan(query.Get("limit"), &limit)
+
+	var messages []entities.Message
+	for _, message := range api.messages {
+		if message.Owner == query.Get("owner") && message.Contact == query.Get("contact") {
+			messages = append(messages, *message)
+		}
+	}
+
+	if skip >= len(messages) {
+		return []entities.Message{}
+	}
+	messages = messages[skip:]
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+func (api *fakeAPI) respond(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "message": "ok", "data": data})
+}
+
+func (api *fakeAPI) error(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": "error", "code": "error", "message": message})
+}
+
+// run executes the CLI with the URL and API key of the fake API
+func run(serverURL string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--url", serverURL, "--api-key", testAPIKey, "--config", os.DevNull}, args...)
+	code := execute(context.Background(), args, &stdout, &stderr, http.DefaultClient)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestSendCommand(t *testing.T) {
+	t.Run("message is sent and printed as JSON", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Act
+		code, stdout, _ := run(serverURL, "send", "--from", "+18005550199", "--to", "+18005550100", "--content", "hello", "-o", "json")
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+
+		message := new(entities.Message)
+		assert.Nil(t, json.Unmarshal([]byte(stdout), message))
+		assert.Equal(t, api.messages[0].ID, message.ID)
+		assert.Equal(t, "+18005550100", message.Contact)
+		assert.Equal(t, "hello", message.Content)
+	})
+
+	t.Run("missing flags are a usage error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Act
+		code, _, stderr := run(serverURL, "send", "--content", "hello")
+
+		// Assert
+		assert.Equal(t, exitCodeUsage, code)
+		assert.Contains(t, stderr, "--to")
+		assert.Empty(t, api.messages)
+	})
+
+	t.Run("invalid API key is a failure", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, serverURL := newFakeAPI(t)
+
+		// Act
+		var stdout, stderr bytes.Buffer
+		code := execute(context.Background(), []string{"send", "--url", serverURL, "--api-key", "invalid", "--config", os.DevNull, "--to", "+18005550100", "--content", "hello"}, &stdout, &stderr, http.DefaultClient)
+
+		// Assert
+		assert.Equal(t, exitCodeFailure, code)
+		assert.Contains(t, stderr.String(), "You are not authorized to carry out this request. [401]")
+	})
+}
+
+func TestMessagesListCommand(t *testing.T) {
+	t.Run("filters are sent to the API and the status is filtered", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Arrange
+		api.store("+18005550199", "+18005550100", "sent message", entities.MessageStatusSent)
+		failed := api.store("+18005550199", "+18005550100", "failed message", entities.MessageStatusFailed)
+
+		// Act
+		code, stdout, _ := run(serverURL, "messages", "list", "--owner", "+18005550199", "--contact", "+18005550100", "--query", "message", "--limit", "10", "--include-archived", "--status", "FAILED", "-o", "json")
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+
+		var messages []entities.Message
+		assert.Nil(t, json.Unmarshal([]byte(stdout), &messages))
+		assert.Equal(t, 1, len(messages))
+		assert.Equal(t, failed.ID, messages[0].ID)
+
+		assert.Equal(t, "message", api.queries[0].Get("query"))
+		assert.Equal(t, "10", api.queries[0].Get("limit"))
+		assert.Equal(t, "0", api.queries[0].Get("skip"))
+		assert.Equal(t, "true", api.queries[0].Get("include_archived"))
+	})
+
+	t.Run("messages are printed as a table", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Arrange
+		message := api.store("+18005550199", "+18005550100", "hello\nworld", entities.MessageStatusDelivered)
+
+		// Act
+		code, stdout, _ := run(serverURL, "messages", "list", "--owner", "+18005550199", "--contact", "+18005550100")
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		assert.Equal(t, 2, len(lines))
+		assert.Equal(t, []string{"ID", "OWNER", "CONTACT", "STATUS", "CREATED", "AT", "CONTENT"}, strings.Fields(lines[0]))
+		assert.Contains(t, lines[1], message.ID.String())
+		assert.Contains(t, lines[1], "delivered")
+		assert.Contains(t, lines[1], "hello world")
+	})
+
+	t.Run("invalid output format is a usage error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Act
+		code, _, _ := run(serverURL, "messages", "list", "--owner", "+18005550199", "--contact", "+18005550100", "-o", "xml")
+
+		// Assert
+		assert.Equal(t, exitCodeUsage, code)
+		assert.Empty(t, api.queries)
+	})
+}
+
+func TestMessagesGetCommand(t *testing.T) {
+	t.Run("missing message is a failure", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, serverURL := newFakeAPI(t)
+		messageID := uuid.NewString()
+
+		// Act
+		code, stdout, stderr := run(serverURL, "messages", "get", messageID)
+
+		// Assert
+		assert.Equal(t, exitCodeFailure, code)
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, fmt.Sprintf("cannot find message with ID [%s] [404]", messageID))
+	})
+
+	t.Run("missing argument is a usage error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, serverURL := newFakeAPI(t)
+
+		// Act
+		code, _, _ := run(serverURL, "messages", "get")
+
+		// Assert
+		assert.Equal(t, exitCodeUsage, code)
+	})
+}
+
+func TestMessagesCancelCommand(t *testing.T) {
+	t.Run("pending message is deleted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Arrange
+		message := api.store("+18005550199", "+18005550100", "hello", entities.MessageStatusPending)
+
+		// Act
+		code, stdout, _ := run(serverURL, "messages", "cancel", message.ID.String())
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+		assert.Equal(t, []string{message.ID.String()}, api.deleted)
+		assert.Contains(t, stdout, "has been cancelled")
+	})
+
+	t.Run("sent message cannot be cancelled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Arrange
+		message := api.store("+18005550199", "+18005550100", "hello", entities.MessageStatusSent)
+
+		// Act
+		code, _, stderr := run(serverURL, "messages", "cancel", message.ID.String())
+
+		// Assert
+		assert.Equal(t, exitCodeFailure, code)
+		assert.Empty(t, api.deleted)
+		assert.Contains(t, stderr, "cannot be cancelled because it has status [sent]")
+	})
+}
+
+func TestMessagesRequeueCommand(t *testing.T) {
+	t.Run("failed message is sent again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Arrange
+		message := api.store("+18005550199", "+18005550100", "hello", entities.MessageStatusFailed)
+
+		// Act
+		code, stdout, _ := run(serverURL, "messages", "requeue", message.ID.String(), "-o", "json")
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+
+		requeued := new(entities.Message)
+		assert.Nil(t, json.Unmarshal([]byte(stdout), requeued))
+		assert.NotEqual(t, message.ID, requeued.ID)
+		assert.Equal(t, entities.MessageStatusPending, requeued.Status)
+		assert.Equal(t, message.Owner, requeued.Owner)
+		assert.Equal(t, message.Contact, requeued.Contact)
+		assert.Equal(t, message.Content, requeued.Content)
+	})
+
+	t.Run("delivered message cannot be requeued", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Arrange
+		message := api.store("+18005550199", "+18005550100", "hello", entities.MessageStatusDelivered)
+
+		// Act
+		code, _, _ := run(serverURL, "messages", "requeue", message.ID.String())
+
+		// Assert
+		assert.Equal(t, exitCodeFailure, code)
+		assert.Equal(t, 1, len(api.messages))
+	})
+}
+
+func TestMessagesExportCommand(t *testing.T) {
+	t.Run("all the pages are exported as CSV", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+		file := filepath.Join(t.TempDir(), "messages.csv")
+
+		// Arrange
+		for i := 0; i < 25; i++ {
+			api.store("+18005550199", "+18005550100", fmt.Sprintf("message %d", i), entities.MessageStatusDelivered)
+		}
+		api.store("+18005550199", "+18005550101", "other contact", entities.MessageStatusDelivered)
+
+		// Act
+		code, stdout, _ := run(serverURL, "messages", "export", "--owner", "+18005550199", "--contact", "+18005550100", "--format", "csv", "--file", file)
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+		assert.Contains(t, stdout, "exported [25] messages")
+		assert.Equal(t, 2, len(api.queries))
+
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+		assert.Nil(t, err)
+		assert.Equal(t, 26, len(rows))
+		assert.Equal(t, "id", rows[0][0])
+		assert.Equal(t, "message 24", rows[25][6])
+	})
+}
+
+func TestMessagesImportTwilioCommand(t *testing.T) {
+	t.Run("the export is uploaded and the summary is printed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+		file := filepath.Join(t.TempDir(), "messages.csv")
+
+		// Arrange
+		export := "From,To,Body,Status,SentDate,Sid,Direction\n+18005550199,+18005550100,hello,delivered,2023-08-24T05:01:45-07:00,SM1f0e8ae6ade43cb3c0ce4525424e404f,outbound-api\n"
+		require.NoError(t, os.WriteFile(file, []byte(export), 0o600))
+
+		// Act
+		code, stdout, _ := run(serverURL, "messages", "import-twilio", file, "--owner", "+18005550199")
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+		assert.Equal(t, 1, len(api.imports))
+		assert.Equal(t, export, api.uploads[0])
+		assert.Equal(t, "csv", api.imports[0].URL.Query().Get("format"))
+		assert.Equal(t, "+18005550199", api.imports[0].URL.Query().Get("owner"))
+		assert.Equal(t, "text/csv", api.imports[0].Header.Get("Content-Type"))
+		assert.Contains(t, stdout, "IMPORTED  1")
+		assert.Contains(t, stdout, "SM4c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7f")
+	})
+
+	t.Run("an unknown format is a usage error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		api, serverURL := newFakeAPI(t)
+
+		// Act
+		code, _, stderr := run(serverURL, "messages", "import-twilio", "messages.xml", "--format", "xml")
+
+		// Assert
+		assert.Equal(t, exitCodeUsage, code)
+		assert.Contains(t, stderr, "[xml]")
+		assert.Empty(t, api.imports)
+	})
+}
+
+func TestWebhooksTestCommand(t *testing.T) {
+	t.Run("sample event is signed with the signing key", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		received := make(chan *http.Request, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received <- r
+		}))
+		t.Cleanup(server.Close)
+
+		// Act
+		code, stdout, _ := run("http://localhost", "webhooks", "test", server.URL, "--signing-key", "secret", "--event", "message.phone.delivered")
+
+		// Assert
+		assert.Equal(t, exitCodeSuccess, code)
+		assert.Contains(t, stdout, "status code [200]")
+
+		request := <-received
+		assert.Equal(t, "message.phone.delivered", request.Header.Get("X-Event-Type"))
+
+		token, err := jwt.ParseWithClaims(strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer "), &jwt.StandardClaims{}, func(token *jwt.Token) (any, error) {
+			return []byte("secret"), nil
+		})
+		assert.Nil(t, err)
+		assert.True(t, token.Valid)
+		assert.Equal(t, server.URL, token.Claims.(*jwt.StandardClaims).Audience)
+	})
+
+	t.Run("webhook which rejects the event is a failure", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(server.Close)
+
+		// Act
+		code, _, stderr := run("http://localhost", "webhooks", "test", server.URL)
+
+		// Assert
+		assert.Equal(t, exitCodeFailure, code)
+		assert.Contains(t, stderr, "status code [502]")
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("flags take precedence over the environment and the config file", func(t *testing.T) {
+		// Setup
+		file := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"url": "https://file.example.com/", "api_key": "file-key"}`), 0o600))
+
+		// Arrange
+		t.Setenv(envURL, "")
+		t.Setenv(envAPIKey, "env-key")
+
+		// Act
+		fromFile, err := loadConfig(file, true, config{})
+		assert.Nil(t, err)
+		fromFlags, err := loadConfig(file, true, config{APIKey: "flag-key"})
+		assert.Nil(t, err)
+
+		// Assert
+		assert.Equal(t, config{URL: "https://file.example.com", APIKey: "env-key"}, fromFile)
+		assert.Equal(t, config{URL: "https://file.example.com", APIKey: "flag-key"}, fromFlags)
+	})
+
+	t.Run("missing config file is only an error when it is set explicitly", func(t *testing.T) {
+		// Setup
+		file := filepath.Join(t.TempDir(), "missing.json")
+		t.Setenv(envURL, "")
+		t.Setenv(envAPIKey, "")
+
+		// Act
+		_, explicitErr := loadConfig(file, true, config{APIKey: "flag-key"})
+		result, err := loadConfig(file, false, config{APIKey: "flag-key"})
+
+		// Assert
+		assert.NotNil(t, explicitErr)
+		assert.Nil(t, err)
+		assert.Equal(t, defaultURL, result.URL)
+	})
+}
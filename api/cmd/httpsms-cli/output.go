@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// outputTable prints the results as a table which is easy to read in a terminal
+	outputTable = "table"
+
+	// outputJSON prints the results as JSON which can be piped to tools like jq
+	outputJSON = "json"
+
+	// maxContentWidth is the number of characters of the content which are printed in a table
+	maxContentWidth = 40
+)
+
+// printer writes the results of a command in the output format which is selected with the --output flag
+type printer struct {
+	writer io.Writer
+	format string
+}
+
+// Messages prints a list of entities.Message
+func (p *printer) Messages(messages []entities.Message) error {
+	if p.format == outputJSON {
+		return p.json(messages)
+	}
+
+	writer := tabwriter.NewWriter(p.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tOWNER\tCONTACT\tSTATUS\tCREATED AT\tCONTENT")
+	for _, message := range messages {
+		fmt.Fprintf(
+			writer,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			message.ID,
+			message.Owner,
+			message.Contact,
+			message.Status,
+			message.CreatedAt.Format(time.RFC3339),
+			truncate(message.Content, maxContentWidth),
+		)
+	}
+	return writer.Flush()
+}
+
+// Message prints the fields of a single entities.Message
+func (p *printer) Message(message *entities.Message) error {
+	if p.format == outputJSON {
+		return p.json(message)
+	}
+
+	writer := tabwriter.NewWriter(p.writer, 0, 0, 2, ' ', 0)
+	rows := [][2]string{
+		{"ID", message.ID.String()},
+		{"OWNER", message.Owner},
+		{"CONTACT", message.Contact},
+		{"TYPE", string(message.Type)},
+		{"STATUS", string(message.Status)},
+		{"SIM", string(message.SIM)},
+		{"CONTENT", message.Content},
+		{"CREATED AT", message.CreatedAt.Format(time.RFC3339)},
+	}
+	if message.FailureReason != nil {
+		rows = append(rows, [2]string{"FAILURE REASON", *message.FailureReason})
+	}
+	for _, row := range rows {
+		fmt.Fprintf(writer, "%s\t%s\n", row[0], row[1])
+	}
+	return writer.Flush()
+}
+
+// TwilioImport prints the summary of an import of a Twilio export and the rows which could not be imported
+func (p *printer) TwilioImport(result *entities.TwilioImportResult) error {
+	if p.format == outputJSON {
+		return p.json(result)
+	}
+
+	writer := tabwriter.NewWriter(p.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "IMPORTED\t%d\nSKIPPED\t%d\nERRORED\t%d\n", result.Imported, result.Skipped, result.Errored)
+	if len(result.Errors) > 0 {
+		fmt.Fprintln(writer, "\nROW\tSID\tREASON")
+		for _, rowErr := range result.Errors {
+			fmt.Fprintf(writer, "%d\t%s\t%s\n", rowErr.Row, rowErr.SID, rowErr.Reason)
+		}
+	}
+	return writer.Flush()
+}
+
+func (p *printer) json(value any) error {
+	encoder := json.NewEncoder(p.writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot encode [%T] into JSON", value))
+	}
+	return nil
+}
+
+// truncate shortens the content on a single line so that it fits in a table cell
+func truncate(content string, width int) string {
+	content = strings.Join(strings.Fields(content), " ")
+	runes := []rune(content)
+	if len(runes) <= width {
+		return content
+	}
+	return string(runes[:width-3]) + "..."
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// webhookTestSource is the source of the sample events which are sent by the webhooks test command
+const webhookTestSource = "/v1/cli/webhooks/test"
+
+// webhookSamplePayloads are the payloads of the sample events which can be sent to a webhook
+var webhookSamplePayloads = map[string]func(id uuid.UUID, timestamp time.Time) any{
+	events.EventTypeMessagePhoneReceived: func(id uuid.UUID, timestamp time.Time) any {
+		return &events.MessagePhoneReceivedPayload{
+			MessageID: id,
+			UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+			Owner:     "+18005550199",
+			Contact:   "+18005550100",
+			Timestamp: timestamp,
+			Content:   "This is a sample text message",
+			SIM:       entities.SIM1,
+		}
+	},
+	events.EventTypeMessagePhoneSent: func(id uuid.UUID, timestamp time.Time) any {
+		return &events.MessagePhoneSentPayload{
+			ID:        id,
+			UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+			Owner:     "+18005550199",
+			Contact:   "+18005550100",
+			Timestamp: timestamp,
+			Content:   "This is a sample text message",
+			SIM:       entities.SIM1,
+		}
+	},
+	events.EventTypeMessagePhoneDelivered: func(id uuid.UUID, timestamp time.Time) any {
+		return &events.MessagePhoneDeliveredPayload{
+			ID:        id,
+			UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+			Owner:     "+18005550199",
+			Contact:   "+18005550100",
+			Timestamp: timestamp,
+			Content:   "This is a sample text message",
+			SIM:       entities.SIM1,
+		}
+	},
+	events.EventTypeMessageSendFailed: func(id uuid.UUID, timestamp time.Time) any {
+		return &events.MessageSendFailedPayload{
+			ID:           id,
+			ErrorMessage: "RESULT_ERROR_GENERIC_FAILURE",
+			UserID:       "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+			Owner:        "+18005550199",
+			Contact:      "+18005550100",
+			Timestamp:    timestamp,
+			Content:      "This is a sample text message",
+			SIM:          entities.SIM1,
+		}
+	},
+}
+
+// webhookSampleEvents returns the types of the sample events in alphabetical order
+func webhookSampleEvents() []string {
+	types := make([]string, 0, len(webhookSamplePayloads))
+	for eventType := range webhookSamplePayloads {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// sendWebhookTest sends a sample event to a webhook like the API does and returns the status code of the response.
+// The request is signed with the signing key when it is not empty so that the verification of the webhook is tested.
+func sendWebhookTest(ctx context.Context, client *http.Client, url string, signingKey string, eventType string) (int, error) {
+	payload, ok := webhookSamplePayloads[eventType]
+	if !ok {
+		return 0, stacktrace.NewError(fmt.Sprintf("there is no sample for event [%s], use one of [%s]", eventType, strings.Join(webhookSampleEvents(), ", ")))
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.NewString())
+	event.SetSource(webhookTestSource)
+	event.SetType(eventType)
+	event.SetTime(time.Now().UTC())
+	if err := event.SetData(cloudevents.ApplicationJSON, payload(uuid.New(), event.Time())); err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot set the payload of event [%s]", eventType))
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot marshal event [%s] into JSON", eventType))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot create request for webhook [%s]", url))
+	}
+
+	request.Header.Add("X-Event-Type", eventType)
+	request.Header.Set("Content-Type", "application/json")
+
+	if strings.TrimSpace(signingKey) != "" {
+		token, err := webhookAuthToken(url, signingKey)
+		if err != nil {
+			return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot generate auth token for webhook [%s]", url))
+		}
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot send event [%s] to webhook [%s]", eventType, url))
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, stacktrace.NewError(fmt.Sprintf("webhook [%s] responded with status code [%d]", url, response.StatusCode))
+	}
+
+	return response.StatusCode, nil
+}
+
+// webhookAuthToken creates the JWT which is sent by the API in the Authorization header of a webhook request
+func webhookAuthToken(url string, signingKey string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+		Audience:  url,
+		ExpiresAt: time.Now().UTC().Add(10 * time.Minute).Unix(),
+		IssuedAt:  time.Now().UTC().Unix(),
+		Issuer:    "api.httpsms.com",
+		NotBefore: time.Now().UTC().Add(-10 * time.Minute).Unix(),
+		Subject:   "httpsms-cli",
+	})
+	return token.SignedString([]byte(signingKey))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// deletes the media which was uploaded but not attached to a message within MEDIA_TTL, it is scheduled to run hourly
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+
+	count, err := container.MediaService().DeleteUnattached(context.Background())
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot delete unattached media after deleting [%d] media", count)))
+	}
+	logger.Info(fmt.Sprintf("[%d] unattached media deleted successfully", count))
+}
@@ -3,12 +3,18 @@ package main
 import (
 	"log"
 
+	"github.com/NdoleStudio/httpsms/pkg/di"
 	"github.com/joho/godotenv"
 )
 
+// applies the database migrations without starting the API, the migrations are run when the container connects to the database
 func main() {
 	err := godotenv.Load("../../.env")
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
+
+	container := di.NewLiteContainer()
+	container.DB()
+	container.Logger().Info("database migrations applied successfully")
 }
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// deletes the messages which are older than the retention period of each user, it is scheduled to run nightly
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+
+	logger.Info(fmt.Sprintf("applying message retention with hard delete [%t]", container.MessageRetentionHardDelete()))
+	count, err := container.MessageRetentionService().Apply(context.Background(), "cmd/retention")
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot apply message retention after deleting [%d] messages", count)))
+	}
+	logger.Info(fmt.Sprintf("[%d] messages deleted successfully", count))
+}
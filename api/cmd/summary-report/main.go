@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// sends the summary report emails whose period has ended, it is scheduled to run every 15 minutes
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+
+	count, err := container.SummaryReportService().SendDue(context.Background(), time.Now().UTC())
+	if err != nil {
+		logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot send the summary reports after sending [%d] reports", count)))
+	}
+	logger.Info(fmt.Sprintf("[%d] summary reports sent successfully", count))
+}
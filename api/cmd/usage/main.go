@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// rebuilds the monthly billing usage of all users from the messages history
+// messages which have been soft deleted by users are still counted
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	container := di.NewLiteContainer()
+	logger := container.Logger()
+
+	logger.Info("rebuilding billing usage from messages")
+	if err = container.BillingService().RebuildUsage(context.Background()); err != nil {
+		logger.Fatal(stacktrace.Propagate(err, "cannot rebuild billing usage"))
+	}
+	logger.Info("billing usage rebuilt successfully")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/virtualphone"
+	"github.com/hirosassa/zerodriver"
+	"github.com/joho/godotenv"
+	"github.com/palantir/stacktrace"
+)
+
+// runs a virtual phone which sends the outstanding messages of an owner through the public API like the android app.
+// Use -receive-from and -receive-content to synthesize one inbound message instead.
+func main() {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatal("Error loading .env file")
+	}
+
+	config := virtualphone.Config{}
+	flag.StringVar(&config.BaseURL, "url", "http://localhost:8000", "URL of the API")
+	flag.StringVar(&config.Owner, "owner", os.Getenv("HTTPSMS_FROM"), "phone number of the virtual phone")
+	flag.DurationVar(&config.PollInterval, "poll-interval", 2*time.Second, "time between two checks for outstanding messages")
+	flag.Float64Var(&config.FailureRate, "failure-rate", 0, "probability between 0 and 1 that a message fails")
+	flag.DurationVar(&config.MinDelay, "min-delay", 0, "minimum time it takes to send a message")
+	flag.DurationVar(&config.MaxDelay, "max-delay", time.Second, "maximum time it takes to send a message")
+	flag.BoolVar(&config.Deliver, "deliver", true, "emit the DELIVERED event after a message is sent")
+	flag.Int64Var(&config.Seed, "seed", time.Now().UnixNano(), "seed of the random failures and delays")
+	receiveFrom := flag.String("receive-from", "", "contact which sends an inbound message to the virtual phone")
+	receiveContent := flag.String("receive-content", "Hello from the virtual phone", "content of the inbound message")
+	flag.Parse()
+
+	config.APIKey = os.Getenv("HTTPSMS_KEY")
+
+	logger := telemetry.NewZerologLogger("", map[string]string{"pid": strconv.Itoa(os.Getpid())}, zerodriver.NewDevelopmentLogger(), nil)
+	phone := virtualphone.New(logger, http.DefaultClient, config)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	if *receiveFrom != "" {
+		message, err := phone.Receive(ctx, *receiveFrom, *receiveContent)
+		if err != nil {
+			logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot receive message from [%s]", *receiveFrom)))
+		}
+		logger.Info(fmt.Sprintf("message [%s] received from [%s] on [%s]", message.ID, message.Contact, message.Owner))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("virtual phone [%s] is polling [%s] every [%s]", config.Owner, config.BaseURL, config.PollInterval))
+	if err = phone.Run(ctx); err != nil {
+		logger.Fatal(stacktrace.Propagate(err, "cannot run virtual phone"))
+	}
+}
@@ -0,0 +1,206 @@
+package docs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/swaggo/swag"
+)
+
+func TestSwaggerSpecIsCurrent(t *testing.T) {
+	t.Run("the checked in specification matches the annotations on the handlers", func(t *testing.T) {
+		// Arrange
+		parser := swag.New(swag.SetDebugger(log.New(io.Discard, "", 0)), swag.ParseUsingGoList(true))
+		parser.PropNamingStrategy = swag.CamelCase
+		parser.RequiredByDefault = true
+
+		// Act
+		err := parser.ParseAPI("..", "main.go", 100)
+
+		// Assert
+		require.NoError(t, err)
+
+		generated, err := json.Marshal(parser.GetSwagger())
+		require.NoError(t, err)
+
+		assert.JSONEq(t, string(readFile(t, "swagger.json")), string(generated), "the swagger specification is out of date, run `go generate` in the api directory")
+	})
+
+	t.Run("the registered specification matches swagger.json", func(t *testing.T) {
+		// Act
+		doc, err := swag.ReadDoc()
+
+		// Assert
+		require.NoError(t, err)
+		assert.JSONEq(t, string(readFile(t, "swagger.json")), doc)
+	})
+}
+
+func TestSwaggerExamples(t *testing.T) {
+	spec := loadSpec(t)
+
+	t.Run("example payloads validate against the schemas", func(t *testing.T) {
+		examples := map[string]string{
+			"message_send_request.json":          "requests.MessageSend",
+			"message_response.json":              "responses.MessageResponse",
+			"bad_request_response.json":          "responses.BadRequest",
+			"unprocessable_entity_response.json": "responses.UnprocessableEntity",
+		}
+
+		for file, definition := range examples {
+			// Arrange
+			var payload any
+			require.NoError(t, json.Unmarshal(readFile(t, filepath.Join("testdata", file)), &payload))
+
+			// Act
+			errors := spec.validate(schema{"$ref": "#/definitions/" + definition}, payload, file)
+
+			// Assert
+			assert.Empty(t, errors, file)
+		}
+	})
+
+	t.Run("the examples of the properties validate against their schemas", func(t *testing.T) {
+		for name, definition := range spec.Definitions {
+			properties, _ := definition["properties"].(map[string]any)
+			for property, value := range properties {
+				propertySchema := schema(value.(map[string]any))
+				example, ok := propertySchema["example"]
+				if !ok {
+					continue
+				}
+
+				// Act
+				errors := spec.validate(propertySchema, example, name+"."+property)
+
+				// Assert
+				assert.Empty(t, errors, name+"."+property)
+			}
+		}
+	})
+}
+
+type schema map[string]any
+
+type swaggerSpec struct {
+	Definitions map[string]schema `json:"definitions"`
+}
+
+func loadSpec(t *testing.T) *swaggerSpec {
+	spec := new(swaggerSpec)
+	require.NoError(t, json.Unmarshal(readFile(t, "swagger.json"), spec))
+	return spec
+}
+
+func readFile(t *testing.T, name string) []byte {
+	content, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return content
+}
+
+// validate checks a JSON value against the subset of JSON schema which is generated by swag.
+// A null value is always valid because swag does not mark pointer fields as nullable.
+func (spec *swaggerSpec) validate(s schema, value any, path string) (errors []string) {
+	if value == nil {
+		return nil
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		definition, ok := spec.Definitions[strings.TrimPrefix(ref, "#/definitions/")]
+		if !ok {
+			return []string{fmt.Sprintf("%s: cannot find the definition [%s]", path, ref)}
+		}
+		return spec.validate(definition, value, path)
+	}
+
+	if allOf, ok := s["allOf"].([]any); ok {
+		for _, item := range allOf {
+			errors = append(errors, spec.validate(item.(map[string]any), value, path)...)
+		}
+	}
+
+	if enum, ok := s["enum"].([]any); ok && !contains(enum, value) {
+		errors = append(errors, fmt.Sprintf("%s: [%v] is not one of %v", path, value, enum))
+	}
+
+	switch s["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(errors, fmt.Sprintf("%s: [%v] is not an object", path, value))
+		}
+		errors = append(errors, spec.validateObject(s, object, path)...)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return append(errors, fmt.Sprintf("%s: [%v] is not an array", path, value))
+		}
+		if items, ok := s["items"].(map[string]any); ok {
+			for index, item := range array {
+				errors = append(errors, spec.validate(items, item, fmt.Sprintf("%s[%d]", path, index))...)
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errors = append(errors, fmt.Sprintf("%s: [%v] is not a string", path, value))
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			errors = append(errors, fmt.Sprintf("%s: [%v] is not an integer", path, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			errors = append(errors, fmt.Sprintf("%s: [%v] is not a number", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			errors = append(errors, fmt.Sprintf("%s: [%v] is not a boolean", path, value))
+		}
+	}
+
+	return errors
+}
+
+func (spec *swaggerSpec) validateObject(s schema, object map[string]any, path string) (errors []string) {
+	if required, ok := s["required"].([]any); ok {
+		for _, property := range required {
+			if _, ok := object[property.(string)]; !ok {
+				errors = append(errors, fmt.Sprintf("%s: the [%s] property is required", path, property))
+			}
+		}
+	}
+
+	properties, _ := s["properties"].(map[string]any)
+	additional, _ := s["additionalProperties"].(map[string]any)
+	for key, value := range object {
+		if property, ok := properties[key].(map[string]any); ok {
+			errors = append(errors, spec.validate(property, value, path+"."+key)...)
+			continue
+		}
+		if additional != nil {
+			errors = append(errors, spec.validate(additional, value, path+"."+key)...)
+			continue
+		}
+		errors = append(errors, fmt.Sprintf("%s: the [%s] property is not in the schema", path, key))
+	}
+
+	return errors
+}
+
+func contains(values []any, value any) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
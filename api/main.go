@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/NdoleStudio/httpsms/docs"
 	"github.com/NdoleStudio/httpsms/pkg/di"
+	"github.com/palantir/stacktrace"
 )
 
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.2 init --requiredByDefault
+
 // Version is injected at runtime
 var Version string
 
@@ -28,11 +34,40 @@ var Version string
 // @securitydefinitions.apikey ApiKeyAuth
 // @in header
 // @name x-api-Key
+//
+// @securitydefinitions.basic BasicAuth
 func main() {
 	if len(os.Args) == 1 {
 		di.LoadEnv()
 	}
 
 	container := di.NewContainer("http-sms", Version)
-	container.Logger().Info(container.App().Listen(fmt.Sprintf("%s:%s", os.Getenv("APP_HOST"), os.Getenv("APP_PORT"))).Error())
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		go func() {
+			container.Logger().Info(container.ServeGRPC(fmt.Sprintf("%s:%s", os.Getenv("APP_HOST"), port)).Error())
+		}()
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- container.App().Listen(fmt.Sprintf("%s:%s", os.Getenv("APP_HOST"), os.Getenv("APP_PORT")))
+	}()
+
+	// SIGTERM is sent before the instance is stopped, the requests and the events in flight are drained until the timeout
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	select {
+	case err := <-listenErr:
+		container.Logger().Error(stacktrace.Propagate(err, "cannot serve HTTP requests"))
+	case <-ctx.Done():
+		container.Logger().Info("received shutdown signal")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), container.ShutdownTimeout())
+	defer cancel()
+
+	if err := container.Shutdown(ctx); err != nil {
+		container.Logger().Error(stacktrace.Propagate(err, "cannot shutdown the API gracefully"))
+	}
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	otelMetric "go.opentelemetry.io/otel/metric"
@@ -32,10 +34,13 @@ import (
 	"github.com/jinzhu/now"
 
 	"github.com/uptrace/uptrace-go/uptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 
 	"github.com/NdoleStudio/httpsms/pkg/emails"
 
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
+	gcs "cloud.google.com/go/storage"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -48,23 +53,32 @@ import (
 
 	firebase "firebase.google.com/go"
 	"firebase.google.com/go/auth"
+	"github.com/NdoleStudio/httpsms/pkg/grpcapi"
 	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 
 	"github.com/gofiber/fiber/v2/middleware/cors"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/listeners"
+	"github.com/NdoleStudio/httpsms/pkg/migrations"
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
 	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/slack"
+	"github.com/NdoleStudio/httpsms/pkg/storage"
+	"github.com/NdoleStudio/httpsms/pkg/telegram"
 	"github.com/gofiber/fiber/v2"
 	fiberLogger "github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/swagger"
 	"github.com/palantir/stacktrace"
 	ttlCache "github.com/patrickmn/go-cache"
 	"gorm.io/gorm"
 
 	"github.com/NdoleStudio/httpsms/pkg/handlers"
+	"github.com/NdoleStudio/httpsms/pkg/ids"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/NdoleStudio/httpsms/pkg/validators"
 	"gorm.io/driver/postgres"
@@ -76,9 +90,19 @@ type Container struct {
 	projectID       string
 	db              *gorm.DB
 	dedicatedDB     *gorm.DB
+	replicaDB       *gorm.DB
 	version         string
 	app             *fiber.App
 	eventDispatcher *services.EventDispatcher
+	eventBroker     *services.EventBroker
+	websocketHub    *services.WebsocketHub
+	rateLimiter     *services.RateLimiter
+	grpcServer      *grpc.Server
+	flushTelemetry  func()
+	ristrettoCache  *ristretto.Cache
+	apiKeyService   *services.APIKeyService
+	metricsRegistry *telemetry.MetricsRegistry
+	errorReporter   telemetry.ErrorReporter
 	logger          telemetry.Logger
 }
 
@@ -107,21 +131,31 @@ func NewContainer(projectID string, version string) (container *Container) {
 		logger:    logger(3).WithService(fmt.Sprintf("%T", container)),
 	}
 
-	container.InitializeTraceProvider()
+	container.flushTelemetry = container.InitializeTraceProvider()
+	container.InitializeLogRedaction()
 
 	container.RegisterMessageListeners()
+	container.RegisterMessageStreamRoutes()
+	container.RegisterMessageStreamListeners()
 	container.RegisterMessageRoutes()
 	container.RegisterBulkMessageRoutes()
 
 	container.RegisterMessageThreadRoutes()
 	container.RegisterMessageThreadListeners()
 
+	container.RegisterWebsocketRoutes()
+	container.RegisterWebsocketListeners()
+
 	container.RegisterHeartbeatRoutes()
 	container.RegisterHeartbeatListeners()
 
 	container.RegisterUserRoutes()
 	container.RegisterUserListeners()
 
+	container.RegisterAPIKeyRoutes()
+
+	container.RegisterTeamRoutes()
+
 	container.RegisterPhoneRoutes()
 
 	container.RegisterEventRoutes()
@@ -140,9 +174,39 @@ func NewContainer(projectID string, version string) (container *Container) {
 	container.RegisterIntegration3CXRoutes()
 	container.RegisterIntegration3CXListeners()
 
+	container.RegisterTwilioRoutes()
+	container.RegisterTwilioListeners()
+
 	container.RegisterDiscordRoutes()
 	container.RegisterDiscordListeners()
 
+	container.RegisterSlackRoutes()
+	container.RegisterSlackListeners()
+
+	container.RegisterTelegramRoutes()
+	container.RegisterTelegramListeners()
+
+	container.RegisterFallbackRoutes()
+	container.RegisterFallbackListeners()
+
+	container.RegisterNotificationPreferenceRoutes()
+	container.RegisterSummaryReportRoutes()
+	container.RegisterContactRoutes()
+	container.RegisterContactGroupRoutes()
+	container.RegisterNumberLookupRoutes()
+	container.RegisterRoutingRuleRoutes()
+	container.RegisterMessageRateRoutes()
+	container.RegisterMessageStatisticsRoutes()
+	container.RegisterShortLinkRoutes()
+	container.RegisterMediaRoutes()
+	container.RegisterContentExpiryRuleRoutes()
+	container.RegisterAccountExportRoutes()
+	container.RegisterTwilioImportRoutes()
+
+	container.RegisterMetricsRoutes()
+	container.RegisterHealthRoutes()
+	container.StartMessageQueueMetrics()
+
 	// this has to be last since it registers the /* route
 	container.RegisterSwaggerRoutes()
 
@@ -157,18 +221,30 @@ func (container *Container) App() (app *fiber.App) {
 
 	container.logger.Debug(fmt.Sprintf("creating %T", app))
 
-	app = fiber.New()
+	// the request bodies are streamed so that large uploads e.g. Twilio exports are not read into memory, the
+	// default limit still applies to the bodies of the other routes
+	app = fiber.New(fiber.Config{StreamRequestBody: true})
 
 	if os.Getenv("APP_HTTP_LOGGER") == "true" {
 		app.Use(fiberLogger.New())
 	}
 
 	app.Use(otelfiber.Middleware())
-	app.Use(cors.New())
+	app.Use(middlewares.RequestID())
+	app.Use(middlewares.BodyLimit(fiber.DefaultBodyLimit, container.StreamedRoutes()...))
+	app.Use(middlewares.APIVersion(app, container.APIVersionConfig()))
+	app.Use(middlewares.HTTPMetrics(container.HTTPRequestDuration()))
+	app.Use(cors.New(cors.Config{
+		// the pagination headers are read by the web app
+		ExposeHeaders: "Link, X-Total-Count, API-Version, Deprecation, Sunset, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After",
+	}))
 	app.Use(middlewares.HTTPRequestLogger(container.Tracer(), container.Logger()))
+	app.Use(middlewares.Timeout(container.Logger(), container.Tracer(), container.TimeoutConfig()))
 
-	app.Use(middlewares.BearerAuth(container.Logger(), container.Tracer(), container.FirebaseAuthClient()))
-	app.Use(middlewares.APIKeyAuth(container.Logger(), container.Tracer(), container.UserRepository()))
+	app.Use(middlewares.BearerAuth(container.Logger(), container.Tracer(), container.TokenVerifier(), container.UserService()))
+	app.Use(middlewares.APIKeyAuth(container.Logger(), container.Tracer(), container.UserRepository(), container.APIKeyRepository(), container.APIKeyService()))
+	app.Use(middlewares.TeamAuth(container.Logger(), container.Tracer(), container.TeamRepository()))
+	app.Use(middlewares.RateLimit(container.Logger(), container.Tracer(), container.RateLimiter(), container.RateLimitConfig()))
 
 	container.app = app
 	return app
@@ -177,7 +253,13 @@ func (container *Container) App() (app *fiber.App) {
 // BearerAPIKeyMiddleware creates a new instance of middlewares.BearerAPIKeyAuth
 func (container *Container) BearerAPIKeyMiddleware() fiber.Handler {
 	container.logger.Debug("creating middlewares.BearerAPIKeyAuth")
-	return middlewares.BearerAPIKeyAuth(container.Logger(), container.Tracer(), container.UserRepository())
+	return middlewares.BearerAPIKeyAuth(container.Logger(), container.Tracer(), container.UserRepository(), container.APIKeyRepository(), container.APIKeyService())
+}
+
+// BasicAPIKeyMiddleware creates a new instance of middlewares.BasicAPIKeyAuth
+func (container *Container) BasicAPIKeyMiddleware() fiber.Handler {
+	container.logger.Debug("creating middlewares.BasicAPIKeyAuth")
+	return middlewares.BasicAPIKeyAuth(container.Logger(), container.Tracer(), container.UserRepository(), container.APIKeyRepository(), container.APIKeyService())
 }
 
 // AuthenticatedMiddleware creates a new instance of middlewares.Authenticated
@@ -186,10 +268,115 @@ func (container *Container) AuthenticatedMiddleware() fiber.Handler {
 	return middlewares.Authenticated(container.Tracer())
 }
 
-// AuthRouter creates router for authenticated requests
+// MinimumAppVersionMiddleware creates a new instance of middlewares.MinimumAppVersion
+func (container *Container) MinimumAppVersionMiddleware() fiber.Handler {
+	container.logger.Debug("creating middlewares.MinimumAppVersion")
+	return middlewares.MinimumAppVersion(container.Logger(), container.Tracer(), os.Getenv("MINIMUM_APP_VERSION"))
+}
+
+// AuthRouter creates router for authenticated requests to the responses.APIVersion1 routes
 func (container *Container) AuthRouter() fiber.Router {
-	container.logger.Debug("creating authRouter")
-	return container.App().Group("v1").Use(container.AuthenticatedMiddleware())
+	return container.VersionRouter(responses.APIVersion1)
+}
+
+// VersionRouter creates router for authenticated requests to a version of the API e.g. /v2
+func (container *Container) VersionRouter(version string) fiber.Router {
+	container.logger.Debug(fmt.Sprintf("creating router for version [%s]", version))
+	return container.App().Group(version).Use(container.AuthenticatedMiddleware())
+}
+
+// APIVersionConfig creates the middlewares.APIVersionConfig of the versions of the API. The paths without a version
+// are routed to API_DEFAULT_VERSION until the RFC3339 timestamp in API_UNVERSIONED_SUNSET
+func (container *Container) APIVersionConfig() (config middlewares.APIVersionConfig) {
+	container.logger.Debug(fmt.Sprintf("creating %T", config))
+
+	config = middlewares.APIVersionConfig{
+		Versions: responses.APIVersions(),
+		Default:  os.Getenv("API_DEFAULT_VERSION"),
+	}
+
+	if value := os.Getenv("API_UNVERSIONED_SUNSET"); value != "" {
+		sunset, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			container.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot parse API_UNVERSIONED_SUNSET [%s], the Sunset header is not sent", value)))
+		}
+		config.Sunset = sunset
+	}
+
+	return config
+}
+
+// RateLimiter creates a new instance of services.RateLimiter
+func (container *Container) RateLimiter() (limiter *services.RateLimiter) {
+	if container.rateLimiter != nil {
+		return container.rateLimiter
+	}
+
+	container.logger.Debug(fmt.Sprintf("creating %T", limiter))
+	container.rateLimiter = services.NewRateLimiter()
+	return container.rateLimiter
+}
+
+// RateLimitConfig creates the middlewares.RateLimitConfig from the RATE_LIMIT_* environment variables in the <limit>/<window>
+// format e.g. 60/1m, a rate limit which is empty or invalid is not enforced
+func (container *Container) RateLimitConfig() (config middlewares.RateLimitConfig) {
+	container.logger.Debug(fmt.Sprintf("creating %T", config))
+
+	return middlewares.RateLimitConfig{
+		Global: container.rateLimit("RATE_LIMIT_GLOBAL"),
+		APIKey: container.rateLimit("RATE_LIMIT_API_KEY"),
+		Classes: map[string]services.RateLimit{
+			middlewares.RateLimitClassSend:  container.rateLimit("RATE_LIMIT_SEND"),
+			middlewares.RateLimitClassRead:  container.rateLimit("RATE_LIMIT_READ"),
+			middlewares.RateLimitClassWrite: container.rateLimit("RATE_LIMIT_WRITE"),
+		},
+		SendRoutes: []string{"/messages/send", "/messages/bulk-send", "/bulk-messages"},
+	}
+}
+
+func (container *Container) rateLimit(name string) services.RateLimit {
+	limit, err := services.ParseRateLimit(os.Getenv(name))
+	if err != nil {
+		container.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot parse [%s], the rate limit is not enforced", name)))
+	}
+	return limit
+}
+
+// TimeoutConfig creates the middlewares.TimeoutConfig from the APP_TIMEOUT_READ, APP_TIMEOUT_WRITE and APP_TIMEOUT_LONG
+// environment variables e.g. 10s. The long deadline is used by the bulk routes, the imports, the events which are
+// pushed by the queue and the handshake of the message streams and the websockets.
+func (container *Container) TimeoutConfig() (config middlewares.TimeoutConfig) {
+	container.logger.Debug(fmt.Sprintf("creating %T", config))
+
+	long := container.duration("APP_TIMEOUT_LONG", 2*time.Minute)
+	return middlewares.TimeoutConfig{
+		Read:  container.duration("APP_TIMEOUT_READ", 10*time.Second),
+		Write: container.duration("APP_TIMEOUT_WRITE", 30*time.Second),
+		Routes: map[string]time.Duration{
+			"/messages/bulk-send":     long,
+			"/bulk-messages":          long,
+			"/events":                 long,
+			"/events/receive":         long,
+			"/messages/stream":        long,
+			"/websocket":              long,
+			"/messages/import/twilio": long,
+		},
+	}
+}
+
+// StreamedRoutes are the paths without the version whose handlers read the body of the request as a stream, their
+// bodies are not limited by middlewares.BodyLimit
+func (container *Container) StreamedRoutes() []string {
+	return []string{"/messages/import/twilio"}
+}
+
+// duration parses the duration in an environment variable e.g. 10s, the fallback is used when it is empty or invalid
+func (container *Container) duration(name string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
 }
 
 // Logger creates a new instance of telemetry.Logger
@@ -257,7 +444,7 @@ func (container *Container) DB() (db *gorm.DB) {
 		config.Logger = container.GormLogger()
 	}
 
-	db, err := gorm.Open(postgres.Open(os.Getenv("DATABASE_URL")), config)
+	db, err := gorm.Open(container.DatabaseDialector(), config)
 	if err != nil {
 		container.logger.Fatal(err)
 	}
@@ -268,44 +455,65 @@ func (container *Container) DB() (db *gorm.DB) {
 	}
 
 	container.logger.Debug(fmt.Sprintf("Running migrations for %T", db))
-
-	if err = db.AutoMigrate(&entities.Message{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.Message{})))
+	runner, err := migrations.NewRunner(container.Logger(), db)
+	if err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, "cannot create the migration runner"))
 	}
 
-	if err = db.AutoMigrate(&entities.MessageThread{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.MessageThread{})))
+	if err = runner.Run(context.Background()); err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot run migrations for %T", db)))
 	}
 
-	if err = db.AutoMigrate(&entities.User{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.User{})))
-	}
+	return container.db
+}
 
-	if err = db.AutoMigrate(&entities.Phone{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.Phone{})))
+// ReplicaDB creates an instance of gorm.DB for the read replica in DATABASE_REPLICA_URL, it returns nil when there is
+// no replica and all the queries are sent to DB
+func (container *Container) ReplicaDB() (db *gorm.DB) {
+	if container.replicaDB != nil || os.Getenv("DATABASE_REPLICA_URL") == "" {
+		return container.replicaDB
 	}
 
-	if err = db.AutoMigrate(&entities.PhoneNotification{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.PhoneNotification{})))
-	}
+	container.logger.Debug(fmt.Sprintf("creating replica %T", db))
 
-	if err = db.AutoMigrate(&entities.BillingUsage{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.BillingUsage{})))
+	config := &gorm.Config{TranslateError: true}
+	if isLocal() {
+		config.Logger = container.GormLogger()
 	}
 
-	if err = db.AutoMigrate(&entities.Webhook{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.Webhook{})))
+	db, err := gorm.Open(container.databaseDialector(os.Getenv("DATABASE_REPLICA_URL")), config)
+	if err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, "cannot open the replica database"))
 	}
 
-	if err = db.AutoMigrate(&entities.Discord{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.Discord{})))
+	if err = db.Use(tracing.NewPlugin()); err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, "cannot use GORM tracing plugin"))
 	}
 
-	if err = db.AutoMigrate(&entities.Integration3CX{}); err != nil {
-		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &entities.Integration3CX{})))
+	container.replicaDB = db
+	return container.replicaDB
+}
+
+// DatabaseDialector opens the database in DATABASE_URL with the driver in DATABASE_DRIVER, postgres is used by default.
+// Set DATABASE_DRIVER=sqlite and DATABASE_URL to the path of a file to self-host the API without postgres,
+// or DATABASE_DRIVER=mysql and DATABASE_URL to the DSN of a MySQL or MariaDB database.
+func (container *Container) DatabaseDialector() gorm.Dialector {
+	return container.databaseDialector(os.Getenv("DATABASE_URL"))
+}
+
+func (container *Container) databaseDialector(dsn string) gorm.Dialector {
+	switch driver := os.Getenv("DATABASE_DRIVER"); driver {
+	case "", "postgres":
+		return postgres.Open(dsn)
+	case "sqlite":
+		container.logger.Info(fmt.Sprintf("using the SQLite database [%s]", dsn))
+		return repositories.NewSqliteDialector(dsn)
+	case "mysql":
+		return repositories.NewMysqlDialector(dsn)
+	default:
+		container.logger.Fatal(stacktrace.NewError(fmt.Sprintf("DATABASE_DRIVER [%s] is not supported, use [postgres], [mysql] or [sqlite]", driver)))
+		return nil
 	}
-
-	return container.db
 }
 
 // FirebaseApp creates a new instance of firebase.App
@@ -363,6 +571,12 @@ func (container *Container) FirebaseAuthClient() (client *auth.Client) {
 	return authClient
 }
 
+// TokenVerifier creates the middlewares.TokenVerifier used to verify bearer ID tokens
+func (container *Container) TokenVerifier() middlewares.TokenVerifier {
+	container.logger.Debug("creating firebase middlewares.TokenVerifier")
+	return middlewares.NewFirebaseTokenVerifier(container.FirebaseAuthClient())
+}
+
 // CloudTasksClient creates a new instance of cloudtasks.Client
 func (container *Container) CloudTasksClient() (client *cloudtasks.Client) {
 	container.logger.Debug(fmt.Sprintf("creating %T", client))
@@ -440,12 +654,61 @@ func (container *Container) FirebaseCredentials() []byte {
 // Tracer creates a new instance of telemetry.Tracer
 func (container *Container) Tracer() (t telemetry.Tracer) {
 	container.logger.Debug("creating telemetry.Tracer")
-	return telemetry.NewOtelLogger(
+	return telemetry.NewOtelTracer(
 		container.projectID,
 		container.Logger(),
+		container.ErrorReporter(),
 	)
 }
 
+// InitializeLogRedaction redacts message content and phone numbers in logs unless LOG_MESSAGE_CONTENT is true
+func (container *Container) InitializeLogRedaction() {
+	logContent, _ := strconv.ParseBool(os.Getenv("LOG_MESSAGE_CONTENT"))
+	if logContent {
+		container.logger.Warn(stacktrace.NewError("LOG_MESSAGE_CONTENT is enabled, message content and phone numbers are logged verbatim"))
+	}
+	telemetry.SetRedaction(!logContent)
+}
+
+// ErrorReporter creates a new telemetry.ErrorReporter which is selected with the ERROR_REPORTER env variable
+func (container *Container) ErrorReporter() (reporter telemetry.ErrorReporter) {
+	if container.errorReporter != nil {
+		return container.errorReporter
+	}
+
+	container.logger.Debug("creating telemetry.ErrorReporter")
+
+	switch os.Getenv("ERROR_REPORTER") {
+	case telemetry.ErrorReporterSentry:
+		sentry, err := telemetry.NewSentryErrorReporter(
+			container.Logger(),
+			&http.Client{Timeout: 5 * time.Second},
+			os.Getenv("SENTRY_DSN"),
+			container.version,
+		)
+		if err != nil {
+			container.logger.Fatal(stacktrace.Propagate(err, "cannot create the sentry error reporter"))
+		}
+		container.errorReporter = sentry
+	case telemetry.ErrorReporterGCP:
+		credentials, err := google.CredentialsFromJSON(context.Background(), container.FirebaseCredentials(), "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			container.logger.Fatal(stacktrace.Propagate(err, "cannot parse the credentials of the GCP error reporter"))
+		}
+		container.errorReporter = telemetry.NewGCPErrorReporter(
+			container.Logger(),
+			&http.Client{Timeout: 5 * time.Second, Transport: &oauth2.Transport{Source: credentials.TokenSource}},
+			credentials.ProjectID,
+			container.projectID,
+			container.version,
+		)
+	default:
+		container.errorReporter = telemetry.NewNoopErrorReporter()
+	}
+
+	return container.errorReporter
+}
+
 // MessageHandlerValidator creates a new instance of validators.MessageHandlerValidator
 func (container *Container) MessageHandlerValidator() (validator *validators.MessageHandlerValidator) {
 	container.logger.Debug(fmt.Sprintf("creating %T", validator))
@@ -456,6 +719,16 @@ func (container *Container) MessageHandlerValidator() (validator *validators.Mes
 	)
 }
 
+// TwilioHandlerValidator creates a new instance of validators.TwilioHandlerValidator
+func (container *Container) TwilioHandlerValidator() (validator *validators.TwilioHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewTwilioHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageHandlerValidator(),
+	)
+}
+
 // BulkMessageHandlerValidator creates a new instance of validators.BulkMessageHandlerValidator
 func (container *Container) BulkMessageHandlerValidator() (validator *validators.BulkMessageHandlerValidator) {
 	container.logger.Debug(fmt.Sprintf("creating %T", validator))
@@ -474,6 +747,7 @@ func (container *Container) HeartbeatHandler() (h *handlers.HeartbeatHandler) {
 		container.Logger(),
 		container.Tracer(),
 		container.HeartbeatHandlerValidator(),
+		container.EventsQueueConfiguration(),
 		container.HeartbeatService(),
 	)
 }
@@ -500,6 +774,28 @@ func (container *Container) WebhookHandler() (h *handlers.WebhookHandler) {
 	)
 }
 
+// APIKeyHandler creates a new instance of handlers.APIKeyHandler
+func (container *Container) APIKeyHandler() (h *handlers.APIKeyHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", h))
+	return handlers.NewAPIKeyHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.APIKeyService(),
+		container.APIKeyHandlerValidator(),
+	)
+}
+
+// TeamHandler creates a new instance of handlers.TeamHandler
+func (container *Container) TeamHandler() (h *handlers.TeamHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", h))
+	return handlers.NewTeamHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.TeamService(),
+		container.TeamHandlerValidator(),
+	)
+}
+
 // HeartbeatHandlerValidator creates a new instance of validators.HeartbeatHandlerValidator
 func (container *Container) HeartbeatHandlerValidator() (validator *validators.HeartbeatHandlerValidator) {
 	container.logger.Debug(fmt.Sprintf("creating %T", validator))
@@ -528,6 +824,72 @@ func (container *Container) DiscordHandlerValidator() (validator *validators.Dis
 	)
 }
 
+// SlackHandlerValidator creates a new instance of validators.SlackHandlerValidator
+func (container *Container) SlackHandlerValidator() (validator *validators.SlackHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewSlackHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+		container.PhoneService(),
+	)
+}
+
+// TelegramHandlerValidator creates a new instance of validators.TelegramHandlerValidator
+func (container *Container) TelegramHandlerValidator() (validator *validators.TelegramHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewTelegramHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+		container.TelegramClient(),
+		container.PhoneService(),
+	)
+}
+
+// FallbackHandlerValidator creates a new instance of validators.FallbackHandlerValidator
+func (container *Container) FallbackHandlerValidator() (validator *validators.FallbackHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewFallbackHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// NotificationPreferenceHandlerValidator creates a new instance of validators.NotificationPreferenceHandlerValidator
+func (container *Container) NotificationPreferenceHandlerValidator() (validator *validators.NotificationPreferenceHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewNotificationPreferenceHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// SummaryReportHandlerValidator creates a new instance of validators.SummaryReportHandlerValidator
+func (container *Container) SummaryReportHandlerValidator() (validator *validators.SummaryReportHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewSummaryReportHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// ContactGroupHandlerValidator creates a new instance of validators.ContactGroupHandlerValidator
+func (container *Container) ContactGroupHandlerValidator() (validator *validators.ContactGroupHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewContactGroupHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// ContactHandlerValidator creates a new instance of validators.ContactHandlerValidator
+func (container *Container) ContactHandlerValidator() (validator *validators.ContactHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewContactHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
 // WebhookHandlerValidator creates a new instance of validators.WebhookHandlerValidator
 func (container *Container) WebhookHandlerValidator() (validator *validators.WebhookHandlerValidator) {
 	container.logger.Debug(fmt.Sprintf("creating %T", validator))
@@ -538,6 +900,24 @@ func (container *Container) WebhookHandlerValidator() (validator *validators.Web
 	)
 }
 
+// APIKeyHandlerValidator creates a new instance of validators.APIKeyHandlerValidator
+func (container *Container) APIKeyHandlerValidator() (validator *validators.APIKeyHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewAPIKeyHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// TeamHandlerValidator creates a new instance of validators.TeamHandlerValidator
+func (container *Container) TeamHandlerValidator() (validator *validators.TeamHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewTeamHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
 // MessageThreadHandler creates a new instance of handlers.MessageThreadHandler
 func (container *Container) MessageThreadHandler() (h *handlers.MessageThreadHandler) {
 	container.logger.Debug(fmt.Sprintf("creating %T", h))
@@ -546,6 +926,8 @@ func (container *Container) MessageThreadHandler() (h *handlers.MessageThreadHan
 		container.Tracer(),
 		container.MessageThreadHandlerValidator(),
 		container.MessageThreadService(),
+		container.HeartbeatService(),
+		container.ContactService(),
 	)
 }
 
@@ -589,12 +971,45 @@ func (container *Container) EventDispatcher() (dispatcher *services.EventDispatc
 		container.Float64Histogram("event.publisher.duration", "ms", "measures the duration of processing CloudEvents"),
 		container.EventsQueue(),
 		container.EventsQueueConfiguration(),
+		container.EventRepository(),
 	)
 
 	container.eventDispatcher = dispatcher
 	return dispatcher
 }
 
+// EventBroker creates a cached instance of services.EventBroker
+func (container *Container) EventBroker() (broker *services.EventBroker) {
+	if container.eventBroker != nil {
+		return container.eventBroker
+	}
+
+	container.logger.Debug(fmt.Sprintf("creating %T", broker))
+	container.eventBroker = services.NewEventBroker(
+		container.Logger(),
+		container.Tracer(),
+		container.EventRepository(),
+		64,
+	)
+	return container.eventBroker
+}
+
+// WebsocketHub creates a cached instance of services.WebsocketHub
+func (container *Container) WebsocketHub() (hub *services.WebsocketHub) {
+	if container.websocketHub != nil {
+		return container.websocketHub
+	}
+
+	container.logger.Debug(fmt.Sprintf("creating %T", hub))
+	container.websocketHub = services.NewWebsocketHub(
+		container.Logger(),
+		container.Tracer(),
+		10,
+		64,
+	)
+	return container.websocketHub
+}
+
 // Float64Histogram creates a new instance of metric.Float64Histogram
 func (container *Container) Float64Histogram(name, unit, description string) otelMetric.Float64Histogram {
 	container.logger.Debug("creating GORM repositories.MessageRepository")
@@ -612,13 +1027,56 @@ func (container *Container) Float64Histogram(name, unit, description string) ote
 // MessageRepository creates a new instance of repositories.MessageRepository
 func (container *Container) MessageRepository() (repository repositories.MessageRepository) {
 	container.logger.Debug("creating GORM repositories.MessageRepository")
-	return repositories.NewGormMessageRepository(
+	return repositories.NewInstrumentedMessageRepository(
 		container.Logger(),
-		container.Tracer(),
-		container.DB(),
+		repositories.NewRetryMessageRepository(
+			container.Logger(),
+			repositories.NewGormMessageRepository(
+				container.Logger(),
+				container.Tracer(),
+				container.DB(),
+				container.ReplicaDB(),
+			),
+			container.DatabaseRetryAttempts(),
+		),
+		container.RepositoryMetricsSink(),
+		container.DatabaseSlowQueryThreshold(),
+	)
+}
+
+// RepositoryMetricsSink creates the repositories.MetricsSink of the repository call durations, it returns nil when
+// DATABASE_METRICS_DISABLED is true
+func (container *Container) RepositoryMetricsSink() (sink repositories.MetricsSink) {
+	if disabled, _ := strconv.ParseBool(os.Getenv("DATABASE_METRICS_DISABLED")); disabled {
+		return nil
+	}
+
+	container.logger.Debug(fmt.Sprintf("creating %T", sink))
+	return repositories.NewOtelMetricsSink(
+		container.Float64Histogram("repository.duration", "ms", "measures the duration of repository calls"),
 	)
 }
 
+// DatabaseSlowQueryThreshold is the duration in DATABASE_SLOW_QUERY_THRESHOLD after which a repository call is logged, slow calls are not logged when it is not set
+func (container *Container) DatabaseSlowQueryThreshold() time.Duration {
+	threshold, err := time.ParseDuration(os.Getenv("DATABASE_SLOW_QUERY_THRESHOLD"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse DATABASE_SLOW_QUERY_THRESHOLD [%s] slow repository calls will not be logged", os.Getenv("DATABASE_SLOW_QUERY_THRESHOLD")))
+		return 0
+	}
+	return threshold
+}
+
+// DatabaseRetryAttempts is the maximum number of attempts of a repository operation which fails with a transient database error
+func (container *Container) DatabaseRetryAttempts() int {
+	attempts, err := strconv.Atoi(os.Getenv("DATABASE_RETRY_ATTEMPTS"))
+	if err != nil || attempts < 1 {
+		container.logger.Debug(fmt.Sprintf("cannot parse DATABASE_RETRY_ATTEMPTS [%s] using [%d] attempts", os.Getenv("DATABASE_RETRY_ATTEMPTS"), 3))
+		return 3
+	}
+	return attempts
+}
+
 // Integration3CXRepository creates a new instance of repositories.Integration3CxRepository
 func (container *Container) Integration3CXRepository() (repository repositories.Integration3CxRepository) {
 	container.logger.Debug("creating GORM repositories.Integration3CxRepository")
@@ -629,6 +1087,16 @@ func (container *Container) Integration3CXRepository() (repository repositories.
 	)
 }
 
+// TwilioStatusCallbackRepository creates a new instance of repositories.TwilioStatusCallbackRepository
+func (container *Container) TwilioStatusCallbackRepository() (repository repositories.TwilioStatusCallbackRepository) {
+	container.logger.Debug("creating GORM repositories.TwilioStatusCallbackRepository")
+	return repositories.NewGormTwilioStatusCallbackRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
 // PhoneRepository creates a new instance of repositories.PhoneRepository
 func (container *Container) PhoneRepository() (repository repositories.PhoneRepository) {
 	container.logger.Debug("creating GORM repositories.PhoneRepository")
@@ -646,6 +1114,7 @@ func (container *Container) BillingUsageRepository() (repository repositories.Bi
 		container.Logger(),
 		container.Tracer(),
 		container.DB(),
+		container.ReplicaDB(),
 	)
 }
 
@@ -659,133 +1128,622 @@ func (container *Container) DiscordRepository() (repository repositories.Discord
 	)
 }
 
-// WebhookRepository creates a new instance of repositories.WebhookRepository
-func (container *Container) WebhookRepository() (repository repositories.WebhookRepository) {
-	container.logger.Debug("creating GORM repositories.WebhookRepository")
-	return repositories.NewGormWebhookRepository(
+// SlackRepository creates a new instance of repositories.SlackRepository
+func (container *Container) SlackRepository() (repository repositories.SlackRepository) {
+	container.logger.Debug("creating GORM repositories.SlackRepository")
+	return repositories.NewGormSlackRepository(
 		container.Logger(),
 		container.Tracer(),
 		container.DB(),
 	)
 }
 
-// PhoneNotificationRepository creates a new instance of repositories.PhoneNotificationRepository
-func (container *Container) PhoneNotificationRepository() (repository repositories.PhoneNotificationRepository) {
-	container.logger.Debug("creating GORM repositories.PhoneNotificationRepository")
-	return repositories.NewGormPhoneNotificationRepository(
+// SlackThreadRepository creates a new instance of repositories.SlackThreadRepository
+func (container *Container) SlackThreadRepository() (repository repositories.SlackThreadRepository) {
+	container.logger.Debug("creating GORM repositories.SlackThreadRepository")
+	return repositories.NewGormSlackThreadRepository(
 		container.Logger(),
 		container.Tracer(),
 		container.DB(),
 	)
 }
 
-// MessageThreadRepository creates a new instance of repositories.MessageThreadRepository
-func (container *Container) MessageThreadRepository() (repository repositories.MessageThreadRepository) {
-	container.logger.Debug("creating GORM repositories.MessageThreadRepository")
-	return repositories.NewGormMessageThreadRepository(
+// TelegramRepository creates a new instance of repositories.TelegramRepository
+func (container *Container) TelegramRepository() (repository repositories.TelegramRepository) {
+	container.logger.Debug("creating GORM repositories.TelegramRepository")
+	return repositories.NewGormTelegramRepository(
 		container.Logger(),
 		container.Tracer(),
 		container.DB(),
 	)
 }
 
-// EventRepository creates a new instance of repositories.EventRepository
-func (container *Container) EventRepository() (repository repositories.EventRepository) {
-	container.logger.Debug("creating GORM repositories.EventRepository")
-	return repositories.NewGormEventRepository(
+// TelegramMessageRepository creates a new instance of repositories.TelegramMessageRepository
+func (container *Container) TelegramMessageRepository() (repository repositories.TelegramMessageRepository) {
+	container.logger.Debug("creating GORM repositories.TelegramMessageRepository")
+	return repositories.NewGormTelegramMessageRepository(
 		container.Logger(),
 		container.Tracer(),
 		container.DB(),
 	)
 }
 
-// HeartbeatMonitorRepository creates a new instance of repositories.HeartbeatMonitorRepository
-func (container *Container) HeartbeatMonitorRepository() (repository repositories.HeartbeatMonitorRepository) {
-	container.logger.Debug("creating GORM repositories.HeartbeatMonitorRepository")
-	return repositories.NewGormHeartbeatMonitorRepository(
+// FallbackProviderRepository creates a new instance of repositories.FallbackProviderRepository
+func (container *Container) FallbackProviderRepository() (repository repositories.FallbackProviderRepository) {
+	container.logger.Debug("creating GORM repositories.FallbackProviderRepository")
+	return repositories.NewGormFallbackProviderRepository(
 		container.Logger(),
 		container.Tracer(),
-		container.DedicatedDB(),
+		container.DB(),
 	)
 }
 
-// EventListenerLogRepository creates a new instance of repositories.EventListenerLogRepository
-func (container *Container) EventListenerLogRepository() (repository repositories.EventListenerLogRepository) {
-	container.logger.Debug("creating GORM repositories.EventListenerLogRepository")
-	return repositories.NewGormEventListenerLogRepository(
+// NotificationPreferenceRepository creates a new instance of repositories.NotificationPreferenceRepository
+func (container *Container) NotificationPreferenceRepository() (repository repositories.NotificationPreferenceRepository) {
+	container.logger.Debug("creating GORM repositories.NotificationPreferenceRepository")
+	return repositories.NewGormNotificationPreferenceRepository(
 		container.Logger(),
 		container.Tracer(),
 		container.DB(),
 	)
 }
 
-// HeartbeatService creates a new instance of services.HeartbeatService
-func (container *Container) HeartbeatService() (service *services.HeartbeatService) {
-	container.logger.Debug(fmt.Sprintf("creating %T", service))
-	return services.NewHeartbeatService(
+// SummaryReportRepository creates a new instance of repositories.SummaryReportRepository
+func (container *Container) SummaryReportRepository() (repository repositories.SummaryReportRepository) {
+	container.logger.Debug("creating GORM repositories.SummaryReportRepository")
+	return repositories.NewGormSummaryReportRepository(
 		container.Logger(),
 		container.Tracer(),
-		container.HeartbeatRepository(),
-		container.HeartbeatMonitorRepository(),
-		container.EventDispatcher(),
+		container.DB(),
 	)
 }
 
-// BillingService creates a new instance of services.BillingService
-func (container *Container) BillingService() (service *services.BillingService) {
-	container.logger.Debug(fmt.Sprintf("creating %T", service))
-	return services.NewBillingService(
+// ContactGroupRepository creates a new instance of repositories.ContactGroupRepository
+func (container *Container) ContactGroupRepository() (repository repositories.ContactGroupRepository) {
+	container.logger.Debug("creating GORM repositories.ContactGroupRepository")
+	return repositories.NewGormContactGroupRepository(
 		container.Logger(),
 		container.Tracer(),
-		container.InMemoryCache(),
-		container.Mailer(),
-		container.UserEmailFactory(),
-		container.BillingUsageRepository(),
-		container.UserRepository(),
+		container.DB(),
 	)
 }
 
-// DiscordService creates a new instance of services.DiscordService
-func (container *Container) DiscordService() (service *services.DiscordService) {
-	container.logger.Debug(fmt.Sprintf("creating %T", service))
-	return services.NewDiscordService(
+// NumberLookupRepository creates a new instance of repositories.NumberLookupRepository
+func (container *Container) NumberLookupRepository() (repository repositories.NumberLookupRepository) {
+	container.logger.Debug("creating GORM repositories.NumberLookupRepository")
+	return repositories.NewGormNumberLookupRepository(
 		container.Logger(),
 		container.Tracer(),
-		container.DiscordClient(),
-		container.DiscordRepository(),
-		container.EventDispatcher(),
+		container.DB(),
 	)
 }
 
-// WebhookService creates a new instance of services.WebhookService
-func (container *Container) WebhookService() (service *services.WebhookService) {
-	container.logger.Debug(fmt.Sprintf("creating %T", service))
-	return services.NewWebhookService(
+// RoutingRuleRepository creates a new instance of repositories.RoutingRuleRepository
+func (container *Container) RoutingRuleRepository() (repository repositories.RoutingRuleRepository) {
+	container.logger.Debug("creating GORM repositories.RoutingRuleRepository")
+	return repositories.NewGormRoutingRuleRepository(
 		container.Logger(),
 		container.Tracer(),
-		container.HTTPClient("webhook"),
-		container.WebhookRepository(),
-		container.EventDispatcher(),
+		container.DB(),
 	)
 }
 
-// Integration3CXService creates a new instance of services.Integration3CXService
-func (container *Container) Integration3CXService() (service *services.Integration3CXService) {
-	container.logger.Debug(fmt.Sprintf("creating %T", service))
-	return services.NewIntegration3CXService(
+// MessageRateRepository creates a new instance of repositories.MessageRateRepository
+func (container *Container) MessageRateRepository() (repository repositories.MessageRateRepository) {
+	container.logger.Debug("creating GORM repositories.MessageRateRepository")
+	return repositories.NewGormMessageRateRepository(
 		container.Logger(),
 		container.Tracer(),
-		container.HTTPClient("integration_3cx"),
-		container.Integration3CXRepository(),
+		container.DB(),
 	)
 }
 
-// HTTPClient creates a new http.Client
-func (container *Container) HTTPClient(name string) *http.Client {
-	container.logger.Debug(fmt.Sprintf("creating %s %T", name, http.DefaultClient))
-	return &http.Client{
-		Timeout:   60 * time.Second,
-		Transport: container.HTTPRoundTripper(name),
+// PhoneDailySendUsageRepository creates a new instance of repositories.PhoneDailySendUsageRepository
+func (container *Container) PhoneDailySendUsageRepository() (repository repositories.PhoneDailySendUsageRepository) {
+	container.logger.Debug("creating GORM repositories.PhoneDailySendUsageRepository")
+	return repositories.NewGormPhoneDailySendUsageRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// MessageStatisticsRepository creates a new instance of repositories.MessageStatisticsRepository
+func (container *Container) MessageStatisticsRepository() (repository repositories.MessageStatisticsRepository) {
+	container.logger.Debug("creating GORM repositories.MessageStatisticsRepository")
+	return repositories.NewGormMessageStatisticsRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// ShortLinkRepository creates a new instance of repositories.ShortLinkRepository
+func (container *Container) ShortLinkRepository() (repository repositories.ShortLinkRepository) {
+	container.logger.Debug("creating GORM repositories.ShortLinkRepository")
+	return repositories.NewGormShortLinkRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// MediaRepository creates a new instance of repositories.MediaRepository
+func (container *Container) MediaRepository() (repository repositories.MediaRepository) {
+	container.logger.Debug("creating GORM repositories.MediaRepository")
+	return repositories.NewGormMediaRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// ContentExpiryRuleRepository creates a new instance of repositories.ContentExpiryRuleRepository
+func (container *Container) ContentExpiryRuleRepository() (repository repositories.ContentExpiryRuleRepository) {
+	container.logger.Debug("creating GORM repositories.ContentExpiryRuleRepository")
+	return repositories.NewGormContentExpiryRuleRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// AccountExportRepository creates a new instance of repositories.AccountExportRepository
+func (container *Container) AccountExportRepository() (repository repositories.AccountExportRepository) {
+	container.logger.Debug("creating GORM repositories.AccountExportRepository")
+	return repositories.NewGormAccountExportRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// ContactRepository creates a new instance of repositories.ContactRepository
+func (container *Container) ContactRepository() (repository repositories.ContactRepository) {
+	container.logger.Debug("creating GORM repositories.ContactRepository")
+	return repositories.NewGormContactRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// NotificationDigestRepository creates a new instance of repositories.NotificationDigestRepository
+func (container *Container) NotificationDigestRepository() (repository repositories.NotificationDigestRepository) {
+	container.logger.Debug("creating GORM repositories.NotificationDigestRepository")
+	return repositories.NewGormNotificationDigestRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// APIKeyRepository creates a new instance of repositories.APIKeyRepository
+func (container *Container) APIKeyRepository() (repository repositories.APIKeyRepository) {
+	container.logger.Debug("creating GORM repositories.APIKeyRepository")
+	return repositories.NewGormAPIKeyRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.RistrettoCache(),
+		container.DB(),
+	)
+}
+
+// TeamRepository creates a new instance of repositories.TeamRepository
+func (container *Container) TeamRepository() (repository repositories.TeamRepository) {
+	container.logger.Debug("creating GORM repositories.TeamRepository")
+	return repositories.NewGormTeamRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.RistrettoCache(),
+		container.DB(),
+	)
+}
+
+// DailyMessageUsageRepository creates a new instance of repositories.DailyMessageUsageRepository
+func (container *Container) DailyMessageUsageRepository() (repository repositories.DailyMessageUsageRepository) {
+	container.logger.Debug("creating GORM repositories.DailyMessageUsageRepository")
+	return repositories.NewGormDailyMessageUsageRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// WebhookRepository creates a new instance of repositories.WebhookRepository
+func (container *Container) WebhookRepository() (repository repositories.WebhookRepository) {
+	container.logger.Debug("creating GORM repositories.WebhookRepository")
+	return repositories.NewGormWebhookRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// PhoneNotificationRepository creates a new instance of repositories.PhoneNotificationRepository
+func (container *Container) PhoneNotificationRepository() (repository repositories.PhoneNotificationRepository) {
+	container.logger.Debug("creating GORM repositories.PhoneNotificationRepository")
+	return repositories.NewGormPhoneNotificationRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// MessageThreadRepository creates a new instance of repositories.MessageThreadRepository
+func (container *Container) MessageThreadRepository() (repository repositories.MessageThreadRepository) {
+	container.logger.Debug("creating GORM repositories.MessageThreadRepository")
+	return repositories.NewGormMessageThreadRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+		container.ReplicaDB(),
+	)
+}
+
+// EventRepository creates a new instance of repositories.EventRepository
+func (container *Container) EventRepository() (repository repositories.EventRepository) {
+	container.logger.Debug("creating GORM repositories.EventRepository")
+	return repositories.NewGormEventRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
+	)
+}
+
+// HeartbeatMonitorRepository creates a new instance of repositories.HeartbeatMonitorRepository
+func (container *Container) HeartbeatMonitorRepository() (repository repositories.HeartbeatMonitorRepository) {
+	container.logger.Debug("creating GORM repositories.HeartbeatMonitorRepository")
+	return repositories.NewGormHeartbeatMonitorRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DedicatedDB(),
+	)
+}
+
+// Transactor creates a new instance of repositories.Transactor
+func (container *Container) Transactor() (transactor repositories.Transactor) {
+	container.logger.Debug("creating GORM repositories.Transactor")
+	return repositories.NewGormTransactor(container.DB())
+}
+
+// EventListenerLogRepository creates a new instance of repositories.EventListenerLogRepository
+func (container *Container) EventListenerLogRepository() (repository repositories.EventListenerLogRepository) {
+	container.logger.Debug("creating GORM repositories.EventListenerLogRepository")
+	return repositories.NewInstrumentedEventListenerLogRepository(
+		container.Logger(),
+		repositories.NewRetryEventListenerLogRepository(
+			container.Logger(),
+			repositories.NewGormEventListenerLogRepository(
+				container.Logger(),
+				container.Tracer(),
+				container.DB(),
+			),
+			container.DatabaseRetryAttempts(),
+		),
+		container.RepositoryMetricsSink(),
+		container.DatabaseSlowQueryThreshold(),
+	)
+}
+
+// HeartbeatService creates a new instance of services.HeartbeatService
+func (container *Container) HeartbeatService() (service *services.HeartbeatService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewHeartbeatService(
+		container.Logger(),
+		container.Tracer(),
+		container.HeartbeatRepository(),
+		container.HeartbeatMonitorRepository(),
+		container.EventDispatcher(),
+		container.PhoneService(),
+		container.HeartbeatDeadThreshold(),
+		container.HeartbeatAlertCooldown(),
+		container.HeartbeatRetention(),
+	)
+}
+
+// HeartbeatRetention is the duration for which heartbeats are stored before they are pruned
+func (container *Container) HeartbeatRetention() time.Duration {
+	retention, err := time.ParseDuration(os.Getenv("HEARTBEAT_RETENTION"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse HEARTBEAT_RETENTION [%s] heartbeats will be kept forever", os.Getenv("HEARTBEAT_RETENTION")))
+		return 0
+	}
+	return retention
+}
+
+// HeartbeatDeadThreshold is the duration after the last heartbeat when a phone is considered offline
+func (container *Container) HeartbeatDeadThreshold() time.Duration {
+	threshold, err := time.ParseDuration(os.Getenv("HEARTBEAT_DEAD_THRESHOLD"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse HEARTBEAT_DEAD_THRESHOLD [%s] using default threshold", os.Getenv("HEARTBEAT_DEAD_THRESHOLD")))
+		return 0
+	}
+	return threshold
+}
+
+// HeartbeatAlertCooldown is the default minimum duration between 2 offline alerts of a phone
+func (container *Container) HeartbeatAlertCooldown() time.Duration {
+	cooldown, err := time.ParseDuration(os.Getenv("HEARTBEAT_ALERT_COOLDOWN"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse HEARTBEAT_ALERT_COOLDOWN [%s] using default cooldown", os.Getenv("HEARTBEAT_ALERT_COOLDOWN")))
+		return 0
+	}
+	return cooldown
+}
+
+// BillingService creates a new instance of services.BillingService
+func (container *Container) BillingService() (service *services.BillingService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewBillingService(
+		container.Logger(),
+		container.Tracer(),
+		container.InMemoryCache(),
+		container.Mailer(),
+		container.UserEmailFactory(),
+		container.BillingUsageRepository(),
+		container.UserRepository(),
+	)
+}
+
+// DiscordService creates a new instance of services.DiscordService
+func (container *Container) DiscordService() (service *services.DiscordService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewDiscordService(
+		container.Logger(),
+		container.Tracer(),
+		container.DiscordClient(),
+		container.DiscordRepository(),
+		container.EventDispatcher(),
+	)
+}
+
+// SlackService creates a new instance of services.SlackService
+func (container *Container) SlackService() (service *services.SlackService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewSlackService(
+		container.Logger(),
+		container.Tracer(),
+		container.SlackClient(),
+		container.RateLimiter(),
+		container.SlackRepository(),
+		container.SlackThreadRepository(),
+	)
+}
+
+// TelegramService creates a new instance of services.TelegramService
+func (container *Container) TelegramService() (service *services.TelegramService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewTelegramService(
+		container.Logger(),
+		container.Tracer(),
+		container.TelegramClient(),
+		os.Getenv("TELEGRAM_WEBHOOK_URL"),
+		container.TelegramRepository(),
+		container.TelegramMessageRepository(),
+	)
+}
+
+// ContactGroupService creates a new instance of services.ContactGroupService
+func (container *Container) ContactGroupService() (service *services.ContactGroupService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewContactGroupService(
+		container.Logger(),
+		container.Tracer(),
+		container.ContactGroupRepository(),
+		container.MessageService(),
+	)
+}
+
+// NumberLookupService creates a new instance of services.NumberLookupService
+func (container *Container) NumberLookupService() (service *services.NumberLookupService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewNumberLookupService(
+		container.Logger(),
+		container.Tracer(),
+		container.NumberLookupRepository(),
+		container.NumberLookup(),
+		container.duration("NUMBER_LOOKUP_TTL", 30*24*time.Hour),
+	)
+}
+
+// NumberLookup creates the services.NumberLookup of the HTTP provider in NUMBER_LOOKUP_URL, the numbering plan of the
+// country of a number is used when it is empty
+func (container *Container) NumberLookup() (lookup services.NumberLookup) {
+	container.logger.Debug(fmt.Sprintf("creating %T", lookup))
+	if os.Getenv("NUMBER_LOOKUP_URL") == "" {
+		return services.NewOfflineNumberLookup()
+	}
+	return services.NewHTTPNumberLookup(container.HTTPClient("number_lookup"), os.Getenv("NUMBER_LOOKUP_URL"), os.Getenv("NUMBER_LOOKUP_API_KEY"))
+}
+
+// RoutingRuleService creates a new instance of services.RoutingRuleService
+func (container *Container) RoutingRuleService() (service *services.RoutingRuleService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewRoutingRuleService(
+		container.Logger(),
+		container.Tracer(),
+		container.RoutingRuleRepository(),
+		container.PhoneService(),
+	)
+}
+
+// MessageRateService creates a new instance of services.MessageRateService
+func (container *Container) MessageRateService() (service *services.MessageRateService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewMessageRateService(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageRateRepository(),
+	)
+}
+
+// MessageStatisticsService creates a new instance of services.MessageStatisticsService
+func (container *Container) MessageStatisticsService() (service *services.MessageStatisticsService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewMessageStatisticsService(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageStatisticsRepository(),
+	)
+}
+
+// ShortLinkService creates a new instance of services.ShortLinkService, the URLs are not shortened when SHORT_LINK_BASE_URL is empty
+func (container *Container) ShortLinkService() (service *services.ShortLinkService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewShortLinkService(
+		container.Logger(),
+		container.Tracer(),
+		container.ShortLinkRepository(),
+		os.Getenv("SHORT_LINK_BASE_URL"),
+	)
+}
+
+// MediaService creates a new instance of services.MediaService, media larger than MEDIA_MAX_SIZE bytes is rejected
+// and media which is not sent within MEDIA_TTL is deleted
+func (container *Container) MediaService() (service *services.MediaService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewMediaService(
+		container.Logger(),
+		container.Tracer(),
+		container.MediaRepository(),
+		container.MediaBlobStore(),
+		container.MediaMaxSize(),
+		container.duration("MEDIA_TTL", 24*time.Hour),
+	)
+}
+
+// ContentExpiryService creates a new instance of services.ContentExpiryService
+func (container *Container) ContentExpiryService() (service *services.ContentExpiryService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewContentExpiryService(
+		container.Logger(),
+		container.Tracer(),
+		container.ContentExpiryRuleRepository(),
+		container.MessageRepository(),
+		container.MessageThreadRepository(),
+		container.Transactor(),
+		container.PhoneService(),
+	)
+}
+
+// AccountExportService creates a new instance of services.AccountExportService
+func (container *Container) AccountExportService() (service *services.AccountExportService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewAccountExportService(
+		container.Logger(),
+		container.Tracer(),
+		container.AccountExportRepository(),
+		container.UserRepository(),
+	)
+}
+
+// TwilioImportService creates a new instance of services.TwilioImportService
+func (container *Container) TwilioImportService() (service *services.TwilioImportService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewTwilioImportService(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageRepository(),
+		container.MessageThreadService(),
+	)
+}
+
+// ContactService creates a new instance of services.ContactService
+func (container *Container) ContactService() (service *services.ContactService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewContactService(
+		container.Logger(),
+		container.Tracer(),
+		container.ContactRepository(),
+	)
+}
+
+// FallbackService creates a new instance of services.FallbackService
+func (container *Container) FallbackService() (service *services.FallbackService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewFallbackService(
+		container.Logger(),
+		container.Tracer(),
+		os.Getenv("FALLBACK_CALLBACK_URL"),
+		map[entities.FallbackProviderType]services.FallbackProvider{
+			entities.FallbackProviderTypeTwilio: services.NewTwilioFallbackProvider(container.HTTPClient("fallback_twilio"), "https://api.twilio.com"),
+			entities.FallbackProviderTypeHTTP:   services.NewHTTPFallbackProvider(container.HTTPClient("fallback_http")),
+		},
+		container.FallbackProviderRepository(),
+		container.MessageRepository(),
+		container.MessageService(),
+		container.HeartbeatService(),
+	)
+}
+
+// WebhookService creates a new instance of services.WebhookService
+func (container *Container) WebhookService() (service *services.WebhookService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewWebhookService(
+		container.Logger(),
+		container.Tracer(),
+		container.HTTPClient("webhook"),
+		container.WebhookRepository(),
+		container.EventDispatcher(),
+	)
+}
+
+// APIKeyService creates a services.APIKeyService which is shared so that the usage of an entities.APIKey is throttled across requests
+func (container *Container) APIKeyService() (service *services.APIKeyService) {
+	if container.apiKeyService != nil {
+		return container.apiKeyService
+	}
+
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	container.apiKeyService = services.NewAPIKeyService(
+		container.Logger(),
+		container.Tracer(),
+		container.APIKeyRepository(),
+	)
+	return container.apiKeyService
+}
+
+// TeamService creates a new instance of services.TeamService
+func (container *Container) TeamService() (service *services.TeamService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewTeamService(
+		container.Logger(),
+		container.Tracer(),
+		container.TeamRepository(),
+	)
+}
+
+// Integration3CXService creates a new instance of services.Integration3CXService
+func (container *Container) Integration3CXService() (service *services.Integration3CXService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewIntegration3CXService(
+		container.Logger(),
+		container.Tracer(),
+		container.HTTPClient("integration_3cx"),
+		container.Integration3CXRepository(),
+	)
+}
+
+// TwilioService creates a new instance of services.TwilioService
+func (container *Container) TwilioService() (service *services.TwilioService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewTwilioService(
+		container.Logger(),
+		container.Tracer(),
+		container.HTTPClient("twilio"),
+		container.MessageIDGenerator(),
+		container.MessageService(),
+		container.TwilioStatusCallbackRepository(),
+	)
+}
+
+// HTTPClient creates a new http.Client
+func (container *Container) HTTPClient(name string) *http.Client {
+	container.logger.Debug(fmt.Sprintf("creating %s %T", name, http.DefaultClient))
+	return &http.Client{
+		Timeout:   60 * time.Second,
+		Transport: container.HTTPRoundTripper(name),
 	}
 }
 
@@ -827,9 +1785,25 @@ func (container *Container) PhoneService() (service *services.PhoneService) {
 		container.Tracer(),
 		container.PhoneRepository(),
 		container.EventDispatcher(),
+		container.PhoneBatteryLowThreshold(),
+		services.PhoneClockConfig{
+			SkewThreshold:   container.duration("PHONE_CLOCK_SKEW_THRESHOLD", 2*time.Minute),
+			FutureTolerance: container.duration("PHONE_TIMESTAMP_FUTURE_TOLERANCE", 5*time.Minute),
+			PastTolerance:   container.duration("PHONE_TIMESTAMP_PAST_TOLERANCE", 30*24*time.Hour),
+		},
 	)
 }
 
+// PhoneBatteryLowThreshold is the battery percentage below which a phone is considered to have a low battery
+func (container *Container) PhoneBatteryLowThreshold() uint {
+	threshold, err := strconv.ParseUint(os.Getenv("PHONE_BATTERY_LOW_THRESHOLD"), 10, 32)
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse PHONE_BATTERY_LOW_THRESHOLD [%s] using default threshold", os.Getenv("PHONE_BATTERY_LOW_THRESHOLD")))
+		return 0
+	}
+	return uint(threshold)
+}
+
 // MarketingService creates a new instance of services.MarketingService
 func (container *Container) MarketingService() (service *services.MarketingService) {
 	container.logger.Debug(fmt.Sprintf("creating %T", service))
@@ -849,10 +1823,13 @@ func (container *Container) UserService() (service *services.UserService) {
 		container.Logger(),
 		container.Tracer(),
 		container.UserRepository(),
+		container.APIKeyRepository(),
 		container.Mailer(),
 		container.UserEmailFactory(),
 		container.MarketingService(),
 		container.LemonsqueezyClient(),
+		container.Cache(),
+		container.EventDispatcher(),
 	)
 }
 
@@ -909,22 +1886,197 @@ func (container *Container) EmailNotificationService() (service *services.EmailN
 	return services.NewEmailNotificationService(
 		container.Logger(),
 		container.Tracer(),
-		container.UserRepository(),
-		container.NotificationEmailFactory(),
-		container.Mailer(),
-		container.Cache(),
+		container.UserRepository(),
+		container.NotificationPreferenceRepository(),
+		container.NotificationDigestRepository(),
+		container.EventDispatcher(),
+		container.NotificationEmailFactory(),
+		container.Mailer(),
+		container.Cache(),
+	)
+}
+
+// SummaryReportService creates a new instance of services.SummaryReportService
+func (container *Container) SummaryReportService() (service *services.SummaryReportService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewSummaryReportService(
+		container.Logger(),
+		container.Tracer(),
+		container.SummaryReportRepository(),
+		container.MessageStatisticsRepository(),
+		container.UserRepository(),
+		container.NotificationEmailFactory(),
+		container.Mailer(),
+	)
+}
+
+// GRPCServer creates a new grpc.Server which serves the messages API with the same services as the HTTP API
+func (container *Container) GRPCServer() (server *grpc.Server) {
+	container.logger.Debug(fmt.Sprintf("creating %T", server))
+	return grpcapi.NewServer(container.GRPCAuthenticator(), container.GRPCMessageServer())
+}
+
+// GRPCAuthenticator creates a new instance of grpcapi.Authenticator
+func (container *Container) GRPCAuthenticator() (authenticator *grpcapi.Authenticator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", authenticator))
+	return grpcapi.NewAuthenticator(
+		container.Logger(),
+		container.Tracer(),
+		container.UserRepository(),
+		container.APIKeyRepository(),
+		container.APIKeyService(),
+	)
+}
+
+// GRPCMessageServer creates a new instance of grpcapi.MessageServer
+func (container *Container) GRPCMessageServer() (server *grpcapi.MessageServer) {
+	container.logger.Debug(fmt.Sprintf("creating %T", server))
+	return grpcapi.NewMessageServer(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageHandlerValidator(),
+		container.BillingService(),
+		container.MessageService(),
+	)
+}
+
+// ServeGRPC serves the GRPCServer on the address, it blocks until the server stops
+func (container *Container) ServeGRPC(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot listen for gRPC calls on [%s]", address))
+	}
+
+	container.logger.Info(fmt.Sprintf("serving gRPC calls on [%s]", address))
+	container.grpcServer = container.GRPCServer()
+	return container.grpcServer.Serve(listener)
+}
+
+// ShutdownTimeout is the grace period in APP_SHUTDOWN_TIMEOUT e.g. 10s which the API has to drain the requests and the
+// pending events after it receives SIGTERM
+func (container *Container) ShutdownTimeout() time.Duration {
+	return container.duration("APP_SHUTDOWN_TIMEOUT", 10*time.Second)
+}
+
+// Shutdown stops the API gracefully. The message streams and the websockets are closed so that the HTTP and gRPC
+// servers can wait for the requests in flight, then the pending events are published and the telemetry is flushed
+// before the database connections are closed. Every step runs even when a previous step cannot finish before the
+// context.Context is done.
+func (container *Container) Shutdown(ctx context.Context) error {
+	container.logger.Info("shutting down the API")
+
+	if container.eventBroker != nil {
+		container.eventBroker.Close()
+	}
+	if container.websocketHub != nil {
+		container.websocketHub.Close()
+	}
+
+	var failures int
+	if container.app != nil {
+		if err := container.app.ShutdownWithContext(ctx); err != nil {
+			container.logger.Error(stacktrace.Propagate(err, "cannot wait for the HTTP requests in flight"))
+			failures++
+		}
+	}
+
+	if container.grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			container.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			container.logger.Error(stacktrace.Propagate(ctx.Err(), "cannot wait for the gRPC calls in flight"))
+			container.grpcServer.Stop()
+			failures++
+		}
+	}
+
+	if container.eventDispatcher != nil {
+		if err := container.eventDispatcher.Shutdown(ctx); err != nil {
+			container.logger.Error(stacktrace.Propagate(err, "cannot publish the pending events"))
+			failures++
+		}
+	}
+
+	if container.flushTelemetry != nil {
+		container.flushTelemetry()
+	}
+
+	databases := map[string]*gorm.DB{"primary": container.db, "dedicated": container.dedicatedDB, "replica": container.replicaDB}
+	for name, db := range databases {
+		if db == nil {
+			continue
+		}
+
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			container.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot close the connections of the [%s] database", name)))
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return stacktrace.NewError(fmt.Sprintf("[%d] steps of the shutdown failed", failures))
+	}
+
+	container.logger.Info("the API was shut down gracefully")
+	return nil
+}
+
+// MessageHandler creates a new instance of handlers.MessageHandler
+func (container *Container) MessageHandler() (handler *handlers.MessageHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewMessageHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageHandlerValidator(),
+		container.BillingService(),
+		container.MessageService(),
+		container.PhoneService(),
+		container.HeartbeatService(),
+		container.ContactService(),
+		container.ContactGroupService(),
+		container.MediaService(),
+	)
+}
+
+// MessageStreamHandler creates a new instance of handlers.MessageStreamHandler
+func (container *Container) MessageStreamHandler() (handler *handlers.MessageStreamHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewMessageStreamHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageHandlerValidator(),
+		container.EventBroker(),
+		15*time.Second,
 	)
 }
 
-// MessageHandler creates a new instance of handlers.MessageHandler
-func (container *Container) MessageHandler() (handler *handlers.MessageHandler) {
+// WebsocketHandler creates a new instance of handlers.WebsocketHandler
+func (container *Container) WebsocketHandler() (handler *handlers.WebsocketHandler) {
 	container.logger.Debug(fmt.Sprintf("creating %T", handler))
-	return handlers.NewMessageHandler(
+
+	origins := []string{os.Getenv("APP_URL")}
+	if value := os.Getenv("WEBSOCKET_ALLOWED_ORIGINS"); value != "" {
+		origins = strings.Split(value, ",")
+	}
+
+	return handlers.NewWebsocketHandler(
 		container.Logger(),
 		container.Tracer(),
-		container.MessageHandlerValidator(),
-		container.BillingService(),
-		container.MessageService(),
+		container.MessageThreadHandlerValidator(),
+		container.WebsocketHub(),
+		container.MessageThreadService(),
+		origins,
+		30*time.Second,
 	)
 }
 
@@ -971,9 +2123,21 @@ func (container *Container) EventsHandler() (handler *handlers.EventsHandler) {
 		container.Tracer(),
 		container.EventsQueueConfiguration(),
 		container.EventDispatcher(),
+		container.ReceivableEventTypes(),
 	)
 }
 
+// ReceivableEventTypes returns the types of the events in EVENTS_RECEIVER_TYPES which other systems can send to /v1/events/receive
+func (container *Container) ReceivableEventTypes() []string {
+	eventTypes := make([]string, 0)
+	for _, eventType := range strings.Split(os.Getenv("EVENTS_RECEIVER_TYPES"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	return eventTypes
+}
+
 // RegisterMessageListeners registers event listeners for listeners.MessageListener
 func (container *Container) RegisterMessageListeners() {
 	container.logger.Debug(fmt.Sprintf("registering listners for %T", listeners.MessageListener{}))
@@ -981,6 +2145,8 @@ func (container *Container) RegisterMessageListeners() {
 		container.Logger(),
 		container.Tracer(),
 		container.MessageService(),
+		container.Transactor(),
+		container.EventListenerLogRepository(),
 	)
 
 	for event, handler := range routes {
@@ -1023,6 +2189,19 @@ func (container *Container) Integration3CXHandler() (handler *handlers.Integrati
 	)
 }
 
+// TwilioHandler creates a new instance of handlers.TwilioHandler
+func (container *Container) TwilioHandler() (handler *handlers.TwilioHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+
+	return handlers.NewTwilioHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.TwilioHandlerValidator(),
+		container.BillingService(),
+		container.TwilioService(),
+	)
+}
+
 // DiscordHandler creates a new instance of handlers.DiscordHandler
 func (container *Container) DiscordHandler() (handler *handlers.DiscordHandler) {
 	container.logger.Debug(fmt.Sprintf("creating %T", handler))
@@ -1038,52 +2217,442 @@ func (container *Container) DiscordHandler() (handler *handlers.DiscordHandler)
 	)
 }
 
-// LemonsqueezyHandlerValidator creates a new instance of validators.LemonsqueezyHandlerValidator
-func (container *Container) LemonsqueezyHandlerValidator() (validator *validators.LemonsqueezyHandlerValidator) {
-	container.logger.Debug(fmt.Sprintf("creating %T", validator))
-	return validators.NewLemonsqueezyHandlerValidator(
-		container.Logger(),
-		container.Tracer(),
-		container.LemonsqueezyClient(),
-	)
+// SlackHandler creates a new instance of handlers.SlackHandler
+func (container *Container) SlackHandler() (handler *handlers.SlackHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewSlackHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.SlackHandlerValidator(),
+		container.SlackService(),
+	)
+}
+
+// TelegramHandler creates a new instance of handlers.TelegramHandler
+func (container *Container) TelegramHandler() (handler *handlers.TelegramHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewTelegramHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.TelegramHandlerValidator(),
+		container.TelegramService(),
+		container.MessageHandlerValidator(),
+		container.MessageService(),
+		container.BillingService(),
+	)
+}
+
+// FallbackHandler creates a new instance of handlers.FallbackHandler
+func (container *Container) FallbackHandler() (handler *handlers.FallbackHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewFallbackHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.FallbackHandlerValidator(),
+		container.FallbackService(),
+	)
+}
+
+// NotificationPreferenceHandler creates a new instance of handlers.NotificationPreferenceHandler
+func (container *Container) NotificationPreferenceHandler() (handler *handlers.NotificationPreferenceHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewNotificationPreferenceHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.NotificationPreferenceHandlerValidator(),
+		container.EmailNotificationService(),
+	)
+}
+
+// SummaryReportHandler creates a new instance of handlers.SummaryReportHandler
+func (container *Container) SummaryReportHandler() (handler *handlers.SummaryReportHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewSummaryReportHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.SummaryReportHandlerValidator(),
+		container.SummaryReportService(),
+	)
+}
+
+// ContactGroupHandler creates a new instance of handlers.ContactGroupHandler
+func (container *Container) ContactGroupHandler() (handler *handlers.ContactGroupHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewContactGroupHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.ContactGroupHandlerValidator(),
+		container.ContactGroupService(),
+	)
+}
+
+// ContactHandler creates a new instance of handlers.ContactHandler
+func (container *Container) ContactHandler() (handler *handlers.ContactHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewContactHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.ContactHandlerValidator(),
+		container.ContactService(),
+	)
+}
+
+// NumberLookupHandler creates a new instance of handlers.NumberLookupHandler
+func (container *Container) NumberLookupHandler() (handler *handlers.NumberLookupHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewNumberLookupHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.NumberLookupHandlerValidator(),
+		container.NumberLookupService(),
+	)
+}
+
+// NumberLookupHandlerValidator creates a new instance of validators.NumberLookupHandlerValidator
+func (container *Container) NumberLookupHandlerValidator() (validator *validators.NumberLookupHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewNumberLookupHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// RoutingRuleHandler creates a new instance of handlers.RoutingRuleHandler
+func (container *Container) RoutingRuleHandler() (handler *handlers.RoutingRuleHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewRoutingRuleHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.RoutingRuleHandlerValidator(),
+		container.RoutingRuleService(),
+	)
+}
+
+// RoutingRuleHandlerValidator creates a new instance of validators.RoutingRuleHandlerValidator
+func (container *Container) RoutingRuleHandlerValidator() (validator *validators.RoutingRuleHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewRoutingRuleHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// MessageRateHandler creates a new instance of handlers.MessageRateHandler
+func (container *Container) MessageRateHandler() (handler *handlers.MessageRateHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewMessageRateHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageRateHandlerValidator(),
+		container.MessageRateService(),
+	)
+}
+
+// MessageRateHandlerValidator creates a new instance of validators.MessageRateHandlerValidator
+func (container *Container) MessageRateHandlerValidator() (validator *validators.MessageRateHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewMessageRateHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// MessageStatisticsHandler creates a new instance of handlers.MessageStatisticsHandler
+func (container *Container) MessageStatisticsHandler() (handler *handlers.MessageStatisticsHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewMessageStatisticsHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.MessageStatisticsHandlerValidator(),
+		container.MessageStatisticsService(),
+	)
+}
+
+// MessageStatisticsHandlerValidator creates a new instance of validators.MessageStatisticsHandlerValidator
+func (container *Container) MessageStatisticsHandlerValidator() (validator *validators.MessageStatisticsHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewMessageStatisticsHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// ShortLinkHandler creates a new instance of handlers.ShortLinkHandler
+func (container *Container) ShortLinkHandler() (handler *handlers.ShortLinkHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewShortLinkHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.ShortLinkHandlerValidator(),
+		container.ShortLinkService(),
+		container.RateLimiter(),
+		container.rateLimit("RATE_LIMIT_SHORT_LINK"),
+	)
+}
+
+// ShortLinkHandlerValidator creates a new instance of validators.ShortLinkHandlerValidator
+func (container *Container) ShortLinkHandlerValidator() (validator *validators.ShortLinkHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewShortLinkHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// MediaHandler creates a new instance of handlers.MediaHandler
+func (container *Container) MediaHandler() (handler *handlers.MediaHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewMediaHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.MediaHandlerValidator(),
+		container.MediaService(),
+	)
+}
+
+// MediaHandlerValidator creates a new instance of validators.MediaHandlerValidator
+func (container *Container) MediaHandlerValidator() (validator *validators.MediaHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewMediaHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// ContentExpiryRuleHandler creates a new instance of handlers.ContentExpiryRuleHandler
+func (container *Container) ContentExpiryRuleHandler() (handler *handlers.ContentExpiryRuleHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewContentExpiryRuleHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.ContentExpiryRuleHandlerValidator(),
+		container.ContentExpiryService(),
+	)
+}
+
+// ContentExpiryRuleHandlerValidator creates a new instance of validators.ContentExpiryRuleHandlerValidator
+func (container *Container) ContentExpiryRuleHandlerValidator() (validator *validators.ContentExpiryRuleHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewContentExpiryRuleHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+	)
+}
+
+// AccountExportHandler creates a new instance of handlers.AccountExportHandler
+func (container *Container) AccountExportHandler() (handler *handlers.AccountExportHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewAccountExportHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.AccountExportService(),
+	)
+}
+
+// TwilioImportHandler creates a new instance of handlers.TwilioImportHandler
+func (container *Container) TwilioImportHandler() (handler *handlers.TwilioImportHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", handler))
+	return handlers.NewTwilioImportHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.TwilioHandlerValidator(),
+		container.TwilioImportService(),
+	)
+}
+
+// LemonsqueezyHandlerValidator creates a new instance of validators.LemonsqueezyHandlerValidator
+func (container *Container) LemonsqueezyHandlerValidator() (validator *validators.LemonsqueezyHandlerValidator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", validator))
+	return validators.NewLemonsqueezyHandlerValidator(
+		container.Logger(),
+		container.Tracer(),
+		container.LemonsqueezyClient(),
+	)
+}
+
+// LemonsqueezyClient creates a new instance of lemonsqueezy.Client
+func (container *Container) LemonsqueezyClient() (client *lemonsqueezy.Client) {
+	container.logger.Debug(fmt.Sprintf("creating %T", client))
+	return lemonsqueezy.New(
+		lemonsqueezy.WithHTTPClient(container.HTTPClient("lemonsqueezy")),
+		lemonsqueezy.WithAPIKey(os.Getenv("LEMONSQUEEZY_API_KEY")),
+		lemonsqueezy.WithSigningSecret(os.Getenv("LEMONSQUEEZY_SIGNING_SECRET")),
+	)
+}
+
+// DiscordClient creates a new instance of discord.Client
+func (container *Container) DiscordClient() (client *discord.Client) {
+	container.logger.Debug(fmt.Sprintf("creating %T", client))
+	return discord.New(
+		discord.WithHTTPClient(container.HTTPClient("discord")),
+		discord.WithApplicationID(os.Getenv("DISCORD_APPLICATION_ID")),
+		discord.WithBotToken(os.Getenv("DISCORD_BOT_TOKEN")),
+	)
+}
+
+// SlackClient creates a new instance of slack.Client
+func (container *Container) SlackClient() (client *slack.Client) {
+	container.logger.Debug(fmt.Sprintf("creating %T", client))
+	return slack.New(
+		slack.WithHTTPClient(container.HTTPClient("slack")),
+	)
+}
+
+// TelegramClient creates a new instance of telegram.Client
+func (container *Container) TelegramClient() (client *telegram.Client) {
+	container.logger.Debug(fmt.Sprintf("creating %T", client))
+	return telegram.New(
+		telegram.WithHTTPClient(container.HTTPClient("telegram")),
+	)
+}
+
+// RegisterLemonsqueezyRoutes registers routes for the /lemonsqueezy prefix
+func (container *Container) RegisterLemonsqueezyRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.LemonsqueezyHandler{}))
+	container.LemonsqueezyHandler().RegisterRoutes(container.App())
+}
+
+// RegisterIntegration3CXRoutes registers routes for the /integration/3cx prefix
+func (container *Container) RegisterIntegration3CXRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering [%T] routes", &handlers.Integration3CXHandler{}))
+	container.Integration3CXHandler().RegisterRoutes(container.App(), container.BearerAPIKeyMiddleware(), container.AuthenticatedMiddleware())
+}
+
+// RegisterTwilioRoutes registers routes for the Twilio compatible API, the handlers.TwilioHandler renders its own
+// authentication errors like the Twilio REST API
+func (container *Container) RegisterTwilioRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering [%T] routes", &handlers.TwilioHandler{}))
+	container.TwilioHandler().RegisterRoutes(container.App(), container.BasicAPIKeyMiddleware())
+}
+
+// RegisterDiscordRoutes registers routes for the /discord prefix
+func (container *Container) RegisterDiscordRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.DiscordHandler{}))
+	container.DiscordHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+}
+
+// RegisterSlackRoutes registers routes for the /v1/slack-integrations prefix
+func (container *Container) RegisterSlackRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.SlackHandler{}))
+	container.SlackHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+}
+
+// RegisterTelegramRoutes registers routes for the /telegram and /v1/telegram-integrations prefixes
+func (container *Container) RegisterTelegramRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.TelegramHandler{}))
+	container.TelegramHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+}
+
+// RegisterFallbackRoutes registers routes for the /fallback-callbacks and /v1/fallback-provider prefixes
+func (container *Container) RegisterFallbackRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.FallbackHandler{}))
+	container.FallbackHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+}
+
+// RegisterNotificationPreferenceRoutes registers routes for the /v1/notification-preference prefix
+func (container *Container) RegisterNotificationPreferenceRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.NotificationPreferenceHandler{}))
+	container.NotificationPreferenceHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+}
+
+// RegisterSummaryReportRoutes registers routes for the /v1/summary-report prefix
+func (container *Container) RegisterSummaryReportRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.SummaryReportHandler{}))
+	container.SummaryReportHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+}
+
+// RegisterContactGroupRoutes registers routes for the /contact-groups prefix
+func (container *Container) RegisterContactGroupRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.ContactGroupHandler{}))
+	container.ContactGroupHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterContactRoutes registers routes for the /contacts prefix
+func (container *Container) RegisterContactRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.ContactHandler{}))
+	container.ContactHandler().RegisterRoutes(container.AuthRouter(), container.MinimumAppVersionMiddleware())
+}
+
+// RegisterNumberLookupRoutes registers routes for the /number-lookup prefix
+func (container *Container) RegisterNumberLookupRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.NumberLookupHandler{}))
+	container.NumberLookupHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterRoutingRuleRoutes registers routes for the /routing-rules prefix
+func (container *Container) RegisterRoutingRuleRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.RoutingRuleHandler{}))
+	container.RoutingRuleHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterMessageRateRoutes registers routes for the /message-rates and /message-costs prefixes
+func (container *Container) RegisterMessageRateRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MessageRateHandler{}))
+	container.MessageRateHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterMessageStatisticsRoutes registers routes for the /statistics prefix
+func (container *Container) RegisterMessageStatisticsRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MessageStatisticsHandler{}))
+	container.MessageStatisticsHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterShortLinkRoutes registers routes for the /s and /v1/messages/:messageID/short-links prefixes
+func (container *Container) RegisterShortLinkRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.ShortLinkHandler{}))
+	container.ShortLinkHandler().RegisterRoutes(container.App(), container.AuthRouter())
+}
+
+// RegisterMediaRoutes registers routes for the /media prefix
+func (container *Container) RegisterMediaRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MediaHandler{}))
+	container.MediaHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterContentExpiryRuleRoutes registers routes for the /content-expiry-rules prefix
+func (container *Container) RegisterContentExpiryRuleRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.ContentExpiryRuleHandler{}))
+	container.ContentExpiryRuleHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterAccountExportRoutes registers routes for the /users/me/export prefix
+func (container *Container) RegisterAccountExportRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.AccountExportHandler{}))
+	container.AccountExportHandler().RegisterRoutes(container.AuthRouter())
 }
 
-// LemonsqueezyClient creates a new instance of lemonsqueezy.Client
-func (container *Container) LemonsqueezyClient() (client *lemonsqueezy.Client) {
-	container.logger.Debug(fmt.Sprintf("creating %T", client))
-	return lemonsqueezy.New(
-		lemonsqueezy.WithHTTPClient(container.HTTPClient("lemonsqueezy")),
-		lemonsqueezy.WithAPIKey(os.Getenv("LEMONSQUEEZY_API_KEY")),
-		lemonsqueezy.WithSigningSecret(os.Getenv("LEMONSQUEEZY_SIGNING_SECRET")),
-	)
+// RegisterTwilioImportRoutes registers routes for the /messages/import/twilio prefix
+func (container *Container) RegisterTwilioImportRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.TwilioImportHandler{}))
+	container.TwilioImportHandler().RegisterRoutes(container.AuthRouter())
 }
 
-// DiscordClient creates a new instance of discord.Client
-func (container *Container) DiscordClient() (client *discord.Client) {
-	container.logger.Debug(fmt.Sprintf("creating %T", client))
-	return discord.New(
-		discord.WithHTTPClient(container.HTTPClient("discord")),
-		discord.WithApplicationID(os.Getenv("DISCORD_APPLICATION_ID")),
-		discord.WithBotToken(os.Getenv("DISCORD_BOT_TOKEN")),
+// RegisterMessageStreamListeners registers event listeners for listeners.MessageStreamListener
+func (container *Container) RegisterMessageStreamListeners() {
+	container.logger.Debug(fmt.Sprintf("registering listners for %T", listeners.MessageStreamListener{}))
+	_, routes := listeners.NewMessageStreamListener(
+		container.Logger(),
+		container.Tracer(),
+		container.EventBroker(),
 	)
-}
 
-// RegisterLemonsqueezyRoutes registers routes for the /lemonsqueezy prefix
-func (container *Container) RegisterLemonsqueezyRoutes() {
-	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.LemonsqueezyHandler{}))
-	container.LemonsqueezyHandler().RegisterRoutes(container.App())
+	for event, handler := range routes {
+		container.EventDispatcher().Subscribe(event, handler)
+	}
 }
 
-// RegisterIntegration3CXRoutes registers routes for the /integration/3cx prefix
-func (container *Container) RegisterIntegration3CXRoutes() {
-	container.logger.Debug(fmt.Sprintf("registering [%T] routes", &handlers.Integration3CXHandler{}))
-	container.Integration3CXHandler().RegisterRoutes(container.App(), container.BearerAPIKeyMiddleware(), container.AuthenticatedMiddleware())
-}
+// RegisterWebsocketListeners registers event listeners for listeners.WebsocketListener
+func (container *Container) RegisterWebsocketListeners() {
+	container.logger.Debug(fmt.Sprintf("registering listners for %T", listeners.WebsocketListener{}))
+	_, routes := listeners.NewWebsocketListener(
+		container.Logger(),
+		container.Tracer(),
+		container.WebsocketHub(),
+	)
 
-// RegisterDiscordRoutes registers routes for the /discord prefix
-func (container *Container) RegisterDiscordRoutes() {
-	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.DiscordHandler{}))
-	container.DiscordHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
+	for event, handler := range routes {
+		container.EventDispatcher().Subscribe(event, handler)
+	}
 }
 
 // RegisterMessageThreadListeners registers event listeners for listeners.MessageThreadListener
@@ -1177,6 +2746,51 @@ func (container *Container) RegisterDiscordListeners() {
 		container.Logger(),
 		container.Tracer(),
 		container.DiscordService(),
+		container.MessageThreadService(),
+	)
+
+	for event, handler := range routes {
+		container.EventDispatcher().Subscribe(event, handler)
+	}
+}
+
+// RegisterSlackListeners registers event listeners for listeners.SlackListener
+func (container *Container) RegisterSlackListeners() {
+	container.logger.Debug(fmt.Sprintf("registering listeners for %T", listeners.SlackListener{}))
+	_, routes := listeners.NewSlackListener(
+		container.Logger(),
+		container.Tracer(),
+		container.SlackService(),
+		container.MessageThreadService(),
+	)
+
+	for event, handler := range routes {
+		container.EventDispatcher().Subscribe(event, handler)
+	}
+}
+
+// RegisterTelegramListeners registers event listeners for listeners.TelegramListener
+func (container *Container) RegisterTelegramListeners() {
+	container.logger.Debug(fmt.Sprintf("registering listeners for %T", listeners.TelegramListener{}))
+	_, routes := listeners.NewTelegramListener(
+		container.Logger(),
+		container.Tracer(),
+		container.TelegramService(),
+		container.MessageThreadService(),
+	)
+
+	for event, handler := range routes {
+		container.EventDispatcher().Subscribe(event, handler)
+	}
+}
+
+// RegisterFallbackListeners registers event listeners for listeners.FallbackListener
+func (container *Container) RegisterFallbackListeners() {
+	container.logger.Debug(fmt.Sprintf("registering listeners for %T", listeners.FallbackListener{}))
+	_, routes := listeners.NewFallbackListener(
+		container.Logger(),
+		container.Tracer(),
+		container.FallbackService(),
 	)
 
 	for event, handler := range routes {
@@ -1198,6 +2812,20 @@ func (container *Container) RegisterIntegration3CXListeners() {
 	}
 }
 
+// RegisterTwilioListeners registers event listeners for listeners.TwilioListener
+func (container *Container) RegisterTwilioListeners() {
+	container.logger.Debug(fmt.Sprintf("registering listeners for %T", listeners.TwilioListener{}))
+	_, routes := listeners.NewTwilioListener(
+		container.Logger(),
+		container.Tracer(),
+		container.TwilioService(),
+	)
+
+	for event, handler := range routes {
+		container.EventDispatcher().Subscribe(event, handler)
+	}
+}
+
 // RegisterWebhookListeners registers event listeners for listeners.WebhookListener
 func (container *Container) RegisterWebhookListeners() {
 	container.logger.Debug(fmt.Sprintf("registering listeners for %T", listeners.WebhookListener{}))
@@ -1205,6 +2833,7 @@ func (container *Container) RegisterWebhookListeners() {
 		container.Logger(),
 		container.Tracer(),
 		container.WebhookService(),
+		container.MessageThreadService(),
 	)
 
 	for event, handler := range routes {
@@ -1218,12 +2847,157 @@ func (container *Container) MessageService() (service *services.MessageService)
 	return services.NewMessageService(
 		container.Logger(),
 		container.Tracer(),
+		services.MessageServiceDeps{
+			Repository:            container.MessageRepository(),
+			EventDispatcher:       container.EventDispatcher(),
+			EventRepository:       container.EventRepository(),
+			PhoneService:          container.PhoneService(),
+			UserRepository:        container.UserRepository(),
+			UsageRepository:       container.DailyMessageUsageRepository(),
+			UsageLocation:         container.DailyMessageLimitLocation(),
+			ArchiveAfter:          container.MessageArchiveAfter(),
+			IDs:                   container.MessageIDGenerator(),
+			Metrics:               container.MessageMetrics(),
+			ContactRepository:     container.ContactRepository(),
+			NumberLookupService:   container.NumberLookupService(),
+			RoutingRuleRepository: container.RoutingRuleRepository(),
+			MessageRateRepository: container.MessageRateRepository(),
+			PhoneUsageRepository:  container.PhoneDailySendUsageRepository(),
+			ShortLinkService:      container.ShortLinkService(),
+			MediaService:          container.MediaService(),
+			ContentExpiryService:  container.ContentExpiryService(),
+			ThreadRepository:      container.MessageThreadRepository(),
+		},
+	)
+}
+
+// MessageIDGenerator creates the ids.Generator of message IDs with the strategy in MESSAGE_ID_STRATEGY
+func (container *Container) MessageIDGenerator() (generator ids.Generator) {
+	container.logger.Debug(fmt.Sprintf("creating %T", generator))
+	generator, err := ids.NewGenerator(ids.Strategy(os.Getenv("MESSAGE_ID_STRATEGY")))
+	if err != nil {
+		msg := fmt.Sprintf("MESSAGE_ID_STRATEGY [%s] is not supported, use [%s] or [%s]", os.Getenv("MESSAGE_ID_STRATEGY"), ids.StrategyTimeOrdered, ids.StrategyRandom)
+		container.logger.Fatal(stacktrace.Propagate(err, msg))
+	}
+	return generator
+}
+
+// MessageRetentionService creates a new instance of services.MessageRetentionService
+func (container *Container) MessageRetentionService() (service *services.MessageRetentionService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewMessageRetentionService(
+		container.Logger(),
+		container.Tracer(),
+		container.UserRepository(),
 		container.MessageRepository(),
 		container.EventDispatcher(),
-		container.PhoneService(),
+		container.MessageRetentionHardDelete(),
+	)
+}
+
+// MessageRetentionHardDelete checks if the messages which are older than the retention period of a user are permanently deleted instead of soft deleted
+func (container *Container) MessageRetentionHardDelete() bool {
+	hardDelete, err := strconv.ParseBool(os.Getenv("MESSAGE_RETENTION_HARD_DELETE"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse MESSAGE_RETENTION_HARD_DELETE [%s] messages will be soft deleted", os.Getenv("MESSAGE_RETENTION_HARD_DELETE")))
+		return false
+	}
+	return hardDelete
+}
+
+// MessageExportService creates a new instance of services.MessageExportService
+func (container *Container) MessageExportService() (service *services.MessageExportService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewMessageExportService(
+		container.Logger(),
+		container.Tracer(),
+		container.BlobStore(),
+		container.MessageRepository(),
+		container.MessageExportRepository(),
+		container.MessageExportAfter(),
+	)
+}
+
+// MessageExportRepository creates a new instance of repositories.MessageExportRepository
+func (container *Container) MessageExportRepository() (repository repositories.MessageExportRepository) {
+	container.logger.Debug("creating GORM repositories.MessageExportRepository")
+	return repositories.NewGormMessageExportRepository(
+		container.Logger(),
+		container.Tracer(),
+		container.DB(),
 	)
 }
 
+// MessageExportAfter is the duration after which archived messages are exported to the storage.BlobStore and deleted from the database
+func (container *Container) MessageExportAfter() time.Duration {
+	exportAfter, err := time.ParseDuration(os.Getenv("MESSAGE_EXPORT_AFTER"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse MESSAGE_EXPORT_AFTER [%s] messages will not be exported", os.Getenv("MESSAGE_EXPORT_AFTER")))
+		return 0
+	}
+	return exportAfter
+}
+
+// BlobStore creates a new instance of storage.BlobStore in the GCS bucket BLOB_STORE_BUCKET or the directory BLOB_STORE_DIRECTORY
+func (container *Container) BlobStore() (store storage.BlobStore) {
+	if bucket := os.Getenv("BLOB_STORE_BUCKET"); bucket != "" {
+		container.logger.Debug(fmt.Sprintf("creating GCS %T for bucket [%s]", &store, bucket))
+		client, err := gcs.NewClient(context.Background(), option.WithCredentialsJSON(container.FirebaseCredentials()))
+		if err != nil {
+			container.logger.Fatal(stacktrace.Propagate(err, "cannot create GCS client"))
+		}
+		return storage.NewGCSBlobStore(container.Tracer(), client, bucket)
+	}
+
+	if directory := os.Getenv("BLOB_STORE_DIRECTORY"); directory != "" {
+		container.logger.Debug(fmt.Sprintf("creating file %T in directory [%s]", &store, directory))
+		return storage.NewFileBlobStore(container.Tracer(), directory)
+	}
+
+	container.logger.Fatal(stacktrace.NewError("set BLOB_STORE_BUCKET or BLOB_STORE_DIRECTORY to create a storage.BlobStore"))
+	return nil
+}
+
+// MediaBlobStore creates the storage.BlobStore of the media in MMS messages, it is nil when BLOB_STORE_BUCKET and
+// BLOB_STORE_DIRECTORY are empty so that the API can run without media
+func (container *Container) MediaBlobStore() storage.BlobStore {
+	if os.Getenv("BLOB_STORE_BUCKET") == "" && os.Getenv("BLOB_STORE_DIRECTORY") == "" {
+		container.logger.Debug("BLOB_STORE_BUCKET and BLOB_STORE_DIRECTORY are empty, media cannot be sent")
+		return nil
+	}
+	return container.BlobStore()
+}
+
+// MediaMaxSize is the maximum number of bytes of the media in MMS messages, the MMS size limit of most carriers is 1 MB
+func (container *Container) MediaMaxSize() int64 {
+	size, err := strconv.ParseInt(os.Getenv("MEDIA_MAX_SIZE"), 10, 64)
+	if err != nil || size <= 0 {
+		container.logger.Debug(fmt.Sprintf("cannot parse MEDIA_MAX_SIZE [%s] using default size", os.Getenv("MEDIA_MAX_SIZE")))
+		return 1024 * 1024
+	}
+	return size
+}
+
+// MessageArchiveAfter is the duration after which messages which will not be updated again are moved into the archive
+func (container *Container) MessageArchiveAfter() time.Duration {
+	archiveAfter, err := time.ParseDuration(os.Getenv("MESSAGE_ARCHIVE_AFTER"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse MESSAGE_ARCHIVE_AFTER [%s] messages will not be archived", os.Getenv("MESSAGE_ARCHIVE_AFTER")))
+		return 0
+	}
+	return archiveAfter
+}
+
+// DailyMessageLimitLocation is the timezone in which the daily message usage of a user resets at midnight
+func (container *Container) DailyMessageLimitLocation() *time.Location {
+	location, err := time.LoadLocation(os.Getenv("DAILY_MESSAGE_LIMIT_TIMEZONE"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot load DAILY_MESSAGE_LIMIT_TIMEZONE [%s] using UTC", os.Getenv("DAILY_MESSAGE_LIMIT_TIMEZONE")))
+		return time.UTC
+	}
+	return location
+}
+
 // NotificationService creates a new instance of services.PhoneNotificationService
 func (container *Container) NotificationService() (service *services.PhoneNotificationService) {
 	container.logger.Debug(fmt.Sprintf("creating %T", service))
@@ -1233,6 +3007,7 @@ func (container *Container) NotificationService() (service *services.PhoneNotifi
 		container.FirebaseMessagingClient(),
 		container.PhoneRepository(),
 		container.PhoneNotificationRepository(),
+		container.MessageRepository(),
 		container.EventDispatcher(),
 	)
 }
@@ -1240,7 +3015,14 @@ func (container *Container) NotificationService() (service *services.PhoneNotifi
 // RegisterMessageRoutes registers routes for the /messages prefix
 func (container *Container) RegisterMessageRoutes() {
 	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MessageHandler{}))
-	container.MessageHandler().RegisterRoutes(container.AuthRouter())
+	container.MessageHandler().RegisterRoutes(container.AuthRouter(), container.MinimumAppVersionMiddleware())
+	container.MessageHandler().RegisterRoutes(container.VersionRouter(responses.APIVersion2), container.MinimumAppVersionMiddleware())
+}
+
+// RegisterMessageStreamRoutes registers routes for the /messages/stream prefix
+func (container *Container) RegisterMessageStreamRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MessageStreamHandler{}))
+	container.MessageStreamHandler().RegisterRoutes(container.AuthRouter())
 }
 
 // RegisterBulkMessageRoutes registers routes for the /bulk-messages prefix
@@ -1249,10 +3031,17 @@ func (container *Container) RegisterBulkMessageRoutes() {
 	container.BulkMessageHandler().RegisterRoutes(container.AuthRouter())
 }
 
+// RegisterWebsocketRoutes registers routes for the /websocket prefix
+func (container *Container) RegisterWebsocketRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.WebsocketHandler{}))
+	container.WebsocketHandler().RegisterRoutes(container.AuthRouter())
+}
+
 // RegisterMessageThreadRoutes registers routes for the /message-threads prefix
 func (container *Container) RegisterMessageThreadRoutes() {
 	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MessageThreadHandler{}))
 	container.MessageThreadHandler().RegisterRoutes(container.AuthRouter())
+	container.MessageThreadHandler().RegisterRoutes(container.VersionRouter(responses.APIVersion2))
 }
 
 // RegisterHeartbeatRoutes registers routes for the /heartbeats prefix
@@ -1273,6 +3062,18 @@ func (container *Container) RegisterWebhookRoutes() {
 	container.WebhookHandler().RegisterRoutes(container.App(), container.AuthenticatedMiddleware())
 }
 
+// RegisterAPIKeyRoutes registers routes for the /api-keys prefix
+func (container *Container) RegisterAPIKeyRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.APIKeyHandler{}))
+	container.APIKeyHandler().RegisterRoutes(container.AuthRouter())
+}
+
+// RegisterTeamRoutes registers routes for the /teams prefix
+func (container *Container) RegisterTeamRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.TeamHandler{}))
+	container.TeamHandler().RegisterRoutes(container.AuthRouter())
+}
+
 // RegisterPhoneRoutes registers routes for the /phone prefix
 func (container *Container) RegisterPhoneRoutes() {
 	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.PhoneHandler{}))
@@ -1291,10 +3092,173 @@ func (container *Container) RegisterEventRoutes() {
 	container.EventsHandler().RegisterRoutes(container.AuthRouter())
 }
 
+// RegisterHealthRoutes registers the /live and /ready routes which are used by the load balancer
+func (container *Container) RegisterHealthRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.HealthHandler{}))
+	container.HealthHandler().RegisterRoutes(container.App())
+}
+
+// HealthHandler creates a new instance of handlers.HealthHandler
+func (container *Container) HealthHandler() (h *handlers.HealthHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", h))
+	return handlers.NewHealthHandler(
+		container.Logger(),
+		container.Tracer(),
+		container.HealthService(),
+	)
+}
+
+// HealthService creates a new instance of services.HealthService
+func (container *Container) HealthService() (service *services.HealthService) {
+	container.logger.Debug(fmt.Sprintf("creating %T", service))
+	return services.NewHealthService(
+		container.Logger(),
+		container.Tracer(),
+		container.HealthChecks(),
+	)
+}
+
+// HealthChecks creates the services.HealthCheck of the dependencies which are checked by the readiness probe
+func (container *Container) HealthChecks() []services.HealthCheck {
+	timeout := container.HealthCheckTimeout()
+
+	checks := []services.HealthCheck{
+		container.DatabaseHealthCheck(timeout),
+		services.NewMessageBacklogHealthCheck(container.MessageRepository(), container.HealthMessageBacklogThreshold(), timeout),
+	}
+
+	if fcm, _ := strconv.ParseBool(os.Getenv("HEALTH_CHECK_FCM")); fcm {
+		checks = append(checks, container.FirebaseHealthCheck(timeout))
+	}
+
+	return checks
+}
+
+// HealthCheckTimeout is the duration in HEALTH_CHECK_TIMEOUT after which a health check fails
+func (container *Container) HealthCheckTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("HEALTH_CHECK_TIMEOUT"))
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse HEALTH_CHECK_TIMEOUT [%s] using default timeout", os.Getenv("HEALTH_CHECK_TIMEOUT")))
+		return 0
+	}
+	return timeout
+}
+
+// HealthMessageBacklogThreshold is the number of messages waiting to be sent above which the API is not ready
+func (container *Container) HealthMessageBacklogThreshold() int64 {
+	threshold, err := strconv.ParseInt(os.Getenv("HEALTH_MESSAGE_BACKLOG_THRESHOLD"), 10, 64)
+	if err != nil {
+		container.logger.Debug(fmt.Sprintf("cannot parse HEALTH_MESSAGE_BACKLOG_THRESHOLD [%s] using default threshold", os.Getenv("HEALTH_MESSAGE_BACKLOG_THRESHOLD")))
+		return 10_000
+	}
+	return threshold
+}
+
+// DatabaseHealthCheck creates a services.HealthCheck which pings the database
+func (container *Container) DatabaseHealthCheck(timeout time.Duration) services.HealthCheck {
+	db := container.DB()
+	return services.HealthCheck{
+		Name:    "database",
+		Timeout: timeout,
+		Check: func(ctx context.Context) error {
+			pool, err := db.DB()
+			if err != nil {
+				return stacktrace.Propagate(err, "cannot get the database connection pool")
+			}
+			return pool.PingContext(ctx)
+		},
+	}
+}
+
+// FirebaseHealthCheck creates a services.HealthCheck which fetches an access token with the FIREBASE_CREDENTIALS
+func (container *Container) FirebaseHealthCheck(timeout time.Duration) services.HealthCheck {
+	credentials, credentialsErr := google.CredentialsFromJSON(context.Background(), container.FirebaseCredentials(), "https://www.googleapis.com/auth/firebase.messaging")
+	return services.HealthCheck{
+		Name:    "fcm",
+		Timeout: timeout,
+		Check: func(ctx context.Context) error {
+			if credentialsErr != nil {
+				return stacktrace.Propagate(credentialsErr, "cannot parse the firebase credentials")
+			}
+
+			if _, err := credentials.TokenSource.Token(); err != nil {
+				return stacktrace.Propagate(err, "cannot fetch an access token with the firebase credentials")
+			}
+			return nil
+		},
+	}
+}
+
+// RegisterMetricsRoutes registers the /metrics route which is scraped by Prometheus
+func (container *Container) RegisterMetricsRoutes() {
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.MetricsHandler{}))
+	container.MetricsHandler().RegisterRoutes(container.App())
+}
+
+// MetricsHandler creates a new instance of handlers.MetricsHandler
+func (container *Container) MetricsHandler() (h *handlers.MetricsHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", h))
+	return handlers.NewMetricsHandler(
+		container.Logger(),
+		container.MetricsRegistry(),
+		os.Getenv("METRICS_USERNAME"),
+		os.Getenv("METRICS_PASSWORD"),
+	)
+}
+
+// MetricsRegistry creates the telemetry.MetricsRegistry if it has not been created already
+func (container *Container) MetricsRegistry() (registry *telemetry.MetricsRegistry) {
+	if container.metricsRegistry != nil {
+		return container.metricsRegistry
+	}
+
+	container.logger.Debug(fmt.Sprintf("creating %T", registry))
+	container.metricsRegistry = telemetry.NewMetricsRegistry()
+	return container.metricsRegistry
+}
+
+// MessageMetrics creates a new instance of services.MessageMetrics
+func (container *Container) MessageMetrics() (metrics *services.MessageMetrics) {
+	container.logger.Debug(fmt.Sprintf("creating %T", metrics))
+	return services.NewMessageMetrics(container.MetricsRegistry())
+}
+
+// HTTPRequestDuration creates the telemetry.Histogram of the latency of the HTTP handlers
+func (container *Container) HTTPRequestDuration() (histogram *telemetry.Histogram) {
+	container.logger.Debug(fmt.Sprintf("creating %T", histogram))
+	return container.MetricsRegistry().Histogram(
+		"httpsms_http_request_duration_seconds",
+		"Latency of the HTTP handlers",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		"method", "route", "status",
+	)
+}
+
+// StartMessageQueueMetrics counts the queued messages every MESSAGE_QUEUE_METRICS_INTERVAL in the background
+func (container *Container) StartMessageQueueMetrics() {
+	interval := 30 * time.Second
+	if value := os.Getenv("MESSAGE_QUEUE_METRICS_INTERVAL"); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("MESSAGE_QUEUE_METRICS_INTERVAL [%s] is not a valid duration", value)))
+		}
+		interval = duration
+	}
+
+	container.logger.Debug(fmt.Sprintf("counting the queued messages every [%s]", interval))
+	go container.MessageMetrics().WatchQueue(context.Background(), container.Logger(), container.MessageRepository(), interval)
+}
+
 // RegisterSwaggerRoutes registers routes for swagger
 func (container *Container) RegisterSwaggerRoutes() {
-	container.logger.Debug(fmt.Sprintf("registering %T routes", swagger.HandlerDefault))
-	container.App().Get("/*", swagger.HandlerDefault)
+	container.logger.Debug(fmt.Sprintf("registering %T routes", &handlers.SwaggerHandler{}))
+	container.SwaggerHandler().RegisterRoutes(container.App())
+}
+
+// SwaggerHandler creates a new instance of handlers.SwaggerHandler
+func (container *Container) SwaggerHandler() (h *handlers.SwaggerHandler) {
+	container.logger.Debug(fmt.Sprintf("creating %T", h))
+	return handlers.NewSwaggerHandler(container.Logger())
 }
 
 // HeartbeatRepository registers a new instance of repositories.HeartbeatRepository
@@ -1318,8 +3282,12 @@ func (container *Container) UserRepository() repositories.UserRepository {
 	)
 }
 
-// RistrettoCache creates an in-memory *ristretto.Cache
+// RistrettoCache creates an in-memory *ristretto.Cache which is shared so that revoked API keys are evicted from all repositories
 func (container *Container) RistrettoCache() (cache *ristretto.Cache) {
+	if container.ristrettoCache != nil {
+		return container.ristrettoCache
+	}
+
 	container.logger.Debug(fmt.Sprintf("creating %T", cache))
 	ristrettoCache, err := ristretto.NewCache(&ristretto.Config{
 		MaxCost:     5000,
@@ -1329,12 +3297,18 @@ func (container *Container) RistrettoCache() (cache *ristretto.Cache) {
 	if err != nil {
 		container.logger.Fatal(stacktrace.Propagate(err, "cannot create ristretto cache"))
 	}
+
+	container.ristrettoCache = ristrettoCache
 	return ristrettoCache
 }
 
 // InitializeTraceProvider initializes the open telemetry trace provider
 func (container *Container) InitializeTraceProvider() func() {
-	return container.initializeUptraceProvider(container.version, container.projectID)
+	config := container.TraceConfig()
+	if config.Exporter == telemetry.TraceExporterUptrace {
+		return container.initializeUptraceProvider(container.version, container.projectID, config)
+	}
+	return container.initializeTraceProvider(container.version, container.projectID, config)
 	//if isLocal() {
 	//	return container.initializeUptraceProvider(container.version, container.projectID)
 	//}
@@ -1377,7 +3351,93 @@ func (container *Container) initializeGoogleTraceProvider(version string, namesp
 	}
 }
 
-func (container *Container) initializeUptraceProvider(version string, namespace string) (flush func()) {
+// TraceConfig creates the telemetry.TraceConfig from the TRACE_* environment variables
+func (container *Container) TraceConfig() (config telemetry.TraceConfig) {
+	container.logger.Debug(fmt.Sprintf("creating %T", config))
+
+	config = telemetry.TraceConfig{
+		Sampler:  telemetry.TraceSamplerAlways,
+		Ratio:    1,
+		Exporter: telemetry.TraceExporterUptrace,
+	}
+
+	if value := os.Getenv("TRACE_SAMPLER"); value != "" {
+		config.Sampler = value
+	}
+
+	if value := os.Getenv("TRACE_SAMPLER_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot parse TRACE_SAMPLER_RATIO [%s]", value)))
+		}
+		config.Ratio = ratio
+	}
+
+	routeRatios, err := telemetry.ParseRouteRatios(os.Getenv("TRACE_SAMPLER_ROUTES"))
+	if err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot parse TRACE_SAMPLER_ROUTES [%s]", os.Getenv("TRACE_SAMPLER_ROUTES"))))
+	}
+	config.RouteRatios = routeRatios
+
+	config.ExportErrors, _ = strconv.ParseBool(os.Getenv("TRACE_EXPORT_ERRORS"))
+
+	if value := os.Getenv("TRACE_EXPORTER"); value != "" {
+		config.Exporter = value
+	}
+
+	if err = config.Validate(); err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, "invalid trace configuration"))
+	}
+
+	if config.ExportErrors && config.Exporter == telemetry.TraceExporterUptrace {
+		container.logger.Warn(stacktrace.NewError("TRACE_EXPORT_ERRORS is not supported by the uptrace exporter, spans with errors are sampled like other spans"))
+		config.ExportErrors = false
+	}
+
+	return config
+}
+
+// initializeTraceProvider exports spans with the otlp or stdout exporter, spans are not exported with the none exporter
+func (container *Container) initializeTraceProvider(version string, namespace string, config telemetry.TraceConfig) (flush func()) {
+	container.logger.Debug(fmt.Sprintf("initializing trace provider with [%s] exporter", config.Exporter))
+
+	options := []trace.TracerProviderOption{
+		trace.WithResource(container.OtelResources(version, namespace)),
+	}
+
+	var exporter trace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case telemetry.TraceExporterOTLP:
+		exporter, err = otlptracegrpc.New(context.Background())
+	case telemetry.TraceExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	}
+	if err != nil {
+		container.logger.Fatal(stacktrace.Propagate(err, fmt.Sprintf("cannot create [%s] trace exporter", config.Exporter)))
+	}
+
+	if exporter == nil {
+		options = append(options, trace.WithSampler(trace.NeverSample()))
+	} else {
+		options = append(
+			options,
+			trace.WithSampler(config.NewSampler()),
+			trace.WithSpanProcessor(config.NewSpanProcessor(trace.NewBatchSpanProcessor(exporter))),
+		)
+	}
+
+	tp := trace.NewTracerProvider(options...)
+	otel.SetTracerProvider(tp)
+
+	return func() {
+		if err = tp.Shutdown(context.Background()); err != nil {
+			container.logger.Error(stacktrace.Propagate(err, "cannot shutdown trace provider"))
+		}
+	}
+}
+
+func (container *Container) initializeUptraceProvider(version string, namespace string, config telemetry.TraceConfig) (flush func()) {
 	container.logger.Debug("initializing uptrace provider")
 	// Configure OpenTelemetry with sensible defaults.
 	uptrace.ConfigureOpentelemetry(
@@ -1385,6 +3445,7 @@ func (container *Container) initializeUptraceProvider(version string, namespace
 		uptrace.WithServiceName(namespace),
 		uptrace.WithServiceVersion(version),
 		uptrace.WithDeploymentEnvironment(os.Getenv("ENV")),
+		uptrace.WithTraceSampler(config.NewSampler()),
 	)
 
 	// Send buffered spans and free resources.
@@ -14,6 +14,15 @@ func (factory *factory) formatPhoneNumber(number string) string {
 	return phonenumbers.Format(value, phonenumbers.INTERNATIONAL)
 }
 
+// formatContact formats the phone number of a contact, the contact is not changed when it is not a phone number e.g. an alphanumeric sender ID
+func (factory *factory) formatContact(contact string) string {
+	value, err := phonenumbers.Parse(contact, phonenumbers.UNKNOWN_REGION)
+	if err != nil {
+		return contact
+	}
+	return phonenumbers.Format(value, phonenumbers.INTERNATIONAL)
+}
+
 func (factory *factory) formatHTTPResponseCode(code *int) string {
 	responseCode := "-"
 	if code != nil {
@@ -21,3 +30,10 @@ func (factory *factory) formatHTTPResponseCode(code *int) string {
 	}
 	return responseCode
 }
+
+func (factory *factory) pluralize(value string, count int) string {
+	if count == 1 {
+		return value
+	}
+	return value + "s"
+}
@@ -6,8 +6,6 @@ import (
 
 	"github.com/NdoleStudio/httpsms/pkg/events"
 
-	"github.com/google/uuid"
-
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/matcornic/hermes/v2"
 	"github.com/palantir/stacktrace"
@@ -129,22 +127,47 @@ func (factory *hermesNotificationEmailFactory) WebhookSendFailed(user *entities.
 	}, nil
 }
 
-func (factory *hermesNotificationEmailFactory) MessageExpired(user *entities.User, messageID uuid.UUID, owner string, contact string, content string) (*Email, error) {
+func (factory *hermesNotificationEmailFactory) MessageDigest(user *entities.User, owner string, openedAt time.Time, messages []*entities.NotificationDigestMessage) (*Email, error) {
+	failed := 0
+	rows := make([][]hermes.Entry, 0, len(messages))
+	for _, message := range messages {
+		if message.Status == entities.MessageStatusFailed {
+			failed++
+		}
+
+		reason := "-"
+		if message.ErrorMessage != nil && *message.ErrorMessage != "" {
+			reason = *message.ErrorMessage
+		}
+
+		rows = append(rows, []hermes.Entry{
+			{Key: "To", Value: factory.formatPhoneNumber(message.Contact)},
+			{Key: "Status", Value: string(message.Status)},
+			{Key: "Reason", Value: reason},
+			{Key: "Message", Value: fmt.Sprintf("https://api.httpsms.com/v1/messages/%s", message.MessageID)},
+		})
+	}
+
 	email := hermes.Email{
 		Body: hermes.Body{
 			Title: "Hello",
 			Intros: []string{
-				fmt.Sprintf("The SMS message which you sent to %s has expired at %s and you will need to resend this message.", factory.formatPhoneNumber(contact), user.UserTimeString(time.Now())),
+				fmt.Sprintf(
+					"%d %s which you sent from %s failed or expired since %s, %d failed and %d expired. You will need to resend these messages.",
+					len(messages),
+					factory.pluralize("message", len(messages)),
+					factory.formatPhoneNumber(owner),
+					user.UserTimeString(openedAt),
+					failed,
+					len(messages)-failed,
+				),
 			},
-			Dictionary: []hermes.Entry{
-				{"ID", messageID.String()},
-				{"From", factory.formatPhoneNumber(owner)},
-				{"To", factory.formatPhoneNumber(contact)},
-				{"Message", content},
+			Table: hermes.Table{
+				Data: rows,
 			},
 			Actions: []hermes.Action{
 				{
-					Instructions: "Messages usually expire because we couldn't connect with your mobile phone to send the outgoing SMS. You can fix this by making sure your phone is connected to the internet and also connect your phone to the charger all the time since Android may kill the httpSMS app if it has been active for a very long time so save phone battery.",
+					Instructions: "Messages usually expire because we couldn't connect with your mobile phone and they fail when the phone cannot send the SMS. Make sure your phone is connected to the internet and to the charger since Android may kill the httpSMS app to save battery, and check the default SMS messaging app on your phone to find out why a message failed.",
 					Button: hermes.Button{
 						Color:     "#329ef4",
 						TextColor: "#FFFFFF",
@@ -155,7 +178,7 @@ func (factory *hermesNotificationEmailFactory) MessageExpired(user *entities.Use
 			},
 			Signature: "Cheers",
 			Outros: []string{
-				fmt.Sprintf("Don't hesitate to contact us by replying to this email. You can disable this email notification on https://httpsms.com/settings/#email-notifications"),
+				"Don't hesitate to contact us by replying to this email. You can change the threshold and quiet hours of this email notification with the /v1/notification-preference API or disable it on https://httpsms.com/settings/#email-notifications",
 			},
 		},
 	}
@@ -172,29 +195,49 @@ func (factory *hermesNotificationEmailFactory) MessageExpired(user *entities.Use
 
 	return &Email{
 		ToEmail: user.Email,
-		Subject: "📢 Your SMS message has expired on httpSMS",
+		Subject: fmt.Sprintf("📢 %d of your SMS %s failed or expired on httpSMS", len(messages), factory.pluralize("message", len(messages))),
 		HTML:    html,
 		Text:    text,
 	}, nil
 }
 
-func (factory *hermesNotificationEmailFactory) MessageFailed(user *entities.User, messageID uuid.UUID, owner, contact, content, reason string) (*Email, error) {
+func (factory *hermesNotificationEmailFactory) SummaryReport(user *entities.User, report *entities.SummaryReport) (*Email, error) {
+	period, subject := factory.summaryReportPeriod(report)
+
+	intros := []string{
+		fmt.Sprintf(
+			"%s you sent %d %s, %d delivered, %d failed and %d expired, and you received %d %s.",
+			period,
+			report.Outgoing,
+			factory.pluralize("message", int(report.Outgoing)),
+			report.Delivered,
+			report.Failed,
+			report.Expired,
+			report.Incoming,
+			factory.pluralize("message", int(report.Incoming)),
+		),
+	}
+
+	busiestContact := "-"
+	if report.BusiestContact != nil {
+		busiestContact = fmt.Sprintf("%s with %d %s", factory.formatContact(*report.BusiestContact), report.BusiestContactCount, factory.pluralize("message", int(report.BusiestContactCount)))
+	}
+
 	email := hermes.Email{
 		Body: hermes.Body{
-			Title: "Hello",
-			Intros: []string{
-				fmt.Sprintf("The SMS message which you sent to %s has failed at %s and you will need to resend this message.", factory.formatPhoneNumber(contact), user.UserTimeString(time.Now())),
-			},
+			Title:  "Hello",
+			Intros: intros,
 			Dictionary: []hermes.Entry{
-				{"ID", messageID.String()},
-				{"From", factory.formatPhoneNumber(owner)},
-				{"To", factory.formatPhoneNumber(contact)},
-				{"Message", content},
-				{"Failure Reason", reason},
+				{Key: "Sent", Value: fmt.Sprintf("%d", report.Outgoing)},
+				{Key: "Delivered", Value: fmt.Sprintf("%d", report.Delivered)},
+				{Key: "Failed", Value: fmt.Sprintf("%d", report.Failed)},
+				{Key: "Expired", Value: fmt.Sprintf("%d", report.Expired)},
+				{Key: "Received", Value: fmt.Sprintf("%d", report.Incoming)},
+				{Key: "Busiest contact", Value: busiestContact},
 			},
 			Actions: []hermes.Action{
 				{
-					Instructions: "Check the default SMS messaging app on your phone to find out the exact reason why the message failed. Usually messages fail because the httpSMS app phone has been un-installed or it is not active. Logout and login again on the mobile app on your Android phone and retry sending the SMS.",
+					Instructions: "You can see the charts of your messages on the dashboard.",
 					Button: hermes.Button{
 						Color:     "#329ef4",
 						TextColor: "#FFFFFF",
@@ -205,7 +248,7 @@ func (factory *hermesNotificationEmailFactory) MessageFailed(user *entities.User
 			},
 			Signature: "Cheers",
 			Outros: []string{
-				fmt.Sprintf("Don't hesitate to contact us by replying to this email. You can disable this email notification on https://httpsms.com/settings/#email-notifications"),
+				"Don't hesitate to contact us by replying to this email. You can change the frequency and the hour of this report or disable it with the /v1/summary-report API.",
 			},
 		},
 	}
@@ -222,8 +265,25 @@ func (factory *hermesNotificationEmailFactory) MessageFailed(user *entities.User
 
 	return &Email{
 		ToEmail: user.Email,
-		Subject: "📢 Your SMS message has failed on httpSMS",
+		Subject: fmt.Sprintf("📊 Your httpSMS report for %s", subject),
 		HTML:    html,
 		Text:    text,
 	}, nil
 }
+
+// summaryReportPeriod describes the period of a report in its timezone for the intro and the subject of the email
+func (factory *hermesNotificationEmailFactory) summaryReportPeriod(report *entities.SummaryReport) (string, string) {
+	location, err := time.LoadLocation(report.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	start := report.PeriodStart.In(location)
+	if report.Frequency == entities.SummaryReportFrequencyWeekly {
+		last := report.PeriodEnd.In(location).AddDate(0, 0, -1)
+		period := fmt.Sprintf("From %s to %s", start.Format("Monday, 2 January"), last.Format("Monday, 2 January 2006"))
+		return period, fmt.Sprintf("the week of %s", start.Format("2 January 2006"))
+	}
+
+	return fmt.Sprintf("On %s", start.Format("Monday, 2 January 2006")), start.Format("Monday, 2 January 2006")
+}
@@ -0,0 +1,97 @@
+package emails
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHermesNotificationEmailFactory_SummaryReport(t *testing.T) {
+	factory := NewHermesNotificationEmailFactory(&HermesGeneratorConfig{
+		AppURL:     "https://httpsms.com",
+		AppName:    "httpSMS",
+		AppLogoURL: "https://httpsms.com/logo.svg",
+	})
+	user := &entities.User{ID: "user-id", Email: "name@email.com"}
+
+	t.Run("the daily report is rendered in the timezone of the report", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		contact := "+18005550100"
+		report := &entities.SummaryReport{
+			Frequency:           entities.SummaryReportFrequencyDaily,
+			PeriodStart:         time.Date(2022, 10, 29, 22, 0, 0, 0, time.UTC),
+			PeriodEnd:           time.Date(2022, 10, 30, 23, 0, 0, 0, time.UTC),
+			Timezone:            "Europe/Berlin",
+			Outgoing:            12,
+			Delivered:           9,
+			Failed:              2,
+			Expired:             1,
+			Incoming:            1,
+			BusiestContact:      &contact,
+			BusiestContactCount: 7,
+		}
+
+		// Act
+		email, err := factory.SummaryReport(user, report)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "name@email.com", email.ToEmail)
+		assert.Equal(t, "📊 Your httpSMS report for Sunday, 30 October 2022", email.Subject)
+		assert.Contains(t, email.Text, "On Sunday, 30 October 2022 you sent 12 messages, 9 delivered, 2 failed and 1 expired, and you received 1 message.")
+		assert.Contains(t, email.Text, "+1 800-555-0100 with 7 messages")
+		assert.Contains(t, email.Text, "Busiest contact")
+	})
+
+	t.Run("the weekly report is rendered from Monday to Sunday", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		contact := "MTN"
+		report := &entities.SummaryReport{
+			Frequency:           entities.SummaryReportFrequencyWeekly,
+			PeriodStart:         time.Date(2022, 3, 7, 5, 0, 0, 0, time.UTC),
+			PeriodEnd:           time.Date(2022, 3, 14, 4, 0, 0, 0, time.UTC),
+			Timezone:            "America/New_York",
+			Incoming:            3,
+			BusiestContact:      &contact,
+			BusiestContactCount: 3,
+		}
+
+		// Act
+		email, err := factory.SummaryReport(user, report)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "📊 Your httpSMS report for the week of 7 March 2022", email.Subject)
+		assert.Contains(t, email.Text, "From Monday, 7 March to Sunday, 13 March 2022 you sent 0 messages, 0 delivered, 0 failed and 0 expired, and you received 3 messages.")
+		assert.Contains(t, email.Text, "MTN with 3 messages")
+	})
+
+	t.Run("a report without messages has no busiest contact", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		report := &entities.SummaryReport{
+			Frequency:   entities.SummaryReportFrequencyDaily,
+			PeriodStart: time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC),
+			PeriodEnd:   time.Date(2022, 6, 6, 0, 0, 0, 0, time.UTC),
+			Timezone:    "UTC",
+		}
+
+		// Act
+		email, err := factory.SummaryReport(user, report)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "📊 Your httpSMS report for Sunday, 5 June 2022", email.Subject)
+		assert.Contains(t, email.Text, "you received 0 messages.")
+	})
+}
@@ -105,6 +105,145 @@ func (factory *hermesUserEmailFactory) UsageLimitAlert(user *entities.User, usag
 	}, nil
 }
 
+// PhoneAlive is the email sent to a user when their phone is sending heartbeats again
+func (factory *hermesUserEmailFactory) PhoneAlive(user *entities.User, lastHeartbeatTimestamp time.Time, owner string) (*Email, error) {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Intros: []string{
+				fmt.Sprintf("Your android phone %s is back online, we received a heartbeat event from it at %s.", factory.formatPhoneNumber(owner), user.UserTimeString(lastHeartbeatTimestamp)),
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Check your heartbeat events on httpSMS",
+					Button: hermes.Button{
+						Color:     "#329ef4",
+						TextColor: "#FFFFFF",
+						Text:      "HEARTBEATS",
+						Link:      fmt.Sprintf("https://httpsms.com/heartbeats/%s", owner),
+					},
+				},
+			},
+			Title:     "Hey,",
+			Signature: "Cheers",
+			Outros: []string{
+				fmt.Sprintf("Don't hesitate to contact us by replying to this email. You can disable this email notification on https://httpsms.com/settings/#email-notifications"),
+			},
+		},
+	}
+
+	html, err := factory.generator.GenerateHTML(email)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot generate html email")
+	}
+
+	text, err := factory.generator.GeneratePlainText(email)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot generate text email")
+	}
+
+	return &Email{
+		ToEmail: user.Email,
+		Subject: fmt.Sprintf("✅ Android phone [%s] is back online", factory.formatPhoneNumber(owner)),
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
+// PhoneBatteryLow is the email sent to a user when the battery of their phone is low
+func (factory *hermesUserEmailFactory) PhoneBatteryLow(user *entities.User, owner string, batteryLevel uint) (*Email, error) {
+	email := hermes.Email{
+		Body: hermes.Body{
+			Intros: []string{
+				fmt.Sprintf("The battery of your android phone %s is at %d%% and it is not charging.", factory.formatPhoneNumber(owner), batteryLevel),
+				fmt.Sprintf("Plug in the phone to a charger so that it can continue sending and receiving messages."),
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Check your heartbeat events on httpSMS",
+					Button: hermes.Button{
+						Color:     "#329ef4",
+						TextColor: "#FFFFFF",
+						Text:      "HEARTBEATS",
+						Link:      fmt.Sprintf("https://httpsms.com/heartbeats/%s", owner),
+					},
+				},
+			},
+			Title:     "Hey,",
+			Signature: "Cheers",
+			Outros: []string{
+				fmt.Sprintf("Don't hesitate to contact us by replying to this email. You can disable this email notification on https://httpsms.com/settings/#email-notifications"),
+			},
+		},
+	}
+
+	html, err := factory.generator.GenerateHTML(email)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot generate html email")
+	}
+
+	text, err := factory.generator.GeneratePlainText(email)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot generate text email")
+	}
+
+	return &Email{
+		ToEmail: user.Email,
+		Subject: fmt.Sprintf("🪫 Low battery on android phone [%s]", factory.formatPhoneNumber(owner)),
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
+// PhoneClockSkewed is the email sent to a user when the clock of their phone is wrong
+func (factory *hermesUserEmailFactory) PhoneClockSkewed(user *entities.User, owner string, skew time.Duration) (*Email, error) {
+	direction := "ahead of"
+	if skew < 0 {
+		direction, skew = "behind", -skew
+	}
+
+	email := hermes.Email{
+		Body: hermes.Body{
+			Intros: []string{
+				fmt.Sprintf("The clock of your android phone %s is %s %s the correct time.", factory.formatPhoneNumber(owner), skew.Round(time.Second), direction),
+				"httpSMS corrects the timestamps of the messages which are sent and received by the phone, but you should turn on the automatic date and time in the settings of the phone.",
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Check your heartbeat events on httpSMS",
+					Button: hermes.Button{
+						Color:     "#329ef4",
+						TextColor: "#FFFFFF",
+						Text:      "HEARTBEATS",
+						Link:      fmt.Sprintf("https://httpsms.com/heartbeats/%s", owner),
+					},
+				},
+			},
+			Title:     "Hey,",
+			Signature: "Cheers",
+			Outros: []string{
+				fmt.Sprintf("Don't hesitate to contact us by replying to this email. You can disable this email notification on https://httpsms.com/settings/#email-notifications"),
+			},
+		},
+	}
+
+	html, err := factory.generator.GenerateHTML(email)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot generate html email")
+	}
+
+	text, err := factory.generator.GeneratePlainText(email)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot generate text email")
+	}
+
+	return &Email{
+		ToEmail: user.Email,
+		Subject: fmt.Sprintf("🕒 Wrong clock on android phone [%s]", factory.formatPhoneNumber(owner)),
+		HTML:    html,
+		Text:    text,
+	}, nil
+}
+
 // NewHermesUserEmailFactory creates a new instance of the UserEmailFactory
 func NewHermesUserEmailFactory(config *HermesGeneratorConfig) UserEmailFactory {
 	return &hermesUserEmailFactory{
@@ -114,7 +253,7 @@ func NewHermesUserEmailFactory(config *HermesGeneratorConfig) UserEmailFactory {
 }
 
 // PhoneDead is the email sent to a user when their phone is dead
-func (factory *hermesUserEmailFactory) PhoneDead(user *entities.User, lastHeartbeatTimestamp time.Time, owner string) (*Email, error) {
+func (factory *hermesUserEmailFactory) PhoneDead(user *entities.User, lastHeartbeatTimestamp time.Time, owner string, threshold time.Duration) (*Email, error) {
 	location, err := time.LoadLocation(user.Timezone)
 	if err != nil {
 		location = time.UTC
@@ -124,6 +263,7 @@ func (factory *hermesUserEmailFactory) PhoneDead(user *entities.User, lastHeartb
 		Body: hermes.Body{
 			Intros: []string{
 				fmt.Sprintf("We haven't received any heartbeat event from android  phone %s since %s.", factory.formatPhoneNumber(owner), lastHeartbeatTimestamp.In(location).Format(time.RFC1123)),
+				fmt.Sprintf("A phone is considered offline when it doesn't send a heartbeat for %s.", threshold.String()),
 				fmt.Sprintf("Check if the mobile phone is powered on and if it has stable internet connection."),
 			},
 			Actions: []hermes.Action{
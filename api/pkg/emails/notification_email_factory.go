@@ -1,18 +1,19 @@
 package emails
 
 import (
+	"time"
+
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/events"
-	"github.com/google/uuid"
 )
 
 // NotificationEmailFactory generates emails to users about a message
 type NotificationEmailFactory interface {
-	// MessageExpired sends an email when the user's message is expired
-	MessageExpired(user *entities.User, messageID uuid.UUID, owner, contact, content string) (*Email, error)
+	// MessageDigest sends an email with the messages of a phone which failed or expired since openedAt
+	MessageDigest(user *entities.User, owner string, openedAt time.Time, messages []*entities.NotificationDigestMessage) (*Email, error)
 
-	// MessageFailed sends an email when the user's message is failed
-	MessageFailed(user *entities.User, messageID uuid.UUID, owner, contact, content, reason string) (*Email, error)
+	// SummaryReport sends an email with the summary of the messages of a user in the period of the report
+	SummaryReport(user *entities.User, report *entities.SummaryReport) (*Email, error)
 
 	// DiscordSendFailed sends an email when the user's discord message is failed
 	DiscordSendFailed(user *entities.User, payload *events.DiscordSendFailedPayload) (*Email, error)
@@ -9,7 +9,16 @@ import (
 // UserEmailFactory generates emails to a user
 type UserEmailFactory interface {
 	// PhoneDead sends an emails when the user's phone is not sending heartbeats
-	PhoneDead(user *entities.User, lastHeartbeatTimestamp time.Time, owner string) (*Email, error)
+	PhoneDead(user *entities.User, lastHeartbeatTimestamp time.Time, owner string, threshold time.Duration) (*Email, error)
+
+	// PhoneAlive sends an email when the user's phone starts sending heartbeats again
+	PhoneAlive(user *entities.User, lastHeartbeatTimestamp time.Time, owner string) (*Email, error)
+
+	// PhoneBatteryLow sends an email when the battery of the user's phone is low
+	PhoneBatteryLow(user *entities.User, owner string, batteryLevel uint) (*Email, error)
+
+	// PhoneClockSkewed sends an email when the clock of the user's phone is wrong
+	PhoneClockSkewed(user *entities.User, owner string, skew time.Duration) (*Email, error)
 
 	// UsageLimitExceeded sends an email when the user's limit is exceeded
 	UsageLimitExceeded(user *entities.User) (*Email, error)
@@ -0,0 +1,100 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AccountExportVersion is the version of the format of the account export bundles which are created by this API
+const AccountExportVersion = 1
+
+// AccountExportRecordType is the type of the record on a line of an account export bundle
+type AccountExportRecordType string
+
+const (
+	// AccountExportRecordTypeHeader is the first line of a bundle, its data is an AccountExportHeader
+	AccountExportRecordTypeHeader = AccountExportRecordType("header")
+
+	// AccountExportRecordTypeManifest is the last line of a bundle, its data is an AccountExportManifest
+	AccountExportRecordTypeManifest = AccountExportRecordType("manifest")
+
+	// AccountExportRecordTypeUser is the User who owns the account
+	AccountExportRecordTypeUser = AccountExportRecordType("user")
+
+	// AccountExportRecordTypePhone is a Phone of the account
+	AccountExportRecordTypePhone = AccountExportRecordType("phone")
+
+	// AccountExportRecordTypePhoneSIM is a PhoneSIM of a phone
+	AccountExportRecordTypePhoneSIM = AccountExportRecordType("phone_sim")
+
+	// AccountExportRecordTypeWebhook is a Webhook of the account
+	AccountExportRecordTypeWebhook = AccountExportRecordType("webhook")
+
+	// AccountExportRecordTypeContact is a Contact of the account
+	AccountExportRecordTypeContact = AccountExportRecordType("contact")
+
+	// AccountExportRecordTypeContactLabel is a ContactLabel of a contact
+	AccountExportRecordTypeContactLabel = AccountExportRecordType("contact_label")
+
+	// AccountExportRecordTypeContactGroup is a ContactGroup of the account
+	AccountExportRecordTypeContactGroup = AccountExportRecordType("contact_group")
+
+	// AccountExportRecordTypeContactGroupMember is a ContactGroupMember of a contact group
+	AccountExportRecordTypeContactGroupMember = AccountExportRecordType("contact_group_member")
+
+	// AccountExportRecordTypeMessageThread is a MessageThread of the account
+	AccountExportRecordTypeMessageThread = AccountExportRecordType("message_thread")
+
+	// AccountExportRecordTypeMessage is a Message of the account
+	AccountExportRecordTypeMessage = AccountExportRecordType("message")
+)
+
+// AccountExportRecordTypes are the types of the records of an account in the order in which they are exported and imported
+var AccountExportRecordTypes = []AccountExportRecordType{
+	AccountExportRecordTypeUser,
+	AccountExportRecordTypePhone,
+	AccountExportRecordTypePhoneSIM,
+	AccountExportRecordTypeWebhook,
+	AccountExportRecordTypeContact,
+	AccountExportRecordTypeContactLabel,
+	AccountExportRecordTypeContactGroup,
+	AccountExportRecordTypeContactGroupMember,
+	AccountExportRecordTypeMessageThread,
+	AccountExportRecordTypeMessage,
+}
+
+// AccountExportRecord is a line of an account export bundle which is gzip compressed NDJSON
+type AccountExportRecord struct {
+	Type AccountExportRecordType `json:"type"`
+	Data json.RawMessage         `json:"data"`
+}
+
+// AccountExportHeader is the first line of an account export bundle
+type AccountExportHeader struct {
+	Version   int       `json:"version" example:"1"`
+	UserID    UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+}
+
+// AccountExportSection is the number of records of a type in an account export bundle and the hex encoded SHA-256
+// checksum of the data of the records, each followed by a new line
+type AccountExportSection struct {
+	Count    int    `json:"count" example:"1000"`
+	Checksum string `json:"checksum" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// AccountExportManifest is the last line of an account export bundle, a bundle without a manifest is incomplete
+type AccountExportManifest struct {
+	Version   int                                              `json:"version" example:"1"`
+	UserID    UserID                                           `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	CreatedAt time.Time                                        `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	Sections  map[AccountExportRecordType]AccountExportSection `json:"sections"`
+}
+
+// AccountImportResult is the number of records of each type which were restored from an account export bundle.
+// The records whose ID already exists are skipped.
+type AccountImportResult struct {
+	UserID   UserID                          `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Imported map[AccountExportRecordType]int `json:"imported"`
+	Skipped  map[AccountExportRecordType]int `json:"skipped"`
+}
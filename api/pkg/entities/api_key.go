@@ -0,0 +1,101 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIKeyScope is a permission granted to an APIKey
+type APIKeyScope string
+
+const (
+	// APIKeyScopeMessagesRead allows fetching messages and message threads
+	APIKeyScopeMessagesRead = APIKeyScope("messages:read")
+
+	// APIKeyScopeMessagesSend allows sending messages
+	APIKeyScopeMessagesSend = APIKeyScope("messages:send")
+
+	// APIKeyScopeMessagesWrite allows updating and deleting messages and message threads
+	APIKeyScopeMessagesWrite = APIKeyScope("messages:write")
+
+	// APIKeyScopePhonesRead allows fetching phones and heartbeats
+	APIKeyScopePhonesRead = APIKeyScope("phones:read")
+
+	// APIKeyScopePhonesWrite allows updating phones and sending events from the android app
+	APIKeyScopePhonesWrite = APIKeyScope("phones:write")
+
+	// APIKeyScopeWebhooksRead allows fetching webhooks
+	APIKeyScopeWebhooksRead = APIKeyScope("webhooks:read")
+
+	// APIKeyScopeWebhooksWrite allows creating, updating and deleting webhooks
+	APIKeyScopeWebhooksWrite = APIKeyScope("webhooks:write")
+
+	// APIKeyScopeEventsWrite allows other systems to send cloud events to the events receiver
+	APIKeyScopeEventsWrite = APIKeyScope("events:write")
+
+	// APIKeyScopeAdmin allows an administrator to list and suspend users
+	APIKeyScopeAdmin = APIKeyScope("admin")
+)
+
+// String converts the APIKeyScope to a string
+func (scope APIKeyScope) String() string {
+	return string(scope)
+}
+
+// APIKeyScopes returns all the scopes which can be granted to an APIKey
+func APIKeyScopes() []APIKeyScope {
+	return []APIKeyScope{
+		APIKeyScopeMessagesRead,
+		APIKeyScopeMessagesSend,
+		APIKeyScopeMessagesWrite,
+		APIKeyScopePhonesRead,
+		APIKeyScopePhonesWrite,
+		APIKeyScopeWebhooksRead,
+		APIKeyScopeWebhooksWrite,
+		APIKeyScopeEventsWrite,
+		APIKeyScopeAdmin,
+	}
+}
+
+// APIKey is an additional API key of a user which is restricted to a set of scopes
+type APIKey struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"index:idx_api_keys_user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Label  string    `json:"label" example:"BI team"`
+	// Key is only returned once when the API key is created, only the hash of the key is stored
+	Key        string         `json:"key,omitempty" gorm:"-" example:"hsk_pDRKJfMnTaUAdkrBZHLESiGSzaJvhPvWD6zu3Ud8LJG9TZzY"`
+	KeyHash    string         `json:"-" gorm:"uniqueIndex:idx_api_keys_key_hash"`
+	KeyPrefix  string         `json:"key_prefix" example:"hsk_pDRK"`
+	Scopes     pq.StringArray `json:"scopes" example:"[messages:read]" gorm:"type:text[]" swaggertype:"array,string"`
+	LastUsedAt *time.Time     `json:"last_used_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	// LastUsedIPAddress and LastUsedUserAgent identify the caller which last used the APIKey
+	LastUsedIPAddress *string    `json:"last_used_ip_address" example:"203.0.113.10"`
+	LastUsedUserAgent *string    `json:"last_used_user_agent" example:"python-requests/2.31.0"`
+	RevokedAt         *time.Time `json:"revoked_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	CreatedAt         time.Time  `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt         time.Time  `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// IsRevoked checks if the APIKey can no longer be used
+func (key *APIKey) IsRevoked() bool {
+	return key.RevokedAt != nil
+}
+
+// ScopeList returns the scopes of the APIKey
+func (key *APIKey) ScopeList() []APIKeyScope {
+	scopes := make([]APIKeyScope, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, APIKeyScope(scope))
+	}
+	return scopes
+}
+
+// HashAPIKey returns the hash of an API key which is stored in the database
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
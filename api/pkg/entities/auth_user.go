@@ -1,12 +1,65 @@
 package entities
 
+import "github.com/google/uuid"
+
 // AuthUser is the user gotten from an auth request
 type AuthUser struct {
-	ID    UserID `json:"id"`
-	Email string `json:"email"`
+	ID      UserID `json:"id"`
+	Email   string `json:"email"`
+	IsAdmin bool   `json:"is_admin,omitempty"`
+
+	// APIKeyID is the ID of the APIKey used to authenticate the request, it is nil for the primary API key of the user
+	APIKeyID *uuid.UUID    `json:"api_key_id,omitempty"`
+	Scopes   []APIKeyScope `json:"scopes,omitempty"`
+
+	// ActorID is the ID of the user who made the request when acting in the Team with ID, it is nil in the personal team of the user
+	ActorID  *UserID  `json:"actor_id,omitempty"`
+	TeamRole TeamRole `json:"team_role,omitempty"`
 }
 
 // IsNoop checks if a user is empty
 func (user AuthUser) IsNoop() bool {
 	return user.ID == "" || user.Email == ""
 }
+
+// IsScoped checks if the user is authenticated with an APIKey which is restricted to a set of scopes
+func (user AuthUser) IsScoped() bool {
+	return user.APIKeyID != nil
+}
+
+// HasScope checks if the user is allowed to perform actions in the APIKeyScope
+func (user AuthUser) HasScope(scope APIKeyScope) bool {
+	if !user.IsScoped() {
+		return true
+	}
+
+	for _, item := range user.Scopes {
+		if item == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAdminister checks if the user is an administrator who is allowed to manage other users
+func (user AuthUser) CanAdminister() bool {
+	return user.IsAdmin && user.HasScope(APIKeyScopeAdmin)
+}
+
+// IsTeamMember checks if the user is acting in a Team which is not the personal team of the user
+func (user AuthUser) IsTeamMember() bool {
+	return user.ActorID != nil
+}
+
+// IsTeamAdmin checks if the user can carry out destructive operations in the acting Team
+func (user AuthUser) IsTeamAdmin() bool {
+	return !user.IsTeamMember() || user.TeamRole == TeamRoleAdmin
+}
+
+// ActingUserID returns the ID of the user who made the request
+func (user AuthUser) ActingUserID() UserID {
+	if user.ActorID != nil {
+		return *user.ActorID
+	}
+	return user.ID
+}
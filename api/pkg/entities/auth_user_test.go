@@ -0,0 +1,148 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthUser_HasScope(t *testing.T) {
+	t.Run("primary api key has all scopes", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "name@email.com"}
+
+		// Act
+		hasScope := user.HasScope(APIKeyScopeMessagesSend)
+
+		// Assert
+		assert.True(t, hasScope)
+	})
+
+	t.Run("scoped api key has the granted scope", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		apiKeyID := uuid.New()
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", APIKeyID: &apiKeyID, Scopes: []APIKeyScope{APIKeyScopeMessagesRead}}
+
+		// Act
+		hasScope := user.HasScope(APIKeyScopeMessagesRead)
+
+		// Assert
+		assert.True(t, hasScope)
+	})
+
+	t.Run("scoped api key does not have a scope which is not granted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		apiKeyID := uuid.New()
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", APIKeyID: &apiKeyID, Scopes: []APIKeyScope{APIKeyScopeMessagesRead}}
+
+		// Act
+		hasScope := user.HasScope(APIKeyScopeMessagesSend)
+
+		// Assert
+		assert.False(t, hasScope)
+	})
+}
+
+func TestAuthUser_CanAdminister(t *testing.T) {
+	t.Run("admin with the primary api key can administer", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "name@email.com", IsAdmin: true}
+
+		// Act
+		canAdminister := user.CanAdminister()
+
+		// Assert
+		assert.True(t, canAdminister)
+	})
+
+	t.Run("admin with a scoped api key without the admin scope cannot administer", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		apiKeyID := uuid.New()
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", IsAdmin: true, APIKeyID: &apiKeyID, Scopes: []APIKeyScope{APIKeyScopeMessagesRead}}
+
+		// Act
+		canAdminister := user.CanAdminister()
+
+		// Assert
+		assert.False(t, canAdminister)
+	})
+
+	t.Run("user who is not an admin cannot administer with the admin scope", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		apiKeyID := uuid.New()
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", APIKeyID: &apiKeyID, Scopes: []APIKeyScope{APIKeyScopeAdmin}}
+
+		// Act
+		canAdminister := user.CanAdminister()
+
+		// Assert
+		assert.False(t, canAdminister)
+	})
+}
+
+func TestAuthUser_IsTeamAdmin(t *testing.T) {
+	t.Run("user is the admin of the personal team", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "name@email.com"}
+
+		// Act
+		isTeamAdmin := user.IsTeamAdmin()
+
+		// Assert
+		assert.True(t, isTeamAdmin)
+		assert.Equal(t, user.ID, user.ActingUserID())
+	})
+
+	t.Run("member of a team is not a team admin", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		actorID := UserID("KfMnTaUAdkrBZHLESiGSzaJvhPvW")
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "member@email.com", ActorID: &actorID, TeamRole: TeamRoleMember}
+
+		// Act
+		isTeamAdmin := user.IsTeamAdmin()
+
+		// Assert
+		assert.False(t, isTeamAdmin)
+		assert.Equal(t, actorID, user.ActingUserID())
+	})
+
+	t.Run("admin of a team is a team admin", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		actorID := UserID("KfMnTaUAdkrBZHLESiGSzaJvhPvW")
+		user := AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "member@email.com", ActorID: &actorID, TeamRole: TeamRoleAdmin}
+
+		// Act
+		isTeamAdmin := user.IsTeamAdmin()
+
+		// Assert
+		assert.True(t, isTeamAdmin)
+	})
+}
@@ -9,16 +9,22 @@ import (
 // BillingUsage tracks the billing usage of an account
 type BillingUsage struct {
 	ID               uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
-	UserID           UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	UserID           UserID    `json:"user_id" gorm:"uniqueIndex:idx_billing_usages_user_id_start_timestamp" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
 	SentMessages     uint      `json:"sent_messages" example:"321"`
 	ReceivedMessages uint      `json:"received_messages" example:"465"`
 	TotalCost        uint      `json:"total_cost" example:"0"`
-	StartTimestamp   time.Time `json:"start_timestamp" example:"2022-01-01T00:00:00+00:00"`
+	StartTimestamp   time.Time `json:"start_timestamp" gorm:"uniqueIndex:idx_billing_usages_user_id_start_timestamp" example:"2022-01-01T00:00:00+00:00"`
 	EndTimestamp     time.Time `json:"end_timestamp" example:"2022-01-31T23:59:59+00:00"`
 	CreatedAt        time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
 	UpdatedAt        time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
 }
 
+// UserUsage is the number of messages sent and received by a user in the current and previous months
+type UserUsage struct {
+	Current  *BillingUsage `json:"current"`
+	Previous *BillingUsage `json:"previous"`
+}
+
 // TotalMessages returns the sum of sent and received messages
 func (usage *BillingUsage) TotalMessages() uint {
 	return usage.SentMessages + usage.ReceivedMessages
@@ -0,0 +1,119 @@
+package entities
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContactSource is how the name of a Contact was set
+type ContactSource string
+
+const (
+	// ContactSourceSynced is a contact from the address book of a phone
+	ContactSourceSynced = ContactSource("synced")
+
+	// ContactSourceManual is a contact which is set with the API, it is never changed by the address book of a phone
+	ContactSourceManual = ContactSource("manual")
+)
+
+// Contact is the display name of a phone number in the address book of a user
+type Contact struct {
+	ID          uuid.UUID     `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID      UserID        `json:"user_id" gorm:"uniqueIndex:idx_contacts_user_id_phone_number" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	PhoneNumber string        `json:"phone_number" gorm:"uniqueIndex:idx_contacts_user_id_phone_number" example:"+18005550100"`
+	Name        string        `json:"name" example:"Jane Doe"`
+	Source      ContactSource `json:"source" example:"synced"`
+
+	// SyncedAt is the time when the entry was changed in the address book of the phone, an entry which is older is ignored
+	SyncedAt *time.Time `json:"synced_at" example:"2022-06-05T14:26:02.302718+03:00"`
+
+	// DNDStart is the time in DNDTimezone e.g. 18:00 after which messages are not sent to the contact until DNDEnd, the
+	// messages are sent at any time when it is empty
+	DNDStart    string `json:"dnd_start" example:"18:00"`
+	DNDEnd      string `json:"dnd_end" example:"09:00"`
+	DNDTimezone string `json:"dnd_timezone" example:"Europe/Berlin"`
+
+	// Notes is free text about the contact e.g. "prefers French", NotesUpdatedBy is the user who last changed it
+	Notes          string     `json:"notes" example:"prefers French"`
+	NotesUpdatedBy *UserID    `json:"notes_updated_by" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	NotesUpdatedAt *time.Time `json:"notes_updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+
+	// Labels are stored as ContactLabel rows so that the contacts with a label can be found and renamed at once
+	Labels []string `json:"labels" gorm:"-" example:"vip,french"`
+
+	// DeletedAt is set when the entry is deleted from the address book of the phone. The contact is kept as a tombstone
+	// so that an older entry of the same number which is synced later does not add it again.
+	DeletedAt *time.Time `json:"-"`
+
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// IsDeleted checks if the Contact is a tombstone of an entry which was deleted from the address book
+func (contact *Contact) IsDeleted() bool {
+	return contact.DeletedAt != nil
+}
+
+// HasDND checks if the Contact has a do-not-disturb window
+func (contact *Contact) HasDND() bool {
+	return contact.DNDStart != "" && contact.DNDEnd != ""
+}
+
+// DNDUntil returns the end of the do-not-disturb window of the Contact when now is in the window
+func (contact *Contact) DNDUntil(now time.Time) (time.Time, bool) {
+	if !contact.HasDND() {
+		return now, false
+	}
+	return quietUntil(contact.DNDStart, contact.DNDEnd, contact.DNDTimezone, now)
+}
+
+// SetNotes sets the Notes of the Contact and the user who changed them
+func (contact *Contact) SetNotes(notes string, userID UserID, timestamp time.Time) *Contact {
+	contact.Notes = notes
+	contact.NotesUpdatedBy = &userID
+	contact.NotesUpdatedAt = &timestamp
+	return contact
+}
+
+// ContactLabel is a label e.g. "vip" on a Contact
+type ContactLabel struct {
+	ContactID uuid.UUID `gorm:"primaryKey;type:uuid;"`
+	Label     string    `gorm:"primaryKey;index:idx_contact_labels_user_id_label,priority:2"`
+	UserID    UserID    `gorm:"index:idx_contact_labels_user_id_label,priority:1"`
+	CreatedAt time.Time
+}
+
+// NormalizeContactLabels trims and lowercases labels and removes the empty and duplicate labels, the labels are sorted
+func NormalizeContactLabels(labels []string) []string {
+	result := make([]string, 0, len(labels))
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		result = append(result, label)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ContactLabelRenameResult is the result of renaming a label on the contacts of a user
+type ContactLabelRenameResult struct {
+	// Contacts is the number of contacts which had the label
+	Contacts int `json:"contacts" example:"12"`
+}
+
+// ContactSyncResult is the result of syncing a batch of the address book of a phone
+type ContactSyncResult struct {
+	// Synced is the number of entries which changed a contact
+	Synced int `json:"synced" example:"98"`
+
+	// Skipped is the number of entries which do not have a valid phone number, which are older than the contact or
+	// which have the number of a manual contact
+	Skipped int `json:"skipped" example:"2"`
+}
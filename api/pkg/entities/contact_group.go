@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContactGroup is a named list of the contacts of a user e.g. "all drivers" which messages can be sent to at once
+type ContactGroup struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID    UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Name      string    `json:"name" example:"All Drivers"`
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// ContactGroupMember is a Contact which is in a ContactGroup
+type ContactGroupMember struct {
+	ContactGroupID uuid.UUID `gorm:"primaryKey;type:uuid;"`
+	ContactID      uuid.UUID `gorm:"primaryKey;type:uuid;index"`
+	UserID         UserID    `gorm:"index"`
+	CreatedAt      time.Time
+}
+
+// ContactGroupMemberResult is the result of adding contacts to a ContactGroup
+type ContactGroupMemberResult struct {
+	// Added is the number of contacts which were not in the group before
+	Added int `json:"added" example:"2"`
+}
+
+// ContactGroupSendError is a member of a ContactGroup which a message could not be sent to
+type ContactGroupSendError struct {
+	ContactID   uuid.UUID `json:"contact_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	PhoneNumber string    `json:"phone_number" example:"+18005550100"`
+	Error       string    `json:"error" example:"cannot send the message to the contact"`
+}
+
+// ContactGroupSendResult is the summary of sending a message to the members of a ContactGroup
+type ContactGroupSendResult struct {
+	ContactGroupID uuid.UUID `json:"contact_group_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+
+	// RequestID is set on all the messages which are sent to the group
+	RequestID string `json:"request_id" example:"group-32343a19-da5e-4b1b-a767-3298a73703cb"`
+
+	// Total is the number of members of the group when the messages were sent
+	Total    int                     `json:"total" example:"3"`
+	Sent     int                     `json:"sent" example:"2"`
+	Failed   int                     `json:"failed" example:"1"`
+	Messages []*Message              `json:"messages"`
+	Errors   []ContactGroupSendError `json:"errors"`
+}
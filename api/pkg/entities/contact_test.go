@@ -0,0 +1,83 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContact_DNDUntil(t *testing.T) {
+	contact := &Contact{DNDStart: "18:00", DNDEnd: "09:00", DNDTimezone: "Europe/Berlin"}
+
+	t.Run("evening in the timezone of the contact is deferred until the next morning", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 20, 30, 0, 0, time.UTC)
+
+		// Act
+		until, isDND := contact.DNDUntil(now)
+
+		// Assert
+		assert.True(t, isDND)
+		assert.Equal(t, time.Date(2022, 6, 6, 7, 0, 0, 0, time.UTC), until)
+	})
+
+	t.Run("time after midnight in the timezone of the contact is deferred until the same morning", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 23, 0, 0, 0, time.UTC)
+
+		// Act
+		until, isDND := contact.DNDUntil(now)
+
+		// Assert
+		assert.True(t, isDND)
+		assert.Equal(t, time.Date(2022, 6, 6, 7, 0, 0, 0, time.UTC), until)
+	})
+
+	t.Run("working hours in the timezone of the contact are not deferred", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 8, 0, 0, 0, time.UTC)
+
+		// Act
+		_, isDND := contact.DNDUntil(now)
+
+		// Assert
+		assert.False(t, isDND)
+	})
+
+	t.Run("contact without a window is not deferred", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 20, 30, 0, 0, time.UTC)
+
+		// Act
+		_, isDND := (&Contact{}).DNDUntil(now)
+
+		// Assert
+		assert.False(t, isDND)
+	})
+}
+
+func TestNormalizeContactLabels(t *testing.T) {
+	t.Run("labels are lowercase without duplicates", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		labels := NormalizeContactLabels([]string{" VIP ", "french", "vip", "", "French"})
+
+		// Assert
+		assert.Equal(t, []string{"french", "vip"}, labels)
+	})
+}
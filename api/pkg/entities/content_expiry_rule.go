@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ContentExpiryPatternCode is the Pattern of a ContentExpiryRule which matches a verification code of 4 to 8 digits
+const ContentExpiryPatternCode = `\b\d{4,8}\b`
+
+// MessageContentExpiredMarker replaces the content of a message after the content has expired
+const MessageContentExpiredMarker = "[content expired]"
+
+// ContentExpiryRule removes the content of the messages which are received by the phone with the Owner number e.g.
+// verification codes after the TTL. The message is kept for the history but its content is replaced with the
+// MessageContentExpiredMarker.
+type ContentExpiryRule struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Owner  string    `json:"owner" example:"+18005550199"`
+	// Pattern is the regular expression which matches the content of a message, it is empty to match every content
+	Pattern string `json:"pattern" example:"\\b\\d{4,8}\\b"`
+	// Contacts are the phone numbers or sender IDs whose messages expire, it is empty to match every contact
+	Contacts   []string  `json:"contacts" gorm:"serializer:json" example:"+18005550100"`
+	TTLSeconds uint      `json:"ttl_seconds" example:"3600"`
+	CreatedAt  time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// TTL is the duration after which the content of a message which matches the ContentExpiryRule expires
+func (rule *ContentExpiryRule) TTL() time.Duration {
+	return time.Duration(rule.TTLSeconds) * time.Second
+}
+
+// Matches is true when a message which is received by the Owner matches the Pattern and the Contacts of the
+// ContentExpiryRule, the Pattern is not used for encrypted messages because their content is ciphertext
+func (rule *ContentExpiryRule) Matches(message *Message) bool {
+	if message.Type != MessageTypeMobileOriginated || message.Owner != rule.Owner {
+		return false
+	}
+
+	if len(rule.Contacts) > 0 && !rule.hasContact(message.Contact) {
+		return false
+	}
+
+	if rule.Pattern == "" {
+		return true
+	}
+
+	pattern, err := regexp.Compile(rule.Pattern)
+	if err != nil || message.IsEncrypted {
+		return false
+	}
+	return pattern.MatchString(message.Content)
+}
+
+func (rule *ContentExpiryRule) hasContact(contact string) bool {
+	for _, item := range rule.Contacts {
+		if item == contact {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchContentExpiryRule returns the ContentExpiryRule with the shortest TTL which matches a message, it is nil when no rule matches
+func MatchContentExpiryRule(rules []*ContentExpiryRule, message *Message) *ContentExpiryRule {
+	var match *ContentExpiryRule
+	for _, rule := range rules {
+		if rule.Matches(message) && (match == nil || rule.TTLSeconds < match.TTLSeconds) {
+			match = rule
+		}
+	}
+	return match
+}
+
+// ContentExpiryMatch is the ContentExpiryRule which matches a message and the time when the content of the message expires
+type ContentExpiryMatch struct {
+	// Rule is the ContentExpiryRule with the shortest TTL which matches the message, it is nil when no rule matches
+	Rule             *ContentExpiryRule `json:"rule"`
+	ContentExpiresAt *time.Time         `json:"content_expires_at" example:"2022-06-05T15:26:02.302718+03:00"`
+}
@@ -0,0 +1,88 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentExpiryRule_Matches(t *testing.T) {
+	t.Run("received message with a verification code matches the pattern", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rule := &ContentExpiryRule{Owner: "+18005550199", Pattern: ContentExpiryPatternCode}
+		message := &Message{Owner: "+18005550199", Contact: "Google", Type: MessageTypeMobileOriginated, Content: "G-123456 is your Google verification code"}
+
+		// Act
+		matches := rule.Matches(message)
+
+		// Assert
+		assert.True(t, matches)
+	})
+
+	t.Run("message from another contact does not match", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rule := &ContentExpiryRule{Owner: "+18005550199", Contacts: []string{"Google"}}
+		message := &Message{Owner: "+18005550199", Contact: "+18005550100", Type: MessageTypeMobileOriginated, Content: "hello"}
+
+		// Act
+		matches := rule.Matches(message)
+
+		// Assert
+		assert.False(t, matches)
+	})
+
+	t.Run("sent message does not match", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rule := &ContentExpiryRule{Owner: "+18005550199", Contacts: []string{"+18005550100"}}
+		message := &Message{Owner: "+18005550199", Contact: "+18005550100", Type: MessageTypeMobileTerminated, Content: "1234"}
+
+		// Act
+		matches := rule.Matches(message)
+
+		// Assert
+		assert.False(t, matches)
+	})
+
+	t.Run("encrypted message does not match the pattern", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rule := &ContentExpiryRule{Owner: "+18005550199", Pattern: ".*"}
+		message := &Message{Owner: "+18005550199", Contact: "Google", Type: MessageTypeMobileOriginated, Content: "ciphertext", IsEncrypted: true}
+
+		// Act
+		matches := rule.Matches(message)
+
+		// Assert
+		assert.False(t, matches)
+	})
+}
+
+func TestMatchContentExpiryRule(t *testing.T) {
+	// Setup
+	t.Parallel()
+
+	// Arrange
+	rules := []*ContentExpiryRule{
+		{Owner: "+18005550199", Pattern: ContentExpiryPatternCode, TTLSeconds: 3600},
+		{Owner: "+18005550199", Contacts: []string{"Google"}, TTLSeconds: 600},
+		{Owner: "+18005550199", Contacts: []string{"Amazon"}, TTLSeconds: 60},
+	}
+	message := &Message{Owner: "+18005550199", Contact: "Google", Type: MessageTypeMobileOriginated, Content: "G-123456 is your Google verification code"}
+
+	// Act
+	rule := MatchContentExpiryRule(rules, message)
+
+	// Assert
+	assert.Equal(t, rules[1], rule)
+}
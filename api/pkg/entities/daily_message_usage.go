@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DailyMessageUsage counts the messages sent by a user in a day, it is used to enforce User.DailyMessageLimit
+type DailyMessageUsage struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"uniqueIndex:idx_daily_message_usages_user_id_day" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	// Day is the midnight timestamp of the day in the timezone used for the daily message limit
+	Day          time.Time `json:"day" gorm:"uniqueIndex:idx_daily_message_usages_user_id_day" example:"2022-06-05T00:00:00+03:00"`
+	SentMessages uint      `json:"sent_messages" example:"321"`
+	CreatedAt    time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt    time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// DailyMessageUsageDay returns the midnight timestamp of the day of the timestamp in the location
+func DailyMessageUsageDay(timestamp time.Time, location *time.Location) time.Time {
+	timestamp = timestamp.In(location)
+	return time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, location)
+}
+
+// DailyMessageUsageResetAt returns the timestamp when the daily message usage of the timestamp rolls over
+func DailyMessageUsageResetAt(timestamp time.Time, location *time.Location) time.Time {
+	day := DailyMessageUsageDay(timestamp, location)
+	return time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, location)
+}
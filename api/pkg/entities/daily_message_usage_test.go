@@ -0,0 +1,57 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyMessageUsageDay(t *testing.T) {
+	t.Run("day starts at midnight in the location", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		location, _ := time.LoadLocation("America/New_York")
+		timestamp := time.Date(2022, 6, 5, 2, 30, 0, 0, time.UTC)
+
+		// Act
+		day := DailyMessageUsageDay(timestamp, location)
+
+		// Assert
+		assert.Equal(t, time.Date(2022, 6, 4, 0, 0, 0, 0, location), day)
+	})
+}
+
+func TestDailyMessageUsageResetAt(t *testing.T) {
+	t.Run("usage resets at the next midnight in the location", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		location, _ := time.LoadLocation("America/New_York")
+		timestamp := time.Date(2022, 6, 5, 2, 30, 0, 0, time.UTC)
+
+		// Act
+		resetAt := DailyMessageUsageResetAt(timestamp, location)
+
+		// Assert
+		assert.Equal(t, time.Date(2022, 6, 5, 4, 0, 0, 0, time.UTC), resetAt.UTC())
+	})
+
+	t.Run("usage resets at midnight when the clocks change", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		location, _ := time.LoadLocation("America/New_York")
+		timestamp := time.Date(2022, 3, 13, 12, 0, 0, 0, location)
+
+		// Act
+		resetAt := DailyMessageUsageResetAt(timestamp, location)
+
+		// Assert
+		assert.Equal(t, time.Date(2022, 3, 14, 4, 0, 0, 0, time.UTC), resetAt.UTC())
+	})
+}
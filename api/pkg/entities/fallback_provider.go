@@ -0,0 +1,64 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FallbackProviderType is the external SMS provider which sends the messages of a phone which cannot send them
+type FallbackProviderType string
+
+const (
+	// FallbackProviderTypeTwilio sends the messages with the Twilio REST API
+	FallbackProviderTypeTwilio = FallbackProviderType("twilio")
+
+	// FallbackProviderTypeHTTP sends the messages with a signed JSON request to an HTTP endpoint
+	FallbackProviderTypeHTTP = FallbackProviderType("http")
+)
+
+// FallbackProvider is the external SMS provider of a user which sends the messages that the phone of the user cannot
+// send. Only the messages which are sent with allow_fallback are sent by the provider.
+type FallbackProvider struct {
+	ID     uuid.UUID            `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID               `json:"user_id" gorm:"uniqueIndex" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Type   FallbackProviderType `json:"type" example:"twilio"`
+
+	// From is the phone number of the provider which sends the messages
+	From string `json:"from" example:"+18005550199"`
+
+	// AccountSID is the account SID of a twilio provider
+	AccountSID string `json:"account_sid" example:"AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1"`
+
+	// AuthToken is the auth token of a twilio provider, it is never returned by the API
+	AuthToken string `json:"-"`
+
+	// URL is the endpoint of an http provider
+	URL string `json:"url" example:"https://example.com/sms"`
+
+	// SigningKey signs the requests to an http provider and verifies its status callbacks, it is never returned by the API
+	SigningKey string `json:"-"`
+
+	// OfflineAfterSeconds is the number of seconds without a heartbeat after which the new messages of a phone are
+	// sent by the provider, the messages are not sent by the provider when a phone is offline when it is 0
+	OfflineAfterSeconds uint `json:"offline_after_seconds" example:"900"`
+
+	// FallbackOnExpired sends a message with the provider when it expires after the last send attempt of the phone
+	FallbackOnExpired bool `json:"fallback_on_expired" example:"true"`
+
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// Channel is the MessageChannel of the messages which are sent by the FallbackProvider
+func (provider *FallbackProvider) Channel() MessageChannel {
+	return MessageChannel(provider.Type)
+}
+
+// IsOffline checks if a phone whose last heartbeat was received at lastHeartbeatAt has been offline for longer than OfflineAfterSeconds
+func (provider *FallbackProvider) IsOffline(lastHeartbeatAt *time.Time, now time.Time) bool {
+	if provider.OfflineAfterSeconds == 0 {
+		return false
+	}
+	return lastHeartbeatAt == nil || now.Sub(*lastHeartbeatAt) > time.Duration(provider.OfflineAfterSeconds)*time.Second
+}
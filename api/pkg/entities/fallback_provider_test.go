@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackProvider_IsOffline(t *testing.T) {
+	now := time.Date(2022, 6, 5, 14, 26, 0, 0, time.UTC)
+
+	t.Run("phone with a recent heartbeat is not offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := &FallbackProvider{OfflineAfterSeconds: 900}
+		lastHeartbeatAt := now.Add(-10 * time.Minute)
+
+		// Act
+		isOffline := provider.IsOffline(&lastHeartbeatAt, now)
+
+		// Assert
+		assert.False(t, isOffline)
+	})
+
+	t.Run("phone without a heartbeat for longer than the threshold is offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := &FallbackProvider{OfflineAfterSeconds: 900}
+		lastHeartbeatAt := now.Add(-15*time.Minute - time.Second)
+
+		// Act
+		isOffline := provider.IsOffline(&lastHeartbeatAt, now)
+
+		// Assert
+		assert.True(t, isOffline)
+	})
+
+	t.Run("phone which has never sent a heartbeat is offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := &FallbackProvider{OfflineAfterSeconds: 900}
+
+		// Act
+		isOffline := provider.IsOffline(nil, now)
+
+		// Assert
+		assert.True(t, isOffline)
+	})
+
+	t.Run("phone is never offline when the threshold is not set", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := &FallbackProvider{}
+
+		// Act
+		isOffline := provider.IsOffline(nil, now)
+
+		// Assert
+		assert.False(t, isOffline)
+	})
+}
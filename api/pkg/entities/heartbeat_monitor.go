@@ -8,16 +8,26 @@ import (
 
 // HeartbeatMonitor is used to monitor heartbeats of a phone
 type HeartbeatMonitor struct {
-	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
-	PhoneID   uuid.UUID `json:"phone_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
-	UserID    UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
-	QueueID   string    `json:"queue_id" example:"0360259236613675274"`
-	Owner     string    `json:"owner" example:"+18005550199"`
-	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
-	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+	ID          uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	PhoneID     uuid.UUID `json:"phone_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID      UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	QueueID     string    `json:"queue_id" example:"0360259236613675274"`
+	Owner       string    `json:"owner" example:"+18005550199"`
+	PhoneOnline bool      `json:"phone_online" gorm:"default:true" example:"true"`
+	// ConsecutiveMisses is the number of consecutive checks in which the last heartbeat was older than the dead threshold
+	ConsecutiveMisses uint `json:"consecutive_misses" gorm:"default:0" example:"0"`
+	// LastAlertedAt is the time when the phone was last declared offline
+	LastAlertedAt *time.Time `json:"last_alerted_at" example:"2022-06-05T14:26:10.303278+03:00"`
+	CreatedAt     time.Time  `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt     time.Time  `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
 }
 
 // RequiresCheck returns true if the heartbeat monitor requires a check
 func (h *HeartbeatMonitor) RequiresCheck() bool {
 	return h.UpdatedAt.Add(2 * time.Hour).Before(time.Now())
 }
+
+// CanAlert returns true if the phone was not declared offline within the cooldown
+func (h *HeartbeatMonitor) CanAlert(cooldown time.Duration, now time.Time) bool {
+	return h.LastAlertedAt == nil || now.Sub(*h.LastAlertedAt) >= cooldown
+}
@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeatMonitor_CanAlert(t *testing.T) {
+	now := time.Date(2022, 6, 5, 14, 26, 0, 0, time.UTC)
+
+	t.Run("monitor which has never alerted can alert", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		monitor := &HeartbeatMonitor{}
+
+		// Act
+		canAlert := monitor.CanAlert(time.Hour, now)
+
+		// Assert
+		assert.True(t, canAlert)
+	})
+
+	t.Run("monitor cannot alert within the cooldown", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		alertedAt := now.Add(-30 * time.Minute)
+		monitor := &HeartbeatMonitor{LastAlertedAt: &alertedAt}
+
+		// Act
+		canAlert := monitor.CanAlert(time.Hour, now)
+
+		// Assert
+		assert.False(t, canAlert)
+	})
+
+	t.Run("monitor can alert after the cooldown", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		alertedAt := now.Add(-61 * time.Minute)
+		monitor := &HeartbeatMonitor{LastAlertedAt: &alertedAt}
+
+		// Act
+		canAlert := monitor.CanAlert(time.Hour, now)
+
+		// Assert
+		assert.True(t, canAlert)
+	})
+}
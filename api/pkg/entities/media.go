@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MediaMaxPerMessage is the maximum number of Media which can be attached to a message
+const MediaMaxPerMessage = 10
+
+// MediaContentTypes are the content types of the Media which can be sent by the phone in an MMS message
+var MediaContentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"video/mp4",
+	"video/3gpp",
+	"audio/mpeg",
+	"audio/amr",
+}
+
+// IsMediaContentType checks if a content type can be sent in an MMS message
+func IsMediaContentType(contentType string) bool {
+	for _, item := range MediaContentTypes {
+		if item == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Media is a file e.g. an image which is uploaded to the blob store with a signed URL and attached to an outgoing
+// message so that the phone sends it as an MMS message. Media which is not attached to a message is deleted after a TTL.
+type Media struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	// MessageID is the ID of the message which the media is attached to, it is nil until the message is sent
+	MessageID   *uuid.UUID `json:"message_id" gorm:"index;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	ContentType string     `json:"content_type" example:"image/jpeg"`
+	// Size is the maximum number of bytes which can be uploaded
+	Size int64 `json:"size" example:"524288"`
+	// Path is the path of the object in the blob store
+	Path string `json:"-"`
+
+	// UploadURL is the signed URL which uploads the content with a PUT request, it is only set when the media is registered
+	UploadURL *string `json:"upload_url,omitempty" gorm:"-" example:"https://storage.googleapis.com/httpsms/media/32343a19-da5e-4b1b-a767-3298a73703cb?X-Goog-Signature=..."`
+	// DownloadURL is the signed URL which downloads the content, it is only set for the phone which sends the message
+	DownloadURL *string `json:"download_url,omitempty" gorm:"-" example:"https://storage.googleapis.com/httpsms/media/32343a19-da5e-4b1b-a767-3298a73703cb?X-Goog-Signature=..."`
+	// URLExpiresAt is the time after which the UploadURL or the DownloadURL cannot be used
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty" gorm:"-" example:"2022-06-05T14:41:02.302718+03:00"`
+
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// TableName overrides the table name used by Media
+func (Media) TableName() string {
+	return "media"
+}
@@ -1,9 +1,13 @@
 package entities
 
 import (
+	"math"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // MessageType is the type of message if it is incoming or outgoing
@@ -49,6 +53,9 @@ const (
 	MessageStatusDeleted = "deleted"
 )
 
+// MessageFailureReasonPhoneDeleted is the failure reason of outstanding messages which are cancelled when their phone is deleted
+const MessageFailureReasonPhoneDeleted = "PHONE_DELETED"
+
 // MessageEventName is the type of event generated by the mobile phone for a message
 type MessageEventName string
 
@@ -78,6 +85,24 @@ func (s SIM) String() string {
 	return string(s)
 }
 
+// MessageDeliveryTracking determines if the phone requests a delivery report from the carrier when it sends a message
+type MessageDeliveryTracking string
+
+const (
+	// MessageDeliveryTrackingOn means the phone requests a delivery report and the message can be delivered
+	MessageDeliveryTrackingOn = MessageDeliveryTracking("on")
+
+	// MessageDeliveryTrackingOff means the phone does not request a delivery report and the message is not counted as
+	// undelivered when it is sent
+	MessageDeliveryTrackingOff = MessageDeliveryTracking("off")
+)
+
+// MessageChannel is the channel which sends a message, it is the type of the FallbackProvider when the message is not sent by the phone
+type MessageChannel string
+
+// MessageChannelPhone means the message is sent by the android phone of the owner
+const MessageChannelPhone = MessageChannel("phone")
+
 // Message represents a message sent between 2 phone numbers
 type Message struct {
 	ID        uuid.UUID     `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
@@ -89,10 +114,9 @@ type Message struct {
 	Type      MessageType   `json:"type" example:"mobile-terminated"`
 	Status    MessageStatus `json:"status" example:"pending"`
 	// SIM is the SIM card to use to send the message
-	// * SMS1: use the SIM card in slot 1
-	// * SMS2: use the SIM card in slot 2
-	// * DEFAULT: used the default communication SIM card
-	SIM SIM `json:"sim" example:"DEFAULT"`
+	// * SIM1: use the SIM card in slot 1
+	// * SIM2: use the SIM card in slot 2
+	SIM SIM `json:"sim" example:"SIM1"`
 
 	// SendDuration is the number of nanoseconds from when the request was received until when the mobile phone send the message
 	SendDuration *int64 `json:"send_time" example:"133414"`
@@ -113,6 +137,76 @@ type Message struct {
 	MaxSendAttempts         uint       `json:"max_send_attempts" example:"1"`
 	ReceivedAt              *time.Time `json:"received_at" example:"2022-06-05T14:26:09.527976+03:00"`
 	FailureReason           *string    `json:"failure_reason" example:"UNKNOWN"`
+	// OriginalOwner is the phone number which owned the message before it was moved to a failover phone
+	OriginalOwner *string `json:"original_owner" example:"+18005550199"`
+	// RetryBackoffBaseSeconds is the BackoffBaseSeconds of the RetryPolicy which was resolved when the message was created
+	RetryBackoffBaseSeconds uint `json:"retry_backoff_base_seconds" gorm:"default:0" example:"30"`
+	// RetryBackoffCapSeconds is the BackoffCapSeconds of the RetryPolicy which was resolved when the message was created
+	RetryBackoffCapSeconds uint `json:"retry_backoff_cap_seconds" gorm:"default:0" example:"3600"`
+	// RetryBackoffMultiplier is the BackoffMultiplier of the RetryPolicy which was resolved when the message was created
+	RetryBackoffMultiplier float64 `json:"retry_backoff_multiplier" gorm:"default:0" example:"2"`
+	// DeliveryTracking is "off" when the phone does not request a delivery report from the carrier and the lifecycle of
+	// the message ends when it is sent
+	DeliveryTracking MessageDeliveryTracking `json:"delivery_tracking" gorm:"default:on" example:"on"`
+
+	// Channel is "phone" when the message is sent by the android phone or the type of the fallback provider which sent it
+	Channel MessageChannel `json:"channel" gorm:"default:phone" example:"phone"`
+	// AllowFallback is true when the message can be sent by the fallback provider of the user
+	AllowFallback bool `json:"allow_fallback" example:"false"`
+	// ProviderMessageID is the ID of the message in the fallback provider which sent it
+	ProviderMessageID *string `json:"provider_message_id" example:"SM1f0e8ae6ade43cb3c0ce4525424e404f"`
+	// RoutingRuleID is the ID of the RoutingRule which selected the owner of the message when it was sent without an owner
+	RoutingRuleID *uuid.UUID `json:"routing_rule_id" gorm:"type:uuid" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	// ForwardedFromID is the ID of the received message whose content was forwarded in this message
+	ForwardedFromID *uuid.UUID `json:"forwarded_from_id" gorm:"index;type:uuid" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	// ForwardedToID is the ID of the last message which forwarded the content of this received message
+	ForwardedToID *uuid.UUID `json:"forwarded_to_id" gorm:"type:uuid" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	// ThreadID is the ID of the MessageThread when the message was sent as a reply in the thread
+	ThreadID *uuid.UUID `json:"thread_id" gorm:"type:uuid" example:"32343a19-da5e-4b1b-a767-3298a73703ca"`
+	// InReplyToID is the ID of the latest message which was received in the thread when the message was sent as a reply
+	InReplyToID *uuid.UUID `json:"in_reply_to_id" gorm:"type:uuid" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+
+	// IsEncrypted is true when the Content is end-to-end encrypted between the API client and the phone, the server
+	// stores and relays the ciphertext without decrypting it
+	IsEncrypted bool `json:"is_encrypted" gorm:"default:false" example:"false"`
+	// EncryptionKeyID is a hint of the key which the phone uses to decrypt the Content of an encrypted message
+	EncryptionKeyID *string `json:"encryption_key_id" example:"key-2022-06"`
+
+	// ContentExpiresAt is the time when the content of a received message is removed because it matches a ContentExpiryRule
+	ContentExpiresAt *time.Time `json:"content_expires_at" gorm:"index" example:"2022-06-05T15:26:09.527976+03:00"`
+	// ContentExpiredAt is the time when the content of the message was replaced with the MessageContentExpiredMarker
+	ContentExpiredAt *time.Time `json:"content_expired_at" example:"2022-06-05T15:26:10.527976+03:00"`
+
+	// Metadata are the key value pairs which are stored with the message e.g. the SID of a message imported from Twilio
+	Metadata MessageMetadata `json:"metadata" swaggertype:"object,string"`
+	// ImportedAt is the time when the message was imported from the history of another provider, no events are
+	// dispatched for an imported message
+	ImportedAt *time.Time `json:"imported_at" example:"2022-06-05T14:26:09.527976+03:00"`
+
+	// MediaCount is the number of Media attached to the message, the message is sent as an MMS message when it has media
+	MediaCount uint `json:"-" gorm:"default:0"`
+
+	// Segments is the number of SMS segments which are needed to send the content of an outgoing message, it is 0 when the content is encrypted
+	Segments uint `json:"segments" example:"1"`
+	// Cost is the estimated cost of the segments with the MessageRate which applied when the message was sent
+	Cost *float64 `json:"cost" example:"0.0075"`
+	// CostCurrency is the currency of the MessageRate of the Cost
+	CostCurrency *string `json:"cost_currency" example:"USD"`
+
+	// Version is incremented on every update so that concurrent updates of the message are detected
+	Version uint `json:"-" gorm:"not null;default:0"`
+
+	// DeletedAt is set when the message is deleted, a deleted message is excluded from all queries unless they are unscoped
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggertype:"string" example:"2022-06-05T14:26:09.527976+03:00"`
+
+	// Phone is the liveness of the owner's phone, it is omitted when the owner has no registered phone
+	Phone *PhoneLiveness `json:"phone,omitempty" gorm:"-"`
+
+	// ContactName is the name of the contact in the address book of the user, it is omitted when the contact has no name
+	ContactName *string `json:"contact_name,omitempty" gorm:"-" example:"Jane Doe"`
+
+	// Media are the files which are sent with the message as an MMS message, it is omitted when the message has no media
+	Media []*Media `json:"media,omitempty" gorm:"-"`
 }
 
 // IsSending determines if a message is being sent
@@ -120,6 +214,11 @@ func (message *Message) IsSending() bool {
 	return message.Status == MessageStatusSending
 }
 
+// HasMedia checks if the message is sent with media as an MMS message
+func (message *Message) HasMedia() bool {
+	return message.MediaCount > 0
+}
+
 // IsDelivered checks if a message is delivered
 func (message *Message) IsDelivered() bool {
 	return message.Status == MessageStatusDelivered
@@ -135,6 +234,82 @@ func (message *Message) IsScheduled() bool {
 	return message.Status == MessageStatusScheduled
 }
 
+// CanBeUpdated checks if the content of a message can be changed because it has not been fetched by the phone
+func (message *Message) CanBeUpdated() bool {
+	return message.IsPending() || message.IsScheduled()
+}
+
+// CanBeForwarded checks if the content of a message can be forwarded to another contact, only the plaintext content
+// of a received message which has not expired can be forwarded
+func (message *Message) CanBeForwarded() bool {
+	return message.Type == MessageTypeMobileOriginated && !message.IsEncrypted && !message.IsContentExpired()
+}
+
+// ForwardContent is the content of the message which forwards this message, the prefix e.g. "FWD from +18005550100:"
+// is separated from the content by a space
+func (message *Message) ForwardContent(prefix string) string {
+	if prefix = strings.TrimSpace(prefix); prefix == "" {
+		return message.Content
+	}
+	return prefix + " " + message.Content
+}
+
+// UpdateContent changes the content of a message which has not been sent, the Cost is estimated for the new number of
+// segments with the rate of the previous content. The segments of an encrypted content are not counted.
+func (message *Message) UpdateContent(timestamp time.Time, content string) *Message {
+	segments := uint(SegmentCount(content))
+	if message.IsEncrypted {
+		segments = 0
+	}
+	if message.Cost != nil && message.Segments > 0 {
+		cost := RoundCost(*message.Cost / float64(message.Segments) * float64(segments))
+		message.Cost = &cost
+	}
+
+	message.Content = content
+	message.Segments = segments
+	message.UpdatedAt = timestamp
+	return message
+}
+
+// SegmentCount is the number of SMS segments which are needed to send the content, a content with characters outside
+// of the ASCII range is encoded in UCS-2 which has fewer characters per segment.
+func SegmentCount(content string) int {
+	single, multiple := 160, 153
+	for _, character := range content {
+		if character >= utf8.RuneSelf {
+			single, multiple = 70, 67
+			break
+		}
+	}
+
+	length := utf8.RuneCountInString(content)
+	if length <= single {
+		return 1
+	}
+	return int(math.Ceil(float64(length) / float64(multiple)))
+}
+
+// IsContentExpired checks if the content of the message was replaced with the MessageContentExpiredMarker
+func (message *Message) IsContentExpired() bool {
+	return message.ContentExpiredAt != nil
+}
+
+// ExpireContent replaces the content of the message with the MessageContentExpiredMarker, the marker is not encrypted
+func (message *Message) ExpireContent(timestamp time.Time) *Message {
+	message.Content = MessageContentExpiredMarker
+	message.IsEncrypted = false
+	message.EncryptionKeyID = nil
+	message.ContentExpiredAt = &timestamp
+	message.UpdatedAt = timestamp
+	return message
+}
+
+// IsSentByProvider checks if a message was moved from the phone to a fallback provider
+func (message *Message) IsSentByProvider() bool {
+	return message.Channel != "" && message.Channel != MessageChannelPhone
+}
+
 // IsExpired checks if a message is expired
 func (message *Message) IsExpired() bool {
 	return message.Status == MessageStatusExpired
@@ -145,11 +320,57 @@ func (message *Message) CanBeRescheduled() bool {
 	return message.SendAttemptCount < message.MaxSendAttempts
 }
 
+// RetryPolicy is the RetryPolicy which was snapshotted on the message when it was created, a change of the policy of
+// the user does not change the retries of a message which is in flight
+func (message *Message) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:        message.MaxSendAttempts,
+		BackoffBaseSeconds: message.RetryBackoffBaseSeconds,
+		BackoffCapSeconds:  message.RetryBackoffCapSeconds,
+		BackoffMultiplier:  message.RetryBackoffMultiplier,
+	}
+}
+
+// RetryBackoff is the delay before the message is sent to the phone again after its last attempt expired
+func (message *Message) RetryBackoff() time.Duration {
+	return message.RetryPolicy().Backoff(message.SendAttemptCount)
+}
+
 // IsSent determines if a message has been sent
 func (message *Message) IsSent() bool {
 	return message.Status == MessageStatusSent
 }
 
+// messageStatusTransitions are the statuses from which a message can move to a status when an event is received, a
+// message which is not delivered can always fail
+var messageStatusTransitions = map[MessageStatus][]MessageStatus{
+	MessageStatusSent:      {MessageStatusSending, MessageStatusExpired},
+	MessageStatusDelivered: {MessageStatusSent, MessageStatusSending, MessageStatusExpired, MessageStatusScheduled},
+	MessageStatusExpired:   {MessageStatusSending, MessageStatusScheduled},
+}
+
+// CanTransitionTo checks if the status of a message can change to the status
+func (message *Message) CanTransitionTo(status MessageStatus) bool {
+	if status == MessageStatusFailed {
+		return !message.IsDelivered()
+	}
+	for _, previous := range messageStatusTransitions[status] {
+		if message.Status == previous {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatus is the status of a message after an event which changes it to the status, the status is not changed when
+// the transition is not allowed e.g. when the message is sent after it was delivered because the events were out of order
+func (message *Message) NextStatus(status MessageStatus) MessageStatus {
+	if message.CanTransitionTo(status) {
+		return status
+	}
+	return message.Status
+}
+
 // Sent registers a message as sent
 func (message *Message) Sent(timestamp time.Time) *Message {
 	sendDuration := timestamp.UnixNano() - message.RequestReceivedAt.UnixNano()
@@ -183,6 +404,11 @@ func (message *Message) Delivered(timestamp time.Time) *Message {
 	return message
 }
 
+// RequestsDeliveryReport is true when the phone requests a delivery report from the carrier for the message
+func (message *Message) RequestsDeliveryReport() bool {
+	return message.DeliveryTracking != MessageDeliveryTrackingOff
+}
+
 // AddSendAttemptCount increments the send attempt count of a message
 func (message *Message) AddSendAttemptCount() *Message {
 	message.SendAttemptCount++
@@ -218,6 +444,10 @@ func (message *Message) AddSendAttempt(timestamp time.Time) *Message {
 	return message
 }
 
+// MessageOrderTimestampStep is the offset between the OrderTimestamp of the messages which are created in the same
+// request so that they are ordered like in the request, the databases store timestamps with microseconds.
+const MessageOrderTimestampStep = time.Microsecond
+
 func (message *Message) updateOrderTimestamp(timestamp time.Time) {
 	if timestamp.UnixNano() > message.OrderTimestamp.UnixNano() {
 		message.OrderTimestamp = timestamp
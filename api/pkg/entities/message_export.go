@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageExportStatus is the state of a MessageExport
+type MessageExportStatus string
+
+const (
+	// MessageExportStatusPending means that the object may not have been written completely
+	MessageExportStatusPending = MessageExportStatus("pending")
+
+	// MessageExportStatusVerified means that the checksum and the messages of the object have been verified
+	MessageExportStatusVerified = MessageExportStatus("verified")
+
+	// MessageExportStatusPurged means that the exported messages have been deleted from the database
+	MessageExportStatusPurged = MessageExportStatus("purged")
+)
+
+// MessageExport is the manifest of an object which contains archived messages as gzip compressed NDJSON
+type MessageExport struct {
+	ID             uuid.UUID           `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	ObjectPath     string              `json:"object_path" example:"messages/2024/01/31/32343a19-da5e-4b1b-a767-3298a73703cb.ndjson.gz"`
+	FirstMessageID uuid.UUID           `json:"first_message_id" gorm:"type:uuid;index" example:"0a0b4d3e-46e1-4c1c-b2c6-2b0f3c4f3f56"`
+	LastMessageID  uuid.UUID           `json:"last_message_id" gorm:"type:uuid;index" example:"fd5f0d48-7b0e-4d3b-9c87-0c1a1f7d5f44"`
+	Count          int                 `json:"count" example:"1000"`
+	Checksum       string              `json:"checksum" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	Status         MessageExportStatus `json:"status" example:"purged"`
+	CreatedAt      time.Time           `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt      time.Time           `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// Contains checks if a message ID is in the ID range of the MessageExport
+func (export *MessageExport) Contains(messageID uuid.UUID) bool {
+	id := messageID.String()
+	return export.FirstMessageID.String() <= id && id <= export.LastMessageID.String()
+}
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageExport_Contains(t *testing.T) {
+	export := &MessageExport{
+		FirstMessageID: uuid.MustParse("20000000-0000-4000-8000-000000000000"),
+		LastMessageID:  uuid.MustParse("a0000000-0000-4000-8000-000000000000"),
+	}
+
+	messageIDs := []struct {
+		name     string
+		id       string
+		contains bool
+	}{
+		{name: "first message", id: "20000000-0000-4000-8000-000000000000", contains: true},
+		{name: "last message", id: "a0000000-0000-4000-8000-000000000000", contains: true},
+		{name: "message in the range", id: "9fffffff-ffff-4fff-bfff-ffffffffffff", contains: true},
+		{name: "message before the range", id: "1fffffff-ffff-4fff-bfff-ffffffffffff", contains: false},
+		{name: "message after the range", id: "a0000000-0000-4000-8000-000000000001", contains: false},
+	}
+
+	for _, item := range messageIDs {
+		item := item
+		t.Run(item.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			contains := export.Contains(uuid.MustParse(item.id))
+
+			// Assert
+			assert.Equal(t, item.contains, contains)
+		})
+	}
+}
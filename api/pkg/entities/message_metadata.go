@@ -0,0 +1,59 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MessageMetadataKeyTwilioSID is the key of the SID of a message which was imported from a Twilio export
+const MessageMetadataKeyTwilioSID = "twilio_sid"
+
+// MessageMetadata are the key value pairs which are stored with a message, it is stored as JSON
+type MessageMetadata map[string]string
+
+// Value encodes the MessageMetadata as JSON, empty metadata is stored as NULL
+func (metadata MessageMetadata) Value() (driver.Value, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return string(content), nil
+}
+
+// Scan decodes the JSON of the MessageMetadata from the database
+func (metadata *MessageMetadata) Scan(value any) error {
+	var content []byte
+	switch v := value.(type) {
+	case nil:
+		*metadata = nil
+		return nil
+	case []byte:
+		content = v
+	case string:
+		content = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan value of type [%T] into %T", value, metadata)
+	}
+
+	if len(content) == 0 {
+		*metadata = nil
+		return nil
+	}
+	return json.Unmarshal(content, metadata)
+}
+
+// GormDBDataType is the type of the column of the MessageMetadata, it is JSONB on postgres so that it can be indexed
+func (MessageMetadata) GormDBDataType(db *gorm.DB, _ *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+	return "JSON"
+}
@@ -0,0 +1,77 @@
+package entities
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageRate is the price of one SMS segment which is sent by a user from the phone with the Owner number to the phone
+// numbers which start with Prefix, from the EffectiveFrom time. An empty Owner or Prefix matches all the phones or all
+// the contacts of the user.
+type MessageRate struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	// Owner is the phone number which sends the messages, it is empty for all the phones of the user
+	Owner string `json:"owner" example:"+18005550199"`
+	// Prefix is the start of the phone number of the contact in the E.164 format, it is empty for all the contacts
+	Prefix        string    `json:"prefix" example:"+44"`
+	Rate          float64   `json:"rate" example:"0.0075"`
+	Currency      string    `json:"currency" example:"USD"`
+	EffectiveFrom time.Time `json:"effective_from" example:"2022-06-01T00:00:00Z"`
+	CreatedAt     time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt     time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// Matches is true when the MessageRate applies to a message from the owner to the contact which is sent at the timestamp
+func (rate *MessageRate) Matches(owner string, contact string, timestamp time.Time) bool {
+	return (rate.Owner == "" || rate.Owner == owner) &&
+		strings.HasPrefix(contact, rate.Prefix) &&
+		!rate.EffectiveFrom.After(timestamp)
+}
+
+// Cost is the estimated cost of sending a message with the number of segments
+func (rate *MessageRate) Cost(segments int) float64 {
+	return RoundCost(rate.Rate * float64(segments))
+}
+
+// isMoreSpecific is true when the MessageRate is for the owner of a message and the other is not, the rate with the
+// longer Prefix or the later EffectiveFrom is more specific when both rates have the same owner.
+func (rate *MessageRate) isMoreSpecific(other *MessageRate) bool {
+	if (rate.Owner == "") != (other.Owner == "") {
+		return rate.Owner != ""
+	}
+	if len(rate.Prefix) != len(other.Prefix) {
+		return len(rate.Prefix) > len(other.Prefix)
+	}
+	return rate.EffectiveFrom.After(other.EffectiveFrom)
+}
+
+// MatchMessageRate returns the most specific MessageRate which applies to a message from the owner to the contact at
+// the timestamp. It is nil when no rate applies.
+func MatchMessageRate(rates []*MessageRate, owner string, contact string, timestamp time.Time) *MessageRate {
+	var match *MessageRate
+	for _, rate := range rates {
+		if rate.Matches(owner, contact, timestamp) && (match == nil || rate.isMoreSpecific(match)) {
+			match = rate
+		}
+	}
+	return match
+}
+
+// RoundCost rounds a cost to 6 decimal places so that a sum of costs does not accumulate floating point errors
+func RoundCost(cost float64) float64 {
+	return math.Round(cost*1e6) / 1e6
+}
+
+// MessageCostSummary is the estimated cost of the messages of a user in one currency
+type MessageCostSummary struct {
+	// RequestID is the request_id of the messages e.g. a campaign, it is only set when the costs are grouped by request_id
+	RequestID *string `json:"request_id" example:"153554b5-ae44-44a0-8f4f-7bbac5657ad4"`
+	Currency  string  `json:"currency" example:"USD"`
+	Messages  uint    `json:"messages" example:"120"`
+	Segments  uint    `json:"segments" example:"135"`
+	Cost      float64 `json:"cost" example:"1.0125"`
+}
@@ -0,0 +1,97 @@
+package entities
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchMessageRate(t *testing.T) {
+	june := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	july := time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	all := &MessageRate{Rate: 0.01, EffectiveFrom: june}
+	uk := &MessageRate{Prefix: "+44", Rate: 0.04, EffectiveFrom: june}
+	ukJuly := &MessageRate{Prefix: "+44", Rate: 0.05, EffectiveFrom: july}
+	owner := &MessageRate{Owner: "+18005550199", Rate: 0.002, EffectiveFrom: june}
+
+	t.Run("rate of the owner is matched before the rates of all phones", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		rate := MatchMessageRate([]*MessageRate{all, uk, owner}, "+18005550199", "+447911123456", june.Add(time.Hour))
+
+		// Assert
+		assert.Equal(t, owner, rate)
+	})
+
+	t.Run("rate with the longest prefix is matched", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		rate := MatchMessageRate([]*MessageRate{all, uk, owner}, "+447700900000", "+447911123456", june.Add(time.Hour))
+
+		// Assert
+		assert.Equal(t, uk, rate)
+	})
+
+	t.Run("rate which applied when the message was sent is matched", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		before := MatchMessageRate([]*MessageRate{ukJuly, uk}, "+447700900000", "+447911123456", july.Add(-time.Hour))
+		after := MatchMessageRate([]*MessageRate{ukJuly, uk}, "+447700900000", "+447911123456", july)
+
+		// Assert
+		assert.Equal(t, uk, before)
+		assert.Equal(t, ukJuly, after)
+	})
+
+	t.Run("no rate is matched before the rates are effective", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		rate := MatchMessageRate([]*MessageRate{all, uk}, "+447700900000", "+447911123456", june.Add(-time.Hour))
+
+		// Assert
+		assert.Nil(t, rate)
+	})
+}
+
+func TestMessage_UpdateContent(t *testing.T) {
+	t.Run("cost is estimated for the segments of the new content", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		cost := 0.0075
+		message := &Message{Content: "Hello", Segments: 1, Cost: &cost}
+
+		// Act
+		message.UpdateContent(time.Now().UTC(), strings.Repeat("a", 200))
+
+		// Assert
+		assert.Equal(t, uint(2), message.Segments)
+		assert.Equal(t, 0.015, *message.Cost)
+	})
+	t.Run("segments of encrypted content are not counted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		message := &Message{Content: "q83vEjRWeJA=", IsEncrypted: true}
+
+		// Act
+		message.UpdateContent(time.Now().UTC(), strings.Repeat("A", 400))
+
+		// Assert
+		assert.Equal(t, uint(0), message.Segments)
+		assert.Nil(t, message.Cost)
+	})
+}
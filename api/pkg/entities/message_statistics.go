@@ -0,0 +1,237 @@
+package entities
+
+import (
+	"time"
+)
+
+// MessageStatisticsGranularity is the size of a bucket of a MessageTimeSeries
+type MessageStatisticsGranularity string
+
+const (
+	// MessageStatisticsGranularityHour counts the messages in each hour
+	MessageStatisticsGranularityHour = MessageStatisticsGranularity("hour")
+
+	// MessageStatisticsGranularityDay counts the messages in each day
+	MessageStatisticsGranularityDay = MessageStatisticsGranularity("day")
+)
+
+// MessageHourlyCount is the number of messages of a user with a type, status and delivery tracking which were received
+// by the API in a UTC hour
+type MessageHourlyCount struct {
+	Hour             time.Time
+	Type             MessageType
+	Status           MessageStatus
+	DeliveryTracking MessageDeliveryTracking
+	Count            int64
+	// SentCount is the number of messages with a SendDuration
+	SentCount int64
+	// SendDuration is the sum of the SendDuration of the messages in nanoseconds
+	SendDuration float64
+}
+
+// MessageContactCount is the number of messages of a user which were sent to or received from a contact
+type MessageContactCount struct {
+	Contact string
+	Count   int64
+}
+
+// MessageTimeSeries is the number of messages of a user in each bucket of a time range. All the series have one
+// value for each of the Buckets so that they can be plotted without joining them.
+type MessageTimeSeries struct {
+	Granularity MessageStatisticsGranularity `json:"granularity" example:"day"`
+	Timezone    string                       `json:"timezone" example:"Africa/Douala"`
+	// Buckets is the start of each hour or day in the timezone
+	Buckets []time.Time `json:"buckets" example:"2022-06-05T00:00:00+01:00"`
+	// Outgoing is the number of messages which were sent with the API
+	Outgoing []int64 `json:"outgoing" example:"120"`
+	// Sent is the number of outgoing messages which were sent or delivered by the phone
+	Sent      []int64 `json:"sent" example:"110"`
+	Delivered []int64 `json:"delivered" example:"100"`
+	// Undelivered is the number of sent messages which are waiting for a delivery report, the messages which were sent
+	// with delivery_tracking "off" are not counted because the phone does not request a delivery report
+	Undelivered []int64 `json:"undelivered" example:"8"`
+	Failed      []int64 `json:"failed" example:"6"`
+	Expired     []int64 `json:"expired" example:"4"`
+	// Incoming is the number of messages which were received by the phone
+	Incoming []int64 `json:"incoming" example:"35"`
+	// AverageSendDuration is the average number of seconds from when the API received a message until the phone sent it, it is null when no message was sent
+	AverageSendDuration []*float64 `json:"average_send_duration" example:"12.5"`
+	// FailureRate is the fraction of the sent, failed and expired messages which failed or expired, it is null when no message was sent, failed or expired
+	FailureRate []*float64 `json:"failure_rate" example:"0.083"`
+}
+
+// NewMessageTimeSeries creates a zero-filled MessageTimeSeries with the buckets from the start of the hour or day of
+// from until to in the location and adds the hourly counts to the bucket which contains the start of their hour, so the
+// hourly buckets of a location with an offset which is not a whole hour e.g. Asia/Kolkata are approximate.
+func NewMessageTimeSeries(counts []*MessageHourlyCount, from time.Time, to time.Time, granularity MessageStatisticsGranularity, location *time.Location) *MessageTimeSeries {
+	series := &MessageTimeSeries{
+		Granularity: granularity,
+		Timezone:    location.String(),
+	}
+
+	indexes := map[int64]int{}
+	for bucket := truncateMessageBucket(from, granularity, location); bucket.Before(to); bucket = nextMessageBucket(bucket, granularity, location) {
+		indexes[bucket.Unix()] = len(series.Buckets)
+		series.Buckets = append(series.Buckets, bucket)
+	}
+
+	size := len(series.Buckets)
+	series.Outgoing, series.Sent, series.Delivered = make([]int64, size), make([]int64, size), make([]int64, size)
+	series.Undelivered = make([]int64, size)
+	series.Failed, series.Expired, series.Incoming = make([]int64, size), make([]int64, size), make([]int64, size)
+	series.AverageSendDuration, series.FailureRate = make([]*float64, size), make([]*float64, size)
+
+	sentCount := make([]int64, size)
+	sendDuration := make([]float64, size)
+	for _, count := range counts {
+		index, ok := indexes[truncateMessageBucket(count.Hour, granularity, location).Unix()]
+		if !ok {
+			continue
+		}
+
+		sentCount[index] += count.SentCount
+		sendDuration[index] += count.SendDuration
+
+		if count.Type == MessageTypeMobileOriginated {
+			series.Incoming[index] += count.Count
+			continue
+		}
+
+		series.Outgoing[index] += count.Count
+		switch count.Status {
+		case MessageStatusSent:
+			series.Sent[index] += count.Count
+			if count.DeliveryTracking != MessageDeliveryTrackingOff {
+				series.Undelivered[index] += count.Count
+			}
+		case MessageStatusDelivered:
+			series.Sent[index] += count.Count
+			series.Delivered[index] += count.Count
+		case MessageStatusFailed:
+			series.Failed[index] += count.Count
+		case MessageStatusExpired:
+			series.Expired[index] += count.Count
+		}
+	}
+
+	for index := range series.Buckets {
+		if sentCount[index] > 0 {
+			average := time.Duration(sendDuration[index] / float64(sentCount[index])).Seconds()
+			series.AverageSendDuration[index] = &average
+		}
+		if finished := series.Sent[index] + series.Failed[index] + series.Expired[index]; finished > 0 {
+			rate := float64(series.Failed[index]+series.Expired[index]) / float64(finished)
+			series.FailureRate[index] = &rate
+		}
+	}
+
+	return series
+}
+
+// MessageTimeSeriesRange returns the start of the first bucket and the end of the last bucket of a MessageTimeSeries
+// from until to, the messages in this time range are counted because the buckets are whole hours or days.
+func MessageTimeSeriesRange(from time.Time, to time.Time, granularity MessageStatisticsGranularity, location *time.Location) (time.Time, time.Time) {
+	start := truncateMessageBucket(from, granularity, location)
+	end := start
+	for end.Before(to) {
+		end = nextMessageBucket(end, granularity, location)
+	}
+	return start, end
+}
+
+// truncateMessageBucket returns the start of the hour or day of a timestamp in the location. The hour is truncated
+// with the offset of the timestamp so that the repeated hour at the end of daylight saving time is a separate bucket.
+func truncateMessageBucket(timestamp time.Time, granularity MessageStatisticsGranularity, location *time.Location) time.Time {
+	timestamp = timestamp.In(location)
+	if granularity == MessageStatisticsGranularityDay {
+		return time.Date(timestamp.Year(), timestamp.Month(), timestamp.Day(), 0, 0, 0, 0, location)
+	}
+	_, offset := timestamp.Zone()
+	shift := time.Duration(offset) * time.Second
+	return timestamp.Add(shift).Truncate(time.Hour).Add(-shift)
+}
+
+// nextMessageBucket returns the start of the bucket after the bucket which starts at the timestamp
+func nextMessageBucket(bucket time.Time, granularity MessageStatisticsGranularity, location *time.Location) time.Time {
+	if granularity == MessageStatisticsGranularityDay {
+		return time.Date(bucket.Year(), bucket.Month(), bucket.Day()+1, 0, 0, 0, 0, location)
+	}
+	return truncateMessageBucket(bucket.Add(time.Hour), granularity, location)
+}
+
+// MessageLatencyCounts are the outbound messages of a user in a time range which are counted for the send SLO
+type MessageLatencyCounts struct {
+	// Messages is the number of messages which were sent, failed or expired
+	Messages int64
+	// Sent is the number of messages with a SendDuration
+	Sent int64
+	// Failed is the number of messages which failed or expired
+	Failed int64
+	// Violations is the number of messages which failed, expired or were sent after the threshold
+	Violations int64
+	// P50, P90 and P99 are the percentiles of the SendDuration in nanoseconds, they are nil when no message was sent
+	P50 *float64
+	P90 *float64
+	P99 *float64
+}
+
+// MessageLatencySummary is the send latency of the outbound messages of a user in a time range and how it compares to
+// an SLO e.g. 95% of messages are sent within 60 seconds. Messages which failed or expired violate the SLO.
+type MessageLatencySummary struct {
+	From time.Time `json:"from" example:"2022-06-01T00:00:00Z"`
+	To   time.Time `json:"to" example:"2022-07-01T00:00:00Z"`
+	// Threshold is the number of seconds in which a message must be sent to meet the SLO
+	Threshold float64 `json:"threshold" example:"60"`
+	// Objective is the fraction of the messages which must meet the SLO
+	Objective  float64 `json:"objective" example:"0.95"`
+	Messages   int64   `json:"messages" example:"1200"`
+	Sent       int64   `json:"sent" example:"1180"`
+	Failed     int64   `json:"failed" example:"20"`
+	Violations int64   `json:"violations" example:"45"`
+	// P50, P90 and P99 are the percentiles of the send duration in seconds, they are null when no message was sent
+	P50 *float64 `json:"p50" example:"4.2"`
+	P90 *float64 `json:"p90" example:"21.7"`
+	P99 *float64 `json:"p99" example:"95.3"`
+	// Compliance is the fraction of the messages which met the SLO, it is null when there are no messages
+	Compliance *float64 `json:"compliance" example:"0.9625"`
+	// BurnRate is the rate at which the error budget of the SLO is spent, the budget is exhausted in the time range when it is more than 1
+	BurnRate *float64 `json:"burn_rate" example:"0.75"`
+	Met      bool     `json:"met" example:"true"`
+}
+
+// NewMessageLatencySummary creates a MessageLatencySummary from the MessageLatencyCounts of a time range
+func NewMessageLatencySummary(from time.Time, to time.Time, threshold time.Duration, objective float64, counts *MessageLatencyCounts) *MessageLatencySummary {
+	summary := &MessageLatencySummary{
+		From:       from,
+		To:         to,
+		Threshold:  threshold.Seconds(),
+		Objective:  objective,
+		Messages:   counts.Messages,
+		Sent:       counts.Sent,
+		Failed:     counts.Failed,
+		Violations: counts.Violations,
+		P50:        durationSeconds(counts.P50),
+		P90:        durationSeconds(counts.P90),
+		P99:        durationSeconds(counts.P99),
+		Met:        true,
+	}
+
+	if counts.Messages > 0 {
+		errorRate := float64(counts.Violations) / float64(counts.Messages)
+		compliance := 1 - errorRate
+		burnRate := errorRate / (1 - objective)
+		summary.Compliance, summary.BurnRate = &compliance, &burnRate
+		summary.Met = compliance >= objective
+	}
+
+	return summary
+}
+
+// durationSeconds converts a number of nanoseconds to seconds
+func durationSeconds(nanoseconds *float64) *float64 {
+	if nanoseconds == nil {
+		return nil
+	}
+	seconds := time.Duration(*nanoseconds).Seconds()
+	return &seconds
+}
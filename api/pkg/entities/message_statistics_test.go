@@ -0,0 +1,176 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMessageTimeSeries(t *testing.T) {
+	douala, _ := time.LoadLocation("Africa/Douala")
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	t.Run("empty buckets are zero-filled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		from := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+
+		// Act
+		series := NewMessageTimeSeries(nil, from, from.AddDate(0, 0, 3), MessageStatisticsGranularityDay, time.UTC)
+
+		// Assert
+		assert.Equal(t, 3, len(series.Buckets))
+		assert.Equal(t, []int64{0, 0, 0}, series.Outgoing)
+		assert.Equal(t, []int64{0, 0, 0}, series.Incoming)
+		assert.Equal(t, []*float64{nil, nil, nil}, series.FailureRate)
+	})
+
+	t.Run("hours are counted in the day of the timezone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		from := time.Date(2022, time.June, 5, 0, 0, 0, 0, douala)
+		counts := []*MessageHourlyCount{
+			// 22:00 UTC is 23:00 on the 5th in Douala
+			{Hour: time.Date(2022, time.June, 5, 22, 0, 0, 0, time.UTC), Type: MessageTypeMobileTerminated, Status: MessageStatusDelivered, Count: 3},
+			// 23:00 UTC is midnight on the 6th in Douala
+			{Hour: time.Date(2022, time.June, 5, 23, 0, 0, 0, time.UTC), Type: MessageTypeMobileTerminated, Status: MessageStatusFailed, Count: 1},
+			{Hour: time.Date(2022, time.June, 5, 23, 0, 0, 0, time.UTC), Type: MessageTypeMobileOriginated, Status: MessageStatusReceived, Count: 2},
+		}
+
+		// Act
+		series := NewMessageTimeSeries(counts, from, from.AddDate(0, 0, 2), MessageStatisticsGranularityDay, douala)
+
+		// Assert
+		assert.Equal(t, "Africa/Douala", series.Timezone)
+		assert.Equal(t, []time.Time{from, from.AddDate(0, 0, 1)}, series.Buckets)
+		assert.Equal(t, []int64{3, 1}, series.Outgoing)
+		assert.Equal(t, []int64{3, 0}, series.Sent)
+		assert.Equal(t, []int64{3, 0}, series.Delivered)
+		assert.Equal(t, []int64{0, 1}, series.Failed)
+		assert.Equal(t, []int64{0, 2}, series.Incoming)
+		assert.Equal(t, 0.0, *series.FailureRate[0])
+		assert.Equal(t, 1.0, *series.FailureRate[1])
+	})
+
+	t.Run("messages without delivery tracking are not undelivered", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		from := time.Date(2022, time.June, 5, 14, 0, 0, 0, time.UTC)
+		counts := []*MessageHourlyCount{
+			{Hour: from, Type: MessageTypeMobileTerminated, Status: MessageStatusSent, DeliveryTracking: MessageDeliveryTrackingOn, Count: 2},
+			{Hour: from, Type: MessageTypeMobileTerminated, Status: MessageStatusSent, DeliveryTracking: MessageDeliveryTrackingOff, Count: 5},
+			{Hour: from, Type: MessageTypeMobileTerminated, Status: MessageStatusDelivered, DeliveryTracking: MessageDeliveryTrackingOn, Count: 3},
+		}
+
+		// Act
+		series := NewMessageTimeSeries(counts, from, from.Add(time.Hour), MessageStatisticsGranularityHour, time.UTC)
+
+		// Assert
+		assert.Equal(t, []int64{10}, series.Sent)
+		assert.Equal(t, []int64{3}, series.Delivered)
+		assert.Equal(t, []int64{2}, series.Undelivered)
+		assert.Equal(t, 0.0, *series.FailureRate[0])
+	})
+
+	t.Run("average send duration is in seconds", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		from := time.Date(2022, time.June, 5, 14, 0, 0, 0, time.UTC)
+		counts := []*MessageHourlyCount{
+			{Hour: from, Type: MessageTypeMobileTerminated, Status: MessageStatusSent, Count: 2, SentCount: 2, SendDuration: float64(30 * time.Second)},
+			{Hour: from, Type: MessageTypeMobileTerminated, Status: MessageStatusPending, Count: 1},
+		}
+
+		// Act
+		series := NewMessageTimeSeries(counts, from, from.Add(2*time.Hour), MessageStatisticsGranularityHour, time.UTC)
+
+		// Assert
+		assert.Equal(t, []int64{3, 0}, series.Outgoing)
+		assert.Equal(t, 15.0, *series.AverageSendDuration[0])
+		assert.Nil(t, series.AverageSendDuration[1])
+	})
+
+	t.Run("repeated hour at the end of daylight saving time is a separate bucket", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		from := time.Date(2022, time.October, 30, 0, 0, 0, 0, time.UTC)
+
+		// Act
+		series := NewMessageTimeSeries(nil, from, from.Add(3*time.Hour), MessageStatisticsGranularityHour, berlin)
+
+		// Assert
+		assert.Equal(t, 3, len(series.Buckets))
+		assert.Equal(t, series.Buckets[0].Hour(), series.Buckets[1].Hour())
+	})
+}
+
+func TestMessageTimeSeriesRange(t *testing.T) {
+	t.Run("range is extended to whole days of the timezone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		douala, _ := time.LoadLocation("Africa/Douala")
+
+		// Act
+		from, to := MessageTimeSeriesRange(
+			time.Date(2022, time.June, 5, 12, 0, 0, 0, time.UTC),
+			time.Date(2022, time.June, 6, 12, 0, 0, 0, time.UTC),
+			MessageStatisticsGranularityDay,
+			douala,
+		)
+
+		// Assert
+		assert.Equal(t, time.Date(2022, time.June, 4, 23, 0, 0, 0, time.UTC), from.UTC())
+		assert.Equal(t, time.Date(2022, time.June, 6, 23, 0, 0, 0, time.UTC), to.UTC())
+	})
+}
+
+func TestNewMessageLatencySummary(t *testing.T) {
+	from := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	t.Run("violations spend the error budget", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		p50 := float64(4 * time.Second)
+		counts := &MessageLatencyCounts{Messages: 100, Sent: 98, Failed: 2, Violations: 10, P50: &p50}
+
+		// Act
+		summary := NewMessageLatencySummary(from, to, time.Minute, 0.95, counts)
+
+		// Assert
+		assert.Equal(t, 60.0, summary.Threshold)
+		assert.Equal(t, 4.0, *summary.P50)
+		assert.Nil(t, summary.P90)
+		assert.InDelta(t, 0.9, *summary.Compliance, 1e-9)
+		assert.InDelta(t, 2.0, *summary.BurnRate, 1e-9)
+		assert.False(t, summary.Met)
+	})
+
+	t.Run("SLO is met without messages", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		summary := NewMessageLatencySummary(from, to, time.Minute, 0.95, &MessageLatencyCounts{})
+
+		// Assert
+		assert.Nil(t, summary.Compliance)
+		assert.Nil(t, summary.BurnRate)
+		assert.True(t, summary.Met)
+	})
+}
@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessage_NextStatus(t *testing.T) {
+	t.Run("sent message is delivered", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		message := &Message{Status: MessageStatusSent}
+
+		// Act
+		status := message.NextStatus(MessageStatusDelivered)
+
+		// Assert
+		assert.Equal(t, MessageStatus(MessageStatusDelivered), status)
+	})
+
+	t.Run("delivered message is not changed when it is sent out of order", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		message := &Message{Status: MessageStatusDelivered}
+
+		// Act
+		status := message.NextStatus(MessageStatusSent)
+
+		// Assert
+		assert.Equal(t, MessageStatus(MessageStatusDelivered), status)
+	})
+
+	t.Run("message fails unless it is delivered", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		pending := &Message{Status: MessageStatusPending}
+		delivered := &Message{Status: MessageStatusDelivered}
+
+		// Assert
+		assert.True(t, pending.CanTransitionTo(MessageStatusFailed))
+		assert.False(t, delivered.CanTransitionTo(MessageStatusFailed))
+	})
+}
+
+func TestMessage_ExpireContent(t *testing.T) {
+	// Setup
+	t.Parallel()
+
+	// Arrange
+	keyID := "key-1"
+	message := &Message{Content: "ciphertext", IsEncrypted: true, EncryptionKeyID: &keyID}
+	timestamp := time.Now().UTC()
+
+	// Act
+	message.ExpireContent(timestamp)
+
+	// Assert
+	assert.Equal(t, MessageContentExpiredMarker, message.Content)
+	assert.False(t, message.IsEncrypted)
+	assert.Nil(t, message.EncryptionKeyID)
+	assert.True(t, message.IsContentExpired())
+}
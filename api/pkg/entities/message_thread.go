@@ -9,10 +9,10 @@ import (
 // MessageThread represents a message thread between 2 phone numbers
 type MessageThread struct {
 	ID                 uuid.UUID     `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703ca"`
-	Owner              string        `json:"owner" example:"+18005550199"`
+	Owner              string        `json:"owner" gorm:"index:idx_message_threads_user_id_owner" example:"+18005550199"`
 	Contact            string        `json:"contact" example:"+18005550100"`
 	IsArchived         bool          `json:"is_archived" example:"false"`
-	UserID             UserID        `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	UserID             UserID        `json:"user_id" gorm:"index:idx_message_threads_user_id_owner,priority:1" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
 	Color              string        `json:"color" example:"indigo"`
 	Status             MessageStatus `json:"status" example:"PENDING"`
 	LastMessageContent *string       `json:"last_message_content" example:"This is a sample message content"`
@@ -20,6 +20,39 @@ type MessageThread struct {
 	CreatedAt          time.Time     `json:"created_at" example:"2022-06-05T14:26:09.527976+03:00"`
 	UpdatedAt          time.Time     `json:"updated_at" example:"2022-06-05T14:26:09.527976+03:00"`
 	OrderTimestamp     time.Time     `json:"order_timestamp" example:"2022-06-05T14:26:09.527976+03:00"`
+	LastReadAt         *time.Time    `json:"last_read_at" example:"2022-06-05T14:26:09.527976+03:00"`
+
+	// IsMuted is true when the notifications e.g. slack and telegram of the messages received in the thread are not sent,
+	// the messages are still stored
+	IsMuted bool `json:"is_muted" example:"false"`
+	// MutedUntil is the time when the mute is lifted, the thread is muted until it is unmuted when it is nil
+	MutedUntil *time.Time `json:"muted_until" example:"2022-06-05T18:26:09.527976+03:00"`
+	// MuteWebhooks is true when the webhooks do not receive the messages received in a muted thread
+	MuteWebhooks bool `json:"mute_webhooks" example:"false"`
+
+	// Phone is the liveness of the owner's phone, it is omitted when the owner has no registered phone
+	Phone *PhoneLiveness `json:"phone,omitempty" gorm:"-"`
+
+	// ContactName is the name of the contact in the address book of the user, it is omitted when the contact has no name
+	ContactName *string `json:"contact_name,omitempty" gorm:"-" example:"Jane Doe"`
+
+	// ContactNotes and ContactLabels are the notes and labels of the contact, they are omitted when the contact has none
+	ContactNotes  *string  `json:"contact_notes,omitempty" gorm:"-" example:"prefers French"`
+	ContactLabels []string `json:"contact_labels,omitempty" gorm:"-" example:"vip,french"`
+}
+
+// SetContact sets the name, notes and labels of the Contact of the thread
+func (thread *MessageThread) SetContact(contact *Contact) *MessageThread {
+	if contact.Name != "" {
+		thread.ContactName = &contact.Name
+	}
+	if contact.Notes != "" {
+		thread.ContactNotes = &contact.Notes
+	}
+	if len(contact.Labels) > 0 {
+		thread.ContactLabels = contact.Labels
+	}
+	return thread
 }
 
 // Update a message thread after a message event
@@ -37,6 +70,50 @@ func (thread *MessageThread) UpdateArchive(isArchived bool) *MessageThread {
 	return thread
 }
 
+// Mute stops the notifications of the thread until a time, the thread is muted until it is unmuted when until is nil
+func (thread *MessageThread) Mute(timestamp time.Time, until *time.Time, muteWebhooks bool) *MessageThread {
+	thread.IsMuted = true
+	thread.MutedUntil = until
+	thread.MuteWebhooks = muteWebhooks
+	thread.UpdatedAt = timestamp
+	return thread
+}
+
+// Unmute sends the notifications of the thread again
+func (thread *MessageThread) Unmute(timestamp time.Time) *MessageThread {
+	thread.IsMuted = false
+	thread.MutedUntil = nil
+	thread.MuteWebhooks = false
+	thread.UpdatedAt = timestamp
+	return thread
+}
+
+// IsMutedAt checks if the notifications of the thread are muted at a time, a mute is lifted after MutedUntil
+func (thread *MessageThread) IsMutedAt(timestamp time.Time) bool {
+	return thread.IsMuted && (thread.MutedUntil == nil || timestamp.Before(*thread.MutedUntil))
+}
+
+// IsWebhookMutedAt checks if the webhooks of the thread are muted at a time
+func (thread *MessageThread) IsWebhookMutedAt(timestamp time.Time) bool {
+	return thread.MuteWebhooks && thread.IsMutedAt(timestamp)
+}
+
+// LiftExpiredMute unmutes the thread when its mute has expired so that it is not displayed as muted
+func (thread *MessageThread) LiftExpiredMute(timestamp time.Time) *MessageThread {
+	if thread.IsMuted && !thread.IsMutedAt(timestamp) {
+		thread.IsMuted = false
+		thread.MutedUntil = nil
+		thread.MuteWebhooks = false
+	}
+	return thread
+}
+
+// MarkRead sets the time when the messages in the thread were read
+func (thread *MessageThread) MarkRead(timestamp time.Time) *MessageThread {
+	thread.LastReadAt = &timestamp
+	return thread
+}
+
 // HasLastMessage checks the last message in a thread by ID
 func (thread *MessageThread) HasLastMessage(id uuid.UUID) bool {
 	if thread.LastMessageID == nil {
@@ -0,0 +1,119 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageThread_IsMutedAt(t *testing.T) {
+	now := time.Date(2022, 6, 5, 14, 26, 0, 0, time.UTC)
+
+	t.Run("thread which is not muted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		thread := &MessageThread{}
+
+		// Assert
+		assert.False(t, thread.IsMutedAt(now))
+		assert.False(t, thread.IsWebhookMutedAt(now))
+	})
+
+	t.Run("thread which is muted without an expiry", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		thread := (&MessageThread{}).Mute(now, nil, false)
+
+		// Assert
+		assert.True(t, thread.IsMutedAt(now.Add(24*time.Hour)))
+		assert.False(t, thread.IsWebhookMutedAt(now))
+	})
+
+	t.Run("thread which is muted until a time", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		until := now.Add(time.Hour)
+		thread := (&MessageThread{}).Mute(now, &until, true)
+
+		// Assert
+		assert.True(t, thread.IsMutedAt(now))
+		assert.True(t, thread.IsWebhookMutedAt(now))
+		assert.False(t, thread.IsMutedAt(until))
+		assert.False(t, thread.IsWebhookMutedAt(until))
+	})
+
+	t.Run("thread which is unmuted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		thread := (&MessageThread{}).Mute(now, nil, true).Unmute(now)
+
+		// Assert
+		assert.False(t, thread.IsMuted)
+		assert.False(t, thread.MuteWebhooks)
+		assert.False(t, thread.IsMutedAt(now))
+	})
+}
+
+func TestMessageThread_LiftExpiredMute(t *testing.T) {
+	now := time.Date(2022, 6, 5, 14, 26, 0, 0, time.UTC)
+
+	t.Run("expired mute is lifted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		until := now.Add(-time.Minute)
+		thread := (&MessageThread{}).Mute(now.Add(-time.Hour), &until, true)
+
+		// Act
+		thread.LiftExpiredMute(now)
+
+		// Assert
+		assert.False(t, thread.IsMuted)
+		assert.Nil(t, thread.MutedUntil)
+		assert.False(t, thread.MuteWebhooks)
+	})
+
+	t.Run("active mute is not lifted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		until := now.Add(time.Minute)
+		thread := (&MessageThread{}).Mute(now, &until, false)
+
+		// Act
+		thread.LiftExpiredMute(now)
+
+		// Assert
+		assert.True(t, thread.IsMuted)
+		assert.Equal(t, &until, thread.MutedUntil)
+	})
+
+	t.Run("muted thread still stores the last message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		messageID := uuid.New()
+		thread := (&MessageThread{}).Mute(now, nil, false)
+
+		// Act
+		thread.Update(now, messageID, "hello", MessageStatusReceived)
+
+		// Assert
+		assert.True(t, thread.HasLastMessage(messageID))
+		assert.Equal(t, "hello", *thread.LastMessageContent)
+		assert.True(t, thread.IsMutedAt(now))
+	})
+}
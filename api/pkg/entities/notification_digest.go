@@ -0,0 +1,35 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationDigest is the window of a phone in which the messages which fail or expire are collected into one email
+type NotificationDigest struct {
+	UserID UserID `json:"user_id" gorm:"primaryKey;type:string;" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Owner  string `json:"owner" gorm:"primaryKey;type:string;" example:"+18005550199"`
+
+	// WindowID is the ID of the open window, it is nil when no message has failed or expired since the last digest
+	WindowID *uuid.UUID `json:"window_id" gorm:"type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	OpenedAt *time.Time `json:"opened_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	SendAt   *time.Time `json:"send_at" example:"2022-06-05T14:36:02.302718+03:00"`
+
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// NotificationDigestMessage is a message which failed or expired and is sent in a digest email
+type NotificationDigestMessage struct {
+	ID uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+
+	// DigestID is the ID of the digest email which contains the message, it is nil until the digest is sent
+	DigestID     *uuid.UUID    `json:"digest_id" gorm:"type:uuid;index" example:"32343a19-da5e-4b1b-a767-3298a73703cc"`
+	UserID       UserID        `json:"user_id" gorm:"index:idx_notification_digest_messages_user_id_owner" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Owner        string        `json:"owner" gorm:"index:idx_notification_digest_messages_user_id_owner" example:"+18005550199"`
+	MessageID    uuid.UUID     `json:"message_id" gorm:"type:uuid;uniqueIndex" example:"32343a19-da5e-4b1b-a767-3298a73703cd"`
+	Contact      string        `json:"contact" example:"+18005550100"`
+	Status       MessageStatus `json:"status" example:"failed"`
+	ErrorMessage *string       `json:"error_message" example:"NO_SERVICE"`
+	CreatedAt    time.Time     `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+}
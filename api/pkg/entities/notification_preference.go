@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"time"
+)
+
+// NotificationPreference are the settings of the email digest of the messages of a user which fail or expire
+type NotificationPreference struct {
+	UserID  UserID `json:"user_id" gorm:"primaryKey;type:string;" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Enabled bool   `json:"enabled" example:"true"`
+
+	// Threshold is the minimum number of messages of a phone which fail or expire in a digest before the digest is sent
+	Threshold uint `json:"threshold" example:"1"`
+
+	// QuietHoursStart is the time in the timezone of the user e.g. 22:00 after which the digests are not sent until
+	// QuietHoursEnd, the digests which are due in the quiet hours are sent when the quiet hours end
+	QuietHoursStart string `json:"quiet_hours_start" example:"22:00"`
+	QuietHoursEnd   string `json:"quiet_hours_end" example:"07:00"`
+
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// DefaultNotificationPreference is the NotificationPreference of a user who has not changed the settings of the digest
+func DefaultNotificationPreference(user *User) *NotificationPreference {
+	return &NotificationPreference{
+		UserID:    user.ID,
+		Enabled:   user.NotificationMessageStatusEnabled,
+		Threshold: 1,
+	}
+}
+
+// QuietUntil returns the end of the quiet hours when now is in the quiet hours of the timezone
+func (preference *NotificationPreference) QuietUntil(timezone string, now time.Time) (time.Time, bool) {
+	return quietUntil(preference.QuietHoursStart, preference.QuietHoursEnd, timezone, now)
+}
+
+// quietUntil returns the end of the daily window from startValue to endValue e.g. 22:00 - 07:00 in the timezone when
+// now is in the window, a window whose start is after its end is over midnight.
+func quietUntil(startValue string, endValue string, timezone string, now time.Time) (time.Time, bool) {
+	start, startErr := time.Parse("15:04", startValue)
+	end, endErr := time.Parse("15:04", endValue)
+	if startErr != nil || endErr != nil || startValue == endValue {
+		return now, false
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	minutes := local.Hour()*60 + local.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	endAt := midnight.Add(time.Duration(endMinutes) * time.Minute)
+	if startMinutes < endMinutes {
+		if minutes < startMinutes || minutes >= endMinutes {
+			return now, false
+		}
+		return endAt.UTC(), true
+	}
+
+	// the window is over midnight e.g. 22:00 - 07:00
+	if minutes >= endMinutes && minutes < startMinutes {
+		return now, false
+	}
+	if minutes >= startMinutes {
+		endAt = time.Date(local.Year(), local.Month(), local.Day()+1, end.Hour(), end.Minute(), 0, 0, location)
+	}
+	return endAt.UTC(), true
+}
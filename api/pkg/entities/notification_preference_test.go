@@ -0,0 +1,85 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreference_QuietUntil(t *testing.T) {
+	preference := &NotificationPreference{QuietHoursStart: "22:00", QuietHoursEnd: "07:00"}
+
+	t.Run("time before midnight is quiet until the next morning", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 23, 15, 0, 0, time.UTC)
+
+		// Act
+		until, isQuiet := preference.QuietUntil("UTC", now)
+
+		// Assert
+		assert.True(t, isQuiet)
+		assert.Equal(t, time.Date(2022, 6, 6, 7, 0, 0, 0, time.UTC), until)
+	})
+
+	t.Run("time after midnight is quiet until the same morning", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 3, 0, 0, 0, time.UTC)
+
+		// Act
+		until, isQuiet := preference.QuietUntil("UTC", now)
+
+		// Assert
+		assert.True(t, isQuiet)
+		assert.Equal(t, time.Date(2022, 6, 5, 7, 0, 0, 0, time.UTC), until)
+	})
+
+	t.Run("time outside the quiet hours is not quiet", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 12, 0, 0, 0, time.UTC)
+
+		// Act
+		_, isQuiet := preference.QuietUntil("UTC", now)
+
+		// Assert
+		assert.False(t, isQuiet)
+	})
+
+	t.Run("quiet hours are in the timezone of the user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 20, 30, 0, 0, time.UTC)
+
+		// Act
+		until, isQuiet := preference.QuietUntil("Europe/Helsinki", now)
+
+		// Assert
+		assert.True(t, isQuiet)
+		assert.Equal(t, time.Date(2022, 6, 6, 4, 0, 0, 0, time.UTC), until)
+	})
+
+	t.Run("preference without quiet hours is never quiet", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 23, 15, 0, 0, time.UTC)
+
+		// Act
+		_, isQuiet := (&NotificationPreference{}).QuietUntil("UTC", now)
+
+		// Assert
+		assert.False(t, isQuiet)
+	})
+}
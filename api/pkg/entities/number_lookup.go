@@ -0,0 +1,46 @@
+package entities
+
+import "time"
+
+// NumberType is the kind of line of a phone number
+type NumberType string
+
+const (
+	// NumberTypeMobile is a mobile number which can receive SMS messages
+	NumberTypeMobile = NumberType("mobile")
+
+	// NumberTypeLandline is a fixed line number which cannot receive SMS messages
+	NumberTypeLandline = NumberType("landline")
+
+	// NumberTypeVoIP is a virtual number which may receive SMS messages
+	NumberTypeVoIP = NumberType("voip")
+
+	// NumberTypeInvalid is a number which is not assigned in its numbering plan
+	NumberTypeInvalid = NumberType("invalid")
+
+	// NumberTypeUnknown is a number which cannot be classified e.g. a number which is either a fixed line or a mobile
+	NumberTypeUnknown = NumberType("unknown")
+)
+
+// NumberLookup is the type and carrier of a phone number, it is cached until ExpiresAt
+type NumberLookup struct {
+	PhoneNumber string     `json:"phone_number" gorm:"primaryKey" example:"+18005550100"`
+	Type        NumberType `json:"type" example:"mobile"`
+	Carrier     string     `json:"carrier" example:"T-Mobile"`
+	CountryCode string     `json:"country_code" example:"US"`
+
+	// Source is the name of the lookup which classified the number e.g. http or offline
+	Source     string    `json:"source" example:"offline"`
+	LookedUpAt time.Time `json:"looked_up_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	ExpiresAt  time.Time `json:"expires_at" gorm:"index" example:"2022-07-05T14:26:02.302718+03:00"`
+}
+
+// CanReceiveSMS checks if messages can be sent to the number, a number which cannot be classified is allowed
+func (lookup *NumberLookup) CanReceiveSMS() bool {
+	return lookup.Type != NumberTypeLandline && lookup.Type != NumberTypeInvalid
+}
+
+// IsExpired checks if the NumberLookup must be looked up again
+func (lookup *NumberLookup) IsExpired(now time.Time) bool {
+	return !now.Before(lookup.ExpiresAt)
+}
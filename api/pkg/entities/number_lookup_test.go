@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberLookup_CanReceiveSMS(t *testing.T) {
+	t.Run("landline and invalid numbers cannot receive SMS messages", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Assert
+		assert.False(t, (&NumberLookup{Type: NumberTypeLandline}).CanReceiveSMS())
+		assert.False(t, (&NumberLookup{Type: NumberTypeInvalid}).CanReceiveSMS())
+	})
+
+	t.Run("number which cannot be classified is allowed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Assert
+		assert.True(t, (&NumberLookup{Type: NumberTypeMobile}).CanReceiveSMS())
+		assert.True(t, (&NumberLookup{Type: NumberTypeUnknown}).CanReceiveSMS())
+	})
+}
+
+func TestNumberLookup_IsExpired(t *testing.T) {
+	t.Run("lookup expires at the end of its TTL", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		now := time.Date(2022, 6, 5, 14, 26, 0, 0, time.UTC)
+		lookup := &NumberLookup{ExpiresAt: now}
+
+		// Assert
+		assert.True(t, lookup.IsExpired(now))
+		assert.False(t, lookup.IsExpired(now.Add(-time.Second)))
+	})
+}
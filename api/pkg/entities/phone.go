@@ -6,20 +6,61 @@ import (
 	"github.com/google/uuid"
 )
 
+// DefaultMaxSendAttempts is the number of attempts used when a phone does not configure MaxSendAttempts
+const DefaultMaxSendAttempts = 2
+
+// phoneClockSkewSmoothing is the weight of a new sample in the moving average of the clock skew of a phone, so that
+// a heartbeat which is delayed by the network does not change the correction of the timestamps too much
+const phoneClockSkewSmoothing = 0.2
+
 // Phone represents an android phone which has installed the http sms app
 type Phone struct {
 	ID                uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
-	UserID            UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	UserID            UserID    `json:"user_id" gorm:"index:idx_phones_user_id_is_default,unique,where:is_default = true;index:idx_phones_user_id_phone_number" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
 	FcmToken          *string   `json:"fcm_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzd....."`
-	PhoneNumber       string    `json:"phone_number" example:"+18005550199"`
+	PhoneNumber       string    `json:"phone_number" gorm:"index:idx_phones_user_id_phone_number" example:"+18005550199"`
 	MessagesPerMinute uint      `json:"messages_per_minute" example:"1"`
 	SIM               SIM       `json:"sim" gorm:"default:SIM1"`
+	// SIMs are the SIM card subscriptions which are registered on the phone
+	SIMs []PhoneSIM `json:"sims" gorm:"foreignKey:PhoneID"`
+	// AppVersion is the version of the android app which was last reported by the phone
+	AppVersion string `json:"app_version" example:"v1.2.3"`
+	// IsDefault is true for the phone which is used to send messages when the owner is not specified
+	IsDefault bool `json:"is_default" gorm:"default:false" example:"true"`
+	// IsPaused is true when the phone should not be sent any outstanding messages
+	IsPaused bool       `json:"is_paused" gorm:"default:false" example:"false"`
+	PausedAt *time.Time `json:"paused_at" example:"2022-06-05T14:26:10.303278+03:00"`
 	// MaxSendAttempts determines how many times to retry sending an SMS message
 	MaxSendAttempts uint `json:"max_send_attempts" example:"2"`
+	// DailySendLimit is the number of messages which the phone sends in a day before the other messages are kept pending until midnight, 0 means the phone has no daily limit
+	DailySendLimit uint `json:"daily_send_limit" example:"300"`
+	// DailySendLimitTimezone is the timezone in which the DailySendLimit resets at midnight, the default timezone is used when it is empty
+	DailySendLimitTimezone string `json:"daily_send_limit_timezone" example:"Africa/Douala"`
+
+	// EncryptionEnabled is true when the messages of the phone are end-to-end encrypted, the API only accepts
+	// encrypted messages for the phone and the phone decrypts them before sending the SMS
+	EncryptionEnabled bool `json:"encryption_enabled" gorm:"default:false" example:"false"`
+
+	// BatteryLevel is the battery percentage of the phone from the last heartbeat
+	BatteryLevel     *uint      `json:"battery_level" example:"80"`
+	BatteryCharging  bool       `json:"battery_charging" example:"true"`
+	BatteryLow       bool       `json:"battery_low" example:"false"`
+	BatteryUpdatedAt *time.Time `json:"battery_updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+
+	// ClockSkewMilliseconds is the moving average of the difference between the clock of the phone and the clock of the server, it is positive when the clock of the phone is ahead
+	ClockSkewMilliseconds int64 `json:"clock_skew_milliseconds" example:"-1520"`
+	// ClockSkewed is true when the clock skew exceeded the threshold and the owner was told to fix the clock of the phone
+	ClockSkewed        bool       `json:"clock_skewed" gorm:"default:false" example:"false"`
+	ClockSkewUpdatedAt *time.Time `json:"clock_skew_updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
 
 	// MessageExpirationSeconds is the duration in seconds after sending a message when it is considered to be expired.
 	MessageExpirationSeconds uint `json:"message_expiration_seconds"`
 
+	// HeartbeatDeadThresholdSeconds is the duration in seconds after the last heartbeat when the phone is considered offline, 0 uses the global default
+	HeartbeatDeadThresholdSeconds uint `json:"heartbeat_dead_threshold_seconds" example:"3840"`
+	// HeartbeatAlertCooldownSeconds is the minimum duration in seconds between 2 offline alerts of the phone, 0 uses the global default
+	HeartbeatAlertCooldownSeconds uint `json:"heartbeat_alert_cooldown_seconds" example:"3600"`
+
 	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
 	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
 }
@@ -37,10 +78,106 @@ func (phone *Phone) MessageExpirationSecondsSanitized() uint {
 	return phone.MessageExpirationSeconds
 }
 
-// MaxSendAttemptsSanitized returns the max send attempts replacing 0 with 2
+// MaxSendAttemptsSanitized returns the max send attempts replacing 0 with DefaultMaxSendAttempts
 func (phone *Phone) MaxSendAttemptsSanitized() uint {
 	if phone.MaxSendAttempts == 0 {
-		return 2
+		return DefaultMaxSendAttempts
 	}
 	return phone.MaxSendAttempts
 }
+
+// RetryPolicy is the RetryPolicy of the messages which are sent by the phone when it is not overridden by the user
+func (phone *Phone) RetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       phone.MaxSendAttemptsSanitized(),
+		BackoffCapSeconds: DefaultRetryBackoffCapSeconds,
+		BackoffMultiplier: DefaultRetryBackoffMultiplier,
+	}
+}
+
+// HeartbeatDeadThreshold returns the duration after the last heartbeat when the phone is considered offline or the fallback when it is not configured
+func (phone *Phone) HeartbeatDeadThreshold(fallback time.Duration) time.Duration {
+	if phone.HeartbeatDeadThresholdSeconds == 0 {
+		return fallback
+	}
+	return time.Duration(phone.HeartbeatDeadThresholdSeconds) * time.Second
+}
+
+// HeartbeatAlertCooldown returns the minimum duration between 2 offline alerts of the phone or the fallback when it is not configured
+func (phone *Phone) HeartbeatAlertCooldown(fallback time.Duration) time.Duration {
+	if phone.HeartbeatAlertCooldownSeconds == 0 {
+		return fallback
+	}
+	return time.Duration(phone.HeartbeatAlertCooldownSeconds) * time.Second
+}
+
+// HasDailySendLimit checks if the phone stops sending messages after DailySendLimit messages in a day
+func (phone *Phone) HasDailySendLimit() bool {
+	return phone.DailySendLimit > 0
+}
+
+// DailySendLimitLocation returns the location of DailySendLimitTimezone or the fallback when the timezone is empty or invalid
+func (phone *Phone) DailySendLimitLocation(fallback *time.Location) *time.Location {
+	if phone.DailySendLimitTimezone == "" {
+		return fallback
+	}
+	location, err := time.LoadLocation(phone.DailySendLimitTimezone)
+	if err != nil {
+		return fallback
+	}
+	return location
+}
+
+// ClockSkew is the difference between the clock of the phone and the clock of the server
+func (phone *Phone) ClockSkew() time.Duration {
+	return time.Duration(phone.ClockSkewMilliseconds) * time.Millisecond
+}
+
+// AddClockSkewSample adds the difference between the time reported by the phone and the time when the server received
+// it to the moving average of the clock skew, the first sample is used as the clock skew
+func (phone *Phone) AddClockSkewSample(reportedAt time.Time, receivedAt time.Time) {
+	sample := reportedAt.Sub(receivedAt)
+	if phone.ClockSkewUpdatedAt == nil {
+		phone.ClockSkewMilliseconds = sample.Milliseconds()
+	} else {
+		skew := float64(phone.ClockSkew()) + phoneClockSkewSmoothing*float64(sample-phone.ClockSkew())
+		phone.ClockSkewMilliseconds = time.Duration(skew).Round(time.Millisecond).Milliseconds()
+	}
+	phone.ClockSkewUpdatedAt = &receivedAt
+}
+
+// CorrectTimestamp converts a timestamp which was reported by the phone to the clock of the server
+func (phone *Phone) CorrectTimestamp(timestamp time.Time) time.Time {
+	return timestamp.Add(-phone.ClockSkew())
+}
+
+// ClampPhoneTimestamp returns now when a timestamp which was reported by a phone is more than the futureTolerance after
+// now or more than the pastTolerance before now, and whether the timestamp was clamped
+func ClampPhoneTimestamp(timestamp time.Time, now time.Time, futureTolerance time.Duration, pastTolerance time.Duration) (time.Time, bool) {
+	if timestamp.After(now.Add(futureTolerance)) || timestamp.Before(now.Add(-pastTolerance)) {
+		return now, true
+	}
+	return timestamp, false
+}
+
+// HasSIM checks if a SIM is registered on the phone, it is always true when the phone has not registered any SIM
+func (phone *Phone) HasSIM(sim SIM) bool {
+	if len(phone.SIMs) == 0 {
+		return true
+	}
+	for _, item := range phone.SIMs {
+		if item.SIM() == sim {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisteredSIMs returns the SIM of each registered SIM card
+func (phone *Phone) RegisteredSIMs() []string {
+	sims := make([]string, 0, len(phone.SIMs))
+	for _, item := range phone.SIMs {
+		sims = append(sims, item.SIM().String())
+	}
+	return sims
+}
@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneDailySendUsage counts the messages which a phone has sent or is sending in a day, it is used to enforce Phone.DailySendLimit
+type PhoneDailySendUsage struct {
+	ID      uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID  UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	PhoneID uuid.UUID `json:"phone_id" gorm:"type:uuid;uniqueIndex:idx_phone_daily_send_usages_phone_id_day" example:"32343a19-da5e-4b1b-a767-3298a73703ca"`
+	// Day is the midnight timestamp of the day in the timezone of the daily send limit of the phone
+	Day          time.Time `json:"day" gorm:"uniqueIndex:idx_phone_daily_send_usages_phone_id_day" example:"2022-06-05T00:00:00+01:00"`
+	SentMessages uint      `json:"sent_messages" example:"300"`
+	// ExhaustedAt is the time when a message was first held back because the phone reached its daily send limit on the Day
+	ExhaustedAt *time.Time `json:"exhausted_at" example:"2022-06-05T14:26:10.303278+03:00"`
+	CreatedAt   time.Time  `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt   time.Time  `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// PhoneQueueStats is the state of the queue of the outstanding messages of a phone
+type PhoneQueueStats struct {
+	PhoneID  uuid.UUID `json:"phone_id" example:"32343a19-da5e-4b1b-a767-3298a73703ca"`
+	Owner    string    `json:"owner" example:"+18005550199"`
+	IsPaused bool      `json:"is_paused" example:"false"`
+	// PendingMessages is the number of messages which are waiting to be sent by the phone
+	PendingMessages int64 `json:"pending_messages" example:"25"`
+	DailySendLimit  uint  `json:"daily_send_limit" example:"300"`
+	// DailySentMessages is the number of messages which the phone has sent or is sending today
+	DailySentMessages uint `json:"daily_sent_messages" example:"275"`
+	// DailySendRemaining is the number of messages which the phone can still send today, it is nil when the phone has no daily send limit
+	DailySendRemaining *uint `json:"daily_send_remaining" example:"25"`
+	// DailySendResetAt is the time when the daily send limit resets, it is nil when the phone has no daily send limit
+	DailySendResetAt *time.Time `json:"daily_send_reset_at" example:"2022-06-06T00:00:00+01:00"`
+}
+
+// NewPhoneQueueStats creates the PhoneQueueStats of a phone with the messages which it has sent today
+func NewPhoneQueueStats(phone *Phone, pending int64, sent uint, resetAt time.Time) *PhoneQueueStats {
+	stats := &PhoneQueueStats{
+		PhoneID:           phone.ID,
+		Owner:             phone.PhoneNumber,
+		IsPaused:          phone.IsPaused,
+		PendingMessages:   pending,
+		DailySendLimit:    phone.DailySendLimit,
+		DailySentMessages: sent,
+	}
+
+	if !phone.HasDailySendLimit() {
+		return stats
+	}
+
+	remaining := uint(0)
+	if sent < phone.DailySendLimit {
+		remaining = phone.DailySendLimit - sent
+	}
+	stats.DailySendRemaining = &remaining
+	stats.DailySendResetAt = &resetAt
+	return stats
+}
@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+// PhoneLiveness is the online status of a phone derived from the timestamp of its last heartbeat
+type PhoneLiveness struct {
+	// LastHeartbeatAt is nil when the phone has never sent a heartbeat
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at" example:"2022-06-05T14:26:01.520828+03:00"`
+	IsOnline        bool       `json:"is_online" example:"true"`
+}
+
+// NewPhoneLiveness creates a PhoneLiveness which is online when the last heartbeat was received within the threshold
+func NewPhoneLiveness(lastHeartbeatAt *time.Time, threshold time.Duration, now time.Time) *PhoneLiveness {
+	return &PhoneLiveness{
+		LastHeartbeatAt: lastHeartbeatAt,
+		IsOnline:        lastHeartbeatAt != nil && now.Sub(*lastHeartbeatAt) <= threshold,
+	}
+}
@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPhoneLiveness(t *testing.T) {
+	threshold := 64 * time.Minute
+	now := time.Date(2022, 6, 5, 14, 26, 0, 0, time.UTC)
+
+	t.Run("phone with a recent heartbeat is online", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		lastHeartbeatAt := now.Add(-15 * time.Minute)
+
+		// Act
+		liveness := NewPhoneLiveness(&lastHeartbeatAt, threshold, now)
+
+		// Assert
+		assert.True(t, liveness.IsOnline)
+		assert.Equal(t, &lastHeartbeatAt, liveness.LastHeartbeatAt)
+	})
+
+	t.Run("phone with a stale heartbeat is offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		lastHeartbeatAt := now.Add(-threshold - time.Second)
+
+		// Act
+		liveness := NewPhoneLiveness(&lastHeartbeatAt, threshold, now)
+
+		// Assert
+		assert.False(t, liveness.IsOnline)
+		assert.Equal(t, &lastHeartbeatAt, liveness.LastHeartbeatAt)
+	})
+
+	t.Run("phone which has never sent a heartbeat is offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		liveness := NewPhoneLiveness(nil, threshold, now)
+
+		// Assert
+		assert.False(t, liveness.IsOnline)
+		assert.Nil(t, liveness.LastHeartbeatAt)
+	})
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneSIM is a SIM card subscription which is registered on an android phone
+type PhoneSIM struct {
+	ID      uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	PhoneID uuid.UUID `json:"phone_id" gorm:"type:uuid;index:idx_phone_sims_phone_id_slot,unique" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID  UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	// Slot is the 0 based index of the SIM card slot on the phone
+	Slot        uint      `json:"slot" gorm:"index:idx_phone_sims_phone_id_slot,unique" example:"0"`
+	CarrierName string    `json:"carrier_name" example:"T-Mobile"`
+	PhoneNumber *string   `json:"phone_number" example:"+18005550199"`
+	CreatedAt   time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// SIM returns the SIM used when sending messages through the slot
+func (sim PhoneSIM) SIM() SIM {
+	return SIM(fmt.Sprintf("SIM%d", sim.Slot+1))
+}
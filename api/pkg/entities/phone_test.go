@@ -0,0 +1,325 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhone_MaxSendAttemptsSanitized(t *testing.T) {
+	t.Run("default is used when max send attempts is not set", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{}
+
+		// Act
+		attempts := phone.MaxSendAttemptsSanitized()
+
+		// Assert
+		assert.Equal(t, uint(DefaultMaxSendAttempts), attempts)
+	})
+
+	t.Run("phones with different max send attempts fail identical messages at different attempts", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		failFast := &Phone{MaxSendAttempts: 1}
+		retryMore := &Phone{MaxSendAttempts: 4}
+
+		// Act
+		failFastAttempts := sendUntilFailed(&Message{MaxSendAttempts: failFast.MaxSendAttemptsSanitized()})
+		retryMoreAttempts := sendUntilFailed(&Message{MaxSendAttempts: retryMore.MaxSendAttemptsSanitized()})
+
+		// Assert
+		assert.Equal(t, uint(1), failFastAttempts)
+		assert.Equal(t, uint(4), retryMoreAttempts)
+	})
+
+	t.Run("changing the phone does not change a message which is in flight", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{MaxSendAttempts: 3}
+		message := &Message{MaxSendAttempts: phone.MaxSendAttemptsSanitized()}
+
+		// Act
+		phone.MaxSendAttempts = 10
+		attempts := sendUntilFailed(message)
+
+		// Assert
+		assert.Equal(t, uint(3), attempts)
+	})
+}
+
+func sendUntilFailed(message *Message) uint {
+	for message.CanBeRescheduled() {
+		message.AddSendAttemptCount()
+		message.AddSendAttempt(time.Now().UTC())
+	}
+	return message.SendAttemptCount
+}
+
+func TestPhone_HeartbeatDeadThreshold(t *testing.T) {
+	t.Run("fallback is used when the threshold is not set", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{}
+
+		// Act
+		threshold := phone.HeartbeatDeadThreshold(time.Hour)
+
+		// Assert
+		assert.Equal(t, time.Hour, threshold)
+	})
+
+	t.Run("threshold of the phone overrides the fallback", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{HeartbeatDeadThresholdSeconds: 7200}
+
+		// Act
+		threshold := phone.HeartbeatDeadThreshold(time.Hour)
+
+		// Assert
+		assert.Equal(t, 2*time.Hour, threshold)
+	})
+}
+
+func TestPhone_DailySendLimitLocation(t *testing.T) {
+	t.Run("timezone of the phone is used", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{DailySendLimit: 300, DailySendLimitTimezone: "Africa/Douala"}
+
+		// Act
+		location := phone.DailySendLimitLocation(time.UTC)
+
+		// Assert
+		assert.Equal(t, "Africa/Douala", location.String())
+	})
+
+	t.Run("fallback is used when the timezone is invalid", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{DailySendLimit: 300, DailySendLimitTimezone: "Mars/Olympus"}
+
+		// Act
+		location := phone.DailySendLimitLocation(time.UTC)
+
+		// Assert
+		assert.Equal(t, time.UTC, location)
+	})
+}
+
+func TestNewPhoneQueueStats(t *testing.T) {
+	resetAt := time.Date(2022, 6, 6, 0, 0, 0, 0, time.UTC)
+
+	t.Run("remaining quota is the daily send limit minus the sent messages", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{PhoneNumber: "+18005550199", DailySendLimit: 300}
+
+		// Act
+		stats := NewPhoneQueueStats(phone, 25, 275, resetAt)
+
+		// Assert
+		assert.Equal(t, uint(25), *stats.DailySendRemaining)
+		assert.Equal(t, resetAt, *stats.DailySendResetAt)
+	})
+
+	t.Run("remaining quota is 0 when the phone sent more than the limit", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{PhoneNumber: "+18005550199", DailySendLimit: 300}
+
+		// Act
+		stats := NewPhoneQueueStats(phone, 25, 301, resetAt)
+
+		// Assert
+		assert.Equal(t, uint(0), *stats.DailySendRemaining)
+	})
+
+	t.Run("remaining quota is nil when the phone has no daily send limit", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{PhoneNumber: "+18005550199"}
+
+		// Act
+		stats := NewPhoneQueueStats(phone, 25, 301, resetAt)
+
+		// Assert
+		assert.Nil(t, stats.DailySendRemaining)
+		assert.Nil(t, stats.DailySendResetAt)
+	})
+}
+
+func TestPhone_AddClockSkewSample(t *testing.T) {
+	receivedAt := time.Date(2022, time.June, 5, 14, 26, 0, 0, time.UTC)
+
+	t.Run("positive skew when the clock of the phone is ahead", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{}
+
+		// Act
+		phone.AddClockSkewSample(receivedAt.Add(3*time.Minute), receivedAt)
+
+		// Assert
+		assert.Equal(t, 3*time.Minute, phone.ClockSkew())
+		assert.Equal(t, receivedAt, phone.CorrectTimestamp(receivedAt.Add(3*time.Minute)))
+		assert.Equal(t, receivedAt, *phone.ClockSkewUpdatedAt)
+	})
+
+	t.Run("negative skew when the clock of the phone is behind", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{}
+
+		// Act
+		phone.AddClockSkewSample(receivedAt.Add(-90*time.Second), receivedAt)
+
+		// Assert
+		assert.Equal(t, int64(-90000), phone.ClockSkewMilliseconds)
+		assert.Equal(t, receivedAt, phone.CorrectTimestamp(receivedAt.Add(-90*time.Second)))
+	})
+
+	t.Run("samples are smoothed with a moving average", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{}
+		phone.AddClockSkewSample(receivedAt.Add(10*time.Second), receivedAt)
+
+		// Act
+		phone.AddClockSkewSample(receivedAt.Add(time.Minute+20*time.Second), receivedAt.Add(time.Minute))
+		afterOutlier := phone.ClockSkew()
+		for i := 0; i < 20; i++ {
+			phone.AddClockSkewSample(receivedAt.Add(10*time.Second), receivedAt)
+		}
+
+		// Assert
+		assert.Equal(t, 12*time.Second, afterOutlier)
+		assert.InDelta(t, float64(10*time.Second), float64(phone.ClockSkew()), float64(50*time.Millisecond))
+	})
+
+	t.Run("timestamps are not changed without a sample", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{}
+
+		// Act
+		timestamp := phone.CorrectTimestamp(receivedAt)
+
+		// Assert
+		assert.Equal(t, receivedAt, timestamp)
+		assert.Nil(t, phone.ClockSkewUpdatedAt)
+	})
+}
+
+func TestClampPhoneTimestamp(t *testing.T) {
+	now := time.Date(2022, time.June, 5, 14, 26, 0, 0, time.UTC)
+	futureTolerance := 5 * time.Minute
+	pastTolerance := 30 * 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		expected  time.Time
+		clamped   bool
+	}{
+		{name: "timestamp at the time of the server", timestamp: now, expected: now, clamped: false},
+		{name: "timestamp in the future within the tolerance", timestamp: now.Add(4 * time.Minute), expected: now.Add(4 * time.Minute), clamped: false},
+		{name: "timestamp in the future at the tolerance", timestamp: now.Add(futureTolerance), expected: now.Add(futureTolerance), clamped: false},
+		{name: "timestamp a day in the future", timestamp: now.Add(24 * time.Hour), expected: now, clamped: true},
+		{name: "timestamp in the past within the tolerance", timestamp: now.Add(-29 * 24 * time.Hour), expected: now.Add(-29 * 24 * time.Hour), clamped: false},
+		{name: "timestamp before the past tolerance", timestamp: now.Add(-31 * 24 * time.Hour), expected: now, clamped: true},
+		{name: "timestamp of a phone with a reset clock", timestamp: time.Unix(0, 0).UTC(), expected: now, clamped: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			timestamp, clamped := ClampPhoneTimestamp(test.timestamp, now, futureTolerance, pastTolerance)
+
+			// Assert
+			assert.Equal(t, test.expected, timestamp)
+			assert.Equal(t, test.clamped, clamped)
+		})
+	}
+}
+
+func TestPhone_HasSIM(t *testing.T) {
+	tests := []struct {
+		name  string
+		sims  []PhoneSIM
+		sim   SIM
+		valid bool
+	}{
+		{name: "any SIM is valid when the phone has not registered a SIM", sim: SIM2, valid: true},
+		{name: "registered SIM of a single SIM phone is valid", sims: []PhoneSIM{{Slot: 0}}, sim: SIM1, valid: true},
+		{name: "second SIM of a single SIM phone is not valid", sims: []PhoneSIM{{Slot: 0}}, sim: SIM2, valid: false},
+		{name: "second SIM of a dual SIM phone is valid", sims: []PhoneSIM{{Slot: 0}, {Slot: 1}}, sim: SIM2, valid: true},
+		{name: "first SIM is not valid when only the second slot has a SIM", sims: []PhoneSIM{{Slot: 1}}, sim: SIM1, valid: false},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Arrange
+			phone := &Phone{SIMs: test.sims}
+
+			// Act
+			valid := phone.HasSIM(test.sim)
+
+			// Assert
+			assert.Equal(t, test.valid, valid)
+		})
+	}
+}
+
+func TestPhone_RegisteredSIMs(t *testing.T) {
+	// Setup
+	t.Parallel()
+
+	// Arrange
+	phone := &Phone{SIMs: []PhoneSIM{{Slot: 0}, {Slot: 1}}}
+
+	// Act
+	sims := phone.RegisteredSIMs()
+
+	// Assert
+	assert.Equal(t, []string{"SIM1", "SIM2"}, sims)
+}
@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// DefaultRetryBackoffCapSeconds is the longest delay between two attempts when a RetryPolicy does not set BackoffCapSeconds
+	DefaultRetryBackoffCapSeconds = 60 * 60
+
+	// DefaultRetryBackoffMultiplier is the growth of the delay between attempts when a RetryPolicy does not set BackoffMultiplier
+	DefaultRetryBackoffMultiplier = 2
+)
+
+// RetryPolicy determines how many times a message is sent to the phone and how long to wait before it is sent again
+// when an attempt expires. A field which is 0 is not set and the value of the phone or the defaults is used instead.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a message is sent to the phone before it fails
+	MaxAttempts uint `json:"max_attempts" gorm:"default:0" example:"3"`
+	// BackoffBaseSeconds is the delay before the first retry, an expired message is retried immediately when it is 0
+	BackoffBaseSeconds uint `json:"backoff_base_seconds" gorm:"default:0" example:"30"`
+	// BackoffCapSeconds is the longest delay between two attempts
+	BackoffCapSeconds uint `json:"backoff_cap_seconds" gorm:"default:0" example:"3600"`
+	// BackoffMultiplier multiplies the delay after every retry
+	BackoffMultiplier float64 `json:"backoff_multiplier" gorm:"default:0" example:"2"`
+}
+
+// Override replaces the fields of the policy with the fields which are set in the override
+func (policy RetryPolicy) Override(override RetryPolicy) RetryPolicy {
+	if override.MaxAttempts != 0 {
+		policy.MaxAttempts = override.MaxAttempts
+	}
+	if override.BackoffBaseSeconds != 0 {
+		policy.BackoffBaseSeconds = override.BackoffBaseSeconds
+	}
+	if override.BackoffCapSeconds != 0 {
+		policy.BackoffCapSeconds = override.BackoffCapSeconds
+	}
+	if override.BackoffMultiplier != 0 {
+		policy.BackoffMultiplier = override.BackoffMultiplier
+	}
+	return policy
+}
+
+// Backoff is the delay before the next attempt after the number of attempts which have expired. The delay starts at
+// BackoffBaseSeconds and grows by BackoffMultiplier after every attempt until it reaches BackoffCapSeconds.
+func (policy RetryPolicy) Backoff(attempts uint) time.Duration {
+	if policy.BackoffBaseSeconds == 0 {
+		return 0
+	}
+
+	limit := float64(policy.BackoffCapSeconds)
+	if limit == 0 {
+		limit = DefaultRetryBackoffCapSeconds
+	}
+
+	multiplier := policy.BackoffMultiplier
+	if multiplier == 0 {
+		multiplier = DefaultRetryBackoffMultiplier
+	}
+
+	exponent := float64(0)
+	if attempts > 1 {
+		exponent = float64(attempts - 1)
+	}
+
+	seconds := math.Min(float64(policy.BackoffBaseSeconds)*math.Pow(math.Max(multiplier, 1), exponent), limit)
+	return time.Duration(seconds * float64(time.Second))
+}
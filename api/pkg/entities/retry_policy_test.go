@@ -0,0 +1,105 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Run("an expired message is retried immediately without a backoff base", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		policy := (&Phone{}).RetryPolicy()
+
+		// Act
+		backoff := policy.Backoff(1)
+
+		// Assert
+		assert.Equal(t, time.Duration(0), backoff)
+	})
+
+	t.Run("the backoff grows by the multiplier until the cap", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		policy := RetryPolicy{BackoffBaseSeconds: 60, BackoffCapSeconds: 600, BackoffMultiplier: 3}
+
+		// Act
+		backoffs := []time.Duration{policy.Backoff(1), policy.Backoff(2), policy.Backoff(3), policy.Backoff(4), policy.Backoff(40)}
+
+		// Assert
+		assert.Equal(t, []time.Duration{time.Minute, 3 * time.Minute, 9 * time.Minute, 10 * time.Minute, 10 * time.Minute}, backoffs)
+	})
+
+	t.Run("the defaults are used for the cap and the multiplier which are not set", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		policy := RetryPolicy{BackoffBaseSeconds: 1800}
+
+		// Act
+		backoffs := []time.Duration{policy.Backoff(1), policy.Backoff(2), policy.Backoff(3)}
+
+		// Assert
+		assert.Equal(t, []time.Duration{30 * time.Minute, time.Hour, time.Hour}, backoffs)
+	})
+}
+
+func TestRetryPolicy_Override(t *testing.T) {
+	t.Run("the policy of the user overrides the phone and the message overrides the user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &Phone{MaxSendAttempts: 4}
+		user := RetryPolicy{MaxAttempts: 2, BackoffBaseSeconds: 5}
+		message := RetryPolicy{BackoffBaseSeconds: 10}
+
+		// Act
+		policy := phone.RetryPolicy().Override(user).Override(message)
+
+		// Assert
+		assert.Equal(t, RetryPolicy{
+			MaxAttempts:        2,
+			BackoffBaseSeconds: 10,
+			BackoffCapSeconds:  DefaultRetryBackoffCapSeconds,
+			BackoffMultiplier:  DefaultRetryBackoffMultiplier,
+		}, policy)
+	})
+}
+
+func TestMessage_RetryBackoff(t *testing.T) {
+	t.Run("identical messages with different policies have different retry timelines", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		fast := &Message{MaxSendAttempts: 2, RetryBackoffBaseSeconds: 5, RetryBackoffCapSeconds: 5}
+		patient := &Message{MaxSendAttempts: 4, RetryBackoffBaseSeconds: 300, RetryBackoffCapSeconds: 3600, RetryBackoffMultiplier: 4}
+
+		// Act
+		fastTimeline := retryUntilFailed(fast)
+		patientTimeline := retryUntilFailed(patient)
+
+		// Assert
+		assert.Equal(t, []time.Duration{5 * time.Second}, fastTimeline)
+		assert.Equal(t, []time.Duration{5 * time.Minute, 20 * time.Minute, time.Hour}, patientTimeline)
+	})
+}
+
+func retryUntilFailed(message *Message) []time.Duration {
+	var backoffs []time.Duration
+	for {
+		message.AddSendAttemptCount()
+		if !message.CanBeRescheduled() {
+			return backoffs
+		}
+		backoffs = append(backoffs, message.RetryBackoff())
+	}
+}
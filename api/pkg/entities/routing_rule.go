@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RoutingRule sends the messages of a user to phone numbers which start with Prefix from the phone with the Owner
+// number e.g. +44 numbers from the UK phone. A rule with an empty Prefix is the default route of the user.
+type RoutingRule struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"uniqueIndex:idx_routing_rules_user_id_prefix" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	// Prefix is the start of the phone number of the contact in the E.164 format, it is empty for the default route
+	Prefix    string    `json:"prefix" gorm:"uniqueIndex:idx_routing_rules_user_id_prefix" example:"+44"`
+	Owner     string    `json:"owner" example:"+447700900000"`
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// IsDefault is true when the RoutingRule matches every phone number
+func (rule *RoutingRule) IsDefault() bool {
+	return rule.Prefix == ""
+}
+
+// Matches is true when the phone number starts with the Prefix of the RoutingRule
+func (rule *RoutingRule) Matches(phoneNumber string) bool {
+	return strings.HasPrefix(phoneNumber, rule.Prefix)
+}
+
+// MatchRoutingRule returns the RoutingRule with the longest Prefix which matches the phone number, the default route
+// is returned when no other rule matches and it is nil when the user has no default route.
+func MatchRoutingRule(rules []*RoutingRule, phoneNumber string) *RoutingRule {
+	var match *RoutingRule
+	for _, rule := range rules {
+		if rule.Matches(phoneNumber) && (match == nil || len(rule.Prefix) > len(match.Prefix)) {
+			match = rule
+		}
+	}
+	return match
+}
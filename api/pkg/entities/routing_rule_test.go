@@ -0,0 +1,71 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchRoutingRule(t *testing.T) {
+	defaultRoute := &RoutingRule{Prefix: "", Owner: "+18005550199"}
+	uk := &RoutingRule{Prefix: "+44", Owner: "+447700900000"}
+	london := &RoutingRule{Prefix: "+4420", Owner: "+447700900001"}
+	cameroon := &RoutingRule{Prefix: "+237", Owner: "+237670000000"}
+
+	t.Run("longest of the overlapping prefixes is matched", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rules := []*RoutingRule{uk, london, cameroon, defaultRoute}
+
+		// Act
+		rule := MatchRoutingRule(rules, "+442071838750")
+
+		// Assert
+		assert.Equal(t, london, rule)
+	})
+
+	t.Run("order of the rules does not change the match", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rules := []*RoutingRule{defaultRoute, london, uk, cameroon}
+
+		// Act
+		rule := MatchRoutingRule(rules, "+447911123456")
+
+		// Assert
+		assert.Equal(t, uk, rule)
+	})
+
+	t.Run("default route is matched when no prefix matches", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rules := []*RoutingRule{uk, london, defaultRoute, cameroon}
+
+		// Act
+		rule := MatchRoutingRule(rules, "+18005550100")
+
+		// Assert
+		assert.Equal(t, defaultRoute, rule)
+		assert.True(t, rule.IsDefault())
+	})
+
+	t.Run("nothing is matched without a default route", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		rules := []*RoutingRule{uk, london, cameroon}
+
+		// Act
+		rule := MatchRoutingRule(rules, "+18005550100")
+
+		// Assert
+		assert.Nil(t, rule)
+	})
+}
@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShortLink is a short URL in the content of an outgoing message which redirects to the URL which was in the content
+// when the message was sent, the clicks of the short URL are counted.
+type ShortLink struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID    UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	MessageID uuid.UUID `json:"message_id" gorm:"index;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	// Code is the random part of the short URL e.g. https://api.httpsms.com/s/aB3dE6gH
+	Code string `json:"code" gorm:"uniqueIndex;size:16" example:"aB3dE6gH"`
+	// ShortURL is the URL in the content of the message
+	ShortURL      string     `json:"short_url" example:"https://api.httpsms.com/s/aB3dE6gH"`
+	URL           string     `json:"url" example:"https://example.com/orders/12345?utm_source=sms"`
+	Clicks        uint       `json:"clicks" example:"3"`
+	LastClickedAt *time.Time `json:"last_clicked_at" example:"2022-06-05T14:26:09.527976+03:00"`
+	CreatedAt     time.Time  `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt     time.Time  `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// contentURLPattern matches the http and https URLs in the content of a message
+var contentURLPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// ContentURLs returns the distinct http and https URLs in the content of a message in the order in which they appear.
+// The punctuation at the end of a URL e.g. the full stop of a sentence is not part of the URL.
+func ContentURLs(content string) []string {
+	var urls []string
+	seen := map[string]bool{}
+	for _, match := range contentURLPattern.FindAllString(content, -1) {
+		url := trimContentURL(match)
+		if strings.HasSuffix(url, "://") || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// ReplaceContentURLs replaces the URLs in the content of a message which are keys of the replacements with their value
+func ReplaceContentURLs(content string, replacements map[string]string) string {
+	if len(replacements) == 0 {
+		return content
+	}
+
+	return contentURLPattern.ReplaceAllStringFunc(content, func(match string) string {
+		url := trimContentURL(match)
+		replacement, ok := replacements[url]
+		if !ok {
+			return match
+		}
+		return replacement + strings.TrimPrefix(match, url)
+	})
+}
+
+// trimContentURL removes the punctuation at the end of a URL, a closing parenthesis is kept when the URL has an
+// opening parenthesis e.g. https://en.wikipedia.org/wiki/Go_(programming_language)
+func trimContentURL(url string) string {
+	for len(url) > 0 {
+		last := url[len(url)-1]
+		if strings.IndexByte(".,;:!?", last) >= 0 || (last == ')' && strings.Count(url, "(") < strings.Count(url, ")")) {
+			url = url[:len(url)-1]
+			continue
+		}
+		return url
+	}
+	return url
+}
@@ -0,0 +1,60 @@
+package entities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentURLs(t *testing.T) {
+	t.Run("punctuation at the end of a url is not part of it", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		urls := ContentURLs("Track your order at https://example.com/orders/1?ref=sms. Questions? (see https://example.com/help)")
+
+		// Assert
+		assert.Equal(t, []string{"https://example.com/orders/1?ref=sms", "https://example.com/help"}, urls)
+	})
+
+	t.Run("urls are distinct", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		urls := ContentURLs("http://example.com/a http://example.com/a, https://en.wikipedia.org/wiki/Go_(programming_language)")
+
+		// Assert
+		assert.Equal(t, []string{"http://example.com/a", "https://en.wikipedia.org/wiki/Go_(programming_language)"}, urls)
+	})
+
+	t.Run("content without urls", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		urls := ContentURLs("This is a sample text message, visit example.com or https://")
+
+		// Assert
+		assert.Empty(t, urls)
+	})
+}
+
+func TestReplaceContentURLs(t *testing.T) {
+	t.Run("only the replaced urls are changed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		content := "Pay at https://example.com/invoices/12345?token=abcdef. Docs: https://example.com/a"
+
+		// Act
+		replaced := ReplaceContentURLs(content, map[string]string{
+			"https://example.com/invoices/12345?token=abcdef": "https://httpsms.com/s/aB3dE6gH",
+		})
+
+		// Assert
+		assert.Equal(t, "Pay at https://httpsms.com/s/aB3dE6gH. Docs: https://example.com/a", replaced)
+	})
+}
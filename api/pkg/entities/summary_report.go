@@ -0,0 +1,189 @@
+package entities
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SummaryReportFrequency is how often a SummaryReport is sent
+type SummaryReportFrequency string
+
+const (
+	// SummaryReportFrequencyDaily sends the report of the previous day
+	SummaryReportFrequencyDaily = SummaryReportFrequency("daily")
+
+	// SummaryReportFrequencyWeekly sends the report of the previous week from Monday to Sunday on Monday
+	SummaryReportFrequencyWeekly = SummaryReportFrequency("weekly")
+)
+
+// SummaryReportSchedule are the settings of the email with the summary of the messages of a user in the previous day or week
+type SummaryReportSchedule struct {
+	UserID    UserID                 `json:"user_id" gorm:"primaryKey;type:string;" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Enabled   bool                   `json:"enabled" gorm:"index" example:"true"`
+	Frequency SummaryReportFrequency `json:"frequency" example:"daily"`
+
+	// Hour is the hour of the day in the timezone at which the report of the previous period is sent
+	Hour     uint   `json:"hour" example:"8"`
+	Timezone string `json:"timezone" example:"Europe/Berlin"`
+
+	// SkipEmpty does not send the report of a period in which no message was sent or received
+	SkipEmpty bool `json:"skip_empty" example:"true"`
+
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// DefaultSummaryReportSchedule is the SummaryReportSchedule of a user who has not enabled the summary report
+func DefaultSummaryReportSchedule(user *User) *SummaryReportSchedule {
+	return &SummaryReportSchedule{
+		UserID:    user.ID,
+		Enabled:   false,
+		Frequency: SummaryReportFrequencyDaily,
+		Hour:      8,
+		Timezone:  user.Timezone,
+		SkipEmpty: true,
+	}
+}
+
+// Location is the location of the Timezone, it is UTC when the timezone is not valid
+func (schedule *SummaryReportSchedule) Location() *time.Location {
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// PeriodStart is the start of the day or the week which contains the timestamp in the timezone of the schedule.
+// The periods start at midnight so a period is 23 or 25 hours long when the clocks change.
+func (schedule *SummaryReportSchedule) PeriodStart(timestamp time.Time) time.Time {
+	local := timestamp.In(schedule.Location())
+	day := local.Day()
+	if schedule.Frequency == SummaryReportFrequencyWeekly {
+		day -= (int(local.Weekday()) + 6) % 7
+	}
+	return time.Date(local.Year(), local.Month(), day, 0, 0, 0, 0, local.Location())
+}
+
+// PeriodEnd is the end of the period which starts at start, it is the start of the next period
+func (schedule *SummaryReportSchedule) PeriodEnd(start time.Time) time.Time {
+	return schedule.shift(start, 1)
+}
+
+// DueAt is the time at which the report of the period which starts at start is sent, it is the Hour of the day on
+// which the period ends
+func (schedule *SummaryReportSchedule) DueAt(start time.Time) time.Time {
+	end := schedule.PeriodEnd(start)
+	return time.Date(end.Year(), end.Month(), end.Day(), int(schedule.Hour), 0, 0, 0, end.Location())
+}
+
+// DuePeriods returns the start of the periods whose report is due at now, oldest first. The periods start after the
+// period which starts at last, which is the period of the last report, and only the latest limit periods are returned
+// so that a schedule which was not run for a long time does not send a report for every missed period. The period
+// whose report was due before the schedule was created is not returned when no report has been sent.
+func (schedule *SummaryReportSchedule) DuePeriods(last *time.Time, now time.Time, limit int) []time.Time {
+	latest := schedule.shift(schedule.PeriodStart(now), -1)
+	if schedule.DueAt(latest).After(now) {
+		latest = schedule.shift(latest, -1)
+	}
+
+	if last == nil {
+		if schedule.DueAt(latest).Before(schedule.CreatedAt) {
+			return nil
+		}
+		return []time.Time{latest}
+	}
+
+	var periods []time.Time
+	for start := latest; start.After(*last) && len(periods) < limit; start = schedule.shift(start, -1) {
+		periods = append(periods, start)
+	}
+
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].Before(periods[j])
+	})
+	return periods
+}
+
+// shift moves the start of a period by a number of periods in the timezone of the schedule
+func (schedule *SummaryReportSchedule) shift(start time.Time, periods int) time.Time {
+	days := periods
+	if schedule.Frequency == SummaryReportFrequencyWeekly {
+		days *= 7
+	}
+	local := start.In(schedule.Location())
+	return time.Date(local.Year(), local.Month(), local.Day()+days, 0, 0, 0, 0, local.Location())
+}
+
+// SummaryReport is the summary of the messages of a user in the period of a SummaryReportSchedule. It is stored before
+// the email is sent so that the report of a period is sent once when the scheduler runs more than once.
+type SummaryReport struct {
+	ID          uuid.UUID              `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID      UserID                 `json:"user_id" gorm:"uniqueIndex:idx_summary_reports_user_id_frequency_period_start,priority:1" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Frequency   SummaryReportFrequency `json:"frequency" gorm:"uniqueIndex:idx_summary_reports_user_id_frequency_period_start,priority:2" example:"daily"`
+	PeriodStart time.Time              `json:"period_start" gorm:"uniqueIndex:idx_summary_reports_user_id_frequency_period_start,priority:3" example:"2022-06-05T00:00:00+02:00"`
+	PeriodEnd   time.Time              `json:"period_end" example:"2022-06-06T00:00:00+02:00"`
+	Timezone    string                 `json:"timezone" example:"Europe/Berlin"`
+
+	// Outgoing is the number of messages which were sent with the API in the period
+	Outgoing  int64 `json:"outgoing" example:"412"`
+	Delivered int64 `json:"delivered" example:"396"`
+	Failed    int64 `json:"failed" example:"9"`
+	Expired   int64 `json:"expired" example:"7"`
+	// Incoming is the number of messages which were received by the phones in the period
+	Incoming int64 `json:"incoming" example:"35"`
+
+	// BusiestContact is the contact with the most messages in the period, it is nil when there are no messages
+	BusiestContact      *string `json:"busiest_contact" example:"+18005550100"`
+	BusiestContactCount int64   `json:"busiest_contact_count" example:"120"`
+
+	// Skipped is true when the email was not sent because there were no messages in the period
+	Skipped bool `json:"skipped" example:"false"`
+
+	CreatedAt time.Time `json:"created_at" example:"2022-06-06T08:00:02.302718+02:00"`
+}
+
+// NewSummaryReport creates the SummaryReport of the period which starts at start from the hourly counts of the
+// messages in the period and the contacts with the most messages
+func NewSummaryReport(schedule *SummaryReportSchedule, start time.Time, counts []*MessageHourlyCount, contacts []*MessageContactCount) *SummaryReport {
+	report := &SummaryReport{
+		ID:          uuid.New(),
+		UserID:      schedule.UserID,
+		Frequency:   schedule.Frequency,
+		PeriodStart: start.UTC(),
+		PeriodEnd:   schedule.PeriodEnd(start).UTC(),
+		Timezone:    schedule.Location().String(),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	for _, count := range counts {
+		if count.Type == MessageTypeMobileOriginated {
+			report.Incoming += count.Count
+			continue
+		}
+
+		report.Outgoing += count.Count
+		switch count.Status {
+		case MessageStatusDelivered:
+			report.Delivered += count.Count
+		case MessageStatusFailed:
+			report.Failed += count.Count
+		case MessageStatusExpired:
+			report.Expired += count.Count
+		}
+	}
+
+	if len(contacts) > 0 {
+		report.BusiestContact = &contacts[0].Contact
+		report.BusiestContactCount = contacts[0].Count
+	}
+
+	return report
+}
+
+// IsEmpty is true when no message was sent or received in the period of the report
+func (report *SummaryReport) IsEmpty() bool {
+	return report.Outgoing == 0 && report.Incoming == 0
+}
@@ -0,0 +1,279 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryReportSchedule_PeriodStart(t *testing.T) {
+	t.Run("a daily period starts at midnight in the timezone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Timezone: "Africa/Douala"}
+
+		// Act
+		start := schedule.PeriodStart(time.Date(2022, 6, 5, 23, 30, 0, 0, time.UTC))
+
+		// Assert
+		assert.Equal(t, "2022-06-06T00:00:00+01:00", start.Format(time.RFC3339))
+	})
+
+	t.Run("a weekly period starts on Monday and a Sunday is in the week which started on the previous Monday", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyWeekly, Timezone: "America/New_York"}
+
+		// Act
+		sunday := schedule.PeriodStart(time.Date(2022, 6, 5, 12, 0, 0, 0, time.UTC))
+		monday := schedule.PeriodStart(time.Date(2022, 6, 6, 12, 0, 0, 0, time.UTC))
+
+		// Assert
+		assert.Equal(t, "2022-05-30T00:00:00-04:00", sunday.Format(time.RFC3339))
+		assert.Equal(t, "2022-06-06T00:00:00-04:00", monday.Format(time.RFC3339))
+	})
+
+	t.Run("an invalid timezone uses UTC", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Timezone: "Mars/Olympus"}
+
+		// Act
+		start := schedule.PeriodStart(time.Date(2022, 6, 5, 23, 30, 0, 0, time.UTC))
+
+		// Assert
+		assert.Equal(t, "2022-06-05T00:00:00Z", start.Format(time.RFC3339))
+	})
+}
+
+func TestSummaryReportSchedule_PeriodEnd(t *testing.T) {
+	t.Run("the day on which the clocks go forward is 23 hours long", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Timezone: "Europe/Berlin"}
+		start := schedule.PeriodStart(time.Date(2022, 3, 27, 12, 0, 0, 0, time.UTC))
+
+		// Act
+		end := schedule.PeriodEnd(start)
+
+		// Assert
+		assert.Equal(t, "2022-03-27T00:00:00+01:00", start.Format(time.RFC3339))
+		assert.Equal(t, "2022-03-28T00:00:00+02:00", end.Format(time.RFC3339))
+		assert.Equal(t, 23*time.Hour, end.Sub(start))
+	})
+
+	t.Run("the day on which the clocks go back is 25 hours long", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Timezone: "Europe/Berlin"}
+		start := schedule.PeriodStart(time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC))
+
+		// Act
+		end := schedule.PeriodEnd(start)
+
+		// Assert
+		assert.Equal(t, "2022-10-30T00:00:00+02:00", start.Format(time.RFC3339))
+		assert.Equal(t, "2022-10-31T00:00:00+01:00", end.Format(time.RFC3339))
+		assert.Equal(t, 25*time.Hour, end.Sub(start))
+	})
+
+	t.Run("the week in which the clocks go forward ends at midnight on Monday", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyWeekly, Timezone: "America/New_York"}
+		start := schedule.PeriodStart(time.Date(2022, 3, 13, 12, 0, 0, 0, time.UTC))
+
+		// Act
+		end := schedule.PeriodEnd(start)
+
+		// Assert
+		assert.Equal(t, "2022-03-07T00:00:00-05:00", start.Format(time.RFC3339))
+		assert.Equal(t, "2022-03-14T00:00:00-04:00", end.Format(time.RFC3339))
+		assert.Equal(t, 7*24*time.Hour-time.Hour, end.Sub(start))
+	})
+}
+
+func TestSummaryReportSchedule_DueAt(t *testing.T) {
+	t.Run("the report is due at the hour in the timezone after the clocks go forward", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Hour: 8, Timezone: "Europe/Berlin"}
+		start := time.Date(2022, 3, 26, 0, 0, 0, 0, schedule.Location())
+
+		// Act
+		dueAt := schedule.DueAt(start)
+
+		// Assert
+		assert.Equal(t, time.Date(2022, 3, 27, 6, 0, 0, 0, time.UTC), dueAt.UTC())
+	})
+
+	t.Run("the weekly report is due on the Monday after the week", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyWeekly, Hour: 9, Timezone: "America/New_York"}
+		start := time.Date(2022, 3, 7, 0, 0, 0, 0, schedule.Location())
+
+		// Act
+		dueAt := schedule.DueAt(start)
+
+		// Assert
+		assert.Equal(t, time.Date(2022, 3, 14, 13, 0, 0, 0, time.UTC), dueAt.UTC())
+	})
+}
+
+func TestSummaryReportSchedule_DuePeriods(t *testing.T) {
+	location, _ := time.LoadLocation("Europe/Berlin")
+	createdAt := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("the previous day is not due before the hour", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Hour: 8, Timezone: "Europe/Berlin", CreatedAt: createdAt}
+		last := time.Date(2022, 6, 4, 0, 0, 0, 0, location)
+
+		// Act
+		before := schedule.DuePeriods(&last, time.Date(2022, 6, 6, 5, 59, 0, 0, time.UTC), 7)
+		after := schedule.DuePeriods(&last, time.Date(2022, 6, 6, 6, 0, 0, 0, time.UTC), 7)
+
+		// Assert
+		assert.Empty(t, before)
+		assert.Equal(t, []time.Time{time.Date(2022, 6, 5, 0, 0, 0, 0, location)}, after)
+	})
+
+	t.Run("the period of the last report is not due again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Hour: 8, Timezone: "Europe/Berlin", CreatedAt: createdAt}
+		last := time.Date(2022, 6, 4, 22, 0, 0, 0, time.UTC)
+
+		// Act
+		periods := schedule.DuePeriods(&last, time.Date(2022, 6, 6, 12, 0, 0, 0, time.UTC), 7)
+
+		// Assert
+		assert.Empty(t, periods)
+	})
+
+	t.Run("the missed periods are due oldest first up to the limit", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyDaily, Hour: 8, Timezone: "Europe/Berlin", CreatedAt: createdAt}
+		last := time.Date(2022, 6, 1, 0, 0, 0, 0, location)
+
+		// Act
+		periods := schedule.DuePeriods(&last, time.Date(2022, 6, 6, 7, 0, 0, 0, time.UTC), 3)
+
+		// Assert
+		assert.Equal(t, []time.Time{
+			time.Date(2022, 6, 3, 0, 0, 0, 0, location),
+			time.Date(2022, 6, 4, 0, 0, 0, 0, location),
+			time.Date(2022, 6, 5, 0, 0, 0, 0, location),
+		}, periods)
+	})
+
+	t.Run("only the latest period is due without a last report", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{Frequency: SummaryReportFrequencyWeekly, Hour: 8, Timezone: "Europe/Berlin", CreatedAt: createdAt}
+
+		// Act
+		periods := schedule.DuePeriods(nil, time.Date(2022, 6, 8, 7, 0, 0, 0, time.UTC), 7)
+
+		// Assert
+		assert.Equal(t, []time.Time{time.Date(2022, 5, 30, 0, 0, 0, 0, location)}, periods)
+	})
+
+	t.Run("the period which was due before the schedule was created is not due", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{
+			Frequency: SummaryReportFrequencyDaily,
+			Hour:      8,
+			Timezone:  "Europe/Berlin",
+			CreatedAt: time.Date(2022, 6, 6, 6, 30, 0, 0, time.UTC),
+		}
+
+		// Act
+		periods := schedule.DuePeriods(nil, time.Date(2022, 6, 6, 7, 0, 0, 0, time.UTC), 7)
+
+		// Assert
+		assert.Empty(t, periods)
+	})
+}
+
+func TestNewSummaryReport(t *testing.T) {
+	t.Run("the counts are summed by type and status", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{UserID: "user-id", Frequency: SummaryReportFrequencyDaily, Timezone: "Europe/Berlin"}
+		start := time.Date(2022, 10, 30, 0, 0, 0, 0, schedule.Location())
+		counts := []*MessageHourlyCount{
+			{Type: MessageTypeMobileTerminated, Status: MessageStatusDelivered, Count: 5},
+			{Type: MessageTypeMobileTerminated, Status: MessageStatusDelivered, Count: 2},
+			{Type: MessageTypeMobileTerminated, Status: MessageStatusFailed, Count: 3},
+			{Type: MessageTypeMobileTerminated, Status: MessageStatusExpired, Count: 1},
+			{Type: MessageTypeMobileTerminated, Status: MessageStatusPending, Count: 4},
+			{Type: MessageTypeMobileOriginated, Status: MessageStatusReceived, Count: 6},
+		}
+		contacts := []*MessageContactCount{{Contact: "+18005550100", Count: 9}}
+
+		// Act
+		report := NewSummaryReport(schedule, start, counts, contacts)
+
+		// Assert
+		assert.Equal(t, int64(15), report.Outgoing)
+		assert.Equal(t, int64(7), report.Delivered)
+		assert.Equal(t, int64(3), report.Failed)
+		assert.Equal(t, int64(1), report.Expired)
+		assert.Equal(t, int64(6), report.Incoming)
+		assert.Equal(t, "+18005550100", *report.BusiestContact)
+		assert.Equal(t, int64(9), report.BusiestContactCount)
+		assert.Equal(t, time.Date(2022, 10, 29, 22, 0, 0, 0, time.UTC), report.PeriodStart)
+		assert.Equal(t, time.Date(2022, 10, 30, 23, 0, 0, 0, time.UTC), report.PeriodEnd)
+		assert.False(t, report.IsEmpty())
+	})
+
+	t.Run("a report without messages is empty", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		schedule := &SummaryReportSchedule{UserID: "user-id", Frequency: SummaryReportFrequencyWeekly, Timezone: "UTC"}
+
+		// Act
+		report := NewSummaryReport(schedule, time.Date(2022, 6, 6, 0, 0, 0, 0, time.UTC), nil, nil)
+
+		// Assert
+		assert.True(t, report.IsEmpty())
+		assert.Nil(t, report.BusiestContact)
+		assert.Equal(t, time.Date(2022, 6, 13, 0, 0, 0, 0, time.UTC), report.PeriodEnd)
+	})
+}
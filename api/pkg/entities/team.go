@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TeamRole is the role of a user in a team
+type TeamRole string
+
+const (
+	// TeamRoleAdmin can invite users and carry out destructive operations e.g deleting phones
+	TeamRoleAdmin = TeamRole("admin")
+
+	// TeamRoleMember can read and send messages with the phones of the team
+	TeamRoleMember = TeamRole("member")
+)
+
+// String converts the TeamRole to a string
+func (role TeamRole) String() string {
+	return string(role)
+}
+
+// Team is an account whose phones, messages and webhooks are shared with the members of the team.
+// The ID of a team is the UserID of the account which owns the data so every user has an implicit personal team.
+type Team struct {
+	ID         UserID   `json:"id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Name       string   `json:"name" example:"name@email.com"`
+	Role       TeamRole `json:"role" example:"member"`
+	IsPersonal bool     `json:"is_personal" example:"false"`
+}
+
+// TeamMember gives a user access to the phones, messages and webhooks of a Team
+type TeamMember struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	TeamID    UserID    `json:"team_id" gorm:"uniqueIndex:idx_team_members_team_id_user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	UserID    UserID    `json:"user_id" gorm:"uniqueIndex:idx_team_members_team_id_user_id;index:idx_team_members_user_id" example:"KfMnTaUAdkrBZHLESiGSzaJvhPvW"`
+	Email     string    `json:"email" example:"member@email.com"`
+	Role      TeamRole  `json:"role" example:"member"`
+	CreatedAt time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// TeamInvitation allows the user with the email to join a Team
+type TeamInvitation struct {
+	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	TeamID    UserID    `json:"team_id" gorm:"index:idx_team_invitations_team_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	InvitedBy UserID    `json:"invited_by" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Email     string    `json:"email" example:"member@email.com"`
+	Role      TeamRole  `json:"role" example:"member"`
+	// Token is only returned once when the invitation is created, only the hash of the token is stored
+	Token      string     `json:"token,omitempty" gorm:"-" example:"pDRKJfMnTaUAdkrBZHLESiGSzaJvhPvWD6zu3Ud8LJG9TZzY"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex:idx_team_invitations_token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" example:"2022-06-12T14:26:02.302718+03:00"`
+	AcceptedAt *time.Time `json:"accepted_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	CreatedAt  time.Time  `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt  time.Time  `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// CanBeAcceptedBy checks if the invitation is still valid for the email of a user
+func (invitation *TeamInvitation) CanBeAcceptedBy(email string, timestamp time.Time) bool {
+	return invitation.AcceptedAt == nil && timestamp.Before(invitation.ExpiresAt) && strings.EqualFold(invitation.Email, email)
+}
+
+// HashTeamInvitationToken returns the hash of an invitation token which is stored in the database
+func HashTeamInvitationToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
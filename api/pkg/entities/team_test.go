@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamInvitation_CanBeAcceptedBy(t *testing.T) {
+	timestamp := time.Date(2023, 6, 5, 14, 26, 2, 0, time.UTC)
+
+	t.Run("pending invitation can be accepted by the invited email", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		invitation := &TeamInvitation{Email: "member@email.com", ExpiresAt: timestamp.Add(time.Hour)}
+
+		// Act
+		canBeAccepted := invitation.CanBeAcceptedBy("Member@Email.com", timestamp)
+
+		// Assert
+		assert.True(t, canBeAccepted)
+	})
+
+	t.Run("invitation cannot be accepted by another email", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		invitation := &TeamInvitation{Email: "member@email.com", ExpiresAt: timestamp.Add(time.Hour)}
+
+		// Act
+		canBeAccepted := invitation.CanBeAcceptedBy("name@email.com", timestamp)
+
+		// Assert
+		assert.False(t, canBeAccepted)
+	})
+
+	t.Run("expired invitation cannot be accepted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		invitation := &TeamInvitation{Email: "member@email.com", ExpiresAt: timestamp.Add(-time.Second)}
+
+		// Act
+		canBeAccepted := invitation.CanBeAcceptedBy("member@email.com", timestamp)
+
+		// Assert
+		assert.False(t, canBeAccepted)
+	})
+
+	t.Run("accepted invitation cannot be accepted again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		acceptedAt := timestamp.Add(-time.Minute)
+		invitation := &TeamInvitation{Email: "member@email.com", ExpiresAt: timestamp.Add(time.Hour), AcceptedAt: &acceptedAt}
+
+		// Act
+		canBeAccepted := invitation.CanBeAcceptedBy("member@email.com", timestamp)
+
+		// Assert
+		assert.False(t, canBeAccepted)
+	})
+}
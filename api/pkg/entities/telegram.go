@@ -0,0 +1,45 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Telegram stores the telegram integration which forwards the messages received by a phone to a telegram chat, the
+// replies in the chat are sent as SMS to the contact of the forwarded message
+type Telegram struct {
+	ID     uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	UserID UserID    `json:"user_id" gorm:"index" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Name   string    `json:"name" example:"Support"`
+
+	// Owner is the phone number whose received messages are forwarded to telegram and which sends the replies
+	Owner string `json:"owner" gorm:"index" example:"+18005550199"`
+
+	// BotToken is the token of the telegram bot, it is never returned by the API
+	BotToken string `json:"-"`
+
+	// ChatID is the only chat which receives the messages and whose replies are sent as SMS
+	ChatID int64 `json:"chat_id" example:"123456789"`
+
+	// WebhookSecret is the secret token which telegram sends with the updates of the bot
+	WebhookSecret string `json:"-"`
+
+	// LastUpdateID is the ID of the last update which was handled so that an update which is delivered twice is not sent twice
+	LastUpdateID int64     `json:"-"`
+	CreatedAt    time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	UpdatedAt    time.Time `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// TelegramMessage maps a message in a telegram chat to the contact of the SMS so that a reply to the message can be
+// sent to the contact
+type TelegramMessage struct {
+	TelegramID        uuid.UUID `json:"telegram_id" gorm:"primaryKey;type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	TelegramMessageID int64     `json:"telegram_message_id" gorm:"primaryKey;autoIncrement:false" example:"42"`
+	ChatID            int64     `json:"chat_id" example:"123456789"`
+	UserID            UserID    `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	MessageID         uuid.UUID `json:"message_id" gorm:"type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	Owner             string    `json:"owner" example:"+18005550199"`
+	Contact           string    `json:"contact" example:"+18005550100"`
+	CreatedAt         time.Time `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+}
@@ -0,0 +1,18 @@
+package entities
+
+// TwilioImportRowError is a row of a Twilio export which could not be imported
+type TwilioImportRowError struct {
+	Row    int    `json:"row" example:"12"`
+	SID    string `json:"sid" example:"SM1f0e8ae6ade43cb3c0ce4525424e404f"`
+	Reason string `json:"reason" example:"the status [queued] of an outgoing message is not final"`
+}
+
+// TwilioImportResult is the summary of an import of the messages of a Twilio export, the messages which were imported
+// by a previous import are skipped
+type TwilioImportResult struct {
+	Imported int `json:"imported" example:"980"`
+	Skipped  int `json:"skipped" example:"15"`
+	Errored  int `json:"errored" example:"5"`
+	// Errors are the reasons of the rows which could not be imported, only the first errors are listed when there are too many
+	Errors []TwilioImportRowError `json:"errors"`
+}
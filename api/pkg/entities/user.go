@@ -69,7 +69,10 @@ const SubscriptionName100KYearly = SubscriptionName("100k-yearly")
 type User struct {
 	ID                               UserID           `json:"id" gorm:"primaryKey;type:string;" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
 	Email                            string           `json:"email" example:"name@email.com"`
-	APIKey                           string           `json:"api_key" example:"xyz"`
+	APIKey                           string           `json:"api_key,omitempty" gorm:"-" example:"xyz"`
+	APIKeyHash                       *string          `json:"-" gorm:"index:idx_users_api_key_hash"`
+	PreviousAPIKeyHash               *string          `json:"-" gorm:"index:idx_users_previous_api_key_hash"`
+	PreviousAPIKeyExpiresAt          *time.Time       `json:"previous_api_key_expires_at" example:"2022-06-05T14:26:02.302718+03:00"`
 	Timezone                         string           `json:"timezone" example:"Europe/Helsinki" gorm:"default:Africa/Accra"`
 	ActivePhoneID                    *uuid.UUID       `json:"active_phone_id" gorm:"type:uuid;" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
 	SubscriptionName                 SubscriptionName `json:"subscription_name" example:"free"`
@@ -80,6 +83,16 @@ type User struct {
 	NotificationMessageStatusEnabled bool             `json:"notification_message_status_enabled" gorm:"default:true" example:"true"`
 	NotificationWebhookEnabled       bool             `json:"notification_webhook_enabled" gorm:"default:true" example:"true"`
 	NotificationHeartbeatEnabled     bool             `json:"notification_heartbeat_enabled" gorm:"default:true" example:"true"`
+	NotificationHeartbeatQuietHours  uint             `json:"notification_heartbeat_quiet_hours" gorm:"default:0" example:"2"`
+	FailoverEnabled                  bool             `json:"failover_enabled" gorm:"default:false" example:"false"`
+	FailoverPhoneNumber              *string          `json:"failover_phone_number" example:"+18005550100"`
+	DailyMessageLimit                uint             `json:"daily_message_limit" gorm:"default:0" example:"500"`
+	RetentionReceivedDays            uint             `json:"retention_received_days" gorm:"default:0" example:"30"`
+	RetentionSentDays                uint             `json:"retention_sent_days" gorm:"default:0" example:"0"`
+	RetryPolicy                      RetryPolicy      `json:"retry_policy" gorm:"embedded;embeddedPrefix:retry_policy_"`
+	RequestDeliveryReports           bool             `json:"request_delivery_reports" gorm:"default:true" example:"true"`
+	IsAdmin                          bool             `json:"is_admin" gorm:"default:false" example:"false"`
+	SuspendedAt                      *time.Time       `json:"suspended_at" example:"2022-06-05T14:26:02.302718+03:00"`
 	CreatedAt                        time.Time        `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
 	UpdatedAt                        time.Time        `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
 }
@@ -113,6 +126,38 @@ func (user User) UserTimeString(timestamp time.Time) string {
 	return timestamp.In(location).Format(time.RFC1123)
 }
 
+// HeartbeatQuietPeriod is the duration in which a phone which goes offline again will not trigger a new notification
+func (user User) HeartbeatQuietPeriod() time.Duration {
+	return time.Duration(user.NotificationHeartbeatQuietHours) * time.Hour
+}
+
+// FailoverOwner returns the backup phone number which takes over the pending messages of an offline owner
+func (user User) FailoverOwner(owner string) (string, bool) {
+	if !user.FailoverEnabled || user.FailoverPhoneNumber == nil || *user.FailoverPhoneNumber == owner {
+		return "", false
+	}
+	return *user.FailoverPhoneNumber, true
+}
+
+// HasDailyMessageLimit checks if the number of messages which the user can send per day is capped
+func (user User) HasDailyMessageLimit() bool {
+	return user.DailyMessageLimit > 0
+}
+
+// RetentionPeriod is the age after which the messages of a type are deleted, the messages are kept when it is 0
+func (user User) RetentionPeriod(messageType MessageType) time.Duration {
+	days := user.RetentionSentDays
+	if messageType == MessageTypeMobileOriginated {
+		days = user.RetentionReceivedDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// IsSuspended checks if the user has been suspended by an administrator
+func (user User) IsSuspended() bool {
+	return user.SuspendedAt != nil
+}
+
 // Location gets the timezone of a user
 func (user User) Location() *time.Location {
 	location, err := time.LoadLocation(user.Timezone)
@@ -0,0 +1,31 @@
+package entities
+
+import "time"
+
+// UserSummary is the activity of an entities.User which is visible to administrators
+type UserSummary struct {
+	ID                   UserID           `json:"id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	Email                string           `json:"email" example:"name@email.com"`
+	SubscriptionName     SubscriptionName `json:"subscription_name" example:"free"`
+	IsAdmin              bool             `json:"is_admin" example:"false"`
+	SuspendedAt          *time.Time       `json:"suspended_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	PhoneCount           uint             `json:"phone_count" example:"2"`
+	PendingMessageCount  uint             `json:"pending_message_count" example:"12"`
+	SentMessageCount     uint             `json:"sent_message_count" example:"321"`
+	ReceivedMessageCount uint             `json:"received_message_count" example:"465"`
+	FailedMessageCount   uint             `json:"failed_message_count" example:"3"`
+	LastMessageAt        *time.Time       `json:"last_message_at" example:"2022-06-05T14:26:02.302718+03:00"`
+	CreatedAt            time.Time        `json:"created_at" example:"2022-06-05T14:26:02.302718+03:00"`
+}
+
+// NewUserSummary creates a UserSummary without any activity for an entities.User
+func NewUserSummary(user *User) *UserSummary {
+	return &UserSummary{
+		ID:               user.ID,
+		Email:            user.Email,
+		SubscriptionName: user.SubscriptionName,
+		IsAdmin:          user.IsAdmin,
+		SuspendedAt:      user.SuspendedAt,
+		CreatedAt:        user.CreatedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_FailoverOwner(t *testing.T) {
+	backup := "+18005550100"
+
+	t.Run("failover owner is returned when failover is enabled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := User{FailoverEnabled: true, FailoverPhoneNumber: &backup}
+
+		// Act
+		owner, ok := user.FailoverOwner("+18005550199")
+
+		// Assert
+		assert.True(t, ok)
+		assert.Equal(t, backup, owner)
+	})
+
+	t.Run("no failover owner when failover is disabled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := User{FailoverEnabled: false, FailoverPhoneNumber: &backup}
+
+		// Act
+		_, ok := user.FailoverOwner("+18005550199")
+
+		// Assert
+		assert.False(t, ok)
+	})
+
+	t.Run("no failover owner when the offline phone is the backup phone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := User{FailoverEnabled: true, FailoverPhoneNumber: &backup}
+
+		// Act
+		_, ok := user.FailoverOwner(backup)
+
+		// Assert
+		assert.False(t, ok)
+	})
+}
+
+func TestUser_RetentionPeriod(t *testing.T) {
+	t.Run("received and sent messages have separate retention periods", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := User{RetentionReceivedDays: 30, RetentionSentDays: 7}
+
+		// Act
+		received := user.RetentionPeriod(MessageTypeMobileOriginated)
+		sent := user.RetentionPeriod(MessageTypeMobileTerminated)
+
+		// Assert
+		assert.Equal(t, 30*24*time.Hour, received)
+		assert.Equal(t, 7*24*time.Hour, sent)
+	})
+
+	t.Run("messages are kept when the retention is not set", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := User{}
+
+		// Act
+		period := user.RetentionPeriod(MessageTypeMobileOriginated)
+
+		// Assert
+		assert.Equal(t, time.Duration(0), period)
+	})
+}
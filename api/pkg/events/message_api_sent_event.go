@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 
 	"github.com/google/uuid"
 )
@@ -23,4 +24,33 @@ type MessageAPISentPayload struct {
 	RequestReceivedAt time.Time       `json:"request_received_at"`
 	Content           string          `json:"content"`
 	SIM               entities.SIM    `json:"sim"`
+	AllowFallback     bool            `json:"allow_fallback"`
+	RoutingRuleID     *uuid.UUID      `json:"routing_rule_id"`
+	Cost              *float64        `json:"cost"`
+	CostCurrency      *string         `json:"cost_currency"`
+	// IsEncrypted is true when the Content is end-to-end encrypted and can only be decrypted by the phone
+	IsEncrypted     bool    `json:"is_encrypted,omitempty"`
+	EncryptionKeyID *string `json:"encryption_key_id,omitempty"`
+	// MediaIDs are the IDs of the entities.Media which are sent with the message as an MMS message
+	MediaIDs []uuid.UUID `json:"media_ids,omitempty"`
+	// ForwardedFromID is the ID of the received message whose content is forwarded in this message
+	ForwardedFromID *uuid.UUID `json:"forwarded_from_id,omitempty"`
+	// ThreadID and InReplyToID are set when the message is sent as a reply in a thread
+	ThreadID    *uuid.UUID `json:"thread_id,omitempty"`
+	InReplyToID *uuid.UUID `json:"in_reply_to_id,omitempty"`
+	// RetryPolicy is the retry policy which was resolved for the message, its MaxAttempts is the MaxSendAttempts
+	RetryPolicy entities.RetryPolicy `json:"retry_policy"`
+	// DeliveryTracking is "off" when the phone does not request a delivery report, it is empty in the payloads which
+	// were created before the delivery tracking and the message is tracked
+	DeliveryTracking entities.MessageDeliveryTracking `json:"delivery_tracking,omitempty"`
+	// Metadata are the key value pairs which are stored with the message e.g. the order_id of the client
+	Metadata entities.MessageMetadata `json:"metadata,omitempty"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload MessageAPISentPayload) Redacted() MessageAPISentPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	payload.Contact = telemetry.RedactPhoneNumber(payload.Contact)
+	payload.Content = telemetry.RedactContent(payload.Content)
+	return payload
 }
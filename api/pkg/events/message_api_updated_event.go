@@ -0,0 +1,27 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+
+	"github.com/google/uuid"
+)
+
+// MessageAPIUpdated is emitted when the content of a message is changed before it is sent
+const MessageAPIUpdated = "message.api.updated"
+
+// MessageAPIUpdatedPayload is the payload of the MessageAPIUpdated event, the PreviousContent is kept so that the
+// events of the message record every version of the content
+type MessageAPIUpdatedPayload struct {
+	MessageID       uuid.UUID              `json:"message_id"`
+	UserID          entities.UserID        `json:"user_id"`
+	Owner           string                 `json:"owner"`
+	RequestID       *string                `json:"request_id"`
+	Contact         string                 `json:"contact"`
+	Status          entities.MessageStatus `json:"status"`
+	Timestamp       time.Time              `json:"timestamp"`
+	PreviousContent string                 `json:"previous_content"`
+	Content         string                 `json:"content"`
+	SIM             entities.SIM           `json:"sim"`
+}
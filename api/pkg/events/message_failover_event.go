@@ -0,0 +1,23 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// EventTypeMessageFailover is emitted when a pending message is moved from an offline phone to the failover phone
+const EventTypeMessageFailover = "message.failover"
+
+// MessageFailoverPayload is the payload of the EventTypeMessageFailover event
+type MessageFailoverPayload struct {
+	MessageID     uuid.UUID       `json:"message_id"`
+	UserID        entities.UserID `json:"user_id"`
+	OriginalOwner string          `json:"original_owner"`
+	Owner         string          `json:"owner"`
+	Contact       string          `json:"contact"`
+	Content       string          `json:"content"`
+	SIM           entities.SIM    `json:"sim"`
+	Timestamp     time.Time       `json:"timestamp"`
+}
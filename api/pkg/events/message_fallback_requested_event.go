@@ -0,0 +1,20 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// EventTypeMessageFallbackRequested is emitted when a message which allows the fallback provider expires after the last send attempt of the phone
+const EventTypeMessageFallbackRequested = "message.fallback.requested"
+
+// MessageFallbackRequestedPayload is the payload of the EventTypeMessageFallbackRequested event
+type MessageFallbackRequestedPayload struct {
+	MessageID uuid.UUID       `json:"message_id"`
+	UserID    entities.UserID `json:"user_id"`
+	Owner     string          `json:"owner"`
+	Contact   string          `json:"contact"`
+	Timestamp time.Time       `json:"timestamp"`
+}
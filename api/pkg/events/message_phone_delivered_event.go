@@ -19,6 +19,9 @@ type MessagePhoneDeliveredPayload struct {
 	RequestID *string         `json:"request_id"`
 	UserID    entities.UserID `json:"user_id"`
 	Timestamp time.Time       `json:"timestamp"`
-	Content   string          `json:"content"`
-	SIM       entities.SIM    `json:"sim"`
+	// ReportedTimestamp is the timestamp which was reported by the phone when the Timestamp is corrected with the clock skew of the phone
+	ReportedTimestamp *time.Time   `json:"reported_timestamp,omitempty"`
+	Content           string       `json:"content"`
+	SIM               entities.SIM `json:"sim"`
+	MessageStatusTransition
 }
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 
 	"github.com/google/uuid"
 )
@@ -18,6 +19,19 @@ type MessagePhoneReceivedPayload struct {
 	Owner     string          `json:"owner"`
 	Contact   string          `json:"contact"`
 	Timestamp time.Time       `json:"timestamp"`
-	Content   string          `json:"content"`
-	SIM       entities.SIM    `json:"sim"`
+	// ReportedTimestamp is the timestamp which was reported by the phone when the Timestamp is corrected with the clock skew of the phone
+	ReportedTimestamp *time.Time   `json:"reported_timestamp,omitempty"`
+	Content           string       `json:"content"`
+	SIM               entities.SIM `json:"sim"`
+	// IsEncrypted is true when the Content was end-to-end encrypted by the phone before it was uploaded
+	IsEncrypted     bool    `json:"is_encrypted,omitempty"`
+	EncryptionKeyID *string `json:"encryption_key_id,omitempty"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload MessagePhoneReceivedPayload) Redacted() MessagePhoneReceivedPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	payload.Contact = telemetry.RedactPhoneNumber(payload.Contact)
+	payload.Content = telemetry.RedactContent(payload.Content)
+	return payload
 }
@@ -20,4 +20,8 @@ type MessagePhoneSendingPayload struct {
 	Contact   string          `json:"contact"`
 	Content   string          `json:"content"`
 	SIM       entities.SIM    `json:"sim"`
+	// Media are the files which the phone downloads with the signed download URLs and sends as an MMS message
+	Media []*entities.Media `json:"media,omitempty"`
+	// RequestDeliveryReport is true when the phone registers for the delivery report of the carrier
+	RequestDeliveryReport bool `json:"request_delivery_report"`
 }
@@ -19,6 +19,9 @@ type MessagePhoneSentPayload struct {
 	Owner     string          `json:"owner"`
 	Contact   string          `json:"contact"`
 	Timestamp time.Time       `json:"timestamp"`
-	Content   string          `json:"content"`
-	SIM       entities.SIM    `json:"sim"`
+	// ReportedTimestamp is the timestamp which was reported by the phone when the Timestamp is corrected with the clock skew of the phone
+	ReportedTimestamp *time.Time   `json:"reported_timestamp,omitempty"`
+	Content           string       `json:"content"`
+	SIM               entities.SIM `json:"sim"`
+	MessageStatusTransition
 }
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// EventTypeMessageRetentionApplied is emitted when the messages of a user which are older than the retention period are deleted
+const EventTypeMessageRetentionApplied = "message.retention.applied"
+
+// MessageRetentionAppliedPayload is the payload of the EventTypeMessageRetentionApplied event
+type MessageRetentionAppliedPayload struct {
+	UserID               entities.UserID `json:"user_id"`
+	ReceivedDeletedCount int64           `json:"received_deleted_count"`
+	SentDeletedCount     int64           `json:"sent_deleted_count"`
+	ReceivedBefore       *time.Time      `json:"received_before"`
+	SentBefore           *time.Time      `json:"sent_before"`
+	HardDelete           bool            `json:"hard_delete"`
+	Timestamp            time.Time       `json:"timestamp"`
+}
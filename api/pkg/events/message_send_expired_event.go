@@ -23,4 +23,5 @@ type MessageSendExpiredPayload struct {
 	Timestamp        time.Time       `json:"timestamp"`
 	Content          string          `json:"content"`
 	SIM              entities.SIM    `json:"sim"`
+	MessageStatusTransition
 }
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const (
+	// MessagePreviousStatusExtension is the cloud event extension with the status of the message before the event
+	MessagePreviousStatusExtension = "previousstatus"
+
+	// MessageNewStatusExtension is the cloud event extension with the status of the message after the event
+	MessageNewStatusExtension = "newstatus"
+
+	// MessageTransitionedAtExtension is the cloud event extension with the time of the status transition
+	MessageTransitionedAtExtension = "transitionedat"
+
+	// MessageAttemptCountExtension is the cloud event extension with the number of send attempts of the message
+	MessageAttemptCountExtension = "attemptcount"
+)
+
+// MessageStatusTransition is the change of the status of a message which is caused by an event. The NewStatus is the
+// same as the PreviousStatus when the event arrives out of order e.g. a message is sent after it was delivered.
+type MessageStatusTransition struct {
+	PreviousStatus entities.MessageStatus `json:"previous_status"`
+	NewStatus      entities.MessageStatus `json:"new_status"`
+	TransitionedAt time.Time              `json:"transitioned_at"`
+	AttemptCount   uint                   `json:"attempt_count"`
+}
+
+// NewMessageStatusTransition is the MessageStatusTransition of a message which changes to the status at the timestamp
+func NewMessageStatusTransition(message *entities.Message, status entities.MessageStatus, timestamp time.Time) MessageStatusTransition {
+	return MessageStatusTransition{
+		PreviousStatus: message.Status,
+		NewStatus:      message.NextStatus(status),
+		TransitionedAt: timestamp,
+		AttemptCount:   message.SendAttemptCount,
+	}
+}
+
+// SetExtensions adds the MessageStatusTransition to the extensions of a cloudevents.Event so that consumers of the raw
+// cloud event don't have to decode the data
+func (transition MessageStatusTransition) SetExtensions(event *cloudevents.Event) {
+	event.SetExtension(MessagePreviousStatusExtension, string(transition.PreviousStatus))
+	event.SetExtension(MessageNewStatusExtension, string(transition.NewStatus))
+	event.SetExtension(MessageTransitionedAtExtension, transition.TransitionedAt)
+	event.SetExtension(MessageAttemptCountExtension, transition.AttemptCount)
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+
+	"github.com/google/uuid"
+)
+
+// EventTypeMessageThreadRead is emitted when the messages in a thread are read by the user
+const EventTypeMessageThreadRead = "message-thread.read"
+
+// MessageThreadReadPayload is the payload of the EventTypeMessageThreadRead event
+type MessageThreadReadPayload struct {
+	MessageThreadID uuid.UUID       `json:"message_thread_id"`
+	UserID          entities.UserID `json:"user_id"`
+	Owner           string          `json:"owner"`
+	Contact         string          `json:"contact"`
+	Timestamp       time.Time       `json:"timestamp"`
+}
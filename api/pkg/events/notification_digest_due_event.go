@@ -0,0 +1,20 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// EventTypeNotificationDigestDue is emitted when the window of an entities.NotificationDigest should be sent
+const EventTypeNotificationDigestDue = "notification.digest.due"
+
+// NotificationDigestDuePayload is the payload of the EventTypeNotificationDigestDue event
+type NotificationDigestDuePayload struct {
+	UserID   entities.UserID `json:"user_id"`
+	Owner    string          `json:"owner"`
+	WindowID uuid.UUID       `json:"window_id"`
+	OpenedAt time.Time       `json:"opened_at"`
+	SendAt   time.Time       `json:"send_at"`
+}
@@ -0,0 +1,28 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneBatteryLow is emitted when the battery level of a discharging phone drops below the threshold
+const EventTypePhoneBatteryLow = "phone.battery.low"
+
+// PhoneBatteryLowPayload is the payload of the EventTypePhoneBatteryLow event
+type PhoneBatteryLowPayload struct {
+	PhoneID      uuid.UUID       `json:"phone_id"`
+	UserID       entities.UserID `json:"user_id"`
+	Owner        string          `json:"owner"`
+	BatteryLevel uint            `json:"battery_level"`
+	Threshold    uint            `json:"threshold"`
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload PhoneBatteryLowPayload) Redacted() PhoneBatteryLowPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	return payload
+}
@@ -0,0 +1,29 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneClockSkewed is emitted when the difference between the clock of a phone and the server exceeds the threshold
+const EventTypePhoneClockSkewed = "phone.clock.skewed"
+
+// PhoneClockSkewedPayload is the payload of the EventTypePhoneClockSkewed event
+type PhoneClockSkewedPayload struct {
+	PhoneID uuid.UUID       `json:"phone_id"`
+	UserID  entities.UserID `json:"user_id"`
+	Owner   string          `json:"owner"`
+	// ClockSkewMilliseconds is positive when the clock of the phone is ahead of the server
+	ClockSkewMilliseconds int64     `json:"clock_skew_milliseconds"`
+	ThresholdMilliseconds int64     `json:"threshold_milliseconds"`
+	Timestamp             time.Time `json:"timestamp"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload PhoneClockSkewedPayload) Redacted() PhoneClockSkewedPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	return payload
+}
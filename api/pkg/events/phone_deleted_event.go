@@ -17,4 +17,6 @@ type PhoneDeletedPayload struct {
 	Timestamp time.Time       `json:"timestamp"`
 	Owner     string          `json:"owner"`
 	SIM       entities.SIM    `json:"sim"`
+	// CancelledMessageCount is the number of outstanding messages which were cancelled when the phone was deleted
+	CancelledMessageCount int64 `json:"cancelled_message_count"`
 }
@@ -0,0 +1,22 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneHeartbeatAlive is emitted when a phone which was offline sends a heartbeat again
+const EventTypePhoneHeartbeatAlive = "phone.heartbeat.alive"
+
+// PhoneHeartbeatAlivePayload is the payload of the EventTypePhoneHeartbeatAlive event
+type PhoneHeartbeatAlivePayload struct {
+	PhoneID                uuid.UUID       `json:"phone_id"`
+	UserID                 entities.UserID `json:"user_id"`
+	LastHeartbeatTimestamp time.Time       `json:"last_heartbeat_timestamp"`
+	Timestamp              time.Time       `json:"timestamp"`
+	MonitorID              uuid.UUID       `json:"monitor_id"`
+	Owner                  string          `json:"owner"`
+	Threshold              time.Duration   `json:"threshold"`
+}
@@ -18,4 +18,5 @@ type PhoneHeartbeatDeadPayload struct {
 	Timestamp              time.Time       `json:"timestamp"`
 	MonitorID              uuid.UUID       `json:"monitor_id"`
 	Owner                  string          `json:"owner"`
+	Threshold              time.Duration   `json:"threshold"`
 }
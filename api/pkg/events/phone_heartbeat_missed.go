@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/google/uuid"
 )
 
@@ -19,3 +20,9 @@ type PhoneHeartbeatMissedPayload struct {
 	MonitorID              uuid.UUID       `json:"monitor_id"`
 	Owner                  string          `json:"owner"`
 }
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload PhoneHeartbeatMissedPayload) Redacted() PhoneHeartbeatMissedPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	return payload
+}
@@ -0,0 +1,21 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneHeartbeatUnseen is emitted when a registered phone has never sent a heartbeat
+const EventTypePhoneHeartbeatUnseen = "phone.heartbeat.unseen"
+
+// PhoneHeartbeatUnseenPayload is the payload of the EventTypePhoneHeartbeatUnseen event
+type PhoneHeartbeatUnseenPayload struct {
+	PhoneID      uuid.UUID       `json:"phone_id"`
+	UserID       entities.UserID `json:"user_id"`
+	RegisteredAt time.Time       `json:"registered_at"`
+	Timestamp    time.Time       `json:"timestamp"`
+	MonitorID    uuid.UUID       `json:"monitor_id"`
+	Owner        string          `json:"owner"`
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// EventTypePhonePaused is emitted when sending messages is paused on a phone
+const EventTypePhonePaused = "phone.paused"
+
+// PhonePausedPayload is the payload of the EventTypePhonePaused event
+type PhonePausedPayload struct {
+	PhoneID   uuid.UUID       `json:"phone_id"`
+	UserID    entities.UserID `json:"user_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Owner     string          `json:"owner"`
+}
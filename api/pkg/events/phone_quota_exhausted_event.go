@@ -0,0 +1,28 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneQuotaExhausted is emitted once a day when a message is held back because the phone reached its daily send limit
+const EventTypePhoneQuotaExhausted = "phone.quota.exhausted"
+
+// PhoneQuotaExhaustedPayload is the payload of the EventTypePhoneQuotaExhausted event
+type PhoneQuotaExhaustedPayload struct {
+	PhoneID        uuid.UUID       `json:"phone_id"`
+	UserID         entities.UserID `json:"user_id"`
+	Owner          string          `json:"owner"`
+	DailySendLimit uint            `json:"daily_send_limit"`
+	ResetAt        time.Time       `json:"reset_at"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload PhoneQuotaExhaustedPayload) Redacted() PhoneQuotaExhaustedPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	return payload
+}
@@ -0,0 +1,26 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneQuotaReset is emitted at midnight after a phone reached its daily send limit so that the pending messages are released
+const EventTypePhoneQuotaReset = "phone.quota.reset"
+
+// PhoneQuotaResetPayload is the payload of the EventTypePhoneQuotaReset event
+type PhoneQuotaResetPayload struct {
+	PhoneID   uuid.UUID       `json:"phone_id"`
+	UserID    entities.UserID `json:"user_id"`
+	Owner     string          `json:"owner"`
+	Timestamp time.Time       `json:"timestamp"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload PhoneQuotaResetPayload) Redacted() PhoneQuotaResetPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	return payload
+}
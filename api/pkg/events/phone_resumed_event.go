@@ -0,0 +1,26 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// EventTypePhoneResumed is emitted when sending messages is resumed on a phone
+const EventTypePhoneResumed = "phone.resumed"
+
+// PhoneResumedPayload is the payload of the EventTypePhoneResumed event
+type PhoneResumedPayload struct {
+	PhoneID   uuid.UUID       `json:"phone_id"`
+	UserID    entities.UserID `json:"user_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Owner     string          `json:"owner"`
+}
+
+// Redacted returns a copy of the payload which is safe to log
+func (payload PhoneResumedPayload) Redacted() PhoneResumedPayload {
+	payload.Owner = telemetry.RedactPhoneNumber(payload.Owner)
+	return payload
+}
@@ -0,0 +1,20 @@
+package events
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// UserAPIKeyRotated is raised when the API key of a user is rotated
+const UserAPIKeyRotated = "user.api-key.rotated"
+
+// UserAPIKeyRotatedPayload stores the data for the UserAPIKeyRotated event
+type UserAPIKeyRotatedPayload struct {
+	UserID entities.UserID `json:"user_id"`
+	// PreviousAPIKeyExpiresAt is nil when the previous API key was revoked immediately
+	PreviousAPIKeyExpiresAt *time.Time `json:"previous_api_key_expires_at"`
+	IPAddress               string     `json:"ip_address"`
+	UserAgent               string     `json:"user_agent"`
+	Timestamp               time.Time  `json:"timestamp"`
+}
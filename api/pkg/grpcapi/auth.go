@@ -0,0 +1,150 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/grpcapi/httpsmsv1"
+	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const metadataAPIKey = "x-api-key"
+
+type contextKey string
+
+const contextKeyAuthUser = contextKey("auth.user")
+
+// methodScopes is the entities.APIKeyScope which is needed by a scoped entities.APIKey to call a method, it matches the scopes of the HTTP routes
+var methodScopes = map[string]entities.APIKeyScope{
+	httpsmsv1.MessageService_SendMessage_FullMethodName:    entities.APIKeyScopeMessagesSend,
+	httpsmsv1.MessageService_GetMessage_FullMethodName:     entities.APIKeyScopeMessagesRead,
+	httpsmsv1.MessageService_IndexMessages_FullMethodName:  entities.APIKeyScopeMessagesRead,
+	httpsmsv1.MessageService_GetOutstanding_FullMethodName: entities.APIKeyScopePhonesWrite,
+	httpsmsv1.MessageService_StoreEvent_FullMethodName:     entities.APIKeyScopePhonesWrite,
+	httpsmsv1.MessageService_ReceiveMessage_FullMethodName: entities.APIKeyScopePhonesWrite,
+}
+
+// Authenticator authenticates the calls to the gRPC server with the API key in the x-api-key metadata, the same API keys as the HTTP API are accepted
+type Authenticator struct {
+	logger           telemetry.Logger
+	tracer           telemetry.Tracer
+	userRepository   repositories.UserRepository
+	apiKeyRepository repositories.APIKeyRepository
+	usageRecorder    middlewares.APIKeyUsageRecorder
+}
+
+// NewAuthenticator creates a new Authenticator
+func NewAuthenticator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	userRepository repositories.UserRepository,
+	apiKeyRepository repositories.APIKeyRepository,
+	usageRecorder middlewares.APIKeyUsageRecorder,
+) (authenticator *Authenticator) {
+	return &Authenticator{
+		logger:           logger.WithService(fmt.Sprintf("%T", authenticator)),
+		tracer:           tracer,
+		userRepository:   userRepository,
+		apiKeyRepository: apiKeyRepository,
+		usageRecorder:    usageRecorder,
+	}
+}
+
+// Unary authenticates unary calls
+func (authenticator *Authenticator) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticator.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, request)
+	}
+}
+
+// Stream authenticates streaming calls
+func (authenticator *Authenticator) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticator.authenticate(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (authenticator *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	ctx, span, ctxLogger := authenticator.tracer.StartWithLogger(ctx, authenticator.logger)
+	defer span.End()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(metadataAPIKey)
+	if len(values) == 0 || values[0] == "" {
+		return ctx, status.Errorf(codes.Unauthenticated, "make sure your API key is set in the [%s] metadata of the call", metadataAPIKey)
+	}
+
+	authUser, err := middlewares.LoadAuthUser(ctx, authenticator.userRepository, authenticator.apiKeyRepository, values[0])
+	if stacktrace.GetCode(err) == repositories.ErrCodeUserSuspended {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("the user with api key [%s] is suspended", telemetry.RedactAPIKey(values[0]))))
+		return ctx, status.Error(codes.PermissionDenied, "your account has been suspended so you cannot carry out this request")
+	}
+
+	if err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot load user with api key [%s]", telemetry.RedactAPIKey(values[0]))))
+		return ctx, status.Error(codes.Unauthenticated, "you are not authorized to carry out this request")
+	}
+
+	if scope, ok := methodScopes[method]; ok && !authUser.HasScope(scope) {
+		return ctx, status.Errorf(codes.PermissionDenied, "the API key does not have the [%s] scope which is needed for this request", scope)
+	}
+
+	authenticator.recordUsage(ctx, ctxLogger, authUser, md)
+	return context.WithValue(ctx, contextKeyAuthUser, authUser), nil
+}
+
+// recordUsage records the caller of a scoped entities.APIKey, the call is not rejected when the usage cannot be recorded
+func (authenticator *Authenticator) recordUsage(ctx context.Context, ctxLogger telemetry.Logger, authUser entities.AuthUser, md metadata.MD) {
+	if !authUser.IsScoped() {
+		return
+	}
+
+	var ipAddress string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ipAddress = p.Addr.String()
+	}
+
+	var userAgent string
+	if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+
+	if err := authenticator.usageRecorder.RecordUsage(ctx, *authUser.APIKeyID, ipAddress, userAgent); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot record usage of api key with ID [%s]", authUser.APIKeyID)))
+	}
+}
+
+// authUserFromContext returns the entities.AuthUser which was set by the Authenticator
+func authUserFromContext(ctx context.Context) entities.AuthUser {
+	if authUser, ok := ctx.Value(contextKeyAuthUser).(entities.AuthUser); ok && !authUser.IsNoop() {
+		return authUser
+	}
+	panic("user does not exist in context.")
+}
+
+// authenticatedStream replaces the context of a grpc.ServerStream with the context of the authenticated user
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *authenticatedStream) Context() context.Context {
+	return stream.ctx
+}
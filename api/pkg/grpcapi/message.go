@@ -0,0 +1,63 @@
+package grpcapi
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/grpcapi/httpsmsv1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toMessage converts an entities.Message into an httpsmsv1.Message
+func toMessage(message *entities.Message) *httpsmsv1.Message {
+	return &httpsmsv1.Message{
+		Id:                message.ID.String(),
+		RequestId:         toString(message.RequestID),
+		Owner:             message.Owner,
+		UserId:            string(message.UserID),
+		Contact:           message.Contact,
+		Content:           message.Content,
+		Type:              string(message.Type),
+		Status:            string(message.Status),
+		Sim:               string(message.SIM),
+		RequestReceivedAt: timestamppb.New(message.RequestReceivedAt),
+		CreatedAt:         timestamppb.New(message.CreatedAt),
+		UpdatedAt:         timestamppb.New(message.UpdatedAt),
+		OrderTimestamp:    timestamppb.New(message.OrderTimestamp),
+		LastAttemptedAt:   toTimestamp(message.LastAttemptedAt),
+		ScheduledSendTime: toTimestamp(message.ScheduledSendTime),
+		SentAt:            toTimestamp(message.SentAt),
+		DeliveredAt:       toTimestamp(message.DeliveredAt),
+		ExpiredAt:         toTimestamp(message.ExpiredAt),
+		FailedAt:          toTimestamp(message.FailedAt),
+		ReceivedAt:        toTimestamp(message.ReceivedAt),
+		SendAttemptCount:  uint32(message.SendAttemptCount),
+		MaxSendAttempts:   uint32(message.MaxSendAttempts),
+		FailureReason:     toString(message.FailureReason),
+		CanBePolled:       message.CanBePolled,
+	}
+}
+
+// toTimestamp converts an optional time.Time into a timestamppb.Timestamp which is nil when the time is not set
+func toTimestamp(value *time.Time) *timestamppb.Timestamp {
+	if value == nil {
+		return nil
+	}
+	return timestamppb.New(*value)
+}
+
+// toTime converts an optional timestamppb.Timestamp into a time.Time which is nil when the timestamp is not set
+func toTime(value *timestamppb.Timestamp) *time.Time {
+	if value == nil {
+		return nil
+	}
+	result := value.AsTime()
+	return &result
+}
+
+func toString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,231 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/grpcapi/httpsmsv1"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MessageServer implements the httpsmsv1.MessageServiceServer with the same validation and services as the handlers.MessageHandler
+type MessageServer struct {
+	httpsmsv1.UnimplementedMessageServiceServer
+	logger         telemetry.Logger
+	tracer         telemetry.Tracer
+	validator      *validators.MessageHandlerValidator
+	billingService *services.BillingService
+	service        *services.MessageService
+}
+
+// NewMessageServer creates a new MessageServer
+func NewMessageServer(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.MessageHandlerValidator,
+	billingService *services.BillingService,
+	service *services.MessageService,
+) (s *MessageServer) {
+	return &MessageServer{
+		logger:         logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:         tracer,
+		validator:      validator,
+		billingService: billingService,
+		service:        service,
+	}
+}
+
+// SendMessage adds a new SMS message to be sent by the android phone
+func (s *MessageServer) SendMessage(ctx context.Context, in *httpsmsv1.SendMessageRequest) (*httpsmsv1.Message, error) {
+	ctx, span, ctxLogger := s.tracer.StartWithLogger(ctx, s.logger)
+	defer span.End()
+
+	userID := authUserFromContext(ctx).ID
+	request := requests.MessageSend{
+		From:      in.GetFrom(),
+		To:        in.GetTo(),
+		Content:   in.GetContent(),
+		RequestID: in.GetRequestId(),
+		SendAt:    toTime(in.GetSendAt()),
+		SIM:       in.GetSim(),
+	}
+
+	if errors := s.validator.ValidateMessageSend(ctx, userID, request); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while sending message for user [%s]", spew.Sdump(errors), userID)))
+		return nil, validationStatus(errors, "validation errors while sending message")
+	}
+
+	request.Sanitize()
+
+	if msg := s.billingService.IsEntitled(ctx, userID); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't send a message", userID)))
+		return nil, status.Error(codes.FailedPrecondition, *msg)
+	}
+
+	message, err := s.service.SendMessage(ctx, request.ToMessageSendParams(userID, httpsmsv1.MessageService_SendMessage_FullMethodName))
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send message for user [%s]", userID)))
+		return nil, serviceErrorStatus(err)
+	}
+
+	return toMessage(message), nil
+}
+
+// GetMessage fetches a message by its ID
+func (s *MessageServer) GetMessage(ctx context.Context, in *httpsmsv1.GetMessageRequest) (*httpsmsv1.Message, error) {
+	ctx, span, ctxLogger := s.tracer.StartWithLogger(ctx, s.logger)
+	defer span.End()
+
+	messageID, err := uuid.Parse(in.GetMessageId())
+	if err != nil {
+		return nil, validationStatus(url.Values{"message_id": []string{"The message_id field must be a valid UUID"}}, "validation errors while fetching message")
+	}
+
+	message, err := s.service.GetMessage(ctx, authUserFromContext(ctx).ID, messageID)
+	if err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot get message with ID [%s]", messageID)))
+		return nil, serviceErrorStatus(err)
+	}
+
+	return toMessage(message), nil
+}
+
+// IndexMessages streams the messages which are sent between 2 phone numbers
+func (s *MessageServer) IndexMessages(in *httpsmsv1.IndexMessagesRequest, stream httpsmsv1.MessageService_IndexMessagesServer) error {
+	ctx, span, ctxLogger := s.tracer.StartWithLogger(stream.Context(), s.logger)
+	defer span.End()
+
+	request := requests.MessageIndex{
+		Owner:           in.GetOwner(),
+		Contact:         in.GetContact(),
+		Query:           in.GetQuery(),
+		Skip:            strconv.FormatUint(uint64(in.GetSkip()), 10),
+		IncludeArchived: in.GetIncludeArchived(),
+	}
+	if in.GetLimit() > 0 {
+		request.Limit = strconv.FormatUint(uint64(in.GetLimit()), 10)
+	}
+
+	if errors := s.validator.ValidateMessageIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while fetching messages [%+#v]", spew.Sdump(errors), request)))
+		return validationStatus(errors, "validation errors while fetching messages")
+	}
+
+	messages, err := s.service.GetMessages(ctx, request.ToGetParams(authUserFromContext(ctx).ID))
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot get messages with params [%+#v]", request)))
+		return serviceErrorStatus(err)
+	}
+
+	for index := range *messages {
+		if err = stream.Send(toMessage(&(*messages)[index])); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot stream message with ID [%s]", (*messages)[index].ID))
+		}
+	}
+
+	return nil
+}
+
+// GetOutstanding fetches an outstanding message which should be sent by the android phone
+func (s *MessageServer) GetOutstanding(ctx context.Context, in *httpsmsv1.GetOutstandingRequest) (*httpsmsv1.Message, error) {
+	ctx, span, ctxLogger := s.tracer.StartWithLogger(ctx, s.logger)
+	defer span.End()
+
+	request := requests.MessageOutstanding{MessageID: in.GetMessageId()}
+	if errors := s.validator.ValidateMessageOutstanding(ctx, request.Sanitize()); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while fetching outstanding message [%s]", spew.Sdump(errors), request.MessageID)))
+		return nil, validationStatus(errors, "validation errors while fetching outstanding messages")
+	}
+
+	message, err := s.service.GetOutstanding(ctx, request.ToGetOutstandingParams(httpsmsv1.MessageService_GetOutstanding_FullMethodName, authUserFromContext(ctx).ID, time.Now().UTC()))
+	if err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot get outstanding message with ID [%s]", request.MessageID)))
+		return nil, serviceErrorStatus(err)
+	}
+
+	return toMessage(message), nil
+}
+
+// StoreEvent stores an event for a message which is emitted by the android phone
+func (s *MessageServer) StoreEvent(ctx context.Context, in *httpsmsv1.StoreEventRequest) (*httpsmsv1.Message, error) {
+	ctx, span, ctxLogger := s.tracer.StartWithLogger(ctx, s.logger)
+	defer span.End()
+
+	request := requests.MessageEvent{
+		MessageID: in.GetMessageId(),
+		EventName: in.GetEventName(),
+	}
+	if in.GetReason() != "" {
+		reason := in.GetReason()
+		request.Reason = &reason
+	}
+	if in.GetTimestamp() != nil {
+		request.Timestamp = in.GetTimestamp().AsTime()
+	}
+
+	if errors := s.validator.ValidateMessageEvent(ctx, request.Sanitize()); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while storing event for message [%s]", spew.Sdump(errors), request.MessageID)))
+		return nil, validationStatus(errors, "validation errors while storing event")
+	}
+
+	message, err := s.service.GetMessage(ctx, authUserFromContext(ctx).ID, uuid.MustParse(request.MessageID))
+	if err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot find message with ID [%s]", request.MessageID)))
+		return nil, serviceErrorStatus(err)
+	}
+
+	message, err = s.service.StoreEvent(ctx, message, request.ToMessageStoreEventParams(httpsmsv1.MessageService_StoreEvent_FullMethodName))
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot store event [%s] for message [%s]", request.EventName, request.MessageID)))
+		return nil, serviceErrorStatus(err)
+	}
+
+	return toMessage(message), nil
+}
+
+// ReceiveMessage stores a new SMS message which is received by the android phone
+func (s *MessageServer) ReceiveMessage(ctx context.Context, in *httpsmsv1.ReceiveMessageRequest) (*httpsmsv1.Message, error) {
+	ctx, span, ctxLogger := s.tracer.StartWithLogger(ctx, s.logger)
+	defer span.End()
+
+	userID := authUserFromContext(ctx).ID
+	request := requests.MessageReceive{
+		From:    in.GetFrom(),
+		To:      in.GetTo(),
+		Content: in.GetContent(),
+		SIM:     entities.SIM(in.GetSim()),
+	}
+	if in.GetTimestamp() != nil {
+		request.Timestamp = in.GetTimestamp().AsTime()
+	}
+
+	if errors := s.validator.ValidateMessageReceive(ctx, request.Sanitize()); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while receiving message for user [%s]", spew.Sdump(errors), userID)))
+		return nil, validationStatus(errors, "validation errors while receiving message")
+	}
+
+	if msg := s.billingService.IsEntitled(ctx, userID); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't receive a message", userID)))
+		return nil, status.Error(codes.FailedPrecondition, *msg)
+	}
+
+	message, err := s.service.ReceiveMessage(ctx, request.ToMessageReceiveParams(userID, httpsmsv1.MessageService_ReceiveMessage_FullMethodName))
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot receive message for user [%s]", userID)))
+		return nil, serviceErrorStatus(err)
+	}
+
+	return toMessage(message), nil
+}
@@ -0,0 +1,273 @@
+package grpcapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/grpcapi/httpsmsv1"
+	"github.com/NdoleStudio/httpsms/pkg/ids"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	primaryAPIKey   = "primary-api-key"
+	readOnlyAPIKey  = "read-only-api-key"
+	suspendedAPIKey = "suspended-api-key"
+)
+
+// stubUserRepository authenticates the primary API key and loads users without a daily message limit
+type stubUserRepository struct {
+	repositories.UserRepository
+	users map[entities.UserID]entities.User
+}
+
+func (repository *stubUserRepository) LoadAuthUser(_ context.Context, apiKey string) (entities.AuthUser, error) {
+	switch apiKey {
+	case primaryAPIKey:
+		return entities.AuthUser{ID: "user-a", Email: "name@email.com"}, nil
+	case suspendedAPIKey:
+		return entities.AuthUser{}, stacktrace.NewErrorWithCode(repositories.ErrCodeUserSuspended, "user is suspended")
+	default:
+		return entities.AuthUser{}, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "user does not exist")
+	}
+}
+
+func (repository *stubUserRepository) Load(_ context.Context, userID entities.UserID) (*entities.User, error) {
+	if user, ok := repository.users[userID]; ok {
+		return &user, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "user does not exist")
+}
+
+// stubAPIKeyRepository authenticates a scoped API key which can only read messages
+type stubAPIKeyRepository struct {
+	repositories.APIKeyRepository
+}
+
+func (repository *stubAPIKeyRepository) LoadAuthUser(_ context.Context, apiKey string) (entities.AuthUser, error) {
+	if apiKey != readOnlyAPIKey {
+		return entities.AuthUser{}, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "api key does not exist")
+	}
+	apiKeyID := uuid.New()
+	return entities.AuthUser{ID: "user-a", Email: "name@email.com", APIKeyID: &apiKeyID, Scopes: []entities.APIKeyScope{entities.APIKeyScopeMessagesRead}}, nil
+}
+
+// stubUsageRecorder does not record the usage of API keys
+type stubUsageRecorder struct{}
+
+func (recorder stubUsageRecorder) RecordUsage(_ context.Context, _ uuid.UUID, _ string, _ string) error {
+	return nil
+}
+
+// stubPhoneRepository loads entities.Phone from a fixed set of phones
+type stubPhoneRepository struct {
+	repositories.PhoneRepository
+	phones []entities.Phone
+}
+
+func (repository *stubPhoneRepository) Load(_ context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	for _, phone := range repository.phones {
+		if phone.UserID == userID && phone.PhoneNumber == phoneNumber {
+			return &phone, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "phone does not exist")
+}
+
+func (repository *stubPhoneRepository) LoadOwner(ctx context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	return repository.Load(ctx, userID, phoneNumber)
+}
+
+// capturingPushQueue stores the tasks instead of sending them to the consumer endpoint
+type capturingPushQueue struct {
+	mutex sync.Mutex
+	tasks []*services.PushQueueTask
+}
+
+func (queue *capturingPushQueue) Enqueue(_ context.Context, task *services.PushQueueTask, _ time.Duration) (string, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.tasks = append(queue.tasks, task)
+	return uuid.NewString(), nil
+}
+
+func newTestClient(t *testing.T) httpsmsv1.MessageServiceClient {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	userRepository := &stubUserRepository{users: map[entities.UserID]entities.User{"user-a": {ID: "user-a"}}}
+	phoneService := services.NewPhoneService(logger, tracer, &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}, nil, 0, services.PhoneClockConfig{})
+	dispatcher := services.NewEventDispatcher(logger, tracer, noop.Float64Histogram{}, &capturingPushQueue{}, services.PushQueueConfig{}, memory.NewEventRepository())
+	generator, _ := ids.NewGenerator(ids.StrategyTimeOrdered)
+	messageService := services.NewMessageService(logger, tracer, services.MessageServiceDeps{
+		Repository:      memory.NewMessageRepository(),
+		EventDispatcher: dispatcher,
+		EventRepository: memory.NewEventRepository(),
+		PhoneService:    phoneService,
+		UserRepository:  userRepository,
+		UsageLocation:   time.UTC,
+		IDs:             generator,
+	})
+	billingService := services.NewBillingService(logger, tracer, nil, nil, nil, nil, &stubUserRepository{})
+
+	server := NewServer(
+		NewAuthenticator(logger, tracer, userRepository, &stubAPIKeyRepository{}, stubUsageRecorder{}),
+		NewMessageServer(logger, tracer, validators.NewMessageHandlerValidator(logger, tracer, phoneService), billingService, messageService),
+	)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return httpsmsv1.NewMessageServiceClient(conn)
+}
+
+func withAPIKey(apiKey string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), metadataAPIKey, apiKey)
+}
+
+func TestMessageServer_SendMessage(t *testing.T) {
+	t.Run("sent message can be fetched and streamed in the index", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		client := newTestClient(t)
+
+		// Arrange
+		ctx := withAPIKey(primaryAPIKey)
+
+		// Act
+		message, err := client.SendMessage(ctx, &httpsmsv1.SendMessageRequest{
+			From:      "+18005550199",
+			To:        "+18005550100",
+			Content:   "This is a sample text message",
+			RequestId: "153554b5-ae44-44a0-8f4f-7bbac5657ad4",
+		})
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, "+18005550199", message.GetOwner())
+		assert.Equal(t, "+18005550100", message.GetContact())
+		assert.Equal(t, "153554b5-ae44-44a0-8f4f-7bbac5657ad4", message.GetRequestId())
+		assert.Equal(t, entities.MessageStatusPending, entities.MessageStatus(message.GetStatus()))
+
+		fetched, err := client.GetMessage(ctx, &httpsmsv1.GetMessageRequest{MessageId: message.GetId()})
+		require.NoError(t, err)
+		assert.Equal(t, message.GetId(), fetched.GetId())
+
+		stream, err := client.IndexMessages(ctx, &httpsmsv1.IndexMessagesRequest{Owner: "+18005550199", Contact: "+18005550100"})
+		require.NoError(t, err)
+
+		var streamed []string
+		for {
+			item, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			streamed = append(streamed, item.GetId())
+		}
+		assert.Equal(t, []string{message.GetId()}, streamed)
+	})
+
+	t.Run("invalid request returns the same validation errors as the HTTP API", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		client := newTestClient(t)
+
+		// Act
+		_, err := client.SendMessage(withAPIKey(primaryAPIKey), &httpsmsv1.SendMessageRequest{From: "+18005550199", Content: "This is a sample text message"})
+
+		// Assert
+		result := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, result.Code())
+		require.Equal(t, 1, len(result.Details()))
+
+		badRequest, ok := result.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		assert.Equal(t, "to", badRequest.GetFieldViolations()[0].GetField())
+	})
+
+	t.Run("call without an API key is unauthenticated", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		client := newTestClient(t)
+
+		// Act
+		_, err := client.SendMessage(context.Background(), &httpsmsv1.SendMessageRequest{From: "+18005550199", To: "+18005550100", Content: "This is a sample text message"})
+
+		// Assert
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("call of a suspended user is denied", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		client := newTestClient(t)
+
+		// Act
+		_, err := client.SendMessage(withAPIKey(suspendedAPIKey), &httpsmsv1.SendMessageRequest{From: "+18005550199", To: "+18005550100", Content: "This is a sample text message"})
+
+		// Assert
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("API key without the messages:send scope is denied", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		client := newTestClient(t)
+
+		// Act
+		_, err := client.SendMessage(withAPIKey(readOnlyAPIKey), &httpsmsv1.SendMessageRequest{From: "+18005550199", To: "+18005550100", Content: "This is a sample text message"})
+
+		// Assert
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestMessageServer_GetMessage(t *testing.T) {
+	t.Run("message which does not exist is not found", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		client := newTestClient(t)
+
+		// Act
+		_, err := client.GetMessage(withAPIKey(readOnlyAPIKey), &httpsmsv1.GetMessageRequest{MessageId: uuid.NewString()})
+
+		// Assert
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
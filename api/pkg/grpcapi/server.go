@@ -0,0 +1,16 @@
+package grpcapi
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/grpcapi/httpsmsv1"
+	"google.golang.org/grpc"
+)
+
+// NewServer creates a grpc.Server which authenticates every call with the Authenticator and serves the MessageServer
+func NewServer(authenticator *Authenticator, messageServer *MessageServer) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authenticator.Unary()),
+		grpc.ChainStreamInterceptor(authenticator.Stream()),
+	)
+	httpsmsv1.RegisterMessageServiceServer(server, messageServer)
+	return server
+}
@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/palantir/stacktrace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validationStatus converts the validation errors of a request into an InvalidArgument status with a field violation for every error
+func validationStatus(errors url.Values, message string) error {
+	fields := make([]string, 0, len(errors))
+	for field := range errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	badRequest := &errdetails.BadRequest{}
+	for _, field := range fields {
+		for _, description := range errors[field] {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+		}
+	}
+
+	result, err := status.New(codes.InvalidArgument, message).WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return result.Err()
+}
+
+// serviceErrorStatus converts an error from a service into a status with the same meaning as the HTTP response of handlers.responseServiceError
+func serviceErrorStatus(err error) error {
+	if validationErr, ok := services.AsValidationError(err); ok {
+		return validationStatus(validationErr.Errors, "validation errors while handling the request")
+	}
+
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		return status.Error(codes.ResourceExhausted, limitErr.Error())
+	}
+
+	if dndErr, ok := services.AsContactDNDError(err); ok {
+		return status.Error(codes.FailedPrecondition, dndErr.Error())
+	}
+
+	if recipientErr, ok := services.AsRecipientNotSendableError(err); ok {
+		return status.Error(codes.InvalidArgument, recipientErr.Error())
+	}
+
+	if mismatchErr, ok := services.AsEncryptionMismatchError(err); ok {
+		return status.Error(codes.FailedPrecondition, mismatchErr.Error())
+	}
+
+	if contentErr, ok := services.AsEncryptedContentError(err); ok {
+		return status.Error(codes.InvalidArgument, contentErr.Error())
+	}
+
+	if forwardErr, ok := services.AsMessageNotForwardableError(err); ok {
+		return status.Error(codes.FailedPrecondition, forwardErr.Error())
+	}
+
+	switch stacktrace.GetCode(err) {
+	case repositories.ErrCodeNotFound:
+		return status.Error(codes.NotFound, "cannot find the resource in the request")
+	case repositories.ErrCodeStaleUpdate, services.ErrCodeInvalidTransition:
+		return status.Error(codes.FailedPrecondition, "the resource cannot be changed from its current state")
+	case services.ErrCodeRateLimited:
+		return status.Error(codes.ResourceExhausted, "the phone has exceeded its messages per minute, try again later")
+	case services.ErrCodeDailySendLimit:
+		return status.Error(codes.ResourceExhausted, "the phone has reached its daily send limit, try again after midnight")
+	case services.ErrCodeMediaUnavailable:
+		return status.Error(codes.Unavailable, "media cannot be sent because the blob store is not configured")
+	default:
+		return status.Error(codes.Internal, "we ran into an internal error while handling the request")
+	}
+}
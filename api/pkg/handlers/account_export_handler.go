@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// AccountExportHandler handles the export of all the records of an account for backups and migrations
+type AccountExportHandler struct {
+	handler
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	service *services.AccountExportService
+}
+
+// NewAccountExportHandler creates a new AccountExportHandler
+func NewAccountExportHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.AccountExportService,
+) (h *AccountExportHandler) {
+	return &AccountExportHandler{
+		logger:  logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:  tracer,
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for the AccountExportHandler
+func (h *AccountExportHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/users/me/export", h.requirePrimaryAPIKey(h.Export))
+}
+
+// Export streams all the records of the account of the user
+// @Summary      Export your account
+// @Description  Download the messages, message threads, contacts, contact groups, phones and webhooks of your account as a gzip compressed NDJSON bundle. The first line of the bundle is a header with the format version and the last line is a manifest with the number of records and the SHA-256 checksum of every type, a bundle without a manifest was not downloaded completely. Restore the bundle on another installation with `go run ./cmd/account import`.
+// @Security	 ApiKeyAuth
+// @Tags         Users
+// @Produce      application/gzip
+// @Success      200 	{file}		file	"gzip compressed NDJSON bundle"
+// @Failure 	 401    {object}	responses.Unauthorized
+// @Failure 	 403    {object}	responses.Forbidden
+// @Failure      500	{object}	responses.InternalServerError
+// @Router       /users/me/export [get]
+func (h *AccountExportHandler) Export(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	userID := h.userIDFomContext(c)
+	filename := fmt.Sprintf("httpsms-account-%s.ndjson.gz", time.Now().UTC().Format("2006-01-02"))
+
+	c.Set(fiber.HeaderContentType, "application/gzip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+	// the status is sent before the records are read so an error truncates the bundle before the manifest
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		manifest, err := h.service.Export(ctx, userID, w)
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot export the account of user [%s]", userID)))
+			return
+		}
+
+		if err = w.Flush(); err != nil {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot flush the export of user [%s]", userID)))
+			return
+		}
+
+		ctxLogger.Info(fmt.Sprintf("exported account of user [%s] with [%d] sections", userID, len(manifest.Sections)))
+	})
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// APIKeyHandler handles API key requests
+type APIKeyHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	service   *services.APIKeyService
+	validator *validators.APIKeyHandlerValidator
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler
+func NewAPIKeyHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.APIKeyService,
+	validator *validators.APIKeyHandlerValidator,
+) (h *APIKeyHandler) {
+	return &APIKeyHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		service:   service,
+		validator: validator,
+	}
+}
+
+// RegisterRoutes registers the routes for the APIKeyHandler
+func (h *APIKeyHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/api-keys", h.requirePrimaryAPIKey(h.Index))
+	router.Post("/api-keys", h.requirePrimaryAPIKey(h.Store))
+	router.Delete("/api-keys/:apiKeyID", h.requirePrimaryAPIKey(h.Revoke))
+}
+
+// Index returns the scoped API keys of a user
+// @Summary      Get API keys of a user
+// @Description  Get the scoped API keys of a user. The API keys can only be managed with the primary API key of the user.
+// @Security	 ApiKeyAuth
+// @Tags         APIKeys
+// @Accept       json
+// @Produce      json
+// @Param        skip		query  int  	false	"number of API keys to skip"		minimum(0)
+// @Param        query		query  string  	false 	"filter API keys with a label containing query"
+// @Param        limit		query  int  	false	"number of API keys to return"	minimum(1)	maximum(100)
+// @Success      200 		{object}	responses.APIKeysResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /api-keys 	[get]
+func (h *APIKeyHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.APIKeyIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching api keys [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching API keys")
+	}
+
+	params := request.ToIndexParams()
+	apiKeys, err := h.service.Index(ctx, h.userIDFomContext(c), params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get api keys with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d API %s", len(apiKeys), h.pluralize("key", len(apiKeys))), apiKeys, params, len(apiKeys))
+}
+
+// Store an API key
+// @Summary      Store an API key
+// @Description  Create a scoped API key for the authenticated user. The key is only returned in this response, store it securely.
+// @Security	 ApiKeyAuth
+// @Tags         APIKeys
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.APIKeyStore  		true "Payload of the API key request"
+// @Success      201 		{object}	responses.APIKeyResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /api-keys [post]
+func (h *APIKeyHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.APIKeyStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall body [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while storing api key [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while storing API key")
+	}
+
+	apiKey, err := h.service.Store(ctx, request.ToStoreParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot store api key with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseCreated(c, "API key created successfully", apiKey)
+}
+
+// Revoke an API key
+// @Summary      Revoke an API key
+// @Description  Revoke a scoped API key of the authenticated user so that it can no longer be used
+// @Security	 ApiKeyAuth
+// @Tags         APIKeys
+// @Accept       json
+// @Produce      json
+// @Param 		 apiKeyID 	path		string 							true 	"ID of the API key"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200 		{object}	responses.APIKeyResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure 	 404    	{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /api-keys/{apiKeyID} [delete]
+func (h *APIKeyHandler) Revoke(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	apiKeyID := c.Params("apiKeyID")
+	if errors := h.validator.ValidateUUID(ctx, apiKeyID, "apiKeyID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while revoking api key with ID [%s]", spew.Sdump(errors), apiKeyID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while revoking API key")
+	}
+
+	apiKey, err := h.service.Revoke(ctx, h.userIDFomContext(c), uuid.MustParse(apiKeyID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find API key with ID [%s]", apiKeyID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot revoke api key with ID [%s]", apiKeyID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "API key revoked successfully", apiKey)
+}
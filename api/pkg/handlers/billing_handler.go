@@ -38,8 +38,9 @@ func NewBillingHandler(
 
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *BillingHandler) RegisterRoutes(router fiber.Router) {
-	router.Get("/billing/usage-history", h.UsageHistory)
-	router.Get("/billing/usage", h.Usage)
+	router.Get("/billing/usage-history", h.requirePrimaryAPIKey(h.UsageHistory))
+	router.Get("/billing/usage", h.requirePrimaryAPIKey(h.Usage))
+	router.Get("/users/me/usage", h.requirePrimaryAPIKey(h.UserUsage))
 }
 
 // UsageHistory returns the usage history of a user
@@ -76,14 +77,15 @@ func (h *BillingHandler) UsageHistory(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching usage history")
 	}
 
-	heartbeats, err := h.service.GetUsageHistory(ctx, h.userIDFomContext(c), request.ToIndexParams())
+	params := request.ToIndexParams()
+	heartbeats, err := h.service.GetUsageHistory(ctx, h.userIDFomContext(c), params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot get billing usage history with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d billing usage %s", len(*heartbeats), h.pluralize("record", len(*heartbeats))), heartbeats)
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d billing usage %s", len(*heartbeats), h.pluralize("record", len(*heartbeats))), heartbeats, params, len(*heartbeats))
 }
 
 // Usage returns the current usage history of a user
@@ -109,8 +111,34 @@ func (h *BillingHandler) Usage(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot get current usage record for user [%s]", h.userFromContext(c))
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "fetched current billing usage", billingUsage)
 }
+
+// UserUsage returns the usage of a user in the current and previous months
+// @Summary      Get user usage.
+// @Description  Get the number of messages sent and received by the authenticated user in the current and previous months
+// @Security	 ApiKeyAuth
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.UserUsageResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /users/me/usage [get]
+func (h *BillingHandler) UserUsage(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	usage, err := h.service.GetUserUsage(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot get usage for user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "fetched user usage", usage)
+}
@@ -2,9 +2,8 @@ package handlers
 
 import (
 	"fmt"
-	"sync"
 
-	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/google/uuid"
 
 	"github.com/NdoleStudio/httpsms/pkg/services"
@@ -44,7 +43,7 @@ func NewBulkMessageHandler(
 
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *BulkMessageHandler) RegisterRoutes(router fiber.Router) {
-	router.Post("/bulk-messages", h.Store)
+	router.Post("/bulk-messages", h.requireScope(entities.APIKeyScopeMessagesSend, h.Store))
 }
 
 // Store sends bulk SMS messages from a CSV file.
@@ -58,6 +57,7 @@ func (h *BulkMessageHandler) RegisterRoutes(router fiber.Router) {
 // @Failure      400		{object}	responses.BadRequest
 // @Failure 	 401	    {object}	responses.Unauthorized
 // @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      429		{object}	responses.TooManyRequests
 // @Failure      500		{object}	responses.InternalServerError
 // @Router       /bulk-messages [post]
 func (h *BulkMessageHandler) Store(c *fiber.Ctx) error {
@@ -83,24 +83,31 @@ func (h *BulkMessageHandler) Store(c *fiber.Ctx) error {
 		return h.responsePaymentRequired(c, *msg)
 	}
 
+	reservedAt, err := h.messageService.ReserveDailyMessages(ctx, h.userIDFomContext(c), uint(len(messages)))
+	if err != nil {
+		if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send [%d] messages", h.userIDFomContext(c), len(messages))))
+			return h.responseDailyMessageLimitExceeded(c, limitErr)
+		}
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot reserve [%d] messages for user with ID [%s]", len(messages), h.userIDFomContext(c))))
+		return h.responseInternalServerError(c)
+	}
+
 	requestID := uuid.New()
-	wg := sync.WaitGroup{}
+	ownerPhones := services.NewMessageOwnerPhones()
+	params := make([]services.MessageSendParams, 0, len(messages))
 	for _, message := range messages {
-		wg.Add(1)
-		go func(message *requests.BulkMessage) {
-			_, err = h.messageService.SendMessage(
-				ctx,
-				message.ToMessageSendParams(h.userIDFomContext(c), requestID, c.OriginalURL()),
-			)
+		param := message.ToMessageSendParams(h.userIDFomContext(c), requestID, c.OriginalURL())
+		param.DailyLimitReserved = true
+		param.DailyLimitReservedAt = reservedAt
+		param.OwnerPhones = ownerPhones
+		params = append(params, param)
+	}
 
-			if err != nil {
-				msg := fmt.Sprintf("cannot send message with paylod [%s]", c.Body())
-				ctxLogger.Error(stacktrace.Propagate(err, msg))
-			}
-			wg.Done()
-		}(message)
+	if _, err = h.messageService.SendMessages(ctx, params); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send [%d] messages from CSV file [%s] for user with ID [%s]", len(params), file.Filename, h.userIDFomContext(c))))
+		return h.responseServiceError(c, err)
 	}
 
-	wg.Wait()
 	return h.responseAccepted(c, fmt.Sprintf("Added %d messages to the queue", len(messages)))
 }
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// ContactGroupHandler handles the groups of contacts of a user and their members
+type ContactGroupHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.ContactGroupHandlerValidator
+	service   *services.ContactGroupService
+}
+
+// NewContactGroupHandler creates a new ContactGroupHandler
+func NewContactGroupHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.ContactGroupHandlerValidator,
+	service *services.ContactGroupService,
+) (h *ContactGroupHandler) {
+	return &ContactGroupHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the ContactGroupHandler
+func (h *ContactGroupHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/contact-groups", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+	router.Post("/contact-groups", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Store))
+	router.Get("/contact-groups/:contactGroupID", h.requireScope(entities.APIKeyScopeMessagesRead, h.Show))
+	router.Put("/contact-groups/:contactGroupID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Update))
+	router.Delete("/contact-groups/:contactGroupID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Delete))
+	router.Get("/contact-groups/:contactGroupID/members", h.requireScope(entities.APIKeyScopeMessagesRead, h.IndexMembers))
+	router.Post("/contact-groups/:contactGroupID/members", h.requireScope(entities.APIKeyScopeMessagesWrite, h.StoreMembers))
+	router.Delete("/contact-groups/:contactGroupID/members/:contactID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.DeleteMember))
+}
+
+// Index returns the contact groups of a user
+// @Summary      Get contact groups of a user
+// @Description  Get the contact groups of the currently authenticated user ordered by name
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param        skip		query  int  	false	"number of contact groups to skip"		minimum(0)
+// @Param        query		query  string  	false 	"filter contact groups with a name containing query"
+// @Param        limit		query  int  	false	"number of contact groups to return"	minimum(1)	maximum(100)
+// @Success      200 		{object}	responses.ContactGroupsResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups 	[get]
+func (h *ContactGroupHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactGroupIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching contact groups [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching contact groups")
+	}
+
+	params := request.ToIndexParams()
+	groups, err := h.service.Index(ctx, h.userIDFomContext(c), params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get contact groups with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(groups), h.pluralize("contact group", len(groups))), groups, params, len(groups))
+}
+
+// Store creates a contact group
+// @Summary      Create a contact group
+// @Description  Create a named group of contacts which a message can be sent to with the bulk send API
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.ContactGroupStore  	true 	"Payload of the contact group"
+// @Success      200 		{object}	responses.ContactGroupResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups 	[post]
+func (h *ContactGroupHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactGroupStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while creating contact group [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while creating contact group")
+	}
+
+	group, err := h.service.Store(ctx, request.ToStoreParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot create contact group [%s] for user [%s]", request.Name, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact group created successfully", group)
+}
+
+// Show returns a contact group of a user
+// @Summary      Get a contact group
+// @Description  Get a contact group of the currently authenticated user by ID
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param 		 contactGroupID	path		string 	true 	"ID of the contact group" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      200 		{object}	responses.ContactGroupResponse
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups/{contactGroupID} 	[get]
+func (h *ContactGroupHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	groupID := c.Params("contactGroupID")
+	if errors := h.validator.ValidateUUID(ctx, groupID, "contactGroupID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching contact group with ID [%s]", spew.Sdump(errors), groupID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching contact group")
+	}
+
+	group, err := h.service.Get(ctx, h.userIDFomContext(c), uuid.MustParse(groupID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s] for user [%s]", groupID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact group fetched successfully", group)
+}
+
+// Update the name of a contact group
+// @Summary      Update a contact group
+// @Description  Update the name of a contact group of the currently authenticated user
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param 		 contactGroupID	path		string 						true 	"ID of the contact group" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   		body 		requests.ContactGroupUpdate  true 	"Payload of the contact group"
+// @Success      200 		{object}	responses.ContactGroupResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups/{contactGroupID} 	[put]
+func (h *ContactGroupHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactGroupUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContactGroupID = c.Params("contactGroupID")
+	if errors := h.validator.ValidateUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating contact group [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating contact group")
+	}
+
+	group, err := h.service.Update(ctx, request.ToUpdateParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update contact group with ID [%s] for user [%s]", request.ContactGroupID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact group updated successfully", group)
+}
+
+// Delete a contact group
+// @Summary      Delete a contact group
+// @Description  Delete a contact group of the currently authenticated user, the contacts in the group are not deleted.
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param 		 contactGroupID	path		string 	true 	"ID of the contact group" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups/{contactGroupID} 	[delete]
+func (h *ContactGroupHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	groupID := c.Params("contactGroupID")
+	if errors := h.validator.ValidateUUID(ctx, groupID, "contactGroupID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting contact group with ID [%s]", spew.Sdump(errors), groupID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting contact group")
+	}
+
+	if err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(groupID)); err != nil {
+		msg := fmt.Sprintf("cannot delete contact group with ID [%s] for user [%s]", groupID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "contact group deleted successfully")
+}
+
+// IndexMembers returns the members of a contact group
+// @Summary      Get the members of a contact group
+// @Description  Get the contacts in a contact group of the currently authenticated user ordered by name
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param 		 contactGroupID	path		string 	true 	"ID of the contact group" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        skip		query  int  	false	"number of contacts to skip"		minimum(0)
+// @Param        limit		query  int  	false	"number of contacts to return"	minimum(1)	maximum(100)
+// @Success      200 		{object}	responses.ContactsResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups/{contactGroupID}/members 	[get]
+func (h *ContactGroupHandler) IndexMembers(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactGroupMemberIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContactGroupID = c.Params("contactGroupID")
+	if errors := h.validator.ValidateMemberIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the members of contact group [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching the members of the contact group")
+	}
+
+	params := request.ToIndexParams()
+	contacts, err := h.service.IndexMembers(ctx, h.userIDFomContext(c), uuid.MustParse(request.ContactGroupID), params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get the members of contact group [%s] with params [%+#v]", request.ContactGroupID, request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(contacts), h.pluralize("contact", len(contacts))), contacts, params, len(contacts))
+}
+
+// StoreMembers adds contacts to a contact group
+// @Summary      Add contacts to a contact group
+// @Description  Add up to 500 contacts to a contact group by ID, a contact which is already in the group is not added again. No contact is added when one of the contacts does not exist.
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param 		 contactGroupID	path		string 								true 	"ID of the contact group" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   		body 		requests.ContactGroupMemberStore  	true 	"IDs of the contacts"
+// @Success      200 		{object}	responses.ContactGroupMemberStoreResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups/{contactGroupID}/members 	[post]
+func (h *ContactGroupHandler) StoreMembers(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactGroupMemberStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContactGroupID = c.Params("contactGroupID")
+	if errors := h.validator.ValidateMemberStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while adding contacts to contact group [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while adding contacts to the contact group")
+	}
+
+	added, err := h.service.AddMembers(ctx, h.userIDFomContext(c), uuid.MustParse(request.ContactGroupID), request.ToUUIDs())
+	if err != nil {
+		msg := fmt.Sprintf("cannot add [%d] contacts to contact group [%s] for user [%s]", len(request.ContactIDs), request.ContactGroupID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("added %d %s to the contact group", added, h.pluralize("contact", added)), entities.ContactGroupMemberResult{Added: added})
+}
+
+// DeleteMember removes a contact from a contact group
+// @Summary      Remove a contact from a contact group
+// @Description  Remove a contact from a contact group, the contact is not deleted.
+// @Security	 ApiKeyAuth
+// @Tags         ContactGroups
+// @Accept       json
+// @Produce      json
+// @Param 		 contactGroupID	path		string 	true 	"ID of the contact group" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param 		 contactID		path		string 	true 	"ID of the contact" 		default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contact-groups/{contactGroupID}/members/{contactID} 	[delete]
+func (h *ContactGroupHandler) DeleteMember(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	groupID := c.Params("contactGroupID")
+	contactID := c.Params("contactID")
+
+	errors := h.validator.ValidateUUID(ctx, groupID, "contactGroupID")
+	for key, values := range h.validator.ValidateUUID(ctx, contactID, "contactID") {
+		errors[key] = values
+	}
+	if len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while removing contact [%s] from contact group [%s]", spew.Sdump(errors), contactID, groupID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while removing the contact from the contact group")
+	}
+
+	if err := h.service.RemoveMember(ctx, h.userIDFomContext(c), uuid.MustParse(groupID), uuid.MustParse(contactID)); err != nil {
+		msg := fmt.Sprintf("cannot remove contact [%s] from contact group [%s] for user [%s]", contactID, groupID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "contact removed from the contact group successfully")
+}
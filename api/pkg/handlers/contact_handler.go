@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// ContactHandler handles the contacts of a user and the sync of the address book of a phone
+type ContactHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.ContactHandlerValidator
+	service   *services.ContactService
+}
+
+// NewContactHandler creates a new ContactHandler
+func NewContactHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.ContactHandlerValidator,
+	service *services.ContactService,
+) (h *ContactHandler) {
+	return &ContactHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the ContactHandler
+func (h *ContactHandler) RegisterRoutes(router fiber.Router, phoneMiddlewares ...fiber.Handler) {
+	router.Get("/contacts", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+	router.Post("/contacts", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Store))
+	router.Post("/contacts/sync", h.computeRoute(phoneMiddlewares, h.requireScope(entities.APIKeyScopePhonesWrite, h.Sync))...)
+	router.Post("/contacts/labels/rename", h.requireScope(entities.APIKeyScopeMessagesWrite, h.RenameLabel))
+	router.Get("/contacts/:contactID", h.requireScope(entities.APIKeyScopeMessagesRead, h.Show))
+	router.Put("/contacts/:contactID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Update))
+	router.Put("/contacts/:contactID/notes", h.requireScope(entities.APIKeyScopeMessagesWrite, h.UpdateNotes))
+	router.Put("/contacts/:contactID/labels", h.requireScope(entities.APIKeyScopeMessagesWrite, h.UpdateLabels))
+	router.Delete("/contacts/:contactID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Delete))
+}
+
+// Index returns the contacts of a user
+// @Summary      Get contacts of a user
+// @Description  Get the names of the phone numbers of the currently authenticated user ordered by name
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        skip		query  int  	false	"number of contacts to skip"		minimum(0)
+// @Param        query		query  string  	false 	"filter contacts with a name or phone number containing query"
+// @Param        limit		query  int  	false	"number of contacts to return"	minimum(1)	maximum(100)
+// @Success      200 		{object}	responses.ContactsResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts 	[get]
+func (h *ContactHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching contacts [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching contacts")
+	}
+
+	params := request.ToIndexParams()
+	contacts, err := h.service.Index(ctx, h.userIDFomContext(c), params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get contacts with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(contacts), h.pluralize("contact", len(contacts))), contacts, params, len(contacts))
+}
+
+// Store sets the name of a phone number
+// @Summary      Set the name of a phone number
+// @Description  Set the name of a phone number which is displayed in the message threads and messages. A contact which is set with the API is not changed by the address book of the phone.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.ContactStore  	true 	"Payload of the contact"
+// @Success      200 		{object}	responses.ContactResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts 	[post]
+func (h *ContactHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while setting the contact of [%s]", spew.Sdump(errors), request.PhoneNumber)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while setting the contact")
+	}
+
+	contact, err := h.service.Upsert(ctx, request.ToUpsertParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot set the contact of [%s] for user [%s]", request.PhoneNumber, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact saved successfully", contact)
+}
+
+// Sync the address book of a phone
+// @Summary      Sync the address book of a phone
+// @Description  Upsert a batch of up to 500 entries of the address book of the phone by phone number. A number in the national format is parsed in the region of the owner. Set deleted for an entry which was deleted from the address book, an entry which is older than the contact and the number of a contact which was set with the API are skipped.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.ContactSync  	true 	"Batch of the address book"
+// @Success      200 		{object}	responses.ContactSyncResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/sync 	[post]
+func (h *ContactHandler) Sync(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactSync
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateSync(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while syncing [%d] contacts of phone [%s]", spew.Sdump(errors), len(request.Contacts), request.Owner)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while syncing contacts")
+	}
+
+	result, err := h.service.Sync(ctx, request.ToSyncParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot sync [%d] contacts of phone [%s] for user [%s]", len(request.Contacts), request.Owner, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("synced %d %s", result.Synced, h.pluralize("contact", result.Synced)), result)
+}
+
+// Show returns a contact of a user
+// @Summary      Get a contact
+// @Description  Get a contact of the currently authenticated user by ID
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param 		 contactID	path		string 	true 	"ID of the contact" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      200 		{object}	responses.ContactResponse
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/{contactID} 	[get]
+func (h *ContactHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	contactID := c.Params("contactID")
+	if errors := h.validator.ValidateUUID(ctx, contactID, "contactID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching contact with ID [%s]", spew.Sdump(errors), contactID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching contact")
+	}
+
+	contact, err := h.service.Get(ctx, h.userIDFomContext(c), uuid.MustParse(contactID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s] for user [%s]", contactID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact fetched successfully", contact)
+}
+
+// Update the name of a contact
+// @Summary      Update a contact
+// @Description  Update the name of a contact, a contact which is updated with the API is not changed by the address book of the phone.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param 		 contactID	path		string 					true 	"ID of the contact" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   	body 		requests.ContactUpdate  true 	"Payload of the contact"
+// @Success      200 		{object}	responses.ContactResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/{contactID} 	[put]
+func (h *ContactHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContactID = c.Params("contactID")
+	if errors := h.validator.ValidateUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating contact [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating contact")
+	}
+
+	contact, err := h.service.Update(ctx, request.ToUpdateParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update contact with ID [%s] for user [%s]", request.ContactID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact updated successfully", contact)
+}
+
+// UpdateNotes of a contact
+// @Summary      Update the notes of a contact
+// @Description  Set free text notes e.g. "prefers French" on a contact, the user who changed the notes is stored with the time of the change.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param 		 contactID	path		string 						true 	"ID of the contact" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   	body 		requests.ContactNotesUpdate	true 	"Payload of the notes"
+// @Success      200 		{object}	responses.ContactResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/{contactID}/notes 	[put]
+func (h *ContactHandler) UpdateNotes(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactNotesUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContactID = c.Params("contactID")
+	if errors := h.validator.ValidateNotesUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating the notes of contact [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating the notes of contact")
+	}
+
+	contact, err := h.service.UpdateNotes(ctx, request.ToNotesParams(h.userIDFomContext(c), h.userFromContext(c).ActingUserID()))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update the notes of contact with ID [%s] for user [%s]", request.ContactID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact notes updated successfully", contact)
+}
+
+// UpdateLabels of a contact
+// @Summary      Update the labels of a contact
+// @Description  Replace the labels of a contact e.g. ["vip", "french"], the labels are lowercase and duplicate labels are removed.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param 		 contactID	path		string 						true 	"ID of the contact" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   	body 		requests.ContactLabelsUpdate	true 	"Payload of the labels"
+// @Success      200 		{object}	responses.ContactResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/{contactID}/labels 	[put]
+func (h *ContactHandler) UpdateLabels(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactLabelsUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContactID = c.Params("contactID")
+	if errors := h.validator.ValidateLabelsUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating the labels of contact [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating the labels of contact")
+	}
+
+	contact, err := h.service.UpdateLabels(ctx, request.ToLabelsParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update the labels of contact with ID [%s] for user [%s]", request.ContactID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "contact labels updated successfully", contact)
+}
+
+// RenameLabel on all the contacts of a user
+// @Summary      Rename a contact label
+// @Description  Rename a label on all the contacts of the currently authenticated user which have it, a contact which already has the new label keeps it once.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.ContactLabelRename	true 	"Payload of the label to rename"
+// @Success      200 		{object}	responses.ContactLabelRenameResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/labels/rename 	[post]
+func (h *ContactHandler) RenameLabel(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContactLabelRename
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateLabelRename(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while renaming contact label [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while renaming contact label")
+	}
+
+	result, err := h.service.RenameLabel(ctx, request.ToRenameParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot rename label [%s] for user [%s]", request.Label, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("renamed label on %d %s", result.Contacts, h.pluralize("contact", result.Contacts)), result)
+}
+
+// Delete a contact
+// @Summary      Delete a contact
+// @Description  Delete a contact of the currently authenticated user, the phone number gets the name in the address book of the phone again on the next sync.
+// @Security	 ApiKeyAuth
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param 		 contactID	path		string 	true 	"ID of the contact" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /contacts/{contactID} 	[delete]
+func (h *ContactHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	contactID := c.Params("contactID")
+	if errors := h.validator.ValidateUUID(ctx, contactID, "contactID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting contact with ID [%s]", spew.Sdump(errors), contactID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting contact")
+	}
+
+	if err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(contactID)); err != nil {
+		msg := fmt.Sprintf("cannot delete contact with ID [%s] for user [%s]", contactID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "contact deleted successfully")
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// ContentExpiryRuleHandler handles the rules which remove the content of received messages e.g. verification codes after a TTL
+type ContentExpiryRuleHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.ContentExpiryRuleHandlerValidator
+	service   *services.ContentExpiryService
+}
+
+// NewContentExpiryRuleHandler creates a new ContentExpiryRuleHandler
+func NewContentExpiryRuleHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.ContentExpiryRuleHandlerValidator,
+	service *services.ContentExpiryService,
+) (h *ContentExpiryRuleHandler) {
+	return &ContentExpiryRuleHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the ContentExpiryRuleHandler
+func (h *ContentExpiryRuleHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/content-expiry-rules", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+	router.Post("/content-expiry-rules", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Store))
+	router.Post("/content-expiry-rules/dry-run", h.requireScope(entities.APIKeyScopeMessagesRead, h.DryRun))
+	router.Get("/content-expiry-rules/:contentExpiryRuleID", h.requireScope(entities.APIKeyScopeMessagesRead, h.Show))
+	router.Put("/content-expiry-rules/:contentExpiryRuleID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Update))
+	router.Delete("/content-expiry-rules/:contentExpiryRuleID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Delete))
+}
+
+// Index returns the content expiry rules of a user
+// @Summary      Get content expiry rules of a user
+// @Description  Get all the rules which remove the content of the messages received by the phones of a user after a TTL
+// @Security	 ApiKeyAuth
+// @Tags         ContentExpiryRules
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.ContentExpiryRulesResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /content-expiry-rules 	[get]
+func (h *ContentExpiryRuleHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	rules, err := h.service.Index(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot get content expiry rules for user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(rules), h.pluralize("content expiry rule", len(rules))), rules)
+}
+
+// Store creates a content expiry rule
+// @Summary      Create a content expiry rule
+// @Description  Replace the content of the messages received by the owner phone number which match the pattern or are sent by one of the contacts with "[content expired]" after ttl_seconds. The rule with the shortest TTL is used when many rules match a message.
+// @Security	 ApiKeyAuth
+// @Tags         ContentExpiryRules
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.ContentExpiryRuleStore  	true 	"Payload of the content expiry rule"
+// @Success      200 		{object}	responses.ContentExpiryRuleResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /content-expiry-rules 	[post]
+func (h *ContentExpiryRuleHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContentExpiryRuleStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while creating content expiry rule [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while creating content expiry rule")
+	}
+
+	rule, err := h.service.Store(ctx, request.ToStoreParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot create content expiry rule with owner [%s] for user [%s]", request.Owner, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "content expiry rule created successfully", rule)
+}
+
+// Show returns a content expiry rule of a user
+// @Summary      Get a content expiry rule
+// @Description  Get a content expiry rule of the currently authenticated user by ID
+// @Security	 ApiKeyAuth
+// @Tags         ContentExpiryRules
+// @Accept       json
+// @Produce      json
+// @Param 		 contentExpiryRuleID	path		string 	true 	"ID of the content expiry rule" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      200 		{object}	responses.ContentExpiryRuleResponse
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /content-expiry-rules/{contentExpiryRuleID} 	[get]
+func (h *ContentExpiryRuleHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	ruleID := c.Params("contentExpiryRuleID")
+	if errors := h.validator.ValidateUUID(ctx, ruleID, "contentExpiryRuleID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching content expiry rule with ID [%s]", spew.Sdump(errors), ruleID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching content expiry rule")
+	}
+
+	rule, err := h.service.Get(ctx, h.userIDFomContext(c), uuid.MustParse(ruleID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load content expiry rule with ID [%s] for user [%s]", ruleID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "content expiry rule fetched successfully", rule)
+}
+
+// Update a content expiry rule
+// @Summary      Update a content expiry rule
+// @Description  Update the matching and the TTL of a content expiry rule of the currently authenticated user, the messages which were received before the update keep their expiry time
+// @Security	 ApiKeyAuth
+// @Tags         ContentExpiryRules
+// @Accept       json
+// @Produce      json
+// @Param 		 contentExpiryRuleID	path		string 						true 	"ID of the content expiry rule" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   		body 		requests.ContentExpiryRuleStore  	true 	"Payload of the content expiry rule"
+// @Success      200 		{object}	responses.ContentExpiryRuleResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /content-expiry-rules/{contentExpiryRuleID} 	[put]
+func (h *ContentExpiryRuleHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContentExpiryRuleUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.ContentExpiryRuleID = c.Params("contentExpiryRuleID")
+	if errors := h.validator.ValidateUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating content expiry rule [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating content expiry rule")
+	}
+
+	rule, err := h.service.Update(ctx, request.ToUpdateParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update content expiry rule with ID [%s] for user [%s]", request.ContentExpiryRuleID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "content expiry rule updated successfully", rule)
+}
+
+// Delete a content expiry rule
+// @Summary      Delete a content expiry rule
+// @Description  Delete a content expiry rule of the currently authenticated user, the content of the messages which it matched still expires.
+// @Security	 ApiKeyAuth
+// @Tags         ContentExpiryRules
+// @Accept       json
+// @Produce      json
+// @Param 		 contentExpiryRuleID	path		string 	true 	"ID of the content expiry rule" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /content-expiry-rules/{contentExpiryRuleID} 	[delete]
+func (h *ContentExpiryRuleHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	ruleID := c.Params("contentExpiryRuleID")
+	if errors := h.validator.ValidateUUID(ctx, ruleID, "contentExpiryRuleID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting content expiry rule with ID [%s]", spew.Sdump(errors), ruleID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting content expiry rule")
+	}
+
+	if err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(ruleID)); err != nil {
+		msg := fmt.Sprintf("cannot delete content expiry rule with ID [%s] for user [%s]", ruleID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "content expiry rule deleted successfully")
+}
+
+// DryRun matches a message with the content expiry rules of a user
+// @Summary      Test the content expiry rules
+// @Description  Find the content expiry rule which matches a message as if it was received now and the time when its content would expire, the message is not stored.
+// @Security	 ApiKeyAuth
+// @Tags         ContentExpiryRules
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.ContentExpiryRuleDryRun  	true 	"The received message"
+// @Success      200 		{object}	responses.ContentExpiryMatchResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /content-expiry-rules/dry-run 	[post]
+func (h *ContentExpiryRuleHandler) DryRun(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.ContentExpiryRuleDryRun
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateDryRun(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while testing content expiry rules [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while testing content expiry rules")
+	}
+
+	match, err := h.service.DryRun(ctx, request.ToDryRunParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot test content expiry rules with owner [%s] for user [%s]", request.Owner, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	if match.Rule == nil {
+		return h.responseOK(c, "no content expiry rule matches the message", match)
+	}
+	return h.responseOK(c, "the content of the message expires with the content expiry rule", match)
+}
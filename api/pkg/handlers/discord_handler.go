@@ -60,10 +60,10 @@ func (h *DiscordHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Han
 	router.Post("/event", h.computeRoute(middlewares, h.Event)...)
 
 	authRouter := app.Group("v1/discord-integrations")
-	authRouter.Post("/", h.computeRoute(append(middlewares, authMiddleware), h.Store)...)
-	authRouter.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.Index)...)
-	authRouter.Delete("/:discordID", h.computeRoute(append(middlewares, authMiddleware), h.Delete)...)
-	authRouter.Put("/:discordID", h.computeRoute(append(middlewares, authMiddleware), h.Update)...)
+	authRouter.Post("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Store))...)
+	authRouter.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Index))...)
+	authRouter.Delete("/:discordID", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Delete))...)
+	authRouter.Put("/:discordID", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Update))...)
 }
 
 // Index returns the discord integrations of a user
@@ -99,14 +99,15 @@ func (h *DiscordHandler) Index(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching discord integrations")
 	}
 
-	discordIntegrations, err := h.service.Index(ctx, h.userIDFomContext(c), request.ToIndexParams())
+	params := request.ToIndexParams()
+	discordIntegrations, err := h.service.Index(ctx, h.userIDFomContext(c), params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot get discord integrations with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d discord %s", len(discordIntegrations), h.pluralize("integration", len(discordIntegrations))), discordIntegrations)
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d discord %s", len(discordIntegrations), h.pluralize("integration", len(discordIntegrations))), discordIntegrations, params, len(discordIntegrations))
 }
 
 // Delete a discord integration
@@ -138,7 +139,7 @@ func (h *DiscordHandler) Delete(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot delete discord integration with ID [%+#v]", discordID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "discord integration deleted successfully", nil)
@@ -181,7 +182,7 @@ func (h *DiscordHandler) Update(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot update discord integration with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "discord integration updated successfully", user)
@@ -223,7 +224,7 @@ func (h *DiscordHandler) Store(c *fiber.Ctx) error {
 	discordIntegrations, err := h.service.Index(ctx, h.userIDFomContext(c), repositories.IndexParams{Skip: 0, Limit: 1})
 	if err != nil {
 		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot index discord integrations for user [%s]", h.userIDFomContext(c))))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	if len(discordIntegrations) > 0 {
@@ -235,7 +236,7 @@ func (h *DiscordHandler) Store(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot store discord integration with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseCreated(c, "discord integration created successfully", discordIntegration)
@@ -341,7 +342,7 @@ func (h *DiscordHandler) sendSMS(ctx context.Context, c *fiber.Ctx, payload map[
 		},
 	}
 
-	if errors := h.messageValidator.ValidateMessageSend(ctx, discord.UserID, request.Sanitize()); len(errors) != 0 {
+	if errors := h.messageValidator.ValidateMessageSend(ctx, discord.UserID, request); len(errors) != 0 {
 		msg := fmt.Sprintf("validation errors [%s], while sending payload [%s]", spew.Sdump(errors), c.Body())
 		ctxLogger.Warn(stacktrace.NewError(msg))
 
@@ -382,7 +383,44 @@ func (h *DiscordHandler) sendSMS(ctx context.Context, c *fiber.Ctx, payload map[
 		)
 	}
 
+	request.Sanitize()
 	message, err := h.messageService.SendMessage(ctx, request.ToMessageSendParams(discord.UserID, c.OriginalURL()))
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] exceeded the daily message limit", discord.UserID)))
+		return c.JSON(
+			fiber.Map{
+				"type": 4,
+				"data": fiber.Map{
+					"content": "**⚠️ error while sending message**",
+					"embeds": append([]fiber.Map{
+						{
+							"title": limitErr.Error(),
+							"color": 14681092,
+						},
+					}, messageEmbed),
+				},
+			},
+		)
+	}
+
+	if ownerErr, ok := services.AsOwnerNotRegisteredError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send a message from [%s]", discord.UserID, ownerErr.Owner)))
+		return c.JSON(
+			fiber.Map{
+				"type": 4,
+				"data": fiber.Map{
+					"content": "**⚠️ error while sending message**",
+					"embeds": append([]fiber.Map{
+						{
+							"title": ownerErr.Error(),
+							"color": 14681092,
+						},
+					}, messageEmbed),
+				},
+			},
+		)
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("cannot send message with paylod [%s] from discord server [%s]", c.Body(), discord.ServerID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
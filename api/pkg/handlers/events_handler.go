@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"net/http"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/uuid"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/services"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/davecgh/go-spew/spew"
@@ -12,33 +19,46 @@ import (
 	"github.com/palantir/stacktrace"
 )
 
+// maxReceivedEventSize is the maximum size in bytes of the body of an event which is sent to the events receiver
+const maxReceivedEventSize = 64 * 1024
+
 // EventsHandler handles heartbeat http requests.
 type EventsHandler struct {
 	handler
-	logger      telemetry.Logger
-	tracer      telemetry.Tracer
-	queueConfig services.PushQueueConfig
-	service     *services.EventDispatcher
+	logger          telemetry.Logger
+	tracer          telemetry.Tracer
+	queueConfig     services.PushQueueConfig
+	service         *services.EventDispatcher
+	receivableTypes map[string]bool
 }
 
-// NewEventsHandler creates a new EventsHandler
+// NewEventsHandler creates a new EventsHandler, receivableTypes are the types of the events which other systems
+// can send to the events receiver
 func NewEventsHandler(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	queueConfig services.PushQueueConfig,
 	service *services.EventDispatcher,
+	receivableTypes []string,
 ) (h *EventsHandler) {
+	types := make(map[string]bool, len(receivableTypes))
+	for _, eventType := range receivableTypes {
+		types[eventType] = true
+	}
+
 	return &EventsHandler{
-		logger:      logger.WithService(fmt.Sprintf("%T", h)),
-		tracer:      tracer,
-		queueConfig: queueConfig,
-		service:     service,
+		logger:          logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:          tracer,
+		queueConfig:     queueConfig,
+		service:         service,
+		receivableTypes: types,
 	}
 }
 
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *EventsHandler) RegisterRoutes(router fiber.Router) {
-	router.Post("/events", h.Dispatch)
+	router.Post("/events/receive", h.requireScope(entities.APIKeyScopeEventsWrite, h.Receive))
+	router.Post("/events", h.requirePrimaryAPIKey(h.Dispatch))
 }
 
 // Dispatch a cloud event
@@ -72,9 +92,77 @@ func (h *EventsHandler) Dispatch(c *fiber.Ctx) error {
 	err := h.service.DispatchSync(ctx, request)
 	if err != nil {
 		msg := fmt.Sprintf("cannot dispatch [%s] event with ID [%s]", request.Type(), request.ID())
-		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		err = stacktrace.Propagate(err, msg)
+		ctxLogger.Error(err)
+		h.tracer.ReportError(ctx, err, fmt.Sprintf("%T.Dispatch:%s", h, request.Type()))
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseNoContent(c, "event dispatched successfully")
 }
+
+// Receive a cloud event from another system in the binary or structured HTTP encoding and dispatch it to the listeners.
+// The payload of the event must have the ID of the authenticated user in the "user_id" field.
+// This is an internal API so no documentation provided
+func (h *EventsHandler) Receive(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	if len(c.Body()) > maxReceivedEventSize {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("event with size [%d] bytes is larger than [%d] bytes", len(c.Body()), maxReceivedEventSize)))
+		return h.responsePayloadTooLarge(c, fmt.Sprintf("The event must not be larger than %d bytes", maxReceivedEventSize))
+	}
+
+	header := http.Header{}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+
+	event, err := binding.ToEvent(ctx, cehttp.NewMessage(header, io.NopCloser(bytes.NewReader(c.Body()))))
+	if err != nil {
+		msg := fmt.Sprintf("cannot decode the cloud event in the request [%s]", c.OriginalURL())
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validateReceivedEvent(c, event); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while receiving event [%+#v]", spew.Sdump(errors), event)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while receiving event")
+	}
+
+	ctxLogger.Info(fmt.Sprintf("received [%s] event with ID [%s] from [%s]", event.Type(), event.ID(), event.Source()))
+	if err = h.service.Dispatch(ctx, *event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch received [%s] event with ID [%s]", event.Type(), event.ID())
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseAccepted(c, "event received successfully")
+}
+
+func (h *EventsHandler) validateReceivedEvent(c *fiber.Ctx, event *cloudevents.Event) map[string][]string {
+	if err := event.Validate(); err != nil {
+		return map[string][]string{"event": {err.Error()}}
+	}
+
+	errors := map[string][]string{}
+	if _, err := uuid.Parse(event.ID()); err != nil {
+		errors["id"] = append(errors["id"], "The id of the event must be a UUID")
+	}
+
+	if !h.receivableTypes[event.Type()] {
+		errors["type"] = append(errors["type"], fmt.Sprintf("Events with type [%s] cannot be received", event.Type()))
+	}
+
+	payload := struct {
+		UserID entities.UserID `json:"user_id"`
+	}{}
+	if err := event.DataAs(&payload); err != nil || payload.UserID != h.userIDFomContext(c) {
+		errors["data"] = append(errors["data"], "The data of the event must be a JSON object with the ID of the authenticated user in the [user_id] field")
+	}
+
+	return errors
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+const receivedEventType = "user.subscription.updated"
+
+// publishingPushQueue publishes the events in the tasks immediately instead of sending them to a queue
+type publishingPushQueue struct {
+	dispatcher *services.EventDispatcher
+}
+
+func (queue *publishingPushQueue) Enqueue(ctx context.Context, task *services.PushQueueTask, _ time.Duration) (string, error) {
+	var event cloudevents.Event
+	if err := json.Unmarshal(task.Body, &event); err != nil {
+		return "", err
+	}
+	queue.dispatcher.Publish(ctx, event)
+	return event.ID(), nil
+}
+
+func newEventsReceiverApp(user entities.AuthUser, received *[]cloudevents.Event) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	queue := &publishingPushQueue{}
+	dispatcher := services.NewEventDispatcher(logger, tracer, noop.Float64Histogram{}, queue, services.PushQueueConfig{}, memory.NewEventRepository())
+	queue.dispatcher = dispatcher
+	dispatcher.Subscribe(receivedEventType, func(_ context.Context, event cloudevents.Event) error {
+		*received = append(*received, event)
+		return nil
+	})
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middlewares.ContextKeyAuthUserID, user)
+		return c.Next()
+	})
+	NewEventsHandler(logger, tracer, services.PushQueueConfig{}, dispatcher, []string{receivedEventType}).RegisterRoutes(app)
+	return app
+}
+
+func TestEventsHandler_Receive(t *testing.T) {
+	user := entities.AuthUser{ID: "user-a", Email: "name@email.com"}
+	payload := `{"user_id":"user-a","subscription_status":"active"}`
+
+	t.Run("event in the structured encoding is dispatched to the listeners", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var received []cloudevents.Event
+		app := newEventsReceiverApp(user, &received)
+
+		// Arrange
+		eventID := uuid.NewString()
+		body := `{"specversion":"1.0","id":"` + eventID + `","type":"` + receivedEventType + `","source":"https://billing.example.com","datacontenttype":"application/json","data":` + payload + `}`
+		request := httptest.NewRequest(fiber.MethodPost, "/events/receive", strings.NewReader(body))
+		request.Header.Set(fiber.HeaderContentType, "application/cloudevents+json")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusAccepted, response.StatusCode)
+		assert.Equal(t, 1, len(received))
+		assert.Equal(t, eventID, received[0].ID())
+		assert.Equal(t, "https://billing.example.com", received[0].Source())
+		assert.JSONEq(t, payload, string(received[0].Data()))
+	})
+
+	t.Run("event in the binary encoding is dispatched to the listeners", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var received []cloudevents.Event
+		app := newEventsReceiverApp(user, &received)
+
+		// Arrange
+		eventID := uuid.NewString()
+		request := httptest.NewRequest(fiber.MethodPost, "/events/receive", strings.NewReader(payload))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		request.Header.Set("ce-specversion", "1.0")
+		request.Header.Set("ce-id", eventID)
+		request.Header.Set("ce-type", receivedEventType)
+		request.Header.Set("ce-source", "https://billing.example.com")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusAccepted, response.StatusCode)
+		assert.Equal(t, 1, len(received))
+		assert.Equal(t, eventID, received[0].ID())
+		assert.JSONEq(t, payload, string(received[0].Data()))
+	})
+
+	t.Run("event with a type which is not allowed is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var received []cloudevents.Event
+		app := newEventsReceiverApp(user, &received)
+
+		// Arrange
+		request := httptest.NewRequest(fiber.MethodPost, "/events/receive", strings.NewReader(payload))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		request.Header.Set("ce-specversion", "1.0")
+		request.Header.Set("ce-id", uuid.NewString())
+		request.Header.Set("ce-type", "message.phone.delivered")
+		request.Header.Set("ce-source", "https://billing.example.com")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, response.StatusCode)
+		assert.Empty(t, received)
+	})
+
+	t.Run("event of another user is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var received []cloudevents.Event
+		app := newEventsReceiverApp(user, &received)
+
+		// Arrange
+		request := httptest.NewRequest(fiber.MethodPost, "/events/receive", strings.NewReader(`{"user_id":"user-b"}`))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		request.Header.Set("ce-specversion", "1.0")
+		request.Header.Set("ce-id", uuid.NewString())
+		request.Header.Set("ce-type", receivedEventType)
+		request.Header.Set("ce-source", "https://billing.example.com")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, response.StatusCode)
+		assert.Empty(t, received)
+	})
+
+	t.Run("oversized event is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var received []cloudevents.Event
+		app := newEventsReceiverApp(user, &received)
+
+		// Arrange
+		body := `{"user_id":"user-a","content":"` + strings.Repeat("a", maxReceivedEventSize) + `"}`
+		request := httptest.NewRequest(fiber.MethodPost, "/events/receive", strings.NewReader(body))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		request.Header.Set("ce-specversion", "1.0")
+		request.Header.Set("ce-id", uuid.NewString())
+		request.Header.Set("ce-type", receivedEventType)
+		request.Header.Set("ce-source", "https://billing.example.com")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, response.StatusCode)
+		assert.Empty(t, received)
+	})
+
+	t.Run("API key without the events:write scope is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var received []cloudevents.Event
+		apiKeyID := uuid.New()
+		app := newEventsReceiverApp(entities.AuthUser{ID: "user-a", Email: "name@email.com", APIKeyID: &apiKeyID, Scopes: []entities.APIKeyScope{entities.APIKeyScopeMessagesRead}}, &received)
+
+		// Arrange
+		request := httptest.NewRequest(fiber.MethodPost, "/events/receive", strings.NewReader(payload))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusForbidden, response.StatusCode)
+		assert.Empty(t, received)
+	})
+}
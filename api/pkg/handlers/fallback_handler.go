@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// FallbackHandler handles the fallback provider of a user and the status callbacks of the provider
+type FallbackHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.FallbackHandlerValidator
+	service   *services.FallbackService
+}
+
+// NewFallbackHandler creates a new FallbackHandler
+func NewFallbackHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.FallbackHandlerValidator,
+	service *services.FallbackService,
+) (h *FallbackHandler) {
+	return &FallbackHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the FallbackHandler
+func (h *FallbackHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler, middlewares ...fiber.Handler) {
+	router := app.Group("fallback-callbacks")
+	router.Post("/:providerID/messages/:messageID", h.computeRoute(middlewares, h.Callback)...)
+
+	authRouter := app.Group("v1/fallback-provider")
+	authRouter.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Show))...)
+	authRouter.Put("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Upsert))...)
+	authRouter.Delete("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Delete))...)
+}
+
+// Show returns the fallback provider of a user
+// @Summary      Get the fallback provider
+// @Description  Get the SMS provider which sends the messages that the phone of the currently authenticated user cannot send, the auth token and signing key are never returned
+// @Security	 ApiKeyAuth
+// @Tags         FallbackProvider
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.FallbackProviderResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /fallback-provider 	[get]
+func (h *FallbackHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	provider, err := h.service.Load(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the fallback provider of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "fallback provider fetched successfully", provider)
+}
+
+// Upsert the entities.FallbackProvider of a user
+// @Summary      Set the fallback provider
+// @Description  Set the twilio or http SMS provider which sends the messages with allow_fallback when the phone has been offline for offline_after_seconds or when they expire after the last send attempt of the phone. A message is sent either by the phone or the provider, never both.
+// @Security	 ApiKeyAuth
+// @Tags         FallbackProvider
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.FallbackProviderUpsert  	true 	"Payload of the fallback provider"
+// @Success      200 		{object}	responses.FallbackProviderResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /fallback-provider 	[put]
+func (h *FallbackHandler) Upsert(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.FallbackProviderUpsert
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateUpsert(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while setting the [%s] fallback provider", spew.Sdump(errors), request.Type)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while setting the fallback provider")
+	}
+
+	provider, err := h.service.Upsert(ctx, request.ToUpsertParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot set the [%s] fallback provider of user [%s]", request.Type, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "fallback provider saved successfully", provider)
+}
+
+// Delete the fallback provider of a user
+// @Summary      Delete the fallback provider
+// @Description  Delete the fallback provider of the currently authenticated user, the messages are then only sent by the phone
+// @Security	 ApiKeyAuth
+// @Tags         FallbackProvider
+// @Accept       json
+// @Produce      json
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /fallback-provider [delete]
+func (h *FallbackHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	if err := h.service.Delete(ctx, h.userIDFomContext(c)); err != nil {
+		msg := fmt.Sprintf("cannot delete the fallback provider of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "fallback provider deleted successfully")
+}
+
+// Callback handles a status callback of a fallback provider
+// @Summary      Receive a status callback of a fallback provider
+// @Description  Store the status of a message which was sent by a fallback provider. A twilio callback must have a valid X-Twilio-Signature header and an http callback must have the HMAC-SHA256 of its body with the signing key in the X-Httpsms-Signature header.
+// @Tags         FallbackProvider
+// @Accept       json
+// @Produce      json
+// @Param 		 providerID	path		string 				true 	"ID of the fallback provider"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param 		 messageID	path		string 				true 	"ID of the message"				default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /fallback-callbacks/{providerID}/messages/{messageID} [post]
+func (h *FallbackHandler) Callback(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	providerID := c.Params("providerID")
+	messageID := c.Params("messageID")
+	errors := h.validator.ValidateUUID(ctx, providerID, "providerID")
+	for key, values := range h.validator.ValidateUUID(ctx, messageID, "messageID") {
+		errors[key] = values
+	}
+	if len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while receiving the callback of fallback provider [%s]", spew.Sdump(errors), providerID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while receiving fallback provider callback")
+	}
+
+	err := h.service.HandleCallback(ctx, services.FallbackCallbackParams{
+		ProviderID: uuid.MustParse(providerID),
+		MessageID:  uuid.MustParse(messageID),
+		Request: services.FallbackCallbackRequest{
+			Body:   c.Body(),
+			Header: func(key string) string { return c.Get(key) },
+		},
+		Source: c.OriginalURL(),
+	})
+	if stacktrace.GetCode(err) == services.ErrCodeFallbackUnauthorized {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot authenticate the callback of fallback provider [%s] for message [%s]", providerID, messageID)))
+		return h.responseUnauthorized(c)
+	}
+
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot handle the callback of fallback provider [%s] for message [%s]", providerID, messageID)))
+		return h.responseServiceError(c, err)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
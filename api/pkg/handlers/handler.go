@@ -1,93 +1,369 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
 )
 
+// headerTotalCount is the response header with the total number of entities which match the filters of a paginated request
+const headerTotalCount = "X-Total-Count"
+
 // handler is the base struct for handling requests
 type handler struct{}
 
-func (h *handler) responseBadRequest(c *fiber.Ctx, err error) error {
-	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+// render writes the envelope of a response in the shape of the version of the API in the request
+func (h *handler) render(c *fiber.Ctx, status int, envelope fiber.Map) error {
+	return c.Status(status).JSON(responses.Envelope(telemetry.APIVersion(c.UserContext()), envelope))
+}
+
+// responseError renders the envelope of an error with a machine readable code
+func (h *handler) responseError(c *fiber.Ctx, status int, code responses.ErrorCode, message string, data any) error {
+	payload := fiber.Map{
 		"status":  "error",
-		"message": "The request isn't properly formed",
-		"data":    err,
-	})
+		"code":    code,
+		"message": message,
+	}
+	if data != nil {
+		payload["data"] = data
+	}
+	return h.render(c, status, payload)
+}
+
+// responseServiceError maps the typed errors returned by the services to the status code of the response
+func (h *handler) responseServiceError(c *fiber.Ctx, err error) error {
+	if errors.Is(stacktrace.RootCause(err), context.DeadlineExceeded) || errors.Is(c.UserContext().Err(), context.DeadlineExceeded) {
+		return h.responseGatewayTimeout(c)
+	}
+
+	if validationErr, ok := services.AsValidationError(err); ok {
+		return h.responseValidationError(c, validationErr, "validation errors while handling the request")
+	}
+
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		return h.responseDailyMessageLimitExceeded(c, limitErr)
+	}
+
+	if dndErr, ok := services.AsContactDNDError(err); ok {
+		return h.responseContactDND(c, dndErr)
+	}
+
+	if recipientErr, ok := services.AsRecipientNotSendableError(err); ok {
+		return h.responseRecipientNotSendable(c, recipientErr)
+	}
+
+	if mismatchErr, ok := services.AsEncryptionMismatchError(err); ok {
+		return h.responseEncryptionMismatch(c, mismatchErr)
+	}
+
+	if contentErr, ok := services.AsEncryptedContentError(err); ok {
+		return h.responseEncryptedContent(c, contentErr)
+	}
+
+	if forwardErr, ok := services.AsMessageNotForwardableError(err); ok {
+		return h.responseNotForwardable(c, forwardErr)
+	}
+
+	switch stacktrace.GetCode(err) {
+	case repositories.ErrCodeNotFound:
+		return h.responseNotFound(c, "cannot find the resource in the request")
+	case repositories.ErrCodeStaleUpdate, services.ErrCodeInvalidTransition:
+		return h.responseConflict(c, "the resource cannot be changed from its current state")
+	case services.ErrCodeRateLimited:
+		return h.responseTooManyRequests(c, "the phone has exceeded its messages per minute, try again later")
+	case services.ErrCodeDailySendLimit:
+		return h.responseTooManyRequests(c, "the phone has reached its daily send limit, try again after midnight")
+	case services.ErrCodeMediaUnavailable:
+		return h.responseServiceUnavailable(c, "media cannot be sent because the blob store is not configured", nil)
+	default:
+		return h.responseInternalServerError(c)
+	}
+}
+
+func (h *handler) responseBadRequest(c *fiber.Ctx, err error) error {
+	return h.responseError(c, fiber.StatusBadRequest, responses.ErrorCodeBadRequest, "The request isn't properly formed", err.Error())
 }
 
 func (h *handler) responseInternalServerError(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-		"status":  "error",
-		"message": "We ran into an internal error while handling the request.",
-	})
+	return h.responseError(c, fiber.StatusInternalServerError, responses.ErrorCodeInternal, "We ran into an internal error while handling the request.", nil)
+}
+
+func (h *handler) responseGatewayTimeout(c *fiber.Ctx) error {
+	return h.responseError(c, fiber.StatusGatewayTimeout, responses.ErrorCodeTimeout, "We could not handle the request before its deadline, try again later.", nil)
 }
 
 func (h *handler) responseUnauthorized(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-		"status":  "error",
-		"message": "You are not authorized to carry out this request.",
-		"data":    "Make sure your API key is set in the [X-API-Key] header in the request",
-	})
+	return h.responseError(c, fiber.StatusUnauthorized, responses.ErrorCodeUnauthorized, "You are not authorized to carry out this request.", "Make sure your API key is set in the [X-API-Key] header in the request")
 }
 
 func (h *handler) responseForbidden(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-		"status":  "error",
-		"message": fiber.ErrForbidden.Message,
-	})
+	return h.responseError(c, fiber.StatusForbidden, responses.ErrorCodeForbidden, fiber.ErrForbidden.Message, nil)
+}
+
+func (h *handler) responseMissingScope(c *fiber.Ctx, scope entities.APIKeyScope) error {
+	return h.responseError(c, fiber.StatusForbidden, responses.ErrorCodeMissingScope, fmt.Sprintf("The API key does not have the [%s] scope which is required to carry out this request.", scope), scope)
+}
+
+func (h *handler) responseScopedAPIKeyForbidden(c *fiber.Ctx) error {
+	return h.responseError(c, fiber.StatusForbidden, responses.ErrorCodeForbidden, "This request cannot be carried out with a scoped API key.", "Make sure you use the primary API key of your account in the [X-API-Key] header in the request")
+}
+
+func (h *handler) responseTeamForbidden(c *fiber.Ctx, message string) error {
+	return h.responseError(c, fiber.StatusForbidden, responses.ErrorCodeForbidden, message, nil)
 }
 
+// responseUnprocessableEntity renders the validation errors keyed by the field in the request, "data" is kept for clients which don't read "errors"
 func (h *handler) responseUnprocessableEntity(c *fiber.Ctx, errors url.Values, message string) error {
-	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+	return h.render(c, fiber.StatusUnprocessableEntity, fiber.Map{
 		"status":  "error",
+		"code":    responses.ErrorCodeValidationFailed,
 		"message": message,
+		"errors":  errors,
 		"data":    errors,
 	})
 }
 
+func (h *handler) responseValidationError(c *fiber.Ctx, err *services.ValidationError, message string) error {
+	return h.responseUnprocessableEntity(c, err.Errors, message)
+}
+
 func (h *handler) responseNotFound(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"status":  "error",
-		"message": message,
-	})
+	return h.responseError(c, fiber.StatusNotFound, responses.ErrorCodeNotFound, message, nil)
+}
+
+func (h *handler) responseConflict(c *fiber.Ctx, message string) error {
+	return h.responseError(c, fiber.StatusConflict, responses.ErrorCodeConflict, message, nil)
+}
+
+func (h *handler) responsePayloadTooLarge(c *fiber.Ctx, message string) error {
+	return h.responseError(c, fiber.StatusRequestEntityTooLarge, responses.ErrorCodePayloadTooLarge, message, nil)
 }
 
 func (h *handler) responsePaymentRequired(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusPaymentRequired).JSON(fiber.Map{
-		"status":  "error",
-		"message": message,
+	return h.responseError(c, fiber.StatusPaymentRequired, responses.ErrorCodePaymentRequired, message, nil)
+}
+
+func (h *handler) responseTooManyRequests(c *fiber.Ctx, message string) error {
+	return h.responseError(c, fiber.StatusTooManyRequests, responses.ErrorCodeRateLimited, message, nil)
+}
+
+func (h *handler) responseDailyMessageLimitExceeded(c *fiber.Ctx, err *services.DailyMessageLimitExceededError) error {
+	message := fmt.Sprintf("You have exceeded your daily limit of [%d] messages, you can send more messages after [%s]", err.Limit, err.ResetAt.Format(time.RFC3339))
+	return h.responseError(c, fiber.StatusTooManyRequests, responses.ErrorCodeDailyLimitExceeded, message, fiber.Map{
+		"limit":    err.Limit,
+		"reset_at": err.ResetAt,
+	})
+}
+
+func (h *handler) responseContactDND(c *fiber.Ctx, err *services.ContactDNDError) error {
+	message := fmt.Sprintf("The contact [%s] is in the do-not-disturb window, you can send the message after [%s]", err.Contact, err.Until.Format(time.RFC3339))
+	return h.responseError(c, fiber.StatusConflict, responses.ErrorCodeContactDND, message, fiber.Map{
+		"until": err.Until,
+	})
+}
+
+func (h *handler) responseRecipientNotSendable(c *fiber.Ctx, err *services.RecipientNotSendableError) error {
+	message := fmt.Sprintf("The recipient [%s] is a [%s] number which cannot receive SMS messages", err.Contact, err.Type)
+	return h.responseError(c, fiber.StatusUnprocessableEntity, responses.ErrorCodeRecipientNotSendable, message, fiber.Map{
+		"type": err.Type,
 	})
 }
 
+func (h *handler) responseEncryptionMismatch(c *fiber.Ctx, err *services.EncryptionMismatchError) error {
+	message := fmt.Sprintf("The phone [%s] cannot decrypt end-to-end encrypted messages, send the message without is_encrypted", err.Owner)
+	if err.EncryptionEnabled {
+		message = fmt.Sprintf("The phone [%s] has end-to-end encryption enabled, encrypt the content and send the message with is_encrypted=true", err.Owner)
+	}
+	return h.responseError(c, fiber.StatusUnprocessableEntity, responses.ErrorCodeEncryptionMismatch, message, fiber.Map{
+		"encryption_enabled": err.EncryptionEnabled,
+	})
+}
+
+func (h *handler) responseEncryptedContent(c *fiber.Ctx, err *services.EncryptedContentError) error {
+	message := fmt.Sprintf("The [%s] option needs the plaintext content so it cannot be used with an end-to-end encrypted message", err.Feature)
+	return h.responseError(c, fiber.StatusUnprocessableEntity, responses.ErrorCodeEncryptedContent, message, fiber.Map{
+		"feature": err.Feature,
+	})
+}
+
+func (h *handler) responseNotForwardable(c *fiber.Ctx, err *services.MessageNotForwardableError) error {
+	return h.responseError(c, fiber.StatusUnprocessableEntity, responses.ErrorCodeNotForwardable, "The message cannot be forwarded", fiber.Map{
+		"message_id": err.MessageID,
+		"reason":     err.Reason,
+	})
+}
+
+func (h *handler) responseServiceUnavailable(c *fiber.Ctx, message string, data interface{}) error {
+	return h.responseError(c, fiber.StatusServiceUnavailable, responses.ErrorCodeServiceUnavailable, message, data)
+}
+
 func (h *handler) responseNoContent(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
+	return h.render(c, fiber.StatusNoContent, fiber.Map{
 		"status":  "success",
 		"message": message,
 	})
 }
 
 func (h *handler) responseAccepted(c *fiber.Ctx, message string) error {
-	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+	return h.render(c, fiber.StatusAccepted, fiber.Map{
 		"status":  "success",
 		"message": message,
 	})
 }
 
 func (h *handler) responseOK(c *fiber.Ctx, message string, data interface{}) error {
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+	return h.render(c, fiber.StatusOK, fiber.Map{
 		"status":  "success",
 		"message": message,
 		"data":    data,
 	})
 }
 
+// responsePaginated renders a page of the entities which were fetched with the repositories.IndexParams
+func (h *handler) responsePaginated(c *fiber.Ctx, message string, data interface{}, params repositories.IndexParams, count int) error {
+	return h.responsePage(c, message, data, responses.NewPagination(params.Skip, params.Limit, count))
+}
+
+// responsePaginatedWithTotal renders a page like responsePaginated with the total number of entities which match the filters
+func (h *handler) responsePaginatedWithTotal(c *fiber.Ctx, message string, data interface{}, params repositories.IndexParams, count int, total int64) error {
+	c.Set(headerTotalCount, strconv.FormatInt(total, 10))
+	return h.responsePage(c, message, data, responses.NewPagination(params.Skip, params.Limit, count).WithTotal(total))
+}
+
+func (h *handler) responsePage(c *fiber.Ctx, message string, data interface{}, pagination responses.Pagination) error {
+	if links := h.paginationLinks(c, pagination); len(links) != 0 {
+		c.Append(fiber.HeaderLink, strings.Join(links, ", "))
+	}
+
+	return h.render(c, fiber.StatusOK, fiber.Map{
+		"status":     "success",
+		"message":    message,
+		"data":       data,
+		"pagination": pagination,
+	})
+}
+
+// paginationLinks creates the RFC 5988 links of the next and previous pages, the links keep every query parameter
+// of the request and only change the skip and limit parameters
+func (h *handler) paginationLinks(c *fiber.Ctx, pagination responses.Pagination) []string {
+	if pagination.Limit <= 0 {
+		return nil
+	}
+
+	link := func(skip int, rel string) string {
+		query := url.Values{}
+		c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+			query.Add(string(key), string(value))
+		})
+		query.Set("skip", strconv.Itoa(skip))
+		query.Set("limit", strconv.Itoa(pagination.Limit))
+		return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, c.BaseURL(), c.Path(), query.Encode(), rel)
+	}
+
+	var links []string
+	if pagination.HasMore {
+		links = append(links, link(pagination.Skip+pagination.Count, "next"))
+	}
+	if pagination.Skip > 0 {
+		skip := pagination.Skip - pagination.Limit
+		if skip < 0 {
+			skip = 0
+		}
+		links = append(links, link(skip, "prev"))
+	}
+	return links
+}
+
+// queryMap collects the query parameters with the format name[key]=value e.g. metadata[order_id]=42 into a map because
+// the query parser does not decode maps, it returns nil when the request has no such parameter
+func (h *handler) queryMap(c *fiber.Ctx, name string) map[string]string {
+	var values map[string]string
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		parameter := string(key)
+		if !strings.HasPrefix(parameter, name+"[") || !strings.HasSuffix(parameter, "]") {
+			return
+		}
+		if values == nil {
+			values = map[string]string{}
+		}
+		values[strings.TrimSuffix(strings.TrimPrefix(parameter, name+"["), "]")] = string(value)
+	})
+	return values
+}
+
+// etag creates an opaque entity tag from the parts which identify the version of a representation. A weak tag is used
+// when two responses with the same tag are only semantically equivalent e.g. a page which contains the liveness of the phone.
+func (h *handler) etag(weak bool, parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	tag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// pageETag creates the weak entity tag of a page, the query of the request is part of the tag so that every page and
+// filter has its own tag even when two pages contain the same entities
+func (h *handler) pageETag(c *fiber.Ctx, versions []string) string {
+	return h.etag(true, append([]string{c.Path(), string(c.Context().QueryArgs().QueryString())}, versions...)...)
+}
+
+// livenessETag is the part of an entity tag which changes when the entities.PhoneLiveness in a response changes
+func (h *handler) livenessETag(liveness *entities.PhoneLiveness) string {
+	if liveness == nil {
+		return "offline"
+	}
+
+	lastHeartbeatAt := ""
+	if liveness.LastHeartbeatAt != nil {
+		lastHeartbeatAt = liveness.LastHeartbeatAt.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%s-%t", lastHeartbeatAt, liveness.IsOnline)
+}
+
+// notModified sets the ETag of the response and checks it against the If-None-Match header of the request.
+// The weak comparison of RFC 7232 is used so a weak tag matches the same tag with or without the W/ prefix.
+func (h *handler) notModified(c *fiber.Ctx, etag string) bool {
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+
+	for _, candidate := range strings.Split(c.Get(fiber.HeaderIfNoneMatch), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || (candidate != "" && strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseNotModified renders a 304 response without a body when the client already has the representation
+func (h *handler) responseNotModified(c *fiber.Ctx) error {
+	return c.SendStatus(fiber.StatusNotModified)
+}
+
 func (h *handler) responseCreated(c *fiber.Ctx, message string, data interface{}) error {
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	return h.render(c, fiber.StatusCreated, fiber.Map{
 		"status":  "success",
 		"message": message,
 		"data":    data,
@@ -115,3 +391,60 @@ func (h *handler) userIDFomContext(c *fiber.Ctx) entities.UserID {
 func (h *handler) computeRoute(middlewares []fiber.Handler, route fiber.Handler) []fiber.Handler {
 	return append(append([]fiber.Handler{}, middlewares...), route)
 }
+
+// requireScope only allows a scoped entities.APIKey to access the route if it has the entities.APIKeyScope
+func (h *handler) requireScope(scope entities.APIKeyScope, route fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !h.userFromContext(c).HasScope(scope) {
+			return h.responseMissingScope(c, scope)
+		}
+		return route(c)
+	}
+}
+
+// requirePrimaryAPIKey prevents a scoped entities.APIKey or a member of a team from accessing the account settings on the route
+func (h *handler) requirePrimaryAPIKey(route fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.userFromContext(c).IsScoped() {
+			return h.responseScopedAPIKeyForbidden(c)
+		}
+		if h.userFromContext(c).IsTeamMember() {
+			return h.responseTeamForbidden(c, "The account settings can only be managed in your personal team.")
+		}
+		return route(c)
+	}
+}
+
+// requireUnscopedAPIKey prevents a scoped entities.APIKey from accessing the route, unlike requirePrimaryAPIKey it allows the members of a team
+func (h *handler) requireUnscopedAPIKey(route fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if h.userFromContext(c).IsScoped() {
+			return h.responseScopedAPIKeyForbidden(c)
+		}
+		return route(c)
+	}
+}
+
+// requireTeamAdmin only allows the admins of the acting entities.Team to access the route
+func (h *handler) requireTeamAdmin(route fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !h.userFromContext(c).IsTeamAdmin() {
+			return h.responseTeamForbidden(c, fmt.Sprintf("Only a team member with the [%s] role can carry out this request.", entities.TeamRoleAdmin))
+		}
+		return route(c)
+	}
+}
+
+// requireAdmin only allows an administrator to access the route, a scoped entities.APIKey must also have the entities.APIKeyScopeAdmin scope
+func (h *handler) requireAdmin(route fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := h.userFromContext(c)
+		if !user.IsAdmin {
+			return h.responseForbidden(c)
+		}
+		if !user.CanAdminister() {
+			return h.responseMissingScope(c, entities.APIKeyScopeAdmin)
+		}
+		return route(c)
+	}
+}
@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func respond(t *testing.T, render func(h *handler, c *fiber.Ctx) error) (int, string) {
+	return respondWithVersion(t, "", render)
+}
+
+func respondWithVersion(t *testing.T, version string, render func(h *handler, c *fiber.Ctx) error) (int, string) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if version != "" {
+			c.SetUserContext(telemetry.WithAPIVersion(c.UserContext(), version))
+		}
+		return render(new(handler), c)
+	})
+
+	response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	assert.Nil(t, err)
+
+	body, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+
+	return response.StatusCode, string(body)
+}
+
+func TestHandlerResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(h *handler, c *fiber.Ctx) error
+		status int
+		body   string
+	}{
+		{
+			name: "ok",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseOK(c, "fetched 1 phone", []string{"+18005550199"})
+			},
+			status: fiber.StatusOK,
+			body:   `{"status":"success","message":"fetched 1 phone","data":["+18005550199"]}`,
+		},
+		{
+			name: "paginated",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responsePaginated(c, "fetched 2 phones", []string{"+18005550199", "+18005550100"}, repositories.IndexParams{Skip: 10, Limit: 2}, 2)
+			},
+			status: fiber.StatusOK,
+			body:   `{"status":"success","message":"fetched 2 phones","data":["+18005550199","+18005550100"],"pagination":{"skip":10,"limit":2,"count":2,"has_more":true}}`,
+		},
+		{
+			name: "bad request",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseBadRequest(c, errors.New("unexpected end of JSON input"))
+			},
+			status: fiber.StatusBadRequest,
+			body:   `{"status":"error","code":"bad_request","message":"The request isn't properly formed","data":"unexpected end of JSON input"}`,
+		},
+		{
+			name: "not found",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseNotFound(c, "cannot find phone with ID [1]")
+			},
+			status: fiber.StatusNotFound,
+			body:   `{"status":"error","code":"not_found","message":"cannot find phone with ID [1]"}`,
+		},
+		{
+			name: "validation errors",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseValidationError(c, services.NewValidationError("to", "the to field is required"), "validation errors while sending message")
+			},
+			status: fiber.StatusUnprocessableEntity,
+			body:   `{"status":"error","code":"validation_failed","message":"validation errors while sending message","errors":{"to":["the to field is required"]},"data":{"to":["the to field is required"]}}`,
+		},
+		{
+			name: "internal server error",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseInternalServerError(c)
+			},
+			status: fiber.StatusInternalServerError,
+			body:   `{"status":"error","code":"internal_error","message":"We ran into an internal error while handling the request."}`,
+		},
+		{
+			name: "daily message limit exceeded",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseDailyMessageLimitExceeded(c, &services.DailyMessageLimitExceededError{Limit: 200, ResetAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)})
+			},
+			status: fiber.StatusTooManyRequests,
+			body:   `{"status":"error","code":"daily_limit_exceeded","message":"You have exceeded your daily limit of [200] messages, you can send more messages after [2024-01-02T00:00:00Z]","data":{"limit":200,"reset_at":"2024-01-02T00:00:00Z"}}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			status, body := respond(t, test.render)
+
+			// Assert
+			assert.Equal(t, test.status, status)
+			assert.JSONEq(t, test.body, body)
+		})
+	}
+}
+
+func TestHandlerResponsesV1Contract(t *testing.T) {
+	// the android app and the web app parse these bytes, a change here is a breaking change of the v1 API
+	tests := []struct {
+		name   string
+		render func(h *handler, c *fiber.Ctx) error
+		status int
+		body   string
+	}{
+		{
+			name: "ok",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseOK(c, "fetched 1 phone", []string{"+18005550199"})
+			},
+			status: fiber.StatusOK,
+			body:   `{"data":["+18005550199"],"message":"fetched 1 phone","status":"success"}`,
+		},
+		{
+			name: "paginated",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responsePaginated(c, "fetched 2 phones", []string{"+18005550199", "+18005550100"}, repositories.IndexParams{Skip: 10, Limit: 2}, 2)
+			},
+			status: fiber.StatusOK,
+			body:   `{"data":["+18005550199","+18005550100"],"message":"fetched 2 phones","pagination":{"skip":10,"limit":2,"count":2,"has_more":true},"status":"success"}`,
+		},
+		{
+			name: "not found",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseNotFound(c, "cannot find phone with ID [1]")
+			},
+			status: fiber.StatusNotFound,
+			body:   `{"code":"not_found","message":"cannot find phone with ID [1]","status":"error"}`,
+		},
+		{
+			name: "validation errors",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseValidationError(c, services.NewValidationError("to", "the to field is required"), "validation errors while sending message")
+			},
+			status: fiber.StatusUnprocessableEntity,
+			body:   `{"code":"validation_failed","data":{"to":["the to field is required"]},"errors":{"to":["the to field is required"]},"message":"validation errors while sending message","status":"error"}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		for name, version := range map[string]string{"without version": "", "with version": responses.APIVersion1} {
+			version := version
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				// Setup
+				t.Parallel()
+
+				// Act
+				status, body := respondWithVersion(t, version, test.render)
+
+				// Assert
+				assert.Equal(t, test.status, status)
+				assert.Equal(t, test.body, body)
+			})
+		}
+	}
+}
+
+func TestHandlerResponsesV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		render func(h *handler, c *fiber.Ctx) error
+		status int
+		body   string
+	}{
+		{
+			name: "ok",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseOK(c, "fetched 1 phone", []string{"+18005550199"})
+			},
+			status: fiber.StatusOK,
+			body:   `{"data":["+18005550199"]}`,
+		},
+		{
+			name: "paginated",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responsePaginated(c, "fetched 2 phones", []string{"+18005550199", "+18005550100"}, repositories.IndexParams{Skip: 10, Limit: 2}, 2)
+			},
+			status: fiber.StatusOK,
+			body:   `{"data":["+18005550199","+18005550100"],"pagination":{"skip":10,"limit":2,"count":2,"has_more":true}}`,
+		},
+		{
+			name: "bad request",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseBadRequest(c, errors.New("unexpected end of JSON input"))
+			},
+			status: fiber.StatusBadRequest,
+			body:   `{"error":{"code":"bad_request","message":"The request isn't properly formed","details":"unexpected end of JSON input"}}`,
+		},
+		{
+			name: "validation errors",
+			render: func(h *handler, c *fiber.Ctx) error {
+				return h.responseValidationError(c, services.NewValidationError("to", "the to field is required"), "validation errors while sending message")
+			},
+			status: fiber.StatusUnprocessableEntity,
+			body:   `{"error":{"code":"validation_failed","message":"validation errors while sending message","fields":{"to":["the to field is required"]}}}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			status, body := respondWithVersion(t, responses.APIVersion2, test.render)
+
+			// Assert
+			assert.Equal(t, test.status, status)
+			assert.JSONEq(t, test.body, body)
+		})
+	}
+}
+
+func TestHandlerResponseServiceError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{
+			name:   "not found",
+			err:    stacktrace.Propagate(stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "record not found"), "cannot load phone"),
+			status: fiber.StatusNotFound,
+			body:   `{"status":"error","code":"not_found","message":"cannot find the resource in the request"}`,
+		},
+		{
+			name:   "invalid transition",
+			err:    stacktrace.Propagate(stacktrace.NewErrorWithCode(services.ErrCodeInvalidTransition, "message has wrong status [delivered]"), "cannot handle event"),
+			status: fiber.StatusConflict,
+			body:   `{"status":"error","code":"conflict","message":"the resource cannot be changed from its current state"}`,
+		},
+		{
+			name:   "stale update",
+			err:    stacktrace.NewErrorWithCode(repositories.ErrCodeStaleUpdate, "message was updated"),
+			status: fiber.StatusConflict,
+			body:   `{"status":"error","code":"conflict","message":"the resource cannot be changed from its current state"}`,
+		},
+		{
+			name:   "validation error",
+			err:    stacktrace.Propagate(services.NewValidationError("token", "the invitation is invalid"), "cannot join team"),
+			status: fiber.StatusUnprocessableEntity,
+			body:   `{"status":"error","code":"validation_failed","message":"validation errors while handling the request","errors":{"token":["the invitation is invalid"]},"data":{"token":["the invitation is invalid"]}}`,
+		},
+		{
+			name:   "message not forwardable",
+			err:    stacktrace.Propagate(&services.MessageNotForwardableError{MessageID: uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"), Reason: "it was not received by the phone [+18005550199]"}, "cannot forward message"),
+			status: fiber.StatusUnprocessableEntity,
+			body:   `{"status":"error","code":"not_forwardable","message":"The message cannot be forwarded","data":{"message_id":"32343a19-da5e-4b1b-a767-3298a73703cb","reason":"it was not received by the phone [+18005550199]"}}`,
+		},
+		{
+			name:   "rate limited",
+			err:    stacktrace.NewErrorWithCode(services.ErrCodeRateLimited, "rate limit exceeded"),
+			status: fiber.StatusTooManyRequests,
+			body:   `{"status":"error","code":"rate_limited","message":"the phone has exceeded its messages per minute, try again later"}`,
+		},
+		{
+			name:   "deadline exceeded",
+			err:    stacktrace.Propagate(context.DeadlineExceeded, "cannot load messages"),
+			status: fiber.StatusGatewayTimeout,
+			body:   `{"status":"error","code":"timeout","message":"We could not handle the request before its deadline, try again later."}`,
+		},
+		{
+			name:   "unknown error",
+			err:    stacktrace.NewError("connection refused"),
+			status: fiber.StatusInternalServerError,
+			body:   `{"status":"error","code":"internal_error","message":"We ran into an internal error while handling the request."}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			status, body := respond(t, func(h *handler, c *fiber.Ctx) error {
+				return h.responseServiceError(c, test.err)
+			})
+
+			// Assert
+			assert.Equal(t, test.status, status)
+			assert.JSONEq(t, test.body, body)
+		})
+	}
+}
+
+func TestHandlerResponsePaginatedLinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		params     repositories.IndexParams
+		count      int
+		total      *int64
+		link       string
+		totalCount string
+	}{
+		{
+			name:   "middle page keeps the filters in the next and previous links",
+			target: "/v1/messages?owner=%2B18005550199&contact=%2B18005550100&query=hello+world&include_archived=true&skip=20&limit=10",
+			params: repositories.IndexParams{Skip: 20, Limit: 10},
+			count:  10,
+			link:   `<http://example.com/v1/messages?contact=%2B18005550100&include_archived=true&limit=10&owner=%2B18005550199&query=hello+world&skip=30>; rel="next", <http://example.com/v1/messages?contact=%2B18005550100&include_archived=true&limit=10&owner=%2B18005550199&query=hello+world&skip=10>; rel="prev"`,
+		},
+		{
+			name:   "last page has no next link",
+			target: "/v1/messages?owner=%2B18005550199&contact=%2B18005550100&skip=20&limit=10",
+			params: repositories.IndexParams{Skip: 20, Limit: 10},
+			count:  3,
+			link:   `<http://example.com/v1/messages?contact=%2B18005550100&limit=10&owner=%2B18005550199&skip=10>; rel="prev"`,
+		},
+		{
+			name:   "first page has no previous link and uses the default limit",
+			target: "/v1/phones",
+			params: repositories.IndexParams{Skip: 0, Limit: 2},
+			count:  2,
+			link:   `<http://example.com/v1/phones?limit=2&skip=2>; rel="next"`,
+		},
+		{
+			name:   "previous link does not have a negative skip",
+			target: "/v1/phones?skip=5&limit=10",
+			params: repositories.IndexParams{Skip: 5, Limit: 10},
+			count:  1,
+			link:   `<http://example.com/v1/phones?limit=10&skip=0>; rel="prev"`,
+		},
+		{
+			name:   "single page has no links",
+			target: "/v1/phones?limit=10",
+			params: repositories.IndexParams{Skip: 0, Limit: 10},
+			count:  3,
+			link:   "",
+		},
+		{
+			name:       "full last page has no next link when the total is known",
+			target:     "/v1/messages?owner=%2B18005550199&contact=%2B18005550100&include_total=true&skip=10&limit=10",
+			params:     repositories.IndexParams{Skip: 10, Limit: 10},
+			count:      10,
+			total:      func() *int64 { total := int64(20); return &total }(),
+			link:       `<http://example.com/v1/messages?contact=%2B18005550100&include_total=true&limit=10&owner=%2B18005550199&skip=0>; rel="prev"`,
+			totalCount: "20",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			app := fiber.New()
+			app.Get("/v1/:resource", func(c *fiber.Ctx) error {
+				if test.total != nil {
+					return new(handler).responsePaginatedWithTotal(c, "fetched", []string{}, test.params, test.count, *test.total)
+				}
+				return new(handler).responsePaginated(c, "fetched", []string{}, test.params, test.count)
+			})
+
+			// Act
+			response, err := app.Test(httptest.NewRequest(fiber.MethodGet, test.target, nil))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, fiber.StatusOK, response.StatusCode)
+			assert.Equal(t, test.link, response.Header.Get(fiber.HeaderLink))
+			assert.Equal(t, test.totalCount, response.Header.Get(headerTotalCount))
+		})
+	}
+}
+
+func TestHandlerNotModified(t *testing.T) {
+	tests := []struct {
+		name        string
+		weak        bool
+		ifNoneMatch func(etag string) string
+		status      int
+	}{
+		{
+			name:        "no If-None-Match header renders the representation",
+			ifNoneMatch: func(etag string) string { return "" },
+			status:      fiber.StatusOK,
+		},
+		{
+			name:        "matching strong tag is not modified",
+			ifNoneMatch: func(etag string) string { return etag },
+			status:      fiber.StatusNotModified,
+		},
+		{
+			name:        "weak comparison ignores the W/ prefix of the request",
+			ifNoneMatch: func(etag string) string { return "W/" + etag },
+			status:      fiber.StatusNotModified,
+		},
+		{
+			name:        "weak tag matches the same strong tag",
+			weak:        true,
+			ifNoneMatch: func(etag string) string { return strings.TrimPrefix(etag, "W/") },
+			status:      fiber.StatusNotModified,
+		},
+		{
+			name:        "tag in a list is not modified",
+			weak:        true,
+			ifNoneMatch: func(etag string) string { return `"stale", ` + etag },
+			status:      fiber.StatusNotModified,
+		},
+		{
+			name:        "wildcard is not modified",
+			ifNoneMatch: func(etag string) string { return "*" },
+			status:      fiber.StatusNotModified,
+		},
+		{
+			name:        "stale tag renders the representation",
+			ifNoneMatch: func(etag string) string { return `"stale"` },
+			status:      fiber.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			etag := new(handler).etag(test.weak, "32343a19-da5e-4b1b-a767-3298a73703ca", "2")
+			app := fiber.New()
+			app.Get("/v1/messages/:messageID", func(c *fiber.Ctx) error {
+				h := new(handler)
+				if h.notModified(c, etag) {
+					return h.responseNotModified(c)
+				}
+				return h.responseOK(c, "message fetched successfully", "hello")
+			})
+
+			// Arrange
+			request := httptest.NewRequest(fiber.MethodGet, "/v1/messages/32343a19-da5e-4b1b-a767-3298a73703ca", nil)
+			if header := test.ifNoneMatch(etag); header != "" {
+				request.Header.Set(fiber.HeaderIfNoneMatch, header)
+			}
+
+			// Act
+			response, err := app.Test(request)
+
+			// Assert
+			assert.Nil(t, err)
+			body, err := io.ReadAll(response.Body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, test.status, response.StatusCode)
+			assert.Equal(t, etag, response.Header.Get(fiber.HeaderETag))
+			assert.Equal(t, "private, no-cache", response.Header.Get(fiber.HeaderCacheControl))
+			if test.status == fiber.StatusNotModified {
+				assert.Empty(t, body)
+			}
+		})
+	}
+}
+
+func TestHandlerPageETag(t *testing.T) {
+	// Setup
+	t.Parallel()
+	app := fiber.New()
+	app.Get("/v1/messages", func(c *fiber.Ctx) error {
+		return c.SendString(new(handler).pageETag(c, []string{"32343a19-da5e-4b1b-a767-3298a73703ca", "2024-01-02T00:00:00Z"}))
+	})
+
+	etag := func(target string) string {
+		response, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+		assert.Nil(t, err)
+
+		body, err := io.ReadAll(response.Body)
+		assert.Nil(t, err)
+		return string(body)
+	}
+
+	// Act
+	first := etag("/v1/messages?owner=%2B18005550199&skip=0&limit=10")
+	same := etag("/v1/messages?owner=%2B18005550199&skip=0&limit=10")
+	nextPage := etag("/v1/messages?owner=%2B18005550199&skip=10&limit=10")
+	otherLimit := etag("/v1/messages?owner=%2B18005550199&skip=0&limit=20")
+
+	// Assert
+	assert.True(t, strings.HasPrefix(first, `W/"`))
+	assert.Equal(t, first, same)
+	assert.NotEqual(t, first, nextPage)
+	assert.NotEqual(t, first, otherLimit)
+}
+
+func TestHandlerQueryMap(t *testing.T) {
+	// Setup
+	t.Parallel()
+	var request requests.MessageIndex
+	var parseErr error
+	app := fiber.New()
+	app.Get("/v1/messages", func(c *fiber.Ctx) error {
+		parseErr = c.QueryParser(&request)
+		request.Metadata = new(handler).queryMap(c, "metadata")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	// Act
+	_, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages?owner=%2B18005550199&metadata%5Border_id%5D=42&metadata[channel]=web&status=sent&metadata=ignored", nil))
+
+	// Assert
+	assert.Nil(t, err)
+	assert.Nil(t, parseErr)
+	assert.Equal(t, "+18005550199", request.Owner)
+	assert.Equal(t, "sent", request.Status)
+	assert.Equal(t, map[string]string{"order_id": "42", "channel": "web"}, request.Metadata)
+}
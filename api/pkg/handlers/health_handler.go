@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthHandler handles the liveness and readiness probes of the load balancer
+type HealthHandler struct {
+	handler
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	service *services.HealthService
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.HealthService,
+) (h *HealthHandler) {
+	return &HealthHandler{
+		logger:  logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:  tracer,
+		service: service,
+	}
+}
+
+// RegisterRoutes registers the routes for the HealthHandler
+func (h *HealthHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/live", h.Live)
+	router.Get("/ready", h.Ready)
+}
+
+// Live checks if the process is running
+// @Summary      Liveness probe
+// @Description  Returns 200 when the API process is running without checking its dependencies.
+// @Tags         Health
+// @Produce      json
+// @Success      200 		{object}	responses.NoContent
+// @Router       /live [get]
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return h.responseOK(c, "the API is live", nil)
+}
+
+// Ready checks if the dependencies of the API are working
+// @Summary      Readiness probe
+// @Description  Checks the database, the message backlog and the other dependencies concurrently and returns 503 when a check fails.
+// @Tags         Health
+// @Produce      json
+// @Success      200 		{object}	responses.HealthReportResponse
+// @Failure      503		{object}	responses.HealthReportResponse
+// @Router       /ready [get]
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	report := h.service.Ready(ctx)
+	if !report.IsHealthy() {
+		return h.responseServiceUnavailable(c, "the API is not ready", report)
+	}
+
+	return h.responseOK(c, "the API is ready", report)
+}
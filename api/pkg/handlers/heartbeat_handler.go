@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/NdoleStudio/httpsms/pkg/services"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
@@ -15,10 +16,11 @@ import (
 // HeartbeatHandler handles heartbeat http requests.
 type HeartbeatHandler struct {
 	handler
-	logger    telemetry.Logger
-	tracer    telemetry.Tracer
-	validator *validators.HeartbeatHandlerValidator
-	service   *services.HeartbeatService
+	logger      telemetry.Logger
+	tracer      telemetry.Tracer
+	validator   *validators.HeartbeatHandlerValidator
+	queueConfig services.PushQueueConfig
+	service     *services.HeartbeatService
 }
 
 // NewHeartbeatHandler creates a new HeartbeatHandler
@@ -26,20 +28,25 @@ func NewHeartbeatHandler(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	validator *validators.HeartbeatHandlerValidator,
+	queueConfig services.PushQueueConfig,
 	service *services.HeartbeatService,
 ) (h *HeartbeatHandler) {
 	return &HeartbeatHandler{
-		logger:    logger.WithService(fmt.Sprintf("%T", h)),
-		tracer:    tracer,
-		validator: validator,
-		service:   service,
+		logger:      logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:      tracer,
+		validator:   validator,
+		queueConfig: queueConfig,
+		service:     service,
 	}
 }
 
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *HeartbeatHandler) RegisterRoutes(router fiber.Router) {
-	router.Get("/heartbeats", h.Index)
-	router.Post("/heartbeats", h.Store)
+	router.Get("/heartbeats", h.requireScope(entities.APIKeyScopePhonesRead, h.Index))
+	router.Post("/heartbeats", h.requireScope(entities.APIKeyScopePhonesWrite, h.Store))
+	router.Get("/heartbeats/hourly", h.requireScope(entities.APIKeyScopePhonesRead, h.HourlyIndex))
+	router.Post("/heartbeats/check", h.requirePrimaryAPIKey(h.CheckAll))
+	router.Post("/heartbeats/prune", h.requirePrimaryAPIKey(h.Prune))
 }
 
 // Index returns the heartbeats of a phone number
@@ -53,6 +60,8 @@ func (h *HeartbeatHandler) RegisterRoutes(router fiber.Router) {
 // @Param        skip		query  int  	false	"number of heartbeats to skip"		minimum(0)
 // @Param        query		query  string  	false 	"filter containing query"
 // @Param        limit		query  int  	false	"number of heartbeats to return"	minimum(1)	maximum(20)
+// @Param        from		query  string  	false	"RFC3339 timestamp of the start of the time range"	default(2022-06-05T00:00:00Z)
+// @Param        to			query  string  	false	"RFC3339 timestamp of the end of the time range, it cannot be more than 31 days after from"	default(2022-06-06T00:00:00Z)
 // @Success      200 		{object}	responses.HeartbeatsResponse
 // @Failure      400		{object}	responses.BadRequest
 // @Failure 	 401	    {object}	responses.Unauthorized
@@ -78,14 +87,60 @@ func (h *HeartbeatHandler) Index(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching heartbeats")
 	}
 
-	heartbeats, err := h.service.Index(ctx, h.userIDFomContext(c), request.Owner, request.ToIndexParams())
+	params := request.ToIndexParams()
+	heartbeats, err := h.service.Index(ctx, h.userIDFomContext(c), request.Owner, params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot get messgaes with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(*heartbeats), h.pluralize("heartbeat", len(*heartbeats))), heartbeats)
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(*heartbeats), h.pluralize("heartbeat", len(*heartbeats))), heartbeats, params.IndexParams, len(*heartbeats))
+}
+
+// HourlyIndex returns the number of heartbeats of a phone number in each hour
+// @Summary      Get hourly heartbeat counts of an owner phone number
+// @Description  Get the number of heartbeats received from a phone number in each hour of a time range. Hours without heartbeats are omitted so gaps can be charted. Heartbeats older than the retention period are not counted.
+// @Security	 ApiKeyAuth
+// @Tags         Heartbeats
+// @Accept       json
+// @Produce      json
+// @Param        owner		query  string  	true 	"the owner's phone number" 			default(+18005550199)
+// @Param        from		query  string  	false	"RFC3339 timestamp of the start of the time range, defaults to 24 hours before to"	default(2022-06-05T00:00:00Z)
+// @Param        to			query  string  	false	"RFC3339 timestamp of the end of the time range, defaults to now"	default(2022-06-06T00:00:00Z)
+// @Success      200 		{object}	responses.HeartbeatCountsResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /heartbeats/hourly [get]
+func (h *HeartbeatHandler) HourlyIndex(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	var request requests.HeartbeatHourlyIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateHourlyIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching hourly heartbeats [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching hourly heartbeats")
+	}
+
+	counts, err := h.service.CountHourly(ctx, h.userIDFomContext(c), request.Owner, request.FromTime(), request.ToTime())
+	if err != nil {
+		msg := fmt.Sprintf("cannot count hourly heartbeats with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched heartbeat counts for %d %s", len(*counts), h.pluralize("hour", len(*counts))), counts)
 }
 
 // Store the heartbeat of a phone number
@@ -121,12 +176,58 @@ func (h *HeartbeatHandler) Store(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while storing heartbeat")
 	}
 
-	heartbeat, err := h.service.Store(ctx, request.ToStoreParams(h.userFromContext(c), c.Get("X-Client-Version")))
+	heartbeat, err := h.service.Store(ctx, request.ToStoreParams(h.userFromContext(c), c.OriginalURL(), c.Get("X-Client-Version")))
 	if err != nil {
 		msg := fmt.Sprintf("cannot store heartbeat with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseCreated(c, "heartbeat created successfully", heartbeat)
 }
+
+// CheckAll checks the liveness of all monitored phones
+// This is an internal API so no documentation provided
+func (h *HeartbeatHandler) CheckAll(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	if h.userIDFomContext(c) != h.queueConfig.UserID {
+		msg := fmt.Sprintf("user with ID [%s] cannot check heartbeat monitors", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.NewError(msg))
+		return h.responseForbidden(c)
+	}
+
+	if err := h.service.CheckAll(ctx); err != nil {
+		msg := fmt.Sprintf("cannot check heartbeat monitors")
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseInternalServerError(c)
+	}
+
+	return h.responseNoContent(c, "heartbeat monitors checked successfully")
+}
+
+// Prune deletes the heartbeats which are older than the retention period
+// This is an internal API so no documentation provided
+func (h *HeartbeatHandler) Prune(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	if h.userIDFomContext(c) != h.queueConfig.UserID {
+		msg := fmt.Sprintf("user with ID [%s] cannot prune heartbeats", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.NewError(msg))
+		return h.responseForbidden(c)
+	}
+
+	if err := h.service.Prune(ctx); err != nil {
+		msg := fmt.Sprintf("cannot prune heartbeats")
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseInternalServerError(c)
+	}
+
+	return h.responseNoContent(c, "heartbeats pruned successfully")
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/davecgh/go-spew/spew"
 
@@ -39,7 +40,7 @@ func NewIntegration3CxHandler(
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *Integration3CXHandler) RegisterRoutes(app *fiber.App, middlewares ...fiber.Handler) {
 	router := app.Group("integration/3cx/")
-	router.Post("/messages", h.computeRoute(middlewares, h.Messages)...)
+	router.Post("/messages", h.computeRoute(middlewares, h.requireScope(entities.APIKeyScopeMessagesSend, h.Messages))...)
 }
 
 // Messages consumes a 3cx event
@@ -74,10 +75,20 @@ func (h *Integration3CXHandler) Messages(c *fiber.Ctx) error {
 
 	request.Sanitize()
 	message, err := h.messageService.SendMessage(ctx, request.ToMessageSendParams(h.userIDFomContext(c), c.OriginalURL()))
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] exceeded the daily message limit", h.userIDFomContext(c))))
+		return h.responseDailyMessageLimitExceeded(c, limitErr)
+	}
+
+	if validationErr, ok := services.AsValidationError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send a [3cx] message with payload [%s]", h.userIDFomContext(c), c.Body())))
+		return h.responseValidationError(c, validationErr, "validation errors while sending message")
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("cannot send [3cx] message with paylod [%s]", c.Body())
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	ctxLogger.Info(fmt.Sprintf("[3cx] message sent with ID [%s]", message.ID))
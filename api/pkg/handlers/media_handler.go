@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// MediaHandler handles the media which is uploaded with signed URLs and sent in MMS messages
+type MediaHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.MediaHandlerValidator
+	service   *services.MediaService
+}
+
+// NewMediaHandler creates a new MediaHandler
+func NewMediaHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.MediaHandlerValidator,
+	service *services.MediaService,
+) (h *MediaHandler) {
+	return &MediaHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the MediaHandler
+func (h *MediaHandler) RegisterRoutes(router fiber.Router) {
+	router.Post("/media", h.requireScope(entities.APIKeyScopeMessagesSend, h.Store))
+}
+
+// Store registers a media file and returns the signed URL which uploads it
+// @Summary      Register media for an MMS message
+// @Description  Register a file e.g. an image with its content type and size. Upload the file with a PUT request to the upload_url and the same Content-Type header before it expires, then send a message with the ID of the media in media_ids. Media which is not sent in a message is deleted.
+// @Security	 ApiKeyAuth
+// @Tags         Media
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.MediaStore  	true 	"Content type and size of the file"
+// @Success      201 		{object}	responses.MediaResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Failure      503		{object}	responses.ServiceUnavailable
+// @Router       /media 	[post]
+func (h *MediaHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MediaStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while registering media [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while registering media")
+	}
+
+	media, err := h.service.Register(ctx, request.ToRegisterParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot register [%s] media for user [%s]", request.ContentType, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseCreated(c, "media registered successfully", media)
+}
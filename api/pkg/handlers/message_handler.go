@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +14,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
 	"github.com/NdoleStudio/httpsms/pkg/services"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/NdoleStudio/httpsms/pkg/validators"
@@ -23,11 +26,16 @@ import (
 // MessageHandler handles message http requests.
 type MessageHandler struct {
 	handler
-	logger         telemetry.Logger
-	tracer         telemetry.Tracer
-	billingService *services.BillingService
-	validator      *validators.MessageHandlerValidator
-	service        *services.MessageService
+	logger           telemetry.Logger
+	tracer           telemetry.Tracer
+	billingService   *services.BillingService
+	validator        *validators.MessageHandlerValidator
+	service          *services.MessageService
+	phoneService     *services.PhoneService
+	heartbeatService *services.HeartbeatService
+	contactService   *services.ContactService
+	groupService     *services.ContactGroupService
+	mediaService     *services.MediaService
 }
 
 // NewMessageHandler creates a new MessageHandler
@@ -37,25 +45,42 @@ func NewMessageHandler(
 	validator *validators.MessageHandlerValidator,
 	billingService *services.BillingService,
 	service *services.MessageService,
+	phoneService *services.PhoneService,
+	heartbeatService *services.HeartbeatService,
+	contactService *services.ContactService,
+	groupService *services.ContactGroupService,
+	mediaService *services.MediaService,
 ) (h *MessageHandler) {
 	return &MessageHandler{
-		logger:         logger.WithService(fmt.Sprintf("%T", h)),
-		tracer:         tracer,
-		validator:      validator,
-		billingService: billingService,
-		service:        service,
+		logger:           logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:           tracer,
+		validator:        validator,
+		billingService:   billingService,
+		service:          service,
+		phoneService:     phoneService,
+		heartbeatService: heartbeatService,
+		contactService:   contactService,
+		groupService:     groupService,
+		mediaService:     mediaService,
 	}
 }
 
 // RegisterRoutes registers the routes for the MessageHandler
-func (h *MessageHandler) RegisterRoutes(router fiber.Router) {
-	router.Post("/messages/send", h.PostSend)
-	router.Post("/messages/bulk-send", h.BulkSend)
-	router.Post("/messages/receive", h.PostReceive)
-	router.Get("/messages/outstanding", h.GetOutstanding)
-	router.Get("/messages", h.Index)
-	router.Post("/messages/:messageID/events", h.PostEvent)
-	router.Delete("/messages/:messageID", h.Delete)
+func (h *MessageHandler) RegisterRoutes(router fiber.Router, phoneMiddlewares ...fiber.Handler) {
+	router.Post("/messages/send", h.requireScope(entities.APIKeyScopeMessagesSend, h.PostSend))
+	router.Post("/messages/bulk-send", h.requireScope(entities.APIKeyScopeMessagesSend, h.BulkSend))
+	router.Post("/messages/receive", h.computeRoute(phoneMiddlewares, h.requireScope(entities.APIKeyScopePhonesWrite, h.PostReceive))...)
+	router.Get("/messages/outstanding", h.computeRoute(phoneMiddlewares, h.requireScope(entities.APIKeyScopePhonesWrite, h.GetOutstanding))...)
+	router.Get("/messages", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+	router.Post("/messages/status", h.requireScope(entities.APIKeyScopeMessagesRead, h.Status))
+	router.Get("/messages/queue-stats", h.requireScope(entities.APIKeyScopeMessagesRead, h.QueueStats))
+	router.Get("/messages/:messageID", h.requireScope(entities.APIKeyScopeMessagesRead, h.Show))
+	router.Get("/messages/:messageID/events", h.requireScope(entities.APIKeyScopeMessagesRead, h.GetEvents))
+	router.Post("/messages/:messageID/forward", h.requireScope(entities.APIKeyScopeMessagesSend, h.Forward))
+	router.Post("/message-threads/:messageThreadID/reply", h.requireScope(entities.APIKeyScopeMessagesSend, h.Reply))
+	router.Post("/messages/:messageID/events", h.computeRoute(phoneMiddlewares, h.requireScope(entities.APIKeyScopePhonesWrite, h.PostEvent))...)
+	router.Put("/messages/:messageID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Update))
+	router.Delete("/messages/:messageID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.requireTeamAdmin(h.Delete)))
 }
 
 // PostSend a new entities.Message
@@ -70,6 +95,7 @@ func (h *MessageHandler) RegisterRoutes(router fiber.Router) {
 // @Failure      400  {object}  responses.BadRequest
 // @Failure 	 401  {object}	responses.Unauthorized
 // @Failure      422  {object}  responses.UnprocessableEntity
+// @Failure      429  {object}  responses.TooManyRequests
 // @Failure      500  {object}  responses.InternalServerError
 // @Router       /messages/send [post]
 func (h *MessageHandler) PostSend(c *fiber.Ctx) error {
@@ -85,22 +111,38 @@ func (h *MessageHandler) PostSend(c *fiber.Ctx) error {
 		return h.responseBadRequest(c, err)
 	}
 
-	if errors := h.validator.ValidateMessageSend(ctx, h.userIDFomContext(c), request.Sanitize()); len(errors) != 0 {
+	if errors := h.validator.ValidateMessageSend(ctx, h.userIDFomContext(c), request); len(errors) != 0 {
 		msg := fmt.Sprintf("validation errors [%s], while sending payload [%s]", spew.Sdump(errors), c.Body())
 		ctxLogger.Warn(stacktrace.NewError(msg))
 		return h.responseUnprocessableEntity(c, errors, "validation errors while sending message")
 	}
 
+	request.Sanitize()
+
 	if msg := h.billingService.IsEntitled(ctx, h.userIDFomContext(c)); msg != nil {
 		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't send a message", h.userIDFomContext(c))))
 		return h.responsePaymentRequired(c, *msg)
 	}
 
 	message, err := h.service.SendMessage(ctx, request.ToMessageSendParams(h.userIDFomContext(c), c.OriginalURL()))
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] exceeded the daily message limit", h.userIDFomContext(c))))
+		return h.responseDailyMessageLimitExceeded(c, limitErr)
+	}
+
+	if validationErr, ok := services.AsValidationError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send a message with payload [%s]", h.userIDFomContext(c), c.Body())))
+		return h.responseValidationError(c, validationErr, "validation errors while sending message")
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("cannot send message with paylod [%s]", c.Body())
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
+	}
+
+	if phone, err := h.phoneService.Load(ctx, message.UserID, message.Owner); err == nil && phone.IsPaused {
+		return h.responseOK(c, fmt.Sprintf("message added to queue but the phone [%s] is paused, it will be sent when the phone is resumed", message.Owner), message)
 	}
 
 	return h.responseOK(c, "message added to queue", message)
@@ -108,7 +150,7 @@ func (h *MessageHandler) PostSend(c *fiber.Ctx) error {
 
 // BulkSend a bulk entities.Message
 // @Summary      Send bulk SMS messages
-// @Description  Add bulk SMS messages to be sent by the android phone
+// @Description  Add bulk SMS messages to be sent by the android phone. Set group_id instead of to for sending the message to each member of a contact group, the response is a summary of the messages which were sent with the errors of the members which the message could not be sent to in the format of responses.ContactGroupSendResponse.
 // @Security	 ApiKeyAuth
 // @Tags         Messages
 // @Accept       json
@@ -117,7 +159,9 @@ func (h *MessageHandler) PostSend(c *fiber.Ctx) error {
 // @Success      200  {object}  []responses.MessagesResponse
 // @Failure      400  {object}  responses.BadRequest
 // @Failure 	 401  {object}	responses.Unauthorized
+// @Failure      404  {object}  responses.NotFound
 // @Failure      422  {object}  responses.UnprocessableEntity
+// @Failure      429  {object}  responses.TooManyRequests
 // @Failure      500  {object}  responses.InternalServerError
 // @Router       /messages/bulk-send [post]
 func (h *MessageHandler) BulkSend(c *fiber.Ctx) error {
@@ -133,22 +177,42 @@ func (h *MessageHandler) BulkSend(c *fiber.Ctx) error {
 		return h.responseBadRequest(c, err)
 	}
 
-	if errors := h.validator.ValidateMessageBulkSend(ctx, h.userIDFomContext(c), request.Sanitize()); len(errors) != 0 {
+	if errors := h.validator.ValidateMessageBulkSend(ctx, h.userIDFomContext(c), request); len(errors) != 0 {
 		msg := fmt.Sprintf("validation errors [%s], while sending payload [%s]", spew.Sdump(errors), c.Body())
 		ctxLogger.Warn(stacktrace.NewError(msg))
 		return h.responseUnprocessableEntity(c, errors, "validation errors while sending messages")
 	}
 
+	request.Sanitize()
+
+	if request.GroupID != "" {
+		return h.groupSend(ctx, c, ctxLogger, request)
+	}
+
 	if msg := h.billingService.IsEntitledWithCount(ctx, h.userIDFomContext(c), uint(len(request.To))); msg != nil {
 		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] is not entitled to send [%d] messages", h.userIDFomContext(c), len(request.To))))
 		return h.responsePaymentRequired(c, *msg)
 	}
 
+	reservedAt, err := h.service.ReserveDailyMessages(ctx, h.userIDFomContext(c), uint(len(request.To)))
+	if err != nil {
+		if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send [%d] messages", h.userIDFomContext(c), len(request.To))))
+			return h.responseDailyMessageLimitExceeded(c, limitErr)
+		}
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot reserve [%d] messages for user with ID [%s]", len(request.To), h.userIDFomContext(c))))
+		return h.responseInternalServerError(c)
+	}
+
 	wg := sync.WaitGroup{}
 	params := request.ToMessageSendParams(h.userIDFomContext(c), c.OriginalURL())
 	responses := make([]*entities.Message, len(params))
+	ownerPhones := services.NewMessageOwnerPhones()
 
 	for index, message := range params {
+		message.DailyLimitReserved = true
+		message.DailyLimitReservedAt = reservedAt
+		message.OwnerPhones = ownerPhones
 		wg.Add(1)
 		go func(message services.MessageSendParams, index int) {
 			response, err := h.service.SendMessage(ctx, message)
@@ -165,6 +229,121 @@ func (h *MessageHandler) BulkSend(c *fiber.Ctx) error {
 	return h.responseOK(c, fmt.Sprintf("[%d] messages processed successfully", len(responses)), responses)
 }
 
+// groupSend sends a bulk message to the snapshot of the members of a contact group when the request starts, so the
+// members which are added or removed while the messages are sent do not get a message or a duplicate.
+func (h *MessageHandler) groupSend(ctx context.Context, c *fiber.Ctx, ctxLogger telemetry.Logger, request requests.MessageBulkSend) error {
+	group, members, err := h.groupService.Members(ctx, h.userIDFomContext(c), request.ContactGroupID())
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the members of contact group [%s] for user [%s]", request.GroupID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	if msg := h.billingService.IsEntitledWithCount(ctx, h.userIDFomContext(c), uint(len(members))); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] is not entitled to send [%d] messages", h.userIDFomContext(c), len(members))))
+		return h.responsePaymentRequired(c, *msg)
+	}
+
+	reservedAt, err := h.service.ReserveDailyMessages(ctx, h.userIDFomContext(c), uint(len(members)))
+	if err != nil {
+		if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send [%d] messages", h.userIDFomContext(c), len(members))))
+			return h.responseDailyMessageLimitExceeded(c, limitErr)
+		}
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot reserve [%d] messages for user with ID [%s]", len(members), h.userIDFomContext(c))))
+		return h.responseInternalServerError(c)
+	}
+
+	result := h.groupService.Send(ctx, request.ToContactGroupSendParams(h.userIDFomContext(c), c.OriginalURL(), group, members, reservedAt))
+	return h.responseOK(c, fmt.Sprintf("sent [%d] of [%d] messages to the contact group", result.Sent, result.Total), result)
+}
+
+// Status returns the status of many entities.Message by ID
+// @Summary      Get the status of many messages
+// @Description  Get the status, timestamps and failure reason of up to 500 messages by ID. The response has an entry for every requested ID, the entry of a message which does not exist or belongs to another user is not found.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param        payload   body requests.MessageStatus  true  "IDs of the messages"
+// @Success      200  {object}  responses.MessageStatusesResponse
+// @Failure      400  {object}  responses.BadRequest
+// @Failure 	 401  {object}	responses.Unauthorized
+// @Failure      422  {object}  responses.UnprocessableEntity
+// @Failure      500  {object}  responses.InternalServerError
+// @Router       /messages/status [post]
+func (h *MessageHandler) Status(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	var request requests.MessageStatus
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall [%s] into %T", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateMessageStatus(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching message statuses [%s]", spew.Sdump(errors), c.Body())
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching message statuses")
+	}
+
+	messageIDs := request.ToUUIDs()
+	messages, err := h.service.GetMessagesByID(ctx, h.userIDFomContext(c), messageIDs)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch [%d] messages for user [%s]", len(messageIDs), h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched the status of %d %s", len(messageIDs), h.pluralize("message", len(messageIDs))), responses.NewMessageStatuses(messageIDs, messages))
+}
+
+// QueueStats returns the entities.PhoneQueueStats of the phone of an owner
+// @Summary      Get the queue of a phone
+// @Description  Get the number of messages which are waiting to be sent by a phone and the number of messages which it can still send today when it has a daily send limit.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param        owner	query  		string  						true "the phone number of the phone"	default(+18005550199)
+// @Success      200 	{object}	responses.PhoneQueueStatsResponse
+// @Failure      400	{object}	responses.BadRequest
+// @Failure 	 401    {object}	responses.Unauthorized
+// @Failure      404	{object}	responses.NotFound
+// @Failure      422	{object}	responses.UnprocessableEntity
+// @Failure      500	{object}	responses.InternalServerError
+// @Router       /messages/queue-stats [get]
+func (h *MessageHandler) QueueStats(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageQueueStats
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateMessageQueueStats(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the queue stats of a phone [%s]", spew.Sdump(errors), c.OriginalURL())
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching the queue stats of a phone")
+	}
+
+	stats, err := h.service.QueueStats(ctx, h.userIDFomContext(c), request.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the queue stats of the phone of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "queue stats fetched successfully", stats)
+}
+
 // GetOutstanding returns an entities.Message which is still to be sent by the mobile phone
 // @Summary      Get an outstanding message
 // @Description  Get an outstanding message to be sent by an android phone
@@ -177,6 +356,8 @@ func (h *MessageHandler) BulkSend(c *fiber.Ctx) error {
 // @Failure      400		{object}	responses.BadRequest
 // @Failure 	 401    	{object}	responses.Unauthorized
 // @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      429		{object}	responses.TooManyRequests
+// @Failure      426		{object}	responses.UpgradeRequired
 // @Failure      500		{object}	responses.InternalServerError
 // @Router       /messages/outstanding [get]
 func (h *MessageHandler) GetOutstanding(c *fiber.Ctx) error {
@@ -206,10 +387,22 @@ func (h *MessageHandler) GetOutstanding(c *fiber.Ctx) error {
 		return h.responseNotFound(c, "outstanding message already processed")
 	}
 
+	if stacktrace.GetCode(err) == services.ErrCodeRateLimited {
+		msg := fmt.Sprintf("outstanding message with id [%s] exceeds the messages per minute of the phone", request.MessageID)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseTooManyRequests(c, "the phone has exceeded its messages per minute, try again later")
+	}
+
+	if stacktrace.GetCode(err) == services.ErrCodeDailySendLimit {
+		msg := fmt.Sprintf("outstanding message with id [%s] exceeds the daily send limit of the phone", request.MessageID)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseTooManyRequests(c, "the phone has reached its daily send limit, the message will be sent after midnight")
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("cannot get outstanding messgage with ID [%s]", request.MessageID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "outstanding message fetched successfully", message)
@@ -227,7 +420,18 @@ func (h *MessageHandler) GetOutstanding(c *fiber.Ctx) error {
 // @Param        skip		query  int  	false	"number of messages to skip"		minimum(0)
 // @Param        query		query  string  	false 	"filter messages containing query"
 // @Param        limit		query  int  	false	"number of messages to return"		minimum(1)	maximum(20)
+// @Param        include_archived	query  bool  	false	"also return messages which have been moved into the archive"
+// @Param        include_total		query  bool  	false	"also count the messages which match the filters in the X-Total-Count header"
+// @Param        status			query  string  	false	"only return the messages with the status"	Enums(pending, scheduled, sending, sent, received, failed, delivered, expired)
+// @Param        after			query  string  	false	"only return the messages at or after the RFC3339 timestamp"	default(2022-06-05T14:26:09+03:00)
+// @Param        before			query  string  	false	"only return the messages before the RFC3339 timestamp"	default(2022-06-06T14:26:09+03:00)
+// @Param        metadata[key]	query  string  	false	"only return the messages whose metadata has the value at the key e.g. metadata[twilio_sid]=SM123, at most 3 keys can be combined"
+// @Param        If-None-Match		header string  	false	"ETag of the page which the client already has"
 // @Success      200 		{object}	responses.MessagesResponse
+// @Header       200 		{string}	ETag			"weak entity tag which changes when a message in the page or the liveness of the phone changes"
+// @Header       200 		{string}	Link			"links of the next and previous pages e.g. <https://api.httpsms.com/v1/messages?skip=20&limit=20>; rel=\"next\""
+// @Header       200 		{integer}	X-Total-Count	"number of messages which match the filters when include_total is set"
+// @Success      304 		"the page has not changed since the ETag in the If-None-Match header"
 // @Failure      400		{object}	responses.BadRequest
 // @Failure 	 401    	{object}	responses.Unauthorized
 // @Failure      422		{object}	responses.UnprocessableEntity
@@ -245,6 +449,7 @@ func (h *MessageHandler) Index(c *fiber.Ctx) error {
 		ctxLogger.Warn(stacktrace.Propagate(err, msg))
 		return h.responseBadRequest(c, err)
 	}
+	request.Metadata = h.queryMap(c, "metadata")
 
 	if errors := h.validator.ValidateMessageIndex(ctx, request.Sanitize()); len(errors) != 0 {
 		msg := fmt.Sprintf("validation errors [%s], while fetching messages [%+#v]", spew.Sdump(errors), request)
@@ -252,14 +457,56 @@ func (h *MessageHandler) Index(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching messages")
 	}
 
-	messages, err := h.service.GetMessages(ctx, request.ToGetParams(h.userIDFomContext(c)))
+	params := request.ToGetParams(h.userIDFomContext(c))
+	messages, err := h.service.GetMessages(ctx, params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot get messgaes with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(*messages), h.pluralize("message", len(*messages))), messages)
+	liveness, err := h.heartbeatService.Liveness(ctx, h.userIDFomContext(c), request.Owner)
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load liveness of phone [%s] for messages", request.Owner)))
+	}
+
+	contacts := make([]string, 0, len(*messages))
+	for _, message := range *messages {
+		contacts = append(contacts, message.Contact)
+	}
+	names := h.contactService.Names(ctx, h.userIDFomContext(c), contacts...)
+	pointers := make([]*entities.Message, 0, len(*messages))
+	for index := range *messages {
+		pointers = append(pointers, &(*messages)[index])
+	}
+	h.setMedia(ctx, h.userIDFomContext(c), pointers)
+
+	versions := []string{h.livenessETag(liveness)}
+	for index := range *messages {
+		(*messages)[index].Phone = liveness
+		h.setContactName(&(*messages)[index], names)
+		versions = append(versions, h.messageETag(&(*messages)[index])...)
+	}
+
+	if request.IncludeTotal {
+		total, err := h.service.CountMessages(ctx, params)
+		if err != nil {
+			msg := fmt.Sprintf("cannot count messages with params [%+#v]", request)
+			ctxLogger.Error(stacktrace.Propagate(err, msg))
+			return h.responseServiceError(c, err)
+		}
+
+		if h.notModified(c, h.pageETag(c, append(versions, strconv.FormatInt(total, 10)))) {
+			return h.responseNotModified(c)
+		}
+		return h.responsePaginatedWithTotal(c, fmt.Sprintf("fetched %d %s", len(*messages), h.pluralize("message", len(*messages))), messages, params.IndexParams, len(*messages), total)
+	}
+
+	if h.notModified(c, h.pageETag(c, versions)) {
+		return h.responseNotModified(c)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(*messages), h.pluralize("message", len(*messages))), messages, params.IndexParams, len(*messages))
 }
 
 // PostEvent registers an event on a message
@@ -275,7 +522,9 @@ func (h *MessageHandler) Index(c *fiber.Ctx) error {
 // @Failure      400  		{object}  	responses.BadRequest
 // @Failure 	 401    	{object}	responses.Unauthorized
 // @Failure 	 404		{object}	responses.NotFound
+// @Failure      409		{object}	responses.Conflict
 // @Failure      422  		{object} 	responses.UnprocessableEntity
+// @Failure      426		{object}	responses.UpgradeRequired
 // @Failure      500  		{object}  	responses.InternalServerError
 // @Router       /messages/{messageID}/events [post]
 func (h *MessageHandler) PostEvent(c *fiber.Ctx) error {
@@ -310,14 +559,14 @@ func (h *MessageHandler) PostEvent(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot find message with id [%s]", request.MessageID)
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	message, err = h.service.StoreEvent(ctx, message, request.ToMessageStoreEventParams(c.OriginalURL()))
 	if err != nil {
 		msg := fmt.Sprintf("cannot store event for message [%s] with paylod [%s]", request.MessageID, c.Body())
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "message event stored successfully", message)
@@ -334,6 +583,7 @@ func (h *MessageHandler) PostEvent(c *fiber.Ctx) error {
 // @Success      200  {object}  responses.MessageResponse
 // @Failure      400  {object}  responses.BadRequest
 // @Failure      422  {object}  responses.UnprocessableEntity
+// @Failure      426		{object}	responses.UpgradeRequired
 // @Failure      500  {object}  responses.InternalServerError
 // @Router       /messages/receive [post]
 func (h *MessageHandler) PostReceive(c *fiber.Ctx) error {
@@ -364,12 +614,266 @@ func (h *MessageHandler) PostReceive(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot receive message with paylod [%s]", c.Body())
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "message received successfully", message)
 }
 
+// Show fetches a message
+// @Summary      Get a message
+// @Description  Get a message by its ID e.g. to check the status of a message which was sent.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param 		 messageID 	path		string 							true 	"ID of the message" 			default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param 		 If-None-Match 	header	string 							false 	"ETag of the message which the client already has"
+// @Success      200  		{object} 	responses.MessageResponse
+// @Header       200 		{string}	ETag	"strong entity tag which changes when the message is updated"
+// @Success      304  		"the message has not changed since the ETag in the If-None-Match header"
+// @Failure      400  		{object}  	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422  		{object} 	responses.UnprocessableEntity
+// @Failure      500  		{object}  	responses.InternalServerError
+// @Router       /messages/{messageID} [get]
+func (h *MessageHandler) Show(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	messageID := c.Params("messageID")
+	if errors := h.validator.ValidateUUID(ctx, messageID, "messageID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching message with ID [%s]", spew.Sdump(errors), messageID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching message")
+	}
+
+	message, err := h.service.GetMessage(ctx, h.userIDFomContext(c), uuid.MustParse(messageID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find message with ID [%s]", messageID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot find message with id [%s]", messageID)
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	h.setContactName(message, h.contactService.Names(ctx, h.userIDFomContext(c), message.Contact))
+	h.setMedia(ctx, h.userIDFomContext(c), []*entities.Message{message})
+
+	// the version is incremented on every update so the response is identical while the tag does not change
+	if h.notModified(c, h.etag(false, h.messageETag(message)...)) {
+		return h.responseNotModified(c)
+	}
+
+	return h.responseOK(c, "message fetched successfully", message)
+}
+
+// GetEvents lists the events of a message
+// @Summary      Get the events of a message
+// @Description  Get the cloud events which were emitted for a message ordered by time in ascending order.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param 		 messageID 	path		string 							true 	"ID of the message" 			default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200  		{object} 	responses.MessageEventsResponse
+// @Failure      400  		{object}  	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422  		{object} 	responses.UnprocessableEntity
+// @Failure      500  		{object}  	responses.InternalServerError
+// @Router       /messages/{messageID}/events [get]
+func (h *MessageHandler) GetEvents(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	messageID := c.Params("messageID")
+	if errors := h.validator.ValidateUUID(ctx, messageID, "messageID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the events of message with ID [%s]", spew.Sdump(errors), messageID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching message events")
+	}
+
+	message, err := h.service.GetMessage(ctx, h.userIDFomContext(c), uuid.MustParse(messageID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find message with ID [%s]", messageID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot find message with id [%s]", messageID)
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	cloudEvents, err := h.service.GetMessageEvents(ctx, message.ID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the events of message with ID [%s]", message.ID)
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	messageEvents, err := responses.NewMessageEvents(*cloudEvents)
+	if err != nil {
+		msg := fmt.Sprintf("cannot decode the events of message with ID [%s]", message.ID)
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseInternalServerError(c)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d message %s", len(messageEvents), h.pluralize("event", len(messageEvents))), messageEvents)
+}
+
+// Update the content of a message
+// @Summary      Update the content of a message
+// @Description  Change the content of a message which is still pending or scheduled because the phone has not fetched it. A message which is being sent or has already been sent cannot be changed.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param 		 messageID 	path		string 							true 	"ID of the message" 			default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.MessageUpdate 			true 	"New content of the message"
+// @Success      200  		{object} 	responses.MessageResponse
+// @Failure      400  		{object}  	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure 	 409		{object}	responses.Conflict
+// @Failure      422  		{object} 	responses.UnprocessableEntity
+// @Failure      500  		{object}  	responses.InternalServerError
+// @Router       /messages/{messageID} [put]
+func (h *MessageHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall [%s] into %T", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.MessageID = c.Params("messageID")
+	if errors := h.validator.ValidateMessageUpdate(ctx, request); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating message [%s]", spew.Sdump(errors), request.MessageID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating message")
+	}
+
+	message, err := h.service.UpdateMessageContent(ctx, c.OriginalURL(), h.userIDFomContext(c), request.MessageUUID(), request.Content)
+	if err != nil {
+		msg := fmt.Sprintf("cannot update content of message with ID [%s] for user [%s]", request.MessageID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "message updated successfully", message)
+}
+
+// Forward a received message
+// @Summary      Forward a received message to another contact
+// @Description  Send the content of a message received by the phone in the "from" field to the "to" contact from the same phone with an optional prefix e.g. "FWD from +237677777777:". The new message has the ID of the received message in forwarded_from_id and the received message has the ID of its last forward in forwarded_to_id.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param 		 messageID 	path		string 							true 	"ID of the received message" 	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.MessageForward 		true 	"Contact which the message is forwarded to"
+// @Success      200  		{object} 	responses.MessageResponse
+// @Failure      400  		{object}  	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422  		{object} 	responses.UnprocessableEntity
+// @Failure      429  		{object}  	responses.TooManyRequests
+// @Failure      500  		{object}  	responses.InternalServerError
+// @Router       /messages/{messageID}/forward [post]
+func (h *MessageHandler) Forward(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageForward
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall [%s] into %T", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.MessageID = c.Params("messageID")
+	if errors := h.validator.ValidateMessageForward(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while forwarding message [%s]", spew.Sdump(errors), request.MessageID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while forwarding message")
+	}
+
+	if msg := h.billingService.IsEntitled(ctx, h.userIDFomContext(c)); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't forward a message", h.userIDFomContext(c))))
+		return h.responsePaymentRequired(c, *msg)
+	}
+
+	message, err := h.service.ForwardMessage(ctx, request.ToForwardParams(h.userIDFomContext(c), c.OriginalURL()))
+	if err != nil {
+		msg := fmt.Sprintf("cannot forward message with ID [%s] for user [%s]", request.MessageID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "forwarded message added to queue", message)
+}
+
+// Reply in a message thread
+// @Summary      Send a reply in a message thread
+// @Description  Send a message to the contact of a thread from the owner of the thread. The new message has the ID of the thread in thread_id and the ID of the latest message which was received in the thread in in_reply_to_id. An archived thread is unarchived.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       json
+// @Produce      json
+// @Param 		 messageThreadID 	path		string 							true 	"ID of the message thread" 	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   			body 		requests.MessageThreadReply 	true 	"Content of the reply"
+// @Success      200  		{object} 	responses.MessageResponse
+// @Failure      400  		{object}  	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422  		{object} 	responses.UnprocessableEntity
+// @Failure      429  		{object}  	responses.TooManyRequests
+// @Failure      500  		{object}  	responses.InternalServerError
+// @Router       /message-threads/{messageThreadID}/reply [post]
+func (h *MessageHandler) Reply(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageThreadReply
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall [%s] into %T", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.MessageThreadID = c.Params("messageThreadID")
+	if errors := h.validator.ValidateMessageThreadReply(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while replying in thread [%s]", spew.Sdump(errors), request.MessageThreadID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while replying in thread")
+	}
+
+	if msg := h.billingService.IsEntitled(ctx, h.userIDFomContext(c)); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't reply in a thread", h.userIDFomContext(c))))
+		return h.responsePaymentRequired(c, *msg)
+	}
+
+	message, err := h.service.ReplyInThread(ctx, h.userIDFomContext(c), request.ThreadID(), request.Content, request.ToReplyOptions(c.OriginalURL()))
+	if err != nil {
+		msg := fmt.Sprintf("cannot reply in thread with ID [%s] for user [%s]", request.MessageThreadID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "reply added to queue", message)
+}
+
 // Delete a message
 // @Summary      Delete a message from the database.
 // @Description  Delete a message from the database and removes the message content from the list of threads.
@@ -406,14 +910,41 @@ func (h *MessageHandler) Delete(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot find message with id [%s]", messageID)
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	if err = h.service.DeleteMessage(ctx, c.OriginalURL(), message); err != nil {
 		msg := fmt.Sprintf("cannot delete message with ID [%s] for user with ID [%s]", messageID, message.UserID)
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseNoContent(c, "message deleted successfully")
 }
+
+// messageETag is the part of an entity tag which changes when an entities.Message or the name of its contact is updated
+func (h *MessageHandler) messageETag(message *entities.Message) []string {
+	contactName := ""
+	if message.ContactName != nil {
+		contactName = *message.ContactName
+	}
+	return []string{message.ID.String(), strconv.FormatUint(uint64(message.Version), 10), message.UpdatedAt.UTC().Format(time.RFC3339Nano), contactName}
+}
+
+// setContactName sets the name of the contact of a message when the contact has a name
+func (h *MessageHandler) setContactName(message *entities.Message, names map[string]string) {
+	if name, ok := names[message.Contact]; ok {
+		message.ContactName = &name
+	}
+}
+
+// setMedia sets the media of the messages which are sent as MMS messages, the messages are returned without their
+// media when it cannot be fetched
+func (h *MessageHandler) setMedia(ctx context.Context, userID entities.UserID, messages []*entities.Message) {
+	ctx, span, ctxLogger := h.tracer.StartWithLogger(ctx, h.logger)
+	defer span.End()
+
+	if err := h.mediaService.IndexByMessages(ctx, userID, messages); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the media of [%d] messages for user [%s]", len(messages), userID)))
+	}
+}
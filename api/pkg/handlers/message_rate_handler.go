@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// MessageRateHandler handles the rates which estimate the cost of the messages of a user
+type MessageRateHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.MessageRateHandlerValidator
+	service   *services.MessageRateService
+}
+
+// NewMessageRateHandler creates a new MessageRateHandler
+func NewMessageRateHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.MessageRateHandlerValidator,
+	service *services.MessageRateService,
+) (h *MessageRateHandler) {
+	return &MessageRateHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the MessageRateHandler
+func (h *MessageRateHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/message-rates", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+	router.Post("/message-rates", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Store))
+	router.Get("/message-rates/:messageRateID", h.requireScope(entities.APIKeyScopeMessagesRead, h.Show))
+	router.Put("/message-rates/:messageRateID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Update))
+	router.Delete("/message-rates/:messageRateID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Delete))
+	router.Get("/message-costs", h.requireScope(entities.APIKeyScopeMessagesRead, h.Costs))
+}
+
+// Index returns the message rates of a user
+// @Summary      Get message rates of a user
+// @Description  Get all the rates of the currently authenticated user which estimate the cost of a message when it is sent
+// @Security	 ApiKeyAuth
+// @Tags         MessageRates
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.MessageRatesResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /message-rates 	[get]
+func (h *MessageRateHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	rates, err := h.service.Index(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot get message rates for user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(rates), h.pluralize("message rate", len(rates))), rates)
+}
+
+// Store creates a message rate
+// @Summary      Create a message rate
+// @Description  Set the cost of one SMS segment from the effective_from time for the messages from the owner to the contacts with a prefix e.g. +44. The rate of the owner is used before a rate for all phones, then the rate with the longest prefix and then the latest rate.
+// @Security	 ApiKeyAuth
+// @Tags         MessageRates
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.MessageRateStore  	true 	"Payload of the message rate"
+// @Success      200 		{object}	responses.MessageRateResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /message-rates 	[post]
+func (h *MessageRateHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageRateStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while creating message rate [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while creating message rate")
+	}
+
+	rate, err := h.service.Store(ctx, request.ToStoreParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot create message rate with prefix [%s] for user [%s]", request.Prefix, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "message rate created successfully", rate)
+}
+
+// Show returns a message rate of a user
+// @Summary      Get a message rate
+// @Description  Get a message rate of the currently authenticated user by ID
+// @Security	 ApiKeyAuth
+// @Tags         MessageRates
+// @Accept       json
+// @Produce      json
+// @Param 		 messageRateID	path		string 	true 	"ID of the message rate" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      200 		{object}	responses.MessageRateResponse
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /message-rates/{messageRateID} 	[get]
+func (h *MessageRateHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	rateID := c.Params("messageRateID")
+	if errors := h.validator.ValidateUUID(ctx, rateID, "messageRateID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching message rate with ID [%s]", spew.Sdump(errors), rateID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching message rate")
+	}
+
+	rate, err := h.service.Get(ctx, h.userIDFomContext(c), uuid.MustParse(rateID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message rate with ID [%s] for user [%s]", rateID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "message rate fetched successfully", rate)
+}
+
+// Update a message rate
+// @Summary      Update a message rate
+// @Description  Update a message rate of the currently authenticated user, the cost of the messages which are already sent is not changed
+// @Security	 ApiKeyAuth
+// @Tags         MessageRates
+// @Accept       json
+// @Produce      json
+// @Param 		 messageRateID	path		string 						true 	"ID of the message rate" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   		body 		requests.MessageRateStore  	true 	"Payload of the message rate"
+// @Success      200 		{object}	responses.MessageRateResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /message-rates/{messageRateID} 	[put]
+func (h *MessageRateHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageRateUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.MessageRateID = c.Params("messageRateID")
+	if errors := h.validator.ValidateUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating message rate [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating message rate")
+	}
+
+	rate, err := h.service.Update(ctx, request.ToUpdateParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update message rate with ID [%s] for user [%s]", request.MessageRateID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "message rate updated successfully", rate)
+}
+
+// Delete a message rate
+// @Summary      Delete a message rate
+// @Description  Delete a message rate of the currently authenticated user, the cost of the messages which are already sent is not changed.
+// @Security	 ApiKeyAuth
+// @Tags         MessageRates
+// @Accept       json
+// @Produce      json
+// @Param 		 messageRateID	path		string 	true 	"ID of the message rate" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /message-rates/{messageRateID} 	[delete]
+func (h *MessageRateHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	rateID := c.Params("messageRateID")
+	if errors := h.validator.ValidateUUID(ctx, rateID, "messageRateID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting message rate with ID [%s]", spew.Sdump(errors), rateID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting message rate")
+	}
+
+	if err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(rateID)); err != nil {
+		msg := fmt.Sprintf("cannot delete message rate with ID [%s] for user [%s]", rateID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "message rate deleted successfully")
+}
+
+// Costs returns the estimated cost of the messages of a user
+// @Summary      Get the cost of messages
+// @Description  Sum the estimated cost of the messages of the currently authenticated user in each currency, use group_by=request_id to get the cost of each campaign. Messages which failed or expired are not included.
+// @Security	 ApiKeyAuth
+// @Tags         MessageRates
+// @Accept       json
+// @Produce      json
+// @Param        from		query  string  	false	"RFC3339 time of the first message"		default(2022-06-01T00:00:00Z)
+// @Param        to			query  string  	false	"RFC3339 time after the last message"	default(2022-07-01T00:00:00Z)
+// @Param        request_id	query  string  	false	"only sum the messages with this request_id"
+// @Param        group_by	query  string  	false	"set to request_id to sum the cost of each request_id"	Enums(request_id)
+// @Success      200 		{object}	responses.MessageCostsResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /message-costs 	[get]
+func (h *MessageRateHandler) Costs(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageCostIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateCostIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the cost of messages [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching the cost of messages")
+	}
+
+	summaries, err := h.service.SummarizeCosts(ctx, h.userIDFomContext(c), request.ToCostParams())
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the cost of messages with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched the cost of messages in %d %s", len(summaries), h.pluralize("group", len(summaries))), summaries)
+}
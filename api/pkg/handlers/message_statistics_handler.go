@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// MessageStatisticsHandler handles the statistics of the messages which are charted on the dashboard
+type MessageStatisticsHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.MessageStatisticsHandlerValidator
+	service   *services.MessageStatisticsService
+}
+
+// NewMessageStatisticsHandler creates a new MessageStatisticsHandler
+func NewMessageStatisticsHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.MessageStatisticsHandlerValidator,
+	service *services.MessageStatisticsService,
+) (h *MessageStatisticsHandler) {
+	return &MessageStatisticsHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the MessageStatisticsHandler
+func (h *MessageStatisticsHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/statistics/timeseries", h.requireScope(entities.APIKeyScopeMessagesRead, h.TimeSeries))
+	router.Get("/statistics/latency", h.requireScope(entities.APIKeyScopeMessagesRead, h.Latency))
+}
+
+// TimeSeries returns the number of messages of a user in each hour or day
+// @Summary      Get the time series of messages
+// @Description  Count the outgoing messages by status, the incoming messages, the average send duration and the failure rate in each hour or day of a time range. Every series has one value for each bucket, the buckets without messages are 0 and the average send duration and failure rate are null. The hourly granularity is limited to 31 days and the daily granularity to 366 days.
+// @Security	 ApiKeyAuth
+// @Tags         Statistics
+// @Accept       json
+// @Produce      json
+// @Param        from			query  string  	false	"RFC3339 start of the time range, defaults to 30 days or 24 hours before to"	default(2022-06-01T00:00:00Z)
+// @Param        to				query  string  	false	"RFC3339 end of the time range, defaults to now"	default(2022-07-01T00:00:00Z)
+// @Param        granularity	query  string  	false	"size of the buckets, defaults to day"	Enums(hour, day)
+// @Param        timezone		query  string  	false	"timezone of the buckets, defaults to UTC"	default(Africa/Douala)
+// @Param        owner			query  string  	false	"only count the messages of this phone number"	default(+18005550199)
+// @Success      200 		{object}	responses.MessageTimeSeriesResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /statistics/timeseries 	[get]
+func (h *MessageStatisticsHandler) TimeSeries(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageTimeSeries
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateTimeSeries(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the time series of messages [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching the time series of messages")
+	}
+
+	series, err := h.service.TimeSeries(ctx, h.userIDFomContext(c), request.ToTimeSeriesParams())
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the time series of messages with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched the messages in %d %s", len(series.Buckets), h.pluralize(string(series.Granularity), len(series.Buckets))), series)
+}
+
+// Latency returns the send latency of the messages of a user
+// @Summary      Get the send latency of messages
+// @Description  Get the 50th, 90th and 99th percentile of the time between the API receiving an outgoing message and the phone sending it, and whether the messages met an SLO e.g. 95% of messages are sent within 60 seconds. Messages which failed or expired violate the SLO.
+// @Security	 ApiKeyAuth
+// @Tags         Statistics
+// @Accept       json
+// @Produce      json
+// @Param        from			query  string  	false	"RFC3339 start of the time range, defaults to 30 days before to"	default(2022-06-01T00:00:00Z)
+// @Param        to				query  string  	false	"RFC3339 end of the time range, defaults to now"	default(2022-07-01T00:00:00Z)
+// @Param        owner			query  string  	false	"only count the messages of this phone number"	default(+18005550199)
+// @Param        threshold		query  int  	false	"number of seconds in which a message must be sent, defaults to 60"	minimum(1)	maximum(86400)
+// @Param        objective		query  number  	false	"fraction of the messages which must be sent within the threshold, defaults to 0.95"
+// @Success      200 		{object}	responses.MessageLatencyResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /statistics/latency 	[get]
+func (h *MessageStatisticsHandler) Latency(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageLatency
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateLatency(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the latency of messages [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching the latency of messages")
+	}
+
+	summary, err := h.service.Latency(ctx, h.userIDFomContext(c), request.ToLatencyParams())
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the latency of messages with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched the latency of %d %s", summary.Messages, h.pluralize("message", int(summary.Messages))), summary)
+}
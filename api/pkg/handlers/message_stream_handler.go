@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// maxReplayedEvents is the maximum number of events which are sent to a client which reconnects with the Last-Event-ID header
+const maxReplayedEvents = 500
+
+// MessageStreamHandler streams the events of entities.Message as server-sent events
+type MessageStreamHandler struct {
+	handler
+	logger            telemetry.Logger
+	tracer            telemetry.Tracer
+	validator         *validators.MessageHandlerValidator
+	broker            *services.EventBroker
+	heartbeatInterval time.Duration
+}
+
+// NewMessageStreamHandler creates a new MessageStreamHandler
+func NewMessageStreamHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.MessageHandlerValidator,
+	broker *services.EventBroker,
+	heartbeatInterval time.Duration,
+) (h *MessageStreamHandler) {
+	return &MessageStreamHandler{
+		logger:            logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:            tracer,
+		validator:         validator,
+		broker:            broker,
+		heartbeatInterval: heartbeatInterval,
+	}
+}
+
+// RegisterRoutes registers the routes for the MessageStreamHandler
+func (h *MessageStreamHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/messages/stream", h.requireScope(entities.APIKeyScopeMessagesRead, h.Stream))
+}
+
+// Stream sends the events of the messages of the user as server-sent events
+// @Summary      Stream message events
+// @Description  Stream the events of your messages as [server-sent events](https://developer.mozilla.org/en-US/docs/Web/API/Server-sent_events). The ID of every event can be sent in the `Last-Event-ID` header when reconnecting to receive the events which were missed.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Produce      text/event-stream
+// @Param        owner			query  string  	false 	"only stream the events of this phone number" 		default(+18005550199)
+// @Param        last_event_id	query  string  	false 	"the ID of the last event which was received, it can also be sent in the Last-Event-ID header"
+// @Success      200 		{string}	string	"stream of responses.MessageEvent"
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /messages/stream [get]
+func (h *MessageStreamHandler) Stream(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	var request requests.MessageStream
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if lastEventID := c.Get("Last-Event-ID"); lastEventID != "" {
+		request.LastEventID = lastEventID
+	}
+
+	if errors := h.validator.ValidateMessageStream(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while streaming messages [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while streaming messages")
+	}
+
+	// the subscription is created before the replay so that no event is lost between the replay and the live events
+	subscription := h.broker.Subscribe(h.userIDFomContext(c), request.Owner)
+
+	var backlog []cloudevents.Event
+	if request.LastEventID != "" {
+		replayed, err := h.broker.Replay(ctx, subscription, uuid.MustParse(request.LastEventID), maxReplayedEvents)
+		if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+			h.broker.Unsubscribe(subscription)
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot replay events after [%s]", request.LastEventID)))
+			return h.responseServiceError(c, err)
+		}
+		backlog = replayed
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.broker.Unsubscribe(subscription)
+
+		ticker := time.NewTicker(h.heartbeatInterval)
+		defer ticker.Stop()
+
+		sent := make(map[string]bool, len(backlog))
+		for _, event := range backlog {
+			sent[event.ID()] = true
+			if err := h.writeEvent(w, event); err != nil {
+				return
+			}
+		}
+
+		if _, err := w.WriteString(": connected\n\n"); err != nil || w.Flush() != nil {
+			return
+		}
+
+		for {
+			select {
+			case event, ok := <-subscription.Events():
+				if !ok {
+					return
+				}
+				if sent[event.ID()] {
+					continue
+				}
+				if err := h.writeEvent(w, event); err != nil || w.Flush() != nil {
+					return
+				}
+			case <-ticker.C:
+				if _, err := w.WriteString(": heartbeat\n\n"); err != nil || w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeEvent writes a cloudevents.Event as a server-sent event whose data is a responses.MessageEvent
+func (h *MessageStreamHandler) writeEvent(w *bufio.Writer, event cloudevents.Event) error {
+	messageEvents, err := responses.NewMessageEvents([]cloudevents.Event{event})
+	if err != nil {
+		h.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot decode event [%s] for the message stream", event.ID())))
+		return nil
+	}
+
+	data, err := json.Marshal(messageEvents[0])
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot marshal event [%s] into JSON", event.ID()))
+	}
+
+	if _, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID(), event.Type(), data); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot write event [%s] to the message stream", event.ID()))
+	}
+	return nil
+}
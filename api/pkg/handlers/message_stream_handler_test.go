@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMessageStreamServer(t *testing.T, repository repositories.EventRepository) (*services.EventBroker, string) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	broker := services.NewEventBroker(logger, tracer, repository, 10)
+	validator := validators.NewMessageHandlerValidator(logger, tracer, services.NewPhoneService(logger, tracer, nil, nil, 0, services.PhoneClockConfig{}))
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middlewares.ContextKeyAuthUserID, entities.AuthUser{ID: "user-a", Email: "name@email.com"})
+		return c.Next()
+	})
+	NewMessageStreamHandler(logger, tracer, validator, broker, 10*time.Millisecond).RegisterRoutes(app)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = app.Listener(listener)
+	}()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	return broker, "http://" + listener.Addr().String()
+}
+
+func newStreamEvent(t *testing.T, timestamp time.Time, payload map[string]any) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.NewString())
+	event.SetType("message.phone.delivered")
+	event.SetSource("/v1/messages/" + uuid.NewString() + "/events")
+	event.SetTime(timestamp)
+	assert.Nil(t, event.SetData(cloudevents.ApplicationJSON, payload))
+	return event
+}
+
+// readEventIDs reads the IDs of the server-sent events until the stream has sent the connected comment and the expected number of events
+func readEventIDs(t *testing.T, reader *bufio.Reader, count int, connected *bool) []string {
+	var ids []string
+	for len(ids) < count || !*connected {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		switch {
+		case strings.HasPrefix(line, ": connected"):
+			*connected = true
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+	return ids
+}
+
+func TestMessageStreamHandler_Stream(t *testing.T) {
+	t.Run("reconnecting client receives the missed events and then the live events of its user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := memory.NewEventRepository()
+		broker, url := newMessageStreamServer(t, repository)
+		start := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+
+		// Arrange
+		last := newStreamEvent(t, start, map[string]any{"user_id": "user-a", "owner": "+18005550199"})
+		missed := newStreamEvent(t, start.Add(time.Second), map[string]any{"user_id": "user-a", "owner": "+18005550199"})
+		otherUser := newStreamEvent(t, start.Add(2*time.Second), map[string]any{"user_id": "user-b", "owner": "+18005550199"})
+		for _, event := range []cloudevents.Event{last, missed, otherUser} {
+			require.Nil(t, repository.Create(context.Background(), event))
+		}
+
+		request, err := http.NewRequest(http.MethodGet, url+"/messages/stream", nil)
+		require.NoError(t, err)
+		request.Header.Set("Last-Event-ID", last.ID())
+
+		// Act
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+
+		connected := false
+		reader := bufio.NewReader(response.Body)
+		replayed := readEventIDs(t, reader, 1, &connected)
+
+		live := newStreamEvent(t, time.Now().UTC(), map[string]any{"user_id": "user-a", "owner": "+18005550100"})
+		require.Nil(t, broker.Publish(context.Background(), newStreamEvent(t, time.Now().UTC(), map[string]any{"user_id": "user-b"})))
+		require.Nil(t, broker.Publish(context.Background(), live))
+		received := readEventIDs(t, reader, 1, &connected)
+
+		// Assert
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		assert.Equal(t, "text/event-stream", response.Header.Get(fiber.HeaderContentType))
+		assert.Equal(t, []string{missed.ID()}, replayed)
+		assert.Equal(t, []string{live.ID()}, received)
+
+		assert.Nil(t, response.Body.Close())
+		assert.Eventually(t, func() bool { return broker.Subscribers() == 0 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("stream of an owner does not receive the events of the other phones", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		broker, url := newMessageStreamServer(t, memory.NewEventRepository())
+
+		// Act
+		response, err := http.Get(url + "/messages/stream?owner=%2B18005550199")
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		connected := false
+		reader := bufio.NewReader(response.Body)
+		readEventIDs(t, reader, 0, &connected)
+
+		expected := newStreamEvent(t, time.Now().UTC(), map[string]any{"user_id": "user-a", "owner": "+18005550199"})
+		require.Nil(t, broker.Publish(context.Background(), newStreamEvent(t, time.Now().UTC(), map[string]any{"user_id": "user-a", "owner": "+18005550100"})))
+		require.Nil(t, broker.Publish(context.Background(), expected))
+
+		// Assert
+		assert.Equal(t, []string{expected.ID()}, readEventIDs(t, reader, 1, &connected))
+	})
+
+	t.Run("invalid last event ID is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		broker, url := newMessageStreamServer(t, memory.NewEventRepository())
+
+		// Act
+		response, err := http.Get(url + "/messages/stream?last_event_id=123")
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		// Assert
+		assert.Equal(t, fiber.StatusUnprocessableEntity, response.StatusCode)
+		assert.Equal(t, 0, broker.Subscribers())
+	})
+}
@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/google/uuid"
 
@@ -18,10 +22,12 @@ import (
 // MessageThreadHandler handles message-thead http requests.
 type MessageThreadHandler struct {
 	handler
-	logger    telemetry.Logger
-	tracer    telemetry.Tracer
-	validator *validators.MessageThreadHandlerValidator
-	service   *services.MessageThreadService
+	logger           telemetry.Logger
+	tracer           telemetry.Tracer
+	validator        *validators.MessageThreadHandlerValidator
+	service          *services.MessageThreadService
+	heartbeatService *services.HeartbeatService
+	contactService   *services.ContactService
 }
 
 // NewMessageThreadHandler creates a new MessageThreadHandler
@@ -30,20 +36,26 @@ func NewMessageThreadHandler(
 	tracer telemetry.Tracer,
 	validator *validators.MessageThreadHandlerValidator,
 	service *services.MessageThreadService,
+	heartbeatService *services.HeartbeatService,
+	contactService *services.ContactService,
 ) (h *MessageThreadHandler) {
 	return &MessageThreadHandler{
-		logger:    logger.WithService(fmt.Sprintf("%T", h)),
-		tracer:    tracer,
-		validator: validator,
-		service:   service,
+		logger:           logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:           tracer,
+		validator:        validator,
+		service:          service,
+		heartbeatService: heartbeatService,
+		contactService:   contactService,
 	}
 }
 
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *MessageThreadHandler) RegisterRoutes(router fiber.Router) {
-	router.Get("/message-threads", h.Index)
-	router.Put("/message-threads/:messageThreadID", h.Update)
-	router.Delete("/message-threads/:messageThreadID", h.Delete)
+	router.Get("/message-threads", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+	router.Put("/message-threads/:messageThreadID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Update))
+	router.Put("/message-threads/:messageThreadID/mute", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Mute))
+	router.Delete("/message-threads/:messageThreadID/mute", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Unmute))
+	router.Delete("/message-threads/:messageThreadID", h.requireScope(entities.APIKeyScopeMessagesWrite, h.requireTeamAdmin(h.Delete)))
 }
 
 // Index returns message threads for a phone number
@@ -56,8 +68,12 @@ func (h *MessageThreadHandler) RegisterRoutes(router fiber.Router) {
 // @Param        owner	query  string  	true 	"owner phone number" 						default(+18005550199)
 // @Param        skip	query  int  	false	"number of messages to skip"				minimum(0)
 // @Param        query	query  string  	false 	"filter message threads containing query"
+// @Param        label	query  string  	false 	"filter message threads with a contact which has the label"
 // @Param        limit	query  int  	false	"number of messages to return"				minimum(1)	maximum(20)
+// @Param        If-None-Match	header string  	false	"ETag of the page which the client already has"
 // @Success      200 	{object}	responses.MessageThreadsResponse
+// @Header       200 	{string}	ETag	"weak entity tag which changes when a thread in the page or the liveness of the phone changes"
+// @Success      304 	"the page has not changed since the ETag in the If-None-Match header"
 // @Failure      400	{object}	responses.BadRequest
 // @Failure 	 401    {object}	responses.Unauthorized
 // @Failure      422	{object}	responses.UnprocessableEntity
@@ -84,14 +100,47 @@ func (h *MessageThreadHandler) Index(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching message threads")
 	}
 
-	threads, err := h.service.GetThreads(ctx, request.ToGetParams(h.userIDFomContext(c)))
+	params := request.ToGetParams(h.userIDFomContext(c))
+	threads, err := h.service.GetThreads(ctx, params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot get message threads with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d message %s", len(*threads), h.pluralize("thread", len(*threads))), threads)
+	liveness, err := h.heartbeatService.Liveness(ctx, h.userIDFomContext(c), request.Owner)
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load liveness of phone [%s] for message threads", request.Owner)))
+	}
+
+	contacts := make([]string, 0, len(*threads))
+	for _, thread := range *threads {
+		contacts = append(contacts, thread.Contact)
+	}
+	details := h.contactService.Details(ctx, h.userIDFomContext(c), contacts...)
+
+	versions := []string{h.livenessETag(liveness)}
+	for index, thread := range *threads {
+		(*threads)[index].Phone = liveness
+
+		contactVersion := ""
+		if contact, ok := details[thread.Contact]; ok {
+			(*threads)[index].SetContact(contact)
+			contactVersion = contact.UpdatedAt.UTC().Format(time.RFC3339Nano) + strings.Join(contact.Labels, ",")
+		}
+
+		lastReadAt := ""
+		if thread.LastReadAt != nil {
+			lastReadAt = thread.LastReadAt.UTC().Format(time.RFC3339Nano)
+		}
+		versions = append(versions, thread.ID.String(), thread.UpdatedAt.UTC().Format(time.RFC3339Nano), lastReadAt, strconv.FormatBool(thread.IsMuted), contactVersion)
+	}
+
+	if h.notModified(c, h.pageETag(c, versions)) {
+		return h.responseNotModified(c)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d message %s", len(*threads), h.pluralize("thread", len(*threads))), threads, params.IndexParams, len(*threads))
 }
 
 // Update an entities.MessageThread
@@ -131,12 +180,91 @@ func (h *MessageThreadHandler) Update(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot update message thread with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "message thread updated successfully", thread)
 }
 
+// Mute the notifications of a message thread
+// @Summary      Mute a message thread
+// @Description  Stop the slack, telegram and discord notifications of the messages received in a thread until muted_until or until the thread is unmuted. The messages are still stored and the webhooks still receive them unless mute_webhooks is true.
+// @Security	 ApiKeyAuth
+// @Tags         MessageThreads
+// @Accept       json
+// @Produce      json
+// @Param 		 messageThreadID	path		string 							true 	"ID of the message thread" 			default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   			body 		requests.MessageThreadMute 		true 	"Expiry of the mute"
+// @Success      200 				{object}	responses.MessageThreadResponse
+// @Failure      400				{object}	responses.BadRequest
+// @Failure 	 401    			{object}	responses.Unauthorized
+// @Failure      404				{object}	responses.NotFound
+// @Failure      422				{object}	responses.UnprocessableEntity
+// @Failure      500				{object}	responses.InternalServerError
+// @Router       /message-threads/{messageThreadID}/mute [put]
+func (h *MessageThreadHandler) Mute(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.MessageThreadMute
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.MessageThreadID = c.Params("messageThreadID")
+	if errors := h.validator.ValidateMute(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while muting message thread [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while muting message thread")
+	}
+
+	thread, err := h.service.Mute(ctx, request.ToMuteParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot mute message thread with ID [%s] for user [%s]", request.MessageThreadID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "message thread muted successfully", thread)
+}
+
+// Unmute the notifications of a message thread
+// @Summary      Unmute a message thread
+// @Description  Send the notifications and the webhooks of the messages received in a muted thread again
+// @Security	 ApiKeyAuth
+// @Tags         MessageThreads
+// @Accept       json
+// @Produce      json
+// @Param 		 messageThreadID	path		string 		true 	"ID of the message thread" 			default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200 				{object}	responses.MessageThreadResponse
+// @Failure 	 401    			{object}	responses.Unauthorized
+// @Failure      404				{object}	responses.NotFound
+// @Failure      422				{object}	responses.UnprocessableEntity
+// @Failure      500				{object}	responses.InternalServerError
+// @Router       /message-threads/{messageThreadID}/mute [delete]
+func (h *MessageThreadHandler) Unmute(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	messageThreadID := c.Params("messageThreadID")
+	if errors := h.validator.ValidateUUID(ctx, messageThreadID, "messageThreadID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while unmuting message thread with ID [%s]", spew.Sdump(errors), messageThreadID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while unmuting message thread")
+	}
+
+	thread, err := h.service.Unmute(ctx, h.userIDFomContext(c), uuid.MustParse(messageThreadID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot unmute message thread with ID [%s] for user [%s]", messageThreadID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "message thread unmuted successfully", thread)
+}
+
 // Delete a message thread
 // @Summary      Delete a message thread from the database.
 // @Description  Delete a message thread from the database and also deletes all the messages in the thread.
@@ -171,13 +299,13 @@ func (h *MessageThreadHandler) Delete(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot find thread thread with id [%s]", messageThreadID)
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	if err = h.service.DeleteThread(ctx, c.OriginalURL(), thread); err != nil {
 		msg := fmt.Sprintf("cannot delete thread thread with ID [%s] for user with ID [%s]", messageThreadID, thread.UserID)
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseNoContent(c, "thread thread deleted successfully")
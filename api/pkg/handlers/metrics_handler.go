@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// MetricsHandler renders the metrics which are scraped by Prometheus
+type MetricsHandler struct {
+	handler
+	logger   telemetry.Logger
+	registry *telemetry.MetricsRegistry
+	username string
+	password string
+}
+
+// NewMetricsHandler creates a new MetricsHandler, the metrics can be scraped without credentials when the password is empty
+func NewMetricsHandler(
+	logger telemetry.Logger,
+	registry *telemetry.MetricsRegistry,
+	username string,
+	password string,
+) (h *MetricsHandler) {
+	return &MetricsHandler{
+		logger:   logger.WithService(fmt.Sprintf("%T", h)),
+		registry: registry,
+		username: username,
+		password: password,
+	}
+}
+
+// RegisterRoutes registers the routes for the MetricsHandler
+func (h *MetricsHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/metrics", h.Index)
+}
+
+// Index renders the metrics in the Prometheus text exposition format
+// This is an internal API so no documentation provided
+func (h *MetricsHandler) Index(c *fiber.Ctx) error {
+	if !h.isAuthorized(c) {
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="metrics"`)
+		return h.responseUnauthorized(c)
+	}
+
+	buffer := new(bytes.Buffer)
+	if _, err := h.registry.WriteTo(buffer); err != nil {
+		h.logger.Error(stacktrace.Propagate(err, "cannot render metrics"))
+		return h.responseInternalServerError(c)
+	}
+
+	c.Set(fiber.HeaderContentType, telemetry.MetricsContentType)
+	return c.Send(buffer.Bytes())
+}
+
+// isAuthorized checks the credentials in the basic authorization header
+func (h *MetricsHandler) isAuthorized(c *fiber.Ctx) bool {
+	if h.password == "" {
+		return true
+	}
+
+	header := c.Get(fiber.HeaderAuthorization)
+	if !strings.HasPrefix(header, "Basic ") {
+		return false
+	}
+
+	credentials, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, "Basic "))
+	if err != nil {
+		return false
+	}
+
+	username, password, ok := strings.Cut(string(credentials), ":")
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(h.username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(h.password)) == 1
+}
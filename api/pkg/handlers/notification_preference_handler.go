@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// NotificationPreferenceHandler handles the settings of the email digest of the messages which fail or expire
+type NotificationPreferenceHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.NotificationPreferenceHandlerValidator
+	service   *services.EmailNotificationService
+}
+
+// NewNotificationPreferenceHandler creates a new NotificationPreferenceHandler
+func NewNotificationPreferenceHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.NotificationPreferenceHandlerValidator,
+	service *services.EmailNotificationService,
+) (h *NotificationPreferenceHandler) {
+	return &NotificationPreferenceHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the NotificationPreferenceHandler
+func (h *NotificationPreferenceHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler, middlewares ...fiber.Handler) {
+	router := app.Group("v1/notification-preference")
+	router.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Show))...)
+	router.Put("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Upsert))...)
+	router.Delete("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Delete))...)
+}
+
+// Show returns the notification preference of a user
+// @Summary      Get the notification preference
+// @Description  Get the settings of the email which is sent with the messages of a phone which failed or expired in the last 10 minutes. The default preference is returned when it has not been set.
+// @Security	 ApiKeyAuth
+// @Tags         NotificationPreference
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.NotificationPreferenceResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /notification-preference 	[get]
+func (h *NotificationPreferenceHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	preference, err := h.service.LoadPreference(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the notification preference of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "notification preference fetched successfully", preference)
+}
+
+// Upsert the entities.NotificationPreference of a user
+// @Summary      Set the notification preference
+// @Description  Set the settings of the email which is sent with the messages of a phone which failed or expired in the last 10 minutes. The email is sent when at least threshold messages failed or expired, and the emails which are due in the quiet hours are sent when the quiet hours end.
+// @Security	 ApiKeyAuth
+// @Tags         NotificationPreference
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.NotificationPreferenceUpsert  	true 	"Payload of the notification preference"
+// @Success      200 		{object}	responses.NotificationPreferenceResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /notification-preference 	[put]
+func (h *NotificationPreferenceHandler) Upsert(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.NotificationPreferenceUpsert
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateUpsert(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while setting the notification preference [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while setting the notification preference")
+	}
+
+	preference, err := h.service.UpsertPreference(ctx, request.ToUpsertParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot set the notification preference of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "notification preference saved successfully", preference)
+}
+
+// Delete the notification preference of a user
+// @Summary      Delete the notification preference
+// @Description  Delete the notification preference of the currently authenticated user so that the default preference is used
+// @Security	 ApiKeyAuth
+// @Tags         NotificationPreference
+// @Accept       json
+// @Produce      json
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /notification-preference [delete]
+func (h *NotificationPreferenceHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	if err := h.service.DeletePreference(ctx, h.userIDFomContext(c)); err != nil {
+		msg := fmt.Sprintf("cannot delete the notification preference of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "notification preference deleted successfully")
+}
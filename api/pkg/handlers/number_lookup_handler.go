@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// NumberLookupHandler handles the lookup of the type and carrier of phone numbers
+type NumberLookupHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.NumberLookupHandlerValidator
+	service   *services.NumberLookupService
+}
+
+// NewNumberLookupHandler creates a new NumberLookupHandler
+func NewNumberLookupHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.NumberLookupHandlerValidator,
+	service *services.NumberLookupService,
+) (h *NumberLookupHandler) {
+	return &NumberLookupHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the NumberLookupHandler
+func (h *NumberLookupHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/number-lookup", h.requireScope(entities.APIKeyScopeMessagesRead, h.Show))
+}
+
+// Show looks up a phone number
+// @Summary      Lookup a phone number
+// @Description  Get the type e.g. mobile or landline and the carrier of a phone number, the lookup is cached so a number is not looked up again until the lookup expires.
+// @Security	 ApiKeyAuth
+// @Tags         NumberLookup
+// @Accept       json
+// @Produce      json
+// @Param        phone_number	query  string  	true 	"phone number in the E.164 format" 	default(+18005550100)
+// @Success      200 		{object}	responses.NumberLookupResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /number-lookup 	[get]
+func (h *NumberLookupHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.NumberLookupShow
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateShow(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while looking up phone number [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while looking up phone number")
+	}
+
+	lookup, err := h.service.Lookup(ctx, request.PhoneNumber)
+	if err != nil {
+		msg := fmt.Sprintf("cannot lookup phone number [%s] for user [%s]", telemetry.RedactPhoneNumber(request.PhoneNumber), h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "phone number looked up successfully", lookup)
+}
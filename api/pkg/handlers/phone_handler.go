@@ -3,9 +3,12 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/NdoleStudio/httpsms/pkg/validators"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/google/uuid"
 
 	"github.com/NdoleStudio/httpsms/pkg/services"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
@@ -39,9 +42,12 @@ func NewPhoneHandler(
 
 // RegisterRoutes registers the routes for the PhoneHandler
 func (h *PhoneHandler) RegisterRoutes(router fiber.Router) {
-	router.Get("/phones", h.Index)
-	router.Put("/phones", h.Upsert)
-	router.Delete("/phones/:phoneID", h.Delete)
+	router.Get("/phones", h.requireScope(entities.APIKeyScopePhonesRead, h.Index))
+	router.Put("/phones", h.requireScope(entities.APIKeyScopePhonesWrite, h.Upsert))
+	router.Put("/phones/:phoneID/default", h.requireScope(entities.APIKeyScopePhonesWrite, h.SetDefault))
+	router.Put("/phones/:phoneID/pause", h.requireScope(entities.APIKeyScopePhonesWrite, h.Pause))
+	router.Put("/phones/:phoneID/resume", h.requireScope(entities.APIKeyScopePhonesWrite, h.Resume))
+	router.Delete("/phones/:phoneID", h.requireScope(entities.APIKeyScopePhonesWrite, h.requireTeamAdmin(h.Delete)))
 }
 
 // Index returns the phones of a user
@@ -79,14 +85,15 @@ func (h *PhoneHandler) Index(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching phones")
 	}
 
-	phones, err := h.service.Index(ctx, h.userFromContext(c), request.ToIndexParams())
+	params := request.ToIndexParams()
+	phones, err := h.service.Index(ctx, h.userFromContext(c), params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot index phones with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(*phones), h.pluralize("phone", len(*phones))), phones)
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(*phones), h.pluralize("phone", len(*phones))), phones, params, len(*phones))
 }
 
 // Upsert a phone
@@ -122,27 +129,155 @@ func (h *PhoneHandler) Upsert(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while updating phones")
 	}
 
-	phone, err := h.service.Upsert(ctx, request.ToUpsertParams(h.userFromContext(c), c.OriginalURL()))
+	phone, err := h.service.Upsert(ctx, request.ToUpsertParams(h.userFromContext(c), c.OriginalURL(), c.Get("X-Client-Version")))
 	if err != nil {
 		msg := fmt.Sprintf("cannot update phones with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "phone updated successfully", phone)
 }
 
+// SetDefault makes a phone the default phone of a user
+// @Summary      Set default phone
+// @Description  Makes a phone the default phone used to send messages when the `from` field is omitted. The previous default phone is unset.
+// @Security	 ApiKeyAuth
+// @Tags         Phones
+// @Accept       json
+// @Produce      json
+// @Param 		 phoneID 	path		string 							true 	"ID of the phone"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200 		{object}	responses.PhoneResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /phones/{phoneID}/default [put]
+func (h *PhoneHandler) SetDefault(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	phoneID := c.Params("phoneID")
+	if errors := h.validator.ValidateUUID(ctx, phoneID, "phoneID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while setting default phone [%s]", spew.Sdump(errors), phoneID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while setting default phone")
+	}
+
+	phone, err := h.service.SetDefault(ctx, c.OriginalURL(), h.userIDFomContext(c), uuid.MustParse(phoneID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find phone with ID [%s]", phoneID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot set phone with ID [%s] as default", phoneID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "default phone updated successfully", phone)
+}
+
+// Pause stops outstanding messages from being sent to a phone
+// @Summary      Pause a phone
+// @Description  Freezes the message queue of a phone. New messages are still accepted and stored as pending until the phone is resumed.
+// @Security	 ApiKeyAuth
+// @Tags         Phones
+// @Accept       json
+// @Produce      json
+// @Param 		 phoneID 	path		string 							true 	"ID of the phone"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200 		{object}	responses.PhoneResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /phones/{phoneID}/pause [put]
+func (h *PhoneHandler) Pause(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	phoneID := c.Params("phoneID")
+	if errors := h.validator.ValidateUUID(ctx, phoneID, "phoneID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while pausing phone [%s]", spew.Sdump(errors), phoneID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while pausing phone")
+	}
+
+	phone, err := h.service.Pause(ctx, c.OriginalURL(), h.userIDFomContext(c), uuid.MustParse(phoneID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find phone with ID [%s]", phoneID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot pause phone with ID [%s]", phoneID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "phone paused successfully", phone)
+}
+
+// Resume releases the outstanding messages of a paused phone
+// @Summary      Resume a phone
+// @Description  Resumes sending messages on a paused phone. Pending messages are released in the order in which they were sent.
+// @Security	 ApiKeyAuth
+// @Tags         Phones
+// @Accept       json
+// @Produce      json
+// @Param 		 phoneID 	path		string 							true 	"ID of the phone"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200 		{object}	responses.PhoneResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /phones/{phoneID}/resume [put]
+func (h *PhoneHandler) Resume(c *fiber.Ctx) error {
+	ctx, span := h.tracer.StartFromFiberCtx(c)
+	defer span.End()
+
+	ctxLogger := h.tracer.CtxLogger(h.logger, span)
+
+	phoneID := c.Params("phoneID")
+	if errors := h.validator.ValidateUUID(ctx, phoneID, "phoneID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while resuming phone [%s]", spew.Sdump(errors), phoneID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while resuming phone")
+	}
+
+	phone, err := h.service.Resume(ctx, c.OriginalURL(), h.userIDFomContext(c), uuid.MustParse(phoneID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find phone with ID [%s]", phoneID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot resume phone with ID [%s]", phoneID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "phone resumed successfully", phone)
+}
+
 // Delete a phone
 // @Summary      Delete Phone
-// @Description  Delete a phone that has been sored in the database
+// @Description  Delete a phone together with its heartbeats and settings. Outstanding messages are cancelled unless `keep_pending` is true. Sent and received messages are preserved.
 // @Security	 ApiKeyAuth
 // @Tags         Phones
 // @Accept       json
 // @Produce      json
-// @Param 		 phoneID 	path		string 							true 	"ID of the phone"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param 		 phoneID 		path		string 							true 	"ID of the phone"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param 		 keep_pending 	query		bool 							false 	"keep the outstanding messages of the phone instead of cancelling them"
 // @Success      204		{object}    responses.NoContent
 // @Failure      400		{object}	responses.BadRequest
 // @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 404		{object}	responses.NotFound
 // @Failure      422		{object}	responses.UnprocessableEntity
 // @Failure      500		{object}	responses.InternalServerError
 // @Router       /phones/{phoneID} [delete]
@@ -152,18 +287,29 @@ func (h *PhoneHandler) Delete(c *fiber.Ctx) error {
 
 	ctxLogger := h.tracer.CtxLogger(h.logger, span)
 
-	request := requests.PhoneDelete{PhoneID: c.Params("phoneID")}
-	if errors := h.validator.ValidateDelete(ctx, request); len(errors) != 0 {
+	var request requests.PhoneDelete
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.PhoneID = c.Params("phoneID")
+	if errors := h.validator.ValidateDelete(ctx, request.Sanitize()); len(errors) != 0 {
 		msg := fmt.Sprintf("validation errors [%s], while deleting phone [%+#v]", spew.Sdump(errors), request)
 		ctxLogger.Warn(stacktrace.NewError(msg))
 		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting phone")
 	}
 
-	err := h.service.Delete(ctx, c.OriginalURL(), h.userIDFomContext(c), request.PhoneIDUuid())
+	err := h.service.Delete(ctx, request.ToDeleteParams(h.userIDFomContext(c), c.OriginalURL()))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find phone with ID [%s]", request.PhoneID))
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("cannot delete phones with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "phone deleted successfully", nil)
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// RoutingRuleHandler handles the rules which select the phone that sends a message by the country of the contact
+type RoutingRuleHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.RoutingRuleHandlerValidator
+	service   *services.RoutingRuleService
+}
+
+// NewRoutingRuleHandler creates a new RoutingRuleHandler
+func NewRoutingRuleHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.RoutingRuleHandlerValidator,
+	service *services.RoutingRuleService,
+) (h *RoutingRuleHandler) {
+	return &RoutingRuleHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the RoutingRuleHandler
+func (h *RoutingRuleHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/routing-rules", h.requireScope(entities.APIKeyScopePhonesRead, h.Index))
+	router.Post("/routing-rules", h.requireScope(entities.APIKeyScopePhonesWrite, h.Store))
+	router.Get("/routing-rules/:routingRuleID", h.requireScope(entities.APIKeyScopePhonesRead, h.Show))
+	router.Put("/routing-rules/:routingRuleID", h.requireScope(entities.APIKeyScopePhonesWrite, h.Update))
+	router.Delete("/routing-rules/:routingRuleID", h.requireScope(entities.APIKeyScopePhonesWrite, h.Delete))
+}
+
+// Index returns the routing rules of a user
+// @Summary      Get routing rules of a user
+// @Description  Get all the rules which select the phone that sends a message without a "from" number by the prefix of the contact
+// @Security	 ApiKeyAuth
+// @Tags         RoutingRules
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.RoutingRulesResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /routing-rules 	[get]
+func (h *RoutingRuleHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	rules, err := h.service.Index(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot get routing rules for user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(rules), h.pluralize("routing rule", len(rules))), rules)
+}
+
+// Store creates a routing rule
+// @Summary      Create a routing rule
+// @Description  Send the messages to contacts with a prefix e.g. +44 from the phone with the owner number when the message has no "from" number. The rule with the longest matching prefix is used and a rule with an empty prefix is the default route.
+// @Security	 ApiKeyAuth
+// @Tags         RoutingRules
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.RoutingRuleStore  	true 	"Payload of the routing rule"
+// @Success      200 		{object}	responses.RoutingRuleResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /routing-rules 	[post]
+func (h *RoutingRuleHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.RoutingRuleStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while creating routing rule [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while creating routing rule")
+	}
+
+	rule, err := h.service.Store(ctx, request.ToStoreParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot create routing rule with prefix [%s] for user [%s]", request.Prefix, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "routing rule created successfully", rule)
+}
+
+// Show returns a routing rule of a user
+// @Summary      Get a routing rule
+// @Description  Get a routing rule of the currently authenticated user by ID
+// @Security	 ApiKeyAuth
+// @Tags         RoutingRules
+// @Accept       json
+// @Produce      json
+// @Param 		 routingRuleID	path		string 	true 	"ID of the routing rule" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      200 		{object}	responses.RoutingRuleResponse
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /routing-rules/{routingRuleID} 	[get]
+func (h *RoutingRuleHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	ruleID := c.Params("routingRuleID")
+	if errors := h.validator.ValidateUUID(ctx, ruleID, "routingRuleID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching routing rule with ID [%s]", spew.Sdump(errors), ruleID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching routing rule")
+	}
+
+	rule, err := h.service.Get(ctx, h.userIDFomContext(c), uuid.MustParse(ruleID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load routing rule with ID [%s] for user [%s]", ruleID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "routing rule fetched successfully", rule)
+}
+
+// Update a routing rule
+// @Summary      Update a routing rule
+// @Description  Update the prefix and the owner of a routing rule of the currently authenticated user
+// @Security	 ApiKeyAuth
+// @Tags         RoutingRules
+// @Accept       json
+// @Produce      json
+// @Param 		 routingRuleID	path		string 						true 	"ID of the routing rule" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Param        payload   		body 		requests.RoutingRuleStore  	true 	"Payload of the routing rule"
+// @Success      200 		{object}	responses.RoutingRuleResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /routing-rules/{routingRuleID} 	[put]
+func (h *RoutingRuleHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.RoutingRuleUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params into [%T]", request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.RoutingRuleID = c.Params("routingRuleID")
+	if errors := h.validator.ValidateUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating routing rule [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating routing rule")
+	}
+
+	rule, err := h.service.Update(ctx, request.ToUpdateParams(h.userIDFomContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update routing rule with ID [%s] for user [%s]", request.RoutingRuleID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "routing rule updated successfully", rule)
+}
+
+// Delete a routing rule
+// @Summary      Delete a routing rule
+// @Description  Delete a routing rule of the currently authenticated user, the messages which it routed are not changed.
+// @Security	 ApiKeyAuth
+// @Tags         RoutingRules
+// @Accept       json
+// @Produce      json
+// @Param 		 routingRuleID	path		string 	true 	"ID of the routing rule" 	default(32343a19-da5e-4b1b-a767-3298a73703cb)
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /routing-rules/{routingRuleID} 	[delete]
+func (h *RoutingRuleHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	ruleID := c.Params("routingRuleID")
+	if errors := h.validator.ValidateUUID(ctx, ruleID, "routingRuleID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting routing rule with ID [%s]", spew.Sdump(errors), ruleID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting routing rule")
+	}
+
+	if err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(ruleID)); err != nil {
+		msg := fmt.Sprintf("cannot delete routing rule with ID [%s] for user [%s]", ruleID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "routing rule deleted successfully")
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// ShortLinkHandler handles the short links in the content of outgoing messages
+type ShortLinkHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.ShortLinkHandlerValidator
+	service   *services.ShortLinkService
+	limiter   *services.RateLimiter
+	limit     services.RateLimit
+}
+
+// NewShortLinkHandler creates a new ShortLinkHandler, the redirects of an IP address are limited by the services.RateLimit
+func NewShortLinkHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.ShortLinkHandlerValidator,
+	service *services.ShortLinkService,
+	limiter *services.RateLimiter,
+	limit services.RateLimit,
+) (h *ShortLinkHandler) {
+	return &ShortLinkHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+		limiter:   limiter,
+		limit:     limit,
+	}
+}
+
+// RegisterRoutes registers the routes for the ShortLinkHandler, the redirect is not authenticated
+func (h *ShortLinkHandler) RegisterRoutes(app *fiber.App, router fiber.Router) {
+	app.Get("/s/:code", h.Redirect)
+	router.Get("/messages/:messageID/short-links", h.requireScope(entities.APIKeyScopeMessagesRead, h.Index))
+}
+
+// Redirect redirects a short link to its URL and counts the click
+// @Summary      Redirect a short link
+// @Description  Redirect a short link in the content of a message to the original URL and count the click. The requests of an IP address are rate limited.
+// @Tags         ShortLinks
+// @Param 		 code	path	string 	true 	"code of the short link" 	default(aB3dE6gH)
+// @Success      302 	"redirect to the URL of the short link"
+// @Failure      404		{object}	responses.NotFound
+// @Failure      429		{object}	responses.TooManyRequests
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /s/{code} 	[get]
+func (h *ShortLinkHandler) Redirect(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	result := h.limiter.Take(time.Now().UTC(), services.RateLimitBucket{Key: "short_link:" + c.IP(), Limit: h.limit})
+	if !result.Allowed {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("IP address [%s] exceeded the [%d] requests rate limit of short links", c.IP(), result.Limit)))
+		return h.responseTooManyRequests(c, "You have exceeded the rate limit of short links, try again later.")
+	}
+
+	link, err := h.service.Click(ctx, c.Params("code"))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, "The short link does not exist.")
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot redirect short link with code [%s]", c.Params("code"))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseInternalServerError(c)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(link.URL, fiber.StatusFound)
+}
+
+// Index returns the short links in the content of a message with their clicks
+// @Summary      Get the short links of a message
+// @Description  Get the short links in the content of a message which was sent with shorten_urls and the number of times each of them was clicked
+// @Security	 ApiKeyAuth
+// @Tags         ShortLinks
+// @Accept       json
+// @Produce      json
+// @Param 		 messageID	path		string 	true 	"ID of the message" 	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      200 		{object}	responses.ShortLinksResponse
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /messages/{messageID}/short-links 	[get]
+func (h *ShortLinkHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	messageID := c.Params("messageID")
+	if errors := h.validator.ValidateUUID(ctx, messageID, "messageID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching the short links of message [%s]", spew.Sdump(errors), messageID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching the short links of the message")
+	}
+
+	links, err := h.service.IndexByMessage(ctx, h.userIDFomContext(c), uuid.MustParse(messageID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the short links of message [%s] for user [%s]", messageID, h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(links), h.pluralize("short link", len(links))), links)
+}
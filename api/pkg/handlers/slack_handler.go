@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// SlackHandler handles the slack integrations of a user
+type SlackHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.SlackHandlerValidator
+	service   *services.SlackService
+}
+
+// NewSlackHandler creates a new SlackHandler
+func NewSlackHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.SlackHandlerValidator,
+	service *services.SlackService,
+) (h *SlackHandler) {
+	return &SlackHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the SlackHandler
+func (h *SlackHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler, middlewares ...fiber.Handler) {
+	router := app.Group("v1/slack-integrations")
+	router.Post("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Store))...)
+	router.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Index))...)
+	router.Delete("/:slackID", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Delete))...)
+	router.Put("/:slackID", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Update))...)
+}
+
+// Index returns the slack integrations of a user
+// @Summary      Get slack integrations of a user
+// @Description  Get the slack integrations of a user, the bot tokens are never returned
+// @Security	 ApiKeyAuth
+// @Tags         SlackIntegration
+// @Accept       json
+// @Produce      json
+// @Param        skip		query  int  	false	"number of slack integrations to skip"		minimum(0)
+// @Param        query		query  string  	false 	"filter slack integrations containing query"
+// @Param        limit		query  int  	false	"number of slack integrations to return"	minimum(1)	maximum(20)
+// @Success      200 		{object}	responses.SlacksResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /slack-integrations 	[get]
+func (h *SlackHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.SlackIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching slack integrations [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching slack integrations")
+	}
+
+	params := request.ToIndexParams()
+	slackIntegrations, err := h.service.Index(ctx, h.userIDFomContext(c), params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get slack integrations with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d slack %s", len(slackIntegrations), h.pluralize("integration", len(slackIntegrations))), slackIntegrations, params, len(slackIntegrations))
+}
+
+// Delete a slack integration
+// @Summary      Delete slack integration
+// @Description  Delete a slack integration and its threads for a user
+// @Security	 ApiKeyAuth
+// @Tags         SlackIntegration
+// @Accept       json
+// @Produce      json
+// @Param 		 slackID 	path		string 				true 	"ID of the slack integration"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      204		{object}    responses.NoContent
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /slack-integrations/{slackID} [delete]
+func (h *SlackHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	slackID := c.Params("slackID")
+	if errors := h.validator.ValidateUUID(ctx, slackID, "slackID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting slack integration with ID [%s]", spew.Sdump(errors), slackID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting slack integration")
+	}
+
+	err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(slackID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete slack integration with ID [%+#v]", slackID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "slack integration deleted successfully", nil)
+}
+
+// Update an entities.Slack
+// @Summary      Update a slack integration
+// @Description  Update a slack integration for the currently authenticated user. The bot token must be sent again when the messages are posted with a bot.
+// @Security	 ApiKeyAuth
+// @Tags         SlackIntegration
+// @Accept       json
+// @Produce      json
+// @Param 		 slackID	path		string 							true 	"ID of the slack integration" 					default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.SlackUpdate  			true 	"Payload of slack integration to update"
+// @Success      200 		{object}	responses.SlackResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /slack-integrations/{slackID} 	[put]
+func (h *SlackHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.SlackUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.SlackID = c.Params("slackID")
+	if errors := h.validator.ValidateUpdate(ctx, h.userIDFomContext(c), request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating slack integration [%s]", spew.Sdump(errors), request.SlackID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating slack integration")
+	}
+
+	slackIntegration, err := h.service.Update(ctx, request.ToUpdateParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update slack integration with ID [%s]", request.SlackID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "slack integration updated successfully", slackIntegration)
+}
+
+// Store an entities.Slack
+// @Summary      Store slack integration
+// @Description  Store a slack integration which posts the messages received by a phone to slack with an incoming webhook URL or a bot token and channel. The messages from a contact are posted in one thread when thread_by_contact is set, which requires a bot token.
+// @Security	 ApiKeyAuth
+// @Tags         SlackIntegration
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.SlackStore  		true "Payload of the slack integration request"
+// @Success      201 		{object}	responses.SlackResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /slack-integrations [post]
+func (h *SlackHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.SlackStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall body [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, h.userIDFomContext(c), request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while storing slack integration [%s]", spew.Sdump(errors), request.Name)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while storing slack integration")
+	}
+
+	slackIntegration, err := h.service.Store(ctx, request.ToStoreParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot store slack integration [%s]", request.Name)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseCreated(c, "slack integration created successfully", slackIntegration)
+}
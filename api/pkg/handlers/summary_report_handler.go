@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// SummaryReportHandler handles the schedule of the email with the summary of the messages of a user
+type SummaryReportHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.SummaryReportHandlerValidator
+	service   *services.SummaryReportService
+}
+
+// NewSummaryReportHandler creates a new SummaryReportHandler
+func NewSummaryReportHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.SummaryReportHandlerValidator,
+	service *services.SummaryReportService,
+) (h *SummaryReportHandler) {
+	return &SummaryReportHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the SummaryReportHandler
+func (h *SummaryReportHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler, middlewares ...fiber.Handler) {
+	router := app.Group("v1/summary-report")
+	router.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Show))...)
+	router.Put("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Upsert))...)
+	router.Delete("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Delete))...)
+}
+
+// Show returns the summary report schedule of a user
+// @Summary      Get the summary report schedule
+// @Description  Get the settings of the daily or weekly email with the number of messages which you sent, which were delivered, failed or expired and which you received in the previous period. The default schedule is returned when it has not been set.
+// @Security	 ApiKeyAuth
+// @Tags         SummaryReport
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.SummaryReportScheduleResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /summary-report 	[get]
+func (h *SummaryReportHandler) Show(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	schedule, err := h.service.LoadSchedule(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the summary report schedule of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "summary report schedule fetched successfully", schedule)
+}
+
+// Upsert the entities.SummaryReportSchedule of a user
+// @Summary      Set the summary report schedule
+// @Description  Set the settings of the summary report email. A daily report with the messages of the previous day is sent every day and a weekly report with the messages of the previous week is sent on Monday, at the hour in the timezone. The report of a period without messages is not sent when skip_empty is set.
+// @Security	 ApiKeyAuth
+// @Tags         SummaryReport
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.SummaryReportScheduleUpsert  	true 	"Payload of the summary report schedule"
+// @Success      200 		{object}	responses.SummaryReportScheduleResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /summary-report 	[put]
+func (h *SummaryReportHandler) Upsert(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.SummaryReportScheduleUpsert
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateUpsert(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while setting the summary report schedule [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while setting the summary report schedule")
+	}
+
+	schedule, err := h.service.UpsertSchedule(ctx, request.ToUpsertParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot set the summary report schedule of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "summary report schedule saved successfully", schedule)
+}
+
+// Delete the summary report schedule of a user
+// @Summary      Delete the summary report schedule
+// @Description  Delete the summary report schedule of the currently authenticated user so that the default schedule is used and no report is sent
+// @Security	 ApiKeyAuth
+// @Tags         SummaryReport
+// @Accept       json
+// @Produce      json
+// @Success      204		{object}    responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /summary-report [delete]
+func (h *SummaryReportHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	if err := h.service.DeleteSchedule(ctx, h.userIDFomContext(c)); err != nil {
+		msg := fmt.Sprintf("cannot delete the summary report schedule of user [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "summary report schedule deleted successfully")
+}
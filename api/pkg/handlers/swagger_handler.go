@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/swagger"
+	"github.com/palantir/stacktrace"
+	"github.com/swaggo/swag"
+)
+
+// SwaggerHandler serves the OpenAPI specification which is generated from the annotations on the handlers
+type SwaggerHandler struct {
+	handler
+	logger telemetry.Logger
+}
+
+// NewSwaggerHandler creates a new SwaggerHandler
+func NewSwaggerHandler(logger telemetry.Logger) (h *SwaggerHandler) {
+	return &SwaggerHandler{
+		logger: logger.WithService(fmt.Sprintf("%T", h)),
+	}
+}
+
+// RegisterRoutes registers the routes for the SwaggerHandler, it has to be called last since it registers the /* route
+func (h *SwaggerHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/swagger.json", h.Spec)
+	router.Get("/docs/*", swagger.New(swagger.Config{URL: "/swagger.json"}))
+	router.Get("/*", swagger.HandlerDefault)
+}
+
+// Spec renders the OpenAPI specification as JSON so that clients can be generated from it
+// This is an internal API so no documentation provided
+func (h *SwaggerHandler) Spec(c *fiber.Ctx) error {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		h.logger.Error(stacktrace.Propagate(err, "cannot read the swagger specification"))
+		return h.responseInternalServerError(c)
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.SendString(doc)
+}
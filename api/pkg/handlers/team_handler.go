@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// TeamHandler handles team requests
+type TeamHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	service   *services.TeamService
+	validator *validators.TeamHandlerValidator
+}
+
+// NewTeamHandler creates a new TeamHandler
+func NewTeamHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.TeamService,
+	validator *validators.TeamHandlerValidator,
+) (h *TeamHandler) {
+	return &TeamHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		service:   service,
+		validator: validator,
+	}
+}
+
+// RegisterRoutes registers the routes for the TeamHandler
+func (h *TeamHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/teams", h.requireUnscopedAPIKey(h.Index))
+	router.Post("/teams/join", h.requireUnscopedAPIKey(h.Join))
+	router.Get("/teams/members", h.requireUnscopedAPIKey(h.IndexMembers))
+	router.Delete("/teams/members/:userID", h.requireUnscopedAPIKey(h.DeleteMember))
+	router.Post("/teams/invitations", h.requireUnscopedAPIKey(h.requireTeamAdmin(h.StoreInvitation)))
+}
+
+// Index returns the teams of a user
+// @Summary      Get teams of a user
+// @Description  Get the teams which the authenticated user can access. The personal team of the user is always the first team, set the x-team-id header to the ID of a team to act on behalf of the team.
+// @Security	 ApiKeyAuth
+// @Tags         Teams
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.TeamsResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /teams 	[get]
+func (h *TeamHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	authUser := h.userFromContext(c)
+	teams, err := h.service.Index(ctx, authUser.ActingUserID(), authUser.Email)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get teams of user [%s]", authUser.ActingUserID())
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(teams), h.pluralize("team", len(teams))), teams)
+}
+
+// IndexMembers returns the members of the acting team
+// @Summary      Get members of a team
+// @Description  Get the members of the team selected with the x-team-id header, the personal team is used when the header is not set.
+// @Security	 ApiKeyAuth
+// @Tags         Teams
+// @Accept       json
+// @Produce      json
+// @Success      200 		{object}	responses.TeamMembersResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /teams/members [get]
+func (h *TeamHandler) IndexMembers(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	members, err := h.service.IndexMembers(ctx, h.userIDFomContext(c))
+	if err != nil {
+		msg := fmt.Sprintf("cannot get members of team [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched %d team %s", len(members), h.pluralize("member", len(members))), members)
+}
+
+// StoreInvitation invites a user to the acting team
+// @Summary      Invite a user to a team
+// @Description  Invite a user by email to the team selected with the x-team-id header. The invitation token is only returned in this response and it expires after 7 days.
+// @Security	 ApiKeyAuth
+// @Tags         Teams
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.TeamInvitationStore  	true "Payload of the invitation request"
+// @Success      201 		{object}	responses.TeamInvitationResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /teams/invitations [post]
+func (h *TeamHandler) StoreInvitation(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.TeamInvitationStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall body [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateInvitationStore(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while storing team invitation [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while inviting user to team")
+	}
+
+	invitation, err := h.service.Invite(ctx, request.ToInviteParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot store team invitation with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseCreated(c, "team invitation created successfully", invitation)
+}
+
+// Join accepts a team invitation
+// @Summary      Join a team
+// @Description  Accept an invitation to a team. The invitation can only be accepted by the user with the email address which was invited.
+// @Security	 ApiKeyAuth
+// @Tags         Teams
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.TeamJoin  		true "Payload of the join request"
+// @Success      200 		{object}	responses.TeamMemberResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /teams/join [post]
+func (h *TeamHandler) Join(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.TeamJoin
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall body [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateJoin(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while joining team [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while joining team")
+	}
+
+	member, err := h.service.Join(ctx, request.ToJoinParams(h.userFromContext(c)))
+	if validationErr, ok := services.AsValidationError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("invalid team invitation for user [%s]", h.userFromContext(c).ActingUserID())))
+		return h.responseValidationError(c, validationErr, "validation errors while joining team")
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot join team for user [%s]", h.userFromContext(c).ActingUserID())
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "team joined successfully", member)
+}
+
+// DeleteMember removes a user from the acting team
+// @Summary      Remove a member from a team
+// @Description  Remove a user from the team selected with the x-team-id header. Only an admin of the team can remove other members, a member can always leave the team.
+// @Security	 ApiKeyAuth
+// @Tags         Teams
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user"	default(WB7DRDWrJZRGbYrv2CKGkqbzvqdC)
+// @Success      204 		{object}	responses.NoContent
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure 	 404    	{object}	responses.NotFound
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /teams/members/{userID} [delete]
+func (h *TeamHandler) DeleteMember(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	authUser := h.userFromContext(c)
+	userID := entities.UserID(c.Params("userID"))
+	if userID != authUser.ActingUserID() && !authUser.IsTeamAdmin() {
+		return h.responseTeamForbidden(c, fmt.Sprintf("Only a team member with the [%s] role can remove other members.", entities.TeamRoleAdmin))
+	}
+
+	err := h.service.DeleteMember(ctx, authUser.ID, userID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find member with ID [%s] in the team", userID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot remove user [%s] from team [%s]", userID, authUser.ID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseNoContent(c, "team member removed successfully")
+}
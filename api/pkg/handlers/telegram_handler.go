@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telegram"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// TelegramHandler handles the telegram integrations of a user and the updates of their telegram bots
+type TelegramHandler struct {
+	handler
+	logger           telemetry.Logger
+	tracer           telemetry.Tracer
+	validator        *validators.TelegramHandlerValidator
+	service          *services.TelegramService
+	messageValidator *validators.MessageHandlerValidator
+	messageService   *services.MessageService
+	billingService   *services.BillingService
+}
+
+// NewTelegramHandler creates a new TelegramHandler
+func NewTelegramHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.TelegramHandlerValidator,
+	service *services.TelegramService,
+	messageValidator *validators.MessageHandlerValidator,
+	messageService *services.MessageService,
+	billingService *services.BillingService,
+) (h *TelegramHandler) {
+	return &TelegramHandler{
+		logger:           logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:           tracer,
+		validator:        validator,
+		service:          service,
+		messageValidator: messageValidator,
+		messageService:   messageService,
+		billingService:   billingService,
+	}
+}
+
+// RegisterRoutes registers the routes for the TelegramHandler
+func (h *TelegramHandler) RegisterRoutes(app *fiber.App, authMiddleware fiber.Handler, middlewares ...fiber.Handler) {
+	router := app.Group("telegram")
+	router.Post("/:telegramID/updates", h.computeRoute(middlewares, h.Receive)...)
+
+	authRouter := app.Group("v1/telegram-integrations")
+	authRouter.Post("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Store))...)
+	authRouter.Get("/", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Index))...)
+	authRouter.Delete("/:telegramID", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Delete))...)
+	authRouter.Put("/:telegramID", h.computeRoute(append(middlewares, authMiddleware), h.requirePrimaryAPIKey(h.Update))...)
+}
+
+// Index returns the telegram integrations of a user
+// @Summary      Get telegram integrations of a user
+// @Description  Get the telegram integrations of a user, the bot tokens are never returned
+// @Security	 ApiKeyAuth
+// @Tags         TelegramIntegration
+// @Accept       json
+// @Produce      json
+// @Param        skip		query  int  	false	"number of telegram integrations to skip"		minimum(0)
+// @Param        query		query  string  	false 	"filter telegram integrations containing query"
+// @Param        limit		query  int  	false	"number of telegram integrations to return"	minimum(1)	maximum(20)
+// @Success      200 		{object}	responses.TelegramsResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /telegram-integrations 	[get]
+func (h *TelegramHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.TelegramIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching telegram integrations [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching telegram integrations")
+	}
+
+	params := request.ToIndexParams()
+	telegramIntegrations, err := h.service.Index(ctx, h.userIDFomContext(c), params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get telegram integrations with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d telegram %s", len(telegramIntegrations), h.pluralize("integration", len(telegramIntegrations))), telegramIntegrations, params, len(telegramIntegrations))
+}
+
+// Delete a telegram integration
+// @Summary      Delete telegram integration
+// @Description  Delete a telegram integration for a user and the webhook of its telegram bot
+// @Security	 ApiKeyAuth
+// @Tags         TelegramIntegration
+// @Accept       json
+// @Produce      json
+// @Param 		 telegramID 	path		string 				true 	"ID of the telegram integration"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Success      204		{object}    responses.NoContent
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /telegram-integrations/{telegramID} [delete]
+func (h *TelegramHandler) Delete(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	telegramID := c.Params("telegramID")
+	if errors := h.validator.ValidateUUID(ctx, telegramID, "telegramID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while deleting telegram integration with ID [%s]", spew.Sdump(errors), telegramID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while deleting telegram integration")
+	}
+
+	err := h.service.Delete(ctx, h.userIDFomContext(c), uuid.MustParse(telegramID))
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete telegram integration with ID [%+#v]", telegramID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "telegram integration deleted successfully", nil)
+}
+
+// Update an entities.Telegram
+// @Summary      Update a telegram integration
+// @Description  Update a telegram integration for the currently authenticated user, the webhook of the telegram bot is set again when the bot token changes.
+// @Security	 ApiKeyAuth
+// @Tags         TelegramIntegration
+// @Accept       json
+// @Produce      json
+// @Param 		 telegramID	path		string 							true 	"ID of the telegram integration" 					default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.TelegramUpdate  			true 	"Payload of telegram integration to update"
+// @Success      200 		{object}	responses.TelegramResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      404		{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /telegram-integrations/{telegramID} 	[put]
+func (h *TelegramHandler) Update(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.TelegramUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	request.TelegramID = c.Params("telegramID")
+	if errors := h.validator.ValidateUpdate(ctx, h.userIDFomContext(c), request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating telegram integration [%s]", spew.Sdump(errors), request.TelegramID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating telegram integration")
+	}
+
+	telegramIntegration, err := h.service.Update(ctx, request.ToUpdateParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot update telegram integration with ID [%s]", request.TelegramID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "telegram integration updated successfully", telegramIntegration)
+}
+
+// Store an entities.Telegram
+// @Summary      Store telegram integration
+// @Description  Store a telegram integration which forwards the messages received by a phone to a telegram chat. The replies to the forwarded messages in the chat are sent as SMS to their senders, the updates from other chats are ignored.
+// @Security	 ApiKeyAuth
+// @Tags         TelegramIntegration
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.TelegramStore  		true "Payload of the telegram integration request"
+// @Success      201 		{object}	responses.TelegramResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /telegram-integrations [post]
+func (h *TelegramHandler) Store(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.TelegramStore
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall body [%s] into [%T]", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateStore(ctx, h.userIDFomContext(c), request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while storing telegram integration [%s]", spew.Sdump(errors), request.Name)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while storing telegram integration")
+	}
+
+	telegramIntegration, err := h.service.Store(ctx, request.ToStoreParams(h.userFromContext(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot store telegram integration [%s]", request.Name)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseCreated(c, "telegram integration created successfully", telegramIntegration)
+}
+
+// Receive handles an update of a telegram bot
+// @Summary      Receive a telegram update
+// @Description  Send the reply to a forwarded message in the chat of a telegram integration as an SMS to the sender of the forwarded message. The update must have the secret token of the webhook in the X-Telegram-Bot-Api-Secret-Token header.
+// @Tags         TelegramIntegration
+// @Accept       json
+// @Produce      json
+// @Param 		 telegramID	path		string 				true 	"ID of the telegram integration"	default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		telegram.Update  	true 	"Update of the telegram bot"
+// @Success      204		{object}    responses.NoContent
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /telegram/{telegramID}/updates [post]
+func (h *TelegramHandler) Receive(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	telegramID := c.Params("telegramID")
+	if errors := h.validator.ValidateUUID(ctx, telegramID, "telegramID"); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while receiving update of telegram integration [%s]", spew.Sdump(errors), telegramID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while receiving telegram update")
+	}
+
+	var update telegram.Update
+	if err := c.BodyParser(&update); err != nil {
+		msg := fmt.Sprintf("cannot marshall body [%s] into [%T]", telemetry.RedactContent(string(c.Body())), update)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	reply, err := h.service.ResolveReply(ctx, uuid.MustParse(telegramID), c.Get(telegram.SecretTokenHeader), &update)
+	if stacktrace.GetCode(err) == services.ErrCodeTelegramUnauthorized {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot authenticate update [%d] of telegram integration [%s]", update.UpdateID, telegramID)))
+		return h.responseUnauthorized(c)
+	}
+
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot resolve the reply of update [%d] of telegram integration [%s]", update.UpdateID, telegramID)))
+		return h.responseServiceError(c, err)
+	}
+
+	if reply != nil {
+		h.sendReply(c, ctxLogger, reply)
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// sendReply sends a reply in a telegram chat as an SMS, the errors are sent back to the chat
+func (h *TelegramHandler) sendReply(c *fiber.Ctx, ctxLogger telemetry.Logger, reply *services.TelegramReply) {
+	ctx := c.UserContext()
+	userID := reply.Telegram.UserID
+
+	request := requests.MessageSend{
+		From:    reply.Telegram.Owner,
+		To:      reply.Contact,
+		Content: reply.Message.Text,
+	}
+
+	if errors := h.messageValidator.ValidateMessageSend(ctx, userID, request); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while sending telegram reply [%d]", spew.Sdump(errors), reply.Message.MessageID)))
+		var messages []string
+		for _, value := range errors {
+			messages = append(messages, value...)
+		}
+		h.service.Notify(ctx, reply.Telegram, reply.Message.MessageID, fmt.Sprintf("⚠️ %s", strings.Join(messages, "\n")))
+		return
+	}
+
+	if msg := h.billingService.IsEntitled(ctx, userID); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't send a [telegram] message", userID)))
+		h.service.Notify(ctx, reply.Telegram, reply.Message.MessageID, fmt.Sprintf("⚠️ %s", *msg))
+		return
+	}
+
+	request.Sanitize()
+	message, err := h.messageService.SendMessage(ctx, request.ToMessageSendParams(userID, c.OriginalURL()))
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] exceeded the daily message limit", userID)))
+		h.service.Notify(ctx, reply.Telegram, reply.Message.MessageID, fmt.Sprintf("⚠️ %s", limitErr.Error()))
+		return
+	}
+
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send telegram reply [%d] of integration [%s]", reply.Message.MessageID, reply.Telegram.ID)))
+		h.service.Notify(ctx, reply.Telegram, reply.Message.MessageID, "⚠️ The SMS cannot be sent, try again later")
+		return
+	}
+
+	h.service.HandleReplySent(ctx, reply, message)
+}
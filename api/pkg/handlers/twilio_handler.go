@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// TwilioHandler handles the Twilio compatible API, the errors are rendered like the errors of the Twilio REST API
+type TwilioHandler struct {
+	handler
+	logger         telemetry.Logger
+	tracer         telemetry.Tracer
+	validator      *validators.TwilioHandlerValidator
+	billingService *services.BillingService
+	service        *services.TwilioService
+}
+
+// NewTwilioHandler creates a new TwilioHandler
+func NewTwilioHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.TwilioHandlerValidator,
+	billingService *services.BillingService,
+	service *services.TwilioService,
+) (h *TwilioHandler) {
+	return &TwilioHandler{
+		logger:         logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:         tracer,
+		validator:      validator,
+		billingService: billingService,
+		service:        service,
+	}
+}
+
+// RegisterRoutes registers the routes for the TwilioHandler
+func (h *TwilioHandler) RegisterRoutes(app *fiber.App, middlewares ...fiber.Handler) {
+	router := app.Group(fmt.Sprintf("/%s/Accounts/:accountSid", twilio.APIVersion))
+	router.Post("/Messages.json", h.computeRoute(middlewares, h.Send)...)
+}
+
+// Send a message with the Twilio compatible API
+// @Summary      Send an SMS message with the Twilio compatible API
+// @Description  Add an SMS message to be sent by the android phone using the form of the Twilio Messages API. Use any account SID as the username of the basic authentication and your API key as the password. The SID of the message is its httpSMS ID, and the status callbacks of the message are sent to the StatusCallback URL.
+// @Security	 BasicAuth
+// @Tags         Twilio
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        accountSid  	path 		string 		true 	"Account SID which is sent back in the status callbacks"
+// @Param        To  			formData 	string 		true 	"Phone number which receives the message"
+// @Param        From  			formData 	string 		true 	"Phone number of your android phone which sends the message"
+// @Param        Body  			formData 	string 		true 	"Content of the message"
+// @Param        StatusCallback	formData 	string 		false 	"URL which receives the status callbacks of the message"
+// @Success      201  {object}  twilio.Message
+// @Failure      400  {object}  twilio.Error
+// @Failure      401  {object}  twilio.Error
+// @Failure      402  {object}  twilio.Error
+// @Failure      403  {object}  twilio.Error
+// @Failure      429  {object}  twilio.Error
+// @Failure      500  {object}  twilio.Error
+// @Router       /2010-04-01/Accounts/{accountSid}/Messages.json [post]
+func (h *TwilioHandler) Send(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	user, ok := c.Locals(middlewares.ContextKeyAuthUserID).(entities.AuthUser)
+	if !ok || user.IsNoop() {
+		return h.responseTwilioError(c, twilio.NewError(http.StatusUnauthorized, twilio.ErrorCodeAuthenticate, "Authenticate"))
+	}
+
+	if !user.HasScope(entities.APIKeyScopeMessagesSend) {
+		msg := fmt.Sprintf("The API key does not have the [%s] scope", entities.APIKeyScopeMessagesSend)
+		return h.responseTwilioError(c, twilio.NewError(http.StatusForbidden, twilio.ErrorCodeAuthenticate, msg))
+	}
+
+	var request requests.TwilioMessageSend
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall [%s] into %T", c.Body(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseTwilioError(c, twilio.NewError(http.StatusBadRequest, twilio.ErrorCodeInvalidParameter, "The request isn't properly formed"))
+	}
+
+	if errors := h.validator.ValidateMessageSend(ctx, user.ID, request); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while sending twilio payload [%s]", spew.Sdump(errors), c.Body())
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseTwilioError(c, twilio.NewValidationError(errors))
+	}
+
+	request.Sanitize()
+
+	if msg := h.billingService.IsEntitled(ctx, user.ID); msg != nil {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user with ID [%s] can't send a [twilio] message", user.ID)))
+		return h.responseTwilioError(c, twilio.NewError(http.StatusPaymentRequired, twilio.ErrorCodeAccountNotActive, *msg))
+	}
+
+	message, err := h.service.Send(ctx, request.ToTwilioSendParams(user.ID, c.Params("accountSid"), c.OriginalURL()))
+	if limitErr, ok := services.AsDailyMessageLimitExceededError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] exceeded the daily message limit", user.ID)))
+		return h.responseTwilioError(c, twilio.NewError(http.StatusTooManyRequests, twilio.ErrorCodeTooManyRequests, limitErr.Error()))
+	}
+
+	if validationErr, ok := services.AsValidationError(err); ok {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user with ID [%s] cannot send a twilio message with payload [%s]", user.ID, c.Body())))
+		return h.responseTwilioError(c, twilio.NewValidationError(validationErr.Errors))
+	}
+
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send twilio message with payload [%s]", c.Body())))
+		return h.responseTwilioError(c, twilio.NewError(http.StatusInternalServerError, twilio.ErrorCodeInternal, "We ran into an internal error while handling the request."))
+	}
+
+	return c.Status(http.StatusCreated).JSON(twilio.NewMessage(c.Params("accountSid"), message))
+}
+
+func (h *TwilioHandler) responseTwilioError(c *fiber.Ctx, err twilio.Error) error {
+	return c.Status(err.Status).JSON(err)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// TwilioImportHandler handles the imports of the history of messages which were sent and received with Twilio
+type TwilioImportHandler struct {
+	handler
+	logger    telemetry.Logger
+	tracer    telemetry.Tracer
+	validator *validators.TwilioHandlerValidator
+	service   *services.TwilioImportService
+}
+
+// NewTwilioImportHandler creates a new TwilioImportHandler
+func NewTwilioImportHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.TwilioHandlerValidator,
+	service *services.TwilioImportService,
+) (h *TwilioImportHandler) {
+	return &TwilioImportHandler{
+		logger:    logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:    tracer,
+		validator: validator,
+		service:   service,
+	}
+}
+
+// RegisterRoutes registers the routes for the TwilioImportHandler
+func (h *TwilioImportHandler) RegisterRoutes(router fiber.Router) {
+	router.Post("/messages/import/twilio", h.requireScope(entities.APIKeyScopeMessagesWrite, h.Import))
+}
+
+// Import stores the messages of a Twilio export
+// @Summary      Import messages from Twilio
+// @Description  Import the history of your messages from the CSV file which is exported from the message logs of the Twilio console or from the JSON response of the list messages endpoint of the Twilio REST API. The file is the body of the request and it is read one row at a time so it can be larger than the other requests. The imported messages keep their Twilio dates, no webhooks or notifications are sent for them, and the Twilio SID of each message is stored in its metadata so a file which is imported again only adds the new messages. Outgoing messages which were still queued or sending in Twilio are not imported.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Accept       text/csv
+// @Accept       json
+// @Produce      json
+// @Param        format		query  		string  	false 	"format of the export"					Enums(csv, json) default(csv)
+// @Param        owner		query  		string  	false 	"phone number which owns all the imported messages instead of the Twilio phone number"	default(+18005550199)
+// @Param        payload   	body 		string  	true 	"the CSV or JSON export of your Twilio messages"
+// @Success      200 		{object}	responses.TwilioImportResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /messages/import/twilio [post]
+func (h *TwilioImportHandler) Import(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.TwilioImport
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateImport(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while importing a Twilio export [%s]", spew.Sdump(errors), c.OriginalURL())
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		c.Context().SetConnectionClose()
+		return h.responseUnprocessableEntity(c, errors, "validation errors while importing the Twilio export")
+	}
+
+	result, err := h.service.Import(ctx, request.ToImportParams(h.userIDFomContext(c), h.bodyReader(c)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot import the %s Twilio export of user [%s]", request.Format, h.userIDFomContext(c))
+		if _, ok := services.AsValidationError(err); ok {
+			ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		} else {
+			ctxLogger.Error(stacktrace.Propagate(err, msg))
+		}
+		// the rest of the body is not read so the connection cannot be reused
+		c.Context().SetConnectionClose()
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("imported %d of the messages in the Twilio export", result.Imported), result)
+}
+
+// bodyReader reads the body of the request as a stream when the app streams the request bodies
+func (h *TwilioImportHandler) bodyReader(c *fiber.Ctx) io.Reader {
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.Body())
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/NdoleStudio/httpsms/pkg/validators"
 	"github.com/davecgh/go-spew/spew"
@@ -39,16 +41,24 @@ func NewUserHandler(
 
 // RegisterRoutes registers the routes for the MessageHandler
 func (h *UserHandler) RegisterRoutes(router fiber.Router) {
-	router.Get("/users/me", h.Show)
-	router.Put("/users/me", h.Update)
-	router.Put("/users/:userID/notifications", h.UpdateNotifications)
-	router.Get("/users/subscription-update-url", h.subscriptionUpdateURL)
-	router.Delete("/users/subscription", h.cancelSubscription)
+	router.Get("/users/me", h.requirePrimaryAPIKey(h.Show))
+	router.Put("/users/me", h.requirePrimaryAPIKey(h.Update))
+	router.Put("/users/me/api-key", h.requirePrimaryAPIKey(h.RotateAPIKey))
+	router.Put("/users/:userID/notifications", h.requirePrimaryAPIKey(h.UpdateNotifications))
+	router.Put("/users/:userID/failover", h.requirePrimaryAPIKey(h.UpdateFailover))
+	router.Put("/users/:userID/retention", h.requirePrimaryAPIKey(h.UpdateRetention))
+	router.Put("/users/:userID/retry-policy", h.requirePrimaryAPIKey(h.UpdateRetryPolicy))
+	router.Get("/users/subscription-update-url", h.requirePrimaryAPIKey(h.subscriptionUpdateURL))
+	router.Delete("/users/subscription", h.requirePrimaryAPIKey(h.cancelSubscription))
+	router.Get("/admin/users", h.requireAdmin(h.Index))
+	router.Get("/admin/users/:userID", h.requireAdmin(h.ShowSummary))
+	router.Post("/admin/users/:userID/suspension", h.requireAdmin(h.Suspend))
+	router.Delete("/admin/users/:userID/suspension", h.requireAdmin(h.Reinstate))
 }
 
 // Show returns an entities.User
 // @Summary      Get current user
-// @Description  Get details of the currently authenticated user
+// @Description  Get details of the currently authenticated user. The API key is only returned when the user is created, it is stored as a hash.
 // @Security	 ApiKeyAuth
 // @Tags         Users
 // @Accept       json
@@ -71,7 +81,7 @@ func (h *UserHandler) Show(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot get user with ID [%s]", authUser.ID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "user fetched successfully", user)
@@ -114,12 +124,55 @@ func (h *UserHandler) Update(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot update user with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "user updated successfully", user)
 }
 
+// RotateAPIKey revokes the API key of an entities.User
+// @Summary      Rotate the API key
+// @Description  Generates a new API key for the currently authenticated user. The new API key is only returned in this response since it is stored as a hash. The previous API key is still valid during the grace period, it is revoked immediately when the grace period is 0.
+// @Security	 ApiKeyAuth
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        payload   	body 		requests.UserAPIKeyRotate	true 	"Grace period of the previous API key"
+// @Success      200 		{object}	responses.UserResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /users/me/api-key [put]
+func (h *UserHandler) RotateAPIKey(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.UserAPIKeyRotate
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+			ctxLogger.Warn(stacktrace.Propagate(err, msg))
+			return h.responseBadRequest(c, err)
+		}
+	}
+
+	if errors := h.validator.ValidateAPIKeyRotate(ctx, request); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while rotating API key [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while rotating API key")
+	}
+
+	user, err := h.service.RotateAPIKey(ctx, request.ToUserRotateAPIKeyParams(h.userIDFomContext(c), c.OriginalURL(), c.IP(), c.Get(fiber.HeaderUserAgent)))
+	if err != nil {
+		msg := fmt.Sprintf("cannot rotate API key for user with ID [%s]", h.userIDFomContext(c))
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "API key rotated successfully", user)
+}
+
 // UpdateNotifications an entities.User
 // @Summary      Update notification settings
 // @Description  Update the email notification settings for a user
@@ -148,16 +201,148 @@ func (h *UserHandler) UpdateNotifications(c *fiber.Ctx) error {
 		return h.responseBadRequest(c, err)
 	}
 
+	if errors := h.validator.ValidateNotificationUpdate(ctx, request); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating user notifications [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating user notifications")
+	}
+
 	user, err := h.service.UpdateNotificationSettings(ctx, h.userIDFomContext(c), request.ToUserNotificationUpdateParams())
 	if err != nil {
 		msg := fmt.Sprintf("cannot update notification for [%T] with ID [%s]", user, h.userIDFomContext(c))
 		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "user notification settings updated successfully", user)
 }
 
+// UpdateFailover an entities.User
+// @Summary      Update failover settings
+// @Description  Update the phone which takes over the pending messages of a phone when it goes offline
+// @Security	 ApiKeyAuth
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user to update" 				default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.UserFailoverUpdate		true 	"User failover details to update"
+// @Success      200 		{object}	responses.UserResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /users/{userID}/failover [put]
+func (h *UserHandler) UpdateFailover(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.UserFailoverUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateFailoverUpdate(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating user failover [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating user failover")
+	}
+
+	user, err := h.service.UpdateFailoverSettings(ctx, h.userIDFomContext(c), request.ToUserFailoverUpdateParams())
+	if err != nil {
+		msg := fmt.Sprintf("cannot update failover for [%T] with ID [%s]", user, h.userIDFomContext(c))
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "user failover settings updated successfully", user)
+}
+
+// UpdateRetention an entities.User
+// @Summary      Update data retention settings
+// @Description  Update the number of days after which the received and sent messages of a user are deleted. Use 0 to keep the messages.
+// @Security	 ApiKeyAuth
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user to update" 				default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.UserRetentionUpdate	true 	"User retention details to update"
+// @Success      200 		{object}	responses.UserResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /users/{userID}/retention [put]
+func (h *UserHandler) UpdateRetention(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.UserRetentionUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateRetentionUpdate(ctx, request); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating user retention [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating user retention")
+	}
+
+	user, err := h.service.UpdateRetentionSettings(ctx, h.userIDFomContext(c), request.ToUserRetentionUpdateParams())
+	if err != nil {
+		msg := fmt.Sprintf("cannot update retention for [%T] with ID [%s]", user, h.userIDFomContext(c))
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "user retention settings updated successfully", user)
+}
+
+// UpdateRetryPolicy an entities.User
+// @Summary      Update the retry policy
+// @Description  Update how many times the messages of a user are sent to the phone and how long to wait before an expired message is sent again. The policy is applied to new messages, a field which is 0 uses the setting of the phone or the default.
+// @Security	 ApiKeyAuth
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user to update" 				default(32343a19-da5e-4b1b-a767-3298a73703ca)
+// @Param        payload   	body 		requests.UserRetryPolicyUpdate	true 	"Retry policy to update"
+// @Success      200 		{object}	responses.UserResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /users/{userID}/retry-policy [put]
+func (h *UserHandler) UpdateRetryPolicy(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.UserRetryPolicyUpdate
+	if err := c.BodyParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall params [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateRetryPolicyUpdate(ctx, request); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while updating user retry policy [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while updating user retry policy")
+	}
+
+	user, err := h.service.UpdateRetryPolicy(ctx, h.userIDFomContext(c), request.ToUserRetryPolicyUpdateParams())
+	if err != nil {
+		msg := fmt.Sprintf("cannot update retry policy for [%T] with ID [%s]", user, h.userIDFomContext(c))
+		ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "user retry policy updated successfully", user)
+}
+
 // subscriptionUpdateURL returns the subscription update URL for the authenticated entities.User
 // @Summary      Currently authenticated user subscription update URL
 // @Description  Fetches the subscription URL of the authenticated user.
@@ -181,7 +366,7 @@ func (h *UserHandler) subscriptionUpdateURL(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot get user with ID [%s]", authUser.ID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "Subscription update URL fetched successfully", url)
@@ -210,8 +395,155 @@ func (h *UserHandler) cancelSubscription(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot get user with ID [%s]", authUser.ID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseNoContent(c, "Subscription cancelled successfully")
 }
+
+// Index returns the summaries of users
+// @Summary      Get users
+// @Description  Get the users of this instance with the number of phones and messages of each user. This endpoint can only be used by an administrator.
+// @Security	 ApiKeyAuth
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        skip		query  int  	false	"number of users to skip"		minimum(0)
+// @Param        query		query  string  	false 	"filter users with an email containing query"
+// @Param        limit		query  int  	false	"number of users to return"	minimum(1)	maximum(100)
+// @Success      200 		{object}	responses.UserSummariesResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /admin/users [get]
+func (h *UserHandler) Index(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.UserIndex
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateIndex(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching users [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching users")
+	}
+
+	params := request.ToIndexParams()
+	summaries, err := h.service.Index(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot get users with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(summaries), h.pluralize("user", len(summaries))), summaries, params, len(summaries))
+}
+
+// ShowSummary returns the summary of a user
+// @Summary      Get a user
+// @Description  Get the number of phones and messages of a user. This endpoint can only be used by an administrator.
+// @Security	 ApiKeyAuth
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user"	default(WB7DRDWrJZRGbYrv2CKGkqbzvqdC)
+// @Success      200 		{object}	responses.UserSummaryResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure 	 404    	{object}	responses.NotFound
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /admin/users/{userID} [get]
+func (h *UserHandler) ShowSummary(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	userID := c.Params("userID")
+
+	summary, err := h.service.GetSummary(ctx, entities.UserID(userID))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find user with ID [%s]", userID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot get summary of user with ID [%s]", userID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, "user fetched successfully", summary)
+}
+
+// Suspend a user
+// @Summary      Suspend a user
+// @Description  Suspend a user so that the API keys of the user are rejected. This endpoint can only be used by an administrator.
+// @Security	 ApiKeyAuth
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user"	default(WB7DRDWrJZRGbYrv2CKGkqbzvqdC)
+// @Success      200 		{object}	responses.UserSummaryResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure 	 404    	{object}	responses.NotFound
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /admin/users/{userID}/suspension [post]
+func (h *UserHandler) Suspend(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	userID := c.Params("userID")
+	if errors := h.validator.ValidateSuspend(ctx, h.userFromContext(c), userID); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while suspending user with ID [%s]", spew.Sdump(errors), userID)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while suspending user")
+	}
+
+	return h.setSuspended(c, entities.UserID(userID), true)
+}
+
+// Reinstate a suspended user
+// @Summary      Reinstate a user
+// @Description  Reinstate a suspended user so that the API keys of the user can be used again. This endpoint can only be used by an administrator.
+// @Security	 ApiKeyAuth
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param 		 userID 	path		string 							true 	"ID of the user"	default(WB7DRDWrJZRGbYrv2CKGkqbzvqdC)
+// @Success      200 		{object}	responses.UserSummaryResponse
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure 	 403	    {object}	responses.Forbidden
+// @Failure 	 404    	{object}	responses.NotFound
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /admin/users/{userID}/suspension [delete]
+func (h *UserHandler) Reinstate(c *fiber.Ctx) error {
+	return h.setSuspended(c, entities.UserID(c.Params("userID")), false)
+}
+
+func (h *UserHandler) setSuspended(c *fiber.Ctx, userID entities.UserID, suspended bool) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	summary, err := h.service.Suspend(ctx, userID, suspended)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return h.responseNotFound(c, fmt.Sprintf("cannot find user with ID [%s]", userID))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot set suspended [%t] for user with ID [%s]", suspended, userID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	if suspended {
+		return h.responseOK(c, "user suspended successfully", summary)
+	}
+	return h.responseOK(c, "user reinstated successfully", summary)
+}
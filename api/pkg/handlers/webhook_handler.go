@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 
 	"github.com/NdoleStudio/httpsms/pkg/requests"
@@ -43,10 +44,11 @@ func NewWebhookHandler(
 // RegisterRoutes registers the routes for the WebhookHandler
 func (h *WebhookHandler) RegisterRoutes(app *fiber.App, middlewares ...fiber.Handler) {
 	router := app.Group("/v1/webhooks")
-	router.Get("/", h.computeRoute(middlewares, h.Index)...)
-	router.Post("/", h.computeRoute(middlewares, h.Store)...)
-	router.Put("/:webhookID", h.computeRoute(middlewares, h.Update)...)
-	router.Delete("/:webhookID", h.computeRoute(middlewares, h.Delete)...)
+	router.Get("/", h.computeRoute(middlewares, h.requireScope(entities.APIKeyScopeWebhooksRead, h.Index))...)
+	router.Get("/samples", h.computeRoute(middlewares, h.requireScope(entities.APIKeyScopeWebhooksRead, h.Sample))...)
+	router.Post("/", h.computeRoute(middlewares, h.requireScope(entities.APIKeyScopeWebhooksWrite, h.Store))...)
+	router.Put("/:webhookID", h.computeRoute(middlewares, h.requireScope(entities.APIKeyScopeWebhooksWrite, h.Update))...)
+	router.Delete("/:webhookID", h.computeRoute(middlewares, h.requireScope(entities.APIKeyScopeWebhooksWrite, h.Delete))...)
 }
 
 // Index returns the webhooks of a user
@@ -82,14 +84,57 @@ func (h *WebhookHandler) Index(c *fiber.Ctx) error {
 		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching webhooks")
 	}
 
-	webhooks, err := h.service.Index(ctx, h.userIDFomContext(c), request.ToIndexParams())
+	params := request.ToIndexParams()
+	webhooks, err := h.service.Index(ctx, h.userIDFomContext(c), params)
 	if err != nil {
 		msg := fmt.Sprintf("cannot get webhooks with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
-	return h.responseOK(c, fmt.Sprintf("fetched %d %s", len(webhooks), h.pluralize("webhook", len(webhooks))), webhooks)
+	return h.responsePaginated(c, fmt.Sprintf("fetched %d %s", len(webhooks), h.pluralize("webhook", len(webhooks))), webhooks, params, len(webhooks))
+}
+
+// Sample returns the sample payload of an event
+// @Summary      Get the sample payload of a webhook event
+// @Description  Get the payload which is sent to a webhook for an event in the raw or flat format. The sample has the same values every time so that you can use it to map the fields in no-code tools like Zapier, Make and n8n.
+// @Security	 ApiKeyAuth
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        event				query  string  	true	"event of the sample payload"	default(message.phone.received)
+// @Param        payload_format		query  string  	false 	"format of the sample payload"	Enums(raw, flat)	default(raw)
+// @Success      200 		{object}	responses.WebhookSampleResponse
+// @Failure      400		{object}	responses.BadRequest
+// @Failure 	 401	    {object}	responses.Unauthorized
+// @Failure      422		{object}	responses.UnprocessableEntity
+// @Failure      500		{object}	responses.InternalServerError
+// @Router       /webhooks/samples 	[get]
+func (h *WebhookHandler) Sample(c *fiber.Ctx) error {
+	ctx, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	var request requests.WebhookSample
+	if err := c.QueryParser(&request); err != nil {
+		msg := fmt.Sprintf("cannot marshall URL [%s] into %T", c.OriginalURL(), request)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+		return h.responseBadRequest(c, err)
+	}
+
+	if errors := h.validator.ValidateSample(ctx, request.Sanitize()); len(errors) != 0 {
+		msg := fmt.Sprintf("validation errors [%s], while fetching webhook sample [%+#v]", spew.Sdump(errors), request)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+		return h.responseUnprocessableEntity(c, errors, "validation errors while fetching webhook sample")
+	}
+
+	payload, err := h.service.Sample(ctx, request.Event, request.ToPayloadFormat())
+	if err != nil {
+		msg := fmt.Sprintf("cannot get webhook sample with params [%+#v]", request)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+		return h.responseServiceError(c, err)
+	}
+
+	return h.responseOK(c, fmt.Sprintf("fetched [%s] sample of the [%s] event", request.PayloadFormat, request.Event), payload)
 }
 
 // Delete a webhook
@@ -121,7 +166,7 @@ func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot delete webhook with ID [%+#v]", webhookID)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "webhook deleted successfully", nil)
@@ -175,7 +220,7 @@ func (h *WebhookHandler) Store(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot store webhoook with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseCreated(c, "webhook created successfully", webhook)
@@ -218,7 +263,7 @@ func (h *WebhookHandler) Update(c *fiber.Ctx) error {
 	if err != nil {
 		msg := fmt.Sprintf("cannot update user with params [%+#v]", request)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return h.responseInternalServerError(c)
+		return h.responseServiceError(c, err)
 	}
 
 	return h.responseOK(c, "webhook updated successfully", user)
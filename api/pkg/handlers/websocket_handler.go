@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/davecgh/go-spew/spew"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// websocketWriteWait is the time allowed to write a message to the client
+	websocketWriteWait = 10 * time.Second
+
+	// websocketMaxCommandSize is the maximum size in bytes of a command which is sent by the client
+	websocketMaxCommandSize = 4096
+
+	// websocketSource is the source of the events which are emitted by the commands of the client
+	websocketSource = "/v1/websocket"
+)
+
+// WebsocketHandler handles the websocket connections which receive the live updates of messages and threads and send commands
+type WebsocketHandler struct {
+	handler
+	logger       telemetry.Logger
+	tracer       telemetry.Tracer
+	validator    *validators.MessageThreadHandlerValidator
+	hub          *services.WebsocketHub
+	service      *services.MessageThreadService
+	origins      []string
+	pingInterval time.Duration
+}
+
+// NewWebsocketHandler creates a new WebsocketHandler, the connections from a browser are only accepted from the origins
+func NewWebsocketHandler(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	validator *validators.MessageThreadHandlerValidator,
+	hub *services.WebsocketHub,
+	service *services.MessageThreadService,
+	origins []string,
+	pingInterval time.Duration,
+) (h *WebsocketHandler) {
+	return &WebsocketHandler{
+		logger:       logger.WithService(fmt.Sprintf("%T", h)),
+		tracer:       tracer,
+		validator:    validator,
+		hub:          hub,
+		service:      service,
+		origins:      origins,
+		pingInterval: pingInterval,
+	}
+}
+
+// RegisterRoutes registers the routes for the WebsocketHandler
+func (h *WebsocketHandler) RegisterRoutes(router fiber.Router) {
+	router.Get("/websocket", h.requireScope(entities.APIKeyScopeMessagesRead, h.Upgrade), websocket.New(h.Connect))
+}
+
+// Upgrade checks the websocket handshake before the connection is upgraded
+// @Summary      Connect to the websocket
+// @Description  Open a websocket connection which receives the events of your messages and threads as JSON messages with the `type`, `id` and `data` fields. The connection accepts the `message-thread.read` and `message-thread.typing` commands e.g. `{"type": "message-thread.read", "message_thread_id": "32343a19-da5e-4b1b-a767-3298a73703ca"}`.
+// @Security	 ApiKeyAuth
+// @Tags         Messages
+// @Success      101 		{string}	string	"switching protocols"
+// @Failure 	 401    	{object}	responses.Unauthorized
+// @Failure 	 403    	{object}	responses.Forbidden
+// @Failure 	 426    	{object}	responses.UpgradeRequired
+// @Failure 	 429    	{object}	responses.TooManyRequests
+// @Router       /websocket [get]
+func (h *WebsocketHandler) Upgrade(c *fiber.Ctx) error {
+	_, span, ctxLogger := h.tracer.StartFromFiberCtxWithLogger(c, h.logger)
+	defer span.End()
+
+	if !websocket.IsWebSocketUpgrade(c) {
+		return h.responseError(c, fiber.StatusUpgradeRequired, responses.ErrorCodeUpgradeRequired, "The request must be a websocket handshake", nil)
+	}
+
+	if origin := c.Get(fiber.HeaderOrigin); !h.isAllowedOrigin(origin) {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("websocket connection of user [%s] from origin [%s] is not allowed", h.userIDFomContext(c), origin)))
+		return h.responseForbidden(c)
+	}
+
+	if connections := h.hub.Connections(h.userIDFomContext(c)); connections >= h.hub.MaxConnections() {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("user [%s] already has [%d] websocket connections", h.userIDFomContext(c), connections)))
+		return h.responseTooManyRequests(c, "you have opened too many websocket connections, close a connection and try again")
+	}
+
+	return c.Next()
+}
+
+// Connect sends the messages of the services.WebsocketHub to the connection and handles the commands of the client until the connection is closed
+func (h *WebsocketHandler) Connect(conn *websocket.Conn) {
+	authUser, _ := conn.Locals(middlewares.ContextKeyAuthUserID).(entities.AuthUser)
+
+	client, err := h.hub.Register(authUser.ID)
+	if err != nil {
+		h.logger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot register websocket connection of user [%s]", authUser.ID)))
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many connections"), time.Now().Add(websocketWriteWait))
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.write(conn, client)
+	}()
+
+	h.read(conn, client)
+
+	// the connection is released when Connect returns so the writer must stop before that
+	h.hub.Unregister(client)
+	<-done
+}
+
+// write sends the messages of the client and the pings which keep the connection alive, the connection is closed when it returns so that read stops
+func (h *WebsocketHandler) write(conn *websocket.Conn, client *services.WebsocketClient) {
+	ticker := time.NewTicker(h.pingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.Messages():
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "connection closed"), time.Now().Add(websocketWriteWait))
+				return
+			}
+
+			_ = conn.SetWriteDeadline(time.Now().Add(websocketWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// read handles the commands of the client, a connection which does not answer the pings is closed
+func (h *WebsocketHandler) read(conn *websocket.Conn, client *services.WebsocketClient) {
+	pongWait := 2 * h.pingInterval
+
+	conn.SetReadLimit(websocketMaxCommandSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				h.logger.Info(fmt.Sprintf("websocket connection of user [%s] closed with error [%s]", client.UserID, err.Error()))
+			}
+			return
+		}
+		h.handleCommand(client, data)
+	}
+}
+
+// handleCommand executes a requests.WebsocketCommand which is sent by the client
+func (h *WebsocketHandler) handleCommand(client *services.WebsocketClient, data []byte) {
+	ctx, span, ctxLogger := h.tracer.StartWithLogger(context.Background(), h.logger)
+	defer span.End()
+
+	var request requests.WebsocketCommand
+	if err := json.Unmarshal(data, &request); err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot unmarshall websocket command [%s] into %T", data, request)))
+		h.sendError(ctx, client, "The command isn't properly formed", nil)
+		return
+	}
+
+	if errors := h.validator.ValidateWebsocketCommand(ctx, request.Sanitize()); len(errors) != 0 {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("validation errors [%s], while handling websocket command [%+#v]", spew.Sdump(errors), request)))
+		h.sendError(ctx, client, "validation errors while handling the command", errors)
+		return
+	}
+
+	switch request.Type {
+	case requests.WebsocketCommandMarkThreadRead:
+		// the connections of the user are notified by the events.EventTypeMessageThreadRead event
+		_, err := h.service.MarkRead(ctx, request.ToMessageThreadReadParams(client.UserID, websocketSource))
+		if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+			h.sendError(ctx, client, fmt.Sprintf("cannot find message thread with ID [%s]", request.MessageThreadID), nil)
+			return
+		}
+		if err != nil {
+			ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot mark message thread [%s] as read", request.MessageThreadID))))
+			h.sendError(ctx, client, "cannot mark the message thread as read", nil)
+		}
+	case requests.WebsocketCommandTyping:
+		message := &services.WebsocketMessage{
+			Type: request.Type,
+			Data: fiber.Map{"message_thread_id": request.MessageThreadID, "timestamp": time.Now().UTC()},
+		}
+		if err := h.hub.Broadcast(ctx, client.UserID, message, client); err != nil {
+			ctxLogger.Error(h.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot broadcast typing in message thread [%s]", request.MessageThreadID))))
+		}
+	}
+}
+
+// sendError sends an error message to the client which sent a command
+func (h *WebsocketHandler) sendError(ctx context.Context, client *services.WebsocketClient, message string, errors url.Values) {
+	data := fiber.Map{"message": message}
+	if len(errors) != 0 {
+		data["errors"] = errors
+	}
+
+	if err := h.hub.Send(ctx, client, &services.WebsocketMessage{Type: "error", Data: data}); err != nil {
+		h.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send error [%s] to websocket client of user [%s]", message, client.UserID)))
+	}
+}
+
+// isAllowedOrigin checks the Origin header of a handshake, requests without the header are not sent by a browser
+func (h *WebsocketHandler) isAllowedOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range h.origins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/middlewares"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/validators"
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWebsocketServer(t *testing.T, maxConnections int) (*services.WebsocketHub, string) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	hub := services.NewWebsocketHub(logger, tracer, maxConnections, 10)
+	handler := NewWebsocketHandler(
+		logger,
+		tracer,
+		validators.NewMessageThreadHandlerValidator(logger, tracer),
+		hub,
+		services.NewMessageThreadService(logger, tracer, nil, nil),
+		[]string{"https://httpsms.com"},
+		time.Second,
+	)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(middlewares.ContextKeyAuthUserID, entities.AuthUser{ID: "user-a", Email: "name@email.com"})
+		return c.Next()
+	})
+	handler.RegisterRoutes(app)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = app.Listener(listener)
+	}()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	return hub, "ws://" + listener.Addr().String() + "/websocket"
+}
+
+func TestWebsocketHandler_Connect(t *testing.T) {
+	t.Run("connection from an origin which is not allowed is forbidden", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub, url := newWebsocketServer(t, 2)
+
+		// Act
+		_, response, err := websocket.DefaultDialer.Dial(url, http.Header{fiber.HeaderOrigin: []string{"https://example.com"}})
+
+		// Assert
+		assert.Error(t, err)
+		assert.Equal(t, fiber.StatusForbidden, response.StatusCode)
+		assert.Equal(t, 0, hub.Connections("user-a"))
+	})
+
+	t.Run("connections above the limit of the user are rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub, url := newWebsocketServer(t, 1)
+
+		// Arrange
+		conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{fiber.HeaderOrigin: []string{"https://httpsms.com"}})
+		require.NoError(t, err)
+		assert.Eventually(t, func() bool { return hub.Connections("user-a") == 1 }, time.Second, 5*time.Millisecond)
+
+		// Act
+		_, response, err := websocket.DefaultDialer.Dial(url, nil)
+
+		// Assert
+		assert.Error(t, err)
+		assert.Equal(t, fiber.StatusTooManyRequests, response.StatusCode)
+
+		assert.Nil(t, conn.Close())
+		assert.Eventually(t, func() bool { return hub.Connections("user-a") == 0 }, 5*time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("typing command is sent to the other connections of the user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub, url := newWebsocketServer(t, 2)
+
+		// Arrange
+		sender, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer sender.Close()
+
+		receiver, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer receiver.Close()
+
+		assert.Eventually(t, func() bool { return hub.Connections("user-a") == 2 }, time.Second, 5*time.Millisecond)
+
+		// Act
+		err = sender.WriteJSON(map[string]string{"type": "message-thread.typing", "message_thread_id": "32343a19-da5e-4b1b-a767-3298a73703ca"})
+
+		// Assert
+		assert.Nil(t, err)
+
+		message := new(services.WebsocketMessage)
+		assert.Nil(t, receiver.ReadJSON(message))
+		assert.Equal(t, "message-thread.typing", message.Type)
+	})
+
+	t.Run("invalid command is answered with an error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, url := newWebsocketServer(t, 2)
+
+		// Arrange
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// Act
+		err = conn.WriteJSON(map[string]string{"type": "message-thread.deleted"})
+
+		// Assert
+		assert.Nil(t, err)
+
+		message := struct {
+			Type string `json:"type"`
+			Data struct {
+				Errors map[string][]string `json:"errors"`
+			} `json:"data"`
+		}{}
+		_, data, err := conn.ReadMessage()
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(data, &message))
+		assert.Equal(t, "error", message.Type)
+		assert.Contains(t, message.Data.Errors, "type")
+		assert.Contains(t, message.Data.Errors, "message_thread_id")
+	})
+}
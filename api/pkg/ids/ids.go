@@ -0,0 +1,54 @@
+package ids
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// Strategy is the UUID version which a Generator creates
+type Strategy string
+
+const (
+	// StrategyRandom creates random version 4 UUIDs
+	StrategyRandom = Strategy("v4")
+
+	// StrategyTimeOrdered creates version 7 UUIDs which are ordered by the time they are created
+	StrategyTimeOrdered = Strategy("v7")
+)
+
+// Generator creates the IDs of new entities
+type Generator interface {
+	// New creates a new ID
+	New() uuid.UUID
+}
+
+// NewGenerator creates the Generator of a Strategy, StrategyTimeOrdered is used when the strategy is empty
+func NewGenerator(strategy Strategy) (Generator, error) {
+	switch strategy {
+	case StrategyTimeOrdered, "":
+		return timeOrderedGenerator{}, nil
+	case StrategyRandom:
+		return randomGenerator{}, nil
+	default:
+		return nil, stacktrace.NewError(fmt.Sprintf("cannot create ID generator for unknown strategy [%s]", strategy))
+	}
+}
+
+// randomGenerator creates version 4 UUIDs
+type randomGenerator struct{}
+
+// New creates a random UUID
+func (randomGenerator) New() uuid.UUID {
+	return uuid.New()
+}
+
+// timeOrderedGenerator creates version 7 UUIDs, new rows are appended to the end of the primary key index instead of
+// being scattered across it.
+type timeOrderedGenerator struct{}
+
+// New creates a time ordered UUID, it panics like uuid.New if the random source fails
+func (timeOrderedGenerator) New() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
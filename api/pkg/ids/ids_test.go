@@ -0,0 +1,71 @@
+package ids
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGenerator(t *testing.T) {
+	t.Run("time ordered IDs are sorted by creation time", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		generator, err := NewGenerator(StrategyTimeOrdered)
+		assert.Nil(t, err)
+
+		// Act
+		first := generator.New()
+		time.Sleep(2 * time.Millisecond)
+		second := generator.New()
+
+		// Assert
+		assert.Equal(t, uuid.Version(7), first.Version())
+		assert.Less(t, first.String(), second.String())
+	})
+
+	t.Run("empty strategy creates time ordered IDs", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		generator, err := NewGenerator("")
+
+		// Act
+		id := generator.New()
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uuid.Version(7), id.Version())
+	})
+
+	t.Run("random strategy creates version 4 IDs", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		generator, err := NewGenerator(StrategyRandom)
+
+		// Act
+		id := generator.New()
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uuid.Version(4), id.Version())
+	})
+
+	t.Run("unknown strategy returns an error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		generator, err := NewGenerator("v1")
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Nil(t, generator)
+	})
+}
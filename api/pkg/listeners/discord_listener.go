@@ -13,9 +13,10 @@ import (
 
 // DiscordListener sends messages to discord
 type DiscordListener struct {
-	logger  telemetry.Logger
-	tracer  telemetry.Tracer
-	service *services.DiscordService
+	logger        telemetry.Logger
+	tracer        telemetry.Tracer
+	service       *services.DiscordService
+	threadService *services.MessageThreadService
 }
 
 // NewDiscordListener creates a new instance of DiscordListener
@@ -23,11 +24,13 @@ func NewDiscordListener(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	service *services.DiscordService,
+	threadService *services.MessageThreadService,
 ) (l *DiscordListener, routes map[string]events.EventListener) {
 	l = &DiscordListener{
-		logger:  logger.WithService(fmt.Sprintf("%T", l)),
-		tracer:  tracer,
-		service: service,
+		logger:        logger.WithService(fmt.Sprintf("%T", l)),
+		tracer:        tracer,
+		service:       service,
+		threadService: threadService,
 	}
 
 	return l, map[string]events.EventListener{
@@ -42,10 +45,15 @@ func (listener *DiscordListener) OnMessagePhoneReceived(ctx context.Context, eve
 
 	var payload events.MessagePhoneReceivedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if listener.threadService.IsMuted(ctx, payload.UserID, payload.Owner, payload.Contact) {
+		listener.tracer.CtxLogger(listener.logger, span).Info(fmt.Sprintf("skipped [%s] event with ID [%s] because the notifications of the thread are muted", event.Type(), event.ID()))
+		return nil
+	}
+
 	if err := listener.service.HandleMessageReceived(ctx, payload.UserID, event); err != nil {
 		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
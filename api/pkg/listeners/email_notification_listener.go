@@ -31,10 +31,11 @@ func NewEmailNotificationListener(
 	}
 
 	return l, map[string]events.EventListener{
-		events.EventTypeMessageSendExpired: l.OnMessageSendExpired,
-		events.EventTypeMessageSendFailed:  l.OnMessageSendFailed,
-		events.EventTypeWebhookSendFailed:  l.OnWebhookSendFailed,
-		events.EventTypeDiscordSendFailed:  l.OnDiscordSendFailed,
+		events.EventTypeMessageSendExpired:    l.OnMessageSendExpired,
+		events.EventTypeMessageSendFailed:     l.OnMessageSendFailed,
+		events.EventTypeWebhookSendFailed:     l.OnWebhookSendFailed,
+		events.EventTypeDiscordSendFailed:     l.OnDiscordSendFailed,
+		events.EventTypeNotificationDigestDue: l.OnNotificationDigestDue,
 	}
 }
 
@@ -45,11 +46,11 @@ func (listener *EmailNotificationListener) OnMessageSendExpired(ctx context.Cont
 
 	payload := new(events.MessageSendExpiredPayload)
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if err := listener.service.NotifyMessageExpired(ctx, payload); err != nil {
+	if err := listener.service.NotifyMessageExpired(ctx, event.Source(), payload); err != nil {
 		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -64,11 +65,11 @@ func (listener *EmailNotificationListener) OnMessageSendFailed(ctx context.Conte
 
 	payload := new(events.MessageSendFailedPayload)
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if err := listener.service.NotifyMessageFailed(ctx, payload); err != nil {
+	if err := listener.service.NotifyMessageFailed(ctx, event.Source(), payload); err != nil {
 		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -83,7 +84,7 @@ func (listener *EmailNotificationListener) OnWebhookSendFailed(ctx context.Conte
 
 	payload := new(events.WebhookSendFailedPayload)
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -102,7 +103,7 @@ func (listener *EmailNotificationListener) OnDiscordSendFailed(ctx context.Conte
 
 	payload := new(events.DiscordSendFailedPayload)
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -113,3 +114,22 @@ func (listener *EmailNotificationListener) OnDiscordSendFailed(ctx context.Conte
 
 	return nil
 }
+
+// OnNotificationDigestDue handles the events.EventTypeNotificationDigestDue event
+func (listener *EmailNotificationListener) OnNotificationDigestDue(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	payload := new(events.NotificationDigestDuePayload)
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.NotifyDigestDue(ctx, event.Source(), payload); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
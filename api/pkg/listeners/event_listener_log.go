@@ -0,0 +1,69 @@
+package listeners
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// handleOnce executes the handler of an event and stores its entities.EventListenerLog in one transaction.
+// When the transaction fails, none of the writes of the handler are persisted so a redelivered event can be handled again safely.
+// The events which are dispatched by the handler are only published after the transaction is committed.
+func handleOnce(
+	ctx context.Context,
+	logger telemetry.Logger,
+	transactor repositories.Transactor,
+	logRepository repositories.EventListenerLogRepository,
+	handlerName string,
+	event cloudevents.Event,
+	handler events.EventListener,
+) error {
+	logger = logger.WithFields(map[string]interface{}{
+		telemetry.LogFieldEventID:   event.ID(),
+		telemetry.LogFieldEventType: event.Type(),
+		telemetry.LogFieldHandler:   handlerName,
+		telemetry.LogFieldRequestID: telemetry.EventRequestID(event),
+	})
+
+	return transactor.Execute(ctx, func(ctx context.Context) error {
+		start := time.Now().UTC()
+
+		handled, err := logRepository.Has(ctx, event.ID(), handlerName)
+		if err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot check if event [%s] was handled by [%s]", event.ID(), handlerName))
+		}
+
+		if handled {
+			logger.Info("event was already handled, skipping")
+			return nil
+		}
+
+		if err = handler(ctx, event); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot handle event [%s] with [%s]", event.ID(), handlerName))
+		}
+
+		log := &entities.EventListenerLog{
+			ID:        uuid.New(),
+			EventID:   event.ID(),
+			EventType: event.Type(),
+			Handler:   handlerName,
+			Duration:  time.Since(start),
+			HandledAt: start,
+			CreatedAt: time.Now().UTC(),
+		}
+		if err = logRepository.Store(ctx, log); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot store log for event [%s] handled by [%s]", event.ID(), handlerName))
+		}
+
+		logger.WithField(telemetry.LogFieldDuration, log.Duration.String()).Info("event handled")
+		return nil
+	})
+}
@@ -0,0 +1,230 @@
+package listeners
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+	"gorm.io/gorm"
+)
+
+// memoryStore keeps the writes of the handler and the entities.EventListenerLog in memory
+type memoryStore struct {
+	messages    []string
+	logs        []*entities.EventListenerLog
+	storeErrors int
+}
+
+// Execute discards the writes of fn when it fails like a rolled back transaction
+func (store *memoryStore) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	messages, logs := len(store.messages), len(store.logs)
+	if err := fn(ctx); err != nil {
+		store.messages, store.logs = store.messages[:messages], store.logs[:logs]
+		return err
+	}
+	return nil
+}
+
+func (store *memoryStore) Store(_ context.Context, log *entities.EventListenerLog) error {
+	store.logs = append(store.logs, log)
+	if store.storeErrors > 0 {
+		store.storeErrors--
+		return stacktrace.NewError("connection reset by peer")
+	}
+	return nil
+}
+
+func (store *memoryStore) Has(_ context.Context, eventID string, handler string) (bool, error) {
+	for _, log := range store.logs {
+		if log.EventID == eventID && log.Handler == handler {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func newTestLogger() telemetry.Logger {
+	zl := zerolog.Nop()
+	return telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+}
+
+func newTestEvent() cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID("e6b2b1c2-6c6d-4b0a-9d4b-3c1c5c1e8f00")
+	event.SetType("message.phone.sent")
+	event.SetSource("/v1/messages/events")
+	return event
+}
+
+func TestHandleOnce(t *testing.T) {
+	t.Run("event is handled again after the log cannot be stored", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		store := &memoryStore{storeErrors: 1}
+		event := newTestEvent()
+		handler := func(_ context.Context, event cloudevents.Event) error {
+			store.messages = append(store.messages, event.ID())
+			return nil
+		}
+
+		// Act
+		firstErr := handleOnce(context.Background(), newTestLogger(), store, store, "MessageListener", event, handler)
+		messagesAfterFailure := len(store.messages)
+		secondErr := handleOnce(context.Background(), newTestLogger(), store, store, "MessageListener", event, handler)
+
+		// Assert
+		assert.NotNil(t, firstErr)
+		assert.Equal(t, 0, messagesAfterFailure)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, []string{event.ID()}, store.messages)
+		assert.Equal(t, 1, len(store.logs))
+	})
+
+	t.Run("redelivered event is not handled twice", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		store := new(memoryStore)
+		event := newTestEvent()
+		handler := func(_ context.Context, event cloudevents.Event) error {
+			store.messages = append(store.messages, event.ID())
+			return nil
+		}
+
+		// Act
+		firstErr := handleOnce(context.Background(), newTestLogger(), store, store, "MessageListener", event, handler)
+		secondErr := handleOnce(context.Background(), newTestLogger(), store, store, "MessageListener", event, handler)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, []string{event.ID()}, store.messages)
+	})
+
+	t.Run("failed handler does not store a log", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		store := new(memoryStore)
+		handler := func(_ context.Context, event cloudevents.Event) error {
+			store.messages = append(store.messages, event.ID())
+			return stacktrace.NewError("cannot update message")
+		}
+
+		// Act
+		err := handleOnce(context.Background(), newTestLogger(), store, store, "MessageListener", newTestEvent(), handler)
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Empty(t, store.messages)
+		assert.Empty(t, store.logs)
+	})
+}
+
+// countingPushQueue counts the tasks which are enqueued
+type countingPushQueue struct {
+	mutex sync.Mutex
+	tasks int
+}
+
+func (queue *countingPushQueue) Enqueue(_ context.Context, _ *services.PushQueueTask, _ time.Duration) (string, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.tasks++
+	return "task", nil
+}
+
+func TestHandleOnce_Transaction(t *testing.T) {
+	// handle stores a thread, a phone and dispatches an event in the transaction of handleOnce, the handler fails with handlerErr
+	handle := func(t *testing.T, handlerErr error) (*gorm.DB, *countingPushQueue, error) {
+		db, openErr := gorm.Open(repositories.NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")), &gorm.Config{TranslateError: true})
+		assert.Nil(t, openErr)
+		assert.Nil(t, db.AutoMigrate(&entities.MessageThread{}, &entities.Phone{}, &entities.EventListenerLog{}, &repositories.GormEvent{}))
+
+		logger := newTestLogger()
+		tracer := telemetry.NewOtelLogger("test", logger)
+		threadRepository := repositories.NewGormMessageThreadRepository(logger, tracer, db, db)
+		phoneRepository := repositories.NewGormPhoneRepository(logger, tracer, db)
+		histogram, _ := noop.NewMeterProvider().Meter("test").Float64Histogram("test")
+		queue := &countingPushQueue{}
+		dispatcher := services.NewEventDispatcher(logger, tracer, histogram, queue, services.PushQueueConfig{}, repositories.NewGormEventRepository(logger, tracer, db))
+
+		handler := func(ctx context.Context, event cloudevents.Event) error {
+			timestamp := time.Now().UTC()
+			thread := &entities.MessageThread{ID: uuid.New(), UserID: "user-a", Owner: "+18005550199", Contact: "+18005550100", CreatedAt: timestamp, UpdatedAt: timestamp, OrderTimestamp: timestamp}
+			if err := threadRepository.Store(ctx, thread); err != nil {
+				return err
+			}
+
+			phone := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199", SIM: entities.SIM1, CreatedAt: timestamp, UpdatedAt: timestamp}
+			if err := phoneRepository.Save(ctx, phone); err != nil {
+				return err
+			}
+
+			dispatched := newTestEvent()
+			dispatched.SetID(uuid.NewString())
+			if err := dispatcher.Dispatch(ctx, dispatched); err != nil {
+				return err
+			}
+			return handlerErr
+		}
+
+		logRepository := repositories.NewGormEventListenerLogRepository(logger, tracer, db)
+		return db, queue, handleOnce(context.Background(), logger, repositories.NewGormTransactor(db), logRepository, "MessageListener", newTestEvent(), handler)
+	}
+
+	count := func(t *testing.T, db *gorm.DB, model any) int64 {
+		var total int64
+		assert.Nil(t, db.Model(model).Count(&total).Error)
+		return total
+	}
+
+	t.Run("the writes and events of a failed handler are rolled back", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		db, queue, err := handle(t, stacktrace.NewError("cannot update message"))
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, int64(0), count(t, db, &entities.MessageThread{}))
+		assert.Equal(t, int64(0), count(t, db, &entities.Phone{}))
+		assert.Equal(t, int64(0), count(t, db, &repositories.GormEvent{}))
+		assert.Equal(t, int64(0), count(t, db, &entities.EventListenerLog{}))
+		assert.Equal(t, 0, queue.tasks)
+	})
+
+	t.Run("the events of a handler are published after its writes are committed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		db, queue, err := handle(t, nil)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count(t, db, &entities.MessageThread{}))
+		assert.Equal(t, int64(1), count(t, db, &entities.Phone{}))
+		assert.Equal(t, int64(1), count(t, db, &repositories.GormEvent{}))
+		assert.Equal(t, int64(1), count(t, db, &entities.EventListenerLog{}))
+		assert.Equal(t, 1, queue.tasks)
+	})
+}
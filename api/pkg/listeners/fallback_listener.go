@@ -0,0 +1,75 @@
+package listeners
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// FallbackListener sends the messages which the phone cannot send with the fallback provider of the user
+type FallbackListener struct {
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	service *services.FallbackService
+}
+
+// NewFallbackListener creates a new instance of FallbackListener
+func NewFallbackListener(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.FallbackService,
+) (l *FallbackListener, routes map[string]events.EventListener) {
+	l = &FallbackListener{
+		logger:  logger.WithService(fmt.Sprintf("%T", l)),
+		tracer:  tracer,
+		service: service,
+	}
+
+	return l, map[string]events.EventListener{
+		events.EventTypeMessageAPISent:           l.OnMessageAPISent,
+		events.EventTypeMessageFallbackRequested: l.OnMessageFallbackRequested,
+	}
+}
+
+// OnMessageAPISent handles the events.EventTypeMessageAPISent event
+func (listener *FallbackListener) OnMessageAPISent(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageAPISentPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.HandleMessageAPISent(ctx, event.Source(), payload); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnMessageFallbackRequested handles the events.EventTypeMessageFallbackRequested event
+func (listener *FallbackListener) OnMessageFallbackRequested(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageFallbackRequestedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.HandleMessageFallbackRequested(ctx, event.Source(), payload); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -46,7 +46,7 @@ func (listener *HeartbeatListener) onPhoneUpdated(ctx context.Context, event clo
 
 	var payload events.PhoneUpdatedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -58,7 +58,7 @@ func (listener *HeartbeatListener) onPhoneUpdated(ctx context.Context, event clo
 	}
 
 	if _, err := listener.service.StoreMonitor(ctx, storeParams); err != nil {
-		msg := fmt.Sprintf("cannot store heartbeat monitor with params [%s] for event with ID [%s]", spew.Sdump(storeParams), event.ID())
+		msg := fmt.Sprintf("cannot store heartbeat monitor with params [%s] for event with ID [%s]", spew.Sdump(storeParams.Redacted()), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -72,7 +72,7 @@ func (listener *HeartbeatListener) onPhoneDeleted(ctx context.Context, event clo
 
 	var payload events.PhoneDeletedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -91,7 +91,7 @@ func (listener *HeartbeatListener) onPhoneHeartbeatCheck(ctx context.Context, ev
 
 	var payload events.PhoneHeartbeatCheckPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/services"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
@@ -14,9 +15,11 @@ import (
 
 // MessageListener handles cloud events which need to update entities.Message
 type MessageListener struct {
-	logger  telemetry.Logger
-	tracer  telemetry.Tracer
-	service *services.MessageService
+	logger        telemetry.Logger
+	tracer        telemetry.Tracer
+	service       *services.MessageService
+	transactor    repositories.Transactor
+	logRepository repositories.EventListenerLogRepository
 }
 
 // NewMessageListener creates a new instance of MessageListener
@@ -24,24 +27,36 @@ func NewMessageListener(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	service *services.MessageService,
+	transactor repositories.Transactor,
+	logRepository repositories.EventListenerLogRepository,
 ) (l *MessageListener, routes map[string]events.EventListener) {
 	l = &MessageListener{
-		logger:  logger.WithService(fmt.Sprintf("%T", l)),
-		tracer:  tracer,
-		service: service,
+		logger:        logger.WithService(fmt.Sprintf("%T", l)),
+		tracer:        tracer,
+		service:       service,
+		transactor:    transactor,
+		logRepository: logRepository,
 	}
 
 	return l, map[string]events.EventListener{
-		events.EventTypeMessagePhoneSending:          l.OnMessagePhoneSending,
-		events.EventTypeMessagePhoneSent:             l.OnMessagePhoneSent,
-		events.EventTypeMessagePhoneDelivered:        l.OnMessagePhoneDelivered,
-		events.EventTypeMessageSendFailed:            l.OnMessagePhoneFailed,
-		events.EventTypeMessageNotificationSent:      l.onMessageNotificationSent,
-		events.EventTypeMessageNotificationFailed:    l.onMessageNotificationFailed,
-		events.EventTypeMessageSendExpiredCheck:      l.onMessageSendExpiredCheck,
-		events.EventTypeMessageSendExpired:           l.onMessageSendExpired,
-		events.EventTypeMessageNotificationScheduled: l.onMessageNotificationScheduled,
-		events.MessageThreadAPIDeleted:               l.onMessageThreadAPIDeleted,
+		events.EventTypeMessagePhoneSending:          l.once(l.OnMessagePhoneSending),
+		events.EventTypeMessagePhoneSent:             l.once(l.OnMessagePhoneSent),
+		events.EventTypeMessagePhoneDelivered:        l.once(l.OnMessagePhoneDelivered),
+		events.EventTypeMessageSendFailed:            l.once(l.OnMessagePhoneFailed),
+		events.EventTypeMessageNotificationSent:      l.once(l.onMessageNotificationSent),
+		events.EventTypeMessageNotificationFailed:    l.once(l.onMessageNotificationFailed),
+		events.EventTypeMessageSendExpiredCheck:      l.once(l.onMessageSendExpiredCheck),
+		events.EventTypeMessageSendExpired:           l.once(l.onMessageSendExpired),
+		events.EventTypeMessageNotificationScheduled: l.once(l.onMessageNotificationScheduled),
+		events.MessageThreadAPIDeleted:               l.once(l.onMessageThreadAPIDeleted),
+		events.EventTypePhoneHeartbeatDead:           l.once(l.onPhoneHeartbeatDead),
+	}
+}
+
+// once handles an event in a transaction with its entities.EventListenerLog so that a redelivered event is only handled once
+func (listener *MessageListener) once(handler events.EventListener) events.EventListener {
+	return func(ctx context.Context, event cloudevents.Event) error {
+		return handleOnce(ctx, listener.logger, listener.transactor, listener.logRepository, fmt.Sprintf("%T.%s", listener, event.Type()), event, handler)
 	}
 }
 
@@ -52,7 +67,7 @@ func (listener *MessageListener) OnMessagePhoneSending(ctx context.Context, even
 
 	var payload events.MessagePhoneSendingPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -78,7 +93,7 @@ func (listener *MessageListener) OnMessagePhoneSent(ctx context.Context, event c
 
 	var payload events.MessagePhoneSentPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -103,7 +118,7 @@ func (listener *MessageListener) OnMessagePhoneDelivered(ctx context.Context, ev
 
 	var payload events.MessagePhoneDeliveredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -128,7 +143,7 @@ func (listener *MessageListener) OnMessagePhoneFailed(ctx context.Context, event
 
 	var payload events.MessageSendFailedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -154,7 +169,7 @@ func (listener *MessageListener) onMessageNotificationFailed(ctx context.Context
 
 	var payload events.MessageNotificationFailedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -186,7 +201,7 @@ func (listener *MessageListener) onMessageNotificationSent(ctx context.Context,
 
 	var payload events.MessageNotificationSentPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -224,7 +239,7 @@ func (listener *MessageListener) onMessageSendExpiredCheck(ctx context.Context,
 
 	var payload events.MessageSendExpiredCheckPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -248,7 +263,7 @@ func (listener *MessageListener) onMessageSendExpired(ctx context.Context, event
 
 	var payload events.MessageSendExpiredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -273,7 +288,7 @@ func (listener *MessageListener) onMessageNotificationScheduled(ctx context.Cont
 
 	var payload events.MessageNotificationScheduledPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -298,7 +313,7 @@ func (listener *MessageListener) onMessageThreadAPIDeleted(ctx context.Context,
 
 	var payload events.MessageThreadAPIDeletedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -309,3 +324,22 @@ func (listener *MessageListener) onMessageThreadAPIDeleted(ctx context.Context,
 
 	return nil
 }
+
+// onPhoneHeartbeatDead handles the events.EventTypePhoneHeartbeatDead event
+func (listener *MessageListener) onPhoneHeartbeatDead(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	payload := new(events.PhoneHeartbeatDeadPayload)
+	if err := event.DataAs(payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Failover(ctx, event.Source(), payload); err != nil {
+		msg := fmt.Sprintf("cannot failover messages of owner [%s] for event with ID [%s]", payload.Owner, event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package listeners
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+)
+
+// MessageStreamListener publishes the events which change an entities.Message to the services.EventBroker of the message stream
+type MessageStreamListener struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	broker *services.EventBroker
+}
+
+// NewMessageStreamListener creates a new instance of MessageStreamListener
+func NewMessageStreamListener(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	broker *services.EventBroker,
+) (l *MessageStreamListener, routes map[string]events.EventListener) {
+	l = &MessageStreamListener{
+		logger: logger.WithService(fmt.Sprintf("%T", l)),
+		tracer: tracer,
+		broker: broker,
+	}
+
+	return l, map[string]events.EventListener{
+		events.EventTypeMessageAPISent:        l.broker.Publish,
+		events.MessageAPIDeleted:              l.broker.Publish,
+		events.MessageAPIUpdated:              l.broker.Publish,
+		events.EventTypeMessagePhoneSending:   l.broker.Publish,
+		events.EventTypeMessagePhoneSent:      l.broker.Publish,
+		events.EventTypeMessagePhoneDelivered: l.broker.Publish,
+		events.EventTypeMessageSendFailed:     l.broker.Publish,
+		events.EventTypeMessageSendExpired:    l.broker.Publish,
+		events.EventTypeMessagePhoneReceived:  l.broker.Publish,
+	}
+}
@@ -35,6 +35,7 @@ func NewMessageThreadListener(
 	return l, map[string]events.EventListener{
 		events.EventTypeMessageAPISent:               l.OnMessageAPISent,
 		events.MessageAPIDeleted:                     l.onMessageDeleted,
+		events.MessageAPIUpdated:                     l.onMessageUpdated,
 		events.EventTypeMessagePhoneSending:          l.OnMessagePhoneSending,
 		events.EventTypeMessagePhoneSent:             l.OnMessagePhoneSent,
 		events.EventTypeMessagePhoneDelivered:        l.OnMessagePhoneDelivered,
@@ -52,7 +53,7 @@ func (listener *MessageThreadListener) OnMessageAPISent(ctx context.Context, eve
 
 	var payload events.MessageAPISentPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -81,7 +82,7 @@ func (listener *MessageThreadListener) onMessageDeleted(ctx context.Context, eve
 
 	var payload events.MessageAPIDeletedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -93,6 +94,25 @@ func (listener *MessageThreadListener) onMessageDeleted(ctx context.Context, eve
 	return nil
 }
 
+// onMessageUpdated handles the events.MessageAPIUpdated event
+func (listener *MessageThreadListener) onMessageUpdated(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageAPIUpdatedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.UpdateAfterUpdatedMessage(ctx, payload.UserID, payload.Owner, payload.Contact, payload.MessageID, payload.Content); err != nil {
+		msg := fmt.Sprintf("cannot update thread for message with ID [%s] for event with ID [%s]", payload.MessageID, event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
 // OnMessagePhoneSending handles the events.EventTypeMessagePhoneSending event
 func (listener *MessageThreadListener) OnMessagePhoneSending(ctx context.Context, event cloudevents.Event) error {
 	ctx, span := listener.tracer.Start(ctx)
@@ -100,7 +120,7 @@ func (listener *MessageThreadListener) OnMessagePhoneSending(ctx context.Context
 
 	var payload events.MessagePhoneSendingPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -129,7 +149,7 @@ func (listener *MessageThreadListener) OnMessagePhoneSent(ctx context.Context, e
 
 	var payload events.MessagePhoneSentPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -158,7 +178,7 @@ func (listener *MessageThreadListener) OnMessagePhoneDelivered(ctx context.Conte
 
 	var payload events.MessagePhoneDeliveredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -187,7 +207,7 @@ func (listener *MessageThreadListener) OnMessagePhoneFailed(ctx context.Context,
 
 	var payload events.MessageSendFailedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T] for event [%s]", event.Data(), payload, event.ID())
+		msg := fmt.Sprintf("cannot decode [%s] into [%T] for event [%s]", telemetry.RedactContent(string(event.Data())), payload, event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -216,7 +236,7 @@ func (listener *MessageThreadListener) OnMessagePhoneReceived(ctx context.Contex
 
 	var payload events.MessagePhoneReceivedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -245,7 +265,7 @@ func (listener *MessageThreadListener) onMessageNotificationScheduled(ctx contex
 
 	var payload events.MessageNotificationScheduledPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -274,7 +294,7 @@ func (listener *MessageThreadListener) onMessageExpired(ctx context.Context, eve
 
 	var payload events.MessageSendExpiredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
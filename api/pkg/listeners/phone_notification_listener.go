@@ -37,6 +37,9 @@ func NewNotificationListener(
 		events.EventTypeMessageSendRetry:        l.onMessageSendRetry,
 		events.EventTypeMessageNotificationSend: l.onMessageNotificationSend,
 		events.PhoneHeartbeatMissed:             l.onPhoneHeartbeatMissed,
+		events.EventTypePhoneResumed:            l.onPhoneResumed,
+		events.EventTypePhoneQuotaReset:         l.onPhoneQuotaReset,
+		events.EventTypeMessageFailover:         l.onMessageFailover,
 	}
 }
 
@@ -47,7 +50,7 @@ func (listener *PhoneNotificationListener) onMessageAPISent(ctx context.Context,
 
 	var payload events.MessageAPISentPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -62,7 +65,7 @@ func (listener *PhoneNotificationListener) onMessageAPISent(ctx context.Context,
 	}
 
 	if err := listener.service.Schedule(ctx, sendParams); err != nil {
-		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams), event.ID())
+		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams.Redacted()), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -76,7 +79,7 @@ func (listener *PhoneNotificationListener) onMessageSendRetry(ctx context.Contex
 
 	var payload events.MessageSendRetryPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -91,7 +94,7 @@ func (listener *PhoneNotificationListener) onMessageSendRetry(ctx context.Contex
 	}
 
 	if err := listener.service.Schedule(ctx, sendParams); err != nil {
-		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams), event.ID())
+		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams.Redacted()), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -105,12 +108,50 @@ func (listener *PhoneNotificationListener) onPhoneHeartbeatMissed(ctx context.Co
 
 	payload := new(events.PhoneHeartbeatMissedPayload)
 	if err := event.DataAs(payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
 	if err := listener.service.SendHeartbeatFCM(ctx, payload); err != nil {
-		msg := fmt.Sprintf("cannot schedule send heartbeat FCM with params [%s] for event with ID [%s]", spew.Sdump(payload), event.ID())
+		msg := fmt.Sprintf("cannot schedule send heartbeat FCM with params [%s] for event with ID [%s]", spew.Sdump(payload.Redacted()), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// onPhoneResumed handles the events.EventTypePhoneResumed event
+func (listener *PhoneNotificationListener) onPhoneResumed(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	payload := new(events.PhoneResumedPayload)
+	if err := event.DataAs(payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.ScheduleOutstanding(ctx, event.Source(), payload.UserID, payload.PhoneID, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot schedule outstanding messages with params [%s] for event with ID [%s]", spew.Sdump(payload.Redacted()), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// onPhoneQuotaReset handles the events.EventTypePhoneQuotaReset event
+func (listener *PhoneNotificationListener) onPhoneQuotaReset(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	payload := new(events.PhoneQuotaResetPayload)
+	if err := event.DataAs(payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.ScheduleOutstanding(ctx, event.Source(), payload.UserID, payload.PhoneID, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot schedule outstanding messages with params [%s] for event with ID [%s]", spew.Sdump(payload.Redacted()), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -124,7 +165,7 @@ func (listener *PhoneNotificationListener) onMessageNotificationSend(ctx context
 
 	var payload events.MessageNotificationSendPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -144,3 +185,32 @@ func (listener *PhoneNotificationListener) onMessageNotificationSend(ctx context
 
 	return nil
 }
+
+// onMessageFailover handles the events.EventTypeMessageFailover event
+func (listener *PhoneNotificationListener) onMessageFailover(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageFailoverPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	sendParams := &services.PhoneNotificationScheduleParams{
+		UserID:    payload.UserID,
+		Owner:     payload.Owner,
+		Contact:   payload.Contact,
+		Content:   payload.Content,
+		SIM:       payload.SIM,
+		Source:    event.Source(),
+		MessageID: payload.MessageID,
+	}
+
+	if err := listener.service.Schedule(ctx, sendParams); err != nil {
+		msg := fmt.Sprintf("cannot schedule notification for failover message [%s] for event with ID [%s]", payload.MessageID, event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package listeners
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// TelegramListener forwards the received messages to a telegram chat
+type TelegramListener struct {
+	logger        telemetry.Logger
+	tracer        telemetry.Tracer
+	service       *services.TelegramService
+	threadService *services.MessageThreadService
+}
+
+// NewTelegramListener creates a new instance of TelegramListener
+func NewTelegramListener(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.TelegramService,
+	threadService *services.MessageThreadService,
+) (l *TelegramListener, routes map[string]events.EventListener) {
+	l = &TelegramListener{
+		logger:        logger.WithService(fmt.Sprintf("%T", l)),
+		tracer:        tracer,
+		service:       service,
+		threadService: threadService,
+	}
+
+	return l, map[string]events.EventListener{
+		events.EventTypeMessagePhoneReceived: l.OnMessagePhoneReceived,
+	}
+}
+
+// OnMessagePhoneReceived handles the events.EventTypeMessagePhoneReceived event
+func (listener *TelegramListener) OnMessagePhoneReceived(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessagePhoneReceivedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if listener.threadService.IsMuted(ctx, payload.UserID, payload.Owner, payload.Contact) {
+		listener.tracer.CtxLogger(listener.logger, span).Info(fmt.Sprintf("skipped [%s] event with ID [%s] because the notifications of the thread are muted", event.Type(), event.ID()))
+		return nil
+	}
+
+	if err := listener.service.HandleMessageReceived(ctx, event, &payload); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package listeners
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// TwilioListener sends the Twilio status callbacks of the messages which were sent with the Twilio compatible API
+type TwilioListener struct {
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	service *services.TwilioService
+}
+
+// NewTwilioListener creates a new instance of TwilioListener
+func NewTwilioListener(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	service *services.TwilioService,
+) (l *TwilioListener, routes map[string]events.EventListener) {
+	l = &TwilioListener{
+		logger:  logger.WithService(fmt.Sprintf("%T", l)),
+		tracer:  tracer,
+		service: service,
+	}
+
+	return l, map[string]events.EventListener{
+		events.EventTypeMessageAPISent:        l.OnMessageAPISent,
+		events.EventTypeMessagePhoneSent:      l.OnMessagePhoneSent,
+		events.EventTypeMessagePhoneDelivered: l.OnMessagePhoneDelivered,
+		events.EventTypeMessageSendFailed:     l.OnMessageSendFailed,
+		events.EventTypeMessageSendExpired:    l.OnMessageSendExpired,
+	}
+}
+
+// OnMessageAPISent handles the events.EventTypeMessageAPISent event
+func (listener *TwilioListener) OnMessageAPISent(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageAPISentPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return listener.sendStatusCallback(ctx, event, services.TwilioStatusCallbackParams{
+		UserID:    payload.UserID,
+		MessageID: payload.MessageID,
+		Owner:     payload.Owner,
+		Contact:   payload.Contact,
+		Status:    twilio.StatusQueued,
+	})
+}
+
+// OnMessagePhoneSent handles the events.EventTypeMessagePhoneSent event
+func (listener *TwilioListener) OnMessagePhoneSent(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessagePhoneSentPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return listener.sendStatusCallback(ctx, event, services.TwilioStatusCallbackParams{
+		UserID:    payload.UserID,
+		MessageID: payload.ID,
+		Owner:     payload.Owner,
+		Contact:   payload.Contact,
+		Status:    twilio.StatusSent,
+	})
+}
+
+// OnMessagePhoneDelivered handles the events.EventTypeMessagePhoneDelivered event
+func (listener *TwilioListener) OnMessagePhoneDelivered(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessagePhoneDeliveredPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return listener.sendStatusCallback(ctx, event, services.TwilioStatusCallbackParams{
+		UserID:    payload.UserID,
+		MessageID: payload.ID,
+		Owner:     payload.Owner,
+		Contact:   payload.Contact,
+		Status:    twilio.StatusDelivered,
+	})
+}
+
+// OnMessageSendFailed handles the events.EventTypeMessageSendFailed event
+func (listener *TwilioListener) OnMessageSendFailed(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageSendFailedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return listener.sendStatusCallback(ctx, event, services.TwilioStatusCallbackParams{
+		UserID:    payload.UserID,
+		MessageID: payload.ID,
+		Owner:     payload.Owner,
+		Contact:   payload.Contact,
+		Status:    twilio.StatusFailed,
+	})
+}
+
+// OnMessageSendExpired handles the events.EventTypeMessageSendExpired event, the message has failed when there are no more send attempts
+func (listener *TwilioListener) OnMessageSendExpired(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageSendExpiredPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !payload.IsFinal {
+		return nil
+	}
+
+	return listener.sendStatusCallback(ctx, event, services.TwilioStatusCallbackParams{
+		UserID:    payload.UserID,
+		MessageID: payload.MessageID,
+		Owner:     payload.Owner,
+		Contact:   payload.Contact,
+		Status:    twilio.StatusFailed,
+	})
+}
+
+func (listener *TwilioListener) sendStatusCallback(ctx context.Context, event cloudevents.Event, params services.TwilioStatusCallbackParams) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	if err := listener.service.SendStatusCallback(ctx, params); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -33,11 +33,14 @@ func NewUserListener(
 	}
 
 	return l, map[string]events.EventListener{
-		events.EventTypePhoneHeartbeatDead: l.onPhoneHeartbeatDead,
-		events.UserSubscriptionCreated:     l.OnUserSubscriptionCreated,
-		events.UserSubscriptionCancelled:   l.OnUserSubscriptionCancelled,
-		events.UserSubscriptionUpdated:     l.OnUserSubscriptionUpdated,
-		events.UserSubscriptionExpired:     l.OnUserSubscriptionExpired,
+		events.EventTypePhoneHeartbeatDead:  l.onPhoneHeartbeatDead,
+		events.EventTypePhoneHeartbeatAlive: l.onPhoneHeartbeatAlive,
+		events.EventTypePhoneBatteryLow:     l.onPhoneBatteryLow,
+		events.EventTypePhoneClockSkewed:    l.onPhoneClockSkewed,
+		events.UserSubscriptionCreated:      l.OnUserSubscriptionCreated,
+		events.UserSubscriptionCancelled:    l.OnUserSubscriptionCancelled,
+		events.UserSubscriptionUpdated:      l.OnUserSubscriptionUpdated,
+		events.UserSubscriptionExpired:      l.OnUserSubscriptionExpired,
 	}
 }
 
@@ -48,7 +51,7 @@ func (listener *UserListener) onPhoneHeartbeatDead(ctx context.Context, event cl
 
 	var payload events.PhoneHeartbeatDeadPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -57,10 +60,75 @@ func (listener *UserListener) onPhoneHeartbeatDead(ctx context.Context, event cl
 		PhoneID:                payload.PhoneID,
 		Owner:                  payload.Owner,
 		LastHeartbeatTimestamp: payload.LastHeartbeatTimestamp,
+		Threshold:              payload.Threshold,
 	}
 
 	if err := listener.service.SendPhoneDeadEmail(ctx, sendParams); err != nil {
-		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams), event.ID())
+		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams.Redacted()), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// onPhoneHeartbeatAlive handles the events.EventTypePhoneHeartbeatAlive event
+func (listener *UserListener) onPhoneHeartbeatAlive(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneHeartbeatAlivePayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	sendParams := &services.UserSendPhoneAliveEmailParams{
+		UserID:                 payload.UserID,
+		PhoneID:                payload.PhoneID,
+		Owner:                  payload.Owner,
+		LastHeartbeatTimestamp: payload.LastHeartbeatTimestamp,
+	}
+
+	if err := listener.service.SendPhoneAliveEmail(ctx, sendParams); err != nil {
+		msg := fmt.Sprintf("cannot send notification with params [%s] for event with ID [%s]", spew.Sdump(sendParams.Redacted()), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// onPhoneBatteryLow handles the events.EventTypePhoneBatteryLow event
+func (listener *UserListener) onPhoneBatteryLow(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneBatteryLowPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.SendPhoneBatteryLowEmail(ctx, &payload); err != nil {
+		msg := fmt.Sprintf("cannot send notification with payload [%s] for event with ID [%s]", spew.Sdump(payload.Redacted()), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// onPhoneClockSkewed handles the events.EventTypePhoneClockSkewed event
+func (listener *UserListener) onPhoneClockSkewed(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneClockSkewedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.SendPhoneClockSkewedEmail(ctx, &payload); err != nil {
+		msg := fmt.Sprintf("cannot send notification with payload [%s] for event with ID [%s]", spew.Sdump(payload.Redacted()), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -74,7 +142,7 @@ func (listener *UserListener) OnUserSubscriptionCreated(ctx context.Context, eve
 
 	var payload events.UserSubscriptionCreatedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -93,7 +161,7 @@ func (listener *UserListener) OnUserSubscriptionCancelled(ctx context.Context, e
 
 	var payload events.UserSubscriptionCancelledPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -112,7 +180,7 @@ func (listener *UserListener) OnUserSubscriptionExpired(ctx context.Context, eve
 
 	var payload events.UserSubscriptionExpiredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -131,7 +199,7 @@ func (listener *UserListener) OnUserSubscriptionUpdated(ctx context.Context, eve
 
 	var payload events.UserSubscriptionUpdatedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
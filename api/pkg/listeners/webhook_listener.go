@@ -13,9 +13,10 @@ import (
 
 // WebhookListener sends webhook events to users
 type WebhookListener struct {
-	logger  telemetry.Logger
-	tracer  telemetry.Tracer
-	service *services.WebhookService
+	logger        telemetry.Logger
+	tracer        telemetry.Tracer
+	service       *services.WebhookService
+	threadService *services.MessageThreadService
 }
 
 // NewWebhookListener creates a new instance of WebhookListener
@@ -23,11 +24,13 @@ func NewWebhookListener(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	service *services.WebhookService,
+	threadService *services.MessageThreadService,
 ) (l *WebhookListener, routes map[string]events.EventListener) {
 	l = &WebhookListener{
-		logger:  logger.WithService(fmt.Sprintf("%T", l)),
-		tracer:  tracer,
-		service: service,
+		logger:        logger.WithService(fmt.Sprintf("%T", l)),
+		tracer:        tracer,
+		service:       service,
+		threadService: threadService,
 	}
 
 	return l, map[string]events.EventListener{
@@ -36,6 +39,14 @@ func NewWebhookListener(
 		events.EventTypeMessagePhoneDelivered: l.OnMessagePhoneDelivered,
 		events.EventTypeMessageSendFailed:     l.OnMessageSendFailed,
 		events.EventTypeMessagePhoneSent:      l.OnMessagePhoneSent,
+		events.EventTypePhoneHeartbeatDead:    l.OnPhoneHeartbeatDead,
+		events.EventTypePhoneHeartbeatAlive:   l.OnPhoneHeartbeatAlive,
+		events.EventTypePhoneBatteryLow:       l.OnPhoneBatteryLow,
+		events.EventTypePhonePaused:           l.OnPhonePaused,
+		events.EventTypePhoneResumed:          l.OnPhoneResumed,
+		events.EventTypePhoneQuotaExhausted:   l.OnPhoneQuotaExhausted,
+		events.EventTypePhoneClockSkewed:      l.OnPhoneClockSkewed,
+		events.EventTypeMessageFailover:       l.OnMessageFailover,
 	}
 }
 
@@ -46,10 +57,15 @@ func (listener *WebhookListener) OnMessagePhoneReceived(ctx context.Context, eve
 
 	var payload events.MessagePhoneReceivedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if listener.threadService.IsWebhookMuted(ctx, payload.UserID, payload.Owner, payload.Contact) {
+		listener.tracer.CtxLogger(listener.logger, span).Info(fmt.Sprintf("skipped [%s] event with ID [%s] because the webhooks of the thread are muted", event.Type(), event.ID()))
+		return nil
+	}
+
 	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
 		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
@@ -65,7 +81,7 @@ func (listener *WebhookListener) OnMessageSendExpired(ctx context.Context, event
 
 	var payload events.MessageSendExpiredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -84,7 +100,7 @@ func (listener *WebhookListener) OnMessageSendFailed(ctx context.Context, event
 
 	var payload events.MessageSendFailedPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -103,7 +119,7 @@ func (listener *WebhookListener) OnMessagePhoneSent(ctx context.Context, event c
 
 	var payload events.MessagePhoneSentPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -122,7 +138,7 @@ func (listener *WebhookListener) OnMessagePhoneDelivered(ctx context.Context, ev
 
 	var payload events.MessagePhoneDeliveredPayload
 	if err := event.DataAs(&payload); err != nil {
-		msg := fmt.Sprintf("cannot decode [%s] into [%T]", event.Data(), payload)
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
 		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
@@ -133,3 +149,155 @@ func (listener *WebhookListener) OnMessagePhoneDelivered(ctx context.Context, ev
 
 	return nil
 }
+
+// OnPhoneHeartbeatDead handles the events.EventTypePhoneHeartbeatDead event
+func (listener *WebhookListener) OnPhoneHeartbeatDead(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneHeartbeatDeadPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnPhoneHeartbeatAlive handles the events.EventTypePhoneHeartbeatAlive event
+func (listener *WebhookListener) OnPhoneHeartbeatAlive(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneHeartbeatAlivePayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnPhoneBatteryLow handles the events.EventTypePhoneBatteryLow event
+func (listener *WebhookListener) OnPhoneBatteryLow(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneBatteryLowPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnPhonePaused handles the events.EventTypePhonePaused event
+func (listener *WebhookListener) OnPhonePaused(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhonePausedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnPhoneResumed handles the events.EventTypePhoneResumed event
+func (listener *WebhookListener) OnPhoneResumed(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneResumedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnPhoneQuotaExhausted handles the events.EventTypePhoneQuotaExhausted event
+func (listener *WebhookListener) OnPhoneQuotaExhausted(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneQuotaExhaustedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnPhoneClockSkewed handles the events.EventTypePhoneClockSkewed event
+func (listener *WebhookListener) OnPhoneClockSkewed(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.PhoneClockSkewedPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.Owner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// OnMessageFailover handles the events.EventTypeMessageFailover event
+func (listener *WebhookListener) OnMessageFailover(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := listener.tracer.Start(ctx)
+	defer span.End()
+
+	var payload events.MessageFailoverPayload
+	if err := event.DataAs(&payload); err != nil {
+		msg := fmt.Sprintf("cannot decode [%s] into [%T]", telemetry.RedactContent(string(event.Data())), payload)
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := listener.service.Send(ctx, payload.UserID, event, payload.OriginalOwner); err != nil {
+		msg := fmt.Sprintf("cannot process [%s] event with ID [%s]", event.Type(), event.ID())
+		return listener.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package listeners
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+)
+
+// WebsocketListener publishes the events which change an entities.Message or an entities.MessageThread to the services.WebsocketHub
+type WebsocketListener struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	hub    *services.WebsocketHub
+}
+
+// NewWebsocketListener creates a new instance of WebsocketListener
+func NewWebsocketListener(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	hub *services.WebsocketHub,
+) (l *WebsocketListener, routes map[string]events.EventListener) {
+	l = &WebsocketListener{
+		logger: logger.WithService(fmt.Sprintf("%T", l)),
+		tracer: tracer,
+		hub:    hub,
+	}
+
+	return l, map[string]events.EventListener{
+		events.EventTypeMessageAPISent:        l.hub.Publish,
+		events.MessageAPIDeleted:              l.hub.Publish,
+		events.MessageAPIUpdated:              l.hub.Publish,
+		events.EventTypeMessagePhoneReceived:  l.hub.Publish,
+		events.EventTypeMessagePhoneSending:   l.hub.Publish,
+		events.EventTypeMessagePhoneSent:      l.hub.Publish,
+		events.EventTypeMessagePhoneDelivered: l.hub.Publish,
+		events.EventTypeMessageSendFailed:     l.hub.Publish,
+		events.EventTypeMessageSendExpired:    l.hub.Publish,
+		events.EventTypeMessageThreadRead:     l.hub.Publish,
+		events.MessageThreadAPIDeleted:        l.hub.Publish,
+	}
+}
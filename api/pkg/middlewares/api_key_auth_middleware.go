@@ -1,16 +1,24 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/palantir/stacktrace"
 )
 
+// APIKeyUsageRecorder records the last usage of a scoped entities.APIKey
+type APIKeyUsageRecorder interface {
+	RecordUsage(ctx context.Context, apiKeyID uuid.UUID, ipAddress string, userAgent string) error
+}
+
 // APIKeyAuth authenticates a user from the X-API-Key header
-func APIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepository repositories.UserRepository) fiber.Handler {
+func APIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepository repositories.UserRepository, apiKeyRepository repositories.APIKeyRepository, usageRecorder APIKeyUsageRecorder) fiber.Handler {
 	logger = logger.WithService("middlewares.APIKeyAuth")
 
 	return func(c *fiber.Ctx) error {
@@ -25,13 +33,31 @@ func APIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepository
 			return c.Next()
 		}
 
-		authUser, err := userRepository.LoadAuthUser(ctx, apiKey)
+		authUser, err := LoadAuthUser(ctx, userRepository, apiKeyRepository, apiKey)
+		if stacktrace.GetCode(err) == repositories.ErrCodeUserSuspended {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("the user with api key [%s] is suspended", telemetry.RedactAPIKey(apiKey))))
+			return responseUserSuspended(c)
+		}
+
 		if err != nil {
-			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load user with api key [%s]", apiKey)))
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load user with api key [%s]", telemetry.RedactAPIKey(apiKey))))
 			return c.Next()
 		}
+		recordAPIKeyUsage(ctx, ctxLogger, usageRecorder, authUser, c)
+
 		c.Locals(ContextKeyAuthUserID, authUser)
 		ctxLogger.Info(fmt.Sprintf("[%T] set successfully for user with ID [%s]", authUser, authUser.ID))
 		return c.Next()
 	}
 }
+
+// recordAPIKeyUsage records the caller of a scoped entities.APIKey, the request is not rejected when the usage cannot be recorded
+func recordAPIKeyUsage(ctx context.Context, ctxLogger telemetry.Logger, usageRecorder APIKeyUsageRecorder, authUser entities.AuthUser, c *fiber.Ctx) {
+	if !authUser.IsScoped() {
+		return
+	}
+
+	if err := usageRecorder.RecordUsage(ctx, *authUser.APIKeyID, c.IP(), c.Get(fiber.HeaderUserAgent)); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot record usage of api key with ID [%s]", authUser.APIKeyID)))
+	}
+}
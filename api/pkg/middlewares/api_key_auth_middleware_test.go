@@ -0,0 +1,171 @@
+package middlewares
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubUserRepository resolves entities.AuthUser from a fixed set of API keys
+type stubUserRepository struct {
+	repositories.UserRepository
+	users map[string]entities.AuthUser
+}
+
+func (repository *stubUserRepository) LoadAuthUser(_ context.Context, apiKey string) (entities.AuthUser, error) {
+	if apiKey == "suspended-api-key" {
+		return entities.AuthUser{}, stacktrace.NewErrorWithCode(repositories.ErrCodeUserSuspended, "user with api key is suspended")
+	}
+	if user, ok := repository.users[apiKey]; ok {
+		return user, nil
+	}
+	return entities.AuthUser{}, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "user with api key does not exist")
+}
+
+// stubAPIKeyRepository resolves entities.AuthUser from a fixed set of scoped API keys
+type stubAPIKeyRepository struct {
+	repositories.APIKeyRepository
+	users map[string]entities.AuthUser
+}
+
+func (repository *stubAPIKeyRepository) LoadAuthUser(_ context.Context, apiKey string) (entities.AuthUser, error) {
+	if user, ok := repository.users[apiKey]; ok {
+		return user, nil
+	}
+	return entities.AuthUser{}, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "api key does not exist")
+}
+
+// stubAPIKeyUsageRecorder records the IDs of the scoped API keys which are used
+type stubAPIKeyUsageRecorder struct {
+	apiKeyIDs []uuid.UUID
+}
+
+func (recorder *stubAPIKeyUsageRecorder) RecordUsage(_ context.Context, apiKeyID uuid.UUID, _ string, _ string) error {
+	recorder.apiKeyIDs = append(recorder.apiKeyIDs, apiKeyID)
+	return nil
+}
+
+func apiKeyAuthApp(users map[string]entities.AuthUser, scopedUsers ...map[string]entities.AuthUser) *fiber.App {
+	return apiKeyAuthAppWithRecorder(new(stubAPIKeyUsageRecorder), users, scopedUsers...)
+}
+
+func apiKeyAuthAppWithRecorder(recorder *stubAPIKeyUsageRecorder, users map[string]entities.AuthUser, scopedUsers ...map[string]entities.AuthUser) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	apiKeyRepository := &stubAPIKeyRepository{users: map[string]entities.AuthUser{}}
+	if len(scopedUsers) > 0 {
+		apiKeyRepository.users = scopedUsers[0]
+	}
+
+	app.Use(APIKeyAuth(logger, tracer, &stubUserRepository{users: users}, apiKeyRepository, recorder))
+	app.Use(Authenticated(tracer))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(string(c.Locals(ContextKeyAuthUserID).(entities.AuthUser).ID))
+	})
+	return app
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	user := entities.AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "name@email.com"}
+
+	t.Run("request with a valid api key is authenticated", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := apiKeyAuthApp(map[string]entities.AuthUser{"valid-api-key": user})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderAPIKey, "valid-api-key")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+	})
+
+	t.Run("request without an api key is unauthorized", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := apiKeyAuthApp(map[string]entities.AuthUser{"valid-api-key": user})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("request with a revoked api key is unauthorized", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := apiKeyAuthApp(map[string]entities.AuthUser{"rotated-api-key": user})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderAPIKey, "revoked-api-key")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+	})
+
+	t.Run("request with a scoped api key is authenticated", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		apiKeyID := uuid.New()
+		scopedUser := entities.AuthUser{ID: user.ID, Email: user.Email, APIKeyID: &apiKeyID, Scopes: []entities.APIKeyScope{entities.APIKeyScopeMessagesRead}}
+		recorder := new(stubAPIKeyUsageRecorder)
+		app := apiKeyAuthAppWithRecorder(recorder, map[string]entities.AuthUser{"valid-api-key": user}, map[string]entities.AuthUser{"hsk_scoped-api-key": scopedUser})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderAPIKey, "hsk_scoped-api-key")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		assert.Equal(t, []uuid.UUID{apiKeyID}, recorder.apiKeyIDs)
+	})
+
+	t.Run("request with the api key of a suspended user is forbidden", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := apiKeyAuthApp(map[string]entities.AuthUser{"valid-api-key": user})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderAPIKey, "suspended-api-key")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusForbidden, response.StatusCode)
+	})
+}
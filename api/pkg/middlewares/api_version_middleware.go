@@ -0,0 +1,84 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIVersionConfig configures the versions of the API which are served by the APIVersion middleware
+type APIVersionConfig struct {
+	// Versions are the path prefixes of the supported versions e.g. v1 and v2
+	Versions []string
+
+	// Default is the version which handles a path without a version e.g. /messages/send is handled by /v1/messages/send.
+	// The paths without a version are not routed when it is empty.
+	Default string
+
+	// Sunset is when the paths without a version stop working, it is sent in the Sunset header when it is not zero
+	Sunset time.Time
+}
+
+// APIVersion stores the version of the API in the path of the request in the user context and in the API-Version
+// response header. A path without a version whose resource exists in the default version is rewritten to the default
+// version during the deprecation window, the response has the Deprecation header and a link to the versioned path.
+// The resources are read from the routes of the app on the first request so all the routes must be registered by then.
+func APIVersion(app *fiber.App, config APIVersionConfig) fiber.Handler {
+	versions := map[string]bool{}
+	for _, version := range config.Versions {
+		versions[version] = true
+	}
+
+	var once sync.Once
+	resources := map[string]bool{}
+	loadResources := func() {
+		for _, route := range app.GetRoutes(true) {
+			if config.Default == "" || firstPathSegment(route.Path) != config.Default {
+				continue
+			}
+			if resource := firstPathSegment(strings.TrimPrefix(route.Path, "/"+config.Default)); resource != "" {
+				resources[resource] = true
+			}
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		version := firstPathSegment(c.Path())
+		if !versions[version] {
+			once.Do(loadResources)
+			if !resources[version] {
+				return c.Next()
+			}
+
+			version = config.Default
+			c.Set("Deprecation", "true")
+			if !config.Sunset.IsZero() {
+				c.Set("Sunset", config.Sunset.UTC().Format(http.TimeFormat))
+			}
+			c.Append(fiber.HeaderLink, fmt.Sprintf(`</%s%s>; rel="successor-version"`, version, c.Path()))
+			c.Path("/" + version + c.Path())
+		}
+
+		c.SetUserContext(telemetry.WithAPIVersion(c.UserContext(), version))
+		c.Set(telemetry.APIVersionHeader, version)
+
+		return c.Next()
+	}
+}
+
+// render writes the envelope of an error in the shape of the version of the API in the request
+func render(c *fiber.Ctx, status int, envelope fiber.Map) error {
+	return c.Status(status).JSON(responses.Envelope(telemetry.APIVersion(c.UserContext()), envelope))
+}
+
+// firstPathSegment returns the first segment of a path e.g. v1 for /v1/messages/send
+func firstPathSegment(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return segment
+}
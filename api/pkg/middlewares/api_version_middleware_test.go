@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAPIVersionApp(config APIVersionConfig) *fiber.App {
+	app := fiber.New()
+	app.Use(APIVersion(app, config))
+
+	handler := func(c *fiber.Ctx) error {
+		return c.SendString(telemetry.APIVersion(c.UserContext()) + " " + c.Route().Path)
+	}
+	app.Get("/v1/messages/:messageID", handler)
+	app.Get("/v2/messages/:messageID", handler)
+	app.Get("/live", handler)
+
+	return app
+}
+
+func TestAPIVersion(t *testing.T) {
+	config := APIVersionConfig{
+		Versions: []string{"v1", "v2"},
+		Default:  "v1",
+		Sunset:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name        string
+		config      APIVersionConfig
+		target      string
+		status      int
+		body        string
+		version     string
+		deprecation string
+		sunset      string
+		link        string
+	}{
+		{
+			name:    "version in the path is stored in the context",
+			config:  config,
+			target:  "/v2/messages/32343a19-da5e-4b1b-a767-3298a73703ca",
+			status:  fiber.StatusOK,
+			body:    "v2 /v2/messages/:messageID",
+			version: "v2",
+		},
+		{
+			name:        "path without a version is handled by the default version",
+			config:      config,
+			target:      "/messages/32343a19-da5e-4b1b-a767-3298a73703ca",
+			status:      fiber.StatusOK,
+			body:        "v1 /v1/messages/:messageID",
+			version:     "v1",
+			deprecation: "true",
+			sunset:      "Wed, 01 Jan 2025 00:00:00 GMT",
+			link:        `</v1/messages/32343a19-da5e-4b1b-a767-3298a73703ca>; rel="successor-version"`,
+		},
+		{
+			name:   "path which is not a resource of the default version is not changed",
+			config: config,
+			target: "/live",
+			status: fiber.StatusOK,
+			body:   " /live",
+		},
+		{
+			name:   "path without a version is not routed when there is no default version",
+			config: APIVersionConfig{Versions: []string{"v1", "v2"}},
+			target: "/messages/32343a19-da5e-4b1b-a767-3298a73703ca",
+			status: fiber.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			app := newAPIVersionApp(test.config)
+
+			// Act
+			response, err := app.Test(httptest.NewRequest(fiber.MethodGet, test.target, nil))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, response.StatusCode)
+			assert.Equal(t, test.version, response.Header.Get(telemetry.APIVersionHeader))
+			assert.Equal(t, test.deprecation, response.Header.Get("Deprecation"))
+			assert.Equal(t, test.sunset, response.Header.Get("Sunset"))
+			assert.Equal(t, test.link, response.Header.Get(fiber.HeaderLink))
+
+			if test.body != "" {
+				body, err := io.ReadAll(response.Body)
+				assert.Nil(t, err)
+				assert.Equal(t, test.body, string(body))
+			}
+		})
+	}
+}
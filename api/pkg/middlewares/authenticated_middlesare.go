@@ -1,15 +1,23 @@
 package middlewares
 
 import (
+	"context"
+
 	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
 )
 
 const (
 	authHeaderBearer = "Authorization"
 	authHeaderAPIKey = "x-api-key"
 	bearerScheme     = "Bearer"
+
+	// authQueryAccessToken is the query parameter of the bearer token in a websocket handshake because browsers cannot set headers on websockets
+	authQueryAccessToken = "access_token"
 )
 
 const (
@@ -24,8 +32,9 @@ func Authenticated(tracer telemetry.Tracer) fiber.Handler {
 		defer span.End()
 
 		if tokenUser, ok := c.Locals(ContextKeyAuthUserID).(entities.AuthUser); !ok || tokenUser.IsNoop() {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			return render(c, fiber.StatusUnauthorized, fiber.Map{
 				"status":  "error",
+				"code":    responses.ErrorCodeUnauthorized,
 				"message": "You are not authorized to carry out this request.",
 				"data":    "Make sure your API key is set in the [x-api-key] header in the request",
 			})
@@ -34,3 +43,27 @@ func Authenticated(tracer telemetry.Tracer) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// responseUserSuspended rejects the request of an entities.User who has been suspended by an administrator
+func responseUserSuspended(c *fiber.Ctx) error {
+	return render(c, fiber.StatusForbidden, fiber.Map{
+		"status":  "error",
+		"code":    responses.ErrorCodeUserSuspended,
+		"message": "Your account has been suspended so you cannot carry out this request.",
+		"data":    "Contact support to reinstate your account",
+	})
+}
+
+// LoadAuthUser fetches the entities.AuthUser of the primary API key of a user or a scoped entities.APIKey
+func LoadAuthUser(ctx context.Context, userRepository repositories.UserRepository, apiKeyRepository repositories.APIKeyRepository, apiKey string) (entities.AuthUser, error) {
+	authUser, err := userRepository.LoadAuthUser(ctx, apiKey)
+	if stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		return authUser, err
+	}
+
+	authUser, err = apiKeyRepository.LoadAuthUser(ctx, apiKey)
+	if err != nil {
+		return authUser, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), "cannot load scoped api key")
+	}
+	return authUser, nil
+}
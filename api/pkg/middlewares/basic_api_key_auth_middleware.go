@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+const basicScheme = "Basic"
+
+// BasicAPIKeyAuth authenticates an API key which is the password of the Basic header e.g. the auth token of a Twilio
+// client whose username is the account SID
+func BasicAPIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepository repositories.UserRepository, apiKeyRepository repositories.APIKeyRepository, usageRecorder APIKeyUsageRecorder) fiber.Handler {
+	logger = logger.WithService("middlewares.BasicAPIKeyAuth")
+
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracer.StartFromFiberCtx(c, "middlewares.BasicAPIKeyAuth")
+		defer span.End()
+
+		ctxLogger := tracer.CtxLogger(logger, span)
+
+		apiKey := basicPassword(c.Get(authHeaderBearer))
+		if len(apiKey) == 0 {
+			span.AddEvent(fmt.Sprintf("the request header has no [%s] api key", basicScheme))
+			return c.Next()
+		}
+
+		authUser, err := LoadAuthUser(ctx, userRepository, apiKeyRepository, apiKey)
+		if stacktrace.GetCode(err) == repositories.ErrCodeUserSuspended {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("the user with api key [%s] is suspended", telemetry.RedactAPIKey(apiKey))))
+			return responseUserSuspended(c)
+		}
+
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load user with api key [%s] using the [%s] header", telemetry.RedactAPIKey(apiKey), basicScheme)))
+			return c.Next()
+		}
+
+		recordAPIKeyUsage(ctx, ctxLogger, usageRecorder, authUser, c)
+
+		c.Locals(ContextKeyAuthUserID, authUser)
+
+		ctxLogger.Info(fmt.Sprintf("[%T] set successfully for user with ID [%s]", authUser, authUser.ID))
+
+		return c.Next()
+	}
+}
+
+// basicPassword returns the password of a Basic authorization header, it is empty when the header is not valid
+func basicPassword(header string) string {
+	if !strings.HasPrefix(header, basicScheme+" ") {
+		return ""
+	}
+
+	credentials, err := base64.StdEncoding.DecodeString(strings.TrimSpace(header[len(basicScheme)+1:]))
+	if err != nil {
+		return ""
+	}
+
+	_, password, _ := strings.Cut(string(credentials), ":")
+	return strings.TrimSpace(password)
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func basicAPIKeyAuthApp(users map[string]entities.AuthUser) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	app.Use(BasicAPIKeyAuth(logger, tracer, &stubUserRepository{users: users}, &stubAPIKeyRepository{users: map[string]entities.AuthUser{}}, new(stubAPIKeyUsageRecorder)))
+	app.Use(Authenticated(tracer))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(string(c.Locals(ContextKeyAuthUserID).(entities.AuthUser).ID))
+	})
+	return app
+}
+
+func TestBasicAPIKeyAuth(t *testing.T) {
+	user := entities.AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "name@email.com"}
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{
+			name:   "request with the api key as the password is authenticated",
+			header: "Basic " + base64.StdEncoding.EncodeToString([]byte("AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1:valid-api-key")),
+			status: fiber.StatusOK,
+		},
+		{
+			name:   "request with an invalid api key is unauthorized",
+			header: "Basic " + base64.StdEncoding.EncodeToString([]byte("AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1:revoked-api-key")),
+			status: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "request with a malformed header is unauthorized",
+			header: "Basic valid-api-key",
+			status: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "request with the api key of a suspended user is forbidden",
+			header: "Basic " + base64.StdEncoding.EncodeToString([]byte("AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1:suspended-api-key")),
+			status: fiber.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Arrange
+			app := basicAPIKeyAuthApp(map[string]entities.AuthUser{"valid-api-key": user})
+			request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			request.Header.Set(fiber.HeaderAuthorization, test.header)
+
+			// Act
+			response, err := app.Test(request)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, response.StatusCode)
+		})
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 // BearerAPIKeyAuth authenticates an API key using the Bearer header
-func BearerAPIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepository repositories.UserRepository) fiber.Handler {
+func BearerAPIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepository repositories.UserRepository, apiKeyRepository repositories.APIKeyRepository, usageRecorder APIKeyUsageRecorder) fiber.Handler {
 	logger = logger.WithService("middlewares.APIKeyAuth")
 
 	return func(c *fiber.Ctx) error {
@@ -26,12 +26,19 @@ func BearerAPIKeyAuth(logger telemetry.Logger, tracer telemetry.Tracer, userRepo
 			return c.Next()
 		}
 
-		authUser, err := userRepository.LoadAuthUser(ctx, apiKey)
+		authUser, err := LoadAuthUser(ctx, userRepository, apiKeyRepository, apiKey)
+		if stacktrace.GetCode(err) == repositories.ErrCodeUserSuspended {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("the user with api key [%s] is suspended", telemetry.RedactAPIKey(apiKey))))
+			return responseUserSuspended(c)
+		}
+
 		if err != nil {
-			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load user with api key [%s] using header [%s]", apiKey, c.Get(authHeaderBearer))))
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load user with api key [%s] using header [%s]", telemetry.RedactAPIKey(apiKey), authHeaderBearer)))
 			return c.Next()
 		}
 
+		recordAPIKeyUsage(ctx, ctxLogger, usageRecorder, authUser, c)
+
 		c.Locals(ContextKeyAuthUserID, authUser)
 
 		ctxLogger.Info(fmt.Sprintf("[%T] set successfully for user with ID [%s]", authUser, authUser.ID))
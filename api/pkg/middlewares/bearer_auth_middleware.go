@@ -5,46 +5,82 @@ import (
 	"fmt"
 	"strings"
 
-	"firebase.google.com/go/auth"
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/gofiber/fiber/v2"
 	"github.com/palantir/stacktrace"
 )
 
-// BearerAuth authenticates a user based on the bearer token
-func BearerAuth(logger telemetry.Logger, tracer telemetry.Tracer, authClient *auth.Client) fiber.Handler {
+const (
+	// ErrCodeTokenExpired is thrown when a bearer ID token is expired
+	ErrCodeTokenExpired = stacktrace.ErrorCode(iota + 100)
+
+	// ErrCodeTokenInvalid is thrown when a bearer ID token cannot be verified
+	ErrCodeTokenInvalid
+)
+
+// UserProvider loads the entities.User of an entities.AuthUser and creates it on the first login
+type UserProvider interface {
+	Get(ctx context.Context, authUser entities.AuthUser) (*entities.User, error)
+}
+
+// BearerAuth authenticates a user based on the bearer token.
+// The x-api-key header takes precedence over the bearer token when both are set.
+func BearerAuth(logger telemetry.Logger, tracer telemetry.Tracer, verifier TokenVerifier, userProvider UserProvider) fiber.Handler {
 	logger = logger.WithService("middlewares.BearerAuth")
 	return func(c *fiber.Ctx) error {
-		_, span := tracer.StartFromFiberCtx(c, "middlewares.BearerAuth")
+		ctx, span := tracer.StartFromFiberCtx(c, "middlewares.BearerAuth")
 		defer span.End()
 
 		authToken := c.Get(authHeaderBearer)
+		if accessToken := c.Query(authQueryAccessToken); authToken == "" && accessToken != "" && strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") {
+			authToken = bearerScheme + " " + accessToken
+		}
+
 		if !strings.HasPrefix(authToken, bearerScheme) {
 			span.AddEvent(fmt.Sprintf("The request header has no [%s] token", bearerScheme))
 			return c.Next()
 		}
 
+		if apiKey := c.Get(authHeaderAPIKey); len(apiKey) > 0 && apiKey != "undefined" {
+			span.AddEvent(fmt.Sprintf("the [%s] header takes precedence over the [%s] token", authHeaderAPIKey, bearerScheme))
+			return c.Next()
+		}
+
 		if len(authToken) > len(bearerScheme)+1 {
 			authToken = authToken[len(bearerScheme)+1:]
 		}
 
 		ctxLogger := tracer.CtxLogger(logger, span)
 
-		token, err := authClient.VerifyIDToken(context.Background(), authToken)
+		authUser, err := verifier.Verify(ctx, authToken)
+		if stacktrace.GetCode(err) == ErrCodeTokenExpired {
+			ctxLogger.Info(fmt.Sprintf("expired [%s] token", bearerScheme))
+			return c.Next()
+		}
+
 		if err != nil {
-			msg := fmt.Sprintf("invalid firebase id token [%s]", authToken)
+			msg := fmt.Sprintf("invalid [%s] token [%s]", bearerScheme, telemetry.RedactToken(authToken))
 			ctxLogger.Warn(tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
 			return c.Next()
 		}
 
 		span.AddEvent(fmt.Sprintf("[%s] token is valid", bearerScheme))
 
-		authUser := entities.AuthUser{
-			Email: token.Claims["email"].(string),
-			ID:    entities.UserID(token.Claims["user_id"].(string)),
+		user, err := userProvider.Get(ctx, authUser)
+		if err != nil {
+			msg := fmt.Sprintf("cannot load user with ID [%s] from [%s] token", authUser.ID, bearerScheme)
+			ctxLogger.Error(tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+			return c.Next()
+		}
+
+		if user.IsSuspended() {
+			ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("the user with ID [%s] is suspended", user.ID)))
+			return responseUserSuspended(c)
 		}
 
+		authUser.IsAdmin = user.IsAdmin
+
 		c.Locals(ContextKeyAuthUserID, authUser)
 
 		ctxLogger.Info(fmt.Sprintf("[%T] set successfully for user with ID [%s]", authUser, authUser.ID))
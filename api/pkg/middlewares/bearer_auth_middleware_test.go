@@ -0,0 +1,174 @@
+package middlewares
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubTokenVerifier verifies a fixed set of ID tokens
+type stubTokenVerifier struct {
+	users map[string]entities.AuthUser
+}
+
+func (verifier *stubTokenVerifier) Verify(_ context.Context, token string) (entities.AuthUser, error) {
+	if token == "expired-token" {
+		return entities.AuthUser{}, stacktrace.NewErrorWithCode(ErrCodeTokenExpired, "token is expired")
+	}
+	if user, ok := verifier.users[token]; ok {
+		return user, nil
+	}
+	return entities.AuthUser{}, stacktrace.NewErrorWithCode(ErrCodeTokenInvalid, "token is invalid")
+}
+
+// stubUserProvider records the users which are provisioned
+type stubUserProvider struct {
+	provisioned []entities.UserID
+}
+
+func (provider *stubUserProvider) Get(_ context.Context, authUser entities.AuthUser) (*entities.User, error) {
+	provider.provisioned = append(provider.provisioned, authUser.ID)
+	return &entities.User{ID: authUser.ID, Email: authUser.Email}, nil
+}
+
+func bearerAuthApp(tokens map[string]entities.AuthUser, apiKeys map[string]entities.AuthUser, provider *stubUserProvider) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	app.Use(BearerAuth(logger, tracer, &stubTokenVerifier{users: tokens}, provider))
+	app.Use(APIKeyAuth(logger, tracer, &stubUserRepository{users: apiKeys}, &stubAPIKeyRepository{users: map[string]entities.AuthUser{}}, new(stubAPIKeyUsageRecorder)))
+	app.Use(Authenticated(tracer))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(string(c.Locals(ContextKeyAuthUserID).(entities.AuthUser).ID))
+	})
+	return app
+}
+
+func TestBearerAuth(t *testing.T) {
+	user := entities.AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "name@email.com"}
+
+	t.Run("request with a valid token is authenticated and provisioned", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := new(stubUserProvider)
+		app := bearerAuthApp(map[string]entities.AuthUser{"valid-token": user}, map[string]entities.AuthUser{}, provider)
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderBearer, "Bearer valid-token")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		assert.Equal(t, []entities.UserID{user.ID}, provider.provisioned)
+	})
+
+	t.Run("request with an expired token is unauthorized", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := new(stubUserProvider)
+		app := bearerAuthApp(map[string]entities.AuthUser{"valid-token": user}, map[string]entities.AuthUser{}, provider)
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderBearer, "Bearer expired-token")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+		assert.Empty(t, provider.provisioned)
+	})
+
+	t.Run("request with an invalid token is unauthorized", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := new(stubUserProvider)
+		app := bearerAuthApp(map[string]entities.AuthUser{"valid-token": user}, map[string]entities.AuthUser{}, provider)
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderBearer, "Bearer invalid-token")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+		assert.Empty(t, provider.provisioned)
+	})
+
+	t.Run("websocket handshake with the token in the query is authenticated", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := new(stubUserProvider)
+		app := bearerAuthApp(map[string]entities.AuthUser{"valid-token": user}, map[string]entities.AuthUser{}, provider)
+		request := httptest.NewRequest(fiber.MethodGet, "/?access_token=valid-token", nil)
+		request.Header.Set(fiber.HeaderUpgrade, "websocket")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		assert.Equal(t, []entities.UserID{user.ID}, provider.provisioned)
+	})
+
+	t.Run("request which is not a websocket handshake cannot send the token in the query", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		provider := new(stubUserProvider)
+		app := bearerAuthApp(map[string]entities.AuthUser{"valid-token": user}, map[string]entities.AuthUser{}, provider)
+		request := httptest.NewRequest(fiber.MethodGet, "/?access_token=valid-token", nil)
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+		assert.Empty(t, provider.provisioned)
+	})
+
+	t.Run("api key takes precedence over the bearer token", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		apiKeyUser := entities.AuthUser{ID: "api-key-user", Email: "api@email.com"}
+		provider := new(stubUserProvider)
+		app := bearerAuthApp(map[string]entities.AuthUser{"valid-token": user}, map[string]entities.AuthUser{"valid-api-key": apiKeyUser}, provider)
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderBearer, "Bearer valid-token")
+		request.Header.Set(authHeaderAPIKey, "valid-api-key")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		assert.Empty(t, provider.provisioned)
+	})
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit limits the size of the body of the requests when the app streams the request bodies. The streamedRoutes
+// are the paths without the version e.g. /messages/import/twilio whose handlers read the body as a stream so the body
+// is not limited, the body of the other routes is read into memory before the handler like when the bodies are not
+// streamed and a body which is larger than the limit is answered with a 413 status code.
+func BodyLimit(limit int, streamedRoutes ...string) fiber.Handler {
+	streamed := make(map[string]bool, len(streamedRoutes))
+	for _, route := range streamedRoutes {
+		streamed[route] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if streamed[strings.TrimPrefix(c.Path(), "/"+firstPathSegment(c.Path()))] {
+			return c.Next()
+		}
+
+		// a multipart form with a content length is parsed before the middlewares so it is only limited by its header
+		if c.Request().Header.ContentLength() > limit {
+			return renderPayloadTooLarge(c, limit)
+		}
+
+		if !c.Request().IsBodyStream() {
+			return c.Next()
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Context().RequestBodyStream(), int64(limit)+1))
+		if err != nil {
+			return render(c, fiber.StatusBadRequest, fiber.Map{
+				"status":  "error",
+				"code":    responses.ErrorCodeBadRequest,
+				"message": "The body of the request could not be read",
+			})
+		}
+
+		if len(body) > limit {
+			return renderPayloadTooLarge(c, limit)
+		}
+
+		c.Request().SetBody(body)
+		return c.Next()
+	}
+}
+
+// renderPayloadTooLarge closes the connection after the response because the rest of the body is not read
+func renderPayloadTooLarge(c *fiber.Ctx, limit int) error {
+	c.Context().SetConnectionClose()
+	return render(c, fiber.StatusRequestEntityTooLarge, fiber.Map{
+		"status":  "error",
+		"code":    responses.ErrorCodePayloadTooLarge,
+		"message": fmt.Sprintf("The body of the request must not be larger than %d bytes", limit),
+	})
+}
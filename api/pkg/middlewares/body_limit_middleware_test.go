@@ -0,0 +1,94 @@
+package middlewares
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBodyLimit = 4 * 1024
+
+func newBodyLimitApp() *fiber.App {
+	app := fiber.New(fiber.Config{StreamRequestBody: true, BodyLimit: 1024})
+	app.Use(BodyLimit(testBodyLimit, "/messages/import/twilio"))
+
+	app.Post("/v1/messages/send", func(c *fiber.Ctx) error {
+		return c.SendString(fmt.Sprintf("%t %d", c.Request().IsBodyStream(), len(c.Body())))
+	})
+	app.Post("/v1/messages/import/twilio", func(c *fiber.Ctx) error {
+		size, err := io.Copy(io.Discard, c.Context().RequestBodyStream())
+		if err != nil {
+			return err
+		}
+		return c.SendString(fmt.Sprintf("%t %d", c.Request().IsBodyStream(), size))
+	})
+	return app
+}
+
+func TestBodyLimit(t *testing.T) {
+	send := func(t *testing.T, path string, size int) (int, string) {
+		response, err := newBodyLimitApp().Test(httptest.NewRequest(fiber.MethodPost, path, bytes.NewReader(bytes.Repeat([]byte("a"), size))), -1)
+		assert.Nil(t, err)
+		body, err := io.ReadAll(response.Body)
+		assert.Nil(t, err)
+		return response.StatusCode, string(body)
+	}
+
+	t.Run("the body of a route which is not streamed is read into memory", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		status, body := send(t, "/v1/messages/send", testBodyLimit)
+
+		// Assert
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, fmt.Sprintf("false %d", testBodyLimit), body)
+	})
+
+	t.Run("a body which is larger than the limit is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		status, body := send(t, "/v1/messages/send", testBodyLimit+1)
+
+		// Assert
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, status)
+		assert.Contains(t, body, "payload_too_large")
+	})
+
+	t.Run("a body which is larger than the limit is rejected when it is not streamed by the server", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := fiber.New(fiber.Config{StreamRequestBody: true})
+		app.Use(BodyLimit(testBodyLimit))
+		app.Post("/v1/bulk-messages", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusAccepted)
+		})
+
+		// Act
+		response, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/v1/bulk-messages", bytes.NewReader(bytes.Repeat([]byte("a"), testBodyLimit+1))), -1)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusRequestEntityTooLarge, response.StatusCode)
+	})
+
+	t.Run("the body of a streamed route is not limited", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		status, body := send(t, "/v1/messages/import/twilio", 4*testBodyLimit)
+
+		// Assert
+		assert.Equal(t, fiber.StatusOK, status)
+		assert.Equal(t, fmt.Sprintf("true %d", 4*testBodyLimit), body)
+	})
+}
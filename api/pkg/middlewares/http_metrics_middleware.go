@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HTTPMetrics records the latency of every request in a telemetry.Histogram labeled by the method, the route and the
+// status code. The route is the path of the matched route e.g. /v1/messages/:messageID/events so that the number of
+// time series does not grow with the IDs in the URL.
+func HTTPMetrics(histogram *telemetry.Histogram) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		statusCode := c.Response().StatusCode()
+		if fiberErr, ok := err.(*fiber.Error); ok {
+			statusCode = fiberErr.Code
+		}
+
+		histogram.Observe(time.Since(start).Seconds(), c.Method(), c.Route().Path, strconv.Itoa(statusCode))
+		return err
+	}
+}
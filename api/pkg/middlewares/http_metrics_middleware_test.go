@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMetrics(t *testing.T) {
+	t.Run("request is recorded with the path of the matched route", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		registry := telemetry.NewMetricsRegistry()
+
+		// Arrange
+		app := fiber.New()
+		app.Use(HTTPMetrics(registry.Histogram("test_duration_seconds", "Test duration", []float64{60}, "method", "route", "status")))
+		app.Get("/v1/messages/:messageID", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusNoContent)
+		})
+		request := httptest.NewRequest(fiber.MethodGet, "/v1/messages/32343a19-da5e-4b1b-a767-3298a73703cb", nil)
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusNoContent, response.StatusCode)
+
+		buffer := new(bytes.Buffer)
+		_, err = registry.WriteTo(buffer)
+		assert.Nil(t, err)
+		assert.Contains(t, buffer.String(), `test_duration_seconds_count{method="GET",route="/v1/messages/:messageID",status="204"} 1`)
+	})
+
+	t.Run("status code of a fiber error is recorded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		registry := telemetry.NewMetricsRegistry()
+
+		// Arrange
+		app := fiber.New()
+		app.Use(HTTPMetrics(registry.Histogram("test_duration_seconds", "Test duration", []float64{60}, "method", "route", "status")))
+		app.Get("/", func(c *fiber.Ctx) error {
+			return fiber.ErrBadRequest
+		})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, response.StatusCode)
+
+		buffer := new(bytes.Buffer)
+		_, err = registry.WriteTo(buffer)
+		assert.Nil(t, err)
+		assert.Contains(t, buffer.String(), `test_duration_seconds_count{method="GET",route="/",status="400"} 1`)
+	})
+}
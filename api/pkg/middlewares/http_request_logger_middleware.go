@@ -27,8 +27,9 @@ func HTTPRequestLogger(tracer telemetry.Tracer, logger telemetry.Logger) fiber.H
 
 		statusCode := c.Response().StatusCode()
 		span.AddEvent(fmt.Sprintf("finished handling request with traceID: [%s], statusCode: [%d]", span.SpanContext().TraceID().String(), statusCode))
-		if statusCode >= 300 && len(c.Request().Body()) > 0 {
-			ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("http.status [%d], body [%s]", statusCode, string(c.Request().Body()))))
+		// a body which is still a stream is not logged because it is not in memory e.g. an uploaded export
+		if statusCode >= 300 && !c.Request().IsBodyStream() && len(c.Request().Body()) > 0 {
+			ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("http.status [%d], body [%s]", statusCode, telemetry.RedactContent(string(c.Request().Body())))))
 		}
 
 		return response
@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPRequestLogger(t *testing.T) {
+	t.Run("message content in the body of a failed request is not logged", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		buffer := new(bytes.Buffer)
+		zl := zerolog.New(buffer)
+		logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+
+		// Arrange
+		app := fiber.New()
+		app.Use(HTTPRequestLogger(telemetry.NewOtelLogger("test", logger), logger))
+		app.Post("/v1/messages/send", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusUnprocessableEntity)
+		})
+		body := `{"from":"+18005550199","to":"+18005550100","content":"Your OTP is 482910"}`
+		request := httptest.NewRequest(fiber.MethodPost, "/v1/messages/send", strings.NewReader(body))
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, response.StatusCode)
+		assert.Contains(t, buffer.String(), "http.status [422]")
+		assert.NotContains(t, buffer.String(), "482910")
+	})
+}
@@ -0,0 +1,56 @@
+package middlewares
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// MinimumAppVersion rejects requests from android apps which are older than the minimum supported version.
+// The check is disabled when the minimum version is empty and requests without a valid semver version are allowed.
+func MinimumAppVersion(logger telemetry.Logger, tracer telemetry.Tracer, minimumVersion string) fiber.Handler {
+	logger = logger.WithService("middlewares.MinimumAppVersion")
+
+	minimum, err := semver.NewVersion(strings.TrimSpace(minimumVersion))
+	if err != nil {
+		if strings.TrimSpace(minimumVersion) != "" {
+			logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot parse minimum app version [%s], the check is disabled", minimumVersion)))
+		}
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		_, span := tracer.StartFromFiberCtx(c, "middlewares.MinimumAppVersion")
+		defer span.End()
+
+		ctxLogger := tracer.CtxLogger(logger, span)
+
+		version, err := semver.NewVersion(strings.TrimSpace(c.Get(clientVersionHeader)))
+		if err != nil {
+			span.AddEvent(fmt.Sprintf("the request header has no valid [%s] header", clientVersionHeader))
+			return c.Next()
+		}
+
+		if !version.LessThan(minimum) {
+			return c.Next()
+		}
+
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("app version [%s] is older than the minimum supported version [%s]", version, minimum)))
+		return render(c, fiber.StatusUpgradeRequired, fiber.Map{
+			"status":  "error",
+			"code":    responses.ErrorCodeUpgradeRequired,
+			"message": fmt.Sprintf("Your httpSMS app version [%s] is no longer supported, update the app to version [%s] or later.", version.Original(), minimum.Original()),
+			"data": fiber.Map{
+				"current_version": version.Original(),
+				"minimum_version": minimum.Original(),
+			},
+		})
+	}
+}
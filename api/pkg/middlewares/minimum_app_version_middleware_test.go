@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMinimumAppVersionApp(minimumVersion string) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	app.Use(MinimumAppVersion(logger, tracer, minimumVersion))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	return app
+}
+
+func TestMinimumAppVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		minimum string
+		version string
+		status  int
+	}{
+		{name: "version below the minimum is rejected", minimum: "1.2.0", version: "1.1.9", status: fiber.StatusUpgradeRequired},
+		{name: "major version below the minimum is rejected", minimum: "1.2.0", version: "0.9.0", status: fiber.StatusUpgradeRequired},
+		{name: "version at the minimum is allowed", minimum: "1.2.0", version: "1.2.0", status: fiber.StatusOK},
+		{name: "version above the minimum is allowed", minimum: "1.2.0", version: "1.2.1", status: fiber.StatusOK},
+		{name: "major version above the minimum is allowed", minimum: "1.2.0", version: "2.0.0", status: fiber.StatusOK},
+		{name: "version with surrounding spaces is compared", minimum: "1.2.0", version: " 1.1.9 ", status: fiber.StatusUpgradeRequired},
+		{name: "pre-release of the minimum is rejected", minimum: "1.2.0", version: "1.2.0-beta.1", status: fiber.StatusUpgradeRequired},
+		{name: "pre-release above the minimum is allowed", minimum: "1.2.0", version: "1.3.0-beta.1", status: fiber.StatusOK},
+		{name: "malformed version is allowed", minimum: "1.2.0", version: "not-a-version", status: fiber.StatusOK},
+		{name: "missing version is allowed", minimum: "1.2.0", version: "", status: fiber.StatusOK},
+		{name: "empty minimum disables the check", minimum: "", version: "0.0.1", status: fiber.StatusOK},
+		{name: "malformed minimum disables the check", minimum: "latest", version: "0.0.1", status: fiber.StatusOK},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			app := newMinimumAppVersionApp(test.minimum)
+
+			request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if test.version != "" {
+				request.Header.Set(clientVersionHeader, test.version)
+			}
+
+			// Act
+			response, err := app.Test(request)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, test.status, response.StatusCode)
+		})
+	}
+
+	t.Run("rejected version is returned with the minimum version", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := newMinimumAppVersionApp("1.2.0")
+
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(clientVersionHeader, "1.1.9")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusUpgradeRequired, response.StatusCode)
+
+		body, err := io.ReadAll(response.Body)
+		assert.Nil(t, err)
+
+		payload := struct {
+			Code string `json:"code"`
+			Data struct {
+				CurrentVersion string `json:"current_version"`
+				MinimumVersion string `json:"minimum_version"`
+			} `json:"data"`
+		}{}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, string(responses.ErrorCodeUpgradeRequired), payload.Code)
+		assert.Equal(t, "1.1.9", payload.Data.CurrentVersion)
+		assert.Equal(t, "1.2.0", payload.Data.MinimumVersion)
+	})
+}
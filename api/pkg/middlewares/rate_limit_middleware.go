@@ -0,0 +1,133 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// RateLimitClassSend is the class of the routes which send messages e.g. /v1/messages/send
+	RateLimitClassSend = "send"
+	// RateLimitClassRead is the class of the GET routes
+	RateLimitClassRead = "read"
+	// RateLimitClassWrite is the class of the routes which change resources without sending messages
+	RateLimitClassWrite = "write"
+)
+
+const (
+	headerRateLimitLimit     = "X-RateLimit-Limit"
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+)
+
+// RateLimitConfig configures the services.RateLimit of the requests of a user, a zero services.RateLimit is not enforced
+type RateLimitConfig struct {
+	// Global limits all the requests of a user
+	Global services.RateLimit
+
+	// APIKey limits the requests which are made with the same API key, the requests of the web app are not limited by it
+	APIKey services.RateLimit
+
+	// Classes limits the requests of a user to a class of routes e.g. RateLimitClassSend
+	Classes map[string]services.RateLimit
+
+	// SendRoutes are the paths without the version of the routes in RateLimitClassSend e.g. /messages/send
+	SendRoutes []string
+}
+
+// RateLimit rejects the requests of an authenticated user which exceed the RateLimitConfig with a 429 status code.
+// The X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers of the most restrictive bucket are added
+// to every response, the reset and the Retry-After header are in seconds.
+func RateLimit(logger telemetry.Logger, tracer telemetry.Tracer, limiter *services.RateLimiter, config RateLimitConfig) fiber.Handler {
+	logger = logger.WithService("middlewares.RateLimit")
+
+	sendRoutes := map[string]bool{}
+	for _, route := range config.SendRoutes {
+		sendRoutes[route] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		_, span := tracer.StartFromFiberCtx(c, "middlewares.RateLimit")
+		defer span.End()
+
+		authUser, ok := c.Locals(ContextKeyAuthUserID).(entities.AuthUser)
+		if !ok || authUser.IsNoop() {
+			return c.Next()
+		}
+
+		class := rateLimitClass(c, sendRoutes)
+		result := limiter.Take(
+			time.Now().UTC(),
+			services.RateLimitBucket{Key: "global:" + string(authUser.ID), Limit: config.Global},
+			services.RateLimitBucket{Key: rateLimitAPIKey(authUser), Limit: rateLimitAPIKeyLimit(c, config)},
+			services.RateLimitBucket{Key: class + ":" + string(authUser.ID), Limit: config.Classes[class]},
+		)
+		if result.Limit == 0 {
+			return c.Next()
+		}
+
+		c.Set(headerRateLimitLimit, strconv.Itoa(result.Limit))
+		c.Set(headerRateLimitRemaining, strconv.Itoa(result.Remaining))
+		c.Set(headerRateLimitReset, strconv.Itoa(seconds(result.Reset)))
+
+		if result.Allowed {
+			return c.Next()
+		}
+
+		retryAfter := seconds(result.RetryAfter)
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+
+		msg := fmt.Sprintf("user with ID [%s] exceeded the [%d] requests rate limit of the [%s] route [%s]", authUser.ID, result.Limit, class, c.Path())
+		tracer.CtxLogger(logger, span).Warn(stacktrace.NewError(msg))
+
+		return render(c, fiber.StatusTooManyRequests, fiber.Map{
+			"status":  "error",
+			"code":    responses.ErrorCodeRateLimited,
+			"message": fmt.Sprintf("You have exceeded the rate limit of [%d] requests, try again in [%d] seconds.", result.Limit, retryAfter),
+		})
+	}
+}
+
+// rateLimitClass returns the class of the route of the request, the version is removed from the path e.g. /v1/messages/send is /messages/send
+func rateLimitClass(c *fiber.Ctx, sendRoutes map[string]bool) string {
+	if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+		return RateLimitClassRead
+	}
+
+	if sendRoutes[strings.TrimPrefix(c.Path(), "/"+firstPathSegment(c.Path()))] {
+		return RateLimitClassSend
+	}
+
+	return RateLimitClassWrite
+}
+
+// rateLimitAPIKey returns the key of the bucket of the API key in the request, the primary API key of a user has the ID of the user
+func rateLimitAPIKey(authUser entities.AuthUser) string {
+	if authUser.APIKeyID != nil {
+		return "api_key:" + authUser.APIKeyID.String()
+	}
+	return "api_key:" + string(authUser.ID)
+}
+
+// rateLimitAPIKeyLimit returns the services.RateLimit of the API key bucket, it is disabled when the request is not made with an API key
+func rateLimitAPIKeyLimit(c *fiber.Ctx, config RateLimitConfig) services.RateLimit {
+	if c.Get(authHeaderAPIKey) == "" {
+		return services.RateLimit{}
+	}
+	return config.APIKey
+}
+
+// seconds rounds a duration up to whole seconds so that a client which waits for it is not rejected again
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,136 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimitApp(authUser entities.AuthUser, config RateLimitConfig) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(ContextKeyAuthUserID, authUser)
+		return c.Next()
+	})
+	app.Use(RateLimit(logger, tracer, services.NewRateLimiter(), config))
+	app.Get("/v1/messages", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/v1/messages/send", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestRateLimit(t *testing.T) {
+	user := entities.AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "user@email.com"}
+
+	t.Run("requests are rejected when the limit of the route class is exhausted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := rateLimitApp(user, RateLimitConfig{
+			Classes: map[string]services.RateLimit{
+				RateLimitClassSend: {Limit: 2, Window: time.Minute},
+			},
+			SendRoutes: []string{"/messages/send"},
+		})
+
+		// Act
+		var results []map[string]string
+		for i := 0; i < 3; i++ {
+			response, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/v1/messages/send", nil))
+			assert.Nil(t, err)
+			results = append(results, map[string]string{
+				"status":    response.Status,
+				"limit":     response.Header.Get(headerRateLimitLimit),
+				"remaining": response.Header.Get(headerRateLimitRemaining),
+				"reset":     response.Header.Get(headerRateLimitReset),
+				"retry":     response.Header.Get(fiber.HeaderRetryAfter),
+			})
+		}
+
+		// Assert
+		assert.Equal(t, map[string]string{"status": "200 OK", "limit": "2", "remaining": "1", "reset": "30", "retry": ""}, results[0])
+		assert.Equal(t, map[string]string{"status": "200 OK", "limit": "2", "remaining": "0", "reset": "60", "retry": ""}, results[1])
+		assert.Equal(t, map[string]string{"status": "429 Too Many Requests", "limit": "2", "remaining": "0", "reset": "60", "retry": "30"}, results[2])
+	})
+
+	t.Run("requests are allowed again after the window", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := rateLimitApp(user, RateLimitConfig{Global: services.RateLimit{Limit: 1, Window: 100 * time.Millisecond}})
+
+		// Act
+		first, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil))
+		assert.Nil(t, err)
+		limited, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil))
+		assert.Nil(t, err)
+
+		time.Sleep(150 * time.Millisecond)
+		recovered, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil))
+		assert.Nil(t, err)
+
+		// Assert
+		assert.Equal(t, fiber.StatusOK, first.StatusCode)
+		assert.Equal(t, fiber.StatusTooManyRequests, limited.StatusCode)
+		assert.Equal(t, "1", limited.Header.Get(fiber.HeaderRetryAfter))
+		assert.Equal(t, fiber.StatusOK, recovered.StatusCode)
+		assert.Equal(t, "0", recovered.Header.Get(headerRateLimitRemaining))
+	})
+
+	t.Run("routes of other classes are not limited by the route class", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := rateLimitApp(user, RateLimitConfig{
+			Classes: map[string]services.RateLimit{
+				RateLimitClassSend: {Limit: 1, Window: time.Minute},
+			},
+			SendRoutes: []string{"/messages/send"},
+		})
+
+		// Act
+		send, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/v1/messages/send", nil))
+		assert.Nil(t, err)
+		read, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil))
+		assert.Nil(t, err)
+
+		// Assert
+		assert.Equal(t, fiber.StatusOK, send.StatusCode)
+		assert.Equal(t, fiber.StatusOK, read.StatusCode)
+		assert.Equal(t, "", read.Header.Get(headerRateLimitLimit))
+	})
+
+	t.Run("API key limit is only applied to requests with an API key", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := rateLimitApp(user, RateLimitConfig{APIKey: services.RateLimit{Limit: 1, Window: time.Minute}})
+
+		// Act
+		var statuses []int
+		for i := 0; i < 2; i++ {
+			apiKeyRequest := httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil)
+			apiKeyRequest.Header.Set(authHeaderAPIKey, "pk_test")
+			response, err := app.Test(apiKeyRequest)
+			assert.Nil(t, err)
+			statuses = append(statuses, response.StatusCode)
+		}
+		browser, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil))
+		assert.Nil(t, err)
+
+		// Assert
+		assert.Equal(t, []int{fiber.StatusOK, fiber.StatusTooManyRequests}, statuses)
+		assert.Equal(t, fiber.StatusOK, browser.StatusCode)
+	})
+}
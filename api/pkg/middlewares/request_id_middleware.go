@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"regexp"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// requestIDPattern limits the request IDs sent by clients so that they are safe to log and to store in cloud events
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID stores the ID of the request in the user context and in the X-Request-ID response header. The ID in the
+// X-Request-ID request header is used when it is valid, otherwise a new ID is generated.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(telemetry.RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.SetUserContext(telemetry.WithRequestID(c.UserContext(), requestID))
+		c.Set(telemetry.RequestIDHeader, requestID)
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequestIDApp(requestID *string) *fiber.App {
+	app := fiber.New()
+	app.Use(RequestID())
+	app.Get("/", func(c *fiber.Ctx) error {
+		*requestID = telemetry.RequestID(c.UserContext())
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	return app
+}
+
+func TestRequestID(t *testing.T) {
+	t.Run("request ID in the header is used", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var requestID string
+		app := newRequestIDApp(&requestID)
+
+		// Arrange
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(telemetry.RequestIDHeader, "req_8f9c71b8-b84e")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "req_8f9c71b8-b84e", requestID)
+		assert.Equal(t, "req_8f9c71b8-b84e", response.Header.Get(telemetry.RequestIDHeader))
+	})
+
+	t.Run("request ID is generated when the header is missing or invalid", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var requestID string
+		app := newRequestIDApp(&requestID)
+
+		// Arrange
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(telemetry.RequestIDHeader, "request id with spaces")
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		_, parseErr := uuid.Parse(requestID)
+		assert.Nil(t, parseErr)
+		assert.Equal(t, requestID, response.Header.Get(telemetry.RequestIDHeader))
+	})
+}
@@ -0,0 +1,67 @@
+package middlewares
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+const authHeaderTeamID = "x-team-id"
+
+// TeamAuth resolves the entities.Team in the X-Team-ID header in which the authenticated user is acting.
+// The phones, messages and webhooks of a team are owned by the account of the team so the ID of the
+// entities.AuthUser is replaced with the ID of the team and the authenticated user is kept in entities.AuthUser.ActorID
+func TeamAuth(logger telemetry.Logger, tracer telemetry.Tracer, teamRepository repositories.TeamRepository) fiber.Handler {
+	logger = logger.WithService("middlewares.TeamAuth")
+
+	return func(c *fiber.Ctx) error {
+		ctx, span := tracer.StartFromFiberCtx(c, "middlewares.TeamAuth")
+		defer span.End()
+
+		ctxLogger := tracer.CtxLogger(logger, span)
+
+		authUser, ok := c.Locals(ContextKeyAuthUserID).(entities.AuthUser)
+		if !ok || authUser.IsNoop() {
+			return c.Next()
+		}
+
+		teamID := entities.UserID(c.Get(authHeaderTeamID))
+		if teamID == "" || teamID == authUser.ID {
+			span.AddEvent(fmt.Sprintf("user [%s] is acting in the personal team", authUser.ID))
+			return c.Next()
+		}
+
+		member, err := teamRepository.LoadMember(ctx, teamID, authUser.ID)
+		if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("user [%s] is not a member of team [%s]", authUser.ID, teamID)))
+			return render(c, fiber.StatusForbidden, fiber.Map{
+				"status":  "error",
+				"code":    responses.ErrorCodeForbidden,
+				"message": fmt.Sprintf("You are not a member of the team [%s] in the [%s] header.", teamID, authHeaderTeamID),
+			})
+		}
+
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load member [%s] of team [%s]", authUser.ID, teamID)))
+			return render(c, fiber.StatusInternalServerError, fiber.Map{
+				"status":  "error",
+				"code":    responses.ErrorCodeInternal,
+				"message": "We ran into an internal error while handling the request.",
+			})
+		}
+
+		actorID := authUser.ID
+		authUser.ActorID = &actorID
+		authUser.ID = member.TeamID
+		authUser.TeamRole = member.Role
+		c.Locals(ContextKeyAuthUserID, authUser)
+
+		ctxLogger.Info(fmt.Sprintf("user [%s] is acting in team [%s] with role [%s]", actorID, member.TeamID, member.Role))
+		return c.Next()
+	}
+}
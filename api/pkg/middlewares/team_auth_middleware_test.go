@@ -0,0 +1,109 @@
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubTeamRepository loads the members of teams from memory
+type stubTeamRepository struct {
+	repositories.TeamRepository
+	members []*entities.TeamMember
+}
+
+func (repository *stubTeamRepository) LoadMember(_ context.Context, teamID entities.UserID, userID entities.UserID) (*entities.TeamMember, error) {
+	for _, member := range repository.members {
+		if member.TeamID == teamID && member.UserID == userID {
+			return member, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "member not found")
+}
+
+func teamAuthApp(authUser entities.AuthUser, members []*entities.TeamMember) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals(ContextKeyAuthUserID, authUser)
+		return c.Next()
+	})
+	app.Use(TeamAuth(logger, tracer, &stubTeamRepository{members: members}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		user := c.Locals(ContextKeyAuthUserID).(entities.AuthUser)
+		return c.SendString(string(user.ID) + ":" + string(user.ActingUserID()) + ":" + user.TeamRole.String())
+	})
+	return app
+}
+
+func TestTeamAuth(t *testing.T) {
+	user := entities.AuthUser{ID: "WB7DRDWrJZRGbYrv2CKGkqbzvqdC", Email: "member@email.com"}
+	teamID := entities.UserID("Kq2tZ9mTeamOwnerAccount7xYz")
+
+	t.Run("request without a team header acts in the personal team", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := teamAuthApp(user, nil)
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		body, _ := io.ReadAll(response.Body)
+		assert.Equal(t, string(user.ID)+":"+string(user.ID)+":", string(body))
+	})
+
+	t.Run("request from a member acts as the team", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := teamAuthApp(user, []*entities.TeamMember{{TeamID: teamID, UserID: user.ID, Role: entities.TeamRoleMember}})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderTeamID, string(teamID))
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusOK, response.StatusCode)
+		body, _ := io.ReadAll(response.Body)
+		assert.Equal(t, string(teamID)+":"+string(user.ID)+":"+entities.TeamRoleMember.String(), string(body))
+	})
+
+	t.Run("request from a user who is not a member is forbidden", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		app := teamAuthApp(user, []*entities.TeamMember{{TeamID: teamID, UserID: "another-user", Role: entities.TeamRoleAdmin}})
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		request.Header.Set(authHeaderTeamID, string(teamID))
+
+		// Act
+		response, err := app.Test(request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusForbidden, response.StatusCode)
+	})
+}
@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// TimeoutConfig configures the deadline of the requests, a request whose deadline is zero has no deadline
+type TimeoutConfig struct {
+	// Read is the deadline of the GET routes
+	Read time.Duration
+
+	// Write is the deadline of the routes which change resources
+	Write time.Duration
+
+	// Routes are the deadlines of the paths without the version which take longer than the other routes of their
+	// method e.g. /messages/bulk-send
+	Routes map[string]time.Duration
+}
+
+// Timeout adds the deadline of the route of the request to the user context so that the services and the repositories
+// stop working on the request when the deadline is exceeded. A request which fails or returns a 5xx status code after
+// its deadline is exceeded is answered with a 504 status code so that it is distinct from the other errors.
+func Timeout(logger telemetry.Logger, tracer telemetry.Tracer, config TimeoutConfig) fiber.Handler {
+	logger = logger.WithService("middlewares.Timeout")
+
+	return func(c *fiber.Ctx) error {
+		timeout := requestTimeout(c, config)
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) || (err == nil && c.Response().StatusCode() < fiber.StatusInternalServerError) {
+			return err
+		}
+
+		_, span := tracer.StartFromFiberCtx(c, "middlewares.Timeout")
+		defer span.End()
+
+		msg := fmt.Sprintf("request [%s %s] was not handled before its deadline of [%s]", c.Method(), c.Path(), timeout)
+		if err == nil {
+			err = stacktrace.NewError(msg)
+		}
+		tracer.CtxLogger(logger, span).Warn(stacktrace.Propagate(err, msg))
+
+		return render(c, fiber.StatusGatewayTimeout, fiber.Map{
+			"status":  "error",
+			"code":    responses.ErrorCodeTimeout,
+			"message": "We could not handle the request before its deadline, try again later.",
+		})
+	}
+}
+
+// requestTimeout returns the deadline of the route of the request, the version is removed from the path e.g. /v1/messages/bulk-send is /messages/bulk-send
+func requestTimeout(c *fiber.Ctx, config TimeoutConfig) time.Duration {
+	if timeout, ok := config.Routes[strings.TrimPrefix(c.Path(), "/"+firstPathSegment(c.Path()))]; ok {
+		return timeout
+	}
+
+	if c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+		return config.Read
+	}
+
+	return config.Write
+}
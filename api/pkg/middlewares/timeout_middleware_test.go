@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/gofiber/fiber/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowRepository is a repository whose queries hang until the context.Context is done
+type slowRepository struct {
+	released chan struct{}
+}
+
+func (repository *slowRepository) Load(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		close(repository.released)
+		return stacktrace.Propagate(ctx.Err(), "cannot load the entity")
+	case <-time.After(time.Minute):
+		return nil
+	}
+}
+
+func timeoutApp(repository *slowRepository, config TimeoutConfig) *fiber.App {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	app := fiber.New()
+	app.Use(Timeout(logger, tracer, config))
+
+	handler := func(c *fiber.Ctx) error {
+		if err := repository.Load(c.UserContext()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error", "code": "internal_error"})
+		}
+		return c.SendStatus(fiber.StatusOK)
+	}
+	app.Get("/v1/messages", handler)
+	app.Post("/v1/messages/send", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	})
+	app.Get("/v1/messages/deadline", func(c *fiber.Ctx) error {
+		deadline, ok := c.UserContext().Deadline()
+		if !ok {
+			return c.JSON(fiber.Map{"deadline": nil})
+		}
+		return c.JSON(fiber.Map{"deadline": time.Until(deadline).Round(time.Minute).String()})
+	})
+	return app
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("slow requests are cut off with a 504 when the deadline is exceeded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := &slowRepository{released: make(chan struct{})}
+		app := timeoutApp(repository, TimeoutConfig{Read: 50 * time.Millisecond})
+
+		// Act
+		start := time.Now()
+		response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages", nil), -1)
+		elapsed := time.Since(start)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusGatewayTimeout, response.StatusCode)
+		assert.Less(t, elapsed, time.Second)
+
+		body, err := io.ReadAll(response.Body)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"status":"error","code":"timeout","message":"We could not handle the request before its deadline, try again later."}`, string(body))
+
+		select {
+		case <-repository.released:
+		default:
+			assert.Fail(t, "the slow repository was not released when the deadline was exceeded")
+		}
+	})
+
+	t.Run("routes with a longer deadline are not cut off by the deadline of their method", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := timeoutApp(nil, TimeoutConfig{
+			Read:   time.Second,
+			Routes: map[string]time.Duration{"/messages/deadline": 2 * time.Minute},
+		})
+
+		// Act
+		response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages/deadline", nil), -1)
+
+		// Assert
+		assert.Nil(t, err)
+		body, err := io.ReadAll(response.Body)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"deadline":"2m0s"}`, string(body))
+	})
+
+	t.Run("routes with a zero deadline have no deadline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := timeoutApp(nil, TimeoutConfig{
+			Read:   time.Second,
+			Routes: map[string]time.Duration{"/messages/deadline": 0},
+		})
+
+		// Act
+		response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/v1/messages/deadline", nil), -1)
+
+		// Assert
+		assert.Nil(t, err)
+		body, err := io.ReadAll(response.Body)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"deadline":null}`, string(body))
+	})
+
+	t.Run("errors before the deadline are not changed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		app := timeoutApp(nil, TimeoutConfig{Write: time.Second})
+
+		// Act
+		response, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/v1/messages/send", nil), -1)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, fiber.StatusInternalServerError, response.StatusCode)
+	})
+}
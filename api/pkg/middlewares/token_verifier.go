@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"context"
+
+	"firebase.google.com/go/auth"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/palantir/stacktrace"
+)
+
+// TokenVerifier verifies a bearer ID token and returns the entities.AuthUser in the token.
+// Self-hosted instances can use their own OIDC provider by implementing this interface.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (entities.AuthUser, error)
+}
+
+// firebaseTokenVerifier verifies firebase ID tokens
+type firebaseTokenVerifier struct {
+	client *auth.Client
+}
+
+// NewFirebaseTokenVerifier creates a TokenVerifier for firebase ID tokens
+func NewFirebaseTokenVerifier(client *auth.Client) TokenVerifier {
+	return &firebaseTokenVerifier{client: client}
+}
+
+// Verify a firebase ID token
+func (verifier *firebaseTokenVerifier) Verify(ctx context.Context, token string) (entities.AuthUser, error) {
+	idToken, err := verifier.client.VerifyIDToken(ctx, token)
+	if auth.IsIDTokenExpired(err) {
+		return entities.AuthUser{}, stacktrace.PropagateWithCode(err, ErrCodeTokenExpired, "the firebase id token is expired")
+	}
+
+	if err != nil {
+		return entities.AuthUser{}, stacktrace.PropagateWithCode(err, ErrCodeTokenInvalid, "cannot verify firebase id token")
+	}
+
+	email, ok := idToken.Claims["email"].(string)
+	if !ok || email == "" {
+		return entities.AuthUser{}, stacktrace.NewErrorWithCode(ErrCodeTokenInvalid, "the firebase id token of user [%s] has no email", idToken.UID)
+	}
+
+	return entities.AuthUser{
+		ID:    entities.UserID(idToken.UID),
+		Email: email,
+	}, nil
+}
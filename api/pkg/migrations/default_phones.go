@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// defaultPhoneIndex is the unique index which allows one default phone per user
+const defaultPhoneIndex = "idx_phones_user_id_is_default"
+
+// setDefaultPhones makes the oldest phone of every user without a default phone the default phone, the phones which
+// were registered before a user had a default phone are not the default. MySQL does not have the partial unique index
+// of the default phone so a generated column which is only set for the default phone is indexed instead.
+func setDefaultPhones(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+
+	var userIDs []entities.UserID
+	err := db.Model(&entities.Phone{}).
+		Group("user_id").
+		Having("SUM(CASE WHEN is_default THEN 1 ELSE 0 END) = 0").
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot fetch the users without a default phone")
+	}
+
+	for _, userID := range userIDs {
+		phone := new(entities.Phone)
+		err = db.Select("id").
+			Where("user_id = ?", userID).
+			Order("created_at ASC").
+			Order("id ASC").
+			Take(phone).Error
+		if err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot load the oldest phone of user with ID [%s]", userID))
+		}
+
+		err = db.Model(&entities.Phone{}).
+			Where("id = ?", phone.ID).
+			UpdateColumn("is_default", true).Error
+		if err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot set phone with ID [%s] as the default phone of user with ID [%s]", phone.ID, userID))
+		}
+	}
+
+	if db.Dialector.Name() != "mysql" {
+		return nil
+	}
+
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&entities.Phone{}, "default_user_id") {
+		if err = db.Exec("ALTER TABLE phones ADD COLUMN default_user_id VARCHAR(191) AS (IF(is_default, user_id, NULL)) STORED").Error; err != nil {
+			return stacktrace.Propagate(err, "cannot add the generated column of the default phone")
+		}
+	}
+
+	if !migrator.HasIndex(&entities.Phone{}, defaultPhoneIndex) {
+		if err = db.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON phones (default_user_id)", defaultPhoneIndex)).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot create the index [%s] of the default phone", defaultPhoneIndex))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestSetDefaultPhones(t *testing.T) {
+	t.Run("the oldest phone of a user without a default phone is the default phone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		db, err := gorm.Open(repositories.NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")), &gorm.Config{TranslateError: true})
+		assert.Nil(t, err)
+		assert.Nil(t, db.AutoMigrate(&entities.Phone{}))
+
+		// Arrange
+		timestamp := time.Now().UTC()
+		newPhone := func(userID entities.UserID, phoneNumber string, age time.Duration, isDefault bool) *entities.Phone {
+			return &entities.Phone{
+				ID:          uuid.New(),
+				UserID:      userID,
+				PhoneNumber: phoneNumber,
+				IsDefault:   isDefault,
+				CreatedAt:   timestamp.Add(-age),
+				UpdatedAt:   timestamp.Add(-age),
+			}
+		}
+
+		oldest := newPhone("user-a", "+18005550199", 2*time.Hour, false)
+		newest := newPhone("user-a", "+18005550198", time.Hour, false)
+		current := newPhone("user-b", "+18005550197", time.Hour, true)
+		older := newPhone("user-b", "+18005550196", 2*time.Hour, false)
+		assert.Nil(t, db.Create([]*entities.Phone{oldest, newest, current, older}).Error)
+
+		// Act
+		firstErr := setDefaultPhones(context.Background(), db)
+		secondErr := setDefaultPhones(context.Background(), db)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+
+		var defaults []uuid.UUID
+		assert.Nil(t, db.Model(&entities.Phone{}).Where("is_default = ?", true).Order("user_id ASC").Pluck("id", &defaults).Error)
+		assert.Equal(t, []uuid.UUID{oldest.ID, current.ID}, defaults)
+	})
+}
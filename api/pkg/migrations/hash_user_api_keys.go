@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// hashUserAPIKeysBatchSize is the number of users whose API keys are hashed in one query
+const hashUserAPIKeysBatchSize = 500
+
+// userPlainTextAPIKey is the plain text API key of a user which was stored before the API keys were hashed
+type userPlainTextAPIKey struct {
+	ID             entities.UserID
+	APIKey         *string
+	PreviousAPIKey *string
+}
+
+// hashUserAPIKeys stores the hash of the plain text api_key and previous_api_key of the users and clears the plain
+// text columns. The columns are not dropped because SQLite recreates the table to drop a column.
+func hashUserAPIKeys(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&entities.User{}, "api_key") {
+		return nil
+	}
+
+	columns := []string{"id", "api_key"}
+	hasPrevious := migrator.HasColumn(&entities.User{}, "previous_api_key")
+	if hasPrevious {
+		columns = append(columns, "previous_api_key")
+	}
+
+	for {
+		var users []userPlainTextAPIKey
+		err := db.Table("users").
+			Select(columns).
+			Where("api_key IS NOT NULL").
+			Where("api_key <> ?", "").
+			Limit(hashUserAPIKeysBatchSize).
+			Find(&users).Error
+		if err != nil {
+			return stacktrace.Propagate(err, "cannot fetch the users with a plain text api key")
+		}
+
+		if len(users) == 0 {
+			break
+		}
+
+		for _, user := range users {
+			updates := map[string]any{
+				"api_key_hash": entities.HashAPIKey(*user.APIKey),
+				"api_key":      nil,
+			}
+			if hasPrevious {
+				updates["previous_api_key"] = nil
+			}
+			if user.PreviousAPIKey != nil && *user.PreviousAPIKey != "" {
+				updates["previous_api_key_hash"] = entities.HashAPIKey(*user.PreviousAPIKey)
+			}
+
+			if err = db.Table("users").Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot hash the api key of user with ID [%s]", user.ID))
+			}
+		}
+	}
+
+	for _, index := range []string{"idx_users_api_key", "idx_users_previous_api_key"} {
+		if !migrator.HasIndex(&entities.User{}, index) {
+			continue
+		}
+		if err := migrator.DropIndex(&entities.User{}, index); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot drop the index [%s] of the plain text api keys", index))
+		}
+	}
+
+	return nil
+}
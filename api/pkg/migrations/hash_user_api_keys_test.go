@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestHashUserAPIKeys(t *testing.T) {
+	t.Run("plain text api keys are replaced with their hash", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		db, err := gorm.Open(repositories.NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")), &gorm.Config{TranslateError: true})
+		assert.Nil(t, err)
+
+		// Arrange
+		assert.Nil(t, db.AutoMigrate(&entities.User{}))
+		assert.Nil(t, db.Exec("ALTER TABLE users ADD COLUMN api_key text").Error)
+		assert.Nil(t, db.Exec("ALTER TABLE users ADD COLUMN previous_api_key text").Error)
+		assert.Nil(t, db.Exec("CREATE INDEX idx_users_api_key ON users (api_key)").Error)
+		assert.Nil(t, db.Exec("INSERT INTO users (id, email, api_key, previous_api_key) VALUES (?, ?, ?, ?), (?, ?, ?, NULL)",
+			"user-a", "a@example.com", "key-a", "previous-key-a",
+			"user-b", "b@example.com", "key-b",
+		).Error)
+
+		// Act
+		firstErr := hashUserAPIKeys(context.Background(), db)
+		secondErr := hashUserAPIKeys(context.Background(), db)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+
+		var users []struct {
+			ID                 string
+			APIKey             *string
+			PreviousAPIKey     *string
+			APIKeyHash         *string
+			PreviousAPIKeyHash *string
+		}
+		assert.Nil(t, db.Table("users").Order("id ASC").Find(&users).Error)
+		assert.Len(t, users, 2)
+
+		assert.Nil(t, users[0].APIKey)
+		assert.Nil(t, users[0].PreviousAPIKey)
+		assert.Equal(t, entities.HashAPIKey("key-a"), *users[0].APIKeyHash)
+		assert.Equal(t, entities.HashAPIKey("previous-key-a"), *users[0].PreviousAPIKeyHash)
+
+		assert.Nil(t, users[1].APIKey)
+		assert.Equal(t, entities.HashAPIKey("key-b"), *users[1].APIKeyHash)
+		assert.Nil(t, users[1].PreviousAPIKeyHash)
+
+		assert.False(t, db.Migrator().HasIndex(&entities.User{}, "idx_users_api_key"))
+	})
+
+	t.Run("a database without plain text api keys is not changed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		db, err := gorm.Open(repositories.NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")), &gorm.Config{TranslateError: true})
+		assert.Nil(t, err)
+
+		// Arrange
+		assert.Nil(t, db.AutoMigrate(&entities.User{}))
+
+		// Act
+		err = hashUserAPIKeys(context.Background(), db)
+
+		// Assert
+		assert.Nil(t, err)
+	})
+}
@@ -0,0 +1,187 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Migration is a versioned SQL change of the database schema or data.
+// The SQL of a migration must be idempotent e.g. CREATE INDEX IF NOT EXISTS because it is applied again when
+// the Runner stops after applying the SQL and before recording the version.
+type Migration struct {
+	Version uint
+	Name    string
+	SQL     string
+
+	// Dialects is the SQL of the migration for the databases which do not support SQL e.g. partial indexes on mysql
+	Dialects map[string]string
+
+	// Func is applied instead of the SQL for a migration which cannot be written in the SQL of every dialect e.g.
+	// hashing with SHA-256 which SQLite does not have, it must be idempotent like the SQL of a migration
+	Func func(ctx context.Context, db *gorm.DB) error
+}
+
+// SQLFor returns the SQL of the migration for a database dialect
+func (migration Migration) SQLFor(dialect string) string {
+	if sql, ok := migration.Dialects[dialect]; ok {
+		return sql
+	}
+	return migration.SQL
+}
+
+// functions are the migrations which are Go functions, their versions follow the versions of the SQL files
+var functions = []Migration{
+	{Version: 3, Name: "hash_user_api_keys", Func: hashUserAPIKeys},
+	{Version: 4, Name: "default_phones", Func: setDefaultPhones},
+}
+
+// models are the entities whose tables are created and altered with GORM before the versioned migrations are applied
+var models = []any{
+	&entities.Message{},
+	&repositories.GormArchivedMessage{},
+	&entities.MessageExport{},
+	&entities.MessageThread{},
+	&entities.EventListenerLog{},
+	&repositories.GormEvent{},
+	&entities.User{},
+	&entities.Phone{},
+	&entities.PhoneSIM{},
+	&entities.PhoneNotification{},
+	&entities.BillingUsage{},
+	&entities.Webhook{},
+	&entities.Discord{},
+	&entities.APIKey{},
+	&entities.TeamMember{},
+	&entities.TeamInvitation{},
+	&entities.DailyMessageUsage{},
+	&entities.Integration3CX{},
+	&entities.TwilioStatusCallback{},
+	&entities.Slack{},
+	&entities.SlackThread{},
+	&entities.Telegram{},
+	&entities.TelegramMessage{},
+	&entities.FallbackProvider{},
+	&entities.NotificationPreference{},
+	&entities.NotificationDigest{},
+	&entities.NotificationDigestMessage{},
+	&entities.SummaryReportSchedule{},
+	&entities.SummaryReport{},
+	&entities.Contact{},
+	&entities.ContactGroup{},
+	&entities.ContactGroupMember{},
+	&entities.ContactLabel{},
+	&entities.NumberLookup{},
+	&entities.RoutingRule{},
+	&entities.MessageRate{},
+	&entities.PhoneDailySendUsage{},
+	&entities.ShortLink{},
+	&entities.Media{},
+	&entities.ContentExpiryRule{},
+}
+
+// Load reads the migrations in a file system ordered by the version.
+// The name of a migration file is the version followed by the name of the migration e.g. 0001_message_indexes.sql
+// and the SQL for a database dialect is in a file with the name of the dialect e.g. 0001_message_indexes.mysql.sql
+func Load(fsys fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot list migration files")
+	}
+
+	migrations := map[uint]*Migration{}
+	dialects := map[uint]map[string]string{}
+	versions := map[string]string{}
+	for _, filePath := range paths {
+		name := strings.TrimSuffix(path.Base(filePath), ".sql")
+		parts := strings.SplitN(name, "_", 2)
+		if len(parts) != 2 {
+			return nil, stacktrace.NewError(fmt.Sprintf("migration file [%s] does not have the format [version_name.sql]", filePath))
+		}
+
+		version, err := strconv.ParseUint(parts[0], 10, 32)
+		if err != nil || version == 0 {
+			return nil, stacktrace.NewError(fmt.Sprintf("migration file [%s] does not start with a positive version", filePath))
+		}
+
+		name, dialect := parts[1], ""
+		if index := strings.LastIndex(name, "."); index != -1 {
+			name, dialect = name[:index], name[index+1:]
+		}
+
+		key := fmt.Sprintf("%d.%s", version, dialect)
+		if existing, ok := versions[key]; ok {
+			return nil, stacktrace.NewError(fmt.Sprintf("migration files [%s] and [%s] have the same version [%d]", existing, filePath, version))
+		}
+		versions[key] = filePath
+
+		content, err := fs.ReadFile(fsys, filePath)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot read migration file [%s]", filePath))
+		}
+
+		if dialect == "" {
+			migrations[uint(version)] = &Migration{Version: uint(version), Name: name, SQL: string(content)}
+			continue
+		}
+
+		if dialects[uint(version)] == nil {
+			dialects[uint(version)] = map[string]string{}
+		}
+		dialects[uint(version)][dialect] = string(content)
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		migration.Dialects = dialects[migration.Version]
+		result = append(result, *migration)
+	}
+
+	for version := range dialects {
+		if _, ok := migrations[version]; !ok {
+			return nil, stacktrace.NewError(fmt.Sprintf("migration [%d] has SQL for a dialect but no migration file with the format [version_name.sql]", version))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// embedded returns the SQL migrations which are embedded in the binary and the functions ordered by the version
+func embedded() ([]Migration, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot load the embedded migration files")
+	}
+
+	for _, function := range functions {
+		for _, migration := range migrations {
+			if migration.Version == function.Version {
+				return nil, stacktrace.NewError(fmt.Sprintf("migration function [%s] and migration [%s] have the same version [%d]", function.Name, migration.Name, function.Version))
+			}
+		}
+		migrations = append(migrations, function)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
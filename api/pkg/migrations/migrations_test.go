@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("migrations are ordered by version", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		fsys := fstest.MapFS{
+			"sql/0010_backfill.sql":       {Data: []byte("SELECT 10;")},
+			"sql/0002_phone_indexes.sql":  {Data: []byte("SELECT 2;")},
+			"sql/0001_message_index.sql":  {Data: []byte("SELECT 1;")},
+			"sql/not_a_migration_file.md": {Data: []byte("readme")},
+		}
+
+		// Act
+		migrations, err := Load(fsys)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, []Migration{
+			{Version: 1, Name: "message_index", SQL: "SELECT 1;"},
+			{Version: 2, Name: "phone_indexes", SQL: "SELECT 2;"},
+			{Version: 10, Name: "backfill", SQL: "SELECT 10;"},
+		}, migrations)
+	})
+
+	t.Run("migrations with the same version are rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		fsys := fstest.MapFS{
+			"sql/0001_message_index.sql": {Data: []byte("SELECT 1;")},
+			"sql/001_phone_index.sql":    {Data: []byte("SELECT 1;")},
+		}
+
+		// Act
+		_, err := Load(fsys)
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+
+	t.Run("migrations have the SQL of a dialect", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		fsys := fstest.MapFS{
+			"sql/0001_message_index.sql":       {Data: []byte("CREATE INDEX IF NOT EXISTS idx ON messages (owner) WHERE deleted_at IS NULL;")},
+			"sql/0001_message_index.mysql.sql": {Data: []byte("CREATE INDEX idx ON messages (owner(32));")},
+			"sql/0002_phone_index.sql":         {Data: []byte("SELECT 2;")},
+		}
+
+		// Act
+		migrations, err := Load(fsys)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, migrations, 2)
+		assert.Equal(t, "message_index", migrations[0].Name)
+		assert.Equal(t, "CREATE INDEX idx ON messages (owner(32));", migrations[0].SQLFor("mysql"))
+		assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx ON messages (owner) WHERE deleted_at IS NULL;", migrations[0].SQLFor("postgres"))
+		assert.Equal(t, "SELECT 2;", migrations[1].SQLFor("mysql"))
+	})
+
+	t.Run("dialect SQL without a migration is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		fsys := fstest.MapFS{
+			"sql/0001_message_index.mysql.sql": {Data: []byte("SELECT 1;")},
+		}
+
+		// Act
+		_, err := Load(fsys)
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+
+	t.Run("embedded migrations are valid", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		migrations, err := embedded()
+
+		// Assert
+		assert.Nil(t, err)
+		assert.NotEmpty(t, migrations)
+		for i, migration := range migrations {
+			assert.Equal(t, uint(i+1), migration.Version)
+			assert.True(t, migration.SQL != "" || migration.Func != nil, migration.Name)
+		}
+	})
+}
+
+func TestMessageThreadUsers(t *testing.T) {
+	t.Run("a thread which was attached to another user is attached to the user of its last message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		db, err := gorm.Open(repositories.NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")), &gorm.Config{TranslateError: true})
+		assert.Nil(t, err)
+		assert.Nil(t, db.AutoMigrate(&entities.Message{}, &entities.MessageThread{}))
+
+		migrations, err := embedded()
+		assert.Nil(t, err)
+
+		var migration Migration
+		for _, item := range migrations {
+			if item.Name == "message_thread_users" {
+				migration = item
+			}
+		}
+
+		// Arrange
+		timestamp := time.Now().UTC()
+		message := &entities.Message{ID: uuid.New(), UserID: "user-a", Owner: "+18005550199", Contact: "+18005550100", CreatedAt: timestamp, UpdatedAt: timestamp}
+		assert.Nil(t, db.Create(message).Error)
+
+		stolen := &entities.MessageThread{ID: uuid.New(), UserID: "user-b", Owner: message.Owner, Contact: message.Contact, LastMessageID: &message.ID, CreatedAt: timestamp, UpdatedAt: timestamp}
+		empty := &entities.MessageThread{ID: uuid.New(), UserID: "user-c", Owner: message.Owner, Contact: message.Contact, CreatedAt: timestamp, UpdatedAt: timestamp}
+		assert.Nil(t, db.Create([]*entities.MessageThread{stolen, empty}).Error)
+
+		// Act
+		firstErr := db.Exec(migration.SQLFor(db.Dialector.Name())).Error
+		secondErr := db.Exec(migration.SQLFor(db.Dialector.Name())).Error
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+
+		var userIDs []entities.UserID
+		assert.Nil(t, db.Model(&entities.MessageThread{}).Where("id IN ?", []uuid.UUID{stolen.ID, empty.ID}).Order("user_id ASC").Pluck("user_id", &userIDs).Error)
+		assert.Equal(t, []entities.UserID{"user-a", "user-c"}, userIDs)
+	})
+}
+
+// TestRunner_Run applies the migrations to the clean database in the DATABASE_URL_TEST environment variable
+func TestRunner_Run(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL_TEST")
+	if dsn == "" {
+		t.Skip("DATABASE_URL_TEST is not set")
+	}
+
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	assert.Nil(t, err)
+
+	runner, err := NewRunner(logger, db)
+	assert.Nil(t, err)
+
+	t.Run("migrations can be applied more than once", func(t *testing.T) {
+		// Act
+		firstErr := runner.Run(context.Background())
+		secondErr := runner.Run(context.Background())
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+
+		var count int64
+		assert.Nil(t, db.Model(&SchemaMigration{}).Count(&count).Error)
+		assert.Equal(t, int64(len(runner.migrations)), count)
+	})
+
+	t.Run("indexes of the hot queries exist", func(t *testing.T) {
+		// Act
+		var indexes []string
+		err = db.Raw("SELECT indexname FROM pg_indexes WHERE tablename = ?", "messages").Scan(&indexes).Error
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Contains(t, indexes, "idx_messages_user_id_owner_contact_order_timestamp")
+		assert.Contains(t, indexes, "idx_messages_user_id_owner_status_order_timestamp")
+	})
+
+	t.Run("the metadata filter uses the GIN index", func(t *testing.T) {
+		// Act
+		var plan []string
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// the planner prefers a sequential scan of the small test table so it is disabled to see if the index can be used
+			if err := tx.Exec("SET LOCAL enable_seqscan = off").Error; err != nil {
+				return err
+			}
+			return tx.Raw("EXPLAIN SELECT id FROM messages WHERE metadata @> ?::jsonb AND deleted_at IS NULL", `{"order_id":"42"}`).Scan(&plan).Error
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Contains(t, strings.Join(plan, "\n"), "idx_messages_metadata")
+	})
+
+	t.Run("event listener logs are unique per handler", func(t *testing.T) {
+		// Act
+		var indexes []string
+		err = db.Raw("SELECT indexname FROM pg_indexes WHERE tablename = ?", "event_listener_logs").Scan(&indexes).Error
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Contains(t, indexes, "idx_event_listener_logs_event_id_handler")
+	})
+}
@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchemaMigration records a Migration which has been applied
+type SchemaMigration struct {
+	Version   uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Runner applies the schema of the entities and the versioned migrations to a database
+type Runner struct {
+	logger     telemetry.Logger
+	db         *gorm.DB
+	models     []any
+	migrations []Migration
+}
+
+// NewRunner creates a Runner with the embedded migrations
+func NewRunner(logger telemetry.Logger, db *gorm.DB) (runner *Runner, err error) {
+	migrations, err := embedded()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot load the embedded migrations")
+	}
+
+	return &Runner{
+		logger:     logger.WithService(fmt.Sprintf("%T", runner)),
+		db:         db,
+		models:     models,
+		migrations: migrations,
+	}, nil
+}
+
+// Run creates the tables of the entities and applies the migrations which have not been applied in order of the version.
+// It is safe to run concurrently because the migrations are idempotent.
+func (runner *Runner) Run(ctx context.Context) error {
+	db := runner.db.WithContext(ctx)
+
+	for _, model := range runner.models {
+		if err := db.AutoMigrate(model); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", model))
+		}
+	}
+
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot migrate %T", &SchemaMigration{}))
+	}
+
+	var applied []uint
+	if err := db.Model(&SchemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return stacktrace.Propagate(err, "cannot fetch the applied migrations")
+	}
+
+	isApplied := map[uint]bool{}
+	for _, version := range applied {
+		isApplied[version] = true
+	}
+
+	for _, migration := range runner.migrations {
+		if isApplied[migration.Version] {
+			continue
+		}
+
+		if err := runner.apply(ctx, db, migration); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot apply migration [%d] [%s]", migration.Version, migration.Name))
+		}
+
+		record := &SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot record migration [%d] [%s]", migration.Version, migration.Name))
+		}
+
+		runner.logger.Info(fmt.Sprintf("applied migration [%d] [%s]", migration.Version, migration.Name))
+	}
+
+	return nil
+}
+
+// apply runs the function of a migration or executes its SQL for the dialect of the database
+func (runner *Runner) apply(ctx context.Context, db *gorm.DB, migration Migration) error {
+	if migration.Func != nil {
+		return migration.Func(ctx, db)
+	}
+	return db.Exec(migration.SQLFor(db.Dialector.Name())).Error
+}
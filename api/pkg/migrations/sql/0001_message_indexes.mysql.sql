@@ -0,0 +1,6 @@
+-- MySQL does not support partial indexes or CREATE INDEX IF NOT EXISTS, the text columns are indexed with a prefix
+CREATE INDEX idx_messages_user_id_owner_contact_order_timestamp
+    ON messages (user_id(64), owner(32), contact(32), order_timestamp DESC);
+
+CREATE INDEX idx_messages_user_id_owner_status_order_timestamp
+    ON messages (user_id(64), owner(32), status(32), order_timestamp);
@@ -0,0 +1,9 @@
+-- MessageRepository.Index fetches the messages between an owner and a contact ordered by the order timestamp
+CREATE INDEX IF NOT EXISTS idx_messages_user_id_owner_contact_order_timestamp
+    ON messages (user_id, owner, contact, order_timestamp DESC)
+    WHERE deleted_at IS NULL;
+
+-- MessageRepository.IndexPending and MessageRepository.CountSendAttemptsSince fetch the messages of an owner by status
+CREATE INDEX IF NOT EXISTS idx_messages_user_id_owner_status_order_timestamp
+    ON messages (user_id, owner, status, order_timestamp)
+    WHERE deleted_at IS NULL;
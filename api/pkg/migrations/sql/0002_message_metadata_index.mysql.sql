@@ -0,0 +1,2 @@
+-- MySQL cannot index a JSON object for JSON_CONTAINS, the metadata filter uses the index of the owner and the contact
+SELECT 1;
@@ -0,0 +1,4 @@
+-- MessageRepository.Index filters the messages by metadata with the @> containment operator of JSONB
+CREATE INDEX IF NOT EXISTS idx_messages_metadata
+    ON messages USING GIN (metadata jsonb_path_ops)
+    WHERE deleted_at IS NULL;
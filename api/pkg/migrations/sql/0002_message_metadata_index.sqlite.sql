@@ -0,0 +1,2 @@
+-- SQLite does not have GIN indexes, the metadata filter uses the index of the owner and the contact
+SELECT 1;
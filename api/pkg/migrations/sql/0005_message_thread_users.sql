@@ -0,0 +1,9 @@
+-- MessageThreadRepository.Update did not filter by the user so a thread could be attached to another user, the thread
+-- is attached again to the user of its last message
+UPDATE message_threads
+SET user_id = (SELECT messages.user_id FROM messages WHERE messages.id = message_threads.last_message_id)
+WHERE EXISTS (
+    SELECT 1 FROM messages
+    WHERE messages.id = message_threads.last_message_id
+      AND messages.user_id <> message_threads.user_id
+);
@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// AccountExportRepository reads and restores all the records of an account for backups and migrations
+type AccountExportRepository interface {
+	// Index fetches a page of the records of a user ordered by the primary key into records which is a pointer to a
+	// slice of entities e.g. *[]entities.Message
+	Index(ctx context.Context, userID entities.UserID, records any, offset int, limit int) error
+
+	// Count the phones, webhooks, contacts, contact groups, message threads and messages of a user
+	Count(ctx context.Context, userID entities.UserID) (int64, error)
+
+	// Store inserts records which is a pointer to a slice of entities, the records whose primary key already exists
+	// are skipped. It returns the number of records which were inserted.
+	Store(ctx context.Context, records any) (int64, error)
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// APIKeyRepository loads and persists an entities.APIKey
+type APIKeyRepository interface {
+	// Store a new entities.APIKey
+	Store(ctx context.Context, apiKey *entities.APIKey) error
+
+	// Index entities.APIKey by entities.UserID
+	Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.APIKey, error)
+
+	// Load an entities.APIKey by ID
+	Load(ctx context.Context, userID entities.UserID, apiKeyID uuid.UUID) (*entities.APIKey, error)
+
+	// Revoke an entities.APIKey so that it can no longer be used
+	Revoke(ctx context.Context, apiKey *entities.APIKey) error
+
+	// LoadAuthUser fetches an entities.AuthUser with the scopes of an entities.APIKey which is not revoked
+	LoadAuthUser(ctx context.Context, key string) (entities.AuthUser, error)
+
+	// RecordUsage sets the last used time and caller of an entities.APIKey, the update is skipped when the entities.APIKey was used after usedAfter
+	RecordUsage(ctx context.Context, apiKeyID uuid.UUID, usedAt time.Time, usedAfter time.Time, ipAddress string, userAgent string) error
+
+	// EvictUser removes the cached entities.AuthUser of all the entities.APIKey of an entities.UserID
+	EvictUser(ctx context.Context, userID entities.UserID) error
+}
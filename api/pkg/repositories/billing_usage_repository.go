@@ -18,6 +18,12 @@ type BillingUsageRepository interface {
 	// GetCurrent returns the current billing usage by entities.UserID
 	GetCurrent(ctx context.Context, userID entities.UserID) (*entities.BillingUsage, error)
 
+	// Get returns the billing usage of an entities.UserID in the month of the timestamp
+	Get(ctx context.Context, userID entities.UserID, timestamp time.Time) (*entities.BillingUsage, error)
+
+	// Rebuild recomputes the billing usage of all users from the messages table and the messages archive
+	Rebuild(ctx context.Context) error
+
 	// GetHistory returns past billing usage by entities.UserID
 	GetHistory(ctx context.Context, userID entities.UserID, params IndexParams) (*[]entities.BillingUsage, error)
 }
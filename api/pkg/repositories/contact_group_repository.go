@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// ContactGroupRepository loads and persists an entities.ContactGroup and its members
+type ContactGroupRepository interface {
+	// Store a new entities.ContactGroup
+	Store(ctx context.Context, group *entities.ContactGroup) error
+
+	// Update an entities.ContactGroup
+	Update(ctx context.Context, group *entities.ContactGroup) error
+
+	// Load an entities.ContactGroup by ID
+	Load(ctx context.Context, userID entities.UserID, groupID uuid.UUID) (*entities.ContactGroup, error)
+
+	// Index entities.ContactGroup of a user
+	Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.ContactGroup, error)
+
+	// Delete an entities.ContactGroup and its members
+	Delete(ctx context.Context, group *entities.ContactGroup) error
+
+	// AddMembers adds the entities.Contact of a user to an entities.ContactGroup and returns the number of new members.
+	// A contact which is already a member is not added again and no member is added when a contact does not exist.
+	AddMembers(ctx context.Context, group *entities.ContactGroup, contactIDs []uuid.UUID) (int, error)
+
+	// RemoveMember removes an entities.Contact from an entities.ContactGroup
+	RemoveMember(ctx context.Context, group *entities.ContactGroup, contactID uuid.UUID) error
+
+	// IndexMembers fetches a page of the entities.Contact in an entities.ContactGroup ordered by name
+	IndexMembers(ctx context.Context, group *entities.ContactGroup, params IndexParams) ([]*entities.Contact, error)
+
+	// FetchMembers fetches all the entities.Contact in an entities.ContactGroup with a single query
+	FetchMembers(ctx context.Context, group *entities.ContactGroup) ([]*entities.Contact, error)
+}
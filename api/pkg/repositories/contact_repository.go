@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// ContactRepository loads and persists an entities.Contact
+type ContactRepository interface {
+	// Upsert sets the entities.Contact of a phone number, a tombstone of the number is replaced
+	Upsert(ctx context.Context, contact *entities.Contact) (*entities.Contact, error)
+
+	// Update an entities.Contact
+	Update(ctx context.Context, contact *entities.Contact) error
+
+	// Sync applies an entry of the address book of a phone to the entities.Contact with the same number. The entry is
+	// ignored when it is older than the contact or when the contact is entities.ContactSourceManual.
+	Sync(ctx context.Context, contact *entities.Contact) (bool, error)
+
+	// SetLabels replaces the labels of an entities.Contact with contact.Labels
+	SetLabels(ctx context.Context, contact *entities.Contact) error
+
+	// RenameLabel renames a label on all the entities.Contact of a user which have it and returns the number of contacts
+	RenameLabel(ctx context.Context, userID entities.UserID, label string, name string) (int, error)
+
+	// Load an entities.Contact by ID, a tombstone is not found
+	Load(ctx context.Context, userID entities.UserID, contactID uuid.UUID) (*entities.Contact, error)
+
+	// Index entities.Contact of a user without the tombstones
+	Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.Contact, error)
+
+	// FetchNames returns the names of the phone numbers which have an entities.Contact
+	FetchNames(ctx context.Context, userID entities.UserID, phoneNumbers []string) (map[string]string, error)
+
+	// FetchByPhoneNumbers returns the entities.Contact of the phone numbers with their labels
+	FetchByPhoneNumbers(ctx context.Context, userID entities.UserID, phoneNumbers []string) (map[string]*entities.Contact, error)
+
+	// FetchDND returns the entities.Contact of the phone numbers which have a do-not-disturb window
+	FetchDND(ctx context.Context, userID entities.UserID, phoneNumbers []string) (map[string]*entities.Contact, error)
+
+	// Delete an entities.Contact
+	Delete(ctx context.Context, contact *entities.Contact) error
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// ContentExpiryRuleRepository loads and persists an entities.ContentExpiryRule
+type ContentExpiryRuleRepository interface {
+	// Store a new entities.ContentExpiryRule
+	Store(ctx context.Context, rule *entities.ContentExpiryRule) error
+
+	// Update an entities.ContentExpiryRule
+	Update(ctx context.Context, rule *entities.ContentExpiryRule) error
+
+	// Load an entities.ContentExpiryRule by ID
+	Load(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) (*entities.ContentExpiryRule, error)
+
+	// Fetch all the entities.ContentExpiryRule of a user ordered by the owner and the creation time
+	Fetch(ctx context.Context, userID entities.UserID) ([]*entities.ContentExpiryRule, error)
+
+	// FetchByOwner fetches the entities.ContentExpiryRule of a user for the messages which are received by an owner
+	FetchByOwner(ctx context.Context, userID entities.UserID, owner string) ([]*entities.ContentExpiryRule, error)
+
+	// Delete an entities.ContentExpiryRule
+	Delete(ctx context.Context, rule *entities.ContentExpiryRule) error
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// DailyMessageUsageRepository loads and persists an entities.DailyMessageUsage
+type DailyMessageUsageRepository interface {
+	// Reserve increments the sent messages of a day by count if the total does not exceed the limit, it returns false when the limit is exceeded
+	Reserve(ctx context.Context, userID entities.UserID, day time.Time, count uint, limit uint) (bool, error)
+
+	// Release decrements the sent messages of a day by count e.g. when messages which were reserved are not sent
+	Release(ctx context.Context, userID entities.UserID, day time.Time, count uint) error
+}
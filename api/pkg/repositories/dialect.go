@@ -0,0 +1,160 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// dialectSqlite is the name of the GORM dialector of SQLite
+	dialectSqlite = "sqlite"
+
+	// dialectMysql is the name of the GORM dialector of MySQL and MariaDB
+	dialectMysql = "mysql"
+)
+
+// ilike is a case-insensitive LIKE condition on a column in the dialect of db.
+// LIKE is case-insensitive in SQLite for ASCII characters only and in MySQL with the default case-insensitive collation.
+func ilike(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case dialectSqlite, dialectMysql:
+		return column + " LIKE ?"
+	default:
+		return column + " ILIKE ?"
+	}
+}
+
+// jsonText is the text value of a key in a JSON column in the dialect of db.
+// MariaDB does not support the ->> operator of postgres, SQLite and MySQL
+func jsonText(db *gorm.DB, column string, key string) string {
+	if db.Dialector.Name() == dialectMysql {
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s'))", column, key)
+	}
+	return fmt.Sprintf("%s->>'%s'", column, key)
+}
+
+// jsonFalse is a condition which is true when a boolean key of a JSON column is false or missing in the dialect of db,
+// SQLite returns a JSON boolean as an integer and postgres and MySQL return it as text
+func jsonFalse(db *gorm.DB, column string, key string) string {
+	if db.Dialector.Name() == dialectSqlite {
+		return fmt.Sprintf("COALESCE(%s, 0) = 0", jsonText(db, column, key))
+	}
+	return fmt.Sprintf("COALESCE(%s, 'false') = 'false'", jsonText(db, column, key))
+}
+
+// jsonNull is a condition which is true when a key of a JSON column is null or missing in the dialect of db, MySQL
+// returns the text "null" for a JSON null
+func jsonNull(db *gorm.DB, column string, key string) string {
+	if db.Dialector.Name() == dialectMysql {
+		return fmt.Sprintf("COALESCE(JSON_TYPE(JSON_EXTRACT(%s, '$.%s')), 'NULL') = 'NULL'", column, key)
+	}
+	return fmt.Sprintf("%s IS NULL", jsonText(db, column, key))
+}
+
+// jsonContains is a condition which is true when the JSON object at the key of a JSON column contains all the pairs
+// in the dialect of db, the object is the column itself when the key is empty. Postgres uses the @> operator which can
+// use a GIN index on the column, SQLite does not have a containment function so every pair is compared on its own.
+func jsonContains(db *gorm.DB, column string, key string, pairs map[string]string) (string, []any) {
+	switch db.Dialector.Name() {
+	case dialectSqlite:
+		keys := make([]string, 0, len(pairs))
+		for name := range pairs {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+
+		path := "$"
+		if key != "" {
+			path += "." + key
+		}
+
+		conditions := make([]string, 0, len(keys))
+		values := make([]any, 0, len(keys)*2)
+		for _, name := range keys {
+			conditions = append(conditions, fmt.Sprintf("json_extract(%s, ?) = ?", column))
+			values = append(values, fmt.Sprintf("%s.%q", path, name), pairs[name])
+		}
+		return strings.Join(conditions, " AND "), values
+	case dialectMysql:
+		// a map of strings is always encoded
+		document, _ := json.Marshal(pairs)
+		if key != "" {
+			return fmt.Sprintf("JSON_CONTAINS(%s, ?, '$.%s')", column, key), []any{string(document)}
+		}
+		return fmt.Sprintf("JSON_CONTAINS(%s, ?)", column), []any{string(document)}
+	default:
+		document, _ := json.Marshal(pairs)
+		if key != "" {
+			return fmt.Sprintf("%s->'%s' @> ?::jsonb", column, key), []any{string(document)}
+		}
+		return fmt.Sprintf("%s @> ?::jsonb", column), []any{string(document)}
+	}
+}
+
+// hourBucket is the start of the UTC hour of a timestamp column in the dialect of db, it is a timestamp in postgres
+// and a text in the "2006-01-02 15:00:00" layout in SQLite and MySQL which do not have date_trunc.
+func hourBucket(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case dialectSqlite:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00', %s)", column)
+	case dialectMysql:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00')", column)
+	default:
+		return fmt.Sprintf("date_trunc('hour', %s)", column)
+	}
+}
+
+// monthBucket is the UTC month of a timestamp column in the "2006-01" layout in the dialect of db
+func monthBucket(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case dialectSqlite:
+		return fmt.Sprintf("strftime('%%Y-%%m', %s)", column)
+	case dialectMysql:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m')", column)
+	default:
+		return fmt.Sprintf("to_char(%s AT TIME ZONE 'UTC', 'YYYY-MM')", column)
+	}
+}
+
+// jsonMonthBucket is the UTC month of an RFC3339 timestamp at a key of a JSON column in the "2006-01" layout in the
+// dialect of db. SQLite converts a timestamp with an offset to UTC, MySQL takes the month of the text because it
+// cannot parse an offset so the timestamp must be in UTC.
+func jsonMonthBucket(db *gorm.DB, column string, key string) string {
+	switch db.Dialector.Name() {
+	case dialectSqlite:
+		return monthBucket(db, jsonText(db, column, key))
+	case dialectMysql:
+		return fmt.Sprintf("LEFT(%s, 7)", jsonText(db, column, key))
+	default:
+		return monthBucket(db, fmt.Sprintf("(%s)::TIMESTAMPTZ", jsonText(db, column, key)))
+	}
+}
+
+// hasPercentileCont is true when the dialect of db has the percentile_cont ordered-set aggregate of postgres
+func hasPercentileCont(db *gorm.DB) bool {
+	switch db.Dialector.Name() {
+	case dialectSqlite, dialectMysql:
+		return false
+	default:
+		return true
+	}
+}
+
+// timestampLayouts are the layouts of a timestamp which is scanned as text, postgres and MySQL return a time which is
+// formatted as RFC3339 and SQLite returns the text of an aggregated timestamp in the layout which its driver stores
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999"}
+
+// parseTimestamp parses a timestamp which is scanned as text in the dialect of any database
+func parseTimestamp(value string) (timestamp time.Time, err error) {
+	for _, layout := range timestampLayouts {
+		if timestamp, err = time.Parse(layout, value); err == nil {
+			return timestamp.UTC(), nil
+		}
+	}
+	return timestamp, err
+}
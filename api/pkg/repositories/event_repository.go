@@ -3,7 +3,10 @@ package repositories
 import (
 	"context"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
 )
 
 // EventRepository is responsible for persisting cloudevents.Event
@@ -16,4 +19,11 @@ type EventRepository interface {
 
 	// FetchAll returns all cloudevents.Event ordered by time in ascending order
 	FetchAll(ctx context.Context) (*[]cloudevents.Event, error)
+
+	// FetchByMessageID returns the cloudevents.Event whose payload references the entities.Message ordered by time in ascending order
+	FetchByMessageID(ctx context.Context, messageID uuid.UUID) (*[]cloudevents.Event, error)
+
+	// FetchByUserIDAfter returns at most limit cloudevents.Event of the entities.User which were emitted after the event with the ID, ordered by time in ascending order.
+	// An error with the ErrCodeNotFound code is returned when the user has no event with the ID
+	FetchByUserIDAfter(ctx context.Context, userID entities.UserID, eventID uuid.UUID, limit int) (*[]cloudevents.Event, error)
 }
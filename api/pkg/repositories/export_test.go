@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/stretchr/testify/assert"
+)
+
+// ConformanceBackend exposes the repositories of a testBackend to the conformance tests in the repositories_test
+// package, the conformance tests cannot be in this package because repositorytest imports it
+type ConformanceBackend struct {
+	Name                          string
+	NewMessageRepository          func() MessageRepository
+	NewEventListenerLogRepository func() EventListenerLogRepository
+	NewEventRepository            func() EventRepository
+	NewPhoneRepository            func() PhoneRepository
+	NewWebhookRepository          func() WebhookRepository
+	NewMessageThreadRepository    func() MessageThreadRepository
+	NewAPIKeyRepository           func() APIKeyRepository
+	NewUserRepository             func() UserRepository
+	NewBillingUsageRepository     func() BillingUsageRepository
+}
+
+// ConformanceBackends returns a ConformanceBackend for every database in testBackends
+func ConformanceBackends(t *testing.T) []ConformanceBackend {
+	backends := make([]ConformanceBackend, 0)
+	for _, backend := range testBackends(t) {
+		backend := backend
+		cache, err := ristretto.NewCache(&ristretto.Config{MaxCost: 100, NumCounters: 1000, BufferItems: 64})
+		assert.Nil(t, err)
+
+		backends = append(backends, ConformanceBackend{
+			Name: backend.name,
+			NewMessageRepository: func() MessageRepository {
+				return NewGormMessageRepository(backend.logger, backend.tracer, backend.db, nil)
+			},
+			NewEventListenerLogRepository: func() EventListenerLogRepository {
+				return NewGormEventListenerLogRepository(backend.logger, backend.tracer, backend.db)
+			},
+			NewEventRepository: func() EventRepository {
+				return NewGormEventRepository(backend.logger, backend.tracer, backend.db)
+			},
+			NewPhoneRepository: func() PhoneRepository {
+				return NewGormPhoneRepository(backend.logger, backend.tracer, backend.db)
+			},
+			NewWebhookRepository: func() WebhookRepository {
+				return NewGormWebhookRepository(backend.logger, backend.tracer, backend.db)
+			},
+			NewMessageThreadRepository: func() MessageThreadRepository {
+				return NewGormMessageThreadRepository(backend.logger, backend.tracer, backend.db, backend.db)
+			},
+			NewAPIKeyRepository: func() APIKeyRepository {
+				return NewGormAPIKeyRepository(backend.logger, backend.tracer, cache, backend.db)
+			},
+			NewUserRepository: func() UserRepository {
+				return NewGormUserRepository(backend.logger, backend.tracer, cache, backend.db)
+			},
+			NewBillingUsageRepository: func() BillingUsageRepository {
+				return NewGormBillingUsageRepository(backend.logger, backend.tracer, backend.db, backend.db)
+			},
+		})
+	}
+	return backends
+}
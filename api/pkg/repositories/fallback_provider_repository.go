@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// FallbackProviderRepository loads and persists an entities.FallbackProvider
+type FallbackProviderRepository interface {
+	// Save Upsert the entities.FallbackProvider of a user
+	Save(ctx context.Context, provider *entities.FallbackProvider) error
+
+	// Load the entities.FallbackProvider of a user
+	Load(ctx context.Context, userID entities.UserID) (*entities.FallbackProvider, error)
+
+	// LoadByID loads an entities.FallbackProvider by ID for the status callbacks of the provider which are not authenticated with an API key
+	LoadByID(ctx context.Context, providerID uuid.UUID) (*entities.FallbackProvider, error)
+
+	// Delete the entities.FallbackProvider of a user
+	Delete(ctx context.Context, userID entities.UserID) error
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// accountExportStoreBatchSize is the number of records which are inserted in one query when an account is imported
+const accountExportStoreBatchSize = 100
+
+// gormAccountExportRepository is responsible for reading and restoring the records of an account
+type gormAccountExportRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormAccountExportRepository creates the GORM version of the AccountExportRepository
+func NewGormAccountExportRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) AccountExportRepository {
+	return &gormAccountExportRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormAccountExportRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormAccountExportRepository) Index(ctx context.Context, userID entities.UserID, records any, offset int, limit int) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	statement := &gorm.Statement{DB: repository.db}
+	if err := statement.Parse(records); err != nil {
+		msg := fmt.Sprintf("cannot parse the schema of [%T]", records)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
+	if statement.Schema.LookUpField("deleted_at") != nil {
+		query = query.Where("deleted_at IS NULL")
+	}
+
+	// the primary key is unique so that a record is not on two pages
+	for _, column := range statement.Schema.PrimaryFieldDBNames {
+		query = query.Order(column)
+	}
+
+	if err := query.Offset(offset).Limit(limit).Find(records).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch [%T] of user [%s] with offset [%d] and limit [%d]", records, userID, offset, limit)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormAccountExportRepository) Count(ctx context.Context, userID entities.UserID) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	models := []any{
+		&entities.Phone{},
+		&entities.Webhook{},
+		&entities.ContactGroup{},
+		&entities.MessageThread{},
+		&entities.Message{},
+	}
+
+	var total int64
+	for _, model := range models {
+		var count int64
+		if err := repository.db.WithContext(ctx).Model(model).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			msg := fmt.Sprintf("cannot count [%T] of user [%s]", model, userID)
+			return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		total += count
+	}
+
+	var contacts int64
+	err := repository.db.WithContext(ctx).Model(&entities.Contact{}).Where("user_id = ?", userID).Where("deleted_at IS NULL").Count(&contacts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count contacts of user [%s]", userID)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return total + contacts, nil
+}
+
+func (repository *gormAccountExportRepository) Store(ctx context.Context, records any) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(records, accountExportStoreBatchSize)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot store [%T]", records)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected, nil
+}
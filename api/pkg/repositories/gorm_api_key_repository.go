@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/dgraph-io/ristretto"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// apiKeyCacheTTL is the duration in which an entities.AuthUser is cached
+const apiKeyCacheTTL = 10 * time.Minute
+
+// gormAPIKeyRepository is responsible for persisting entities.APIKey
+type gormAPIKeyRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	cache  *ristretto.Cache
+	db     *gorm.DB
+}
+
+// NewGormAPIKeyRepository creates the GORM version of the APIKeyRepository
+func NewGormAPIKeyRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	cache *ristretto.Cache,
+	db *gorm.DB,
+) APIKeyRepository {
+	return &gormAPIKeyRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormAPIKeyRepository{})),
+		tracer: tracer,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// Store a new entities.APIKey
+func (repository *gormAPIKeyRepository) Store(ctx context.Context, apiKey *entities.APIKey) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(apiKey).Error; err != nil {
+		msg := fmt.Sprintf("cannot save api key with ID [%s]", apiKey.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// Index entities.APIKey of a user
+func (repository *gormAPIKeyRepository) Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.APIKey, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
+	if len(params.Query) > 0 {
+		query.Where(ilike(repository.db, "label"), "%"+params.Query+"%")
+	}
+
+	apiKeys := make([]*entities.APIKey, 0)
+	if err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Skip).Find(&apiKeys).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch api keys for user [%s] and params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return apiKeys, nil
+}
+
+// Load an entities.APIKey by ID
+func (repository *gormAPIKeyRepository) Load(ctx context.Context, userID entities.UserID, apiKeyID uuid.UUID) (*entities.APIKey, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	apiKey := new(entities.APIKey)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", apiKeyID).First(apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("api key with ID [%s] for user [%s] does not exist", apiKeyID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load api key with ID [%s] for user [%s]", apiKeyID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return apiKey, nil
+}
+
+// Revoke an entities.APIKey so that it can no longer be used
+func (repository *gormAPIKeyRepository) Revoke(ctx context.Context, apiKey *entities.APIKey) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	revokedAt := time.Now().UTC()
+	err := repository.db.WithContext(ctx).
+		Model(apiKey).
+		Updates(map[string]any{
+			"revoked_at": revokedAt,
+			"updated_at": revokedAt,
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot revoke api key with ID [%s] for user [%s]", apiKey.ID, apiKey.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	repository.cache.Del(repository.cacheKey(apiKey.KeyHash))
+
+	apiKey.RevokedAt = &revokedAt
+	return nil
+}
+
+// LoadAuthUser fetches an entities.AuthUser with the scopes of an entities.APIKey which is not revoked
+func (repository *gormAPIKeyRepository) LoadAuthUser(ctx context.Context, key string) (entities.AuthUser, error) {
+	ctx, span, ctxLogger := repository.tracer.StartWithLogger(ctx, repository.logger)
+	defer span.End()
+
+	keyHash := entities.HashAPIKey(key)
+	if authUser, found := repository.cache.Get(repository.cacheKey(keyHash)); found {
+		ctxLogger.Info(fmt.Sprintf("cache hit for user with ID [%s]", authUser.(entities.AuthUser).ID))
+		return authUser.(entities.AuthUser), nil
+	}
+
+	apiKey := new(entities.APIKey)
+	err := repository.db.WithContext(ctx).Where("key_hash = ?", keyHash).Where("revoked_at IS NULL").First(apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("api key with hash [%s] does not exist", keyHash)
+		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := "cannot load api key from the database"
+		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	user := new(entities.User)
+	if err = repository.db.WithContext(ctx).Select("id", "email", "is_admin", "suspended_at").Where("id = ?", apiKey.UserID).First(user).Error; err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s] for api key with ID [%s]", apiKey.UserID, apiKey.ID)
+		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if user.IsSuspended() {
+		msg := fmt.Sprintf("user with ID [%s] of api key with ID [%s] is suspended", user.ID, apiKey.ID)
+		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeUserSuspended, msg))
+	}
+
+	authUser := entities.AuthUser{
+		ID:       user.ID,
+		Email:    user.Email,
+		IsAdmin:  user.IsAdmin,
+		APIKeyID: &apiKey.ID,
+		Scopes:   apiKey.ScopeList(),
+	}
+
+	if result := repository.cache.SetWithTTL(repository.cacheKey(keyHash), authUser, 1, apiKeyCacheTTL); !result {
+		msg := fmt.Sprintf("cannot cache [%T] with ID [%s] and result [%t]", authUser, user.ID, result)
+		ctxLogger.Error(repository.tracer.WrapErrorSpan(span, stacktrace.NewError(msg)))
+	}
+
+	return authUser, nil
+}
+
+// RecordUsage sets the last used time and caller of an entities.APIKey, the update is skipped when the entities.APIKey was used after usedAfter
+func (repository *gormAPIKeyRepository) RecordUsage(ctx context.Context, apiKeyID uuid.UUID, usedAt time.Time, usedAfter time.Time, ipAddress string, userAgent string) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).
+		Model(&entities.APIKey{}).
+		Where("id = ?", apiKeyID).
+		Where(repository.db.Where("last_used_at IS NULL").Or("last_used_at < ?", usedAfter)).
+		UpdateColumns(map[string]any{
+			"last_used_at":         usedAt,
+			"last_used_ip_address": ipAddress,
+			"last_used_user_agent": userAgent,
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot record usage of api key with ID [%s]", apiKeyID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// EvictUser removes the cached entities.AuthUser of all the entities.APIKey of an entities.UserID
+func (repository *gormAPIKeyRepository) EvictUser(ctx context.Context, userID entities.UserID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var keyHashes []string
+	err := repository.db.WithContext(ctx).Model(&entities.APIKey{}).Where("user_id = ?", userID).Pluck("key_hash", &keyHashes).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch api keys of user with ID [%s]", userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, keyHash := range keyHashes {
+		repository.cache.Del(repository.cacheKey(keyHash))
+	}
+
+	return nil
+}
+
+func (repository *gormAPIKeyRepository) cacheKey(keyHash string) string {
+	return "api-key:" + keyHash
+}
@@ -13,13 +13,15 @@ import (
 	"github.com/jinzhu/now"
 	"github.com/palantir/stacktrace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // gormBillingUsageRepository is responsible for persisting entities.BillingUsage
 type gormBillingUsageRepository struct {
-	logger telemetry.Logger
-	tracer telemetry.Tracer
-	db     *gorm.DB
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	db      *gorm.DB
+	replica *gorm.DB
 }
 
 // NewGormBillingUsageRepository creates the GORM version of the BillingUsageRepository
@@ -27,11 +29,13 @@ func NewGormBillingUsageRepository(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	db *gorm.DB,
+	replica *gorm.DB,
 ) BillingUsageRepository {
 	return &gormBillingUsageRepository{
-		logger: logger.WithService(fmt.Sprintf("%T", &gormBillingUsageRepository{})),
-		tracer: tracer,
-		db:     db,
+		logger:  logger.WithService(fmt.Sprintf("%T", &gormBillingUsageRepository{})),
+		tracer:  tracer,
+		db:      db,
+		replica: replica,
 	}
 }
 
@@ -40,20 +44,11 @@ func (repository *gormBillingUsageRepository) RegisterSentMessage(ctx context.Co
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	return crdbgorm.ExecuteTx(ctx, repository.db, nil,
-		func(tx *gorm.DB) error {
-			result := tx.WithContext(ctx).
-				Model(&entities.BillingUsage{}).
-				Where("start_timestamp = ?", now.New(timestamp).BeginningOfMonth()).
-				Where("user_id = ?", userID).
-				UpdateColumn("sent_messages", gorm.Expr("sent_messages + ?", 1))
-
-			if result.Error == nil && result.RowsAffected == 0 {
-				return tx.Create(repository.createBillingUsage(userID, timestamp, 1, 0)).Error
-			}
-			return result.Error
-		},
-	)
+	if err := repository.increment(ctx, userID, timestamp, 1, 0); err != nil {
+		msg := fmt.Sprintf("cannot register sent message for user [%s] at [%s]", userID, timestamp)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return nil
 }
 
 // RegisterReceivedMessage registers a message as received
@@ -61,20 +56,11 @@ func (repository *gormBillingUsageRepository) RegisterReceivedMessage(ctx contex
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	return crdbgorm.ExecuteTx(ctx, repository.db, nil,
-		func(tx *gorm.DB) error {
-			result := tx.WithContext(ctx).
-				Model(&entities.BillingUsage{}).
-				Where("start_timestamp = ?", now.New(timestamp).BeginningOfMonth()).
-				Where("user_id = ?", userID).
-				UpdateColumn("received_messages", gorm.Expr("received_messages + ?", 1))
-
-			if result.Error == nil && result.RowsAffected == 0 {
-				return tx.Create(repository.createBillingUsage(userID, timestamp, 0, 1)).Error
-			}
-			return result.Error
-		},
-	)
+	if err := repository.increment(ctx, userID, timestamp, 0, 1); err != nil {
+		msg := fmt.Sprintf("cannot register received message for user [%s] at [%s]", userID, timestamp)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return nil
 }
 
 // GetCurrent returns the current billing usage by entities.UserID
@@ -87,25 +73,142 @@ func (repository *gormBillingUsageRepository) GetCurrent(ctx context.Context, us
 
 	err := crdbgorm.ExecuteTx(ctx, repository.db, nil,
 		func(tx *gorm.DB) error {
-			loadedUsage := &entities.BillingUsage{}
-			result := tx.WithContext(ctx).
+			err := tx.WithContext(ctx).
+				Clauses(clause.OnConflict{Columns: repository.conflictColumns(), DoNothing: true}).
+				Create(repository.createBillingUsage(userID, timestamp, 0, 0)).
+				Error
+			if err != nil {
+				return err
+			}
+
+			return tx.WithContext(ctx).
 				Where("user_id = ?", userID).
 				Where("start_timestamp = ?", now.New(timestamp).BeginningOfMonth()).
-				First(&loadedUsage)
+				First(usage).
+				Error
+		},
+	)
+	if err != nil {
+		return usage, stacktrace.Propagate(err, fmt.Sprintf("cannot load billing usage for user [%s]", userID))
+	}
+
+	return usage, err
+}
+
+// Get returns the billing usage of an entities.UserID in the month of the timestamp
+func (repository *gormBillingUsageRepository) Get(ctx context.Context, userID entities.UserID, timestamp time.Time) (*entities.BillingUsage, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	usage := new(entities.BillingUsage)
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("start_timestamp = ?", now.New(timestamp).BeginningOfMonth()).
+		First(usage).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.createBillingUsage(userID, timestamp, 0, 0), nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load billing usage for user [%s] at [%s]", userID, timestamp)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return usage, nil
+}
+
+// Rebuild recomputes the billing usage of all users from the messages table and the messages archive, the messages
+// which were imported from another provider are not counted
+func (repository *gormBillingUsageRepository) Rebuild(ctx context.Context) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := crdbgorm.ExecuteTx(ctx, repository.db, nil,
+		func(tx *gorm.DB) error {
+			err := tx.WithContext(ctx).
+				Model(&entities.BillingUsage{}).
+				Where("1 = 1").
+				Updates(map[string]any{"sent_messages": 0, "received_messages": 0}).
+				Error
+			if err != nil {
+				return err
+			}
+
+			rows, err := repository.monthlyMessages(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			timestamp := time.Now().UTC()
+			usages := make([]*entities.BillingUsage, 0, len(rows))
+			for _, row := range rows {
+				month, err := time.Parse("2006-01", row.Month)
+				if err != nil {
+					return stacktrace.Propagate(err, fmt.Sprintf("cannot parse the month [%s] of the messages of user [%s]", row.Month, row.UserID))
+				}
 
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return tx.WithContext(ctx).Create(usage).Error
+				usage := repository.createBillingUsage(row.UserID, month, row.SentMessages, row.ReceivedMessages)
+				usage.CreatedAt = timestamp
+				usage.UpdatedAt = timestamp
+				usages = append(usages, usage)
 			}
 
-			*usage = *loadedUsage
-			return result.Error
+			if len(usages) == 0 {
+				return nil
+			}
+
+			return tx.WithContext(ctx).
+				Clauses(clause.OnConflict{
+					Columns:   repository.conflictColumns(),
+					DoUpdates: clause.AssignmentColumns([]string{"sent_messages", "received_messages", "updated_at"}),
+				}).
+				CreateInBatches(usages, billingUsageRebuildBatchSize).
+				Error
 		},
 	)
 	if err != nil {
-		return usage, stacktrace.Propagate(err, fmt.Sprintf("cannot load billing usage for user [%s]", userID))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot rebuild billing usages from messages"))
 	}
 
-	return usage, err
+	return nil
+}
+
+// billingUsageRebuildBatchSize is the number of entities.BillingUsage which are stored in one INSERT by Rebuild
+const billingUsageRebuildBatchSize = 500
+
+// monthlyMessagesRow is the number of messages of a user in a month, the month is scanned as text in the "2006-01" layout
+type monthlyMessagesRow struct {
+	UserID           entities.UserID
+	Month            string
+	SentMessages     uint
+	ReceivedMessages uint
+}
+
+// monthlyMessages counts the sent and received messages of every user in each UTC month in the messages table and the messages archive
+func (repository *gormBillingUsageRepository) monthlyMessages(ctx context.Context, tx *gorm.DB) ([]monthlyMessagesRow, error) {
+	query := fmt.Sprintf(`
+SELECT user_id, month,
+	SUM(CASE WHEN type = @sent THEN 1 ELSE 0 END) AS sent_messages,
+	SUM(CASE WHEN type = @received THEN 1 ELSE 0 END) AS received_messages
+FROM (
+	SELECT user_id, type, %s AS month FROM messages WHERE imported_at IS NULL
+	UNION ALL
+	SELECT user_id, %s, %s FROM messages_archive WHERE %s
+) AS monthly_messages
+GROUP BY user_id, month`,
+		monthBucket(tx, "request_received_at"),
+		jsonText(tx, "data", "type"),
+		jsonMonthBucket(tx, "data", "request_received_at"),
+		jsonNull(tx, "data", "imported_at"),
+	)
+
+	var rows []monthlyMessagesRow
+	err := tx.WithContext(ctx).Raw(query, map[string]any{
+		"sent":     entities.MessageTypeMobileTerminated,
+		"received": entities.MessageTypeMobileOriginated,
+	}).Scan(&rows).Error
+	return rows, err
 }
 
 // GetHistory returns past billing usage by entities.UserID
@@ -115,7 +218,7 @@ func (repository *gormBillingUsageRepository) GetHistory(ctx context.Context, us
 
 	usages := new([]entities.BillingUsage)
 
-	err := repository.db.WithContext(ctx).
+	err := replicaFromContext(ctx, repository.db, repository.replica).
 		Where("user_id = ?", userID).
 		Where("start_timestamp != ?", now.BeginningOfMonth()).
 		Order("start_timestamp DESC").
@@ -131,6 +234,25 @@ func (repository *gormBillingUsageRepository) GetHistory(ctx context.Context, us
 	return usages, err
 }
 
+// increment adds the sent and received messages to the billing usage in a single atomic statement
+func (repository *gormBillingUsageRepository) increment(ctx context.Context, userID entities.UserID, timestamp time.Time, sent uint, received uint) error {
+	return repository.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: repository.conflictColumns(),
+			DoUpdates: clause.Assignments(map[string]any{
+				"sent_messages":     gorm.Expr("billing_usages.sent_messages + ?", sent),
+				"received_messages": gorm.Expr("billing_usages.received_messages + ?", received),
+				"updated_at":        time.Now().UTC(),
+			}),
+		}).
+		Create(repository.createBillingUsage(userID, timestamp, sent, received)).
+		Error
+}
+
+func (repository *gormBillingUsageRepository) conflictColumns() []clause.Column {
+	return []clause.Column{{Name: "user_id"}, {Name: "start_timestamp"}}
+}
+
 func (repository *gormBillingUsageRepository) createBillingUsage(userID entities.UserID, timestamp time.Time, sent uint, received uint) *entities.BillingUsage {
 	return &entities.BillingUsage{
 		ID:               uuid.New(),
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormBillingUsageRepository_ConcurrentIncrements verifies that concurrent increments of a month which does not
+// have a billing usage yet are all counted
+func TestGormBillingUsageRepository_ConcurrentIncrements(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormBillingUsageRepository(backend.logger, backend.tracer, backend.db, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			const writers = 20
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			timestamp := time.Date(2002, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+			wg := sync.WaitGroup{}
+			errs := make(chan error, writers*2)
+
+			// Act
+			for i := 0; i < writers; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					errs <- repository.RegisterSentMessage(ctx, timestamp, userID)
+				}()
+				go func() {
+					defer wg.Done()
+					errs <- repository.RegisterReceivedMessage(ctx, timestamp, userID)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			// Assert
+			for err := range errs {
+				assert.Nil(t, err)
+			}
+
+			usage, err := repository.Get(ctx, userID, timestamp)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(writers), usage.SentMessages)
+			assert.Equal(t, uint(writers), usage.ReceivedMessages)
+
+			var count int64
+			assert.Nil(t, backend.db.Model(&entities.BillingUsage{}).Where("user_id = ?", userID).Count(&count).Error)
+			assert.Equal(t, int64(1), count)
+		})
+	}
+}
+
+// TestGormBillingUsageRepository_Rebuild verifies that the billing usage which is rebuilt by the usage command matches
+// the number of messages in the messages table
+func TestGormBillingUsageRepository_Rebuild(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormBillingUsageRepository(backend.logger, backend.tracer, backend.db, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			inactiveUserID := entities.UserID(uuid.NewString())
+			timestamp := time.Date(2002, time.July, 15, 12, 0, 0, 0, time.UTC)
+
+			// the counters have drifted from the messages which were stored
+			for i := 0; i < 5; i++ {
+				assert.Nil(t, repository.RegisterSentMessage(ctx, timestamp, userID))
+				assert.Nil(t, repository.RegisterSentMessage(ctx, timestamp, inactiveUserID))
+			}
+
+			for _, messageType := range []entities.MessageType{entities.MessageTypeMobileTerminated, entities.MessageTypeMobileTerminated, entities.MessageTypeMobileOriginated} {
+				message := newTestMessage(userID, "hello world", timestamp)
+				message.Type = messageType
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			err := repository.Rebuild(ctx)
+
+			// Assert
+			assert.Nil(t, err)
+
+			var sent, received int64
+			assert.Nil(t, backend.db.Model(&entities.Message{}).Where("user_id = ?", userID).Where("type = ?", entities.MessageTypeMobileTerminated).Count(&sent).Error)
+			assert.Nil(t, backend.db.Model(&entities.Message{}).Where("user_id = ?", userID).Where("type = ?", entities.MessageTypeMobileOriginated).Count(&received).Error)
+
+			usage, err := repository.Get(ctx, userID, timestamp)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(sent), usage.SentMessages)
+			assert.Equal(t, uint(received), usage.ReceivedMessages)
+
+			usage, err = repository.Get(ctx, inactiveUserID, timestamp)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(0), usage.SentMessages)
+			assert.Equal(t, uint(0), usage.ReceivedMessages)
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package repositories_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/repositorytest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGormMessageRepository_Conformance(t *testing.T) {
+	for _, backend := range repositories.ConformanceBackends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			repositorytest.MessageRepository(t, backend.NewMessageRepository)
+		})
+	}
+}
+
+func TestGormEventListenerLogRepository_Conformance(t *testing.T) {
+	for _, backend := range repositories.ConformanceBackends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			repositorytest.EventListenerLogRepository(t, backend.NewEventListenerLogRepository)
+		})
+	}
+}
+
+func TestGormEventRepository_Conformance(t *testing.T) {
+	for _, backend := range repositories.ConformanceBackends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			repositorytest.EventRepository(t, backend.NewEventRepository)
+		})
+	}
+}
+
+// TestGormRepositories_SearchConformance verifies that the search of the Index queries is case-insensitive on every backend
+func TestGormRepositories_SearchConformance(t *testing.T) {
+	for _, backend := range repositories.ConformanceBackends(t) {
+		t.Run(backend.Name+"/phones", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			repository := backend.NewPhoneRepository()
+			userID := entities.UserID(uuid.NewString())
+			for _, phoneNumber := range []string{"+18005550199", "+18005550100"} {
+				phone := &entities.Phone{ID: uuid.New(), UserID: userID, PhoneNumber: phoneNumber, SIM: entities.SIM1, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
+				assert.Nil(t, repository.Save(ctx, phone))
+			}
+
+			// Act
+			phones, err := repository.Index(ctx, userID, repositories.IndexParams{Query: "5550199", Limit: 10})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Len(t, *phones, 1)
+			assert.Equal(t, "+18005550199", (*phones)[0].PhoneNumber)
+		})
+
+		t.Run(backend.Name+"/webhooks", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			repository := backend.NewWebhookRepository()
+			userID := entities.UserID(uuid.NewString())
+			for _, url := range []string{"https://Example.com/Hooks", "https://example.org"} {
+				webhook := &entities.Webhook{ID: uuid.New(), UserID: userID, URL: url, Events: []string{events.EventTypeMessagePhoneReceived}, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
+				assert.Nil(t, repository.Save(ctx, webhook))
+			}
+
+			// Act
+			webhooks, err := repository.Index(ctx, userID, repositories.IndexParams{Query: "EXAMPLE.COM/hooks", Limit: 10})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Len(t, webhooks, 1)
+			assert.Equal(t, "https://Example.com/Hooks", webhooks[0].URL)
+			assert.Equal(t, []string{events.EventTypeMessagePhoneReceived}, []string(webhooks[0].Events))
+		})
+
+		t.Run(backend.Name+"/message threads", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			repository := backend.NewMessageThreadRepository()
+			userID := entities.UserID(uuid.NewString())
+			for _, content := range []string{"Hello World", "Good bye"} {
+				content := content
+				thread := &entities.MessageThread{ID: uuid.New(), UserID: userID, Owner: "+18005550199", Contact: "+18005550100", Status: entities.MessageStatusSent, LastMessageContent: &content, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC(), OrderTimestamp: time.Now().UTC()}
+				assert.Nil(t, repository.Store(ctx, thread))
+			}
+
+			// Act
+			threads, err := repository.Index(ctx, userID, "+18005550199", false, repositories.MessageThreadIndexParams{IndexParams: repositories.IndexParams{Query: "hello WORLD", Limit: 10}})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Len(t, *threads, 1)
+			assert.Equal(t, "Hello World", *(*threads)[0].LastMessageContent)
+		})
+
+		t.Run(backend.Name+"/api keys", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			repository := backend.NewAPIKeyRepository()
+			userID := entities.UserID(uuid.NewString())
+			for _, label := range []string{"BI Team", "Mobile app"} {
+				apiKey := &entities.APIKey{ID: uuid.New(), UserID: userID, Label: label, KeyHash: entities.HashAPIKey(uuid.NewString()), Scopes: []string{entities.APIKeyScopeMessagesRead.String()}, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
+				assert.Nil(t, repository.Store(ctx, apiKey))
+			}
+
+			// Act
+			apiKeys, err := repository.Index(ctx, userID, repositories.IndexParams{Query: "bi team", Limit: 10})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Len(t, apiKeys, 1)
+			assert.Equal(t, "BI Team", apiKeys[0].Label)
+		})
+
+		t.Run(backend.Name+"/users", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			repository := backend.NewUserRepository()
+			name := uuid.NewString()
+			user := &entities.User{ID: entities.UserID(uuid.NewString()), Email: name + "@Example.com", SubscriptionName: entities.SubscriptionNameFree, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
+			assert.Nil(t, repository.Store(ctx, user))
+
+			// Act
+			users, err := repository.Index(ctx, repositories.IndexParams{Query: strings.ToUpper(name) + "@example", Limit: 10})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Len(t, users, 1)
+			assert.Equal(t, user.ID, users[0].ID)
+		})
+	}
+}
+
+// TestGormUserRepository_SummariesConformance verifies the phone and message counts of the users on every backend
+func TestGormUserRepository_SummariesConformance(t *testing.T) {
+	for _, backend := range repositories.ConformanceBackends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			phoneRepository := backend.NewPhoneRepository()
+			messageRepository := backend.NewMessageRepository()
+			user := &entities.User{ID: entities.UserID(uuid.NewString()), Email: "name@email.com", SubscriptionName: entities.SubscriptionNameFree}
+			inactive := &entities.User{ID: entities.UserID(uuid.NewString()), Email: "inactive@email.com", SubscriptionName: entities.SubscriptionNameFree}
+
+			for _, phoneNumber := range []string{"+18005550199", "+18005550100"} {
+				phone := &entities.Phone{ID: uuid.New(), UserID: user.ID, PhoneNumber: phoneNumber, SIM: entities.SIM1, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
+				assert.Nil(t, phoneRepository.Save(ctx, phone))
+			}
+
+			timestamp := time.Now().UTC().Truncate(time.Second)
+			lastMessageAt := timestamp.Add(-time.Minute)
+			for _, message := range []struct {
+				messageType entities.MessageType
+				status      entities.MessageStatus
+				timestamp   time.Time
+			}{
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusPending, timestamp.Add(-2 * time.Minute)},
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusSent, lastMessageAt},
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusDelivered, timestamp.Add(-3 * time.Minute)},
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusFailed, timestamp.Add(-4 * time.Minute)},
+				{entities.MessageTypeMobileOriginated, entities.MessageStatusReceived, timestamp},
+			} {
+				assert.Nil(t, messageRepository.Store(ctx, &entities.Message{
+					ID:                uuid.New(),
+					Owner:             "+18005550199",
+					Contact:           "+18005550100",
+					UserID:            user.ID,
+					Content:           "hello world",
+					Type:              message.messageType,
+					Status:            message.status,
+					RequestReceivedAt: message.timestamp,
+					OrderTimestamp:    message.timestamp,
+					CreatedAt:         message.timestamp,
+					UpdatedAt:         message.timestamp,
+				}))
+			}
+
+			// Act
+			summaries, err := backend.NewUserRepository().Summaries(ctx, []*entities.User{user, inactive})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Len(t, summaries, 2)
+
+			assert.Equal(t, user.ID, summaries[0].ID)
+			assert.Equal(t, uint(2), summaries[0].PhoneCount)
+			assert.Equal(t, uint(1), summaries[0].PendingMessageCount)
+			assert.Equal(t, uint(2), summaries[0].SentMessageCount)
+			assert.Equal(t, uint(1), summaries[0].ReceivedMessageCount)
+			assert.Equal(t, uint(1), summaries[0].FailedMessageCount)
+			assert.NotNil(t, summaries[0].LastMessageAt)
+			assert.True(t, lastMessageAt.Equal(*summaries[0].LastMessageAt), summaries[0].LastMessageAt)
+
+			assert.Equal(t, inactive.ID, summaries[1].ID)
+			assert.Equal(t, uint(0), summaries[1].PhoneCount)
+			assert.Equal(t, uint(0), summaries[1].SentMessageCount)
+			assert.Nil(t, summaries[1].LastMessageAt)
+		})
+	}
+}
+
+// TestGormBillingUsageRepository_RebuildConformance verifies that the billing usage is rebuilt from the messages table
+// and the messages archive on every backend
+func TestGormBillingUsageRepository_RebuildConformance(t *testing.T) {
+	for _, backend := range repositories.ConformanceBackends(t) {
+		t.Run(backend.Name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			messageRepository := backend.NewMessageRepository()
+			repository := backend.NewBillingUsageRepository()
+			userID := entities.UserID(uuid.NewString())
+
+			march := time.Date(2001, time.March, 15, 12, 0, 0, 0, time.UTC)
+			april := time.Date(2001, time.April, 10, 12, 0, 0, 0, time.UTC)
+			for _, message := range []struct {
+				messageType entities.MessageType
+				status      entities.MessageStatus
+				timestamp   time.Time
+				imported    bool
+				archived    bool
+			}{
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusSent, march, false, false},
+				{entities.MessageTypeMobileOriginated, entities.MessageStatusReceived, march, false, false},
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusSent, march, true, false},
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusSent, april, false, true},
+				{entities.MessageTypeMobileTerminated, entities.MessageStatusDelivered, april, false, true},
+				{entities.MessageTypeMobileOriginated, entities.MessageStatusReceived, april, true, true},
+			} {
+				entity := &entities.Message{
+					ID:                uuid.New(),
+					Owner:             "+18005550199",
+					Contact:           "+18005550100",
+					UserID:            userID,
+					Content:           "hello world",
+					Type:              message.messageType,
+					Status:            message.status,
+					RequestReceivedAt: message.timestamp,
+					OrderTimestamp:    time.Now().UTC(),
+					CreatedAt:         message.timestamp,
+					UpdatedAt:         message.timestamp,
+				}
+				if message.archived {
+					entity.OrderTimestamp = message.timestamp
+				}
+				if message.imported {
+					entity.ImportedAt = &message.timestamp
+				}
+				assert.Nil(t, messageRepository.Store(ctx, entity))
+			}
+
+			_, err := messageRepository.Archive(ctx, time.Date(2001, time.May, 1, 0, 0, 0, 0, time.UTC), 100)
+			assert.Nil(t, err)
+
+			// Act
+			err = repository.Rebuild(ctx)
+
+			// Assert
+			assert.Nil(t, err)
+
+			usage, err := repository.Get(ctx, userID, march)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(1), usage.SentMessages)
+			assert.Equal(t, uint(1), usage.ReceivedMessages)
+
+			usage, err = repository.Get(ctx, userID, april)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(2), usage.SentMessages)
+			assert.Equal(t, uint(0), usage.ReceivedMessages)
+		})
+	}
+}
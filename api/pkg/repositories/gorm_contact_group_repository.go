@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormContactGroupRepository is responsible for persisting entities.ContactGroup
+type gormContactGroupRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormContactGroupRepository creates the GORM version of the ContactGroupRepository
+func NewGormContactGroupRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) ContactGroupRepository {
+	return &gormContactGroupRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormContactGroupRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormContactGroupRepository) Store(ctx context.Context, group *entities.ContactGroup) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(group).Error; err != nil {
+		msg := fmt.Sprintf("cannot store contact group with ID [%s] for user [%s]", group.ID, group.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContactGroupRepository) Update(ctx context.Context, group *entities.ContactGroup) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(group).Error; err != nil {
+		msg := fmt.Sprintf("cannot update contact group with ID [%s] for user [%s]", group.ID, group.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContactGroupRepository) Load(ctx context.Context, userID entities.UserID, groupID uuid.UUID) (*entities.ContactGroup, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	group := new(entities.ContactGroup)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", groupID).First(group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("contact group with ID [%s] for user [%s] does not exist", groupID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s] for user [%s]", groupID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return group, nil
+}
+
+func (repository *gormContactGroupRepository) Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.ContactGroup, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
+	if len(params.Query) > 0 {
+		query.Where(ilike(repository.db, "name"), "%"+params.Query+"%")
+	}
+
+	groups := make([]*entities.ContactGroup, 0)
+	if err := query.Order("name ASC").Order("created_at ASC").Limit(params.Limit).Offset(params.Skip).Find(&groups).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch contact groups for user [%s] and params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return groups, nil
+}
+
+func (repository *gormContactGroupRepository) Delete(ctx context.Context, group *entities.ContactGroup) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		if err := tx.Where("contact_group_id = ?", group.ID).Delete(&entities.ContactGroupMember{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete the members of contact group [%s]", group.ID))
+		}
+		if err := tx.Where("user_id = ?", group.UserID).Where("id = ?", group.ID).Delete(&entities.ContactGroup{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete contact group [%s]", group.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete contact group with ID [%s] for user [%s]", group.ID, group.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContactGroupRepository) AddMembers(ctx context.Context, group *entities.ContactGroup, contactIDs []uuid.UUID) (int, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if len(contactIDs) == 0 {
+		return 0, nil
+	}
+
+	existing := make([]uuid.UUID, 0, len(contactIDs))
+	err := repository.db.WithContext(ctx).
+		Model(&entities.Contact{}).
+		Where("user_id = ?", group.UserID).
+		Where("id IN ?", contactIDs).
+		Where("deleted_at IS NULL").
+		Pluck("id", &existing).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch [%d] contacts of user [%s]", len(contactIDs), group.UserID)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	found := map[uuid.UUID]bool{}
+	for _, contactID := range existing {
+		found[contactID] = true
+	}
+	for _, contactID := range contactIDs {
+		if !found[contactID] {
+			msg := fmt.Sprintf("contact with ID [%s] for user [%s] does not exist", contactID, group.UserID)
+			return 0, repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeNotFound, msg))
+		}
+	}
+
+	timestamp := time.Now().UTC()
+	members := make([]*entities.ContactGroupMember, 0, len(contactIDs))
+	for _, contactID := range contactIDs {
+		members = append(members, &entities.ContactGroupMember{
+			ContactGroupID: group.ID,
+			ContactID:      contactID,
+			UserID:         group.UserID,
+			CreatedAt:      timestamp,
+		})
+	}
+
+	result := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&members)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot add [%d] members to contact group [%s] for user [%s]", len(members), group.ID, group.UserID)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return int(result.RowsAffected), nil
+}
+
+func (repository *gormContactGroupRepository) RemoveMember(ctx context.Context, group *entities.ContactGroup, contactID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).
+		Where("user_id = ?", group.UserID).
+		Where("contact_group_id = ?", group.ID).
+		Where("contact_id = ?", contactID).
+		Delete(&entities.ContactGroupMember{})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot remove contact [%s] from contact group [%s] for user [%s]", contactID, group.ID, group.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 0 {
+		msg := fmt.Sprintf("contact [%s] is not a member of contact group [%s] for user [%s]", contactID, group.ID, group.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeNotFound, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContactGroupRepository) IndexMembers(ctx context.Context, group *entities.ContactGroup, params IndexParams) ([]*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	contacts := make([]*entities.Contact, 0)
+	err := repository.members(ctx, group).
+		Order("contacts.name ASC").
+		Order("contacts.phone_number ASC").
+		Limit(params.Limit).
+		Offset(params.Skip).
+		Find(&contacts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the members of contact group [%s] for user [%s] and params [%+#v]", group.ID, group.UserID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contacts, nil
+}
+
+func (repository *gormContactGroupRepository) FetchMembers(ctx context.Context, group *entities.ContactGroup) ([]*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	contacts := make([]*entities.Contact, 0)
+	if err := repository.members(ctx, group).Order("contacts.phone_number ASC").Find(&contacts).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch the members of contact group [%s] for user [%s]", group.ID, group.UserID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contacts, nil
+}
+
+// members is the query of the entities.Contact in a group, a contact which was deleted from the address book is not a member
+func (repository *gormContactGroupRepository) members(ctx context.Context, group *entities.ContactGroup) *gorm.DB {
+	return repository.db.WithContext(ctx).
+		Model(&entities.Contact{}).
+		Joins("JOIN contact_group_members ON contact_group_members.contact_id = contacts.id").
+		Where("contact_group_members.contact_group_id = ?", group.ID).
+		Where("contact_group_members.user_id = ?", group.UserID).
+		Where("contacts.user_id = ?", group.UserID).
+		Where("contacts.deleted_at IS NULL")
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContactGroup(userID entities.UserID) *entities.ContactGroup {
+	return &entities.ContactGroup{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      "All Drivers",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+func newTestGroupContact(userID entities.UserID, phoneNumber string) *entities.Contact {
+	contact := newTestSyncedContact(userID, "Jane Doe", time.Now().UTC())
+	contact.PhoneNumber = phoneNumber
+	return contact
+}
+
+// TestGormContactGroupRepository_AddMembers verifies that the members of a group are only the contacts of the user
+func TestGormContactGroupRepository_AddMembers(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormContactGroupRepository(backend.logger, backend.tracer, backend.db)
+		contactRepository := NewGormContactRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": contact which is already a member is not added again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			group := newTestContactGroup(userID)
+			assert.Nil(t, repository.Store(ctx, group))
+
+			first := newTestGroupContact(userID, "+18005550100")
+			second := newTestGroupContact(userID, "+18005550101")
+			for _, contact := range []*entities.Contact{first, second} {
+				_, err := contactRepository.Sync(ctx, contact)
+				assert.Nil(t, err)
+			}
+
+			_, err := repository.AddMembers(ctx, group, []uuid.UUID{first.ID})
+			assert.Nil(t, err)
+
+			// Act
+			added, err := repository.AddMembers(ctx, group, []uuid.UUID{first.ID, second.ID})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 1, added)
+
+			members, err := repository.FetchMembers(ctx, group)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(members))
+		})
+
+		t.Run(backend.name+": contact of another user is not added", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			group := newTestContactGroup(userID)
+			assert.Nil(t, repository.Store(ctx, group))
+
+			contact := newTestGroupContact(userID, "+18005550100")
+			other := newTestGroupContact(entities.UserID(uuid.NewString()), "+18005550101")
+			for _, item := range []*entities.Contact{contact, other} {
+				_, err := contactRepository.Sync(ctx, item)
+				assert.Nil(t, err)
+			}
+
+			// Act
+			_, err := repository.AddMembers(ctx, group, []uuid.UUID{contact.ID, other.ID})
+
+			// Assert
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+
+			members, err := repository.FetchMembers(ctx, group)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(members))
+		})
+
+		t.Run(backend.name+": contact which is deleted from the address book is not a member", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			group := newTestContactGroup(userID)
+			assert.Nil(t, repository.Store(ctx, group))
+
+			contact := newTestGroupContact(userID, "+18005550100")
+			_, err := contactRepository.Sync(ctx, contact)
+			assert.Nil(t, err)
+
+			_, err = repository.AddMembers(ctx, group, []uuid.UUID{contact.ID})
+			assert.Nil(t, err)
+
+			_, err = contactRepository.Sync(ctx, newTestDeletedContact(userID, time.Now().UTC().Add(time.Minute)))
+			assert.Nil(t, err)
+
+			// Act
+			members, err := repository.FetchMembers(ctx, group)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(members))
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormContactRepository is responsible for persisting entities.Contact
+type gormContactRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormContactRepository creates the GORM version of the ContactRepository
+func NewGormContactRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) ContactRepository {
+	return &gormContactRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormContactRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormContactRepository) Upsert(ctx context.Context, contact *entities.Contact) (*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "phone_number"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "source", "dnd_start", "dnd_end", "dnd_timezone", "synced_at", "deleted_at", "updated_at"}),
+		}).
+		Create(contact).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot upsert contact with phone number [%s] for user [%s]", contact.PhoneNumber, contact.UserID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	// the ID of the contact is not changed when the phone number already has a contact
+	stored := new(entities.Contact)
+	err = repository.db.WithContext(ctx).
+		Where("user_id = ?", contact.UserID).
+		Where("phone_number = ?", contact.PhoneNumber).
+		First(stored).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with phone number [%s] for user [%s]", contact.PhoneNumber, contact.UserID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return stored, nil
+}
+
+func (repository *gormContactRepository) Update(ctx context.Context, contact *entities.Contact) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(contact).Error; err != nil {
+		msg := fmt.Sprintf("cannot update contact with ID [%s] for user [%s]", contact.ID, contact.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContactRepository) Sync(ctx context.Context, contact *entities.Contact) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(contact)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot create contact with phone number [%s] for user [%s]", contact.PhoneNumber, contact.UserID)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// the condition is part of the update so that concurrent syncs of the same number keep the newest entry
+	result = repository.db.WithContext(ctx).
+		Model(&entities.Contact{}).
+		Where("user_id = ?", contact.UserID).
+		Where("phone_number = ?", contact.PhoneNumber).
+		Where("source = ?", entities.ContactSourceSynced).
+		Where(repository.db.Where("synced_at IS NULL").Or("synced_at <= ?", contact.SyncedAt)).
+		Updates(map[string]any{
+			"name":       contact.Name,
+			"synced_at":  contact.SyncedAt,
+			"deleted_at": contact.DeletedAt,
+			"updated_at": contact.UpdatedAt,
+		})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot sync contact with phone number [%s] for user [%s]", contact.PhoneNumber, contact.UserID)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (repository *gormContactRepository) Load(ctx context.Context, userID entities.UserID, contactID uuid.UUID) (*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	contact := new(entities.Contact)
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("id = ?", contactID).
+		Where("deleted_at IS NULL").
+		First(contact).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("contact with ID [%s] for user [%s] does not exist", contactID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s] for user [%s]", contactID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = repository.loadLabels(ctx, contact); err != nil {
+		msg := fmt.Sprintf("cannot load the labels of contact with ID [%s] for user [%s]", contactID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contact, nil
+}
+
+func (repository *gormContactRepository) Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("deleted_at IS NULL")
+	if len(params.Query) > 0 {
+		queryPattern := "%" + params.Query + "%"
+		query.Where(repository.db.Where(ilike(repository.db, "name"), queryPattern).Or("phone_number LIKE ?", queryPattern))
+	}
+
+	contacts := make([]*entities.Contact, 0)
+	if err := query.Order("name ASC").Order("phone_number ASC").Limit(params.Limit).Offset(params.Skip).Find(&contacts).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch contacts for user [%s] and params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := repository.loadLabels(ctx, contacts...); err != nil {
+		msg := fmt.Sprintf("cannot load the labels of [%d] contacts for user [%s]", len(contacts), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contacts, nil
+}
+
+func (repository *gormContactRepository) SetLabels(ctx context.Context, contact *entities.Contact) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	labels := make([]*entities.ContactLabel, 0, len(contact.Labels))
+	for _, label := range contact.Labels {
+		labels = append(labels, &entities.ContactLabel{
+			ContactID: contact.ID,
+			Label:     label,
+			UserID:    contact.UserID,
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		if err := tx.Where("contact_id = ?", contact.ID).Delete(&entities.ContactLabel{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete the labels of contact [%s]", contact.ID))
+		}
+		if len(labels) == 0 {
+			return nil
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&labels).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot store [%d] labels of contact [%s]", len(labels), contact.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot set the labels of contact with ID [%s] for user [%s]", contact.ID, contact.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContactRepository) RenameLabel(ctx context.Context, userID entities.UserID, label string, name string) (int, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	count := 0
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		existing := make([]*entities.ContactLabel, 0)
+		if err := tx.Where("user_id = ?", userID).Where("label = ?", label).Find(&existing).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the contacts with label [%s]", label))
+		}
+
+		count = len(existing)
+		if count == 0 || label == name {
+			return nil
+		}
+
+		// a contact which already has the new label keeps a single label
+		renamed := make([]*entities.ContactLabel, 0, len(existing))
+		for _, item := range existing {
+			renamed = append(renamed, &entities.ContactLabel{ContactID: item.ContactID, Label: name, UserID: userID, CreatedAt: item.CreatedAt})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&renamed).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot add label [%s] to [%d] contacts", name, len(renamed)))
+		}
+
+		if err := tx.Where("user_id = ?", userID).Where("label = ?", label).Delete(&entities.ContactLabel{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete label [%s] from [%d] contacts", label, count))
+		}
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot rename label [%s] to [%s] for user [%s]", label, name, userID)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count, nil
+}
+
+// loadLabels sets the labels of the contacts with one query
+func (repository *gormContactRepository) loadLabels(ctx context.Context, contacts ...*entities.Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*entities.Contact, len(contacts))
+	for _, contact := range contacts {
+		contact.Labels = []string{}
+		byID[contact.ID] = contact
+	}
+
+	contactIDs := make([]uuid.UUID, 0, len(byID))
+	for contactID := range byID {
+		contactIDs = append(contactIDs, contactID)
+	}
+
+	labels := make([]*entities.ContactLabel, 0)
+	err := repository.db.WithContext(ctx).
+		Where("contact_id IN ?", contactIDs).
+		Order("label ASC").
+		Find(&labels).Error
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the labels of [%d] contacts", len(contactIDs)))
+	}
+
+	for _, label := range labels {
+		byID[label.ContactID].Labels = append(byID[label.ContactID].Labels, label.Label)
+	}
+	return nil
+}
+
+func (repository *gormContactRepository) FetchNames(ctx context.Context, userID entities.UserID, phoneNumbers []string) (map[string]string, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	names := map[string]string{}
+	if len(phoneNumbers) == 0 {
+		return names, nil
+	}
+
+	contacts := make([]*entities.Contact, 0, len(phoneNumbers))
+	err := repository.db.WithContext(ctx).
+		Select("phone_number", "name").
+		Where("user_id = ?", userID).
+		Where("phone_number IN ?", phoneNumbers).
+		Where("deleted_at IS NULL").
+		Find(&contacts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the names of [%d] phone numbers for user [%s]", len(phoneNumbers), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, contact := range contacts {
+		if contact.Name != "" {
+			names[contact.PhoneNumber] = contact.Name
+		}
+	}
+
+	return names, nil
+}
+
+func (repository *gormContactRepository) FetchByPhoneNumbers(ctx context.Context, userID entities.UserID, phoneNumbers []string) (map[string]*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := map[string]*entities.Contact{}
+	if len(phoneNumbers) == 0 {
+		return result, nil
+	}
+
+	contacts := make([]*entities.Contact, 0, len(phoneNumbers))
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("phone_number IN ?", phoneNumbers).
+		Where("deleted_at IS NULL").
+		Find(&contacts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the contacts of [%d] phone numbers for user [%s]", len(phoneNumbers), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = repository.loadLabels(ctx, contacts...); err != nil {
+		msg := fmt.Sprintf("cannot load the labels of [%d] contacts for user [%s]", len(contacts), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, contact := range contacts {
+		result[contact.PhoneNumber] = contact
+	}
+
+	return result, nil
+}
+
+func (repository *gormContactRepository) FetchDND(ctx context.Context, userID entities.UserID, phoneNumbers []string) (map[string]*entities.Contact, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := map[string]*entities.Contact{}
+	if len(phoneNumbers) == 0 {
+		return result, nil
+	}
+
+	contacts := make([]*entities.Contact, 0, len(phoneNumbers))
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("phone_number IN ?", phoneNumbers).
+		Where("deleted_at IS NULL").
+		Where("dnd_start <> ?", "").
+		Find(&contacts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the do-not-disturb windows of [%d] phone numbers for user [%s]", len(phoneNumbers), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, contact := range contacts {
+		result[contact.PhoneNumber] = contact
+	}
+
+	return result, nil
+}
+
+func (repository *gormContactRepository) Delete(ctx context.Context, contact *entities.Contact) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		if err := tx.Where("contact_id = ?", contact.ID).Delete(&entities.ContactLabel{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete the labels of contact [%s]", contact.ID))
+		}
+		if err := tx.Where("user_id = ?", contact.UserID).Where("id = ?", contact.ID).Delete(&entities.Contact{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete contact [%s]", contact.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete contact with ID [%s] for user [%s]", contact.ID, contact.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSyncedContact(userID entities.UserID, name string, syncedAt time.Time) *entities.Contact {
+	return &entities.Contact{
+		ID:          uuid.New(),
+		UserID:      userID,
+		PhoneNumber: "+18005550100",
+		Name:        name,
+		Source:      entities.ContactSourceSynced,
+		SyncedAt:    &syncedAt,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+}
+
+func newTestDeletedContact(userID entities.UserID, deletedAt time.Time) *entities.Contact {
+	contact := newTestSyncedContact(userID, "", deletedAt)
+	contact.DeletedAt = &deletedAt
+	return contact
+}
+
+// TestGormContactRepository_Sync verifies that the newest entry of the address book of a phone is kept
+func TestGormContactRepository_Sync(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormContactRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": entry which is renamed in the address book changes the name", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			_, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane", now))
+			assert.Nil(t, err)
+
+			// Act
+			synced, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane Doe", now.Add(time.Minute)))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.True(t, synced)
+
+			names, err := repository.FetchNames(ctx, userID, []string{"+18005550100"})
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"+18005550100": "Jane Doe"}, names)
+		})
+
+		t.Run(backend.name+": entry which is older than the tombstone does not add the contact again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			_, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane", now))
+			assert.Nil(t, err)
+			_, err = repository.Sync(ctx, newTestDeletedContact(userID, now.Add(2*time.Minute)))
+			assert.Nil(t, err)
+
+			// Act
+			synced, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane Doe", now.Add(time.Minute)))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.False(t, synced)
+
+			names, err := repository.FetchNames(ctx, userID, []string{"+18005550100"})
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(names))
+
+			contacts, err := repository.Index(ctx, userID, IndexParams{Limit: 10})
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(contacts))
+		})
+
+		t.Run(backend.name+": entry which is newer than the tombstone adds the contact again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			_, err := repository.Sync(ctx, newTestDeletedContact(userID, now))
+			assert.Nil(t, err)
+
+			// Act
+			synced, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane", now.Add(time.Minute)))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.True(t, synced)
+
+			contacts, err := repository.Index(ctx, userID, IndexParams{Limit: 10})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(contacts))
+			assert.Equal(t, "Jane", contacts[0].Name)
+		})
+
+		t.Run(backend.name+": entry does not change a manual contact", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			_, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane", now))
+			assert.Nil(t, err)
+
+			manual := newTestSyncedContact(userID, "Jane from work", now)
+			manual.Source = entities.ContactSourceManual
+			manual.SyncedAt = nil
+			stored, err := repository.Upsert(ctx, manual)
+			assert.Nil(t, err)
+
+			// Act
+			synced, err := repository.Sync(ctx, newTestSyncedContact(userID, "Jane Doe", now.Add(time.Minute)))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.False(t, synced)
+
+			contact, err := repository.Load(ctx, userID, stored.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "Jane from work", contact.Name)
+			assert.Equal(t, entities.ContactSourceManual, contact.Source)
+		})
+	}
+}
+
+// TestGormContactRepository_RenameLabel verifies that a label is renamed on all the contacts of a user at once
+func TestGormContactRepository_RenameLabel(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormContactRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": contact which already has the new label keeps it once", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+
+			first := newTestGroupContact(userID, "+18005550100")
+			first.Labels = []string{"vip"}
+			second := newTestGroupContact(userID, "+18005550101")
+			second.Labels = []string{"priority", "vip"}
+			other := newTestGroupContact(entities.UserID(uuid.NewString()), "+18005550100")
+			other.Labels = []string{"vip"}
+			for _, contact := range []*entities.Contact{first, second, other} {
+				_, err := repository.Sync(ctx, contact)
+				assert.Nil(t, err)
+				assert.Nil(t, repository.SetLabels(ctx, contact))
+			}
+
+			// Act
+			count, err := repository.RenameLabel(ctx, userID, "vip", "priority")
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 2, count)
+
+			contacts, err := repository.FetchByPhoneNumbers(ctx, userID, []string{first.PhoneNumber, second.PhoneNumber})
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"priority"}, contacts[first.PhoneNumber].Labels)
+			assert.Equal(t, []string{"priority"}, contacts[second.PhoneNumber].Labels)
+
+			unchanged, err := repository.Load(ctx, other.UserID, other.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"vip"}, unchanged.Labels)
+		})
+	}
+}
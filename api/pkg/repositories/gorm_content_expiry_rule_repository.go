@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormContentExpiryRuleRepository is responsible for persisting entities.ContentExpiryRule
+type gormContentExpiryRuleRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormContentExpiryRuleRepository creates the GORM version of the ContentExpiryRuleRepository
+func NewGormContentExpiryRuleRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) ContentExpiryRuleRepository {
+	return &gormContentExpiryRuleRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormContentExpiryRuleRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormContentExpiryRuleRepository) Store(ctx context.Context, rule *entities.ContentExpiryRule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(rule).Error; err != nil {
+		msg := fmt.Sprintf("cannot store content expiry rule with ID [%s] for user [%s]", rule.ID, rule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContentExpiryRuleRepository) Update(ctx context.Context, rule *entities.ContentExpiryRule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(rule).Error; err != nil {
+		msg := fmt.Sprintf("cannot update content expiry rule with ID [%s] for user [%s]", rule.ID, rule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormContentExpiryRuleRepository) Load(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) (*entities.ContentExpiryRule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rule := new(entities.ContentExpiryRule)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", ruleID).First(rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("content expiry rule with ID [%s] for user [%s] does not exist", ruleID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load content expiry rule with ID [%s] for user [%s]", ruleID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rule, nil
+}
+
+func (repository *gormContentExpiryRuleRepository) Fetch(ctx context.Context, userID entities.UserID) ([]*entities.ContentExpiryRule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rules := make([]*entities.ContentExpiryRule, 0)
+	if err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Order("owner ASC").Order("created_at ASC").Find(&rules).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch content expiry rules for user [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rules, nil
+}
+
+func (repository *gormContentExpiryRuleRepository) FetchByOwner(ctx context.Context, userID entities.UserID, owner string) ([]*entities.ContentExpiryRule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rules := make([]*entities.ContentExpiryRule, 0)
+	if err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("owner = ?", owner).Find(&rules).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch content expiry rules for user [%s] and owner [%s]", userID, owner)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rules, nil
+}
+
+func (repository *gormContentExpiryRuleRepository) Delete(ctx context.Context, rule *entities.ContentExpiryRule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Where("user_id = ?", rule.UserID).Where("id = ?", rule.ID).Delete(&entities.ContentExpiryRule{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete content expiry rule with ID [%s] for user [%s]", rule.ID, rule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
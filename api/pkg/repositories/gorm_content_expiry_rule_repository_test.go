@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContentExpiryRule(userID entities.UserID, owner string, contacts []string) *entities.ContentExpiryRule {
+	return &entities.ContentExpiryRule{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Owner:      owner,
+		Pattern:    entities.ContentExpiryPatternCode,
+		Contacts:   contacts,
+		TTLSeconds: 3600,
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+	}
+}
+
+// TestGormContentExpiryRuleRepository_FetchByOwner verifies that the rules of an owner are fetched with their contacts
+func TestGormContentExpiryRuleRepository_FetchByOwner(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormContentExpiryRuleRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": rules of the owner are fetched with their contacts", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			rule := newTestContentExpiryRule(userID, "+18005550199", []string{"Google", "+18005550100"})
+			assert.Nil(t, repository.Store(ctx, rule))
+			assert.Nil(t, repository.Store(ctx, newTestContentExpiryRule(userID, "+18005550198", nil)))
+			assert.Nil(t, repository.Store(ctx, newTestContentExpiryRule(entities.UserID(uuid.NewString()), "+18005550199", nil)))
+
+			// Act
+			rules, err := repository.FetchByOwner(ctx, userID, "+18005550199")
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(rules))
+			assert.Equal(t, rule.ID, rules[0].ID)
+			assert.Equal(t, []string{"Google", "+18005550100"}, rules[0].Contacts)
+		})
+	}
+}
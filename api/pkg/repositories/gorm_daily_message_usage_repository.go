@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormDailyMessageUsageRepository is responsible for persisting entities.DailyMessageUsage
+type gormDailyMessageUsageRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormDailyMessageUsageRepository creates the GORM version of the DailyMessageUsageRepository
+func NewGormDailyMessageUsageRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) DailyMessageUsageRepository {
+	return &gormDailyMessageUsageRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormDailyMessageUsageRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+// Reserve increments the sent messages of a day by count if the total does not exceed the limit
+func (repository *gormDailyMessageUsageRepository) Reserve(ctx context.Context, userID entities.UserID, day time.Time, count uint, limit uint) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	reserved := false
+	err := crdbgorm.ExecuteTx(ctx, repository.db, nil,
+		func(tx *gorm.DB) error {
+			result := repository.increment(tx.WithContext(ctx), userID, day, count, limit)
+			if result.Error != nil || result.RowsAffected > 0 {
+				reserved = result.RowsAffected > 0
+				return result.Error
+			}
+
+			usage := &entities.DailyMessageUsage{
+				ID:        uuid.New(),
+				UserID:    userID,
+				Day:       day,
+				CreatedAt: time.Now().UTC(),
+				UpdatedAt: time.Now().UTC(),
+			}
+			err := tx.WithContext(ctx).Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+				DoNothing: true,
+			}).Create(usage).Error
+			if err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot create daily message usage for user [%s] and day [%s]", userID, day))
+			}
+
+			result = repository.increment(tx.WithContext(ctx), userID, day, count, limit)
+			reserved = result.RowsAffected > 0
+			return result.Error
+		},
+	)
+	if err != nil {
+		msg := fmt.Sprintf("cannot reserve [%d] messages for user [%s] and day [%s]", count, userID, day)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return reserved, nil
+}
+
+// Release decrements the sent messages of a day by count
+func (repository *gormDailyMessageUsageRepository) Release(ctx context.Context, userID entities.UserID, day time.Time, count uint) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.DailyMessageUsage{}).
+		Where("user_id = ?", userID).
+		Where("day = ?", day).
+		Where("sent_messages >= ?", count).
+		Updates(map[string]any{
+			"sent_messages": gorm.Expr("sent_messages - ?", count),
+			"updated_at":    time.Now().UTC(),
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot release [%d] messages for user [%s] and day [%s]", count, userID, day)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormDailyMessageUsageRepository) increment(tx *gorm.DB, userID entities.UserID, day time.Time, count uint, limit uint) *gorm.DB {
+	return tx.Model(&entities.DailyMessageUsage{}).
+		Where("user_id = ?", userID).
+		Where("day = ?", day).
+		Where("sent_messages + ? <= ?", count, limit).
+		Updates(map[string]any{
+			"sent_messages": gorm.Expr("sent_messages + ?", count),
+			"updated_at":    time.Now().UTC(),
+		})
+}
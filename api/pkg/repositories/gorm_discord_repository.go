@@ -52,7 +52,7 @@ func (repository *gormDiscordRepository) Index(ctx context.Context, userID entit
 	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
 	if len(params.Query) > 0 {
 		queryPattern := "%" + params.Query + "%"
-		query.Where(repository.db.Where("url ILIKE ?", queryPattern))
+		query.Where(repository.db.Where(ilike(repository.db, "url"), queryPattern))
 	}
 
 	discords := make([]*entities.Discord, 0)
@@ -35,7 +35,7 @@ func (repository *gormEventListenerLogRepository) Store(ctx context.Context, mes
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Create(message).Error; err != nil {
+	if err := dbFromContext(ctx, repository.db).Create(message).Error; err != nil {
 		msg := fmt.Sprintf("cannot save message with ID [%s]", message.ID)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -49,7 +49,7 @@ func (repository *gormEventListenerLogRepository) Has(ctx context.Context, event
 	defer span.End()
 
 	var exists bool
-	err := repository.db.WithContext(ctx).Model(&entities.EventListenerLog{}).
+	err := dbFromContext(ctx, repository.db).Model(&entities.EventListenerLog{}).
 		Select("count(*) > 0").
 		Where("event_id = ?", eventID).
 		Where("handler = ?", handler).
@@ -3,9 +3,12 @@ package repositories
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/google/uuid"
@@ -16,8 +19,13 @@ import (
 
 // GormEvent is a serialized version of cloudevents.Event
 type GormEvent struct {
-	ID        uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;"`
-	Time      time.Time
+	ID   uuid.UUID `json:"id" gorm:"primaryKey;type:uuid;"`
+	Time time.Time `gorm:"index:idx_events__message_id__time,priority:2;index:idx_events__user_id__time,priority:2"`
+	// MessageID is the ID of the entities.Message in the payload of the event, it is set when the event is written so
+	// that the events of a message are found without scanning the JSON payloads
+	MessageID *uuid.UUID `gorm:"type:uuid;index:idx_events__message_id__time,priority:1"`
+	// UserID is the ID of the entities.User in the payload of the event, it is used to replay the events of a user
+	UserID    *entities.UserID `gorm:"index:idx_events__user_id__time,priority:1"`
 	CreatedAt time.Time
 	Source    string
 	Type      string
@@ -29,6 +37,65 @@ func (GormEvent) TableName() string {
 	return "events"
 }
 
+// NewGormEvent serializes a cloudevents.Event into a GormEvent
+func NewGormEvent(event cloudevents.Event) (*GormEvent, error) {
+	data, err := event.MarshalJSON()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot marshall event [%s]  and type [%s] into JSON", event.ID(), event.Type()))
+	}
+
+	id, err := uuid.Parse(event.ID())
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("the ID of event [%s] with type [%s] is not a UUID", event.ID(), event.Type()))
+	}
+
+	return &GormEvent{
+		ID:        id,
+		Time:      event.Time(),
+		MessageID: EventMessageID(event),
+		UserID:    EventUserID(event),
+		Source:    event.Source(),
+		CreatedAt: event.Time().UTC(),
+		Type:      event.Type(),
+		Data:      datatypes.JSON(data),
+	}, nil
+}
+
+// EventMessageID returns the ID of the entities.Message which is referenced by the payload of a cloudevents.Event.
+// The payloads of some message events store the ID of the message in the "id" field instead of "message_id".
+func EventMessageID(event cloudevents.Event) *uuid.UUID {
+	payload := struct {
+		MessageID *uuid.UUID `json:"message_id"`
+		ID        *uuid.UUID `json:"id"`
+	}{}
+
+	if err := json.Unmarshal(event.Data(), &payload); err != nil {
+		return nil
+	}
+
+	if payload.MessageID != nil {
+		return payload.MessageID
+	}
+
+	if strings.HasPrefix(event.Type(), "message.") {
+		return payload.ID
+	}
+
+	return nil
+}
+
+// EventUserID returns the ID of the entities.User which is referenced in the "user_id" field of the payload of a cloudevents.Event
+func EventUserID(event cloudevents.Event) *entities.UserID {
+	payload := struct {
+		UserID *entities.UserID `json:"user_id"`
+	}{}
+
+	if err := json.Unmarshal(event.Data(), &payload); err != nil || payload.UserID == nil || *payload.UserID == "" {
+		return nil
+	}
+	return payload.UserID
+}
+
 type gormEventRepository struct {
 	logger telemetry.Logger
 	tracer telemetry.Tracer
@@ -59,12 +126,81 @@ func (repository *gormEventRepository) FetchAll(ctx context.Context) (*[]cloudev
 		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	results, err := repository.decode(events)
+	if err != nil {
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot decode cloudevents"))
+	}
+	return results, nil
+}
+
+// FetchByMessageID returns the cloudevents.Event whose payload references the entities.Message ordered by time in ascending order
+func (repository *gormEventRepository) FetchByMessageID(ctx context.Context, messageID uuid.UUID) (*[]cloudevents.Event, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var events []GormEvent
+	err := repository.db.WithContext(ctx).
+		Where("message_id = ?", messageID).
+		Order("time ASC").
+		Find(&events).
+		Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch cloudevents of message with ID [%s]", messageID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	results, err := repository.decode(events)
+	if err != nil {
+		msg := fmt.Sprintf("cannot decode cloudevents of message with ID [%s]", messageID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return results, nil
+}
+
+// FetchByUserIDAfter returns at most limit cloudevents.Event of the entities.User which were emitted after the event with the ID, ordered by time in ascending order
+func (repository *gormEventRepository) FetchByUserIDAfter(ctx context.Context, userID entities.UserID, eventID uuid.UUID, limit int) (*[]cloudevents.Event, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	last := new(GormEvent)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", eventID).First(last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("user [%s] has no event with ID [%s]", userID, eventID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot load event with ID [%s] of user [%s]", eventID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	var events []GormEvent
+	err = repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("(time > ?) OR (time = ? AND id > ?)", last.Time, last.Time, last.ID).
+		Order("time ASC").
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).
+		Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch cloudevents of user [%s] after event [%s]", userID, eventID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	results, err := repository.decode(events)
+	if err != nil {
+		msg := fmt.Sprintf("cannot decode cloudevents of user [%s] after event [%s]", userID, eventID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return results, nil
+}
+
+func (repository *gormEventRepository) decode(events []GormEvent) (*[]cloudevents.Event, error) {
 	results := make([]cloudevents.Event, 0, len(events))
 	for _, event := range events {
 		var cloudevent cloudevents.Event
 		if err := json.Unmarshal(event.Data, &cloudevent); err != nil {
-			msg := fmt.Sprintf("cannot unmarshal [%s] into [%T]", event.Data, cloudevent)
-			return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot unmarshal [%s] into [%T]", event.Data, cloudevent))
 		}
 		results = append(results, cloudevent)
 	}
@@ -76,18 +212,9 @@ func (repository *gormEventRepository) Create(ctx context.Context, event cloudev
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	data, err := event.MarshalJSON()
+	gormEvent, err := NewGormEvent(event)
 	if err != nil {
-		return stacktrace.Propagate(err, fmt.Sprintf("cannot marshall event [%s]  and type [%s] into JSON", event.ID(), event.Type()))
-	}
-
-	gormEvent := GormEvent{
-		ID:        uuid.MustParse(event.ID()),
-		Time:      event.Time(),
-		Source:    event.Source(),
-		CreatedAt: event.Time().UTC(),
-		Type:      event.Type(),
-		Data:      datatypes.JSON(data),
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot serialize event [%s]", event.ID())))
 	}
 
 	if err = repository.db.WithContext(ctx).Create(gormEvent).Error; err != nil {
@@ -102,18 +229,9 @@ func (repository *gormEventRepository) Save(ctx context.Context, event cloudeven
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	data, err := event.MarshalJSON()
+	gormEvent, err := NewGormEvent(event)
 	if err != nil {
-		return stacktrace.Propagate(err, fmt.Sprintf("cannot marshall event [%s]  and type [%s] into JSON", event.ID(), event.Type()))
-	}
-
-	gormEvent := GormEvent{
-		ID:        uuid.MustParse(event.ID()),
-		Time:      event.Time(),
-		Source:    event.Source(),
-		CreatedAt: event.Time().UTC(),
-		Type:      event.Type(),
-		Data:      datatypes.JSON(data),
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot serialize event [%s]", event.ID())))
 	}
 
 	if err = repository.db.WithContext(ctx).Save(gormEvent).Error; err != nil {
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormFallbackProviderRepository is responsible for persisting entities.FallbackProvider
+type gormFallbackProviderRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormFallbackProviderRepository creates the GORM version of the FallbackProviderRepository
+func NewGormFallbackProviderRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) FallbackProviderRepository {
+	return &gormFallbackProviderRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormFallbackProviderRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormFallbackProviderRepository) Save(ctx context.Context, provider *entities.FallbackProvider) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(provider).Error; err != nil {
+		msg := fmt.Sprintf("cannot save fallback provider with ID [%s] for user [%s]", provider.ID, provider.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormFallbackProviderRepository) Load(ctx context.Context, userID entities.UserID) (*entities.FallbackProvider, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	provider := new(entities.FallbackProvider)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).First(provider).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("fallback provider for user [%s] does not exist", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load fallback provider for user [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return provider, nil
+}
+
+func (repository *gormFallbackProviderRepository) LoadByID(ctx context.Context, providerID uuid.UUID) (*entities.FallbackProvider, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	provider := new(entities.FallbackProvider)
+	err := repository.db.WithContext(ctx).Where("id = ?", providerID).First(provider).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("fallback provider with ID [%s] does not exist", providerID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load fallback provider with ID [%s]", providerID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return provider, nil
+}
+
+func (repository *gormFallbackProviderRepository) Delete(ctx context.Context, userID entities.UserID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entities.FallbackProvider{}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete fallback provider of user [%s]", userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -31,6 +31,7 @@ func (repository *gormHeartbeatMonitorRepository) UpdateQueueID(ctx context.Cont
 	defer cancel()
 
 	err := repository.db.
+		WithContext(ctx).
 		Model(&entities.HeartbeatMonitor{}).
 		Where("id = ?", monitorID).
 		Updates(map[string]any{
@@ -44,6 +45,78 @@ func (repository *gormHeartbeatMonitorRepository) UpdateQueueID(ctx context.Cont
 	return nil
 }
 
+// UpdatePhoneOnline changes the last known liveness of the phone of a monitor with a compare-and-set
+func (repository *gormHeartbeatMonitorRepository) UpdatePhoneOnline(ctx context.Context, monitorID uuid.UUID, phoneOnline bool) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationDuration)
+	defer cancel()
+
+	result := repository.db.WithContext(ctx).
+		Model(&entities.HeartbeatMonitor{}).
+		Where("id = ?", monitorID).
+		Where("phone_online = ?", !phoneOnline).
+		UpdateColumn("phone_online", phoneOnline)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot update phone_online to [%t] for heartbeat monitor ID [%s]", phoneOnline, monitorID)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// UpdateConsecutiveMisses updates the number of consecutive missed heartbeat checks of a monitor
+func (repository *gormHeartbeatMonitorRepository) UpdateConsecutiveMisses(ctx context.Context, monitorID uuid.UUID, misses uint) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationDuration)
+	defer cancel()
+
+	err := repository.db.WithContext(ctx).
+		Model(&entities.HeartbeatMonitor{}).
+		Where("id = ?", monitorID).
+		UpdateColumn("consecutive_misses", misses).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot update consecutive_misses to [%d] for heartbeat monitor ID [%s]", misses, monitorID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return nil
+}
+
+// UpdateLastAlertedAt updates the time when the phone of a monitor was last declared offline
+func (repository *gormHeartbeatMonitorRepository) UpdateLastAlertedAt(ctx context.Context, monitorID uuid.UUID, timestamp time.Time) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationDuration)
+	defer cancel()
+
+	err := repository.db.WithContext(ctx).
+		Model(&entities.HeartbeatMonitor{}).
+		Where("id = ?", monitorID).
+		UpdateColumn("last_alerted_at", timestamp).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot update last_alerted_at to [%s] for heartbeat monitor ID [%s]", timestamp, monitorID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return nil
+}
+
+// Fetch all heartbeat monitors
+func (repository *gormHeartbeatMonitorRepository) Fetch(ctx context.Context) (*[]entities.HeartbeatMonitor, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	monitors := new([]entities.HeartbeatMonitor)
+	if err := repository.db.WithContext(ctx).Order("created_at ASC").Find(monitors).Error; err != nil {
+		msg := "cannot fetch heartbeat monitors"
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return monitors, nil
+}
+
 func (repository *gormHeartbeatMonitorRepository) Delete(ctx context.Context, userID entities.UserID, owner string) error {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
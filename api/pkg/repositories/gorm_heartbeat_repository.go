@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -58,8 +59,45 @@ func (repository *gormHeartbeatRepository) Last(ctx context.Context, userID enti
 	return heartbeat, nil
 }
 
+// LastTimestamp returns the timestamp of the last entities.Heartbeat of a registered entities.Phone
+func (repository *gormHeartbeatRepository) LastTimestamp(ctx context.Context, userID entities.UserID, owner string) (*time.Time, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationDuration)
+	defer cancel()
+
+	lastHeartbeat := repository.db.
+		Model(&entities.Heartbeat{}).
+		Select("MAX(heartbeats.timestamp)").
+		Where("heartbeats.user_id = phones.user_id").
+		Where("heartbeats.owner = phones.phone_number")
+
+	result := struct {
+		LastHeartbeatAt *time.Time
+	}{}
+
+	err := repository.db.WithContext(ctx).
+		Model(&entities.Phone{}).
+		Select("(?) AS last_heartbeat_at", lastHeartbeat).
+		Where("phones.user_id = ?", userID).
+		Where("phones.phone_number = ?", owner).
+		Take(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("phone with userID [%s] and owner [%s] does not exist", userID, owner)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load last heartbeat timestamp with userID [%s] and owner [%s]", userID, owner)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return result.LastHeartbeatAt, nil
+}
+
 // Index entities.Message between 2 parties
-func (repository *gormHeartbeatRepository) Index(ctx context.Context, userID entities.UserID, owner string, params IndexParams) (*[]entities.Heartbeat, error) {
+func (repository *gormHeartbeatRepository) Index(ctx context.Context, userID entities.UserID, owner string, params HeartbeatIndexParams) (*[]entities.Heartbeat, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
@@ -72,6 +110,14 @@ func (repository *gormHeartbeatRepository) Index(ctx context.Context, userID ent
 		query.Where("version LIKE ?", queryPattern)
 	}
 
+	if params.From != nil {
+		query.Where("timestamp >= ?", *params.From)
+	}
+
+	if params.To != nil {
+		query.Where("timestamp <= ?", *params.To)
+	}
+
 	heartbeats := new([]entities.Heartbeat)
 	if err := query.Order("timestamp DESC").Limit(params.Limit).Offset(params.Skip).Find(&heartbeats).Error; err != nil {
 		msg := fmt.Sprintf("cannot fetch heartbeats with owner [%s] and params [%+#v]", owner, params)
@@ -96,3 +142,44 @@ func (repository *gormHeartbeatRepository) Store(ctx context.Context, heartbeat
 
 	return nil
 }
+
+// CountHourly counts the entities.Heartbeat of an owner in each hour of a time range
+func (repository *gormHeartbeatRepository) CountHourly(ctx context.Context, userID entities.UserID, owner string, from time.Time, to time.Time) (*[]entities.HeartbeatCount, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationDuration)
+	defer cancel()
+
+	counts := new([]entities.HeartbeatCount)
+	err := repository.db.WithContext(ctx).
+		Model(&entities.Heartbeat{}).
+		Select("date_trunc('hour', timestamp) AS hour, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("timestamp >= ?", from).
+		Where("timestamp <= ?", to).
+		Group("hour").
+		Order("hour ASC").
+		Scan(counts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count hourly heartbeats with owner [%s] from [%s] to [%s]", owner, from, to)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return counts, nil
+}
+
+// DeleteBefore deletes all entities.Heartbeat older than a timestamp
+func (repository *gormHeartbeatRepository) DeleteBefore(ctx context.Context, timestamp time.Time) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).Where("timestamp < ?", timestamp).Delete(&entities.Heartbeat{})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot delete heartbeats older than [%s]", timestamp)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected, nil
+}
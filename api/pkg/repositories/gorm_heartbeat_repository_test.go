@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormHeartbeatRepository_Index verifies that the heartbeat history of a phone is ordered by the most recent
+// heartbeat, paginated and scoped to the user and the time range
+func TestGormHeartbeatRepository_Index(t *testing.T) {
+	const owner = "+18005550199"
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormHeartbeatRepository(backend.logger, backend.tracer, backend.db)
+
+		storeHeartbeats := func(t *testing.T, userID entities.UserID, count int) []*entities.Heartbeat {
+			heartbeats := make([]*entities.Heartbeat, 0, count)
+			for i := 0; i < count; i++ {
+				heartbeat := &entities.Heartbeat{ID: uuid.New(), Owner: owner, Version: "344c10f", UserID: userID, Timestamp: start.Add(time.Duration(i) * time.Minute)}
+				assert.Nil(t, repository.Store(context.Background(), heartbeat))
+				heartbeats = append(heartbeats, heartbeat)
+			}
+			return heartbeats
+		}
+
+		ids := func(heartbeats []entities.Heartbeat) []uuid.UUID {
+			result := make([]uuid.UUID, 0, len(heartbeats))
+			for _, heartbeat := range heartbeats {
+				result = append(result, heartbeat.ID)
+			}
+			return result
+		}
+
+		t.Run(backend.name+": heartbeats are ordered with the most recent first", func(t *testing.T) {
+			// Arrange
+			userID := entities.UserID(uuid.NewString())
+			heartbeats := storeHeartbeats(t, userID, 3)
+
+			// Act
+			result, err := repository.Index(context.Background(), userID, owner, HeartbeatIndexParams{IndexParams: IndexParams{Limit: 10}})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, []uuid.UUID{heartbeats[2].ID, heartbeats[1].ID, heartbeats[0].ID}, ids(*result))
+		})
+
+		t.Run(backend.name+": heartbeats are paginated without gaps or duplicates", func(t *testing.T) {
+			// Arrange
+			userID := entities.UserID(uuid.NewString())
+			heartbeats := storeHeartbeats(t, userID, 5)
+
+			// Act
+			first, firstErr := repository.Index(context.Background(), userID, owner, HeartbeatIndexParams{IndexParams: IndexParams{Limit: 2}})
+			second, secondErr := repository.Index(context.Background(), userID, owner, HeartbeatIndexParams{IndexParams: IndexParams{Limit: 2, Skip: 2}})
+			last, lastErr := repository.Index(context.Background(), userID, owner, HeartbeatIndexParams{IndexParams: IndexParams{Limit: 2, Skip: 4}})
+
+			// Assert
+			assert.Nil(t, firstErr)
+			assert.Nil(t, secondErr)
+			assert.Nil(t, lastErr)
+			assert.Equal(t, []uuid.UUID{heartbeats[4].ID, heartbeats[3].ID}, ids(*first))
+			assert.Equal(t, []uuid.UUID{heartbeats[2].ID, heartbeats[1].ID}, ids(*second))
+			assert.Equal(t, []uuid.UUID{heartbeats[0].ID}, ids(*last))
+		})
+
+		t.Run(backend.name+": heartbeats of the same phone number for another user are not returned", func(t *testing.T) {
+			// Arrange
+			userID := entities.UserID(uuid.NewString())
+			heartbeats := storeHeartbeats(t, userID, 1)
+			storeHeartbeats(t, entities.UserID(uuid.NewString()), 2)
+
+			// Act
+			result, err := repository.Index(context.Background(), userID, owner, HeartbeatIndexParams{IndexParams: IndexParams{Limit: 10}})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, []uuid.UUID{heartbeats[0].ID}, ids(*result))
+		})
+
+		t.Run(backend.name+": heartbeats outside of the time range are not returned", func(t *testing.T) {
+			// Arrange
+			userID := entities.UserID(uuid.NewString())
+			heartbeats := storeHeartbeats(t, userID, 4)
+			from := heartbeats[1].Timestamp
+			to := heartbeats[2].Timestamp
+
+			// Act
+			result, err := repository.Index(context.Background(), userID, owner, HeartbeatIndexParams{IndexParams: IndexParams{Limit: 10}, From: &from, To: &to})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, []uuid.UUID{heartbeats[2].ID, heartbeats[1].ID}, ids(*result))
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormMediaRepository is responsible for persisting entities.Media
+type gormMediaRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormMediaRepository creates the GORM version of the MediaRepository
+func NewGormMediaRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) MediaRepository {
+	return &gormMediaRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormMediaRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormMediaRepository) Store(ctx context.Context, media *entities.Media) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(media).Error; err != nil {
+		msg := fmt.Sprintf("cannot store media with ID [%s] for user [%s]", media.ID, media.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormMediaRepository) Load(ctx context.Context, userID entities.UserID, mediaID uuid.UUID) (*entities.Media, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	media := new(entities.Media)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", mediaID).First(media).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("media with ID [%s] does not exist for user [%s]", mediaID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load media with ID [%s] for user [%s]", mediaID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return media, nil
+}
+
+func (repository *gormMediaRepository) Attach(ctx context.Context, userID entities.UserID, messageID uuid.UUID, mediaIDs []uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entities.Media{}).
+			Where("user_id = ?", userID).
+			Where("id IN ?", mediaIDs).
+			Where("message_id IS NULL").
+			Updates(map[string]any{
+				"message_id": messageID,
+				"updated_at": time.Now().UTC(),
+			})
+		if result.Error != nil {
+			return stacktrace.Propagate(result.Error, fmt.Sprintf("cannot attach [%d] media to message [%s]", len(mediaIDs), messageID))
+		}
+
+		if result.RowsAffected != int64(len(mediaIDs)) {
+			msg := fmt.Sprintf("[%d] of [%d] media are already attached to a message or do not exist", int64(len(mediaIDs))-result.RowsAffected, len(mediaIDs))
+			return stacktrace.NewErrorWithCode(ErrCodeStaleUpdate, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot attach media to message [%s] for user [%s]", messageID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return nil
+}
+
+func (repository *gormMediaRepository) FetchByMessages(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]*entities.Media, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	media := make([]*entities.Media, 0)
+	if len(messageIDs) == 0 {
+		return media, nil
+	}
+
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("message_id IN ?", messageIDs).
+		Order("created_at ASC").
+		Order("id ASC").
+		Find(&media).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the media of [%d] messages for user [%s]", len(messageIDs), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return media, nil
+}
+
+func (repository *gormMediaRepository) FetchUnattached(ctx context.Context, before time.Time, limit int) ([]*entities.Media, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	media := make([]*entities.Media, 0)
+	err := repository.db.WithContext(ctx).
+		Where("message_id IS NULL").
+		Where("created_at < ?", before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&media).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the media created before [%s] which is not attached to a message", before)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return media, nil
+}
+
+func (repository *gormMediaRepository) Delete(ctx context.Context, mediaIDs []uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	if err := repository.db.WithContext(ctx).Where("id IN ?", mediaIDs).Delete(&entities.Media{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete [%d] media", len(mediaIDs))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormMediaRepository_Attach verifies that media can only be attached to one message
+func TestGormMediaRepository_Attach(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMediaRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			first := newTestMedia("user-a", time.Now().UTC())
+			second := newTestMedia("user-a", time.Now().UTC())
+			assert.Nil(t, repository.Store(ctx, first))
+			assert.Nil(t, repository.Store(ctx, second))
+			messageID := uuid.New()
+
+			// Act
+			err1 := repository.Attach(ctx, "user-a", messageID, []uuid.UUID{first.ID, second.ID})
+			err2 := repository.Attach(ctx, "user-a", uuid.New(), []uuid.UUID{first.ID})
+
+			// Assert
+			assert.Nil(t, err1)
+			assert.Equal(t, ErrCodeStaleUpdate, stacktrace.GetCode(err2))
+
+			media, err := repository.FetchByMessages(ctx, "user-a", []uuid.UUID{messageID})
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(media))
+			assert.Equal(t, messageID, *media[0].MessageID)
+
+			media, err = repository.FetchByMessages(ctx, "user-b", []uuid.UUID{messageID})
+			assert.Nil(t, err)
+			assert.Empty(t, media)
+		})
+	}
+}
+
+// TestGormMediaRepository_FetchUnattached verifies that only the expired media which is not attached to a message is garbage collected
+func TestGormMediaRepository_FetchUnattached(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMediaRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			before := time.Now().UTC().Add(-time.Hour)
+
+			expired := newTestMedia(userID, before.Add(-time.Minute))
+			attached := newTestMedia(userID, before.Add(-time.Minute))
+			recent := newTestMedia(userID, time.Now().UTC())
+			for _, media := range []*entities.Media{expired, attached, recent} {
+				assert.Nil(t, repository.Store(ctx, media))
+			}
+			assert.Nil(t, repository.Attach(ctx, userID, uuid.New(), []uuid.UUID{attached.ID}))
+
+			// Act
+			media, err := repository.FetchUnattached(ctx, before, 1000)
+
+			// Assert
+			assert.Nil(t, err)
+			var ids []uuid.UUID
+			for _, item := range media {
+				if item.UserID == userID {
+					ids = append(ids, item.ID)
+				}
+			}
+			assert.Equal(t, []uuid.UUID{expired.ID}, ids)
+
+			assert.Nil(t, repository.Delete(ctx, ids))
+			_, err = repository.Load(ctx, userID, expired.ID)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+		})
+	}
+}
+
+func newTestMedia(userID entities.UserID, createdAt time.Time) *entities.Media {
+	id := uuid.New()
+	return &entities.Media{
+		ID:          id,
+		UserID:      userID,
+		ContentType: "image/jpeg",
+		Size:        1024,
+		Path:        "media/" + string(userID) + "/" + id.String(),
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+}
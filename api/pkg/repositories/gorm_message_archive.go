@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// archivableMessageStatuses are the statuses of an entities.Message which will not be updated again by the phone
+var archivableMessageStatuses = []entities.MessageStatus{
+	entities.MessageStatusSent,
+	entities.MessageStatusReceived,
+	entities.MessageStatusDelivered,
+	entities.MessageStatusFailed,
+	entities.MessageStatusExpired,
+	entities.MessageStatusDeleted,
+}
+
+// GormArchivedMessage is an entities.Message which has been moved out of the messages table by MessageRepository.Archive.
+// The message is serialized in Data so that the archive does not need to be migrated when a column is added to entities.Message
+type GormArchivedMessage struct {
+	ID             uuid.UUID       `gorm:"primaryKey;type:uuid;"`
+	UserID         entities.UserID `gorm:"index:idx_messages_archive_user_id_owner_contact,priority:1"`
+	Owner          string          `gorm:"index:idx_messages_archive_user_id_owner_contact,priority:2"`
+	Contact        string          `gorm:"index:idx_messages_archive_user_id_owner_contact,priority:3"`
+	OrderTimestamp time.Time       `gorm:"index:idx_messages_archive_user_id_owner_contact,priority:4"`
+	Data           datatypes.JSON
+	ArchivedAt     time.Time
+	DeletedAt      gorm.DeletedAt
+}
+
+// TableName overrides the table name used by GormArchivedMessage to `messages_archive`
+func (GormArchivedMessage) TableName() string {
+	return "messages_archive"
+}
+
+// Archive moves up to limit entities.Message which were last ordered before a timestamp into the archive
+func (repository *gormMessageRepository) Archive(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var count int64
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		var messages []entities.Message
+		err := tx.WithContext(ctx).
+			Unscoped().
+			Where("order_timestamp < ?", before).
+			Where("status IN ?", archivableMessageStatuses).
+			Where("content_expires_at IS NULL OR content_expired_at IS NOT NULL").
+			Order("order_timestamp ASC, id ASC").
+			Limit(limit).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		archived := make([]GormArchivedMessage, 0, len(messages))
+		ids := make([]uuid.UUID, 0, len(messages))
+		for _, message := range messages {
+			data, err := json.Marshal(message)
+			if err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot marshal message with ID [%s]", message.ID))
+			}
+
+			archived = append(archived, GormArchivedMessage{
+				ID:             message.ID,
+				UserID:         message.UserID,
+				Owner:          message.Owner,
+				Contact:        message.Contact,
+				OrderTimestamp: message.OrderTimestamp,
+				Data:           datatypes.JSON(data),
+				ArchivedAt:     time.Now().UTC(),
+				DeletedAt:      message.DeletedAt,
+			})
+			ids = append(ids, message.ID)
+		}
+
+		if err = tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&archived).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot archive [%d] messages", len(archived)))
+		}
+
+		result := tx.WithContext(ctx).Unscoped().Where("id IN ?", ids).Delete(&entities.Message{})
+		count = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot archive messages before [%s]", before)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count, nil
+}
+
+// IndexArchived fetches up to limit archived entities.Message which were ordered before a timestamp ordered by the ID
+func (repository *gormMessageRepository) IndexArchived(ctx context.Context, before time.Time, limit int) ([]entities.Message, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var archived []GormArchivedMessage
+	err := replicaFromContext(ctx, repository.db, repository.replica).
+		Unscoped().
+		Where("order_timestamp < ?", before).
+		Order("id ASC").
+		Limit(limit).
+		Find(&archived).
+		Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch archived messages before [%s]", before)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	messages := make([]entities.Message, 0, len(archived))
+	for index := range archived {
+		message, err := repository.unmarshalArchived(&archived[index])
+		if err != nil {
+			return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot unmarshal archived message"))
+		}
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+// PurgeArchived permanently deletes archived entities.Message by ID
+func (repository *gormMessageRepository) PurgeArchived(ctx context.Context, messageIDs []uuid.UUID) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if len(messageIDs) == 0 {
+		return 0, nil
+	}
+
+	result := dbFromContext(ctx, repository.db).Unscoped().Where("id IN ?", messageIDs).Delete(&GormArchivedMessage{})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot purge [%d] archived messages", len(messageIDs))
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected, nil
+}
+
+// loadArchived loads an entities.Message from the archive
+func (repository *gormMessageRepository) loadArchived(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
+	archived := new(GormArchivedMessage)
+	err := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id = ?", messageID).First(archived).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("archived message with ID [%s] and userID [%s] does not exist", messageID, userID)
+		return nil, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg)
+	}
+
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot load archived message with ID [%s]", messageID))
+	}
+
+	return repository.unmarshalArchived(archived)
+}
+
+// loadManyArchived loads the entities.Message of a user with the IDs from the archive
+func (repository *gormMessageRepository) loadManyArchived(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]entities.Message, error) {
+	var archived []GormArchivedMessage
+	if err := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id IN ?", messageIDs).Find(&archived).Error; err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot load [%d] archived messages", len(messageIDs)))
+	}
+
+	messages := make([]entities.Message, 0, len(archived))
+	for index := range archived {
+		message, err := repository.unmarshalArchived(&archived[index])
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot unmarshal archived message")
+		}
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+// indexArchived fetches the archived entities.Message between 2 parties, the archived messages are ordered after the messages which are not archived
+func (repository *gormMessageRepository) indexArchived(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) ([]entities.Message, error) {
+	query := replicaFromContext(ctx, repository.db, repository.replica).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("contact = ?", contact)
+	if len(params.Query) > 0 {
+		query.Where(ilike(repository.db, jsonText(repository.db, "data", "content")), "%"+params.Query+"%").Where(jsonFalse(repository.db, "data", "is_encrypted"))
+	}
+
+	query = repository.indexFilters(query, params, true)
+	if params.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	var archived []GormArchivedMessage
+	if err := query.Order("order_timestamp DESC, id DESC").Limit(params.Limit).Offset(params.Skip).Find(&archived).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch archived messages with owner [%s] and contact [%s] and params [%+#v]", owner, contact, params)
+		return nil, stacktrace.Propagate(err, msg)
+	}
+
+	messages := make([]entities.Message, 0, len(archived))
+	for index := range archived {
+		message, err := repository.unmarshalArchived(&archived[index])
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot unmarshal archived message")
+		}
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+// countArchived counts the archived entities.Message between 2 parties which match the filters of indexArchived
+func (repository *gormMessageRepository) countArchived(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (int64, error) {
+	query := replicaFromContext(ctx, repository.db, repository.replica).
+		Model(&GormArchivedMessage{}).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("contact = ?", contact)
+	if len(params.Query) > 0 {
+		query.Where(ilike(repository.db, jsonText(repository.db, "data", "content")), "%"+params.Query+"%").Where(jsonFalse(repository.db, "data", "is_encrypted"))
+	}
+
+	query = repository.indexFilters(query, params, true)
+	if params.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		msg := fmt.Sprintf("cannot count archived messages with owner [%s] and contact [%s] and params [%+#v]", owner, contact, params)
+		return 0, stacktrace.Propagate(err, msg)
+	}
+	return count, nil
+}
+
+func (repository *gormMessageRepository) unmarshalArchived(archived *GormArchivedMessage) (*entities.Message, error) {
+	message := new(entities.Message)
+	if err := json.Unmarshal(archived.Data, message); err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot unmarshal archived message with ID [%s]", archived.ID))
+	}
+	message.DeletedAt = archived.DeletedAt
+	return message, nil
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// BenchmarkGormMessageRepository_Index compares the latency of the first page of a conversation before and after
+// the old messages are archived. It seeds the database in the DATABASE_URL_TEST environment variable.
+func BenchmarkGormMessageRepository_Index(b *testing.B) {
+	dsn := os.Getenv("DATABASE_URL_TEST")
+	if dsn == "" {
+		b.Skip("DATABASE_URL_TEST is not set")
+	}
+
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	assert.Nil(b, err)
+	assert.Nil(b, db.AutoMigrate(&entities.Message{}, &GormArchivedMessage{}))
+
+	ctx := context.Background()
+	repository := NewGormMessageRepository(logger, tracer, db, nil)
+
+	userID := entities.UserID(fmt.Sprintf("benchmark-%s", uuid.NewString()))
+	owner := "+18005550199"
+	contact := "+18005550100"
+	cutoff := time.Now().UTC().Add(-180 * 24 * time.Hour)
+
+	const total = 50_000
+	messages := make([]entities.Message, 0, total)
+	for i := 0; i < total; i++ {
+		timestamp := cutoff.Add(time.Duration(i-total+total/10) * time.Minute)
+		messages = append(messages, entities.Message{
+			ID:                uuid.New(),
+			Owner:             owner,
+			Contact:           contact,
+			UserID:            userID,
+			Content:           fmt.Sprintf("benchmark message %d", i),
+			Type:              entities.MessageTypeMobileTerminated,
+			Status:            entities.MessageStatusDelivered,
+			RequestReceivedAt: timestamp,
+			OrderTimestamp:    timestamp,
+			CreatedAt:         timestamp,
+			UpdatedAt:         timestamp,
+		})
+	}
+	assert.Nil(b, db.CreateInBatches(&messages, 1000).Error)
+
+	b.Cleanup(func() {
+		db.Unscoped().Where("user_id = ?", userID).Delete(&entities.Message{})
+		db.Unscoped().Where("user_id = ?", userID).Delete(&GormArchivedMessage{})
+	})
+
+	index := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err = repository.Index(ctx, userID, owner, contact, IndexParams{Limit: 20}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("before archive", index)
+
+	for {
+		count, err := repository.Archive(ctx, cutoff, 1000)
+		assert.Nil(b, err)
+		if count < 1000 {
+			break
+		}
+	}
+
+	b.Run("after archive", index)
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormMessageExportRepository is responsible for persisting entities.MessageExport
+type gormMessageExportRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormMessageExportRepository creates the GORM version of the MessageExportRepository
+func NewGormMessageExportRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) MessageExportRepository {
+	return &gormMessageExportRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormMessageExportRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+// Store a new entities.MessageExport
+func (repository *gormMessageExportRepository) Store(ctx context.Context, export *entities.MessageExport) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := dbFromContext(ctx, repository.db).Create(export).Error; err != nil {
+		msg := fmt.Sprintf("cannot save message export with ID [%s]", export.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// Update an entities.MessageExport
+func (repository *gormMessageExportRepository) Update(ctx context.Context, export *entities.MessageExport) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := dbFromContext(ctx, repository.db).Save(export).Error; err != nil {
+		msg := fmt.Sprintf("cannot update message export with ID [%s]", export.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// Delete an entities.MessageExport
+func (repository *gormMessageExportRepository) Delete(ctx context.Context, exportID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := dbFromContext(ctx, repository.db).Where("id = ?", exportID).Delete(&entities.MessageExport{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete message export with ID [%s]", exportID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// IndexIncomplete fetches the entities.MessageExport whose messages have not been purged
+func (repository *gormMessageExportRepository) IndexIncomplete(ctx context.Context) ([]entities.MessageExport, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var exports []entities.MessageExport
+	err := dbFromContext(ctx, repository.db).
+		Where("status != ?", entities.MessageExportStatusPurged).
+		Order("created_at ASC").
+		Find(&exports).
+		Error
+	if err != nil {
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot fetch incomplete message exports"))
+	}
+
+	return exports, nil
+}
+
+// IndexByMessageID fetches the entities.MessageExport whose ID range contains a message ID
+func (repository *gormMessageExportRepository) IndexByMessageID(ctx context.Context, messageID uuid.UUID) ([]entities.MessageExport, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var exports []entities.MessageExport
+	err := dbFromContext(ctx, repository.db).
+		Where("first_message_id <= ?", messageID).
+		Where("last_message_id >= ?", messageID).
+		Where("status != ?", entities.MessageExportStatusPending).
+		Order("created_at DESC").
+		Find(&exports).
+		Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch message exports containing message with ID [%s]", messageID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return exports, nil
+}
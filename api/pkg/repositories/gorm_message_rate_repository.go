@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormMessageRateRepository is responsible for persisting entities.MessageRate
+type gormMessageRateRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormMessageRateRepository creates the GORM version of the MessageRateRepository
+func NewGormMessageRateRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) MessageRateRepository {
+	return &gormMessageRateRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormMessageRateRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormMessageRateRepository) Store(ctx context.Context, rate *entities.MessageRate) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(rate).Error; err != nil {
+		msg := fmt.Sprintf("cannot store message rate with ID [%s] for user [%s]", rate.ID, rate.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormMessageRateRepository) Update(ctx context.Context, rate *entities.MessageRate) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(rate).Error; err != nil {
+		msg := fmt.Sprintf("cannot update message rate with ID [%s] for user [%s]", rate.ID, rate.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormMessageRateRepository) Load(ctx context.Context, userID entities.UserID, rateID uuid.UUID) (*entities.MessageRate, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rate := new(entities.MessageRate)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", rateID).First(rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("message rate with ID [%s] for user [%s] does not exist", rateID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message rate with ID [%s] for user [%s]", rateID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rate, nil
+}
+
+func (repository *gormMessageRateRepository) Fetch(ctx context.Context, userID entities.UserID) ([]*entities.MessageRate, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rates := make([]*entities.MessageRate, 0)
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("effective_from ASC").
+		Order("prefix ASC").
+		Find(&rates).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch message rates for user [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rates, nil
+}
+
+func (repository *gormMessageRateRepository) Delete(ctx context.Context, rate *entities.MessageRate) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Where("user_id = ?", rate.UserID).Where("id = ?", rate.ID).Delete(&entities.MessageRate{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete message rate with ID [%s] for user [%s]", rate.ID, rate.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormMessageRateRepository) SummarizeCosts(ctx context.Context, userID entities.UserID, params MessageCostParams) ([]*entities.MessageCostSummary, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	columns := "cost_currency"
+	if params.GroupByRequestID {
+		columns = "request_id, cost_currency"
+	}
+
+	query := repository.db.WithContext(ctx).
+		Unscoped().
+		Model(&entities.Message{}).
+		Select(columns+" AS currency, COUNT(*) AS messages, SUM(segments) AS segments, SUM(cost) AS cost").
+		Where("user_id = ?", userID).
+		Where("type = ?", entities.MessageTypeMobileTerminated).
+		Where("cost IS NOT NULL").
+		Where("status NOT IN ?", []entities.MessageStatus{entities.MessageStatusFailed, entities.MessageStatusExpired})
+
+	if params.From != nil {
+		query.Where("request_received_at >= ?", *params.From)
+	}
+	if params.To != nil {
+		query.Where("request_received_at < ?", *params.To)
+	}
+	if params.RequestID != nil {
+		query.Where("request_id = ?", *params.RequestID)
+	}
+
+	summaries := make([]*entities.MessageCostSummary, 0)
+	if err := query.Group(columns).Order(columns).Scan(&summaries).Error; err != nil {
+		msg := fmt.Sprintf("cannot summarize the cost of the messages of user [%s] with params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, summary := range summaries {
+		summary.Cost = entities.RoundCost(summary.Cost)
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCostedMessage(userID entities.UserID, requestID string, currency string, segments uint, cost float64) *entities.Message {
+	message := newTestMessage(userID, "This is a sample text message", time.Now().UTC())
+	message.RequestID = &requestID
+	message.Segments = segments
+	message.Cost = &cost
+	message.CostCurrency = &currency
+	return message
+}
+
+// TestGormMessageRateRepository_SummarizeCosts verifies that the cost of the messages of a user is summed in each currency
+func TestGormMessageRateRepository_SummarizeCosts(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageRateRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": cost is summed for each request and currency", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			failed := newTestCostedMessage(userID, "campaign-a", "USD", 1, 0.5)
+			failed.Status = entities.MessageStatusFailed
+			messages := []*entities.Message{
+				newTestCostedMessage(userID, "campaign-a", "USD", 1, 0.0075),
+				newTestCostedMessage(userID, "campaign-a", "USD", 2, 0.015),
+				newTestCostedMessage(userID, "campaign-b", "USD", 1, 0.0075),
+				newTestCostedMessage(userID, "campaign-b", "EUR", 1, 0.01),
+				newTestCostedMessage(entities.UserID(uuid.NewString()), "campaign-a", "USD", 1, 0.0075),
+				failed,
+			}
+			for _, message := range messages {
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			summaries, err := repository.SummarizeCosts(ctx, userID, MessageCostParams{GroupByRequestID: true})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 3, len(summaries))
+			assert.Equal(t, "campaign-a", *summaries[0].RequestID)
+			assert.Equal(t, "USD", summaries[0].Currency)
+			assert.Equal(t, uint(2), summaries[0].Messages)
+			assert.Equal(t, uint(3), summaries[0].Segments)
+			assert.Equal(t, 0.0225, summaries[0].Cost)
+			assert.Equal(t, "EUR", summaries[1].Currency)
+			assert.Equal(t, 0.01, summaries[1].Cost)
+		})
+
+		t.Run(backend.name+": cost of a request is summed in each currency", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			requestID := "campaign-a"
+			for _, message := range []*entities.Message{
+				newTestCostedMessage(userID, requestID, "USD", 1, 0.0075),
+				newTestCostedMessage(userID, "campaign-b", "USD", 1, 0.0075),
+			} {
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			summaries, err := repository.SummarizeCosts(ctx, userID, MessageCostParams{RequestID: &requestID})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(summaries))
+			assert.Nil(t, summaries[0].RequestID)
+			assert.Equal(t, uint(1), summaries[0].Messages)
+			assert.Equal(t, 0.0075, summaries[0].Cost)
+		})
+	}
+}
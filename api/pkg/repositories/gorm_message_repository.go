@@ -4,22 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
-
-	"gorm.io/gorm/clause"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
-	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	"github.com/google/uuid"
 	"github.com/palantir/stacktrace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // gormMessageRepository is responsible for persisting entities.Message
 type gormMessageRepository struct {
-	logger telemetry.Logger
-	tracer telemetry.Tracer
-	db     *gorm.DB
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	db      *gorm.DB
+	replica *gorm.DB
 }
 
 // NewGormMessageRepository creates the GORM version of the MessageRepository
@@ -27,11 +27,13 @@ func NewGormMessageRepository(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	db *gorm.DB,
+	replica *gorm.DB,
 ) MessageRepository {
 	return &gormMessageRepository{
-		logger: logger.WithService(fmt.Sprintf("%T", &gormMessageRepository{})),
-		tracer: tracer,
-		db:     db,
+		logger:  logger.WithService(fmt.Sprintf("%T", &gormMessageRepository{})),
+		tracer:  tracer,
+		db:      db,
+		replica: replica,
 	}
 }
 
@@ -40,7 +42,7 @@ func (repository *gormMessageRepository) DeleteByOwnerAndContact(ctx context.Con
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	err := repository.db.WithContext(ctx).
+	err := dbFromContext(ctx, repository.db).
 		Where("user_id = ?", userID).
 		Where("owner = ?", owner).
 		Where("contact = ?", contact).
@@ -51,6 +53,17 @@ func (repository *gormMessageRepository) DeleteByOwnerAndContact(ctx context.Con
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	err = dbFromContext(ctx, repository.db).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("contact = ?", contact).
+		Delete(&GormArchivedMessage{}).
+		Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete archived messages between owner [%s] and contact [%s] for user with ID [%s]", owner, contact, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
 	return nil
 }
 
@@ -59,12 +72,47 @@ func (repository *gormMessageRepository) Delete(ctx context.Context, userID enti
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", messageID).Delete(&entities.Message{}).Error
+	err := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id = ?", messageID).Delete(&entities.Message{}).Error
 	if err != nil {
 		msg := fmt.Sprintf("cannot delete message with ID [%s] for user with ID [%s]", messageID, userID)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	err = dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id = ?", messageID).Delete(&GormArchivedMessage{}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete archived message with ID [%s] for user with ID [%s]", messageID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// Restore an entities.Message which was soft deleted
+func (repository *gormMessageRepository) Restore(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := dbFromContext(ctx, repository.db).
+		Unscoped().
+		Model(&entities.Message{}).
+		Where("user_id = ?", userID).
+		Where("id = ?", messageID).
+		Where("deleted_at IS NOT NULL").
+		Updates(map[string]any{
+			"deleted_at": nil,
+			"updated_at": time.Now().UTC(),
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot restore message with ID [%s] for user with ID [%s]", messageID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 0 {
+		msg := fmt.Sprintf("deleted message with ID [%s] does not exist for user with ID [%s]", messageID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeNotFound, msg))
+	}
+
 	return nil
 }
 
@@ -73,31 +121,151 @@ func (repository *gormMessageRepository) Index(ctx context.Context, userID entit
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	query := repository.db.
-		WithContext(ctx).
-		Where("user_id = ?", userID).
-		Where("owner = ?", owner).
-		Where("contact =  ?", contact)
-	if len(params.Query) > 0 {
-		queryPattern := "%" + params.Query + "%"
-		query.Where("content ILIKE ?", queryPattern)
+	filter := func() *gorm.DB {
+		query := replicaFromContext(ctx, repository.db, repository.replica).
+			Where("user_id = ?", userID).
+			Where("owner = ?", owner).
+			Where("contact =  ?", contact)
+		if len(params.Query) > 0 {
+			queryPattern := "%" + params.Query + "%"
+			// the content of an encrypted message is ciphertext which cannot be searched
+			query.Where(ilike(repository.db, "content"), queryPattern).Where("is_encrypted = ?", false)
+		}
+
+		query = repository.indexFilters(query, params, false)
+		if params.IncludeDeleted {
+			query = query.Unscoped()
+		}
+		return query
 	}
 
 	messages := new([]entities.Message)
-	if err := query.Order("order_timestamp DESC").Limit(params.Limit).Offset(params.Skip).Find(&messages).Error; err != nil {
+	if err := filter().Order("order_timestamp DESC, id DESC").Limit(params.Limit).Offset(params.Skip).Find(&messages).Error; err != nil {
 		msg := fmt.Sprintf("cannot fetch messges with owner [%s] and contact [%s] and params [%+#v]", owner, contact, params)
 		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if !params.IncludeArchived || len(*messages) >= params.Limit {
+		return messages, nil
+	}
+
+	archiveParams := params
+	archiveParams.Skip = 0
+	archiveParams.Limit = params.Limit - len(*messages)
+	if len(*messages) == 0 && params.Skip > 0 {
+		var count int64
+		if err := filter().Model(&entities.Message{}).Count(&count).Error; err != nil {
+			msg := fmt.Sprintf("cannot count messges with owner [%s] and contact [%s] and params [%+#v]", owner, contact, params)
+			return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		archiveParams.Skip = params.Skip - int(count)
+	}
+
+	archived, err := repository.indexArchived(ctx, userID, owner, contact, archiveParams)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch archived messges with owner [%s] and contact [%s] and params [%+#v]", owner, contact, archiveParams)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	*messages = append(*messages, archived...)
 	return messages, nil
 }
 
+// Fallback moves a pending, scheduled or expired entities.Message from the phone to the channel of a fallback provider
+func (repository *gormMessageRepository) Fallback(ctx context.Context, userID entities.UserID, messageID uuid.UUID, channel entities.MessageChannel) (*entities.Message, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	timestamp := time.Now().UTC()
+	message := new(entities.Message)
+	err := executeTx(ctx, repository.db,
+		func(tx *gorm.DB) error {
+			query := func(tx *gorm.DB) *gorm.DB {
+				return tx.
+					Where("user_id = ?", userID).
+					Where("id = ?", messageID).
+					Where("channel = ?", entities.MessageChannelPhone).
+					Where("status IN ?", []entities.MessageStatus{entities.MessageStatusPending, entities.MessageStatusScheduled, entities.MessageStatusExpired})
+			}
+			rowsAffected, err := updateReturning(tx.WithContext(ctx), message, query, map[string]any{
+				"channel":           channel,
+				"status":            entities.MessageStatusSending,
+				"last_attempted_at": timestamp,
+				"updated_at":        timestamp,
+				"version":           gorm.Expr("version + 1"),
+			})
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			return nil
+		},
+	)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("message with ID [%s] cannot be sent by the phone of user [%s]", messageID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot move message with ID [%s] to channel [%s] for user [%s]", messageID, channel, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return message, nil
+}
+
+// Failover moves a pending entities.Message from an owner to the failover owner
+func (repository *gormMessageRepository) Failover(ctx context.Context, userID entities.UserID, messageID uuid.UUID, owner string, failoverOwner string, sim entities.SIM) (*entities.Message, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	message := new(entities.Message)
+	err := executeTx(ctx, repository.db,
+		func(tx *gorm.DB) error {
+			query := func(tx *gorm.DB) *gorm.DB {
+				return tx.
+					Where("user_id = ?", userID).
+					Where("id = ?", messageID).
+					Where("owner = ?", owner).
+					Where("status = ?", entities.MessageStatusPending)
+			}
+			rowsAffected, err := updateReturning(tx.WithContext(ctx), message, query, map[string]any{
+				"owner":          failoverOwner,
+				"original_owner": gorm.Expr("COALESCE(original_owner, ?)", owner),
+				"sim":            sim,
+				"updated_at":     time.Now().UTC(),
+				"version":        gorm.Expr("version + 1"),
+			})
+			if err != nil {
+				return err
+			}
+			if rowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+			return nil
+		},
+	)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("pending message with ID [%s] and owner [%s] does not exist for user [%s]", messageID, owner, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot move message with ID [%s] from owner [%s] to [%s] for user [%s]", messageID, owner, failoverOwner, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return message, nil
+}
+
 // Store a new entities.Message
 func (repository *gormMessageRepository) Store(ctx context.Context, message *entities.Message) error {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Create(message).Error; err != nil {
+	if err := dbFromContext(ctx, repository.db).Create(message).Error; err != nil {
 		msg := fmt.Sprintf("cannot save message with ID [%s]", message.ID)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -105,14 +273,85 @@ func (repository *gormMessageRepository) Store(ctx context.Context, message *ent
 	return nil
 }
 
+// messageStoreBatchSize is the number of entities.Message in one INSERT, it keeps the number of parameters below the
+// limit of SQLite (32766) which is the lowest of the supported databases
+const messageStoreBatchSize = 500
+
+// StoreMany inserts entities.Message with one multi-row INSERT per batch
+func (repository *gormMessageRepository) StoreMany(ctx context.Context, messages []*entities.Message) ([]StoreOutcome, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	outcomes := make([]StoreOutcome, len(messages))
+	for start := 0; start < len(messages); start += messageStoreBatchSize {
+		end := start + messageStoreBatchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+			return repository.storeBatch(tx.WithContext(ctx), messages[start:end], outcomes[start:end])
+		})
+		if err != nil {
+			msg := fmt.Sprintf("cannot store messages [%d] to [%d] of [%d]", start, end, len(messages))
+			return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	return outcomes, nil
+}
+
+// storeBatch inserts the messages whose ID does not exist. ON CONFLICT DO NOTHING skips a message which is inserted
+// by a concurrent transaction after the existing IDs are fetched.
+func (repository *gormMessageRepository) storeBatch(tx *gorm.DB, messages []*entities.Message, outcomes []StoreOutcome) error {
+	ids := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+
+	var existing []uuid.UUID
+	if err := tx.Unscoped().Model(&entities.Message{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the existing IDs of [%d] messages", len(ids)))
+	}
+
+	exists := map[uuid.UUID]bool{}
+	for _, id := range existing {
+		exists[id] = true
+	}
+
+	inserts := make([]*entities.Message, 0, len(messages))
+	for index, message := range messages {
+		outcomes[index] = StoreOutcomeSkipped
+		if !exists[message.ID] {
+			exists[message.ID] = true
+			outcomes[index] = StoreOutcomeInserted
+			inserts = append(inserts, message)
+		}
+	}
+
+	if len(inserts) == 0 {
+		return nil
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&inserts).Error; err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot insert [%d] messages", len(inserts)))
+	}
+
+	return nil
+}
+
 // Load an entities.Message by ID
 func (repository *gormMessageRepository) Load(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
 	message := new(entities.Message)
-	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", messageID).First(message).Error
+	err := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id = ?", messageID).First(message).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
+		message, err = repository.loadArchived(ctx, userID, messageID)
+	}
+
+	if stacktrace.GetCode(err) == ErrCodeNotFound {
 		msg := fmt.Sprintf("message with ID [%s] and userID [%s] does not exist", messageID, userID)
 		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
 	}
@@ -125,33 +364,130 @@ func (repository *gormMessageRepository) Load(ctx context.Context, userID entiti
 	return message, nil
 }
 
-// Update an entities.Message
+// LoadLastReceived fetches the latest entities.Message which the owner received from the contact, the archived messages are not included
+func (repository *gormMessageRepository) LoadLastReceived(ctx context.Context, userID entities.UserID, owner string, contact string) (*entities.Message, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	message := new(entities.Message)
+	err := dbFromContext(ctx, repository.db).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("contact = ?", contact).
+		Where("type = ?", entities.MessageTypeMobileOriginated).
+		Order("order_timestamp DESC").
+		First(message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("owner [%s] has not received a message from contact [%s] for user [%s]", owner, contact, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the last message received by owner [%s] from contact [%s]", owner, contact)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return message, nil
+}
+
+// LoadMany loads the entities.Message of a user with the IDs, the archive is only queried for the IDs which are not in the messages table
+func (repository *gormMessageRepository) LoadMany(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]entities.Message, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if len(messageIDs) == 0 {
+		return []entities.Message{}, nil
+	}
+
+	var messages []entities.Message
+	if err := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id IN ?", messageIDs).Find(&messages).Error; err != nil {
+		msg := fmt.Sprintf("cannot load [%d] messages for user [%s]", len(messageIDs), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if len(messages) == len(messageIDs) {
+		return messages, nil
+	}
+
+	found := make(map[uuid.UUID]struct{}, len(messages))
+	for _, message := range messages {
+		found[message.ID] = struct{}{}
+	}
+
+	missing := make([]uuid.UUID, 0, len(messageIDs)-len(messages))
+	for _, messageID := range messageIDs {
+		if _, ok := found[messageID]; !ok {
+			missing = append(missing, messageID)
+		}
+	}
+
+	archived, err := repository.loadManyArchived(ctx, userID, missing)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load [%d] archived messages for user [%s]", len(missing), userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return append(messages, archived...), nil
+}
+
+// Update an entities.Message if its version has not changed since it was loaded
 func (repository *gormMessageRepository) Update(ctx context.Context, message *entities.Message) error {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Save(message).Error; err != nil {
+	version := message.Version
+	message.Version++
+
+	result := dbFromContext(ctx, repository.db).Model(message).Where("version = ?", version).Select("*").Updates(message)
+	if result.Error != nil {
+		message.Version = version
 		msg := fmt.Sprintf("cannot update message with ID [%s]", message.ID)
-		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 0 {
+		message.Version = version
+		return repository.tracer.WrapErrorSpan(span, repository.updateMissError(ctx, message.ID, version))
 	}
 
 	return nil
 }
 
+// updateMissError explains why no row was updated, an archived or deleted message is not in the messages table so
+// retrying the update cannot succeed
+func (repository *gormMessageRepository) updateMissError(ctx context.Context, messageID uuid.UUID, version uint) error {
+	var count int64
+	if err := dbFromContext(ctx, repository.db).Model(&entities.Message{}).Where("id = ?", messageID).Count(&count).Error; err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot check if message with ID [%s] exists", messageID))
+	}
+
+	if count == 0 {
+		return stacktrace.NewErrorWithCode(ErrCodeNotFound, fmt.Sprintf("message with ID [%s] is archived or deleted and cannot be updated", messageID))
+	}
+
+	return stacktrace.NewErrorWithCode(ErrCodeStaleUpdate, fmt.Sprintf("message with ID [%s] and version [%d] was updated concurrently", messageID, version))
+}
+
 // GetOutstanding fetches messages that still to be sent to the phone
 func (repository *gormMessageRepository) GetOutstanding(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
 	message := new(entities.Message)
-	err := crdbgorm.ExecuteTx(ctx, repository.db, nil,
+	err := executeTx(ctx, repository.db,
 		func(tx *gorm.DB) error {
-			return tx.WithContext(ctx).Model(message).
-				Clauses(clause.Returning{}).
-				Where("user_id = ?", userID).
-				Where("id = ?", messageID).
-				Where(repository.db.Where("status = ?", entities.MessageStatusScheduled).Or("status = ?", entities.MessageStatusPending).Or("status = ?", entities.MessageStatusExpired)).
-				Update("status", entities.MessageStatusSending).Error
+			query := func(tx *gorm.DB) *gorm.DB {
+				return tx.
+					Where("user_id = ?", userID).
+					Where("id = ?", messageID).
+					Where("channel = ?", entities.MessageChannelPhone).
+					Where(repository.db.Where("status = ?", entities.MessageStatusScheduled).Or("status = ?", entities.MessageStatusPending).Or("status = ?", entities.MessageStatusExpired))
+			}
+			_, err := updateReturning(tx.WithContext(ctx), message, query, map[string]any{
+				"status":  entities.MessageStatusSending,
+				"version": gorm.Expr("version + 1"),
+			})
+			return err
 		},
 	)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -171,3 +507,167 @@ func (repository *gormMessageRepository) GetOutstanding(ctx context.Context, use
 
 	return message, nil
 }
+
+// Count counts the entities.Message between 2 phone numbers which match the filters of Index
+func (repository *gormMessageRepository) Count(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := replicaFromContext(ctx, repository.db, repository.replica).
+		Model(&entities.Message{}).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("contact = ?", contact)
+	if len(params.Query) > 0 {
+		query.Where(ilike(repository.db, "content"), "%"+params.Query+"%").Where("is_encrypted = ?", false)
+	}
+
+	query = repository.indexFilters(query, params, false)
+	if params.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		msg := fmt.Sprintf("cannot count messges with owner [%s] and contact [%s] and params [%+#v]", owner, contact, params)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !params.IncludeArchived {
+		return count, nil
+	}
+
+	archived, err := repository.countArchived(ctx, userID, owner, contact, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot count archived messges with owner [%s] and contact [%s] and params [%+#v]", owner, contact, params)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count + archived, nil
+}
+
+// indexFilters applies the status, order timestamp and metadata filters of the IndexParams to a query of Index.
+// The status and the metadata of an archived message are in the JSON of the data column.
+func (repository *gormMessageRepository) indexFilters(query *gorm.DB, params IndexParams, archived bool) *gorm.DB {
+	if params.Status != "" {
+		if archived {
+			query = query.Where(jsonText(repository.db, "data", "status")+" = ?", params.Status)
+		} else {
+			query = query.Where("status = ?", params.Status)
+		}
+	}
+
+	if params.After != nil {
+		query = query.Where("order_timestamp >= ?", *params.After)
+	}
+
+	if params.Before != nil {
+		query = query.Where("order_timestamp < ?", *params.Before)
+	}
+
+	if len(params.Metadata) > 0 {
+		column, key := "metadata", ""
+		if archived {
+			column, key = "data", "metadata"
+		}
+		condition, values := jsonContains(repository.db, column, key, params.Metadata)
+		query = query.Where(condition, values...)
+	}
+
+	return query
+}
+
+// CountSendAttemptsSince counts the entities.Message which an owner has attempted to send since a timestamp
+func (repository *gormMessageRepository) CountSendAttemptsSince(ctx context.Context, userID entities.UserID, owner string, since time.Time) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var count int64
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.Message{}).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("type = ?", entities.MessageTypeMobileTerminated).
+		Where(repository.db.Where("last_attempted_at >= ?", since).Or(repository.db.Where("status = ?", entities.MessageStatusSending).Where("updated_at >= ?", since))).
+		Count(&count).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count send attempts for owner [%s] and userID [%s] since [%s]", owner, userID, since)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count, nil
+}
+
+// CountByStatus counts the entities.Message of all users with each status
+func (repository *gormMessageRepository) CountByStatus(ctx context.Context, statuses []entities.MessageStatus) (map[entities.MessageStatus]int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var rows []struct {
+		Status entities.MessageStatus
+		Count  int64
+	}
+	err := replicaFromContext(ctx, repository.db, repository.replica).
+		Model(&entities.Message{}).
+		Select("status, count(*) AS count").
+		Where("status IN ?", statuses).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count messages with statuses %v", statuses)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	counts := make(map[entities.MessageStatus]int64, len(statuses))
+	for _, status := range statuses {
+		counts[status] = 0
+	}
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
+
+// IndexPending fetches the mobile terminated entities.Message of an owner which are still to be sent ordered by the OrderTimestamp
+func (repository *gormMessageRepository) IndexPending(ctx context.Context, userID entities.UserID, owner string) (*[]entities.Message, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	messages := new([]entities.Message)
+	err := repository.pending(ctx, userID, owner).
+		Order("order_timestamp ASC, id ASC").
+		Find(messages).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch pending messages for owner [%s] and userID [%s]", owner, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return messages, nil
+}
+
+// CountPending counts the entities.Message of an owner which are still to be sent
+func (repository *gormMessageRepository) CountPending(ctx context.Context, userID entities.UserID, owner string) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var count int64
+	err := repository.pending(ctx, userID, owner).
+		Model(&entities.Message{}).
+		Count(&count).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count pending messages for owner [%s] and userID [%s]", owner, userID)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count, nil
+}
+
+// pending filters the mobile terminated entities.Message of an owner which are still to be sent
+func (repository *gormMessageRepository) pending(ctx context.Context, userID entities.UserID, owner string) *gorm.DB {
+	return dbFromContext(ctx, repository.db).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("type = ?", entities.MessageTypeMobileTerminated).
+		Where(repository.db.Where("status = ?", entities.MessageStatusPending).Or(repository.db.Where("status = ?", entities.MessageStatusExpired).Where("send_attempt_count < max_send_attempts")))
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// statementRecorder records the SQL statements which are built by GORM
+type statementRecorder struct {
+	mutex      sync.Mutex
+	statements []string
+}
+
+func (recorder *statementRecorder) record(db *gorm.DB) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	recorder.statements = append(recorder.statements, db.Statement.SQL.String())
+}
+
+func (recorder *statementRecorder) last() string {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	if len(recorder.statements) == 0 {
+		return ""
+	}
+	return recorder.statements[len(recorder.statements)-1]
+}
+
+// newDryRunMessageRepository creates a MessageRepository which builds the SQL statements without a database connection
+func newDryRunMessageRepository(t *testing.T) (context.Context, MessageRepository, *statementRecorder) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost user=httpsms dbname=httpsms sslmode=disable"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	assert.Nil(t, err)
+
+	recorder := new(statementRecorder)
+	assert.Nil(t, db.Callback().Query().After("gorm:query").Register("test:record", recorder.record))
+	assert.Nil(t, db.Callback().Update().After("gorm:update").Register("test:record", recorder.record))
+	assert.Nil(t, db.Callback().Delete().After("gorm:delete").Register("test:record", recorder.record))
+
+	// the dry run database is used as the transaction so that no connection is opened for a transaction
+	ctx := context.WithValue(context.Background(), contextKeyTransaction, db)
+	return ctx, NewGormMessageRepository(logger, tracer, db, nil), recorder
+}
+
+func TestGormMessageRepository_SoftDelete(t *testing.T) {
+	userID := entities.UserID("WB7DRDWrJZRGbYrv2CKGkqbzvqdC")
+	messageID := uuid.New()
+	owner := "+18005550199"
+	contact := "+18005550100"
+
+	paths := []struct {
+		name           string
+		includeDeleted bool
+		call           func(ctx context.Context, repository MessageRepository)
+	}{
+		{
+			name: "Load",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.Load(ctx, userID, messageID)
+			},
+		},
+		{
+			name: "Index",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.Index(ctx, userID, owner, contact, IndexParams{Limit: 10, Query: "hello"})
+			},
+		},
+		{
+			name:           "Index with IncludeDeleted",
+			includeDeleted: true,
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.Index(ctx, userID, owner, contact, IndexParams{Limit: 10, IncludeDeleted: true})
+			},
+		},
+		{
+			name: "IndexPending",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.IndexPending(ctx, userID, owner)
+			},
+		},
+		{
+			name: "CountSendAttemptsSince",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.CountSendAttemptsSince(ctx, userID, owner, time.Now().UTC())
+			},
+		},
+		{
+			name: "GetOutstanding",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.GetOutstanding(ctx, userID, messageID)
+			},
+		},
+		{
+			name: "Failover",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_, _ = repository.Failover(ctx, userID, messageID, owner, "+18005550198", entities.SIM1)
+			},
+		},
+		{
+			name: "Update",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_ = repository.Update(ctx, &entities.Message{ID: messageID, UserID: userID})
+			},
+		},
+		{
+			name: "Delete",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_ = repository.Delete(ctx, userID, messageID)
+			},
+		},
+		{
+			name: "DeleteByOwnerAndContact",
+			call: func(ctx context.Context, repository MessageRepository) {
+				_ = repository.DeleteByOwnerAndContact(ctx, userID, owner, contact)
+			},
+		},
+	}
+
+	for _, path := range paths {
+		path := path
+		t.Run(path.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Arrange
+			ctx, repository, recorder := newDryRunMessageRepository(t)
+
+			// Act
+			path.call(ctx, repository)
+
+			// Assert
+			statement := recorder.last()
+			assert.NotEmpty(t, statement)
+			assert.Equal(t, !path.includeDeleted, strings.Contains(statement, `"deleted_at" IS NULL`), statement)
+		})
+	}
+
+	t.Run("Restore", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		ctx, repository, recorder := newDryRunMessageRepository(t)
+
+		// Act
+		_ = repository.Restore(ctx, userID, messageID)
+
+		// Assert
+		statement := recorder.last()
+		assert.Contains(t, statement, "deleted_at IS NOT NULL")
+		assert.NotContains(t, statement, `"deleted_at" IS NULL`)
+	})
+}
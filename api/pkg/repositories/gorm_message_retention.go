@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// DeleteExpired deletes up to limit entities.Message of a user and type which were ordered before a timestamp
+func (repository *gormMessageRepository) DeleteExpired(ctx context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	count, err := repository.deleteExpired(ctx, userID, messageType, before, hard, limit)
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete [%s] messages of user [%s] before [%s]", messageType, userID, before)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if count >= int64(limit) {
+		return count, nil
+	}
+
+	archived, err := repository.deleteExpiredArchived(ctx, userID, messageType, before, hard, limit-int(count))
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete archived [%s] messages of user [%s] before [%s]", messageType, userID, before)
+		return count, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count + archived, nil
+}
+
+// deleteExpired deletes the messages by ID and checks the status again in the DELETE so that a message which is
+// updated by the phone after it was selected is not deleted
+func (repository *gormMessageRepository) deleteExpired(ctx context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (int64, error) {
+	query := repository.retentionQuery(ctx, hard)
+
+	var ids []uuid.UUID
+	err := query().
+		Model(&entities.Message{}).
+		Where("user_id = ?", userID).
+		Where("type = ?", messageType).
+		Where("order_timestamp < ?", before).
+		Where("status IN ?", archivableMessageStatuses).
+		Limit(limit).
+		Pluck("id", &ids).
+		Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := query().
+		Where("id IN ?", ids).
+		Where("status IN ?", archivableMessageStatuses).
+		Delete(&entities.Message{})
+	return result.RowsAffected, result.Error
+}
+
+// deleteExpiredArchived deletes the archived messages by ID, archived messages are not updated by the phone
+func (repository *gormMessageRepository) deleteExpiredArchived(ctx context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (int64, error) {
+	query := repository.retentionQuery(ctx, hard)
+
+	var ids []uuid.UUID
+	err := query().
+		Model(&GormArchivedMessage{}).
+		Where("user_id = ?", userID).
+		Where(jsonText(repository.db, "data", "type")+" = ?", messageType).
+		Where("order_timestamp < ?", before).
+		Limit(limit).
+		Pluck("id", &ids).
+		Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	result := query().Where("id IN ?", ids).Delete(&GormArchivedMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// retentionQuery creates a new query for every statement, soft deleted messages are included when they are permanently deleted
+func (repository *gormMessageRepository) retentionQuery(ctx context.Context, hard bool) func() *gorm.DB {
+	return func() *gorm.DB {
+		query := dbFromContext(ctx, repository.db)
+		if hard {
+			return query.Unscoped()
+		}
+		return query
+	}
+}
+
+// ExpireContent replaces the content of the messages by ID and checks that the content has not expired in the UPDATE
+// so that the content of a message which expired after it was selected is not replaced again
+func (repository *gormMessageRepository) ExpireContent(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var ids []uuid.UUID
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		err := tx.WithContext(ctx).
+			Unscoped().
+			Model(&entities.Message{}).
+			Where("content_expires_at <= ?", before).
+			Where("content_expired_at IS NULL").
+			Order("content_expires_at ASC").
+			Limit(limit).
+			Pluck("id", &ids).
+			Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		return tx.WithContext(ctx).
+			Unscoped().
+			Model(&entities.Message{}).
+			Where("id IN ?", ids).
+			Where("content_expired_at IS NULL").
+			Updates(map[string]any{
+				"content":            entities.MessageContentExpiredMarker,
+				"is_encrypted":       false,
+				"encryption_key_id":  nil,
+				"content_expired_at": before,
+				"updated_at":         before,
+				"version":            gorm.Expr("version + 1"),
+			}).
+			Error
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot expire the content of messages before [%s]", before)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormMessageStatisticsRepository aggregates entities.Message with GORM
+type gormMessageStatisticsRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormMessageStatisticsRepository creates the GORM version of the MessageStatisticsRepository
+func NewGormMessageStatisticsRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) MessageStatisticsRepository {
+	return &gormMessageStatisticsRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormMessageStatisticsRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+// messageHourlyCountRow is a row of the hourly message counts, the hour is scanned as text because SQLite and MySQL
+// return the text of the hour
+type messageHourlyCountRow struct {
+	Hour         string
+	Type         entities.MessageType
+	Status       entities.MessageStatus
+	Count        int64
+	SentCount    int64
+	SendDuration float64
+	// DeliveryTracking is "off" for the messages without a delivery report
+	DeliveryTracking entities.MessageDeliveryTracking
+}
+
+// CountHourly counts the entities.Message of a user in each UTC hour of a time range by type, status and delivery tracking
+func (repository *gormMessageStatisticsRepository) CountHourly(ctx context.Context, userID entities.UserID, params MessageStatisticsParams) ([]*entities.MessageHourlyCount, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).
+		Unscoped().
+		Model(&entities.Message{}).
+		Select(hourBucket(repository.db, "request_received_at")+" AS hour, type, status, delivery_tracking, COUNT(*) AS count, COUNT(send_duration) AS sent_count, COALESCE(SUM(send_duration), 0) AS send_duration").
+		Where("user_id = ?", userID).
+		Where("request_received_at >= ?", params.From).
+		Where("request_received_at < ?", params.To)
+
+	if params.Owner != nil {
+		query.Where("owner = ?", *params.Owner)
+	}
+
+	var rows []messageHourlyCountRow
+	if err := query.Group("hour, type, status, delivery_tracking").Order("hour").Scan(&rows).Error; err != nil {
+		msg := fmt.Sprintf("cannot count the hourly messages of user [%s] with params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	counts := make([]*entities.MessageHourlyCount, 0, len(rows))
+	for _, row := range rows {
+		hour, err := repository.parseHour(row.Hour)
+		if err != nil {
+			msg := fmt.Sprintf("cannot parse the hour [%s] of the messages of user [%s]", row.Hour, userID)
+			return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		counts = append(counts, &entities.MessageHourlyCount{
+			Hour:         hour,
+			Type:         row.Type,
+			Status:       row.Status,
+			Count:        row.Count,
+			SentCount:    row.SentCount,
+			SendDuration: row.SendDuration,
+
+			DeliveryTracking: row.DeliveryTracking,
+		})
+	}
+
+	return counts, nil
+}
+
+// CountLatency counts the outbound entities.Message of a user which were sent, failed or expired with the percentiles
+// of their send duration. SQLite and MySQL do not have percentile_cont so the send durations are sorted by the database
+// and the percentiles are interpolated in the same way.
+func (repository *gormMessageStatisticsRepository) CountLatency(ctx context.Context, userID entities.UserID, params MessageLatencyParams) (*entities.MessageLatencyCounts, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	finished := []entities.MessageStatus{entities.MessageStatusFailed, entities.MessageStatusExpired}
+
+	counts := new(entities.MessageLatencyCounts)
+	err := repository.latencyQuery(ctx, userID, params).
+		Select(
+			"COUNT(*) AS messages, COUNT(send_duration) AS sent, "+
+				"COALESCE(SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END), 0) AS failed, "+
+				"COALESCE(SUM(CASE WHEN status IN ? OR send_duration > ? THEN 1 ELSE 0 END), 0) AS violations",
+			finished, finished, params.Threshold.Nanoseconds(),
+		).
+		Where("send_duration IS NOT NULL OR status IN ?", finished).
+		Scan(counts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the latency of the messages of user [%s] with params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if hasPercentileCont(repository.db) {
+		var percentiles struct {
+			P50 *float64
+			P90 *float64
+			P99 *float64
+		}
+		err = repository.latencyQuery(ctx, userID, params).
+			Select("percentile_cont(0.5) WITHIN GROUP (ORDER BY send_duration) AS p50, " +
+				"percentile_cont(0.9) WITHIN GROUP (ORDER BY send_duration) AS p90, " +
+				"percentile_cont(0.99) WITHIN GROUP (ORDER BY send_duration) AS p99").
+			Where("send_duration IS NOT NULL").
+			Scan(&percentiles).Error
+		counts.P50, counts.P90, counts.P99 = percentiles.P50, percentiles.P90, percentiles.P99
+	} else {
+		var durations []float64
+		err = repository.latencyQuery(ctx, userID, params).
+			Where("send_duration IS NOT NULL").
+			Order("send_duration").
+			Pluck("send_duration", &durations).Error
+		counts.P50, counts.P90, counts.P99 = percentileCont(durations, 0.5), percentileCont(durations, 0.9), percentileCont(durations, 0.99)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot calculate the send duration percentiles of the messages of user [%s] with params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return counts, nil
+}
+
+// CountContacts counts the entities.Message of a user in a time range by contact, the contacts with the same number of
+// messages are ordered by the contact so that the busiest contact is always the same
+func (repository *gormMessageStatisticsRepository) CountContacts(ctx context.Context, userID entities.UserID, params MessageStatisticsParams, limit int) ([]*entities.MessageContactCount, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).
+		Unscoped().
+		Model(&entities.Message{}).
+		Select("contact, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Where("request_received_at >= ?", params.From).
+		Where("request_received_at < ?", params.To)
+
+	if params.Owner != nil {
+		query.Where("owner = ?", *params.Owner)
+	}
+
+	var counts []*entities.MessageContactCount
+	if err := query.Group("contact").Order("count DESC, contact").Limit(limit).Scan(&counts).Error; err != nil {
+		msg := fmt.Sprintf("cannot count the messages of user [%s] by contact with params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return counts, nil
+}
+
+// latencyQuery selects the outbound messages of a user which were received by the API in the time range
+func (repository *gormMessageStatisticsRepository) latencyQuery(ctx context.Context, userID entities.UserID, params MessageLatencyParams) *gorm.DB {
+	query := repository.db.WithContext(ctx).
+		Unscoped().
+		Model(&entities.Message{}).
+		Where("user_id = ?", userID).
+		Where("type = ?", entities.MessageTypeMobileTerminated).
+		Where("request_received_at >= ?", params.From).
+		Where("request_received_at < ?", params.To)
+
+	if params.Owner != nil {
+		query.Where("owner = ?", *params.Owner)
+	}
+
+	return query
+}
+
+// percentileCont interpolates the percentile of the sorted values like percentile_cont in postgres, it is nil when there are no values
+func percentileCont(sorted []float64, fraction float64) *float64 {
+	if len(sorted) == 0 {
+		return nil
+	}
+
+	position := fraction * float64(len(sorted)-1)
+	lower, upper := int(math.Floor(position)), int(math.Ceil(position))
+	value := sorted[lower] + (sorted[upper]-sorted[lower])*(position-float64(lower))
+	return &value
+}
+
+// parseHour parses the text of an hour bucket which is RFC3339 when the database returns a timestamp
+func (repository *gormMessageStatisticsRepository) parseHour(value string) (time.Time, error) {
+	if hour, err := time.Parse("2006-01-02 15:04:05", value); err == nil {
+		return hour, nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormMessageStatisticsRepository_CountHourly verifies that the messages are counted in the UTC hour when the API received them
+func TestGormMessageStatisticsRepository_CountHourly(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageStatisticsRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			hour := time.Date(2022, time.June, 5, 14, 0, 0, 0, time.UTC)
+			sendDuration := int64(10 * time.Second)
+
+			first := newTestMessage(userID, "first", hour.Add(10*time.Minute))
+			first.Status = entities.MessageStatusSent
+			first.SendDuration = &sendDuration
+			second := newTestMessage(userID, "second", hour.Add(50*time.Minute))
+			second.Status = entities.MessageStatusSent
+			second.SendDuration = &sendDuration
+			next := newTestMessage(userID, "next", hour.Add(70*time.Minute))
+			other := newTestMessage(userID, "other", hour.Add(20*time.Minute))
+			other.Owner = "+18005550100"
+			late := newTestMessage(userID, "late", hour.Add(3*time.Hour))
+
+			for _, message := range []*entities.Message{first, second, next, other, late} {
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			owner := "+18005550199"
+			counts, err := repository.CountHourly(ctx, userID, MessageStatisticsParams{
+				From:  hour,
+				To:    hour.Add(2 * time.Hour),
+				Owner: &owner,
+			})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(counts))
+
+			assert.True(t, hour.Equal(counts[0].Hour))
+			assert.Equal(t, entities.MessageStatus(entities.MessageStatusSent), counts[0].Status)
+			assert.Equal(t, int64(2), counts[0].Count)
+			assert.Equal(t, int64(2), counts[0].SentCount)
+			assert.Equal(t, float64(2*sendDuration), counts[0].SendDuration)
+
+			assert.True(t, hour.Add(time.Hour).Equal(counts[1].Hour))
+			assert.Equal(t, int64(1), counts[1].Count)
+			assert.Equal(t, int64(0), counts[1].SentCount)
+		})
+	}
+}
+
+// TestGormMessageStatisticsRepository_CountHourlyDeliveryTracking verifies that the messages without a delivery report are counted separately
+func TestGormMessageStatisticsRepository_CountHourlyDeliveryTracking(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageStatisticsRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			hour := time.Date(2022, time.June, 5, 14, 0, 0, 0, time.UTC)
+
+			tracked := newTestMessage(userID, "tracked", hour.Add(10*time.Minute))
+			tracked.Status = entities.MessageStatusSent
+			tracked.DeliveryTracking = entities.MessageDeliveryTrackingOn
+			untracked := newTestMessage(userID, "untracked", hour.Add(20*time.Minute))
+			untracked.Status = entities.MessageStatusSent
+			untracked.DeliveryTracking = entities.MessageDeliveryTrackingOff
+			unset := newTestMessage(userID, "unset", hour.Add(30*time.Minute))
+			// the database default is used for a message which was created before the delivery tracking
+			unset.Status = entities.MessageStatusSent
+
+			for _, message := range []*entities.Message{tracked, untracked, unset} {
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			counts, err := repository.CountHourly(ctx, userID, MessageStatisticsParams{From: hour, To: hour.Add(time.Hour)})
+
+			// Assert
+			assert.Nil(t, err)
+			tracking := map[entities.MessageDeliveryTracking]int64{}
+			for _, count := range counts {
+				tracking[count.DeliveryTracking] += count.Count
+			}
+			assert.Equal(t, map[entities.MessageDeliveryTracking]int64{
+				entities.MessageDeliveryTrackingOn:  2,
+				entities.MessageDeliveryTrackingOff: 1,
+			}, tracking)
+		})
+	}
+}
+
+// TestGormMessageStatisticsRepository_CountLatency verifies the percentiles of the send duration and the messages which violate the SLO
+func TestGormMessageStatisticsRepository_CountLatency(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageStatisticsRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			from := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+
+			// the send durations are 1 to 10 seconds
+			for i := 1; i <= 10; i++ {
+				message := newTestMessage(userID, fmt.Sprintf("message %d", i), from.Add(time.Duration(i)*time.Minute))
+				message.Status = entities.MessageStatusSent
+				sendDuration := int64(time.Duration(i) * time.Second)
+				message.SendDuration = &sendDuration
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			failed := newTestMessage(userID, "failed", from.Add(time.Hour))
+			failed.Status = entities.MessageStatusFailed
+			expired := newTestMessage(userID, "expired", from.Add(time.Hour))
+			expired.Status = entities.MessageStatusExpired
+			pending := newTestMessage(userID, "pending", from.Add(time.Hour))
+			received := newTestMessage(userID, "received", from.Add(time.Hour))
+			received.Type = entities.MessageTypeMobileOriginated
+			received.Status = entities.MessageStatusReceived
+
+			for _, message := range []*entities.Message{failed, expired, pending, received} {
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			counts, err := repository.CountLatency(ctx, userID, MessageLatencyParams{
+				From:      from,
+				To:        from.Add(24 * time.Hour),
+				Threshold: 8 * time.Second,
+			})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, int64(12), counts.Messages)
+			assert.Equal(t, int64(10), counts.Sent)
+			assert.Equal(t, int64(2), counts.Failed)
+			assert.Equal(t, int64(4), counts.Violations)
+			assert.InDelta(t, float64(5500*time.Millisecond), *counts.P50, 1)
+			assert.InDelta(t, float64(9100*time.Millisecond), *counts.P90, 1)
+			assert.InDelta(t, float64(9910*time.Millisecond), *counts.P99, 1)
+		})
+	}
+}
+
+// TestGormMessageStatisticsRepository_CountContacts verifies that the contacts with the most messages in the time range are counted first
+func TestGormMessageStatisticsRepository_CountContacts(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageStatisticsRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			from := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+
+			var messages []*entities.Message
+			for i, contact := range []string{"+18005550100", "+18005550101", "+18005550101", "MTN", "MTN"} {
+				message := newTestMessage(userID, fmt.Sprintf("message %d", i), from.Add(time.Duration(i)*time.Hour))
+				message.Contact = contact
+				messages = append(messages, message)
+			}
+			late := newTestMessage(userID, "late", from.Add(24*time.Hour))
+			late.Contact = "+18005550100"
+			messages = append(messages, late)
+
+			for _, message := range messages {
+				assert.Nil(t, backend.db.Create(message).Error)
+			}
+
+			// Act
+			counts, err := repository.CountContacts(ctx, userID, MessageStatisticsParams{From: from, To: from.Add(24 * time.Hour)}, 2)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, []*entities.MessageContactCount{
+				{Contact: "+18005550101", Count: 2},
+				{Contact: "MTN", Count: 2},
+			}, counts)
+		})
+	}
+}
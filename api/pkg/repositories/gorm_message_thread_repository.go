@@ -17,9 +17,10 @@ import (
 
 // gormMessageThreadRepository is responsible for persisting entities.MessageThread
 type gormMessageThreadRepository struct {
-	logger telemetry.Logger
-	tracer telemetry.Tracer
-	db     *gorm.DB
+	logger  telemetry.Logger
+	tracer  telemetry.Tracer
+	db      *gorm.DB
+	replica *gorm.DB
 }
 
 // NewGormMessageThreadRepository creates the GORM version of the MessageRepository
@@ -27,11 +28,13 @@ func NewGormMessageThreadRepository(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	db *gorm.DB,
+	replica *gorm.DB,
 ) MessageThreadRepository {
 	return &gormMessageThreadRepository{
-		logger: logger.WithService(fmt.Sprintf("%T", &gormMessageThreadRepository{})),
-		tracer: tracer,
-		db:     db,
+		logger:  logger.WithService(fmt.Sprintf("%T", &gormMessageThreadRepository{})),
+		tracer:  tracer,
+		db:      db,
+		replica: replica,
 	}
 }
 
@@ -40,10 +43,15 @@ func (repository *gormMessageThreadRepository) Delete(ctx context.Context, userI
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", messageThreadID).Delete(&entities.MessageThread{}).Error
-	if err != nil {
+	result := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("id = ?", messageThreadID).Delete(&entities.MessageThread{})
+	if result.Error != nil {
 		msg := fmt.Sprintf("cannot delete message thread with ID [%s] for user with ID [%s]", messageThreadID, userID)
-		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 0 {
+		msg := fmt.Sprintf("message thread with ID [%s] does not exist for user with ID [%s]", messageThreadID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeNotFound, msg))
 	}
 
 	return nil
@@ -54,7 +62,7 @@ func (repository *gormMessageThreadRepository) UpdateAfterDeletedMessage(ctx con
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	err := repository.db.WithContext(ctx).Model(&entities.MessageThread{}).
+	err := dbFromContext(ctx, repository.db).Model(&entities.MessageThread{}).
 		Where("user_id = ?", userID).
 		Where("last_message_id = ?", messageID).
 		Updates(map[string]any{
@@ -70,12 +78,29 @@ func (repository *gormMessageThreadRepository) UpdateAfterDeletedMessage(ctx con
 	return nil
 }
 
+// ExpireLastMessageContent replaces the last message content of the threads whose last message content has expired
+func (repository *gormMessageThreadRepository) ExpireLastMessageContent(ctx context.Context, messageIDs []uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).Model(&entities.MessageThread{}).
+		Where("last_message_id IN ?", messageIDs).
+		Update("last_message_content", entities.MessageContentExpiredMarker).
+		Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot expire the last message content of the threads of [%d] messages", len(messageIDs))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
 // Store a new entities.MessageThread
 func (repository *gormMessageThreadRepository) Store(ctx context.Context, thread *entities.MessageThread) error {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(thread).Error; err != nil {
+	if err := dbFromContext(ctx, repository.db).Clauses(clause.OnConflict{DoNothing: true}).Create(thread).Error; err != nil {
 		msg := fmt.Sprintf("cannot save message thread with ID [%s]", thread.ID)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -83,14 +108,20 @@ func (repository *gormMessageThreadRepository) Store(ctx context.Context, thread
 	return nil
 }
 
-// Update a new entities.MessageThread
+// Update an entities.MessageThread, the thread is not found when it belongs to another user
 func (repository *gormMessageThreadRepository) Update(ctx context.Context, thread *entities.MessageThread) error {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Save(thread).Error; err != nil {
+	result := dbFromContext(ctx, repository.db).Model(thread).Where("user_id = ?", thread.UserID).Select("*").Updates(thread)
+	if result.Error != nil {
 		msg := fmt.Sprintf("cannot update message thread thread with ID [%s]", thread.ID)
-		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 0 {
+		msg := fmt.Sprintf("message thread with ID [%s] does not exist for user with ID [%s]", thread.ID, thread.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeNotFound, msg))
 	}
 
 	return nil
@@ -103,8 +134,7 @@ func (repository *gormMessageThreadRepository) LoadByOwnerContact(ctx context.Co
 
 	thread := new(entities.MessageThread)
 
-	err := repository.db.
-		WithContext(ctx).
+	err := dbFromContext(ctx, repository.db).
 		Where("user_id = ?", userID).
 		Where("owner = ?", owner).
 		Where("contact = ?", contact).
@@ -130,8 +160,7 @@ func (repository *gormMessageThreadRepository) Load(ctx context.Context, userID
 
 	thread := new(entities.MessageThread)
 
-	err := repository.db.
-		WithContext(ctx).
+	err := dbFromContext(ctx, repository.db).
 		Where("user_id = ?", userID).
 		Where("id = ?", ID).
 		First(thread).
@@ -150,12 +179,11 @@ func (repository *gormMessageThreadRepository) Load(ctx context.Context, userID
 }
 
 // Index message threads for an owner
-func (repository *gormMessageThreadRepository) Index(ctx context.Context, userID entities.UserID, owner string, isArchived bool, params IndexParams) (*[]entities.MessageThread, error) {
+func (repository *gormMessageThreadRepository) Index(ctx context.Context, userID entities.UserID, owner string, isArchived bool, params MessageThreadIndexParams) (*[]entities.MessageThread, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	query := repository.db.
-		WithContext(ctx).
+	query := replicaFromContext(ctx, repository.db, repository.replica).
 		Where("user_id = ?", userID).
 		Where("owner = ?", owner)
 
@@ -168,14 +196,26 @@ func (repository *gormMessageThreadRepository) Index(ctx context.Context, userID
 	if len(params.Query) > 0 {
 		queryPattern := "%" + params.Query + "%"
 		query.Where(
-			repository.db.Where("last_message_content ILIKE ?", queryPattern).
-				Or("owner ILIKE ?", queryPattern).
-				Or("contact ILIKE ?", queryPattern),
+			repository.db.Where(ilike(repository.db, "last_message_content"), queryPattern).
+				Or(ilike(repository.db, "owner"), queryPattern).
+				Or(ilike(repository.db, "contact"), queryPattern),
+		)
+	}
+
+	if len(params.Label) > 0 {
+		query.Where(
+			"contact IN (?)",
+			repository.db.Model(&entities.Contact{}).
+				Select("contacts.phone_number").
+				Joins("JOIN contact_labels ON contact_labels.contact_id = contacts.id").
+				Where("contacts.user_id = ?", userID).
+				Where("contacts.deleted_at IS NULL").
+				Where("contact_labels.label = ?", params.Label),
 		)
 	}
 
 	threads := new([]entities.MessageThread)
-	if err := query.Order("order_timestamp DESC").Limit(params.Limit).Offset(params.Skip).Find(&threads).Error; err != nil {
+	if err := query.Order("order_timestamp DESC, id DESC").Limit(params.Limit).Offset(params.Skip).Find(&threads).Error; err != nil {
 		msg := fmt.Sprintf("cannot fetch message threads with owner [%s] and params [%+#v]", owner, params)
 		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
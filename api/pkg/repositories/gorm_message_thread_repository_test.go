@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMessageThread(userID entities.UserID, timestamp time.Time) *entities.MessageThread {
+	return &entities.MessageThread{
+		ID:             uuid.New(),
+		Owner:          "+18005550199",
+		Contact:        "+18005550100",
+		UserID:         userID,
+		Color:          "indigo",
+		Status:         entities.MessageStatusPending,
+		CreatedAt:      timestamp,
+		UpdatedAt:      timestamp,
+		OrderTimestamp: timestamp,
+	}
+}
+
+// TestGormMessageThreadRepository_Ownership verifies that a user cannot load, update or delete the message thread of another user
+func TestGormMessageThreadRepository_Ownership(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageThreadRepository(backend.logger, backend.tracer, backend.db, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userA := entities.UserID(uuid.NewString())
+			userB := entities.UserID(uuid.NewString())
+			threadA := newTestMessageThread(userA, time.Now().UTC())
+			threadB := newTestMessageThread(userB, time.Now().UTC())
+			assert.Nil(t, repository.Store(ctx, threadA))
+			assert.Nil(t, repository.Store(ctx, threadB))
+
+			// Act
+			_, loadErr := repository.Load(ctx, userB, threadA.ID)
+			contactThread, contactErr := repository.LoadByOwnerContact(ctx, userB, threadA.Owner, threadA.Contact)
+
+			stolen := *threadA
+			stolen.UserID = userB
+			stolen.IsArchived = true
+			updateErr := repository.Update(ctx, &stolen)
+			deleteErr := repository.Delete(ctx, userB, threadA.ID)
+
+			// Assert
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(loadErr))
+			assert.Nil(t, contactErr)
+			assert.Equal(t, threadB.ID, contactThread.ID)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(updateErr))
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(deleteErr))
+
+			stored, err := repository.Load(ctx, userA, threadA.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, userA, stored.UserID)
+			assert.False(t, stored.IsArchived)
+
+			assert.Nil(t, repository.Update(ctx, stored.UpdateArchive(true)))
+			assert.Nil(t, repository.Delete(ctx, userA, threadA.ID))
+
+			_, err = repository.Load(ctx, userA, threadA.ID)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormNotificationDigestRepository is responsible for persisting entities.NotificationDigest
+type gormNotificationDigestRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormNotificationDigestRepository creates the GORM version of the NotificationDigestRepository
+func NewGormNotificationDigestRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) NotificationDigestRepository {
+	return &gormNotificationDigestRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormNotificationDigestRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormNotificationDigestRepository) StoreMessage(ctx context.Context, message *entities.NotificationDigestMessage) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(message).Error; err != nil {
+		msg := fmt.Sprintf("cannot store notification digest message for message [%s]", message.MessageID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormNotificationDigestRepository) Open(ctx context.Context, userID entities.UserID, owner string, windowID uuid.UUID, sendAt time.Time, staleBefore time.Time) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	timestamp := time.Now().UTC()
+	digest := &entities.NotificationDigest{UserID: userID, Owner: owner, UpdatedAt: timestamp}
+	if err := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(digest).Error; err != nil {
+		msg := fmt.Sprintf("cannot create notification digest for owner [%s] of user [%s]", owner, userID)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	result := repository.db.WithContext(ctx).
+		Model(&entities.NotificationDigest{}).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where(repository.db.Where("window_id IS NULL").Or("send_at < ?", staleBefore)).
+		Updates(map[string]any{
+			"window_id":  windowID,
+			"opened_at":  timestamp,
+			"send_at":    sendAt,
+			"updated_at": timestamp,
+		})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot open notification digest window [%s] for owner [%s] of user [%s]", windowID, owner, userID)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (repository *gormNotificationDigestRepository) Postpone(ctx context.Context, userID entities.UserID, owner string, windowID uuid.UUID, sendAt time.Time) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).
+		Model(&entities.NotificationDigest{}).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Where("window_id = ?", windowID).
+		Updates(map[string]any{
+			"send_at":    sendAt,
+			"updated_at": time.Now().UTC(),
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot postpone notification digest window [%s] for owner [%s] of user [%s]", windowID, owner, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormNotificationDigestRepository) Close(ctx context.Context, userID entities.UserID, owner string, windowID uuid.UUID, digestID uuid.UUID) ([]*entities.NotificationDigestMessage, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var messages []*entities.NotificationDigestMessage
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		result := tx.WithContext(ctx).
+			Model(&entities.NotificationDigest{}).
+			Where("user_id = ?", userID).
+			Where("owner = ?", owner).
+			Where("window_id = ?", windowID).
+			Updates(map[string]any{
+				"window_id":  nil,
+				"opened_at":  nil,
+				"send_at":    nil,
+				"updated_at": time.Now().UTC(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		err := tx.WithContext(ctx).
+			Model(&entities.NotificationDigestMessage{}).
+			Where("user_id = ?", userID).
+			Where("owner = ?", owner).
+			Where("digest_id IS NULL").
+			Update("digest_id", digestID).Error
+		if err != nil {
+			return err
+		}
+
+		messages = []*entities.NotificationDigestMessage{}
+		return tx.WithContext(ctx).Where("digest_id = ?", digestID).Order("created_at ASC").Find(&messages).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("notification digest window [%s] for owner [%s] of user [%s] is not open", windowID, owner, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot close notification digest window [%s] for owner [%s] of user [%s]", windowID, owner, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormNotificationPreferenceRepository is responsible for persisting entities.NotificationPreference
+type gormNotificationPreferenceRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormNotificationPreferenceRepository creates the GORM version of the NotificationPreferenceRepository
+func NewGormNotificationPreferenceRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) NotificationPreferenceRepository {
+	return &gormNotificationPreferenceRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormNotificationPreferenceRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormNotificationPreferenceRepository) Save(ctx context.Context, preference *entities.NotificationPreference) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(preference).Error; err != nil {
+		msg := fmt.Sprintf("cannot save notification preference for user [%s]", preference.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormNotificationPreferenceRepository) Load(ctx context.Context, userID entities.UserID) (*entities.NotificationPreference, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	preference := new(entities.NotificationPreference)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).First(preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("notification preference for user [%s] does not exist", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load notification preference for user [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return preference, nil
+}
+
+func (repository *gormNotificationPreferenceRepository) Delete(ctx context.Context, userID entities.UserID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entities.NotificationPreference{}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete notification preference of user [%s]", userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormNumberLookupRepository is responsible for persisting entities.NumberLookup
+type gormNumberLookupRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormNumberLookupRepository creates the GORM version of the NumberLookupRepository
+func NewGormNumberLookupRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) NumberLookupRepository {
+	return &gormNumberLookupRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormNumberLookupRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormNumberLookupRepository) Save(ctx context.Context, lookup *entities.NumberLookup) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(lookup).Error; err != nil {
+		msg := fmt.Sprintf("cannot save the lookup of phone number [%s]", telemetry.RedactPhoneNumber(lookup.PhoneNumber))
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormNumberLookupRepository) Load(ctx context.Context, phoneNumber string) (*entities.NumberLookup, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	lookup := new(entities.NumberLookup)
+	err := repository.db.WithContext(ctx).Where("phone_number = ?", phoneNumber).First(lookup).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("lookup of phone number [%s] does not exist", telemetry.RedactPhoneNumber(phoneNumber))
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the lookup of phone number [%s]", telemetry.RedactPhoneNumber(phoneNumber))
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return lookup, nil
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormPhoneDailySendUsageRepository is responsible for persisting entities.PhoneDailySendUsage
+type gormPhoneDailySendUsageRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormPhoneDailySendUsageRepository creates the GORM version of the PhoneDailySendUsageRepository
+func NewGormPhoneDailySendUsageRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) PhoneDailySendUsageRepository {
+	return &gormPhoneDailySendUsageRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormPhoneDailySendUsageRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+// Reserve increments the sent messages of a phone in a day by 1 if the total does not exceed the limit
+func (repository *gormPhoneDailySendUsageRepository) Reserve(ctx context.Context, userID entities.UserID, phoneID uuid.UUID, day time.Time, limit uint) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	reserved := false
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		result := repository.increment(tx, phoneID, day, limit)
+		if result.Error != nil || result.RowsAffected > 0 {
+			reserved = result.RowsAffected > 0
+			return result.Error
+		}
+
+		usage := &entities.PhoneDailySendUsage{
+			ID:        uuid.New(),
+			UserID:    userID,
+			PhoneID:   phoneID,
+			Day:       day,
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "phone_id"}, {Name: "day"}},
+			DoNothing: true,
+		}).Create(usage).Error
+		if err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot create daily send usage for phone [%s] and day [%s]", phoneID, day))
+		}
+
+		result = repository.increment(tx, phoneID, day, limit)
+		reserved = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot reserve a message for phone [%s] and day [%s]", phoneID, day)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return reserved, nil
+}
+
+// Release decrements the sent messages of a phone in a day by 1
+func (repository *gormPhoneDailySendUsageRepository) Release(ctx context.Context, phoneID uuid.UUID, day time.Time) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.PhoneDailySendUsage{}).
+		Where("phone_id = ?", phoneID).
+		Where("day = ?", day).
+		Where("sent_messages > 0").
+		Updates(map[string]any{
+			"sent_messages": gorm.Expr("sent_messages - 1"),
+			"updated_at":    time.Now().UTC(),
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot release a message for phone [%s] and day [%s]", phoneID, day)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// MarkExhausted sets the entities.PhoneDailySendUsage of a day as exhausted
+func (repository *gormPhoneDailySendUsageRepository) MarkExhausted(ctx context.Context, phoneID uuid.UUID, day time.Time, timestamp time.Time) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := dbFromContext(ctx, repository.db).
+		Model(&entities.PhoneDailySendUsage{}).
+		Where("phone_id = ?", phoneID).
+		Where("day = ?", day).
+		Where("exhausted_at IS NULL").
+		Updates(map[string]any{
+			"exhausted_at": timestamp,
+			"updated_at":   time.Now().UTC(),
+		})
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot mark the daily send usage of phone [%s] and day [%s] as exhausted", phoneID, day)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// Load the entities.PhoneDailySendUsage of a phone in a day
+func (repository *gormPhoneDailySendUsageRepository) Load(ctx context.Context, phoneID uuid.UUID, day time.Time) (*entities.PhoneDailySendUsage, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	usage := new(entities.PhoneDailySendUsage)
+	err := dbFromContext(ctx, repository.db).
+		Where("phone_id = ?", phoneID).
+		Where("day = ?", day).
+		First(usage).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("daily send usage of phone [%s] and day [%s] does not exist", phoneID, day)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load daily send usage of phone [%s] and day [%s]", phoneID, day)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return usage, nil
+}
+
+func (repository *gormPhoneDailySendUsageRepository) increment(tx *gorm.DB, phoneID uuid.UUID, day time.Time, limit uint) *gorm.DB {
+	return tx.Model(&entities.PhoneDailySendUsage{}).
+		Where("phone_id = ?", phoneID).
+		Where("day = ?", day).
+		Where("sent_messages < ?", limit).
+		Updates(map[string]any{
+			"sent_messages": gorm.Expr("sent_messages + 1"),
+			"updated_at":    time.Now().UTC(),
+		})
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormPhoneDailySendUsageRepository_Reserve verifies that the messages of a phone in a day cannot exceed the limit
+func TestGormPhoneDailySendUsageRepository_Reserve(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormPhoneDailySendUsageRepository(backend.logger, backend.tracer, backend.db)
+		day := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+
+		t.Run(backend.name+": messages are reserved until the limit", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			phoneID := uuid.New()
+
+			// Act
+			reserved := make([]bool, 0, 3)
+			for i := 0; i < 3; i++ {
+				ok, err := repository.Reserve(ctx, "user-a", phoneID, day, 2)
+				assert.Nil(t, err)
+				reserved = append(reserved, ok)
+			}
+
+			// Assert
+			assert.Equal(t, []bool{true, true, false}, reserved)
+
+			usage, err := repository.Load(ctx, phoneID, day)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(2), usage.SentMessages)
+		})
+
+		t.Run(backend.name+": released message can be reserved again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			phoneID := uuid.New()
+			ok, err := repository.Reserve(ctx, "user-a", phoneID, day, 1)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+
+			// Act
+			assert.Nil(t, repository.Release(ctx, phoneID, day))
+			ok, err = repository.Reserve(ctx, "user-a", phoneID, day, 1)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		})
+
+		t.Run(backend.name+": usage of the next day starts from zero", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			phoneID := uuid.New()
+			ok, err := repository.Reserve(ctx, "user-a", phoneID, day, 1)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+
+			// Act
+			ok, err = repository.Reserve(ctx, "user-a", phoneID, day.AddDate(0, 0, 1), 1)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+// TestGormPhoneDailySendUsageRepository_MarkExhausted verifies that the usage of a day is only marked as exhausted once
+func TestGormPhoneDailySendUsageRepository_MarkExhausted(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormPhoneDailySendUsageRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			phoneID := uuid.New()
+			day := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+			_, err := repository.Reserve(ctx, "user-a", phoneID, day, 1)
+			assert.Nil(t, err)
+
+			// Act
+			first, err1 := repository.MarkExhausted(ctx, phoneID, day, time.Now().UTC())
+			second, err2 := repository.MarkExhausted(ctx, phoneID, day, time.Now().UTC())
+
+			// Assert
+			assert.Nil(t, err1)
+			assert.Nil(t, err2)
+			assert.True(t, first)
+			assert.False(t, second)
+		})
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/google/uuid"
 	"github.com/palantir/stacktrace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // gormPhoneRepository is responsible for persisting entities.Phone
@@ -38,7 +40,8 @@ func (repository *gormPhoneRepository) LoadByID(ctx context.Context, userID enti
 	defer span.End()
 
 	phone := new(entities.Phone)
-	err := repository.db.WithContext(ctx).
+	err := dbFromContext(ctx, repository.db).
+		Preload("SIMs", repository.orderSIMs).
 		Where("user_id = ?", userID).
 		Where("id = ?", phoneID).
 		First(&phone).Error
@@ -55,30 +58,212 @@ func (repository *gormPhoneRepository) LoadByID(ctx context.Context, userID enti
 	return phone, nil
 }
 
-// Delete an entities.Phone
-func (repository *gormPhoneRepository) Delete(ctx context.Context, userID entities.UserID, phoneID uuid.UUID) error {
+// LoadDefault loads the default phone of a user
+func (repository *gormPhoneRepository) LoadDefault(ctx context.Context, userID entities.UserID) (*entities.Phone, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	err := repository.db.WithContext(ctx).
+	phone := new(entities.Phone)
+	err := dbFromContext(ctx, repository.db).
+		Preload("SIMs", repository.orderSIMs).
 		Where("user_id = ?", userID).
-		Where("id = ?", phoneID).
-		Delete(&entities.Phone{}).Error
+		Where("is_default = ?", true).
+		First(phone).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("default phone for user with ID [%s] does not exist", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load default phone for user with ID [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return phone, nil
+}
+
+// SetDefault makes a phone the default phone of a user
+func (repository *gormPhoneRepository) SetDefault(ctx context.Context, userID entities.UserID, phoneID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		// lock the phones of the user so concurrent requests are serialized
+		var phoneIDs []uuid.UUID
+		if err := tx.Model(&entities.Phone{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			Pluck("id", &phoneIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&entities.Phone{}).
+			Where("user_id = ?", userID).
+			Where("is_default = ?", true).
+			Update("is_default", false).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&entities.Phone{}).
+			Where("user_id = ?", userID).
+			Where("id = ?", phoneID).
+			Update("is_default", true)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("phone with ID [%s] does not exist for user [%s]", phoneID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot set phone with ID [%s] as default for user [%s]", phoneID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// UpdateBattery updates the battery state of a phone
+func (repository *gormPhoneRepository) UpdateBattery(ctx context.Context, phone *entities.Phone) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.Phone{}).
+		Where("user_id = ?", phone.UserID).
+		Where("id = ?", phone.ID).
+		UpdateColumns(map[string]any{
+			"battery_level":      phone.BatteryLevel,
+			"battery_charging":   phone.BatteryCharging,
+			"battery_low":        phone.BatteryLow,
+			"battery_updated_at": phone.BatteryUpdatedAt,
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot update battery of phone with ID [%s]", phone.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// UpdateClockSkew updates the clock skew of a phone
+func (repository *gormPhoneRepository) UpdateClockSkew(ctx context.Context, phone *entities.Phone) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.Phone{}).
+		Where("user_id = ?", phone.UserID).
+		Where("id = ?", phone.ID).
+		UpdateColumns(map[string]any{
+			"clock_skew_milliseconds": phone.ClockSkewMilliseconds,
+			"clock_skewed":            phone.ClockSkewed,
+			"clock_skew_updated_at":   phone.ClockSkewUpdatedAt,
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot update clock skew of phone with ID [%s]", phone.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// UpdateAppVersion updates the version of the android app on an entities.Phone
+func (repository *gormPhoneRepository) UpdateAppVersion(ctx context.Context, userID entities.UserID, phoneNumber string, version string) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.Phone{}).
+		Where("user_id = ?", userID).
+		Where("phone_number = ?", phoneNumber).
+		UpdateColumn("app_version", version).Error
 	if err != nil {
-		msg := fmt.Sprintf("cannot delete phone with ID [%s] and userID [%s]", phoneID, userID)
+		msg := fmt.Sprintf("cannot update app version of phone [%s] for user [%s] to [%s]", phoneNumber, userID, version)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
 	return nil
 }
 
+// Delete an entities.Phone
+func (repository *gormPhoneRepository) Delete(ctx context.Context, phone *entities.Phone, cancelPending bool) (int64, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var cancelled int64
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", phone.UserID).Where("id = ?", phone.ID).Delete(&entities.Phone{})
+		if result.Error != nil {
+			return stacktrace.Propagate(result.Error, fmt.Sprintf("cannot delete phone with ID [%s]", phone.ID))
+		}
+
+		if result.RowsAffected == 0 {
+			return stacktrace.NewErrorWithCode(ErrCodeNotFound, fmt.Sprintf("phone with ID [%s] does not exist", phone.ID))
+		}
+
+		if err := tx.Where("user_id = ?", phone.UserID).Where("owner = ?", phone.PhoneNumber).Delete(&entities.Heartbeat{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete heartbeats of phone with ID [%s]", phone.ID))
+		}
+
+		if err := tx.Where("user_id = ?", phone.UserID).Where("owner = ?", phone.PhoneNumber).Delete(&entities.HeartbeatMonitor{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete heartbeat monitor of phone with ID [%s]", phone.ID))
+		}
+
+		if err := tx.Where("user_id = ?", phone.UserID).Where("phone_id = ?", phone.ID).Delete(&entities.PhoneSIM{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete SIMs of phone with ID [%s]", phone.ID))
+		}
+
+		if err := tx.Where("user_id = ?", phone.UserID).Where("phone_id = ?", phone.ID).Delete(&entities.PhoneNotification{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete notifications of phone with ID [%s]", phone.ID))
+		}
+
+		if !cancelPending {
+			return nil
+		}
+
+		timestamp := time.Now().UTC()
+		result = tx.Model(&entities.Message{}).
+			Where("user_id = ?", phone.UserID).
+			Where("owner = ?", phone.PhoneNumber).
+			Where("type = ?", entities.MessageTypeMobileTerminated).
+			Where("status IN ?", []string{entities.MessageStatusPending, entities.MessageStatusScheduled, entities.MessageStatusExpired}).
+			Updates(map[string]any{
+				"status":         entities.MessageStatusFailed,
+				"failure_reason": entities.MessageFailureReasonPhoneDeleted,
+				"failed_at":      timestamp,
+				"updated_at":     timestamp,
+				"version":        gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return stacktrace.Propagate(result.Error, fmt.Sprintf("cannot cancel outstanding messages of phone with ID [%s]", phone.ID))
+		}
+
+		cancelled = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete phone with ID [%s] and userID [%s]", phone.ID, phone.UserID)
+		return 0, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return cancelled, nil
+}
+
 // Save a new entities.Phone
 func (repository *gormPhoneRepository) Save(ctx context.Context, phone *entities.Phone) error {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	err := repository.db.
-		WithContext(ctx).
+	err := dbFromContext(ctx, repository.db).
+		Omit(clause.Associations).
 		Save(phone).
 		Error
 	if err != nil {
@@ -95,7 +280,7 @@ func (repository *gormPhoneRepository) Load(ctx context.Context, userID entities
 	defer span.End()
 
 	phone := new(entities.Phone)
-	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("phone_number = ?", phoneNumber).First(phone).Error
+	err := dbFromContext(ctx, repository.db).Preload("SIMs", repository.orderSIMs).Where("user_id = ?", userID).Where("phone_number = ?", phoneNumber).First(phone).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		msg := fmt.Sprintf("phone with userID [%s] and phoneNumber [%s] does not exist", userID, phoneNumber)
 		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
@@ -109,14 +294,34 @@ func (repository *gormPhoneRepository) Load(ctx context.Context, userID entities
 	return phone, nil
 }
 
+// LoadOwner loads a phone by user and phone number in a single query without the entities.PhoneSIM
+func (repository *gormPhoneRepository) LoadOwner(ctx context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	phone := new(entities.Phone)
+	err := dbFromContext(ctx, repository.db).Where("user_id = ?", userID).Where("phone_number = ?", phoneNumber).First(phone).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("phone with userID [%s] and phoneNumber [%s] does not exist", userID, phoneNumber)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone with userID [%s] and phoneNumber [%s]", userID, phoneNumber)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return phone, nil
+}
+
 func (repository *gormPhoneRepository) Index(ctx context.Context, userID entities.UserID, params IndexParams) (*[]entities.Phone, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
+	query := dbFromContext(ctx, repository.db).Preload("SIMs", repository.orderSIMs).Where("user_id = ?", userID)
 	if len(params.Query) > 0 {
 		queryPattern := "%" + params.Query + "%"
-		query.Where("phone_number ILIKE ?", queryPattern)
+		query.Where(ilike(repository.db, "phone_number"), queryPattern)
 	}
 
 	phones := new([]entities.Phone)
@@ -127,3 +332,48 @@ func (repository *gormPhoneRepository) Index(ctx context.Context, userID entitie
 
 	return phones, nil
 }
+
+// SaveSIMs replaces the entities.PhoneSIM of an entities.Phone, removed slots are deleted and existing slots are updated
+func (repository *gormPhoneRepository) SaveSIMs(ctx context.Context, phone *entities.Phone, sims []entities.PhoneSIM) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	slots := make([]uint, 0, len(sims))
+	for _, sim := range sims {
+		slots = append(slots, sim.Slot)
+	}
+
+	err := executeTx(ctx, repository.db, func(tx *gorm.DB) error {
+		query := tx.Where("user_id = ?", phone.UserID).Where("phone_id = ?", phone.ID)
+		if len(slots) > 0 {
+			query = query.Where("slot NOT IN ?", slots)
+		}
+
+		if err := query.Delete(&entities.PhoneSIM{}).Error; err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot delete removed SIMs of phone with ID [%s]", phone.ID))
+		}
+
+		if len(sims) == 0 {
+			return nil
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "phone_id"}, {Name: "slot"}},
+			DoUpdates: clause.AssignmentColumns([]string{"carrier_name", "phone_number", "updated_at"}),
+		}).Create(&sims).Error
+		if err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot save SIMs of phone with ID [%s]", phone.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot save SIMs of phone with ID [%s] and userID [%s]", phone.ID, phone.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormPhoneRepository) orderSIMs(db *gorm.DB) *gorm.DB {
+	return db.Order("slot ASC")
+}
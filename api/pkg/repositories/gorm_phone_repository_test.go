@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newTestPhone(userID entities.UserID, phoneNumber string, timestamp time.Time) *entities.Phone {
+	return &entities.Phone{
+		ID:          uuid.New(),
+		UserID:      userID,
+		PhoneNumber: phoneNumber,
+		SIM:         entities.SIM1,
+		CreatedAt:   timestamp,
+		UpdatedAt:   timestamp,
+	}
+}
+
+// TestGormPhoneRepository_SetDefault verifies that a user has one default phone when it is set concurrently
+func TestGormPhoneRepository_SetDefault(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormPhoneRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			const phones = 10
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			phoneIDs := make([]uuid.UUID, 0, phones)
+			for i := 0; i < phones; i++ {
+				phone := newTestPhone(userID, fmt.Sprintf("+180055501%02d", i), time.Now().UTC())
+				assert.Nil(t, repository.Save(ctx, phone))
+				phoneIDs = append(phoneIDs, phone.ID)
+			}
+
+			wg := sync.WaitGroup{}
+			errs := make(chan error, phones)
+
+			// Act
+			for _, phoneID := range phoneIDs {
+				wg.Add(1)
+				go func(phoneID uuid.UUID) {
+					defer wg.Done()
+					errs <- repository.SetDefault(ctx, userID, phoneID)
+				}(phoneID)
+			}
+			wg.Wait()
+			close(errs)
+
+			// Assert
+			for err := range errs {
+				assert.Nil(t, err)
+			}
+
+			var count int64
+			assert.Nil(t, backend.db.Model(&entities.Phone{}).Where("user_id = ?", userID).Where("is_default = ?", true).Count(&count).Error)
+			assert.Equal(t, int64(1), count)
+
+			phone, err := repository.LoadDefault(ctx, userID)
+			assert.Nil(t, err)
+			assert.Contains(t, phoneIDs, phone.ID)
+
+			err = repository.SetDefault(ctx, entities.UserID(uuid.NewString()), phoneIDs[0])
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+		})
+
+		if backend.name == dialectMysql {
+			// the index of the default phone on MySQL is created by a migration
+			continue
+		}
+
+		t.Run(backend.name+"/unique index", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			first := newTestPhone(userID, "+18005550199", time.Now().UTC())
+			first.IsDefault = true
+			assert.Nil(t, repository.Save(ctx, first))
+
+			second := newTestPhone(userID, "+18005550198", time.Now().UTC())
+			second.IsDefault = true
+
+			// Act
+			err := repository.Save(ctx, second)
+
+			// Assert
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+// failingDeleteKey is the context key which makes the deletion of phone notifications fail
+type failingDeleteKey struct{}
+
+// TestGormPhoneRepository_Delete verifies that a phone is deleted with its heartbeats, monitor, SIMs and notifications
+// in one transaction and that the phone number can be registered again after it is deleted
+func TestGormPhoneRepository_Delete(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormPhoneRepository(backend.logger, backend.tracer, backend.db)
+
+		// the notifications of a phone cannot be deleted with a failing context so the cascade fails after the
+		// heartbeats, monitor and SIMs are deleted
+		assert.Nil(t, backend.db.Callback().Delete().Before("gorm:delete").Register("test:fail_phone_notifications", func(db *gorm.DB) {
+			if db.Statement.Table == "phone_notifications" && db.Statement.Context.Value(failingDeleteKey{}) != nil {
+				_ = db.AddError(errors.New("cannot delete phone notifications"))
+			}
+		}))
+
+		storePhone := func(t *testing.T, userID entities.UserID) *entities.Phone {
+			ctx := context.Background()
+			phone := newTestPhone(userID, "+18005550199", time.Now().UTC())
+			assert.Nil(t, repository.Save(ctx, phone))
+			assert.Nil(t, repository.SaveSIMs(ctx, phone, []entities.PhoneSIM{{ID: uuid.New(), PhoneID: phone.ID, UserID: userID, Slot: 0}}))
+			assert.Nil(t, backend.db.Create(&entities.Heartbeat{ID: uuid.New(), Owner: phone.PhoneNumber, UserID: userID, Timestamp: time.Now().UTC()}).Error)
+			assert.Nil(t, backend.db.Create(&entities.HeartbeatMonitor{ID: uuid.New(), PhoneID: phone.ID, Owner: phone.PhoneNumber, UserID: userID}).Error)
+			assert.Nil(t, backend.db.Create(&entities.PhoneNotification{ID: uuid.New(), MessageID: uuid.New(), PhoneID: phone.ID, UserID: userID, Status: "pending", ScheduledAt: time.Now().UTC()}).Error)
+			assert.Nil(t, backend.db.Create(newTestMessage(userID, "hello world", time.Now().UTC())).Error)
+			return phone
+		}
+
+		count := func(t *testing.T, model any, userID entities.UserID) int64 {
+			var count int64
+			assert.Nil(t, backend.db.Model(model).Where("user_id = ?", userID).Count(&count).Error)
+			return count
+		}
+
+		t.Run(backend.name+": nothing is deleted when the cascade fails", func(t *testing.T) {
+			// Arrange
+			failingUserID := entities.UserID(uuid.NewString())
+			phone := storePhone(t, failingUserID)
+
+			// Act
+			_, err := repository.Delete(context.WithValue(context.Background(), failingDeleteKey{}, true), phone, true)
+
+			// Assert
+			assert.NotNil(t, err)
+			assert.Equal(t, int64(1), count(t, &entities.Phone{}, failingUserID))
+			assert.Equal(t, int64(1), count(t, &entities.PhoneSIM{}, failingUserID))
+			assert.Equal(t, int64(1), count(t, &entities.Heartbeat{}, failingUserID))
+			assert.Equal(t, int64(1), count(t, &entities.HeartbeatMonitor{}, failingUserID))
+			assert.Equal(t, int64(1), count(t, &entities.PhoneNotification{}, failingUserID))
+
+			var pending int64
+			assert.Nil(t, backend.db.Model(&entities.Message{}).Where("user_id = ?", failingUserID).Where("status = ?", entities.MessageStatusPending).Count(&pending).Error)
+			assert.Equal(t, int64(1), pending)
+		})
+
+		t.Run(backend.name+": the phone is deleted with its heartbeats, monitor, SIMs and notifications", func(t *testing.T) {
+			// Arrange
+			userID := entities.UserID(uuid.NewString())
+			phone := storePhone(t, userID)
+
+			// Act
+			cancelled, err := repository.Delete(context.Background(), phone, true)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), cancelled)
+			assert.Equal(t, int64(0), count(t, &entities.Phone{}, userID))
+			assert.Equal(t, int64(0), count(t, &entities.PhoneSIM{}, userID))
+			assert.Equal(t, int64(0), count(t, &entities.Heartbeat{}, userID))
+			assert.Equal(t, int64(0), count(t, &entities.HeartbeatMonitor{}, userID))
+			assert.Equal(t, int64(0), count(t, &entities.PhoneNotification{}, userID))
+		})
+
+		t.Run(backend.name+": a deleted phone number can be registered again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			deleted := storePhone(t, userID)
+			_, err := repository.Delete(ctx, deleted, false)
+			assert.Nil(t, err)
+
+			phone := newTestPhone(userID, deleted.PhoneNumber, time.Now().UTC())
+
+			// Act
+			err = repository.Save(ctx, phone)
+
+			// Assert
+			assert.Nil(t, err)
+
+			stored, err := repository.Load(ctx, userID, deleted.PhoneNumber)
+			assert.Nil(t, err)
+			assert.Equal(t, phone.ID, stored.ID)
+			assert.Equal(t, 0, len(stored.SIMs))
+
+			_, err = repository.LoadByID(ctx, userID, deleted.ID)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+		})
+	}
+}
+
+// TestGormPhoneRepository_SaveSIMs verifies that the SIMs of a phone are reconciled with the SIMs which are reported by the phone
+func TestGormPhoneRepository_SaveSIMs(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormPhoneRepository(backend.logger, backend.tracer, backend.db)
+
+		newSIM := func(phone *entities.Phone, slot uint, carrier string, phoneNumber string) entities.PhoneSIM {
+			return entities.PhoneSIM{ID: uuid.New(), PhoneID: phone.ID, UserID: phone.UserID, Slot: slot, CarrierName: carrier, PhoneNumber: &phoneNumber, CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()}
+		}
+
+		storePhone := func(t *testing.T, sims func(phone *entities.Phone) []entities.PhoneSIM) *entities.Phone {
+			phone := newTestPhone(entities.UserID(uuid.NewString()), "+18005550199", time.Now().UTC())
+			assert.Nil(t, repository.Save(context.Background(), phone))
+			assert.Nil(t, repository.SaveSIMs(context.Background(), phone, sims(phone)))
+			return phone
+		}
+
+		t.Run(backend.name+": both SIMs of a dual SIM phone are registered", func(t *testing.T) {
+			// Arrange
+			phone := storePhone(t, func(phone *entities.Phone) []entities.PhoneSIM {
+				return []entities.PhoneSIM{newSIM(phone, 1, "Vodafone", "+18005550198"), newSIM(phone, 0, "T-Mobile", "+18005550199")}
+			})
+
+			// Act
+			stored, err := repository.Load(context.Background(), phone.UserID, phone.PhoneNumber)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(stored.SIMs))
+			assert.Equal(t, entities.SIM1, stored.SIMs[0].SIM())
+			assert.Equal(t, "T-Mobile", stored.SIMs[0].CarrierName)
+			assert.Equal(t, entities.SIM2, stored.SIMs[1].SIM())
+			assert.Equal(t, "Vodafone", stored.SIMs[1].CarrierName)
+		})
+
+		t.Run(backend.name+": a swapped SIM replaces the SIM in the same slot", func(t *testing.T) {
+			// Arrange
+			phone := storePhone(t, func(phone *entities.Phone) []entities.PhoneSIM {
+				return []entities.PhoneSIM{newSIM(phone, 0, "T-Mobile", "+18005550199")}
+			})
+
+			// Act
+			err := repository.SaveSIMs(context.Background(), phone, []entities.PhoneSIM{newSIM(phone, 0, "Vodafone", "+18005550197")})
+
+			// Assert
+			assert.Nil(t, err)
+
+			stored, err := repository.Load(context.Background(), phone.UserID, phone.PhoneNumber)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(stored.SIMs))
+			assert.Equal(t, uint(0), stored.SIMs[0].Slot)
+			assert.Equal(t, "Vodafone", stored.SIMs[0].CarrierName)
+			assert.Equal(t, "+18005550197", *stored.SIMs[0].PhoneNumber)
+		})
+
+		t.Run(backend.name+": a removed SIM is deleted", func(t *testing.T) {
+			// Arrange
+			phone := storePhone(t, func(phone *entities.Phone) []entities.PhoneSIM {
+				return []entities.PhoneSIM{newSIM(phone, 0, "T-Mobile", "+18005550199"), newSIM(phone, 1, "Vodafone", "+18005550198")}
+			})
+
+			// Act
+			err := repository.SaveSIMs(context.Background(), phone, []entities.PhoneSIM{newSIM(phone, 0, "T-Mobile", "+18005550199")})
+
+			// Assert
+			assert.Nil(t, err)
+
+			stored, err := repository.Load(context.Background(), phone.UserID, phone.PhoneNumber)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(stored.SIMs))
+			assert.Equal(t, entities.SIM1, stored.SIMs[0].SIM())
+			assert.False(t, stored.HasSIM(entities.SIM2))
+		})
+
+		t.Run(backend.name+": all SIMs are deleted when the phone reports no SIM", func(t *testing.T) {
+			// Arrange
+			phone := storePhone(t, func(phone *entities.Phone) []entities.PhoneSIM {
+				return []entities.PhoneSIM{newSIM(phone, 0, "T-Mobile", "+18005550199"), newSIM(phone, 1, "Vodafone", "+18005550198")}
+			})
+
+			// Act
+			err := repository.SaveSIMs(context.Background(), phone, []entities.PhoneSIM{})
+
+			// Assert
+			assert.Nil(t, err)
+
+			stored, err := repository.Load(context.Background(), phone.UserID, phone.PhoneNumber)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(stored.SIMs))
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/ids"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testBackend is a database which the repository test suite runs against
+type testBackend struct {
+	name   string
+	db     *gorm.DB
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// testBackends opens a SQLite database in a temporary directory, the postgres database in the DATABASE_URL_TEST
+// environment variable and the MySQL database in DATABASE_URL_TEST_MYSQL when they are set, so the behaviour of
+// the backends cannot diverge.
+func testBackends(t testing.TB) []testBackend {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	dialectors := map[string]gorm.Dialector{
+		dialectSqlite: NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")),
+	}
+	if dsn := os.Getenv("DATABASE_URL_TEST"); dsn != "" {
+		dialectors["postgres"] = postgres.Open(dsn)
+	}
+	if dsn := os.Getenv("DATABASE_URL_TEST_MYSQL"); dsn != "" {
+		dialectors[dialectMysql] = NewMysqlDialector(dsn)
+	}
+
+	backends := make([]testBackend, 0, len(dialectors))
+	for name, dialector := range dialectors {
+		db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+		assert.Nil(t, err)
+		assert.Nil(t, db.AutoMigrate(&entities.User{}, &entities.Message{}, &entities.MessageThread{}, &entities.Phone{}, &entities.PhoneSIM{}, &GormArchivedMessage{}, &entities.EventListenerLog{}, &GormEvent{}, &entities.Contact{}, &entities.ContactGroup{}, &entities.ContactGroupMember{}, &entities.ContactLabel{}, &entities.RoutingRule{}, &entities.MessageRate{}, &entities.PhoneDailySendUsage{}, &entities.ShortLink{}, &entities.Media{}, &entities.ContentExpiryRule{}, &entities.SummaryReportSchedule{}, &entities.SummaryReport{}, &entities.Webhook{}, &entities.APIKey{}, &entities.BillingUsage{}, &entities.Heartbeat{}, &entities.HeartbeatMonitor{}, &entities.PhoneNotification{}))
+
+		backends = append(backends, testBackend{name: name, db: db, logger: logger, tracer: tracer})
+	}
+
+	return backends
+}
+
+func newTestMessage(userID entities.UserID, content string, timestamp time.Time) *entities.Message {
+	return &entities.Message{
+		ID:                uuid.New(),
+		Owner:             "+18005550199",
+		Contact:           "+18005550100",
+		UserID:            userID,
+		Content:           content,
+		Type:              entities.MessageTypeMobileTerminated,
+		Status:            entities.MessageStatusPending,
+		RequestReceivedAt: timestamp,
+		OrderTimestamp:    timestamp,
+		CreatedAt:         timestamp,
+		UpdatedAt:         timestamp,
+	}
+}
+
+// TestGormMessageRepository_StoreManyBatches verifies that StoreMany inserts every message when they do not fit in one batch
+func TestGormMessageRepository_StoreManyBatches(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageRepository(backend.logger, backend.tracer, backend.db, nil)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			messages := make([]*entities.Message, 0, messageStoreBatchSize+1)
+			for i := 0; i < messageStoreBatchSize+1; i++ {
+				messages = append(messages, newTestMessage(userID, fmt.Sprintf("message %d", i), time.Now().UTC()))
+			}
+
+			// Act
+			_, err := repository.StoreMany(ctx, messages)
+
+			// Assert
+			assert.Nil(t, err)
+
+			var count int64
+			assert.Nil(t, backend.db.Model(&entities.Message{}).Where("user_id = ?", userID).Count(&count).Error)
+			assert.Equal(t, int64(messageStoreBatchSize+1), count)
+		})
+	}
+}
+
+// TestGormMessageRepository_ConcurrentWrites verifies that concurrent writers wait for the write lock of SQLite
+// instead of failing with SQLITE_BUSY
+func TestGormMessageRepository_ConcurrentWrites(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormMessageRepository(backend.logger, backend.tracer, backend.db, nil)
+		transactor := NewGormTransactor(backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			const writers = 20
+			ctx := context.Background()
+			message := newTestMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+			assert.Nil(t, repository.Store(ctx, message))
+
+			wg := sync.WaitGroup{}
+			errs := make(chan error, writers*2)
+
+			// Act
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					// every writer stores a new message and updates the same message in one transaction
+					errs <- transactor.Execute(ctx, func(ctx context.Context) error {
+						if err := repository.Store(ctx, newTestMessage(message.UserID, fmt.Sprintf("message %d", i), time.Now().UTC())); err != nil {
+							return err
+						}
+
+						stale := *message
+						return repository.Update(ctx, &stale)
+					})
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			// Assert
+			updated := 0
+			for err := range errs {
+				if err == nil {
+					updated++
+					continue
+				}
+				assert.Equal(t, ErrCodeStaleUpdate, stacktrace.GetCode(err), err.Error())
+			}
+			assert.Equal(t, 1, updated)
+
+			var count int64
+			assert.Nil(t, backend.db.Model(&entities.Message{}).Where("user_id = ?", message.UserID).Count(&count).Error)
+			assert.Equal(t, int64(2), count)
+		})
+	}
+}
+
+// BenchmarkGormMessageRepository_StoreMany compares storing 1000 messages one at a time with a bulk insert
+func BenchmarkGormMessageRepository_StoreMany(b *testing.B) {
+	const size = 1000
+	newMessages := func() []*entities.Message {
+		userID := entities.UserID(uuid.NewString())
+		messages := make([]*entities.Message, 0, size)
+		for i := 0; i < size; i++ {
+			messages = append(messages, newTestMessage(userID, fmt.Sprintf("message %d", i), time.Now().UTC()))
+		}
+		return messages
+	}
+
+	for _, backend := range testBackends(b) {
+		repository := NewGormMessageRepository(backend.logger, backend.tracer, backend.db, nil)
+
+		b.Run(backend.name+"/loop", func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				messages := newMessages()
+				b.StartTimer()
+
+				for _, message := range messages {
+					if err := repository.Store(context.Background(), message); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+
+		b.Run(backend.name+"/bulk", func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				messages := newMessages()
+				b.StartTimer()
+
+				if _, err := repository.StoreMany(context.Background(), messages); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGormMessageRepository_StoreIDStrategy compares inserting into a seeded messages table with random and
+// time ordered IDs. Random IDs are written to random pages of the primary key index while time ordered IDs are
+// appended to its last page.
+func BenchmarkGormMessageRepository_StoreIDStrategy(b *testing.B) {
+	const seed = 50_000
+
+	for _, backend := range testBackends(b) {
+		repository := NewGormMessageRepository(backend.logger, backend.tracer, backend.db, nil)
+
+		for _, strategy := range []ids.Strategy{ids.StrategyRandom, ids.StrategyTimeOrdered} {
+			generator, err := ids.NewGenerator(strategy)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			userID := entities.UserID(uuid.NewString())
+			messages := make([]*entities.Message, 0, seed)
+			for i := 0; i < seed; i++ {
+				message := newTestMessage(userID, fmt.Sprintf("seed %d", i), time.Now().UTC())
+				message.ID = generator.New()
+				messages = append(messages, message)
+			}
+			if _, err = repository.StoreMany(context.Background(), messages); err != nil {
+				b.Fatal(err)
+			}
+
+			b.Run(fmt.Sprintf("%s/%s", backend.name, strategy), func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					message := newTestMessage(userID, "hello world", time.Now().UTC())
+					message.ID = generator.New()
+					if err := repository.Store(context.Background(), message); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormRoutingRuleRepository is responsible for persisting entities.RoutingRule
+type gormRoutingRuleRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormRoutingRuleRepository creates the GORM version of the RoutingRuleRepository
+func NewGormRoutingRuleRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) RoutingRuleRepository {
+	return &gormRoutingRuleRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormRoutingRuleRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormRoutingRuleRepository) Store(ctx context.Context, rule *entities.RoutingRule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(rule).Error; err != nil {
+		msg := fmt.Sprintf("cannot store routing rule with ID [%s] for user [%s]", rule.ID, rule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormRoutingRuleRepository) Update(ctx context.Context, rule *entities.RoutingRule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(rule).Error; err != nil {
+		msg := fmt.Sprintf("cannot update routing rule with ID [%s] for user [%s]", rule.ID, rule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormRoutingRuleRepository) Load(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) (*entities.RoutingRule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rule := new(entities.RoutingRule)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", ruleID).First(rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("routing rule with ID [%s] for user [%s] does not exist", ruleID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load routing rule with ID [%s] for user [%s]", ruleID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rule, nil
+}
+
+func (repository *gormRoutingRuleRepository) Fetch(ctx context.Context, userID entities.UserID) ([]*entities.RoutingRule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	rules := make([]*entities.RoutingRule, 0)
+	if err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Order("prefix ASC").Find(&rules).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch routing rules for user [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rules, nil
+}
+
+func (repository *gormRoutingRuleRepository) Delete(ctx context.Context, rule *entities.RoutingRule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Where("user_id = ?", rule.UserID).Where("id = ?", rule.ID).Delete(&entities.RoutingRule{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete routing rule with ID [%s] for user [%s]", rule.ID, rule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
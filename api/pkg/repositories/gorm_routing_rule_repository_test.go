@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRoutingRule(userID entities.UserID, prefix string) *entities.RoutingRule {
+	return &entities.RoutingRule{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Prefix:    prefix,
+		Owner:     "+447700900000",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// TestGormRoutingRuleRepository_Fetch verifies that the rules of a user are fetched with the default route first
+func TestGormRoutingRuleRepository_Fetch(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormRoutingRuleRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": rules of the user are ordered by prefix", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			for _, prefix := range []string{"+44", "", "+237"} {
+				assert.Nil(t, repository.Store(ctx, newTestRoutingRule(userID, prefix)))
+			}
+			assert.Nil(t, repository.Store(ctx, newTestRoutingRule(entities.UserID(uuid.NewString()), "+1")))
+
+			// Act
+			rules, err := repository.Fetch(ctx, userID)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 3, len(rules))
+			assert.Equal(t, []string{"", "+237", "+44"}, []string{rules[0].Prefix, rules[1].Prefix, rules[2].Prefix})
+		})
+
+		t.Run(backend.name+": user cannot have two rules with the same prefix", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			assert.Nil(t, repository.Store(ctx, newTestRoutingRule(userID, "+44")))
+
+			// Act
+			err := repository.Store(ctx, newTestRoutingRule(userID, "+44"))
+
+			// Assert
+			assert.NotNil(t, err)
+		})
+	}
+}
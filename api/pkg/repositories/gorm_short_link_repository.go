@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormShortLinkRepository is responsible for persisting entities.ShortLink
+type gormShortLinkRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormShortLinkRepository creates the GORM version of the ShortLinkRepository
+func NewGormShortLinkRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) ShortLinkRepository {
+	return &gormShortLinkRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormShortLinkRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormShortLinkRepository) Store(ctx context.Context, link *entities.ShortLink) (StoreOutcome, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(link)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot store short link with ID [%s] for message [%s]", link.ID, link.MessageID)
+		return "", repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	if result.RowsAffected == 0 {
+		return StoreOutcomeSkipped, nil
+	}
+	return StoreOutcomeInserted, nil
+}
+
+func (repository *gormShortLinkRepository) Load(ctx context.Context, code string) (*entities.ShortLink, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	link := new(entities.ShortLink)
+	err := repository.db.WithContext(ctx).Where("code = ?", code).First(link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("short link with code [%s] does not exist", code)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load short link with code [%s]", code)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return link, nil
+}
+
+func (repository *gormShortLinkRepository) RecordClick(ctx context.Context, linkID uuid.UUID, timestamp time.Time) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).
+		Model(&entities.ShortLink{}).
+		Where("id = ?", linkID).
+		Updates(map[string]any{
+			"clicks":          gorm.Expr("clicks + 1"),
+			"last_clicked_at": timestamp,
+			"updated_at":      timestamp,
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot record a click of short link with ID [%s]", linkID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormShortLinkRepository) FetchByMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID) ([]*entities.ShortLink, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	links := make([]*entities.ShortLink, 0)
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("message_id = ?", messageID).
+		Order("created_at ASC").
+		Find(&links).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the short links of message [%s] for user [%s]", messageID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return links, nil
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormShortLinkRepository_Store verifies that a code cannot be used by two short links
+func TestGormShortLinkRepository_Store(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormShortLinkRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			code := uuid.NewString()[:8]
+			link := newTestShortLink("user-a", uuid.New(), code)
+
+			// Act
+			first, err1 := repository.Store(ctx, link)
+			second, err2 := repository.Store(ctx, newTestShortLink("user-b", uuid.New(), code))
+
+			// Assert
+			assert.Nil(t, err1)
+			assert.Nil(t, err2)
+			assert.Equal(t, StoreOutcomeInserted, first)
+			assert.Equal(t, StoreOutcomeSkipped, second)
+
+			stored, err := repository.Load(ctx, code)
+			assert.Nil(t, err)
+			assert.Equal(t, link.ID, stored.ID)
+		})
+	}
+}
+
+// TestGormShortLinkRepository_RecordClick verifies that the clicks of the short links of a message are counted
+func TestGormShortLinkRepository_RecordClick(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormShortLinkRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			messageID := uuid.New()
+			link := newTestShortLink("user-a", messageID, uuid.NewString()[:8])
+			_, err := repository.Store(ctx, link)
+			assert.Nil(t, err)
+
+			// Act
+			for i := 0; i < 3; i++ {
+				assert.Nil(t, repository.RecordClick(ctx, link.ID, time.Now().UTC()))
+			}
+
+			// Assert
+			links, err := repository.FetchByMessage(ctx, "user-a", messageID)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(links))
+			assert.Equal(t, uint(3), links[0].Clicks)
+			assert.NotNil(t, links[0].LastClickedAt)
+
+			links, err = repository.FetchByMessage(ctx, "user-b", messageID)
+			assert.Nil(t, err)
+			assert.Empty(t, links)
+		})
+	}
+}
+
+func newTestShortLink(userID entities.UserID, messageID uuid.UUID, code string) *entities.ShortLink {
+	return &entities.ShortLink{
+		ID:        uuid.New(),
+		UserID:    userID,
+		MessageID: messageID,
+		Code:      code,
+		ShortURL:  "https://httpsms.com/s/" + code,
+		URL:       "https://example.com/orders/12345?utm_source=sms",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormSlackRepository is responsible for persisting entities.Slack
+type gormSlackRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormSlackRepository creates the GORM version of the SlackRepository
+func NewGormSlackRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) SlackRepository {
+	return &gormSlackRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormSlackRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormSlackRepository) Save(ctx context.Context, slack *entities.Slack) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(slack).Error; err != nil {
+		msg := fmt.Sprintf("cannot update slack integration with ID [%s]", slack.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormSlackRepository) Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.Slack, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
+	if len(params.Query) > 0 {
+		queryPattern := "%" + params.Query + "%"
+		query.Where(repository.db.Where(ilike(repository.db, "name"), queryPattern).Or(ilike(repository.db, "owner"), queryPattern))
+	}
+
+	slacks := make([]*entities.Slack, 0)
+	if err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Skip).Find(&slacks).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch slack integrations for user [%s] and params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return slacks, nil
+}
+
+func (repository *gormSlackRepository) FetchByOwner(ctx context.Context, userID entities.UserID, owner string) ([]*entities.Slack, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	slacks := make([]*entities.Slack, 0)
+	err := repository.db.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Find(&slacks).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot load slack integrations for user with ID [%s] and owner [%s]", userID, owner)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return slacks, nil
+}
+
+func (repository *gormSlackRepository) Load(ctx context.Context, userID entities.UserID, slackID uuid.UUID) (*entities.Slack, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	slack := new(entities.Slack)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", slackID).First(slack).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("slack integration with ID [%s] for user [%s] does not exist", slackID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load slack integration with ID [%s] for user [%s]", slackID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return slack, nil
+}
+
+func (repository *gormSlackRepository) Delete(ctx context.Context, userID entities.UserID, slackID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("id = ?", slackID).
+		Delete(&entities.Slack{}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete slack integration with ID [%s] and userID [%s]", slackID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormSlackThreadRepository is responsible for persisting entities.SlackThread
+type gormSlackThreadRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormSlackThreadRepository creates the GORM version of the SlackThreadRepository
+func NewGormSlackThreadRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) SlackThreadRepository {
+	return &gormSlackThreadRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormSlackThreadRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+// Store a new entities.SlackThread, the first thread of a contact is kept when 2 messages are posted at the same time
+func (repository *gormSlackThreadRepository) Store(ctx context.Context, thread *entities.SlackThread) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(thread).Error; err != nil {
+		msg := fmt.Sprintf("cannot store slack thread for slack integration [%s] and contact [%s]", thread.SlackID, thread.Contact)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// Load the entities.SlackThread of a contact
+func (repository *gormSlackThreadRepository) Load(ctx context.Context, slackID uuid.UUID, contact string) (*entities.SlackThread, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	thread := new(entities.SlackThread)
+	err := repository.db.WithContext(ctx).Where("slack_id = ?", slackID).Where("contact = ?", contact).First(thread).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("slack thread for slack integration [%s] and contact [%s] does not exist", slackID, contact)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load slack thread for slack integration [%s] and contact [%s]", slackID, contact)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return thread, nil
+}
+
+// DeleteAll deletes the entities.SlackThread of an entities.Slack
+func (repository *gormSlackThreadRepository) DeleteAll(ctx context.Context, slackID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Where("slack_id = ?", slackID).Delete(&entities.SlackThread{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete slack threads for slack integration [%s]", slackID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormSummaryReportRepository is responsible for persisting entities.SummaryReportSchedule and entities.SummaryReport
+type gormSummaryReportRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormSummaryReportRepository creates the GORM version of the SummaryReportRepository
+func NewGormSummaryReportRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) SummaryReportRepository {
+	return &gormSummaryReportRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormSummaryReportRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormSummaryReportRepository) SaveSchedule(ctx context.Context, schedule *entities.SummaryReportSchedule) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(schedule).Error; err != nil {
+		msg := fmt.Sprintf("cannot save summary report schedule for user [%s]", schedule.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormSummaryReportRepository) LoadSchedule(ctx context.Context, userID entities.UserID) (*entities.SummaryReportSchedule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	schedule := new(entities.SummaryReportSchedule)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).First(schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("summary report schedule for user [%s] does not exist", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load summary report schedule for user [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return schedule, nil
+}
+
+func (repository *gormSummaryReportRepository) DeleteSchedule(ctx context.Context, userID entities.UserID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&entities.SummaryReportSchedule{}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete summary report schedule of user [%s]", userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormSummaryReportRepository) IndexEnabledSchedules(ctx context.Context, after entities.UserID, limit int) ([]*entities.SummaryReportSchedule, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var schedules []*entities.SummaryReportSchedule
+	err := repository.db.WithContext(ctx).
+		Where("enabled = ?", true).
+		Where("user_id > ?", after).
+		Order("user_id").
+		Limit(limit).
+		Find(&schedules).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch [%d] enabled summary report schedules after user [%s]", limit, after)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return schedules, nil
+}
+
+func (repository *gormSummaryReportRepository) LoadLastReport(ctx context.Context, userID entities.UserID, frequency entities.SummaryReportFrequency) (*entities.SummaryReport, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	report := new(entities.SummaryReport)
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("frequency = ?", frequency).
+		Order("period_start DESC").
+		First(report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("[%s] summary report for user [%s] does not exist", frequency, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the last [%s] summary report for user [%s]", frequency, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return report, nil
+}
+
+func (repository *gormSummaryReportRepository) StoreReport(ctx context.Context, report *entities.SummaryReport) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(report)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot store [%s] summary report of user [%s] for the period starting at [%s]", report.Frequency, report.UserID, report.PeriodStart)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (repository *gormSummaryReportRepository) DeleteReport(ctx context.Context, reportID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Where("id = ?", reportID).Delete(&entities.SummaryReport{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete summary report with ID [%s]", reportID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
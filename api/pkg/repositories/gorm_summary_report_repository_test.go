@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSummaryReport(userID entities.UserID, start time.Time) *entities.SummaryReport {
+	return &entities.SummaryReport{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Frequency:   entities.SummaryReportFrequencyDaily,
+		PeriodStart: start,
+		PeriodEnd:   start.Add(24 * time.Hour),
+		Timezone:    "UTC",
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// TestGormSummaryReportRepository_StoreReport verifies that the report of a period is stored once
+func TestGormSummaryReportRepository_StoreReport(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		repository := NewGormSummaryReportRepository(backend.logger, backend.tracer, backend.db)
+
+		t.Run(backend.name+": the report of a period which is already stored is not stored again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			start := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+
+			stored, err := repository.StoreReport(ctx, newTestSummaryReport(userID, start))
+			assert.Nil(t, err)
+			assert.True(t, stored)
+
+			// Act
+			stored, err = repository.StoreReport(ctx, newTestSummaryReport(userID, start))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.False(t, stored)
+		})
+
+		t.Run(backend.name+": the last report is the report of the latest period", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			userID := entities.UserID(uuid.NewString())
+			start := time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC)
+
+			last := newTestSummaryReport(userID, start.Add(24*time.Hour))
+			for _, report := range []*entities.SummaryReport{newTestSummaryReport(userID, start), last} {
+				_, err := repository.StoreReport(ctx, report)
+				assert.Nil(t, err)
+			}
+
+			// Act
+			report, err := repository.LoadLastReport(ctx, userID, entities.SummaryReportFrequencyDaily)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, last.ID, report.ID)
+		})
+
+		t.Run(backend.name+": the deleted report can be stored again", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			report := newTestSummaryReport(entities.UserID(uuid.NewString()), time.Date(2022, time.June, 5, 0, 0, 0, 0, time.UTC))
+			_, err := repository.StoreReport(ctx, report)
+			assert.Nil(t, err)
+			assert.Nil(t, repository.DeleteReport(ctx, report.ID))
+
+			// Act
+			stored, err := repository.StoreReport(ctx, newTestSummaryReport(report.UserID, report.PeriodStart))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.True(t, stored)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"github.com/dgraph-io/ristretto"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// teamMemberCacheTTL is the duration in which an entities.TeamMember is cached for authenticating requests
+const teamMemberCacheTTL = 10 * time.Minute
+
+// gormTeamRepository is responsible for persisting entities.TeamMember and entities.TeamInvitation
+type gormTeamRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	cache  *ristretto.Cache
+	db     *gorm.DB
+}
+
+// NewGormTeamRepository creates the GORM version of the TeamRepository
+func NewGormTeamRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	cache *ristretto.Cache,
+	db *gorm.DB,
+) TeamRepository {
+	return &gormTeamRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormTeamRepository{})),
+		tracer: tracer,
+		cache:  cache,
+		db:     db,
+	}
+}
+
+// Index fetches the entities.Team which a user has joined, it does not include the personal team of the user
+func (repository *gormTeamRepository) Index(ctx context.Context, userID entities.UserID) ([]*entities.Team, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var teams []*entities.Team
+	err := repository.db.WithContext(ctx).
+		Model(&entities.TeamMember{}).
+		Select("team_members.team_id AS id, users.email AS name, team_members.role AS role").
+		Joins("JOIN users ON users.id = team_members.team_id").
+		Where("team_members.user_id = ?", userID).
+		Order("team_members.created_at ASC").
+		Scan(&teams).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch teams of user with ID [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return teams, nil
+}
+
+// IndexMembers fetches the entities.TeamMember of a team
+func (repository *gormTeamRepository) IndexMembers(ctx context.Context, teamID entities.UserID) ([]*entities.TeamMember, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var members []*entities.TeamMember
+	if err := repository.db.WithContext(ctx).Where("team_id = ?", teamID).Order("created_at ASC").Find(&members).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch members of team with ID [%s]", teamID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return members, nil
+}
+
+// LoadMember fetches the entities.TeamMember of a user in a team
+func (repository *gormTeamRepository) LoadMember(ctx context.Context, teamID entities.UserID, userID entities.UserID) (*entities.TeamMember, error) {
+	ctx, span, ctxLogger := repository.tracer.StartWithLogger(ctx, repository.logger)
+	defer span.End()
+
+	if member, found := repository.cache.Get(repository.cacheKey(teamID, userID)); found {
+		ctxLogger.Info(fmt.Sprintf("cache hit for member [%s] of team [%s]", userID, teamID))
+		result := member.(entities.TeamMember)
+		return &result, nil
+	}
+
+	member := new(entities.TeamMember)
+	err := repository.db.WithContext(ctx).Where("team_id = ?", teamID).Where("user_id = ?", userID).First(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("user with ID [%s] is not a member of team [%s]", userID, teamID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load member [%s] of team [%s]", userID, teamID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if result := repository.cache.SetWithTTL(repository.cacheKey(teamID, userID), *member, 1, teamMemberCacheTTL); !result {
+		msg := fmt.Sprintf("cannot cache [%T] with ID [%s] and result [%t]", member, member.ID, result)
+		ctxLogger.Error(repository.tracer.WrapErrorSpan(span, stacktrace.NewError(msg)))
+	}
+
+	return member, nil
+}
+
+// DeleteMember removes an entities.TeamMember from a team
+func (repository *gormTeamRepository) DeleteMember(ctx context.Context, member *entities.TeamMember) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Delete(member).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete member [%s] of team [%s]", member.UserID, member.TeamID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	repository.cache.Del(repository.cacheKey(member.TeamID, member.UserID))
+	return nil
+}
+
+// StoreInvitation stores a new entities.TeamInvitation
+func (repository *gormTeamRepository) StoreInvitation(ctx context.Context, invitation *entities.TeamInvitation) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Create(invitation).Error; err != nil {
+		msg := fmt.Sprintf("cannot save team invitation with ID [%s]", invitation.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// LoadInvitation fetches an entities.TeamInvitation by the hash of the token
+func (repository *gormTeamRepository) LoadInvitation(ctx context.Context, tokenHash string) (*entities.TeamInvitation, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	invitation := new(entities.TeamInvitation)
+	err := repository.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("team invitation with token hash [%s] does not exist", tokenHash)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load team invitation with token hash [%s]", tokenHash)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return invitation, nil
+}
+
+// AcceptInvitation marks an entities.TeamInvitation as accepted and stores the entities.TeamMember in a single transaction
+func (repository *gormTeamRepository) AcceptInvitation(ctx context.Context, invitation *entities.TeamInvitation, member *entities.TeamMember) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	acceptedAt := time.Now().UTC()
+	err := crdbgorm.ExecuteTx(ctx, repository.db, nil, func(tx *gorm.DB) error {
+		result := tx.WithContext(ctx).
+			Model(invitation).
+			Where("accepted_at IS NULL").
+			Updates(map[string]any{
+				"accepted_at": acceptedAt,
+				"updated_at":  acceptedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return stacktrace.NewErrorWithCode(ErrCodeNotFound, "team invitation with ID [%s] has already been accepted", invitation.ID)
+		}
+
+		return tx.WithContext(ctx).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "team_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+		}).Create(member).Error
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot accept team invitation with ID [%s] for user [%s]", invitation.ID, member.UserID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	repository.cache.Del(repository.cacheKey(member.TeamID, member.UserID))
+
+	invitation.AcceptedAt = &acceptedAt
+	return nil
+}
+
+func (repository *gormTeamRepository) cacheKey(teamID entities.UserID, userID entities.UserID) string {
+	return fmt.Sprintf("team-member:%s/%s", teamID, userID)
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormTelegramMessageRepository is responsible for persisting entities.TelegramMessage
+type gormTelegramMessageRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormTelegramMessageRepository creates the GORM version of the TelegramMessageRepository
+func NewGormTelegramMessageRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) TelegramMessageRepository {
+	return &gormTelegramMessageRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormTelegramMessageRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+// Store a new entities.TelegramMessage
+func (repository *gormTelegramMessageRepository) Store(ctx context.Context, message *entities.TelegramMessage) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(message).Error; err != nil {
+		msg := fmt.Sprintf("cannot store telegram message [%d] for telegram integration [%s]", message.TelegramMessageID, message.TelegramID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// Load the entities.TelegramMessage of a message in the telegram chat
+func (repository *gormTelegramMessageRepository) Load(ctx context.Context, telegramID uuid.UUID, telegramMessageID int64) (*entities.TelegramMessage, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	message := new(entities.TelegramMessage)
+	err := repository.db.WithContext(ctx).Where("telegram_id = ?", telegramID).Where("telegram_message_id = ?", telegramMessageID).First(message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("telegram message [%d] for telegram integration [%s] does not exist", telegramMessageID, telegramID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram message [%d] for telegram integration [%s]", telegramMessageID, telegramID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return message, nil
+}
+
+// DeleteAll deletes the entities.TelegramMessage of an entities.Telegram
+func (repository *gormTelegramMessageRepository) DeleteAll(ctx context.Context, telegramID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Where("telegram_id = ?", telegramID).Delete(&entities.TelegramMessage{}).Error; err != nil {
+		msg := fmt.Sprintf("cannot delete telegram messages for telegram integration [%s]", telegramID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// gormTelegramRepository is responsible for persisting entities.Telegram
+type gormTelegramRepository struct {
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	db     *gorm.DB
+}
+
+// NewGormTelegramRepository creates the GORM version of the TelegramRepository
+func NewGormTelegramRepository(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	db *gorm.DB,
+) TelegramRepository {
+	return &gormTelegramRepository{
+		logger: logger.WithService(fmt.Sprintf("%T", &gormTelegramRepository{})),
+		tracer: tracer,
+		db:     db,
+	}
+}
+
+func (repository *gormTelegramRepository) Save(ctx context.Context, telegram *entities.Telegram) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if err := repository.db.WithContext(ctx).Save(telegram).Error; err != nil {
+		msg := fmt.Sprintf("cannot update telegram integration with ID [%s]", telegram.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+func (repository *gormTelegramRepository) Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.Telegram, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
+	if len(params.Query) > 0 {
+		queryPattern := "%" + params.Query + "%"
+		query.Where(repository.db.Where(ilike(repository.db, "name"), queryPattern).Or(ilike(repository.db, "owner"), queryPattern))
+	}
+
+	telegrams := make([]*entities.Telegram, 0)
+	if err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Skip).Find(&telegrams).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch telegram integrations for user [%s] and params [%+#v]", userID, params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return telegrams, nil
+}
+
+func (repository *gormTelegramRepository) FetchByOwner(ctx context.Context, userID entities.UserID, owner string) ([]*entities.Telegram, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	telegrams := make([]*entities.Telegram, 0)
+	err := repository.db.
+		WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("owner = ?", owner).
+		Find(&telegrams).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integrations for user with ID [%s] and owner [%s]", userID, owner)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return telegrams, nil
+}
+
+func (repository *gormTelegramRepository) Load(ctx context.Context, userID entities.UserID, telegramID uuid.UUID) (*entities.Telegram, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	telegram := new(entities.Telegram)
+	err := repository.db.WithContext(ctx).Where("user_id = ?", userID).Where("id = ?", telegramID).First(telegram).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("telegram integration with ID [%s] for user [%s] does not exist", telegramID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integration with ID [%s] for user [%s]", telegramID, userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return telegram, nil
+}
+
+func (repository *gormTelegramRepository) LoadByID(ctx context.Context, telegramID uuid.UUID) (*entities.Telegram, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	telegram := new(entities.Telegram)
+	err := repository.db.WithContext(ctx).Where("id = ?", telegramID).First(telegram).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		msg := fmt.Sprintf("telegram integration with ID [%s] does not exist", telegramID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integration with ID [%s]", telegramID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return telegram, nil
+}
+
+func (repository *gormTelegramRepository) ClaimUpdate(ctx context.Context, telegramID uuid.UUID, updateID int64) (bool, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	result := repository.db.WithContext(ctx).
+		Model(&entities.Telegram{}).
+		Where("id = ?", telegramID).
+		Where("last_update_id < ?", updateID).
+		Update("last_update_id", updateID)
+	if result.Error != nil {
+		msg := fmt.Sprintf("cannot claim update [%d] of telegram integration with ID [%s]", updateID, telegramID)
+		return false, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(result.Error, msg))
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (repository *gormTelegramRepository) Delete(ctx context.Context, userID entities.UserID, telegramID uuid.UUID) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := repository.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where("id = ?", telegramID).
+		Delete(&entities.Telegram{}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete telegram integration with ID [%s] and userID [%s]", telegramID, userID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
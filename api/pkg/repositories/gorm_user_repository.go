@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
 	"github.com/dgraph-io/ristretto"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
@@ -45,7 +44,7 @@ func (repository *gormUserRepository) LoadBySubscriptionID(ctx context.Context,
 	defer span.End()
 
 	user := new(entities.User)
-	err := repository.db.WithContext(ctx).
+	err := dbFromContext(ctx, repository.db).
 		Where("subscription_id = ?", subscriptionID).
 		First(user).
 		Error
@@ -66,7 +65,7 @@ func (repository *gormUserRepository) Store(ctx context.Context, user *entities.
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Create(user).Error; err != nil {
+	if err := dbFromContext(ctx, repository.db).Create(user).Error; err != nil {
 		msg := fmt.Sprintf("cannot save user with ID [%s]", user.ID)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -78,7 +77,7 @@ func (repository *gormUserRepository) Update(ctx context.Context, user *entities
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
-	if err := repository.db.WithContext(ctx).Save(user).Error; err != nil {
+	if err := dbFromContext(ctx, repository.db).Save(user).Error; err != nil {
 		msg := fmt.Sprintf("cannot update user with ID [%s]", user.ID)
 		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
@@ -90,29 +89,49 @@ func (repository *gormUserRepository) LoadAuthUser(ctx context.Context, apiKey s
 	ctx, span, ctxLogger := repository.tracer.StartWithLogger(ctx, repository.logger)
 	defer span.End()
 
-	if authUser, found := repository.cache.Get(apiKey); found {
+	if apiKey == "" {
+		msg := "user with an empty api key does not exist"
+		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeNotFound, msg))
+	}
+
+	keyHash := entities.HashAPIKey(apiKey)
+	if authUser, found := repository.cache.Get(repository.cacheKey(keyHash)); found {
 		ctxLogger.Info(fmt.Sprintf("cache hit for user with ID [%s]", authUser.(entities.AuthUser).ID))
 		return authUser.(entities.AuthUser), nil
 	}
 
 	user := new(entities.User)
-	err := repository.db.WithContext(ctx).Where("api_key = ?", apiKey).First(user).Error
+	err := dbFromContext(ctx, repository.db).
+		Where("api_key_hash = ?", keyHash).
+		Or(repository.db.Where("previous_api_key_hash = ?", keyHash).Where("previous_api_key_expires_at > ?", time.Now().UTC())).
+		First(user).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		msg := fmt.Sprintf("user with api key [%s] does not exist", apiKey)
+		msg := fmt.Sprintf("user with api key [%s] does not exist", telemetry.RedactAPIKey(apiKey))
 		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
 	}
 
 	if err != nil {
-		msg := fmt.Sprintf("cannot load user with api key [%s]", apiKey)
+		msg := fmt.Sprintf("cannot load user with api key [%s]", telemetry.RedactAPIKey(apiKey))
 		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if user.IsSuspended() {
+		msg := fmt.Sprintf("user with ID [%s] is suspended", user.ID)
+		return entities.AuthUser{}, repository.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeUserSuspended, msg))
+	}
+
 	authUser := entities.AuthUser{
-		ID:    user.ID,
-		Email: user.Email,
+		ID:      user.ID,
+		Email:   user.Email,
+		IsAdmin: user.IsAdmin,
 	}
 
-	if result := repository.cache.SetWithTTL(apiKey, authUser, 1, 2*time.Hour); !result {
+	ttl := 2 * time.Hour
+	if repository.apiKeyHash(user) != keyHash && time.Until(*user.PreviousAPIKeyExpiresAt) < ttl {
+		ttl = time.Until(*user.PreviousAPIKeyExpiresAt)
+	}
+
+	if result := repository.cache.SetWithTTL(repository.cacheKey(keyHash), authUser, 1, ttl); !result {
 		msg := fmt.Sprintf("cannot cache [%T] with ID [%s] and result [%t]", authUser, user.ID, result)
 		ctxLogger.Error(repository.tracer.WrapErrorSpan(span, stacktrace.NewError(msg)))
 	}
@@ -120,12 +139,250 @@ func (repository *gormUserRepository) LoadAuthUser(ctx context.Context, apiKey s
 	return authUser, nil
 }
 
+// RotateAPIKey generates a new API key for an entities.User, the previous API key stays valid for the grace period.
+// Only the hash of the new API key is stored, the plain text API key is only available in the returned entities.User
+func (repository *gormUserRepository) RotateAPIKey(ctx context.Context, userID entities.UserID, gracePeriod time.Duration) (*entities.User, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	user, err := repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	apiKey, err := repository.generateAPIKey(64)
+	if err != nil {
+		msg := fmt.Sprintf("cannot generate apiKey for user with ID [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	revokedKeyHashes := repository.apiKeyHashes(user)
+
+	apiKeyHash := entities.HashAPIKey(apiKey)
+	var previousAPIKeyHash *string
+	var previousAPIKeyExpiresAt *time.Time
+	if gracePeriod > 0 {
+		expiresAt := time.Now().UTC().Add(gracePeriod)
+		keyHash := repository.apiKeyHash(user)
+		previousAPIKeyHash = &keyHash
+		previousAPIKeyExpiresAt = &expiresAt
+	}
+
+	err = dbFromContext(ctx, repository.db).
+		Model(user).
+		Updates(map[string]any{
+			"api_key_hash":                apiKeyHash,
+			"previous_api_key_hash":       previousAPIKeyHash,
+			"previous_api_key_expires_at": previousAPIKeyExpiresAt,
+			"updated_at":                  time.Now().UTC(),
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot update apiKey for user with ID [%s]", userID)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	// the previous keys are loaded again from the database which checks the grace period
+	repository.evict(ctx, revokedKeyHashes)
+
+	user.APIKey = apiKey
+	user.APIKeyHash = &apiKeyHash
+	user.PreviousAPIKeyHash = previousAPIKeyHash
+	user.PreviousAPIKeyExpiresAt = previousAPIKeyExpiresAt
+	return user, nil
+}
+
+// Suspend an entities.User so that the API keys of the user are rejected, the user is reinstated when suspendedAt is nil
+func (repository *gormUserRepository) Suspend(ctx context.Context, user *entities.User, suspendedAt *time.Time) error {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	err := dbFromContext(ctx, repository.db).
+		Model(user).
+		Updates(map[string]any{
+			"suspended_at": suspendedAt,
+			"updated_at":   time.Now().UTC(),
+		}).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot update suspended_at for user with ID [%s]", user.ID)
+		return repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	repository.evict(ctx, repository.apiKeyHashes(user))
+
+	user.SuspendedAt = suspendedAt
+	return nil
+}
+
+// evict removes the cached entities.AuthUser of API key hashes, the cache is only changed after the transaction of ctx
+// is committed so that a concurrent request cannot cache the user again before the update is visible
+func (repository *gormUserRepository) evict(ctx context.Context, keyHashes []string) {
+	evict := func(context.Context) {
+		for _, keyHash := range keyHashes {
+			repository.cache.Del(repository.cacheKey(keyHash))
+		}
+	}
+	if !AfterCommit(ctx, evict) {
+		evict(ctx)
+	}
+}
+
+// apiKeyHash returns the hash of the current API key of an entities.User
+func (repository *gormUserRepository) apiKeyHash(user *entities.User) string {
+	if user.APIKeyHash != nil {
+		return *user.APIKeyHash
+	}
+	return ""
+}
+
+// apiKeyHashes returns the hashes of the current and previous API keys of an entities.User
+func (repository *gormUserRepository) apiKeyHashes(user *entities.User) []string {
+	keyHashes := []string{repository.apiKeyHash(user)}
+	if user.PreviousAPIKeyHash != nil {
+		keyHashes = append(keyHashes, *user.PreviousAPIKeyHash)
+	}
+	return keyHashes
+}
+
+func (repository *gormUserRepository) cacheKey(keyHash string) string {
+	return "user-api-key:" + keyHash
+}
+
+// Index fetches entities.User with an email which matches the params
+func (repository *gormUserRepository) Index(ctx context.Context, params IndexParams) ([]*entities.User, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	query := dbFromContext(ctx, repository.db)
+	if len(params.Query) > 0 {
+		queryPattern := "%" + params.Query + "%"
+		query = query.Where(ilike(repository.db, "email"), queryPattern)
+	}
+
+	var users []*entities.User
+	if err := query.Order("created_at DESC").Limit(params.Limit).Offset(params.Skip).Find(&users).Error; err != nil {
+		msg := fmt.Sprintf("cannot fetch users with params [%+#v]", params)
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return users, nil
+}
+
+// IndexWithRetention fetches the entities.User who have a retention period for received or sent messages
+func (repository *gormUserRepository) IndexWithRetention(ctx context.Context) ([]*entities.User, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	var users []*entities.User
+	err := dbFromContext(ctx, repository.db).
+		Where("retention_received_days > ?", 0).
+		Or("retention_sent_days > ?", 0).
+		Order("created_at ASC").
+		Find(&users).
+		Error
+	if err != nil {
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot fetch users with a retention period"))
+	}
+
+	return users, nil
+}
+
+// Summaries aggregates the phones and messages of a list of entities.User
+func (repository *gormUserRepository) Summaries(ctx context.Context, users []*entities.User) ([]*entities.UserSummary, error) {
+	ctx, span := repository.tracer.Start(ctx)
+	defer span.End()
+
+	if len(users) == 0 {
+		return []*entities.UserSummary{}, nil
+	}
+
+	userIDs := make([]entities.UserID, 0, len(users))
+	summaries := make(map[entities.UserID]*entities.UserSummary, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
+		summaries[user.ID] = entities.NewUserSummary(user)
+	}
+
+	var phoneCounts []struct {
+		UserID     entities.UserID
+		PhoneCount uint
+	}
+	err := dbFromContext(ctx, repository.db).
+		Model(&entities.Phone{}).
+		Select("user_id, COUNT(*) AS phone_count").
+		Where("user_id IN ?", userIDs).
+		Group("user_id").
+		Scan(&phoneCounts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot count phones of [%d] users", len(userIDs))
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, row := range phoneCounts {
+		summaries[row.UserID].PhoneCount = row.PhoneCount
+	}
+
+	var messageCounts []struct {
+		UserID               entities.UserID
+		PendingMessageCount  uint
+		SentMessageCount     uint
+		ReceivedMessageCount uint
+		FailedMessageCount   uint
+		// LastMessageAt is scanned as text because SQLite returns the text of an aggregated timestamp
+		LastMessageAt *string
+	}
+	err = dbFromContext(ctx, repository.db).
+		Model(&entities.Message{}).
+		Select(
+			`user_id,
+			SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS pending_message_count,
+			SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS sent_message_count,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS received_message_count,
+			SUM(CASE WHEN status IN ? THEN 1 ELSE 0 END) AS failed_message_count,
+			MAX(CASE WHEN type = ? THEN order_timestamp END) AS last_message_at`,
+			[]entities.MessageStatus{entities.MessageStatusPending, entities.MessageStatusScheduled, entities.MessageStatusSending},
+			[]entities.MessageStatus{entities.MessageStatusSent, entities.MessageStatusDelivered},
+			entities.MessageStatusReceived,
+			[]entities.MessageStatus{entities.MessageStatusFailed, entities.MessageStatusExpired},
+			entities.MessageTypeMobileTerminated,
+		).
+		Where("user_id IN ?", userIDs).
+		Group("user_id").
+		Scan(&messageCounts).Error
+	if err != nil {
+		msg := fmt.Sprintf("cannot aggregate messages of [%d] users", len(userIDs))
+		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, row := range messageCounts {
+		summaries[row.UserID].PendingMessageCount = row.PendingMessageCount
+		summaries[row.UserID].SentMessageCount = row.SentMessageCount
+		summaries[row.UserID].ReceivedMessageCount = row.ReceivedMessageCount
+		summaries[row.UserID].FailedMessageCount = row.FailedMessageCount
+
+		if row.LastMessageAt != nil {
+			lastMessageAt, err := parseTimestamp(*row.LastMessageAt)
+			if err != nil {
+				msg := fmt.Sprintf("cannot parse the timestamp [%s] of the last message of user [%s]", *row.LastMessageAt, row.UserID)
+				return nil, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+			}
+			summaries[row.UserID].LastMessageAt = &lastMessageAt
+		}
+	}
+
+	result := make([]*entities.UserSummary, 0, len(users))
+	for _, userID := range userIDs {
+		result = append(result, summaries[userID])
+	}
+	return result, nil
+}
+
 func (repository *gormUserRepository) Load(ctx context.Context, userID entities.UserID) (*entities.User, error) {
 	ctx, span := repository.tracer.Start(ctx)
 	defer span.End()
 
 	user := new(entities.User)
-	err := repository.db.WithContext(ctx).First(user, userID).Error
+	err := dbFromContext(ctx, repository.db).First(user, userID).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		msg := fmt.Sprintf("user with ID [%s] does not exist", user.ID)
 		return nil, repository.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeNotFound, msg))
@@ -153,17 +410,18 @@ func (repository *gormUserRepository) LoadOrStore(ctx context.Context, authUser
 		return nil, false, stacktrace.Propagate(err, "cannot generate apiKey")
 	}
 
+	apiKeyHash := entities.HashAPIKey(apiKey)
 	user = &entities.User{
 		ID:               authUser.ID,
 		Email:            authUser.Email,
-		APIKey:           apiKey,
+		APIKeyHash:       &apiKeyHash,
 		SubscriptionName: entities.SubscriptionNameFree,
 		CreatedAt:        time.Now().UTC(),
 		UpdatedAt:        time.Now().UTC(),
 	}
 
 	isNew := false
-	err = crdbgorm.ExecuteTx(ctx, repository.db, nil, func(tx *gorm.DB) error {
+	err = executeTx(ctx, repository.db, func(tx *gorm.DB) error {
 		result := tx.WithContext(ctx).Where(entities.User{ID: user.ID}).FirstOrCreate(user)
 		if result.Error != nil {
 			return result.Error
@@ -178,6 +436,11 @@ func (repository *gormUserRepository) LoadOrStore(ctx context.Context, authUser
 		return user, isNew, repository.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	// the plain text API key is only returned to the request which created the user since only the hash is stored
+	if isNew {
+		user.APIKey = apiKey
+	}
+
 	return user, isNew, nil
 }
 
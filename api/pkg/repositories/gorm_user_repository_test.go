@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/dgraph-io/ristretto"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormUserRepository_RotateAPIKey verifies that a rotated API key is stored as a hash and the previous API key is valid during the grace period
+func TestGormUserRepository_RotateAPIKey(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		cache, err := ristretto.NewCache(&ristretto.Config{MaxCost: 100, NumCounters: 1000, BufferItems: 64})
+		assert.Nil(t, err)
+		repository := NewGormUserRepository(backend.logger, backend.tracer, cache, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			initialAPIKey := uuid.NewString()
+			initialAPIKeyHash := entities.HashAPIKey(initialAPIKey)
+			user := &entities.User{
+				ID:               entities.UserID(uuid.NewString()),
+				Email:            "name@email.com",
+				APIKeyHash:       &initialAPIKeyHash,
+				SubscriptionName: entities.SubscriptionNameFree,
+				CreatedAt:        time.Now().UTC(),
+				UpdatedAt:        time.Now().UTC(),
+			}
+			assert.Nil(t, repository.Store(ctx, user))
+
+			_, err := repository.LoadAuthUser(ctx, initialAPIKey)
+			assert.Nil(t, err)
+
+			// Act
+			rotated, err := repository.RotateAPIKey(ctx, user.ID, time.Hour)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.NotEqual(t, initialAPIKey, rotated.APIKey)
+
+			stored, err := repository.Load(ctx, user.ID)
+			assert.Nil(t, err)
+			assert.Empty(t, stored.APIKey)
+			assert.Equal(t, entities.HashAPIKey(rotated.APIKey), *stored.APIKeyHash)
+			assert.Equal(t, entities.HashAPIKey(initialAPIKey), *stored.PreviousAPIKeyHash)
+
+			authUser, err := repository.LoadAuthUser(ctx, rotated.APIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, user.ID, authUser.ID)
+
+			authUser, err = repository.LoadAuthUser(ctx, initialAPIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, user.ID, authUser.ID)
+
+			_, err = repository.LoadAuthUser(ctx, "")
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+
+			// Act
+			revoked, err := repository.RotateAPIKey(ctx, user.ID, 0)
+
+			// Assert
+			assert.Nil(t, err)
+
+			_, err = repository.LoadAuthUser(ctx, rotated.APIKey)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+
+			_, err = repository.LoadAuthUser(ctx, initialAPIKey)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+
+			authUser, err = repository.LoadAuthUser(ctx, revoked.APIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, user.ID, authUser.ID)
+		})
+	}
+}
+
+// TestGormUserRepository_RotateAPIKeyGracePeriod verifies that the previous API key is accepted inside the grace period
+// and rejected after it, also when it was cached inside the grace period
+func TestGormUserRepository_RotateAPIKeyGracePeriod(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		cache, err := ristretto.NewCache(&ristretto.Config{MaxCost: 100, NumCounters: 1000, BufferItems: 64})
+		assert.Nil(t, err)
+		repository := NewGormUserRepository(backend.logger, backend.tracer, cache, backend.db)
+
+		storeUser := func(t *testing.T) (*entities.User, string) {
+			apiKey := uuid.NewString()
+			apiKeyHash := entities.HashAPIKey(apiKey)
+			user := &entities.User{
+				ID:               entities.UserID(uuid.NewString()),
+				Email:            "name@email.com",
+				APIKeyHash:       &apiKeyHash,
+				SubscriptionName: entities.SubscriptionNameFree,
+				CreatedAt:        time.Now().UTC(),
+				UpdatedAt:        time.Now().UTC(),
+			}
+			assert.Nil(t, repository.Store(context.Background(), user))
+			return user, apiKey
+		}
+
+		t.Run(backend.name+": previous API key is rejected after the grace period", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			user, previousAPIKey := storeUser(t)
+
+			rotated, err := repository.RotateAPIKey(ctx, user.ID, time.Hour)
+			assert.Nil(t, err)
+
+			// Act
+			assert.Nil(t, backend.db.Model(&entities.User{}).Where("id = ?", user.ID).Update("previous_api_key_expires_at", time.Now().UTC().Add(-time.Second)).Error)
+
+			// Assert
+			_, err = repository.LoadAuthUser(ctx, previousAPIKey)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+
+			authUser, err := repository.LoadAuthUser(ctx, rotated.APIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, user.ID, authUser.ID)
+		})
+
+		t.Run(backend.name+": previous API key which is cached inside the grace period is rejected after it", func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			user, previousAPIKey := storeUser(t)
+
+			rotated, err := repository.RotateAPIKey(ctx, user.ID, time.Second)
+			assert.Nil(t, err)
+
+			authUser, err := repository.LoadAuthUser(ctx, previousAPIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, user.ID, authUser.ID)
+			cache.Wait()
+
+			// Act
+			time.Sleep(time.Until(*rotated.PreviousAPIKeyExpiresAt) + 100*time.Millisecond)
+
+			// Assert
+			_, err = repository.LoadAuthUser(ctx, previousAPIKey)
+			assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+
+			authUser, err = repository.LoadAuthUser(ctx, rotated.APIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, user.ID, authUser.ID)
+		})
+	}
+}
+
+// TestGormUserRepository_LoadOrStore verifies that the API key of a new user is stored as a hash and only returned when the user is created
+func TestGormUserRepository_LoadOrStore(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		cache, err := ristretto.NewCache(&ristretto.Config{MaxCost: 100, NumCounters: 1000, BufferItems: 64})
+		assert.Nil(t, err)
+		repository := NewGormUserRepository(backend.logger, backend.tracer, cache, backend.db)
+
+		t.Run(backend.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			authUser := entities.AuthUser{ID: entities.UserID(uuid.NewString()), Email: "name@email.com"}
+
+			// Act
+			created, isNew, err := repository.LoadOrStore(ctx, authUser)
+			loaded, isNewAgain, errAgain := repository.LoadOrStore(ctx, authUser)
+
+			// Assert
+			assert.Nil(t, err)
+			assert.True(t, isNew)
+			assert.NotEmpty(t, created.APIKey)
+			assert.Equal(t, entities.HashAPIKey(created.APIKey), *created.APIKeyHash)
+
+			assert.Nil(t, errAgain)
+			assert.False(t, isNewAgain)
+			assert.Empty(t, loaded.APIKey)
+			assert.Equal(t, *created.APIKeyHash, *loaded.APIKeyHash)
+
+			result, err := repository.LoadAuthUser(ctx, created.APIKey)
+			assert.Nil(t, err)
+			assert.Equal(t, authUser.ID, result.ID)
+		})
+	}
+}
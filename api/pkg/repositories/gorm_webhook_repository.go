@@ -52,7 +52,7 @@ func (repository *gormWebhookRepository) Index(ctx context.Context, userID entit
 	query := repository.db.WithContext(ctx).Where("user_id = ?", userID)
 	if len(params.Query) > 0 {
 		queryPattern := "%" + params.Query + "%"
-		query.Where(repository.db.Where("url ILIKE ?", queryPattern))
+		query.Where(repository.db.Where(ilike(repository.db, "url"), queryPattern))
 	}
 
 	webhooks := make([]*entities.Webhook, 0)
@@ -70,6 +70,7 @@ func (repository *gormWebhookRepository) LoadByEvent(ctx context.Context, userID
 
 	webhooks := make([]*entities.Webhook, 0)
 	err := repository.db.
+		WithContext(ctx).
 		Raw("SELECT * FROM webhooks WHERE user_id = ? AND CAST(? as TEXT) = ANY(events) AND CAST(? as TEXT) = ANY(phone_numbers)", userID, event, phoneNumber).
 		Scan(&webhooks).
 		Error
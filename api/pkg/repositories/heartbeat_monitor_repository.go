@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -22,6 +23,19 @@ type HeartbeatMonitorRepository interface {
 	// UpdateQueueID updates the queueID of a monitor
 	UpdateQueueID(ctx context.Context, monitorID uuid.UUID, queueID string) error
 
+	// UpdatePhoneOnline changes the last known liveness of the phone of a monitor, it returns false when the liveness
+	// was already changed e.g. by a concurrent check so that the change is only handled once
+	UpdatePhoneOnline(ctx context.Context, monitorID uuid.UUID, phoneOnline bool) (bool, error)
+
+	// UpdateConsecutiveMisses updates the number of consecutive missed heartbeat checks of a monitor
+	UpdateConsecutiveMisses(ctx context.Context, monitorID uuid.UUID, misses uint) error
+
+	// UpdateLastAlertedAt updates the time when the phone of a monitor was last declared offline
+	UpdateLastAlertedAt(ctx context.Context, monitorID uuid.UUID, timestamp time.Time) error
+
+	// Fetch all entities.HeartbeatMonitor
+	Fetch(ctx context.Context) (*[]entities.HeartbeatMonitor, error)
+
 	// Delete an entities.HeartbeatMonitor
 	Delete(ctx context.Context, userID entities.UserID, phoneNumber string) error
 }
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 )
@@ -12,8 +13,24 @@ type HeartbeatRepository interface {
 	Store(ctx context.Context, heartbeat *entities.Heartbeat) error
 
 	// Index entities.Heartbeat of an owner
-	Index(ctx context.Context, userID entities.UserID, owner string, params IndexParams) (*[]entities.Heartbeat, error)
+	Index(ctx context.Context, userID entities.UserID, owner string, params HeartbeatIndexParams) (*[]entities.Heartbeat, error)
+
+	// CountHourly counts the entities.Heartbeat of an owner in each hour of a time range
+	CountHourly(ctx context.Context, userID entities.UserID, owner string, from time.Time, to time.Time) (*[]entities.HeartbeatCount, error)
+
+	// DeleteBefore deletes all entities.Heartbeat older than a timestamp
+	DeleteBefore(ctx context.Context, timestamp time.Time) (int64, error)
 
 	// Last entities.Heartbeat returns the last heartbeat
 	Last(ctx context.Context, userID entities.UserID, owner string) (*entities.Heartbeat, error)
+
+	// LastTimestamp returns the timestamp of the last entities.Heartbeat of a registered entities.Phone which is nil when the phone has never sent a heartbeat
+	LastTimestamp(ctx context.Context, userID entities.UserID, owner string) (*time.Time, error)
+}
+
+// HeartbeatIndexParams parameters for indexing entities.Heartbeat in an optional time range
+type HeartbeatIndexParams struct {
+	IndexParams
+	From *time.Time
+	To   *time.Time
 }
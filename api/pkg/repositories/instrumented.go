@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MetricsSink records the duration of repository calls
+type MetricsSink interface {
+	// Record the duration of a call to a repository method and whether it succeeded
+	Record(ctx context.Context, repository string, method string, duration time.Duration, success bool)
+}
+
+// otelMetricsSink records the duration of repository calls in an OpenTelemetry histogram
+type otelMetricsSink struct {
+	histogram metric.Float64Histogram
+}
+
+// NewOtelMetricsSink creates a MetricsSink which records durations in milliseconds with the repository, method and success attributes.
+// The number of failed calls of a method is the count of the histogram with success=false.
+func NewOtelMetricsSink(histogram metric.Float64Histogram) MetricsSink {
+	return &otelMetricsSink{histogram: histogram}
+}
+
+// Record the duration of a call to a repository method
+func (sink *otelMetricsSink) Record(ctx context.Context, repository string, method string, duration time.Duration, success bool) {
+	sink.histogram.Record(
+		ctx,
+		float64(duration.Microseconds())/1000,
+		metric.WithAttributes(
+			attribute.String("repository", repository),
+			attribute.String("method", method),
+			attribute.Bool("success", success),
+		),
+	)
+}
+
+// instrumenter measures the calls of a repository and logs the calls which are slower than a threshold
+type instrumenter struct {
+	logger        telemetry.Logger
+	sink          MetricsSink
+	repository    string
+	slowThreshold time.Duration
+	now           func() time.Time
+}
+
+func newInstrumenter(logger telemetry.Logger, sink MetricsSink, repository string, slowThreshold time.Duration) instrumenter {
+	return instrumenter{
+		logger:        logger,
+		sink:          sink,
+		repository:    repository,
+		slowThreshold: slowThreshold,
+		now:           time.Now,
+	}
+}
+
+// do calls fn and records its duration. fn is called directly when there is no sink and no threshold.
+// summary is only called for slow calls, it must describe the parameters without the content of messages.
+func (instrumenter instrumenter) do(ctx context.Context, method string, summary func() string, fn func() error) error {
+	if instrumenter.sink == nil && instrumenter.slowThreshold <= 0 {
+		return fn()
+	}
+
+	start := instrumenter.now()
+	err := fn()
+	duration := instrumenter.now().Sub(start)
+
+	if instrumenter.sink != nil {
+		instrumenter.sink.Record(ctx, instrumenter.repository, method, duration, err == nil)
+	}
+
+	if instrumenter.slowThreshold > 0 && duration >= instrumenter.slowThreshold {
+		msg := fmt.Sprintf("slow call to [%s.%s] took [%s] with threshold [%s] and params [%s]", instrumenter.repository, method, duration, instrumenter.slowThreshold, summary())
+		instrumenter.logger.Warn(stacktrace.NewError(msg))
+	}
+
+	return err
+}
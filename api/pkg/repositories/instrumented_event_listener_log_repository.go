@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+)
+
+// instrumentedEventListenerLogRepository measures the calls of an EventListenerLogRepository
+type instrumentedEventListenerLogRepository struct {
+	repository   EventListenerLogRepository
+	instrumenter instrumenter
+}
+
+// NewInstrumentedEventListenerLogRepository creates an EventListenerLogRepository which records the duration of every call
+// in the MetricsSink and logs the calls which take longer than slowThreshold.
+func NewInstrumentedEventListenerLogRepository(
+	logger telemetry.Logger,
+	repository EventListenerLogRepository,
+	sink MetricsSink,
+	slowThreshold time.Duration,
+) EventListenerLogRepository {
+	return &instrumentedEventListenerLogRepository{
+		repository:   repository,
+		instrumenter: newInstrumenter(logger.WithService(fmt.Sprintf("%T", &instrumentedEventListenerLogRepository{})), sink, "EventListenerLogRepository", slowThreshold),
+	}
+}
+
+// Store a new entities.EventListenerLog
+func (repository *instrumentedEventListenerLogRepository) Store(ctx context.Context, log *entities.EventListenerLog) error {
+	return repository.instrumenter.do(ctx, "Store", func() string {
+		return fmt.Sprintf("event=%s handler=%s", log.EventID, log.Handler)
+	}, func() error {
+		return repository.repository.Store(ctx, log)
+	})
+}
+
+// Has verifies that the listener has not already been called
+func (repository *instrumentedEventListenerLogRepository) Has(ctx context.Context, eventID string, handler string) (exists bool, err error) {
+	err = repository.instrumenter.do(ctx, "Has", func() string {
+		return fmt.Sprintf("event=%s handler=%s", eventID, handler)
+	}, func() error {
+		exists, err = repository.repository.Has(ctx, eventID, handler)
+		return err
+	})
+	return exists, err
+}
@@ -0,0 +1,283 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// instrumentedMessageRepository measures the calls of a MessageRepository
+type instrumentedMessageRepository struct {
+	repository   MessageRepository
+	instrumenter instrumenter
+}
+
+// NewInstrumentedMessageRepository creates a MessageRepository which records the duration of every call in the MetricsSink
+// and logs the calls which take longer than slowThreshold. The sink is optional and slow calls are not logged when slowThreshold is 0.
+func NewInstrumentedMessageRepository(
+	logger telemetry.Logger,
+	repository MessageRepository,
+	sink MetricsSink,
+	slowThreshold time.Duration,
+) MessageRepository {
+	return &instrumentedMessageRepository{
+		repository:   repository,
+		instrumenter: newInstrumenter(logger.WithService(fmt.Sprintf("%T", &instrumentedMessageRepository{})), sink, "MessageRepository", slowThreshold),
+	}
+}
+
+// Store a new entities.Message
+func (repository *instrumentedMessageRepository) Store(ctx context.Context, message *entities.Message) error {
+	return repository.instrumenter.do(ctx, "Store", func() string {
+		return fmt.Sprintf("id=%s user=%s", message.ID, message.UserID)
+	}, func() error {
+		return repository.repository.Store(ctx, message)
+	})
+}
+
+// StoreMany inserts entities.Message in batches
+func (repository *instrumentedMessageRepository) StoreMany(ctx context.Context, messages []*entities.Message) (outcomes []StoreOutcome, err error) {
+	err = repository.instrumenter.do(ctx, "StoreMany", func() string {
+		return fmt.Sprintf("count=%d", len(messages))
+	}, func() error {
+		outcomes, err = repository.repository.StoreMany(ctx, messages)
+		return err
+	})
+	return outcomes, err
+}
+
+// Update an entities.Message
+func (repository *instrumentedMessageRepository) Update(ctx context.Context, message *entities.Message) error {
+	return repository.instrumenter.do(ctx, "Update", func() string {
+		return fmt.Sprintf("id=%s user=%s version=%d", message.ID, message.UserID, message.Version)
+	}, func() error {
+		return repository.repository.Update(ctx, message)
+	})
+}
+
+// Load an entities.Message by ID
+func (repository *instrumentedMessageRepository) Load(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (message *entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "Load", func() string {
+		return fmt.Sprintf("id=%s user=%s", messageID, userID)
+	}, func() error {
+		message, err = repository.repository.Load(ctx, userID, messageID)
+		return err
+	})
+	return message, err
+}
+
+// LoadLastReceived fetches the latest entities.Message which the owner received from the contact
+func (repository *instrumentedMessageRepository) LoadLastReceived(ctx context.Context, userID entities.UserID, owner string, contact string) (message *entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "LoadLastReceived", func() string {
+		return fmt.Sprintf("user=%s owner=%s contact=%s", userID, owner, contact)
+	}, func() error {
+		message, err = repository.repository.LoadLastReceived(ctx, userID, owner, contact)
+		return err
+	})
+	return message, err
+}
+
+// LoadMany loads the entities.Message of a user with the IDs
+func (repository *instrumentedMessageRepository) LoadMany(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) (messages []entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "LoadMany", func() string {
+		return fmt.Sprintf("ids=%d user=%s", len(messageIDs), userID)
+	}, func() error {
+		messages, err = repository.repository.LoadMany(ctx, userID, messageIDs)
+		return err
+	})
+	return messages, err
+}
+
+// Index entities.Message between 2 phone numbers
+func (repository *instrumentedMessageRepository) Index(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (messages *[]entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "Index", func() string {
+		return fmt.Sprintf("user=%s owner=%s contact=%s %s", userID, owner, contact, summarizeIndexParams(params))
+	}, func() error {
+		messages, err = repository.repository.Index(ctx, userID, owner, contact, params)
+		return err
+	})
+	return messages, err
+}
+
+// GetOutstanding fetches an entities.Message which is outstanding
+func (repository *instrumentedMessageRepository) GetOutstanding(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (message *entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "GetOutstanding", func() string {
+		return fmt.Sprintf("id=%s user=%s", messageID, userID)
+	}, func() error {
+		message, err = repository.repository.GetOutstanding(ctx, userID, messageID)
+		return err
+	})
+	return message, err
+}
+
+// IndexPending fetches the mobile terminated entities.Message of an owner which are still to be sent
+func (repository *instrumentedMessageRepository) IndexPending(ctx context.Context, userID entities.UserID, owner string) (messages *[]entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "IndexPending", func() string {
+		return fmt.Sprintf("user=%s owner=%s", userID, owner)
+	}, func() error {
+		messages, err = repository.repository.IndexPending(ctx, userID, owner)
+		return err
+	})
+	return messages, err
+}
+
+// CountPending counts the entities.Message of an owner which are still to be sent
+func (repository *instrumentedMessageRepository) CountPending(ctx context.Context, userID entities.UserID, owner string) (count int64, err error) {
+	err = repository.instrumenter.do(ctx, "CountPending", func() string {
+		return fmt.Sprintf("user=%s owner=%s", userID, owner)
+	}, func() error {
+		count, err = repository.repository.CountPending(ctx, userID, owner)
+		return err
+	})
+	return count, err
+}
+
+// Failover moves a pending entities.Message from an owner to the failover owner
+func (repository *instrumentedMessageRepository) Failover(ctx context.Context, userID entities.UserID, messageID uuid.UUID, owner string, failoverOwner string, sim entities.SIM) (message *entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "Failover", func() string {
+		return fmt.Sprintf("id=%s user=%s owner=%s failover_owner=%s", messageID, userID, owner, failoverOwner)
+	}, func() error {
+		message, err = repository.repository.Failover(ctx, userID, messageID, owner, failoverOwner, sim)
+		return err
+	})
+	return message, err
+}
+
+// Fallback moves an entities.Message from the phone to the channel of a fallback provider
+func (repository *instrumentedMessageRepository) Fallback(ctx context.Context, userID entities.UserID, messageID uuid.UUID, channel entities.MessageChannel) (message *entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "Fallback", func() string {
+		return fmt.Sprintf("id=%s user=%s channel=%s", messageID, userID, channel)
+	}, func() error {
+		message, err = repository.repository.Fallback(ctx, userID, messageID, channel)
+		return err
+	})
+	return message, err
+}
+
+// Count counts the entities.Message between 2 phone numbers which match the filters of Index
+func (repository *instrumentedMessageRepository) Count(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (count int64, err error) {
+	err = repository.instrumenter.do(ctx, "Count", func() string {
+		return fmt.Sprintf("user=%s owner=%s contact=%s %s", userID, owner, contact, summarizeIndexParams(params))
+	}, func() error {
+		count, err = repository.repository.Count(ctx, userID, owner, contact, params)
+		return err
+	})
+	return count, err
+}
+
+// CountSendAttemptsSince counts the entities.Message which an owner has attempted to send since a timestamp
+func (repository *instrumentedMessageRepository) CountSendAttemptsSince(ctx context.Context, userID entities.UserID, owner string, since time.Time) (count int64, err error) {
+	err = repository.instrumenter.do(ctx, "CountSendAttemptsSince", func() string {
+		return fmt.Sprintf("user=%s owner=%s since=%s", userID, owner, since.Format(time.RFC3339))
+	}, func() error {
+		count, err = repository.repository.CountSendAttemptsSince(ctx, userID, owner, since)
+		return err
+	})
+	return count, err
+}
+
+// CountByStatus counts the entities.Message of all users with each status
+func (repository *instrumentedMessageRepository) CountByStatus(ctx context.Context, statuses []entities.MessageStatus) (counts map[entities.MessageStatus]int64, err error) {
+	err = repository.instrumenter.do(ctx, "CountByStatus", func() string {
+		return fmt.Sprintf("statuses=%v", statuses)
+	}, func() error {
+		counts, err = repository.repository.CountByStatus(ctx, statuses)
+		return err
+	})
+	return counts, err
+}
+
+// Delete soft deletes an entities.Message by ID
+func (repository *instrumentedMessageRepository) Delete(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	return repository.instrumenter.do(ctx, "Delete", func() string {
+		return fmt.Sprintf("id=%s user=%s", messageID, userID)
+	}, func() error {
+		return repository.repository.Delete(ctx, userID, messageID)
+	})
+}
+
+// DeleteByOwnerAndContact soft deletes messages between an owner and a contact
+func (repository *instrumentedMessageRepository) DeleteByOwnerAndContact(ctx context.Context, userID entities.UserID, owner string, contact string) error {
+	return repository.instrumenter.do(ctx, "DeleteByOwnerAndContact", func() string {
+		return fmt.Sprintf("user=%s owner=%s contact=%s", userID, owner, contact)
+	}, func() error {
+		return repository.repository.DeleteByOwnerAndContact(ctx, userID, owner, contact)
+	})
+}
+
+// Archive moves up to limit entities.Message into the archive
+func (repository *instrumentedMessageRepository) Archive(ctx context.Context, before time.Time, limit int) (count int64, err error) {
+	err = repository.instrumenter.do(ctx, "Archive", func() string {
+		return fmt.Sprintf("before=%s limit=%d", before.Format(time.RFC3339), limit)
+	}, func() error {
+		count, err = repository.repository.Archive(ctx, before, limit)
+		return err
+	})
+	return count, err
+}
+
+// IndexArchived fetches archived entities.Message which were ordered before a timestamp
+func (repository *instrumentedMessageRepository) IndexArchived(ctx context.Context, before time.Time, limit int) (messages []entities.Message, err error) {
+	err = repository.instrumenter.do(ctx, "IndexArchived", func() string {
+		return fmt.Sprintf("before=%s limit=%d", before.Format(time.RFC3339), limit)
+	}, func() error {
+		messages, err = repository.repository.IndexArchived(ctx, before, limit)
+		return err
+	})
+	return messages, err
+}
+
+// PurgeArchived permanently deletes archived entities.Message by ID
+func (repository *instrumentedMessageRepository) PurgeArchived(ctx context.Context, messageIDs []uuid.UUID) (count int64, err error) {
+	err = repository.instrumenter.do(ctx, "PurgeArchived", func() string {
+		return fmt.Sprintf("count=%d", len(messageIDs))
+	}, func() error {
+		count, err = repository.repository.PurgeArchived(ctx, messageIDs)
+		return err
+	})
+	return count, err
+}
+
+// DeleteExpired deletes the entities.Message of a user and type which were ordered before a timestamp
+func (repository *instrumentedMessageRepository) DeleteExpired(ctx context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (count int64, err error) {
+	err = repository.instrumenter.do(ctx, "DeleteExpired", func() string {
+		return fmt.Sprintf("user=%s type=%s before=%s hard=%t limit=%d", userID, messageType, before.Format(time.RFC3339), hard, limit)
+	}, func() error {
+		count, err = repository.repository.DeleteExpired(ctx, userID, messageType, before, hard, limit)
+		return err
+	})
+	return count, err
+}
+
+// ExpireContent replaces the content of the entities.Message whose content expires before a timestamp
+func (repository *instrumentedMessageRepository) ExpireContent(ctx context.Context, before time.Time, limit int) (messageIDs []uuid.UUID, err error) {
+	err = repository.instrumenter.do(ctx, "ExpireContent", func() string {
+		return fmt.Sprintf("before=%s limit=%d", before.Format(time.RFC3339), limit)
+	}, func() error {
+		messageIDs, err = repository.repository.ExpireContent(ctx, before, limit)
+		return err
+	})
+	return messageIDs, err
+}
+
+// Restore an entities.Message which was soft deleted
+func (repository *instrumentedMessageRepository) Restore(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	return repository.instrumenter.do(ctx, "Restore", func() string {
+		return fmt.Sprintf("id=%s user=%s", messageID, userID)
+	}, func() error {
+		return repository.repository.Restore(ctx, userID, messageID)
+	})
+}
+
+// summarizeIndexParams describes IndexParams with the length of the search query and the number of metadata filters
+// instead of the query and the metadata
+func summarizeIndexParams(params IndexParams) string {
+	return fmt.Sprintf(
+		"skip=%d limit=%d query_length=%d include_deleted=%t include_archived=%t status=%s after=%t before=%t metadata_filters=%d",
+		params.Skip, params.Limit, len(params.Query), params.IncludeDeleted, params.IncludeArchived, params.Status, params.After != nil, params.Before != nil, len(params.Metadata),
+	)
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// measurement is a call recorded by the fakeMetricsSink
+type measurement struct {
+	repository string
+	method     string
+	duration   time.Duration
+	success    bool
+}
+
+// fakeMetricsSink stores the measurements in memory
+type fakeMetricsSink struct {
+	mutex        sync.Mutex
+	measurements []measurement
+}
+
+func (sink *fakeMetricsSink) Record(_ context.Context, repository string, method string, duration time.Duration, success bool) {
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	sink.measurements = append(sink.measurements, measurement{repository: repository, method: method, duration: duration, success: success})
+}
+
+// newTestInstrumentedMessageRepository creates an instrumentedMessageRepository whose calls take callDuration and whose logs are written to a buffer
+func newTestInstrumentedMessageRepository(repository MessageRepository, sink MetricsSink, slowThreshold time.Duration, callDuration time.Duration) (*instrumentedMessageRepository, *bytes.Buffer) {
+	buffer := new(bytes.Buffer)
+	zl := zerolog.New(buffer)
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+
+	instrumented := NewInstrumentedMessageRepository(logger, repository, sink, slowThreshold).(*instrumentedMessageRepository)
+
+	timestamp := time.Now()
+	instrumented.instrumenter.now = func() time.Time {
+		timestamp = timestamp.Add(callDuration)
+		return timestamp
+	}
+	return instrumented, buffer
+}
+
+func TestInstrumentedMessageRepository(t *testing.T) {
+	t.Run("successful call is recorded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		sink := &fakeMetricsSink{}
+		repository, _ := newTestInstrumentedMessageRepository(&flakyMessageRepository{}, sink, 0, 10*time.Millisecond)
+
+		// Act
+		_, err := repository.Load(context.Background(), "user-id", uuid.New())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, []measurement{{repository: "MessageRepository", method: "Load", duration: 10 * time.Millisecond, success: true}}, sink.measurements)
+	})
+
+	t.Run("failed call is recorded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		sink := &fakeMetricsSink{}
+		repository, _ := newTestInstrumentedMessageRepository(&flakyMessageRepository{}, sink, 0, 10*time.Millisecond)
+
+		// Act
+		err := repository.Store(context.Background(), &entities.Message{ID: uuid.New()})
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, []measurement{{repository: "MessageRepository", method: "Store", duration: 10 * time.Millisecond, success: false}}, sink.measurements)
+	})
+
+	t.Run("slow call is logged without the message content", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		sink := &fakeMetricsSink{}
+		repository, logs := newTestInstrumentedMessageRepository(&flakyMessageRepository{}, sink, 100*time.Millisecond, 150*time.Millisecond)
+
+		// Arrange
+		message := &entities.Message{ID: uuid.New(), Content: "my secret code is 1234"}
+
+		// Act
+		_ = repository.Store(context.Background(), message)
+
+		// Assert
+		assert.Contains(t, logs.String(), "slow call to [MessageRepository.Store]")
+		assert.Contains(t, logs.String(), message.ID.String())
+		assert.NotContains(t, logs.String(), message.Content)
+	})
+
+	t.Run("fast call is not logged", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository, logs := newTestInstrumentedMessageRepository(&flakyMessageRepository{}, nil, 100*time.Millisecond, 10*time.Millisecond)
+
+		// Act
+		_, err := repository.Load(context.Background(), "user-id", uuid.New())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, logs.String())
+	})
+}
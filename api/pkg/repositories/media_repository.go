@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// MediaRepository loads and persists an entities.Media
+type MediaRepository interface {
+	// Store a new entities.Media
+	Store(ctx context.Context, media *entities.Media) error
+
+	// Load an entities.Media by ID
+	Load(ctx context.Context, userID entities.UserID, mediaID uuid.UUID) (*entities.Media, error)
+
+	// Attach sets the message of the entities.Media with the IDs, it fails with ErrCodeStaleUpdate when one of them
+	// is already attached to a message
+	Attach(ctx context.Context, userID entities.UserID, messageID uuid.UUID, mediaIDs []uuid.UUID) error
+
+	// FetchByMessages fetches the entities.Media attached to the messages ordered by when they were created
+	FetchByMessages(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]*entities.Media, error)
+
+	// FetchUnattached fetches at most limit entities.Media which were created before the timestamp and are not attached to a message
+	FetchUnattached(ctx context.Context, before time.Time, limit int) ([]*entities.Media, error)
+
+	// Delete the entities.Media with the IDs
+	Delete(ctx context.Context, mediaIDs []uuid.UUID) error
+}
@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories/repositorytest"
+)
+
+func TestMessageRepository_Conformance(t *testing.T) {
+	repositorytest.MessageRepository(t, NewMessageRepository)
+}
+
+func TestEventListenerLogRepository_Conformance(t *testing.T) {
+	repositorytest.EventListenerLogRepository(t, NewEventListenerLogRepository)
+}
+
+func TestEventRepository_Conformance(t *testing.T) {
+	repositorytest.EventRepository(t, NewEventRepository)
+}
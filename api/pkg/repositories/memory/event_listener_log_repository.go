@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// eventListenerLogKey is the unique index of the event_listener_logs table
+type eventListenerLogKey struct {
+	eventID string
+	handler string
+}
+
+// eventListenerLogRepository keeps entities.EventListenerLog in a map
+type eventListenerLogRepository struct {
+	mutex sync.RWMutex
+	ids   map[uuid.UUID]bool
+	logs  map[eventListenerLogKey]entities.EventListenerLog
+}
+
+// NewEventListenerLogRepository creates the in-memory version of the repositories.EventListenerLogRepository
+func NewEventListenerLogRepository() repositories.EventListenerLogRepository {
+	return &eventListenerLogRepository{
+		ids:  map[uuid.UUID]bool{},
+		logs: map[eventListenerLogKey]entities.EventListenerLog{},
+	}
+}
+
+// Store a new entities.EventListenerLog
+func (repository *eventListenerLogRepository) Store(_ context.Context, log *entities.EventListenerLog) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	key := eventListenerLogKey{eventID: log.EventID, handler: log.Handler}
+	if _, ok := repository.logs[key]; ok || repository.ids[log.ID] {
+		return stacktrace.Propagate(gorm.ErrDuplicatedKey, fmt.Sprintf("cannot save event listener log with ID [%s]", log.ID))
+	}
+
+	repository.ids[log.ID] = true
+	repository.logs[key] = *log
+	return nil
+}
+
+// Has checks if an event has been handled
+func (repository *eventListenerLogRepository) Has(_ context.Context, eventID string, handler string) (bool, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	_, ok := repository.logs[eventListenerLogKey{eventID: eventID, handler: handler}]
+	return ok, nil
+}
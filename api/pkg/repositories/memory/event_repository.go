@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// eventRepository keeps cloudevents.Event in a map
+type eventRepository struct {
+	mutex  sync.RWMutex
+	events map[string]cloudevents.Event
+}
+
+// NewEventRepository creates the in-memory version of the repositories.EventRepository
+func NewEventRepository() repositories.EventRepository {
+	return &eventRepository{
+		events: map[string]cloudevents.Event{},
+	}
+}
+
+// Create a new cloudevents.Event
+func (repository *eventRepository) Create(ctx context.Context, event cloudevents.Event) error {
+	return repository.Save(ctx, event)
+}
+
+// Save a cloudevents.Event
+func (repository *eventRepository) Save(_ context.Context, event cloudevents.Event) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.events[event.ID()] = event.Clone()
+	return nil
+}
+
+// FetchAll returns all cloudevents.Event ordered by time in ascending order
+func (repository *eventRepository) FetchAll(_ context.Context) (*[]cloudevents.Event, error) {
+	return repository.filter(func(cloudevents.Event) bool { return true }), nil
+}
+
+// FetchByMessageID returns the cloudevents.Event whose payload references the entities.Message ordered by time in ascending order
+func (repository *eventRepository) FetchByMessageID(_ context.Context, messageID uuid.UUID) (*[]cloudevents.Event, error) {
+	return repository.filter(func(event cloudevents.Event) bool {
+		id := repositories.EventMessageID(event)
+		return id != nil && *id == messageID
+	}), nil
+}
+
+// FetchByUserIDAfter returns at most limit cloudevents.Event of the entities.User which were emitted after the event with the ID, ordered by time in ascending order
+func (repository *eventRepository) FetchByUserIDAfter(_ context.Context, userID entities.UserID, eventID uuid.UUID, limit int) (*[]cloudevents.Event, error) {
+	isUserEvent := func(event cloudevents.Event) bool {
+		id := repositories.EventUserID(event)
+		return id != nil && *id == userID
+	}
+
+	repository.mutex.RLock()
+	last, ok := repository.events[eventID.String()]
+	repository.mutex.RUnlock()
+	if !ok || !isUserEvent(last) {
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, fmt.Sprintf("user [%s] has no event with ID [%s]", userID, eventID))
+	}
+
+	results := *repository.filter(func(event cloudevents.Event) bool {
+		return isUserEvent(event) && (event.Time().After(last.Time()) || (event.Time().Equal(last.Time()) && event.ID() > last.ID()))
+	})
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Time().Equal(results[j].Time()) {
+			return results[i].ID() < results[j].ID()
+		}
+		return results[i].Time().Before(results[j].Time())
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return &results, nil
+}
+
+func (repository *eventRepository) filter(match func(event cloudevents.Event) bool) *[]cloudevents.Event {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	results := make([]cloudevents.Event, 0, len(repository.events))
+	for _, event := range repository.events {
+		if match(event) {
+			results = append(results, event.Clone())
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Time().Before(results[j].Time())
+	})
+	return &results
+}
@@ -0,0 +1,576 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+// archivableMessageStatuses are the statuses of an entities.Message which will not be updated again by the phone
+var archivableMessageStatuses = map[entities.MessageStatus]bool{
+	entities.MessageStatusSent:      true,
+	entities.MessageStatusReceived:  true,
+	entities.MessageStatusDelivered: true,
+	entities.MessageStatusFailed:    true,
+	entities.MessageStatusExpired:   true,
+	entities.MessageStatusDeleted:   true,
+}
+
+// messageRepository keeps entities.Message in maps, the messages table and the messages_archive table of the SQL
+// implementation are the messages and archived maps
+type messageRepository struct {
+	mutex    sync.RWMutex
+	messages map[uuid.UUID]entities.Message
+	archived map[uuid.UUID]entities.Message
+}
+
+// NewMessageRepository creates the in-memory version of the repositories.MessageRepository
+func NewMessageRepository() repositories.MessageRepository {
+	return &messageRepository{
+		messages: map[uuid.UUID]entities.Message{},
+		archived: map[uuid.UUID]entities.Message{},
+	}
+}
+
+// Store a new entities.Message
+func (repository *messageRepository) Store(_ context.Context, message *entities.Message) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if _, ok := repository.messages[message.ID]; ok {
+		return stacktrace.Propagate(gorm.ErrDuplicatedKey, fmt.Sprintf("cannot save message with ID [%s]", message.ID))
+	}
+
+	repository.messages[message.ID] = *message
+	return nil
+}
+
+// StoreMany inserts the entities.Message whose ID does not exist
+func (repository *messageRepository) StoreMany(_ context.Context, messages []*entities.Message) ([]repositories.StoreOutcome, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	outcomes := make([]repositories.StoreOutcome, len(messages))
+	for index, message := range messages {
+		outcomes[index] = repositories.StoreOutcomeSkipped
+		if _, ok := repository.messages[message.ID]; !ok {
+			repository.messages[message.ID] = *message
+			outcomes[index] = repositories.StoreOutcomeInserted
+		}
+	}
+
+	return outcomes, nil
+}
+
+// Update an entities.Message if its version has not changed since it was loaded
+func (repository *messageRepository) Update(_ context.Context, message *entities.Message) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	stored, ok := repository.messages[message.ID]
+	if !ok || stored.DeletedAt.Valid {
+		msg := fmt.Sprintf("message with ID [%s] is archived or deleted and cannot be updated", message.ID)
+		return stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+	}
+
+	if stored.Version != message.Version {
+		msg := fmt.Sprintf("message with ID [%s] and version [%d] was updated concurrently", message.ID, message.Version)
+		return stacktrace.NewErrorWithCode(repositories.ErrCodeStaleUpdate, msg)
+	}
+
+	message.Version++
+	message.UpdatedAt = time.Now().UTC()
+	repository.messages[message.ID] = *message
+	return nil
+}
+
+// Load an entities.Message by ID
+func (repository *messageRepository) Load(_ context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	for _, table := range []map[uuid.UUID]entities.Message{repository.messages, repository.archived} {
+		if message, ok := table[messageID]; ok && message.UserID == userID && !message.DeletedAt.Valid {
+			return &message, nil
+		}
+	}
+
+	msg := fmt.Sprintf("message with ID [%s] and userID [%s] does not exist", messageID, userID)
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+}
+
+// LoadLastReceived fetches the latest entities.Message which the owner received from the contact, the archived messages are not included
+func (repository *messageRepository) LoadLastReceived(_ context.Context, userID entities.UserID, owner string, contact string) (*entities.Message, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	var last *entities.Message
+	for _, message := range repository.messages {
+		message := message
+		if message.UserID != userID || message.Owner != owner || message.Contact != contact || message.Type != entities.MessageTypeMobileOriginated || message.DeletedAt.Valid {
+			continue
+		}
+		if last == nil || message.OrderTimestamp.After(last.OrderTimestamp) {
+			last = &message
+		}
+	}
+
+	if last == nil {
+		msg := fmt.Sprintf("owner [%s] has not received a message from contact [%s] for user [%s]", owner, contact, userID)
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+	}
+	return last, nil
+}
+
+// LoadMany loads the entities.Message of a user with the IDs
+func (repository *messageRepository) LoadMany(_ context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]entities.Message, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	messages := make([]entities.Message, 0, len(messageIDs))
+	for _, messageID := range messageIDs {
+		for _, table := range []map[uuid.UUID]entities.Message{repository.messages, repository.archived} {
+			if message, ok := table[messageID]; ok && message.UserID == userID && !message.DeletedAt.Valid {
+				messages = append(messages, message)
+				break
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// Index entities.Message between 2 parties, the archived messages are ordered after the messages which are not archived
+func (repository *messageRepository) Index(_ context.Context, userID entities.UserID, owner string, contact string, params repositories.IndexParams) (*[]entities.Message, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	filter := indexFilter(userID, owner, contact, params)
+	newest := func(a, b entities.Message) bool {
+		return orderedBefore(b, a)
+	}
+
+	messages := repository.find(repository.messages, filter, newest)
+	if params.IncludeArchived {
+		messages = append(messages, repository.find(repository.archived, filter, newest)...)
+	}
+
+	messages = paginate(messages, params.Skip, params.Limit)
+	return &messages, nil
+}
+
+// Count counts the entities.Message between 2 phone numbers which match the filters of Index
+func (repository *messageRepository) Count(_ context.Context, userID entities.UserID, owner string, contact string, params repositories.IndexParams) (int64, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	tables := []map[uuid.UUID]entities.Message{repository.messages}
+	if params.IncludeArchived {
+		tables = append(tables, repository.archived)
+	}
+
+	filter := indexFilter(userID, owner, contact, params)
+
+	var count int64
+	for _, table := range tables {
+		for _, message := range table {
+			if filter(message) {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// GetOutstanding sets the status of a scheduled, pending or expired entities.Message to sending
+func (repository *messageRepository) GetOutstanding(_ context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	message, ok := repository.messages[messageID]
+	if !ok || message.UserID != userID || message.DeletedAt.Valid || message.IsSentByProvider() || !(message.IsScheduled() || message.IsPending() || message.IsExpired()) {
+		msg := fmt.Sprintf("outstanding message with ID [%s] and userID [%s] does not exist", messageID, userID)
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+	}
+
+	message.Status = entities.MessageStatusSending
+	message.UpdatedAt = time.Now().UTC()
+	message.Version++
+	repository.messages[messageID] = message
+	return &message, nil
+}
+
+// IndexPending fetches the mobile terminated entities.Message of an owner which are still to be sent ordered by the OrderTimestamp
+func (repository *messageRepository) IndexPending(_ context.Context, userID entities.UserID, owner string) (*[]entities.Message, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	messages := repository.find(
+		repository.messages,
+		pendingFilter(userID, owner),
+		orderedBefore,
+	)
+
+	return &messages, nil
+}
+
+// CountPending counts the mobile terminated entities.Message of an owner which are still to be sent
+func (repository *messageRepository) CountPending(_ context.Context, userID entities.UserID, owner string) (int64, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	var count int64
+	filter := pendingFilter(userID, owner)
+	for _, message := range repository.messages {
+		if filter(message) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// pendingFilter matches the mobile terminated entities.Message of an owner which are still to be sent like the query of IndexPending
+func pendingFilter(userID entities.UserID, owner string) func(message entities.Message) bool {
+	return func(message entities.Message) bool {
+		return message.UserID == userID &&
+			message.Owner == owner &&
+			message.Type == entities.MessageTypeMobileTerminated &&
+			!message.DeletedAt.Valid &&
+			(message.IsPending() || (message.IsExpired() && message.SendAttemptCount < message.MaxSendAttempts))
+	}
+}
+
+// Fallback moves a pending, scheduled or expired entities.Message from the phone to the channel of a fallback provider
+func (repository *messageRepository) Fallback(_ context.Context, userID entities.UserID, messageID uuid.UUID, channel entities.MessageChannel) (*entities.Message, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	message, ok := repository.messages[messageID]
+	if !ok || message.UserID != userID || message.DeletedAt.Valid || message.IsSentByProvider() || !(message.IsPending() || message.IsScheduled() || message.IsExpired()) {
+		msg := fmt.Sprintf("message with ID [%s] cannot be sent by the phone of user [%s]", messageID, userID)
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+	}
+
+	timestamp := time.Now().UTC()
+	message.Channel = channel
+	message.Status = entities.MessageStatusSending
+	message.LastAttemptedAt = &timestamp
+	message.UpdatedAt = timestamp
+	message.Version++
+	repository.messages[messageID] = message
+
+	return &message, nil
+}
+
+// Failover moves a pending entities.Message from an owner to the failover owner
+func (repository *messageRepository) Failover(_ context.Context, userID entities.UserID, messageID uuid.UUID, owner string, failoverOwner string, sim entities.SIM) (*entities.Message, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	message, ok := repository.messages[messageID]
+	if !ok || message.UserID != userID || message.Owner != owner || message.DeletedAt.Valid || !message.IsPending() {
+		msg := fmt.Sprintf("pending message with ID [%s] and owner [%s] does not exist for user [%s]", messageID, owner, userID)
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+	}
+
+	if message.OriginalOwner == nil {
+		message.OriginalOwner = &owner
+	}
+	message.Owner = failoverOwner
+	message.SIM = sim
+	message.UpdatedAt = time.Now().UTC()
+	message.Version++
+	repository.messages[messageID] = message
+
+	return &message, nil
+}
+
+// CountSendAttemptsSince counts the entities.Message which an owner has attempted to send since a timestamp
+func (repository *messageRepository) CountSendAttemptsSince(_ context.Context, userID entities.UserID, owner string, since time.Time) (int64, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	var count int64
+	for _, message := range repository.messages {
+		if message.UserID != userID || message.Owner != owner || message.Type != entities.MessageTypeMobileTerminated || message.DeletedAt.Valid {
+			continue
+		}
+
+		if (message.LastAttemptedAt != nil && !message.LastAttemptedAt.Before(since)) || (message.IsSending() && !message.UpdatedAt.Before(since)) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// CountByStatus counts the entities.Message of all users with each status
+func (repository *messageRepository) CountByStatus(_ context.Context, statuses []entities.MessageStatus) (map[entities.MessageStatus]int64, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	counts := make(map[entities.MessageStatus]int64, len(statuses))
+	for _, status := range statuses {
+		counts[status] = 0
+	}
+
+	for _, message := range repository.messages {
+		if _, ok := counts[message.Status]; ok && !message.DeletedAt.Valid {
+			counts[message.Status]++
+		}
+	}
+
+	return counts, nil
+}
+
+// Delete a message by the ID
+func (repository *messageRepository) Delete(_ context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.softDelete(func(message entities.Message) bool {
+		return message.UserID == userID && message.ID == messageID
+	})
+	return nil
+}
+
+// DeleteByOwnerAndContact deletes all the messages between and owner and a contact
+func (repository *messageRepository) DeleteByOwnerAndContact(_ context.Context, userID entities.UserID, owner string, contact string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.softDelete(func(message entities.Message) bool {
+		return message.UserID == userID && message.Owner == owner && message.Contact == contact
+	})
+	return nil
+}
+
+// Restore an entities.Message which was soft deleted
+func (repository *messageRepository) Restore(_ context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	message, ok := repository.messages[messageID]
+	if !ok || message.UserID != userID || !message.DeletedAt.Valid {
+		msg := fmt.Sprintf("deleted message with ID [%s] does not exist for user with ID [%s]", messageID, userID)
+		return stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg)
+	}
+
+	message.DeletedAt = gorm.DeletedAt{}
+	message.UpdatedAt = time.Now().UTC()
+	message.Version++
+	repository.messages[messageID] = message
+
+	return nil
+}
+
+// Archive moves up to limit entities.Message which were last ordered before a timestamp into the archive
+func (repository *messageRepository) Archive(_ context.Context, before time.Time, limit int) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	messages := repository.find(
+		repository.messages,
+		func(message entities.Message) bool {
+			return message.OrderTimestamp.Before(before) && archivableMessageStatuses[message.Status] &&
+				(message.ContentExpiresAt == nil || message.IsContentExpired())
+		},
+		orderedBefore,
+	)
+
+	messages = paginate(messages, 0, limit)
+	for _, message := range messages {
+		if _, ok := repository.archived[message.ID]; !ok {
+			repository.archived[message.ID] = message
+		}
+		delete(repository.messages, message.ID)
+	}
+
+	return int64(len(messages)), nil
+}
+
+// IndexArchived fetches up to limit archived entities.Message which were ordered before a timestamp ordered by the ID
+func (repository *messageRepository) IndexArchived(_ context.Context, before time.Time, limit int) ([]entities.Message, error) {
+	repository.mutex.RLock()
+	defer repository.mutex.RUnlock()
+
+	messages := repository.find(
+		repository.archived,
+		func(message entities.Message) bool {
+			return message.OrderTimestamp.Before(before)
+		},
+		func(a, b entities.Message) bool {
+			return bytes.Compare(a.ID[:], b.ID[:]) < 0
+		},
+	)
+
+	return paginate(messages, 0, limit), nil
+}
+
+// PurgeArchived permanently deletes archived entities.Message by ID
+func (repository *messageRepository) PurgeArchived(_ context.Context, messageIDs []uuid.UUID) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	var count int64
+	for _, messageID := range messageIDs {
+		if _, ok := repository.archived[messageID]; ok {
+			delete(repository.archived, messageID)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// DeleteExpired deletes up to limit entities.Message of a user and type which were ordered before a timestamp
+func (repository *messageRepository) DeleteExpired(_ context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	var count int64
+	for _, table := range []map[uuid.UUID]entities.Message{repository.messages, repository.archived} {
+		messages := repository.find(
+			table,
+			func(message entities.Message) bool {
+				return message.UserID == userID &&
+					message.Type == messageType &&
+					message.OrderTimestamp.Before(before) &&
+					archivableMessageStatuses[message.Status] &&
+					(hard || !message.DeletedAt.Valid)
+			},
+			orderedBefore,
+		)
+
+		for _, message := range paginate(messages, 0, limit-int(count)) {
+			if hard {
+				delete(table, message.ID)
+			} else {
+				message.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+				table[message.ID] = message
+			}
+			count++
+		}
+
+		if count >= int64(limit) {
+			break
+		}
+	}
+
+	return count, nil
+}
+
+// ExpireContent replaces the content of up to limit entities.Message whose content expires before a timestamp
+func (repository *messageRepository) ExpireContent(_ context.Context, before time.Time, limit int) ([]uuid.UUID, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	messages := repository.find(
+		repository.messages,
+		func(message entities.Message) bool {
+			return message.ContentExpiresAt != nil && !message.ContentExpiresAt.After(before) && !message.IsContentExpired()
+		},
+		func(a, b entities.Message) bool {
+			return a.ContentExpiresAt.Before(*b.ContentExpiresAt)
+		},
+	)
+
+	ids := make([]uuid.UUID, 0, len(messages))
+	for _, message := range paginate(messages, 0, limit) {
+		message.ExpireContent(before)
+		message.Version++
+		repository.messages[message.ID] = message
+		ids = append(ids, message.ID)
+	}
+
+	return ids, nil
+}
+
+// softDelete sets the DeletedAt of the messages and the archived messages which match the filter
+func (repository *messageRepository) softDelete(filter func(message entities.Message) bool) {
+	now := time.Now().UTC()
+	for _, table := range []map[uuid.UUID]entities.Message{repository.messages, repository.archived} {
+		for id, message := range table {
+			if !message.DeletedAt.Valid && filter(message) {
+				message.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+				table[id] = message
+			}
+		}
+	}
+}
+
+// find returns copies of the messages in a table which match the filter sorted with less
+func (repository *messageRepository) find(table map[uuid.UUID]entities.Message, filter func(message entities.Message) bool, less func(a, b entities.Message) bool) []entities.Message {
+	messages := make([]entities.Message, 0)
+	for _, message := range table {
+		if filter(message) {
+			messages = append(messages, message)
+		}
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return less(messages[i], messages[j])
+	})
+	return messages
+}
+
+// orderedBefore sorts messages by the OrderTimestamp and then by the ID so that messages with the same OrderTimestamp
+// have the same order as in the database
+func orderedBefore(a, b entities.Message) bool {
+	if !a.OrderTimestamp.Equal(b.OrderTimestamp) {
+		return a.OrderTimestamp.Before(b.OrderTimestamp)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+// indexFilter matches the entities.Message between 2 parties like the query of Index
+func indexFilter(userID entities.UserID, owner string, contact string, params repositories.IndexParams) func(message entities.Message) bool {
+	return func(message entities.Message) bool {
+		return message.UserID == userID &&
+			message.Owner == owner &&
+			message.Contact == contact &&
+			(params.IncludeDeleted || !message.DeletedAt.Valid) &&
+			(params.Query == "" || !message.IsEncrypted) &&
+			strings.Contains(strings.ToLower(message.Content), strings.ToLower(params.Query)) &&
+			(params.Status == "" || message.Status == params.Status) &&
+			(params.After == nil || !message.OrderTimestamp.Before(*params.After)) &&
+			(params.Before == nil || message.OrderTimestamp.Before(*params.Before)) &&
+			containsMetadata(message.Metadata, params.Metadata)
+	}
+}
+
+// containsMetadata is true when the metadata of a message has all the key value pairs like the JSON containment query
+func containsMetadata(metadata entities.MessageMetadata, pairs map[string]string) bool {
+	for key, value := range pairs {
+		if actual, ok := metadata[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// paginate applies an offset and a limit like the SQL implementation, a negative limit returns all the messages
+func paginate(messages []entities.Message, skip int, limit int) []entities.Message {
+	if skip < 0 {
+		skip = 0
+	}
+	if skip > len(messages) {
+		skip = len(messages)
+	}
+	messages = messages[skip:]
+
+	if limit >= 0 && limit < len(messages) {
+		messages = messages[:limit]
+	}
+	return messages
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// MessageExportRepository loads and persists an entities.MessageExport
+type MessageExportRepository interface {
+	// Store a new entities.MessageExport
+	Store(ctx context.Context, export *entities.MessageExport) error
+
+	// Update an entities.MessageExport
+	Update(ctx context.Context, export *entities.MessageExport) error
+
+	// Delete an entities.MessageExport whose object was not verified
+	Delete(ctx context.Context, exportID uuid.UUID) error
+
+	// IndexIncomplete fetches the entities.MessageExport whose messages have not been purged ordered by the creation time
+	IndexIncomplete(ctx context.Context) ([]entities.MessageExport, error)
+
+	// IndexByMessageID fetches the entities.MessageExport whose ID range contains a message ID
+	IndexByMessageID(ctx context.Context, messageID uuid.UUID) ([]entities.MessageExport, error)
+}
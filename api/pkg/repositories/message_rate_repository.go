@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// MessageCostParams are the parameters for summarizing the estimated cost of the messages of a user
+type MessageCostParams struct {
+	From      *time.Time
+	To        *time.Time
+	RequestID *string
+	// GroupByRequestID summarizes the cost of each request_id e.g. each campaign instead of all the messages
+	GroupByRequestID bool
+}
+
+// MessageRateRepository loads and persists an entities.MessageRate
+type MessageRateRepository interface {
+	// Store a new entities.MessageRate
+	Store(ctx context.Context, rate *entities.MessageRate) error
+
+	// Update an entities.MessageRate
+	Update(ctx context.Context, rate *entities.MessageRate) error
+
+	// Load an entities.MessageRate by ID
+	Load(ctx context.Context, userID entities.UserID, rateID uuid.UUID) (*entities.MessageRate, error)
+
+	// Fetch all the entities.MessageRate of a user ordered by the effective from time
+	Fetch(ctx context.Context, userID entities.UserID) ([]*entities.MessageRate, error)
+
+	// Delete an entities.MessageRate
+	Delete(ctx context.Context, rate *entities.MessageRate) error
+
+	// SummarizeCosts sums the estimated cost of the outgoing messages of a user in each currency, the messages which
+	// failed or expired and the messages which are archived are not included.
+	SummarizeCosts(ctx context.Context, userID entities.UserID, params MessageCostParams) ([]*entities.MessageCostSummary, error)
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/google/uuid"
@@ -12,21 +13,81 @@ type MessageRepository interface {
 	// Store a new entities.Message
 	Store(ctx context.Context, message *entities.Message) error
 
-	// Update a new entities.Message
+	// StoreMany inserts entities.Message in batches, a message whose ID already exists is skipped.
+	// The StoreOutcome at each index is the result of the message at the same index.
+	StoreMany(ctx context.Context, messages []*entities.Message) ([]StoreOutcome, error)
+
+	// Update an entities.Message, it fails with ErrCodeStaleUpdate when the entities.Message was updated after it was loaded
+	// and with ErrCodeNotFound when the entities.Message is archived or deleted
 	Update(ctx context.Context, message *entities.Message) error
 
 	// Load an entities.Message by ID
 	Load(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error)
 
-	// Index entities.Message between 2 phone numbers
+	// LoadLastReceived fetches the latest mobile originated entities.Message which the owner received from the contact,
+	// it fails with ErrCodeNotFound when the owner has not received a message from the contact
+	LoadLastReceived(ctx context.Context, userID entities.UserID, owner string, contact string) (*entities.Message, error)
+
+	// LoadMany loads the entities.Message of a user with the IDs in a single query, the archived messages are included.
+	// An ID which does not exist or belongs to another user is not an error, it is omitted from the result.
+	LoadMany(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]entities.Message, error)
+
+	// Index entities.Message between 2 phone numbers, soft deleted messages are only included when IndexParams.IncludeDeleted is set.
+	// Encrypted messages are excluded when the content is searched with IndexParams.Query because their content is ciphertext.
 	Index(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (*[]entities.Message, error)
 
+	// Count counts the entities.Message between 2 phone numbers which match the filters of Index, IndexParams.Skip and IndexParams.Limit are ignored
+	Count(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (int64, error)
+
 	// GetOutstanding fetches an entities.Message which is outstanding
 	GetOutstanding(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error)
 
-	// Delete an entities.Message by ID
+	// IndexPending fetches the mobile terminated entities.Message of an owner which are still to be sent ordered by the OrderTimestamp
+	IndexPending(ctx context.Context, userID entities.UserID, owner string) (*[]entities.Message, error)
+
+	// CountPending counts the entities.Message of an owner which are fetched by IndexPending
+	CountPending(ctx context.Context, userID entities.UserID, owner string) (int64, error)
+
+	// Failover moves a pending entities.Message from an owner to the failover owner, it returns ErrCodeNotFound when the message is no longer pending on the owner
+	Failover(ctx context.Context, userID entities.UserID, messageID uuid.UUID, owner string, failoverOwner string, sim entities.SIM) (*entities.Message, error)
+
+	// Fallback moves a pending, scheduled or expired entities.Message from the phone to the channel of a fallback provider and sets
+	// its status to sending, it returns ErrCodeNotFound when the message can no longer be sent by the phone
+	Fallback(ctx context.Context, userID entities.UserID, messageID uuid.UUID, channel entities.MessageChannel) (*entities.Message, error)
+
+	// CountSendAttemptsSince counts the entities.Message which an owner has attempted to send since a timestamp
+	CountSendAttemptsSince(ctx context.Context, userID entities.UserID, owner string, since time.Time) (int64, error)
+
+	// CountByStatus counts the entities.Message of all users with each status, it is used to measure the depth of the queue
+	CountByStatus(ctx context.Context, statuses []entities.MessageStatus) (map[entities.MessageStatus]int64, error)
+
+	// Delete soft deletes an entities.Message by ID
 	Delete(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error
 
-	// DeleteByOwnerAndContact deletes messages between an owner and a contact
+	// DeleteByOwnerAndContact soft deletes messages between an owner and a contact
 	DeleteByOwnerAndContact(ctx context.Context, userID entities.UserID, owner string, contact string) error
+
+	// Archive moves up to limit entities.Message which will not be updated again and were ordered before a timestamp into the archive.
+	// A message whose content has not expired yet is archived after ExpireContent.
+	// An archived entities.Message can still be loaded, it is only returned by Index when IndexParams.IncludeArchived is set
+	Archive(ctx context.Context, before time.Time, limit int) (int64, error)
+
+	// IndexArchived fetches up to limit archived entities.Message which were ordered before a timestamp ordered by the ID, soft deleted messages are included
+	IndexArchived(ctx context.Context, before time.Time, limit int) ([]entities.Message, error)
+
+	// PurgeArchived permanently deletes archived entities.Message by ID
+	PurgeArchived(ctx context.Context, messageIDs []uuid.UUID) (int64, error)
+
+	// DeleteExpired deletes up to limit entities.Message of a user and type which were ordered before a timestamp and
+	// will not be updated again by the phone. The messages are permanently deleted when hard is true.
+	// The archived messages are deleted after the messages in the messages table.
+	DeleteExpired(ctx context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (int64, error)
+
+	// ExpireContent replaces the content of up to limit entities.Message whose content expires before a timestamp with
+	// the entities.MessageContentExpiredMarker, the IDs of the messages are returned. A message whose content has already
+	// expired is skipped so ExpireContent can run again after a failure.
+	ExpireContent(ctx context.Context, before time.Time, limit int) ([]uuid.UUID, error)
+
+	// Restore an entities.Message which was soft deleted, it returns ErrCodeNotFound when the message is not deleted
+	Restore(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error
 }
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// MessageStatisticsParams are the parameters for counting the messages of a user in a time range
+type MessageStatisticsParams struct {
+	From  time.Time
+	To    time.Time
+	Owner *string
+}
+
+// MessageLatencyParams are the parameters for counting the outbound messages of a user for the send SLO
+type MessageLatencyParams struct {
+	From      time.Time
+	To        time.Time
+	Owner     *string
+	Threshold time.Duration
+}
+
+// MessageStatisticsRepository aggregates the entities.Message of a user
+type MessageStatisticsRepository interface {
+	// CountHourly counts the entities.Message of a user which were received by the API in each UTC hour of a time range
+	// by type and status, the messages which are archived are not counted.
+	CountHourly(ctx context.Context, userID entities.UserID, params MessageStatisticsParams) ([]*entities.MessageHourlyCount, error)
+
+	// CountLatency counts the outbound entities.Message of a user which were received by the API in a time range and
+	// were sent, failed or expired, with the percentiles of their send duration.
+	CountLatency(ctx context.Context, userID entities.UserID, params MessageLatencyParams) (*entities.MessageLatencyCounts, error)
+
+	// CountContacts counts the entities.Message of a user which were received by the API in a time range by contact,
+	// the contacts with the most messages are first and only the first limit contacts are returned.
+	CountContacts(ctx context.Context, userID entities.UserID, params MessageStatisticsParams, limit int) ([]*entities.MessageContactCount, error)
+}
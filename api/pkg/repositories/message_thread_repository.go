@@ -8,12 +8,19 @@ import (
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 )
 
+// MessageThreadIndexParams are parameters for fetching message threads
+type MessageThreadIndexParams struct {
+	IndexParams
+	// Label only returns the threads with a contact which has the label when it is not empty
+	Label string
+}
+
 // MessageThreadRepository loads and persists an entities.MessageThread
 type MessageThreadRepository interface {
 	// Store a new entities.MessageThread
 	Store(ctx context.Context, thread *entities.MessageThread) error
 
-	// Update a new entities.MessageThread
+	// Update an entities.MessageThread, it returns ErrCodeNotFound when the thread does not belong to the entities.UserID of the thread
 	Update(ctx context.Context, thread *entities.MessageThread) error
 
 	// LoadByOwnerContact fetches a thread between owner and contact
@@ -23,11 +30,15 @@ type MessageThreadRepository interface {
 	Load(ctx context.Context, userID entities.UserID, ID uuid.UUID) (*entities.MessageThread, error)
 
 	// Index message threads for an owner
-	Index(ctx context.Context, userID entities.UserID, owner string, archived bool, params IndexParams) (*[]entities.MessageThread, error)
+	Index(ctx context.Context, userID entities.UserID, owner string, archived bool, params MessageThreadIndexParams) (*[]entities.MessageThread, error)
 
 	// UpdateAfterDeletedMessage updates a thread after the original message has been deleted
 	UpdateAfterDeletedMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error
 
-	// Delete an entities.MessageThread by ID
+	// ExpireLastMessageContent replaces the content of the last message of the threads whose last message has one of
+	// the IDs with the entities.MessageContentExpiredMarker
+	ExpireLastMessageContent(ctx context.Context, messageIDs []uuid.UUID) error
+
+	// Delete an entities.MessageThread by ID, it returns ErrCodeNotFound when the thread does not belong to the entities.UserID
 	Delete(ctx context.Context, userID entities.UserID, messageThreadID uuid.UUID) error
 }
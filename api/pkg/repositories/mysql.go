@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// mysqlDatetimePrecision stores timestamps with microseconds like postgres
+var mysqlDatetimePrecision = 6
+
+// mysqlDialector creates the columns which have the postgres uuid type as char(36) in MySQL and MariaDB
+type mysqlDialector struct {
+	*mysql.Dialector
+}
+
+// NewMysqlDialector opens the MySQL or MariaDB database in dsn e.g. user:password@tcp(localhost:3306)/httpsms.
+// Timestamps are parsed as UTC and multiple statements are allowed so that a migration can have more than one statement.
+func NewMysqlDialector(dsn string) gorm.Dialector {
+	params := url.Values{}
+	params.Set("parseTime", "true")
+	params.Set("loc", "UTC")
+	params.Set("multiStatements", "true")
+	params.Set("charset", "utf8mb4")
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+
+	return &mysqlDialector{
+		Dialector: mysql.New(mysql.Config{
+			DSN:                      dsn + separator + params.Encode(),
+			DefaultDatetimePrecision: &mysqlDatetimePrecision,
+		}).(*mysql.Dialector),
+	}
+}
+
+// DataTypeOf returns char(36) for the uuid type which does not exist in MySQL and text for the text[] arrays of postgres
+// which are stored in their text representation. A string in a unique index is a varchar(191) because MySQL cannot
+// index a longtext, GORM only shortens the strings in an index which is not declared with uniqueIndex.
+func (dialector mysqlDialector) DataTypeOf(field *schema.Field) string {
+	switch {
+	case strings.EqualFold(string(field.DataType), "uuid"):
+		return "char(36)"
+	case strings.EqualFold(string(field.DataType), "text[]"):
+		return "text"
+	case field.DataType == schema.String && field.Size == 0 && field.TagSettings["UNIQUEINDEX"] != "":
+		return "varchar(191)"
+	}
+	return dialector.Dialector.DataTypeOf(field)
+}
+
+// Migrator creates the migrator of MySQL which uses the DataTypeOf of the mysqlDialector. The indexes are created
+// after the table so that the mysqlMigrator can skip the partial indexes.
+func (dialector mysqlDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	migrator := dialector.Dialector.Migrator(db).(mysql.Migrator)
+	migrator.Migrator.Dialector = dialector
+	migrator.Migrator.CreateIndexAfterCreateTable = true
+	return mysqlMigrator{Migrator: migrator}
+}
+
+// mysqlMigrator does not create the partial indexes of postgres and SQLite e.g. the unique index of the default phone
+// of a user. MySQL creates an index on all the rows without the WHERE condition, a migration creates an equivalent index.
+type mysqlMigrator struct {
+	mysql.Migrator
+}
+
+// CreateIndex creates an index which is not a partial index
+func (migrator mysqlMigrator) CreateIndex(value interface{}, name string) error {
+	partial := false
+	err := migrator.Migrator.Migrator.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if index := stmt.Schema.LookIndex(name); index != nil {
+			partial = index.Where != ""
+		}
+		return nil
+	})
+	if err != nil || partial {
+		return err
+	}
+	return migrator.Migrator.CreateIndex(value, name)
+}
+
+// updateReturning applies updates to the entities.Message which matches query and loads the updated message.
+// MySQL does not support UPDATE ... RETURNING so the row is locked with SELECT ... FOR UPDATE, updated and loaded again.
+// It must be called in a transaction.
+func updateReturning(tx *gorm.DB, message *entities.Message, query func(tx *gorm.DB) *gorm.DB, updates map[string]any) (int64, error) {
+	if tx.Dialector.Name() != dialectMysql {
+		result := query(tx.Model(message).Clauses(clause.Returning{})).Updates(updates)
+		return result.RowsAffected, result.Error
+	}
+
+	err := query(tx.Clauses(clause.Locking{Strength: "UPDATE"})).Take(message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	result := tx.Model(&entities.Message{}).Where("id = ?", message.ID).Updates(updates)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, tx.Where("id = ?", message.ID).Take(message).Error
+}
@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// NotificationDigestRepository collects the messages which fail or expire into an entities.NotificationDigest.
+// The window of a phone is opened and closed with conditional updates so that only one instance sends a digest.
+type NotificationDigestRepository interface {
+	// StoreMessage stores an entities.NotificationDigestMessage, a message which is already stored is ignored
+	StoreMessage(ctx context.Context, message *entities.NotificationDigestMessage) error
+
+	// Open the window of a phone with the windowID when the phone has no open window or the open window was due before
+	// staleBefore, it returns false when a window is already open
+	Open(ctx context.Context, userID entities.UserID, owner string, windowID uuid.UUID, sendAt time.Time, staleBefore time.Time) (bool, error)
+
+	// Postpone changes the time at which the open window with the windowID is sent
+	Postpone(ctx context.Context, userID entities.UserID, owner string, windowID uuid.UUID, sendAt time.Time) error
+
+	// Close the open window with the windowID and return the messages which are not in a digest as the digest with the digestID.
+	// It returns ErrCodeNotFound when the window is not open.
+	Close(ctx context.Context, userID entities.UserID, owner string, windowID uuid.UUID, digestID uuid.UUID) ([]*entities.NotificationDigestMessage, error)
+}
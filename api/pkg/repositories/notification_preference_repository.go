@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// NotificationPreferenceRepository loads and persists an entities.NotificationPreference
+type NotificationPreferenceRepository interface {
+	// Save Upsert the entities.NotificationPreference of a user
+	Save(ctx context.Context, preference *entities.NotificationPreference) error
+
+	// Load the entities.NotificationPreference of a user
+	Load(ctx context.Context, userID entities.UserID) (*entities.NotificationPreference, error)
+
+	// Delete the entities.NotificationPreference of a user
+	Delete(ctx context.Context, userID entities.UserID) error
+}
@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// NumberLookupRepository caches the entities.NumberLookup of phone numbers
+type NumberLookupRepository interface {
+	// Save upserts the entities.NumberLookup of a phone number
+	Save(ctx context.Context, lookup *entities.NumberLookup) error
+
+	// Load the entities.NumberLookup of a phone number, an expired lookup is loaded as well
+	Load(ctx context.Context, phoneNumber string) (*entities.NumberLookup, error)
+}
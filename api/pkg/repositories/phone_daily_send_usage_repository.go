@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// PhoneDailySendUsageRepository loads and persists an entities.PhoneDailySendUsage
+type PhoneDailySendUsageRepository interface {
+	// Reserve increments the sent messages of a phone in a day by 1 if the total does not exceed the limit, it returns false when the limit is reached
+	Reserve(ctx context.Context, userID entities.UserID, phoneID uuid.UUID, day time.Time, limit uint) (bool, error)
+
+	// Release decrements the sent messages of a phone in a day by 1 e.g. when a message which was reserved is not sent
+	Release(ctx context.Context, phoneID uuid.UUID, day time.Time) error
+
+	// MarkExhausted sets the entities.PhoneDailySendUsage of a day as exhausted, it returns false when it was already exhausted
+	MarkExhausted(ctx context.Context, phoneID uuid.UUID, day time.Time, timestamp time.Time) (bool, error)
+
+	// Load the entities.PhoneDailySendUsage of a phone in a day
+	Load(ctx context.Context, phoneID uuid.UUID, day time.Time) (*entities.PhoneDailySendUsage, error)
+}
@@ -19,9 +19,32 @@ type PhoneRepository interface {
 	// Load a phone by user and phone number
 	Load(ctx context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error)
 
+	// LoadOwner loads a phone by user and phone number in a single query without the entities.PhoneSIM
+	LoadOwner(ctx context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error)
+
 	// LoadByID a phone by ID
 	LoadByID(ctx context.Context, userID entities.UserID, phoneID uuid.UUID) (*entities.Phone, error)
 
-	// Delete an entities.Phone
-	Delete(ctx context.Context, userID entities.UserID, phoneID uuid.UUID) error
+	// LoadDefault loads the default entities.Phone of a user
+	LoadDefault(ctx context.Context, userID entities.UserID) (*entities.Phone, error)
+
+	// SetDefault makes an entities.Phone the default phone of a user and unsets the previous default
+	SetDefault(ctx context.Context, userID entities.UserID, phoneID uuid.UUID) error
+
+	// UpdateBattery updates the battery state of an entities.Phone
+	UpdateBattery(ctx context.Context, phone *entities.Phone) error
+
+	// UpdateClockSkew updates the clock skew of an entities.Phone
+	UpdateClockSkew(ctx context.Context, phone *entities.Phone) error
+
+	// SaveSIMs replaces the entities.PhoneSIM of an entities.Phone
+	SaveSIMs(ctx context.Context, phone *entities.Phone, sims []entities.PhoneSIM) error
+
+	// UpdateAppVersion updates the version of the android app on an entities.Phone
+	UpdateAppVersion(ctx context.Context, userID entities.UserID, phoneNumber string, version string) error
+
+	// Delete an entities.Phone together with its heartbeats, heartbeat monitor and notifications in a single transaction.
+	// When cancelPending is true, the outstanding entities.Message of the phone are marked as failed.
+	// It returns the number of messages which were cancelled.
+	Delete(ctx context.Context, phone *entities.Phone, cancelPending bool) (int64, error)
 }
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+const contextKeyPrimary = contextKey("repositories.primary")
+
+// WithPrimary returns a context whose read-only queries are sent to the primary database instead of the replica.
+// Use it to read rows which were just written because the replica lags behind the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyPrimary, true)
+}
+
+// replicaFromContext returns the replica for a read-only query. The primary db is returned when there is no replica,
+// when the context has a transaction or when the context was created with WithPrimary.
+func replicaFromContext(ctx context.Context, db *gorm.DB, replica *gorm.DB) *gorm.DB {
+	if primary, _ := ctx.Value(contextKeyPrimary).(bool); primary || replica == nil {
+		return dbFromContext(ctx, db)
+	}
+
+	if _, ok := ctx.Value(contextKeyTransaction).(*gorm.DB); ok {
+		return dbFromContext(ctx, db)
+	}
+
+	return replica.WithContext(ctx)
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// openTestDatabase opens a new SQLite database so the primary and the replica have different rows
+func openTestDatabase(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(NewSqliteDialector(filepath.Join(t.TempDir(), name)), &gorm.Config{TranslateError: true})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&entities.Message{}, &GormArchivedMessage{}, &entities.MessageThread{}))
+	return db
+}
+
+func TestGormMessageRepository_Replica(t *testing.T) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	primary := openTestDatabase(t, "primary.db")
+	replica := openTestDatabase(t, "replica.db")
+	repository := NewGormMessageRepository(logger, tracer, primary, replica)
+
+	message := newTestMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+	assert.Nil(t, repository.Store(context.Background(), message))
+
+	t.Run("index reads from the replica", func(t *testing.T) {
+		// Act
+		messages, err := repository.Index(context.Background(), message.UserID, message.Owner, message.Contact, IndexParams{Limit: 10})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, *messages)
+	})
+
+	t.Run("index reads from the primary with WithPrimary", func(t *testing.T) {
+		// Act
+		messages, err := repository.Index(WithPrimary(context.Background()), message.UserID, message.Owner, message.Contact, IndexParams{Limit: 10})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, *messages, 1)
+	})
+
+	t.Run("index reads from the primary in a transaction", func(t *testing.T) {
+		// Arrange
+		var messages *[]entities.Message
+
+		// Act
+		err := NewGormTransactor(primary).Execute(context.Background(), func(ctx context.Context) (err error) {
+			messages, err = repository.Index(ctx, message.UserID, message.Owner, message.Contact, IndexParams{Limit: 10})
+			return err
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, *messages, 1)
+	})
+
+	t.Run("load reads from the primary", func(t *testing.T) {
+		// Act
+		loaded, err := repository.Load(context.Background(), message.UserID, message.ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, message.ID, loaded.ID)
+	})
+}
+
+func TestGormMessageThreadRepository_Replica(t *testing.T) {
+	t.Run("index reads from the replica", func(t *testing.T) {
+		// Setup
+		zl := zerolog.Nop()
+		logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+		tracer := telemetry.NewOtelLogger("test", logger)
+
+		primary := openTestDatabase(t, "primary.db")
+		replica := openTestDatabase(t, "replica.db")
+		repository := NewGormMessageThreadRepository(logger, tracer, primary, replica)
+
+		// Arrange
+		thread := &entities.MessageThread{
+			ID:             uuid.New(),
+			Owner:          "+18005550199",
+			Contact:        "+18005550100",
+			UserID:         entities.UserID(uuid.NewString()),
+			OrderTimestamp: time.Now().UTC(),
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+		}
+		assert.Nil(t, repository.Store(context.Background(), thread))
+
+		// Act
+		fromReplica, replicaErr := repository.Index(context.Background(), thread.UserID, thread.Owner, false, MessageThreadIndexParams{IndexParams: IndexParams{Limit: 10}})
+		fromPrimary, primaryErr := repository.Index(WithPrimary(context.Background()), thread.UserID, thread.Owner, false, MessageThreadIndexParams{IndexParams: IndexParams{Limit: 10}})
+
+		// Assert
+		assert.Nil(t, replicaErr)
+		assert.Empty(t, *fromReplica)
+		assert.Nil(t, primaryErr)
+		assert.Len(t, *fromPrimary, 1)
+	})
+}
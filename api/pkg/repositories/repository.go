@@ -3,6 +3,7 @@ package repositories
 import (
 	"time"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/palantir/stacktrace"
 )
 
@@ -11,11 +12,46 @@ type IndexParams struct {
 	Skip  int    `json:"skip"`
 	Query string `json:"query"`
 	Limit int    `json:"take"`
+
+	// IncludeDeleted also returns the soft deleted entities, it is used by administrators and to restore deleted entities
+	IncludeDeleted bool `json:"include_deleted"`
+
+	// IncludeArchived also returns the entities which have been moved into an archive table
+	IncludeArchived bool `json:"include_archived"`
+
+	// Status only returns the entities.Message with the status, it is only applied by the MessageRepository
+	Status entities.MessageStatus `json:"status"`
+
+	// After only returns the entities.Message with an order timestamp at or after the time, it is only applied by the MessageRepository
+	After *time.Time `json:"after"`
+
+	// Before only returns the entities.Message with an order timestamp before the time, it is only applied by the MessageRepository
+	Before *time.Time `json:"before"`
+
+	// Metadata only returns the entities.Message whose metadata contains all the key value pairs, it is only applied by the MessageRepository
+	Metadata map[string]string `json:"metadata"`
 }
 
+// StoreOutcome is the result of storing one entity with a bulk insert
+type StoreOutcome string
+
+const (
+	// StoreOutcomeInserted means that the entity was inserted
+	StoreOutcomeInserted = StoreOutcome("inserted")
+
+	// StoreOutcomeSkipped means that the entity was not inserted because an entity with the same ID already exists
+	StoreOutcomeSkipped = StoreOutcome("skipped")
+)
+
 const (
 	// ErrCodeNotFound is thrown when an entity does not exist in storage
 	ErrCodeNotFound = stacktrace.ErrorCode(1000)
 
+	// ErrCodeUserSuspended is thrown when the entities.User of an API key has been suspended
+	ErrCodeUserSuspended = stacktrace.ErrorCode(1001)
+
+	// ErrCodeStaleUpdate is thrown when an entity cannot be updated because it was changed after it was loaded
+	ErrCodeStaleUpdate = stacktrace.ErrorCode(1002)
+
 	dbOperationDuration = 5 * time.Second
 )
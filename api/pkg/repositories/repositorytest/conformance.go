@@ -0,0 +1,854 @@
+// Package repositorytest provides the conformance tests which every implementation of a repository must pass so
+// that the SQL and in-memory implementations cannot diverge.
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+// bulkSize is more than one batch of the SQL implementation of repositories.MessageRepository.StoreMany
+const bulkSize = 1001
+
+func newMessage(userID entities.UserID, content string, timestamp time.Time) *entities.Message {
+	return &entities.Message{
+		ID:                uuid.New(),
+		Owner:             "+18005550199",
+		Contact:           "+18005550100",
+		UserID:            userID,
+		Content:           content,
+		Type:              entities.MessageTypeMobileTerminated,
+		Status:            entities.MessageStatusPending,
+		RequestReceivedAt: timestamp,
+		OrderTimestamp:    timestamp,
+		CreatedAt:         timestamp,
+		UpdatedAt:         timestamp,
+	}
+}
+
+// MessageRepository runs the conformance tests of repositories.MessageRepository against the repository returned
+// by newRepository. The tests use a new user for every subtest so the repository can be shared.
+func MessageRepository(t *testing.T, newRepository func() repositories.MessageRepository) {
+	t.Run("stored message can be loaded", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+
+		// Act
+		storeErr := repository.Store(ctx, message)
+		loaded, loadErr := repository.Load(ctx, message.UserID, message.ID)
+
+		// Assert
+		assert.Nil(t, storeErr)
+		assert.Nil(t, loadErr)
+		assert.Equal(t, message.ID, loaded.ID)
+		assert.Equal(t, message.Content, loaded.Content)
+	})
+
+	t.Run("missing message is not found", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+
+		// Act
+		_, missingErr := repository.Load(ctx, message.UserID, uuid.New())
+		_, otherUserErr := repository.Load(ctx, entities.UserID(uuid.NewString()), message.ID)
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(missingErr))
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(otherUserErr))
+	})
+
+	t.Run("last received message is the latest message from the contact", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		timestamp := time.Now().UTC()
+
+		received := func(content string, contact string, timestamp time.Time) *entities.Message {
+			message := newMessage(userID, content, timestamp)
+			message.Type = entities.MessageTypeMobileOriginated
+			message.Status = entities.MessageStatusReceived
+			message.Contact = contact
+			return message
+		}
+		older := received("older message", "+18005550100", timestamp.Add(-time.Hour))
+		latest := received("latest message", "+18005550100", timestamp.Add(-time.Minute))
+		otherContact := received("message of another contact", "+18005550111", timestamp)
+		sent := newMessage(userID, "sent message", timestamp)
+		for _, message := range []*entities.Message{older, latest, otherContact, sent} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+
+		// Act
+		loaded, loadErr := repository.LoadLastReceived(ctx, userID, latest.Owner, latest.Contact)
+		_, missingErr := repository.LoadLastReceived(ctx, userID, latest.Owner, "+18005550122")
+		_, otherUserErr := repository.LoadLastReceived(ctx, entities.UserID(uuid.NewString()), latest.Owner, latest.Contact)
+
+		// Assert
+		assert.Nil(t, loadErr)
+		assert.Equal(t, latest.ID, loaded.ID)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(missingErr))
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(otherUserErr))
+	})
+
+	t.Run("many messages are loaded with the archived messages of the user", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		old := newMessage(userID, "archived message", time.Now().UTC().Add(-365*24*time.Hour))
+		old.Status = entities.MessageStatusDelivered
+		recent := newMessage(userID, "recent message", time.Now().UTC())
+		deleted := newMessage(userID, "deleted message", time.Now().UTC())
+		other := newMessage(entities.UserID(uuid.NewString()), "message of another user", time.Now().UTC())
+		for _, message := range []*entities.Message{old, recent, deleted, other} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+		assert.Nil(t, repository.Delete(ctx, userID, deleted.ID))
+		_, err := repository.Archive(ctx, time.Now().UTC().Add(-24*time.Hour), 1000)
+		assert.Nil(t, err)
+
+		// Act
+		messages, loadErr := repository.LoadMany(ctx, userID, []uuid.UUID{recent.ID, old.ID, deleted.ID, other.ID, uuid.New()})
+		empty, emptyErr := repository.LoadMany(ctx, userID, nil)
+
+		// Assert
+		assert.Nil(t, loadErr)
+		var ids []uuid.UUID
+		for _, message := range messages {
+			ids = append(ids, message.ID)
+		}
+		assert.ElementsMatch(t, []uuid.UUID{recent.ID, old.ID}, ids)
+		assert.Nil(t, emptyErr)
+		assert.Empty(t, empty)
+	})
+
+	t.Run("bulk insert skips existing messages", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		existing := newMessage(userID, "existing message", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, existing))
+
+		messages := []*entities.Message{existing}
+		for i := 0; i < bulkSize; i++ {
+			messages = append(messages, newMessage(userID, fmt.Sprintf("message %d", i), time.Now().UTC()))
+		}
+		duplicate := *messages[1]
+		messages = append(messages, &duplicate)
+
+		// Act
+		outcomes, err := repository.StoreMany(ctx, messages)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, outcomes, len(messages))
+		assert.Equal(t, repositories.StoreOutcomeSkipped, outcomes[0])
+		assert.Equal(t, repositories.StoreOutcomeInserted, outcomes[1])
+		assert.Equal(t, repositories.StoreOutcomeSkipped, outcomes[len(outcomes)-1])
+
+		stored, err := repository.Index(ctx, userID, existing.Owner, existing.Contact, repositories.IndexParams{Limit: 2 * bulkSize})
+		assert.Nil(t, err)
+		assert.Len(t, *stored, bulkSize+1)
+	})
+
+	t.Run("stale update is rejected", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+
+		stale := *message
+		assert.Nil(t, repository.Update(ctx, message))
+
+		// Act
+		err := repository.Update(ctx, &stale)
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeStaleUpdate, stacktrace.GetCode(err))
+		assert.Equal(t, message.Version-1, stale.Version)
+	})
+
+	t.Run("archived message cannot be updated", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC().Add(-365*24*time.Hour))
+		message.Status = entities.MessageStatusDelivered
+		assert.Nil(t, repository.Store(ctx, message))
+		_, err := repository.Archive(ctx, time.Now().UTC().Add(-24*time.Hour), 1000)
+		assert.Nil(t, err)
+
+		archived, err := repository.Load(ctx, message.UserID, message.ID)
+		assert.Nil(t, err)
+
+		// Act
+		err = repository.Update(ctx, archived)
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+		assert.Equal(t, message.Version, archived.Version)
+	})
+
+	t.Run("index is ordered by the order timestamp", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		timestamp := time.Now().UTC()
+		for i := 0; i < 5; i++ {
+			assert.Nil(t, repository.Store(ctx, newMessage(userID, fmt.Sprintf("message %d", i), timestamp.Add(time.Duration(i)*time.Minute))))
+		}
+
+		// Act
+		messages, err := repository.Index(ctx, userID, "+18005550199", "+18005550100", repositories.IndexParams{Skip: 1, Limit: 3})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, *messages, 3)
+		for i, message := range *messages {
+			assert.Equal(t, fmt.Sprintf("message %d", 3-i), message.Content)
+		}
+	})
+
+	t.Run("messages with the same order timestamp are ordered by the ID", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		timestamp := time.Now().UTC().Truncate(time.Millisecond)
+		stored := make([]*entities.Message, 0, 100)
+		for i := 0; i < 100; i++ {
+			stored = append(stored, newMessage(userID, fmt.Sprintf("message %d", i), timestamp))
+		}
+		_, storeErr := repository.StoreMany(ctx, stored)
+
+		indexPages := func() []uuid.UUID {
+			var ids []uuid.UUID
+			for skip := 0; skip < len(stored); skip += 10 {
+				messages, err := repository.Index(ctx, userID, "+18005550199", "+18005550100", repositories.IndexParams{Skip: skip, Limit: 10})
+				assert.Nil(t, err)
+				for _, message := range *messages {
+					ids = append(ids, message.ID)
+				}
+			}
+			return ids
+		}
+
+		// Act
+		first := indexPages()
+		second := indexPages()
+		pending, pendingErr := repository.IndexPending(ctx, userID, "+18005550199")
+
+		// Assert
+		assert.Nil(t, storeErr)
+		assert.Nil(t, pendingErr)
+		assert.Len(t, first, len(stored))
+		assert.Len(t, *pending, len(stored))
+		assert.Equal(t, first, second)
+
+		unique := map[uuid.UUID]bool{}
+		for i, id := range first {
+			unique[id] = true
+			if i > 0 {
+				assert.Greater(t, first[i-1].String(), id.String())
+			}
+			if i < len(*pending) {
+				assert.Equal(t, id, (*pending)[len(*pending)-1-i].ID)
+			}
+		}
+		assert.Len(t, unique, len(stored))
+	})
+
+	t.Run("index search is case insensitive", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "Hello World", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+		assert.Nil(t, repository.Store(ctx, newMessage(message.UserID, "good bye", time.Now().UTC())))
+
+		// Act
+		messages, err := repository.Index(ctx, message.UserID, message.Owner, message.Contact, repositories.IndexParams{Limit: 10, Query: "hello"})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, *messages, 1)
+	})
+
+	t.Run("index search excludes encrypted messages", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		encrypted := newMessage(message.UserID, "aGVsbG8gd29ybGQ=", time.Now().UTC())
+		encrypted.IsEncrypted = true
+		assert.Nil(t, repository.Store(ctx, message))
+		assert.Nil(t, repository.Store(ctx, encrypted))
+
+		// Act
+		searched, searchErr := repository.Index(ctx, message.UserID, message.Owner, message.Contact, repositories.IndexParams{Limit: 10, Query: "d2"})
+		count, countErr := repository.Count(ctx, message.UserID, message.Owner, message.Contact, repositories.IndexParams{Query: "d2"})
+		all, indexErr := repository.Index(ctx, message.UserID, message.Owner, message.Contact, repositories.IndexParams{Limit: 10})
+
+		// Assert
+		assert.Nil(t, searchErr)
+		assert.Len(t, *searched, 0)
+		assert.Nil(t, countErr)
+		assert.Equal(t, int64(0), count)
+		assert.Nil(t, indexErr)
+		assert.Len(t, *all, 2)
+	})
+
+	t.Run("deleted message can be restored", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+
+		// Act
+		deleteErr := repository.Delete(ctx, message.UserID, message.ID)
+		_, loadErr := repository.Load(ctx, message.UserID, message.ID)
+		live, liveErr := repository.Index(ctx, message.UserID, message.Owner, message.Contact, repositories.IndexParams{Limit: 10})
+		deleted, indexErr := repository.Index(ctx, message.UserID, message.Owner, message.Contact, repositories.IndexParams{Limit: 10, IncludeDeleted: true})
+		restoreErr := repository.Restore(ctx, message.UserID, message.ID)
+		secondRestoreErr := repository.Restore(ctx, message.UserID, message.ID)
+		_, restoredErr := repository.Load(ctx, message.UserID, message.ID)
+
+		// Assert
+		assert.Nil(t, deleteErr)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(loadErr))
+		assert.Nil(t, liveErr)
+		assert.Len(t, *live, 0)
+		assert.Nil(t, indexErr)
+		assert.Len(t, *deleted, 1)
+		assert.Nil(t, restoreErr)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(secondRestoreErr))
+		assert.Nil(t, restoredErr)
+	})
+
+	t.Run("outstanding message is returned once", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+
+		// Act
+		outstanding, firstErr := repository.GetOutstanding(ctx, message.UserID, message.ID)
+		_, secondErr := repository.GetOutstanding(ctx, message.UserID, message.ID)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Equal(t, entities.MessageStatusSending, outstanding.Status)
+		assert.Equal(t, message.Content, outstanding.Content)
+		assert.Equal(t, message.Version+1, outstanding.Version)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(secondErr))
+	})
+
+	t.Run("pending messages are ordered by the order timestamp", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		later := newMessage(userID, "later", time.Now().UTC())
+		earlier := newMessage(userID, "earlier", later.OrderTimestamp.Add(-time.Minute))
+		sent := newMessage(userID, "sent", later.OrderTimestamp.Add(-time.Hour))
+		sent.Status = entities.MessageStatusSent
+		for _, message := range []*entities.Message{later, earlier, sent} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+
+		// Act
+		messages, err := repository.IndexPending(ctx, userID, later.Owner)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, *messages, 2)
+		assert.Equal(t, earlier.ID, (*messages)[0].ID)
+		assert.Equal(t, later.ID, (*messages)[1].ID)
+	})
+
+	t.Run("messages are counted by status", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		statuses := []entities.MessageStatus{entities.MessageStatusPending, entities.MessageStatusSending}
+		before, err := repository.CountByStatus(ctx, statuses)
+		assert.Nil(t, err)
+
+		userID := entities.UserID(uuid.NewString())
+		sent := newMessage(userID, "sent", time.Now().UTC())
+		sent.Status = entities.MessageStatusSent
+		deleted := newMessage(userID, "deleted", time.Now().UTC())
+		for _, message := range []*entities.Message{newMessage(userID, "first", time.Now().UTC()), newMessage(userID, "second", time.Now().UTC()), sent, deleted} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+		assert.Nil(t, repository.Delete(ctx, userID, deleted.ID))
+
+		// Act
+		after, err := repository.CountByStatus(ctx, statuses)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, after, 2)
+		assert.Equal(t, before[entities.MessageStatusPending]+2, after[entities.MessageStatusPending])
+		assert.Equal(t, before[entities.MessageStatusSending], after[entities.MessageStatusSending])
+	})
+
+	t.Run("pending message is moved to the failover owner", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+
+		// Act
+		moved, err := repository.Failover(ctx, message.UserID, message.ID, message.Owner, "+18005550198", entities.SIM1)
+		_, secondErr := repository.Failover(ctx, message.UserID, message.ID, message.Owner, "+18005550198", entities.SIM1)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550198", moved.Owner)
+		assert.Equal(t, message.Owner, *moved.OriginalOwner)
+		assert.Equal(t, message.Version+1, moved.Version)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(secondErr))
+	})
+
+	t.Run("pending message is moved to a fallback channel once", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, message))
+
+		// Act
+		moved, err := repository.Fallback(ctx, message.UserID, message.ID, entities.MessageChannel("twilio"))
+		_, secondErr := repository.Fallback(ctx, message.UserID, message.ID, entities.MessageChannel("twilio"))
+		_, outstandingErr := repository.GetOutstanding(ctx, message.UserID, message.ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageChannel("twilio"), moved.Channel)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusSending), moved.Status)
+		assert.NotNil(t, moved.LastAttemptedAt)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(secondErr))
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(outstandingErr))
+	})
+
+	t.Run("archived message can be loaded and searched", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		old := newMessage(userID, "Archived message", time.Now().UTC().Add(-365*24*time.Hour))
+		old.Status = entities.MessageStatusDelivered
+		recent := newMessage(userID, "recent message", time.Now().UTC())
+		assert.Nil(t, repository.Store(ctx, old))
+		assert.Nil(t, repository.Store(ctx, recent))
+
+		// Act
+		_, archiveErr := repository.Archive(ctx, time.Now().UTC().Add(-24*time.Hour), 1000)
+		loaded, loadErr := repository.Load(ctx, userID, old.ID)
+		live, liveErr := repository.Index(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{Limit: 10})
+		archived, archivedErr := repository.Index(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{Limit: 10, Query: "archived", IncludeArchived: true})
+
+		// Assert
+		assert.Nil(t, archiveErr)
+		assert.Nil(t, loadErr)
+		assert.Equal(t, old.Content, loaded.Content)
+		assert.Nil(t, liveErr)
+		assert.Len(t, *live, 1)
+		assert.Nil(t, archivedErr)
+		assert.Len(t, *archived, 1)
+	})
+
+	t.Run("count ignores the pagination and includes the archived messages when requested", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		old := newMessage(userID, "Archived message", time.Now().UTC().Add(-365*24*time.Hour))
+		old.Status = entities.MessageStatusDelivered
+		assert.Nil(t, repository.Store(ctx, old))
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, repository.Store(ctx, newMessage(userID, fmt.Sprintf("message %d", i), time.Now().UTC())))
+		}
+		_, err := repository.Archive(ctx, time.Now().UTC().Add(-24*time.Hour), 1000)
+		assert.Nil(t, err)
+
+		// Act
+		live, liveErr := repository.Count(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{Skip: 1, Limit: 1})
+		all, allErr := repository.Count(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{Limit: 1, IncludeArchived: true})
+		searched, searchedErr := repository.Count(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{Query: "ARCHIVED", IncludeArchived: true})
+
+		// Assert
+		assert.Nil(t, liveErr)
+		assert.Equal(t, int64(3), live)
+		assert.Nil(t, allErr)
+		assert.Equal(t, int64(4), all)
+		assert.Nil(t, searchedErr)
+		assert.Equal(t, int64(1), searched)
+	})
+
+	t.Run("index combines the metadata filter with the status and time filters", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		now := time.Now().UTC().Truncate(time.Second)
+
+		match := newMessage(userID, "matching message", now.Add(-2*time.Hour))
+		match.Status = entities.MessageStatusDelivered
+		match.Metadata = entities.MessageMetadata{"order_id": "42", "channel": "web"}
+
+		otherStatus := newMessage(userID, "failed message", now.Add(-2*time.Hour))
+		otherStatus.Status = entities.MessageStatusFailed
+		otherStatus.Metadata = entities.MessageMetadata{"order_id": "42"}
+
+		tooOld := newMessage(userID, "old message", now.Add(-48*time.Hour))
+		tooOld.Status = entities.MessageStatusDelivered
+		tooOld.Metadata = entities.MessageMetadata{"order_id": "42"}
+
+		otherOrder := newMessage(userID, "other order", now.Add(-2*time.Hour))
+		otherOrder.Status = entities.MessageStatusDelivered
+		otherOrder.Metadata = entities.MessageMetadata{"order_id": "420"}
+
+		withoutMetadata := newMessage(userID, "without metadata", now.Add(-2*time.Hour))
+		withoutMetadata.Status = entities.MessageStatusDelivered
+
+		for _, message := range []*entities.Message{match, otherStatus, tooOld, otherOrder, withoutMetadata} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+
+		after, before := now.Add(-24*time.Hour), now
+		params := repositories.IndexParams{
+			Limit:    10,
+			Status:   entities.MessageStatusDelivered,
+			After:    &after,
+			Before:   &before,
+			Metadata: map[string]string{"order_id": "42"},
+		}
+
+		// Act
+		messages, indexErr := repository.Index(ctx, userID, match.Owner, match.Contact, params)
+		count, countErr := repository.Count(ctx, userID, match.Owner, match.Contact, params)
+		byMetadata, metadataErr := repository.Index(ctx, userID, match.Owner, match.Contact, repositories.IndexParams{Limit: 10, Metadata: map[string]string{"order_id": "42"}})
+		byPairs, pairsErr := repository.Index(ctx, userID, match.Owner, match.Contact, repositories.IndexParams{Limit: 10, Metadata: map[string]string{"order_id": "42", "channel": "sms"}})
+
+		// Assert
+		assert.Nil(t, indexErr)
+		assert.Len(t, *messages, 1)
+		assert.Equal(t, match.ID, (*messages)[0].ID)
+		assert.Nil(t, countErr)
+		assert.Equal(t, int64(1), count)
+		assert.Nil(t, metadataErr)
+		assert.Len(t, *byMetadata, 3)
+		assert.Nil(t, pairsErr)
+		assert.Empty(t, *byPairs)
+	})
+
+	t.Run("archived messages are filtered by metadata and status", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		old := newMessage(userID, "Archived message", time.Now().UTC().Add(-365*24*time.Hour))
+		old.Status = entities.MessageStatusDelivered
+		old.Metadata = entities.MessageMetadata{"order_id": "42"}
+		assert.Nil(t, repository.Store(ctx, old))
+		_, err := repository.Archive(ctx, time.Now().UTC().Add(-24*time.Hour), 1000)
+		assert.Nil(t, err)
+
+		// Act
+		archived, archivedErr := repository.Index(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{Limit: 10, IncludeArchived: true, Status: entities.MessageStatusDelivered, Metadata: map[string]string{"order_id": "42"}})
+		count, countErr := repository.Count(ctx, userID, old.Owner, old.Contact, repositories.IndexParams{IncludeArchived: true, Status: entities.MessageStatusFailed, Metadata: map[string]string{"order_id": "42"}})
+
+		// Assert
+		assert.Nil(t, archivedErr)
+		assert.Len(t, *archived, 1)
+		assert.Equal(t, old.Metadata, (*archived)[0].Metadata)
+		assert.Nil(t, countErr)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("purged archived message is not found", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		message := newMessage(entities.UserID(uuid.NewString()), "hello world", time.Now().UTC().Add(-365*24*time.Hour))
+		message.Status = entities.MessageStatusDelivered
+		assert.Nil(t, repository.Store(ctx, message))
+		_, err := repository.Archive(ctx, time.Now().UTC().Add(-24*time.Hour), 1000)
+		assert.Nil(t, err)
+
+		// Act
+		count, purgeErr := repository.PurgeArchived(ctx, []uuid.UUID{message.ID})
+		_, loadErr := repository.Load(ctx, message.UserID, message.ID)
+
+		// Assert
+		assert.Nil(t, purgeErr)
+		assert.Equal(t, int64(1), count)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(loadErr))
+	})
+
+	t.Run("expired messages are deleted", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		old := time.Now().UTC().Add(-60 * 24 * time.Hour)
+
+		delivered := newMessage(userID, "delivered", old)
+		delivered.Status = entities.MessageStatusDelivered
+		pending := newMessage(userID, "pending", old)
+		archived := newMessage(userID, "archived", old.Add(-24*time.Hour))
+		archived.Status = entities.MessageStatusFailed
+		received := newMessage(userID, "received", old)
+		received.Type = entities.MessageTypeMobileOriginated
+		received.Status = entities.MessageStatusReceived
+		recent := newMessage(userID, "recent", time.Now().UTC())
+		recent.Status = entities.MessageStatusDelivered
+
+		for _, message := range []*entities.Message{delivered, pending, archived, received, recent} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+		_, err := repository.Archive(ctx, old, 1000)
+		assert.Nil(t, err)
+
+		// Act
+		count, err := repository.DeleteExpired(ctx, userID, entities.MessageTypeMobileTerminated, time.Now().UTC().Add(-30*24*time.Hour), true, 10)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+		for _, message := range []*entities.Message{pending, received, recent} {
+			_, loadErr := repository.Load(ctx, userID, message.ID)
+			assert.Nil(t, loadErr, message.Content)
+		}
+		for _, message := range []*entities.Message{delivered, archived} {
+			_, loadErr := repository.Load(ctx, userID, message.ID)
+			assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(loadErr), message.Content)
+		}
+	})
+
+	t.Run("expired content is replaced once and is not archived before it expires", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		userID := entities.UserID(uuid.NewString())
+		old := time.Now().UTC().Add(-2 * time.Hour)
+		expiresAt := time.Now().UTC().Add(-time.Hour)
+		later := time.Now().UTC().Add(time.Hour)
+
+		expiring := newMessage(userID, "Your code is 123456", old)
+		expiring.Type = entities.MessageTypeMobileOriginated
+		expiring.Status = entities.MessageStatusReceived
+		expiring.ContentExpiresAt = &expiresAt
+		pending := newMessage(userID, "Your code is 654321", old)
+		pending.Type = entities.MessageTypeMobileOriginated
+		pending.Status = entities.MessageStatusReceived
+		pending.ContentExpiresAt = &later
+
+		for _, message := range []*entities.Message{expiring, pending} {
+			assert.Nil(t, repository.Store(ctx, message))
+		}
+		_, err := repository.Archive(ctx, time.Now().UTC(), 1000)
+		assert.Nil(t, err)
+
+		// Act
+		ids, err := repository.ExpireContent(ctx, time.Now().UTC(), 10)
+		again, againErr := repository.ExpireContent(ctx, time.Now().UTC(), 10)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Nil(t, againErr)
+		assert.Contains(t, ids, expiring.ID)
+		assert.NotContains(t, ids, pending.ID)
+		assert.NotContains(t, again, expiring.ID)
+
+		message, err := repository.Load(ctx, userID, expiring.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageContentExpiredMarker, message.Content)
+		assert.NotNil(t, message.ContentExpiredAt)
+
+		message, err = repository.Load(ctx, userID, pending.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, "Your code is 654321", message.Content)
+	})
+}
+
+// EventListenerLogRepository runs the conformance tests of repositories.EventListenerLogRepository against the
+// repository returned by newRepository
+func EventListenerLogRepository(t *testing.T, newRepository func() repositories.EventListenerLogRepository) {
+	newLog := func() *entities.EventListenerLog {
+		return &entities.EventListenerLog{
+			ID:        uuid.New(),
+			EventID:   uuid.NewString(),
+			EventType: "message.api.sent",
+			Handler:   "MessageListener.OnMessageAPISent",
+			HandledAt: time.Now().UTC(),
+			CreatedAt: time.Now().UTC(),
+		}
+	}
+
+	t.Run("stored log is found", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		log := newLog()
+
+		// Act
+		before, beforeErr := repository.Has(ctx, log.EventID, log.Handler)
+		storeErr := repository.Store(ctx, log)
+		after, afterErr := repository.Has(ctx, log.EventID, log.Handler)
+		other, otherErr := repository.Has(ctx, log.EventID, "MessageListener.OnMessagePhoneSent")
+
+		// Assert
+		assert.Nil(t, beforeErr)
+		assert.False(t, before)
+		assert.Nil(t, storeErr)
+		assert.Nil(t, afterErr)
+		assert.True(t, after)
+		assert.Nil(t, otherErr)
+		assert.False(t, other)
+	})
+
+	t.Run("duplicate log is rejected", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		log := newLog()
+		assert.Nil(t, repository.Store(ctx, log))
+
+		duplicate := newLog()
+		duplicate.EventID = log.EventID
+
+		// Act
+		err := repository.Store(ctx, duplicate)
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
+
+// EventRepository runs the conformance tests of repositories.EventRepository against the repository returned by newRepository
+func EventRepository(t *testing.T, newRepository func() repositories.EventRepository) {
+	newEvent := func(eventType string, timestamp time.Time, payload map[string]any) cloudevents.Event {
+		event := cloudevents.NewEvent()
+		event.SetID(uuid.NewString())
+		event.SetType(eventType)
+		event.SetSource("https://api.httpsms.com/v1/messages/send")
+		event.SetTime(timestamp)
+		assert.Nil(t, event.SetData(cloudevents.ApplicationJSON, payload))
+		return event
+	}
+
+	t.Run("events are fetched by the message ID in the payload", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		messageID := uuid.New()
+		start := time.Now().UTC().Truncate(time.Second)
+
+		sent := newEvent("message.api.sent", start, map[string]any{"message_id": messageID})
+		delivered := newEvent("message.phone.delivered", start.Add(2*time.Second), map[string]any{"id": messageID})
+		sending := newEvent("message.phone.sending", start.Add(time.Second), map[string]any{"id": messageID})
+		other := newEvent("message.api.sent", start, map[string]any{"message_id": uuid.New()})
+		phone := newEvent("phone.updated", start, map[string]any{"id": messageID})
+
+		for _, event := range []cloudevents.Event{delivered, sent, other, phone, sending} {
+			assert.Nil(t, repository.Create(ctx, event))
+		}
+
+		// Act
+		events, err := repository.FetchByMessageID(ctx, messageID)
+
+		// Assert
+		assert.Nil(t, err)
+		ids := make([]string, 0, len(*events))
+		for _, event := range *events {
+			ids = append(ids, event.ID())
+		}
+		assert.Equal(t, []string{sent.ID(), sending.ID(), delivered.ID()}, ids)
+		assert.Equal(t, "message.api.sent", (*events)[0].Type())
+		assert.Equal(t, sent.Source(), (*events)[0].Source())
+
+		payload := map[string]string{}
+		assert.Nil(t, (*events)[0].DataAs(&payload))
+		assert.Equal(t, messageID.String(), payload["message_id"])
+	})
+
+	t.Run("no events are fetched for an unknown message", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		assert.Nil(t, repository.Create(ctx, newEvent("message.api.sent", time.Now().UTC(), map[string]any{"message_id": uuid.New()})))
+
+		// Act
+		events, err := repository.FetchByMessageID(ctx, uuid.New())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, *events)
+	})
+
+	t.Run("events of a user are fetched after the last event", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		start := time.Now().UTC().Truncate(time.Second)
+
+		first := newEvent("message.api.sent", start, map[string]any{"user_id": "user-a"})
+		second := newEvent("message.phone.sending", start.Add(time.Second), map[string]any{"user_id": "user-a"})
+		third := newEvent("message.phone.sent", start.Add(2*time.Second), map[string]any{"user_id": "user-a"})
+		fourth := newEvent("message.phone.delivered", start.Add(3*time.Second), map[string]any{"user_id": "user-a"})
+		other := newEvent("message.phone.sent", start.Add(2*time.Second), map[string]any{"user_id": "user-b"})
+
+		for _, event := range []cloudevents.Event{fourth, other, second, first, third} {
+			assert.Nil(t, repository.Create(ctx, event))
+		}
+
+		// Act
+		events, err := repository.FetchByUserIDAfter(ctx, "user-a", uuid.MustParse(first.ID()), 2)
+
+		// Assert
+		assert.Nil(t, err)
+		ids := make([]string, 0, len(*events))
+		for _, event := range *events {
+			ids = append(ids, event.ID())
+		}
+		assert.Equal(t, []string{second.ID(), third.ID()}, ids)
+	})
+
+	t.Run("events cannot be fetched after the event of another user", func(t *testing.T) {
+		// Arrange
+		ctx := context.Background()
+		repository := newRepository()
+		event := newEvent("message.api.sent", time.Now().UTC(), map[string]any{"user_id": "user-b"})
+		assert.Nil(t, repository.Create(ctx, event))
+
+		// Act
+		_, err := repository.FetchByUserIDAfter(ctx, "user-a", uuid.MustParse(event.ID()), 10)
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+	})
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/palantir/stacktrace"
+	"gorm.io/gorm"
+)
+
+const (
+	retryBaseDelay = 50 * time.Millisecond
+	retryMaxDelay  = 1 * time.Second
+)
+
+// transientErrorCodes are the postgres SQLSTATE codes of errors which succeed when the operation is retried
+var transientErrorCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now
+	"53300": true, // too_many_connections
+}
+
+// IsTransientError checks if an error is caused by a dropped connection or a conflicting transaction and the operation can be retried
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	cause := stacktrace.RootCause(err)
+	if errors.Is(cause, context.Canceled) || errors.Is(cause, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(cause, &pgErr) {
+		// class 08 are connection exceptions
+		return transientErrorCodes[pgErr.Code] || strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	if pgconn.SafeToRetry(cause) || pgconn.Timeout(cause) {
+		return true
+	}
+
+	if errors.Is(cause, driver.ErrBadConn) ||
+		errors.Is(cause, io.ErrUnexpectedEOF) ||
+		errors.Is(cause, syscall.ECONNRESET) ||
+		errors.Is(cause, syscall.ECONNREFUSED) ||
+		errors.Is(cause, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr *net.OpError
+	return errors.As(cause, &netErr)
+}
+
+// retrier retries an operation with a jittered exponential backoff when it fails with a transient error
+type retrier struct {
+	logger   telemetry.Logger
+	attempts int
+	sleep    func(ctx context.Context, delay time.Duration) error
+}
+
+func newRetrier(logger telemetry.Logger, attempts int) retrier {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return retrier{
+		logger:   logger,
+		attempts: attempts,
+		sleep:    sleepWithContext,
+	}
+}
+
+// do calls fn until it succeeds, fails with an error which is not transient or the attempts are exhausted.
+// fn is called once when ctx has a transaction because the transaction is aborted after the first error.
+// The error of the last attempt is returned unchanged.
+func (retrier retrier) do(ctx context.Context, operation string, fn func() error) error {
+	if _, ok := ctx.Value(contextKeyTransaction).(*gorm.DB); ok {
+		return fn()
+	}
+
+	var err error
+	for attempt := 1; attempt <= retrier.attempts; attempt++ {
+		if err = fn(); !IsTransientError(err) || attempt == retrier.attempts {
+			return err
+		}
+
+		delay := retrier.delay(attempt)
+		retrier.logger.Warn(stacktrace.Propagate(err, fmt.Sprintf("retrying [%s] in [%s] after transient error on attempt [%d/%d]", operation, delay, attempt, retrier.attempts)))
+		if sleepErr := retrier.sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// delay is a random duration up to the exponential backoff of the attempt
+func (retrier retrier) delay(attempt int) time.Duration {
+	backoff := retryBaseDelay << (attempt - 1)
+	if backoff > retryMaxDelay || backoff <= 0 {
+		backoff = retryMaxDelay
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func sleepWithContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
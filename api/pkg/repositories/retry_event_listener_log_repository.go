@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+)
+
+// retryEventListenerLogRepository retries the operations of an EventListenerLogRepository which fail with a transient error
+type retryEventListenerLogRepository struct {
+	repository EventListenerLogRepository
+	retrier    retrier
+}
+
+// NewRetryEventListenerLogRepository creates an EventListenerLogRepository which retries Has up to attempts times.
+// Store is not retried because a committed attempt violates the unique index of the event and handler on the next attempt.
+func NewRetryEventListenerLogRepository(
+	logger telemetry.Logger,
+	repository EventListenerLogRepository,
+	attempts int,
+) EventListenerLogRepository {
+	return &retryEventListenerLogRepository{
+		repository: repository,
+		retrier:    newRetrier(logger.WithService(fmt.Sprintf("%T", &retryEventListenerLogRepository{})), attempts),
+	}
+}
+
+// Store a new entities.EventListenerLog
+func (repository *retryEventListenerLogRepository) Store(ctx context.Context, log *entities.EventListenerLog) error {
+	return repository.repository.Store(ctx, log)
+}
+
+// Has verifies that the listener has not already been called
+func (repository *retryEventListenerLogRepository) Has(ctx context.Context, eventID string, handler string) (exists bool, err error) {
+	err = repository.retrier.do(ctx, "EventListenerLogRepository.Has", func() error {
+		exists, err = repository.repository.Has(ctx, eventID, handler)
+		return err
+	})
+	return exists, err
+}
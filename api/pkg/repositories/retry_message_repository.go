@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+)
+
+// retryMessageRepository retries the operations of a MessageRepository which fail with a transient error
+type retryMessageRepository struct {
+	repository MessageRepository
+	retrier    retrier
+}
+
+// NewRetryMessageRepository creates a MessageRepository which retries reads and idempotent writes up to attempts times.
+// Store, Failover, Fallback and Restore are not retried because they cannot be applied twice when the first attempt was committed.
+func NewRetryMessageRepository(
+	logger telemetry.Logger,
+	repository MessageRepository,
+	attempts int,
+) MessageRepository {
+	return &retryMessageRepository{
+		repository: repository,
+		retrier:    newRetrier(logger.WithService(fmt.Sprintf("%T", &retryMessageRepository{})), attempts),
+	}
+}
+
+// Store a new entities.Message
+func (repository *retryMessageRepository) Store(ctx context.Context, message *entities.Message) error {
+	return repository.repository.Store(ctx, message)
+}
+
+// StoreMany inserts entities.Message in batches, a message inserted by an attempt which failed after the commit is skipped by the retry
+func (repository *retryMessageRepository) StoreMany(ctx context.Context, messages []*entities.Message) (outcomes []StoreOutcome, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.StoreMany", func() error {
+		outcomes, err = repository.repository.StoreMany(ctx, messages)
+		return err
+	})
+	return outcomes, err
+}
+
+// Update an entities.Message, a retry after a committed attempt fails with ErrCodeStaleUpdate because of the version
+func (repository *retryMessageRepository) Update(ctx context.Context, message *entities.Message) error {
+	return repository.retrier.do(ctx, "MessageRepository.Update", func() error {
+		return repository.repository.Update(ctx, message)
+	})
+}
+
+// Load an entities.Message by ID
+func (repository *retryMessageRepository) Load(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (message *entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.Load", func() error {
+		message, err = repository.repository.Load(ctx, userID, messageID)
+		return err
+	})
+	return message, err
+}
+
+// LoadLastReceived fetches the latest entities.Message which the owner received from the contact
+func (repository *retryMessageRepository) LoadLastReceived(ctx context.Context, userID entities.UserID, owner string, contact string) (message *entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.LoadLastReceived", func() error {
+		message, err = repository.repository.LoadLastReceived(ctx, userID, owner, contact)
+		return err
+	})
+	return message, err
+}
+
+// LoadMany loads the entities.Message of a user with the IDs
+func (repository *retryMessageRepository) LoadMany(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) (messages []entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.LoadMany", func() error {
+		messages, err = repository.repository.LoadMany(ctx, userID, messageIDs)
+		return err
+	})
+	return messages, err
+}
+
+// Index entities.Message between 2 phone numbers
+func (repository *retryMessageRepository) Index(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (messages *[]entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.Index", func() error {
+		messages, err = repository.repository.Index(ctx, userID, owner, contact, params)
+		return err
+	})
+	return messages, err
+}
+
+// GetOutstanding fetches an entities.Message which is outstanding
+func (repository *retryMessageRepository) GetOutstanding(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (message *entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.GetOutstanding", func() error {
+		message, err = repository.repository.GetOutstanding(ctx, userID, messageID)
+		return err
+	})
+	return message, err
+}
+
+// IndexPending fetches the mobile terminated entities.Message of an owner which are still to be sent
+func (repository *retryMessageRepository) IndexPending(ctx context.Context, userID entities.UserID, owner string) (messages *[]entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.IndexPending", func() error {
+		messages, err = repository.repository.IndexPending(ctx, userID, owner)
+		return err
+	})
+	return messages, err
+}
+
+// CountPending counts the entities.Message of an owner which are still to be sent
+func (repository *retryMessageRepository) CountPending(ctx context.Context, userID entities.UserID, owner string) (count int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.CountPending", func() error {
+		count, err = repository.repository.CountPending(ctx, userID, owner)
+		return err
+	})
+	return count, err
+}
+
+// Failover moves a pending entities.Message from an owner to the failover owner
+func (repository *retryMessageRepository) Failover(ctx context.Context, userID entities.UserID, messageID uuid.UUID, owner string, failoverOwner string, sim entities.SIM) (*entities.Message, error) {
+	return repository.repository.Failover(ctx, userID, messageID, owner, failoverOwner, sim)
+}
+
+// Fallback moves an entities.Message from the phone to the channel of a fallback provider
+func (repository *retryMessageRepository) Fallback(ctx context.Context, userID entities.UserID, messageID uuid.UUID, channel entities.MessageChannel) (*entities.Message, error) {
+	return repository.repository.Fallback(ctx, userID, messageID, channel)
+}
+
+// Count counts the entities.Message between 2 phone numbers which match the filters of Index
+func (repository *retryMessageRepository) Count(ctx context.Context, userID entities.UserID, owner string, contact string, params IndexParams) (count int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.Count", func() error {
+		count, err = repository.repository.Count(ctx, userID, owner, contact, params)
+		return err
+	})
+	return count, err
+}
+
+// CountSendAttemptsSince counts the entities.Message which an owner has attempted to send since a timestamp
+func (repository *retryMessageRepository) CountSendAttemptsSince(ctx context.Context, userID entities.UserID, owner string, since time.Time) (count int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.CountSendAttemptsSince", func() error {
+		count, err = repository.repository.CountSendAttemptsSince(ctx, userID, owner, since)
+		return err
+	})
+	return count, err
+}
+
+// CountByStatus counts the entities.Message of all users with each status
+func (repository *retryMessageRepository) CountByStatus(ctx context.Context, statuses []entities.MessageStatus) (counts map[entities.MessageStatus]int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.CountByStatus", func() error {
+		counts, err = repository.repository.CountByStatus(ctx, statuses)
+		return err
+	})
+	return counts, err
+}
+
+// Delete soft deletes an entities.Message by ID
+func (repository *retryMessageRepository) Delete(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	return repository.retrier.do(ctx, "MessageRepository.Delete", func() error {
+		return repository.repository.Delete(ctx, userID, messageID)
+	})
+}
+
+// DeleteByOwnerAndContact soft deletes messages between an owner and a contact
+func (repository *retryMessageRepository) DeleteByOwnerAndContact(ctx context.Context, userID entities.UserID, owner string, contact string) error {
+	return repository.retrier.do(ctx, "MessageRepository.DeleteByOwnerAndContact", func() error {
+		return repository.repository.DeleteByOwnerAndContact(ctx, userID, owner, contact)
+	})
+}
+
+// Archive moves up to limit entities.Message into the archive, messages which are already archived are skipped
+func (repository *retryMessageRepository) Archive(ctx context.Context, before time.Time, limit int) (count int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.Archive", func() error {
+		count, err = repository.repository.Archive(ctx, before, limit)
+		return err
+	})
+	return count, err
+}
+
+// IndexArchived fetches archived entities.Message which were ordered before a timestamp
+func (repository *retryMessageRepository) IndexArchived(ctx context.Context, before time.Time, limit int) (messages []entities.Message, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.IndexArchived", func() error {
+		messages, err = repository.repository.IndexArchived(ctx, before, limit)
+		return err
+	})
+	return messages, err
+}
+
+// PurgeArchived permanently deletes archived entities.Message by ID, messages which are already purged are skipped
+func (repository *retryMessageRepository) PurgeArchived(ctx context.Context, messageIDs []uuid.UUID) (count int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.PurgeArchived", func() error {
+		count, err = repository.repository.PurgeArchived(ctx, messageIDs)
+		return err
+	})
+	return count, err
+}
+
+// DeleteExpired deletes the entities.Message of a user and type which were ordered before a timestamp, deleted messages are skipped by a retry
+func (repository *retryMessageRepository) DeleteExpired(ctx context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (count int64, err error) {
+	err = repository.retrier.do(ctx, "MessageRepository.DeleteExpired", func() error {
+		count, err = repository.repository.DeleteExpired(ctx, userID, messageType, before, hard, limit)
+		return err
+	})
+	return count, err
+}
+
+// Restore an entities.Message which was soft deleted
+func (repository *retryMessageRepository) Restore(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error {
+	return repository.repository.Restore(ctx, userID, messageID)
+}
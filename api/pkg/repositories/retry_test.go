@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newTestRetrier(attempts int) (retrier, *[]time.Duration) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+
+	delays := new([]time.Duration)
+	retrier := newRetrier(logger, attempts)
+	retrier.sleep = func(_ context.Context, delay time.Duration) error {
+		*delays = append(*delays, delay)
+		return nil
+	}
+	return retrier, delays
+}
+
+func TestIsTransientError(t *testing.T) {
+	errs := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "nil", err: nil, transient: false},
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, transient: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, transient: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, transient: true},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, transient: true},
+		{name: "too many connections", err: &pgconn.PgError{Code: "53300"}, transient: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}, transient: false},
+		{name: "syntax error", err: &pgconn.PgError{Code: "42601"}, transient: false},
+		{name: "bad connection", err: driver.ErrBadConn, transient: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, transient: true},
+		{name: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, transient: true},
+		{name: "connection refused", err: syscall.ECONNREFUSED, transient: true},
+		{name: "wrapped serialization failure", err: stacktrace.Propagate(fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), "cannot update message"), transient: true},
+		{name: "record not found", err: stacktrace.PropagateWithCode(gorm.ErrRecordNotFound, ErrCodeNotFound, "cannot load message"), transient: false},
+		{name: "stale update", err: stacktrace.NewErrorWithCode(ErrCodeStaleUpdate, "message was updated concurrently"), transient: false},
+		{name: "context canceled", err: stacktrace.Propagate(context.Canceled, "cannot load message"), transient: false},
+		{name: "context deadline exceeded", err: context.DeadlineExceeded, transient: false},
+	}
+
+	for _, item := range errs {
+		item := item
+		t.Run(item.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			transient := IsTransientError(item.err)
+
+			// Assert
+			assert.Equal(t, item.transient, transient)
+		})
+	}
+}
+
+func TestRetrier_Do(t *testing.T) {
+	t.Run("transient errors are retried until the operation succeeds", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, delays := newTestRetrier(3)
+
+		// Arrange
+		calls := 0
+
+		// Act
+		err := retrier.do(context.Background(), "test", func() error {
+			calls++
+			if calls < 3 {
+				return stacktrace.Propagate(io.ErrUnexpectedEOF, "cannot load message")
+			}
+			return nil
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Len(t, *delays, 2)
+		for i, delay := range *delays {
+			assert.LessOrEqual(t, delay, retryBaseDelay<<i)
+			assert.GreaterOrEqual(t, delay, (retryBaseDelay<<i)/2)
+		}
+	})
+
+	t.Run("the last transient error is returned when the attempts are exhausted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, delays := newTestRetrier(2)
+
+		// Arrange
+		calls := 0
+		lastErr := stacktrace.Propagate(driver.ErrBadConn, "cannot load message")
+
+		// Act
+		err := retrier.do(context.Background(), "test", func() error {
+			calls++
+			if calls == 2 {
+				return lastErr
+			}
+			return stacktrace.Propagate(driver.ErrBadConn, "cannot load message")
+		})
+
+		// Assert
+		assert.Same(t, lastErr, err)
+		assert.Equal(t, 2, calls)
+		assert.Len(t, *delays, 1)
+	})
+
+	t.Run("non transient errors are returned unchanged without a retry", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, delays := newTestRetrier(3)
+
+		// Arrange
+		calls := 0
+		notFound := stacktrace.PropagateWithCode(gorm.ErrRecordNotFound, ErrCodeNotFound, "cannot load message")
+
+		// Act
+		err := retrier.do(context.Background(), "test", func() error {
+			calls++
+			return notFound
+		})
+
+		// Assert
+		assert.Same(t, notFound, err)
+		assert.Equal(t, ErrCodeNotFound, stacktrace.GetCode(err))
+		assert.Equal(t, 1, calls)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("operations in a transaction are not retried", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, delays := newTestRetrier(3)
+
+		// Arrange
+		calls := 0
+		ctx := context.WithValue(context.Background(), contextKeyTransaction, &gorm.DB{})
+
+		// Act
+		err := retrier.do(ctx, "test", func() error {
+			calls++
+			return &pgconn.PgError{Code: "40001"}
+		})
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, calls)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("retries stop when the context is done", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, _ := newTestRetrier(3)
+		retrier.sleep = sleepWithContext
+
+		// Arrange
+		calls := 0
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		err := retrier.do(ctx, "test", func() error {
+			calls++
+			return driver.ErrBadConn
+		})
+
+		// Assert
+		assert.True(t, errors.Is(err, driver.ErrBadConn))
+		assert.Equal(t, 1, calls)
+	})
+}
+
+// flakyMessageRepository fails Load and Store with a transient error before it succeeds
+type flakyMessageRepository struct {
+	MessageRepository
+	failures int
+	calls    int
+}
+
+func (repository *flakyMessageRepository) Load(_ context.Context, _ entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
+	repository.calls++
+	if repository.calls <= repository.failures {
+		return nil, stacktrace.Propagate(&pgconn.PgError{Code: "08006"}, "cannot load message")
+	}
+	return &entities.Message{ID: messageID}, nil
+}
+
+func (repository *flakyMessageRepository) Store(_ context.Context, _ *entities.Message) error {
+	repository.calls++
+	return stacktrace.Propagate(&pgconn.PgError{Code: "08006"}, "cannot store message")
+}
+
+func TestRetryMessageRepository(t *testing.T) {
+	t.Run("Load is retried", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, _ := newTestRetrier(3)
+		flaky := &flakyMessageRepository{failures: 2}
+		repository := &retryMessageRepository{repository: flaky, retrier: retrier}
+
+		// Arrange
+		messageID := uuid.New()
+
+		// Act
+		message, err := repository.Load(context.Background(), "user-id", messageID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, messageID, message.ID)
+		assert.Equal(t, 3, flaky.calls)
+	})
+
+	t.Run("Store is not retried", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		retrier, _ := newTestRetrier(3)
+		flaky := &flakyMessageRepository{}
+		repository := &retryMessageRepository{repository: flaky, retrier: retrier}
+
+		// Act
+		err := repository.Store(context.Background(), &entities.Message{ID: uuid.New()})
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, 1, flaky.calls)
+	})
+}
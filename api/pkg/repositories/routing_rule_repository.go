@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// RoutingRuleRepository loads and persists an entities.RoutingRule
+type RoutingRuleRepository interface {
+	// Store a new entities.RoutingRule
+	Store(ctx context.Context, rule *entities.RoutingRule) error
+
+	// Update an entities.RoutingRule
+	Update(ctx context.Context, rule *entities.RoutingRule) error
+
+	// Load an entities.RoutingRule by ID
+	Load(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) (*entities.RoutingRule, error)
+
+	// Fetch all the entities.RoutingRule of a user ordered by prefix
+	Fetch(ctx context.Context, userID entities.UserID) ([]*entities.RoutingRule, error)
+
+	// Delete an entities.RoutingRule
+	Delete(ctx context.Context, rule *entities.RoutingRule) error
+}
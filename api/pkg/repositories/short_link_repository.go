@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// ShortLinkRepository loads and persists an entities.ShortLink
+type ShortLinkRepository interface {
+	// Store a new entities.ShortLink, it is StoreOutcomeSkipped when another entities.ShortLink has the same code
+	Store(ctx context.Context, link *entities.ShortLink) (StoreOutcome, error)
+
+	// Load an entities.ShortLink by code
+	Load(ctx context.Context, code string) (*entities.ShortLink, error)
+
+	// RecordClick increments the clicks of an entities.ShortLink
+	RecordClick(ctx context.Context, linkID uuid.UUID, timestamp time.Time) error
+
+	// FetchByMessage fetches the entities.ShortLink in the content of a message
+	FetchByMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID) ([]*entities.ShortLink, error)
+}
@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// SlackRepository loads and persists an entities.Slack
+type SlackRepository interface {
+	// Save Upsert a new entities.Slack
+	Save(ctx context.Context, slack *entities.Slack) error
+
+	// Index entities.Slack by entities.UserID
+	Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.Slack, error)
+
+	// FetchByOwner loads the entities.Slack of a user for the phone number which received a message
+	FetchByOwner(ctx context.Context, userID entities.UserID, owner string) ([]*entities.Slack, error)
+
+	// Load an entities.Slack by ID
+	Load(ctx context.Context, userID entities.UserID, slackID uuid.UUID) (*entities.Slack, error)
+
+	// Delete an entities.Slack
+	Delete(ctx context.Context, userID entities.UserID, slackID uuid.UUID) error
+}
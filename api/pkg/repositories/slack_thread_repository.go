@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// SlackThreadRepository loads and persists an entities.SlackThread
+type SlackThreadRepository interface {
+	// Store a new entities.SlackThread
+	Store(ctx context.Context, thread *entities.SlackThread) error
+
+	// Load the entities.SlackThread of a contact
+	Load(ctx context.Context, slackID uuid.UUID, contact string) (*entities.SlackThread, error)
+
+	// DeleteAll deletes the entities.SlackThread of an entities.Slack
+	DeleteAll(ctx context.Context, slackID uuid.UUID) error
+}
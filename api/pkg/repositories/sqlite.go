@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"net/url"
+	"strings"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSqliteDialector opens the SQLite database at path for self-hosting the API without postgres.
+//
+// SQLite allows only one writer at a time, so the connection is configured to wait for the write lock instead of
+// failing with SQLITE_BUSY:
+//   - _busy_timeout makes a connection wait up to 5 seconds for the lock held by another connection
+//   - _txlock=immediate takes the write lock when a transaction begins, a deferred transaction which upgrades
+//     from a read lock to a write lock fails immediately when another connection is writing
+//   - _journal_mode=WAL lets readers continue while a transaction is writing
+//
+// The connection pool is not limited to 1 connection because a repository which is not part of a Transactor
+// transaction would wait forever for the connection held by the transaction.
+func NewSqliteDialector(path string) gorm.Dialector {
+	params := url.Values{}
+	params.Set("_busy_timeout", "5000")
+	params.Set("_txlock", "immediate")
+	params.Set("_journal_mode", "WAL")
+	params.Set("_foreign_keys", "on")
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	return sqlite.Open(path + separator + params.Encode())
+}
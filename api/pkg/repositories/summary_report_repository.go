@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// SummaryReportRepository loads and persists the entities.SummaryReportSchedule of the users and the entities.SummaryReport which were sent
+type SummaryReportRepository interface {
+	// SaveSchedule upserts the entities.SummaryReportSchedule of a user
+	SaveSchedule(ctx context.Context, schedule *entities.SummaryReportSchedule) error
+
+	// LoadSchedule loads the entities.SummaryReportSchedule of a user
+	LoadSchedule(ctx context.Context, userID entities.UserID) (*entities.SummaryReportSchedule, error)
+
+	// DeleteSchedule deletes the entities.SummaryReportSchedule of a user
+	DeleteSchedule(ctx context.Context, userID entities.UserID) error
+
+	// IndexEnabledSchedules fetches the enabled entities.SummaryReportSchedule ordered by the user ID after the user ID
+	IndexEnabledSchedules(ctx context.Context, after entities.UserID, limit int) ([]*entities.SummaryReportSchedule, error)
+
+	// LoadLastReport loads the entities.SummaryReport of a user with the latest period for a frequency
+	LoadLastReport(ctx context.Context, userID entities.UserID, frequency entities.SummaryReportFrequency) (*entities.SummaryReport, error)
+
+	// StoreReport stores an entities.SummaryReport, it returns false when the report of the period has already been stored
+	StoreReport(ctx context.Context, report *entities.SummaryReport) (bool, error)
+
+	// DeleteReport deletes an entities.SummaryReport so that the report of its period is sent again
+	DeleteReport(ctx context.Context, reportID uuid.UUID) error
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// TeamRepository loads and persists entities.TeamMember and entities.TeamInvitation
+type TeamRepository interface {
+	// Index fetches the entities.Team which a user has joined, it does not include the personal team of the user
+	Index(ctx context.Context, userID entities.UserID) ([]*entities.Team, error)
+
+	// IndexMembers fetches the entities.TeamMember of a team
+	IndexMembers(ctx context.Context, teamID entities.UserID) ([]*entities.TeamMember, error)
+
+	// LoadMember fetches the entities.TeamMember of a user in a team
+	LoadMember(ctx context.Context, teamID entities.UserID, userID entities.UserID) (*entities.TeamMember, error)
+
+	// DeleteMember removes an entities.TeamMember from a team
+	DeleteMember(ctx context.Context, member *entities.TeamMember) error
+
+	// StoreInvitation stores a new entities.TeamInvitation
+	StoreInvitation(ctx context.Context, invitation *entities.TeamInvitation) error
+
+	// LoadInvitation fetches an entities.TeamInvitation by the hash of the token
+	LoadInvitation(ctx context.Context, tokenHash string) (*entities.TeamInvitation, error)
+
+	// AcceptInvitation marks an entities.TeamInvitation as accepted and stores the entities.TeamMember in a single transaction
+	AcceptInvitation(ctx context.Context, invitation *entities.TeamInvitation, member *entities.TeamMember) error
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// TelegramMessageRepository loads and persists an entities.TelegramMessage
+type TelegramMessageRepository interface {
+	// Store a new entities.TelegramMessage
+	Store(ctx context.Context, message *entities.TelegramMessage) error
+
+	// Load the entities.TelegramMessage of a message in the telegram chat
+	Load(ctx context.Context, telegramID uuid.UUID, telegramMessageID int64) (*entities.TelegramMessage, error)
+
+	// DeleteAll deletes the entities.TelegramMessage of an entities.Telegram
+	DeleteAll(ctx context.Context, telegramID uuid.UUID) error
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+)
+
+// TelegramRepository loads and persists an entities.Telegram
+type TelegramRepository interface {
+	// Save Upsert a new entities.Telegram
+	Save(ctx context.Context, telegram *entities.Telegram) error
+
+	// Index entities.Telegram by entities.UserID
+	Index(ctx context.Context, userID entities.UserID, params IndexParams) ([]*entities.Telegram, error)
+
+	// FetchByOwner loads the entities.Telegram of a user for the phone number which received a message
+	FetchByOwner(ctx context.Context, userID entities.UserID, owner string) ([]*entities.Telegram, error)
+
+	// Load an entities.Telegram of a user by ID
+	Load(ctx context.Context, userID entities.UserID, telegramID uuid.UUID) (*entities.Telegram, error)
+
+	// LoadByID loads an entities.Telegram by ID for the updates of the telegram bot which are not authenticated with an API key
+	LoadByID(ctx context.Context, telegramID uuid.UUID) (*entities.Telegram, error)
+
+	// ClaimUpdate sets the LastUpdateID of an entities.Telegram, false is returned when the update was already handled
+	ClaimUpdate(ctx context.Context, telegramID uuid.UUID, updateID int64) (bool, error)
+
+	// Delete an entities.Telegram
+	Delete(ctx context.Context, userID entities.UserID, telegramID uuid.UUID) error
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cockroachdb/cockroach-go/v2/crdb/crdbgorm"
+	"gorm.io/gorm"
+)
+
+type contextKey string
+
+const (
+	contextKeyTransaction = contextKey("repositories.transaction")
+	contextKeyAfterCommit = contextKey("repositories.after-commit")
+)
+
+// Transactor executes the writes of multiple repositories in one database transaction
+type Transactor interface {
+	// Execute runs fn in a transaction, the repositories which are called with the context passed to fn take part in the transaction.
+	// The transaction is rolled back when fn returns an error and fn may be retried when the transaction cannot be committed.
+	Execute(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// gormTransactor executes a Transactor with a GORM transaction
+type gormTransactor struct {
+	db *gorm.DB
+}
+
+// NewGormTransactor creates the GORM version of the Transactor
+func NewGormTransactor(db *gorm.DB) Transactor {
+	return &gormTransactor{db: db}
+}
+
+// Execute runs fn in a transaction, fn joins the transaction of ctx when it already has one.
+// The functions registered with AfterCommit are run after the transaction is committed.
+func (transactor *gormTransactor) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(contextKeyTransaction).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	var hooks *afterCommitHooks
+	err := crdbgorm.ExecuteTx(ctx, transactor.db, nil, func(tx *gorm.DB) error {
+		// the hooks of an attempt which is rolled back are discarded when the transaction is retried
+		hooks = &afterCommitHooks{}
+		return fn(context.WithValue(context.WithValue(ctx, contextKeyTransaction, tx), contextKeyAfterCommit, hooks))
+	})
+	if err != nil {
+		return err
+	}
+
+	hooks.run()
+	return nil
+}
+
+// afterCommitHooks are the functions which are run after a transaction is committed
+type afterCommitHooks struct {
+	mutex sync.Mutex
+	hooks []func()
+}
+
+func (hooks *afterCommitHooks) add(hook func()) {
+	hooks.mutex.Lock()
+	defer hooks.mutex.Unlock()
+	hooks.hooks = append(hooks.hooks, hook)
+}
+
+func (hooks *afterCommitHooks) run() {
+	hooks.mutex.Lock()
+	pending := hooks.hooks
+	hooks.hooks = nil
+	hooks.mutex.Unlock()
+
+	for _, hook := range pending {
+		hook()
+	}
+}
+
+// AfterCommit registers fn to run after the transaction of ctx is committed, fn is discarded when the transaction is
+// rolled back. The context.Context passed to fn is not part of the transaction. It returns false without registering fn
+// when ctx has no transaction so that the caller can run fn immediately.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) bool {
+	hooks, ok := ctx.Value(contextKeyAfterCommit).(*afterCommitHooks)
+	if !ok {
+		return false
+	}
+
+	detached := WithoutTransaction(ctx)
+	hooks.add(func() { fn(detached) })
+	return true
+}
+
+// WithoutTransaction returns a context.Context with the values of ctx which does not take part in the transaction of ctx
+func WithoutTransaction(ctx context.Context) context.Context {
+	if ctx.Value(contextKeyTransaction) == nil && ctx.Value(contextKeyAfterCommit) == nil {
+		return ctx
+	}
+	return context.WithValue(context.WithValue(ctx, contextKeyTransaction, nil), contextKeyAfterCommit, nil)
+}
+
+// dbFromContext returns the transaction started by a Transactor or db when the context has no transaction
+func dbFromContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(contextKeyTransaction).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// executeTx runs fn in the transaction started by a Transactor or in a new transaction when the context has no transaction
+func executeTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if tx, ok := ctx.Value(contextKeyTransaction).(*gorm.DB); ok {
+		return fn(tx.WithContext(ctx))
+	}
+	return crdbgorm.ExecuteTx(ctx, db, nil, fn)
+}
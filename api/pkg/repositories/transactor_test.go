@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// TestGormTransactor_AfterCommit verifies that the functions registered with AfterCommit only run when the transaction is committed
+func TestGormTransactor_AfterCommit(t *testing.T) {
+	for _, backend := range testBackends(t) {
+		backend := backend
+		transactor := NewGormTransactor(backend.db)
+
+		t.Run(backend.name+"/committed", func(t *testing.T) {
+			// Arrange
+			calls := 0
+			var hookTransaction any
+
+			// Act
+			err := transactor.Execute(context.Background(), func(ctx context.Context) error {
+				assert.True(t, AfterCommit(ctx, func(ctx context.Context) {
+					calls++
+					hookTransaction = ctx.Value(contextKeyTransaction)
+				}))
+				assert.Equal(t, 0, calls)
+				return nil
+			})
+
+			// Assert
+			assert.Nil(t, err)
+			assert.Equal(t, 1, calls)
+			assert.Nil(t, hookTransaction)
+		})
+
+		t.Run(backend.name+"/rolled back", func(t *testing.T) {
+			// Arrange
+			calls := 0
+
+			// Act
+			err := transactor.Execute(context.Background(), func(ctx context.Context) error {
+				AfterCommit(ctx, func(ctx context.Context) { calls++ })
+				return errors.New("rollback")
+			})
+
+			// Assert
+			assert.NotNil(t, err)
+			assert.Equal(t, 0, calls)
+		})
+
+		t.Run(backend.name+"/without transaction", func(t *testing.T) {
+			// Arrange
+			var detached context.Context
+			err := transactor.Execute(context.Background(), func(ctx context.Context) error {
+				detached = WithoutTransaction(ctx)
+				return nil
+			})
+			assert.Nil(t, err)
+
+			// Act
+			registered := AfterCommit(detached, func(ctx context.Context) {})
+
+			// Assert
+			assert.False(t, registered)
+			_, ok := detached.Value(contextKeyTransaction).(*gorm.DB)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 )
@@ -17,12 +18,28 @@ type UserRepository interface {
 	// LoadAuthUser fetches an entities.AuthUser by apiKey
 	LoadAuthUser(ctx context.Context, apiKey string) (entities.AuthUser, error)
 
+	// RotateAPIKey generates a new API key for an entities.User, the previous API key stays valid for the grace period.
+	// Only the hash of the new API key is stored, the plain text API key is only available in the returned entities.User
+	RotateAPIKey(ctx context.Context, userID entities.UserID, gracePeriod time.Duration) (*entities.User, error)
+
 	// Load an entities.User by entities.UserID
 	Load(ctx context.Context, userID entities.UserID) (*entities.User, error)
 
-	// LoadOrStore an entities.User by entities.AuthUser
+	// LoadOrStore an entities.User by entities.AuthUser, the plain text API key is only set when the entities.User is created
 	LoadOrStore(ctx context.Context, user entities.AuthUser) (*entities.User, bool, error)
 
+	// Index fetches entities.User with an email which matches the params
+	Index(ctx context.Context, params IndexParams) ([]*entities.User, error)
+
+	// IndexWithRetention fetches the entities.User who have a retention period for received or sent messages
+	IndexWithRetention(ctx context.Context) ([]*entities.User, error)
+
+	// Summaries aggregates the phones and messages of a list of entities.User
+	Summaries(ctx context.Context, users []*entities.User) ([]*entities.UserSummary, error)
+
+	// Suspend an entities.User so that the API keys of the user are rejected, the user is reinstated when suspendedAt is nil
+	Suspend(ctx context.Context, user *entities.User, suspendedAt *time.Time) error
+
 	// LoadBySubscriptionID loads a user based on the lemonsqueezy subscriptionID
 	LoadBySubscriptionID(ctx context.Context, subscriptionID string) (*entities.User, error)
 }
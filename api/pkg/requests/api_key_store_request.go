@@ -0,0 +1,37 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// APIKeyStore is the payload for creating a new entities.APIKey
+type APIKeyStore struct {
+	request
+	Label  string   `json:"label" example:"BI team"`
+	Scopes []string `json:"scopes" example:"messages:read,webhooks:read"`
+}
+
+// Sanitize sets defaults to APIKeyStore
+func (input *APIKeyStore) Sanitize() APIKeyStore {
+	input.Label = strings.TrimSpace(input.Label)
+
+	var scopes []string
+	for _, scope := range input.Scopes {
+		scopes = append(scopes, strings.ToLower(strings.TrimSpace(scope)))
+	}
+	input.Scopes = input.removeStringDuplicates(scopes)
+
+	return *input
+}
+
+// ToStoreParams converts APIKeyStore to services.APIKeyStoreParams
+func (input *APIKeyStore) ToStoreParams(user entities.AuthUser) *services.APIKeyStoreParams {
+	return &services.APIKeyStoreParams{
+		UserID: user.ID,
+		Label:  input.Label,
+		Scopes: input.Scopes,
+	}
+}
@@ -0,0 +1,37 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+)
+
+// ContactGroupMemberIndex is the payload for fetching the members of an entities.ContactGroup
+type ContactGroupMemberIndex struct {
+	request
+	Skip  string `json:"skip" query:"skip"`
+	Limit string `json:"limit" query:"limit"`
+
+	ContactGroupID string `json:"contactGroupID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContactGroupMemberIndex
+func (input *ContactGroupMemberIndex) Sanitize() ContactGroupMemberIndex {
+	if strings.TrimSpace(input.Limit) == "" {
+		input.Limit = "20"
+	}
+	input.Skip = strings.TrimSpace(input.Skip)
+	if input.Skip == "" {
+		input.Skip = "0"
+	}
+	input.ContactGroupID = strings.TrimSpace(input.ContactGroupID)
+	return *input
+}
+
+// ToIndexParams converts ContactGroupMemberIndex to repositories.IndexParams
+func (input *ContactGroupMemberIndex) ToIndexParams() repositories.IndexParams {
+	return repositories.IndexParams{
+		Skip:  input.getInt(input.Skip),
+		Limit: input.getInt(input.Limit),
+	}
+}
@@ -0,0 +1,41 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ContactGroupMemberStore is the payload for adding contacts to an entities.ContactGroup
+type ContactGroupMemberStore struct {
+	request
+	ContactIDs []string `json:"contact_ids" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+
+	ContactGroupID string `json:"contactGroupID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContactGroupMemberStore, the duplicate IDs are removed and the order of the IDs is kept
+func (input *ContactGroupMemberStore) Sanitize() ContactGroupMemberStore {
+	seen := map[string]struct{}{}
+	contactIDs := make([]string, 0, len(input.ContactIDs))
+	for _, contactID := range input.ContactIDs {
+		contactID = strings.ToLower(strings.TrimSpace(contactID))
+		if _, ok := seen[contactID]; ok {
+			continue
+		}
+		seen[contactID] = struct{}{}
+		contactIDs = append(contactIDs, contactID)
+	}
+	input.ContactIDs = contactIDs
+	input.ContactGroupID = strings.TrimSpace(input.ContactGroupID)
+	return *input
+}
+
+// ToUUIDs converts the validated ContactGroupMemberStore.ContactIDs into []uuid.UUID
+func (input *ContactGroupMemberStore) ToUUIDs() []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(input.ContactIDs))
+	for _, contactID := range input.ContactIDs {
+		result = append(result, uuid.MustParse(contactID))
+	}
+	return result
+}
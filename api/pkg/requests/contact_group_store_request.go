@@ -0,0 +1,28 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// ContactGroupStore is the payload for creating an entities.ContactGroup
+type ContactGroupStore struct {
+	request
+	Name string `json:"name" example:"All Drivers"`
+}
+
+// Sanitize sets defaults to ContactGroupStore
+func (input *ContactGroupStore) Sanitize() ContactGroupStore {
+	input.Name = strings.TrimSpace(input.Name)
+	return *input
+}
+
+// ToStoreParams converts ContactGroupStore to services.ContactGroupStoreParams
+func (input *ContactGroupStore) ToStoreParams(userID entities.UserID) *services.ContactGroupStoreParams {
+	return &services.ContactGroupStoreParams{
+		UserID: userID,
+		Name:   input.Name,
+	}
+}
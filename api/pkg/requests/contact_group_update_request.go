@@ -0,0 +1,33 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// ContactGroupUpdate is the payload for updating the name of an entities.ContactGroup
+type ContactGroupUpdate struct {
+	request
+	Name string `json:"name" example:"All Drivers"`
+
+	ContactGroupID string `json:"contactGroupID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContactGroupUpdate
+func (input *ContactGroupUpdate) Sanitize() ContactGroupUpdate {
+	input.Name = strings.TrimSpace(input.Name)
+	input.ContactGroupID = strings.TrimSpace(input.ContactGroupID)
+	return *input
+}
+
+// ToUpdateParams converts ContactGroupUpdate to services.ContactGroupUpdateParams
+func (input *ContactGroupUpdate) ToUpdateParams(userID entities.UserID) *services.ContactGroupUpdateParams {
+	return &services.ContactGroupUpdateParams{
+		UserID:         userID,
+		ContactGroupID: uuid.MustParse(input.ContactGroupID),
+		Name:           input.Name,
+	}
+}
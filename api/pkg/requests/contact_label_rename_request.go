@@ -0,0 +1,31 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// ContactLabelRename is the payload for renaming a label on all the contacts of a user
+type ContactLabelRename struct {
+	request
+	Label string `json:"label" example:"vip"`
+	Name  string `json:"name" example:"priority"`
+}
+
+// Sanitize sets defaults to ContactLabelRename
+func (input *ContactLabelRename) Sanitize() ContactLabelRename {
+	input.Label = strings.ToLower(strings.TrimSpace(input.Label))
+	input.Name = strings.ToLower(strings.TrimSpace(input.Name))
+	return *input
+}
+
+// ToRenameParams converts ContactLabelRename to services.ContactLabelRenameParams
+func (input *ContactLabelRename) ToRenameParams(userID entities.UserID) *services.ContactLabelRenameParams {
+	return &services.ContactLabelRenameParams{
+		UserID: userID,
+		Label:  input.Label,
+		Name:   input.Name,
+	}
+}
@@ -0,0 +1,33 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// ContactLabelsUpdate is the payload for replacing the labels of an entities.Contact
+type ContactLabelsUpdate struct {
+	request
+	Labels []string `json:"labels" example:"vip,french"`
+
+	ContactID string `json:"contactID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContactLabelsUpdate
+func (input *ContactLabelsUpdate) Sanitize() ContactLabelsUpdate {
+	input.Labels = entities.NormalizeContactLabels(input.Labels)
+	input.ContactID = strings.TrimSpace(input.ContactID)
+	return *input
+}
+
+// ToLabelsParams converts ContactLabelsUpdate to services.ContactLabelsParams
+func (input *ContactLabelsUpdate) ToLabelsParams(userID entities.UserID) *services.ContactLabelsParams {
+	return &services.ContactLabelsParams{
+		UserID:    userID,
+		ContactID: uuid.MustParse(input.ContactID),
+		Labels:    input.Labels,
+	}
+}
@@ -0,0 +1,34 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// ContactNotesUpdate is the payload for updating the notes of an entities.Contact
+type ContactNotesUpdate struct {
+	request
+	Notes string `json:"notes" example:"prefers French, VIP customer"`
+
+	ContactID string `json:"contactID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContactNotesUpdate
+func (input *ContactNotesUpdate) Sanitize() ContactNotesUpdate {
+	input.Notes = strings.TrimSpace(input.Notes)
+	input.ContactID = strings.TrimSpace(input.ContactID)
+	return *input
+}
+
+// ToNotesParams converts ContactNotesUpdate to services.ContactNotesParams
+func (input *ContactNotesUpdate) ToNotesParams(userID entities.UserID, updatedBy entities.UserID) *services.ContactNotesParams {
+	return &services.ContactNotesParams{
+		UserID:    userID,
+		ContactID: uuid.MustParse(input.ContactID),
+		Notes:     input.Notes,
+		UpdatedBy: updatedBy,
+	}
+}
@@ -0,0 +1,42 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// ContactStore is the payload for setting the name of a phone number
+type ContactStore struct {
+	request
+	PhoneNumber string `json:"phone_number" example:"+18005550100"`
+	Name        string `json:"name" example:"Jane Doe"`
+
+	// DNDStart is the time e.g. 18:00 in DNDTimezone after which messages to the contact are held until DNDEnd, leave the 3 fields empty to send messages at any time
+	DNDStart    string `json:"dnd_start" example:"18:00" validate:"optional"`
+	DNDEnd      string `json:"dnd_end" example:"09:00" validate:"optional"`
+	DNDTimezone string `json:"dnd_timezone" example:"Europe/Berlin" validate:"optional"`
+}
+
+// Sanitize sets defaults to ContactStore
+func (input *ContactStore) Sanitize() ContactStore {
+	input.PhoneNumber = input.sanitizeAddress(input.PhoneNumber)
+	input.Name = strings.TrimSpace(input.Name)
+	input.DNDStart = strings.TrimSpace(input.DNDStart)
+	input.DNDEnd = strings.TrimSpace(input.DNDEnd)
+	input.DNDTimezone = strings.TrimSpace(input.DNDTimezone)
+	return *input
+}
+
+// ToUpsertParams converts ContactStore to services.ContactUpsertParams
+func (input *ContactStore) ToUpsertParams(userID entities.UserID) *services.ContactUpsertParams {
+	return &services.ContactUpsertParams{
+		UserID:      userID,
+		PhoneNumber: input.PhoneNumber,
+		Name:        input.Name,
+		DNDStart:    input.DNDStart,
+		DNDEnd:      input.DNDEnd,
+		DNDTimezone: input.DNDTimezone,
+	}
+}
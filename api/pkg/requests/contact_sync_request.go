@@ -0,0 +1,57 @@
+package requests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// ContactSyncEntry is an entry of the address book of a phone
+type ContactSyncEntry struct {
+	// PhoneNumber is the number of the entry in E.164 or in the national format of the owner phone number
+	PhoneNumber string `json:"phone_number" example:"+18005550100"`
+	Name        string `json:"name" example:"Jane Doe"`
+	// Deleted is true when the entry was deleted from the address book
+	Deleted bool `json:"deleted" example:"false"`
+	// UpdatedAt is the time when the entry was changed in the address book, an entry which is older than the contact is ignored
+	UpdatedAt *time.Time `json:"updated_at" example:"2022-06-05T14:26:02.302718+03:00" validate:"optional"`
+}
+
+// ContactSync is the payload for syncing a batch of the address book of a phone
+type ContactSync struct {
+	request
+	// Owner is the phone number of the phone which syncs its address book
+	Owner    string             `json:"owner" example:"+18005550199"`
+	Contacts []ContactSyncEntry `json:"contacts"`
+}
+
+// Sanitize sets defaults to ContactSync
+func (input *ContactSync) Sanitize() ContactSync {
+	input.Owner = input.sanitizeAddress(input.Owner)
+	for index, contact := range input.Contacts {
+		input.Contacts[index].PhoneNumber = input.sanitizeContactAddress(contact.PhoneNumber, input.Owner)
+		input.Contacts[index].Name = strings.TrimSpace(contact.Name)
+	}
+	return *input
+}
+
+// ToSyncParams converts ContactSync to services.ContactSyncParams
+func (input *ContactSync) ToSyncParams(userID entities.UserID) *services.ContactSyncParams {
+	contacts := make([]services.ContactSyncEntry, 0, len(input.Contacts))
+	for _, contact := range input.Contacts {
+		contacts = append(contacts, services.ContactSyncEntry{
+			PhoneNumber: contact.PhoneNumber,
+			Name:        contact.Name,
+			Deleted:     contact.Deleted,
+			UpdatedAt:   contact.UpdatedAt,
+		})
+	}
+
+	return &services.ContactSyncParams{
+		UserID:   userID,
+		Owner:    input.Owner,
+		Contacts: contacts,
+	}
+}
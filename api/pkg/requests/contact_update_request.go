@@ -0,0 +1,44 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// ContactUpdate is the payload for updating the name of an entities.Contact
+type ContactUpdate struct {
+	request
+	Name string `json:"name" example:"Jane Doe"`
+
+	// DNDStart is the time e.g. 18:00 in DNDTimezone after which messages to the contact are held until DNDEnd, leave the 3 fields empty to send messages at any time
+	DNDStart    string `json:"dnd_start" example:"18:00" validate:"optional"`
+	DNDEnd      string `json:"dnd_end" example:"09:00" validate:"optional"`
+	DNDTimezone string `json:"dnd_timezone" example:"Europe/Berlin" validate:"optional"`
+
+	ContactID string `json:"contactID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContactUpdate
+func (input *ContactUpdate) Sanitize() ContactUpdate {
+	input.Name = strings.TrimSpace(input.Name)
+	input.DNDStart = strings.TrimSpace(input.DNDStart)
+	input.DNDEnd = strings.TrimSpace(input.DNDEnd)
+	input.DNDTimezone = strings.TrimSpace(input.DNDTimezone)
+	input.ContactID = strings.TrimSpace(input.ContactID)
+	return *input
+}
+
+// ToUpdateParams converts ContactUpdate to services.ContactUpdateParams
+func (input *ContactUpdate) ToUpdateParams(userID entities.UserID) *services.ContactUpdateParams {
+	return &services.ContactUpdateParams{
+		UserID:      userID,
+		ContactID:   uuid.MustParse(input.ContactID),
+		Name:        input.Name,
+		DNDStart:    input.DNDStart,
+		DNDEnd:      input.DNDEnd,
+		DNDTimezone: input.DNDTimezone,
+	}
+}
@@ -0,0 +1,36 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// ContentExpiryRuleDryRun is the payload for matching a message with the entities.ContentExpiryRule of a user
+type ContentExpiryRuleDryRun struct {
+	request
+	// Owner is the phone number of the phone which receives the message
+	Owner string `json:"owner" example:"+18005550199"`
+	// Contact is the phone number or the sender ID of the message
+	Contact string `json:"contact" example:"Google"`
+	// Content is the text of the message
+	Content string `json:"content" example:"G-123456 is your Google verification code"`
+}
+
+// Sanitize sets defaults to ContentExpiryRuleDryRun
+func (input *ContentExpiryRuleDryRun) Sanitize() ContentExpiryRuleDryRun {
+	input.Owner = input.sanitizeAddress(input.Owner)
+	input.Contact = input.sanitizeAddress(strings.TrimSpace(input.Contact))
+	return *input
+}
+
+// ToDryRunParams converts ContentExpiryRuleDryRun to services.ContentExpiryDryRunParams
+func (input *ContentExpiryRuleDryRun) ToDryRunParams(userID entities.UserID) *services.ContentExpiryDryRunParams {
+	return &services.ContentExpiryDryRunParams{
+		UserID:  userID,
+		Owner:   input.Owner,
+		Contact: input.Contact,
+		Content: input.Content,
+	}
+}
@@ -0,0 +1,48 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// ContentExpiryRuleStore is the payload for creating an entities.ContentExpiryRule
+type ContentExpiryRuleStore struct {
+	request
+	// Owner is the phone number of the phone which receives the messages
+	Owner string `json:"owner" example:"+18005550199"`
+	// Pattern is a regular expression which matches the content of the messages e.g. a verification code
+	Pattern string `json:"pattern" example:"\\b\\d{4,8}\\b" validate:"optional"`
+	// Contacts are the phone numbers or the sender IDs of the messages, the rule matches all contacts when it is empty
+	Contacts []string `json:"contacts" example:"Google" validate:"optional"`
+	// TTLSeconds is the number of seconds after which the content of a matching message expires
+	TTLSeconds uint `json:"ttl_seconds" example:"600"`
+}
+
+// Sanitize sets defaults to ContentExpiryRuleStore
+func (input *ContentExpiryRuleStore) Sanitize() ContentExpiryRuleStore {
+	input.Owner = input.sanitizeAddress(input.Owner)
+	input.Pattern = strings.TrimSpace(input.Pattern)
+
+	var contacts []string
+	for _, contact := range input.Contacts {
+		if contact = input.sanitizeAddress(strings.TrimSpace(contact)); contact != "" {
+			contacts = append(contacts, contact)
+		}
+	}
+	input.Contacts = input.removeStringDuplicates(contacts)
+
+	return *input
+}
+
+// ToStoreParams converts ContentExpiryRuleStore to services.ContentExpiryRuleStoreParams
+func (input *ContentExpiryRuleStore) ToStoreParams(userID entities.UserID) *services.ContentExpiryRuleStoreParams {
+	return &services.ContentExpiryRuleStoreParams{
+		UserID:     userID,
+		Owner:      input.Owner,
+		Pattern:    input.Pattern,
+		Contacts:   input.Contacts,
+		TTLSeconds: input.TTLSeconds,
+	}
+}
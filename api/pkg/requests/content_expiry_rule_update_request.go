@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// ContentExpiryRuleUpdate is the payload for updating an entities.ContentExpiryRule
+type ContentExpiryRuleUpdate struct {
+	ContentExpiryRuleStore
+
+	ContentExpiryRuleID string `json:"contentExpiryRuleID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to ContentExpiryRuleUpdate
+func (input *ContentExpiryRuleUpdate) Sanitize() ContentExpiryRuleUpdate {
+	input.ContentExpiryRuleStore.Sanitize()
+	input.ContentExpiryRuleID = strings.TrimSpace(input.ContentExpiryRuleID)
+	return *input
+}
+
+// ToUpdateParams converts ContentExpiryRuleUpdate to services.ContentExpiryRuleUpdateParams
+func (input *ContentExpiryRuleUpdate) ToUpdateParams(userID entities.UserID) *services.ContentExpiryRuleUpdateParams {
+	return &services.ContentExpiryRuleUpdateParams{
+		UserID:              userID,
+		ContentExpiryRuleID: uuid.MustParse(input.ContentExpiryRuleID),
+		Owner:               input.Owner,
+		Pattern:             input.Pattern,
+		Contacts:            input.Contacts,
+		TTLSeconds:          input.TTLSeconds,
+	}
+}
@@ -0,0 +1,58 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// FallbackProviderUpsert is the payload for setting the entities.FallbackProvider of a user
+type FallbackProviderUpsert struct {
+	request
+	// Type is the provider which sends the messages, it is either twilio or http
+	Type string `json:"type" example:"twilio"`
+	// From is the phone number of the provider which sends the messages
+	From string `json:"from" example:"+18005550199"`
+
+	// AccountSID is the account SID of a twilio provider
+	AccountSID string `json:"account_sid" example:"AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1" validate:"optional"`
+	// AuthToken is the auth token of a twilio provider
+	AuthToken string `json:"auth_token" example:"3c3f6d7b8e2a4f1d9c0b5a6e7d8f9a0b" validate:"optional"`
+
+	// URL is the endpoint of an http provider
+	URL string `json:"url" example:"https://example.com/sms" validate:"optional"`
+	// SigningKey signs the requests to an http provider and verifies its status callbacks
+	SigningKey string `json:"signing_key" example:"DGW8NwQp7fxcYeJ2" validate:"optional"`
+
+	// OfflineAfterSeconds is the number of seconds without a heartbeat after which the new messages of a phone are sent by the provider, use 0 to disable it
+	OfflineAfterSeconds uint `json:"offline_after_seconds" example:"900"`
+	// FallbackOnExpired sends a message with the provider when it expires after the last send attempt of the phone
+	FallbackOnExpired bool `json:"fallback_on_expired" example:"true"`
+}
+
+// Sanitize sets defaults to FallbackProviderUpsert
+func (input *FallbackProviderUpsert) Sanitize() FallbackProviderUpsert {
+	input.Type = strings.ToLower(strings.TrimSpace(input.Type))
+	input.From = input.sanitizeAddress(input.From)
+	input.AccountSID = strings.TrimSpace(input.AccountSID)
+	input.AuthToken = strings.TrimSpace(input.AuthToken)
+	input.URL = strings.TrimSpace(input.URL)
+	input.SigningKey = strings.TrimSpace(input.SigningKey)
+	return *input
+}
+
+// ToUpsertParams converts FallbackProviderUpsert to services.FallbackProviderUpsertParams
+func (input *FallbackProviderUpsert) ToUpsertParams(user entities.AuthUser) *services.FallbackProviderUpsertParams {
+	return &services.FallbackProviderUpsertParams{
+		UserID:              user.ID,
+		Type:                entities.FallbackProviderType(input.Type),
+		From:                input.From,
+		AccountSID:          input.AccountSID,
+		AuthToken:           input.AuthToken,
+		URL:                 input.URL,
+		SigningKey:          input.SigningKey,
+		OfflineAfterSeconds: input.OfflineAfterSeconds,
+		FallbackOnExpired:   input.FallbackOnExpired,
+	}
+}
@@ -2,6 +2,7 @@ package requests
 
 import (
 	"strings"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 )
@@ -13,6 +14,8 @@ type HeartbeatIndex struct {
 	Owner string `json:"owner" query:"owner"`
 	Query string `json:"query" query:"query"`
 	Limit string `json:"limit" query:"limit"`
+	From  string `json:"from" query:"from"`
+	To    string `json:"to" query:"to"`
 }
 
 // Sanitize sets defaults to MessageOutstanding
@@ -26,14 +29,52 @@ func (input *HeartbeatIndex) Sanitize() HeartbeatIndex {
 	if input.Skip == "" {
 		input.Skip = "0"
 	}
+	input.From = strings.TrimSpace(input.From)
+	input.To = strings.TrimSpace(input.To)
 	return *input
 }
 
-// ToIndexParams converts HeartbeatIndex to repositories.IndexParams
-func (input *HeartbeatIndex) ToIndexParams() repositories.IndexParams {
-	return repositories.IndexParams{
-		Skip:  input.getInt(input.Skip),
-		Query: input.Query,
-		Limit: input.getInt(input.Limit),
+// ToIndexParams converts HeartbeatIndex to repositories.HeartbeatIndexParams
+func (input *HeartbeatIndex) ToIndexParams() repositories.HeartbeatIndexParams {
+	return repositories.HeartbeatIndexParams{
+		IndexParams: repositories.IndexParams{
+			Skip:  input.getInt(input.Skip),
+			Query: input.Query,
+			Limit: input.getInt(input.Limit),
+		},
+		From: input.getTime(input.From),
+		To:   input.getTime(input.To),
 	}
 }
+
+// HeartbeatHourlyIndex is the payload for counting entities.Heartbeat of a phone number per hour
+type HeartbeatHourlyIndex struct {
+	request
+	Owner string `json:"owner" query:"owner"`
+	From  string `json:"from" query:"from"`
+	To    string `json:"to" query:"to"`
+}
+
+// Sanitize sets defaults to HeartbeatHourlyIndex
+func (input *HeartbeatHourlyIndex) Sanitize() HeartbeatHourlyIndex {
+	input.Owner = input.sanitizeAddress(input.Owner)
+	input.To = strings.TrimSpace(input.To)
+	if input.To == "" {
+		input.To = time.Now().UTC().Format(time.RFC3339)
+	}
+	input.From = strings.TrimSpace(input.From)
+	if input.From == "" && input.getTime(input.To) != nil {
+		input.From = input.getTime(input.To).Add(-24 * time.Hour).Format(time.RFC3339)
+	}
+	return *input
+}
+
+// FromTime returns the start of the time range
+func (input *HeartbeatHourlyIndex) FromTime() time.Time {
+	return *input.getTime(input.From)
+}
+
+// ToTime returns the end of the time range
+func (input *HeartbeatHourlyIndex) ToTime() time.Time {
+	return *input.getTime(input.To)
+}
@@ -12,6 +12,12 @@ type HeartbeatStore struct {
 	request
 	Charging bool   `json:"charging"`
 	Owner    string `json:"owner"`
+
+	// BatteryLevel is the battery percentage of the phone
+	BatteryLevel *uint `json:"battery_level" example:"80" validate:"optional"`
+
+	// Timestamp is the time on the phone when the heartbeat was sent, it is used to correct the clock skew of the phone
+	Timestamp *time.Time `json:"timestamp" example:"2022-06-05T14:26:09.527976+03:00" validate:"optional"`
 }
 
 // Sanitize sets defaults to MessageOutstanding
@@ -21,12 +27,15 @@ func (input *HeartbeatStore) Sanitize() HeartbeatStore {
 }
 
 // ToStoreParams converts HeartbeatIndex to repositories.IndexParams
-func (input *HeartbeatStore) ToStoreParams(user entities.AuthUser, version string) services.HeartbeatStoreParams {
+func (input *HeartbeatStore) ToStoreParams(user entities.AuthUser, source string, version string) services.HeartbeatStoreParams {
 	return services.HeartbeatStoreParams{
-		Owner:     input.Owner,
-		Version:   version,
-		Charging:  input.Charging,
-		Timestamp: time.Now().UTC(),
-		UserID:    user.ID,
+		Owner:             input.Owner,
+		Version:           version,
+		Charging:          input.Charging,
+		BatteryLevel:      input.BatteryLevel,
+		Timestamp:         time.Now().UTC(),
+		Source:            source,
+		UserID:            user.ID,
+		ReportedTimestamp: input.Timestamp,
 	}
 }
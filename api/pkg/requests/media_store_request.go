@@ -0,0 +1,32 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// MediaStore is the payload for registering an entities.Media before its content is uploaded
+type MediaStore struct {
+	request
+	// ContentType is the content type of the file e.g. image/jpeg, it must be sent in the Content-Type header of the upload
+	ContentType string `json:"content_type" example:"image/jpeg"`
+	// Size is the number of bytes of the file
+	Size int64 `json:"size" example:"524288"`
+}
+
+// Sanitize sets defaults to MediaStore
+func (input *MediaStore) Sanitize() MediaStore {
+	input.ContentType = strings.ToLower(strings.TrimSpace(input.ContentType))
+	return *input
+}
+
+// ToRegisterParams converts MediaStore to services.MediaRegisterParams
+func (input *MediaStore) ToRegisterParams(userID entities.UserID) *services.MediaRegisterParams {
+	return &services.MediaRegisterParams{
+		UserID:      userID,
+		ContentType: input.ContentType,
+		Size:        input.Size,
+	}
+}
@@ -1,6 +1,7 @@
 package requests
 
 import (
+	"strings"
 	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
@@ -8,6 +9,7 @@ import (
 	"github.com/nyaruka/phonenumbers"
 
 	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
 )
 
 // MessageBulkSend is the payload for sending bulk SMS messages
@@ -17,6 +19,9 @@ type MessageBulkSend struct {
 	To      []string `json:"to" example:"+18005550100,+18005550100"`
 	Content string   `json:"content" example:"This is a sample text message"`
 
+	// GroupID sends the message to each member of a contact group instead of the phone numbers in To
+	GroupID string `json:"group_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb" validate:"optional"`
+
 	// RequestID is an optional parameter used to track a request from the client's perspective
 	RequestID string `json:"request_id" example:"153554b5-ae44-44a0-8f4f-7bbac5657ad4" validate:"optional"`
 }
@@ -29,6 +34,7 @@ func (input *MessageBulkSend) Sanitize() MessageBulkSend {
 	}
 	input.To = to
 	input.From = input.sanitizeAddress(input.From)
+	input.GroupID = strings.TrimSpace(input.GroupID)
 	return *input
 }
 
@@ -51,3 +57,23 @@ func (input *MessageBulkSend) ToMessageSendParams(userID entities.UserID, source
 
 	return result
 }
+
+// ContactGroupID is the ID of the contact group which the message is sent to
+func (input *MessageBulkSend) ContactGroupID() uuid.UUID {
+	return uuid.MustParse(input.GroupID)
+}
+
+// ToContactGroupSendParams converts MessageBulkSend to services.ContactGroupSendParams for the snapshot of the members of a group
+func (input *MessageBulkSend) ToContactGroupSendParams(userID entities.UserID, source string, group *entities.ContactGroup, members []*entities.Contact, reservedAt time.Time) *services.ContactGroupSendParams {
+	from, _ := phonenumbers.Parse(input.From, phonenumbers.UNKNOWN_REGION)
+	return &services.ContactGroupSendParams{
+		UserID:               userID,
+		Owner:                from,
+		Content:              input.Content,
+		Source:               source,
+		RequestID:            input.sanitizeStringPointer(input.RequestID),
+		Group:                group,
+		Members:              members,
+		DailyLimitReservedAt: reservedAt,
+	}
+}
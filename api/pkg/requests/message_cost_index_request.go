@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+)
+
+// MessageCostIndex is the payload for summarizing the estimated cost of the messages of a user
+type MessageCostIndex struct {
+	request
+	From      string `json:"from" query:"from"`
+	To        string `json:"to" query:"to"`
+	RequestID string `json:"request_id" query:"request_id"`
+	GroupBy   string `json:"group_by" query:"group_by"`
+}
+
+// Sanitize sets defaults to MessageCostIndex
+func (input *MessageCostIndex) Sanitize() MessageCostIndex {
+	input.From = strings.TrimSpace(input.From)
+	input.To = strings.TrimSpace(input.To)
+	input.RequestID = strings.TrimSpace(input.RequestID)
+	input.GroupBy = strings.ToLower(strings.TrimSpace(input.GroupBy))
+	return *input
+}
+
+// ToCostParams converts MessageCostIndex to repositories.MessageCostParams
+func (input *MessageCostIndex) ToCostParams() repositories.MessageCostParams {
+	return repositories.MessageCostParams{
+		From:             input.getTime(input.From),
+		To:               input.getTime(input.To),
+		RequestID:        input.sanitizeStringPointer(input.RequestID),
+		GroupByRequestID: input.GroupBy == "request_id",
+	}
+}
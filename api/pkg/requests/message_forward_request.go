@@ -0,0 +1,45 @@
+package requests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// MessageForward is the payload for forwarding a received entities.Message to another contact
+type MessageForward struct {
+	request
+	// From is the phone number which received the message, the forwarded message is sent from the same phone
+	From string `json:"from" example:"+18005550199"`
+	// To is the phone number which the message is forwarded to
+	To string `json:"to" example:"+18005550100"`
+	// Prefix is added before the content of the forwarded message
+	Prefix string `json:"prefix" example:"FWD from +237677777777:" validate:"optional"`
+
+	MessageID string `json:"messageID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to MessageForward
+func (input *MessageForward) Sanitize() MessageForward {
+	input.From = input.sanitizeAddress(input.From)
+	input.To = input.sanitizeContactAddress(input.To, input.From)
+	input.Prefix = strings.TrimSpace(input.Prefix)
+	input.MessageID = strings.TrimSpace(input.MessageID)
+	return *input
+}
+
+// ToForwardParams converts MessageForward to services.MessageForwardParams
+func (input *MessageForward) ToForwardParams(userID entities.UserID, source string) services.MessageForwardParams {
+	return services.MessageForwardParams{
+		UserID:            userID,
+		Source:            source,
+		MessageID:         uuid.MustParse(input.MessageID),
+		Owner:             input.From,
+		Contact:           input.To,
+		Prefix:            input.Prefix,
+		RequestReceivedAt: time.Now().UTC(),
+	}
+}
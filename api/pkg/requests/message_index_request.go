@@ -13,11 +13,31 @@ import (
 
 // MessageIndex is the payload fetching entities.Message sent between 2 numbers
 type MessageIndex struct {
+	request
 	Skip    string `json:"skip" query:"skip"`
 	Contact string `json:"contact" query:"contact"`
 	Owner   string `json:"owner" query:"owner"`
 	Query   string `json:"query" query:"query"`
 	Limit   string `json:"limit" query:"limit"`
+
+	// IncludeArchived also returns the messages which have been moved into the archive
+	IncludeArchived bool `json:"include_archived" query:"include_archived"`
+
+	// IncludeTotal also counts the messages which match the filters in the X-Total-Count header
+	IncludeTotal bool `json:"include_total" query:"include_total"`
+
+	// Status only returns the messages with the status e.g. delivered
+	Status string `json:"status" query:"status"`
+
+	// After only returns the messages at or after the RFC3339 timestamp
+	After string `json:"after" query:"after"`
+
+	// Before only returns the messages before the RFC3339 timestamp
+	Before string `json:"before" query:"before"`
+
+	// Metadata only returns the messages whose metadata has all the key value pairs, it is set from the
+	// metadata[key]=value query parameters by the handler because the query parser does not decode maps
+	Metadata map[string]string `json:"metadata" query:"-"`
 }
 
 // Sanitize sets defaults to MessageOutstanding
@@ -36,6 +56,10 @@ func (input *MessageIndex) Sanitize() MessageIndex {
 		input.Skip = "0"
 	}
 
+	input.Status = strings.ToLower(strings.TrimSpace(input.Status))
+	input.After = strings.TrimSpace(input.After)
+	input.Before = strings.TrimSpace(input.Before)
+
 	return *input
 }
 
@@ -43,9 +67,14 @@ func (input *MessageIndex) Sanitize() MessageIndex {
 func (input *MessageIndex) ToGetParams(userID entities.UserID) services.MessageGetParams {
 	return services.MessageGetParams{
 		IndexParams: repositories.IndexParams{
-			Skip:  input.getInt(input.Skip),
-			Query: input.Query,
-			Limit: input.getInt(input.Limit),
+			Skip:            input.getInt(input.Skip),
+			Query:           input.Query,
+			Limit:           input.getInt(input.Limit),
+			IncludeArchived: input.IncludeArchived,
+			Status:          entities.MessageStatus(input.Status),
+			After:           input.getTime(input.After),
+			Before:          input.getTime(input.Before),
+			Metadata:        input.Metadata,
 		},
 		UserID:  userID,
 		Owner:   input.Owner,
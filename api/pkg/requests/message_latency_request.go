@@ -0,0 +1,61 @@
+package requests
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// MessageLatency is the payload for summarizing the send latency of the messages of a user against an SLO
+type MessageLatency struct {
+	request
+	From      string `json:"from" query:"from"`
+	To        string `json:"to" query:"to"`
+	Owner     string `json:"owner" query:"owner"`
+	Threshold string `json:"threshold" query:"threshold"`
+	Objective string `json:"objective" query:"objective"`
+}
+
+// Sanitize sets defaults to MessageLatency
+func (input *MessageLatency) Sanitize() MessageLatency {
+	input.Owner = strings.TrimSpace(input.Owner)
+	if input.Owner != "" {
+		input.Owner = input.sanitizeAddress(input.Owner)
+	}
+
+	input.Threshold = strings.TrimSpace(input.Threshold)
+	if input.Threshold == "" {
+		input.Threshold = "60"
+	}
+
+	input.Objective = strings.TrimSpace(input.Objective)
+	if input.Objective == "" {
+		input.Objective = "0.95"
+	}
+
+	input.To = strings.TrimSpace(input.To)
+	if input.To == "" {
+		input.To = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	input.From = strings.TrimSpace(input.From)
+	if input.From == "" && input.getTime(input.To) != nil {
+		input.From = input.getTime(input.To).AddDate(0, 0, -30).Format(time.RFC3339)
+	}
+
+	return *input
+}
+
+// ToLatencyParams converts MessageLatency to services.MessageLatencyParams
+func (input *MessageLatency) ToLatencyParams() *services.MessageLatencyParams {
+	objective, _ := strconv.ParseFloat(input.Objective, 64)
+	return &services.MessageLatencyParams{
+		From:      *input.getTime(input.From),
+		To:        *input.getTime(input.To),
+		Owner:     input.sanitizeStringPointer(input.Owner),
+		Threshold: time.Duration(input.getInt(input.Threshold)) * time.Second,
+		Objective: objective,
+	}
+}
@@ -0,0 +1,13 @@
+package requests
+
+// MessageQueueStats is the payload for fetching the entities.PhoneQueueStats of a phone
+type MessageQueueStats struct {
+	request
+	Owner string `json:"owner" query:"owner"`
+}
+
+// Sanitize sets defaults to MessageQueueStats
+func (input *MessageQueueStats) Sanitize() MessageQueueStats {
+	input.Owner = input.sanitizeAddress(input.Owner)
+	return *input
+}
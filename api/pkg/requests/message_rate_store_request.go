@@ -0,0 +1,50 @@
+package requests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// MessageRateStore is the payload for creating an entities.MessageRate
+type MessageRateStore struct {
+	request
+	// Owner is the phone number which sends the messages, the rate is for all the phones of the user when it is empty
+	Owner string `json:"owner" example:"+18005550199" validate:"optional"`
+	// Prefix is the country code or the start of the phone numbers of the contacts e.g. +44, the rate is for all the contacts when it is empty
+	Prefix string `json:"prefix" example:"+44" validate:"optional"`
+	// Rate is the cost of one SMS segment
+	Rate     float64 `json:"rate" example:"0.0075"`
+	Currency string  `json:"currency" example:"USD"`
+	// EffectiveFrom is the RFC3339 time from when the rate applies, it is the current time when it is empty
+	EffectiveFrom string `json:"effective_from" example:"2022-06-01T00:00:00Z" validate:"optional"`
+}
+
+// Sanitize sets defaults to MessageRateStore
+func (input *MessageRateStore) Sanitize() MessageRateStore {
+	input.Owner = strings.TrimSpace(input.Owner)
+	if input.Owner != "" {
+		input.Owner = input.sanitizeAddress(input.Owner)
+	}
+	input.Prefix = input.sanitizePrefix(input.Prefix)
+	input.Currency = strings.ToUpper(strings.TrimSpace(input.Currency))
+	input.EffectiveFrom = strings.TrimSpace(input.EffectiveFrom)
+	if input.EffectiveFrom == "" {
+		input.EffectiveFrom = time.Now().UTC().Format(time.RFC3339)
+	}
+	return *input
+}
+
+// ToStoreParams converts MessageRateStore to services.MessageRateStoreParams
+func (input *MessageRateStore) ToStoreParams(userID entities.UserID) *services.MessageRateStoreParams {
+	return &services.MessageRateStoreParams{
+		UserID:        userID,
+		Owner:         input.Owner,
+		Prefix:        input.Prefix,
+		Rate:          input.Rate,
+		Currency:      input.Currency,
+		EffectiveFrom: input.getTime(input.EffectiveFrom).UTC(),
+	}
+}
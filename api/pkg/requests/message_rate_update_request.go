@@ -0,0 +1,31 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// MessageRateUpdate is the payload for updating an entities.MessageRate
+type MessageRateUpdate struct {
+	MessageRateStore
+
+	MessageRateID string `json:"messageRateID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to MessageRateUpdate
+func (input *MessageRateUpdate) Sanitize() MessageRateUpdate {
+	input.MessageRateStore.Sanitize()
+	input.MessageRateID = strings.TrimSpace(input.MessageRateID)
+	return *input
+}
+
+// ToUpdateParams converts MessageRateUpdate to services.MessageRateUpdateParams
+func (input *MessageRateUpdate) ToUpdateParams(userID entities.UserID) *services.MessageRateUpdateParams {
+	return &services.MessageRateUpdateParams{
+		MessageRateStoreParams: *input.ToStoreParams(userID),
+		MessageRateID:          uuid.MustParse(input.MessageRateID),
+	}
+}
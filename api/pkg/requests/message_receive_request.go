@@ -21,6 +21,10 @@ type MessageReceive struct {
 	SIM entities.SIM `json:"sim" example:"SIM1"`
 	// Timestamp is the time when the event was emitted, Please send the timestamp in UTC with as much precision as possible
 	Timestamp time.Time `json:"timestamp" example:"2022-06-05T14:26:09.527976+03:00"`
+	// IsEncrypted is true when the content was end-to-end encrypted by the phone before it was uploaded
+	IsEncrypted bool `json:"is_encrypted" example:"false"`
+	// EncryptionKeyID is a hint of the key which the API client uses to decrypt the content
+	EncryptionKeyID string `json:"encryption_key_id" example:"key-2022-06"`
 }
 
 // Sanitize sets defaults to MessageReceive
@@ -30,6 +34,7 @@ func (input *MessageReceive) Sanitize() MessageReceive {
 	if strings.TrimSpace(string(input.SIM)) == "" || input.SIM == ("DEFAULT") {
 		input.SIM = entities.SIM1
 	}
+	input.EncryptionKeyID = strings.TrimSpace(input.EncryptionKeyID)
 	return *input
 }
 
@@ -37,12 +42,14 @@ func (input *MessageReceive) Sanitize() MessageReceive {
 func (input *MessageReceive) ToMessageReceiveParams(userID entities.UserID, source string) services.MessageReceiveParams {
 	phone, _ := phonenumbers.Parse(input.To, phonenumbers.UNKNOWN_REGION)
 	return services.MessageReceiveParams{
-		Source:    source,
-		Contact:   input.From,
-		UserID:    userID,
-		Timestamp: input.Timestamp,
-		Owner:     *phone,
-		Content:   input.Content,
-		SIM:       input.SIM,
+		Source:          source,
+		Contact:         input.From,
+		UserID:          userID,
+		Timestamp:       input.Timestamp,
+		Owner:           *phone,
+		Content:         input.Content,
+		SIM:             input.SIM,
+		IsEncrypted:     input.IsEncrypted,
+		EncryptionKeyID: input.sanitizeStringPointer(input.EncryptionKeyID),
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
 
 	"github.com/nyaruka/phonenumbers"
 
@@ -14,7 +15,8 @@ import (
 // MessageSend is the payload for sending and SMS message
 type MessageSend struct {
 	request
-	From    string `json:"from" example:"+18005550199"`
+	// From is optional, the default phone of the user is used when it is empty
+	From    string `json:"from" example:"+18005550199" validate:"optional"`
 	To      string `json:"to" example:"+18005550100"`
 	Content string `json:"content" example:"This is a sample text message"`
 
@@ -22,6 +24,28 @@ type MessageSend struct {
 	RequestID string `json:"request_id" example:"153554b5-ae44-44a0-8f4f-7bbac5657ad4" validate:"optional"`
 	// SendAt is an optional parameter used to schedule a message to be sent at a later time
 	SendAt *time.Time `json:"send_at" example:"2022-06-05T14:26:09.527976+03:00" validate:"optional"`
+	// SIM is an optional parameter used to select the SIM card slot which sends the message, the phone's default SIM is used when it is empty
+	SIM string `json:"sim" example:"SIM1" validate:"optional"`
+	// AllowFallback is an optional parameter which allows the fallback provider of the user to send the message when the phone cannot send it
+	AllowFallback bool `json:"allow_fallback" example:"false" validate:"optional"`
+	// EnforceDND is an optional parameter for a contact in its do-not-disturb window, the message is sent when the window ends with "defer" or it is rejected with "strict"
+	EnforceDND string `json:"enforce_dnd" example:"defer" validate:"optional"`
+	// ValidateRecipient is an optional parameter which rejects the message when the recipient is a landline or an invalid number
+	ValidateRecipient bool `json:"validate_recipient" example:"false" validate:"optional"`
+	// ShortenURLs is an optional parameter which replaces the URLs in the content with short links which count their clicks
+	ShortenURLs bool `json:"shorten_urls" example:"false" validate:"optional"`
+	// IsEncrypted is an optional parameter which is true when the content is end-to-end encrypted with the key of the phone, it is required when the phone has encryption enabled
+	IsEncrypted bool `json:"is_encrypted" example:"false" validate:"optional"`
+	// EncryptionKeyID is an optional hint of the key which the phone uses to decrypt the content
+	EncryptionKeyID string `json:"encryption_key_id" example:"key-2022-06" validate:"optional"`
+	// MediaIDs is an optional list of the IDs of the media which is uploaded with POST /v1/media and sent with the content as an MMS message
+	MediaIDs []string `json:"media_ids" example:"32343a19-da5e-4b1b-a767-3298a73703cb" validate:"optional"`
+	// RetryPolicy is an optional parameter which overrides the retry policy of the user for this message, the fields which are 0 are not overridden
+	RetryPolicy *entities.RetryPolicy `json:"retry_policy" validate:"optional"`
+	// RequestDeliveryReport is an optional parameter which determines if the phone requests a delivery report from the carrier, the default of the user is used when it is omitted
+	RequestDeliveryReport *bool `json:"request_delivery_report" example:"true" validate:"optional"`
+	// Metadata is an optional set of key value pairs which are stored with the message e.g. an order_id so that the message can be fetched with the metadata filter of GET /v1/messages
+	Metadata map[string]string `json:"metadata" validate:"optional"`
 }
 
 // Sanitize sets defaults to MessageReceive
@@ -29,12 +53,39 @@ func (input *MessageSend) Sanitize() MessageSend {
 	input.To = input.sanitizeAddress(input.To)
 	input.RequestID = strings.TrimSpace(input.RequestID)
 	input.From = input.sanitizeAddress(input.From)
+	input.SIM = strings.ToUpper(strings.TrimSpace(input.SIM))
+	input.EnforceDND = strings.ToLower(strings.TrimSpace(input.EnforceDND))
+	input.EncryptionKeyID = strings.TrimSpace(input.EncryptionKeyID)
+	input.MediaIDs = input.sanitizeMediaIDs(input.MediaIDs)
 	return *input
 }
 
+// sanitizeMediaIDs removes the duplicate media IDs and keeps the order in which the media is sent
+func (input *MessageSend) sanitizeMediaIDs(mediaIDs []string) []string {
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	seen := map[string]struct{}{}
+	result := make([]string, 0, len(mediaIDs))
+	for _, mediaID := range mediaIDs {
+		mediaID = strings.ToLower(strings.TrimSpace(mediaID))
+		if _, ok := seen[mediaID]; ok {
+			continue
+		}
+		seen[mediaID] = struct{}{}
+		result = append(result, mediaID)
+	}
+	return result
+}
+
 // ToMessageSendParams converts MessageSend to services.MessageSendParams
 func (input *MessageSend) ToMessageSendParams(userID entities.UserID, source string) services.MessageSendParams {
-	from, _ := phonenumbers.Parse(input.From, phonenumbers.UNKNOWN_REGION)
+	var from *phonenumbers.PhoneNumber
+	if input.From != "" {
+		from, _ = phonenumbers.Parse(input.From, phonenumbers.UNKNOWN_REGION)
+	}
+
 	return services.MessageSendParams{
 		Source:            source,
 		Owner:             from,
@@ -44,5 +95,38 @@ func (input *MessageSend) ToMessageSendParams(userID entities.UserID, source str
 		RequestReceivedAt: time.Now().UTC(),
 		Contact:           input.sanitizeAddress(input.To),
 		Content:           input.Content,
+		SIM:               entities.SIM(input.SIM),
+		AllowFallback:     input.AllowFallback,
+		EnforceDND:        services.DNDEnforcement(input.EnforceDND),
+		ValidateRecipient: input.ValidateRecipient,
+		ShortenURLs:       input.ShortenURLs,
+		IsEncrypted:       input.IsEncrypted,
+		EncryptionKeyID:   input.sanitizeStringPointer(input.EncryptionKeyID),
+		MediaIDs:          input.mediaUUIDs(),
+		RetryPolicy:       input.retryPolicy(),
+		Metadata:          entities.MessageMetadata(input.Metadata),
+
+		RequestDeliveryReport: input.RequestDeliveryReport,
+	}
+}
+
+// retryPolicy is the entities.RetryPolicy which overrides the retry policy of the user, no field is overridden when it is nil
+func (input *MessageSend) retryPolicy() entities.RetryPolicy {
+	if input.RetryPolicy == nil {
+		return entities.RetryPolicy{}
+	}
+	return *input.RetryPolicy
+}
+
+// mediaUUIDs converts the validated MessageSend.MediaIDs into []uuid.UUID
+func (input *MessageSend) mediaUUIDs() []uuid.UUID {
+	if len(input.MediaIDs) == 0 {
+		return nil
+	}
+
+	result := make([]uuid.UUID, 0, len(input.MediaIDs))
+	for _, mediaID := range input.MediaIDs {
+		result = append(result, uuid.MustParse(mediaID))
 	}
+	return result
 }
@@ -0,0 +1,38 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// MessageStatus is the payload for fetching the status of many entities.Message by ID
+type MessageStatus struct {
+	request
+	MessageIDs []string `json:"message_ids" example:"32343a19-da5e-4b1b-a767-3298a73703cb,153554b5-ae44-44a0-8f4f-7bbac5657ad4"`
+}
+
+// Sanitize sets defaults to MessageStatus, the duplicate IDs are removed and the order of the IDs is kept
+func (input *MessageStatus) Sanitize() MessageStatus {
+	seen := map[string]struct{}{}
+	messageIDs := make([]string, 0, len(input.MessageIDs))
+	for _, messageID := range input.MessageIDs {
+		messageID = strings.ToLower(strings.TrimSpace(messageID))
+		if _, ok := seen[messageID]; ok {
+			continue
+		}
+		seen[messageID] = struct{}{}
+		messageIDs = append(messageIDs, messageID)
+	}
+	input.MessageIDs = messageIDs
+	return *input
+}
+
+// ToUUIDs converts the validated MessageStatus.MessageIDs into []uuid.UUID
+func (input *MessageStatus) ToUUIDs() []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(input.MessageIDs))
+	for _, messageID := range input.MessageIDs {
+		result = append(result, uuid.MustParse(messageID))
+	}
+	return result
+}
@@ -0,0 +1,22 @@
+package requests
+
+import (
+	"strings"
+)
+
+// MessageStream is the payload for streaming the events of entities.Message
+type MessageStream struct {
+	request
+	// Owner is optional, the events of all the phones of the user are streamed when it is empty
+	Owner string `json:"owner" query:"owner"`
+
+	// LastEventID is the ID of the last event which was received by the client, the events after it are sent before the live events
+	LastEventID string `json:"last_event_id" query:"last_event_id"`
+}
+
+// Sanitize sets defaults to MessageStream
+func (input *MessageStream) Sanitize() MessageStream {
+	input.Owner = input.sanitizeAddress(input.Owner)
+	input.LastEventID = strings.TrimSpace(input.LastEventID)
+	return *input
+}
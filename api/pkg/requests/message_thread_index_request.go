@@ -18,6 +18,7 @@ type MessageThreadIndex struct {
 	Query      string `json:"query" query:"query"`
 	Limit      string `json:"limit" query:"limit"`
 	Owner      string `json:"owner" query:"owner"`
+	Label      string `json:"label" query:"label"`
 }
 
 // Sanitize sets defaults to MessageOutstanding
@@ -33,6 +34,7 @@ func (input *MessageThreadIndex) Sanitize() MessageThreadIndex {
 	input.IsArchived = input.sanitizeBool(input.IsArchived)
 	input.Query = strings.TrimSpace(input.Query)
 	input.Owner = input.sanitizeAddress(input.Owner)
+	input.Label = strings.ToLower(strings.TrimSpace(input.Label))
 
 	input.Skip = strings.TrimSpace(input.Skip)
 	if input.Skip == "" {
@@ -53,5 +55,6 @@ func (input *MessageThreadIndex) ToGetParams(userID entities.UserID) services.Me
 		UserID:     userID,
 		IsArchived: input.getBool(input.IsArchived),
 		Owner:      input.Owner,
+		Label:      input.Label,
 	}
 }
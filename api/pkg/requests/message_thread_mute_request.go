@@ -0,0 +1,41 @@
+package requests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// MessageThreadMute is the payload for muting the notifications of a message thread
+type MessageThreadMute struct {
+	request
+	// MutedUntil is the time when the mute is lifted, the thread is muted until it is unmuted when it is empty
+	MutedUntil *time.Time `json:"muted_until" example:"2022-06-05T18:26:09.527976+03:00" validate:"optional"`
+	// MuteWebhooks also stops the webhooks of the messages received in the thread
+	MuteWebhooks bool `json:"mute_webhooks" example:"false"`
+
+	MessageThreadID string `json:"messageThreadID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to MessageThreadMute
+func (input *MessageThreadMute) Sanitize() MessageThreadMute {
+	input.MessageThreadID = strings.TrimSpace(input.MessageThreadID)
+	if input.MutedUntil != nil {
+		mutedUntil := input.MutedUntil.UTC()
+		input.MutedUntil = &mutedUntil
+	}
+	return *input
+}
+
+// ToMuteParams converts MessageThreadMute to services.MessageThreadMuteParams
+func (input *MessageThreadMute) ToMuteParams(userID entities.UserID) services.MessageThreadMuteParams {
+	return services.MessageThreadMuteParams{
+		UserID:          userID,
+		MessageThreadID: uuid.MustParse(input.MessageThreadID),
+		MutedUntil:      input.MutedUntil,
+		MuteWebhooks:    input.MuteWebhooks,
+	}
+}
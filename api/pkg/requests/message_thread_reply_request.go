@@ -0,0 +1,58 @@
+package requests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// MessageThreadReply is the payload for sending a reply in an entities.MessageThread
+type MessageThreadReply struct {
+	request
+	Content string `json:"content" example:"This is a sample reply"`
+
+	// RequestID is an optional parameter used to track a request from the client's perspective
+	RequestID string `json:"request_id" example:"153554b5-ae44-44a0-8f4f-7bbac5657ad4" validate:"optional"`
+	// SendAt is an optional parameter used to schedule the reply to be sent at a later time
+	SendAt *time.Time `json:"send_at" example:"2022-06-05T14:26:09.527976+03:00" validate:"optional"`
+	// SIM is an optional parameter used to select the SIM card slot which sends the reply, the phone's default SIM is used when it is empty
+	SIM string `json:"sim" example:"SIM1" validate:"optional"`
+	// AllowFallback is an optional parameter which allows the fallback provider of the user to send the reply when the phone cannot send it
+	AllowFallback bool `json:"allow_fallback" example:"false" validate:"optional"`
+	// ShortenURLs is an optional parameter which replaces the URLs in the content with short links which count their clicks
+	ShortenURLs bool `json:"shorten_urls" example:"false" validate:"optional"`
+	// RequestDeliveryReport is an optional parameter which determines if the phone requests a delivery report from the carrier, the default of the user is used when it is omitted
+	RequestDeliveryReport *bool `json:"request_delivery_report" example:"true" validate:"optional"`
+
+	MessageThreadID string `json:"messageThreadID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to MessageThreadReply
+func (input *MessageThreadReply) Sanitize() MessageThreadReply {
+	input.RequestID = strings.TrimSpace(input.RequestID)
+	input.SIM = strings.ToUpper(strings.TrimSpace(input.SIM))
+	input.MessageThreadID = strings.TrimSpace(input.MessageThreadID)
+	return *input
+}
+
+// ThreadID is the ID of the entities.MessageThread which is replied in
+func (input *MessageThreadReply) ThreadID() uuid.UUID {
+	return uuid.MustParse(input.MessageThreadID)
+}
+
+// ToReplyOptions converts MessageThreadReply to services.MessageReplyOptions
+func (input *MessageThreadReply) ToReplyOptions(source string) services.MessageReplyOptions {
+	return services.MessageReplyOptions{
+		Source:                source,
+		RequestID:             input.sanitizeStringPointer(input.RequestID),
+		SendAt:                input.SendAt,
+		SIM:                   entities.SIM(input.SIM),
+		AllowFallback:         input.AllowFallback,
+		ShortenURLs:           input.ShortenURLs,
+		RequestDeliveryReport: input.RequestDeliveryReport,
+		RequestReceivedAt:     time.Now().UTC(),
+	}
+}
@@ -0,0 +1,64 @@
+package requests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// MessageTimeSeries is the payload for counting the messages of a user in each hour or day of a time range
+type MessageTimeSeries struct {
+	request
+	From        string `json:"from" query:"from"`
+	To          string `json:"to" query:"to"`
+	Granularity string `json:"granularity" query:"granularity"`
+	Timezone    string `json:"timezone" query:"timezone"`
+	Owner       string `json:"owner" query:"owner"`
+}
+
+// Sanitize sets defaults to MessageTimeSeries
+func (input *MessageTimeSeries) Sanitize() MessageTimeSeries {
+	input.Owner = strings.TrimSpace(input.Owner)
+	if input.Owner != "" {
+		input.Owner = input.sanitizeAddress(input.Owner)
+	}
+
+	input.Granularity = strings.ToLower(strings.TrimSpace(input.Granularity))
+	if input.Granularity == "" {
+		input.Granularity = string(entities.MessageStatisticsGranularityDay)
+	}
+
+	input.Timezone = strings.TrimSpace(input.Timezone)
+	if input.Timezone == "" {
+		input.Timezone = time.UTC.String()
+	}
+
+	input.To = strings.TrimSpace(input.To)
+	if input.To == "" {
+		input.To = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	input.From = strings.TrimSpace(input.From)
+	if input.From == "" && input.getTime(input.To) != nil {
+		input.From = input.getTime(input.To).AddDate(0, 0, -30).Format(time.RFC3339)
+		if input.Granularity == string(entities.MessageStatisticsGranularityHour) {
+			input.From = input.getTime(input.To).Add(-24 * time.Hour).Format(time.RFC3339)
+		}
+	}
+
+	return *input
+}
+
+// ToTimeSeriesParams converts MessageTimeSeries to services.MessageTimeSeriesParams
+func (input *MessageTimeSeries) ToTimeSeriesParams() *services.MessageTimeSeriesParams {
+	location, _ := time.LoadLocation(input.Timezone)
+	return &services.MessageTimeSeriesParams{
+		From:        *input.getTime(input.From),
+		To:          *input.getTime(input.To),
+		Granularity: entities.MessageStatisticsGranularity(input.Granularity),
+		Location:    location,
+		Owner:       input.sanitizeStringPointer(input.Owner),
+	}
+}
@@ -0,0 +1,18 @@
+package requests
+
+import (
+	"github.com/google/uuid"
+)
+
+// MessageUpdate is the payload for changing the content of a pending entities.Message
+type MessageUpdate struct {
+	request
+	Content string `json:"content" example:"This is a sample text message"`
+
+	MessageID string `json:"messageID" swaggerignore:"true"` // used internally for validation
+}
+
+// MessageUUID returns the ID of the entities.Message which is updated
+func (input *MessageUpdate) MessageUUID() uuid.UUID {
+	return uuid.MustParse(input.MessageID)
+}
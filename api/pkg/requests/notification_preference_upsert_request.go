@@ -0,0 +1,39 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// NotificationPreferenceUpsert is the payload for setting the entities.NotificationPreference of a user
+type NotificationPreferenceUpsert struct {
+	request
+	// Enabled sends an email with the messages of a phone which failed or expired in the last 10 minutes
+	Enabled bool `json:"enabled" example:"true"`
+	// Threshold is the minimum number of messages which fail or expire in 10 minutes before the email is sent
+	Threshold uint `json:"threshold" example:"5"`
+	// QuietHoursStart is the time in your timezone after which no email is sent until QuietHoursEnd, leave both empty to send the emails at any time
+	QuietHoursStart string `json:"quiet_hours_start" example:"22:00" validate:"optional"`
+	// QuietHoursEnd is the time in your timezone at which the emails which are due in the quiet hours are sent
+	QuietHoursEnd string `json:"quiet_hours_end" example:"07:00" validate:"optional"`
+}
+
+// Sanitize sets defaults to NotificationPreferenceUpsert
+func (input *NotificationPreferenceUpsert) Sanitize() NotificationPreferenceUpsert {
+	input.QuietHoursStart = strings.TrimSpace(input.QuietHoursStart)
+	input.QuietHoursEnd = strings.TrimSpace(input.QuietHoursEnd)
+	return *input
+}
+
+// ToUpsertParams converts NotificationPreferenceUpsert to services.NotificationPreferenceUpsertParams
+func (input *NotificationPreferenceUpsert) ToUpsertParams(user entities.AuthUser) *services.NotificationPreferenceUpsertParams {
+	return &services.NotificationPreferenceUpsertParams{
+		UserID:          user.ID,
+		Enabled:         input.Enabled,
+		Threshold:       input.Threshold,
+		QuietHoursStart: input.QuietHoursStart,
+		QuietHoursEnd:   input.QuietHoursEnd,
+	}
+}
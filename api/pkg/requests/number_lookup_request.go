@@ -0,0 +1,13 @@
+package requests
+
+// NumberLookupShow is the payload for looking up the type and carrier of a phone number
+type NumberLookupShow struct {
+	request
+	PhoneNumber string `json:"phone_number" query:"phone_number"`
+}
+
+// Sanitize sets defaults to NumberLookupShow
+func (input *NumberLookupShow) Sanitize() NumberLookupShow {
+	input.PhoneNumber = input.sanitizeAddress(input.PhoneNumber)
+	return *input
+}
@@ -1,6 +1,8 @@
 package requests
 
 import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
 	"github.com/google/uuid"
 )
 
@@ -8,9 +10,27 @@ import (
 type PhoneDelete struct {
 	request
 	PhoneID string `json:"phoneID" swaggerignore:"true"` // used internally for validation
+	// KeepPending keeps the outstanding messages of the phone instead of cancelling them
+	KeepPending string `json:"keep_pending" query:"keep_pending"`
+}
+
+// Sanitize sets defaults to PhoneDelete
+func (input *PhoneDelete) Sanitize() PhoneDelete {
+	input.KeepPending = input.sanitizeBool(input.KeepPending)
+	return *input
 }
 
 // PhoneIDUuid returns the phoneID as uuid.UUID
 func (input *PhoneDelete) PhoneIDUuid() uuid.UUID {
 	return uuid.MustParse(input.PhoneID)
 }
+
+// ToDeleteParams converts PhoneDelete to services.PhoneDeleteParams
+func (input *PhoneDelete) ToDeleteParams(userID entities.UserID, source string) *services.PhoneDeleteParams {
+	return &services.PhoneDeleteParams{
+		Source:        source,
+		UserID:        userID,
+		PhoneID:       input.PhoneIDUuid(),
+		CancelPending: !input.getBool(input.KeepPending),
+	}
+}
@@ -22,10 +22,36 @@ type PhoneUpsert struct {
 	// MaxSendAttempts is the number of attempts when sending an SMS message to handle the case where the phone is offline.
 	MaxSendAttempts uint `json:"max_send_attempts" example:"2"`
 
+	// HeartbeatDeadThresholdSeconds is the duration in seconds after the last heartbeat when the phone is considered offline.
+	HeartbeatDeadThresholdSeconds uint `json:"heartbeat_dead_threshold_seconds" example:"3840"`
+
+	// HeartbeatAlertCooldownSeconds is the minimum duration in seconds between 2 offline alerts of the phone.
+	HeartbeatAlertCooldownSeconds uint `json:"heartbeat_alert_cooldown_seconds" example:"3600"`
+
+	// DailySendLimit is the number of messages which the phone sends in a day, 0 removes the limit and it is unchanged when it is omitted.
+	DailySendLimit *uint `json:"daily_send_limit" example:"300"`
+
+	// DailySendLimitTimezone is the timezone in which the DailySendLimit resets at midnight, it is unchanged when it is omitted.
+	DailySendLimitTimezone *string `json:"daily_send_limit_timezone" example:"Africa/Douala"`
+
+	// EncryptionEnabled turns on the end-to-end encryption of the messages of the phone, it is unchanged when it is omitted.
+	EncryptionEnabled *bool `json:"encryption_enabled" example:"false"`
+
 	FcmToken string `json:"fcm_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzd....."`
 
 	// SIM is the SIM slot of the phone in case the phone has more than 1 SIM slot
 	SIM string `json:"sim" example:"SIM1"`
+
+	// SIMs are the SIM card subscriptions on the phone, omit it to keep the registered SIM cards
+	SIMs *[]PhoneUpsertSIM `json:"sims"`
+}
+
+// PhoneUpsertSIM is a SIM card subscription on a phone
+type PhoneUpsertSIM struct {
+	// Slot is the 0 based index of the SIM card slot
+	Slot        uint   `json:"slot" example:"0"`
+	CarrierName string `json:"carrier_name" example:"T-Mobile"`
+	PhoneNumber string `json:"phone_number" example:"+18005550199"`
 }
 
 // Sanitize sets defaults to MessageOutstanding
@@ -35,11 +61,23 @@ func (input *PhoneUpsert) Sanitize() PhoneUpsert {
 	if input.SIM == "" {
 		input.SIM = entities.SIM1.String()
 	}
+	if input.DailySendLimitTimezone != nil {
+		timezone := strings.TrimSpace(*input.DailySendLimitTimezone)
+		input.DailySendLimitTimezone = &timezone
+	}
+	if input.SIMs != nil {
+		for index := range *input.SIMs {
+			(*input.SIMs)[index].CarrierName = strings.TrimSpace((*input.SIMs)[index].CarrierName)
+			if (*input.SIMs)[index].PhoneNumber != "" {
+				(*input.SIMs)[index].PhoneNumber = input.sanitizeAddress((*input.SIMs)[index].PhoneNumber)
+			}
+		}
+	}
 	return *input
 }
 
 // ToUpsertParams converts PhoneUpsert to services.PhoneUpsertParams
-func (input *PhoneUpsert) ToUpsertParams(user entities.AuthUser, source string) services.PhoneUpsertParams {
+func (input *PhoneUpsert) ToUpsertParams(user entities.AuthUser, source string, appVersion string) services.PhoneUpsertParams {
 	phone, _ := phonenumbers.Parse(input.PhoneNumber, phonenumbers.UNKNOWN_REGION)
 
 	// ignore value if it's default
@@ -66,14 +104,46 @@ func (input *PhoneUpsert) ToUpsertParams(user entities.AuthUser, source string)
 		maxSendAttempts = &input.MaxSendAttempts
 	}
 
+	var heartbeatDeadThreshold *time.Duration
+	if input.HeartbeatDeadThresholdSeconds != 0 {
+		duration := time.Duration(input.HeartbeatDeadThresholdSeconds) * time.Second
+		heartbeatDeadThreshold = &duration
+	}
+
+	var heartbeatAlertCooldown *time.Duration
+	if input.HeartbeatAlertCooldownSeconds != 0 {
+		duration := time.Duration(input.HeartbeatAlertCooldownSeconds) * time.Second
+		heartbeatAlertCooldown = &duration
+	}
+
+	var sims *[]services.PhoneSIMParams
+	if input.SIMs != nil {
+		items := make([]services.PhoneSIMParams, 0, len(*input.SIMs))
+		for _, item := range *input.SIMs {
+			items = append(items, services.PhoneSIMParams{
+				Slot:        item.Slot,
+				CarrierName: item.CarrierName,
+				PhoneNumber: input.sanitizeStringPointer(item.PhoneNumber),
+			})
+		}
+		sims = &items
+	}
+
 	return services.PhoneUpsertParams{
 		Source:                    source,
+		SIMs:                      sims,
 		PhoneNumber:               *phone,
 		MessagesPerMinute:         messagesPerMinute,
 		MessageExpirationDuration: timeout,
 		MaxSendAttempts:           maxSendAttempts,
+		HeartbeatDeadThreshold:    heartbeatDeadThreshold,
+		HeartbeatAlertCooldown:    heartbeatAlertCooldown,
+		DailySendLimit:            input.DailySendLimit,
+		DailySendLimitTimezone:    input.DailySendLimitTimezone,
+		EncryptionEnabled:         input.EncryptionEnabled,
 		FcmToken:                  fcmToken,
 		UserID:                    user.ID,
 		SIM:                       entities.SIM(input.SIM),
+		AppVersion:                strings.TrimSpace(appVersion),
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/nyaruka/phonenumbers"
@@ -29,6 +30,27 @@ func (input *request) sanitizeAddress(value string) string {
 	return value
 }
 
+// sanitizeContactAddress formats a phone number from the address book of the owner phone number in E.164 like
+// sanitizeAddress, a number in the national format e.g. (800) 555-0100 is parsed in the region of the owner.
+func (input *request) sanitizeContactAddress(value string, owner string) string {
+	value = strings.TrimSpace(value)
+	if ownerNumber, err := phonenumbers.Parse(owner, phonenumbers.UNKNOWN_REGION); err == nil && !strings.HasPrefix(value, "+") {
+		if number, err := phonenumbers.Parse(value, phonenumbers.GetRegionCodeForNumber(ownerNumber)); err == nil {
+			return phonenumbers.Format(number, phonenumbers.E164)
+		}
+	}
+	return input.sanitizeAddress(value)
+}
+
+// sanitizePrefix removes the separators of a prefix e.g. "+1 415" and adds the "+" of the E.164 format
+func (input *request) sanitizePrefix(value string) string {
+	value = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(value))
+	if value != "" && !strings.HasPrefix(value, "+") {
+		value = "+" + value
+	}
+	return value
+}
+
 // sanitizeBool sanitizes a boolean string
 func (input *request) sanitizeBool(value string) string {
 	value = strings.TrimSpace(value)
@@ -102,6 +124,16 @@ func (input *request) getInt(value string) int {
 	return val
 }
 
+// getTime parses an RFC3339 timestamp, it returns nil when the value is empty or invalid
+func (input *request) getTime(value string) *time.Time {
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	timestamp = timestamp.UTC()
+	return &timestamp
+}
+
 func (input *request) isDigits(value string) bool {
 	for _, c := range value {
 		if !unicode.IsDigit(c) {
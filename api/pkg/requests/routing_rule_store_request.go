@@ -0,0 +1,31 @@
+package requests
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// RoutingRuleStore is the payload for creating an entities.RoutingRule
+type RoutingRuleStore struct {
+	request
+	// Prefix is the country code or the start of the phone numbers of the contacts e.g. +44, it is empty for the default route
+	Prefix string `json:"prefix" example:"+44" validate:"optional"`
+	// Owner is the phone number of the phone which sends the messages to the contacts with the prefix
+	Owner string `json:"owner" example:"+447700900000"`
+}
+
+// Sanitize sets defaults to RoutingRuleStore
+func (input *RoutingRuleStore) Sanitize() RoutingRuleStore {
+	input.Prefix = input.sanitizePrefix(input.Prefix)
+	input.Owner = input.sanitizeAddress(input.Owner)
+	return *input
+}
+
+// ToStoreParams converts RoutingRuleStore to services.RoutingRuleStoreParams
+func (input *RoutingRuleStore) ToStoreParams(userID entities.UserID) *services.RoutingRuleStoreParams {
+	return &services.RoutingRuleStoreParams{
+		UserID: userID,
+		Prefix: input.Prefix,
+		Owner:  input.Owner,
+	}
+}
@@ -0,0 +1,33 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// RoutingRuleUpdate is the payload for updating an entities.RoutingRule
+type RoutingRuleUpdate struct {
+	RoutingRuleStore
+
+	RoutingRuleID string `json:"routingRuleID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to RoutingRuleUpdate
+func (input *RoutingRuleUpdate) Sanitize() RoutingRuleUpdate {
+	input.RoutingRuleStore.Sanitize()
+	input.RoutingRuleID = strings.TrimSpace(input.RoutingRuleID)
+	return *input
+}
+
+// ToUpdateParams converts RoutingRuleUpdate to services.RoutingRuleUpdateParams
+func (input *RoutingRuleUpdate) ToUpdateParams(userID entities.UserID) *services.RoutingRuleUpdateParams {
+	return &services.RoutingRuleUpdateParams{
+		UserID:        userID,
+		RoutingRuleID: uuid.MustParse(input.RoutingRuleID),
+		Prefix:        input.Prefix,
+		Owner:         input.Owner,
+	}
+}
@@ -0,0 +1,33 @@
+package requests
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// SlackUpdate is the payload for updating an entities.Slack
+type SlackUpdate struct {
+	SlackStore
+	SlackID string `json:"slackID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to SlackUpdate
+func (input *SlackUpdate) Sanitize() SlackUpdate {
+	input.SlackStore.Sanitize()
+	return *input
+}
+
+// ToUpdateParams converts SlackUpdate to services.SlackUpdateParams
+func (input *SlackUpdate) ToUpdateParams(user entities.AuthUser) *services.SlackUpdateParams {
+	return &services.SlackUpdateParams{
+		UserID:          user.ID,
+		SlackID:         uuid.MustParse(input.SlackID),
+		Name:            input.Name,
+		Owner:           input.Owner,
+		WebhookURL:      input.WebhookURL,
+		BotToken:        input.BotToken,
+		Channel:         input.Channel,
+		ThreadByContact: input.ThreadByContact,
+	}
+}
@@ -0,0 +1,42 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// SummaryReportScheduleUpsert is the payload for setting the entities.SummaryReportSchedule of a user
+type SummaryReportScheduleUpsert struct {
+	request
+	// Enabled sends an email with the summary of your messages in the previous day or week
+	Enabled bool `json:"enabled" example:"true"`
+	// Frequency is daily to receive the report of the previous day every day or weekly to receive the report of the previous week on Monday
+	Frequency string `json:"frequency" example:"daily"`
+	// Hour is the hour of the day in the timezone at which the report is sent
+	Hour uint `json:"hour" example:"8"`
+	// Timezone is the timezone of the periods and the hour of the report, your timezone is used when it is empty
+	Timezone string `json:"timezone" example:"Europe/Berlin" validate:"optional"`
+	// SkipEmpty does not send the report of a period in which no message was sent or received
+	SkipEmpty bool `json:"skip_empty" example:"true"`
+}
+
+// Sanitize sets defaults to SummaryReportScheduleUpsert
+func (input *SummaryReportScheduleUpsert) Sanitize() SummaryReportScheduleUpsert {
+	input.Frequency = strings.ToLower(strings.TrimSpace(input.Frequency))
+	input.Timezone = strings.TrimSpace(input.Timezone)
+	return *input
+}
+
+// ToUpsertParams converts SummaryReportScheduleUpsert to services.SummaryReportScheduleUpsertParams
+func (input *SummaryReportScheduleUpsert) ToUpsertParams(user entities.AuthUser) *services.SummaryReportScheduleUpsertParams {
+	return &services.SummaryReportScheduleUpsertParams{
+		UserID:    user.ID,
+		Enabled:   input.Enabled,
+		Frequency: entities.SummaryReportFrequency(input.Frequency),
+		Hour:      input.Hour,
+		Timezone:  input.Timezone,
+		SkipEmpty: input.SkipEmpty,
+	}
+}
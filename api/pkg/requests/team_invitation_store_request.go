@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// TeamInvitationStore is the payload for inviting a user to a team
+type TeamInvitationStore struct {
+	request
+	Email string `json:"email" example:"member@email.com"`
+	Role  string `json:"role" example:"member"`
+}
+
+// Sanitize sets defaults to TeamInvitationStore
+func (input *TeamInvitationStore) Sanitize() TeamInvitationStore {
+	input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+	input.Role = strings.ToLower(strings.TrimSpace(input.Role))
+	if input.Role == "" {
+		input.Role = entities.TeamRoleMember.String()
+	}
+	return *input
+}
+
+// ToInviteParams converts TeamInvitationStore to services.TeamInviteParams
+func (input *TeamInvitationStore) ToInviteParams(user entities.AuthUser) *services.TeamInviteParams {
+	return &services.TeamInviteParams{
+		TeamID:    user.ID,
+		InvitedBy: user.ActingUserID(),
+		Email:     input.Email,
+		Role:      entities.TeamRole(input.Role),
+	}
+}
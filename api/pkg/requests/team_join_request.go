@@ -0,0 +1,29 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// TeamJoin is the payload for accepting a team invitation
+type TeamJoin struct {
+	request
+	Token string `json:"token" example:"pDRKJfMnTaUAdkrBZHLESiGSzaJvhPvWD6zu3Ud8LJG9TZzY"`
+}
+
+// Sanitize sets defaults to TeamJoin
+func (input *TeamJoin) Sanitize() TeamJoin {
+	input.Token = strings.TrimSpace(input.Token)
+	return *input
+}
+
+// ToJoinParams converts TeamJoin to services.TeamJoinParams
+func (input *TeamJoin) ToJoinParams(user entities.AuthUser) *services.TeamJoinParams {
+	return &services.TeamJoinParams{
+		UserID: user.ActingUserID(),
+		Email:  user.Email,
+		Token:  input.Token,
+	}
+}
@@ -0,0 +1,38 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// TelegramStore is the payload for creating a new entities.Telegram
+type TelegramStore struct {
+	request
+	Name     string `json:"name" example:"Support"`
+	Owner    string `json:"owner" example:"+18005550199"`
+	BotToken string `json:"bot_token" example:"123456789:AAHdqTcvCH1vGWJxfSeofSAs0K5PALDsaw"`
+
+	// ChatID is the ID of the only chat which receives the messages and whose replies are sent as SMS
+	ChatID int64 `json:"chat_id" example:"123456789"`
+}
+
+// Sanitize sets defaults to TelegramStore
+func (input *TelegramStore) Sanitize() TelegramStore {
+	input.Name = strings.TrimSpace(input.Name)
+	input.Owner = input.sanitizeAddress(input.Owner)
+	input.BotToken = strings.TrimSpace(input.BotToken)
+	return *input
+}
+
+// ToStoreParams converts TelegramStore to services.TelegramStoreParams
+func (input *TelegramStore) ToStoreParams(user entities.AuthUser) *services.TelegramStoreParams {
+	return &services.TelegramStoreParams{
+		UserID:   user.ID,
+		Name:     input.Name,
+		Owner:    input.Owner,
+		BotToken: input.BotToken,
+		ChatID:   input.ChatID,
+	}
+}
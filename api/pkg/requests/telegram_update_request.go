@@ -0,0 +1,31 @@
+package requests
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+// TelegramUpdate is the payload for updating an entities.Telegram
+type TelegramUpdate struct {
+	TelegramStore
+	TelegramID string `json:"telegramID" swaggerignore:"true"` // used internally for validation
+}
+
+// Sanitize sets defaults to TelegramUpdate
+func (input *TelegramUpdate) Sanitize() TelegramUpdate {
+	input.TelegramStore.Sanitize()
+	return *input
+}
+
+// ToUpdateParams converts TelegramUpdate to services.TelegramUpdateParams
+func (input *TelegramUpdate) ToUpdateParams(user entities.AuthUser) *services.TelegramUpdateParams {
+	return &services.TelegramUpdateParams{
+		UserID:     user.ID,
+		TelegramID: uuid.MustParse(input.TelegramID),
+		Name:       input.Name,
+		Owner:      input.Owner,
+		BotToken:   input.BotToken,
+		ChatID:     input.ChatID,
+	}
+}
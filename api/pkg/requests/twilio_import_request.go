@@ -0,0 +1,41 @@
+package requests
+
+import (
+	"io"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+)
+
+// TwilioImport is the payload for importing the messages of a Twilio export, the export is the body of the request
+type TwilioImport struct {
+	request
+	Format string `json:"format" query:"format"`
+	Owner  string `json:"owner" query:"owner"`
+}
+
+// Sanitize sets defaults to TwilioImport
+func (input *TwilioImport) Sanitize() TwilioImport {
+	input.Format = strings.ToLower(strings.TrimSpace(input.Format))
+	if input.Format == "" {
+		input.Format = string(twilio.ExportFormatCSV)
+	}
+
+	input.Owner = strings.TrimSpace(input.Owner)
+	if input.Owner != "" {
+		input.Owner = input.sanitizeAddress(input.Owner)
+	}
+	return *input
+}
+
+// ToImportParams converts TwilioImport to services.TwilioImportParams
+func (input *TwilioImport) ToImportParams(userID entities.UserID, export io.Reader) services.TwilioImportParams {
+	return services.TwilioImportParams{
+		UserID: userID,
+		Owner:  input.Owner,
+		Format: twilio.ExportFormat(input.Format),
+		Export: export,
+	}
+}
@@ -0,0 +1,47 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// TwilioMessageSend is the form of a request to send a message with the Twilio compatible API
+type TwilioMessageSend struct {
+	request
+	To   string `json:"to" form:"To" example:"+18005550100"`
+	From string `json:"from" form:"From" example:"+18005550199"`
+	Body string `json:"body" form:"Body" example:"This is a sample text message"`
+
+	// StatusCallback is an optional URL which receives the Twilio status callbacks of the message
+	StatusCallback string `json:"status_callback" form:"StatusCallback" example:"https://example.com/twilio/status" validate:"optional"`
+}
+
+// Sanitize sets defaults to TwilioMessageSend
+func (input *TwilioMessageSend) Sanitize() TwilioMessageSend {
+	input.To = input.sanitizeAddress(input.To)
+	input.From = input.sanitizeAddress(input.From)
+	input.StatusCallback = strings.TrimSpace(input.StatusCallback)
+	return *input
+}
+
+// ToMessageSend converts TwilioMessageSend to MessageSend so that it is validated like the other messages, the
+// From of a Twilio message is the owner of the message and the Body is the content
+func (input *TwilioMessageSend) ToMessageSend() MessageSend {
+	return MessageSend{
+		From:    input.From,
+		To:      input.To,
+		Content: input.Body,
+	}
+}
+
+// ToTwilioSendParams converts TwilioMessageSend to services.TwilioSendParams
+func (input *TwilioMessageSend) ToTwilioSendParams(userID entities.UserID, accountSID string, source string) services.TwilioSendParams {
+	message := input.ToMessageSend()
+	return services.TwilioSendParams{
+		MessageSendParams: message.ToMessageSendParams(userID, source),
+		AccountSID:        accountSID,
+		StatusCallback:    input.StatusCallback,
+	}
+}
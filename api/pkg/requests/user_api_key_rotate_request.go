@@ -0,0 +1,26 @@
+package requests
+
+import (
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// UserAPIKeyRotate is the payload for rotating the API key of a user
+type UserAPIKeyRotate struct {
+	request
+	// GracePeriodSeconds is the duration in seconds in which the previous API key is still valid, the previous API key is revoked immediately when it is 0
+	GracePeriodSeconds uint `json:"grace_period_seconds" example:"3600"`
+}
+
+// ToUserRotateAPIKeyParams converts UserAPIKeyRotate to services.UserRotateAPIKeyParams
+func (input *UserAPIKeyRotate) ToUserRotateAPIKeyParams(userID entities.UserID, source string, ipAddress string, userAgent string) *services.UserRotateAPIKeyParams {
+	return &services.UserRotateAPIKeyParams{
+		UserID:      userID,
+		GracePeriod: time.Duration(input.GracePeriodSeconds) * time.Second,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Source:      source,
+	}
+}
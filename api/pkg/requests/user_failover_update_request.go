@@ -0,0 +1,29 @@
+package requests
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// UserFailoverUpdate is the payload for updating the failover settings of a user
+type UserFailoverUpdate struct {
+	request
+	Enabled bool `json:"enabled" example:"true"`
+	// PhoneNumber is the backup phone which takes over the pending messages of a phone which is offline
+	PhoneNumber string `json:"phone_number" example:"+18005550100"`
+}
+
+// Sanitize sets defaults to UserFailoverUpdate
+func (input *UserFailoverUpdate) Sanitize() UserFailoverUpdate {
+	if input.PhoneNumber != "" {
+		input.PhoneNumber = input.sanitizeAddress(input.PhoneNumber)
+	}
+	return *input
+}
+
+// ToUserFailoverUpdateParams converts UserFailoverUpdate to services.UserFailoverUpdateParams
+func (input *UserFailoverUpdate) ToUserFailoverUpdateParams() *services.UserFailoverUpdateParams {
+	return &services.UserFailoverUpdateParams{
+		Enabled:     input.Enabled,
+		PhoneNumber: input.sanitizeStringPointer(input.PhoneNumber),
+	}
+}
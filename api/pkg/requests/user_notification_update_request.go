@@ -10,6 +10,7 @@ type UserNotificationUpdate struct {
 	MessageStatusEnabled bool `json:"message_status_enabled" example:"true"`
 	WebhookEnabled       bool `json:"webhook_enabled"  example:"true"`
 	HeartbeatEnabled     bool `json:"heartbeat_enabled" example:"true"`
+	HeartbeatQuietHours  uint `json:"heartbeat_quiet_hours" example:"2"`
 }
 
 // ToUserNotificationUpdateParams converts UserNotificationUpdate to services.UserNotificationUpdateParams
@@ -18,5 +19,6 @@ func (input *UserNotificationUpdate) ToUserNotificationUpdateParams() *services.
 		MessageStatusEnabled: input.MessageStatusEnabled,
 		WebhookEnabled:       input.WebhookEnabled,
 		HeartbeatEnabled:     input.HeartbeatEnabled,
+		HeartbeatQuietHours:  input.HeartbeatQuietHours,
 	}
 }
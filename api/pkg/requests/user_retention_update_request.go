@@ -0,0 +1,22 @@
+package requests
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// UserRetentionUpdate is the payload for updating the data retention settings of a user
+type UserRetentionUpdate struct {
+	request
+	// ReceivedDays is the number of days after which received messages are deleted, 0 keeps the messages
+	ReceivedDays uint `json:"received_days" example:"30"`
+	// SentDays is the number of days after which sent messages are deleted, 0 keeps the messages
+	SentDays uint `json:"sent_days" example:"0"`
+}
+
+// ToUserRetentionUpdateParams converts UserRetentionUpdate to services.UserRetentionUpdateParams
+func (input *UserRetentionUpdate) ToUserRetentionUpdateParams() *services.UserRetentionUpdateParams {
+	return &services.UserRetentionUpdateParams{
+		ReceivedDays: input.ReceivedDays,
+		SentDays:     input.SentDays,
+	}
+}
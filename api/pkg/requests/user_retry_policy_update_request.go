@@ -0,0 +1,36 @@
+package requests
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
+
+// UserRetryPolicyUpdate is the payload for updating the retry policy of the messages of a user
+type UserRetryPolicyUpdate struct {
+	request
+	// MaxAttempts is the number of times a message is sent to the phone before it fails, 0 uses the max_send_attempts of the phone
+	MaxAttempts uint `json:"max_attempts" example:"3"`
+	// BackoffBaseSeconds is the delay before the first retry of an expired message, 0 retries the message immediately
+	BackoffBaseSeconds uint `json:"backoff_base_seconds" example:"30"`
+	// BackoffCapSeconds is the longest delay between two attempts, 0 uses the default of 1 hour
+	BackoffCapSeconds uint `json:"backoff_cap_seconds" example:"3600"`
+	// BackoffMultiplier multiplies the delay after every retry, 0 uses the default of 2
+	BackoffMultiplier float64 `json:"backoff_multiplier" example:"2"`
+}
+
+// ToRetryPolicy converts UserRetryPolicyUpdate to entities.RetryPolicy
+func (input *UserRetryPolicyUpdate) ToRetryPolicy() entities.RetryPolicy {
+	return entities.RetryPolicy{
+		MaxAttempts:        input.MaxAttempts,
+		BackoffBaseSeconds: input.BackoffBaseSeconds,
+		BackoffCapSeconds:  input.BackoffCapSeconds,
+		BackoffMultiplier:  input.BackoffMultiplier,
+	}
+}
+
+// ToUserRetryPolicyUpdateParams converts UserRetryPolicyUpdate to services.UserRetryPolicyUpdateParams
+func (input *UserRetryPolicyUpdate) ToUserRetryPolicyUpdateParams() *services.UserRetryPolicyUpdateParams {
+	return &services.UserRetryPolicyUpdateParams{
+		RetryPolicy: input.ToRetryPolicy(),
+	}
+}
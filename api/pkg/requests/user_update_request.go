@@ -14,6 +14,12 @@ type UserUpdate struct {
 	request
 	Timezone      string `json:"timezone" example:"Europe/Helsinki"`
 	ActivePhoneID string `json:"active_phone_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+
+	// DailyMessageLimit is the maximum number of messages which can be sent per day, 0 means unlimited and the limit is unchanged when it is omitted
+	DailyMessageLimit *uint `json:"daily_message_limit" example:"500"`
+
+	// RequestDeliveryReports is the default of messages which are sent without request_delivery_report and it is unchanged when it is omitted
+	RequestDeliveryReports *bool `json:"request_delivery_reports" example:"true"`
 }
 
 // Sanitize sets defaults to MessageOutstanding
@@ -30,7 +36,9 @@ func (input *UserUpdate) ToUpdateParams() services.UserUpdateParams {
 		location = time.UTC
 	}
 	return services.UserUpdateParams{
-		ActivePhoneID: uuid.MustParse(input.ActivePhoneID),
-		Timezone:      location,
+		ActivePhoneID:          uuid.MustParse(input.ActivePhoneID),
+		Timezone:               location,
+		DailyMessageLimit:      input.DailyMessageLimit,
+		RequestDeliveryReports: input.RequestDeliveryReports,
 	}
 }
@@ -0,0 +1,29 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// WebhookSample is the payload for fetching the sample payload of an event which is sent to an entities.Webhook
+type WebhookSample struct {
+	request
+	Event         string `json:"event" query:"event" example:"message.phone.received"`
+	PayloadFormat string `json:"payload_format" query:"payload_format" example:"flat"`
+}
+
+// Sanitize sets defaults to WebhookSample
+func (input *WebhookSample) Sanitize() WebhookSample {
+	input.Event = strings.TrimSpace(input.Event)
+	input.PayloadFormat = strings.ToLower(strings.TrimSpace(input.PayloadFormat))
+	if input.PayloadFormat == "" {
+		input.PayloadFormat = string(entities.WebhookPayloadFormatRaw)
+	}
+	return *input
+}
+
+// ToPayloadFormat returns the entities.WebhookPayloadFormat of the sample
+func (input *WebhookSample) ToPayloadFormat() entities.WebhookPayloadFormat {
+	return entities.WebhookPayloadFormat(input.PayloadFormat)
+}
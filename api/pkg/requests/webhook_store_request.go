@@ -14,6 +14,9 @@ type WebhookStore struct {
 	URL          string   `json:"url"`
 	PhoneNumbers []string `json:"phone_numbers" example:"+18005550100,+18005550100"`
 	Events       []string `json:"events"`
+
+	// PayloadFormat is the format of the payload which is sent to the webhook, it is either raw or flat
+	PayloadFormat string `json:"payload_format" example:"raw" validate:"optional"`
 }
 
 // Sanitize sets defaults to WebhookStore
@@ -22,6 +25,11 @@ func (input *WebhookStore) Sanitize() WebhookStore {
 	input.SigningKey = strings.TrimSpace(input.SigningKey)
 	input.Events = input.removeStringDuplicates(input.Events)
 
+	input.PayloadFormat = strings.ToLower(strings.TrimSpace(input.PayloadFormat))
+	if input.PayloadFormat == "" {
+		input.PayloadFormat = string(entities.WebhookPayloadFormatRaw)
+	}
+
 	var phoneNumbers []string
 	for _, address := range input.PhoneNumbers {
 		phoneNumbers = append(phoneNumbers, input.sanitizeAddress(address))
@@ -33,10 +41,11 @@ func (input *WebhookStore) Sanitize() WebhookStore {
 // ToStoreParams converts WebhookStore to services.WebhookStoreParams
 func (input *WebhookStore) ToStoreParams(user entities.AuthUser) *services.WebhookStoreParams {
 	return &services.WebhookStoreParams{
-		UserID:       user.ID,
-		SigningKey:   input.SigningKey,
-		URL:          input.URL,
-		PhoneNumbers: input.PhoneNumbers,
-		Events:       input.Events,
+		UserID:        user.ID,
+		SigningKey:    input.SigningKey,
+		URL:           input.URL,
+		PhoneNumbers:  input.PhoneNumbers,
+		Events:        input.Events,
+		PayloadFormat: entities.WebhookPayloadFormat(input.PayloadFormat),
 	}
 }
@@ -21,11 +21,12 @@ func (input *WebhookUpdate) Sanitize() WebhookUpdate {
 // ToUpdateParams converts WebhookUpdate to services.WebhookUpdateParams
 func (input *WebhookUpdate) ToUpdateParams(user entities.AuthUser) *services.WebhookUpdateParams {
 	return &services.WebhookUpdateParams{
-		UserID:       user.ID,
-		WebhookID:    uuid.MustParse(input.WebhookID),
-		SigningKey:   input.SigningKey,
-		URL:          input.URL,
-		PhoneNumbers: input.PhoneNumbers,
-		Events:       input.Events,
+		UserID:        user.ID,
+		WebhookID:     uuid.MustParse(input.WebhookID),
+		SigningKey:    input.SigningKey,
+		URL:           input.URL,
+		PhoneNumbers:  input.PhoneNumbers,
+		Events:        input.Events,
+		PayloadFormat: entities.WebhookPayloadFormat(input.PayloadFormat),
 	}
 }
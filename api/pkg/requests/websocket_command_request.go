@@ -0,0 +1,40 @@
+package requests
+
+import (
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/google/uuid"
+)
+
+const (
+	// WebsocketCommandMarkThreadRead marks the messages in a thread as read
+	WebsocketCommandMarkThreadRead = "message-thread.read"
+
+	// WebsocketCommandTyping notifies the other connections of the user that a message is being typed in a thread
+	WebsocketCommandTyping = "message-thread.typing"
+)
+
+// WebsocketCommand is a command which is sent by a client on the websocket connection
+type WebsocketCommand struct {
+	request
+	Type            string `json:"type" example:"message-thread.read"`
+	MessageThreadID string `json:"message_thread_id" example:"32343a19-da5e-4b1b-a767-3298a73703ca"`
+}
+
+// Sanitize sets defaults to WebsocketCommand
+func (input *WebsocketCommand) Sanitize() WebsocketCommand {
+	input.Type = strings.TrimSpace(input.Type)
+	input.MessageThreadID = strings.TrimSpace(input.MessageThreadID)
+	return *input
+}
+
+// ToMessageThreadReadParams converts WebsocketCommand to services.MessageThreadReadParams
+func (input *WebsocketCommand) ToMessageThreadReadParams(userID entities.UserID, source string) services.MessageThreadReadParams {
+	return services.MessageThreadReadParams{
+		Source:          source,
+		UserID:          userID,
+		MessageThreadID: uuid.MustParse(input.MessageThreadID),
+	}
+}
@@ -5,6 +5,7 @@ import "github.com/NdoleStudio/httpsms/pkg/entities"
 // BillingUsagesResponse is the payload containing []entities.BillingUsage
 type BillingUsagesResponse struct {
 	response
+	paginated
 	Data []entities.BillingUsage `json:"data"`
 }
 
@@ -13,3 +14,9 @@ type BillingUsageResponse struct {
 	response
 	Data entities.BillingUsage `json:"data"`
 }
+
+// UserUsageResponse is the payload containing entities.UserUsage
+type UserUsageResponse struct {
+	response
+	Data entities.UserUsage `json:"data"`
+}
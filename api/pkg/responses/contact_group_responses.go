@@ -0,0 +1,28 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// ContactGroupResponse is the payload containing entities.ContactGroup
+type ContactGroupResponse struct {
+	response
+	Data entities.ContactGroup `json:"data"`
+}
+
+// ContactGroupsResponse is the payload containing []entities.ContactGroup
+type ContactGroupsResponse struct {
+	response
+	paginated
+	Data []entities.ContactGroup `json:"data"`
+}
+
+// ContactGroupMemberStoreResponse is the payload containing entities.ContactGroupMemberResult
+type ContactGroupMemberStoreResponse struct {
+	response
+	Data entities.ContactGroupMemberResult `json:"data"`
+}
+
+// ContactGroupSendResponse is the payload containing entities.ContactGroupSendResult
+type ContactGroupSendResponse struct {
+	response
+	Data entities.ContactGroupSendResult `json:"data"`
+}
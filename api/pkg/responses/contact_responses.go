@@ -0,0 +1,28 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// ContactResponse is the payload containing entities.Contact
+type ContactResponse struct {
+	response
+	Data entities.Contact `json:"data"`
+}
+
+// ContactsResponse is the payload containing []entities.Contact
+type ContactsResponse struct {
+	response
+	paginated
+	Data []entities.Contact `json:"data"`
+}
+
+// ContactSyncResponse is the payload containing entities.ContactSyncResult
+type ContactSyncResponse struct {
+	response
+	Data entities.ContactSyncResult `json:"data"`
+}
+
+// ContactLabelRenameResponse is the payload containing entities.ContactLabelRenameResult
+type ContactLabelRenameResponse struct {
+	response
+	Data entities.ContactLabelRenameResult `json:"data"`
+}
@@ -0,0 +1,21 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// ContentExpiryRuleResponse is the payload containing entities.ContentExpiryRule
+type ContentExpiryRuleResponse struct {
+	response
+	Data entities.ContentExpiryRule `json:"data"`
+}
+
+// ContentExpiryRulesResponse is the payload containing []entities.ContentExpiryRule
+type ContentExpiryRulesResponse struct {
+	response
+	Data []entities.ContentExpiryRule `json:"data"`
+}
+
+// ContentExpiryMatchResponse is the payload containing entities.ContentExpiryMatch
+type ContentExpiryMatchResponse struct {
+	response
+	Data entities.ContentExpiryMatch `json:"data"`
+}
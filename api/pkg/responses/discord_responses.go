@@ -11,5 +11,6 @@ type DiscordResponse struct {
 // DiscordsResponse is the payload containing []entities.Discord
 type DiscordsResponse struct {
 	response
+	paginated
 	Data []entities.Discord `json:"data"`
 }
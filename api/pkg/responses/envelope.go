@@ -0,0 +1,56 @@
+package responses
+
+const (
+	// APIVersion1 is the version of the API which is used by the android app and the web app, its envelope must not change
+	APIVersion1 = "v1"
+
+	// APIVersion2 is the preview version of the API, it is served by the handlers of APIVersion1 and only changes the
+	// envelope so that an error is an object with the code and the message e.g. {"error":{"code":"not_found"}}
+	APIVersion2 = "v2"
+)
+
+// APIVersions returns the versions of the API which are routed
+func APIVersions() []string {
+	return []string{APIVersion1, APIVersion2}
+}
+
+// serializer converts the envelope of an APIVersion1 response into the envelope of another version of the API
+type serializer func(envelope map[string]any) map[string]any
+
+// serializers are the serializer of every version of the API whose envelope is different from APIVersion1
+var serializers = map[string]serializer{
+	APIVersion2: serializeV2,
+}
+
+// Envelope converts the APIVersion1 envelope of a response into the envelope of the version, the envelope is not
+// changed for APIVersion1 and for the requests without a version
+func Envelope(version string, envelope map[string]any) map[string]any {
+	if serialize, ok := serializers[version]; ok {
+		return serialize(envelope)
+	}
+	return envelope
+}
+
+// serializeV2 moves the code, message and data of an error into an error object and removes the status and message of a success
+func serializeV2(envelope map[string]any) map[string]any {
+	if envelope["status"] == "error" {
+		body := map[string]any{
+			"code":    envelope["code"],
+			"message": envelope["message"],
+		}
+		if errors, ok := envelope["errors"]; ok {
+			body["fields"] = errors
+		} else if data, ok := envelope["data"]; ok {
+			body["details"] = data
+		}
+		return map[string]any{"error": body}
+	}
+
+	result := map[string]any{}
+	for _, key := range []string{"data", "pagination"} {
+		if value, ok := envelope[key]; ok {
+			result[key] = value
+		}
+	}
+	return result
+}
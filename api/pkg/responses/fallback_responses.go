@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// FallbackProviderResponse is the payload containing entities.FallbackProvider
+type FallbackProviderResponse struct {
+	response
+	Data entities.FallbackProvider `json:"data"`
+}
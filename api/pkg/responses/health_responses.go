@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/services"
+
+// HealthReportResponse is the payload containing services.HealthReport
+type HealthReportResponse struct {
+	response
+	Data services.HealthReport `json:"data"`
+}
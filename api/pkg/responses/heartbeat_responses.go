@@ -5,6 +5,7 @@ import "github.com/NdoleStudio/httpsms/pkg/entities"
 // HeartbeatsResponse is the payload containing []entities.Heartbeat
 type HeartbeatsResponse struct {
 	response
+	paginated
 	Data []entities.Heartbeat `json:"data"`
 }
 
@@ -13,3 +14,9 @@ type HeartbeatResponse struct {
 	response
 	Data entities.Heartbeat `json:"data"`
 }
+
+// HeartbeatCountsResponse is the payload containing []entities.HeartbeatCount
+type HeartbeatCountsResponse struct {
+	response
+	Data []entities.HeartbeatCount `json:"data"`
+}
@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// MediaResponse is the payload containing entities.Media
+type MediaResponse struct {
+	response
+	Data entities.Media `json:"data"`
+}
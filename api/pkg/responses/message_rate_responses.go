@@ -0,0 +1,21 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// MessageRateResponse is the payload containing entities.MessageRate
+type MessageRateResponse struct {
+	response
+	Data entities.MessageRate `json:"data"`
+}
+
+// MessageRatesResponse is the payload containing []entities.MessageRate
+type MessageRatesResponse struct {
+	response
+	Data []entities.MessageRate `json:"data"`
+}
+
+// MessageCostsResponse is the payload containing []entities.MessageCostSummary
+type MessageCostsResponse struct {
+	response
+	Data []entities.MessageCostSummary `json:"data"`
+}
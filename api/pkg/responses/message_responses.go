@@ -1,6 +1,14 @@
 package responses
 
-import "github.com/NdoleStudio/httpsms/pkg/entities"
+import (
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
 
 // MessageResponse is the payload containing an entities.Message
 type MessageResponse struct {
@@ -11,5 +19,96 @@ type MessageResponse struct {
 // MessagesResponse is the payload containing []entities.Message
 type MessagesResponse struct {
 	response
+	paginated
 	Data []entities.Message `json:"data"`
 }
+
+// TwilioImportResponse is the payload containing the entities.TwilioImportResult of an import of a Twilio export
+type TwilioImportResponse struct {
+	response
+	Data entities.TwilioImportResult `json:"data"`
+}
+
+// MessageEvent is a cloud event which references an entities.Message
+type MessageEvent struct {
+	ID     string    `json:"id" example:"0d8b5e2f-6a36-4b8e-9d0f-2a3c5e6b7f81"`
+	Type   string    `json:"type" example:"message.api.sent"`
+	Source string    `json:"source" example:"/v1/messages/send"`
+	Time   time.Time `json:"time" example:"2022-06-05T14:26:02.302718+03:00"`
+	// Data is the decoded payload of the event
+	Data map[string]any `json:"data"`
+}
+
+// NewMessageEvents decodes the payloads of cloudevents.Event into MessageEvent
+func NewMessageEvents(events []cloudevents.Event) ([]MessageEvent, error) {
+	results := make([]MessageEvent, 0, len(events))
+	for _, event := range events {
+		data := map[string]any{}
+		if err := event.DataAs(&data); err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot decode the payload of event [%s] with type [%s]", event.ID(), event.Type()))
+		}
+
+		results = append(results, MessageEvent{
+			ID:     event.ID(),
+			Type:   event.Type(),
+			Source: event.Source(),
+			Time:   event.Time(),
+			Data:   data,
+		})
+	}
+	return results, nil
+}
+
+// MessageEventsResponse is the payload containing []MessageEvent
+type MessageEventsResponse struct {
+	response
+	Data []MessageEvent `json:"data"`
+}
+
+// MessageStatus is the status of an entities.Message which was fetched by ID, every field except Found is null when
+// the message does not exist or belongs to another user
+type MessageStatus struct {
+	Found             bool                    `json:"found" example:"true"`
+	Status            *entities.MessageStatus `json:"status" example:"delivered"`
+	FailureReason     *string                 `json:"failure_reason" example:"UNKNOWN"`
+	RequestReceivedAt *time.Time              `json:"request_received_at" example:"2022-06-05T14:26:01.520828+03:00"`
+	SentAt            *time.Time              `json:"sent_at" example:"2022-06-05T14:26:09.527976+03:00"`
+	DeliveredAt       *time.Time              `json:"delivered_at" example:"2022-06-05T14:26:09.527976+03:00"`
+	FailedAt          *time.Time              `json:"failed_at" example:"2022-06-05T14:26:09.527976+03:00"`
+	ExpiredAt         *time.Time              `json:"expired_at" example:"2022-06-05T14:26:09.527976+03:00"`
+	UpdatedAt         *time.Time              `json:"updated_at" example:"2022-06-05T14:26:10.303278+03:00"`
+}
+
+// NewMessageStatuses creates a MessageStatus for every ID, an ID without an entities.Message is not found
+func NewMessageStatuses(messageIDs []uuid.UUID, messages []entities.Message) map[string]MessageStatus {
+	results := make(map[string]MessageStatus, len(messageIDs))
+	for _, messageID := range messageIDs {
+		results[messageID.String()] = MessageStatus{}
+	}
+
+	for index := range messages {
+		message := &messages[index]
+		if _, ok := results[message.ID.String()]; !ok {
+			continue
+		}
+
+		results[message.ID.String()] = MessageStatus{
+			Found:             true,
+			Status:            &message.Status,
+			FailureReason:     message.FailureReason,
+			RequestReceivedAt: &message.RequestReceivedAt,
+			SentAt:            message.SentAt,
+			DeliveredAt:       message.DeliveredAt,
+			FailedAt:          message.FailedAt,
+			ExpiredAt:         message.ExpiredAt,
+			UpdatedAt:         &message.UpdatedAt,
+		}
+	}
+	return results
+}
+
+// MessageStatusesResponse is the payload containing the MessageStatus of every requested ID
+type MessageStatusesResponse struct {
+	response
+	Data map[string]MessageStatus `json:"data"`
+}
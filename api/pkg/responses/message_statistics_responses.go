@@ -0,0 +1,15 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// MessageTimeSeriesResponse is the payload containing entities.MessageTimeSeries
+type MessageTimeSeriesResponse struct {
+	response
+	Data entities.MessageTimeSeries `json:"data"`
+}
+
+// MessageLatencyResponse is the payload containing entities.MessageLatencySummary
+type MessageLatencyResponse struct {
+	response
+	Data entities.MessageLatencySummary `json:"data"`
+}
@@ -5,5 +5,12 @@ import "github.com/NdoleStudio/httpsms/pkg/entities"
 // MessageThreadsResponse is the payload containing []entities.MessageThread
 type MessageThreadsResponse struct {
 	response
+	paginated
 	Data []entities.MessageThread `json:"data"`
 }
+
+// MessageThreadResponse is the payload containing entities.MessageThread
+type MessageThreadResponse struct {
+	response
+	Data entities.MessageThread `json:"data"`
+}
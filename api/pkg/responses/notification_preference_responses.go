@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// NotificationPreferenceResponse is the payload containing entities.NotificationPreference
+type NotificationPreferenceResponse struct {
+	response
+	Data entities.NotificationPreference `json:"data"`
+}
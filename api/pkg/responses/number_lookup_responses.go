@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// NumberLookupResponse is the payload containing entities.NumberLookup
+type NumberLookupResponse struct {
+	response
+	Data entities.NumberLookup `json:"data"`
+}
@@ -5,6 +5,7 @@ import "github.com/NdoleStudio/httpsms/pkg/entities"
 // PhonesResponse is the payload containing entities.Phone
 type PhonesResponse struct {
 	response
+	paginated
 	Data []entities.Phone `json:"data"`
 }
 
@@ -13,3 +14,9 @@ type PhoneResponse struct {
 	response
 	Data entities.Phone `json:"data"`
 }
+
+// PhoneQueueStatsResponse is the payload containing the entities.PhoneQueueStats of a phone
+type PhoneQueueStatsResponse struct {
+	response
+	Data entities.PhoneQueueStats `json:"data"`
+}
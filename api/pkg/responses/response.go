@@ -1,5 +1,86 @@
 package responses
 
+// ErrorCode is a machine readable code which identifies the kind of error in a response
+type ErrorCode string
+
+const (
+	// ErrorCodeBadRequest is returned when the request is not properly formed
+	ErrorCodeBadRequest = ErrorCode("bad_request")
+	// ErrorCodeUnauthorized is returned when the request is not authenticated
+	ErrorCodeUnauthorized = ErrorCode("unauthorized")
+	// ErrorCodePaymentRequired is returned when the request exceeds the limits of the subscription
+	ErrorCodePaymentRequired = ErrorCode("payment_required")
+	// ErrorCodeForbidden is returned when the user is not allowed to carry out the request
+	ErrorCodeForbidden = ErrorCode("forbidden")
+	// ErrorCodeMissingScope is returned when the API key does not have the scope of the request
+	ErrorCodeMissingScope = ErrorCode("missing_scope")
+	// ErrorCodeUserSuspended is returned when the account of the user is suspended
+	ErrorCodeUserSuspended = ErrorCode("user_suspended")
+	// ErrorCodeNotFound is returned when the resource in the request does not exist
+	ErrorCodeNotFound = ErrorCode("not_found")
+	// ErrorCodeConflict is returned when the resource cannot transition to the requested state
+	ErrorCodeConflict = ErrorCode("conflict")
+	// ErrorCodeUpgradeRequired is returned when the version of the Android app is no longer supported
+	ErrorCodeUpgradeRequired = ErrorCode("upgrade_required")
+	// ErrorCodePayloadTooLarge is returned when the body of the request is larger than the endpoint accepts
+	ErrorCodePayloadTooLarge = ErrorCode("payload_too_large")
+	// ErrorCodeValidationFailed is returned when the fields in the request are invalid
+	ErrorCodeValidationFailed = ErrorCode("validation_failed")
+	// ErrorCodeRateLimited is returned when the request exceeds a rate limit
+	ErrorCodeRateLimited = ErrorCode("rate_limited")
+	// ErrorCodeDailyLimitExceeded is returned when the daily message limit of the user is exceeded
+	ErrorCodeDailyLimitExceeded = ErrorCode("daily_limit_exceeded")
+	// ErrorCodeContactDND is returned when a message is sent with enforce_dnd=strict to a contact in its do-not-disturb window
+	ErrorCodeContactDND = ErrorCode("contact_dnd")
+	// ErrorCodeRecipientNotSendable is returned when a message is sent with validate_recipient=true to a landline or an invalid number
+	ErrorCodeRecipientNotSendable = ErrorCode("recipient_not_sendable")
+	// ErrorCodeEncryptionMismatch is returned when the encryption of a message does not match the end-to-end encryption mode of the phone
+	ErrorCodeEncryptionMismatch = ErrorCode("encryption_mismatch")
+	// ErrorCodeEncryptedContent is returned when a feature which needs the plaintext content is used with an end-to-end encrypted message
+	ErrorCodeEncryptedContent = ErrorCode("encrypted_content")
+	// ErrorCodeNotForwardable is returned when a message which was not received by the phone or whose content has expired is forwarded
+	ErrorCodeNotForwardable = ErrorCode("not_forwardable")
+	// ErrorCodeInternal is returned when an unexpected error happens while handling the request
+	ErrorCodeInternal = ErrorCode("internal_error")
+	// ErrorCodeServiceUnavailable is returned when a dependency of the API is not available
+	ErrorCodeServiceUnavailable = ErrorCode("service_unavailable")
+	// ErrorCodeTimeout is returned when the request is not handled before its deadline
+	ErrorCodeTimeout = ErrorCode("timeout")
+)
+
+// Pagination describes the page of a list response
+type Pagination struct {
+	Skip  int `json:"skip" example:"0"`
+	Limit int `json:"limit" example:"20"`
+	// Count is the number of items in the page
+	Count int `json:"count" example:"1"`
+	// HasMore is true when the page is full so there may be more items after it, it is exact when the Total is known
+	HasMore bool `json:"has_more" example:"false"`
+	// Total is the number of items which match the filters, it is only set when the total is requested
+	Total *int64 `json:"total,omitempty" example:"1"`
+}
+
+// NewPagination creates the Pagination of a page with count items
+func NewPagination(skip int, limit int, count int) Pagination {
+	return Pagination{
+		Skip:    skip,
+		Limit:   limit,
+		Count:   count,
+		HasMore: limit > 0 && count >= limit,
+	}
+}
+
+// WithTotal sets the total number of items which match the filters
+func (pagination Pagination) WithTotal(total int64) Pagination {
+	pagination.Total = &total
+	pagination.HasMore = pagination.Count > 0 && int64(pagination.Skip+pagination.Count) < total
+	return pagination
+}
+
+type paginated struct {
+	Pagination Pagination `json:"pagination"`
+}
+
 type response struct {
 	Status  string `json:"status" example:"success"`
 	Message string `json:"message" example:"item created successfully"`
@@ -7,35 +88,50 @@ type response struct {
 
 // InternalServerError is the response with status code is 500
 type InternalServerError struct {
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"We ran into an internal error while handling the request."`
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"internal_error"`
+	Message string    `json:"message" example:"We ran into an internal error while handling the request."`
 }
 
 // NotFound is the response with status code is 404
 type NotFound struct {
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"cannot find message with ID [32343a19-da5e-4b1b-a767-3298a73703ca]"`
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"not_found"`
+	Message string    `json:"message" example:"cannot find message with ID [32343a19-da5e-4b1b-a767-3298a73703ca]"`
 }
 
 // BadRequest is the response with status code is 400
 type BadRequest struct {
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"The request isn't properly formed"`
-	Data    string `json:"data" example:"The request body is not a valid JSON string"`
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"bad_request"`
+	Message string    `json:"message" example:"The request isn't properly formed"`
+	Data    string    `json:"data" example:"The request body is not a valid JSON string"`
 }
 
 // UnprocessableEntity is the response with status code is 422
 type UnprocessableEntity struct {
-	Status  string              `json:"status" example:"error"`
-	Message string              `json:"message" example:"validation errors while sending message"`
-	Data    map[string][]string `json:"data"`
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"validation_failed"`
+	Message string    `json:"message" example:"validation errors while sending message"`
+	// Errors are the validation errors keyed by the field in the request
+	Errors map[string][]string `json:"errors"`
+	// Data contains the same validation errors as Errors
+	Data map[string][]string `json:"data"`
 }
 
-// Unauthorized is the response with status code is 403
+// Conflict is the response with status code is 409
+type Conflict struct {
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"conflict"`
+	Message string    `json:"message" example:"the resource cannot be changed from its current state"`
+}
+
+// Unauthorized is the response with status code is 401
 type Unauthorized struct {
-	Status  string `json:"status" example:"error"`
-	Message string `json:"message" example:"You are not authorized to carry out this request."`
-	Data    string `json:"data" example:"Make sure your API key is set in the [X-API-Key] header in the request"`
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"unauthorized"`
+	Message string    `json:"message" example:"You are not authorized to carry out this request."`
+	Data    string    `json:"data" example:"Make sure your API key is set in the [X-API-Key] header in the request"`
 }
 
 // NoContent is the response when status code is 204
@@ -53,3 +149,33 @@ type Ok[T any] struct {
 
 // OkString returns a string response
 type OkString Ok[string]
+
+// TooManyRequests is the response with status code is 429
+type TooManyRequests struct {
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"rate_limited"`
+	Message string    `json:"message" example:"the phone has exceeded its messages per minute, try again later"`
+}
+
+// UpgradeRequired is the response with status code is 426
+type UpgradeRequired struct {
+	Status  string            `json:"status" example:"error"`
+	Code    ErrorCode         `json:"code" example:"upgrade_required"`
+	Message string            `json:"message" example:"Your httpSMS app version [v1.0.0] is no longer supported, update the app to version [v1.2.0] or later."`
+	Data    map[string]string `json:"data"`
+}
+
+// Forbidden is the response with status code is 403
+type Forbidden struct {
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"missing_scope"`
+	Message string    `json:"message" example:"The API key does not have the [messages:send] scope which is required to carry out this request."`
+	Data    string    `json:"data" example:"messages:send"`
+}
+
+// ServiceUnavailable is the response with status code is 503
+type ServiceUnavailable struct {
+	Status  string    `json:"status" example:"error"`
+	Code    ErrorCode `json:"code" example:"service_unavailable"`
+	Message string    `json:"message" example:"media cannot be sent because the blob store is not configured"`
+}
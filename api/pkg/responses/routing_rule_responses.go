@@ -0,0 +1,15 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// RoutingRuleResponse is the payload containing entities.RoutingRule
+type RoutingRuleResponse struct {
+	response
+	Data entities.RoutingRule `json:"data"`
+}
+
+// RoutingRulesResponse is the payload containing []entities.RoutingRule
+type RoutingRulesResponse struct {
+	response
+	Data []entities.RoutingRule `json:"data"`
+}
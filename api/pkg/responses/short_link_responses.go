@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// ShortLinksResponse is the payload containing []entities.ShortLink
+type ShortLinksResponse struct {
+	response
+	Data []entities.ShortLink `json:"data"`
+}
@@ -0,0 +1,16 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// SlackResponse is the payload containing entities.Slack
+type SlackResponse struct {
+	response
+	Data entities.Slack `json:"data"`
+}
+
+// SlacksResponse is the payload containing []entities.Slack
+type SlacksResponse struct {
+	response
+	paginated
+	Data []entities.Slack `json:"data"`
+}
@@ -0,0 +1,9 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// SummaryReportScheduleResponse is the payload containing entities.SummaryReportSchedule
+type SummaryReportScheduleResponse struct {
+	response
+	Data entities.SummaryReportSchedule `json:"data"`
+}
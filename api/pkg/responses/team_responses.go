@@ -0,0 +1,27 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// TeamsResponse is the payload containing []entities.Team
+type TeamsResponse struct {
+	response
+	Data []entities.Team `json:"data"`
+}
+
+// TeamMemberResponse is the payload containing entities.TeamMember
+type TeamMemberResponse struct {
+	response
+	Data entities.TeamMember `json:"data"`
+}
+
+// TeamMembersResponse is the payload containing []entities.TeamMember
+type TeamMembersResponse struct {
+	response
+	Data []entities.TeamMember `json:"data"`
+}
+
+// TeamInvitationResponse is the payload containing entities.TeamInvitation
+type TeamInvitationResponse struct {
+	response
+	Data entities.TeamInvitation `json:"data"`
+}
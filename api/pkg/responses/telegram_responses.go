@@ -0,0 +1,16 @@
+package responses
+
+import "github.com/NdoleStudio/httpsms/pkg/entities"
+
+// TelegramResponse is the payload containing entities.Telegram
+type TelegramResponse struct {
+	response
+	Data entities.Telegram `json:"data"`
+}
+
+// TelegramsResponse is the payload containing []entities.Telegram
+type TelegramsResponse struct {
+	response
+	paginated
+	Data []entities.Telegram `json:"data"`
+}
@@ -7,3 +7,16 @@ type UserResponse struct {
 	response
 	Data entities.User `json:"data"`
 }
+
+// UserSummaryResponse is the payload containing entities.UserSummary
+type UserSummaryResponse struct {
+	response
+	Data entities.UserSummary `json:"data"`
+}
+
+// UserSummariesResponse is the payload containing []entities.UserSummary
+type UserSummariesResponse struct {
+	response
+	paginated
+	Data []entities.UserSummary `json:"data"`
+}
@@ -1,6 +1,9 @@
 package responses
 
-import "github.com/NdoleStudio/httpsms/pkg/entities"
+import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+)
 
 // WebhookResponse is the payload containing entities.Webhook
 type WebhookResponse struct {
@@ -11,5 +14,13 @@ type WebhookResponse struct {
 // WebhooksResponse is the payload containing []entities.Webhook
 type WebhooksResponse struct {
 	response
+	paginated
 	Data []entities.Webhook `json:"data"`
 }
+
+// WebhookSampleResponse is the payload containing the sample of an event which is sent to an entities.Webhook, the
+// data is the cloudevent in the raw format or the services.FlatWebhookPayload in the flat format
+type WebhookSampleResponse struct {
+	response
+	Data services.FlatWebhookPayload `json:"data"`
+}
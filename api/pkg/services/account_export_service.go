@@ -0,0 +1,559 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// accountExportPageSize is the number of records of a type which are read from the database in one query
+	accountExportPageSize = 1000
+
+	// accountImportBatchSize is the number of records of a type which are restored in one call to the repository
+	accountImportBatchSize = 500
+
+	// accountExportMaxLineSize is the maximum size of a line of an account export bundle
+	accountExportMaxLineSize = 16 * 1024 * 1024
+)
+
+// AccountExportService exports all the records of an account as a versioned bundle and restores a bundle into an
+// account on another installation
+type AccountExportService struct {
+	service
+	logger         telemetry.Logger
+	tracer         telemetry.Tracer
+	repository     repositories.AccountExportRepository
+	userRepository repositories.UserRepository
+}
+
+// NewAccountExportService creates a new AccountExportService
+func NewAccountExportService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.AccountExportRepository,
+	userRepository repositories.UserRepository,
+) (s *AccountExportService) {
+	return &AccountExportService{
+		logger:         logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:         tracer,
+		repository:     repository,
+		userRepository: userRepository,
+	}
+}
+
+// Export writes the records of a user to the writer as gzip compressed NDJSON. The first line is the
+// entities.AccountExportHeader and the last line is the entities.AccountExportManifest with the number of records and
+// the checksum of every type so that a bundle which was not written completely cannot be imported.
+func (service *AccountExportService) Export(ctx context.Context, userID entities.UserID, writer io.Writer) (*entities.AccountExportManifest, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.userRepository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	bundle := newAccountExportWriter(writer)
+	header := &entities.AccountExportHeader{
+		Version:   entities.AccountExportVersion,
+		UserID:    user.ID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = bundle.write(entities.AccountExportRecordTypeHeader, header); err != nil {
+		msg := fmt.Sprintf("cannot write the header of the export of user [%s]", user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, recordType := range entities.AccountExportRecordTypes {
+		if err = service.exportRecords(ctx, bundle, user, recordType); err != nil {
+			msg := fmt.Sprintf("cannot export the [%s] records of user [%s]", recordType, user.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	manifest := &entities.AccountExportManifest{
+		Version:   header.Version,
+		UserID:    header.UserID,
+		CreatedAt: header.CreatedAt,
+		Sections:  bundle.sections(),
+	}
+	if err = bundle.close(manifest); err != nil {
+		msg := fmt.Sprintf("cannot write the manifest of the export of user [%s]", user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("exported account of user [%s] with sections [%+#v]", user.ID, manifest.Sections))
+	return manifest, nil
+}
+
+func (service *AccountExportService) exportRecords(ctx context.Context, bundle *accountExportWriter, user *entities.User, recordType entities.AccountExportRecordType) error {
+	switch recordType {
+	case entities.AccountExportRecordTypeUser:
+		return bundle.write(recordType, user)
+	case entities.AccountExportRecordTypePhone:
+		return exportAccountRecords[entities.Phone](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypePhoneSIM:
+		return exportAccountRecords[entities.PhoneSIM](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeWebhook:
+		return exportAccountRecords[entities.Webhook](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeContact:
+		return exportAccountRecords[entities.Contact](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeContactLabel:
+		return exportAccountRecords[entities.ContactLabel](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeContactGroup:
+		return exportAccountRecords[entities.ContactGroup](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeContactGroupMember:
+		return exportAccountRecords[entities.ContactGroupMember](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeMessageThread:
+		return exportAccountRecords[entities.MessageThread](ctx, service.repository, bundle, user.ID, recordType)
+	case entities.AccountExportRecordTypeMessage:
+		return exportAccountRecords[entities.Message](ctx, service.repository, bundle, user.ID, recordType)
+	default:
+		return stacktrace.NewError(fmt.Sprintf("cannot export records with type [%s]", recordType))
+	}
+}
+
+// exportAccountRecords writes the records of a user with type T to the bundle one page at a time
+func exportAccountRecords[T any](ctx context.Context, repository repositories.AccountExportRepository, bundle *accountExportWriter, userID entities.UserID, recordType entities.AccountExportRecordType) error {
+	for offset := 0; ; offset += accountExportPageSize {
+		var records []T
+		if err := repository.Index(ctx, userID, &records, offset, accountExportPageSize); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot fetch [%s] records with offset [%d]", recordType, offset))
+		}
+
+		for index := range records {
+			if err := bundle.write(recordType, &records[index]); err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot write [%s] record [%d]", recordType, offset+index))
+			}
+		}
+
+		if len(records) < accountExportPageSize {
+			return nil
+		}
+	}
+}
+
+// AccountImportParams are the parameters for restoring an account export bundle
+type AccountImportParams struct {
+	// UserID is the ID of the user on this installation who owns the imported records
+	UserID entities.UserID
+	// Merge allows importing a bundle into an account which already has phones, contacts, messages etc.
+	Merge bool
+	// Bundle is read twice, once to verify the manifest and once to restore the records
+	Bundle io.ReadSeeker
+}
+
+// Import restores an account export bundle into the account of a user. The user ID of the records is replaced with
+// the ID of the user and the records whose ID already exists are skipped so that an import can be run again after it
+// was interrupted. The settings of the exported user are only restored into an empty account.
+func (service *AccountExportService) Import(ctx context.Context, params AccountImportParams) (*entities.AccountImportResult, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	manifest, err := service.verify(params.Bundle)
+	if err != nil {
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot verify the account export bundle"))
+	}
+
+	user, err := service.userRepository.Load(ctx, params.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s], the user must sign in before an account is imported", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	count, err := service.repository.Count(ctx, user.ID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the records of user [%s]", user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if count > 0 && !params.Merge {
+		msg := fmt.Sprintf("the account of user [%s] has [%d] records, the bundle is only merged into an account which is not empty when merge is set", user.ID, count)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+	}
+
+	if _, err = params.Bundle.Seek(0, io.SeekStart); err != nil {
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot rewind the account export bundle"))
+	}
+
+	bundle, err := newAccountExportReader(params.Bundle)
+	if err != nil {
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot read the account export bundle"))
+	}
+	defer bundle.close()
+
+	importer := &accountImporter{
+		service:       service,
+		user:          user,
+		source:        manifest.UserID,
+		applySettings: count == 0,
+		result: &entities.AccountImportResult{
+			UserID:   user.ID,
+			Imported: map[entities.AccountExportRecordType]int{},
+			Skipped:  map[entities.AccountExportRecordType]int{},
+		},
+	}
+
+	for {
+		record, err := bundle.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot read record from the account export bundle"))
+		}
+
+		if err = importer.add(ctx, record); err != nil {
+			msg := fmt.Sprintf("cannot import [%s] records into the account of user [%s]", record.Type, user.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	if err = importer.flush(ctx); err != nil {
+		msg := fmt.Sprintf("cannot import [%s] records into the account of user [%s]", importer.recordType, user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("imported account of user [%s] into user [%s] with result [%+#v]", manifest.UserID, user.ID, importer.result))
+	return importer.result, nil
+}
+
+// verify reads a bundle and checks the version, the number of records and the checksum of every type in the manifest
+func (service *AccountExportService) verify(reader io.Reader) (*entities.AccountExportManifest, error) {
+	bundle, err := newAccountExportReader(reader)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot read the account export bundle")
+	}
+	defer bundle.close()
+
+	record, err := bundle.next()
+	if err != nil || record.Type != entities.AccountExportRecordTypeHeader {
+		return nil, stacktrace.NewError("the first line of the account export bundle is not the header")
+	}
+
+	header := new(entities.AccountExportHeader)
+	if err = json.Unmarshal(record.Data, header); err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode the header of the account export bundle")
+	}
+
+	if header.Version < 1 || header.Version > entities.AccountExportVersion {
+		return nil, stacktrace.NewError(fmt.Sprintf("account export bundle version [%d] is not supported, the supported version is [%d]", header.Version, entities.AccountExportVersion))
+	}
+
+	checksums := newAccountExportChecksums()
+	var manifest *entities.AccountExportManifest
+	for {
+		record, err = bundle.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot read record from the account export bundle")
+		}
+
+		if manifest != nil {
+			return nil, stacktrace.NewError(fmt.Sprintf("the account export bundle has a [%s] record after the manifest", record.Type))
+		}
+
+		switch {
+		case record.Type == entities.AccountExportRecordTypeManifest:
+			manifest = new(entities.AccountExportManifest)
+			if err = json.Unmarshal(record.Data, manifest); err != nil {
+				return nil, stacktrace.Propagate(err, "cannot decode the manifest of the account export bundle")
+			}
+		case isAccountExportRecordType(record.Type):
+			checksums.add(record.Type, record.Data)
+		default:
+			return nil, stacktrace.NewError(fmt.Sprintf("the account export bundle has a record with the unknown type [%s]", record.Type))
+		}
+	}
+
+	if manifest == nil {
+		return nil, stacktrace.NewError("the account export bundle does not have a manifest, it was not written completely")
+	}
+
+	if manifest.Version != header.Version || manifest.UserID != header.UserID {
+		return nil, stacktrace.NewError(fmt.Sprintf("the manifest [%d, %s] does not match the header [%d, %s]", manifest.Version, manifest.UserID, header.Version, header.UserID))
+	}
+
+	sections := checksums.sections()
+	for _, recordType := range entities.AccountExportRecordTypes {
+		if sections[recordType] != manifest.Sections[recordType] {
+			return nil, stacktrace.NewError(fmt.Sprintf("the [%s] records [%+#v] do not match the manifest [%+#v]", recordType, sections[recordType], manifest.Sections[recordType]))
+		}
+	}
+
+	return manifest, nil
+}
+
+// accountImporter restores the consecutive records of the same type of a bundle in batches
+type accountImporter struct {
+	service       *AccountExportService
+	user          *entities.User
+	source        entities.UserID
+	applySettings bool
+	recordType    entities.AccountExportRecordType
+	lines         []json.RawMessage
+	result        *entities.AccountImportResult
+}
+
+func (importer *accountImporter) add(ctx context.Context, record *entities.AccountExportRecord) error {
+	if record.Type == entities.AccountExportRecordTypeHeader || record.Type == entities.AccountExportRecordTypeManifest {
+		return nil
+	}
+
+	if record.Type != importer.recordType || len(importer.lines) == accountImportBatchSize {
+		if err := importer.flush(ctx); err != nil {
+			return err
+		}
+		importer.recordType = record.Type
+	}
+
+	importer.lines = append(importer.lines, record.Data)
+	return nil
+}
+
+func (importer *accountImporter) flush(ctx context.Context) error {
+	if len(importer.lines) == 0 {
+		return nil
+	}
+
+	imported, err := importer.restore(ctx)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot restore [%d] [%s] records", len(importer.lines), importer.recordType))
+	}
+
+	importer.result.Imported[importer.recordType] += imported
+	importer.result.Skipped[importer.recordType] += len(importer.lines) - imported
+	importer.lines = importer.lines[:0]
+	return nil
+}
+
+func (importer *accountImporter) restore(ctx context.Context) (int, error) {
+	repository := importer.service.repository
+	userID := importer.user.ID
+
+	switch importer.recordType {
+	case entities.AccountExportRecordTypeUser:
+		return importer.restoreSettings(ctx)
+	case entities.AccountExportRecordTypePhone:
+		return importAccountRecords(ctx, repository, importer.lines, func(phone *entities.Phone) {
+			phone.UserID = userID
+			phone.SIMs = nil
+		})
+	case entities.AccountExportRecordTypePhoneSIM:
+		return importAccountRecords(ctx, repository, importer.lines, func(sim *entities.PhoneSIM) { sim.UserID = userID })
+	case entities.AccountExportRecordTypeWebhook:
+		return importAccountRecords(ctx, repository, importer.lines, func(webhook *entities.Webhook) { webhook.UserID = userID })
+	case entities.AccountExportRecordTypeContact:
+		return importAccountRecords(ctx, repository, importer.lines, func(contact *entities.Contact) {
+			contact.UserID = userID
+			if contact.NotesUpdatedBy != nil && *contact.NotesUpdatedBy == importer.source {
+				contact.NotesUpdatedBy = &userID
+			}
+		})
+	case entities.AccountExportRecordTypeContactLabel:
+		return importAccountRecords(ctx, repository, importer.lines, func(label *entities.ContactLabel) { label.UserID = userID })
+	case entities.AccountExportRecordTypeContactGroup:
+		return importAccountRecords(ctx, repository, importer.lines, func(group *entities.ContactGroup) { group.UserID = userID })
+	case entities.AccountExportRecordTypeContactGroupMember:
+		return importAccountRecords(ctx, repository, importer.lines, func(member *entities.ContactGroupMember) { member.UserID = userID })
+	case entities.AccountExportRecordTypeMessageThread:
+		return importAccountRecords(ctx, repository, importer.lines, func(thread *entities.MessageThread) { thread.UserID = userID })
+	case entities.AccountExportRecordTypeMessage:
+		return importAccountRecords(ctx, repository, importer.lines, func(message *entities.Message) { message.UserID = userID })
+	default:
+		return 0, stacktrace.NewError(fmt.Sprintf("cannot import records with type [%s]", importer.recordType))
+	}
+}
+
+// restoreSettings copies the preferences of the exported user into the user who imports an empty account. The ID,
+// email, API key, subscription and admin flag of the user are not changed.
+func (importer *accountImporter) restoreSettings(ctx context.Context) (int, error) {
+	if !importer.applySettings {
+		return 0, nil
+	}
+
+	// a bundle which was exported before the setting existed keeps the default
+	exported := &entities.User{RequestDeliveryReports: true}
+	if err := json.Unmarshal(importer.lines[0], exported); err != nil {
+		return 0, stacktrace.Propagate(err, "cannot decode the exported user")
+	}
+
+	user := importer.user
+	user.Timezone = exported.Timezone
+	user.NotificationMessageStatusEnabled = exported.NotificationMessageStatusEnabled
+	user.NotificationWebhookEnabled = exported.NotificationWebhookEnabled
+	user.NotificationHeartbeatEnabled = exported.NotificationHeartbeatEnabled
+	user.NotificationHeartbeatQuietHours = exported.NotificationHeartbeatQuietHours
+	user.FailoverEnabled = exported.FailoverEnabled
+	user.FailoverPhoneNumber = exported.FailoverPhoneNumber
+	user.DailyMessageLimit = exported.DailyMessageLimit
+	user.RetentionReceivedDays = exported.RetentionReceivedDays
+	user.RetentionSentDays = exported.RetentionSentDays
+	user.RetryPolicy = exported.RetryPolicy
+	user.RequestDeliveryReports = exported.RequestDeliveryReports
+	user.UpdatedAt = time.Now().UTC()
+
+	if err := importer.service.userRepository.Update(ctx, user); err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot restore the settings of user [%s]", user.ID))
+	}
+	return 1, nil
+}
+
+// importAccountRecords decodes the lines into records with type T, replaces the owner of the records with remap and stores them
+func importAccountRecords[T any](ctx context.Context, repository repositories.AccountExportRepository, lines []json.RawMessage, remap func(record *T)) (int, error) {
+	records := make([]T, len(lines))
+	for index, line := range lines {
+		if err := json.Unmarshal(line, &records[index]); err != nil {
+			return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot decode record [%d] into [%T]", index, records[index]))
+		}
+		remap(&records[index])
+	}
+
+	count, err := repository.Store(ctx, &records)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot store [%d] records with type [%T]", len(records), records))
+	}
+	return int(count), nil
+}
+
+func isAccountExportRecordType(recordType entities.AccountExportRecordType) bool {
+	for _, item := range entities.AccountExportRecordTypes {
+		if item == recordType {
+			return true
+		}
+	}
+	return false
+}
+
+// accountExportChecksums counts the records of every type and hashes their data
+type accountExportChecksums struct {
+	counts map[entities.AccountExportRecordType]int
+	hashes map[entities.AccountExportRecordType]hash.Hash
+}
+
+func newAccountExportChecksums() *accountExportChecksums {
+	return &accountExportChecksums{
+		counts: map[entities.AccountExportRecordType]int{},
+		hashes: map[entities.AccountExportRecordType]hash.Hash{},
+	}
+}
+
+func (checksums *accountExportChecksums) add(recordType entities.AccountExportRecordType, data []byte) {
+	if _, ok := checksums.hashes[recordType]; !ok {
+		checksums.hashes[recordType] = sha256.New()
+	}
+	checksums.hashes[recordType].Write(data)
+	checksums.hashes[recordType].Write([]byte("\n"))
+	checksums.counts[recordType]++
+}
+
+func (checksums *accountExportChecksums) sections() map[entities.AccountExportRecordType]entities.AccountExportSection {
+	sections := make(map[entities.AccountExportRecordType]entities.AccountExportSection, len(checksums.counts))
+	for recordType, count := range checksums.counts {
+		sections[recordType] = entities.AccountExportSection{
+			Count:    count,
+			Checksum: hex.EncodeToString(checksums.hashes[recordType].Sum(nil)),
+		}
+	}
+	return sections
+}
+
+// accountExportWriter writes the records of an account as gzip compressed NDJSON
+type accountExportWriter struct {
+	gzip      *gzip.Writer
+	encoder   *json.Encoder
+	checksums *accountExportChecksums
+}
+
+func newAccountExportWriter(writer io.Writer) *accountExportWriter {
+	compressed := gzip.NewWriter(writer)
+	return &accountExportWriter{
+		gzip:      compressed,
+		encoder:   json.NewEncoder(compressed),
+		checksums: newAccountExportChecksums(),
+	}
+}
+
+func (writer *accountExportWriter) write(recordType entities.AccountExportRecordType, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot encode [%T] record", value))
+	}
+
+	if isAccountExportRecordType(recordType) {
+		writer.checksums.add(recordType, data)
+	}
+
+	return writer.encoder.Encode(&entities.AccountExportRecord{Type: recordType, Data: data})
+}
+
+func (writer *accountExportWriter) sections() map[entities.AccountExportRecordType]entities.AccountExportSection {
+	return writer.checksums.sections()
+}
+
+func (writer *accountExportWriter) close(manifest *entities.AccountExportManifest) error {
+	if err := writer.write(entities.AccountExportRecordTypeManifest, manifest); err != nil {
+		return stacktrace.Propagate(err, "cannot write the manifest")
+	}
+
+	if err := writer.gzip.Close(); err != nil {
+		return stacktrace.Propagate(err, "cannot close gzip writer")
+	}
+	return nil
+}
+
+// accountExportReader reads the records of an account from gzip compressed NDJSON
+type accountExportReader struct {
+	gzip    *gzip.Reader
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newAccountExportReader(reader io.Reader) (*accountExportReader, error) {
+	compressed, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot create gzip reader")
+	}
+
+	scanner := bufio.NewScanner(compressed)
+	scanner.Buffer(make([]byte, 0, 64*1024), accountExportMaxLineSize)
+	return &accountExportReader{gzip: compressed, scanner: scanner}, nil
+}
+
+// next returns the next record of the bundle and io.EOF after the last record
+func (reader *accountExportReader) next() (*entities.AccountExportRecord, error) {
+	if !reader.scanner.Scan() {
+		if err := reader.scanner.Err(); err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot read line [%d]", reader.line+1))
+		}
+		return nil, io.EOF
+	}
+
+	reader.line++
+	record := new(entities.AccountExportRecord)
+	if err := json.Unmarshal(reader.scanner.Bytes(), record); err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot decode record on line [%d]", reader.line))
+	}
+	return record, nil
+}
+
+func (reader *accountExportReader) close() {
+	_ = reader.gzip.Close()
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// stubAccountUserRepository stores entities.User in memory
+type stubAccountUserRepository struct {
+	repositories.UserRepository
+	users map[entities.UserID]entities.User
+}
+
+func (repository *stubAccountUserRepository) Load(_ context.Context, userID entities.UserID) (*entities.User, error) {
+	if user, ok := repository.users[userID]; ok {
+		return &user, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "user does not exist")
+}
+
+func (repository *stubAccountUserRepository) Update(_ context.Context, user *entities.User) error {
+	repository.users[user.ID] = *user
+	return nil
+}
+
+// accountExportTestInstance is an installation with its own database
+type accountExportTestInstance struct {
+	repository     repositories.AccountExportRepository
+	userRepository *stubAccountUserRepository
+	service        *AccountExportService
+}
+
+func newAccountExportTestInstance(t *testing.T, users ...entities.User) *accountExportTestInstance {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	db, err := gorm.Open(repositories.NewSqliteDialector(filepath.Join(t.TempDir(), "httpsms.db")), &gorm.Config{TranslateError: true})
+	assert.Nil(t, err)
+	assert.Nil(t, db.AutoMigrate(&entities.Phone{}, &entities.PhoneSIM{}, &entities.Webhook{}, &entities.Contact{}, &entities.ContactLabel{}, &entities.ContactGroup{}, &entities.ContactGroupMember{}, &entities.MessageThread{}, &entities.Message{}))
+
+	userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{}}
+	for _, user := range users {
+		userRepository.users[user.ID] = user
+	}
+
+	repository := repositories.NewGormAccountExportRepository(logger, tracer, db)
+	return &accountExportTestInstance{
+		repository:     repository,
+		userRepository: userRepository,
+		service:        NewAccountExportService(logger, tracer, repository, userRepository),
+	}
+}
+
+// seed stores an account with every type of record for a user and returns the number of records of every type
+func (instance *accountExportTestInstance) seed(t *testing.T, userID entities.UserID) map[entities.AccountExportRecordType]int {
+	timestamp := time.Date(2022, 6, 5, 14, 26, 2, 302718000, time.UTC)
+	owner := "+18005550199"
+	contact := "+18005550100"
+	phoneID := uuid.New()
+	contactID := uuid.New()
+	groupID := uuid.New()
+	messageID := uuid.New()
+	content := "Hello world"
+
+	store := func(records any) {
+		_, err := instance.repository.Store(context.Background(), records)
+		assert.Nil(t, err)
+	}
+
+	store(&[]entities.Phone{{ID: phoneID, UserID: userID, PhoneNumber: owner, SIM: entities.SIM1, IsDefault: true, MessagesPerMinute: 10, MaxSendAttempts: 2, CreatedAt: timestamp, UpdatedAt: timestamp}})
+	store(&[]entities.PhoneSIM{{ID: uuid.New(), PhoneID: phoneID, UserID: userID, Slot: 0, CarrierName: "T-Mobile", PhoneNumber: &owner, CreatedAt: timestamp, UpdatedAt: timestamp}})
+	store(&[]entities.Webhook{{ID: uuid.New(), UserID: userID, URL: "https://example.com", SigningKey: "secret", PhoneNumbers: []string{owner}, Events: []string{"message.phone.received"}, PayloadFormat: "raw", CreatedAt: timestamp, UpdatedAt: timestamp}})
+	store(&[]entities.Contact{{ID: contactID, UserID: userID, PhoneNumber: contact, Name: "Jane Doe", Notes: "prefers French", NotesUpdatedBy: &userID, CreatedAt: timestamp, UpdatedAt: timestamp}})
+	store(&[]entities.ContactLabel{{ContactID: contactID, Label: "vip", UserID: userID}})
+	store(&[]entities.ContactGroup{{ID: groupID, UserID: userID, Name: "All Drivers", CreatedAt: timestamp, UpdatedAt: timestamp}})
+	store(&[]entities.ContactGroupMember{{ContactGroupID: groupID, ContactID: contactID, UserID: userID}})
+	store(&[]entities.MessageThread{{ID: uuid.New(), UserID: userID, Owner: owner, Contact: contact, Color: "blue", Status: entities.MessageStatusReceived, LastMessageID: &messageID, LastMessageContent: &content, OrderTimestamp: timestamp, CreatedAt: timestamp, UpdatedAt: timestamp}})
+
+	messages := make([]entities.Message, 0, accountExportPageSize+1)
+	for index := 0; index < accountExportPageSize+1; index++ {
+		id := uuid.New()
+		if index == 0 {
+			id = messageID
+		}
+		messages = append(messages, entities.Message{ID: id, UserID: userID, Owner: owner, Contact: contact, Content: content, Type: entities.MessageTypeMobileOriginated, Status: entities.MessageStatusReceived, SIM: entities.SIM1, RequestReceivedAt: timestamp, OrderTimestamp: timestamp, CreatedAt: timestamp, UpdatedAt: timestamp})
+	}
+	store(&messages)
+
+	return map[entities.AccountExportRecordType]int{
+		entities.AccountExportRecordTypeUser:               1,
+		entities.AccountExportRecordTypePhone:              1,
+		entities.AccountExportRecordTypePhoneSIM:           1,
+		entities.AccountExportRecordTypeWebhook:            1,
+		entities.AccountExportRecordTypeContact:            1,
+		entities.AccountExportRecordTypeContactLabel:       1,
+		entities.AccountExportRecordTypeContactGroup:       1,
+		entities.AccountExportRecordTypeContactGroupMember: 1,
+		entities.AccountExportRecordTypeMessageThread:      1,
+		entities.AccountExportRecordTypeMessage:            len(messages),
+	}
+}
+
+// snapshot returns the records of a user as JSON with the user ID replaced so that the accounts of two users can be compared
+func (instance *accountExportTestInstance) snapshot(t *testing.T, userID entities.UserID) map[entities.AccountExportRecordType]string {
+	snapshot := map[entities.AccountExportRecordType]string{}
+	for _, recordType := range entities.AccountExportRecordTypes {
+		if recordType == entities.AccountExportRecordTypeUser {
+			continue
+		}
+
+		buffer := new(bytes.Buffer)
+		bundle := newAccountExportWriter(buffer)
+		assert.Nil(t, (&AccountExportService{repository: instance.repository}).exportRecords(context.Background(), bundle, &entities.User{ID: userID}, recordType))
+		assert.Nil(t, bundle.close(&entities.AccountExportManifest{}))
+
+		reader, err := gzip.NewReader(buffer)
+		assert.Nil(t, err)
+		content := new(bytes.Buffer)
+		_, err = content.ReadFrom(reader)
+		assert.Nil(t, err)
+		snapshot[recordType] = strings.ReplaceAll(content.String(), string(userID), "{user_id}")
+	}
+	return snapshot
+}
+
+// rewriteBundle decompresses a bundle, changes its lines and compresses it again
+func rewriteBundle(t *testing.T, bundle []byte, rewrite func(lines []string) []string) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(bundle))
+	assert.Nil(t, err)
+
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), accountExportMaxLineSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Nil(t, scanner.Err())
+
+	buffer := new(bytes.Buffer)
+	writer := gzip.NewWriter(buffer)
+	_, err = writer.Write([]byte(strings.Join(rewrite(lines), "\n") + "\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestAccountExportService_RoundTrip(t *testing.T) {
+	sourceAPIKeyHash, targetAPIKeyHash := entities.HashAPIKey("key-a"), entities.HashAPIKey("key-b")
+	source := entities.User{ID: "user-a", Email: "a@example.com", APIKeyHash: &sourceAPIKeyHash, Timezone: "Africa/Douala", DailyMessageLimit: 500, RetentionReceivedDays: 30, IsAdmin: true}
+	target := entities.User{ID: "user-b", Email: "b@example.com", APIKeyHash: &targetAPIKeyHash, Timezone: "Africa/Accra"}
+
+	export := func(t *testing.T) (*accountExportTestInstance, map[entities.AccountExportRecordType]int, []byte) {
+		instance := newAccountExportTestInstance(t, source)
+		counts := instance.seed(t, source.ID)
+
+		buffer := new(bytes.Buffer)
+		manifest, err := instance.service.Export(context.Background(), source.ID, buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.AccountExportVersion, manifest.Version)
+		for recordType, count := range counts {
+			assert.Equal(t, count, manifest.Sections[recordType].Count, recordType)
+		}
+		return instance, counts, buffer.Bytes()
+	}
+
+	t.Run("exported account is restored into a clean database", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		instance, counts, bundle := export(t)
+		clean := newAccountExportTestInstance(t, target)
+
+		// Act
+		result, err := clean.service.Import(context.Background(), AccountImportParams{UserID: target.ID, Bundle: bytes.NewReader(bundle)})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, target.ID, result.UserID)
+		for recordType, count := range counts {
+			assert.Equal(t, count, result.Imported[recordType], recordType)
+			assert.Equal(t, 0, result.Skipped[recordType], recordType)
+		}
+		assert.Equal(t, instance.snapshot(t, source.ID), clean.snapshot(t, target.ID))
+
+		user := clean.userRepository.users[target.ID]
+		assert.Equal(t, "Africa/Douala", user.Timezone)
+		assert.Equal(t, uint(500), user.DailyMessageLimit)
+		assert.Equal(t, uint(30), user.RetentionReceivedDays)
+		assert.Equal(t, targetAPIKeyHash, *user.APIKeyHash)
+		assert.Equal(t, "b@example.com", user.Email)
+		assert.False(t, user.IsAdmin)
+	})
+
+	t.Run("import is skipped for records which are already present", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, counts, bundle := export(t)
+		clean := newAccountExportTestInstance(t, target)
+		_, err := clean.service.Import(context.Background(), AccountImportParams{UserID: target.ID, Bundle: bytes.NewReader(bundle)})
+		assert.Nil(t, err)
+		snapshot := clean.snapshot(t, target.ID)
+
+		// Act
+		result, err := clean.service.Import(context.Background(), AccountImportParams{UserID: target.ID, Merge: true, Bundle: bytes.NewReader(bundle)})
+
+		// Assert
+		assert.Nil(t, err)
+		for recordType, count := range counts {
+			assert.Equal(t, 0, result.Imported[recordType], recordType)
+			assert.Equal(t, count, result.Skipped[recordType], recordType)
+		}
+		assert.Equal(t, snapshot, clean.snapshot(t, target.ID))
+	})
+
+	t.Run("import into an account which is not empty requires merge", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, _, bundle := export(t)
+		clean := newAccountExportTestInstance(t, target)
+		clean.seed(t, target.ID)
+
+		// Act
+		result, err := clean.service.Import(context.Background(), AccountImportParams{UserID: target.ID, Bundle: bytes.NewReader(bundle)})
+
+		// Assert
+		assert.Nil(t, result)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "merge")
+	})
+
+	t.Run("bundle which cannot be verified is not imported", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		_, _, bundle := export(t)
+
+		bundles := map[string][]byte{
+			"unsupported version": rewriteBundle(t, bundle, func(lines []string) []string {
+				lines[0] = strings.Replace(lines[0], `"version":1`, `"version":2`, 1)
+				return lines
+			}),
+			"missing manifest": rewriteBundle(t, bundle, func(lines []string) []string {
+				return lines[:len(lines)-1]
+			}),
+			"changed record": rewriteBundle(t, bundle, func(lines []string) []string {
+				lines[len(lines)-2] = strings.Replace(lines[len(lines)-2], "Hello world", "Hello world!", 1)
+				return lines
+			}),
+			"missing record": rewriteBundle(t, bundle, func(lines []string) []string {
+				return append(lines[:len(lines)-2], lines[len(lines)-1])
+			}),
+		}
+
+		for name, content := range bundles {
+			clean := newAccountExportTestInstance(t, target)
+
+			// Act
+			result, err := clean.service.Import(context.Background(), AccountImportParams{UserID: target.ID, Bundle: bytes.NewReader(content)})
+
+			// Assert
+			assert.Nil(t, result, name)
+			assert.NotNil(t, err, name)
+			assert.Equal(t, map[entities.AccountExportRecordType]string{}, onlyNonEmpty(clean.snapshot(t, target.ID)), name)
+		}
+	})
+}
+
+// onlyNonEmpty removes the record types which only have the manifest line
+func onlyNonEmpty(snapshot map[entities.AccountExportRecordType]string) map[entities.AccountExportRecordType]string {
+	result := map[entities.AccountExportRecordType]string{}
+	for recordType, content := range snapshot {
+		if strings.Count(content, "\n") > 1 {
+			result[recordType] = content
+		}
+	}
+	return result
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// apiKeyPrefix makes scoped API keys distinguishable from the primary API key of a user
+	apiKeyPrefix = "hsk_"
+
+	apiKeyLength        = 48
+	apiKeyDisplayLength = 8
+
+	// apiKeyUsageInterval is the minimum duration between 2 updates of the usage of an entities.APIKey
+	apiKeyUsageInterval = time.Minute
+
+	apiKeyMaxUserAgentLength = 255
+)
+
+// APIKeyService is responsible for managing entities.APIKey
+type APIKeyService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.APIKeyRepository
+
+	usageMutex      sync.Mutex
+	usageRecordedAt map[uuid.UUID]time.Time
+}
+
+// NewAPIKeyService creates a new APIKeyService
+func NewAPIKeyService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.APIKeyRepository,
+) (s *APIKeyService) {
+	return &APIKeyService{
+		logger:          logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:          tracer,
+		repository:      repository,
+		usageRecordedAt: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Index fetches the entities.APIKey of an entities.UserID
+func (service *APIKeyService) Index(ctx context.Context, userID entities.UserID, params repositories.IndexParams) ([]*entities.APIKey, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	apiKeys, err := service.repository.Index(ctx, userID, params)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch api keys with params [%+#v]", params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("fetched [%d] api keys with prams [%+#v]", len(apiKeys), params))
+	return apiKeys, nil
+}
+
+// APIKeyStoreParams are parameters for creating a new entities.APIKey
+type APIKeyStoreParams struct {
+	UserID entities.UserID
+	Label  string
+	Scopes pq.StringArray
+}
+
+// Store a new entities.APIKey, the plain text key is only available in the returned entities.APIKey
+func (service *APIKeyService) Store(ctx context.Context, params *APIKeyStoreParams) (*entities.APIKey, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	key, err := service.generateKey()
+	if err != nil {
+		msg := fmt.Sprintf("cannot generate api key for user with ID [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	apiKey := &entities.APIKey{
+		ID:        uuid.New(),
+		UserID:    params.UserID,
+		Label:     params.Label,
+		Key:       key,
+		KeyHash:   entities.HashAPIKey(key),
+		KeyPrefix: key[:len(apiKeyPrefix)+apiKeyDisplayLength],
+		Scopes:    params.Scopes,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err = service.repository.Store(ctx, apiKey); err != nil {
+		msg := fmt.Sprintf("cannot save api key with id [%s]", apiKey.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("api key saved with id [%s] and scopes [%s] for user [%s]", apiKey.ID, apiKey.Scopes, apiKey.UserID))
+	return apiKey, nil
+}
+
+// Revoke an entities.APIKey so that it can no longer be used
+func (service *APIKeyService) Revoke(ctx context.Context, userID entities.UserID, apiKeyID uuid.UUID) (*entities.APIKey, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	apiKey, err := service.repository.Load(ctx, userID, apiKeyID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load api key with userID [%s] and ID [%s]", userID, apiKeyID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if apiKey.IsRevoked() {
+		ctxLogger.Info(fmt.Sprintf("api key with id [%s] was already revoked at [%s]", apiKey.ID, apiKey.RevokedAt))
+		return apiKey, nil
+	}
+
+	if err = service.repository.Revoke(ctx, apiKey); err != nil {
+		msg := fmt.Sprintf("cannot revoke api key with id [%s] and user id [%s]", apiKeyID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("revoked api key with id [%s] and user id [%s]", apiKeyID, userID))
+	return apiKey, nil
+}
+
+// RecordUsage stores the last used time and caller of an entities.APIKey.
+// The usage is written at most once in apiKeyUsageInterval per entities.APIKey so that authenticated requests don't write to the database.
+func (service *APIKeyService) RecordUsage(ctx context.Context, apiKeyID uuid.UUID, ipAddress string, userAgent string) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	usedAt := time.Now().UTC()
+	if !service.reserveUsage(apiKeyID, usedAt) {
+		return nil
+	}
+
+	if len(userAgent) > apiKeyMaxUserAgentLength {
+		userAgent = userAgent[:apiKeyMaxUserAgentLength]
+	}
+
+	err := service.repository.RecordUsage(ctx, apiKeyID, usedAt, usedAt.Add(-apiKeyUsageInterval), ipAddress, userAgent)
+	if err != nil {
+		service.releaseUsage(apiKeyID, usedAt)
+		msg := fmt.Sprintf("cannot record usage of api key with ID [%s]", apiKeyID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// reserveUsage checks if the usage of an entities.APIKey should be written, only one of concurrent callers wins the reservation
+func (service *APIKeyService) reserveUsage(apiKeyID uuid.UUID, usedAt time.Time) bool {
+	service.usageMutex.Lock()
+	defer service.usageMutex.Unlock()
+
+	if recordedAt, ok := service.usageRecordedAt[apiKeyID]; ok && usedAt.Sub(recordedAt) < apiKeyUsageInterval {
+		return false
+	}
+
+	service.usageRecordedAt[apiKeyID] = usedAt
+	return true
+}
+
+// releaseUsage removes a reservation when the usage could not be written so that the next request tries again
+func (service *APIKeyService) releaseUsage(apiKeyID uuid.UUID, usedAt time.Time) {
+	service.usageMutex.Lock()
+	defer service.usageMutex.Unlock()
+
+	if service.usageRecordedAt[apiKeyID].Equal(usedAt) {
+		delete(service.usageRecordedAt, apiKeyID)
+	}
+}
+
+func (service *APIKeyService) generateKey() (string, error) {
+	b := make([]byte, apiKeyLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", stacktrace.Propagate(err, "cannot generate random bytes")
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)[:apiKeyLength], nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubAPIKeyRepository counts the number of times the usage of an entities.APIKey is written
+type stubAPIKeyRepository struct {
+	repositories.APIKeyRepository
+	updates int64
+	err     error
+}
+
+func (repository *stubAPIKeyRepository) RecordUsage(_ context.Context, _ uuid.UUID, _ time.Time, _ time.Time, _ string, _ string) error {
+	atomic.AddInt64(&repository.updates, 1)
+	return repository.err
+}
+
+func newTestAPIKeyService(repository repositories.APIKeyRepository) *APIKeyService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	return NewAPIKeyService(logger, telemetry.NewOtelLogger("test", logger), repository)
+}
+
+func recordUsageBurst(service *APIKeyService, apiKeyIDs []uuid.UUID, requests int) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < requests; i++ {
+		for _, apiKeyID := range apiKeyIDs {
+			wg.Add(1)
+			go func(apiKeyID uuid.UUID) {
+				defer wg.Done()
+				_ = service.RecordUsage(context.Background(), apiKeyID, "203.0.113.10", "curl/8.4.0")
+			}(apiKeyID)
+		}
+	}
+	wg.Wait()
+}
+
+func TestAPIKeyService_RecordUsage(t *testing.T) {
+	t.Run("burst of requests for the same api key is written once", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		repository := new(stubAPIKeyRepository)
+		service := newTestAPIKeyService(repository)
+
+		// Act
+		recordUsageBurst(service, []uuid.UUID{uuid.New()}, 100)
+
+		// Assert
+		assert.Equal(t, int64(1), atomic.LoadInt64(&repository.updates))
+	})
+
+	t.Run("burst of requests for different api keys is written once per api key", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		repository := new(stubAPIKeyRepository)
+		service := newTestAPIKeyService(repository)
+
+		// Act
+		recordUsageBurst(service, []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}, 50)
+
+		// Assert
+		assert.Equal(t, int64(3), atomic.LoadInt64(&repository.updates))
+	})
+
+	t.Run("failed write is retried by the next request", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		repository := &stubAPIKeyRepository{err: stacktrace.NewError("database is unavailable")}
+		service := newTestAPIKeyService(repository)
+		apiKeyID := uuid.New()
+
+		// Act
+		firstErr := service.RecordUsage(context.Background(), apiKeyID, "203.0.113.10", "curl/8.4.0")
+		secondErr := service.RecordUsage(context.Background(), apiKeyID, "203.0.113.10", "curl/8.4.0")
+
+		// Assert
+		assert.NotNil(t, firstErr)
+		assert.NotNil(t, secondErr)
+		assert.Equal(t, int64(2), atomic.LoadInt64(&repository.updates))
+	})
+}
@@ -128,6 +128,39 @@ func (service *BillingService) GetCurrentUsage(ctx context.Context, userID entit
 	return service.billingUsageRepository.GetCurrent(ctx, userID)
 }
 
+// GetUserUsage gets the usage of a user in the current and previous months
+func (service *BillingService) GetUserUsage(ctx context.Context, userID entities.UserID) (*entities.UserUsage, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	current, err := service.billingUsageRepository.GetCurrent(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load current usage for user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	previous, err := service.billingUsageRepository.Get(ctx, userID, current.StartTimestamp.AddDate(0, -1, 0))
+	if err != nil {
+		msg := fmt.Sprintf("cannot load previous usage for user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return &entities.UserUsage{Current: current, Previous: previous}, nil
+}
+
+// RebuildUsage recomputes the billing usage of all users from the messages history
+func (service *BillingService) RebuildUsage(ctx context.Context) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.billingUsageRepository.Rebuild(ctx); err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot rebuild billing usage"))
+	}
+
+	ctxLogger.Info("rebuilt the billing usage of all users from the messages history")
+	return nil
+}
+
 // GetUsageHistory gets the billing usage history for a user
 func (service *BillingService) GetUsageHistory(ctx context.Context, userID entities.UserID, params repositories.IndexParams) (*[]entities.BillingUsage, error) {
 	ctx, span := service.tracer.Start(ctx)
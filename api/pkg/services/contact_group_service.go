@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/palantir/stacktrace"
+)
+
+// ContactGroupService handles the groups of contacts of a user and sending messages to them
+type ContactGroupService struct {
+	service
+	logger         telemetry.Logger
+	tracer         telemetry.Tracer
+	repository     repositories.ContactGroupRepository
+	messageService *MessageService
+}
+
+// NewContactGroupService creates a new ContactGroupService
+func NewContactGroupService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.ContactGroupRepository,
+	messageService *MessageService,
+) (s *ContactGroupService) {
+	return &ContactGroupService{
+		logger:         logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:         tracer,
+		repository:     repository,
+		messageService: messageService,
+	}
+}
+
+// ContactGroupStoreParams are parameters for creating an entities.ContactGroup
+type ContactGroupStoreParams struct {
+	UserID entities.UserID
+	Name   string
+}
+
+// Store a new entities.ContactGroup
+func (service *ContactGroupService) Store(ctx context.Context, params *ContactGroupStoreParams) (*entities.ContactGroup, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	group := &entities.ContactGroup{
+		ID:        uuid.New(),
+		UserID:    params.UserID,
+		Name:      params.Name,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err := service.repository.Store(ctx, group); err != nil {
+		msg := fmt.Sprintf("cannot store contact group with name [%s] for user [%s]", params.Name, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("created contact group [%s] for user [%s]", group.ID, group.UserID))
+	return group, nil
+}
+
+// ContactGroupUpdateParams are parameters for updating an entities.ContactGroup
+type ContactGroupUpdateParams struct {
+	UserID         entities.UserID
+	ContactGroupID uuid.UUID
+	Name           string
+}
+
+// Update the name of an entities.ContactGroup
+func (service *ContactGroupService) Update(ctx context.Context, params *ContactGroupUpdateParams) (*entities.ContactGroup, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	group, err := service.Get(ctx, params.UserID, params.ContactGroupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s]", params.ContactGroupID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	group.Name = params.Name
+	group.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Update(ctx, group); err != nil {
+		msg := fmt.Sprintf("cannot update contact group with ID [%s]", params.ContactGroupID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return group, nil
+}
+
+// Get an entities.ContactGroup by ID
+func (service *ContactGroupService) Get(ctx context.Context, userID entities.UserID, groupID uuid.UUID) (*entities.ContactGroup, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	group, err := service.repository.Load(ctx, userID, groupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s] for user [%s]", groupID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return group, nil
+}
+
+// Index fetches the entities.ContactGroup of a user
+func (service *ContactGroupService) Index(ctx context.Context, userID entities.UserID, params repositories.IndexParams) ([]*entities.ContactGroup, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	groups, err := service.repository.Index(ctx, userID, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch contact groups for user [%s] with params [%+#v]", userID, params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return groups, nil
+}
+
+// Delete an entities.ContactGroup, the contacts in the group are not deleted
+func (service *ContactGroupService) Delete(ctx context.Context, userID entities.UserID, groupID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	group, err := service.Get(ctx, userID, groupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s]", groupID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Delete(ctx, group); err != nil {
+		msg := fmt.Sprintf("cannot delete contact group with ID [%s]", groupID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted contact group [%s] for user [%s]", groupID, userID))
+	return nil
+}
+
+// AddMembers adds the entities.Contact of a user to an entities.ContactGroup and returns the number of new members
+func (service *ContactGroupService) AddMembers(ctx context.Context, userID entities.UserID, groupID uuid.UUID, contactIDs []uuid.UUID) (int, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	group, err := service.Get(ctx, userID, groupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s]", groupID)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	added, err := service.repository.AddMembers(ctx, group, contactIDs)
+	if err != nil {
+		msg := fmt.Sprintf("cannot add [%d] contacts to contact group [%s]", len(contactIDs), groupID)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("added [%d] of [%d] contacts to contact group [%s] for user [%s]", added, len(contactIDs), groupID, userID))
+	return added, nil
+}
+
+// RemoveMember removes an entities.Contact from an entities.ContactGroup
+func (service *ContactGroupService) RemoveMember(ctx context.Context, userID entities.UserID, groupID uuid.UUID, contactID uuid.UUID) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	group, err := service.Get(ctx, userID, groupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s]", groupID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.RemoveMember(ctx, group, contactID); err != nil {
+		msg := fmt.Sprintf("cannot remove contact [%s] from contact group [%s]", contactID, groupID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return nil
+}
+
+// IndexMembers fetches a page of the entities.Contact in an entities.ContactGroup
+func (service *ContactGroupService) IndexMembers(ctx context.Context, userID entities.UserID, groupID uuid.UUID, params repositories.IndexParams) ([]*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	group, err := service.Get(ctx, userID, groupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s]", groupID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	contacts, err := service.repository.IndexMembers(ctx, group, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the members of contact group [%s] with params [%+#v]", groupID, params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contacts, nil
+}
+
+// Members fetches a snapshot of all the entities.Contact in an entities.ContactGroup with a single query, the members
+// which are added or removed after the snapshot do not change it.
+func (service *ContactGroupService) Members(ctx context.Context, userID entities.UserID, groupID uuid.UUID) (*entities.ContactGroup, []*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	group, err := service.Get(ctx, userID, groupID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact group with ID [%s]", groupID)
+		return nil, nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	contacts, err := service.repository.FetchMembers(ctx, group)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the members of contact group [%s]", groupID)
+		return nil, nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return group, contacts, nil
+}
+
+// ContactGroupSendParams are parameters for sending a message to the members of an entities.ContactGroup
+type ContactGroupSendParams struct {
+	UserID  entities.UserID
+	Owner   *phonenumbers.PhoneNumber
+	Content string
+	Source  string
+	// RequestID tags all the messages which are sent to the group, it is group-{ContactGroupID} when it is nil
+	RequestID *string
+	Group     *entities.ContactGroup
+	// Members is the snapshot of the members of the group from Members
+	Members []*entities.Contact
+	// DailyLimitReservedAt is the time returned by MessageService.ReserveDailyMessages for the members
+	DailyLimitReservedAt time.Time
+}
+
+// Send a message to each member of an entities.ContactGroup. The daily message limit must be reserved by the caller
+// for all the members, a member which the message cannot be sent to is in the errors of the result.
+func (service *ContactGroupService) Send(ctx context.Context, params *ContactGroupSendParams) *entities.ContactGroupSendResult {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	requestID := fmt.Sprintf("group-%s", params.Group.ID)
+	if params.RequestID != nil {
+		requestID = *params.RequestID
+	}
+
+	result := &entities.ContactGroupSendResult{
+		ContactGroupID: params.Group.ID,
+		RequestID:      requestID,
+		Total:          len(params.Members),
+		Messages:       make([]*entities.Message, 0, len(params.Members)),
+		Errors:         make([]entities.ContactGroupSendError, 0),
+	}
+
+	ownerPhones := NewMessageOwnerPhones()
+	receivedAt := time.Now().UTC()
+	for index, contact := range params.Members {
+		message, err := service.messageService.SendMessage(ctx, MessageSendParams{
+			Owner:                params.Owner,
+			Contact:              contact.PhoneNumber,
+			Content:              params.Content,
+			Source:               params.Source,
+			RequestID:            &requestID,
+			UserID:               params.UserID,
+			RequestReceivedAt:    receivedAt.Add(time.Duration(index) * entities.MessageOrderTimestampStep),
+			DailyLimitReserved:   true,
+			OwnerPhones:          ownerPhones,
+			DailyLimitReservedAt: params.DailyLimitReservedAt,
+		})
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send message to contact [%s] of contact group [%s]", contact.ID, params.Group.ID)))
+			result.Errors = append(result.Errors, entities.ContactGroupSendError{
+				ContactID:   contact.ID,
+				PhoneNumber: contact.PhoneNumber,
+				Error:       "cannot send the message to the contact, please try again later",
+			})
+			continue
+		}
+		result.Messages = append(result.Messages, message)
+	}
+
+	result.Sent = len(result.Messages)
+	result.Failed = len(result.Errors)
+
+	ctxLogger.Info(fmt.Sprintf("sent [%d] of [%d] messages to contact group [%s] for user [%s]", result.Sent, result.Total, params.Group.ID, params.UserID))
+	return result
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/palantir/stacktrace"
+)
+
+// ContactService handles the names of the phone numbers which a user communicates with
+type ContactService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.ContactRepository
+}
+
+// NewContactService creates a new ContactService
+func NewContactService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.ContactRepository,
+) (s *ContactService) {
+	return &ContactService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+	}
+}
+
+// ContactSyncEntry is an entry of the address book of a phone
+type ContactSyncEntry struct {
+	PhoneNumber string
+	Name        string
+	Deleted     bool
+	// UpdatedAt is the time when the entry was changed in the address book, it is the time of the sync when it is nil
+	UpdatedAt *time.Time
+}
+
+// ContactSyncParams are parameters for syncing a batch of the address book of a phone
+type ContactSyncParams struct {
+	UserID   entities.UserID
+	Owner    string
+	Contacts []ContactSyncEntry
+}
+
+// Sync applies a batch of the address book of a phone to the entities.Contact of a user. An entry is skipped when its
+// phone number is not valid, when it is older than the contact or when the contact is entities.ContactSourceManual.
+func (service *ContactService) Sync(ctx context.Context, params *ContactSyncParams) (*entities.ContactSyncResult, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	result := &entities.ContactSyncResult{}
+	for _, entry := range params.Contacts {
+		if _, err := phonenumbers.Parse(entry.PhoneNumber, phonenumbers.UNKNOWN_REGION); err != nil {
+			result.Skipped++
+			continue
+		}
+
+		synced, err := service.repository.Sync(ctx, service.syncedContact(params.UserID, entry))
+		if err != nil {
+			msg := fmt.Sprintf("cannot sync contact [%s] from phone [%s]", entry.PhoneNumber, params.Owner)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		if !synced {
+			result.Skipped++
+			continue
+		}
+		result.Synced++
+	}
+
+	ctxLogger.Info(fmt.Sprintf("synced [%d] and skipped [%d] contacts from phone [%s] of user [%s]", result.Synced, result.Skipped, params.Owner, params.UserID))
+	return result, nil
+}
+
+func (service *ContactService) syncedContact(userID entities.UserID, entry ContactSyncEntry) *entities.Contact {
+	timestamp := time.Now().UTC()
+
+	syncedAt := timestamp
+	if entry.UpdatedAt != nil {
+		syncedAt = entry.UpdatedAt.UTC()
+	}
+
+	contact := &entities.Contact{
+		ID:          uuid.New(),
+		UserID:      userID,
+		PhoneNumber: entry.PhoneNumber,
+		Name:        entry.Name,
+		Source:      entities.ContactSourceSynced,
+		SyncedAt:    &syncedAt,
+		CreatedAt:   timestamp,
+		UpdatedAt:   timestamp,
+	}
+
+	if entry.Deleted {
+		contact.Name = ""
+		contact.DeletedAt = &syncedAt
+	}
+
+	return contact
+}
+
+// ContactUpsertParams are parameters for setting the name of a phone number
+type ContactUpsertParams struct {
+	UserID      entities.UserID
+	PhoneNumber string
+	Name        string
+	DNDStart    string
+	DNDEnd      string
+	DNDTimezone string
+}
+
+// Upsert sets the name of a phone number with the API, the contact is not changed by the address book of the phone afterwards
+func (service *ContactService) Upsert(ctx context.Context, params *ContactUpsertParams) (*entities.Contact, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	contact, err := service.repository.Upsert(ctx, &entities.Contact{
+		ID:          uuid.New(),
+		UserID:      params.UserID,
+		PhoneNumber: params.PhoneNumber,
+		Name:        params.Name,
+		Source:      entities.ContactSourceManual,
+		DNDStart:    params.DNDStart,
+		DNDEnd:      params.DNDEnd,
+		DNDTimezone: params.DNDTimezone,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot set the contact of phone number [%s] for user [%s]", params.PhoneNumber, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("set contact [%s] of phone number [%s] for user [%s]", contact.ID, contact.PhoneNumber, contact.UserID))
+	return contact, nil
+}
+
+// ContactUpdateParams are parameters for updating an entities.Contact
+type ContactUpdateParams struct {
+	UserID      entities.UserID
+	ContactID   uuid.UUID
+	Name        string
+	DNDStart    string
+	DNDEnd      string
+	DNDTimezone string
+}
+
+// Update the name of an entities.Contact, the contact is not changed by the address book of the phone afterwards
+func (service *ContactService) Update(ctx context.Context, params *ContactUpdateParams) (*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	contact, err := service.Get(ctx, params.UserID, params.ContactID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s]", params.ContactID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	contact.Name = params.Name
+	contact.DNDStart = params.DNDStart
+	contact.DNDEnd = params.DNDEnd
+	contact.DNDTimezone = params.DNDTimezone
+	contact.Source = entities.ContactSourceManual
+	contact.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Update(ctx, contact); err != nil {
+		msg := fmt.Sprintf("cannot update contact with ID [%s]", params.ContactID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contact, nil
+}
+
+// ContactNotesParams are parameters for updating the notes of an entities.Contact
+type ContactNotesParams struct {
+	UserID    entities.UserID
+	ContactID uuid.UUID
+	Notes     string
+	UpdatedBy entities.UserID
+}
+
+// UpdateNotes sets the notes of an entities.Contact and the user who changed them
+func (service *ContactService) UpdateNotes(ctx context.Context, params *ContactNotesParams) (*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	contact, err := service.Get(ctx, params.UserID, params.ContactID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s]", params.ContactID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	contact.SetNotes(params.Notes, params.UpdatedBy, time.Now().UTC())
+	contact.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Update(ctx, contact); err != nil {
+		msg := fmt.Sprintf("cannot update the notes of contact with ID [%s]", params.ContactID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contact, nil
+}
+
+// ContactLabelsParams are parameters for replacing the labels of an entities.Contact
+type ContactLabelsParams struct {
+	UserID    entities.UserID
+	ContactID uuid.UUID
+	Labels    []string
+}
+
+// UpdateLabels replaces the labels of an entities.Contact, the labels are normalized with entities.NormalizeContactLabels
+func (service *ContactService) UpdateLabels(ctx context.Context, params *ContactLabelsParams) (*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	contact, err := service.Get(ctx, params.UserID, params.ContactID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s]", params.ContactID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	contact.Labels = entities.NormalizeContactLabels(params.Labels)
+	if err = service.repository.SetLabels(ctx, contact); err != nil {
+		msg := fmt.Sprintf("cannot set the labels of contact with ID [%s]", params.ContactID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contact, nil
+}
+
+// ContactLabelRenameParams are parameters for renaming a label on all the contacts of a user
+type ContactLabelRenameParams struct {
+	UserID entities.UserID
+	Label  string
+	Name   string
+}
+
+// RenameLabel renames a label on all the entities.Contact of a user which have it
+func (service *ContactService) RenameLabel(ctx context.Context, params *ContactLabelRenameParams) (*entities.ContactLabelRenameResult, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	count, err := service.repository.RenameLabel(ctx, params.UserID, params.Label, params.Name)
+	if err != nil {
+		msg := fmt.Sprintf("cannot rename label [%s] to [%s] for user [%s]", params.Label, params.Name, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("renamed label [%s] to [%s] on [%d] contacts of user [%s]", params.Label, params.Name, count, params.UserID))
+	return &entities.ContactLabelRenameResult{Contacts: count}, nil
+}
+
+// Get an entities.Contact by ID
+func (service *ContactService) Get(ctx context.Context, userID entities.UserID, contactID uuid.UUID) (*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	contact, err := service.repository.Load(ctx, userID, contactID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s] for user [%s]", contactID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return contact, nil
+}
+
+// Index fetches the entities.Contact of a user
+func (service *ContactService) Index(ctx context.Context, userID entities.UserID, params repositories.IndexParams) ([]*entities.Contact, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	contacts, err := service.repository.Index(ctx, userID, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch contacts for user [%s] with params [%+#v]", userID, params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return contacts, nil
+}
+
+// Delete an entities.Contact, the phone number gets the name in the address book of the phone again on the next sync
+func (service *ContactService) Delete(ctx context.Context, userID entities.UserID, contactID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	contact, err := service.Get(ctx, userID, contactID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load contact with ID [%s]", contactID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Delete(ctx, contact); err != nil {
+		msg := fmt.Sprintf("cannot delete contact with ID [%s]", contactID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted contact [%s] for user [%s]", contactID, userID))
+	return nil
+}
+
+// Names returns the names of the phone numbers which have an entities.Contact. The names are only displayed so an
+// error is logged and no names are returned when they cannot be fetched.
+func (service *ContactService) Names(ctx context.Context, userID entities.UserID, phoneNumbers ...string) map[string]string {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	unique := service.unique(phoneNumbers)
+	names, err := service.repository.FetchNames(ctx, userID, unique)
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the names of [%d] phone numbers for user [%s]", len(unique), userID)))
+		return map[string]string{}
+	}
+
+	return names
+}
+
+// Details returns the entities.Contact of the phone numbers with their notes and labels. The contacts are only displayed
+// so an error is logged and no contacts are returned when they cannot be fetched.
+func (service *ContactService) Details(ctx context.Context, userID entities.UserID, phoneNumbers ...string) map[string]*entities.Contact {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	contacts, err := service.repository.FetchByPhoneNumbers(ctx, userID, service.unique(phoneNumbers))
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the contacts of [%d] phone numbers for user [%s]", len(phoneNumbers), userID)))
+		return map[string]*entities.Contact{}
+	}
+
+	return contacts
+}
+
+func (service *ContactService) unique(phoneNumbers []string) []string {
+	unique := make([]string, 0, len(phoneNumbers))
+	seen := map[string]bool{}
+	for _, phoneNumber := range phoneNumbers {
+		if !seen[phoneNumber] {
+			seen[phoneNumber] = true
+			unique = append(unique, phoneNumber)
+		}
+	}
+	return unique
+}
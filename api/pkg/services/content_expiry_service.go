@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// contentExpiryBatchSize is the number of entities.Message whose content is expired in one transaction
+const contentExpiryBatchSize = 500
+
+// ContentExpiryService handles the rules which remove the content of received messages e.g. verification codes after a TTL
+type ContentExpiryService struct {
+	service
+	logger            telemetry.Logger
+	tracer            telemetry.Tracer
+	repository        repositories.ContentExpiryRuleRepository
+	messageRepository repositories.MessageRepository
+	threadRepository  repositories.MessageThreadRepository
+	transactor        repositories.Transactor
+	phoneService      *PhoneService
+}
+
+// NewContentExpiryService creates a new ContentExpiryService
+func NewContentExpiryService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.ContentExpiryRuleRepository,
+	messageRepository repositories.MessageRepository,
+	threadRepository repositories.MessageThreadRepository,
+	transactor repositories.Transactor,
+	phoneService *PhoneService,
+) (s *ContentExpiryService) {
+	return &ContentExpiryService{
+		logger:            logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:            tracer,
+		repository:        repository,
+		messageRepository: messageRepository,
+		threadRepository:  threadRepository,
+		transactor:        transactor,
+		phoneService:      phoneService,
+	}
+}
+
+// ContentExpiryRuleStoreParams are parameters for creating an entities.ContentExpiryRule
+type ContentExpiryRuleStoreParams struct {
+	UserID     entities.UserID
+	Owner      string
+	Pattern    string
+	Contacts   []string
+	TTLSeconds uint
+}
+
+// Store a new entities.ContentExpiryRule
+func (service *ContentExpiryService) Store(ctx context.Context, params *ContentExpiryRuleStoreParams) (*entities.ContentExpiryRule, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.validate(ctx, params.UserID, params.Owner); err != nil {
+		msg := fmt.Sprintf("cannot store content expiry rule with owner [%s] for user [%s]", params.Owner, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rule := &entities.ContentExpiryRule{
+		ID:         uuid.New(),
+		UserID:     params.UserID,
+		Owner:      params.Owner,
+		Pattern:    params.Pattern,
+		Contacts:   params.Contacts,
+		TTLSeconds: params.TTLSeconds,
+		CreatedAt:  time.Now().UTC(),
+		UpdatedAt:  time.Now().UTC(),
+	}
+
+	if err := service.repository.Store(ctx, rule); err != nil {
+		msg := fmt.Sprintf("cannot store content expiry rule with owner [%s] for user [%s]", params.Owner, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("created content expiry rule [%s] with owner [%s] for user [%s]", rule.ID, rule.Owner, rule.UserID))
+	return rule, nil
+}
+
+// ContentExpiryRuleUpdateParams are parameters for updating an entities.ContentExpiryRule
+type ContentExpiryRuleUpdateParams struct {
+	UserID              entities.UserID
+	ContentExpiryRuleID uuid.UUID
+	Owner               string
+	Pattern             string
+	Contacts            []string
+	TTLSeconds          uint
+}
+
+// Update the owner, the matching and the TTL of an entities.ContentExpiryRule, the messages which were received
+// before the update keep their expiry time
+func (service *ContentExpiryService) Update(ctx context.Context, params *ContentExpiryRuleUpdateParams) (*entities.ContentExpiryRule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rule, err := service.Get(ctx, params.UserID, params.ContentExpiryRuleID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load content expiry rule with ID [%s]", params.ContentExpiryRuleID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.validate(ctx, params.UserID, params.Owner); err != nil {
+		msg := fmt.Sprintf("cannot update content expiry rule with ID [%s] to owner [%s]", params.ContentExpiryRuleID, params.Owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rule.Owner = params.Owner
+	rule.Pattern = params.Pattern
+	rule.Contacts = params.Contacts
+	rule.TTLSeconds = params.TTLSeconds
+	rule.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Update(ctx, rule); err != nil {
+		msg := fmt.Sprintf("cannot update content expiry rule with ID [%s]", params.ContentExpiryRuleID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rule, nil
+}
+
+// Get an entities.ContentExpiryRule by ID
+func (service *ContentExpiryService) Get(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) (*entities.ContentExpiryRule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rule, err := service.repository.Load(ctx, userID, ruleID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load content expiry rule with ID [%s] for user [%s]", ruleID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return rule, nil
+}
+
+// Index fetches all the entities.ContentExpiryRule of a user
+func (service *ContentExpiryService) Index(ctx context.Context, userID entities.UserID) ([]*entities.ContentExpiryRule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rules, err := service.repository.Fetch(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch content expiry rules for user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rules, nil
+}
+
+// Delete an entities.ContentExpiryRule, the content of the messages which it matched still expires
+func (service *ContentExpiryService) Delete(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	rule, err := service.Get(ctx, userID, ruleID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load content expiry rule with ID [%s]", ruleID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Delete(ctx, rule); err != nil {
+		msg := fmt.Sprintf("cannot delete content expiry rule with ID [%s]", ruleID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted content expiry rule [%s] for user [%s]", ruleID, userID))
+	return nil
+}
+
+// Match finds the entities.ContentExpiryRule of a received entities.Message and the time when its content expires
+func (service *ContentExpiryService) Match(ctx context.Context, message *entities.Message) (*entities.ContentExpiryMatch, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rules, err := service.repository.FetchByOwner(ctx, message.UserID, message.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch content expiry rules for user [%s] and owner [%s]", message.UserID, message.Owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	match := &entities.ContentExpiryMatch{Rule: entities.MatchContentExpiryRule(rules, message)}
+	if match.Rule != nil {
+		expiresAt := message.OrderTimestamp.Add(match.Rule.TTL())
+		match.ContentExpiresAt = &expiresAt
+	}
+
+	return match, nil
+}
+
+// ContentExpiryDryRunParams is a received message which is matched with the entities.ContentExpiryRule of a user without storing it
+type ContentExpiryDryRunParams struct {
+	UserID  entities.UserID
+	Owner   string
+	Contact string
+	Content string
+}
+
+// DryRun matches a message with the entities.ContentExpiryRule of a user as if it was received now
+func (service *ContentExpiryService) DryRun(ctx context.Context, params *ContentExpiryDryRunParams) (*entities.ContentExpiryMatch, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	match, err := service.Match(ctx, &entities.Message{
+		UserID:         params.UserID,
+		Owner:          params.Owner,
+		Contact:        params.Contact,
+		Content:        params.Content,
+		Type:           entities.MessageTypeMobileOriginated,
+		Status:         entities.MessageStatusReceived,
+		OrderTimestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot match the message from [%s] to [%s] with the content expiry rules", params.Contact, params.Owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return match, nil
+}
+
+// Apply replaces the content of the messages whose content has expired in batches and returns the number of messages.
+// The content of a message is only replaced once so Apply can run again after it fails.
+func (service *ContentExpiryService) Apply(ctx context.Context) (int, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	var total int
+	for {
+		var ids []uuid.UUID
+		err := service.transactor.Execute(ctx, func(ctx context.Context) (err error) {
+			ids, err = service.messageRepository.ExpireContent(ctx, time.Now().UTC(), contentExpiryBatchSize)
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			return service.threadRepository.ExpireLastMessageContent(ctx, ids)
+		})
+		if err != nil {
+			msg := fmt.Sprintf("cannot expire the content of messages after expiring [%d] messages", total)
+			return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		total += len(ids)
+		if len(ids) < contentExpiryBatchSize {
+			break
+		}
+	}
+
+	ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldCount: total}).Info(fmt.Sprintf("expired the content of [%d] messages", total))
+	return total, nil
+}
+
+// validate makes sure the owner of a rule has a registered phone
+func (service *ContentExpiryService) validate(ctx context.Context, userID entities.UserID, owner string) error {
+	_, err := service.phoneService.LoadOwner(ctx, userID, owner)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return NewValidationError("owner", fmt.Sprintf("The owner [%s] does not have a registered phone, install the android app on the phone first", owner))
+	}
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot load phone with owner [%s] for user [%s]", owner, userID))
+	}
+	return nil
+}
@@ -289,7 +289,7 @@ func (service *DiscordService) createDiscordMessage(ctxLogger telemetry.Logger,
 					},
 					{
 						"name":  "Content:",
-						"value": payload.Content,
+						"value": displayContent(payload.Content, payload.IsEncrypted),
 					},
 					{
 						"name":  "MessageID:",
@@ -305,7 +305,7 @@ func (service *DiscordService) handleDiscordMessageFailed(ctx context.Context, s
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
 	defer span.End()
 
-	event, err := service.createEvent(events.EventTypeDiscordSendFailed, source, payload)
+	event, err := service.createEvent(ctx, events.EventTypeDiscordSendFailed, source, payload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot create event [%s] for user with id [%s]", events.EventTypeDiscordSendFailed, payload.UserID)
 		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
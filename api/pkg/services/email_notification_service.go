@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/palantir/stacktrace"
 
 	"github.com/NdoleStudio/httpsms/pkg/cache"
 	"github.com/NdoleStudio/httpsms/pkg/emails"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/events"
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
@@ -17,17 +19,19 @@ import (
 // EmailNotificationService is responsible for handling email notifications about messages
 type EmailNotificationService struct {
 	service
-	logger         telemetry.Logger
-	tracer         telemetry.Tracer
-	userRepository repositories.UserRepository
-	factory        emails.NotificationEmailFactory
-	mailer         emails.Mailer
-	cache          cache.Cache
+	logger               telemetry.Logger
+	tracer               telemetry.Tracer
+	userRepository       repositories.UserRepository
+	preferenceRepository repositories.NotificationPreferenceRepository
+	digestRepository     repositories.NotificationDigestRepository
+	dispatcher           *EventDispatcher
+	factory              emails.NotificationEmailFactory
+	mailer               emails.Mailer
+	cache                cache.Cache
 }
 
 const (
-	fifteenMinuteTimeout = 15 * time.Minute
-	oneHourTimeout       = 1 * time.Hour
+	oneHourTimeout = 1 * time.Hour
 )
 
 // NewEmailNotificationService creates a new EmailNotificationService
@@ -35,98 +39,297 @@ func NewEmailNotificationService(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	userRepository repositories.UserRepository,
+	preferenceRepository repositories.NotificationPreferenceRepository,
+	digestRepository repositories.NotificationDigestRepository,
+	dispatcher *EventDispatcher,
 	factory emails.NotificationEmailFactory,
 	mailer emails.Mailer,
 	cache cache.Cache,
 ) *EmailNotificationService {
 	return &EmailNotificationService{
-		logger:         logger.WithService(fmt.Sprintf("%T", &EmailNotificationService{})),
-		tracer:         tracer,
-		userRepository: userRepository,
-		factory:        factory,
-		mailer:         mailer,
-		cache:          cache,
+		logger:               logger.WithService(fmt.Sprintf("%T", &EmailNotificationService{})),
+		tracer:               tracer,
+		userRepository:       userRepository,
+		preferenceRepository: preferenceRepository,
+		digestRepository:     digestRepository,
+		dispatcher:           dispatcher,
+		factory:              factory,
+		mailer:               mailer,
+		cache:                cache,
 	}
 }
 
-// NotifyMessageExpired sends an email to the user about an expired message
-func (service *EmailNotificationService) NotifyMessageExpired(ctx context.Context, payload *events.MessageSendExpiredPayload) error {
-	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+// notificationDigestWindow is the duration in which the messages of a phone which fail or expire are collected into one email
+const notificationDigestWindow = 10 * time.Minute
+
+// notificationDigestStaleAfter is the duration after which a window which was not sent is opened again e.g. when its
+// events.EventTypeNotificationDigestDue event could not be dispatched
+const notificationDigestStaleAfter = 1 * time.Hour
+
+// LoadPreference loads the entities.NotificationPreference of a user, it is the default preference when the user has not changed it
+func (service *EmailNotificationService) LoadPreference(ctx context.Context, userID entities.UserID) (*entities.NotificationPreference, error) {
+	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
-	if !payload.IsFinal {
-		ctxLogger.Info(fmt.Sprintf("[%s] event is not final, send attempt count = [%d]", events.EventTypeMessageSendExpired, payload.SendAttemptCount))
-		return nil
+	user, err := service.userRepository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	if !service.canSendEmail(ctx, events.EventTypeMessageSendExpired, payload.Owner) {
-		ctxLogger.Info(fmt.Sprintf("[%s] email already sent to user [%s] with owner [%s]", events.EventTypeMessageSendExpired, payload.UserID, payload.Owner))
-		return nil
+	preference, err := service.loadPreference(ctx, user)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load notification preference of user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	user, err := service.userRepository.Load(ctx, payload.UserID)
+	return preference, nil
+}
+
+func (service *EmailNotificationService) loadPreference(ctx context.Context, user *entities.User) (*entities.NotificationPreference, error) {
+	preference, err := service.preferenceRepository.Load(ctx, user.ID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return entities.DefaultNotificationPreference(user), nil
+	}
 	if err != nil {
-		msg := fmt.Sprintf("cannot load user with ID [%s] and for expired message with ID [%s]", payload.UserID, payload.MessageID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot load notification preference of user [%s]", user.ID))
+	}
+	return preference, nil
+}
+
+// NotificationPreferenceUpsertParams are parameters for setting the entities.NotificationPreference of a user
+type NotificationPreferenceUpsertParams struct {
+	UserID          entities.UserID
+	Enabled         bool
+	Threshold       uint
+	QuietHoursStart string
+	QuietHoursEnd   string
+}
+
+// UpsertPreference sets the entities.NotificationPreference of a user
+func (service *EmailNotificationService) UpsertPreference(ctx context.Context, params *NotificationPreferenceUpsertParams) (*entities.NotificationPreference, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	preference, err := service.preferenceRepository.Load(ctx, params.UserID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		preference, err = &entities.NotificationPreference{UserID: params.UserID, CreatedAt: time.Now().UTC()}, nil
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot load notification preference of user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	preference.Enabled = params.Enabled
+	preference.Threshold = params.Threshold
+	preference.QuietHoursStart = params.QuietHoursStart
+	preference.QuietHoursEnd = params.QuietHoursEnd
+	preference.UpdatedAt = time.Now().UTC()
+
+	if err = service.preferenceRepository.Save(ctx, preference); err != nil {
+		msg := fmt.Sprintf("cannot save notification preference of user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if !user.NotificationMessageStatusEnabled {
-		ctxLogger.Info(fmt.Sprintf("[%s] email notifications disabled for user [%s] with owner [%s]", events.EventTypeMessageSendExpired, payload.UserID, payload.Owner))
+	ctxLogger.Info(fmt.Sprintf("saved notification preference of user [%s] with threshold [%d]", params.UserID, params.Threshold))
+	return preference, nil
+}
+
+// DeletePreference deletes the entities.NotificationPreference of a user so that the default preference is used
+func (service *EmailNotificationService) DeletePreference(ctx context.Context, userID entities.UserID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.preferenceRepository.Delete(ctx, userID); err != nil {
+		msg := fmt.Sprintf("cannot delete notification preference of user [%s]", userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted notification preference of user [%s]", userID))
+	return nil
+}
+
+// NotifyMessageExpired adds a message which expired after the last send attempt to the digest of its phone
+func (service *EmailNotificationService) NotifyMessageExpired(ctx context.Context, source string, payload *events.MessageSendExpiredPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if !payload.IsFinal {
+		ctxLogger.Info(fmt.Sprintf("[%s] event is not final, send attempt count = [%d]", events.EventTypeMessageSendExpired, payload.SendAttemptCount))
 		return nil
 	}
 
-	email, err := service.factory.MessageExpired(user, payload.MessageID, payload.Owner, payload.Contact, payload.Content)
+	err := service.collect(ctx, source, &entities.NotificationDigestMessage{
+		ID:        uuid.New(),
+		UserID:    payload.UserID,
+		Owner:     payload.Owner,
+		MessageID: payload.MessageID,
+		Contact:   payload.Contact,
+		Status:    entities.MessageStatusExpired,
+		CreatedAt: time.Now().UTC(),
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot create email for user with ID [%s] and for expired message with ID [%s]", payload.UserID, payload.MessageID)
+		msg := fmt.Sprintf("cannot add expired message with ID [%s] to the digest of owner [%s]", payload.MessageID, payload.Owner)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if err = service.mailer.Send(ctx, email); err != nil {
-		msg := fmt.Sprintf("cannot send email for user with ID [%s] and for expired message with ID [%s]", payload.UserID, payload.MessageID)
+	return nil
+}
+
+// NotifyMessageFailed adds a failed message to the digest of its phone
+func (service *EmailNotificationService) NotifyMessageFailed(ctx context.Context, source string, payload *events.MessageSendFailedPayload) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	err := service.collect(ctx, source, &entities.NotificationDigestMessage{
+		ID:           uuid.New(),
+		UserID:       payload.UserID,
+		Owner:        payload.Owner,
+		MessageID:    payload.ID,
+		Contact:      payload.Contact,
+		Status:       entities.MessageStatusFailed,
+		ErrorMessage: &payload.ErrorMessage,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot add failed message with ID [%s] to the digest of owner [%s]", payload.ID, payload.Owner)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("[%s] email sent to [%s] for message with ID [%s]", events.EventTypeMessageSendExpired, user.ID, payload.MessageID))
-
-	service.addToCache(ctx, oneHourTimeout, events.EventTypeMessageSendExpired, payload.Owner)
 	return nil
 }
 
-// NotifyMessageFailed sends an email to the user about a failed message
-func (service *EmailNotificationService) NotifyMessageFailed(ctx context.Context, payload *events.MessageSendFailedPayload) error {
+// collect stores a message in the digest of its phone and opens the window of the digest when it is not open
+func (service *EmailNotificationService) collect(ctx context.Context, source string, message *entities.NotificationDigestMessage) error {
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
 	defer span.End()
 
-	if !service.canSendEmail(ctx, events.EventTypeMessageSendFailed, payload.Owner) {
-		ctxLogger.Info(fmt.Sprintf("[%s] email already sent to user [%s] with owner [%s]", events.EventTypeMessageSendFailed, payload.UserID, payload.Owner))
+	preference, err := service.LoadPreference(ctx, message.UserID)
+	if err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), fmt.Sprintf("cannot load notification preference of user [%s]", message.UserID)))
+	}
+
+	if !preference.Enabled {
+		ctxLogger.Info(fmt.Sprintf("[%s] email notifications disabled for user [%s] with owner [%s]", message.Status, message.UserID, message.Owner))
 		return nil
 	}
 
+	if err = service.digestRepository.StoreMessage(ctx, message); err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot store message [%s] in the notification digest", message.MessageID)))
+	}
+
+	payload := &events.NotificationDigestDuePayload{
+		UserID:   message.UserID,
+		Owner:    message.Owner,
+		WindowID: uuid.New(),
+		OpenedAt: time.Now().UTC(),
+		SendAt:   time.Now().UTC().Add(notificationDigestWindow),
+	}
+
+	opened, err := service.digestRepository.Open(ctx, payload.UserID, payload.Owner, payload.WindowID, payload.SendAt, payload.OpenedAt.Add(-notificationDigestStaleAfter))
+	if err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot open the notification digest of owner [%s]", message.Owner)))
+	}
+
+	if !opened {
+		ctxLogger.Info(fmt.Sprintf("added [%s] message [%s] to the open notification digest of owner [%s]", message.Status, message.MessageID, message.Owner))
+		return nil
+	}
+
+	if err = service.dispatchDigestDue(ctx, source, payload); err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot schedule the notification digest window [%s]", payload.WindowID)))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("opened notification digest window [%s] of owner [%s] for [%s] message [%s]", payload.WindowID, message.Owner, message.Status, message.MessageID))
+	return nil
+}
+
+func (service *EmailNotificationService) dispatchDigestDue(ctx context.Context, source string, payload *events.NotificationDigestDuePayload) error {
+	event, err := service.createEvent(ctx, events.EventTypeNotificationDigestDue, source, payload)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot create [%s] event for window [%s]", events.EventTypeNotificationDigestDue, payload.WindowID))
+	}
+
+	delay := time.Until(payload.SendAt)
+	if delay <= 0 {
+		delay = time.Nanosecond
+	}
+
+	if _, err = service.dispatcher.DispatchWithTimeout(ctx, event, delay); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot dispatch [%s] event for window [%s]", event.Type(), payload.WindowID))
+	}
+	return nil
+}
+
+// NotifyDigestDue sends the email with the messages of a phone which failed or expired in the window of the digest.
+// The digest is postponed to the end of the quiet hours of the user, and the window is closed before the email is sent
+// so that a digest is sent at most once when the event is delivered more than once.
+func (service *EmailNotificationService) NotifyDigestDue(ctx context.Context, source string, payload *events.NotificationDigestDuePayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
 	user, err := service.userRepository.Load(ctx, payload.UserID)
 	if err != nil {
-		msg := fmt.Sprintf("cannot load user with ID [%s] for [%s] message with ID [%s]", payload.UserID, payload.ID)
+		msg := fmt.Sprintf("cannot load user with ID [%s] for notification digest window [%s]", payload.UserID, payload.WindowID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	if !user.NotificationMessageStatusEnabled {
-		ctxLogger.Info(fmt.Sprintf("[%s] email notifications disabled for user [%s] with owner [%s]", events.EventTypeMessageSendFailed, payload.UserID, payload.Owner))
+	preference, err := service.loadPreference(ctx, user)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load notification preference for notification digest window [%s]", payload.WindowID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if until, isQuiet := preference.QuietUntil(user.Timezone, time.Now().UTC()); preference.Enabled && isQuiet {
+		return service.postponeDigest(ctx, source, payload, until)
+	}
+
+	messages, err := service.digestRepository.Close(ctx, payload.UserID, payload.Owner, payload.WindowID, uuid.New())
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Info(fmt.Sprintf("notification digest window [%s] of owner [%s] is already closed", payload.WindowID, payload.Owner))
+		return nil
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot close notification digest window [%s] of owner [%s]", payload.WindowID, payload.Owner)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !preference.Enabled || len(messages) == 0 || uint(len(messages)) < preference.Threshold {
+		ctxLogger.Info(fmt.Sprintf("notification digest window [%s] of owner [%s] with [%d] messages is not sent with threshold [%d] and enabled [%t]", payload.WindowID, payload.Owner, len(messages), preference.Threshold, preference.Enabled))
 		return nil
 	}
 
-	email, err := service.factory.MessageFailed(user, payload.ID, payload.Owner, payload.Contact, payload.Content, payload.ErrorMessage)
+	email, err := service.factory.MessageDigest(user, payload.Owner, payload.OpenedAt, messages)
 	if err != nil {
-		msg := fmt.Sprintf("cannot create email for user with ID [%s] for [%s] message with ID [%s]", payload.UserID, payload.ID)
+		msg := fmt.Sprintf("cannot create digest email for user with ID [%s] and window [%s]", payload.UserID, payload.WindowID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
 	if err = service.mailer.Send(ctx, email); err != nil {
-		msg := fmt.Sprintf("cannot send email for user with ID [%s] for [%s] message with ID [%s]", payload.UserID, events.EventTypeMessageSendFailed, payload.ID)
+		msg := fmt.Sprintf("cannot send digest email for user with ID [%s] and window [%s]", payload.UserID, payload.WindowID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("[%s] email sent to [%s] for message with ID [%s]", events.EventTypeMessageSendFailed, user.ID, payload.ID))
+	ctxLogger.Info(fmt.Sprintf("[%s] email sent to [%s] with [%d] messages of owner [%s]", events.EventTypeNotificationDigestDue, user.ID, len(messages), payload.Owner))
+	return nil
+}
+
+func (service *EmailNotificationService) postponeDigest(ctx context.Context, source string, payload *events.NotificationDigestDuePayload, until time.Time) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.digestRepository.Postpone(ctx, payload.UserID, payload.Owner, payload.WindowID, until); err != nil {
+		msg := fmt.Sprintf("cannot postpone notification digest window [%s] to [%s]", payload.WindowID, until)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	payload.SendAt = until
+	if err := service.dispatchDigestDue(ctx, source, payload); err != nil {
+		msg := fmt.Sprintf("cannot schedule the postponed notification digest window [%s]", payload.WindowID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
 
-	service.addToCache(ctx, fifteenMinuteTimeout, events.EventTypeMessageSendFailed, payload.Owner)
+	ctxLogger.Info(fmt.Sprintf("postponed notification digest window [%s] of owner [%s] to the end of the quiet hours at [%s]", payload.WindowID, payload.Owner, until))
 	return nil
 }
 
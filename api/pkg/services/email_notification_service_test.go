@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/emails"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// stubNotificationUserRepository loads a single user
+type stubNotificationUserRepository struct {
+	repositories.UserRepository
+	user *entities.User
+}
+
+func (repository *stubNotificationUserRepository) Load(_ context.Context, _ entities.UserID) (*entities.User, error) {
+	return repository.user, nil
+}
+
+// stubNotificationPreferenceRepository stores the preferences in memory
+type stubNotificationPreferenceRepository struct {
+	mutex       sync.Mutex
+	preferences map[entities.UserID]*entities.NotificationPreference
+}
+
+func (repository *stubNotificationPreferenceRepository) Save(_ context.Context, preference *entities.NotificationPreference) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.preferences[preference.UserID] = preference
+	return nil
+}
+
+func (repository *stubNotificationPreferenceRepository) Load(_ context.Context, userID entities.UserID) (*entities.NotificationPreference, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if preference, ok := repository.preferences[userID]; ok {
+		return preference, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "preference does not exist")
+}
+
+func (repository *stubNotificationPreferenceRepository) Delete(_ context.Context, userID entities.UserID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	delete(repository.preferences, userID)
+	return nil
+}
+
+// stubNotificationDigestRepository stores the digests and their messages in memory
+type stubNotificationDigestRepository struct {
+	mutex    sync.Mutex
+	digests  map[string]*entities.NotificationDigest
+	messages []*entities.NotificationDigestMessage
+}
+
+func newStubNotificationDigestRepository() *stubNotificationDigestRepository {
+	return &stubNotificationDigestRepository{digests: map[string]*entities.NotificationDigest{}}
+}
+
+func (repository *stubNotificationDigestRepository) StoreMessage(_ context.Context, message *entities.NotificationDigestMessage) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for _, stored := range repository.messages {
+		if stored.MessageID == message.MessageID {
+			return nil
+		}
+	}
+	repository.messages = append(repository.messages, message)
+	return nil
+}
+
+func (repository *stubNotificationDigestRepository) Open(_ context.Context, userID entities.UserID, owner string, windowID uuid.UUID, sendAt time.Time, staleBefore time.Time) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	digest, ok := repository.digests[string(userID)+owner]
+	if ok && digest.WindowID != nil && !digest.SendAt.Before(staleBefore) {
+		return false, nil
+	}
+	openedAt := time.Now().UTC()
+	repository.digests[string(userID)+owner] = &entities.NotificationDigest{UserID: userID, Owner: owner, WindowID: &windowID, OpenedAt: &openedAt, SendAt: &sendAt}
+	return true, nil
+}
+
+func (repository *stubNotificationDigestRepository) Postpone(_ context.Context, userID entities.UserID, owner string, windowID uuid.UUID, sendAt time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if digest, ok := repository.digests[string(userID)+owner]; ok && digest.WindowID != nil && *digest.WindowID == windowID {
+		digest.SendAt = &sendAt
+	}
+	return nil
+}
+
+func (repository *stubNotificationDigestRepository) Close(_ context.Context, userID entities.UserID, owner string, windowID uuid.UUID, digestID uuid.UUID) ([]*entities.NotificationDigestMessage, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	digest, ok := repository.digests[string(userID)+owner]
+	if !ok || digest.WindowID == nil || *digest.WindowID != windowID {
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "window is not open")
+	}
+	digest.WindowID, digest.OpenedAt, digest.SendAt = nil, nil, nil
+
+	var messages []*entities.NotificationDigestMessage
+	for _, message := range repository.messages {
+		if message.UserID == userID && message.Owner == owner && message.DigestID == nil {
+			message.DigestID = &digestID
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// fakeMailer stores the emails which are sent
+type fakeMailer struct {
+	mutex  sync.Mutex
+	emails []*emails.Email
+}
+
+func (mailer *fakeMailer) Send(_ context.Context, mail *emails.Email) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	mailer.emails = append(mailer.emails, mail)
+	return nil
+}
+
+// fakeNotificationEmailFactory creates an email with the IDs of the messages in a digest
+type fakeNotificationEmailFactory struct {
+	emails.NotificationEmailFactory
+	digests [][]*entities.NotificationDigestMessage
+}
+
+func (factory *fakeNotificationEmailFactory) MessageDigest(user *entities.User, owner string, _ time.Time, messages []*entities.NotificationDigestMessage) (*emails.Email, error) {
+	factory.digests = append(factory.digests, messages)
+	return &emails.Email{ToEmail: user.Email, Subject: owner}, nil
+}
+
+func newTestEmailNotificationService(user *entities.User, preference *entities.NotificationPreference) (*EmailNotificationService, *recordingPushQueue, *fakeMailer, *fakeNotificationEmailFactory) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	queue := &recordingPushQueue{}
+	dispatcher := NewEventDispatcher(logger, tracer, noop.Float64Histogram{}, queue, PushQueueConfig{}, memory.NewEventRepository())
+
+	preferences := &stubNotificationPreferenceRepository{preferences: map[entities.UserID]*entities.NotificationPreference{}}
+	if preference != nil {
+		preferences.preferences[user.ID] = preference
+	}
+
+	mailer := &fakeMailer{}
+	factory := &fakeNotificationEmailFactory{}
+	service := NewEmailNotificationService(
+		logger,
+		tracer,
+		&stubNotificationUserRepository{user: user},
+		preferences,
+		newStubNotificationDigestRepository(),
+		dispatcher,
+		factory,
+		mailer,
+		nil,
+	)
+	return service, queue, mailer, factory
+}
+
+func notificationDigestDuePayloads(t *testing.T, queue *recordingPushQueue) []*events.NotificationDigestDuePayload {
+	var payloads []*events.NotificationDigestDuePayload
+	for _, task := range queue.tasks {
+		event := cloudevents.NewEvent()
+		assert.Nil(t, json.Unmarshal(task.Body, &event))
+		assert.Equal(t, events.EventTypeNotificationDigestDue, event.Type())
+
+		payload := new(events.NotificationDigestDuePayload)
+		assert.Nil(t, event.DataAs(payload))
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+func newTestMessageSendFailedPayload(user *entities.User, owner string) *events.MessageSendFailedPayload {
+	return &events.MessageSendFailedPayload{
+		ID:           uuid.New(),
+		ErrorMessage: "NO_SERVICE",
+		UserID:       user.ID,
+		Owner:        owner,
+		Contact:      "+18005550100",
+		Timestamp:    time.Now().UTC(),
+	}
+}
+
+func TestEmailNotificationService_NotifyDigestDue(t *testing.T) {
+	user := &entities.User{ID: "user-id", Email: "name@email.com", Timezone: "UTC", NotificationMessageStatusEnabled: true}
+	owner := "+18005550199"
+
+	t.Run("messages which fail in the same window are sent in one email", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, queue, mailer, factory := newTestEmailNotificationService(user, nil)
+
+		// Arrange
+		var messageIDs []uuid.UUID
+		for i := 0; i < 3; i++ {
+			payload := newTestMessageSendFailedPayload(user, owner)
+			messageIDs = append(messageIDs, payload.ID)
+			assert.Nil(t, service.NotifyMessageFailed(context.Background(), "test", payload))
+		}
+		assert.Nil(t, service.NotifyMessageExpired(context.Background(), "test", &events.MessageSendExpiredPayload{
+			MessageID: uuid.New(),
+			UserID:    user.ID,
+			Owner:     owner,
+			IsFinal:   false,
+		}))
+
+		payloads := notificationDigestDuePayloads(t, queue)
+		assert.Equal(t, 1, len(payloads))
+
+		// Act
+		err := service.NotifyDigestDue(context.Background(), "test", payloads[0])
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(mailer.emails))
+		assert.Equal(t, user.Email, mailer.emails[0].ToEmail)
+
+		var digestIDs []uuid.UUID
+		for _, message := range factory.digests[0] {
+			digestIDs = append(digestIDs, message.MessageID)
+		}
+		assert.Equal(t, messageIDs, digestIDs)
+	})
+
+	t.Run("digest which is delivered twice is sent once", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, queue, mailer, _ := newTestEmailNotificationService(user, nil)
+
+		// Arrange
+		assert.Nil(t, service.NotifyMessageFailed(context.Background(), "test", newTestMessageSendFailedPayload(user, owner)))
+		payload := notificationDigestDuePayloads(t, queue)[0]
+
+		// Act
+		assert.Nil(t, service.NotifyDigestDue(context.Background(), "test", payload))
+		err := service.NotifyDigestDue(context.Background(), "test", payload)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(mailer.emails))
+	})
+
+	t.Run("message which fails after a digest is sent opens a new window", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, queue, mailer, factory := newTestEmailNotificationService(user, nil)
+
+		// Arrange
+		assert.Nil(t, service.NotifyMessageFailed(context.Background(), "test", newTestMessageSendFailedPayload(user, owner)))
+		assert.Nil(t, service.NotifyDigestDue(context.Background(), "test", notificationDigestDuePayloads(t, queue)[0]))
+
+		// Act
+		assert.Nil(t, service.NotifyMessageFailed(context.Background(), "test", newTestMessageSendFailedPayload(user, owner)))
+		payloads := notificationDigestDuePayloads(t, queue)
+		err := service.NotifyDigestDue(context.Background(), "test", payloads[1])
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(payloads))
+		assert.NotEqual(t, payloads[0].WindowID, payloads[1].WindowID)
+		assert.Equal(t, 2, len(mailer.emails))
+		assert.Equal(t, 1, len(factory.digests[1]))
+	})
+
+	t.Run("digest with fewer messages than the threshold is not sent", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, queue, mailer, _ := newTestEmailNotificationService(user, &entities.NotificationPreference{UserID: user.ID, Enabled: true, Threshold: 3})
+
+		// Arrange
+		for i := 0; i < 2; i++ {
+			assert.Nil(t, service.NotifyMessageFailed(context.Background(), "test", newTestMessageSendFailedPayload(user, owner)))
+		}
+
+		// Act
+		err := service.NotifyDigestDue(context.Background(), "test", notificationDigestDuePayloads(t, queue)[0])
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(mailer.emails))
+	})
+
+	t.Run("digest which is due in the quiet hours is postponed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		now := time.Now().UTC()
+		preference := &entities.NotificationPreference{
+			UserID:          user.ID,
+			Enabled:         true,
+			Threshold:       1,
+			QuietHoursStart: now.Add(-time.Hour).Format("15:04"),
+			QuietHoursEnd:   now.Add(time.Hour).Format("15:04"),
+		}
+		service, queue, mailer, _ := newTestEmailNotificationService(user, preference)
+
+		// Arrange
+		assert.Nil(t, service.NotifyMessageFailed(context.Background(), "test", newTestMessageSendFailedPayload(user, owner)))
+
+		// Act
+		err := service.NotifyDigestDue(context.Background(), "test", notificationDigestDuePayloads(t, queue)[0])
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(mailer.emails))
+
+		payloads := notificationDigestDuePayloads(t, queue)
+		assert.Equal(t, 2, len(payloads))
+		assert.Equal(t, payloads[0].WindowID, payloads[1].WindowID)
+		assert.True(t, payloads[1].SendAt.After(now.Add(30*time.Minute)))
+	})
+
+	t.Run("messages are not collected when the notifications are disabled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, queue, mailer, _ := newTestEmailNotificationService(user, &entities.NotificationPreference{UserID: user.ID, Enabled: false, Threshold: 1})
+
+		// Act
+		err := service.NotifyMessageFailed(context.Background(), "test", newTestMessageSendFailedPayload(user, owner))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(queue.tasks))
+		assert.Equal(t, 0, len(mailer.emails))
+	})
+}
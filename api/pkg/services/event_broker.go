@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// EventSubscription receives the cloudevents.Event of an entities.User from the EventBroker
+type EventSubscription struct {
+	UserID entities.UserID
+	// Owner only matches the events of the phone number when it is not empty
+	Owner  string
+	events chan cloudevents.Event
+}
+
+// Events returns the channel of the events, it is closed when the subscription is removed or dropped by the EventBroker
+func (subscription *EventSubscription) Events() <-chan cloudevents.Event {
+	return subscription.events
+}
+
+// Matches checks if the payload of a cloudevents.Event references the user and the owner of the subscription
+func (subscription *EventSubscription) Matches(event cloudevents.Event) bool {
+	payload := struct {
+		UserID entities.UserID `json:"user_id"`
+		Owner  string          `json:"owner"`
+	}{}
+
+	if err := json.Unmarshal(event.Data(), &payload); err != nil {
+		return false
+	}
+
+	return payload.UserID == subscription.UserID && (subscription.Owner == "" || payload.Owner == subscription.Owner)
+}
+
+// EventBroker fans out the events which are processed by this instance to the subscriptions of the users e.g. for server-sent events.
+// A subscription which cannot keep up is dropped so that a slow client never blocks the event listeners.
+type EventBroker struct {
+	service
+	logger        telemetry.Logger
+	tracer        telemetry.Tracer
+	repository    repositories.EventRepository
+	bufferSize    int
+	mutex         sync.RWMutex
+	subscriptions map[*EventSubscription]struct{}
+	closed        bool
+}
+
+// NewEventBroker creates a new EventBroker
+func NewEventBroker(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.EventRepository,
+	bufferSize int,
+) (broker *EventBroker) {
+	return &EventBroker{
+		logger:        logger.WithService(fmt.Sprintf("%T", broker)),
+		tracer:        tracer,
+		repository:    repository,
+		bufferSize:    bufferSize,
+		subscriptions: map[*EventSubscription]struct{}{},
+	}
+}
+
+// Subscribe creates a new EventSubscription for the events of a user, Unsubscribe must be called when the subscription is no longer used
+func (broker *EventBroker) Subscribe(userID entities.UserID, owner string) *EventSubscription {
+	subscription := &EventSubscription{
+		UserID: userID,
+		Owner:  owner,
+		events: make(chan cloudevents.Event, broker.bufferSize),
+	}
+
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	if broker.closed {
+		close(subscription.events)
+		return subscription
+	}
+
+	broker.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+// Close removes all the subscriptions so that the streams which read them end e.g. when the server is shutting down,
+// the subscriptions which are created after it is closed are already closed
+func (broker *EventBroker) Close() {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.closed = true
+	for subscription := range broker.subscriptions {
+		broker.remove(subscription)
+	}
+}
+
+// Unsubscribe removes an EventSubscription and closes its channel, it is safe to call it for a dropped subscription
+func (broker *EventBroker) Unsubscribe(subscription *EventSubscription) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	broker.remove(subscription)
+}
+
+// Subscribers returns the number of active subscriptions
+func (broker *EventBroker) Subscribers() int {
+	broker.mutex.RLock()
+	defer broker.mutex.RUnlock()
+
+	return len(broker.subscriptions)
+}
+
+// Publish sends a cloudevents.Event to the matching subscriptions without blocking, it is an events.EventListener
+func (broker *EventBroker) Publish(ctx context.Context, event cloudevents.Event) error {
+	_, span, ctxLogger := broker.tracer.StartWithLogger(ctx, broker.logger)
+	defer span.End()
+
+	var dropped []*EventSubscription
+
+	broker.mutex.RLock()
+	for subscription := range broker.subscriptions {
+		if !subscription.Matches(event) {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			dropped = append(dropped, subscription)
+		}
+	}
+	broker.mutex.RUnlock()
+
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+
+	for _, subscription := range dropped {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("dropping slow subscription of user [%s] which cannot receive event [%s] with type [%s]", subscription.UserID, event.ID(), event.Type())))
+		broker.remove(subscription)
+	}
+	return nil
+}
+
+// Replay fetches the events of the EventSubscription which were emitted after the event with the ID so that a client can catch up after reconnecting
+func (broker *EventBroker) Replay(ctx context.Context, subscription *EventSubscription, lastEventID uuid.UUID, limit int) ([]cloudevents.Event, error) {
+	ctx, span := broker.tracer.Start(ctx)
+	defer span.End()
+
+	events, err := broker.repository.FetchByUserIDAfter(ctx, subscription.UserID, lastEventID, limit)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the events of user [%s] after event [%s]", subscription.UserID, lastEventID)
+		return nil, broker.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	results := make([]cloudevents.Event, 0, len(*events))
+	for _, event := range *events {
+		if subscription.Matches(event) {
+			results = append(results, event)
+		}
+	}
+	return results, nil
+}
+
+// remove deletes the subscription, the caller must hold the write lock so that no event is sent on the closed channel
+func (broker *EventBroker) remove(subscription *EventSubscription) {
+	if _, ok := broker.subscriptions[subscription]; !ok {
+		return
+	}
+
+	delete(broker.subscriptions, subscription)
+	close(subscription.events)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestEventBroker(bufferSize int) *EventBroker {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewEventBroker(logger, tracer, memory.NewEventRepository(), bufferSize)
+}
+
+func newBrokerEvent(t *testing.T, timestamp time.Time, payload map[string]any) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.NewString())
+	event.SetType("message.phone.sent")
+	event.SetSource("/v1/messages/send")
+	event.SetTime(timestamp)
+	assert.Nil(t, event.SetData(cloudevents.ApplicationJSON, payload))
+	return event
+}
+
+func TestEventBroker_Publish(t *testing.T) {
+	t.Run("events are only sent to the subscriptions of the user and owner", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		broker := newTestEventBroker(10)
+
+		// Arrange
+		all := broker.Subscribe("user-a", "")
+		owner := broker.Subscribe("user-a", "+18005550199")
+		other := broker.Subscribe("user-b", "")
+
+		event := newBrokerEvent(t, time.Now().UTC(), map[string]any{"user_id": "user-a", "owner": "+18005550100"})
+
+		// Act
+		err := broker.Publish(context.Background(), event)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(all.Events()))
+		assert.Equal(t, event.ID(), (<-all.Events()).ID())
+		assert.Equal(t, 0, len(owner.Events()))
+		assert.Equal(t, 0, len(other.Events()))
+	})
+
+	t.Run("slow subscription is dropped without blocking the other subscriptions", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		broker := newTestEventBroker(1)
+
+		// Arrange
+		slow := broker.Subscribe("user-a", "")
+		fast := broker.Subscribe("user-a", "")
+
+		// Act
+		for i := 0; i < 2; i++ {
+			assert.Nil(t, broker.Publish(context.Background(), newBrokerEvent(t, time.Now().UTC(), map[string]any{"user_id": "user-a"})))
+			if i == 0 {
+				<-fast.Events()
+			}
+		}
+
+		// Assert
+		assert.Equal(t, 1, broker.Subscribers())
+		<-slow.Events()
+		_, ok := <-slow.Events()
+		assert.False(t, ok)
+		assert.Equal(t, 1, len(fast.Events()))
+
+		broker.Unsubscribe(slow)
+		broker.Unsubscribe(fast)
+		assert.Equal(t, 0, broker.Subscribers())
+	})
+}
+
+func TestEventBroker_Replay(t *testing.T) {
+	t.Run("events after the last event which match the subscription are replayed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		broker := newTestEventBroker(10)
+		start := time.Now().UTC().Truncate(time.Second)
+
+		// Arrange
+		last := newBrokerEvent(t, start, map[string]any{"user_id": "user-a", "owner": "+18005550199"})
+		missed := newBrokerEvent(t, start.Add(time.Second), map[string]any{"user_id": "user-a", "owner": "+18005550199"})
+		otherOwner := newBrokerEvent(t, start.Add(2*time.Second), map[string]any{"user_id": "user-a", "owner": "+18005550100"})
+		for _, event := range []cloudevents.Event{last, missed, otherOwner} {
+			assert.Nil(t, broker.repository.Create(context.Background(), event))
+		}
+
+		subscription := broker.Subscribe("user-a", "+18005550199")
+		defer broker.Unsubscribe(subscription)
+
+		// Act
+		events, err := broker.Replay(context.Background(), subscription, uuid.MustParse(last.ID()), 10)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(events))
+		assert.Equal(t, missed.ID(), events[0].ID())
+	})
+}
+
+func TestEventBroker_Close(t *testing.T) {
+	t.Run("subscriptions are closed when the broker is closed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		broker := newTestEventBroker(10)
+
+		// Arrange
+		subscription := broker.Subscribe("user-a", "")
+
+		// Act
+		broker.Close()
+		late := broker.Subscribe("user-a", "")
+
+		// Assert
+		_, ok := <-subscription.Events()
+		assert.False(t, ok)
+		_, ok = <-late.Events()
+		assert.False(t, ok)
+		assert.Equal(t, 0, broker.Subscribers())
+	})
+}
@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
 
 	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/palantir/stacktrace"
@@ -26,6 +30,19 @@ type EventDispatcher struct {
 	meter       metric.Float64Histogram
 	queue       PushQueue
 	queueConfig PushQueueConfig
+	repository  repositories.EventRepository
+
+	mutex   sync.Mutex
+	pending map[*pendingEvent]struct{}
+	closing bool
+	drained chan struct{}
+}
+
+// pendingEvent is an event which is published by this instance after a delay because it could not be enqueued
+type pendingEvent struct {
+	ctx   context.Context
+	event cloudevents.Event
+	timer *time.Timer
 }
 
 // NewEventDispatcher creates a new EventDispatcher
@@ -35,6 +52,7 @@ func NewEventDispatcher(
 	meter metric.Float64Histogram,
 	queue PushQueue,
 	queueConfig PushQueueConfig,
+	repository repositories.EventRepository,
 ) (dispatcher *EventDispatcher) {
 	return &EventDispatcher{
 		logger:      logger,
@@ -43,6 +61,8 @@ func NewEventDispatcher(
 		listeners:   make(map[string][]events.EventListener),
 		queue:       queue,
 		queueConfig: queueConfig,
+		repository:  repository,
+		pending:     map[*pendingEvent]struct{}{},
 	}
 }
 
@@ -56,15 +76,28 @@ func (dispatcher *EventDispatcher) DispatchSync(ctx context.Context, event cloud
 		return dispatcher.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	dispatcher.Publish(ctx, event)
+	if repositories.AfterCommit(ctx, func(ctx context.Context) { dispatcher.storeAndPublish(ctx, event) }) {
+		return nil
+	}
+
+	dispatcher.storeAndPublish(ctx, event)
 	return nil
 }
 
-// DispatchWithTimeout dispatches an event with a timeout
+func (dispatcher *EventDispatcher) storeAndPublish(ctx context.Context, event cloudevents.Event) {
+	dispatcher.store(ctx, event)
+	dispatcher.Publish(ctx, event)
+}
+
+// DispatchWithTimeout dispatches an event with a timeout. When the context.Context is part of a transaction, the event is
+// stored and enqueued after the transaction is committed so that an attempt which is rolled back or retried does not
+// dispatch the event, the queue ID is not known until then.
 func (dispatcher *EventDispatcher) DispatchWithTimeout(ctx context.Context, event cloudevents.Event, timeout time.Duration) (queueID string, err error) {
 	ctx, span := dispatcher.tracer.Start(ctx)
 	defer span.End()
 
+	span.SetAttributes(telemetry.EventAttributes(event)...)
+
 	if err = event.Validate(); err != nil {
 		msg := fmt.Sprintf("cannot dispatch event with ID [%s] and type [%s] because it is invalid", event.ID(), event.Type())
 		return queueID, dispatcher.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
@@ -76,14 +109,34 @@ func (dispatcher *EventDispatcher) DispatchWithTimeout(ctx context.Context, even
 		return queueID, dispatcher.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if queueID, err = dispatcher.enqueue(ctx, event, task, timeout); err != nil {
-		msg := fmt.Sprintf("cannot enqueue event with ID [%s] and type [%s]", event.ID(), event.Type())
-		return queueID, dispatcher.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	committed := func(ctx context.Context) {
+		if _, err := dispatcher.storeAndEnqueue(ctx, event, task, timeout); err != nil {
+			dispatcher.logger.Error(stacktrace.Propagate(err, "cannot dispatch event after the transaction is committed"))
+		}
+	}
+	if repositories.AfterCommit(ctx, committed) {
+		return fmt.Sprintf("after-commit-%s", event.ID()), nil
+	}
+
+	if queueID, err = dispatcher.storeAndEnqueue(ctx, event, task, timeout); err != nil {
+		return queueID, dispatcher.tracer.WrapErrorSpan(span, err)
 	}
 
 	return queueID, nil
 }
 
+// storeAndEnqueue stores the event and adds it to the queue
+func (dispatcher *EventDispatcher) storeAndEnqueue(ctx context.Context, event cloudevents.Event, task *PushQueueTask, timeout time.Duration) (string, error) {
+	dispatcher.store(ctx, event)
+
+	queueID, err := dispatcher.enqueue(ctx, event, task, timeout)
+	if err != nil {
+		msg := fmt.Sprintf("cannot enqueue event with ID [%s] and type [%s]", event.ID(), event.Type())
+		return queueID, stacktrace.Propagate(err, msg)
+	}
+	return queueID, nil
+}
+
 func (dispatcher *EventDispatcher) enqueue(ctx context.Context, event cloudevents.Event, task *PushQueueTask, timeout time.Duration) (string, error) {
 	ctx, span, ctxLogger := dispatcher.tracer.StartWithLogger(ctx, dispatcher.logger)
 	defer span.End()
@@ -93,13 +146,107 @@ func (dispatcher *EventDispatcher) enqueue(ctx context.Context, event cloudevent
 		msg := fmt.Sprintf("cannot enqueue event with ID [%s] and type [%s] to [%T]", event.ID(), event.Type(), dispatcher.queue)
 		ctxLogger.Warn(stacktrace.Propagate(err, msg))
 		queueID, err = fmt.Sprintf("local-%s", event.ID()), nil
-		time.AfterFunc(timeout, func() {
-			dispatcher.Publish(ctx, event)
-		})
+		dispatcher.publishAfter(ctx, event, timeout)
 	}
 	return queueID, err
 }
 
+// publishAfter publishes the event in this instance after the delay, it is published immediately when the dispatcher is shutting down
+func (dispatcher *EventDispatcher) publishAfter(ctx context.Context, event cloudevents.Event, delay time.Duration) {
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	if dispatcher.closing {
+		delay = 0
+	}
+
+	pending := &pendingEvent{ctx: detachedContext{repositories.WithoutTransaction(ctx)}, event: event}
+	dispatcher.pending[pending] = struct{}{}
+	pending.timer = time.AfterFunc(delay, func() {
+		dispatcher.publishPending(pending)
+	})
+}
+
+// detachedContext keeps the values of a context.Context without its deadline and cancellation so that an event which
+// is published after the request which dispatched it is handled is not cancelled with the request. The parent must not
+// be part of a transaction because the transaction has ended when the event is published.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (ctx detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (ctx detachedContext) Done() <-chan struct{}             { return nil }
+func (ctx detachedContext) Err() error                        { return nil }
+func (ctx detachedContext) Value(key interface{}) interface{} { return ctx.parent.Value(key) }
+
+// publishPending publishes a pendingEvent and signals Shutdown when it was the last pending event
+func (dispatcher *EventDispatcher) publishPending(pending *pendingEvent) {
+	dispatcher.Publish(pending.ctx, pending.event)
+
+	dispatcher.mutex.Lock()
+	defer dispatcher.mutex.Unlock()
+
+	delete(dispatcher.pending, pending)
+	if dispatcher.drained != nil && len(dispatcher.pending) == 0 {
+		close(dispatcher.drained)
+		dispatcher.drained = nil
+	}
+}
+
+// Shutdown publishes the events which are waiting for their delay immediately and blocks until all the pending events
+// are handled by the listeners or the context.Context is done. The events which are not handled before the deadline
+// are still in the events table since every event is stored before it is dispatched.
+func (dispatcher *EventDispatcher) Shutdown(ctx context.Context) error {
+	ctx, span, ctxLogger := dispatcher.tracer.StartWithLogger(ctx, dispatcher.logger)
+	defer span.End()
+
+	dispatcher.mutex.Lock()
+	dispatcher.closing = true
+	drained := make(chan struct{})
+	if len(dispatcher.pending) == 0 {
+		close(drained)
+	} else {
+		dispatcher.drained = drained
+	}
+
+	for pending := range dispatcher.pending {
+		if pending.timer.Stop() {
+			go dispatcher.publishPending(pending)
+		}
+	}
+	count := len(dispatcher.pending)
+	dispatcher.mutex.Unlock()
+
+	ctxLogger.Info(fmt.Sprintf("waiting for [%d] pending events to be published", count))
+
+	select {
+	case <-drained:
+		ctxLogger.Info(fmt.Sprintf("[%d] pending events were published before the shutdown", count))
+		return nil
+	case <-ctx.Done():
+		dispatcher.mutex.Lock()
+		ids := make([]string, 0, len(dispatcher.pending))
+		for pending := range dispatcher.pending {
+			ids = append(ids, pending.event.ID())
+		}
+		dispatcher.mutex.Unlock()
+
+		msg := fmt.Sprintf("cannot publish [%d] pending events with IDs [%s] before the shutdown deadline", len(ids), strings.Join(ids, ","))
+		return dispatcher.tracer.WrapErrorSpan(span, stacktrace.Propagate(ctx.Err(), msg))
+	}
+}
+
+// store persists the event so that it can be listed later, the event is still dispatched when it cannot be stored
+func (dispatcher *EventDispatcher) store(ctx context.Context, event cloudevents.Event) {
+	ctx, span, ctxLogger := dispatcher.tracer.StartWithLogger(ctx, dispatcher.logger)
+	defer span.End()
+
+	if err := dispatcher.repository.Create(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot store event with ID [%s] and type [%s]", event.ID(), event.Type())
+		ctxLogger.Error(dispatcher.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
 // Dispatch a new event by adding it to the queue to be processed async
 func (dispatcher *EventDispatcher) Dispatch(ctx context.Context, event cloudevents.Event) error {
 	ctx, span := dispatcher.tracer.Start(ctx)
@@ -117,14 +264,17 @@ func (dispatcher *EventDispatcher) Subscribe(eventType string, listener events.E
 	dispatcher.listeners[eventType] = append(dispatcher.listeners[eventType], listener)
 }
 
-// Publish an event to subscribers
+// Publish an event to subscribers. The ID of the request which caused the event is added to the context.Context so
+// that the events created by the subscribers carry the same request ID. The subscribers which have not started when
+// the context.Context is done are skipped, the event can still be replayed from the events table.
 func (dispatcher *EventDispatcher) Publish(ctx context.Context, event cloudevents.Event) {
-	ctx, span := dispatcher.tracer.Start(ctx)
+	ctx = telemetry.WithRequestID(ctx, telemetry.EventRequestID(event))
+	ctx, span, ctxLogger := dispatcher.tracer.StartWithLogger(ctx, dispatcher.logger)
 	defer span.End()
 
-	start := time.Now()
+	span.SetAttributes(telemetry.EventAttributes(event)...)
 
-	ctxLogger := dispatcher.tracer.CtxLogger(dispatcher.logger, span)
+	start := time.Now()
 
 	subscribers, ok := dispatcher.listeners[event.Type()]
 	if !ok {
@@ -134,11 +284,21 @@ func (dispatcher *EventDispatcher) Publish(ctx context.Context, event cloudevent
 
 	var wg sync.WaitGroup
 	for _, sub := range subscribers {
+		if err := ctx.Err(); err != nil {
+			msg := fmt.Sprintf("subscriber [%T] cannot handle event [%s] with ID [%s] because the context is done", sub, event.Type(), event.ID())
+			err = stacktrace.Propagate(err, msg)
+			ctxLogger.Error(err)
+			dispatcher.tracer.ReportError(ctx, err, listenerSignature(event.Type(), sub))
+			continue
+		}
+
 		wg.Add(1)
 		go func(ctx context.Context, sub events.EventListener) {
 			if err := sub(ctx, event); err != nil {
 				msg := fmt.Sprintf("subscriber [%T] cannot handle event [%s]", sub, event.Type())
-				ctxLogger.Error(stacktrace.Propagate(err, msg))
+				err = stacktrace.Propagate(err, msg)
+				ctxLogger.Error(err)
+				dispatcher.tracer.ReportError(ctx, err, listenerSignature(event.Type(), sub))
 			}
 			wg.Done()
 		}(ctx, sub)
@@ -156,6 +316,11 @@ func (dispatcher *EventDispatcher) Publish(ctx context.Context, event cloudevent
 	)
 }
 
+// listenerSignature identifies the listener of an event so that failures of the same listener are grouped together
+func listenerSignature(eventType string, listener events.EventListener) string {
+	return fmt.Sprintf("%s:%s", eventType, runtime.FuncForPC(reflect.ValueOf(listener).Pointer()).Name())
+}
+
 func (dispatcher *EventDispatcher) createCloudTask(event cloudevents.Event) (*PushQueueTask, error) {
 	eventContent, err := json.Marshal(event)
 	if err != nil {
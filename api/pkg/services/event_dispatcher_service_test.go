@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// unavailablePushQueue cannot enqueue tasks so that the events are published by the EventDispatcher
+type unavailablePushQueue struct{}
+
+func (queue *unavailablePushQueue) Enqueue(_ context.Context, _ *PushQueueTask, _ time.Duration) (string, error) {
+	return "", context.DeadlineExceeded
+}
+
+func newTestEventDispatcher() *EventDispatcher {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	histogram, _ := noop.NewMeterProvider().Meter("test").Float64Histogram("test")
+	return NewEventDispatcher(logger, tracer, histogram, &unavailablePushQueue{}, PushQueueConfig{}, memory.NewEventRepository())
+}
+
+func TestEventDispatcher_Shutdown(t *testing.T) {
+	t.Run("events which are dispatched while shutting down are published without leaking goroutines", func(t *testing.T) {
+		// Setup
+		goroutines := runtime.NumGoroutine()
+		dispatcher := newTestEventDispatcher()
+
+		var published int64
+		dispatcher.Subscribe("message.phone.received", func(_ context.Context, _ cloudevents.Event) error {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&published, 1)
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Act
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := dispatcher.DispatchWithTimeout(context.Background(), newHubEvent(t, "user-a"), time.Hour)
+				assert.Nil(t, err)
+			}()
+		}
+
+		shutdownErr := make(chan error, 1)
+		go func() {
+			shutdownErr <- dispatcher.Shutdown(ctx)
+		}()
+
+		wg.Wait()
+		firstErr := <-shutdownErr
+
+		// the events which were dispatched after the first shutdown returned are drained by the second one
+		secondErr := dispatcher.Shutdown(ctx)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, int64(50), atomic.LoadInt64(&published))
+		assert.Eventually(t, func() bool {
+			return runtime.NumGoroutine() <= goroutines
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("shutdown returns an error when the pending events are not published before the deadline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		dispatcher := newTestEventDispatcher()
+
+		release := make(chan struct{})
+		dispatcher.Subscribe("message.phone.received", func(_ context.Context, _ cloudevents.Event) error {
+			<-release
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		// Arrange
+		event := newHubEvent(t, "user-a")
+		_, err := dispatcher.DispatchWithTimeout(context.Background(), event, time.Hour)
+		assert.Nil(t, err)
+
+		// Act
+		err = dispatcher.Shutdown(ctx)
+		close(release)
+
+		// Assert
+		assert.Equal(t, context.DeadlineExceeded, stacktrace.RootCause(err))
+		assert.Contains(t, err.Error(), event.ID())
+	})
+}
+
+func TestEventDispatcher_Publish(t *testing.T) {
+	t.Run("listeners are not started when the context is done", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		dispatcher := newTestEventDispatcher()
+
+		var handled int64
+		dispatcher.Subscribe("message.phone.received", func(_ context.Context, _ cloudevents.Event) error {
+			atomic.AddInt64(&handled, 1)
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// Act
+		dispatcher.Publish(ctx, newHubEvent(t, "user-a"))
+
+		// Assert
+		assert.Equal(t, int64(0), atomic.LoadInt64(&handled))
+	})
+
+	t.Run("pending events are published after the context of the request is cancelled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		dispatcher := newTestEventDispatcher()
+
+		handled := make(chan error, 1)
+		dispatcher.Subscribe("message.phone.received", func(ctx context.Context, _ cloudevents.Event) error {
+			handled <- ctx.Err()
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		// Act
+		_, err := dispatcher.DispatchWithTimeout(ctx, newHubEvent(t, "user-a"), 20*time.Millisecond)
+		cancel()
+
+		// Assert
+		assert.Nil(t, err)
+		select {
+		case err = <-handled:
+			assert.Nil(t, err)
+		case <-time.After(time.Second):
+			assert.Fail(t, "the pending event was not published")
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/palantir/stacktrace"
+)
+
+// FallbackProviderSignatureHeader is the header with the HMAC-SHA256 signature of the body of the requests which are
+// sent to an http fallback provider and of the status callbacks of the provider
+const FallbackProviderSignatureHeader = "X-Httpsms-Signature"
+
+// FallbackCallbackRequest is a status callback which a fallback provider sends for a message
+type FallbackCallbackRequest struct {
+	// URL is the callback URL which was sent to the provider with the message
+	URL  string
+	Body []byte
+	// Header returns the value of a header of the callback
+	Header func(key string) string
+}
+
+// FallbackStatus is the status of a message in a fallback provider
+type FallbackStatus struct {
+	ProviderMessageID string
+	// EventName is nil when the status is not sent, delivered or failed
+	EventName    *entities.MessageEventName
+	ErrorMessage *string
+}
+
+// FallbackProvider sends the messages of a phone which cannot send them with an external SMS provider
+type FallbackProvider interface {
+	// Send a message with the entities.FallbackProvider and return the ID of the message in the provider, the status callbacks of the message are sent to the callbackURL
+	Send(ctx context.Context, provider *entities.FallbackProvider, message *entities.Message, callbackURL string) (string, error)
+
+	// ParseCallback verifies the signature of a status callback of the entities.FallbackProvider and returns the FallbackStatus of the message
+	ParseCallback(provider *entities.FallbackProvider, request FallbackCallbackRequest) (*FallbackStatus, error)
+}
+
+// twilioFallbackProvider sends messages with the Twilio REST API
+type twilioFallbackProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewTwilioFallbackProvider creates a FallbackProvider which sends messages with the Twilio REST API at the baseURL e.g. https://api.twilio.com
+func NewTwilioFallbackProvider(client *http.Client, baseURL string) FallbackProvider {
+	return &twilioFallbackProvider{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Send a message with the Messages API of twilio
+func (provider *twilioFallbackProvider) Send(ctx context.Context, config *entities.FallbackProvider, message *entities.Message, callbackURL string) (string, error) {
+	values := url.Values{
+		"To":             []string{message.Contact},
+		"From":           []string{config.From},
+		"Body":           []string{message.Content},
+		"StatusCallback": []string{callbackURL},
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/Accounts/%s/Messages.json", provider.baseURL, twilio.APIVersion, url.PathEscape(config.AccountSID))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot create twilio request for message [%s]", message.ID))
+	}
+	request.SetBasicAuth(config.AccountSID, config.AuthToken)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot send message [%s] with twilio", message.ID))
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot read the twilio response of message [%s]", message.ID))
+	}
+
+	if response.StatusCode >= 400 {
+		twilioError := new(twilio.Error)
+		if err = json.Unmarshal(body, twilioError); err != nil || twilioError.Code == 0 {
+			return "", stacktrace.NewError(fmt.Sprintf("twilio cannot send message [%s] with response code [%d]", message.ID, response.StatusCode))
+		}
+		return "", stacktrace.NewError(fmt.Sprintf("twilio cannot send message [%s] with error [%d]: %s", message.ID, twilioError.Code, twilioError.Message))
+	}
+
+	twilioMessage := new(twilio.Message)
+	if err = json.Unmarshal(body, twilioMessage); err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot decode the twilio response [%s] of message [%s]", body, message.ID))
+	}
+
+	return twilioMessage.SID, nil
+}
+
+// ParseCallback verifies the X-Twilio-Signature of a status callback with the auth token of the account
+func (provider *twilioFallbackProvider) ParseCallback(config *entities.FallbackProvider, request FallbackCallbackRequest) (*FallbackStatus, error) {
+	values, err := url.ParseQuery(string(request.Body))
+	if err != nil {
+		return nil, stacktrace.PropagateWithCode(err, ErrCodeFallbackUnauthorized, "cannot parse the form of the twilio status callback")
+	}
+
+	expected := twilio.Signature(config.AuthToken, request.URL, values)
+	if !hmac.Equal([]byte(expected), []byte(request.Header(twilio.SignatureHeader))) {
+		return nil, stacktrace.NewErrorWithCode(ErrCodeFallbackUnauthorized, fmt.Sprintf("the [%s] header of the twilio status callback is not valid", twilio.SignatureHeader))
+	}
+
+	status := &FallbackStatus{ProviderMessageID: values.Get("MessageSid")}
+	switch twilio.Status(values.Get("MessageStatus")) {
+	case twilio.StatusSent:
+		status.EventName = fallbackEventName(entities.MessageEventNameSent)
+	case twilio.StatusDelivered:
+		status.EventName = fallbackEventName(entities.MessageEventNameDelivered)
+	case twilio.StatusFailed, twilio.StatusUndelivered:
+		errorMessage := fmt.Sprintf("TWILIO_%s", strings.ToUpper(values.Get("MessageStatus")))
+		if code := values.Get("ErrorCode"); code != "" {
+			errorMessage = fmt.Sprintf("%s: %s", errorMessage, code)
+		}
+		status.EventName = fallbackEventName(entities.MessageEventNameFailed)
+		status.ErrorMessage = &errorMessage
+	}
+
+	return status, nil
+}
+
+// httpFallbackProvider sends messages with a signed JSON request to the URL of the entities.FallbackProvider
+type httpFallbackProvider struct {
+	client *http.Client
+}
+
+// NewHTTPFallbackProvider creates a FallbackProvider which posts the messages as JSON to the URL of the entities.FallbackProvider.
+// The provider responds with the ID of the message e.g. {"id": "..."} and posts the status of the message to the
+// callback_url e.g. {"id": "...", "status": "delivered", "error": ""}, the body of both requests is signed in the
+// X-Httpsms-Signature header with the HMAC-SHA256 of the signing key.
+func NewHTTPFallbackProvider(client *http.Client) FallbackProvider {
+	return &httpFallbackProvider{client: client}
+}
+
+// httpFallbackRequest is the body of a message which is sent to an http fallback provider
+type httpFallbackRequest struct {
+	ID          string `json:"id"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Content     string `json:"content"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// httpFallbackResponse is the body of the response of an http fallback provider and its status callbacks
+type httpFallbackResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Send a message as JSON to the URL of the provider
+func (provider *httpFallbackProvider) Send(ctx context.Context, config *entities.FallbackProvider, message *entities.Message, callbackURL string) (string, error) {
+	payload, err := json.Marshal(httpFallbackRequest{
+		ID:          message.ID.String(),
+		From:        config.From,
+		To:          message.Contact,
+		Content:     message.Content,
+		CallbackURL: callbackURL,
+	})
+	if err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot marshal message [%s] for the http fallback provider", message.ID))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot create http fallback request for message [%s]", message.ID))
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(FallbackProviderSignatureHeader, FallbackProviderSignature(config.SigningKey, payload))
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return "", stacktrace.Propagate(err, fmt.Sprintf("cannot send message [%s] with the http fallback provider", message.ID))
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= 400 {
+		return "", stacktrace.NewError(fmt.Sprintf("http fallback provider cannot send message [%s] with response code [%d]", message.ID, response.StatusCode))
+	}
+
+	result := new(httpFallbackResponse)
+	if err = json.NewDecoder(response.Body).Decode(result); err != nil || result.ID == "" {
+		return "", stacktrace.NewError(fmt.Sprintf("the response of the http fallback provider for message [%s] does not have an ID", message.ID))
+	}
+
+	return result.ID, nil
+}
+
+// ParseCallback verifies the X-Httpsms-Signature of a status callback with the signing key of the provider
+func (provider *httpFallbackProvider) ParseCallback(config *entities.FallbackProvider, request FallbackCallbackRequest) (*FallbackStatus, error) {
+	expected := FallbackProviderSignature(config.SigningKey, request.Body)
+	if !hmac.Equal([]byte(expected), []byte(request.Header(FallbackProviderSignatureHeader))) {
+		return nil, stacktrace.NewErrorWithCode(ErrCodeFallbackUnauthorized, fmt.Sprintf("the [%s] header of the http status callback is not valid", FallbackProviderSignatureHeader))
+	}
+
+	payload := new(httpFallbackResponse)
+	if err := json.Unmarshal(request.Body, payload); err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode the http status callback")
+	}
+
+	status := &FallbackStatus{ProviderMessageID: payload.ID}
+	switch payload.Status {
+	case "sent":
+		status.EventName = fallbackEventName(entities.MessageEventNameSent)
+	case "delivered":
+		status.EventName = fallbackEventName(entities.MessageEventNameDelivered)
+	case "failed":
+		errorMessage := "HTTP_PROVIDER_FAILED"
+		if payload.Error != "" {
+			errorMessage = fmt.Sprintf("%s: %s", errorMessage, payload.Error)
+		}
+		status.EventName = fallbackEventName(entities.MessageEventNameFailed)
+		status.ErrorMessage = &errorMessage
+	}
+
+	return status, nil
+}
+
+// FallbackProviderSignature is the hex encoded HMAC-SHA256 of a body signed with the signing key of an http fallback provider
+func FallbackProviderSignature(signingKey string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func fallbackEventName(name entities.MessageEventName) *entities.MessageEventName {
+	return &name
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwilioFallbackProvider_Send(t *testing.T) {
+	t.Run("message is sent with the messages API of the account", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var values url.Values
+		var path, username, password string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			values = r.PostForm
+			path = r.URL.Path
+			username, password, _ = r.BasicAuth()
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"sid": "SM1234", "status": "queued"}`))
+		}))
+		defer server.Close()
+
+		// Arrange
+		provider := NewTwilioFallbackProvider(server.Client(), server.URL)
+		config := &entities.FallbackProvider{From: "+18005550199", AccountSID: "AC123", AuthToken: "token"}
+		message := &entities.Message{ID: uuid.New(), Contact: "+18005550100", Content: "This is a sample text message"}
+
+		// Act
+		sid, err := provider.Send(context.Background(), config, message, "https://api.httpsms.com/fallback-callbacks/1/messages/2")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "SM1234", sid)
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", path)
+		assert.Equal(t, "AC123", username)
+		assert.Equal(t, "token", password)
+		assert.Equal(t, "+18005550100", values.Get("To"))
+		assert.Equal(t, "+18005550199", values.Get("From"))
+		assert.Equal(t, "This is a sample text message", values.Get("Body"))
+		assert.Equal(t, "https://api.httpsms.com/fallback-callbacks/1/messages/2", values.Get("StatusCallback"))
+	})
+
+	t.Run("error of the messages API is returned", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 21211, "message": "The 'To' number is not a valid phone number.", "status": 400}`))
+		}))
+		defer server.Close()
+
+		// Arrange
+		provider := NewTwilioFallbackProvider(server.Client(), server.URL)
+		message := &entities.Message{ID: uuid.New(), Contact: "+1800", Content: "hello"}
+
+		// Act
+		_, err := provider.Send(context.Background(), &entities.FallbackProvider{AccountSID: "AC123"}, message, "")
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "21211")
+	})
+}
+
+func TestTwilioFallbackProvider_ParseCallback(t *testing.T) {
+	config := &entities.FallbackProvider{AuthToken: "12345"}
+	callbackURL := "https://api.httpsms.com/fallback-callbacks/1/messages/2"
+	values := url.Values{
+		"MessageSid":    []string{"SM1234"},
+		"MessageStatus": []string{"undelivered"},
+		"ErrorCode":     []string{"30003"},
+	}
+
+	t.Run("callback with a valid signature is parsed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider := NewTwilioFallbackProvider(http.DefaultClient, "")
+
+		// Arrange
+		signature := twilio.Signature(config.AuthToken, callbackURL, values)
+		request := FallbackCallbackRequest{
+			URL:    callbackURL,
+			Body:   []byte(values.Encode()),
+			Header: func(string) string { return signature },
+		}
+
+		// Act
+		status, err := provider.ParseCallback(config, request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "SM1234", status.ProviderMessageID)
+		assert.Equal(t, entities.MessageEventNameFailed, *status.EventName)
+		assert.Equal(t, "TWILIO_UNDELIVERED: 30003", *status.ErrorMessage)
+	})
+
+	t.Run("callback with an invalid signature is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider := NewTwilioFallbackProvider(http.DefaultClient, "")
+
+		// Arrange
+		signature := twilio.Signature("another-token", callbackURL, values)
+		request := FallbackCallbackRequest{
+			URL:    callbackURL,
+			Body:   []byte(values.Encode()),
+			Header: func(string) string { return signature },
+		}
+
+		// Act
+		_, err := provider.ParseCallback(config, request)
+
+		// Assert
+		assert.Equal(t, ErrCodeFallbackUnauthorized, stacktrace.GetCode(err))
+	})
+}
+
+func TestHTTPFallbackProvider_Send(t *testing.T) {
+	t.Run("message is posted with the signature of the body", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var body []byte
+		var signature string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			signature = r.Header.Get(FallbackProviderSignatureHeader)
+			_, _ = w.Write([]byte(`{"id": "provider-id"}`))
+		}))
+		defer server.Close()
+
+		// Arrange
+		provider := NewHTTPFallbackProvider(server.Client())
+		config := &entities.FallbackProvider{From: "+18005550199", URL: server.URL, SigningKey: "DGW8NwQp7fxcYeJ2"}
+		message := &entities.Message{ID: uuid.New(), Contact: "+18005550100", Content: "hello"}
+
+		// Act
+		id, err := provider.Send(context.Background(), config, message, "https://example.com/callback")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "provider-id", id)
+		assert.Equal(t, FallbackProviderSignature(config.SigningKey, body), signature)
+
+		payload := map[string]string{}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, message.ID.String(), payload["id"])
+		assert.Equal(t, "+18005550100", payload["to"])
+		assert.Equal(t, "https://example.com/callback", payload["callback_url"])
+	})
+
+	t.Run("response without an ID is an error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		// Arrange
+		provider := NewHTTPFallbackProvider(server.Client())
+		message := &entities.Message{ID: uuid.New()}
+
+		// Act
+		_, err := provider.Send(context.Background(), &entities.FallbackProvider{URL: server.URL}, message, "")
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
+
+func TestHTTPFallbackProvider_ParseCallback(t *testing.T) {
+	config := &entities.FallbackProvider{SigningKey: "DGW8NwQp7fxcYeJ2"}
+	body := []byte(`{"id": "provider-id", "status": "delivered"}`)
+
+	t.Run("callback with a valid signature is parsed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider := NewHTTPFallbackProvider(http.DefaultClient)
+
+		// Arrange
+		signature := FallbackProviderSignature(config.SigningKey, body)
+		request := FallbackCallbackRequest{Body: body, Header: func(string) string { return signature }}
+
+		// Act
+		status, err := provider.ParseCallback(config, request)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "provider-id", status.ProviderMessageID)
+		assert.Equal(t, entities.MessageEventNameDelivered, *status.EventName)
+		assert.Nil(t, status.ErrorMessage)
+	})
+
+	t.Run("callback without a signature is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider := NewHTTPFallbackProvider(http.DefaultClient)
+
+		// Arrange
+		request := FallbackCallbackRequest{Body: body, Header: func(string) string { return "" }}
+
+		// Act
+		_, err := provider.ParseCallback(config, request)
+
+		// Assert
+		assert.Equal(t, ErrCodeFallbackUnauthorized, stacktrace.GetCode(err))
+	})
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// FallbackService sends the messages which the phone of a user cannot send with the entities.FallbackProvider of the user.
+// Only the messages which are sent with AllowFallback are sent by the provider so that bulk messages are never sent
+// with a paid provider by accident.
+type FallbackService struct {
+	service
+	logger            telemetry.Logger
+	tracer            telemetry.Tracer
+	callbackURL       string
+	providers         map[entities.FallbackProviderType]FallbackProvider
+	repository        repositories.FallbackProviderRepository
+	messageRepository repositories.MessageRepository
+	messageService    *MessageService
+	heartbeatService  *HeartbeatService
+}
+
+// NewFallbackService creates a new FallbackService, the callbackURL is the public URL of the API which receives the
+// status callbacks of the providers
+func NewFallbackService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	callbackURL string,
+	providers map[entities.FallbackProviderType]FallbackProvider,
+	repository repositories.FallbackProviderRepository,
+	messageRepository repositories.MessageRepository,
+	messageService *MessageService,
+	heartbeatService *HeartbeatService,
+) (s *FallbackService) {
+	return &FallbackService{
+		logger:            logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:            tracer,
+		callbackURL:       strings.TrimRight(callbackURL, "/"),
+		providers:         providers,
+		repository:        repository,
+		messageRepository: messageRepository,
+		messageService:    messageService,
+		heartbeatService:  heartbeatService,
+	}
+}
+
+// Load the entities.FallbackProvider of a user
+func (service *FallbackService) Load(ctx context.Context, userID entities.UserID) (*entities.FallbackProvider, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	provider, err := service.repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the fallback provider of user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return provider, nil
+}
+
+// FallbackProviderUpsertParams are parameters for setting the entities.FallbackProvider of a user
+type FallbackProviderUpsertParams struct {
+	UserID              entities.UserID
+	Type                entities.FallbackProviderType
+	From                string
+	AccountSID          string
+	AuthToken           string
+	URL                 string
+	SigningKey          string
+	OfflineAfterSeconds uint
+	FallbackOnExpired   bool
+}
+
+// Upsert creates or replaces the entities.FallbackProvider of a user
+func (service *FallbackService) Upsert(ctx context.Context, params *FallbackProviderUpsertParams) (*entities.FallbackProvider, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	provider, err := service.repository.Load(ctx, params.UserID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		provider = &entities.FallbackProvider{ID: uuid.New(), UserID: params.UserID, CreatedAt: time.Now().UTC()}
+		err = nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the fallback provider of user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	provider.Type = params.Type
+	provider.From = params.From
+	provider.AccountSID = params.AccountSID
+	provider.AuthToken = params.AuthToken
+	provider.URL = params.URL
+	provider.SigningKey = params.SigningKey
+	provider.OfflineAfterSeconds = params.OfflineAfterSeconds
+	provider.FallbackOnExpired = params.FallbackOnExpired
+	provider.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Save(ctx, provider); err != nil {
+		msg := fmt.Sprintf("cannot save the fallback provider with id [%s] of user [%s]", provider.ID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("[%s] fallback provider saved with id [%s] in the [%T]", provider.Type, provider.ID, service.repository))
+	return provider, nil
+}
+
+// Delete the entities.FallbackProvider of a user
+func (service *FallbackService) Delete(ctx context.Context, userID entities.UserID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if _, err := service.repository.Load(ctx, userID); err != nil {
+		msg := fmt.Sprintf("cannot load the fallback provider of user [%s]", userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err := service.repository.Delete(ctx, userID); err != nil {
+		msg := fmt.Sprintf("cannot delete the fallback provider of user [%s]", userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted the fallback provider of user [%s]", userID))
+	return nil
+}
+
+// HandleMessageAPISent sends a new message with the fallback provider when the phone of the owner has been offline for
+// longer than the OfflineAfterSeconds of the provider
+func (service *FallbackService) HandleMessageAPISent(ctx context.Context, source string, payload events.MessageAPISentPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if !payload.AllowFallback {
+		return nil
+	}
+
+	provider, err := service.repository.Load(ctx, payload.UserID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Info(fmt.Sprintf("user [%s] has no fallback provider for message [%s]", payload.UserID, payload.MessageID))
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the fallback provider of user [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	liveness, err := service.heartbeatService.Liveness(ctx, payload.UserID, payload.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the liveness of owner [%s] for user [%s]", telemetry.RedactPhoneNumber(payload.Owner), payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !provider.IsOffline(liveness.LastHeartbeatAt, time.Now().UTC()) {
+		return nil
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone of message [%s] has been offline for more than [%d] seconds", payload.MessageID, provider.OfflineAfterSeconds))
+	return service.send(ctx, source, provider, payload.UserID, payload.MessageID)
+}
+
+// HandleMessageFallbackRequested sends a message which expired after the last send attempt of the phone with the
+// fallback provider when the FallbackOnExpired of the provider is set
+func (service *FallbackService) HandleMessageFallbackRequested(ctx context.Context, source string, payload events.MessageFallbackRequestedPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	provider, err := service.repository.Load(ctx, payload.UserID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Info(fmt.Sprintf("user [%s] has no fallback provider for message [%s]", payload.UserID, payload.MessageID))
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the fallback provider of user [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !provider.FallbackOnExpired {
+		ctxLogger.Info(fmt.Sprintf("fallback provider [%s] does not send expired messages", provider.ID))
+		return nil
+	}
+
+	return service.send(ctx, source, provider, payload.UserID, payload.MessageID)
+}
+
+// send moves a message from the phone to the fallback provider and sends it, a message which cannot be sent by the
+// provider is failed because the phone can no longer send it
+func (service *FallbackService) send(ctx context.Context, source string, provider *entities.FallbackProvider, userID entities.UserID, messageID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	sender, ok := service.providers[provider.Type]
+	if !ok {
+		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(fmt.Sprintf("fallback provider type [%s] is not supported", provider.Type)))
+	}
+
+	if service.callbackURL == "" {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("the URL of the status callbacks is not configured, message [%s] is not sent by the fallback provider", messageID)))
+		return nil
+	}
+
+	message, err := service.messageRepository.Fallback(ctx, userID, messageID, provider.Channel())
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Info(fmt.Sprintf("message [%s] can no longer be sent by the fallback provider", messageID))
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot move message [%s] to the [%s] fallback provider", messageID, provider.Type)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	providerMessageID, err := sender.Send(ctx, provider, message, service.messageCallbackURL(provider.ID, message.ID))
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send message [%s] with the [%s] fallback provider", message.ID, provider.Type)))
+		return service.fail(ctx, source, message, fmt.Sprintf("FALLBACK_PROVIDER_ERROR: the [%s] provider cannot send the message", provider.Type))
+	}
+
+	message.ProviderMessageID = &providerMessageID
+	message.UpdatedAt = time.Now().UTC()
+	if err = service.messageRepository.Update(ctx, message); err != nil {
+		msg := fmt.Sprintf("cannot set the provider message ID [%s] of message [%s]", providerMessageID, message.ID)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("message sent with the [%s] fallback provider with ID [%s]", provider.Type, providerMessageID))
+	return nil
+}
+
+func (service *FallbackService) fail(ctx context.Context, source string, message *entities.Message, errorMessage string) error {
+	_, err := service.messageService.StoreEvent(ctx, message, MessageStoreEventParams{
+		MessageID:    message.ID,
+		EventName:    entities.MessageEventNameFailed,
+		Timestamp:    time.Now().UTC(),
+		ErrorMessage: &errorMessage,
+		Source:       source,
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot fail message [%s] which the fallback provider cannot send", message.ID))
+	}
+	return nil
+}
+
+func (service *FallbackService) messageCallbackURL(providerID uuid.UUID, messageID uuid.UUID) string {
+	return fmt.Sprintf("%s/fallback-callbacks/%s/messages/%s", service.callbackURL, providerID, messageID)
+}
+
+// FallbackCallbackParams are parameters for handling a status callback of a fallback provider
+type FallbackCallbackParams struct {
+	ProviderID uuid.UUID
+	MessageID  uuid.UUID
+	Request    FallbackCallbackRequest
+	Source     string
+}
+
+// HandleCallback stores the status in a status callback of a fallback provider like the events of a phone so that the
+// message has the same statuses and webhooks when it is sent by the phone or the provider
+func (service *FallbackService) HandleCallback(ctx context.Context, params FallbackCallbackParams) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	provider, err := service.repository.LoadByID(ctx, params.ProviderID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load fallback provider with ID [%s]", params.ProviderID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	sender, ok := service.providers[provider.Type]
+	if !ok {
+		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(fmt.Sprintf("fallback provider type [%s] is not supported", provider.Type)))
+	}
+
+	// the signature of a twilio callback is computed with the exact URL which was sent to the provider
+	params.Request.URL = service.messageCallbackURL(provider.ID, params.MessageID)
+	status, err := sender.ParseCallback(provider, params.Request)
+	if err != nil {
+		msg := fmt.Sprintf("cannot parse the status callback of fallback provider [%s] for message [%s]", provider.ID, params.MessageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	message, err := service.messageRepository.Load(ctx, provider.UserID, params.MessageID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message [%s] of user [%s]", params.MessageID, provider.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if message.Channel != provider.Channel() || (message.ProviderMessageID != nil && *message.ProviderMessageID != status.ProviderMessageID) {
+		msg := fmt.Sprintf("message [%s] was not sent by fallback provider [%s] with ID [%s]", message.ID, provider.ID, status.ProviderMessageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg))
+	}
+
+	if status.EventName == nil {
+		messageLogger(ctxLogger, message).Info(fmt.Sprintf("ignored a status callback of the [%s] fallback provider which is not sent, delivered or failed", provider.Type))
+		return nil
+	}
+
+	_, err = service.messageService.StoreEvent(ctx, message, MessageStoreEventParams{
+		MessageID:    message.ID,
+		EventName:    *status.EventName,
+		Timestamp:    time.Now().UTC(),
+		ErrorMessage: status.ErrorMessage,
+		Source:       params.Source,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot store the [%s] event of message [%s] from fallback provider [%s]", *status.EventName, message.ID, provider.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("stored the [%s] event from the [%s] fallback provider", *status.EventName, provider.Type))
+	return nil
+}
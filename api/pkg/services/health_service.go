@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// HealthStatusOK is the status of a check which passed
+	HealthStatusOK = "ok"
+
+	// HealthStatusError is the status of a check which failed or timed out
+	HealthStatusError = "error"
+
+	// healthCheckTimeout is the default timeout of a HealthCheck
+	healthCheckTimeout = 2 * time.Second
+)
+
+// backlogMessageStatuses are the statuses of messages which have not been picked up by the phone
+var backlogMessageStatuses = []entities.MessageStatus{
+	entities.MessageStatusPending,
+	entities.MessageStatusSending,
+}
+
+// HealthCheck checks if a dependency of the API is working
+type HealthCheck struct {
+	Name    string
+	Timeout time.Duration
+	Check   func(ctx context.Context) error
+}
+
+// HealthCheckResult is the outcome of a HealthCheck
+type HealthCheckResult struct {
+	Name      string  `json:"name" example:"database"`
+	Status    string  `json:"status" example:"ok"`
+	LatencyMS float64 `json:"latency_ms" example:"1.52"`
+	Error     string  `json:"error,omitempty" example:"context deadline exceeded"`
+}
+
+// HealthReport contains the results of all the health checks
+type HealthReport struct {
+	Status string              `json:"status" example:"ok"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// IsHealthy is true when all the checks passed
+func (report *HealthReport) IsHealthy() bool {
+	return report.Status == HealthStatusOK
+}
+
+// HealthService checks the dependencies of the API for the readiness probe
+type HealthService struct {
+	service
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+	checks []HealthCheck
+}
+
+// NewHealthService creates a new HealthService
+func NewHealthService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	checks []HealthCheck,
+) (s *HealthService) {
+	return &HealthService{
+		logger: logger.WithService(fmt.Sprintf("%T", s)),
+		tracer: tracer,
+		checks: checks,
+	}
+}
+
+// Ready runs the checks concurrently. A check which does not return before its timeout fails so that a slow
+// dependency does not hang the probe.
+func (service *HealthService) Ready(ctx context.Context) *HealthReport {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	results := make([]chan HealthCheckResult, len(service.checks))
+	for index, check := range service.checks {
+		results[index] = make(chan HealthCheckResult, 1)
+		go func(check HealthCheck, result chan<- HealthCheckResult) {
+			result <- service.run(ctx, check)
+		}(check, results[index])
+	}
+
+	report := &HealthReport{Status: HealthStatusOK, Checks: make([]HealthCheckResult, 0, len(service.checks))}
+	for _, result := range results {
+		checkResult := <-result
+		if checkResult.Status != HealthStatusOK {
+			report.Status = HealthStatusError
+			ctxLogger.WithField("check", checkResult.Name).Warn(stacktrace.NewError(fmt.Sprintf("health check [%s] failed: %s", checkResult.Name, checkResult.Error)))
+		}
+		report.Checks = append(report.Checks, checkResult)
+	}
+
+	return report
+}
+
+func (service *HealthService) run(ctx context.Context, check HealthCheck) HealthCheckResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = healthCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = stacktrace.Propagate(ctx.Err(), fmt.Sprintf("health check [%s] did not finish in [%s]", check.Name, timeout))
+	}
+
+	result := HealthCheckResult{
+		Name:      check.Name,
+		Status:    HealthStatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = HealthStatusError
+		result.Error = stacktrace.RootCause(err).Error()
+	}
+	return result
+}
+
+// NewMessageBacklogHealthCheck fails when the number of messages which are waiting to be sent is above the threshold
+func NewMessageBacklogHealthCheck(repository repositories.MessageRepository, threshold int64, timeout time.Duration) HealthCheck {
+	return HealthCheck{
+		Name:    "message_backlog",
+		Timeout: timeout,
+		Check: func(ctx context.Context) error {
+			counts, err := repository.CountByStatus(ctx, backlogMessageStatuses)
+			if err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot count messages with statuses %v", backlogMessageStatuses))
+			}
+
+			var total int64
+			for _, count := range counts {
+				total += count
+			}
+
+			if total > threshold {
+				return stacktrace.NewError(fmt.Sprintf("[%d] messages are waiting to be sent which is above the threshold of [%d]", total, threshold))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHealthService(checks ...HealthCheck) *HealthService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	return NewHealthService(logger, telemetry.NewOtelLogger("test", logger), checks)
+}
+
+func TestHealthService_Ready(t *testing.T) {
+	t.Run("report is healthy when all the checks pass", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestHealthService(
+			HealthCheck{Name: "database", Check: func(ctx context.Context) error { return nil }},
+			HealthCheck{Name: "fcm", Check: func(ctx context.Context) error { return nil }},
+		)
+
+		// Act
+		report := service.Ready(context.Background())
+
+		// Assert
+		assert.True(t, report.IsHealthy())
+		assert.Equal(t, 2, len(report.Checks))
+		assert.Equal(t, "database", report.Checks[0].Name)
+		assert.Equal(t, HealthStatusOK, report.Checks[0].Status)
+		assert.Equal(t, "fcm", report.Checks[1].Name)
+	})
+
+	t.Run("report is not healthy when a check fails", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestHealthService(
+			HealthCheck{Name: "database", Check: func(ctx context.Context) error { return stacktrace.NewError("connection refused") }},
+			HealthCheck{Name: "fcm", Check: func(ctx context.Context) error { return nil }},
+		)
+
+		// Act
+		report := service.Ready(context.Background())
+
+		// Assert
+		assert.False(t, report.IsHealthy())
+		assert.Equal(t, HealthStatusError, report.Checks[0].Status)
+		assert.Equal(t, "connection refused", report.Checks[0].Error)
+		assert.Equal(t, HealthStatusOK, report.Checks[1].Status)
+	})
+
+	t.Run("slow checks time out without blocking the other checks", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		block := make(chan struct{})
+		defer close(block)
+
+		slow := func(ctx context.Context) error {
+			<-block
+			return nil
+		}
+		service := newTestHealthService(
+			HealthCheck{Name: "database", Timeout: 50 * time.Millisecond, Check: slow},
+			HealthCheck{Name: "message_backlog", Timeout: 50 * time.Millisecond, Check: slow},
+			HealthCheck{Name: "fcm", Timeout: 50 * time.Millisecond, Check: func(ctx context.Context) error { return nil }},
+		)
+
+		// Act
+		start := time.Now()
+		report := service.Ready(context.Background())
+
+		// Assert
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+		assert.False(t, report.IsHealthy())
+		assert.Equal(t, HealthStatusError, report.Checks[0].Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+		assert.Equal(t, HealthStatusError, report.Checks[1].Status)
+		assert.Equal(t, HealthStatusOK, report.Checks[2].Status)
+	})
+}
+
+func TestNewMessageBacklogHealthCheck(t *testing.T) {
+	t.Run("check fails when the backlog is above the threshold", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := memory.NewMessageRepository()
+
+		// Arrange
+		for i := 0; i < 3; i++ {
+			assert.Nil(t, repository.Store(context.Background(), &entities.Message{
+				ID:      uuid.New(),
+				UserID:  "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+				Owner:   "+18005550199",
+				Contact: "+18005550100",
+				Status:  entities.MessageStatusPending,
+				Type:    entities.MessageTypeMobileTerminated,
+			}))
+		}
+
+		// Act
+		passed := NewMessageBacklogHealthCheck(repository, 3, time.Second).Check(context.Background())
+		failed := NewMessageBacklogHealthCheck(repository, 2, time.Second).Check(context.Background())
+
+		// Assert
+		assert.Nil(t, passed)
+		assert.NotNil(t, failed)
+	})
+}
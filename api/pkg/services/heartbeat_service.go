@@ -20,6 +20,18 @@ import (
 const (
 	// select id, a.timestamp, a.owner,  a.timestamp - (SELECT timestamp from heartbeats b where  b.timestamp < a.timestamp and a.owner = b.owner and a.user_id = b.user_id order by b.timestamp desc  limit 1) as diff  from heartbeats a;
 	heartbeatCheckInterval = 16 * time.Minute
+
+	// heartbeatDeadThreshold is the default duration after the last heartbeat when a phone is considered offline
+	heartbeatDeadThreshold = heartbeatCheckInterval * 4
+
+	// heartbeatAlertCooldown is the default minimum duration between 2 offline alerts of a phone
+	heartbeatAlertCooldown = time.Hour
+
+	// heartbeatDeadConsecutiveMisses is the number of consecutive checks which must miss the dead threshold before a phone is declared offline
+	heartbeatDeadConsecutiveMisses = 2
+
+	// heartbeatCheckAllSource is the source of events emitted by HeartbeatService.CheckAll
+	heartbeatCheckAllSource = "/v1/heartbeats/check"
 )
 
 // HeartbeatService is handles heartbeat requests
@@ -30,6 +42,10 @@ type HeartbeatService struct {
 	repository        repositories.HeartbeatRepository
 	monitorRepository repositories.HeartbeatMonitorRepository
 	dispatcher        *EventDispatcher
+	phoneService      *PhoneService
+	deadThreshold     time.Duration
+	alertCooldown     time.Duration
+	retention         time.Duration
 }
 
 // NewHeartbeatService creates a new HeartbeatService
@@ -39,23 +55,43 @@ func NewHeartbeatService(
 	repository repositories.HeartbeatRepository,
 	monitorRepository repositories.HeartbeatMonitorRepository,
 	dispatcher *EventDispatcher,
+	phoneService *PhoneService,
+	deadThreshold time.Duration,
+	alertCooldown time.Duration,
+	retention time.Duration,
 ) (s *HeartbeatService) {
+	if deadThreshold <= 0 {
+		deadThreshold = heartbeatDeadThreshold
+	}
+
+	if alertCooldown <= 0 {
+		alertCooldown = heartbeatAlertCooldown
+	}
+
 	return &HeartbeatService{
 		logger:            logger.WithService(fmt.Sprintf("%T", s)),
 		tracer:            tracer,
 		repository:        repository,
 		monitorRepository: monitorRepository,
 		dispatcher:        dispatcher,
+		phoneService:      phoneService,
+		deadThreshold:     deadThreshold,
+		alertCooldown:     alertCooldown,
+		retention:         retention,
 	}
 }
 
 // Index fetches the heartbeats for a phone number
-func (service *HeartbeatService) Index(ctx context.Context, userID entities.UserID, owner string, params repositories.IndexParams) (*[]entities.Heartbeat, error) {
+func (service *HeartbeatService) Index(ctx context.Context, userID entities.UserID, owner string, params repositories.HeartbeatIndexParams) (*[]entities.Heartbeat, error) {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
+	if cutoff := service.retentionCutoff(); cutoff != nil && (params.From == nil || params.From.Before(*cutoff)) {
+		params.From = cutoff
+	}
+
 	heartbeats, err := service.repository.Index(ctx, userID, owner, params)
 	if err != nil {
 		msg := fmt.Sprintf("could not fetch heartbeats with parms [%+#v]", params)
@@ -66,13 +102,80 @@ func (service *HeartbeatService) Index(ctx context.Context, userID entities.User
 	return heartbeats, nil
 }
 
+// Liveness determines if the phone of an owner is online using the timestamp of its last heartbeat
+func (service *HeartbeatService) Liveness(ctx context.Context, userID entities.UserID, owner string) (*entities.PhoneLiveness, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	lastHeartbeatAt, err := service.repository.LastTimestamp(ctx, userID, owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load last heartbeat timestamp for user [%s] and owner [%s]", userID, owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return entities.NewPhoneLiveness(lastHeartbeatAt, service.deadThreshold, time.Now().UTC()), nil
+}
+
+// CountHourly counts the heartbeats of a phone number in each hour of a time range
+func (service *HeartbeatService) CountHourly(ctx context.Context, userID entities.UserID, owner string, from time.Time, to time.Time) (*[]entities.HeartbeatCount, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if cutoff := service.retentionCutoff(); cutoff != nil && from.Before(*cutoff) {
+		from = *cutoff
+	}
+
+	counts, err := service.repository.CountHourly(ctx, userID, owner, from, to)
+	if err != nil {
+		msg := fmt.Sprintf("could not count hourly heartbeats for owner [%s] from [%s] to [%s]", owner, from, to)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("counted heartbeats in [%d] hours for owner [%s] from [%s] to [%s]", len(*counts), owner, from, to))
+	return counts, nil
+}
+
+// Prune deletes the heartbeats which are older than the retention period
+func (service *HeartbeatService) Prune(ctx context.Context) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	cutoff := service.retentionCutoff()
+	if cutoff == nil {
+		ctxLogger.Info("heartbeat retention is not configured, no heartbeats pruned")
+		return nil
+	}
+
+	count, err := service.repository.DeleteBefore(ctx, *cutoff)
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete heartbeats older than [%s]", cutoff)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("pruned [%d] heartbeats older than [%s]", count, cutoff))
+	return nil
+}
+
+// retentionCutoff returns the timestamp before which heartbeats are pruned, it is nil when heartbeats are kept forever
+func (service *HeartbeatService) retentionCutoff() *time.Time {
+	if service.retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().UTC().Add(-1 * service.retention)
+	return &cutoff
+}
+
 // HeartbeatStoreParams are parameters for creating a new entities.Heartbeat
 type HeartbeatStoreParams struct {
-	Owner     string
-	Version   string
-	Charging  bool
-	Timestamp time.Time
-	UserID    entities.UserID
+	Owner        string
+	Version      string
+	Charging     bool
+	BatteryLevel *uint
+	Timestamp    time.Time
+	Source       string
+	UserID       entities.UserID
+	// ReportedTimestamp is the time on the phone when it sent the heartbeat, it is used to estimate the clock skew of the phone
+	ReportedTimestamp *time.Time
 }
 
 // Store a new entities.Heartbeat
@@ -83,12 +186,13 @@ func (service *HeartbeatService) Store(ctx context.Context, params HeartbeatStor
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	heartbeat := &entities.Heartbeat{
-		ID:        uuid.New(),
-		Owner:     params.Owner,
-		Charging:  params.Charging,
-		Timestamp: params.Timestamp,
-		Version:   params.Version,
-		UserID:    params.UserID,
+		ID:           uuid.New(),
+		Owner:        params.Owner,
+		Charging:     params.Charging,
+		BatteryLevel: params.BatteryLevel,
+		Timestamp:    params.Timestamp,
+		Version:      params.Version,
+		UserID:       params.UserID,
 	}
 
 	if err := service.repository.Store(ctx, heartbeat); err != nil {
@@ -97,9 +201,74 @@ func (service *HeartbeatService) Store(ctx context.Context, params HeartbeatStor
 	}
 
 	ctxLogger.Info(fmt.Sprintf("heartbeat saved with id [%s] for user [%s]", heartbeat.ID, heartbeat.UserID))
+
+	if params.BatteryLevel != nil {
+		service.updateBattery(ctx, params)
+	}
+
+	if params.ReportedTimestamp != nil {
+		service.updateClockSkew(ctx, params)
+	}
+
+	if params.Version != "" {
+		if err := service.phoneService.UpdateAppVersion(ctx, params.UserID, params.Owner, params.Version); err != nil {
+			msg := fmt.Sprintf("cannot update app version for userID [%s] and owner [%s]", params.UserID, params.Owner)
+			ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		}
+	}
+
+	monitor, err := service.monitorRepository.Load(ctx, params.UserID, params.Owner)
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("cannot load heartbeat monitor with userID [%s] and owner [%s]", params.UserID, params.Owner)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+
+	if err == nil && !monitor.PhoneOnline {
+		threshold, _ := service.heartbeatSettings(ctx, monitor)
+		if err = service.handleAliveMonitor(ctx, params.Source, heartbeat.Timestamp, threshold, monitor); err != nil {
+			msg := fmt.Sprintf("cannot handle alive heartbeat monitor [%s] for owner [%s]", monitor.ID, monitor.Owner)
+			ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		}
+	}
+
 	return heartbeat, nil
 }
 
+func (service *HeartbeatService) updateBattery(ctx context.Context, params HeartbeatStoreParams) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	_, err := service.phoneService.UpdateBattery(ctx, &PhoneBatteryUpdateParams{
+		UserID:       params.UserID,
+		Owner:        params.Owner,
+		BatteryLevel: *params.BatteryLevel,
+		Charging:     params.Charging,
+		Timestamp:    params.Timestamp,
+		Source:       params.Source,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot update battery for userID [%s] and owner [%s]", params.UserID, params.Owner)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
+func (service *HeartbeatService) updateClockSkew(ctx context.Context, params HeartbeatStoreParams) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	_, err := service.phoneService.UpdateClockSkew(ctx, &PhoneClockSkewUpdateParams{
+		UserID:     params.UserID,
+		Owner:      params.Owner,
+		ReportedAt: *params.ReportedTimestamp,
+		ReceivedAt: params.Timestamp,
+		Source:     params.Source,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot update clock skew for userID [%s] and owner [%s]", params.UserID, params.Owner)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
 // HeartbeatMonitorStoreParams are parameters for creating a new entities.Heartbeat
 type HeartbeatMonitorStoreParams struct {
 	Owner   string
@@ -108,6 +277,12 @@ type HeartbeatMonitorStoreParams struct {
 	UserID  entities.UserID
 }
 
+// Redacted returns a copy of the params which is safe to log
+func (params HeartbeatMonitorStoreParams) Redacted() HeartbeatMonitorStoreParams {
+	params.Owner = telemetry.RedactPhoneNumber(params.Owner)
+	return params
+}
+
 // StoreMonitor a new entities.HeartbeatMonitor
 func (service *HeartbeatService) StoreMonitor(ctx context.Context, params *HeartbeatMonitorStoreParams) (*entities.HeartbeatMonitor, error) {
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
@@ -148,12 +323,13 @@ func (service *HeartbeatService) phoneMonitor(ctx context.Context, params *Heart
 	monitor, err := service.monitorRepository.Load(ctx, params.UserID, params.Owner)
 	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
 		monitor = &entities.HeartbeatMonitor{
-			ID:        uuid.New(),
-			PhoneID:   params.PhoneID,
-			UserID:    params.UserID,
-			Owner:     params.Owner,
-			CreatedAt: time.Now().UTC(),
-			UpdatedAt: time.Now().UTC(),
+			ID:          uuid.New(),
+			PhoneID:     params.PhoneID,
+			UserID:      params.UserID,
+			Owner:       params.Owner,
+			PhoneOnline: true,
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
 		}
 
 		if err = service.monitorRepository.Store(ctx, monitor); err != nil {
@@ -206,7 +382,7 @@ func (service *HeartbeatService) Monitor(ctx context.Context, params *HeartbeatM
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	monitor, err := service.monitorRepository.Load(ctx, params.UserID, params.Owner)
-	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
 		ctxLogger.Info(fmt.Sprintf("heartbeat monitor does not exist for owner [%s] and user [%s]", params.Owner, params.UserID))
 		return nil
 	}
@@ -221,11 +397,15 @@ func (service *HeartbeatService) Monitor(ctx context.Context, params *HeartbeatM
 	params.PhoneID = monitor.PhoneID
 	params.MonitorID = monitor.ID
 
-	heartbeat, err := service.repository.Last(ctx, params.UserID, params.Owner)
+	heartbeat, err := service.checkMonitor(ctx, params.Source, monitor)
 	if err != nil {
-		msg := fmt.Sprintf("cannot fetch last heartbeat for userID [%s] and owner [%s] and ID [%s] removing check", params.UserID, params.Owner, params.MonitorID)
+		msg := fmt.Sprintf("cannot check heartbeat monitor with ID [%s] for userID [%s] and owner [%s]", params.MonitorID, params.UserID, params.Owner)
 		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return nil
+		return service.scheduleHeartbeatCheck(ctx, time.Now().UTC(), params)
+	}
+
+	if heartbeat == nil {
+		return service.scheduleHeartbeatCheck(ctx, time.Now().UTC(), params)
 	}
 
 	// send urgent FCM message if the last heartbeat is late
@@ -234,19 +414,121 @@ func (service *HeartbeatService) Monitor(ctx context.Context, params *HeartbeatM
 		service.handleMissedMonitor(ctx, heartbeat.Timestamp, params)
 	}
 
-	if time.Now().UTC().Sub(heartbeat.Timestamp) > (heartbeatCheckInterval*4) &&
-		time.Now().UTC().Sub(heartbeat.Timestamp) < (heartbeatCheckInterval*5) {
-		return service.handleFailedMonitor(ctx, heartbeat.Timestamp, params)
+	return service.scheduleHeartbeatCheck(ctx, heartbeat.Timestamp, params)
+}
+
+// CheckAll compares the last heartbeat of every monitored phone against the dead threshold
+func (service *HeartbeatService) CheckAll(ctx context.Context) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	monitors, err := service.monitorRepository.Fetch(ctx)
+	if err != nil {
+		msg := "cannot fetch heartbeat monitors"
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, monitor := range *monitors {
+		if _, err = service.checkMonitor(ctx, heartbeatCheckAllSource, &monitor); err != nil {
+			msg := fmt.Sprintf("cannot check heartbeat monitor with ID [%s] for userID [%s] and owner [%s]", monitor.ID, monitor.UserID, monitor.Owner)
+			ctxLogger.Error(stacktrace.Propagate(err, msg))
+		}
 	}
 
-	return service.scheduleHeartbeatCheck(ctx, heartbeat.Timestamp, params)
+	ctxLogger.Info(fmt.Sprintf("checked [%d] heartbeat monitors", len(*monitors)))
+	return nil
+}
+
+// checkMonitor updates the liveness of the phone of an entities.HeartbeatMonitor and emits an event only when it changes.
+// A phone is declared offline after heartbeatDeadConsecutiveMisses consecutive checks miss its dead threshold and no
+// offline alert was emitted within its alert cooldown. It returns a nil entities.Heartbeat when the phone has never sent a heartbeat.
+func (service *HeartbeatService) checkMonitor(ctx context.Context, source string, monitor *entities.HeartbeatMonitor) (*entities.Heartbeat, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	threshold, cooldown := service.heartbeatSettings(ctx, monitor)
+
+	heartbeat, err := service.repository.Last(ctx, monitor.UserID, monitor.Owner)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		if monitor.PhoneOnline && time.Now().UTC().Sub(monitor.CreatedAt) > threshold {
+			return nil, service.handleUnseenMonitor(ctx, source, monitor)
+		}
+		return nil, nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch last heartbeat for userID [%s] and owner [%s]", monitor.UserID, monitor.Owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	isDead := time.Now().UTC().Sub(heartbeat.Timestamp) > threshold
+	if !isDead {
+		if err = service.resetConsecutiveMisses(ctx, monitor); err != nil {
+			return heartbeat, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot reset misses of heartbeat monitor [%s]", monitor.ID)))
+		}
+		if !monitor.PhoneOnline {
+			return heartbeat, service.handleAliveMonitor(ctx, source, heartbeat.Timestamp, threshold, monitor)
+		}
+		return heartbeat, nil
+	}
+
+	if !monitor.PhoneOnline {
+		return heartbeat, nil
+	}
+
+	if err = service.monitorRepository.UpdateConsecutiveMisses(ctx, monitor.ID, monitor.ConsecutiveMisses+1); err != nil {
+		msg := fmt.Sprintf("cannot increment consecutive misses for heartbeat monitor with ID [%s]", monitor.ID)
+		return heartbeat, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	monitor.ConsecutiveMisses++
+
+	if monitor.ConsecutiveMisses < heartbeatDeadConsecutiveMisses {
+		ctxLogger.Info(fmt.Sprintf("heartbeat monitor [%s] for owner [%s] missed [%d/%d] checks with threshold [%s]", monitor.ID, monitor.Owner, monitor.ConsecutiveMisses, heartbeatDeadConsecutiveMisses, threshold))
+		return heartbeat, nil
+	}
+
+	if !monitor.CanAlert(cooldown, time.Now().UTC()) {
+		ctxLogger.Info(fmt.Sprintf("heartbeat monitor [%s] for owner [%s] was last alerted at [%s] which is within the cooldown [%s]", monitor.ID, monitor.Owner, monitor.LastAlertedAt, cooldown))
+		return heartbeat, nil
+	}
+
+	return heartbeat, service.handleFailedMonitor(ctx, source, heartbeat.Timestamp, threshold, monitor)
+}
+
+// heartbeatSettings returns the dead threshold and alert cooldown of the phone of an entities.HeartbeatMonitor.
+// The phone is loaded on every check so that changes to its settings are applied on the next check.
+func (service *HeartbeatService) heartbeatSettings(ctx context.Context, monitor *entities.HeartbeatMonitor) (time.Duration, time.Duration) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	phone, err := service.phoneService.Load(ctx, monitor.UserID, monitor.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone for userID [%s] and owner [%s], using default heartbeat settings", monitor.UserID, monitor.Owner)
+		ctxLogger.Warn(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return service.deadThreshold, service.alertCooldown
+	}
+
+	return phone.HeartbeatDeadThreshold(service.deadThreshold), phone.HeartbeatAlertCooldown(service.alertCooldown)
+}
+
+func (service *HeartbeatService) resetConsecutiveMisses(ctx context.Context, monitor *entities.HeartbeatMonitor) error {
+	if monitor.ConsecutiveMisses == 0 {
+		return nil
+	}
+
+	if err := service.monitorRepository.UpdateConsecutiveMisses(ctx, monitor.ID, 0); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot reset consecutive misses for heartbeat monitor with ID [%s]", monitor.ID))
+	}
+
+	monitor.ConsecutiveMisses = 0
+	return nil
 }
 
 func (service *HeartbeatService) handleMissedMonitor(ctx context.Context, lastTimestamp time.Time, params *HeartbeatMonitorParams) {
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
 	defer span.End()
 
-	event, err := service.createPhoneHeartbeatMissedEvent(params.Source, &events.PhoneHeartbeatMissedPayload{
+	event, err := service.createPhoneHeartbeatMissedEvent(ctx, params.Source, &events.PhoneHeartbeatMissedPayload{
 		PhoneID:                params.PhoneID,
 		UserID:                 params.UserID,
 		MonitorID:              params.MonitorID,
@@ -266,23 +548,37 @@ func (service *HeartbeatService) handleMissedMonitor(ctx context.Context, lastTi
 	}
 }
 
-func (service *HeartbeatService) handleFailedMonitor(ctx context.Context, lastTimestamp time.Time, params *HeartbeatMonitorParams) error {
+func (service *HeartbeatService) handleFailedMonitor(ctx context.Context, source string, lastTimestamp time.Time, threshold time.Duration, monitor *entities.HeartbeatMonitor) error {
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
 	defer span.End()
 
-	err := service.scheduleHeartbeatCheck(ctx, time.Now().UTC(), params)
+	updated, err := service.monitorRepository.UpdatePhoneOnline(ctx, monitor.ID, false)
 	if err != nil {
-		msg := fmt.Sprintf("cannot schedule healthcheck for monitor with owner [%s] and userID [%s]", params.Owner, params.UserID)
+		msg := fmt.Sprintf("cannot mark phone as offline for heartbeat monitor with ID [%s]", monitor.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
+	monitor.PhoneOnline = false
 
-	event, err := service.createPhoneHeartbeatDeadEvent(params.Source, &events.PhoneHeartbeatDeadPayload{
-		PhoneID:                params.PhoneID,
-		UserID:                 params.UserID,
-		MonitorID:              params.MonitorID,
+	if !updated {
+		ctxLogger.Info(fmt.Sprintf("phone of heartbeat monitor [%s] was already marked as offline by a concurrent check", monitor.ID))
+		return nil
+	}
+
+	alertedAt := time.Now().UTC()
+	if err := service.monitorRepository.UpdateLastAlertedAt(ctx, monitor.ID, alertedAt); err != nil {
+		msg := fmt.Sprintf("cannot update last alerted at for heartbeat monitor with ID [%s]", monitor.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	monitor.LastAlertedAt = &alertedAt
+
+	event, err := service.createPhoneHeartbeatDeadEvent(ctx, source, &events.PhoneHeartbeatDeadPayload{
+		PhoneID:                monitor.PhoneID,
+		UserID:                 monitor.UserID,
+		MonitorID:              monitor.ID,
 		LastHeartbeatTimestamp: lastTimestamp,
 		Timestamp:              time.Now().UTC(),
-		Owner:                  params.Owner,
+		Owner:                  monitor.Owner,
+		Threshold:              threshold,
 	})
 	if err != nil {
 		msg := fmt.Sprintf("cannot create event when phone monitor failed")
@@ -290,11 +586,92 @@ func (service *HeartbeatService) handleFailedMonitor(ctx context.Context, lastTi
 	}
 
 	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
-		msg := fmt.Sprintf("cannot dispatch event [%s] for heartbeat monitor with phone id [%s]", event.Type(), params.PhoneID)
+		msg := fmt.Sprintf("cannot dispatch event [%s] for heartbeat monitor with phone id [%s]", event.Type(), monitor.PhoneID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("heartbeat monitor with id [%s] and phone id [%s] failed for user [%s]", params.MonitorID, params.PhoneID, params.UserID))
+	ctxLogger.Info(fmt.Sprintf("heartbeat monitor with id [%s] and phone id [%s] failed for user [%s]", monitor.ID, monitor.PhoneID, monitor.UserID))
+	return nil
+}
+
+func (service *HeartbeatService) handleUnseenMonitor(ctx context.Context, source string, monitor *entities.HeartbeatMonitor) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	updated, err := service.monitorRepository.UpdatePhoneOnline(ctx, monitor.ID, false)
+	if err != nil {
+		msg := fmt.Sprintf("cannot mark phone as offline for heartbeat monitor with ID [%s]", monitor.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	monitor.PhoneOnline = false
+
+	if !updated {
+		ctxLogger.Info(fmt.Sprintf("phone of heartbeat monitor [%s] was already marked as offline by a concurrent check", monitor.ID))
+		return nil
+	}
+
+	event, err := service.createPhoneHeartbeatUnseenEvent(ctx, source, &events.PhoneHeartbeatUnseenPayload{
+		PhoneID:      monitor.PhoneID,
+		UserID:       monitor.UserID,
+		MonitorID:    monitor.ID,
+		RegisteredAt: monitor.CreatedAt,
+		Timestamp:    time.Now().UTC(),
+		Owner:        monitor.Owner,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event when phone monitor [%s] has no heartbeat", monitor.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for heartbeat monitor with phone id [%s]", event.Type(), monitor.PhoneID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone [%s] with heartbeat monitor [%s] has never sent a heartbeat for user [%s]", monitor.PhoneID, monitor.ID, monitor.UserID))
+	return nil
+}
+
+func (service *HeartbeatService) handleAliveMonitor(ctx context.Context, source string, lastTimestamp time.Time, threshold time.Duration, monitor *entities.HeartbeatMonitor) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	updated, err := service.monitorRepository.UpdatePhoneOnline(ctx, monitor.ID, true)
+	if err != nil {
+		msg := fmt.Sprintf("cannot mark phone as online for heartbeat monitor with ID [%s]", monitor.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	monitor.PhoneOnline = true
+
+	if !updated {
+		ctxLogger.Info(fmt.Sprintf("phone of heartbeat monitor [%s] was already marked as online by a concurrent check", monitor.ID))
+		return nil
+	}
+
+	if err = service.resetConsecutiveMisses(ctx, monitor); err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot reset misses of heartbeat monitor [%s]", monitor.ID)))
+	}
+
+	event, err := service.createPhoneHeartbeatAliveEvent(ctx, source, &events.PhoneHeartbeatAlivePayload{
+		PhoneID:                monitor.PhoneID,
+		UserID:                 monitor.UserID,
+		MonitorID:              monitor.ID,
+		LastHeartbeatTimestamp: lastTimestamp,
+		Timestamp:              time.Now().UTC(),
+		Owner:                  monitor.Owner,
+		Threshold:              threshold,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event when phone monitor [%s] is alive", monitor.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for heartbeat monitor with phone id [%s]", event.Type(), monitor.PhoneID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("heartbeat monitor with id [%s] and phone id [%s] is alive for user [%s]", monitor.ID, monitor.PhoneID, monitor.UserID))
 	return nil
 }
 
@@ -302,7 +679,7 @@ func (service *HeartbeatService) scheduleHeartbeatCheck(ctx context.Context, las
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
 	defer span.End()
 
-	event, err := service.createPhoneHeartbeatCheckEvent(params.Source, &events.PhoneHeartbeatCheckPayload{
+	event, err := service.createPhoneHeartbeatCheckEvent(ctx, params.Source, &events.PhoneHeartbeatCheckPayload{
 		PhoneID:     params.PhoneID,
 		UserID:      params.UserID,
 		MonitorID:   params.MonitorID,
@@ -330,14 +707,22 @@ func (service *HeartbeatService) scheduleHeartbeatCheck(ctx context.Context, las
 	return nil
 }
 
-func (service *HeartbeatService) createPhoneHeartbeatMissedEvent(source string, payload *events.PhoneHeartbeatMissedPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.PhoneHeartbeatMissed, source, payload)
+func (service *HeartbeatService) createPhoneHeartbeatMissedEvent(ctx context.Context, source string, payload *events.PhoneHeartbeatMissedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.PhoneHeartbeatMissed, source, payload)
+}
+
+func (service *HeartbeatService) createPhoneHeartbeatDeadEvent(ctx context.Context, source string, payload *events.PhoneHeartbeatDeadPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneHeartbeatDead, source, payload)
+}
+
+func (service *HeartbeatService) createPhoneHeartbeatAliveEvent(ctx context.Context, source string, payload *events.PhoneHeartbeatAlivePayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneHeartbeatAlive, source, payload)
 }
 
-func (service *HeartbeatService) createPhoneHeartbeatDeadEvent(source string, payload *events.PhoneHeartbeatDeadPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypePhoneHeartbeatDead, source, payload)
+func (service *HeartbeatService) createPhoneHeartbeatUnseenEvent(ctx context.Context, source string, payload *events.PhoneHeartbeatUnseenPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneHeartbeatUnseen, source, payload)
 }
 
-func (service *HeartbeatService) createPhoneHeartbeatCheckEvent(source string, payload *events.PhoneHeartbeatCheckPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypePhoneHeartbeatCheck, source, payload)
+func (service *HeartbeatService) createPhoneHeartbeatCheckEvent(ctx context.Context, source string, payload *events.PhoneHeartbeatCheckPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneHeartbeatCheck, source, payload)
 }
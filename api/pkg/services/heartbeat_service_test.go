@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubHeartbeatRepository returns a fixed last entities.Heartbeat
+type stubHeartbeatRepository struct {
+	repositories.HeartbeatRepository
+	last entities.Heartbeat
+}
+
+func (repository *stubHeartbeatRepository) Last(_ context.Context, _ entities.UserID, _ string) (*entities.Heartbeat, error) {
+	heartbeat := repository.last
+	return &heartbeat, nil
+}
+
+// stubHeartbeatMonitorRepository stores a single entities.HeartbeatMonitor and changes its liveness with a compare-and-set
+type stubHeartbeatMonitorRepository struct {
+	repositories.HeartbeatMonitorRepository
+	mutex   sync.Mutex
+	monitor entities.HeartbeatMonitor
+}
+
+func (repository *stubHeartbeatMonitorRepository) UpdatePhoneOnline(_ context.Context, _ uuid.UUID, phoneOnline bool) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if repository.monitor.PhoneOnline == phoneOnline {
+		return false, nil
+	}
+	repository.monitor.PhoneOnline = phoneOnline
+	return true, nil
+}
+
+func (repository *stubHeartbeatMonitorRepository) UpdateConsecutiveMisses(_ context.Context, _ uuid.UUID, misses uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.monitor.ConsecutiveMisses = misses
+	return nil
+}
+
+func (repository *stubHeartbeatMonitorRepository) UpdateLastAlertedAt(_ context.Context, _ uuid.UUID, timestamp time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.monitor.LastAlertedAt = &timestamp
+	return nil
+}
+
+func TestHeartbeatService_CheckMonitor(t *testing.T) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	t.Run("concurrent checks declare a phone offline once", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		monitor := entities.HeartbeatMonitor{
+			ID:                uuid.New(),
+			PhoneID:           uuid.New(),
+			UserID:            "user-a",
+			Owner:             "+18005550199",
+			PhoneOnline:       true,
+			ConsecutiveMisses: heartbeatDeadConsecutiveMisses - 1,
+			CreatedAt:         time.Now().UTC().Add(-24 * time.Hour),
+		}
+		monitorRepository := &stubHeartbeatMonitorRepository{monitor: monitor}
+		repository := &stubHeartbeatRepository{last: entities.Heartbeat{UserID: "user-a", Owner: monitor.Owner, Timestamp: time.Now().UTC().Add(-2 * heartbeatDeadThreshold)}}
+		queue := &capturingPushQueue{}
+		service := NewHeartbeatService(
+			logger,
+			tracer,
+			repository,
+			monitorRepository,
+			newTestQueueEventDispatcher(queue, memory.NewEventRepository()),
+			NewPhoneService(logger, tracer, &stubPhoneRepository{}, nil, 0, PhoneClockConfig{}),
+			0,
+			0,
+			0,
+		)
+
+		// Act
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(monitor entities.HeartbeatMonitor) {
+				defer wg.Done()
+				_, err := service.checkMonitor(context.Background(), heartbeatCheckAllSource, &monitor)
+				assert.Nil(t, err)
+			}(monitor)
+		}
+		wg.Wait()
+
+		// Assert
+		assert.False(t, monitorRepository.monitor.PhoneOnline)
+		assert.Len(t, queue.tasks, 1)
+	})
+}
@@ -237,7 +237,7 @@ func (service *Integration3CXService) getMessageReceivedPayload(event cloudevent
 				},
 				"id":          payload.MessageID,
 				"received_at": payload.Timestamp,
-				"text":        payload.Content,
+				"text":        displayContent(payload.Content, payload.IsEncrypted),
 				"to": []fiber.Map{
 					{
 						"phone_number": payload.Owner,
@@ -53,7 +53,7 @@ func (service *LemonsqueezyService) HandleSubscriptionCreatedEvent(ctx context.C
 		SubscriptionStatus:    request.Data.Attributes.Status,
 	}
 
-	event, err := service.createEvent(events.UserSubscriptionCreated, source, payload)
+	event, err := service.createEvent(ctx, events.UserSubscriptionCreated, source, payload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot create [%s] event for user [%s]", events.UserSubscriptionCreated, payload.UserID)
 		return stacktrace.Propagate(err, msg)
@@ -87,7 +87,7 @@ func (service *LemonsqueezyService) HandleSubscriptionCanceledEvent(ctx context.
 		SubscriptionStatus:      request.Data.Attributes.Status,
 	}
 
-	event, err := service.createEvent(events.UserSubscriptionCancelled, source, payload)
+	event, err := service.createEvent(ctx, events.UserSubscriptionCancelled, source, payload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot created [%s] event for user [%s]", events.UserSubscriptionCancelled, payload.UserID)
 		return stacktrace.Propagate(err, msg)
@@ -122,7 +122,7 @@ func (service *LemonsqueezyService) HandleSubscriptionUpdatedEvent(ctx context.C
 		SubscriptionStatus:    request.Data.Attributes.Status,
 	}
 
-	event, err := service.createEvent(events.UserSubscriptionUpdated, source, payload)
+	event, err := service.createEvent(ctx, events.UserSubscriptionUpdated, source, payload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot created [%s] event for user [%s]", events.UserSubscriptionUpdated, payload.UserID)
 		return stacktrace.Propagate(err, msg)
@@ -157,7 +157,7 @@ func (service *LemonsqueezyService) HandleSubscriptionExpiredEvent(ctx context.C
 		SubscriptionStatus:    request.Data.Attributes.Status,
 	}
 
-	event, err := service.createEvent(events.UserSubscriptionExpired, source, payload)
+	event, err := service.createEvent(ctx, events.UserSubscriptionExpired, source, payload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot created [%s] event for user [%s]", events.UserSubscriptionExpired, payload.UserID)
 		return stacktrace.Propagate(err, msg)
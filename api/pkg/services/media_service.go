@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/storage"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// mediaUploadURLExpiry is the duration in which the content of a registered entities.Media must be uploaded
+	mediaUploadURLExpiry = 15 * time.Minute
+	// mediaDownloadURLExpiry is the duration in which the phone must download the entities.Media of a message
+	mediaDownloadURLExpiry = 15 * time.Minute
+	// mediaDeleteBatchSize is the number of entities.Media which are garbage collected in one batch
+	mediaDeleteBatchSize = 100
+)
+
+// MediaService registers the media which is uploaded with signed URLs and attached to outgoing MMS messages
+type MediaService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.MediaRepository
+	// blobStore is optional, media cannot be registered when it is nil
+	blobStore storage.BlobStore
+	maxSize   int64
+	ttl       time.Duration
+}
+
+// NewMediaService creates a new MediaService, media larger than maxSize is rejected and media which is not attached to
+// a message within the ttl is deleted
+func NewMediaService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.MediaRepository,
+	blobStore storage.BlobStore,
+	maxSize int64,
+	ttl time.Duration,
+) (s *MediaService) {
+	return &MediaService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+		blobStore:  blobStore,
+		maxSize:    maxSize,
+		ttl:        ttl,
+	}
+}
+
+// MediaRegisterParams are the parameters for registering an entities.Media
+type MediaRegisterParams struct {
+	UserID      entities.UserID
+	ContentType string
+	Size        int64
+}
+
+// Register stores a new entities.Media and returns it with a signed URL which uploads the content to the storage.BlobStore
+func (service *MediaService) Register(ctx context.Context, params *MediaRegisterParams) (*entities.Media, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if service.blobStore == nil {
+		msg := fmt.Sprintf("cannot register media for user [%s] because the blob store is not configured", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeMediaUnavailable, msg))
+	}
+
+	if !entities.IsMediaContentType(params.ContentType) {
+		msg := fmt.Sprintf("cannot register media with content type [%s] for user [%s]", params.ContentType, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("content_type", fmt.Sprintf("The content_type [%s] cannot be sent in an MMS message", params.ContentType)), msg))
+	}
+
+	if params.Size > service.maxSize {
+		msg := fmt.Sprintf("cannot register media with [%d] bytes for user [%s]", params.Size, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("size", fmt.Sprintf("The size may not be greater than %d bytes", service.maxSize)), msg))
+	}
+
+	mediaID := uuid.New()
+	media := &entities.Media{
+		ID:          mediaID,
+		UserID:      params.UserID,
+		ContentType: params.ContentType,
+		Size:        params.Size,
+		Path:        fmt.Sprintf("media/%s/%s", params.UserID, mediaID),
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+
+	expiresAt := time.Now().UTC().Add(mediaUploadURLExpiry)
+	uploadURL, err := service.blobStore.SignedURL(ctx, media.Path, storage.SignedURLOptions{
+		Method:      http.MethodPut,
+		ContentType: media.ContentType,
+		Expires:     expiresAt,
+	})
+	if stacktrace.GetCode(err) == storage.ErrCodeSignedURLUnsupported {
+		msg := fmt.Sprintf("cannot register media for user [%s] because the blob store cannot sign URLs", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeMediaUnavailable, msg))
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot create the upload URL of media [%s] for user [%s]", media.ID, media.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.repository.Store(ctx, media); err != nil {
+		msg := fmt.Sprintf("cannot store media [%s] for user [%s]", media.ID, media.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	media.UploadURL = &uploadURL
+	media.URLExpiresAt = &expiresAt
+
+	ctxLogger.Info(fmt.Sprintf("registered [%s] media [%s] with [%d] bytes for user [%s]", media.ContentType, media.ID, media.Size, media.UserID))
+	return media, nil
+}
+
+// Load fetches the entities.Media with the IDs which can be attached to a message. The media must not be attached to
+// another message or expired, and its uploaded content must match the size and the content type which were registered.
+func (service *MediaService) Load(ctx context.Context, userID entities.UserID, mediaIDs []uuid.UUID) ([]*entities.Media, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	if service.blobStore == nil {
+		msg := fmt.Sprintf("cannot load [%d] media for user [%s] because the blob store is not configured", len(mediaIDs), userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeMediaUnavailable, msg))
+	}
+
+	result := make([]*entities.Media, 0, len(mediaIDs))
+	for _, mediaID := range mediaIDs {
+		msg := fmt.Sprintf("cannot load media [%s] for user [%s]", mediaID, userID)
+		media, err := service.repository.Load(ctx, userID, mediaID)
+		if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("media_ids", fmt.Sprintf("The media [%s] does not exist", mediaID)), msg))
+		}
+		if err != nil {
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		if media.MessageID != nil {
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("media_ids", fmt.Sprintf("The media [%s] is already attached to another message", mediaID)), msg))
+		}
+
+		if media.CreatedAt.Add(service.ttl).Before(time.Now().UTC()) {
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("media_ids", fmt.Sprintf("The media [%s] has expired, register and upload it again", mediaID)), msg))
+		}
+
+		if err = service.checkUpload(ctx, media); err != nil {
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		result = append(result, media)
+	}
+
+	return result, nil
+}
+
+// checkUpload makes sure the content of an entities.Media was uploaded with the size and the content type which were registered
+func (service *MediaService) checkUpload(ctx context.Context, media *entities.Media) error {
+	attributes, err := service.blobStore.Attributes(ctx, media.Path)
+	if stacktrace.GetCode(err) == storage.ErrCodeBlobNotFound {
+		return NewValidationError("media_ids", fmt.Sprintf("The content of the media [%s] has not been uploaded", media.ID))
+	}
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot read the attributes of media [%s]", media.ID))
+	}
+
+	if attributes.Size > media.Size {
+		return NewValidationError("media_ids", fmt.Sprintf("The content of the media [%s] has %d bytes which is greater than the registered size of %d bytes", media.ID, attributes.Size, media.Size))
+	}
+
+	if attributes.ContentType != media.ContentType {
+		return NewValidationError("media_ids", fmt.Sprintf("The content of the media [%s] is [%s] instead of the registered content type [%s]", media.ID, attributes.ContentType, media.ContentType))
+	}
+
+	return nil
+}
+
+// Attach attaches the entities.Media to a message so that they are not garbage collected
+func (service *MediaService) Attach(ctx context.Context, messageID uuid.UUID, media []*entities.Media) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	if len(media) == 0 {
+		return nil
+	}
+
+	mediaIDs := make([]uuid.UUID, 0, len(media))
+	for _, item := range media {
+		mediaIDs = append(mediaIDs, item.ID)
+	}
+
+	if err := service.repository.Attach(ctx, media[0].UserID, messageID, mediaIDs); err != nil {
+		msg := fmt.Sprintf("cannot attach [%d] media to message [%s] for user [%s]", len(media), messageID, media[0].UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	for _, item := range media {
+		item.MessageID = &messageID
+	}
+	return nil
+}
+
+// IndexByMessages fetches the entities.Media of the messages which have media and sets them on the messages
+func (service *MediaService) IndexByMessages(ctx context.Context, userID entities.UserID, messages []*entities.Message) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	messageIDs := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		if message.HasMedia() {
+			messageIDs = append(messageIDs, message.ID)
+		}
+	}
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	media, err := service.repository.FetchByMessages(ctx, userID, messageIDs)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the media of [%d] messages for user [%s]", len(messageIDs), userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	byMessage := map[uuid.UUID][]*entities.Media{}
+	for _, item := range media {
+		byMessage[*item.MessageID] = append(byMessage[*item.MessageID], item)
+	}
+
+	for _, message := range messages {
+		message.Media = byMessage[message.ID]
+	}
+	return nil
+}
+
+// DownloadURLs fetches the entities.Media of a message with the signed URLs which the phone uses to download them
+func (service *MediaService) DownloadURLs(ctx context.Context, message *entities.Message) ([]*entities.Media, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	if service.blobStore == nil {
+		msg := fmt.Sprintf("cannot sign the media of message [%s] because the blob store is not configured", message.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeMediaUnavailable, msg))
+	}
+
+	media, err := service.repository.FetchByMessages(ctx, message.UserID, []uuid.UUID{message.ID})
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the media of message [%s] for user [%s]", message.ID, message.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	expiresAt := time.Now().UTC().Add(mediaDownloadURLExpiry)
+	for _, item := range media {
+		url, err := service.blobStore.SignedURL(ctx, item.Path, storage.SignedURLOptions{Method: http.MethodGet, Expires: expiresAt})
+		if err != nil {
+			msg := fmt.Sprintf("cannot create the download URL of media [%s] for message [%s]", item.ID, message.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		item.DownloadURL = &url
+		item.URLExpiresAt = &expiresAt
+	}
+
+	return media, nil
+}
+
+// DeleteUnattached deletes the entities.Media and their content when they are not attached to a message within the ttl
+func (service *MediaService) DeleteUnattached(ctx context.Context) (int, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if service.blobStore == nil {
+		ctxLogger.Info("blob store is not configured, no media deleted")
+		return 0, nil
+	}
+
+	before := time.Now().UTC().Add(-1 * service.ttl)
+
+	total := 0
+	for {
+		media, err := service.repository.FetchUnattached(ctx, before, mediaDeleteBatchSize)
+		if err != nil {
+			msg := fmt.Sprintf("cannot fetch the media created before [%s] after deleting [%d] media", before, total)
+			return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		mediaIDs := make([]uuid.UUID, 0, len(media))
+		for _, item := range media {
+			if err = service.blobStore.Delete(ctx, item.Path); err != nil {
+				msg := fmt.Sprintf("cannot delete the content of media [%s] after deleting [%d] media", item.ID, total)
+				return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+			}
+			mediaIDs = append(mediaIDs, item.ID)
+		}
+
+		if err = service.repository.Delete(ctx, mediaIDs); err != nil {
+			msg := fmt.Sprintf("cannot delete [%d] media after deleting [%d] media", len(mediaIDs), total)
+			return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		total += len(mediaIDs)
+		if len(media) < mediaDeleteBatchSize {
+			break
+		}
+	}
+
+	ctxLogger.WithField(telemetry.LogFieldCount, total).Info(fmt.Sprintf("deleted media created before [%s] which was not attached to a message", before))
+	return total, nil
+}
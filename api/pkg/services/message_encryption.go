@@ -0,0 +1,59 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/palantir/stacktrace"
+)
+
+// encryptedContentPlaceholder replaces the content of an end-to-end encrypted message in the notifications of the
+// integrations e.g. slack and telegram which cannot decrypt it
+const encryptedContentPlaceholder = "(encrypted message)"
+
+// EncryptionMismatchError is returned when a message is sent from a phone whose end-to-end encryption mode does not
+// match the encryption of the message
+type EncryptionMismatchError struct {
+	Owner string
+	// EncryptionEnabled is the end-to-end encryption mode of the phone
+	EncryptionEnabled bool
+}
+
+// Error returns the error message of the EncryptionMismatchError
+func (err *EncryptionMismatchError) Error() string {
+	if err.EncryptionEnabled {
+		return fmt.Sprintf("the phone [%s] only sends messages whose content is end-to-end encrypted", err.Owner)
+	}
+	return fmt.Sprintf("the phone [%s] cannot decrypt messages whose content is end-to-end encrypted", err.Owner)
+}
+
+// AsEncryptionMismatchError returns the EncryptionMismatchError which caused the error
+func AsEncryptionMismatchError(err error) (*EncryptionMismatchError, bool) {
+	mismatchErr, ok := stacktrace.RootCause(err).(*EncryptionMismatchError)
+	return mismatchErr, ok
+}
+
+// EncryptedContentError is returned when a feature which needs the plaintext content of a message is used with a
+// message whose content is end-to-end encrypted
+type EncryptedContentError struct {
+	Feature string
+}
+
+// Error returns the error message of the EncryptedContentError
+func (err *EncryptedContentError) Error() string {
+	return fmt.Sprintf("[%s] cannot be used because the content of the message is end-to-end encrypted", err.Feature)
+}
+
+// AsEncryptedContentError returns the EncryptedContentError which caused the error
+func AsEncryptedContentError(err error) (*EncryptedContentError, bool) {
+	contentErr, ok := stacktrace.RootCause(err).(*EncryptedContentError)
+	return contentErr, ok
+}
+
+// displayContent returns the content of a message which is shown by an integration, the ciphertext of an encrypted
+// message is replaced because the integration cannot decrypt it
+func displayContent(content string, isEncrypted bool) string {
+	if isEncrypted {
+		return encryptedContentPlaceholder
+	}
+	return content
+}
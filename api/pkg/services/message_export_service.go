@@ -0,0 +1,329 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/storage"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// messageExportBatchSize is the number of archived entities.Message in one exported object
+	messageExportBatchSize = 5000
+
+	// messageExportMaxBatches limits the number of objects which are exported by one run of MessageExportService.Export
+	messageExportMaxBatches = 100
+
+	// messagePurgeBatchSize is the number of exported entities.Message which are deleted in one query
+	messagePurgeBatchSize = 500
+)
+
+// MessageExportService exports archived entities.Message to a storage.BlobStore and purges them from the database
+type MessageExportService struct {
+	service
+	logger            telemetry.Logger
+	tracer            telemetry.Tracer
+	blobStore         storage.BlobStore
+	messageRepository repositories.MessageRepository
+	exportRepository  repositories.MessageExportRepository
+	exportAfter       time.Duration
+}
+
+// NewMessageExportService creates a new MessageExportService
+func NewMessageExportService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	blobStore storage.BlobStore,
+	messageRepository repositories.MessageRepository,
+	exportRepository repositories.MessageExportRepository,
+	exportAfter time.Duration,
+) (s *MessageExportService) {
+	return &MessageExportService{
+		logger:            logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:            tracer,
+		blobStore:         blobStore,
+		messageRepository: messageRepository,
+		exportRepository:  exportRepository,
+		exportAfter:       exportAfter,
+	}
+}
+
+// Export writes the archived entities.Message which were ordered before the export period to the storage.BlobStore
+// and deletes them from the database. The exports which were interrupted by a previous run are completed first and
+// messages are only deleted after the exported object has been read back and verified.
+func (service *MessageExportService) Export(ctx context.Context) (int, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if service.exportAfter <= 0 {
+		ctxLogger.Info("message export period is not configured, no messages exported")
+		return 0, nil
+	}
+
+	if err := service.resume(ctx); err != nil {
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot resume the incomplete message exports"))
+	}
+
+	before := time.Now().UTC().Add(-1 * service.exportAfter)
+
+	total := 0
+	for batch := 0; batch < messageExportMaxBatches; batch++ {
+		// the messages of the previous batch are purged from the primary and may still be in the replica
+		messages, err := service.messageRepository.IndexArchived(repositories.WithPrimary(ctx), before, messageExportBatchSize)
+		if err != nil {
+			msg := fmt.Sprintf("cannot fetch archived messages before [%s]", before)
+			return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		if len(messages) == 0 {
+			break
+		}
+
+		if err = service.export(ctx, messages); err != nil {
+			msg := fmt.Sprintf("cannot export [%d] archived messages before [%s]", len(messages), before)
+			return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		total += len(messages)
+		if len(messages) < messageExportBatchSize {
+			break
+		}
+	}
+
+	ctxLogger.Info(fmt.Sprintf("exported [%d] archived messages ordered before [%s]", total, before))
+	return total, nil
+}
+
+// Load fetches an exported entities.Message by ID from the storage.BlobStore using the entities.MessageExport manifests
+func (service *MessageExportService) Load(ctx context.Context, messageID uuid.UUID) (*entities.Message, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	exports, err := service.exportRepository.IndexByMessageID(ctx, messageID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the exports containing message with ID [%s]", messageID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, export := range exports {
+		messages, err := service.read(ctx, &export)
+		if err != nil {
+			msg := fmt.Sprintf("cannot read export [%s] at [%s]", export.ID, export.ObjectPath)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		for index := range messages {
+			if messages[index].ID == messageID {
+				return &messages[index], nil
+			}
+		}
+	}
+
+	msg := fmt.Sprintf("exported message with ID [%s] does not exist", messageID)
+	return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg))
+}
+
+// resume completes the exports which were interrupted. A pending export whose object cannot be verified is deleted
+// because its messages are still in the database and they are exported again.
+func (service *MessageExportService) resume(ctx context.Context) error {
+	ctxLogger := service.tracer.CtxLogger(service.logger, service.tracer.Span(ctx))
+
+	exports, err := service.exportRepository.IndexIncomplete(ctx)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot fetch the incomplete message exports")
+	}
+
+	for _, export := range exports {
+		export := export
+		messageIDs, err := service.verify(ctx, &export)
+		if err != nil && export.Status == entities.MessageExportStatusPending {
+			ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("discarding pending export [%s] which cannot be verified", export.ID)))
+			if err = service.exportRepository.Delete(ctx, export.ID); err != nil {
+				return stacktrace.Propagate(err, fmt.Sprintf("cannot delete pending export [%s]", export.ID))
+			}
+			continue
+		}
+
+		if err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot verify export [%s] at [%s]", export.ID, export.ObjectPath))
+		}
+
+		ctxLogger.Info(fmt.Sprintf("resuming export [%s] with status [%s]", export.ID, export.Status))
+		if err = service.purge(ctx, &export, messageIDs); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot purge the messages of export [%s]", export.ID))
+		}
+	}
+
+	return nil
+}
+
+// export writes the messages to an object, verifies the object and purges the messages
+func (service *MessageExportService) export(ctx context.Context, messages []entities.Message) error {
+	content, err := service.encode(messages)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot encode [%d] messages", len(messages)))
+	}
+
+	exportID := uuid.New()
+	export := &entities.MessageExport{
+		ID:             exportID,
+		ObjectPath:     fmt.Sprintf("messages/%s/%s.ndjson.gz", time.Now().UTC().Format("2006/01/02"), exportID),
+		FirstMessageID: messages[0].ID,
+		LastMessageID:  messages[len(messages)-1].ID,
+		Count:          len(messages),
+		Checksum:       service.checksum(content),
+		Status:         entities.MessageExportStatusPending,
+		CreatedAt:      time.Now().UTC(),
+		UpdatedAt:      time.Now().UTC(),
+	}
+
+	// the manifest is stored before the object is written so that an interrupted export is resumed
+	if err = service.exportRepository.Store(ctx, export); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot store export [%s]", export.ID))
+	}
+
+	if err = service.blobStore.Put(ctx, export.ObjectPath, content); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot write export [%s] to [%s]", export.ID, export.ObjectPath))
+	}
+
+	messageIDs, err := service.verify(ctx, export)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot verify export [%s] at [%s]", export.ID, export.ObjectPath))
+	}
+
+	return service.purge(ctx, export, messageIDs)
+}
+
+// verify reads the object of an export back from the storage.BlobStore and checks the checksum, the number of
+// messages and the ID range. It returns the IDs of the messages in the object which can be purged.
+func (service *MessageExportService) verify(ctx context.Context, export *entities.MessageExport) ([]uuid.UUID, error) {
+	messages, err := service.read(ctx, export)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot read export [%s]", export.ID))
+	}
+
+	if len(messages) != export.Count {
+		return nil, stacktrace.NewError(fmt.Sprintf("export [%s] has [%d] messages but [%d] were exported", export.ID, len(messages), export.Count))
+	}
+
+	messageIDs := make([]uuid.UUID, 0, len(messages))
+	for _, message := range messages {
+		if !export.Contains(message.ID) {
+			return nil, stacktrace.NewError(fmt.Sprintf("message [%s] is not in the ID range of export [%s]", message.ID, export.ID))
+		}
+		messageIDs = append(messageIDs, message.ID)
+	}
+
+	if export.Status == entities.MessageExportStatusPending {
+		export.Status = entities.MessageExportStatusVerified
+		export.UpdatedAt = time.Now().UTC()
+		if err = service.exportRepository.Update(ctx, export); err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot mark export [%s] as verified", export.ID))
+		}
+	}
+
+	return messageIDs, nil
+}
+
+// purge deletes the verified messages of an export from the database in batches
+func (service *MessageExportService) purge(ctx context.Context, export *entities.MessageExport, messageIDs []uuid.UUID) error {
+	if export.Status != entities.MessageExportStatusVerified {
+		return stacktrace.NewError(fmt.Sprintf("export [%s] with status [%s] cannot be purged", export.ID, export.Status))
+	}
+
+	for start := 0; start < len(messageIDs); start += messagePurgeBatchSize {
+		end := start + messagePurgeBatchSize
+		if end > len(messageIDs) {
+			end = len(messageIDs)
+		}
+
+		if _, err := service.messageRepository.PurgeArchived(ctx, messageIDs[start:end]); err != nil {
+			return stacktrace.Propagate(err, fmt.Sprintf("cannot purge [%d] messages of export [%s]", end-start, export.ID))
+		}
+	}
+
+	export.Status = entities.MessageExportStatusPurged
+	export.UpdatedAt = time.Now().UTC()
+	if err := service.exportRepository.Update(ctx, export); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot mark export [%s] as purged", export.ID))
+	}
+
+	return nil
+}
+
+// read fetches the object of an export and decodes the messages after checking the checksum
+func (service *MessageExportService) read(ctx context.Context, export *entities.MessageExport) ([]entities.Message, error) {
+	content, err := service.blobStore.Get(ctx, export.ObjectPath)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot get object [%s]", export.ObjectPath))
+	}
+
+	if service.checksum(content) != export.Checksum {
+		return nil, stacktrace.NewError(fmt.Sprintf("checksum of object [%s] does not match [%s]", export.ObjectPath, export.Checksum))
+	}
+
+	return service.decode(content)
+}
+
+// checksum is the hex encoded SHA-256 hash of the content of an object
+func (service *MessageExportService) checksum(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// encode writes messages as gzip compressed NDJSON
+func (service *MessageExportService) encode(messages []entities.Message) ([]byte, error) {
+	buffer := new(bytes.Buffer)
+	writer := gzip.NewWriter(buffer)
+
+	encoder := json.NewEncoder(writer)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot encode message [%s]", message.ID))
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, stacktrace.Propagate(err, "cannot close gzip writer")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// decode reads messages from gzip compressed NDJSON
+func (service *MessageExportService) decode(content []byte) ([]entities.Message, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot create gzip reader")
+	}
+	defer func() { _ = reader.Close() }()
+
+	var messages []entities.Message
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		message := entities.Message{}
+		if err = json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot decode message on line [%d]", len(messages)+1))
+		}
+		messages = append(messages, message)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, stacktrace.Propagate(err, "cannot read NDJSON")
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/storage"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestArchiveRepository creates an in-memory repositories.MessageRepository with count archived entities.Message
+func newTestArchiveRepository(t *testing.T, count int, orderTimestamp time.Time) repositories.MessageRepository {
+	repository := memory.NewMessageRepository()
+	for i := 0; i < count; i++ {
+		assert.Nil(t, repository.Store(context.Background(), &entities.Message{
+			ID:             uuid.New(),
+			UserID:         "user-id",
+			Content:        fmt.Sprintf("message %d", i),
+			Status:         entities.MessageStatusDelivered,
+			OrderTimestamp: orderTimestamp,
+		}))
+	}
+
+	archived, err := repository.Archive(context.Background(), time.Now().UTC(), count)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(count), archived)
+	return repository
+}
+
+// countArchived counts the archived entities.Message in a repositories.MessageRepository
+func countArchived(t *testing.T, repository repositories.MessageRepository) int {
+	messages, err := repository.IndexArchived(context.Background(), time.Now().UTC(), -1)
+	assert.Nil(t, err)
+	return len(messages)
+}
+
+// stubMessageExportRepository stores entities.MessageExport in memory
+type stubMessageExportRepository struct {
+	mutex   sync.Mutex
+	exports []entities.MessageExport
+}
+
+func (repository *stubMessageExportRepository) Store(_ context.Context, export *entities.MessageExport) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.exports = append(repository.exports, *export)
+	return nil
+}
+
+func (repository *stubMessageExportRepository) Update(_ context.Context, export *entities.MessageExport) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for index := range repository.exports {
+		if repository.exports[index].ID == export.ID {
+			repository.exports[index] = *export
+		}
+	}
+	return nil
+}
+
+func (repository *stubMessageExportRepository) Delete(_ context.Context, exportID uuid.UUID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	exports := repository.exports[:0]
+	for _, export := range repository.exports {
+		if export.ID != exportID {
+			exports = append(exports, export)
+		}
+	}
+	repository.exports = exports
+	return nil
+}
+
+func (repository *stubMessageExportRepository) IndexIncomplete(_ context.Context) ([]entities.MessageExport, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var exports []entities.MessageExport
+	for _, export := range repository.exports {
+		if export.Status != entities.MessageExportStatusPurged {
+			exports = append(exports, export)
+		}
+	}
+	return exports, nil
+}
+
+func (repository *stubMessageExportRepository) IndexByMessageID(_ context.Context, messageID uuid.UUID) ([]entities.MessageExport, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var exports []entities.MessageExport
+	for _, export := range repository.exports {
+		if export.Status != entities.MessageExportStatusPending && export.Contains(messageID) {
+			exports = append(exports, export)
+		}
+	}
+	return exports, nil
+}
+
+func (repository *stubMessageExportRepository) statuses() []entities.MessageExportStatus {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var statuses []entities.MessageExportStatus
+	for _, export := range repository.exports {
+		statuses = append(statuses, export.Status)
+	}
+	return statuses
+}
+
+// corruptBlobStore writes a truncated object to simulate an upload which was not completed
+type corruptBlobStore struct {
+	storage.BlobStore
+}
+
+func (store *corruptBlobStore) Put(ctx context.Context, path string, content []byte) error {
+	return store.BlobStore.Put(ctx, path, content[:len(content)/2])
+}
+
+// failingBlobStore fails every write
+type failingBlobStore struct {
+	storage.BlobStore
+}
+
+func (store *failingBlobStore) Put(_ context.Context, path string, _ []byte) error {
+	return stacktrace.NewError(fmt.Sprintf("connection reset while writing [%s]", path))
+}
+
+func newTestMessageExportService(blobStore storage.BlobStore, messageRepository repositories.MessageRepository, exportRepository repositories.MessageExportRepository) *MessageExportService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewMessageExportService(logger, tracer, blobStore, messageRepository, exportRepository, 24*time.Hour)
+}
+
+func newTestBlobStore(t *testing.T) storage.BlobStore {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	return storage.NewFileBlobStore(telemetry.NewOtelLogger("test", logger), t.TempDir())
+}
+
+func TestMessageExportService_Export(t *testing.T) {
+	t.Run("verified messages are purged and can be loaded from the export", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		messageRepository := newTestArchiveRepository(t, messageExportBatchSize+10, time.Now().UTC().Add(-48*time.Hour))
+		exportRepository := &stubMessageExportRepository{}
+		service := newTestMessageExportService(newTestBlobStore(t), messageRepository, exportRepository)
+
+		messages, _ := messageRepository.IndexArchived(context.Background(), time.Now().UTC(), 1)
+
+		// Act
+		count, err := service.Export(context.Background())
+		loaded, loadErr := service.Load(context.Background(), messages[0].ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, messageExportBatchSize+10, count)
+		assert.Equal(t, 0, countArchived(t, messageRepository))
+		assert.Equal(t, []entities.MessageExportStatus{entities.MessageExportStatusPurged, entities.MessageExportStatusPurged}, exportRepository.statuses())
+
+		assert.Nil(t, loadErr)
+		assert.Equal(t, messages[0].Content, loaded.Content)
+	})
+
+	t.Run("messages which are not older than the export period are kept", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		messageRepository := newTestArchiveRepository(t, 10, time.Now().UTC().Add(-1*time.Hour))
+		exportRepository := &stubMessageExportRepository{}
+		service := newTestMessageExportService(newTestBlobStore(t), messageRepository, exportRepository)
+
+		// Act
+		count, err := service.Export(context.Background())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, count)
+		assert.Equal(t, 10, countArchived(t, messageRepository))
+		assert.Empty(t, exportRepository.statuses())
+	})
+
+	t.Run("messages are not purged when the object cannot be verified", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		messageRepository := newTestArchiveRepository(t, 10, time.Now().UTC().Add(-48*time.Hour))
+		exportRepository := &stubMessageExportRepository{}
+		service := newTestMessageExportService(&corruptBlobStore{newTestBlobStore(t)}, messageRepository, exportRepository)
+
+		// Act
+		_, err := service.Export(context.Background())
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, 10, countArchived(t, messageRepository))
+		assert.Equal(t, []entities.MessageExportStatus{entities.MessageExportStatusPending}, exportRepository.statuses())
+	})
+
+	t.Run("an interrupted export is resumed by the next run", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		blobStore := newTestBlobStore(t)
+		messageRepository := newTestArchiveRepository(t, 10, time.Now().UTC().Add(-48*time.Hour))
+		exportRepository := &stubMessageExportRepository{}
+
+		_, firstErr := newTestMessageExportService(&failingBlobStore{blobStore}, messageRepository, exportRepository).Export(context.Background())
+		firstStatuses := exportRepository.statuses()
+
+		// Act
+		count, err := newTestMessageExportService(blobStore, messageRepository, exportRepository).Export(context.Background())
+
+		// Assert
+		assert.NotNil(t, firstErr)
+		assert.Equal(t, []entities.MessageExportStatus{entities.MessageExportStatusPending}, firstStatuses)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 10, count)
+		assert.Equal(t, 0, countArchived(t, messageRepository))
+		assert.Equal(t, []entities.MessageExportStatus{entities.MessageExportStatusPurged}, exportRepository.statuses())
+	})
+
+	t.Run("a verified export is purged by the next run", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		blobStore := newTestBlobStore(t)
+		messageRepository := newTestArchiveRepository(t, 10, time.Now().UTC().Add(-48*time.Hour))
+		exportRepository := &stubMessageExportRepository{}
+		service := newTestMessageExportService(blobStore, messageRepository, exportRepository)
+
+		messages, _ := messageRepository.IndexArchived(context.Background(), time.Now().UTC(), 10)
+		content, err := service.encode(messages)
+		assert.Nil(t, err)
+		export := &entities.MessageExport{
+			ID:             uuid.New(),
+			ObjectPath:     "messages/verified.ndjson.gz",
+			FirstMessageID: messages[0].ID,
+			LastMessageID:  messages[len(messages)-1].ID,
+			Count:          len(messages),
+			Checksum:       service.checksum(content),
+			Status:         entities.MessageExportStatusVerified,
+		}
+		assert.Nil(t, blobStore.Put(context.Background(), export.ObjectPath, content))
+		assert.Nil(t, exportRepository.Store(context.Background(), export))
+
+		// Act
+		_, err = service.Export(context.Background())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, countArchived(t, messageRepository))
+		assert.Equal(t, []entities.MessageExportStatus{entities.MessageExportStatusPurged}, exportRepository.statuses())
+	})
+}
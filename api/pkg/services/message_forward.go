@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/palantir/stacktrace"
+)
+
+// MessageNotForwardableError is returned when a message cannot be forwarded because it was not received by the owner
+// phone number or its content cannot be read e.g. the content has expired
+type MessageNotForwardableError struct {
+	MessageID uuid.UUID
+	Reason    string
+}
+
+// Error returns the error message of the MessageNotForwardableError
+func (err *MessageNotForwardableError) Error() string {
+	return fmt.Sprintf("the message [%s] cannot be forwarded because %s", err.MessageID, err.Reason)
+}
+
+// AsMessageNotForwardableError returns the MessageNotForwardableError which caused the error
+func AsMessageNotForwardableError(err error) (*MessageNotForwardableError, bool) {
+	forwardErr, ok := stacktrace.RootCause(err).(*MessageNotForwardableError)
+	return forwardErr, ok
+}
+
+// MessageForwardParams are parameters for forwarding a received entities.Message to another contact
+type MessageForwardParams struct {
+	UserID    entities.UserID
+	Source    string
+	MessageID uuid.UUID
+	// Owner is the phone number which received the message and sends the forwarded message
+	Owner string
+	// Contact is the phone number which the message is forwarded to
+	Contact string
+	// Prefix is added before the content of the forwarded message e.g. "FWD from +18005550100:", it is optional
+	Prefix            string
+	RequestReceivedAt time.Time
+}
+
+// ForwardMessage sends the content of a received message to another contact from the phone which received it. The
+// new message is linked to the received message with entities.Message.ForwardedFromID and the received message is
+// linked to the new message with entities.Message.ForwardedToID. A deleted message or a message of another user is not found.
+func (service *MessageService) ForwardMessage(ctx context.Context, params MessageForwardParams) (*entities.Message, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	span.SetAttributes(telemetry.AttributeMessageID.String(params.MessageID.String()))
+
+	original, err := service.repository.Load(ctx, params.UserID, params.MessageID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message with ID [%s] for user [%s] to forward it", params.MessageID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.checkForward(original, params); err != nil {
+		msg := fmt.Sprintf("cannot forward message with ID [%s] for user [%s]", params.MessageID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	owner, err := phonenumbers.Parse(params.Owner, phonenumbers.UNKNOWN_REGION)
+	if err != nil {
+		msg := fmt.Sprintf("cannot parse the owner [%s] of message [%s]", telemetry.RedactPhoneNumber(params.Owner), params.MessageID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("from", "The from field must be a valid phone number"), msg))
+	}
+
+	message, err := service.SendMessage(ctx, MessageSendParams{
+		Owner:             owner,
+		Contact:           params.Contact,
+		Content:           original.ForwardContent(params.Prefix),
+		Source:            params.Source,
+		UserID:            params.UserID,
+		RequestReceivedAt: params.RequestReceivedAt,
+		ForwardedFromID:   &original.ID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot send the forward of message [%s] to [%s]", params.MessageID, telemetry.RedactPhoneNumber(params.Contact))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	// the forwarded message is already queued so it is not failed when the received message cannot be linked to it e.g. it is archived
+	_, err = service.updateMessage(ctx, original.UserID, original.ID, func(original *entities.Message) (bool, error) {
+		original.ForwardedToID = &message.ID
+		original.UpdatedAt = time.Now().UTC()
+		return true, nil
+	})
+	if err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot link message [%s] to its forward [%s]", original.ID, message.ID)))
+	}
+
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("forwarded message [%s]", original.ID))
+	return message, nil
+}
+
+// checkForward makes sure that the owner received the message and that its content can be forwarded
+func (service *MessageService) checkForward(message *entities.Message, params MessageForwardParams) error {
+	if message.Owner != params.Owner {
+		return &MessageNotForwardableError{MessageID: message.ID, Reason: fmt.Sprintf("it was not received by the phone [%s]", params.Owner)}
+	}
+
+	if message.IsEncrypted {
+		return &EncryptedContentError{Feature: "forward"}
+	}
+
+	if !message.CanBeForwarded() {
+		return &MessageNotForwardableError{MessageID: message.ID, Reason: "only the content of received messages which has not expired can be forwarded"}
+	}
+
+	if utf8.RuneCountInString(message.ForwardContent(params.Prefix)) > messageContentMaxLength {
+		return NewValidationError("prefix", fmt.Sprintf("The prefix and the content of the message may not be greater than %d characters", messageContentMaxLength))
+	}
+
+	return nil
+}
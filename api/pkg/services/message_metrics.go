@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	messageDirectionOutbound = "outbound"
+	messageDirectionInbound  = "inbound"
+
+	// messageMetricStatusCreated is the status of an outbound entities.Message when it is stored
+	messageMetricStatusCreated = "created"
+
+	// messageSendSLOThreshold is the time in which an outbound entities.Message must be sent to meet the send SLO
+	messageSendSLOThreshold = 60 * time.Second
+
+	messageSLOResultMet      = "met"
+	messageSLOResultViolated = "violated"
+)
+
+// queuedMessageStatuses are the statuses of an entities.Message which is waiting for the phone
+var queuedMessageStatuses = []entities.MessageStatus{
+	entities.MessageStatusPending,
+	entities.MessageStatusScheduled,
+	entities.MessageStatusSending,
+}
+
+// MessageMetrics records the metrics of the message pipeline. The metrics are labeled by status and direction
+// because labeling them by owner creates a time series for every phone number.
+type MessageMetrics struct {
+	messages         *telemetry.Counter
+	queued           *telemetry.Gauge
+	sendDuration     *telemetry.Histogram
+	deliveryDuration *telemetry.Histogram
+	sendSLO          *telemetry.Counter
+}
+
+// NewMessageMetrics registers the message metrics in a telemetry.MetricsRegistry
+func NewMessageMetrics(registry *telemetry.MetricsRegistry) *MessageMetrics {
+	return &MessageMetrics{
+		messages: registry.Counter(
+			"httpsms_messages_total",
+			"Number of messages which were created, sent, delivered, received, failed or expired",
+			"status", "direction",
+		),
+		queued: registry.Gauge(
+			"httpsms_messages_queued",
+			"Number of messages which are waiting to be sent by the phone",
+			"status",
+		),
+		sendDuration: registry.Histogram(
+			"httpsms_message_send_duration_seconds",
+			"Time between the API receiving a message and the phone sending it",
+			[]float64{1, 5, 10, 30, 60, 300, 900, 3600},
+		),
+		deliveryDuration: registry.Histogram(
+			"httpsms_message_delivery_duration_seconds",
+			"Time between the API receiving a message and the phone receiving its delivery report",
+			[]float64{1, 5, 10, 30, 60, 300, 900, 3600},
+		),
+		sendSLO: registry.Counter(
+			"httpsms_message_send_slo_total",
+			"Number of outbound messages which were sent within 60 seconds or which were sent later, failed or expired",
+			"result",
+		),
+	}
+}
+
+// record increases the message counter, nothing is recorded when the metrics are disabled. The message is only
+// counted after the transaction of ctx is committed so that a change which is rolled back is not counted.
+func (metrics *MessageMetrics) record(ctx context.Context, status string, message *entities.Message) {
+	if metrics == nil {
+		return
+	}
+
+	snapshot := *message
+	if !repositories.AfterCommit(ctx, func(context.Context) { metrics.observe(status, &snapshot) }) {
+		metrics.observe(status, &snapshot)
+	}
+}
+
+// observe increases the message counter and the send and delivery durations of a message
+func (metrics *MessageMetrics) observe(status string, message *entities.Message) {
+
+	direction := messageDirectionOutbound
+	if message.Type == entities.MessageTypeMobileOriginated {
+		direction = messageDirectionInbound
+	}
+	metrics.messages.Inc(status, direction)
+
+	if message.IsSent() && message.SendDuration != nil {
+		metrics.sendDuration.Observe(time.Duration(*message.SendDuration).Seconds())
+		metrics.recordSLO(time.Duration(*message.SendDuration) <= messageSendSLOThreshold)
+	}
+
+	if message.IsDelivered() && message.DeliveredAt != nil {
+		metrics.deliveryDuration.Observe(message.DeliveredAt.Sub(message.RequestReceivedAt).Seconds())
+		// the SLO of a message which is delivered without a sent event is recorded when it is delivered
+		if message.SentAt == nil && message.SendDuration != nil {
+			metrics.recordSLO(time.Duration(*message.SendDuration) <= messageSendSLOThreshold)
+		}
+	}
+
+	if direction == messageDirectionOutbound && (message.Status == entities.MessageStatusFailed || message.IsExpired()) {
+		metrics.recordSLO(false)
+	}
+}
+
+// recordSLO counts an outbound message which met or violated the send SLO
+func (metrics *MessageMetrics) recordSLO(met bool) {
+	if met {
+		metrics.sendSLO.Inc(messageSLOResultMet)
+		return
+	}
+	metrics.sendSLO.Inc(messageSLOResultViolated)
+}
+
+// WatchQueue sets the queue depth gauge with the counts of the queued messages on every tick until the context is cancelled
+func (metrics *MessageMetrics) WatchQueue(ctx context.Context, logger telemetry.Logger, repository repositories.MessageRepository, interval time.Duration) {
+	if metrics == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		counts, err := repository.CountByStatus(ctx, queuedMessageStatuses)
+		if err != nil && ctx.Err() == nil {
+			logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot count messages with statuses %v", queuedMessageStatuses)))
+		}
+
+		for status, count := range counts {
+			metrics.queued.Set(float64(count), string(status))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// MessageRateService handles the rates which estimate the cost of the messages of a user
+type MessageRateService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.MessageRateRepository
+}
+
+// NewMessageRateService creates a new MessageRateService
+func NewMessageRateService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.MessageRateRepository,
+) (s *MessageRateService) {
+	return &MessageRateService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+	}
+}
+
+// MessageRateStoreParams are parameters for creating an entities.MessageRate
+type MessageRateStoreParams struct {
+	UserID        entities.UserID
+	Owner         string
+	Prefix        string
+	Rate          float64
+	Currency      string
+	EffectiveFrom time.Time
+}
+
+// Store a new entities.MessageRate
+func (service *MessageRateService) Store(ctx context.Context, params *MessageRateStoreParams) (*entities.MessageRate, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.validate(ctx, params.UserID, uuid.Nil, params.Owner, params.Prefix, params.EffectiveFrom); err != nil {
+		msg := fmt.Sprintf("cannot store message rate with prefix [%s] for user [%s]", params.Prefix, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rate := &entities.MessageRate{
+		ID:            uuid.New(),
+		UserID:        params.UserID,
+		Owner:         params.Owner,
+		Prefix:        params.Prefix,
+		Rate:          params.Rate,
+		Currency:      params.Currency,
+		EffectiveFrom: params.EffectiveFrom,
+		CreatedAt:     time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
+	}
+
+	if err := service.repository.Store(ctx, rate); err != nil {
+		msg := fmt.Sprintf("cannot store message rate with prefix [%s] for user [%s]", params.Prefix, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("created message rate [%s] with prefix [%s] for user [%s]", rate.ID, rate.Prefix, rate.UserID))
+	return rate, nil
+}
+
+// MessageRateUpdateParams are parameters for updating an entities.MessageRate
+type MessageRateUpdateParams struct {
+	MessageRateStoreParams
+	MessageRateID uuid.UUID
+}
+
+// Update an entities.MessageRate, the cost of the messages which are already sent is not changed
+func (service *MessageRateService) Update(ctx context.Context, params *MessageRateUpdateParams) (*entities.MessageRate, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rate, err := service.Get(ctx, params.UserID, params.MessageRateID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message rate with ID [%s]", params.MessageRateID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.validate(ctx, params.UserID, rate.ID, params.Owner, params.Prefix, params.EffectiveFrom); err != nil {
+		msg := fmt.Sprintf("cannot update message rate with ID [%s]", params.MessageRateID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rate.Owner = params.Owner
+	rate.Prefix = params.Prefix
+	rate.Rate = params.Rate
+	rate.Currency = params.Currency
+	rate.EffectiveFrom = params.EffectiveFrom
+	rate.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Update(ctx, rate); err != nil {
+		msg := fmt.Sprintf("cannot update message rate with ID [%s]", params.MessageRateID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rate, nil
+}
+
+// Get an entities.MessageRate by ID
+func (service *MessageRateService) Get(ctx context.Context, userID entities.UserID, rateID uuid.UUID) (*entities.MessageRate, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rate, err := service.repository.Load(ctx, userID, rateID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message rate with ID [%s] for user [%s]", rateID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return rate, nil
+}
+
+// Index fetches all the entities.MessageRate of a user
+func (service *MessageRateService) Index(ctx context.Context, userID entities.UserID) ([]*entities.MessageRate, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rates, err := service.repository.Fetch(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch message rates for user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rates, nil
+}
+
+// Delete an entities.MessageRate, the cost of the messages which are already sent is not changed
+func (service *MessageRateService) Delete(ctx context.Context, userID entities.UserID, rateID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	rate, err := service.Get(ctx, userID, rateID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message rate with ID [%s]", rateID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Delete(ctx, rate); err != nil {
+		msg := fmt.Sprintf("cannot delete message rate with ID [%s]", rateID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted message rate [%s] for user [%s]", rateID, userID))
+	return nil
+}
+
+// SummarizeCosts sums the estimated cost of the messages of a user in each currency
+func (service *MessageRateService) SummarizeCosts(ctx context.Context, userID entities.UserID, params repositories.MessageCostParams) ([]*entities.MessageCostSummary, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	summaries, err := service.repository.SummarizeCosts(ctx, userID, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot summarize the cost of the messages of user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return summaries, nil
+}
+
+// validate makes sure that no other rate of the user has the same owner, prefix and effective from time
+func (service *MessageRateService) validate(ctx context.Context, userID entities.UserID, rateID uuid.UUID, owner string, prefix string, effectiveFrom time.Time) error {
+	rates, err := service.repository.Fetch(ctx, userID)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot fetch message rates for user [%s]", userID))
+	}
+
+	for _, rate := range rates {
+		if rate.ID != rateID && rate.Owner == owner && rate.Prefix == prefix && rate.EffectiveFrom.Equal(effectiveFrom) {
+			return NewValidationError("effective_from", fmt.Sprintf("You already have the rate [%s] with the same owner, prefix and effective_from", rate.ID))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/palantir/stacktrace"
+)
+
+// MessageReplyOptions are the optional parameters of a reply in an entities.MessageThread, they are applied like the
+// fields of MessageSendParams with the same name
+type MessageReplyOptions struct {
+	Source                string
+	RequestID             *string
+	SendAt                *time.Time
+	SIM                   entities.SIM
+	AllowFallback         bool
+	ShortenURLs           bool
+	RequestDeliveryReport *bool
+	RequestReceivedAt     time.Time
+}
+
+// ReplyInThread sends a message to the contact of a thread from the owner of the thread. The message has the ID of the
+// thread in entities.Message.ThreadID and the ID of the latest message which was received in the thread in
+// entities.Message.InReplyToID. An archived thread is unarchived, and a thread of another user is not found.
+func (service *MessageService) ReplyInThread(ctx context.Context, userID entities.UserID, threadID uuid.UUID, content string, options MessageReplyOptions) (*entities.Message, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if service.threadRepository == nil {
+		msg := fmt.Sprintf("cannot reply in thread [%s] for user [%s] without the thread repository", threadID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+	}
+
+	thread, err := service.threadRepository.Load(ctx, userID, threadID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load thread with ID [%s] for user [%s] to reply in it", threadID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.checkReply(content); err != nil {
+		msg := fmt.Sprintf("cannot reply in thread [%s] for user [%s]", threadID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	owner, err := phonenumbers.Parse(thread.Owner, phonenumbers.UNKNOWN_REGION)
+	if err != nil {
+		msg := fmt.Sprintf("cannot parse the owner [%s] of thread [%s]", telemetry.RedactPhoneNumber(thread.Owner), threadID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	var inReplyToID *uuid.UUID
+	received, err := service.repository.LoadLastReceived(ctx, userID, thread.Owner, thread.Contact)
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("cannot load the last message received in thread [%s]", threadID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	if received != nil {
+		inReplyToID = &received.ID
+	}
+
+	message, err := service.SendMessage(ctx, MessageSendParams{
+		Owner:                 owner,
+		Contact:               thread.Contact,
+		Content:               content,
+		Source:                options.Source,
+		UserID:                userID,
+		RequestID:             options.RequestID,
+		SendAt:                options.SendAt,
+		SIM:                   options.SIM,
+		AllowFallback:         options.AllowFallback,
+		ShortenURLs:           options.ShortenURLs,
+		RequestDeliveryReport: options.RequestDeliveryReport,
+		RequestReceivedAt:     options.RequestReceivedAt,
+		ThreadID:              &thread.ID,
+		InReplyToID:           inReplyToID,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot send the reply in thread [%s] to [%s]", threadID, telemetry.RedactPhoneNumber(thread.Contact))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	// the reply is already queued so it is not failed when the thread cannot be unarchived
+	if thread.IsArchived {
+		thread.UpdateArchive(false).UpdatedAt = time.Now().UTC()
+		if err = service.threadRepository.Update(ctx, thread); err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot unarchive thread [%s] after the reply [%s]", thread.ID, message.ID)))
+		}
+	}
+
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("replied in thread [%s]", thread.ID))
+	return message, nil
+}
+
+// checkReply makes sure that the content of a reply can be sent
+func (service *MessageService) checkReply(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return NewValidationError("content", "The content field is required")
+	}
+
+	if utf8.RuneCountInString(content) > messageContentMaxLength {
+		return NewValidationError("content", fmt.Sprintf("The content field may not be greater than %d characters", messageContentMaxLength))
+	}
+
+	return nil
+}
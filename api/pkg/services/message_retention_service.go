@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+// messageRetentionBatchSize is the number of entities.Message which are deleted in one query, small batches keep the
+// locks short so that the retention can run while the phones are updating messages
+const messageRetentionBatchSize = 500
+
+// MessageRetentionService deletes the entities.Message which are older than the retention period of a user
+type MessageRetentionService struct {
+	service
+	logger            telemetry.Logger
+	tracer            telemetry.Tracer
+	userRepository    repositories.UserRepository
+	messageRepository repositories.MessageRepository
+	eventDispatcher   *EventDispatcher
+	hardDelete        bool
+}
+
+// NewMessageRetentionService creates a new MessageRetentionService, messages are permanently deleted when hardDelete is true
+func NewMessageRetentionService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	userRepository repositories.UserRepository,
+	messageRepository repositories.MessageRepository,
+	eventDispatcher *EventDispatcher,
+	hardDelete bool,
+) (s *MessageRetentionService) {
+	return &MessageRetentionService{
+		logger:            logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:            tracer,
+		userRepository:    userRepository,
+		messageRepository: messageRepository,
+		eventDispatcher:   eventDispatcher,
+		hardDelete:        hardDelete,
+	}
+}
+
+// Apply deletes the messages of every entities.User with a retention period. A user whose messages cannot be deleted
+// is skipped and the error is returned after the other users have been processed.
+func (service *MessageRetentionService) Apply(ctx context.Context, source string) (int64, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	users, err := service.userRepository.IndexWithRetention(ctx)
+	if err != nil {
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot fetch the users with a retention period"))
+	}
+
+	var total int64
+	var failed []entities.UserID
+	for _, user := range users {
+		count, err := service.applyUser(ctx, source, user)
+		total += count
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot apply the retention period of user [%s]", user.ID)))
+			failed = append(failed, user.ID)
+		}
+	}
+
+	if len(failed) > 0 {
+		msg := fmt.Sprintf("cannot apply the retention period of [%d] of [%d] users %v", len(failed), len(users), failed)
+		return total, service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted [%d] messages of [%d] users with a retention period", total, len(users)))
+	return total, nil
+}
+
+func (service *MessageRetentionService) applyUser(ctx context.Context, source string, user *entities.User) (int64, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	payload := &events.MessageRetentionAppliedPayload{
+		UserID:     user.ID,
+		HardDelete: service.hardDelete,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	var err error
+	payload.ReceivedBefore, payload.ReceivedDeletedCount, err = service.delete(ctx, user, entities.MessageTypeMobileOriginated, payload.Timestamp)
+	if err != nil {
+		return payload.ReceivedDeletedCount, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot delete the received messages of user [%s]", user.ID)))
+	}
+
+	payload.SentBefore, payload.SentDeletedCount, err = service.delete(ctx, user, entities.MessageTypeMobileTerminated, payload.Timestamp)
+	total := payload.ReceivedDeletedCount + payload.SentDeletedCount
+	if err != nil {
+		return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot delete the sent messages of user [%s]", user.ID)))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted [%d] received and [%d] sent messages of user [%s] with hard delete [%t]", payload.ReceivedDeletedCount, payload.SentDeletedCount, user.ID, service.hardDelete))
+	if total == 0 {
+		return 0, nil
+	}
+
+	event, err := service.createEvent(ctx, events.EventTypeMessageRetentionApplied, source, payload)
+	if err != nil {
+		return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot create [%s] event for user [%s]", events.EventTypeMessageRetentionApplied, user.ID)))
+	}
+
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot dispatch [%s] event with ID [%s]", event.Type(), event.ID())))
+	}
+
+	return total, nil
+}
+
+// delete removes the messages of a type in batches, nil is returned for the timestamp when the messages are kept
+func (service *MessageRetentionService) delete(ctx context.Context, user *entities.User, messageType entities.MessageType, timestamp time.Time) (*time.Time, int64, error) {
+	period := user.RetentionPeriod(messageType)
+	if period <= 0 {
+		return nil, 0, nil
+	}
+
+	before := timestamp.Add(-1 * period)
+
+	var total int64
+	for {
+		count, err := service.messageRepository.DeleteExpired(ctx, user.ID, messageType, before, service.hardDelete, messageRetentionBatchSize)
+		total += count
+		if err != nil {
+			return &before, total, stacktrace.Propagate(err, fmt.Sprintf("cannot delete [%s] messages before [%s] after deleting [%d]", messageType, before, total))
+		}
+
+		if count < messageRetentionBatchSize {
+			return &before, total, nil
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// stubRetentionUserRepository returns a fixed list of users with a retention period
+type stubRetentionUserRepository struct {
+	repositories.UserRepository
+	users []*entities.User
+}
+
+func (repository *stubRetentionUserRepository) IndexWithRetention(_ context.Context) ([]*entities.User, error) {
+	return repository.users, nil
+}
+
+// expiredDeletion is a call to stubRetentionMessageRepository.DeleteExpired
+type expiredDeletion struct {
+	userID      entities.UserID
+	messageType entities.MessageType
+	before      time.Time
+	hard        bool
+}
+
+// stubRetentionMessageRepository deletes a fixed number of messages per user and type
+type stubRetentionMessageRepository struct {
+	repositories.MessageRepository
+	mutex     sync.Mutex
+	remaining map[entities.UserID]map[entities.MessageType]int
+	deletions []expiredDeletion
+	failUser  entities.UserID
+}
+
+func (repository *stubRetentionMessageRepository) DeleteExpired(_ context.Context, userID entities.UserID, messageType entities.MessageType, before time.Time, hard bool, limit int) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if userID == repository.failUser {
+		return 0, stacktrace.NewError("connection reset")
+	}
+
+	repository.deletions = append(repository.deletions, expiredDeletion{userID: userID, messageType: messageType, before: before, hard: hard})
+	count := repository.remaining[userID][messageType]
+	if count > limit {
+		count = limit
+	}
+	repository.remaining[userID][messageType] -= count
+	return int64(count), nil
+}
+
+// recordingPushQueue stores the tasks which are enqueued
+type recordingPushQueue struct {
+	mutex sync.Mutex
+	tasks []*PushQueueTask
+}
+
+func (queue *recordingPushQueue) Enqueue(_ context.Context, task *PushQueueTask, _ time.Duration) (string, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.tasks = append(queue.tasks, task)
+	return "queue-id", nil
+}
+
+func (queue *recordingPushQueue) payloads(t *testing.T) []events.MessageRetentionAppliedPayload {
+	var payloads []events.MessageRetentionAppliedPayload
+	for _, task := range queue.tasks {
+		event := cloudevents.NewEvent()
+		assert.Nil(t, json.Unmarshal(task.Body, &event))
+
+		payload := events.MessageRetentionAppliedPayload{}
+		assert.Nil(t, event.DataAs(&payload))
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
+
+func newTestMessageRetentionService(users []*entities.User, messageRepository repositories.MessageRepository, queue PushQueue) *MessageRetentionService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	dispatcher := NewEventDispatcher(logger, tracer, noop.Float64Histogram{}, queue, PushQueueConfig{}, memory.NewEventRepository())
+	return NewMessageRetentionService(logger, tracer, &stubRetentionUserRepository{users: users}, messageRepository, dispatcher, true)
+}
+
+func TestMessageRetentionService_Apply(t *testing.T) {
+	t.Run("messages older than the retention period are deleted in batches", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := &entities.User{ID: "user-id", RetentionReceivedDays: 30}
+		messageRepository := &stubRetentionMessageRepository{remaining: map[entities.UserID]map[entities.MessageType]int{
+			user.ID: {entities.MessageTypeMobileOriginated: messageRetentionBatchSize + 10, entities.MessageTypeMobileTerminated: 10},
+		}}
+		queue := &recordingPushQueue{}
+		service := newTestMessageRetentionService([]*entities.User{user}, messageRepository, queue)
+
+		// Act
+		count, err := service.Apply(context.Background(), "test")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, int64(messageRetentionBatchSize+10), count)
+		assert.Len(t, messageRepository.deletions, 2)
+		for _, deletion := range messageRepository.deletions {
+			assert.Equal(t, entities.MessageType(entities.MessageTypeMobileOriginated), deletion.messageType)
+			assert.True(t, deletion.hard)
+			assert.WithinDuration(t, time.Now().UTC().Add(-30*24*time.Hour), deletion.before, time.Minute)
+		}
+		assert.Equal(t, 10, messageRepository.remaining[user.ID][entities.MessageTypeMobileTerminated])
+
+		payloads := queue.payloads(t)
+		assert.Len(t, payloads, 1)
+		assert.Equal(t, user.ID, payloads[0].UserID)
+		assert.Equal(t, int64(messageRetentionBatchSize+10), payloads[0].ReceivedDeletedCount)
+		assert.Equal(t, int64(0), payloads[0].SentDeletedCount)
+		assert.Nil(t, payloads[0].SentBefore)
+	})
+
+	t.Run("a failing user does not stop the retention of other users", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		failing := &entities.User{ID: "failing-user", RetentionSentDays: 7}
+		user := &entities.User{ID: "user-id", RetentionSentDays: 7}
+		messageRepository := &stubRetentionMessageRepository{
+			failUser: failing.ID,
+			remaining: map[entities.UserID]map[entities.MessageType]int{
+				user.ID: {entities.MessageTypeMobileTerminated: 5},
+			},
+		}
+		queue := &recordingPushQueue{}
+		service := newTestMessageRetentionService([]*entities.User{failing, user}, messageRepository, queue)
+
+		// Act
+		count, err := service.Apply(context.Background(), "test")
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, int64(5), count)
+		assert.Len(t, queue.payloads(t), 1)
+	})
+
+	t.Run("no event is emitted when no message is deleted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		user := &entities.User{ID: "user-id", RetentionSentDays: 7}
+		messageRepository := &stubRetentionMessageRepository{remaining: map[entities.UserID]map[entities.MessageType]int{user.ID: {}}}
+		queue := &recordingPushQueue{}
+		service := newTestMessageRetentionService([]*entities.User{user}, messageRepository, queue)
+
+		// Act
+		count, err := service.Apply(context.Background(), "test")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), count)
+		assert.Empty(t, queue.payloads(t))
+	})
+}
@@ -3,13 +3,17 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/davecgh/go-spew/spew"
 
 	"github.com/nyaruka/phonenumbers"
 
 	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/ids"
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/google/uuid"
@@ -25,25 +29,193 @@ type MessageService struct {
 	logger          telemetry.Logger
 	tracer          telemetry.Tracer
 	eventDispatcher *EventDispatcher
+	eventRepository repositories.EventRepository
 	phoneService    *PhoneService
 	repository      repositories.MessageRepository
+	userRepository  repositories.UserRepository
+	usageRepository repositories.DailyMessageUsageRepository
+	usageLocation   *time.Location
+	archiveAfter    time.Duration
+	ids             ids.Generator
+	metrics         *MessageMetrics
+	// contactRepository is optional, the do-not-disturb windows of the contacts are not checked when it is nil
+	contactRepository repositories.ContactRepository
+	// numberLookupService is optional, the recipients are not validated when it is nil
+	numberLookupService *NumberLookupService
+	// routingRuleRepository is optional, the messages without an owner are sent from the default phone when it is nil
+	routingRuleRepository repositories.RoutingRuleRepository
+	// messageRateRepository is optional, the cost of the messages is not estimated when it is nil
+	messageRateRepository repositories.MessageRateRepository
+	// phoneUsageRepository is optional, the daily send limits of the phones are not enforced when it is nil
+	phoneUsageRepository repositories.PhoneDailySendUsageRepository
+	// shortLinkService is optional, the URLs in the content of the messages are not shortened when it is nil
+	shortLinkService *ShortLinkService
+	// mediaService is optional, messages with media cannot be sent when it is nil
+	mediaService *MediaService
+	// contentExpiryService is optional, the content of received messages does not expire when it is nil
+	contentExpiryService *ContentExpiryService
+	// threadRepository is optional, messages cannot be sent as a reply in a thread when it is nil
+	threadRepository repositories.MessageThreadRepository
+}
+
+// MessageServiceDeps are the dependencies of a MessageService, the optional dependencies can be nil
+type MessageServiceDeps struct {
+	Repository      repositories.MessageRepository
+	EventDispatcher *EventDispatcher
+	EventRepository repositories.EventRepository
+	PhoneService    *PhoneService
+	UserRepository  repositories.UserRepository
+	UsageRepository repositories.DailyMessageUsageRepository
+	UsageLocation   *time.Location
+	ArchiveAfter    time.Duration
+	IDs             ids.Generator
+	Metrics         *MessageMetrics
+
+	// ContactRepository is optional, the do-not-disturb windows of the contacts are not checked when it is nil
+	ContactRepository repositories.ContactRepository
+	// NumberLookupService is optional, the recipients are not validated when it is nil
+	NumberLookupService *NumberLookupService
+	// RoutingRuleRepository is optional, the messages without an owner are sent from the default phone when it is nil
+	RoutingRuleRepository repositories.RoutingRuleRepository
+	// MessageRateRepository is optional, the cost of the messages is not estimated when it is nil
+	MessageRateRepository repositories.MessageRateRepository
+	// PhoneUsageRepository is optional, the daily send limits of the phones are not enforced when it is nil
+	PhoneUsageRepository repositories.PhoneDailySendUsageRepository
+	// ShortLinkService is optional, the URLs in the content of the messages are not shortened when it is nil
+	ShortLinkService *ShortLinkService
+	// MediaService is optional, messages with media cannot be sent when it is nil
+	MediaService *MediaService
+	// ContentExpiryService is optional, the content of received messages does not expire when it is nil
+	ContentExpiryService *ContentExpiryService
+	// ThreadRepository is optional, messages cannot be sent as a reply in a thread when it is nil
+	ThreadRepository repositories.MessageThreadRepository
 }
 
 // NewMessageService creates a new MessageService
 func NewMessageService(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
-	repository repositories.MessageRepository,
-	eventDispatcher *EventDispatcher,
-	phoneService *PhoneService,
+	deps MessageServiceDeps,
 ) (s *MessageService) {
 	return &MessageService{
-		logger:          logger.WithService(fmt.Sprintf("%T", s)),
-		tracer:          tracer,
-		repository:      repository,
-		phoneService:    phoneService,
-		eventDispatcher: eventDispatcher,
+		logger:                logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:                tracer,
+		repository:            deps.Repository,
+		phoneService:          deps.PhoneService,
+		userRepository:        deps.UserRepository,
+		usageRepository:       deps.UsageRepository,
+		usageLocation:         deps.UsageLocation,
+		eventDispatcher:       deps.EventDispatcher,
+		eventRepository:       deps.EventRepository,
+		archiveAfter:          deps.ArchiveAfter,
+		ids:                   deps.IDs,
+		metrics:               deps.Metrics,
+		contactRepository:     deps.ContactRepository,
+		numberLookupService:   deps.NumberLookupService,
+		routingRuleRepository: deps.RoutingRuleRepository,
+		messageRateRepository: deps.MessageRateRepository,
+		phoneUsageRepository:  deps.PhoneUsageRepository,
+		shortLinkService:      deps.ShortLinkService,
+		mediaService:          deps.MediaService,
+		contentExpiryService:  deps.ContentExpiryService,
+		threadRepository:      deps.ThreadRepository,
+	}
+}
+
+// DailyMessageLimitExceededError is returned when a user cannot send more messages until the daily message usage resets
+type DailyMessageLimitExceededError struct {
+	Limit   uint
+	ResetAt time.Time
+}
+
+// Error returns the error message of the DailyMessageLimitExceededError
+func (err *DailyMessageLimitExceededError) Error() string {
+	return fmt.Sprintf("you have exceeded your daily limit of [%d] messages, try again after [%s]", err.Limit, err.ResetAt.Format(time.RFC3339))
+}
+
+// AsDailyMessageLimitExceededError returns the DailyMessageLimitExceededError which caused the error
+func AsDailyMessageLimitExceededError(err error) (*DailyMessageLimitExceededError, bool) {
+	limitErr, ok := stacktrace.RootCause(err).(*DailyMessageLimitExceededError)
+	return limitErr, ok
+}
+
+// DNDEnforcement is how a message to a contact in its do-not-disturb window is handled
+type DNDEnforcement string
+
+const (
+	// DNDEnforcementDefer sends the message when the do-not-disturb window of the contact ends
+	DNDEnforcementDefer = DNDEnforcement("defer")
+
+	// DNDEnforcementStrict rejects the message with a ContactDNDError
+	DNDEnforcementStrict = DNDEnforcement("strict")
+)
+
+// ContactDNDError is returned when a message is sent with DNDEnforcementStrict to a contact in its do-not-disturb window
+type ContactDNDError struct {
+	Contact string
+	Until   time.Time
+}
+
+// Error returns the error message of the ContactDNDError
+func (err *ContactDNDError) Error() string {
+	return fmt.Sprintf("the contact [%s] does not want to be disturbed, try again after [%s]", err.Contact, err.Until.Format(time.RFC3339))
+}
+
+// AsContactDNDError returns the ContactDNDError which caused the error
+func AsContactDNDError(err error) (*ContactDNDError, bool) {
+	dndErr, ok := stacktrace.RootCause(err).(*ContactDNDError)
+	return dndErr, ok
+}
+
+// OwnerNotRegisteredError is returned when a message is sent from a phone number which is not registered to the user
+type OwnerNotRegisteredError struct {
+	Owner string
+}
+
+// Error returns the error message of the OwnerNotRegisteredError
+func (err *OwnerNotRegisteredError) Error() string {
+	return fmt.Sprintf("the phone number [%s] is not registered to your account", err.Owner)
+}
+
+// AsOwnerNotRegisteredError returns the OwnerNotRegisteredError which caused the error
+func AsOwnerNotRegisteredError(err error) (*OwnerNotRegisteredError, bool) {
+	ownerErr, ok := stacktrace.RootCause(err).(*OwnerNotRegisteredError)
+	return ownerErr, ok
+}
+
+// MessageOwnerPhones caches the entities.Phone of each owner while sending the messages of a single request e.g. bulk messages
+type MessageOwnerPhones struct {
+	mutex  sync.Mutex
+	phones map[string]*messageOwnerPhone
+}
+
+type messageOwnerPhone struct {
+	once  sync.Once
+	phone *entities.Phone
+	err   error
+}
+
+// NewMessageOwnerPhones creates an empty MessageOwnerPhones
+func NewMessageOwnerPhones() *MessageOwnerPhones {
+	return &MessageOwnerPhones{phones: make(map[string]*messageOwnerPhone)}
+}
+
+// load fetches the entities.Phone of an owner once, concurrent callers for the same owner wait for the first lookup
+func (cache *MessageOwnerPhones) load(userID entities.UserID, owner string, loader func() (*entities.Phone, error)) (*entities.Phone, error) {
+	key := string(userID) + "/" + owner
+
+	cache.mutex.Lock()
+	item, ok := cache.phones[key]
+	if !ok {
+		item = new(messageOwnerPhone)
+		cache.phones[key] = item
 	}
+	cache.mutex.Unlock()
+
+	item.once.Do(func() {
+		item.phone, item.err = loader()
+	})
+	return item.phone, item.err
 }
 
 // MessageGetOutstandingParams parameters for sending a new message
@@ -61,13 +233,31 @@ func (service *MessageService) GetOutstanding(ctx context.Context, params Messag
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
+	phone, err := service.canReleaseOutstanding(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot release outstanding message with ID [%s] for user [%s]", params.MessageID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
 	message, err := service.repository.GetOutstanding(ctx, params.UserID, params.MessageID)
 	if err != nil {
+		service.releaseDailySend(ctx, phone, params.Timestamp)
 		msg := fmt.Sprintf("could not fetch outstanding messages with params [%s]", spew.Sdump(params))
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	event, err := service.createMessagePhoneSendingEvent(params.Source, events.MessagePhoneSendingPayload{
+	span.SetAttributes(telemetry.MessageAttributes(message.ID, message.Owner)...)
+
+	if message.HasMedia() {
+		if message.Media, err = service.mediaDownloadURLs(ctx, message); err != nil {
+			service.releaseDailySend(ctx, phone, params.Timestamp)
+			service.releaseOutstanding(ctx, message)
+			msg := fmt.Sprintf("cannot create the download URLs of the media of message [%s]", message.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+		}
+	}
+
+	event, err := service.createMessagePhoneSendingEvent(ctx, params.Source, events.MessagePhoneSendingPayload{
 		ID:        message.ID,
 		Owner:     message.Owner,
 		Contact:   message.Contact,
@@ -75,28 +265,219 @@ func (service *MessageService) GetOutstanding(ctx context.Context, params Messag
 		UserID:    message.UserID,
 		Content:   message.Content,
 		SIM:       message.SIM,
+		Media:     message.Media,
+
+		RequestDeliveryReport: message.RequestsDeliveryReport(),
 	})
 	if err != nil {
+		service.releaseDailySend(ctx, phone, params.Timestamp)
+		service.releaseOutstanding(ctx, message)
 		msg := fmt.Sprintf("cannot create [%T] for message with ID [%s]", event, message.ID)
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("created event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID))
+	eventLogger(ctxLogger, event, message.ID).Info("created event")
 
 	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		service.releaseDailySend(ctx, phone, params.Timestamp)
+		service.releaseOutstanding(ctx, message)
 		msg := fmt.Sprintf("cannot dispatch event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID)
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("dispatched event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID))
+	eventLogger(ctxLogger, event, message.ID).Info("dispatched event")
 	return message, nil
 }
 
+// canReleaseOutstanding makes sure the phone of an outstanding message is not paused and has not exceeded its
+// MessagesPerMinute, then it reserves the message in the daily send usage of the phone. The phone is returned so that the
+// reservation can be released when the message is not fetched, it is nil when the phone cannot be loaded.
+func (service *MessageService) canReleaseOutstanding(ctx context.Context, params MessageGetOutstandingParams) (*entities.Phone, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	message, err := service.repository.Load(ctx, params.UserID, params.MessageID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load message with ID [%s] for user [%s]", params.MessageID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	phone, err := service.phoneService.Load(ctx, params.UserID, message.Owner)
+	if err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot load phone with owner [%s] for user [%s], skipping messages per minute check", message.Owner, params.UserID)))
+		return nil, nil
+	}
+
+	if phone.IsPaused {
+		msg := fmt.Sprintf("phone with ID [%s] is paused so message [%s] cannot be released", phone.ID, message.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, msg))
+	}
+
+	if phone.MessagesPerMinute > 0 {
+		count, err := service.repository.CountSendAttemptsSince(ctx, params.UserID, phone.PhoneNumber, params.Timestamp.Add(-1*time.Minute))
+		if err != nil {
+			msg := fmt.Sprintf("cannot count send attempts for phone with ID [%s]", phone.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		if count >= int64(phone.MessagesPerMinute) {
+			msg := fmt.Sprintf("phone with ID [%s] has sent [%d] messages in the last minute which exceeds the limit of [%d]", phone.ID, count, phone.MessagesPerMinute)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeRateLimited, msg))
+		}
+	}
+
+	if err = service.reserveDailySend(ctx, params.Source, phone, params.Timestamp); err != nil {
+		msg := fmt.Sprintf("cannot reserve message [%s] in the daily send usage of phone [%s]", message.ID, phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return phone, nil
+}
+
+// reserveDailySend counts a message in the daily send usage of a phone, it fails with ErrCodeDailySendLimit when the
+// phone has reached its DailySendLimit and emits events.EventTypePhoneQuotaExhausted the first time it happens in a day.
+func (service *MessageService) reserveDailySend(ctx context.Context, source string, phone *entities.Phone, timestamp time.Time) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	if service.phoneUsageRepository == nil || !phone.HasDailySendLimit() {
+		return nil
+	}
+
+	location := phone.DailySendLimitLocation(service.usageLocation)
+	day := entities.DailyMessageUsageDay(timestamp, location)
+
+	reserved, err := service.phoneUsageRepository.Reserve(ctx, phone.UserID, phone.ID, day, phone.DailySendLimit)
+	if err != nil {
+		msg := fmt.Sprintf("cannot reserve a message in the daily send usage of phone [%s] on [%s]", phone.ID, day)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if reserved {
+		return nil
+	}
+
+	if err = service.exhaustDailySend(ctx, source, phone, day, entities.DailyMessageUsageResetAt(timestamp, location), timestamp); err != nil {
+		msg := fmt.Sprintf("cannot handle the exhausted daily send limit of phone [%s] on [%s]", phone.ID, day)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	msg := fmt.Sprintf("phone with ID [%s] has reached its daily send limit of [%d] messages on [%s]", phone.ID, phone.DailySendLimit, day)
+	return service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeDailySendLimit, msg))
+}
+
+// exhaustDailySend emits the events.EventTypePhoneQuotaExhausted event once a day and schedules the
+// events.EventTypePhoneQuotaReset event at midnight so that the pending messages of the phone are released.
+func (service *MessageService) exhaustDailySend(ctx context.Context, source string, phone *entities.Phone, day time.Time, resetAt time.Time, timestamp time.Time) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	exhausted, err := service.phoneUsageRepository.MarkExhausted(ctx, phone.ID, day, timestamp)
+	if err != nil {
+		msg := fmt.Sprintf("cannot mark the daily send usage of phone [%s] on [%s] as exhausted", phone.ID, day)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !exhausted {
+		return nil
+	}
+
+	event, err := service.createEvent(ctx, events.EventTypePhoneQuotaExhausted, source, &events.PhoneQuotaExhaustedPayload{
+		PhoneID:        phone.ID,
+		UserID:         phone.UserID,
+		Owner:          phone.PhoneNumber,
+		DailySendLimit: phone.DailySendLimit,
+		ResetAt:        resetAt,
+		Timestamp:      timestamp,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create [%s] event for phone [%s]", events.EventTypePhoneQuotaExhausted, phone.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] with id [%s] for phone [%s]", event.Type(), event.ID(), phone.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	event, err = service.createEvent(ctx, events.EventTypePhoneQuotaReset, source, &events.PhoneQuotaResetPayload{
+		PhoneID:   phone.ID,
+		UserID:    phone.UserID,
+		Owner:     phone.PhoneNumber,
+		Timestamp: resetAt,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create [%s] event for phone [%s]", events.EventTypePhoneQuotaReset, phone.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if _, err = service.eventDispatcher.DispatchWithTimeout(ctx, event, resetAt.Sub(timestamp)); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] with id [%s] for phone [%s]", event.Type(), event.ID(), phone.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone [%s] has reached its daily send limit of [%d] messages, pending messages are released at [%s]", phone.ID, phone.DailySendLimit, resetAt))
+	return nil
+}
+
+// releaseDailySend removes a message which was released at the timestamp from the daily send usage of a phone
+func (service *MessageService) releaseDailySend(ctx context.Context, phone *entities.Phone, timestamp time.Time) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if phone == nil || service.phoneUsageRepository == nil || !phone.HasDailySendLimit() {
+		return
+	}
+
+	day := entities.DailyMessageUsageDay(timestamp, phone.DailySendLimitLocation(service.usageLocation))
+	if err := service.phoneUsageRepository.Release(ctx, phone.ID, day); err != nil {
+		msg := fmt.Sprintf("cannot release a message from the daily send usage of phone [%s] on [%s]", phone.ID, day)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
+// QueueStats returns the number of pending messages and the remaining daily send limit of the phone of an owner
+func (service *MessageService) QueueStats(ctx context.Context, userID entities.UserID, owner string) (*entities.PhoneQueueStats, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	phone, err := service.phoneService.Load(ctx, userID, owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone with owner [%s] for user [%s]", telemetry.RedactPhoneNumber(owner), userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	pending, err := service.repository.CountPending(ctx, userID, phone.PhoneNumber)
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the pending messages of phone [%s]", phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	timestamp := time.Now().UTC()
+	location := phone.DailySendLimitLocation(service.usageLocation)
+
+	sent := uint(0)
+	if service.phoneUsageRepository != nil && phone.HasDailySendLimit() {
+		usage, err := service.phoneUsageRepository.Load(ctx, phone.ID, entities.DailyMessageUsageDay(timestamp, location))
+		if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+			msg := fmt.Sprintf("cannot load the daily send usage of phone [%s]", phone.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		if usage != nil {
+			sent = usage.SentMessages
+		}
+	}
+
+	return entities.NewPhoneQueueStats(phone, pending, sent, entities.DailyMessageUsageResetAt(timestamp, location)), nil
+}
+
 // DeleteMessage deletes a message from the database
 func (service *MessageService) DeleteMessage(ctx context.Context, source string, message *entities.Message) error {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
+	span.SetAttributes(telemetry.MessageAttributes(message.ID, message.Owner)...)
+
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	if err := service.repository.Delete(ctx, message.UserID, message.ID); err != nil {
@@ -104,7 +485,7 @@ func (service *MessageService) DeleteMessage(ctx context.Context, source string,
 		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	event, err := service.createEvent(events.MessageAPIDeleted, source, &events.MessageAPIDeletedPayload{
+	event, err := service.createEvent(ctx, events.MessageAPIDeleted, source, &events.MessageAPIDeletedPayload{
 		MessageID: message.ID,
 		UserID:    message.UserID,
 		Owner:     message.Owner,
@@ -119,16 +500,84 @@ func (service *MessageService) DeleteMessage(ctx context.Context, source string,
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("created event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID))
+	eventLogger(ctxLogger, event, message.ID).Info("created event")
 	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
 		msg := fmt.Sprintf("cannot dispatch event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("dispatched event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID))
+	eventLogger(ctxLogger, event, message.ID).Info("dispatched event")
 	return nil
 }
 
+// messageContentMaxLength is the maximum number of characters in the content of an entities.Message
+const messageContentMaxLength = 1024
+
+// messageEncryptedContentMaxLength is the maximum number of characters in the ciphertext of an encrypted entities.Message
+const messageEncryptedContentMaxLength = 4096
+
+// UpdateMessageContent changes the content of an entities.Message which is pending or scheduled. It fails with
+// ErrCodeInvalidTransition when the phone has already fetched the message. The previous content is kept in the
+// events.MessageAPIUpdated event so that it is in the events of the message.
+func (service *MessageService) UpdateMessageContent(ctx context.Context, source string, userID entities.UserID, messageID uuid.UUID, content string) (*entities.Message, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+
+	if strings.TrimSpace(content) == "" {
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("content", "The content field is required"), fmt.Sprintf("cannot update message [%s] with empty content", messageID)))
+	}
+
+	if utf8.RuneCountInString(content) > messageEncryptedContentMaxLength {
+		msg := fmt.Sprintf("The content field may not be greater than %d characters", messageEncryptedContentMaxLength)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("content", msg), fmt.Sprintf("cannot update message [%s] with [%d] characters", messageID, utf8.RuneCountInString(content))))
+	}
+
+	previousContent := ""
+	message, err := service.updateMessage(ctx, userID, messageID, func(message *entities.Message) (bool, error) {
+		if !message.CanBeUpdated() {
+			return false, stacktrace.NewErrorWithCode(ErrCodeInvalidTransition, fmt.Sprintf("message has wrong status [%s]. expected [%s, %s]", message.Status, entities.MessageStatusPending, entities.MessageStatusScheduled))
+		}
+		if !message.IsEncrypted && utf8.RuneCountInString(content) > messageContentMaxLength {
+			return false, NewValidationError("content", fmt.Sprintf("The content field may not be greater than %d characters", messageContentMaxLength))
+		}
+		previousContent = message.Content
+		message.UpdateContent(time.Now().UTC(), content)
+		return true, nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot update content of message with ID [%s] for user [%s]", messageID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	event, err := service.createEvent(ctx, events.MessageAPIUpdated, source, &events.MessageAPIUpdatedPayload{
+		MessageID:       message.ID,
+		UserID:          message.UserID,
+		Owner:           message.Owner,
+		RequestID:       message.RequestID,
+		Contact:         message.Contact,
+		Status:          message.Status,
+		Timestamp:       message.UpdatedAt,
+		PreviousContent: previousContent,
+		Content:         message.Content,
+		SIM:             message.SIM,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create [%s] event for message with ID [%s]", events.MessageAPIUpdated, message.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	eventLogger(ctxLogger, event, message.ID).Info("created event")
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] with id [%s] for message [%s]", event.Type(), event.ID(), message.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	eventLogger(ctxLogger, event, message.ID).Info("dispatched event")
+	return message, nil
+}
+
 // DeleteByOwnerAndContact deletes all the messages between an owner and a contact
 func (service *MessageService) DeleteByOwnerAndContact(ctx context.Context, userID entities.UserID, owner, contact string) error {
 	ctx, span := service.tracer.Start(ctx)
@@ -137,11 +586,11 @@ func (service *MessageService) DeleteByOwnerAndContact(ctx context.Context, user
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	if err := service.repository.DeleteByOwnerAndContact(ctx, userID, owner, contact); err != nil {
-		msg := fmt.Sprintf("could not all delete messages for user with ID [%s] between owner [%s] and contact [%s] ", userID, owner, contact)
+		msg := fmt.Sprintf("could not all delete messages for user with ID [%s] between owner [%s] and contact [%s] ", userID, owner, telemetry.RedactPhoneNumber(contact))
 		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("deleted all messages for user with ID [%s] between owner [%s] and contact [%s] ", userID, owner, contact))
+	ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldUserID: userID, telemetry.LogFieldOwner: owner}).Info("deleted all messages with contact")
 	return nil
 }
 
@@ -153,6 +602,14 @@ type MessageGetParams struct {
 	Contact string
 }
 
+// Redacted returns a copy of the params which is safe to log
+func (params MessageGetParams) Redacted() MessageGetParams {
+	params.Owner = telemetry.RedactPhoneNumber(params.Owner)
+	params.Contact = telemetry.RedactPhoneNumber(params.Contact)
+	params.Query = telemetry.RedactContent(params.Query)
+	return params
+}
+
 // GetMessages fetches sent between 2 phone numbers
 func (service *MessageService) GetMessages(ctx context.Context, params MessageGetParams) (*[]entities.Message, error) {
 	ctx, span := service.tracer.Start(ctx)
@@ -162,19 +619,35 @@ func (service *MessageService) GetMessages(ctx context.Context, params MessageGe
 
 	messages, err := service.repository.Index(ctx, params.UserID, params.Owner, params.Contact, params.IndexParams)
 	if err != nil {
-		msg := fmt.Sprintf("could not fetch messages with parms [%+#v]", params)
+		msg := fmt.Sprintf("could not fetch messages with parms [%+#v]", params.Redacted())
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("fetched [%d] messages with prams [%+#v]", len(*messages), params))
+	ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldUserID: params.UserID, telemetry.LogFieldOwner: params.Owner, telemetry.LogFieldCount: len(*messages)}).Info("fetched messages")
 	return messages, nil
 }
 
+// CountMessages counts the messages sent between 2 phone numbers which match the filters of GetMessages
+func (service *MessageService) CountMessages(ctx context.Context, params MessageGetParams) (int64, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	count, err := service.repository.Count(ctx, params.UserID, params.Owner, params.Contact, params.IndexParams)
+	if err != nil {
+		msg := fmt.Sprintf("could not count messages with parms [%+#v]", params.Redacted())
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return count, nil
+}
+
 // GetMessage fetches a message by the ID
 func (service *MessageService) GetMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID) (*entities.Message, error) {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
+	span.SetAttributes(telemetry.AttributeMessageID.String(messageID.String()))
+
 	message, err := service.repository.Load(ctx, userID, messageID)
 	if err != nil {
 		msg := fmt.Sprintf("could not fetch messages with ID [%s]", messageID)
@@ -184,6 +657,40 @@ func (service *MessageService) GetMessage(ctx context.Context, userID entities.U
 	return message, nil
 }
 
+// GetMessagesByID fetches the messages of a user with the IDs, an ID which does not exist or belongs to another user is omitted
+func (service *MessageService) GetMessagesByID(ctx context.Context, userID entities.UserID, messageIDs []uuid.UUID) ([]entities.Message, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+
+	messages, err := service.repository.LoadMany(ctx, userID, messageIDs)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch [%d] messages for user [%s]", len(messageIDs), userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldUserID: userID, telemetry.LogFieldCount: len(messages)}).Info(fmt.Sprintf("fetched [%d] of [%d] messages by ID", len(messages), len(messageIDs)))
+	return messages, nil
+}
+
+// GetMessageEvents fetches the cloudevents.Event of an entities.Message ordered by time in ascending order.
+// The caller must check that the user owns the entities.Message e.g. with GetMessage
+func (service *MessageService) GetMessageEvents(ctx context.Context, messageID uuid.UUID) (*[]cloudevents.Event, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	span.SetAttributes(telemetry.AttributeMessageID.String(messageID.String()))
+
+	messageEvents, err := service.eventRepository.FetchByMessageID(ctx, messageID)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch events of message with ID [%s]", messageID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return messageEvents, nil
+}
+
 // MessageStoreEventParams parameters registering a message event
 type MessageStoreEventParams struct {
 	MessageID    uuid.UUID
@@ -191,6 +698,8 @@ type MessageStoreEventParams struct {
 	Timestamp    time.Time
 	ErrorMessage *string
 	Source       string
+	// ReportedTimestamp is the Timestamp which was reported by the phone, it is set by StoreEvent when the Timestamp is corrected with the clock skew of the phone
+	ReportedTimestamp *time.Time
 }
 
 // StoreEvent handles event generated by a mobile phone
@@ -198,6 +707,10 @@ func (service *MessageService) StoreEvent(ctx context.Context, message *entities
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
+	span.SetAttributes(telemetry.MessageAttributes(message.ID, message.Owner)...)
+
+	params.Timestamp, params.ReportedTimestamp = service.phoneService.NormalizeTimestamp(ctx, message.UserID, message.Owner, params.Timestamp)
+
 	var err error
 
 	switch params.EventName {
@@ -228,6 +741,9 @@ type MessageReceiveParams struct {
 	SIM       entities.SIM
 	Timestamp time.Time
 	Source    string
+	// IsEncrypted is true when the Content was end-to-end encrypted by the phone before it was uploaded
+	IsEncrypted     bool
+	EncryptionKeyID *string
 }
 
 // ReceiveMessage handles message received by a mobile phone
@@ -237,31 +753,38 @@ func (service *MessageService) ReceiveMessage(ctx context.Context, params Messag
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
+	owner := phonenumbers.Format(&params.Owner, phonenumbers.E164)
+	timestamp, reportedTimestamp := service.phoneService.NormalizeTimestamp(ctx, params.UserID, owner, params.Timestamp)
+
 	eventPayload := events.MessagePhoneReceivedPayload{
-		MessageID: uuid.New(),
-		UserID:    params.UserID,
-		Owner:     phonenumbers.Format(&params.Owner, phonenumbers.E164),
-		Contact:   params.Contact,
-		Timestamp: params.Timestamp,
-		Content:   params.Content,
-		SIM:       params.SIM,
+		MessageID:         service.ids.New(),
+		UserID:            params.UserID,
+		Owner:             owner,
+		Contact:           params.Contact,
+		Timestamp:         timestamp,
+		ReportedTimestamp: reportedTimestamp,
+		Content:           params.Content,
+		SIM:               params.SIM,
+		IsEncrypted:       params.IsEncrypted,
+		EncryptionKeyID:   params.EncryptionKeyID,
 	}
+	span.SetAttributes(telemetry.MessageAttributes(eventPayload.MessageID, eventPayload.Owner)...)
 
-	ctxLogger.Info(fmt.Sprintf("creating cloud event for received with ID [%s]", eventPayload.MessageID))
+	ctxLogger.WithField(telemetry.LogFieldMessageID, eventPayload.MessageID).Info("creating cloud event for received message")
 
-	event, err := service.createMessagePhoneReceivedEvent(params.Source, eventPayload)
+	event, err := service.createMessagePhoneReceivedEvent(ctx, params.Source, eventPayload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot create %T from payload with message id [%s]", event, eventPayload.MessageID)
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("created event [%s] with id [%s] and message id [%s]", event.Type(), event.ID(), eventPayload.MessageID))
+	eventLogger(ctxLogger, event, eventPayload.MessageID).Info("created event")
 
 	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
 		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
-	ctxLogger.Info(fmt.Sprintf("event [%s] dispatched succesfully", event.ID()))
+	eventLogger(ctxLogger, event, eventPayload.MessageID).Info("dispatched event")
 
 	return service.storeReceivedMessage(ctx, eventPayload)
 }
@@ -270,15 +793,18 @@ func (service *MessageService) handleMessageSentEvent(ctx context.Context, param
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
-	event, err := service.createMessagePhoneSentEvent(params.Source, events.MessagePhoneSentPayload{
-		ID:        message.ID,
-		Owner:     message.Owner,
-		UserID:    message.UserID,
-		RequestID: message.RequestID,
-		Timestamp: params.Timestamp,
-		Contact:   message.Contact,
-		Content:   message.Content,
-		SIM:       message.SIM,
+	event, err := service.createMessagePhoneSentEvent(ctx, params.Source, events.MessagePhoneSentPayload{
+		ID:                message.ID,
+		Owner:             message.Owner,
+		UserID:            message.UserID,
+		RequestID:         message.RequestID,
+		Timestamp:         params.Timestamp,
+		ReportedTimestamp: params.ReportedTimestamp,
+		Contact:           message.Contact,
+		Content:           message.Content,
+		SIM:               message.SIM,
+
+		MessageStatusTransition: events.NewMessageStatusTransition(message, entities.MessageStatusSent, params.Timestamp),
 	})
 	if err != nil {
 		msg := fmt.Sprintf("cannot create event [%s] for message [%s]", events.EventTypeMessagePhoneSent, message.ID)
@@ -296,115 +822,674 @@ func (service *MessageService) handleMessageDeliveredEvent(ctx context.Context,
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
-	event, err := service.createMessagePhoneDeliveredEvent(params.Source, events.MessagePhoneDeliveredPayload{
-		ID:        message.ID,
-		Owner:     message.Owner,
-		UserID:    message.UserID,
-		RequestID: message.RequestID,
-		Timestamp: params.Timestamp,
-		Contact:   message.Contact,
-		Content:   message.Content,
-		SIM:       message.SIM,
+	event, err := service.createMessagePhoneDeliveredEvent(ctx, params.Source, events.MessagePhoneDeliveredPayload{
+		ID:                message.ID,
+		Owner:             message.Owner,
+		UserID:            message.UserID,
+		RequestID:         message.RequestID,
+		Timestamp:         params.Timestamp,
+		ReportedTimestamp: params.ReportedTimestamp,
+		Contact:           message.Contact,
+		Content:           message.Content,
+		SIM:               message.SIM,
+
+		MessageStatusTransition: events.NewMessageStatusTransition(message, entities.MessageStatusDelivered, params.Timestamp),
 	})
 	if err != nil {
 		msg := fmt.Sprintf("cannot create event [%s] for message [%s]", events.EventTypeMessagePhoneSent, message.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
-		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return nil
+}
+
+func (service *MessageService) handleMessageFailedEvent(ctx context.Context, params MessageStoreEventParams, message *entities.Message) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	errorMessage := "UNKNOWN ERROR"
+	if params.ErrorMessage != nil {
+		errorMessage = *params.ErrorMessage
+	}
+
+	event, err := service.createMessageSendFailedEvent(ctx, params.Source, events.MessageSendFailedPayload{
+		ID:                message.ID,
+		Owner:             message.Owner,
+		ErrorMessage:      errorMessage,
+		Timestamp:         params.Timestamp,
+		ReportedTimestamp: params.ReportedTimestamp,
+		Contact:           message.Contact,
+		RequestID:         message.RequestID,
+		UserID:            message.UserID,
+		Content:           message.Content,
+		SIM:               message.SIM,
+
+		MessageStatusTransition: events.NewMessageStatusTransition(message, entities.MessageStatusFailed, params.Timestamp),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event [%s] for message [%s]", events.EventTypeMessageSendFailed, message.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	return nil
+}
+
+// MessageSendParams parameters for sending a new message
+type MessageSendParams struct {
+	Owner             *phonenumbers.PhoneNumber
+	Contact           string
+	Content           string
+	Source            string
+	SendAt            *time.Time
+	RequestID         *string
+	UserID            entities.UserID
+	RequestReceivedAt time.Time
+	// SIM overrides the default SIM of the phone when it is not empty
+	SIM entities.SIM
+	// DailyLimitReserved is true when the message is already counted in the daily message usage e.g for bulk messages
+	DailyLimitReserved bool
+	// DailyLimitReservedAt is the time returned by ReserveDailyMessages, the message is released on the day of this time
+	// when it is not stored. It is zero when the message was not counted because the user has no daily message limit.
+	DailyLimitReservedAt time.Time
+	// OwnerPhones caches the phone of the owner across the messages of a single request, it is optional
+	OwnerPhones *MessageOwnerPhones
+	// MessageID is the ID of the new message, an ID is created when it is uuid.Nil
+	MessageID uuid.UUID
+	// AllowFallback opts the message in to be sent by the fallback provider of the user when the phone cannot send it
+	AllowFallback bool
+	// EnforceDND is how the message is handled when the contact is in its do-not-disturb window, it is DNDEnforcementDefer when it is empty
+	EnforceDND DNDEnforcement
+	// ValidateRecipient rejects the message with a RecipientNotSendableError when the contact is a landline or an invalid number
+	ValidateRecipient bool
+	// RoutingRuleID is the ID of the entities.RoutingRule which selected the Owner, it is set by the MessageService
+	RoutingRuleID *uuid.UUID
+	// ShortenURLs replaces the URLs in the content with short links which count their clicks
+	ShortenURLs bool
+	// IsEncrypted is true when the Content is end-to-end encrypted by the client, the phone of the Owner must have EncryptionEnabled
+	IsEncrypted bool
+	// EncryptionKeyID is a hint of the key which the phone uses to decrypt the Content
+	EncryptionKeyID *string
+	// MediaIDs are the IDs of the entities.Media which are sent with the message as an MMS message
+	MediaIDs []uuid.UUID
+	// ForwardedFromID is the ID of the received message whose content is forwarded, it is set by ForwardMessage
+	ForwardedFromID *uuid.UUID
+	// RetryPolicy overrides the retry policy of the user and the phone for this message, the fields which are 0 are not overridden
+	RetryPolicy entities.RetryPolicy
+	// RequestDeliveryReport determines if the phone requests a delivery report from the carrier, the default of the user is used when it is nil
+	RequestDeliveryReport *bool
+	// ThreadID and InReplyToID are set by ReplyInThread
+	ThreadID    *uuid.UUID
+	InReplyToID *uuid.UUID
+	// Metadata are stored with the message so that it can be found with the metadata filter of repositories.IndexParams
+	Metadata entities.MessageMetadata
+}
+
+// ReserveDailyMessages counts messages in the daily message usage of a user, the whole count is rejected with a DailyMessageLimitExceededError if it exceeds the daily message limit.
+// It returns the time of the reservation which is set as MessageSendParams.DailyLimitReservedAt so that the messages which are not stored are released,
+// the time is zero when the user has no daily message limit.
+func (service *MessageService) ReserveDailyMessages(ctx context.Context, userID entities.UserID, count uint) (time.Time, error) {
+	timestamp := time.Now().UTC()
+	reserved, err := service.reserveDailyMessages(ctx, userID, count, timestamp)
+	if err != nil || reserved == 0 {
+		return time.Time{}, err
+	}
+	return timestamp, nil
+}
+
+// reserveDailyMessages counts messages in the daily message usage of a user on the day of the timestamp, it returns
+// the number of reserved messages which is 0 when the user has no daily message limit
+func (service *MessageService) reserveDailyMessages(ctx context.Context, userID entities.UserID, count uint, timestamp time.Time) (uint, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.userRepository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s]", userID)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if !user.HasDailyMessageLimit() {
+		return 0, nil
+	}
+
+	reserved, err := service.usageRepository.Reserve(ctx, userID, entities.DailyMessageUsageDay(timestamp, service.usageLocation), count, user.DailyMessageLimit)
+	if err != nil {
+		msg := fmt.Sprintf("cannot reserve [%d] messages in the daily message usage of user [%s]", count, userID)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !reserved {
+		limitErr := &DailyMessageLimitExceededError{Limit: user.DailyMessageLimit, ResetAt: entities.DailyMessageUsageResetAt(timestamp, service.usageLocation)}
+		msg := fmt.Sprintf("user [%s] cannot send [%d] messages with a daily limit of [%d]", userID, count, user.DailyMessageLimit)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(limitErr, msg))
+	}
+
+	ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldUserID: userID, telemetry.LogFieldCount: count}).Info("reserved messages in the daily message usage")
+	return count, nil
+}
+
+// releaseDailyMessages removes messages which were reserved at the timestamp from the daily message usage of a user
+func (service *MessageService) releaseDailyMessages(ctx context.Context, userID entities.UserID, count uint, timestamp time.Time) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if count == 0 {
+		return
+	}
+
+	day := entities.DailyMessageUsageDay(timestamp, service.usageLocation)
+	if err := service.usageRepository.Release(ctx, userID, day, count); err != nil {
+		msg := fmt.Sprintf("cannot release [%d] messages from the daily message usage of user [%s] on [%s]", count, userID, day)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
+// releaseUnstoredMessages removes the messages which were reserved by the caller of SendMessages but were not stored from the daily message usage
+func (service *MessageService) releaseUnstoredMessages(ctx context.Context, params []MessageSendParams) {
+	type reservation struct {
+		userID     entities.UserID
+		reservedAt time.Time
+	}
+
+	counts := map[reservation]uint{}
+	for _, param := range params {
+		if param.DailyLimitReserved && !param.DailyLimitReservedAt.IsZero() {
+			counts[reservation{userID: param.UserID, reservedAt: param.DailyLimitReservedAt}]++
+		}
+	}
+
+	for key, count := range counts {
+		service.releaseDailyMessages(ctx, key.userID, count, key.reservedAt)
+	}
+}
+
+// SendMessage a new message
+func (service *MessageService) SendMessage(ctx context.Context, params MessageSendParams) (*entities.Message, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+
+	// the message is released when it is not stored so that it does not count in the daily message usage, a message
+	// which is stored counts even when its event cannot be dispatched
+	var reserved uint
+	reservedAt := params.DailyLimitReservedAt
+	if params.DailyLimitReserved && !reservedAt.IsZero() {
+		reserved = 1
+	}
+	stored := false
+	defer func() {
+		if !stored {
+			service.releaseDailyMessages(ctx, params.UserID, reserved, reservedAt)
+		}
+	}()
+
+	rules, err := service.routingRules(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the routing rules of user [%s] to send a message to [%s]", params.UserID, telemetry.RedactPhoneNumber(params.Contact))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	contacts, err := service.dndContacts(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the do-not-disturb window of contact [%s] for user [%s]", telemetry.RedactPhoneNumber(params.Contact), params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	prepared, err := service.prepareMessage(ctx, params, rules, contacts)
+	if err != nil {
+		msg := fmt.Sprintf("cannot prepare the message to [%s] for user [%s]", telemetry.RedactPhoneNumber(params.Contact), params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+	params, phone, media := prepared.params, prepared.phone, prepared.media
+
+	rates, err := service.messageRates(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the message rates of user [%s] to send a message to [%s]", params.UserID, telemetry.RedactPhoneNumber(params.Contact))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	user, err := service.sender(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the user [%s] to send a message to [%s]", params.UserID, telemetry.RedactPhoneNumber(params.Contact))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	eventPayload := service.newMessageAPISentPayload(phone, params, rates, user)
+	span.SetAttributes(telemetry.MessageAttributes(eventPayload.MessageID, eventPayload.Owner)...)
+
+	event, err := service.createMessageAPISentEvent(ctx, params.Source, eventPayload)
+	if err != nil {
+		msg := fmt.Sprintf("cannot create %T from payload with message id [%s]", event, eventPayload.MessageID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	eventLogger(ctxLogger, event, eventPayload.MessageID).WithField(telemetry.LogFieldUserID, eventPayload.UserID).Info("created event")
+
+	// the message is reserved last so that a message which is rejected does not count in the daily message usage
+	if !params.DailyLimitReserved {
+		reservedAt = time.Now().UTC()
+		if reserved, err = service.reserveDailyMessages(ctx, params.UserID, 1, reservedAt); err != nil {
+			msg := fmt.Sprintf("cannot send message to [%s] for user [%s]", telemetry.RedactPhoneNumber(params.Contact), params.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+		}
+	}
+
+	if len(media) > 0 {
+		if err = service.mediaService.Attach(ctx, eventPayload.MessageID, media); err != nil {
+			msg := fmt.Sprintf("cannot attach [%d] media to message [%s]", len(media), eventPayload.MessageID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+		}
+	}
+
+	message, err := service.storeSentMessage(ctx, eventPayload)
+	if err != nil {
+		msg := fmt.Sprintf("cannot store message with id [%s]", eventPayload.MessageID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	stored = true
+	message.Media = media
+
+	timeout := service.getSendDelay(ctxLogger, eventPayload, params.SendAt)
+	if _, err = service.eventDispatcher.DispatchWithTimeout(ctx, event, timeout); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	eventLogger(ctxLogger, event, eventPayload.MessageID).WithField(telemetry.LogFieldUserID, eventPayload.UserID).Info(fmt.Sprintf("dispatched event with delay [%s]", timeout))
+	return message, err
+}
+
+// SendMessages stores the messages with one INSERT per batch and dispatches an events.EventTypeMessageAPISent event for every stored message.
+// The daily message limit must be reserved by the caller. A message which cannot be sent is logged and skipped, the
+// messages which are not stored are released from the daily message usage.
+func (service *MessageService) SendMessages(ctx context.Context, params []MessageSendParams) ([]*entities.Message, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+
+	unstored := params
+	defer func() { service.releaseUnstoredMessages(ctx, unstored) }()
+
+	contacts, err := service.dndContacts(ctx, params...)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the do-not-disturb windows of the contacts of [%d] messages", len(params))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rules, err := service.routingRules(ctx, params...)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the routing rules of the owners of [%d] messages", len(params))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rates, err := service.messageRates(ctx, params...)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the message rates of [%d] messages", len(params))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	user, err := service.sender(ctx, params...)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the user of [%d] messages", len(params))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	payloads := make([]events.MessageAPISentPayload, 0, len(params))
+	sendParams := make([]MessageSendParams, 0, len(params))
+	messages := make([]*entities.Message, 0, len(params))
+	skipped := make([]MessageSendParams, 0)
+	for _, param := range params {
+		prepared, err := service.prepareMessage(ctx, param, rules, contacts)
+		if err != nil {
+			msg := fmt.Sprintf("cannot prepare the message to [%s] for user [%s]", telemetry.RedactPhoneNumber(param.Contact), param.UserID)
+			ctxLogger.Warn(stacktrace.Propagate(err, msg))
+			skipped = append(skipped, param)
+			continue
+		}
+
+		payload := service.newMessageAPISentPayload(prepared.phone, prepared.params, rates, user)
+		if len(prepared.media) > 0 {
+			if err = service.mediaService.Attach(ctx, payload.MessageID, prepared.media); err != nil {
+				msg := fmt.Sprintf("cannot attach [%d] media to message [%s]", len(prepared.media), payload.MessageID)
+				ctxLogger.Warn(stacktrace.Propagate(err, msg))
+				skipped = append(skipped, param)
+				continue
+			}
+		}
+
+		message := service.newSentMessage(payload)
+		message.OrderTimestamp = message.OrderTimestamp.Add(time.Duration(len(messages)) * entities.MessageOrderTimestampStep)
+		message.Media = prepared.media
+
+		payloads = append(payloads, payload)
+		sendParams = append(sendParams, prepared.params)
+		messages = append(messages, message)
+	}
+
+	outcomes, err := service.repository.StoreMany(ctx, messages)
+	if err != nil {
+		msg := fmt.Sprintf("cannot store [%d] messages", len(messages))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	unstored = skipped
+	sent := make([]*entities.Message, 0, len(messages))
+	for index, payload := range payloads {
+		if outcomes[index] != repositories.StoreOutcomeInserted {
+			ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("message with id [%s] already exists for user [%s]", payload.MessageID, payload.UserID)))
+			unstored = append(unstored, sendParams[index])
+			continue
+		}
+
+		event, err := service.createMessageAPISentEvent(ctx, sendParams[index].Source, payload)
+		if err != nil {
+			msg := fmt.Sprintf("cannot create %T from payload with message id [%s]", event, payload.MessageID)
+			ctxLogger.Error(stacktrace.Propagate(err, msg))
+			continue
+		}
+
+		timeout := service.getSendDelay(ctxLogger, payload, sendParams[index].SendAt)
+		if _, err = service.eventDispatcher.DispatchWithTimeout(ctx, event, timeout); err != nil {
+			msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s] for message [%s]", event.Type(), event.ID(), payload.MessageID)
+			ctxLogger.Error(stacktrace.Propagate(err, msg))
+			continue
+		}
+
+		sent = append(sent, messages[index])
+	}
+
+	ctxLogger.WithField(telemetry.LogFieldCount, len(sent)).Info(fmt.Sprintf("sent [%d] of [%d] messages with [%d] stored", len(sent), len(params), len(messages)))
+	return sent, nil
+}
+
+// preparedMessage is a message which is routed to the phone of its owner and can be sent
+type preparedMessage struct {
+	params MessageSendParams
+	phone  *entities.Phone
+	media  []*entities.Media
+}
+
+// prepareMessage routes a message to the phone of its owner and checks that it can be sent to the contact, then the URLs
+// in the content are shortened and the media are loaded. SendMessage and SendMessages prepare every message with it so
+// that a message is checked in the same way when it is sent alone or in bulk.
+func (service *MessageService) prepareMessage(ctx context.Context, params MessageSendParams, rules []*entities.RoutingRule, contacts map[string]*entities.Contact) (*preparedMessage, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	params = service.route(params, rules)
+
+	phone, err := service.ownerPhone(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the owner phone of user [%s] to send a message to [%s]", params.UserID, telemetry.RedactPhoneNumber(params.Contact))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.checkEncryption(phone, params); err != nil {
+		msg := fmt.Sprintf("cannot send message to [%s] from phone [%s] for user [%s]", telemetry.RedactPhoneNumber(params.Contact), phone.ID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if params.ValidateRecipient && service.numberLookupService != nil {
+		if err = service.numberLookupService.ValidateRecipient(ctx, params.Contact); err != nil {
+			msg := fmt.Sprintf("cannot send message to [%s] for user [%s]", telemetry.RedactPhoneNumber(params.Contact), params.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	if params.SendAt, err = service.dndSendAt(params, contacts[params.Contact]); err != nil {
+		msg := fmt.Sprintf("cannot send message to [%s] for user [%s] in the do-not-disturb window of the contact", telemetry.RedactPhoneNumber(params.Contact), params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if params.ShortenURLs && service.shortLinkService != nil {
+		if params.MessageID == uuid.Nil {
+			params.MessageID = service.ids.New()
+		}
+		if params.Content, err = service.shortLinkService.Shorten(ctx, params.UserID, params.MessageID, params.Content); err != nil {
+			msg := fmt.Sprintf("cannot shorten the URLs in the content of message [%s] for user [%s]", params.MessageID, params.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	media, err := service.loadMedia(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the [%d] media of the message to [%s] for user [%s]", len(params.MediaIDs), telemetry.RedactPhoneNumber(params.Contact), params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return &preparedMessage{params: params, phone: phone, media: media}, nil
+}
+
+func (service *MessageService) newMessageAPISentPayload(phone *entities.Phone, params MessageSendParams, rates []*entities.MessageRate, user *entities.User) events.MessageAPISentPayload {
+	sim := phone.SIM
+	if params.SIM != "" {
+		sim = params.SIM
+	}
+
+	messageID := params.MessageID
+	if messageID == uuid.Nil {
+		messageID = service.ids.New()
+	}
+
+	var cost *float64
+	var currency *string
+	// the segments of an encrypted message are unknown so it has no cost
+	if rate := entities.MatchMessageRate(rates, phone.PhoneNumber, params.Contact, params.RequestReceivedAt); rate != nil && !params.IsEncrypted {
+		value := rate.Cost(entities.SegmentCount(params.Content))
+		cost, currency = &value, &rate.Currency
+	}
+
+	// the policy is resolved when the message is created so that a change of the policies does not affect the message
+	policy := phone.RetryPolicy()
+	requestDeliveryReport := true
+	if user != nil {
+		policy = policy.Override(user.RetryPolicy)
+		requestDeliveryReport = user.RequestDeliveryReports
+	}
+	policy = policy.Override(params.RetryPolicy)
+
+	deliveryTracking := entities.MessageDeliveryTrackingOn
+	if params.RequestDeliveryReport != nil {
+		requestDeliveryReport = *params.RequestDeliveryReport
+	}
+	if !requestDeliveryReport {
+		deliveryTracking = entities.MessageDeliveryTrackingOff
+	}
+
+	return events.MessageAPISentPayload{
+		MessageID:         messageID,
+		UserID:            params.UserID,
+		MaxSendAttempts:   policy.MaxAttempts,
+		RequestID:         params.RequestID,
+		Owner:             phone.PhoneNumber,
+		Contact:           params.Contact,
+		RequestReceivedAt: params.RequestReceivedAt,
+		Content:           params.Content,
+		ScheduledSendTime: params.SendAt,
+		SIM:               sim,
+		AllowFallback:     params.AllowFallback,
+		RoutingRuleID:     params.RoutingRuleID,
+		Cost:              cost,
+		CostCurrency:      currency,
+		IsEncrypted:       params.IsEncrypted,
+		EncryptionKeyID:   params.EncryptionKeyID,
+		MediaIDs:          params.MediaIDs,
+		ForwardedFromID:   params.ForwardedFromID,
+		ThreadID:          params.ThreadID,
+		InReplyToID:       params.InReplyToID,
+		RetryPolicy:       policy,
+		DeliveryTracking:  deliveryTracking,
+		Metadata:          params.Metadata,
+	}
+}
+
+// loadMedia fetches the entities.Media which are sent with a message, they are checked before the message is counted in
+// the daily message usage so that a message with invalid media is rejected without being counted
+func (service *MessageService) loadMedia(ctx context.Context, params MessageSendParams) ([]*entities.Media, error) {
+	if len(params.MediaIDs) == 0 {
+		return nil, nil
+	}
+
+	if service.mediaService == nil {
+		msg := fmt.Sprintf("cannot send [%d] media for user [%s] without the media service", len(params.MediaIDs), params.UserID)
+		return nil, stacktrace.NewErrorWithCode(ErrCodeMediaUnavailable, msg)
+	}
+
+	return service.mediaService.Load(ctx, params.UserID, params.MediaIDs)
+}
+
+// mediaDownloadURLs fetches the entities.Media of an outstanding message with the signed URLs which the phone downloads
+func (service *MessageService) mediaDownloadURLs(ctx context.Context, message *entities.Message) ([]*entities.Media, error) {
+	if service.mediaService == nil {
+		msg := fmt.Sprintf("cannot sign the media of message [%s] without the media service", message.ID)
+		return nil, stacktrace.NewErrorWithCode(ErrCodeMediaUnavailable, msg)
+	}
+	return service.mediaService.DownloadURLs(ctx, message)
+}
+
+// checkEncryption rejects a message whose encryption does not match the end-to-end encryption mode of the phone and an
+// encrypted message which uses a feature that needs the plaintext content
+func (service *MessageService) checkEncryption(phone *entities.Phone, params MessageSendParams) error {
+	if phone.EncryptionEnabled != params.IsEncrypted {
+		return &EncryptionMismatchError{Owner: phone.PhoneNumber, EncryptionEnabled: phone.EncryptionEnabled}
+	}
+
+	if !params.IsEncrypted {
+		return nil
+	}
+
+	if params.ShortenURLs {
+		return &EncryptedContentError{Feature: "shorten_urls"}
 	}
+
+	if params.AllowFallback {
+		return &EncryptedContentError{Feature: "allow_fallback"}
+	}
+
 	return nil
 }
 
-func (service *MessageService) handleMessageFailedEvent(ctx context.Context, params MessageStoreEventParams, message *entities.Message) error {
-	ctx, span := service.tracer.Start(ctx)
-	defer span.End()
-
-	errorMessage := "UNKNOWN ERROR"
-	if params.ErrorMessage != nil {
-		errorMessage = *params.ErrorMessage
+// messageRates fetches the entities.MessageRate of the user of the messages, the messages must be of the same user
+func (service *MessageService) messageRates(ctx context.Context, params ...MessageSendParams) ([]*entities.MessageRate, error) {
+	if service.messageRateRepository == nil || len(params) == 0 {
+		return nil, nil
 	}
 
-	event, err := service.createMessageSendFailedEvent(params.Source, events.MessageSendFailedPayload{
-		ID:           message.ID,
-		Owner:        message.Owner,
-		ErrorMessage: errorMessage,
-		Timestamp:    params.Timestamp,
-		Contact:      message.Contact,
-		RequestID:    message.RequestID,
-		UserID:       message.UserID,
-		Content:      message.Content,
-		SIM:          message.SIM,
-	})
+	rates, err := service.messageRateRepository.Fetch(ctx, params[0].UserID)
 	if err != nil {
-		msg := fmt.Sprintf("cannot create event [%s] for message [%s]", events.EventTypeMessageSendFailed, message.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the message rates of user [%s]", params[0].UserID))
 	}
+	return rates, nil
+}
 
-	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
-		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+// sender fetches the entities.User whose retry policy and delivery report default apply to the messages, the messages
+// must be of the same user. It is nil when the service has no user repository and the defaults of the phones are used.
+func (service *MessageService) sender(ctx context.Context, params ...MessageSendParams) (*entities.User, error) {
+	if service.userRepository == nil || len(params) == 0 {
+		return nil, nil
 	}
-	return nil
-}
 
-// MessageSendParams parameters for sending a new message
-type MessageSendParams struct {
-	Owner             *phonenumbers.PhoneNumber
-	Contact           string
-	Content           string
-	Source            string
-	SendAt            *time.Time
-	RequestID         *string
-	UserID            entities.UserID
-	RequestReceivedAt time.Time
+	user, err := service.userRepository.Load(ctx, params[0].UserID)
+	if err != nil {
+		return nil, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), fmt.Sprintf("cannot load user with ID [%s]", params[0].UserID))
+	}
+	return user, nil
 }
 
-// SendMessage a new message
-func (service *MessageService) SendMessage(ctx context.Context, params MessageSendParams) (*entities.Message, error) {
-	ctx, span := service.tracer.Start(ctx)
-	defer span.End()
+// routingRules fetches the entities.RoutingRule of the user when a message is sent without an owner, the messages must be of the same user
+func (service *MessageService) routingRules(ctx context.Context, params ...MessageSendParams) ([]*entities.RoutingRule, error) {
+	if service.routingRuleRepository == nil || len(params) == 0 {
+		return nil, nil
+	}
 
-	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+	for _, param := range params {
+		if param.Owner != nil {
+			continue
+		}
 
-	sendAttempts, sim := service.phoneSettings(ctx, params.UserID, phonenumbers.Format(params.Owner, phonenumbers.E164))
+		rules, err := service.routingRuleRepository.Fetch(ctx, params[0].UserID)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the routing rules of user [%s]", params[0].UserID))
+		}
+		return rules, nil
+	}
 
-	eventPayload := events.MessageAPISentPayload{
-		MessageID:         uuid.New(),
-		UserID:            params.UserID,
-		MaxSendAttempts:   sendAttempts,
-		RequestID:         params.RequestID,
-		Owner:             phonenumbers.Format(params.Owner, phonenumbers.E164),
-		Contact:           params.Contact,
-		RequestReceivedAt: params.RequestReceivedAt,
-		Content:           params.Content,
-		ScheduledSendTime: params.SendAt,
-		SIM:               sim,
+	return nil, nil
+}
+
+// route sets the owner of a message which is sent without an owner to the owner of the entities.RoutingRule with the
+// longest prefix of the contact. The message is sent from the default phone when no rule matches.
+func (service *MessageService) route(params MessageSendParams, rules []*entities.RoutingRule) MessageSendParams {
+	if params.Owner != nil {
+		return params
+	}
+
+	rule := entities.MatchRoutingRule(rules, params.Contact)
+	if rule == nil {
+		return params
 	}
 
-	event, err := service.createMessageAPISentEvent(params.Source, eventPayload)
+	owner, err := phonenumbers.Parse(rule.Owner, phonenumbers.UNKNOWN_REGION)
 	if err != nil {
-		msg := fmt.Sprintf("cannot create %T from payload with message id [%s]", event, eventPayload.MessageID)
-		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		service.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot parse the owner [%s] of routing rule [%s]", rule.Owner, rule.ID)))
+		return params
 	}
-	ctxLogger.Info(fmt.Sprintf("created event [%s] with id [%s] and message id [%s] and user [%s]", event.Type(), event.ID(), eventPayload.MessageID, eventPayload.UserID))
 
-	message, err := service.storeSentMessage(ctx, eventPayload)
+	ruleID := rule.ID
+	params.Owner = owner
+	params.RoutingRuleID = &ruleID
+	return params
+}
+
+// dndContacts fetches the entities.Contact with a do-not-disturb window of the messages by phone number, the messages must be of the same user
+func (service *MessageService) dndContacts(ctx context.Context, params ...MessageSendParams) (map[string]*entities.Contact, error) {
+	if service.contactRepository == nil || len(params) == 0 {
+		return map[string]*entities.Contact{}, nil
+	}
+
+	phoneNumbers := make([]string, 0, len(params))
+	for _, param := range params {
+		phoneNumbers = append(phoneNumbers, param.Contact)
+	}
+
+	contacts, err := service.contactRepository.FetchDND(ctx, params[0].UserID, phoneNumbers)
 	if err != nil {
-		msg := fmt.Sprintf("cannot store message with id [%s]", eventPayload.MessageID)
-		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch the contacts of [%d] phone numbers for user [%s]", len(phoneNumbers), params[0].UserID))
 	}
+	return contacts, nil
+}
 
-	timeout := service.getSendDelay(ctxLogger, eventPayload, params.SendAt)
-	if _, err = service.eventDispatcher.DispatchWithTimeout(ctx, event, timeout); err != nil {
-		msg := fmt.Sprintf("cannot dispatch event type [%s] and id [%s]", event.Type(), event.ID())
-		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+// dndSendAt returns the send time of a message to a contact with a do-not-disturb window. The message is held until
+// the later of its scheduled send time and the end of the window which that time is in, or it is rejected with a
+// ContactDNDError when it is sent with DNDEnforcementStrict.
+func (service *MessageService) dndSendAt(params MessageSendParams, contact *entities.Contact) (*time.Time, error) {
+	if contact == nil {
+		return params.SendAt, nil
 	}
 
-	ctxLogger.Info(fmt.Sprintf("[%s] event with ID [%s] dispatched succesfully for message [%s] with user [%s] and delay [%s]", event.Type(), event.ID(), eventPayload.MessageID, eventPayload.UserID, timeout))
-	return message, err
+	sendAt := time.Now().UTC()
+	if params.SendAt != nil && params.SendAt.After(sendAt) {
+		sendAt = params.SendAt.UTC()
+	}
+
+	until, isDND := contact.DNDUntil(sendAt)
+	if !isDND {
+		return params.SendAt, nil
+	}
+
+	if params.EnforceDND == DNDEnforcementStrict {
+		return nil, &ContactDNDError{Contact: params.Contact, Until: until}
+	}
+	return &until, nil
 }
 
 func (service *MessageService) getSendDelay(ctxLogger telemetry.Logger, eventPayload events.MessageAPISentPayload, sendAt *time.Time) time.Duration {
@@ -414,7 +1499,7 @@ func (service *MessageService) getSendDelay(ctxLogger telemetry.Logger, eventPay
 
 	delay := sendAt.Sub(time.Now().UTC())
 	if delay < 0 {
-		ctxLogger.Info(fmt.Sprintf("message [%s] has send time [%s] in the past. sending immediately", eventPayload.MessageID, sendAt.String()))
+		ctxLogger.WithField(telemetry.LogFieldMessageID, eventPayload.MessageID).Info(fmt.Sprintf("send time [%s] is in the past, sending immediately", sendAt.String()))
 		return time.Duration(0)
 	}
 
@@ -435,8 +1520,11 @@ func (service *MessageService) storeReceivedMessage(ctx context.Context, params
 		Contact:           params.Contact,
 		Content:           params.Content,
 		SIM:               params.SIM,
+		IsEncrypted:       params.IsEncrypted,
+		EncryptionKeyID:   params.EncryptionKeyID,
 		Type:              entities.MessageTypeMobileOriginated,
 		Status:            entities.MessageStatusReceived,
+		Channel:           entities.MessageChannelPhone,
 		RequestReceivedAt: params.Timestamp,
 		CreatedAt:         time.Now().UTC(),
 		UpdatedAt:         time.Now().UTC(),
@@ -444,15 +1532,56 @@ func (service *MessageService) storeReceivedMessage(ctx context.Context, params
 		ReceivedAt:        &params.Timestamp,
 	}
 
-	if err := service.repository.Store(ctx, message); err != nil {
+	if service.contentExpiryService != nil {
+		match, err := service.contentExpiryService.Match(ctx, message)
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot match message [%s] with the content expiry rules, the content will not expire", message.ID)))
+		} else {
+			message.ContentExpiresAt = match.ContentExpiresAt
+		}
+	}
+
+	message, err := service.storeOnce(ctx, message)
+	if err != nil {
 		msg := fmt.Sprintf("cannot save message with id [%s]", params.MessageID)
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("message saved with id [%s]", message.ID))
+	messageLogger(ctxLogger, message).Info("message saved")
 	return message, nil
 }
 
+// storeOnce inserts an entities.Message and returns the stored message when a message with the same ID already exists,
+// so storing the message of a redelivered event succeeds
+func (service *MessageService) storeOnce(ctx context.Context, message *entities.Message) (*entities.Message, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	outcomes, err := service.repository.StoreMany(ctx, []*entities.Message{message})
+	if err != nil {
+		msg := fmt.Sprintf("cannot store message with id [%s]", message.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if outcomes[0] == repositories.StoreOutcomeInserted {
+		if message.Type == entities.MessageTypeMobileOriginated {
+			service.metrics.record(ctx, string(entities.MessageStatusReceived), message)
+		} else {
+			service.metrics.record(ctx, messageMetricStatusCreated, message)
+		}
+		return message, nil
+	}
+
+	existing, err := service.repository.Load(ctx, message.UserID, message.ID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the existing message with id [%s] for user [%s]", message.ID, message.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	service.tracer.CtxLogger(service.logger, span).Info(fmt.Sprintf("message with id [%s] already exists", message.ID))
+	return existing, nil
+}
+
 // HandleMessageParams are parameters for handling a message event
 type HandleMessageParams struct {
 	ID        uuid.UUID
@@ -461,30 +1590,116 @@ type HandleMessageParams struct {
 	Timestamp time.Time
 }
 
-// HandleMessageSending handles when a message is being sent
-func (service *MessageService) HandleMessageSending(ctx context.Context, params HandleMessageParams) error {
-	ctx, span := service.tracer.Start(ctx)
+// messageArchiveBatchSize is the number of entities.Message which are archived in one transaction
+const messageArchiveBatchSize = 1000
+
+// Archive moves the messages which were ordered before the archive period into the archive in batches
+func (service *MessageService) Archive(ctx context.Context) (int64, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
 	defer span.End()
 
-	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+	if service.archiveAfter <= 0 {
+		ctxLogger.Info("message archive period is not configured, no messages archived")
+		return 0, nil
+	}
+
+	before := time.Now().UTC().Add(-1 * service.archiveAfter)
+
+	var total int64
+	for {
+		count, err := service.repository.Archive(ctx, before, messageArchiveBatchSize)
+		if err != nil {
+			msg := fmt.Sprintf("cannot archive messages before [%s] after archiving [%d] messages", before, total)
+			return total, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		total += count
+		if count < messageArchiveBatchSize {
+			break
+		}
+	}
+
+	ctxLogger.WithField(telemetry.LogFieldCount, total).Info(fmt.Sprintf("archived messages ordered before [%s]", before))
+	return total, nil
+}
+
+// messageUpdateMaxAttempts is the number of times an entities.Message is loaded when it is updated concurrently
+const messageUpdateMaxAttempts = 3
+
+// releaseOutstanding changes an outstanding entities.Message which was not returned to the phone from sending back to
+// pending so that the phone can fetch it again, a message which is no longer sending is not changed
+func (service *MessageService) releaseOutstanding(ctx context.Context, message *entities.Message) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	_, err := service.updateMessage(ctx, message.UserID, message.ID, func(message *entities.Message) (bool, error) {
+		if !message.IsSending() {
+			return false, nil
+		}
+		message.Status = entities.MessageStatusPending
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		msg := fmt.Sprintf("cannot change the status of message [%s] from [%s] to [%s]", message.ID, entities.MessageStatusSending, entities.MessageStatusPending)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
 	}
+}
 
-	if !message.IsSending() {
-		msg := fmt.Sprintf("message has wrong status [%s]. expected %s", message.Status, entities.MessageStatusSending)
-		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+// updateMessage loads an entities.Message and persists the changes made by update. When the entities.Message
+// was changed after it was loaded, it is loaded again and update is retried so that no change is lost.
+// No entities.Message is returned when update returns false. The status transition is added to the span in the context.
+func (service *MessageService) updateMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID, update func(message *entities.Message) (bool, error)) (*entities.Message, error) {
+	span := service.tracer.Span(ctx)
+	span.SetAttributes(telemetry.AttributeMessageID.String(messageID.String()))
+
+	for attempt := 1; ; attempt++ {
+		message, err := service.repository.Load(ctx, userID, messageID)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot find message with id [%s]", messageID))
+		}
+
+		previousStatus := message.Status
+		ok, err := update(message)
+		if err != nil || !ok {
+			return nil, err
+		}
+
+		err = service.repository.Update(ctx, message)
+		if stacktrace.GetCode(err) == repositories.ErrCodeStaleUpdate && attempt < messageUpdateMaxAttempts {
+			continue
+		}
+
+		if err != nil {
+			msg := fmt.Sprintf("cannot update message with id [%s] after [%d] attempts", messageID, attempt)
+			return nil, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg)
+		}
+
+		span.SetAttributes(telemetry.MessageAttributes(message.ID, message.Owner)...)
+		span.SetAttributes(telemetry.StatusTransitionAttributes(string(previousStatus), string(message.Status))...)
+		return message, nil
 	}
+}
 
-	if err = service.repository.Update(ctx, message.AddSendAttempt(params.Timestamp)); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] after sending", message.ID)
+// HandleMessageSending handles when a message is being sent
+func (service *MessageService) HandleMessageSending(ctx context.Context, params HandleMessageParams) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		if !message.IsSending() {
+			return false, stacktrace.NewErrorWithCode(ErrCodeInvalidTransition, fmt.Sprintf("message has wrong status [%s]. expected %s", message.Status, entities.MessageStatusSending))
+		}
+		message.AddSendAttempt(params.Timestamp)
+		return true, nil
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot update message with id [%s] after sending", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("message with id [%s] updated after adding send attempt", message.ID))
+	messageLogger(ctxLogger, message).Info("message updated after adding send attempt")
 	return nil
 }
 
@@ -495,23 +1710,21 @@ func (service *MessageService) HandleMessageSent(ctx context.Context, params Han
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		if !message.CanTransitionTo(entities.MessageStatusSent) {
+			return false, stacktrace.NewErrorWithCode(ErrCodeInvalidTransition, fmt.Sprintf("message has wrong status [%s]. expected [%s, %s]", message.Status, entities.MessageStatusSending, entities.MessageStatusExpired))
+		}
+		message.Sent(params.Timestamp)
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
+		msg := fmt.Sprintf("cannot update message with id [%s] as sent", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if !message.IsSending() && !message.IsExpired() {
-		msg := fmt.Sprintf("message has wrong status [%s]. expected [%s, %s]", message.Status, entities.MessageStatusSending, entities.MessageStatusExpired)
-		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
-	}
-
-	if err = service.repository.Update(ctx, message.Sent(params.Timestamp)); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] as sent", message.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
-	}
+	service.metrics.record(ctx, string(message.Status), message)
 
-	ctxLogger.Info(fmt.Sprintf("message with id [%s] has been updated to status [%s]", message.ID, message.Status))
+	messageLogger(ctxLogger, message).Info("message status updated")
 	return nil
 }
 
@@ -530,23 +1743,29 @@ func (service *MessageService) HandleMessageFailed(ctx context.Context, params H
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	wasSending := false
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		if !message.CanTransitionTo(entities.MessageStatusFailed) {
+			return false, stacktrace.NewErrorWithCode(ErrCodeInvalidTransition, fmt.Sprintf("message has already been delivered with status [%s]", message.Status))
+		}
+		wasSending = message.IsSending()
+		message.Failed(params.Timestamp, params.ErrorMessage)
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
+		msg := fmt.Sprintf("cannot update message with id [%s] as failed", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if message.IsDelivered() {
-		msg := fmt.Sprintf("message has already been delivered with status [%s]", message.Status)
-		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+	if wasSending && message.LastAttemptedAt != nil && service.phoneUsageRepository != nil {
+		if phone, err := service.phoneService.Load(ctx, message.UserID, message.Owner); err == nil {
+			service.releaseDailySend(ctx, phone, *message.LastAttemptedAt)
+		}
 	}
 
-	if err = service.repository.Update(ctx, message.Failed(params.Timestamp, params.ErrorMessage)); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] as sent", message.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
-	}
+	service.metrics.record(ctx, string(message.Status), message)
 
-	ctxLogger.Info(fmt.Sprintf("message with id [%s] has been updated to status [%s]", message.ID, message.Status))
+	messageLogger(ctxLogger, message).Info("message status updated")
 	return nil
 }
 
@@ -557,24 +1776,31 @@ func (service *MessageService) HandleMessageDelivered(ctx context.Context, param
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		if !message.CanTransitionTo(entities.MessageStatusDelivered) {
+			msg := fmt.Sprintf("message has wrong status [%s]. expected [%s, %s, %s, %s]", message.Status, entities.MessageStatusSent, entities.MessageStatusScheduled, entities.MessageStatusSending, entities.MessageStatusExpired)
+			ctxLogger.Warn(stacktrace.NewError(msg))
+			return false, nil
+		}
+		if !message.RequestsDeliveryReport() {
+			ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("message [%s] has delivery tracking [%s] and its lifecycle ends when it is sent", message.ID, message.DeliveryTracking)))
+			return false, nil
+		}
+		message.Delivered(params.Timestamp)
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
+		msg := fmt.Sprintf("cannot update message with id [%s] as delivered", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if !message.IsSent() && !message.IsSending() && !message.IsExpired() && !message.IsScheduled() {
-		msg := fmt.Sprintf("message has wrong status [%s]. expected [%s, %s, %s, %s]", message.Status, entities.MessageStatusSent, entities.MessageStatusScheduled, entities.MessageStatusSending, entities.MessageStatusExpired)
-		ctxLogger.Warn(stacktrace.NewError(msg))
+	if message == nil {
 		return nil
 	}
 
-	if err = service.repository.Update(ctx, message.Delivered(params.Timestamp)); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] as delivered", message.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
-	}
+	service.metrics.record(ctx, string(message.Status), message)
 
-	ctxLogger.Info(fmt.Sprintf("message with id [%s] has been updated to status [%s]", message.ID, message.Status))
+	messageLogger(ctxLogger, message).Info("message status updated")
 	return nil
 }
 
@@ -585,22 +1811,19 @@ func (service *MessageService) HandleMessageNotificationScheduled(ctx context.Co
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		if !message.IsPending() && !message.IsExpired() && !message.IsSending() {
+			ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("received scheduled event for message with id [%s] message has status [%s]", message.ID, message.Status)))
+		}
+		message.NotificationScheduled(params.Timestamp)
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
-	}
-
-	if !message.IsPending() && !message.IsExpired() && !message.IsSending() {
-		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("received scheduled event for message with id [%s] message has status [%s]", message.ID, message.Status)))
-	}
-
-	if err = service.repository.Update(ctx, message.NotificationScheduled(params.Timestamp)); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] as expired", message.ID)
+		msg := fmt.Sprintf("cannot update message with id [%s] as scheduled", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("message with id [%s] has been scheduled to send at [%s]", message.ID, message.NotificationScheduledAt.String()))
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("message scheduled to send at [%s]", message.NotificationScheduledAt.String()))
 	return nil
 }
 
@@ -611,18 +1834,16 @@ func (service *MessageService) HandleMessageNotificationSent(ctx context.Context
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		message.AddSendAttemptCount()
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
-	}
-
-	if err = service.repository.Update(ctx, message.AddSendAttemptCount()); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] as expired", message.ID)
+		msg := fmt.Sprintf("cannot add send attempt to message with id [%s]", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("notification for message with id [%s] has been sent at [%s]", message.ID, params.Timestamp.String()))
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("notification sent at [%s]", params.Timestamp.String()))
 	return nil
 }
 
@@ -633,29 +1854,35 @@ func (service *MessageService) HandleMessageExpired(ctx context.Context, params
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	message, err := service.repository.Load(ctx, params.UserID, params.ID)
+	message, err := service.updateMessage(ctx, params.UserID, params.ID, func(message *entities.Message) (bool, error) {
+		if message.IsSentByProvider() {
+			ctxLogger.Info(fmt.Sprintf("message with id [%s] is sent by the [%s] fallback provider and cannot expire", message.ID, message.Channel))
+			return false, nil
+		}
+		if !message.CanTransitionTo(entities.MessageStatusExpired) {
+			return false, stacktrace.NewErrorWithCode(ErrCodeInvalidTransition, fmt.Sprintf("message has wrong status [%s]. expected [%s, %s]", message.Status, entities.MessageStatusSending, entities.MessageStatusScheduled))
+		}
+		message.Expired(params.Timestamp)
+		return true, nil
+	})
 	if err != nil {
-		msg := fmt.Sprintf("cannot find message with id [%s]", params.ID)
+		msg := fmt.Sprintf("cannot update message with id [%s] as expired", params.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if !message.IsSending() && !message.IsScheduled() {
-		msg := fmt.Sprintf("message has wrong status [%s]. expected [%s, %s, %s]", message.Status, entities.MessageStatusSending, entities.MessageStatusScheduled)
-		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
-	}
-
-	if err = service.repository.Update(ctx, message.Expired(params.Timestamp)); err != nil {
-		msg := fmt.Sprintf("cannot update message with id [%s] as expired", message.ID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	if message == nil {
+		return nil
 	}
 
-	ctxLogger.Info(fmt.Sprintf("message with id [%s] has been updated to status [%s]", message.ID, message.Status))
+	messageLogger(ctxLogger, message).Info("message status updated")
 
 	if !message.CanBeRescheduled() {
-		return nil
+		service.metrics.record(ctx, string(message.Status), message)
+		return service.requestFallback(ctx, params.Source, message)
 	}
 
-	event, err := service.createMessageSendRetryEvent(params.Source, &events.MessageSendRetryPayload{
+	backoff := message.RetryBackoff()
+	event, err := service.createMessageSendRetryEvent(ctx, params.Source, &events.MessageSendRetryPayload{
 		MessageID: message.ID,
 		Timestamp: time.Now().UTC(),
 		Contact:   message.Contact,
@@ -669,12 +1896,12 @@ func (service *MessageService) HandleMessageExpired(ctx context.Context, params
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+	if _, err = service.eventDispatcher.DispatchWithTimeout(ctx, event, backoff); err != nil {
 		msg := fmt.Sprintf("cannot dispatch [%s] event for message with ID [%s]", event.Type(), message.ID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("retried sending message with ID [%s]", message.ID))
+	messageLogger(ctxLogger, message).Info(fmt.Sprintf("retrying to send message after [%s] with [%d/%d] attempts", backoff, message.SendAttemptCount, message.MaxSendAttempts))
 	return nil
 }
 
@@ -693,14 +1920,16 @@ func (service *MessageService) ScheduleExpirationCheck(ctx context.Context, para
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
+	span.SetAttributes(telemetry.AttributeMessageID.String(params.MessageID.String()))
+
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	if params.MessageExpirationDuration == 0 {
-		ctxLogger.Info(fmt.Sprintf("message expiration duration not set for message [%s] using phone [%s]", params.MessageID, params.PhoneID))
+		ctxLogger.WithField(telemetry.LogFieldMessageID, params.MessageID).Info(fmt.Sprintf("message expiration duration not set for phone [%s]", params.PhoneID))
 		return nil
 	}
 
-	event, err := service.createMessageSendExpiredCheckEvent(params.Source, &events.MessageSendExpiredCheckPayload{
+	event, err := service.createMessageSendExpiredCheckEvent(ctx, params.Source, &events.MessageSendExpiredCheckPayload{
 		MessageID:   params.MessageID,
 		ScheduledAt: params.NotificationSentAt.Add(params.MessageExpirationDuration),
 		UserID:      params.UserID,
@@ -715,7 +1944,7 @@ func (service *MessageService) ScheduleExpirationCheck(ctx context.Context, para
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("scheduled message id [%s] to expire at [%s]", params.MessageID, params.NotificationSentAt.Add(params.MessageExpirationDuration)))
+	eventLogger(ctxLogger, event, params.MessageID).Info(fmt.Sprintf("scheduled message to expire at [%s]", params.NotificationSentAt.Add(params.MessageExpirationDuration)))
 	return nil
 }
 
@@ -731,6 +1960,8 @@ func (service *MessageService) CheckExpired(ctx context.Context, params MessageC
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
+	span.SetAttributes(telemetry.AttributeMessageID.String(params.MessageID.String()))
+
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	message, err := service.repository.Load(ctx, params.UserID, params.MessageID)
@@ -740,21 +1971,29 @@ func (service *MessageService) CheckExpired(ctx context.Context, params MessageC
 	}
 
 	if !message.IsPending() && !message.IsSending() && !message.IsScheduled() {
-		ctxLogger.Info(fmt.Sprintf("message with ID [%s] has status [%s] and is not expired", message.ID, message.Status))
+		messageLogger(ctxLogger, message).Info("message is not expired")
+		return nil
+	}
+
+	if message.IsSentByProvider() {
+		messageLogger(ctxLogger, message).Info(fmt.Sprintf("message is sent by the [%s] fallback provider and does not expire", message.Channel))
 		return nil
 	}
 
-	event, err := service.createMessageSendExpiredEvent(params.Source, events.MessageSendExpiredPayload{
+	timestamp := time.Now().UTC()
+	event, err := service.createMessageSendExpiredEvent(ctx, params.Source, events.MessageSendExpiredPayload{
 		MessageID:        message.ID,
 		Owner:            message.Owner,
 		Contact:          message.Contact,
 		RequestID:        message.RequestID,
-		IsFinal:          message.SendAttemptCount == message.MaxSendAttempts,
+		IsFinal:          !message.CanBeRescheduled(),
 		SendAttemptCount: message.SendAttemptCount,
 		UserID:           message.UserID,
-		Timestamp:        time.Now().UTC(),
+		Timestamp:        timestamp,
 		Content:          message.Content,
 		SIM:              message.SIM,
+
+		MessageStatusTransition: events.NewMessageStatusTransition(message, entities.MessageStatusExpired, timestamp),
 	})
 	if err != nil {
 		msg := fmt.Sprintf("cannot create event [%s] for message with id [%s]", events.EventTypeMessageSendExpired, params.MessageID)
@@ -766,24 +2005,175 @@ func (service *MessageService) CheckExpired(ctx context.Context, params MessageC
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("message [%s] has expired with status [%s]", params.MessageID, message.Status))
+	messageLogger(ctxLogger, message).Info("message has expired")
+	return nil
+}
+
+// Failover moves the pending messages of a phone which is offline to the failover phone of the user.
+// Messages which have already been moved are skipped so the same event can be handled multiple times.
+func (service *MessageService) Failover(ctx context.Context, source string, payload *events.PhoneHeartbeatDeadPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	ctxLogger = ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldUserID: payload.UserID, telemetry.LogFieldOwner: payload.Owner})
+	span.SetAttributes(telemetry.AttributeOwnerHash.String(telemetry.HashOwner(payload.Owner)))
+
+	user, err := service.userRepository.Load(ctx, payload.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	failoverOwner, ok := user.FailoverOwner(payload.Owner)
+	if !ok {
+		ctxLogger.Info("failover is not configured")
+		return nil
+	}
+
+	phone, err := service.phoneService.Load(ctx, payload.UserID, failoverOwner)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("failover phone [%s] is not registered for user [%s]", failoverOwner, payload.UserID)))
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load failover phone [%s] for user [%s]", failoverOwner, payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if phone.IsPaused {
+		ctxLogger.Info(fmt.Sprintf("failover phone [%s] is paused, messages are not moved", failoverOwner))
+		return nil
+	}
+
+	messages, err := service.repository.IndexPending(ctx, payload.UserID, payload.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch pending messages for owner [%s] and user [%s]", payload.Owner, payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	count := 0
+	for _, item := range *messages {
+		if item.Status != entities.MessageStatusPending {
+			continue
+		}
+
+		if item.IsEncrypted != phone.EncryptionEnabled {
+			ctxLogger.WithField(telemetry.LogFieldMessageID, item.ID).Info(fmt.Sprintf("message is not moved because the end-to-end encryption of failover phone [%s] does not match", failoverOwner))
+			continue
+		}
+
+		message, err := service.repository.Failover(ctx, item.UserID, item.ID, payload.Owner, failoverOwner, phone.SIM)
+		if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+			ctxLogger.WithField(telemetry.LogFieldMessageID, item.ID).Info("message is no longer pending")
+			continue
+		}
+
+		if err != nil {
+			msg := fmt.Sprintf("cannot move message [%s] from owner [%s] to [%s]", item.ID, payload.Owner, failoverOwner)
+			return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		if err = service.dispatchMessageFailover(ctx, source, payload.Owner, message); err != nil {
+			msg := fmt.Sprintf("cannot dispatch failover event for message [%s]", message.ID)
+			return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		count++
+	}
+
+	ctxLogger.WithField(telemetry.LogFieldCount, count).Info(fmt.Sprintf("moved pending messages to failover owner [%s]", failoverOwner))
+	return nil
+}
+
+// requestFallback dispatches the events.EventTypeMessageFallbackRequested event for an expired message which allows the fallback provider
+func (service *MessageService) requestFallback(ctx context.Context, source string, message *entities.Message) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if !message.AllowFallback {
+		return nil
+	}
+
+	event, err := service.createEvent(ctx, events.EventTypeMessageFallbackRequested, source, &events.MessageFallbackRequestedPayload{
+		MessageID: message.ID,
+		UserID:    message.UserID,
+		Owner:     message.Owner,
+		Contact:   message.Contact,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create [%s] event for message [%s]", events.EventTypeMessageFallbackRequested, message.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for message [%s]", event.Type(), message.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	messageLogger(ctxLogger, message).Info("requested the fallback provider to send the expired message")
+	return nil
+}
+
+func (service *MessageService) dispatchMessageFailover(ctx context.Context, source string, originalOwner string, message *entities.Message) error {
+	event, err := service.createEvent(ctx, events.EventTypeMessageFailover, source, &events.MessageFailoverPayload{
+		MessageID:     message.ID,
+		UserID:        message.UserID,
+		OriginalOwner: originalOwner,
+		Owner:         message.Owner,
+		Contact:       message.Contact,
+		Content:       message.Content,
+		SIM:           message.SIM,
+		Timestamp:     time.Now().UTC(),
+	})
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot create [%s] event for message [%s]", events.EventTypeMessageFailover, message.ID))
+	}
+
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot dispatch event [%s] for message [%s]", event.Type(), message.ID))
+	}
 	return nil
 }
 
-func (service *MessageService) phoneSettings(ctx context.Context, userID entities.UserID, owner string) (uint, entities.SIM) {
+// ownerPhone loads the entities.Phone which sends the message, it must be registered to the user who sends the message
+func (service *MessageService) ownerPhone(ctx context.Context, params MessageSendParams) (*entities.Phone, error) {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
-	ctxLogger := service.tracer.CtxLogger(service.logger, span)
+	if params.Owner == nil {
+		phone, err := service.phoneService.LoadDefault(ctx, params.UserID)
+		if err != nil {
+			msg := fmt.Sprintf("cannot load default phone for user [%s]", params.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+		}
+		return phone, nil
+	}
+
+	owner := phonenumbers.Format(params.Owner, phonenumbers.E164)
+	loader := func() (*entities.Phone, error) {
+		return service.phoneService.LoadOwner(ctx, params.UserID, owner)
+	}
+
+	var phone *entities.Phone
+	var err error
+	if params.OwnerPhones != nil {
+		phone, err = params.OwnerPhones.load(params.UserID, owner, loader)
+	} else {
+		phone, err = loader()
+	}
+
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("user [%s] cannot send a message from owner [%s]", params.UserID, owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(&OwnerNotRegisteredError{Owner: owner}, msg))
+	}
 
-	phone, err := service.phoneService.Load(ctx, userID, owner)
 	if err != nil {
-		msg := fmt.Sprintf("cannot load phone for userID [%s] and owner [%s]. using default max send attempt of 2", userID, owner)
-		ctxLogger.Error(stacktrace.Propagate(err, msg))
-		return 2, entities.SIM1
+		msg := fmt.Sprintf("cannot load phone for user [%s] and owner [%s]", params.UserID, owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
-	return phone.MaxSendAttemptsSanitized(), phone.SIM
+	return phone, nil
 }
 
 // storeSentMessage a new message
@@ -793,12 +2183,33 @@ func (service *MessageService) storeSentMessage(ctx context.Context, payload eve
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
+	message, err := service.storeOnce(ctx, service.newSentMessage(payload))
+	if err != nil {
+		msg := fmt.Sprintf("cannot save message with id [%s]", payload.MessageID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	messageLogger(ctxLogger, message).Info("message saved")
+	return message, nil
+}
+
+func (service *MessageService) newSentMessage(payload events.MessageAPISentPayload) *entities.Message {
 	timestamp := payload.RequestReceivedAt
 	if payload.ScheduledSendTime != nil {
 		timestamp = *payload.ScheduledSendTime
 	}
 
-	message := &entities.Message{
+	segments := uint(entities.SegmentCount(payload.Content))
+	if payload.IsEncrypted {
+		segments = 0
+	}
+
+	deliveryTracking := payload.DeliveryTracking
+	if deliveryTracking == "" {
+		deliveryTracking = entities.MessageDeliveryTrackingOn
+	}
+
+	return &entities.Message{
 		ID:                payload.MessageID,
 		Owner:             payload.Owner,
 		Contact:           payload.Contact,
@@ -809,54 +2220,95 @@ func (service *MessageService) storeSentMessage(ctx context.Context, payload eve
 		ScheduledSendTime: payload.ScheduledSendTime,
 		Type:              entities.MessageTypeMobileTerminated,
 		Status:            entities.MessageStatusPending,
+		Channel:           entities.MessageChannelPhone,
+		AllowFallback:     payload.AllowFallback,
+		RoutingRuleID:     payload.RoutingRuleID,
+		ForwardedFromID:   payload.ForwardedFromID,
+		ThreadID:          payload.ThreadID,
+		InReplyToID:       payload.InReplyToID,
+		Segments:          segments,
+		IsEncrypted:       payload.IsEncrypted,
+		EncryptionKeyID:   payload.EncryptionKeyID,
+		MediaCount:        uint(len(payload.MediaIDs)),
+		Metadata:          payload.Metadata,
+		Cost:              payload.Cost,
+		CostCurrency:      payload.CostCurrency,
 		RequestReceivedAt: payload.RequestReceivedAt,
 		CreatedAt:         time.Now().UTC(),
 		UpdatedAt:         time.Now().UTC(),
 		MaxSendAttempts:   payload.MaxSendAttempts,
 		OrderTimestamp:    timestamp,
+		// the backoff of a payload which was created before the retry policies is 0 so the message is retried immediately
+		RetryBackoffBaseSeconds: payload.RetryPolicy.BackoffBaseSeconds,
+		RetryBackoffCapSeconds:  payload.RetryPolicy.BackoffCapSeconds,
+		RetryBackoffMultiplier:  payload.RetryPolicy.BackoffMultiplier,
+		DeliveryTracking:        deliveryTracking,
 	}
+}
 
-	if err := service.repository.Store(ctx, message); err != nil {
-		msg := fmt.Sprintf("cannot save message with id [%s]", payload.MessageID)
-		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
-	}
+func (service *MessageService) createMessageSendExpiredEvent(ctx context.Context, source string, payload events.MessageSendExpiredPayload) (cloudevents.Event, error) {
+	return service.createMessageStatusEvent(ctx, events.EventTypeMessageSendExpired, source, payload, payload.MessageStatusTransition)
+}
 
-	ctxLogger.Info(fmt.Sprintf("message saved with id [%s]", payload.MessageID))
-	return message, nil
+func (service *MessageService) createMessageSendExpiredCheckEvent(ctx context.Context, source string, payload *events.MessageSendExpiredCheckPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessageSendExpiredCheck, source, payload)
+}
+
+func (service *MessageService) createMessageAPISentEvent(ctx context.Context, source string, payload events.MessageAPISentPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessageAPISent, source, payload)
 }
 
-func (service *MessageService) createMessageSendExpiredEvent(source string, payload events.MessageSendExpiredPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageSendExpired, source, payload)
+func (service *MessageService) createMessagePhoneReceivedEvent(ctx context.Context, source string, payload events.MessagePhoneReceivedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessagePhoneReceived, source, payload)
 }
 
-func (service *MessageService) createMessageSendExpiredCheckEvent(source string, payload *events.MessageSendExpiredCheckPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageSendExpiredCheck, source, payload)
+func (service *MessageService) createMessagePhoneSendingEvent(ctx context.Context, source string, payload events.MessagePhoneSendingPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessagePhoneSending, source, payload)
 }
 
-func (service *MessageService) createMessageAPISentEvent(source string, payload events.MessageAPISentPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageAPISent, source, payload)
+func (service *MessageService) createMessagePhoneSentEvent(ctx context.Context, source string, payload events.MessagePhoneSentPayload) (cloudevents.Event, error) {
+	return service.createMessageStatusEvent(ctx, events.EventTypeMessagePhoneSent, source, payload, payload.MessageStatusTransition)
 }
 
-func (service *MessageService) createMessagePhoneReceivedEvent(source string, payload events.MessagePhoneReceivedPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessagePhoneReceived, source, payload)
+func (service *MessageService) createMessageSendFailedEvent(ctx context.Context, source string, payload events.MessageSendFailedPayload) (cloudevents.Event, error) {
+	return service.createMessageStatusEvent(ctx, events.EventTypeMessageSendFailed, source, payload, payload.MessageStatusTransition)
 }
 
-func (service *MessageService) createMessagePhoneSendingEvent(source string, payload events.MessagePhoneSendingPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessagePhoneSending, source, payload)
+func (service *MessageService) createMessagePhoneDeliveredEvent(ctx context.Context, source string, payload events.MessagePhoneDeliveredPayload) (cloudevents.Event, error) {
+	return service.createMessageStatusEvent(ctx, events.EventTypeMessagePhoneDelivered, source, payload, payload.MessageStatusTransition)
 }
 
-func (service *MessageService) createMessagePhoneSentEvent(source string, payload events.MessagePhoneSentPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessagePhoneSent, source, payload)
+// createMessageStatusEvent creates an event which changes the status of a message, the events.MessageStatusTransition
+// is also added to the extensions of the event
+func (service *MessageService) createMessageStatusEvent(ctx context.Context, eventType string, source string, payload any, transition events.MessageStatusTransition) (cloudevents.Event, error) {
+	event, err := service.createEvent(ctx, eventType, source, payload)
+	if err != nil {
+		return event, stacktrace.Propagate(err, fmt.Sprintf("cannot create status event [%s]", eventType))
+	}
+
+	transition.SetExtensions(&event)
+	return event, nil
 }
 
-func (service *MessageService) createMessageSendFailedEvent(source string, payload events.MessageSendFailedPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageSendFailed, source, payload)
+func (service *MessageService) createMessageSendRetryEvent(ctx context.Context, source string, payload *events.MessageSendRetryPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessageSendRetry, source, payload)
 }
 
-func (service *MessageService) createMessagePhoneDeliveredEvent(source string, payload events.MessagePhoneDeliveredPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessagePhoneDelivered, source, payload)
+// eventLogger adds the fields of a cloud event and the ID of its entities.Message to the logger
+func eventLogger(logger telemetry.Logger, event cloudevents.Event, messageID uuid.UUID) telemetry.Logger {
+	return logger.WithFields(map[string]interface{}{
+		telemetry.LogFieldEventID:   event.ID(),
+		telemetry.LogFieldEventType: event.Type(),
+		telemetry.LogFieldMessageID: messageID,
+	})
 }
 
-func (service *MessageService) createMessageSendRetryEvent(source string, payload *events.MessageSendRetryPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageSendRetry, source, payload)
+// messageLogger adds the fields of an entities.Message to the logger
+func messageLogger(logger telemetry.Logger, message *entities.Message) telemetry.Logger {
+	return logger.WithFields(map[string]interface{}{
+		telemetry.LogFieldMessageID: message.ID,
+		telemetry.LogFieldOwner:     message.Owner,
+		telemetry.LogFieldUserID:    message.UserID,
+		telemetry.LogFieldStatus:    message.Status,
+	})
 }
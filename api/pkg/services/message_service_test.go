@@ -0,0 +1,1937 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/ids"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubPhoneRepository loads entities.Phone from a fixed set of phones
+type stubPhoneRepository struct {
+	repositories.PhoneRepository
+	phones []entities.Phone
+	loads  int64
+}
+
+func (repository *stubPhoneRepository) LoadOwner(_ context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	atomic.AddInt64(&repository.loads, 1)
+	for _, phone := range repository.phones {
+		if phone.UserID == userID && phone.PhoneNumber == phoneNumber {
+			return &phone, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "phone does not exist")
+}
+
+func (repository *stubPhoneRepository) Load(ctx context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	return repository.LoadOwner(ctx, userID, phoneNumber)
+}
+
+// stubMessageRepository stores a single entities.Message and rejects updates of a stale version
+type stubMessageRepository struct {
+	repositories.MessageRepository
+	mutex      sync.Mutex
+	message    entities.Message
+	loads      int
+	racers     int
+	barrier    sync.WaitGroup
+	alwaysFail bool
+}
+
+// Load waits until the loads of all the racers have happened so that their updates race
+func (repository *stubMessageRepository) Load(_ context.Context, _ entities.UserID, _ uuid.UUID) (*entities.Message, error) {
+	repository.mutex.Lock()
+	repository.loads++
+	wait := repository.loads <= repository.racers
+	message := repository.message
+	repository.mutex.Unlock()
+
+	if wait {
+		repository.barrier.Done()
+		repository.barrier.Wait()
+	}
+	return &message, nil
+}
+
+func (repository *stubMessageRepository) Update(_ context.Context, message *entities.Message) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if repository.alwaysFail || message.Version != repository.message.Version {
+		return stacktrace.NewErrorWithCode(repositories.ErrCodeStaleUpdate, "message was updated concurrently")
+	}
+
+	message.Version++
+	repository.message = *message
+	return nil
+}
+
+func newStubMessageRepository(message entities.Message, racers int) *stubMessageRepository {
+	repository := &stubMessageRepository{message: message, racers: racers}
+	repository.barrier.Add(racers)
+	return repository
+}
+
+// newTestMessageService creates a MessageService which sends messages from the phones in the phone repository. The
+// required dependencies which are not set in deps are created in memory and the optional dependencies stay nil.
+func newTestMessageService(phoneRepository repositories.PhoneRepository, deps MessageServiceDeps) *MessageService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	if deps.Repository == nil {
+		deps.Repository = memory.NewMessageRepository()
+	}
+	if deps.EventRepository == nil {
+		deps.EventRepository = memory.NewEventRepository()
+	}
+	if deps.EventDispatcher == nil {
+		deps.EventDispatcher = newTestQueueEventDispatcher(&capturingPushQueue{}, deps.EventRepository)
+	}
+	if deps.PhoneService == nil {
+		deps.PhoneService = NewPhoneService(logger, tracer, phoneRepository, nil, 0, PhoneClockConfig{})
+	}
+	if deps.UsageLocation == nil {
+		deps.UsageLocation = time.UTC
+	}
+	if deps.IDs == nil {
+		deps.IDs, _ = ids.NewGenerator(ids.StrategyTimeOrdered)
+	}
+	return NewMessageService(logger, tracer, deps)
+}
+
+// newTestQueueEventDispatcher creates an EventDispatcher which pushes the events to a queue
+func newTestQueueEventDispatcher(queue PushQueue, eventRepository repositories.EventRepository) *EventDispatcher {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	histogram, _ := noop.NewMeterProvider().Meter("test").Float64Histogram("test")
+	return NewEventDispatcher(logger, tracer, histogram, queue, PushQueueConfig{}, eventRepository)
+}
+
+func TestMessageService_SendMessage(t *testing.T) {
+	t.Run("user cannot send a message from a phone number registered to another user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-b", PhoneNumber: "+18005550199"}}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+
+		// Act
+		message, err := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:   owner,
+			Contact: "+18005550100",
+			Content: "This is a sample text message",
+			UserID:  "user-a",
+		})
+
+		// Assert
+		ownerErr, ok := AsOwnerNotRegisteredError(err)
+		assert.Nil(t, message)
+		assert.True(t, ok)
+		assert.Equal(t, "+18005550199", ownerErr.Owner)
+
+		validationErr, ok := AsValidationError(err)
+		assert.True(t, ok)
+		assert.Contains(t, validationErr.Errors, "from")
+	})
+
+	t.Run("encryption of the message must match the phone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{
+			{UserID: "user-a", PhoneNumber: "+18005550199", EncryptionEnabled: true},
+			{UserID: "user-a", PhoneNumber: "+18005550198"},
+		}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		encryptedOwner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+		plaintextOwner, _ := phonenumbers.Parse("+18005550198", phonenumbers.UNKNOWN_REGION)
+
+		// Act
+		_, plaintextErr := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:   encryptedOwner,
+			Contact: "+18005550100",
+			Content: "This is a sample text message",
+			UserID:  "user-a",
+		})
+		_, encryptedErr := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:       plaintextOwner,
+			Contact:     "+18005550100",
+			Content:     "q83vEjRWeJA=",
+			UserID:      "user-a",
+			IsEncrypted: true,
+		})
+
+		// Assert
+		mismatchErr, ok := AsEncryptionMismatchError(plaintextErr)
+		assert.True(t, ok)
+		assert.True(t, mismatchErr.EncryptionEnabled)
+
+		mismatchErr, ok = AsEncryptionMismatchError(encryptedErr)
+		assert.True(t, ok)
+		assert.False(t, mismatchErr.EncryptionEnabled)
+	})
+
+	t.Run("encrypted message cannot shorten its urls", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199", EncryptionEnabled: true}}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+
+		// Act
+		_, err := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:       owner,
+			Contact:     "+18005550100",
+			Content:     "q83vEjRWeJA=",
+			UserID:      "user-a",
+			IsEncrypted: true,
+			ShortenURLs: true,
+		})
+
+		// Assert
+		contentErr, ok := AsEncryptedContentError(err)
+		assert.True(t, ok)
+		assert.Equal(t, "shorten_urls", contentErr.Feature)
+	})
+}
+
+func TestMessageService_SendMessages(t *testing.T) {
+	t.Run("bulk messages are checked like a single message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+		params := []MessageSendParams{
+			{Owner: owner, Contact: "+18005550100", Content: "This is a sample text message", UserID: "user-a"},
+			{Owner: owner, Contact: "+18005550101", Content: "This is a sample MMS message", UserID: "user-a", MediaIDs: []uuid.UUID{uuid.New()}},
+		}
+		single, singleErr := service.SendMessage(context.Background(), params[1])
+
+		// Act
+		sent, err := service.SendMessages(context.Background(), params)
+
+		// Assert
+		assert.Nil(t, single)
+		assert.Equal(t, ErrCodeMediaUnavailable, stacktrace.GetCode(singleErr))
+
+		assert.Nil(t, err)
+		assert.Len(t, sent, 1)
+		assert.Equal(t, "+18005550100", sent[0].Contact)
+	})
+}
+
+func TestMessageService_SendMessageMetadata(t *testing.T) {
+	// Setup
+	t.Parallel()
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+	service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+	owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+
+	// Arrange
+	for _, orderID := range []string{"order-1", "order-2"} {
+		_, err := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:              owner,
+			Contact:            "+18005550100",
+			Content:            "Your order " + orderID + " has shipped",
+			UserID:             "user-a",
+			DailyLimitReserved: true,
+			Metadata:           entities.MessageMetadata{"order_id": orderID},
+		})
+		assert.Nil(t, err)
+	}
+
+	// Act
+	messages, err := service.GetMessages(context.Background(), MessageGetParams{
+		IndexParams: repositories.IndexParams{Limit: 20, Metadata: map[string]string{"order_id": "order-2"}},
+		UserID:      "user-a",
+		Owner:       "+18005550199",
+		Contact:     "+18005550100",
+	})
+
+	// Assert
+	assert.Nil(t, err)
+	assert.Len(t, *messages, 1)
+	assert.Equal(t, "Your order order-2 has shipped", (*messages)[0].Content)
+	assert.Equal(t, entities.MessageMetadata{"order_id": "order-2"}, (*messages)[0].Metadata)
+}
+
+// stubDailyMessageUsageRepository counts the sent messages of the users in memory
+type stubDailyMessageUsageRepository struct {
+	mutex sync.Mutex
+	sent  map[entities.UserID]uint
+}
+
+func (repository *stubDailyMessageUsageRepository) Reserve(_ context.Context, userID entities.UserID, _ time.Time, count uint, limit uint) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if repository.sent[userID]+count > limit {
+		return false, nil
+	}
+	repository.sent[userID] += count
+	return true, nil
+}
+
+func (repository *stubDailyMessageUsageRepository) Release(_ context.Context, userID entities.UserID, _ time.Time, count uint) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if repository.sent[userID] >= count {
+		repository.sent[userID] -= count
+	}
+	return nil
+}
+
+// failingStoreMessageRepository fails to store messages
+type failingStoreMessageRepository struct {
+	repositories.MessageRepository
+}
+
+func (repository *failingStoreMessageRepository) StoreMany(_ context.Context, _ []*entities.Message) ([]repositories.StoreOutcome, error) {
+	return nil, stacktrace.NewError("connection refused")
+}
+
+func TestMessageService_SendMessageDailyLimit(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+	userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{"user-a": {ID: "user-a", DailyMessageLimit: 10}}}
+	owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+	params := MessageSendParams{
+		Owner:   owner,
+		Contact: "+18005550100",
+		Content: "This is a sample text message",
+		UserID:  "user-a",
+	}
+
+	t.Run("a sent message is counted in the daily message usage", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: userRepository, UsageRepository: usageRepository})
+
+		// Act
+		_, err := service.SendMessage(context.Background(), params)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uint(1), usageRepository.sent["user-a"])
+	})
+
+	t.Run("a message which cannot be stored is released from the daily message usage", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{"user-a": 3}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{
+			Repository:      &failingStoreMessageRepository{},
+			UserRepository:  userRepository,
+			UsageRepository: usageRepository,
+		})
+
+		// Act
+		_, err := service.SendMessage(context.Background(), params)
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, uint(3), usageRepository.sent["user-a"])
+	})
+
+	t.Run("a reserved message which cannot be sent is released from the daily message usage", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: userRepository, UsageRepository: usageRepository})
+
+		// Arrange
+		reservedAt, err := service.ReserveDailyMessages(context.Background(), "user-a", 1)
+		assert.Nil(t, err)
+		unregistered, _ := phonenumbers.Parse("+18005550198", phonenumbers.UNKNOWN_REGION)
+
+		// Act
+		_, err = service.SendMessage(context.Background(), MessageSendParams{
+			Owner:                unregistered,
+			Contact:              "+18005550100",
+			Content:              "This is a sample text message",
+			UserID:               "user-a",
+			DailyLimitReserved:   true,
+			DailyLimitReservedAt: reservedAt,
+		})
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, uint(0), usageRepository.sent["user-a"])
+	})
+
+	t.Run("a stored message whose event cannot be dispatched is counted in the daily message usage", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{}}
+		eventRepository := memory.NewEventRepository()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{
+			EventRepository: eventRepository,
+			EventDispatcher: newTestQueueEventDispatcher(&failingPushQueue{}, eventRepository),
+			UserRepository:  userRepository,
+			UsageRepository: usageRepository,
+		})
+
+		// Act
+		_, err := service.SendMessage(context.Background(), params)
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, uint(1), usageRepository.sent["user-a"])
+	})
+}
+
+func TestMessageService_SendMessagesDailyLimit(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+	userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{"user-a": {ID: "user-a", DailyMessageLimit: 10}}}
+	owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+	unregistered, _ := phonenumbers.Parse("+18005550198", phonenumbers.UNKNOWN_REGION)
+
+	newParams := func(reservedAt time.Time) []MessageSendParams {
+		params := make([]MessageSendParams, 0, 3)
+		for _, from := range []*phonenumbers.PhoneNumber{owner, unregistered, owner} {
+			params = append(params, MessageSendParams{
+				Owner:                from,
+				Contact:              "+18005550100",
+				Content:              "This is a sample text message",
+				UserID:               "user-a",
+				DailyLimitReserved:   true,
+				DailyLimitReservedAt: reservedAt,
+			})
+		}
+		return params
+	}
+
+	t.Run("bulk messages which cannot be sent are released from the daily message usage", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: userRepository, UsageRepository: usageRepository})
+
+		// Arrange
+		reservedAt, err := service.ReserveDailyMessages(context.Background(), "user-a", 3)
+		assert.Nil(t, err)
+
+		// Act
+		messages, err := service.SendMessages(context.Background(), newParams(reservedAt))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, messages, 2)
+		assert.Equal(t, uint(2), usageRepository.sent["user-a"])
+	})
+
+	t.Run("bulk messages which cannot be stored are released from the daily message usage", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{
+			Repository:      &failingStoreMessageRepository{},
+			UserRepository:  userRepository,
+			UsageRepository: usageRepository,
+		})
+
+		// Arrange
+		reservedAt, err := service.ReserveDailyMessages(context.Background(), "user-a", 3)
+		assert.Nil(t, err)
+
+		// Act
+		_, err = service.SendMessages(context.Background(), newParams(reservedAt))
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, uint(0), usageRepository.sent["user-a"])
+	})
+
+	t.Run("bulk messages of a user without a daily message limit are not released", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubDailyMessageUsageRepository{sent: map[entities.UserID]uint{"user-b": 3}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{
+			UserRepository:  &stubAccountUserRepository{users: map[entities.UserID]entities.User{"user-b": {ID: "user-b"}}},
+			UsageRepository: usageRepository,
+		})
+
+		// Arrange
+		reservedAt, err := service.ReserveDailyMessages(context.Background(), "user-b", 3)
+		assert.Nil(t, err)
+		params := newParams(reservedAt)
+		for index := range params {
+			params[index].UserID = "user-b"
+		}
+
+		// Act
+		messages, err := service.SendMessages(context.Background(), params)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, messages)
+		assert.True(t, reservedAt.IsZero())
+		assert.Equal(t, uint(3), usageRepository.sent["user-b"])
+	})
+}
+
+// failingPushQueue rejects all the tasks
+type failingPushQueue struct{}
+
+func (queue *failingPushQueue) Enqueue(_ context.Context, _ *PushQueueTask, _ time.Duration) (string, error) {
+	return "", stacktrace.NewError("permission denied")
+}
+
+// capturingPushQueue stores the tasks and their delays instead of sending them to the consumer endpoint
+type capturingPushQueue struct {
+	mutex    sync.Mutex
+	tasks    []*PushQueueTask
+	timeouts []time.Duration
+}
+
+func (queue *capturingPushQueue) Enqueue(_ context.Context, task *PushQueueTask, timeout time.Duration) (string, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.tasks = append(queue.tasks, task)
+	queue.timeouts = append(queue.timeouts, timeout)
+	return fmt.Sprintf("task-%d", len(queue.tasks)), nil
+}
+
+func TestMessageService_SendMessageRequestID(t *testing.T) {
+	t.Run("request ID is carried by the event from the request to the listener", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		buffer := &lockedBuffer{}
+		zl := zerolog.New(buffer)
+		logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+		tracer := telemetry.NewOtelLogger("test", logger)
+		histogram, _ := noop.NewMeterProvider().Meter("test").Float64Histogram("test")
+		queue := &capturingPushQueue{}
+		dispatcher := NewEventDispatcher(logger, tracer, histogram, queue, PushQueueConfig{}, memory.NewEventRepository())
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+		generator, _ := ids.NewGenerator(ids.StrategyTimeOrdered)
+		service := NewMessageService(logger, tracer, MessageServiceDeps{
+			Repository:      memory.NewMessageRepository(),
+			EventDispatcher: dispatcher,
+			EventRepository: memory.NewEventRepository(),
+			PhoneService:    NewPhoneService(logger, tracer, phoneRepository, nil, 0, PhoneClockConfig{}),
+			UsageLocation:   time.UTC,
+			IDs:             generator,
+		})
+
+		var listenerRequestID string
+		dispatcher.Subscribe(events.EventTypeMessageAPISent, func(ctx context.Context, event cloudevents.Event) error {
+			_, span, ctxLogger := tracer.StartWithLogger(ctx, logger)
+			defer span.End()
+
+			listenerRequestID = telemetry.RequestID(ctx)
+			ctxLogger.Info("handled event")
+			return nil
+		})
+
+		// Arrange
+		ctx := telemetry.WithRequestID(context.Background(), "req_8f9c71b8-b84e")
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+
+		// Act
+		_, err := service.SendMessage(ctx, MessageSendParams{
+			Owner:              owner,
+			Contact:            "+18005550100",
+			Content:            "This is a sample text message",
+			Source:             "/v1/messages/send",
+			UserID:             "user-a",
+			DailyLimitReserved: true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(queue.tasks))
+
+		var event cloudevents.Event
+		assert.Nil(t, json.Unmarshal(queue.tasks[0].Body, &event))
+		err = dispatcher.DispatchSync(context.Background(), event)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "req_8f9c71b8-b84e", telemetry.EventRequestID(event))
+		assert.Equal(t, "req_8f9c71b8-b84e", listenerRequestID)
+		assert.Contains(t, buffer.String(), `"message":"handled event"`)
+		for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			if strings.Contains(line, `"message":"handled event"`) {
+				assert.Contains(t, line, `"request_id":"req_8f9c71b8-b84e"`)
+			}
+		}
+	})
+}
+
+// stubContactRepository fetches the entities.Contact with a do-not-disturb window from a fixed set of contacts
+type stubContactRepository struct {
+	repositories.ContactRepository
+	contacts []*entities.Contact
+}
+
+func (repository *stubContactRepository) FetchDND(_ context.Context, userID entities.UserID, phoneNumbers []string) (map[string]*entities.Contact, error) {
+	result := map[string]*entities.Contact{}
+	for _, contact := range repository.contacts {
+		for _, phoneNumber := range phoneNumbers {
+			if contact.UserID == userID && contact.PhoneNumber == phoneNumber && contact.HasDND() {
+				result[phoneNumber] = contact
+			}
+		}
+	}
+	return result, nil
+}
+
+func TestMessageService_SendMessageDND(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+
+	// the window of the contact is from one hour ago until one hour from now
+	now := time.Now().UTC().Truncate(time.Minute)
+	end := now.Add(time.Hour)
+	contact := &entities.Contact{
+		UserID:      "user-a",
+		PhoneNumber: "+18005550100",
+		DNDStart:    now.Add(-time.Hour).Format("15:04"),
+		DNDEnd:      end.Format("15:04"),
+		DNDTimezone: "UTC",
+	}
+	contactRepository := &stubContactRepository{contacts: []*entities.Contact{contact}}
+
+	newParams := func(enforce DNDEnforcement, sendAt *time.Time) MessageSendParams {
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+		return MessageSendParams{
+			Owner:              owner,
+			Contact:            "+18005550100",
+			Content:            "This is a sample text message",
+			Source:             "/v1/messages/send",
+			UserID:             "user-a",
+			SendAt:             sendAt,
+			EnforceDND:         enforce,
+			DailyLimitReserved: true,
+		}
+	}
+
+	t.Run("message in the window of the contact is deferred until the window ends", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{ContactRepository: contactRepository})
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams(DNDEnforcementDefer, nil))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.NotNil(t, message.ScheduledSendTime)
+		assert.Equal(t, end, message.ScheduledSendTime.UTC())
+	})
+
+	t.Run("message in the window of the contact is rejected when it is strict", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{ContactRepository: contactRepository})
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams(DNDEnforcementStrict, nil))
+
+		// Assert
+		dndErr, ok := AsContactDNDError(err)
+		assert.Nil(t, message)
+		assert.True(t, ok)
+		assert.Equal(t, "+18005550100", dndErr.Contact)
+		assert.Equal(t, end, dndErr.Until)
+	})
+
+	t.Run("message scheduled after the window of the contact keeps its send time", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{ContactRepository: contactRepository})
+		sendAt := now.Add(3 * time.Hour)
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams(DNDEnforcementStrict, &sendAt))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, sendAt, message.ScheduledSendTime.UTC())
+	})
+
+	t.Run("message scheduled in the window of the contact is deferred until the window ends", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{ContactRepository: contactRepository})
+		sendAt := now.Add(30 * time.Minute)
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams(DNDEnforcementDefer, &sendAt))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, end, message.ScheduledSendTime.UTC())
+	})
+}
+
+// stubRoutingRuleRepository fetches the entities.RoutingRule of a user from a fixed set of rules
+type stubRoutingRuleRepository struct {
+	repositories.RoutingRuleRepository
+	rules []*entities.RoutingRule
+}
+
+func (repository *stubRoutingRuleRepository) Fetch(_ context.Context, userID entities.UserID) ([]*entities.RoutingRule, error) {
+	result := make([]*entities.RoutingRule, 0, len(repository.rules))
+	for _, rule := range repository.rules {
+		if rule.UserID == userID {
+			result = append(result, rule)
+		}
+	}
+	return result, nil
+}
+
+func TestMessageService_SendMessageRoutingRules(t *testing.T) {
+	uk := &entities.RoutingRule{ID: uuid.New(), UserID: "user-a", Prefix: "+44", Owner: "+447700900000"}
+	defaultRoute := &entities.RoutingRule{ID: uuid.New(), UserID: "user-a", Prefix: "", Owner: "+18005550199"}
+	routingRuleRepository := &stubRoutingRuleRepository{rules: []*entities.RoutingRule{defaultRoute, uk}}
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{
+		{UserID: "user-a", PhoneNumber: "+18005550199"},
+		{UserID: "user-a", PhoneNumber: "+447700900000"},
+		{UserID: "user-a", PhoneNumber: "+237670000000"},
+	}}
+
+	newParams := func(contact string) MessageSendParams {
+		return MessageSendParams{
+			Contact:            contact,
+			Content:            "This is a sample text message",
+			Source:             "/v1/messages/send",
+			UserID:             "user-a",
+			DailyLimitReserved: true,
+		}
+	}
+
+	t.Run("message without an owner is sent from the owner of the matching prefix", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{RoutingRuleRepository: routingRuleRepository})
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams("+447911123456"))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+447700900000", message.Owner)
+		assert.Equal(t, &uk.ID, message.RoutingRuleID)
+	})
+
+	t.Run("message without a matching prefix is sent from the owner of the default route", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{RoutingRuleRepository: routingRuleRepository})
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams("+23767000001"))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550199", message.Owner)
+		assert.Equal(t, &defaultRoute.ID, message.RoutingRuleID)
+	})
+
+	t.Run("message with an owner is not routed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{RoutingRuleRepository: routingRuleRepository})
+		params := newParams("+447911123456")
+		params.Owner, _ = phonenumbers.Parse("+237670000000", phonenumbers.UNKNOWN_REGION)
+
+		// Act
+		message, err := service.SendMessage(context.Background(), params)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+237670000000", message.Owner)
+		assert.Nil(t, message.RoutingRuleID)
+	})
+}
+
+// stubMessageRateRepository fetches the entities.MessageRate of a user from a fixed set of rates
+type stubMessageRateRepository struct {
+	repositories.MessageRateRepository
+	rates []*entities.MessageRate
+}
+
+func (repository *stubMessageRateRepository) Fetch(_ context.Context, userID entities.UserID) ([]*entities.MessageRate, error) {
+	result := make([]*entities.MessageRate, 0, len(repository.rates))
+	for _, rate := range repository.rates {
+		if rate.UserID == userID {
+			result = append(result, rate)
+		}
+	}
+	return result, nil
+}
+
+func TestMessageService_SendMessageCost(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+
+	newParams := func(content string) MessageSendParams {
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+		return MessageSendParams{
+			Owner:              owner,
+			Contact:            "+447911123456",
+			Content:            content,
+			Source:             "/v1/messages/send",
+			UserID:             "user-a",
+			RequestReceivedAt:  time.Now().UTC(),
+			DailyLimitReserved: true,
+		}
+	}
+
+	t.Run("cost of the segments is estimated with the rate of the contact", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{MessageRateRepository: &stubMessageRateRepository{rates: []*entities.MessageRate{
+			{UserID: "user-a", Rate: 0.01, Currency: "USD", EffectiveFrom: time.Now().UTC().Add(-time.Hour)},
+			{UserID: "user-a", Prefix: "+44", Rate: 0.04, Currency: "GBP", EffectiveFrom: time.Now().UTC().Add(-time.Hour)},
+		}}})
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams(strings.Repeat("a", 200)))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uint(2), message.Segments)
+		assert.Equal(t, 0.08, *message.Cost)
+		assert.Equal(t, "GBP", *message.CostCurrency)
+	})
+
+	t.Run("cost is not estimated without a rate", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{MessageRateRepository: &stubMessageRateRepository{rates: []*entities.MessageRate{{UserID: "user-a", Rate: 0.01, Currency: "USD", EffectiveFrom: time.Now().UTC().Add(time.Hour)}}}})
+
+		// Act
+		message, err := service.SendMessage(context.Background(), newParams("This is a sample text message"))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uint(1), message.Segments)
+		assert.Nil(t, message.Cost)
+		assert.Nil(t, message.CostCurrency)
+	})
+}
+
+// stubPhoneDailySendUsageRepository counts the sent messages of the phones in memory
+type stubPhoneDailySendUsageRepository struct {
+	repositories.PhoneDailySendUsageRepository
+	mutex sync.Mutex
+	sent  map[uuid.UUID]uint
+}
+
+func (repository *stubPhoneDailySendUsageRepository) Reserve(_ context.Context, _ entities.UserID, phoneID uuid.UUID, _ time.Time, limit uint) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if repository.sent[phoneID] >= limit {
+		return false, nil
+	}
+	repository.sent[phoneID]++
+	return true, nil
+}
+
+func (repository *stubPhoneDailySendUsageRepository) Release(_ context.Context, phoneID uuid.UUID, _ time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if repository.sent[phoneID] > 0 {
+		repository.sent[phoneID]--
+	}
+	return nil
+}
+
+func TestMessageService_GetOutstandingMessagesPerMinute(t *testing.T) {
+	phone := entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199", MessagesPerMinute: 3}
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{phone}}
+
+	newOutstanding := func(t *testing.T, repository repositories.MessageRepository, count int) []*entities.Message {
+		messages := make([]*entities.Message, count)
+		for i := range messages {
+			messages[i] = &entities.Message{
+				ID:      uuid.New(),
+				UserID:  "user-a",
+				Owner:   phone.PhoneNumber,
+				Contact: "+18005550100",
+				Content: fmt.Sprintf("This is sample text message [%d]", i),
+				Type:    entities.MessageTypeMobileTerminated,
+				Status:  entities.MessageStatusPending,
+			}
+		}
+		_, err := repository.StoreMany(context.Background(), messages)
+		assert.Nil(t, err)
+		return messages
+	}
+
+	t.Run("the message after the limit in the window is throttled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		messages := newOutstanding(t, service.repository, int(phone.MessagesPerMinute)+1)
+		timestamp := time.Now().UTC()
+
+		// Act
+		for _, message := range messages[:phone.MessagesPerMinute] {
+			_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: timestamp})
+			assert.Nil(t, err)
+		}
+		_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: messages[phone.MessagesPerMinute].ID, Timestamp: timestamp})
+
+		// Assert
+		assert.Equal(t, ErrCodeRateLimited, stacktrace.GetCode(err))
+
+		stored, err := service.repository.Load(context.Background(), "user-a", messages[phone.MessagesPerMinute].ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusPending, stored.Status)
+	})
+
+	t.Run("the throttled message is released after the window", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		messages := newOutstanding(t, service.repository, int(phone.MessagesPerMinute)+1)
+		timestamp := time.Now().UTC()
+
+		for _, message := range messages[:phone.MessagesPerMinute] {
+			_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: timestamp})
+			assert.Nil(t, err)
+		}
+		_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: messages[phone.MessagesPerMinute].ID, Timestamp: timestamp})
+		assert.Equal(t, ErrCodeRateLimited, stacktrace.GetCode(err))
+
+		// Act
+		message, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: messages[phone.MessagesPerMinute].ID, Timestamp: timestamp.Add(time.Minute + time.Second)})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusSending, message.Status)
+	})
+}
+
+func TestMessageService_GetOutstandingDailySend(t *testing.T) {
+	phone := entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199", DailySendLimit: 10}
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{phone}}
+
+	newOutstanding := func(t *testing.T, repository repositories.MessageRepository, mediaCount uint) *entities.Message {
+		message := &entities.Message{
+			ID:         uuid.New(),
+			UserID:     "user-a",
+			Owner:      phone.PhoneNumber,
+			Contact:    "+18005550100",
+			Content:    "This is a sample text message",
+			Type:       entities.MessageTypeMobileTerminated,
+			Status:     entities.MessageStatusPending,
+			MediaCount: mediaCount,
+		}
+		_, err := repository.StoreMany(context.Background(), []*entities.Message{message})
+		assert.Nil(t, err)
+		return message
+	}
+
+	t.Run("a released message is counted in the daily send usage of the phone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubPhoneDailySendUsageRepository{sent: map[uuid.UUID]uint{}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{PhoneUsageRepository: usageRepository})
+		message := newOutstanding(t, service.repository, 0)
+
+		// Act
+		_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, uint(1), usageRepository.sent[phone.ID])
+	})
+
+	t.Run("a message whose media cannot be loaded is released from the daily send usage of the phone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		usageRepository := &stubPhoneDailySendUsageRepository{sent: map[uuid.UUID]uint{phone.ID: 3}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{PhoneUsageRepository: usageRepository})
+		message := newOutstanding(t, service.repository, 1)
+
+		// Act
+		_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Equal(t, ErrCodeMediaUnavailable, stacktrace.GetCode(err))
+		assert.Equal(t, uint(3), usageRepository.sent[phone.ID])
+	})
+}
+
+func TestMessageService_GetOutstandingFailure(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199"}}}
+
+	newOutstanding := func(t *testing.T, repository repositories.MessageRepository, mediaCount uint) *entities.Message {
+		message := &entities.Message{
+			ID:         uuid.New(),
+			UserID:     "user-a",
+			Owner:      "+18005550199",
+			Contact:    "+18005550100",
+			Content:    "This is a sample text message",
+			Type:       entities.MessageTypeMobileTerminated,
+			Status:     entities.MessageStatusPending,
+			MediaCount: mediaCount,
+		}
+		_, err := repository.StoreMany(context.Background(), []*entities.Message{message})
+		assert.Nil(t, err)
+		return message
+	}
+
+	t.Run("a message whose media cannot be loaded is pending again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{})
+		message := newOutstanding(t, service.repository, 1)
+
+		// Act
+		_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+
+		// Assert
+		assert.Equal(t, ErrCodeMediaUnavailable, stacktrace.GetCode(err))
+
+		stored, err := service.repository.Load(context.Background(), "user-a", message.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusPending, stored.Status)
+	})
+
+	t.Run("a message whose event cannot be dispatched can be fetched again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		eventRepository := memory.NewEventRepository()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{
+			EventRepository: eventRepository,
+			EventDispatcher: newTestQueueEventDispatcher(&failingPushQueue{}, eventRepository),
+		})
+		message := newOutstanding(t, service.repository, 0)
+
+		// Act
+		_, err := service.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+
+		// Assert
+		assert.NotNil(t, err)
+
+		stored, err := service.repository.Load(context.Background(), "user-a", message.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusPending, stored.Status)
+
+		outstanding, err := service.repository.GetOutstanding(context.Background(), "user-a", message.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusSending, outstanding.Status)
+	})
+}
+
+func TestMessageService_GetMessageEvents(t *testing.T) {
+	t.Run("events dispatched for a message are fetched by the message ID", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		zl := zerolog.Nop()
+		logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+		tracer := telemetry.NewOtelLogger("test", logger)
+		histogram, _ := noop.NewMeterProvider().Meter("test").Float64Histogram("test")
+		eventRepository := memory.NewEventRepository()
+		dispatcher := NewEventDispatcher(logger, tracer, histogram, &capturingPushQueue{}, PushQueueConfig{}, eventRepository)
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+		generator, _ := ids.NewGenerator(ids.StrategyTimeOrdered)
+		service := NewMessageService(logger, tracer, MessageServiceDeps{
+			Repository:      memory.NewMessageRepository(),
+			EventDispatcher: dispatcher,
+			EventRepository: eventRepository,
+			PhoneService:    NewPhoneService(logger, tracer, phoneRepository, nil, 0, PhoneClockConfig{}),
+			UsageLocation:   time.UTC,
+			IDs:             generator,
+		})
+
+		// Arrange
+		owner, _ := phonenumbers.Parse("+18005550199", phonenumbers.UNKNOWN_REGION)
+		params := MessageSendParams{
+			Owner:              owner,
+			Contact:            "+18005550100",
+			Content:            "This is a sample text message",
+			Source:             "/v1/messages/send",
+			UserID:             "user-a",
+			DailyLimitReserved: true,
+		}
+		message, err := service.SendMessage(context.Background(), params)
+		assert.Nil(t, err)
+		_, err = service.SendMessage(context.Background(), params)
+		assert.Nil(t, err)
+
+		// Act
+		messageEvents, err := service.GetMessageEvents(context.Background(), message.ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(*messageEvents))
+		assert.Equal(t, events.EventTypeMessageAPISent, (*messageEvents)[0].Type())
+		assert.Equal(t, "/v1/messages/send", (*messageEvents)[0].Source())
+	})
+}
+
+func TestMessageService_UpdateMessageContent(t *testing.T) {
+	newMessage := func(status entities.MessageStatus) *entities.Message {
+		return &entities.Message{
+			ID:        uuid.New(),
+			UserID:    "user-a",
+			Owner:     "+18005550199",
+			Contact:   "+18005550100",
+			Content:   "Helo world",
+			Status:    status,
+			Type:      entities.MessageTypeMobileTerminated,
+			UpdatedAt: time.Now().UTC().Add(-time.Minute),
+		}
+	}
+
+	t.Run("content of a pending message is updated and the previous content is in the events", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		eventRepository := memory.NewEventRepository()
+		service := newTestMessageService(&stubPhoneRepository{}, MessageServiceDeps{Repository: messageRepository, EventRepository: eventRepository})
+
+		// Arrange
+		message := newMessage(entities.MessageStatusPending)
+		assert.Nil(t, messageRepository.Store(context.Background(), message))
+
+		// Act
+		updated, err := service.UpdateMessageContent(context.Background(), "/v1/messages", message.UserID, message.ID, "Hello world")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "Hello world", updated.Content)
+		assert.Equal(t, message.Version+1, updated.Version)
+		assert.True(t, updated.UpdatedAt.After(message.UpdatedAt))
+
+		messageEvents, err := service.GetMessageEvents(context.Background(), message.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(*messageEvents))
+		assert.Equal(t, events.MessageAPIUpdated, (*messageEvents)[0].Type())
+
+		var payload events.MessageAPIUpdatedPayload
+		assert.Nil(t, (*messageEvents)[0].DataAs(&payload))
+		assert.Equal(t, "Helo world", payload.PreviousContent)
+		assert.Equal(t, "Hello world", payload.Content)
+	})
+
+	t.Run("content of a message which the phone has fetched cannot be updated", func(t *testing.T) {
+		for _, status := range []entities.MessageStatus{entities.MessageStatusSending, entities.MessageStatusSent, entities.MessageStatusFailed} {
+			status := status
+			t.Run(string(status), func(t *testing.T) {
+				// Setup
+				t.Parallel()
+				messageRepository := memory.NewMessageRepository()
+				service := newTestMessageService(&stubPhoneRepository{}, MessageServiceDeps{Repository: messageRepository})
+
+				// Arrange
+				message := newMessage(status)
+				assert.Nil(t, messageRepository.Store(context.Background(), message))
+
+				// Act
+				_, err := service.UpdateMessageContent(context.Background(), "/v1/messages", message.UserID, message.ID, "Hello world")
+
+				// Assert
+				assert.Equal(t, ErrCodeInvalidTransition, stacktrace.GetCode(err))
+				loaded, loadErr := messageRepository.Load(context.Background(), message.UserID, message.ID)
+				assert.Nil(t, loadErr)
+				assert.Equal(t, "Helo world", loaded.Content)
+			})
+		}
+	})
+
+	t.Run("content which is too long is a validation error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		service := newTestMessageService(&stubPhoneRepository{}, MessageServiceDeps{Repository: messageRepository})
+
+		// Arrange
+		message := newMessage(entities.MessageStatusScheduled)
+		assert.Nil(t, messageRepository.Store(context.Background(), message))
+
+		// Act
+		_, err := service.UpdateMessageContent(context.Background(), "/v1/messages", message.UserID, message.ID, strings.Repeat("é", messageContentMaxLength+1))
+
+		// Assert
+		validationErr, ok := AsValidationError(err)
+		assert.True(t, ok)
+		assert.Contains(t, validationErr.Errors, "content")
+	})
+
+	t.Run("ciphertext of an encrypted message can be longer than the content", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		service := newTestMessageService(&stubPhoneRepository{}, MessageServiceDeps{Repository: messageRepository})
+
+		// Arrange
+		message := newMessage(entities.MessageStatusScheduled)
+		message.IsEncrypted = true
+		assert.Nil(t, messageRepository.Store(context.Background(), message))
+		ciphertext := strings.Repeat("A", messageContentMaxLength+100)
+
+		// Act
+		updated, err := service.UpdateMessageContent(context.Background(), "/v1/messages", message.UserID, message.ID, ciphertext)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, ciphertext, updated.Content)
+		assert.Equal(t, uint(0), updated.Segments)
+	})
+}
+
+// lockedBuffer is a bytes.Buffer which can be written by the goroutines of the dispatcher
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *lockedBuffer) Write(p []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *lockedBuffer) String() string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.String()
+}
+
+// failingMessageRepository fails to index messages
+type failingMessageRepository struct {
+	repositories.MessageRepository
+}
+
+func (repository *failingMessageRepository) Index(_ context.Context, _ entities.UserID, _ string, _ string, _ repositories.IndexParams) (*[]entities.Message, error) {
+	return nil, stacktrace.NewError("connection refused")
+}
+
+func TestMessageService_GetMessages(t *testing.T) {
+	t.Run("search query and phone numbers are redacted in the error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestMessageService(nil, MessageServiceDeps{Repository: &failingMessageRepository{}})
+
+		// Act
+		_, err := service.GetMessages(context.Background(), MessageGetParams{
+			IndexParams: repositories.IndexParams{Query: "Your OTP is 482910", Limit: 20},
+			UserID:      "user-a",
+			Owner:       "+18005550199",
+			Contact:     "+18005550100",
+		})
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.NotContains(t, err.Error(), "482910")
+		assert.NotContains(t, err.Error(), "+18005550100")
+		assert.Contains(t, err.Error(), "+*******0100")
+	})
+}
+
+func TestMessageService_StoreSentMessage(t *testing.T) {
+	t.Run("storing the same message twice creates a single message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := memory.NewMessageRepository()
+		service := newTestMessageService(nil, MessageServiceDeps{Repository: repository})
+
+		// Arrange
+		payload := events.MessageAPISentPayload{
+			MessageID:         uuid.New(),
+			UserID:            "user-id",
+			Owner:             "+18005550199",
+			Contact:           "+18005550100",
+			Content:           "This is a sample text message",
+			RequestReceivedAt: time.Now().UTC(),
+		}
+
+		// Act
+		first, firstErr := service.storeSentMessage(context.Background(), payload)
+		second, secondErr := service.storeSentMessage(context.Background(), payload)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, first.CreatedAt, second.CreatedAt)
+		messages, _ := repository.Index(context.Background(), payload.UserID, payload.Owner, payload.Contact, repositories.IndexParams{Limit: 10})
+		assert.Len(t, *messages, 1)
+	})
+}
+
+func TestMessageService_StoreReceivedMessage(t *testing.T) {
+	t.Run("storing the same message twice creates a single message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := memory.NewMessageRepository()
+		service := newTestMessageService(nil, MessageServiceDeps{Repository: repository})
+
+		// Arrange
+		payload := events.MessagePhoneReceivedPayload{
+			MessageID: uuid.New(),
+			UserID:    "user-id",
+			Owner:     "+18005550199",
+			Contact:   "+18005550100",
+			Content:   "This is a sample text message",
+			Timestamp: time.Now().UTC(),
+		}
+
+		// Act
+		_, firstErr := service.storeReceivedMessage(context.Background(), payload)
+		second, secondErr := service.storeReceivedMessage(context.Background(), payload)
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, payload.MessageID, second.ID)
+		messages, _ := repository.Index(context.Background(), payload.UserID, payload.Owner, payload.Contact, repositories.IndexParams{Limit: 10})
+		assert.Len(t, *messages, 1)
+	})
+}
+
+func TestMessageService_UpdateMessage(t *testing.T) {
+	t.Run("concurrent events for the same message do not overwrite each other", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		messageRepository := newStubMessageRepository(entities.Message{ID: uuid.New(), UserID: "user-a", Status: entities.MessageStatusSending}, 2)
+		service := newTestMessageService(nil, MessageServiceDeps{Repository: messageRepository})
+		params := HandleMessageParams{ID: messageRepository.message.ID, UserID: "user-a", Timestamp: time.Now().UTC()}
+		wg := sync.WaitGroup{}
+		var sendingErr, notificationErr error
+
+		// Act
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sendingErr = service.HandleMessageSending(context.Background(), params)
+		}()
+		go func() {
+			defer wg.Done()
+			notificationErr = service.HandleMessageNotificationSent(context.Background(), params)
+		}()
+		wg.Wait()
+
+		// Assert
+		assert.Nil(t, sendingErr)
+		assert.Nil(t, notificationErr)
+		assert.Equal(t, 3, messageRepository.loads)
+		assert.Equal(t, uint(2), messageRepository.message.Version)
+		assert.Equal(t, uint(1), messageRepository.message.SendAttemptCount)
+		assert.NotNil(t, messageRepository.message.LastAttemptedAt)
+	})
+
+	t.Run("update fails after the maximum number of attempts", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		messageRepository := newStubMessageRepository(entities.Message{ID: uuid.New(), UserID: "user-a", Status: entities.MessageStatusSending}, 0)
+		messageRepository.alwaysFail = true
+		service := newTestMessageService(nil, MessageServiceDeps{Repository: messageRepository})
+
+		// Act
+		err := service.HandleMessageNotificationSent(context.Background(), HandleMessageParams{ID: messageRepository.message.ID, UserID: "user-a"})
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeStaleUpdate, stacktrace.GetCode(err))
+		assert.Equal(t, messageUpdateMaxAttempts, messageRepository.loads)
+	})
+}
+
+func TestMessageService_UpdateArchivedMessage(t *testing.T) {
+	// Setup
+	t.Parallel()
+	service := newTestMessageService(nil, MessageServiceDeps{})
+	timestamp := time.Now().UTC().Add(-365 * 24 * time.Hour)
+	message := &entities.Message{
+		ID:                uuid.New(),
+		UserID:            "user-a",
+		Owner:             "+18005550199",
+		Contact:           "+18005550100",
+		Content:           "This is a sample text message",
+		Type:              entities.MessageTypeMobileTerminated,
+		Status:            entities.MessageStatusDelivered,
+		RequestReceivedAt: timestamp,
+		OrderTimestamp:    timestamp,
+	}
+	_, err := service.repository.StoreMany(context.Background(), []*entities.Message{message})
+	assert.Nil(t, err)
+	_, err = service.repository.Archive(context.Background(), time.Now().UTC(), 10)
+	assert.Nil(t, err)
+
+	// Act
+	attempts := 0
+	_, err = service.updateMessage(context.Background(), message.UserID, message.ID, func(message *entities.Message) (bool, error) {
+		attempts++
+		message.Content = "updated content"
+		return true, nil
+	})
+
+	// Assert
+	assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+	assert.Equal(t, 1, attempts)
+}
+
+func TestMessageService_HandleMessageSent(t *testing.T) {
+	t.Run("message and status transition are added to the span", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		repository := memory.NewMessageRepository()
+		service := newTestMessageService(nil, MessageServiceDeps{Repository: repository})
+
+		// Arrange
+		message := &entities.Message{
+			ID:      uuid.New(),
+			UserID:  "user-a",
+			Owner:   "+18005550199",
+			Contact: "+18005550100",
+			Status:  entities.MessageStatusSending,
+			Type:    entities.MessageTypeMobileTerminated,
+		}
+		assert.Nil(t, repository.Store(context.Background(), message))
+		ctx, root := provider.Tracer("test").Start(context.Background(), "test")
+
+		// Act
+		err := service.HandleMessageSent(ctx, HandleMessageParams{ID: message.ID, UserID: message.UserID, Timestamp: time.Now().UTC()})
+		root.End()
+
+		// Assert
+		assert.Nil(t, err)
+		var attributes map[string]string
+		for _, span := range recorder.Ended() {
+			if strings.HasSuffix(span.Name(), "HandleMessageSent") {
+				attributes = map[string]string{}
+				for _, attribute := range span.Attributes() {
+					attributes[string(attribute.Key)] = attribute.Value.Emit()
+				}
+			}
+		}
+		assert.Equal(t, message.ID.String(), attributes[string(telemetry.AttributeMessageID)])
+		assert.Equal(t, telemetry.HashOwner(message.Owner), attributes[string(telemetry.AttributeOwnerHash)])
+		assert.Equal(t, string(entities.MessageStatusSending), attributes[string(telemetry.AttributeMessagePreviousStatus)])
+		assert.Equal(t, string(entities.MessageStatusSent), attributes[string(telemetry.AttributeMessageStatus)])
+	})
+}
+
+func TestMessageOwnerPhones(t *testing.T) {
+	t.Run("concurrent messages from the same owner load the phone once", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+		ownerPhones := NewMessageOwnerPhones()
+		wg := sync.WaitGroup{}
+
+		// Act
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = ownerPhones.load("user-a", "+18005550199", func() (*entities.Phone, error) {
+					return phoneRepository.LoadOwner(context.Background(), "user-a", "+18005550199")
+				})
+			}()
+		}
+		wg.Wait()
+
+		// Assert
+		assert.Equal(t, int64(1), atomic.LoadInt64(&phoneRepository.loads))
+	})
+
+	t.Run("owners of different users are not shared", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-b", PhoneNumber: "+18005550199"}}}
+		ownerPhones := NewMessageOwnerPhones()
+		loader := func(userID entities.UserID) func() (*entities.Phone, error) {
+			return func() (*entities.Phone, error) {
+				return phoneRepository.LoadOwner(context.Background(), userID, "+18005550199")
+			}
+		}
+
+		// Act
+		_, errB := ownerPhones.load("user-b", "+18005550199", loader("user-b"))
+		_, errA := ownerPhones.load("user-a", "+18005550199", loader("user-a"))
+
+		// Assert
+		assert.Nil(t, errB)
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(errA))
+	})
+}
+
+// stubForwardUserRepository loads a user without a daily message limit
+type stubForwardUserRepository struct {
+	repositories.UserRepository
+}
+
+func (repository *stubForwardUserRepository) Load(_ context.Context, userID entities.UserID) (*entities.User, error) {
+	return &entities.User{ID: userID}, nil
+}
+
+func TestMessageService_ForwardMessage(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+
+	newReceivedMessage := func() entities.Message {
+		return entities.Message{
+			ID:             uuid.New(),
+			UserID:         "user-a",
+			Owner:          "+18005550199",
+			Contact:        "+237677777777",
+			Content:        "The pump at site 4 is broken",
+			Type:           entities.MessageTypeMobileOriginated,
+			Status:         entities.MessageStatusReceived,
+			OrderTimestamp: time.Now().UTC(),
+		}
+	}
+
+	t.Run("received message is forwarded with a prefix and linked to its forward", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{Repository: messageRepository, UserRepository: &stubForwardUserRepository{}})
+
+		// Arrange
+		original := newReceivedMessage()
+		assert.Nil(t, messageRepository.Store(context.Background(), &original))
+
+		// Act
+		message, err := service.ForwardMessage(context.Background(), MessageForwardParams{
+			UserID:    "user-a",
+			MessageID: original.ID,
+			Owner:     "+18005550199",
+			Contact:   "+18005550100",
+			Prefix:    "FWD from +237677777777:",
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "FWD from +237677777777: The pump at site 4 is broken", message.Content)
+		assert.Equal(t, "+18005550100", message.Contact)
+		assert.Equal(t, &original.ID, message.ForwardedFromID)
+
+		stored, err := messageRepository.Load(context.Background(), "user-a", original.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, &message.ID, stored.ForwardedToID)
+	})
+
+	t.Run("message received by another phone cannot be forwarded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{Repository: messageRepository, UserRepository: &stubForwardUserRepository{}})
+
+		// Arrange
+		original := newReceivedMessage()
+		assert.Nil(t, messageRepository.Store(context.Background(), &original))
+
+		// Act
+		_, err := service.ForwardMessage(context.Background(), MessageForwardParams{
+			UserID:    "user-a",
+			MessageID: original.ID,
+			Owner:     "+18005550111",
+			Contact:   "+18005550100",
+		})
+
+		// Assert
+		forwardErr, ok := AsMessageNotForwardableError(err)
+		assert.True(t, ok)
+		assert.Equal(t, original.ID, forwardErr.MessageID)
+	})
+
+	t.Run("deleted message and message of another user are not found", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{Repository: messageRepository, UserRepository: &stubForwardUserRepository{}})
+
+		// Arrange
+		deleted := newReceivedMessage()
+		assert.Nil(t, messageRepository.Store(context.Background(), &deleted))
+		assert.Nil(t, messageRepository.Delete(context.Background(), deleted.UserID, deleted.ID))
+
+		other := newReceivedMessage()
+		other.UserID = "user-b"
+		assert.Nil(t, messageRepository.Store(context.Background(), &other))
+
+		for _, messageID := range []uuid.UUID{deleted.ID, other.ID} {
+			// Act
+			_, err := service.ForwardMessage(context.Background(), MessageForwardParams{
+				UserID:    "user-a",
+				MessageID: messageID,
+				Owner:     "+18005550199",
+				Contact:   "+18005550100",
+			})
+
+			// Assert
+			assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+		}
+	})
+}
+
+func TestMessageService_ReplyInThread(t *testing.T) {
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{{UserID: "user-a", PhoneNumber: "+18005550199"}}}
+
+	newThread := func(isArchived bool) entities.MessageThread {
+		return entities.MessageThread{
+			ID:         uuid.New(),
+			UserID:     "user-a",
+			Owner:      "+18005550199",
+			Contact:    "+237677777777",
+			IsArchived: isArchived,
+		}
+	}
+
+	newReceivedMessage := func(timestamp time.Time) entities.Message {
+		return entities.Message{
+			ID:             uuid.New(),
+			UserID:         "user-a",
+			Owner:          "+18005550199",
+			Contact:        "+237677777777",
+			Content:        "Is the pump at site 4 fixed?",
+			Type:           entities.MessageTypeMobileOriginated,
+			Status:         entities.MessageStatusReceived,
+			OrderTimestamp: timestamp,
+		}
+	}
+
+	t.Run("reply is sent to the contact of the thread in reply to the latest received message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		messageRepository := memory.NewMessageRepository()
+		thread := newThread(false)
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{Repository: messageRepository, UserRepository: &stubForwardUserRepository{}, ThreadRepository: newStubMessageThreadRepository(thread)})
+
+		// Arrange
+		older := newReceivedMessage(time.Now().UTC().Add(-time.Hour))
+		latest := newReceivedMessage(time.Now().UTC().Add(-time.Minute))
+		for _, message := range []entities.Message{older, latest} {
+			message := message
+			assert.Nil(t, messageRepository.Store(context.Background(), &message))
+		}
+
+		// Act
+		message, err := service.ReplyInThread(context.Background(), "user-a", thread.ID, "Yes, it was fixed this morning", MessageReplyOptions{})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550199", message.Owner)
+		assert.Equal(t, "+237677777777", message.Contact)
+		assert.Equal(t, entities.MessageType(entities.MessageTypeMobileTerminated), message.Type)
+		assert.Equal(t, &thread.ID, message.ThreadID)
+		assert.Equal(t, &latest.ID, message.InReplyToID)
+	})
+
+	t.Run("reply in a thread without received messages is not in reply to a message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread(false)
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: &stubForwardUserRepository{}, ThreadRepository: newStubMessageThreadRepository(thread)})
+
+		// Act
+		message, err := service.ReplyInThread(context.Background(), "user-a", thread.ID, "Hello", MessageReplyOptions{})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, &thread.ID, message.ThreadID)
+		assert.Nil(t, message.InReplyToID)
+	})
+
+	t.Run("reply in an archived thread unarchives it", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread(true)
+		threadRepository := newStubMessageThreadRepository(thread)
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: &stubForwardUserRepository{}, ThreadRepository: threadRepository})
+
+		// Act
+		_, err := service.ReplyInThread(context.Background(), "user-a", thread.ID, "Hello again", MessageReplyOptions{})
+
+		// Assert
+		assert.Nil(t, err)
+		stored, err := threadRepository.Load(context.Background(), "user-a", thread.ID)
+		assert.Nil(t, err)
+		assert.False(t, stored.IsArchived)
+	})
+
+	t.Run("unknown thread and thread of another user are not found", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		other := newThread(false)
+		other.UserID = "user-b"
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: &stubForwardUserRepository{}, ThreadRepository: newStubMessageThreadRepository(other)})
+
+		for _, threadID := range []uuid.UUID{uuid.New(), other.ID} {
+			// Act
+			_, err := service.ReplyInThread(context.Background(), "user-a", threadID, "Hello", MessageReplyOptions{})
+
+			// Assert
+			assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+		}
+	})
+
+	t.Run("reply without content is not sent", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread(true)
+		threadRepository := newStubMessageThreadRepository(thread)
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: &stubForwardUserRepository{}, ThreadRepository: threadRepository})
+
+		// Act
+		_, err := service.ReplyInThread(context.Background(), "user-a", thread.ID, "  ", MessageReplyOptions{})
+
+		// Assert
+		_, ok := AsValidationError(err)
+		assert.True(t, ok)
+		stored, _ := threadRepository.Load(context.Background(), "user-a", thread.ID)
+		assert.True(t, stored.IsArchived)
+	})
+}
+
+// fakeClock is the time of a simulation which only moves when it is advanced
+type fakeClock struct {
+	now time.Time
+}
+
+func (clock *fakeClock) Advance(duration time.Duration) time.Time {
+	clock.now = clock.now.Add(duration)
+	return clock.now
+}
+
+func TestMessageService_RetryPolicy(t *testing.T) {
+	const owner = "+18005550199"
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	phoneRepository := &stubPhoneRepository{}
+	for _, userID := range []entities.UserID{"user-a", "user-otp", "user-bulk"} {
+		phoneRepository.phones = append(phoneRepository.phones, entities.Phone{UserID: userID, PhoneNumber: owner, MaxSendAttempts: 2, MessageExpirationSeconds: 600})
+	}
+
+	send := func(t *testing.T, service *MessageService, userID entities.UserID, override entities.RetryPolicy) *entities.Message {
+		phoneNumber, _ := phonenumbers.Parse(owner, phonenumbers.UNKNOWN_REGION)
+		message, err := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:              phoneNumber,
+			Contact:            "+18005550100",
+			Content:            "Your code is 1234",
+			Source:             "/v1/messages/send",
+			UserID:             userID,
+			DailyLimitReserved: true,
+			RetryPolicy:        override,
+		})
+		assert.Nil(t, err)
+		return message
+	}
+
+	// retryTimeline sends the message to the phone with a fake clock until it fails, every attempt expires after the
+	// message expiration of the phone and the times of the retries are returned as offsets from the start
+	retryTimeline := func(t *testing.T, service *MessageService, queue *capturingPushQueue, message *entities.Message) []time.Duration {
+		clock := &fakeClock{now: start}
+		params := func() HandleMessageParams {
+			return HandleMessageParams{ID: message.ID, UserID: message.UserID, Source: "/v1/messages/send", Timestamp: clock.now}
+		}
+
+		var timeline []time.Duration
+		for attempt := 0; attempt < 20; attempt++ {
+			assert.Nil(t, service.HandleMessageNotificationScheduled(context.Background(), params()))
+			assert.Nil(t, service.HandleMessageNotificationSent(context.Background(), params()))
+			clock.Advance(10 * time.Minute)
+
+			dispatched := len(queue.timeouts)
+			assert.Nil(t, service.HandleMessageExpired(context.Background(), params()))
+			if len(queue.timeouts) == dispatched {
+				return timeline
+			}
+			timeline = append(timeline, clock.Advance(queue.timeouts[len(queue.timeouts)-1]).Sub(start))
+		}
+		return timeline
+	}
+
+	t.Run("identical messages of two users follow the retry timelines of their policies", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		otpQueue, bulkQueue := &capturingPushQueue{}, &capturingPushQueue{}
+		userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{
+			"user-otp":  {ID: "user-otp", RetryPolicy: entities.RetryPolicy{MaxAttempts: 2, BackoffBaseSeconds: 5, BackoffCapSeconds: 5}},
+			"user-bulk": {ID: "user-bulk", RetryPolicy: entities.RetryPolicy{MaxAttempts: 5, BackoffBaseSeconds: 60, BackoffCapSeconds: 600, BackoffMultiplier: 3}},
+		}}
+		otpService := newTestMessageService(phoneRepository, MessageServiceDeps{EventDispatcher: newTestQueueEventDispatcher(otpQueue, memory.NewEventRepository()), UserRepository: userRepository})
+		bulkService := newTestMessageService(phoneRepository, MessageServiceDeps{EventDispatcher: newTestQueueEventDispatcher(bulkQueue, memory.NewEventRepository()), UserRepository: userRepository})
+
+		// Arrange
+		otpMessage := send(t, otpService, "user-otp", entities.RetryPolicy{})
+		bulkMessage := send(t, bulkService, "user-bulk", entities.RetryPolicy{})
+
+		// Act
+		otpTimeline := retryTimeline(t, otpService, otpQueue, otpMessage)
+		bulkTimeline := retryTimeline(t, bulkService, bulkQueue, bulkMessage)
+
+		// Assert
+		assert.Equal(t, []time.Duration{10*time.Minute + 5*time.Second}, otpTimeline)
+		assert.Equal(t, []time.Duration{
+			10*time.Minute + 1*time.Minute,
+			20*time.Minute + 4*time.Minute,
+			30*time.Minute + 13*time.Minute,
+			40*time.Minute + 23*time.Minute,
+		}, bulkTimeline)
+	})
+
+	t.Run("a message in flight keeps the retry policy which it was created with", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		queue := &capturingPushQueue{}
+		userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{"user-a": {ID: "user-a", RetryPolicy: entities.RetryPolicy{MaxAttempts: 3, BackoffBaseSeconds: 30}}}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{EventDispatcher: newTestQueueEventDispatcher(queue, memory.NewEventRepository()), UserRepository: userRepository})
+
+		// Arrange
+		message := send(t, service, "user-a", entities.RetryPolicy{})
+		userRepository.users["user-a"] = entities.User{ID: "user-a", RetryPolicy: entities.RetryPolicy{MaxAttempts: 1}}
+
+		// Act
+		timeline := retryTimeline(t, service, queue, message)
+
+		// Assert
+		assert.Equal(t, []time.Duration{10*time.Minute + 30*time.Second, 20*time.Minute + 90*time.Second}, timeline)
+	})
+
+	t.Run("the retry policy of a message overrides the policy of the user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		queue := &capturingPushQueue{}
+		userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{"user-a": {ID: "user-a", RetryPolicy: entities.RetryPolicy{MaxAttempts: 4, BackoffBaseSeconds: 60}}}}
+		service := newTestMessageService(phoneRepository, MessageServiceDeps{EventDispatcher: newTestQueueEventDispatcher(queue, memory.NewEventRepository()), UserRepository: userRepository})
+
+		// Arrange
+		message := send(t, service, "user-a", entities.RetryPolicy{MaxAttempts: 2})
+
+		// Act
+		timeline := retryTimeline(t, service, queue, message)
+
+		// Assert
+		assert.Equal(t, uint(2), message.MaxSendAttempts)
+		assert.Equal(t, []time.Duration{10*time.Minute + time.Minute}, timeline)
+	})
+}
+
+func TestMessageService_DeliveryTracking(t *testing.T) {
+	const owner = "+18005550199"
+
+	phoneRepository := &stubPhoneRepository{phones: []entities.Phone{
+		{UserID: "user-a", PhoneNumber: owner, MaxSendAttempts: 2, MessageExpirationSeconds: 600},
+		{UserID: "user-b", PhoneNumber: owner, MaxSendAttempts: 2, MessageExpirationSeconds: 600},
+	}}
+	newService := func(users ...entities.User) *MessageService {
+		userRepository := &stubAccountUserRepository{users: map[entities.UserID]entities.User{}}
+		for _, user := range users {
+			userRepository.users[user.ID] = user
+		}
+		return newTestMessageService(phoneRepository, MessageServiceDeps{UserRepository: userRepository})
+	}
+
+	// sendAndDeliver sends a message, picks it up like the phone and handles the sent and delivered events of the phone
+	sendAndDeliver := func(t *testing.T, service *MessageService, userID entities.UserID, requestDeliveryReport *bool) *entities.Message {
+		phoneNumber, _ := phonenumbers.Parse(owner, phonenumbers.UNKNOWN_REGION)
+		message, err := service.SendMessage(context.Background(), MessageSendParams{
+			Owner:                 phoneNumber,
+			Contact:               "+18005550100",
+			Content:               "Your code is 1234",
+			Source:                "/v1/messages/send",
+			UserID:                userID,
+			DailyLimitReserved:    true,
+			RequestDeliveryReport: requestDeliveryReport,
+		})
+		assert.Nil(t, err)
+
+		_, err = service.repository.GetOutstanding(context.Background(), userID, message.ID)
+		assert.Nil(t, err)
+
+		params := HandleMessageParams{ID: message.ID, UserID: userID, Source: "/v1/messages/send", Timestamp: time.Now().UTC()}
+		assert.Nil(t, service.HandleMessageSent(context.Background(), params))
+		assert.Nil(t, service.HandleMessageDelivered(context.Background(), params))
+
+		message, err = service.GetMessage(context.Background(), userID, message.ID)
+		assert.Nil(t, err)
+		return message
+	}
+
+	t.Run("a message without a delivery report ends its lifecycle when it is sent", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newService(entities.User{ID: "user-a", RequestDeliveryReports: true})
+		requestDeliveryReport := false
+
+		// Act
+		message := sendAndDeliver(t, service, "user-a", &requestDeliveryReport)
+
+		// Assert
+		assert.Equal(t, entities.MessageDeliveryTrackingOff, message.DeliveryTracking)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusSent), message.Status)
+		assert.Nil(t, message.DeliveredAt)
+	})
+
+	t.Run("a message uses the delivery report default of the user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newService(
+			entities.User{ID: "user-a", RequestDeliveryReports: true},
+			entities.User{ID: "user-b", RequestDeliveryReports: false},
+		)
+
+		// Act
+		tracked := sendAndDeliver(t, service, "user-a", nil)
+		untracked := sendAndDeliver(t, service, "user-b", nil)
+
+		// Assert
+		assert.Equal(t, entities.MessageDeliveryTrackingOn, tracked.DeliveryTracking)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusDelivered), tracked.Status)
+		assert.Equal(t, entities.MessageDeliveryTrackingOff, untracked.DeliveryTracking)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusSent), untracked.Status)
+	})
+
+	t.Run("a message can request a delivery report when the default of the user is off", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newService(entities.User{ID: "user-a", RequestDeliveryReports: false})
+		requestDeliveryReport := true
+
+		// Act
+		message := sendAndDeliver(t, service, "user-a", &requestDeliveryReport)
+
+		// Assert
+		assert.Equal(t, entities.MessageDeliveryTrackingOn, message.DeliveryTracking)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusDelivered), message.Status)
+	})
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+// MessageStatisticsService aggregates the messages of a user for the charts of the dashboard
+type MessageStatisticsService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.MessageStatisticsRepository
+}
+
+// NewMessageStatisticsService creates a new MessageStatisticsService
+func NewMessageStatisticsService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.MessageStatisticsRepository,
+) (s *MessageStatisticsService) {
+	return &MessageStatisticsService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+	}
+}
+
+// MessageTimeSeriesParams are parameters for creating an entities.MessageTimeSeries
+type MessageTimeSeriesParams struct {
+	From        time.Time
+	To          time.Time
+	Granularity entities.MessageStatisticsGranularity
+	Location    *time.Location
+	Owner       *string
+}
+
+// TimeSeries counts the messages of a user in each hour or day of a time range
+func (service *MessageStatisticsService) TimeSeries(ctx context.Context, userID entities.UserID, params *MessageTimeSeriesParams) (*entities.MessageTimeSeries, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	from, to := entities.MessageTimeSeriesRange(params.From, params.To, params.Granularity, params.Location)
+	counts, err := service.repository.CountHourly(ctx, userID, repositories.MessageStatisticsParams{
+		From:  from,
+		To:    to,
+		Owner: params.Owner,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the hourly messages of user [%s] from [%s] to [%s]", userID, params.From, params.To)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return entities.NewMessageTimeSeries(counts, params.From, params.To, params.Granularity, params.Location), nil
+}
+
+// MessageLatencyParams are parameters for creating an entities.MessageLatencySummary
+type MessageLatencyParams struct {
+	From      time.Time
+	To        time.Time
+	Owner     *string
+	Threshold time.Duration
+	Objective float64
+}
+
+// Latency summarizes the send latency of the outbound messages of a user in a time range against an SLO
+func (service *MessageStatisticsService) Latency(ctx context.Context, userID entities.UserID, params *MessageLatencyParams) (*entities.MessageLatencySummary, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	counts, err := service.repository.CountLatency(ctx, userID, repositories.MessageLatencyParams{
+		From:      params.From,
+		To:        params.To,
+		Owner:     params.Owner,
+		Threshold: params.Threshold,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the latency of the messages of user [%s] from [%s] to [%s]", userID, params.From, params.To)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return entities.NewMessageLatencySummary(params.From, params.To, params.Threshold, params.Objective, counts), nil
+}
@@ -87,6 +87,36 @@ func (service *MessageThreadService) UpdateThread(ctx context.Context, params Me
 	return nil
 }
 
+// ImportThread updates a thread with an imported message, the last message of the thread is only replaced when the
+// imported message is newer so that importing old history does not change the order of the threads
+func (service *MessageThreadService) ImportThread(ctx context.Context, params MessageThreadUpdateParams) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	thread, err := service.repository.LoadByOwnerContact(ctx, params.UserID, params.Owner, params.Contact)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return service.createThread(ctx, params)
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot find thread with owner [%s], and contact [%s] for imported message [%s]", params.Owner, params.Contact, params.MessageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !params.Timestamp.After(thread.OrderTimestamp) {
+		ctxLogger.Info(fmt.Sprintf("thread [%s] with timestamp [%s] is newer than imported message [%s]", thread.ID, thread.OrderTimestamp, params.MessageID))
+		return nil
+	}
+
+	if err = service.repository.Update(ctx, thread.Update(params.Timestamp, params.MessageID, params.Content, params.Status)); err != nil {
+		msg := fmt.Sprintf("cannot update message thread with id [%s] after importing message [%s]", thread.ID, params.MessageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("thread with id [%s] updated with imported message [%s]", thread.ID, params.MessageID))
+	return nil
+}
+
 // MessageThreadStatusParams are parameters for updating a thread status
 type MessageThreadStatusParams struct {
 	IsArchived      bool
@@ -116,6 +146,134 @@ func (service *MessageThreadService) UpdateStatus(ctx context.Context, params Me
 	return thread, nil
 }
 
+// MessageThreadReadParams are parameters for marking a thread as read
+type MessageThreadReadParams struct {
+	Source          string
+	UserID          entities.UserID
+	MessageThreadID uuid.UUID
+}
+
+// MarkRead marks the messages in a thread as read and dispatches the events.EventTypeMessageThreadRead event
+func (service *MessageThreadService) MarkRead(ctx context.Context, params MessageThreadReadParams) (*entities.MessageThread, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	thread, err := service.repository.Load(ctx, params.UserID, params.MessageThreadID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot find thread with id [%s]", params.MessageThreadID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	timestamp := time.Now().UTC()
+	if err = service.repository.Update(ctx, thread.MarkRead(timestamp)); err != nil {
+		msg := fmt.Sprintf("cannot mark message thread with id [%s] as read", thread.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	event, err := service.createEvent(ctx, events.EventTypeMessageThreadRead, params.Source, &events.MessageThreadReadPayload{
+		MessageThreadID: thread.ID,
+		UserID:          thread.UserID,
+		Owner:           thread.Owner,
+		Contact:         thread.Contact,
+		Timestamp:       timestamp,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create [%s] event for message thread with ID [%s]", events.EventTypeMessageThreadRead, thread.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.eventDispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] with id [%s] for message thread [%s]", event.Type(), event.ID(), thread.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("thread with id [%s] marked as read at [%s]", thread.ID, timestamp))
+	return thread, nil
+}
+
+// MessageThreadMuteParams are parameters for muting an entities.MessageThread
+type MessageThreadMuteParams struct {
+	UserID          entities.UserID
+	MessageThreadID uuid.UUID
+	// MutedUntil is the time when the mute is lifted, the thread is muted until it is unmuted when it is nil
+	MutedUntil   *time.Time
+	MuteWebhooks bool
+}
+
+// Mute stops the notifications of the messages which are received in a thread, the messages are still stored
+func (service *MessageThreadService) Mute(ctx context.Context, params MessageThreadMuteParams) (*entities.MessageThread, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	thread, err := service.repository.Load(ctx, params.UserID, params.MessageThreadID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot find thread with id [%s]", params.MessageThreadID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Update(ctx, thread.Mute(time.Now().UTC(), params.MutedUntil, params.MuteWebhooks)); err != nil {
+		msg := fmt.Sprintf("cannot mute message thread with id [%s]", thread.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("thread with id [%s] muted until [%v]", thread.ID, thread.MutedUntil))
+	return thread, nil
+}
+
+// Unmute sends the notifications of the messages which are received in a thread again
+func (service *MessageThreadService) Unmute(ctx context.Context, userID entities.UserID, messageThreadID uuid.UUID) (*entities.MessageThread, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	thread, err := service.repository.Load(ctx, userID, messageThreadID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot find thread with id [%s]", messageThreadID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Update(ctx, thread.Unmute(time.Now().UTC())); err != nil {
+		msg := fmt.Sprintf("cannot unmute message thread with id [%s]", thread.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("thread with id [%s] unmuted", thread.ID))
+	return thread, nil
+}
+
+// IsMuted checks if the notifications of the messages received from a contact by the owner are muted. The
+// notifications are sent when the thread cannot be loaded so that a message is not silently dropped.
+func (service *MessageThreadService) IsMuted(ctx context.Context, userID entities.UserID, owner, contact string) bool {
+	return service.loadMuted(ctx, userID, owner, contact) != nil
+}
+
+// IsWebhookMuted checks if the webhooks of the messages received from a contact by the owner are muted
+func (service *MessageThreadService) IsWebhookMuted(ctx context.Context, userID entities.UserID, owner, contact string) bool {
+	thread := service.loadMuted(ctx, userID, owner, contact)
+	return thread != nil && thread.IsWebhookMutedAt(time.Now().UTC())
+}
+
+// loadMuted returns the thread between the owner and the contact when it is muted
+func (service *MessageThreadService) loadMuted(ctx context.Context, userID entities.UserID, owner, contact string) *entities.MessageThread {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	thread, err := service.repository.LoadByOwnerContact(ctx, userID, owner, contact)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load thread between owner [%s] and contact [%s] for user [%s], the thread is not muted", owner, telemetry.RedactPhoneNumber(contact), userID)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return nil
+	}
+
+	if !thread.IsMutedAt(time.Now().UTC()) {
+		return nil
+	}
+	return thread
+}
+
 // UpdateAfterDeletedMessage updates a thread after the last message has been deleted
 func (service *MessageThreadService) UpdateAfterDeletedMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID) error {
 	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
@@ -130,6 +288,37 @@ func (service *MessageThreadService) UpdateAfterDeletedMessage(ctx context.Conte
 	return nil
 }
 
+// UpdateAfterUpdatedMessage changes the content of the last message of a thread when the content of the message is updated
+func (service *MessageThreadService) UpdateAfterUpdatedMessage(ctx context.Context, userID entities.UserID, owner, contact string, messageID uuid.UUID, content string) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	thread, err := service.repository.LoadByOwnerContact(ctx, userID, owner, contact)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Info(fmt.Sprintf("cannot find thread with owner [%s], and contact [%s] for updated message [%s]", owner, contact, messageID))
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot find thread with owner [%s], and contact [%s] for updated message [%s]", owner, contact, messageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !thread.HasLastMessage(messageID) {
+		ctxLogger.Info(fmt.Sprintf("updated message [%s] is not the last message of thread [%s]", messageID, thread.ID))
+		return nil
+	}
+
+	thread.LastMessageContent = &content
+	if err = service.repository.Update(ctx, thread); err != nil {
+		msg := fmt.Sprintf("cannot update content of message thread with id [%s] for message [%s]", thread.ID, messageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("content of last message [%s] has been updated in thread [%s]", messageID, thread.ID))
+	return nil
+}
+
 func (service *MessageThreadService) createThread(ctx context.Context, params MessageThreadUpdateParams) error {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
@@ -197,6 +386,7 @@ type MessageThreadGetParams struct {
 	IsArchived bool
 	UserID     entities.UserID
 	Owner      string
+	Label      string
 }
 
 // GetThreads fetches threads for an owner
@@ -206,12 +396,17 @@ func (service *MessageThreadService) GetThreads(ctx context.Context, params Mess
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	threads, err := service.repository.Index(ctx, params.UserID, params.Owner, params.IsArchived, params.IndexParams)
+	threads, err := service.repository.Index(ctx, params.UserID, params.Owner, params.IsArchived, repositories.MessageThreadIndexParams{IndexParams: params.IndexParams, Label: params.Label})
 	if err != nil {
 		msg := fmt.Sprintf("could not fetch messages threads for params [%+#v]", params)
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	timestamp := time.Now().UTC()
+	for index := range *threads {
+		(*threads)[index].LiftExpiredMute(timestamp)
+	}
+
 	ctxLogger.Info(fmt.Sprintf("fetched [%d] threads with params [%+#v]", len(*threads), params))
 	return threads, nil
 }
@@ -240,7 +435,7 @@ func (service *MessageThreadService) DeleteThread(ctx context.Context, source st
 		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	event, err := service.createEvent(events.MessageThreadAPIDeleted, source, &events.MessageThreadAPIDeletedPayload{
+	event, err := service.createEvent(ctx, events.MessageThreadAPIDeleted, source, &events.MessageThreadAPIDeletedPayload{
 		MessageThreadID: thread.ID,
 		UserID:          thread.UserID,
 		Owner:           thread.Owner,
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubMessageThreadRepository stores entities.MessageThread in memory
+type stubMessageThreadRepository struct {
+	repositories.MessageThreadRepository
+	mutex   sync.Mutex
+	threads map[uuid.UUID]entities.MessageThread
+	fail    bool
+}
+
+func (repository *stubMessageThreadRepository) Store(_ context.Context, thread *entities.MessageThread) error {
+	return repository.Update(context.Background(), thread)
+}
+
+func (repository *stubMessageThreadRepository) Update(_ context.Context, thread *entities.MessageThread) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.threads[thread.ID] = *thread
+	return nil
+}
+
+func (repository *stubMessageThreadRepository) Load(_ context.Context, userID entities.UserID, ID uuid.UUID) (*entities.MessageThread, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if thread, ok := repository.threads[ID]; ok && thread.UserID == userID {
+		return &thread, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "thread does not exist")
+}
+
+func (repository *stubMessageThreadRepository) LoadByOwnerContact(_ context.Context, userID entities.UserID, owner string, contact string) (*entities.MessageThread, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if repository.fail {
+		return nil, stacktrace.NewError("database is unavailable")
+	}
+	for _, thread := range repository.threads {
+		if thread.UserID == userID && thread.Owner == owner && thread.Contact == contact {
+			return &thread, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "thread does not exist")
+}
+
+func (repository *stubMessageThreadRepository) Index(_ context.Context, userID entities.UserID, owner string, _ bool, _ repositories.MessageThreadIndexParams) (*[]entities.MessageThread, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var threads []entities.MessageThread
+	for _, thread := range repository.threads {
+		if thread.UserID == userID && thread.Owner == owner {
+			threads = append(threads, thread)
+		}
+	}
+	return &threads, nil
+}
+
+func newStubMessageThreadRepository(threads ...entities.MessageThread) *stubMessageThreadRepository {
+	repository := &stubMessageThreadRepository{threads: map[uuid.UUID]entities.MessageThread{}}
+	for _, thread := range threads {
+		repository.threads[thread.ID] = thread
+	}
+	return repository
+}
+
+func newTestMessageThreadService(repository repositories.MessageThreadRepository) *MessageThreadService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	return NewMessageThreadService(logger, telemetry.NewOtelLogger("test", logger), repository, nil)
+}
+
+func TestMessageThreadService_Mute(t *testing.T) {
+	newThread := func() entities.MessageThread {
+		messageID := uuid.New()
+		content := "Hello world"
+		return entities.MessageThread{
+			ID:                 uuid.New(),
+			UserID:             "user-a",
+			Owner:              "+18005550199",
+			Contact:            "+18005550100",
+			LastMessageID:      &messageID,
+			LastMessageContent: &content,
+			Status:             entities.MessageStatusReceived,
+			OrderTimestamp:     time.Now().UTC().Add(-time.Hour),
+		}
+	}
+
+	t.Run("muted thread still stores the received messages", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread()
+		repository := newStubMessageThreadRepository(thread)
+		service := newTestMessageThreadService(repository)
+
+		// Arrange
+		_, err := service.Mute(context.Background(), MessageThreadMuteParams{UserID: thread.UserID, MessageThreadID: thread.ID})
+		assert.Nil(t, err)
+		messageID := uuid.New()
+
+		// Act
+		err = service.UpdateThread(context.Background(), MessageThreadUpdateParams{
+			Owner:     thread.Owner,
+			Contact:   thread.Contact,
+			UserID:    thread.UserID,
+			Timestamp: time.Now().UTC(),
+			Content:   "Are you there?",
+			MessageID: messageID,
+			Status:    entities.MessageStatusReceived,
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		stored, err := repository.Load(context.Background(), thread.UserID, thread.ID)
+		assert.Nil(t, err)
+		assert.True(t, stored.HasLastMessage(messageID))
+		assert.Equal(t, "Are you there?", *stored.LastMessageContent)
+		assert.True(t, stored.IsMuted)
+		assert.True(t, service.IsMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+	})
+
+	t.Run("webhooks are muted only when requested", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread()
+		service := newTestMessageThreadService(newStubMessageThreadRepository(thread))
+
+		// Act
+		_, err := service.Mute(context.Background(), MessageThreadMuteParams{UserID: thread.UserID, MessageThreadID: thread.ID})
+		assert.Nil(t, err)
+		webhookMuted := service.IsWebhookMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact)
+
+		_, err = service.Mute(context.Background(), MessageThreadMuteParams{UserID: thread.UserID, MessageThreadID: thread.ID, MuteWebhooks: true})
+		assert.Nil(t, err)
+
+		// Assert
+		assert.False(t, webhookMuted)
+		assert.True(t, service.IsWebhookMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+	})
+
+	t.Run("expired mute is lifted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread()
+		until := time.Now().UTC().Add(-time.Minute)
+		thread.Mute(time.Now().UTC().Add(-time.Hour), &until, true)
+		service := newTestMessageThreadService(newStubMessageThreadRepository(thread))
+
+		// Act
+		threads, err := service.GetThreads(context.Background(), MessageThreadGetParams{UserID: thread.UserID, Owner: thread.Owner})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.False(t, service.IsMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+		assert.False(t, service.IsWebhookMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+		assert.Equal(t, 1, len(*threads))
+		assert.False(t, (*threads)[0].IsMuted)
+		assert.Nil(t, (*threads)[0].MutedUntil)
+	})
+
+	t.Run("unmuted thread is not muted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread()
+		service := newTestMessageThreadService(newStubMessageThreadRepository(thread))
+
+		// Arrange
+		_, err := service.Mute(context.Background(), MessageThreadMuteParams{UserID: thread.UserID, MessageThreadID: thread.ID, MuteWebhooks: true})
+		assert.Nil(t, err)
+
+		// Act
+		unmuted, err := service.Unmute(context.Background(), thread.UserID, thread.ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.False(t, unmuted.IsMuted)
+		assert.False(t, service.IsMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+	})
+
+	t.Run("thread of another user cannot be muted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread()
+		service := newTestMessageThreadService(newStubMessageThreadRepository(thread))
+
+		// Act
+		_, err := service.Mute(context.Background(), MessageThreadMuteParams{UserID: "user-b", MessageThreadID: thread.ID})
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+	})
+
+	t.Run("notifications are sent when the thread cannot be loaded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		thread := newThread()
+		thread.Mute(time.Now().UTC(), nil, true)
+		repository := newStubMessageThreadRepository(thread)
+		repository.fail = true
+		service := newTestMessageThreadService(repository)
+
+		// Assert
+		assert.False(t, service.IsMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+		assert.False(t, service.IsWebhookMuted(context.Background(), thread.UserID, thread.Owner, thread.Contact))
+	})
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/nyaruka/phonenumbers"
+	"github.com/palantir/stacktrace"
+)
+
+// NumberLookup classifies the type and carrier of a phone number
+type NumberLookup interface {
+	// Lookup the entities.NumberLookup of a phone number in the E.164 format, the TTL of the lookup is set by the caller
+	Lookup(ctx context.Context, phoneNumber string) (*entities.NumberLookup, error)
+}
+
+// offlineNumberLookup classifies a phone number with the prefixes of the numbering plan of its country
+type offlineNumberLookup struct{}
+
+// NewOfflineNumberLookup creates a NumberLookup which classifies numbers with the metadata of libphonenumber without a
+// network request. A number which can be either a fixed line or a mobile is entities.NumberTypeUnknown.
+func NewOfflineNumberLookup() NumberLookup {
+	return &offlineNumberLookup{}
+}
+
+// Lookup a phone number with the numbering plan of its country
+func (lookup *offlineNumberLookup) Lookup(_ context.Context, phoneNumber string) (*entities.NumberLookup, error) {
+	result := &entities.NumberLookup{
+		PhoneNumber: phoneNumber,
+		Type:        entities.NumberTypeInvalid,
+		Source:      "offline",
+		LookedUpAt:  time.Now().UTC(),
+	}
+
+	number, err := phonenumbers.Parse(phoneNumber, phonenumbers.UNKNOWN_REGION)
+	if err != nil || !phonenumbers.IsValidNumber(number) {
+		return result, nil
+	}
+
+	result.CountryCode = phonenumbers.GetRegionCodeForNumber(number)
+	result.Carrier, _ = phonenumbers.GetCarrierForNumber(number, "en")
+
+	switch phonenumbers.GetNumberType(number) {
+	case phonenumbers.MOBILE:
+		result.Type = entities.NumberTypeMobile
+	case phonenumbers.FIXED_LINE:
+		result.Type = entities.NumberTypeLandline
+	case phonenumbers.VOIP:
+		result.Type = entities.NumberTypeVoIP
+	default:
+		result.Type = entities.NumberTypeUnknown
+	}
+
+	return result, nil
+}
+
+// httpNumberLookup classifies a phone number with the HLR lookup of an HTTP provider
+type httpNumberLookup struct {
+	client   *http.Client
+	endpoint string
+	apiKey   string
+}
+
+// NewHTTPNumberLookup creates a NumberLookup which fetches {endpoint}?phone_number=+18005550100 with the apiKey as a
+// bearer token. The provider responds with {"type": "mobile", "carrier": "T-Mobile", "country_code": "US"} where the
+// type is one of mobile, landline, fixed_line, voip or invalid.
+func NewHTTPNumberLookup(client *http.Client, endpoint string, apiKey string) NumberLookup {
+	return &httpNumberLookup{
+		client:   client,
+		endpoint: endpoint,
+		apiKey:   apiKey,
+	}
+}
+
+// httpNumberLookupResponse is the body of the response of an HTTP lookup provider
+type httpNumberLookupResponse struct {
+	Type        string `json:"type"`
+	Carrier     string `json:"carrier"`
+	CountryCode string `json:"country_code"`
+}
+
+// Lookup a phone number with the HTTP provider
+func (lookup *httpNumberLookup) Lookup(ctx context.Context, phoneNumber string) (*entities.NumberLookup, error) {
+	endpoint := fmt.Sprintf("%s?%s", lookup.endpoint, url.Values{"phone_number": []string{phoneNumber}}.Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot create the lookup request of phone number [%s]", telemetry.RedactPhoneNumber(phoneNumber)))
+	}
+	request.Header.Set("Accept", "application/json")
+	if lookup.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+lookup.apiKey)
+	}
+
+	response, err := lookup.client.Do(request)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot lookup phone number [%s] with the http provider", telemetry.RedactPhoneNumber(phoneNumber)))
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= 400 {
+		return nil, stacktrace.NewError(fmt.Sprintf("http provider cannot lookup phone number [%s] with response code [%d]", telemetry.RedactPhoneNumber(phoneNumber), response.StatusCode))
+	}
+
+	payload := new(httpNumberLookupResponse)
+	if err = json.NewDecoder(response.Body).Decode(payload); err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot decode the lookup of phone number [%s]", telemetry.RedactPhoneNumber(phoneNumber)))
+	}
+
+	return &entities.NumberLookup{
+		PhoneNumber: phoneNumber,
+		Type:        httpNumberType(payload.Type),
+		Carrier:     payload.Carrier,
+		CountryCode: strings.ToUpper(payload.CountryCode),
+		Source:      "http",
+		LookedUpAt:  time.Now().UTC(),
+	}, nil
+}
+
+// httpNumberType maps the type of the response of an HTTP provider to an entities.NumberType
+func httpNumberType(value string) entities.NumberType {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "mobile", "cell", "cellular":
+		return entities.NumberTypeMobile
+	case "landline", "fixed_line", "fixed":
+		return entities.NumberTypeLandline
+	case "voip":
+		return entities.NumberTypeVoIP
+	case "invalid":
+		return entities.NumberTypeInvalid
+	default:
+		return entities.NumberTypeUnknown
+	}
+}
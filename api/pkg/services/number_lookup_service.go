@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+// RecipientNotSendableError is returned when a message is sent with ValidateRecipient to a number which cannot receive SMS messages
+type RecipientNotSendableError struct {
+	Contact string
+	Type    entities.NumberType
+}
+
+// Error returns the error message of the RecipientNotSendableError
+func (err *RecipientNotSendableError) Error() string {
+	return fmt.Sprintf("the [%s] number [%s] cannot receive SMS messages", err.Type, err.Contact)
+}
+
+// AsRecipientNotSendableError returns the RecipientNotSendableError which caused the error
+func AsRecipientNotSendableError(err error) (*RecipientNotSendableError, bool) {
+	recipientErr, ok := stacktrace.RootCause(err).(*RecipientNotSendableError)
+	return recipientErr, ok
+}
+
+// NumberLookupService looks up the type and carrier of phone numbers and caches them for a TTL
+type NumberLookupService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.NumberLookupRepository
+	lookup     NumberLookup
+	ttl        time.Duration
+}
+
+// NewNumberLookupService creates a new NumberLookupService
+func NewNumberLookupService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.NumberLookupRepository,
+	lookup NumberLookup,
+	ttl time.Duration,
+) (s *NumberLookupService) {
+	return &NumberLookupService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+		lookup:     lookup,
+		ttl:        ttl,
+	}
+}
+
+// Lookup the entities.NumberLookup of a phone number, a cached lookup is used until it expires
+func (service *NumberLookupService) Lookup(ctx context.Context, phoneNumber string) (*entities.NumberLookup, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	cached, err := service.repository.Load(ctx, phoneNumber)
+	if err == nil && !cached.IsExpired(time.Now().UTC()) {
+		return cached, nil
+	}
+
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load the cached lookup of phone number [%s]", telemetry.RedactPhoneNumber(phoneNumber))))
+	}
+
+	lookup, err := service.lookup.Lookup(ctx, phoneNumber)
+	if err != nil {
+		msg := fmt.Sprintf("cannot lookup phone number [%s]", telemetry.RedactPhoneNumber(phoneNumber))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	lookup.ExpiresAt = lookup.LookedUpAt.Add(service.ttl)
+	if err = service.repository.Save(ctx, lookup); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot cache the lookup of phone number [%s]", telemetry.RedactPhoneNumber(phoneNumber))))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("looked up phone number [%s] with type [%s] from [%s]", telemetry.RedactPhoneNumber(phoneNumber), lookup.Type, lookup.Source))
+	return lookup, nil
+}
+
+// ValidateRecipient returns a RecipientNotSendableError when a phone number is a landline or is invalid. The send is
+// allowed with a logged warning when the number cannot be looked up.
+func (service *NumberLookupService) ValidateRecipient(ctx context.Context, phoneNumber string) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	lookup, err := service.Lookup(ctx, phoneNumber)
+	if err != nil {
+		ctxLogger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot validate recipient [%s], the message is sent without validation", telemetry.RedactPhoneNumber(phoneNumber))))
+		return nil
+	}
+
+	if !lookup.CanReceiveSMS() {
+		return &RecipientNotSendableError{Contact: phoneNumber, Type: lookup.Type}
+	}
+
+	return nil
+}
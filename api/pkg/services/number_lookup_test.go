@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPNumberLookup_Lookup(t *testing.T) {
+	t.Run("number is classified with the response of the provider", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		var phoneNumber, authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			phoneNumber = r.URL.Query().Get("phone_number")
+			authorization = r.Header.Get("Authorization")
+			_, _ = w.Write([]byte(`{"type": "fixed_line", "carrier": "AT&T", "country_code": "us"}`))
+		}))
+		defer server.Close()
+
+		// Arrange
+		lookup := NewHTTPNumberLookup(server.Client(), server.URL, "secret")
+
+		// Act
+		result, err := lookup.Lookup(context.Background(), "+18005550100")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550100", phoneNumber)
+		assert.Equal(t, "Bearer secret", authorization)
+		assert.Equal(t, entities.NumberTypeLandline, result.Type)
+		assert.Equal(t, "AT&T", result.Carrier)
+		assert.Equal(t, "US", result.CountryCode)
+		assert.False(t, result.CanReceiveSMS())
+	})
+
+	t.Run("error of the provider is returned", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		// Arrange
+		lookup := NewHTTPNumberLookup(server.Client(), server.URL, "")
+
+		// Act
+		result, err := lookup.Lookup(context.Background(), "+18005550100")
+
+		// Assert
+		assert.NotNil(t, err)
+		assert.Nil(t, result)
+	})
+}
+
+func TestHTTPNumberType(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, entities.NumberTypeMobile, httpNumberType("Mobile"))
+	assert.Equal(t, entities.NumberTypeLandline, httpNumberType("landline"))
+	assert.Equal(t, entities.NumberTypeVoIP, httpNumberType("voip"))
+	assert.Equal(t, entities.NumberTypeInvalid, httpNumberType("invalid"))
+	assert.Equal(t, entities.NumberTypeUnknown, httpNumberType("toll_free"))
+}
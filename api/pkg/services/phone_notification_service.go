@@ -24,6 +24,7 @@ type PhoneNotificationService struct {
 	tracer                      telemetry.Tracer
 	phoneNotificationRepository repositories.PhoneNotificationRepository
 	phoneRepository             repositories.PhoneRepository
+	messageRepository           repositories.MessageRepository
 	messagingClient             *messaging.Client
 	eventDispatcher             *EventDispatcher
 }
@@ -35,6 +36,7 @@ func NewNotificationService(
 	messagingClient *messaging.Client,
 	phoneRepository repositories.PhoneRepository,
 	phoneNotificationRepository repositories.PhoneNotificationRepository,
+	messageRepository repositories.MessageRepository,
 	dispatcher *EventDispatcher,
 ) (s *PhoneNotificationService) {
 	return &PhoneNotificationService{
@@ -43,6 +45,7 @@ func NewNotificationService(
 		messagingClient:             messagingClient,
 		phoneNotificationRepository: phoneNotificationRepository,
 		phoneRepository:             phoneRepository,
+		messageRepository:           messageRepository,
 		eventDispatcher:             dispatcher,
 	}
 }
@@ -136,6 +139,14 @@ type PhoneNotificationScheduleParams struct {
 	MessageID uuid.UUID
 }
 
+// Redacted returns a copy of the params which is safe to log
+func (params PhoneNotificationScheduleParams) Redacted() PhoneNotificationScheduleParams {
+	params.Owner = telemetry.RedactPhoneNumber(params.Owner)
+	params.Contact = telemetry.RedactPhoneNumber(params.Contact)
+	params.Content = telemetry.RedactContent(params.Content)
+	return params
+}
+
 // Schedule a notification to be sent to a phone
 func (service *PhoneNotificationService) Schedule(ctx context.Context, params *PhoneNotificationScheduleParams) error {
 	ctx, span := service.tracer.Start(ctx)
@@ -149,6 +160,11 @@ func (service *PhoneNotificationService) Schedule(ctx context.Context, params *P
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if phone.IsPaused {
+		ctxLogger.Info(fmt.Sprintf("phone with id [%s] is paused, message with id [%s] will be scheduled when the phone is resumed", phone.ID, params.MessageID))
+		return nil
+	}
+
 	notification := &entities.PhoneNotification{
 		ID:          uuid.New(),
 		MessageID:   params.MessageID,
@@ -177,8 +193,39 @@ func (service *PhoneNotificationService) Schedule(ctx context.Context, params *P
 	return nil
 }
 
+// ScheduleOutstanding schedules notifications for the pending messages of a phone which has been resumed or whose daily send limit has reset
+func (service *PhoneNotificationService) ScheduleOutstanding(ctx context.Context, source string, userID entities.UserID, phoneID uuid.UUID, owner string) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	messages, err := service.messageRepository.IndexPending(ctx, userID, owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch pending messages for phone [%s] and user [%s]", phoneID, userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	for _, message := range *messages {
+		params := &PhoneNotificationScheduleParams{
+			UserID:    message.UserID,
+			Owner:     message.Owner,
+			Source:    source,
+			Contact:   message.Contact,
+			Content:   message.Content,
+			SIM:       message.SIM,
+			MessageID: message.ID,
+		}
+		if err = service.Schedule(ctx, params); err != nil {
+			msg := fmt.Sprintf("cannot schedule notification for message [%s] on phone [%s]", message.ID, phoneID)
+			return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	ctxLogger.Info(fmt.Sprintf("scheduled [%d] pending messages for phone [%s] and user [%s]", len(*messages), phoneID, userID))
+	return nil
+}
+
 func (service *PhoneNotificationService) dispatchMessageNotificationSend(ctx context.Context, source string, notification *entities.PhoneNotification) error {
-	event, err := service.createMessageNotificationSendEvent(source, &events.MessageNotificationSendPayload{
+	event, err := service.createMessageNotificationSendEvent(ctx, source, &events.MessageNotificationSendPayload{
 		MessageID:      notification.MessageID,
 		UserID:         notification.UserID,
 		PhoneID:        notification.PhoneID,
@@ -196,7 +243,7 @@ func (service *PhoneNotificationService) dispatchMessageNotificationSend(ctx con
 }
 
 func (service *PhoneNotificationService) dispatchMessageNotificationScheduled(ctx context.Context, params *PhoneNotificationScheduleParams, notification *entities.PhoneNotification) error {
-	event, err := service.createMessageNotificationScheduledEvent(params.Source, &events.MessageNotificationScheduledPayload{
+	event, err := service.createMessageNotificationScheduledEvent(ctx, params.Source, &events.MessageNotificationScheduledPayload{
 		MessageID:      notification.MessageID,
 		Owner:          params.Owner,
 		Contact:        params.Contact,
@@ -226,7 +273,7 @@ func (service *PhoneNotificationService) handleNotificationFailed(ctx context.Co
 	msg := fmt.Sprintf("cannot send notification for message [%s] to phone [%s]", params.MessageID, params.PhoneNotificationID)
 	ctxLogger.Warn(stacktrace.Propagate(err, msg))
 
-	event, err := service.createMessageNotificationFailedEvent(params.Source, err.Error(), params)
+	event, err := service.createMessageNotificationFailedEvent(ctx, params.Source, err.Error(), params)
 	if err != nil {
 		return stacktrace.Propagate(err, fmt.Sprintf("cannot create [%s] event for notification [%s]", events.EventTypeMessageNotificationFailed, params.PhoneNotificationID))
 	}
@@ -247,7 +294,7 @@ func (service *PhoneNotificationService) handleNotificationSent(ctx context.Cont
 
 	ctxLogger.Info(fmt.Sprintf("sent notification [%s] for message [%s] to phone [%s]", result, params.MessageID, params.PhoneID))
 
-	event, err := service.createMessageNotificationSentEvent(params.Source, phone, result, params)
+	event, err := service.createMessageNotificationSentEvent(ctx, params.Source, phone, result, params)
 	if err != nil {
 		return stacktrace.Propagate(err, fmt.Sprintf("cannot create [%s] event for notification [%s]", events.EventTypeMessageNotificationSent, params.PhoneNotificationID))
 	}
@@ -260,22 +307,15 @@ func (service *PhoneNotificationService) handleNotificationSent(ctx context.Cont
 	return nil
 }
 
-func (service *PhoneNotificationService) createMessageNotificationScheduledEvent(source string, payload *events.MessageNotificationScheduledPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageNotificationScheduled, source, payload)
+func (service *PhoneNotificationService) createMessageNotificationScheduledEvent(ctx context.Context, source string, payload *events.MessageNotificationScheduledPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessageNotificationScheduled, source, payload)
 }
 
-func (service *PhoneNotificationService) createMessageNotificationSendEvent(source string, payload *events.MessageNotificationSendPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypeMessageNotificationSend, source, payload)
+func (service *PhoneNotificationService) createMessageNotificationSendEvent(ctx context.Context, source string, payload *events.MessageNotificationSendPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypeMessageNotificationSend, source, payload)
 }
 
-func (service *PhoneNotificationService) createMessageNotificationSentEvent(source string, phone *entities.Phone, fcmMessageID string, params *PhoneNotificationSendParams) (cloudevents.Event, error) {
-	event := cloudevents.NewEvent()
-
-	event.SetSource(source)
-	event.SetType(events.EventTypeMessageNotificationSent)
-	event.SetTime(time.Now().UTC())
-	event.SetID(uuid.New().String())
-
+func (service *PhoneNotificationService) createMessageNotificationSentEvent(ctx context.Context, source string, phone *entities.Phone, fcmMessageID string, params *PhoneNotificationSendParams) (cloudevents.Event, error) {
 	payload := events.MessageNotificationSentPayload{
 		MessageID:                 params.MessageID,
 		UserID:                    params.UserID,
@@ -287,22 +327,10 @@ func (service *PhoneNotificationService) createMessageNotificationSentEvent(sour
 		NotificationID:            params.PhoneNotificationID,
 	}
 
-	if err := event.SetData(cloudevents.ApplicationJSON, payload); err != nil {
-		msg := fmt.Sprintf("cannot encode %T [%#+v] as JSON", payload, payload)
-		return event, stacktrace.Propagate(err, msg)
-	}
-
-	return event, nil
+	return service.createEvent(ctx, events.EventTypeMessageNotificationSent, source, payload)
 }
 
-func (service *PhoneNotificationService) createMessageNotificationFailedEvent(source string, errorMessage string, params *PhoneNotificationSendParams) (cloudevents.Event, error) {
-	event := cloudevents.NewEvent()
-
-	event.SetSource(source)
-	event.SetType(events.EventTypeMessageNotificationFailed)
-	event.SetTime(time.Now().UTC())
-	event.SetID(uuid.New().String())
-
+func (service *PhoneNotificationService) createMessageNotificationFailedEvent(ctx context.Context, source string, errorMessage string, params *PhoneNotificationSendParams) (cloudevents.Event, error) {
 	payload := events.MessageNotificationFailedPayload{
 		MessageID:            params.MessageID,
 		UserID:               params.UserID,
@@ -312,12 +340,7 @@ func (service *PhoneNotificationService) createMessageNotificationFailedEvent(so
 		NotificationID:       params.PhoneNotificationID,
 	}
 
-	if err := event.SetData(cloudevents.ApplicationJSON, payload); err != nil {
-		msg := fmt.Sprintf("cannot encode %T [%#+v] as JSON", payload, payload)
-		return event, stacktrace.Propagate(err, msg)
-	}
-
-	return event, nil
+	return service.createEvent(ctx, events.EventTypeMessageNotificationFailed, source, payload)
 }
 
 func (service *PhoneNotificationService) updateStatus(ctx context.Context, notificationID uuid.UUID, status entities.PhoneNotificationStatus) {
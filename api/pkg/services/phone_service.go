@@ -22,24 +22,71 @@ import (
 // PhoneService is handles phone requests
 type PhoneService struct {
 	service
-	logger     telemetry.Logger
-	tracer     telemetry.Tracer
-	repository repositories.PhoneRepository
-	dispatcher *EventDispatcher
+	logger              telemetry.Logger
+	tracer              telemetry.Tracer
+	repository          repositories.PhoneRepository
+	dispatcher          *EventDispatcher
+	batteryLowThreshold uint
+	clock               PhoneClockConfig
 }
 
+// PhoneClockConfig configures how the timestamps which are reported by the phones are corrected, a zero duration uses the default
+type PhoneClockConfig struct {
+	// SkewThreshold is the clock skew above which the owner is told to fix the clock of a phone
+	SkewThreshold time.Duration
+	// FutureTolerance is how far after the time of the server a timestamp can be before it is clamped to the time of the server
+	FutureTolerance time.Duration
+	// PastTolerance is how far before the time of the server a timestamp can be before it is clamped to the time of the server
+	PastTolerance time.Duration
+}
+
+const (
+	// phoneBatteryLowThreshold is the default battery percentage below which a phone is considered to have a low battery
+	phoneBatteryLowThreshold = 20
+
+	// phoneBatteryLowHysteresis is the battery percentage above the threshold needed before a new low battery event can be emitted
+	phoneBatteryLowHysteresis = 5
+
+	// phoneClockSkewThreshold is the default clock skew above which the owner is told to fix the clock of a phone
+	phoneClockSkewThreshold = 2 * time.Minute
+
+	// phoneTimestampFutureTolerance is the default time after the time of the server when a timestamp of a phone is clamped
+	phoneTimestampFutureTolerance = 5 * time.Minute
+
+	// phoneTimestampPastTolerance is the default time before the time of the server when a timestamp of a phone is clamped
+	phoneTimestampPastTolerance = 30 * 24 * time.Hour
+)
+
 // NewPhoneService creates a new PhoneService
 func NewPhoneService(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	repository repositories.PhoneRepository,
 	dispatcher *EventDispatcher,
+	batteryLowThreshold uint,
+	clock PhoneClockConfig,
 ) (s *PhoneService) {
+	if batteryLowThreshold == 0 {
+		batteryLowThreshold = phoneBatteryLowThreshold
+	}
+
+	if clock.SkewThreshold == 0 {
+		clock.SkewThreshold = phoneClockSkewThreshold
+	}
+	if clock.FutureTolerance == 0 {
+		clock.FutureTolerance = phoneTimestampFutureTolerance
+	}
+	if clock.PastTolerance == 0 {
+		clock.PastTolerance = phoneTimestampPastTolerance
+	}
+
 	return &PhoneService{
-		logger:     logger.WithService(fmt.Sprintf("%T", s)),
-		tracer:     tracer,
-		dispatcher: dispatcher,
-		repository: repository,
+		logger:              logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:              tracer,
+		dispatcher:          dispatcher,
+		repository:          repository,
+		batteryLowThreshold: batteryLowThreshold,
+		clock:               clock,
 	}
 }
 
@@ -68,6 +115,305 @@ func (service *PhoneService) Load(ctx context.Context, userID entities.UserID, o
 	return service.repository.Load(ctx, userID, owner)
 }
 
+// LoadOwner loads the phone of a user with the owner phone number without the SIMs
+func (service *PhoneService) LoadOwner(ctx context.Context, userID entities.UserID, owner string) (*entities.Phone, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	return service.repository.LoadOwner(ctx, userID, owner)
+}
+
+// LoadDefault loads the default phone of a user
+func (service *PhoneService) LoadDefault(ctx context.Context, userID entities.UserID) (*entities.Phone, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	return service.repository.LoadDefault(ctx, userID)
+}
+
+// SetDefault makes an entities.Phone the default phone of a user
+func (service *PhoneService) SetDefault(ctx context.Context, source string, userID entities.UserID, phoneID uuid.UUID) (*entities.Phone, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.repository.SetDefault(ctx, userID, phoneID); err != nil {
+		msg := fmt.Sprintf("cannot set phone with ID [%s] as default for user [%s]", phoneID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	phone, err := service.repository.LoadByID(ctx, userID, phoneID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone with userID [%s] and phoneID [%s]", userID, phoneID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone with id [%s] set as default for user [%s]", phone.ID, phone.UserID))
+	return phone, service.dispatchPhoneUpdatedEvent(ctx, source, phone)
+}
+
+// Pause stops outstanding messages from being sent to an entities.Phone
+func (service *PhoneService) Pause(ctx context.Context, source string, userID entities.UserID, phoneID uuid.UUID) (*entities.Phone, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	phone, err := service.setPaused(ctx, userID, phoneID, true)
+	if err != nil {
+		msg := fmt.Sprintf("cannot pause phone with ID [%s] for user [%s]", phoneID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	event, err := service.createPhonePausedEvent(ctx, source, events.PhonePausedPayload{
+		PhoneID:   phone.ID,
+		UserID:    phone.UserID,
+		Timestamp: phone.UpdatedAt,
+		Owner:     phone.PhoneNumber,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event when phone [%s] is paused for user [%s]", phone.ID, phone.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for phone with id [%s]", event.Type(), phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone with id [%s] paused for user [%s]", phone.ID, phone.UserID))
+	return phone, nil
+}
+
+// Resume allows outstanding messages to be sent to an entities.Phone which was paused
+func (service *PhoneService) Resume(ctx context.Context, source string, userID entities.UserID, phoneID uuid.UUID) (*entities.Phone, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	phone, err := service.setPaused(ctx, userID, phoneID, false)
+	if err != nil {
+		msg := fmt.Sprintf("cannot resume phone with ID [%s] for user [%s]", phoneID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	event, err := service.createPhoneResumedEvent(ctx, source, events.PhoneResumedPayload{
+		PhoneID:   phone.ID,
+		UserID:    phone.UserID,
+		Timestamp: phone.UpdatedAt,
+		Owner:     phone.PhoneNumber,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event when phone [%s] is resumed for user [%s]", phone.ID, phone.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for phone with id [%s]", event.Type(), phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone with id [%s] resumed for user [%s]", phone.ID, phone.UserID))
+	return phone, nil
+}
+
+func (service *PhoneService) setPaused(ctx context.Context, userID entities.UserID, phoneID uuid.UUID, isPaused bool) (*entities.Phone, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	phone, err := service.repository.LoadByID(ctx, userID, phoneID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone with userID [%s] and phoneID [%s]", userID, phoneID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	phone.IsPaused = isPaused
+	phone.PausedAt = nil
+	if isPaused {
+		timestamp := time.Now().UTC()
+		phone.PausedAt = &timestamp
+	}
+
+	if err = service.repository.Save(ctx, phone); err != nil {
+		msg := fmt.Sprintf("cannot save phone with id [%s]", phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return phone, nil
+}
+
+// UpdateAppVersion stores the version of the android app which is installed on an entities.Phone
+func (service *PhoneService) UpdateAppVersion(ctx context.Context, userID entities.UserID, owner string, version string) error {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	if err := service.repository.UpdateAppVersion(ctx, userID, owner, version); err != nil {
+		msg := fmt.Sprintf("cannot update app version of phone [%s] for user [%s]", owner, userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return nil
+}
+
+// PhoneBatteryUpdateParams are parameters for updating the battery state of an entities.Phone
+type PhoneBatteryUpdateParams struct {
+	UserID       entities.UserID
+	Owner        string
+	BatteryLevel uint
+	Charging     bool
+	Timestamp    time.Time
+	Source       string
+}
+
+// UpdateBattery stores the battery state of an entities.Phone and emits events.EventTypePhoneBatteryLow when the
+// battery level drops below the threshold while discharging.
+func (service *PhoneService) UpdateBattery(ctx context.Context, params *PhoneBatteryUpdateParams) (*entities.Phone, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	phone, err := service.repository.Load(ctx, params.UserID, params.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone with userID [%s] and owner [%s]", params.UserID, params.Owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	isLow := !params.Charging && params.BatteryLevel <= service.batteryLowThreshold
+	hasRecovered := params.Charging || params.BatteryLevel >= service.batteryLowThreshold+phoneBatteryLowHysteresis
+
+	dispatchLowEvent := isLow && !phone.BatteryLow
+	if dispatchLowEvent {
+		phone.BatteryLow = true
+	} else if phone.BatteryLow && hasRecovered {
+		phone.BatteryLow = false
+	}
+
+	phone.BatteryLevel = &params.BatteryLevel
+	phone.BatteryCharging = params.Charging
+	phone.BatteryUpdatedAt = &params.Timestamp
+
+	if err = service.repository.UpdateBattery(ctx, phone); err != nil {
+		msg := fmt.Sprintf("cannot update battery of phone with id [%s] for user [%s]", phone.ID, phone.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !dispatchLowEvent {
+		return phone, nil
+	}
+
+	event, err := service.createPhoneBatteryLowEvent(ctx, params.Source, events.PhoneBatteryLowPayload{
+		PhoneID:      phone.ID,
+		UserID:       phone.UserID,
+		Owner:        phone.PhoneNumber,
+		BatteryLevel: params.BatteryLevel,
+		Threshold:    service.batteryLowThreshold,
+		Timestamp:    params.Timestamp,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event when phone [%s] has a low battery for user [%s]", phone.ID, phone.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for phone with id [%s]", event.Type(), phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone [%s] has a low battery level of [%d%%] for user [%s]", phone.ID, params.BatteryLevel, phone.UserID))
+	return phone, nil
+}
+
+// PhoneClockSkewUpdateParams are parameters for adding a sample to the clock skew of an entities.Phone
+type PhoneClockSkewUpdateParams struct {
+	UserID entities.UserID
+	Owner  string
+	// ReportedAt is the time on the phone when it sent the request
+	ReportedAt time.Time
+	// ReceivedAt is the time on the server when it received the request
+	ReceivedAt time.Time
+	Source     string
+}
+
+// UpdateClockSkew adds a sample to the clock skew of an entities.Phone and emits events.EventTypePhoneClockSkewed when
+// the clock skew exceeds the threshold, a new event is emitted after the clock skew drops below half of the threshold.
+func (service *PhoneService) UpdateClockSkew(ctx context.Context, params *PhoneClockSkewUpdateParams) (*entities.Phone, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	phone, err := service.repository.Load(ctx, params.UserID, params.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load phone with userID [%s] and owner [%s]", params.UserID, params.Owner)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	phone.AddClockSkewSample(params.ReportedAt, params.ReceivedAt)
+
+	skew := phone.ClockSkew()
+	if skew < 0 {
+		skew = -skew
+	}
+
+	dispatchSkewedEvent := skew > service.clock.SkewThreshold && !phone.ClockSkewed
+	if dispatchSkewedEvent {
+		phone.ClockSkewed = true
+	} else if phone.ClockSkewed && skew <= service.clock.SkewThreshold/2 {
+		phone.ClockSkewed = false
+	}
+
+	if err = service.repository.UpdateClockSkew(ctx, phone); err != nil {
+		msg := fmt.Sprintf("cannot update clock skew of phone with id [%s] for user [%s]", phone.ID, phone.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !dispatchSkewedEvent {
+		return phone, nil
+	}
+
+	event, err := service.createPhoneClockSkewedEvent(ctx, params.Source, events.PhoneClockSkewedPayload{
+		PhoneID:               phone.ID,
+		UserID:                phone.UserID,
+		Owner:                 phone.PhoneNumber,
+		ClockSkewMilliseconds: phone.ClockSkewMilliseconds,
+		ThresholdMilliseconds: service.clock.SkewThreshold.Milliseconds(),
+		Timestamp:             params.ReceivedAt,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create event when the clock of phone [%s] is skewed for user [%s]", phone.ID, phone.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch event [%s] for phone with id [%s]", event.Type(), phone.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("the clock of phone [%s] is skewed by [%s] for user [%s]", phone.ID, phone.ClockSkew(), phone.UserID)))
+	return phone, nil
+}
+
+// NormalizeTimestamp corrects a timestamp which was reported by the phone of the owner with the clock skew of the phone
+// and clamps it to the time of the server when it is too far in the future or the past. The reported timestamp is
+// returned when the timestamp is changed.
+func (service *PhoneService) NormalizeTimestamp(ctx context.Context, userID entities.UserID, owner string, timestamp time.Time) (time.Time, *time.Time) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	normalized := timestamp
+	phone, err := service.repository.LoadOwner(ctx, userID, owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load the phone [%s] of user [%s] to correct the timestamp [%s]", telemetry.RedactPhoneNumber(owner), userID, timestamp)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+	} else {
+		normalized = phone.CorrectTimestamp(timestamp)
+	}
+
+	normalized, clamped := entities.ClampPhoneTimestamp(normalized, time.Now().UTC(), service.clock.FutureTolerance, service.clock.PastTolerance)
+	if clamped {
+		msg := fmt.Sprintf("the timestamp [%s] of phone [%s] for user [%s] is clamped to [%s]", timestamp, telemetry.RedactPhoneNumber(owner), userID, normalized)
+		ctxLogger.Warn(stacktrace.NewError(msg))
+	}
+
+	if normalized.Equal(timestamp) {
+		return timestamp, nil
+	}
+	return normalized, &timestamp
+}
+
 // PhoneUpsertParams are parameters for creating a new entities.Phone
 type PhoneUpsertParams struct {
 	PhoneNumber               phonenumbers.PhoneNumber
@@ -76,9 +422,24 @@ type PhoneUpsertParams struct {
 	MaxSendAttempts           *uint
 	WebhookURL                *string
 	MessageExpirationDuration *time.Duration
+	HeartbeatDeadThreshold    *time.Duration
+	HeartbeatAlertCooldown    *time.Duration
+	DailySendLimit            *uint
+	DailySendLimitTimezone    *string
+	EncryptionEnabled         *bool
 	SIM                       entities.SIM
-	Source                    string
-	UserID                    entities.UserID
+	AppVersion                string
+	// SIMs is nil when the phone does not report its SIM cards
+	SIMs   *[]PhoneSIMParams
+	Source string
+	UserID entities.UserID
+}
+
+// PhoneSIMParams are parameters for registering a SIM card on an entities.Phone
+type PhoneSIMParams struct {
+	Slot        uint
+	CarrierName string
+	PhoneNumber *string
 }
 
 // Upsert a new entities.Phone
@@ -108,15 +469,53 @@ func (service *PhoneService) Upsert(ctx context.Context, params PhoneUpsertParam
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if err = service.saveSIMs(ctx, phone, params.SIMs); err != nil {
+		msg := fmt.Sprintf("cannot save SIMs of phone with id [%s] and number [%s]", phone.ID, phone.PhoneNumber)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
 	ctxLogger.Info(fmt.Sprintf("phone updated with id [%s] in the phone repository for user [%s]", phone.ID, phone.UserID))
 	return phone, service.dispatchPhoneUpdatedEvent(ctx, params.Source, phone)
 }
 
+// saveSIMs reconciles the SIM cards registered on an entities.Phone with the SIM cards reported by the phone
+func (service *PhoneService) saveSIMs(ctx context.Context, phone *entities.Phone, params *[]PhoneSIMParams) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if params == nil {
+		return nil
+	}
+
+	sims := make([]entities.PhoneSIM, 0, len(*params))
+	for _, item := range *params {
+		sims = append(sims, entities.PhoneSIM{
+			ID:          uuid.New(),
+			PhoneID:     phone.ID,
+			UserID:      phone.UserID,
+			Slot:        item.Slot,
+			CarrierName: item.CarrierName,
+			PhoneNumber: item.PhoneNumber,
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
+		})
+	}
+
+	if err := service.repository.SaveSIMs(ctx, phone, sims); err != nil {
+		msg := fmt.Sprintf("cannot save [%d] SIMs for phone with id [%s]", len(sims), phone.ID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	phone.SIMs = sims
+	ctxLogger.Info(fmt.Sprintf("saved [%d] SIMs for phone with id [%s]", len(sims), phone.ID))
+	return nil
+}
+
 func (service *PhoneService) dispatchPhoneUpdatedEvent(ctx context.Context, source string, phone *entities.Phone) error {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
-	event, err := service.createPhoneUpdatedEvent(source, events.PhoneUpdatedPayload{
+	event, err := service.createPhoneUpdatedEvent(ctx, source, events.PhoneUpdatedPayload{
 		PhoneID:   phone.ID,
 		UserID:    phone.UserID,
 		Timestamp: phone.UpdatedAt,
@@ -135,32 +534,47 @@ func (service *PhoneService) dispatchPhoneUpdatedEvent(ctx context.Context, sour
 	return nil
 }
 
-// Delete an entities.Phone
-func (service *PhoneService) Delete(ctx context.Context, source string, userID entities.UserID, phoneID uuid.UUID) error {
+// PhoneDeleteParams are parameters for deleting an entities.Phone
+type PhoneDeleteParams struct {
+	Source  string
+	UserID  entities.UserID
+	PhoneID uuid.UUID
+	// CancelPending marks the outstanding messages of the phone as failed, otherwise they are kept as they are
+	CancelPending bool
+}
+
+// Delete an entities.Phone with its heartbeats and settings
+func (service *PhoneService) Delete(ctx context.Context, params *PhoneDeleteParams) error {
 	ctx, span := service.tracer.Start(ctx)
 	defer span.End()
 
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
-	phone, err := service.repository.LoadByID(ctx, userID, phoneID)
+	phone, err := service.repository.LoadByID(ctx, params.UserID, params.PhoneID)
 	if err != nil {
-		msg := fmt.Sprintf("cannot load phone with userID [%s] and phoneID [%s]", userID, phoneID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		msg := fmt.Sprintf("cannot load phone with userID [%s] and phoneID [%s]", params.UserID, params.PhoneID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	if err = service.repository.Delete(ctx, userID, phoneID); err != nil {
-		msg := fmt.Sprintf("cannot delete phone with id [%s] and user id [%s]", phoneID, userID)
-		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	cancelled, err := service.repository.Delete(ctx, phone, params.CancelPending)
+	if err != nil {
+		msg := fmt.Sprintf("cannot delete phone with id [%s] and user id [%s]", params.PhoneID, params.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
 	}
 
-	ctxLogger.Info(fmt.Sprintf("deleted phone with id [%s] and user id [%s]", phoneID, userID))
+	ctxLogger.Info(fmt.Sprintf("deleted phone with id [%s] and user id [%s] and cancelled [%d] outstanding messages", params.PhoneID, params.UserID, cancelled))
 
-	event, err := service.createPhoneDeletedEvent(source, events.PhoneDeletedPayload{
-		PhoneID:   phone.ID,
-		UserID:    phone.UserID,
-		Timestamp: phone.UpdatedAt,
-		Owner:     phone.PhoneNumber,
-		SIM:       phone.SIM,
+	if phone.IsDefault {
+		service.promoteDefault(ctx, params.UserID)
+	}
+
+	event, err := service.createPhoneDeletedEvent(ctx, params.Source, events.PhoneDeletedPayload{
+		PhoneID:               phone.ID,
+		UserID:                phone.UserID,
+		Timestamp:             time.Now().UTC(),
+		Owner:                 phone.PhoneNumber,
+		SIM:                   phone.SIM,
+		CancelledMessageCount: cancelled,
 	})
 	if err != nil {
 		msg := "cannot create event when phone is deleted"
@@ -187,28 +601,102 @@ func (service *PhoneService) createPhone(ctx context.Context, params PhoneUpsert
 		// https://android.googlesource.com/platform/frameworks/opt/telephony/+/master/src/java/com/android/internal/telephony/SmsUsageMonitor.java#80
 		MessagesPerMinute:        10,
 		MessageExpirationSeconds: 10 * 60, // 10 minutes
-		MaxSendAttempts:          2,
+		MaxSendAttempts:          entities.DefaultMaxSendAttempts,
 		SIM:                      params.SIM,
+		AppVersion:               params.AppVersion,
 		PhoneNumber:              phonenumbers.Format(&params.PhoneNumber, phonenumbers.E164),
 		CreatedAt:                time.Now().UTC(),
 		UpdatedAt:                time.Now().UTC(),
 	}
 
+	if params.HeartbeatDeadThreshold != nil {
+		phone.HeartbeatDeadThresholdSeconds = uint(params.HeartbeatDeadThreshold.Seconds())
+	}
+
+	if params.HeartbeatAlertCooldown != nil {
+		phone.HeartbeatAlertCooldownSeconds = uint(params.HeartbeatAlertCooldown.Seconds())
+	}
+
+	if params.DailySendLimit != nil {
+		phone.DailySendLimit = *params.DailySendLimit
+	}
+
+	if params.DailySendLimitTimezone != nil {
+		phone.DailySendLimitTimezone = *params.DailySendLimitTimezone
+	}
+
+	if params.EncryptionEnabled != nil {
+		phone.EncryptionEnabled = *params.EncryptionEnabled
+	}
+
 	if err := service.repository.Save(ctx, phone); err != nil {
 		msg := fmt.Sprintf("cannot create phone with id [%s] and number [%s]", phone.ID, phone.PhoneNumber)
 		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	if err := service.saveSIMs(ctx, phone, params.SIMs); err != nil {
+		msg := fmt.Sprintf("cannot save SIMs of phone with id [%s] and number [%s]", phone.ID, phone.PhoneNumber)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	_, err := service.repository.LoadDefault(ctx, phone.UserID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		if err = service.repository.SetDefault(ctx, phone.UserID, phone.ID); err != nil {
+			msg := fmt.Sprintf("cannot set phone with id [%s] as default for user [%s]", phone.ID, phone.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		phone.IsDefault = true
+	}
+
 	ctxLogger.Info(fmt.Sprintf("phone updated with id [%s] in the phone repository for user [%s]", phone.ID, phone.UserID))
 	return phone, service.dispatchPhoneUpdatedEvent(ctx, params.Source, phone)
 }
 
-func (service *PhoneService) createPhoneUpdatedEvent(source string, payload events.PhoneUpdatedPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypePhoneUpdated, source, payload)
+// promoteDefault makes the most recent phone of a user the default phone
+func (service *PhoneService) promoteDefault(ctx context.Context, userID entities.UserID) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	phones, err := service.repository.Index(ctx, userID, repositories.IndexParams{Limit: 1})
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch phones for user [%s] to set a new default phone", userID)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return
+	}
+
+	if len(*phones) == 0 {
+		ctxLogger.Info(fmt.Sprintf("user [%s] has no phone to set as default", userID))
+		return
+	}
+
+	if err = service.repository.SetDefault(ctx, userID, (*phones)[0].ID); err != nil {
+		msg := fmt.Sprintf("cannot set phone with id [%s] as default for user [%s]", (*phones)[0].ID, userID)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
+func (service *PhoneService) createPhoneUpdatedEvent(ctx context.Context, source string, payload events.PhoneUpdatedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneUpdated, source, payload)
+}
+
+func (service *PhoneService) createPhoneBatteryLowEvent(ctx context.Context, source string, payload events.PhoneBatteryLowPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneBatteryLow, source, payload)
+}
+
+func (service *PhoneService) createPhoneClockSkewedEvent(ctx context.Context, source string, payload events.PhoneClockSkewedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneClockSkewed, source, payload)
 }
 
-func (service *PhoneService) createPhoneDeletedEvent(source string, payload events.PhoneDeletedPayload) (cloudevents.Event, error) {
-	return service.createEvent(events.EventTypePhoneDeleted, source, payload)
+func (service *PhoneService) createPhonePausedEvent(ctx context.Context, source string, payload events.PhonePausedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhonePaused, source, payload)
+}
+
+func (service *PhoneService) createPhoneResumedEvent(ctx context.Context, source string, payload events.PhoneResumedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneResumed, source, payload)
+}
+
+func (service *PhoneService) createPhoneDeletedEvent(ctx context.Context, source string, payload events.PhoneDeletedPayload) (cloudevents.Event, error) {
+	return service.createEvent(ctx, events.EventTypePhoneDeleted, source, payload)
 }
 
 func (service *PhoneService) update(phone *entities.Phone, params PhoneUpsertParams) *entities.Phone {
@@ -227,7 +715,31 @@ func (service *PhoneService) update(phone *entities.Phone, params PhoneUpsertPar
 		phone.MessageExpirationSeconds = uint(params.MessageExpirationDuration.Seconds())
 	}
 
+	if params.HeartbeatDeadThreshold != nil {
+		phone.HeartbeatDeadThresholdSeconds = uint(params.HeartbeatDeadThreshold.Seconds())
+	}
+
+	if params.HeartbeatAlertCooldown != nil {
+		phone.HeartbeatAlertCooldownSeconds = uint(params.HeartbeatAlertCooldown.Seconds())
+	}
+
+	if params.DailySendLimit != nil {
+		phone.DailySendLimit = *params.DailySendLimit
+	}
+
+	if params.DailySendLimitTimezone != nil {
+		phone.DailySendLimitTimezone = *params.DailySendLimitTimezone
+	}
+
+	if params.EncryptionEnabled != nil {
+		phone.EncryptionEnabled = *params.EncryptionEnabled
+	}
+
 	phone.SIM = params.SIM
 
+	if params.AppVersion != "" {
+		phone.AppVersion = params.AppVersion
+	}
+
 	return phone
 }
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDefaultPhoneRepository stores the entities.Phone of the users in memory with at most one default phone per user
+type stubDefaultPhoneRepository struct {
+	repositories.PhoneRepository
+	mutex  sync.Mutex
+	phones map[uuid.UUID]*entities.Phone
+}
+
+func newStubDefaultPhoneRepository(phones ...*entities.Phone) *stubDefaultPhoneRepository {
+	repository := &stubDefaultPhoneRepository{phones: map[uuid.UUID]*entities.Phone{}}
+	for _, phone := range phones {
+		repository.phones[phone.ID] = phone
+	}
+	return repository
+}
+
+func (repository *stubDefaultPhoneRepository) LoadByID(_ context.Context, userID entities.UserID, phoneID uuid.UUID) (*entities.Phone, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if phone, ok := repository.phones[phoneID]; ok && phone.UserID == userID {
+		clone := *phone
+		return &clone, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "phone does not exist")
+}
+
+func (repository *stubDefaultPhoneRepository) Load(_ context.Context, userID entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for _, phone := range repository.phones {
+		if phone.UserID == userID && phone.PhoneNumber == phoneNumber {
+			clone := *phone
+			return &clone, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "phone does not exist")
+}
+
+func (repository *stubDefaultPhoneRepository) Save(_ context.Context, phone *entities.Phone) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	clone := *phone
+	repository.phones[phone.ID] = &clone
+	return nil
+}
+
+func (repository *stubDefaultPhoneRepository) LoadDefault(_ context.Context, userID entities.UserID) (*entities.Phone, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for _, phone := range repository.phones {
+		if phone.UserID == userID && phone.IsDefault {
+			clone := *phone
+			return &clone, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "default phone does not exist")
+}
+
+func (repository *stubDefaultPhoneRepository) SetDefault(_ context.Context, userID entities.UserID, phoneID uuid.UUID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if phone, ok := repository.phones[phoneID]; !ok || phone.UserID != userID {
+		return stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "phone does not exist")
+	}
+	for _, phone := range repository.phones {
+		if phone.UserID == userID {
+			phone.IsDefault = phone.ID == phoneID
+		}
+	}
+	return nil
+}
+
+// Index returns the phones of a user with the most recent phone first
+func (repository *stubDefaultPhoneRepository) Index(_ context.Context, userID entities.UserID, params repositories.IndexParams) (*[]entities.Phone, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	phones := make([]entities.Phone, 0)
+	for _, phone := range repository.phones {
+		if phone.UserID == userID {
+			phones = append(phones, *phone)
+		}
+	}
+	sort.Slice(phones, func(i, j int) bool { return phones[i].CreatedAt.After(phones[j].CreatedAt) })
+	if params.Limit > 0 && len(phones) > params.Limit {
+		phones = phones[:params.Limit]
+	}
+	return &phones, nil
+}
+
+func (repository *stubDefaultPhoneRepository) Delete(_ context.Context, phone *entities.Phone, _ bool) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	delete(repository.phones, phone.ID)
+	return 0, nil
+}
+
+func newTestPhoneService(repository repositories.PhoneRepository) *PhoneService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	dispatcher := newTestQueueEventDispatcher(&capturingPushQueue{}, memory.NewEventRepository())
+	return NewPhoneService(logger, tracer, repository, dispatcher, 0, PhoneClockConfig{})
+}
+
+func TestPhoneService_Delete(t *testing.T) {
+	t.Run("the most recent phone is the default phone when the default phone is deleted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		timestamp := time.Now().UTC()
+		deleted := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199", IsDefault: true, CreatedAt: timestamp.Add(-3 * time.Hour)}
+		older := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550198", CreatedAt: timestamp.Add(-2 * time.Hour)}
+		recent := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550197", CreatedAt: timestamp.Add(-time.Hour)}
+		other := &entities.Phone{ID: uuid.New(), UserID: "user-b", PhoneNumber: "+18005550196", CreatedAt: timestamp}
+		repository := newStubDefaultPhoneRepository(deleted, older, recent, other)
+		service := newTestPhoneService(repository)
+
+		// Act
+		err := service.Delete(context.Background(), &PhoneDeleteParams{UserID: "user-a", PhoneID: deleted.ID})
+
+		// Assert
+		assert.Nil(t, err)
+
+		phone, err := service.LoadDefault(context.Background(), "user-a")
+		assert.Nil(t, err)
+		assert.Equal(t, recent.ID, phone.ID)
+
+		_, err = service.LoadDefault(context.Background(), "user-b")
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+	})
+
+	t.Run("the default phone is not changed when another phone is deleted", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		timestamp := time.Now().UTC()
+		current := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199", IsDefault: true, CreatedAt: timestamp.Add(-2 * time.Hour)}
+		deleted := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550198", CreatedAt: timestamp.Add(-time.Hour)}
+		service := newTestPhoneService(newStubDefaultPhoneRepository(current, deleted))
+
+		// Act
+		err := service.Delete(context.Background(), &PhoneDeleteParams{UserID: "user-a", PhoneID: deleted.ID})
+
+		// Assert
+		assert.Nil(t, err)
+
+		phone, err := service.LoadDefault(context.Background(), "user-a")
+		assert.Nil(t, err)
+		assert.Equal(t, current.ID, phone.ID)
+	})
+
+	t.Run("a user without another phone has no default phone", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		deleted := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199", IsDefault: true, CreatedAt: time.Now().UTC()}
+		service := newTestPhoneService(newStubDefaultPhoneRepository(deleted))
+
+		// Act
+		err := service.Delete(context.Background(), &PhoneDeleteParams{UserID: "user-a", PhoneID: deleted.ID})
+
+		// Assert
+		assert.Nil(t, err)
+
+		_, err = service.LoadDefault(context.Background(), "user-a")
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+	})
+}
+
+func TestPhoneService_PauseResume(t *testing.T) {
+	newOutstanding := func(t *testing.T, service *MessageService, phone *entities.Phone) *entities.Message {
+		message := &entities.Message{
+			ID:      uuid.New(),
+			UserID:  phone.UserID,
+			Owner:   phone.PhoneNumber,
+			Contact: "+18005550100",
+			Content: "This is a sample text message",
+			Type:    entities.MessageTypeMobileTerminated,
+			Status:  entities.MessageStatusPending,
+		}
+		_, err := service.repository.StoreMany(context.Background(), []*entities.Message{message})
+		assert.Nil(t, err)
+		return message
+	}
+
+	t.Run("an outstanding message is not released while the phone is paused", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199"}
+		phoneService := newTestPhoneService(newStubDefaultPhoneRepository(phone))
+		messageService := newTestMessageService(nil, MessageServiceDeps{PhoneService: phoneService})
+		message := newOutstanding(t, messageService, phone)
+
+		// Act
+		paused, err := phoneService.Pause(context.Background(), "test", "user-a", phone.ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.True(t, paused.IsPaused)
+		assert.NotNil(t, paused.PausedAt)
+
+		_, err = messageService.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+
+		stored, err := messageService.repository.Load(context.Background(), "user-a", message.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusPending, stored.Status)
+	})
+
+	t.Run("an outstanding message is released after the phone is resumed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &entities.Phone{ID: uuid.New(), UserID: "user-a", PhoneNumber: "+18005550199"}
+		phoneService := newTestPhoneService(newStubDefaultPhoneRepository(phone))
+		messageService := newTestMessageService(nil, MessageServiceDeps{PhoneService: phoneService})
+		message := newOutstanding(t, messageService, phone)
+
+		_, err := phoneService.Pause(context.Background(), "test", "user-a", phone.ID)
+		assert.Nil(t, err)
+
+		_, err = messageService.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+
+		// Act
+		resumed, err := phoneService.Resume(context.Background(), "test", "user-a", phone.ID)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.False(t, resumed.IsPaused)
+		assert.Nil(t, resumed.PausedAt)
+
+		outstanding, err := messageService.GetOutstanding(context.Background(), MessageGetOutstandingParams{UserID: "user-a", MessageID: message.ID, Timestamp: time.Now().UTC()})
+		assert.Nil(t, err)
+		assert.Equal(t, message.ID, outstanding.ID)
+		assert.Equal(t, entities.MessageStatusSending, outstanding.Status)
+	})
+
+	t.Run("the phone of another user cannot be paused", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		phone := &entities.Phone{ID: uuid.New(), UserID: "user-b", PhoneNumber: "+18005550199"}
+		repository := newStubDefaultPhoneRepository(phone)
+		service := newTestPhoneService(repository)
+
+		// Act
+		_, err := service.Pause(context.Background(), "test", "user-a", phone.ID)
+
+		// Assert
+		assert.Equal(t, repositories.ErrCodeNotFound, stacktrace.GetCode(err))
+
+		stored, err := repository.LoadByID(context.Background(), "user-b", phone.ID)
+		assert.Nil(t, err)
+		assert.False(t, stored.IsPaused)
+	})
+}
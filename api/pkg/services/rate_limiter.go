@@ -0,0 +1,171 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+// RateLimit is the number of requests which can be made in a Window, the tokens of a bucket are refilled continuously
+// so a client which waits for Window/Limit can make one more request
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseRateLimit parses a RateLimit in the <limit>/<window> format e.g. 60/1m, an empty value is a disabled RateLimit
+func ParseRateLimit(value string) (RateLimit, error) {
+	if strings.TrimSpace(value) == "" {
+		return RateLimit{}, nil
+	}
+
+	count, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return RateLimit{}, stacktrace.NewError(fmt.Sprintf("rate limit [%s] is not in the <limit>/<window> format e.g. 60/1m", value))
+	}
+
+	limit, err := strconv.Atoi(count)
+	if err != nil || limit < 0 {
+		return RateLimit{}, stacktrace.NewError(fmt.Sprintf("limit [%s] of rate limit [%s] is not a positive number", count, value))
+	}
+
+	duration, err := time.ParseDuration(window)
+	if err != nil || duration <= 0 {
+		return RateLimit{}, stacktrace.NewError(fmt.Sprintf("window [%s] of rate limit [%s] is not a positive duration", window, value))
+	}
+
+	return RateLimit{Limit: limit, Window: duration}, nil
+}
+
+// IsZero checks if the RateLimit is disabled
+func (limit RateLimit) IsZero() bool {
+	return limit.Limit <= 0 || limit.Window <= 0
+}
+
+// refillDuration is the time it takes to refill one token
+func (limit RateLimit) refillDuration() time.Duration {
+	return limit.Window / time.Duration(limit.Limit)
+}
+
+// RateLimitBucket is the token bucket with a Key e.g. send:<userID> which is limited by a RateLimit
+type RateLimitBucket struct {
+	Key   string
+	Limit RateLimit
+}
+
+// RateLimitResult is the state of the most restrictive bucket after a request is taken
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+
+	// RetryAfter is the time until the next request is allowed, it is zero when the request is allowed
+	RetryAfter time.Duration
+}
+
+type tokenBucket struct {
+	tokens    float64
+	window    time.Duration
+	updatedAt time.Time
+}
+
+// RateLimiter limits requests with token buckets which are stored in memory
+type RateLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	sweptAt time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// Take takes a token from every bucket at the timestamp when all the buckets have a token. No token is taken when a
+// bucket is empty so a request which is rejected by one RateLimit does not use the tokens of the others.
+func (limiter *RateLimiter) Take(timestamp time.Time, buckets ...RateLimitBucket) (result RateLimitResult) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.sweep(timestamp)
+
+	result.Allowed = true
+	var selected *tokenBucket
+	var selectedLimit RateLimit
+	for _, bucket := range buckets {
+		if bucket.Limit.IsZero() {
+			continue
+		}
+
+		state := limiter.refill(timestamp, bucket)
+		if selected == nil || state.tokens < selected.tokens {
+			selected, selectedLimit = state, bucket.Limit
+		}
+		if state.tokens < 1 {
+			result.Allowed = false
+		}
+	}
+
+	if selected == nil {
+		return RateLimitResult{Allowed: true}
+	}
+
+	if result.Allowed {
+		for _, bucket := range buckets {
+			if !bucket.Limit.IsZero() {
+				limiter.buckets[bucket.Key].tokens--
+			}
+		}
+	}
+
+	result.Limit = selectedLimit.Limit
+	result.Remaining = int(math.Floor(selected.tokens))
+	result.Reset = time.Duration((float64(selectedLimit.Limit) - selected.tokens) * float64(selectedLimit.refillDuration()))
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - selected.tokens) * float64(selectedLimit.refillDuration()))
+	}
+
+	return result
+}
+
+// refill adds the tokens which were refilled since the last request to a bucket, a new bucket is full
+func (limiter *RateLimiter) refill(timestamp time.Time, bucket RateLimitBucket) *tokenBucket {
+	state, ok := limiter.buckets[bucket.Key]
+	if !ok {
+		state = &tokenBucket{tokens: float64(bucket.Limit.Limit), updatedAt: timestamp}
+		limiter.buckets[bucket.Key] = state
+	}
+
+	if elapsed := timestamp.Sub(state.updatedAt); elapsed > 0 {
+		state.tokens = math.Min(float64(bucket.Limit.Limit), state.tokens+float64(elapsed)/float64(bucket.Limit.refillDuration()))
+		state.updatedAt = timestamp
+	}
+
+	state.window = bucket.Limit.Window
+	return state
+}
+
+// sweep removes the buckets which have been refilled completely once a minute so that the memory does not grow with
+// every key which was ever limited
+func (limiter *RateLimiter) sweep(timestamp time.Time) {
+	if timestamp.Sub(limiter.sweptAt) < time.Minute {
+		return
+	}
+
+	for key, state := range limiter.buckets {
+		if timestamp.Sub(state.updatedAt) >= state.window {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.sweptAt = timestamp
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		limit RateLimit
+		err   bool
+	}{
+		{name: "empty value is disabled", value: "", limit: RateLimit{}},
+		{name: "limit and window", value: "60/1m", limit: RateLimit{Limit: 60, Window: time.Minute}},
+		{name: "missing window", value: "60", err: true},
+		{name: "negative limit", value: "-1/1m", err: true},
+		{name: "invalid window", value: "60/minute", err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			limit, err := ParseRateLimit(test.value)
+
+			// Assert
+			assert.Equal(t, test.err, err != nil)
+			assert.Equal(t, test.limit, limit)
+		})
+	}
+}
+
+func TestRateLimiter_Take(t *testing.T) {
+	timestamp := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := RateLimit{Limit: 3, Window: 30 * time.Second}
+
+	t.Run("requests are rejected when the bucket is empty and allowed after the window", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		limiter := NewRateLimiter()
+		bucket := RateLimitBucket{Key: "send:user", Limit: limit}
+
+		// Act
+		var results []RateLimitResult
+		for i := 0; i < 4; i++ {
+			results = append(results, limiter.Take(timestamp, bucket))
+		}
+		refilled := limiter.Take(timestamp.Add(10*time.Second), bucket)
+		recovered := limiter.Take(timestamp.Add(10*time.Second+limit.Window), bucket)
+
+		// Assert
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 2, Reset: 10 * time.Second}, results[0])
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 1, Reset: 20 * time.Second}, results[1])
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}, results[2])
+		assert.Equal(t, RateLimitResult{Allowed: false, Limit: 3, Remaining: 0, Reset: 30 * time.Second, RetryAfter: 10 * time.Second}, results[3])
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 0, Reset: 30 * time.Second}, refilled)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 3, Remaining: 2, Reset: 10 * time.Second}, recovered)
+	})
+
+	t.Run("tokens are not taken from other buckets when a request is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		limiter := NewRateLimiter()
+		global := RateLimitBucket{Key: "global:user", Limit: RateLimit{Limit: 10, Window: time.Minute}}
+		send := RateLimitBucket{Key: "send:user", Limit: RateLimit{Limit: 1, Window: time.Minute}}
+
+		// Act
+		first := limiter.Take(timestamp, global, send)
+		second := limiter.Take(timestamp, global, send)
+		read := limiter.Take(timestamp, global, RateLimitBucket{Key: "read:user"})
+
+		// Assert
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Minute}, first)
+		assert.Equal(t, RateLimitResult{Allowed: false, Limit: 1, Remaining: 0, Reset: time.Minute, RetryAfter: time.Minute}, second)
+		assert.Equal(t, RateLimitResult{Allowed: true, Limit: 10, Remaining: 8, Reset: 12 * time.Second}, read)
+	})
+
+	t.Run("requests are allowed when the buckets have no rate limit", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		limiter := NewRateLimiter()
+
+		// Act
+		result := limiter.Take(timestamp, RateLimitBucket{Key: "global:user"})
+
+		// Assert
+		assert.Equal(t, RateLimitResult{Allowed: true}, result)
+	})
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// RoutingRuleService handles the rules which select the phone that sends a message by the country of the contact
+type RoutingRuleService struct {
+	service
+	logger       telemetry.Logger
+	tracer       telemetry.Tracer
+	repository   repositories.RoutingRuleRepository
+	phoneService *PhoneService
+}
+
+// NewRoutingRuleService creates a new RoutingRuleService
+func NewRoutingRuleService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.RoutingRuleRepository,
+	phoneService *PhoneService,
+) (s *RoutingRuleService) {
+	return &RoutingRuleService{
+		logger:       logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:       tracer,
+		repository:   repository,
+		phoneService: phoneService,
+	}
+}
+
+// RoutingRuleStoreParams are parameters for creating an entities.RoutingRule
+type RoutingRuleStoreParams struct {
+	UserID entities.UserID
+	Prefix string
+	Owner  string
+}
+
+// Store a new entities.RoutingRule
+func (service *RoutingRuleService) Store(ctx context.Context, params *RoutingRuleStoreParams) (*entities.RoutingRule, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.validate(ctx, params.UserID, uuid.Nil, params.Prefix, params.Owner); err != nil {
+		msg := fmt.Sprintf("cannot store routing rule with prefix [%s] for user [%s]", params.Prefix, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rule := &entities.RoutingRule{
+		ID:        uuid.New(),
+		UserID:    params.UserID,
+		Prefix:    params.Prefix,
+		Owner:     params.Owner,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err := service.repository.Store(ctx, rule); err != nil {
+		msg := fmt.Sprintf("cannot store routing rule with prefix [%s] for user [%s]", params.Prefix, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("created routing rule [%s] with prefix [%s] for user [%s]", rule.ID, rule.Prefix, rule.UserID))
+	return rule, nil
+}
+
+// RoutingRuleUpdateParams are parameters for updating an entities.RoutingRule
+type RoutingRuleUpdateParams struct {
+	UserID        entities.UserID
+	RoutingRuleID uuid.UUID
+	Prefix        string
+	Owner         string
+}
+
+// Update the prefix and the owner of an entities.RoutingRule
+func (service *RoutingRuleService) Update(ctx context.Context, params *RoutingRuleUpdateParams) (*entities.RoutingRule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rule, err := service.Get(ctx, params.UserID, params.RoutingRuleID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load routing rule with ID [%s]", params.RoutingRuleID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.validate(ctx, params.UserID, rule.ID, params.Prefix, params.Owner); err != nil {
+		msg := fmt.Sprintf("cannot update routing rule with ID [%s] to prefix [%s]", params.RoutingRuleID, params.Prefix)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	rule.Prefix = params.Prefix
+	rule.Owner = params.Owner
+	rule.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Update(ctx, rule); err != nil {
+		msg := fmt.Sprintf("cannot update routing rule with ID [%s]", params.RoutingRuleID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rule, nil
+}
+
+// Get an entities.RoutingRule by ID
+func (service *RoutingRuleService) Get(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) (*entities.RoutingRule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rule, err := service.repository.Load(ctx, userID, ruleID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load routing rule with ID [%s] for user [%s]", ruleID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return rule, nil
+}
+
+// Index fetches all the entities.RoutingRule of a user
+func (service *RoutingRuleService) Index(ctx context.Context, userID entities.UserID) ([]*entities.RoutingRule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	rules, err := service.repository.Fetch(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch routing rules for user [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return rules, nil
+}
+
+// Delete an entities.RoutingRule
+func (service *RoutingRuleService) Delete(ctx context.Context, userID entities.UserID, ruleID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	rule, err := service.Get(ctx, userID, ruleID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load routing rule with ID [%s]", ruleID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.Delete(ctx, rule); err != nil {
+		msg := fmt.Sprintf("cannot delete routing rule with ID [%s]", ruleID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted routing rule [%s] for user [%s]", ruleID, userID))
+	return nil
+}
+
+// validate makes sure the owner of a rule has a registered phone and that no other rule of the user has the prefix
+func (service *RoutingRuleService) validate(ctx context.Context, userID entities.UserID, ruleID uuid.UUID, prefix string, owner string) error {
+	_, err := service.phoneService.LoadOwner(ctx, userID, owner)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		return NewValidationError("owner", fmt.Sprintf("The owner [%s] does not have a registered phone, install the android app on the phone first", owner))
+	}
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot load phone with owner [%s] for user [%s]", owner, userID))
+	}
+
+	rules, err := service.repository.Fetch(ctx, userID)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot fetch routing rules for user [%s]", userID))
+	}
+
+	for _, rule := range rules {
+		if rule.Prefix != prefix || rule.ID == ruleID {
+			continue
+		}
+		if rule.IsDefault() {
+			return NewValidationError("prefix", "You already have a default route, update it instead of creating another one")
+		}
+		return NewValidationError("prefix", fmt.Sprintf("You already have a routing rule for the prefix [%s]", prefix))
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"time"
@@ -13,9 +14,31 @@ import (
 	"github.com/palantir/stacktrace"
 )
 
+// ErrCodeRateLimited is thrown when an owner has exceeded the number of messages it can send per minute
+const ErrCodeRateLimited = stacktrace.ErrorCode(2000)
+
+// ErrCodeInvalidTransition is thrown when an entity cannot move from its current status to the requested status
+const ErrCodeInvalidTransition = stacktrace.ErrorCode(2001)
+
+// ErrCodeConnectionLimit is thrown when a user has exceeded the number of connections which can be opened at the same time
+const ErrCodeConnectionLimit = stacktrace.ErrorCode(2002)
+
+// ErrCodeTelegramUnauthorized is thrown when a telegram update is not sent with the secret token of the webhook
+const ErrCodeTelegramUnauthorized = stacktrace.ErrorCode(2003)
+
+// ErrCodeFallbackUnauthorized is thrown when a status callback of a fallback provider does not have a valid signature
+const ErrCodeFallbackUnauthorized = stacktrace.ErrorCode(2004)
+
+// ErrCodeDailySendLimit is thrown when a phone has sent the number of messages which it can send in a day
+const ErrCodeDailySendLimit = stacktrace.ErrorCode(2005)
+
+// ErrCodeMediaUnavailable is thrown when media is used but the blob store which stores it is not configured or cannot sign URLs
+const ErrCodeMediaUnavailable = stacktrace.ErrorCode(2006)
+
 type service struct{}
 
-func (service *service) createEvent(eventType string, source string, payload any) (cloudevents.Event, error) {
+// createEvent creates a cloud event with the ID of the request in the context.Context as an extension
+func (service *service) createEvent(ctx context.Context, eventType string, source string, payload any) (cloudevents.Event, error) {
 	event := cloudevents.NewEvent()
 
 	event.SetSource(source)
@@ -23,8 +46,12 @@ func (service *service) createEvent(eventType string, source string, payload any
 	event.SetTime(time.Now().UTC())
 	event.SetID(uuid.New().String())
 
+	if requestID := telemetry.RequestID(ctx); requestID != "" {
+		event.SetExtension(telemetry.RequestIDExtension, requestID)
+	}
+
 	if err := event.SetData(cloudevents.ApplicationJSON, payload); err != nil {
-		msg := fmt.Sprintf("cannot encode %T [%#+v] as JSON", payload, payload)
+		msg := fmt.Sprintf("cannot encode [%T] of event [%s] as JSON", payload, eventType)
 		return event, stacktrace.Propagate(err, msg)
 	}
 
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	shortLinkCodeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	shortLinkCodeLength   = 8
+	// shortLinkCodeAttempts is the number of random codes which are generated for a link before giving up, a code
+	// is only generated again when it is already used by another link
+	shortLinkCodeAttempts = 5
+)
+
+// ShortLinkService replaces the URLs in the content of outgoing messages with short links and counts their clicks
+type ShortLinkService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.ShortLinkRepository
+	baseURL    string
+}
+
+// NewShortLinkService creates a new ShortLinkService, the short links are served at <baseURL>/s/<code> and the URLs
+// are not shortened when the baseURL is empty
+func NewShortLinkService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.ShortLinkRepository,
+	baseURL string,
+) (s *ShortLinkService) {
+	return &ShortLinkService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Shorten stores an entities.ShortLink for every URL in the content of a message which is longer than its short link
+// and returns the content with the short links. The content is not changed when no URL is shortened.
+func (service *ShortLinkService) Shorten(ctx context.Context, userID entities.UserID, messageID uuid.UUID, content string) (string, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if service.baseURL == "" {
+		return content, nil
+	}
+
+	shortURLLength := len(service.shortURL(strings.Repeat("0", shortLinkCodeLength)))
+	replacements := map[string]string{}
+	for _, url := range entities.ContentURLs(content) {
+		if len(url) <= shortURLLength {
+			continue
+		}
+
+		link, err := service.store(ctx, userID, messageID, url)
+		if err != nil {
+			msg := fmt.Sprintf("cannot store short link for message [%s] of user [%s]", messageID, userID)
+			return content, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		replacements[url] = link.ShortURL
+	}
+
+	if len(replacements) == 0 {
+		return content, nil
+	}
+
+	ctxLogger.Info(fmt.Sprintf("shortened [%d] URLs in the content of message [%s] for user [%s]", len(replacements), messageID, userID))
+	return entities.ReplaceContentURLs(content, replacements), nil
+}
+
+// Click records a click of the entities.ShortLink with the code and returns it so that the client is redirected to the URL
+func (service *ShortLinkService) Click(ctx context.Context, code string) (*entities.ShortLink, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	link, err := service.repository.Load(ctx, code)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load short link with code [%s]", code)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.RecordClick(ctx, link.ID, time.Now().UTC()); err != nil {
+		msg := fmt.Sprintf("cannot record a click of short link [%s]", link.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return link, nil
+}
+
+// IndexByMessage fetches the entities.ShortLink in the content of a message of a user with their clicks
+func (service *ShortLinkService) IndexByMessage(ctx context.Context, userID entities.UserID, messageID uuid.UUID) ([]*entities.ShortLink, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	links, err := service.repository.FetchByMessage(ctx, userID, messageID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot fetch the short links of message [%s] for user [%s]", messageID, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return links, nil
+}
+
+// store creates an entities.ShortLink with a random code which is not used by another link
+func (service *ShortLinkService) store(ctx context.Context, userID entities.UserID, messageID uuid.UUID, url string) (*entities.ShortLink, error) {
+	for attempt := 0; attempt < shortLinkCodeAttempts; attempt++ {
+		code, err := service.code()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot generate a short link code")
+		}
+
+		link := &entities.ShortLink{
+			ID:        uuid.New(),
+			UserID:    userID,
+			MessageID: messageID,
+			Code:      code,
+			ShortURL:  service.shortURL(code),
+			URL:       url,
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		}
+
+		outcome, err := service.repository.Store(ctx, link)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot store short link with code [%s]", code))
+		}
+
+		if outcome == repositories.StoreOutcomeInserted {
+			return link, nil
+		}
+	}
+
+	return nil, stacktrace.NewError(fmt.Sprintf("cannot find an unused short link code after [%d] attempts", shortLinkCodeAttempts))
+}
+
+// code generates a random code which cannot be guessed from the codes of other links
+func (service *ShortLinkService) code() (string, error) {
+	code := make([]byte, shortLinkCodeLength)
+	max := big.NewInt(int64(len(shortLinkCodeAlphabet)))
+	for index := range code {
+		value, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", stacktrace.Propagate(err, "cannot read random number")
+		}
+		code[index] = shortLinkCodeAlphabet[value.Int64()]
+	}
+	return string(code), nil
+}
+
+func (service *ShortLinkService) shortURL(code string) string {
+	return service.baseURL + "/s/" + code
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/slack"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// slackRateLimit is the rate at which messages are posted to an entities.Slack, slack allows about 1 message per
+// second in a channel
+var slackRateLimit = RateLimit{Limit: 1, Window: time.Second}
+
+// SlackService is responsible for handling slack integrations
+type SlackService struct {
+	service
+	logger           telemetry.Logger
+	tracer           telemetry.Tracer
+	client           *slack.Client
+	limiter          *RateLimiter
+	repository       repositories.SlackRepository
+	threadRepository repositories.SlackThreadRepository
+}
+
+// NewSlackService creates a new SlackService
+func NewSlackService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	client *slack.Client,
+	limiter *RateLimiter,
+	repository repositories.SlackRepository,
+	threadRepository repositories.SlackThreadRepository,
+) (s *SlackService) {
+	return &SlackService{
+		logger:           logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:           tracer,
+		client:           client,
+		limiter:          limiter,
+		repository:       repository,
+		threadRepository: threadRepository,
+	}
+}
+
+// Index fetches the entities.Slack for an entities.UserID
+func (service *SlackService) Index(ctx context.Context, userID entities.UserID, params repositories.IndexParams) ([]*entities.Slack, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	slackIntegrations, err := service.repository.Index(ctx, userID, params)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch slack integrations with params [%+#v]", params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("fetched [%d] slack integrations with prams [%+#v]", len(slackIntegrations), params))
+	return slackIntegrations, nil
+}
+
+// SlackStoreParams are parameters for creating a new entities.Slack
+type SlackStoreParams struct {
+	UserID          entities.UserID
+	Name            string
+	Owner           string
+	WebhookURL      string
+	BotToken        string
+	Channel         string
+	ThreadByContact bool
+}
+
+// Store a new entities.Slack
+func (service *SlackService) Store(ctx context.Context, params *SlackStoreParams) (*entities.Slack, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	slackIntegration := &entities.Slack{
+		ID:              uuid.New(),
+		UserID:          params.UserID,
+		Name:            params.Name,
+		Owner:           params.Owner,
+		WebhookURL:      params.WebhookURL,
+		BotToken:        params.BotToken,
+		Channel:         params.Channel,
+		ThreadByContact: params.ThreadByContact,
+		CreatedAt:       time.Now().UTC(),
+		UpdatedAt:       time.Now().UTC(),
+	}
+
+	if err := service.repository.Save(ctx, slackIntegration); err != nil {
+		msg := fmt.Sprintf("cannot save slack integration with id [%s]", slackIntegration.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("slack integration saved with id [%s] in the [%T]", slackIntegration.ID, service.repository))
+	return slackIntegration, nil
+}
+
+// SlackUpdateParams are parameters for updating an entities.Slack
+type SlackUpdateParams struct {
+	UserID          entities.UserID
+	SlackID         uuid.UUID
+	Name            string
+	Owner           string
+	WebhookURL      string
+	BotToken        string
+	Channel         string
+	ThreadByContact bool
+}
+
+// Update an entities.Slack
+func (service *SlackService) Update(ctx context.Context, params *SlackUpdateParams) (*entities.Slack, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	slackIntegration, err := service.repository.Load(ctx, params.UserID, params.SlackID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load slack integration with userID [%s] and slackID [%s]", params.UserID, params.SlackID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if slackIntegration.Channel != params.Channel || slackIntegration.WebhookURL != params.WebhookURL {
+		if err = service.threadRepository.DeleteAll(ctx, slackIntegration.ID); err != nil {
+			msg := fmt.Sprintf("cannot delete the threads of slack integration with id [%s]", slackIntegration.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	slackIntegration.Name = params.Name
+	slackIntegration.Owner = params.Owner
+	slackIntegration.WebhookURL = params.WebhookURL
+	slackIntegration.BotToken = params.BotToken
+	slackIntegration.Channel = params.Channel
+	slackIntegration.ThreadByContact = params.ThreadByContact
+	slackIntegration.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Save(ctx, slackIntegration); err != nil {
+		msg := fmt.Sprintf("cannot save slack integration with id [%s] after update", slackIntegration.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("slack integration updated with id [%s] in the [%T]", slackIntegration.ID, service.repository))
+	return slackIntegration, nil
+}
+
+// Delete an entities.Slack and its threads
+func (service *SlackService) Delete(ctx context.Context, userID entities.UserID, slackID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if _, err := service.repository.Load(ctx, userID, slackID); err != nil {
+		msg := fmt.Sprintf("cannot load slack integration with userID [%s] and slackID [%s]", userID, slackID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err := service.threadRepository.DeleteAll(ctx, slackID); err != nil {
+		msg := fmt.Sprintf("cannot delete the threads of slack integration with id [%s]", slackID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := service.repository.Delete(ctx, userID, slackID); err != nil {
+		msg := fmt.Sprintf("cannot delete slack integration with id [%s] and userID [%s]", slackID, userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted slack integration with id [%s] and user id [%s]", slackID, userID))
+	return nil
+}
+
+// HandleMessageReceived posts a received message to the slack integrations of the owner. The errors of slack are
+// only logged so that a slack outage never affects the received messages.
+func (service *SlackService) HandleMessageReceived(ctx context.Context, event cloudevents.Event, payload *events.MessagePhoneReceivedPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	slackIntegrations, err := service.repository.FetchByOwner(ctx, payload.UserID, payload.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load slack integrations for user with ID [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if len(slackIntegrations) == 0 {
+		ctxLogger.Info(fmt.Sprintf("user [%s] has no slack integration for event [%s]", payload.UserID, event.Type()))
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, slackIntegration := range slackIntegrations {
+		wg.Add(1)
+		go func(slackIntegration *entities.Slack) {
+			defer wg.Done()
+			service.sendMessage(ctx, event, payload, slackIntegration)
+		}(slackIntegration)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (service *SlackService) sendMessage(ctx context.Context, event cloudevents.Event, payload *events.MessagePhoneReceivedPayload, slackIntegration *entities.Slack) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.wait(ctx, slackIntegration); err != nil {
+		msg := fmt.Sprintf("cannot send [%s] event with ID [%s] to slack integration [%s]", event.Type(), event.ID(), slackIntegration.ID)
+		ctxLogger.Warn(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return
+	}
+
+	message := service.createSlackMessage(ctxLogger, payload)
+	if !slackIntegration.HasBotToken() {
+		if _, err := service.client.Webhook.Send(ctx, slackIntegration.WebhookURL, message); err != nil {
+			msg := fmt.Sprintf("cannot send [%s] event with ID [%s] to the webhook of slack integration [%s]", event.Type(), event.ID(), slackIntegration.ID)
+			ctxLogger.Warn(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+			return
+		}
+		ctxLogger.Info(fmt.Sprintf("sent [%s] event with ID [%s] to the webhook of slack integration [%s]", event.Type(), event.ID(), slackIntegration.ID))
+		return
+	}
+
+	message.Channel = slackIntegration.Channel
+	thread := service.loadThread(ctx, ctxLogger, slackIntegration, payload.Contact)
+	if thread != nil {
+		message.ThreadTS = thread.ThreadTS
+	}
+
+	response, _, err := service.client.Chat.PostMessage(ctx, slackIntegration.BotToken, message)
+	if err != nil {
+		msg := fmt.Sprintf("cannot post [%s] event with ID [%s] to channel [%s] of slack integration [%s]", event.Type(), event.ID(), slackIntegration.Channel, slackIntegration.ID)
+		ctxLogger.Warn(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return
+	}
+
+	ctxLogger.Info(fmt.Sprintf("posted slack message [%s] to channel [%s] for [%s] event with ID [%s]", response.TS, response.Channel, event.Type(), event.ID()))
+	if !slackIntegration.ThreadByContact || thread != nil {
+		return
+	}
+
+	thread = &entities.SlackThread{
+		SlackID:   slackIntegration.ID,
+		Contact:   payload.Contact,
+		Channel:   response.Channel,
+		ThreadTS:  response.TS,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = service.threadRepository.Store(ctx, thread); err != nil {
+		msg := fmt.Sprintf("cannot store slack thread [%s] of slack integration [%s]", thread.ThreadTS, slackIntegration.ID)
+		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
+
+// loadThread loads the thread of the contact when the messages are threaded, nil is returned for the first message
+func (service *SlackService) loadThread(ctx context.Context, ctxLogger telemetry.Logger, slackIntegration *entities.Slack, contact string) *entities.SlackThread {
+	if !slackIntegration.ThreadByContact {
+		return nil
+	}
+
+	thread, err := service.threadRepository.Load(ctx, slackIntegration.ID, contact)
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot load slack thread of slack integration [%s], the message is not threaded", slackIntegration.ID)))
+		return nil
+	}
+
+	return thread
+}
+
+// wait blocks until a message can be posted to the slack integration without exceeding the slackRateLimit
+func (service *SlackService) wait(ctx context.Context, slackIntegration *entities.Slack) error {
+	bucket := RateLimitBucket{Key: fmt.Sprintf("slack:%s", slackIntegration.ID), Limit: slackRateLimit}
+	for {
+		result := service.limiter.Take(time.Now().UTC(), bucket)
+		if result.Allowed {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return stacktrace.Propagate(ctx.Err(), fmt.Sprintf("context is done while waiting [%s] for the rate limit of slack integration [%s]", result.RetryAfter, slackIntegration.ID))
+		case <-time.After(result.RetryAfter):
+		}
+	}
+}
+
+func (service *SlackService) createSlackMessage(ctxLogger telemetry.Logger, payload *events.MessagePhoneReceivedPayload) *slack.Message {
+	from := service.getFormattedNumber(ctxLogger, payload.Contact)
+
+	// slack rejects a text object without text
+	content := displayContent(payload.Content, payload.IsEncrypted)
+	if strings.TrimSpace(content) == "" {
+		content = "(empty message)"
+	}
+
+	return &slack.Message{
+		Text: fmt.Sprintf("✉ new message received from %s: %s", from, displayContent(payload.Content, payload.IsEncrypted)),
+		Blocks: []slack.Block{
+			{
+				Type: "section",
+				Text: &slack.Text{Type: "mrkdwn", Text: "✉ *new message received*"},
+			},
+			{
+				Type: "section",
+				Fields: []slack.Text{
+					{Type: "mrkdwn", Text: fmt.Sprintf("*From:*\n%s", from)},
+					{Type: "mrkdwn", Text: fmt.Sprintf("*To:*\n%s", service.getFormattedNumber(ctxLogger, payload.Owner))},
+				},
+			},
+			{
+				Type: "section",
+				Text: &slack.Text{Type: "plain_text", Text: content},
+			},
+			{
+				Type: "context",
+				Elements: []slack.Text{
+					{Type: "mrkdwn", Text: fmt.Sprintf("Received at %s | MessageID: %s", payload.Timestamp.UTC().Format(time.RFC3339), payload.MessageID)},
+				},
+			},
+		},
+	}
+}
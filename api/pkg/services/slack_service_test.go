@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/slack"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSlackRepository returns a fixed list of slack integrations
+type stubSlackRepository struct {
+	repositories.SlackRepository
+	integrations []*entities.Slack
+}
+
+func (repository *stubSlackRepository) FetchByOwner(_ context.Context, userID entities.UserID, owner string) ([]*entities.Slack, error) {
+	var result []*entities.Slack
+	for _, integration := range repository.integrations {
+		if integration.UserID == userID && integration.Owner == owner {
+			result = append(result, integration)
+		}
+	}
+	return result, nil
+}
+
+// stubSlackThreadRepository stores the slack threads in memory
+type stubSlackThreadRepository struct {
+	mutex   sync.Mutex
+	threads map[string]*entities.SlackThread
+}
+
+func (repository *stubSlackThreadRepository) Store(_ context.Context, thread *entities.SlackThread) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.threads[thread.SlackID.String()+thread.Contact] = thread
+	return nil
+}
+
+func (repository *stubSlackThreadRepository) Load(_ context.Context, slackID uuid.UUID, contact string) (*entities.SlackThread, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if thread, ok := repository.threads[slackID.String()+contact]; ok {
+		return thread, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "thread does not exist")
+}
+
+func (repository *stubSlackThreadRepository) DeleteAll(_ context.Context, slackID uuid.UUID) error {
+	return nil
+}
+
+// slackRequest is a request which was received by the fake slack server
+type slackRequest struct {
+	path          string
+	authorization string
+	message       slack.Message
+}
+
+// fakeSlackServer records the messages which are posted and responds like the slack API
+type fakeSlackServer struct {
+	*httptest.Server
+	mutex       sync.Mutex
+	requests    []slackRequest
+	rateLimited int
+}
+
+func newFakeSlackServer(t *testing.T, rateLimited int) *fakeSlackServer {
+	server := &fakeSlackServer{rateLimited: rateLimited}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		if server.rateLimited > 0 {
+			server.rateLimited--
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		request := slackRequest{path: r.URL.Path, authorization: r.Header.Get("Authorization")}
+		assert.Nil(t, json.Unmarshal(body, &request.message))
+		server.requests = append(server.requests, request)
+
+		if r.URL.Path == "/webhook" {
+			_, _ = w.Write([]byte("ok"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"ok":true,"channel":"C0123456789","ts":"1503435956.00000%d"}`, len(server.requests))))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestSlackService(server *fakeSlackServer, integrations ...*entities.Slack) *SlackService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewSlackService(
+		logger,
+		tracer,
+		slack.New(slack.WithBaseURL(server.URL), slack.WithHTTPClient(server.Client())),
+		NewRateLimiter(),
+		&stubSlackRepository{integrations: integrations},
+		&stubSlackThreadRepository{threads: map[string]*entities.SlackThread{}},
+	)
+}
+
+func newTestMessagePhoneReceivedEvent(t *testing.T, contact string, content string) (cloudevents.Event, *events.MessagePhoneReceivedPayload) {
+	payload := &events.MessagePhoneReceivedPayload{
+		MessageID: uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		UserID:    "user-id",
+		Owner:     "+18005550199",
+		Contact:   contact,
+		Timestamp: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Content:   content,
+		SIM:       entities.SIM1,
+	}
+
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.NewString())
+	event.SetType(events.EventTypeMessagePhoneReceived)
+	event.SetSource("test")
+	assert.Nil(t, event.SetData(cloudevents.ApplicationJSON, payload))
+	return event, payload
+}
+
+func TestSlackService_HandleMessageReceived(t *testing.T) {
+	t.Run("the sender, timestamp and content are formatted into blocks", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeSlackServer(t, 0)
+
+		// Arrange
+		integration := &entities.Slack{ID: uuid.New(), UserID: "user-id", Owner: "+18005550199", WebhookURL: server.URL + "/webhook"}
+		service := newTestSlackService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+
+		// Act
+		err := service.HandleMessageReceived(context.Background(), event, payload)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, server.requests, 1)
+
+		request := server.requests[0]
+		assert.Equal(t, "/webhook", request.path)
+		assert.Empty(t, request.authorization)
+		assert.Empty(t, request.message.ThreadTS)
+		assert.Equal(t, "✉ new message received from +1 800-555-0100: Hello World", request.message.Text)
+		assert.Equal(t, []slack.Block{
+			{Type: "section", Text: &slack.Text{Type: "mrkdwn", Text: "✉ *new message received*"}},
+			{Type: "section", Fields: []slack.Text{
+				{Type: "mrkdwn", Text: "*From:*\n+1 800-555-0100"},
+				{Type: "mrkdwn", Text: "*To:*\n+1 800-555-0199"},
+			}},
+			{Type: "section", Text: &slack.Text{Type: "plain_text", Text: "Hello World"}},
+			{Type: "context", Elements: []slack.Text{
+				{Type: "mrkdwn", Text: "Received at 2024-01-02T15:04:05Z | MessageID: 32343a19-da5e-4b1b-a767-3298a73703cb"},
+			}},
+		}, request.message.Blocks)
+	})
+
+	t.Run("the messages from the same contact are posted in one thread", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeSlackServer(t, 0)
+
+		// Arrange
+		integration := &entities.Slack{ID: uuid.New(), UserID: "user-id", Owner: "+18005550199", BotToken: "xoxb-token", Channel: "C0123456789", ThreadByContact: true}
+		service := newTestSlackService(server, integration)
+
+		// Act
+		for _, contact := range []string{"+18005550100", "+18005550100", "+18005550111"} {
+			event, payload := newTestMessagePhoneReceivedEvent(t, contact, "Hello World")
+			assert.Nil(t, service.HandleMessageReceived(context.Background(), event, payload))
+		}
+
+		// Assert
+		assert.Len(t, server.requests, 3)
+		for _, request := range server.requests {
+			assert.Equal(t, "/chat.postMessage", request.path)
+			assert.Equal(t, "Bearer xoxb-token", request.authorization)
+			assert.Equal(t, "C0123456789", request.message.Channel)
+		}
+		assert.Empty(t, server.requests[0].message.ThreadTS)
+		assert.Equal(t, "1503435956.000001", server.requests[1].message.ThreadTS)
+		assert.Empty(t, server.requests[2].message.ThreadTS)
+	})
+
+	t.Run("the messages are not threaded when thread_by_contact is disabled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeSlackServer(t, 0)
+
+		// Arrange
+		integration := &entities.Slack{ID: uuid.New(), UserID: "user-id", Owner: "+18005550199", BotToken: "xoxb-token", Channel: "C0123456789"}
+		service := newTestSlackService(server, integration)
+
+		// Act
+		for i := 0; i < 2; i++ {
+			event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+			assert.Nil(t, service.HandleMessageReceived(context.Background(), event, payload))
+		}
+
+		// Assert
+		assert.Len(t, server.requests, 2)
+		assert.Empty(t, server.requests[1].message.ThreadTS)
+	})
+
+	t.Run("the message is retried when slack responds with 429", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeSlackServer(t, 2)
+
+		// Arrange
+		integration := &entities.Slack{ID: uuid.New(), UserID: "user-id", Owner: "+18005550199", BotToken: "xoxb-token", Channel: "C0123456789"}
+		service := newTestSlackService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+
+		// Act
+		err := service.HandleMessageReceived(context.Background(), event, payload)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, server.requests, 1)
+		assert.Equal(t, "Hello World", server.requests[0].message.Blocks[2].Text.Text)
+	})
+
+	t.Run("a slack failure does not return an error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeSlackServer(t, 10)
+
+		// Arrange
+		integration := &entities.Slack{ID: uuid.New(), UserID: "user-id", Owner: "+18005550199", WebhookURL: server.URL + "/webhook"}
+		service := newTestSlackService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+
+		// Act
+		err := service.HandleMessageReceived(context.Background(), event, payload)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, server.requests)
+	})
+}
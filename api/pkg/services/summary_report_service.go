@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/emails"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+// summaryReportBatchSize is the number of schedules which are loaded at once by SummaryReportService.SendDue
+const summaryReportBatchSize = 100
+
+// summaryReportMaxPeriods is the number of missed periods whose report is sent when the scheduler did not run for a
+// long time, the reports of the older periods are not sent
+const summaryReportMaxPeriods = 7
+
+// SummaryReportService sends the emails with the summary of the messages of the users in the previous day or week
+type SummaryReportService struct {
+	service
+	logger               telemetry.Logger
+	tracer               telemetry.Tracer
+	repository           repositories.SummaryReportRepository
+	statisticsRepository repositories.MessageStatisticsRepository
+	userRepository       repositories.UserRepository
+	factory              emails.NotificationEmailFactory
+	mailer               emails.Mailer
+}
+
+// NewSummaryReportService creates a new SummaryReportService
+func NewSummaryReportService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.SummaryReportRepository,
+	statisticsRepository repositories.MessageStatisticsRepository,
+	userRepository repositories.UserRepository,
+	factory emails.NotificationEmailFactory,
+	mailer emails.Mailer,
+) (s *SummaryReportService) {
+	return &SummaryReportService{
+		logger:               logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:               tracer,
+		repository:           repository,
+		statisticsRepository: statisticsRepository,
+		userRepository:       userRepository,
+		factory:              factory,
+		mailer:               mailer,
+	}
+}
+
+// LoadSchedule loads the entities.SummaryReportSchedule of a user, it is the default schedule when the user has not set it
+func (service *SummaryReportService) LoadSchedule(ctx context.Context, userID entities.UserID) (*entities.SummaryReportSchedule, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	schedule, err := service.repository.LoadSchedule(ctx, userID)
+	if stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		if err != nil {
+			msg := fmt.Sprintf("cannot load summary report schedule of user [%s]", userID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		return schedule, nil
+	}
+
+	user, err := service.userRepository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return entities.DefaultSummaryReportSchedule(user), nil
+}
+
+// SummaryReportScheduleUpsertParams are parameters for setting the entities.SummaryReportSchedule of a user, the
+// timezone of the user is used when the Timezone is empty
+type SummaryReportScheduleUpsertParams struct {
+	UserID    entities.UserID
+	Enabled   bool
+	Frequency entities.SummaryReportFrequency
+	Hour      uint
+	Timezone  string
+	SkipEmpty bool
+}
+
+// UpsertSchedule sets the entities.SummaryReportSchedule of a user
+func (service *SummaryReportService) UpsertSchedule(ctx context.Context, params *SummaryReportScheduleUpsertParams) (*entities.SummaryReportSchedule, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	schedule, err := service.repository.LoadSchedule(ctx, params.UserID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		schedule, err = &entities.SummaryReportSchedule{UserID: params.UserID, CreatedAt: time.Now().UTC()}, nil
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot load summary report schedule of user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if params.Timezone == "" {
+		user, err := service.userRepository.Load(ctx, params.UserID)
+		if err != nil {
+			msg := fmt.Sprintf("cannot load user with ID [%s]", params.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+		}
+		params.Timezone = user.Timezone
+	}
+
+	schedule.Enabled = params.Enabled
+	schedule.Frequency = params.Frequency
+	schedule.Hour = params.Hour
+	schedule.Timezone = params.Timezone
+	schedule.SkipEmpty = params.SkipEmpty
+	schedule.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.SaveSchedule(ctx, schedule); err != nil {
+		msg := fmt.Sprintf("cannot save summary report schedule of user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("saved [%s] summary report schedule of user [%s] at hour [%d] in [%s]", params.Frequency, params.UserID, params.Hour, params.Timezone))
+	return schedule, nil
+}
+
+// DeleteSchedule deletes the entities.SummaryReportSchedule of a user so that the default schedule is used
+func (service *SummaryReportService) DeleteSchedule(ctx context.Context, userID entities.UserID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if err := service.repository.DeleteSchedule(ctx, userID); err != nil {
+		msg := fmt.Sprintf("cannot delete summary report schedule of user [%s]", userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted summary report schedule of user [%s]", userID))
+	return nil
+}
+
+// SendDue sends the reports of the enabled schedules which are due at now and returns the number of emails which were
+// sent. A user whose reports cannot be sent does not stop the reports of the other users, their reports are sent when
+// the scheduler runs again.
+func (service *SummaryReportService) SendDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	var sent, failed int
+	var after entities.UserID
+	for {
+		schedules, err := service.repository.IndexEnabledSchedules(ctx, after, summaryReportBatchSize)
+		if err != nil {
+			msg := fmt.Sprintf("cannot fetch the summary report schedules after user [%s]", after)
+			return sent, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+
+		for _, schedule := range schedules {
+			count, err := service.sendSchedule(ctx, schedule, now)
+			sent += count
+			if err != nil {
+				failed++
+				ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot send the [%s] summary reports of user [%s]", schedule.Frequency, schedule.UserID)))
+			}
+		}
+
+		if len(schedules) < summaryReportBatchSize {
+			break
+		}
+		after = schedules[len(schedules)-1].UserID
+	}
+
+	if failed > 0 {
+		msg := fmt.Sprintf("cannot send the summary reports of [%d] users after sending [%d] reports", failed, sent)
+		return sent, service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+	}
+
+	ctxLogger.WithFields(map[string]interface{}{telemetry.LogFieldCount: sent}).Info(fmt.Sprintf("sent [%d] summary reports", sent))
+	return sent, nil
+}
+
+// sendSchedule sends the reports of the periods of a schedule which are due at now
+func (service *SummaryReportService) sendSchedule(ctx context.Context, schedule *entities.SummaryReportSchedule, now time.Time) (int, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	var last *time.Time
+	report, err := service.repository.LoadLastReport(ctx, schedule.UserID, schedule.Frequency)
+	if err != nil && stacktrace.GetCode(err) != repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("cannot load the last [%s] summary report of user [%s]", schedule.Frequency, schedule.UserID)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	if report != nil {
+		last = &report.PeriodStart
+	}
+
+	periods := schedule.DuePeriods(last, now, summaryReportMaxPeriods)
+	if len(periods) == 0 {
+		return 0, nil
+	}
+
+	user, err := service.userRepository.Load(ctx, schedule.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load user with ID [%s] for the summary report", schedule.UserID)
+		return 0, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	sent := 0
+	for _, start := range periods {
+		isSent, err := service.send(ctx, user, schedule, start)
+		if err != nil {
+			msg := fmt.Sprintf("cannot send the [%s] summary report of user [%s] for the period starting at [%s]", schedule.Frequency, schedule.UserID, start)
+			return sent, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+		if isSent {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// send generates the report of a period and sends it by email. The report is stored before the email is sent so that
+// it is sent once when the scheduler runs more than once, and it is deleted when the email cannot be sent so that it
+// is sent again when the scheduler runs again.
+func (service *SummaryReportService) send(ctx context.Context, user *entities.User, schedule *entities.SummaryReportSchedule, start time.Time) (bool, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	report, err := service.Generate(ctx, schedule, start)
+	if err != nil {
+		return false, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot generate the summary report of user [%s]", schedule.UserID)))
+	}
+
+	report.Skipped = schedule.SkipEmpty && report.IsEmpty()
+	stored, err := service.repository.StoreReport(ctx, report)
+	if err != nil {
+		return false, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot store the summary report [%s]", report.ID)))
+	}
+
+	if !stored {
+		ctxLogger.Info(fmt.Sprintf("[%s] summary report of user [%s] for the period starting at [%s] has already been sent", report.Frequency, report.UserID, report.PeriodStart))
+		return false, nil
+	}
+
+	if report.Skipped {
+		ctxLogger.Info(fmt.Sprintf("skipped the empty [%s] summary report [%s] of user [%s]", report.Frequency, report.ID, report.UserID))
+		return false, nil
+	}
+
+	email, err := service.factory.SummaryReport(user, report)
+	if err == nil {
+		err = service.mailer.Send(ctx, email)
+	}
+	if err != nil {
+		if deleteErr := service.repository.DeleteReport(ctx, report.ID); deleteErr != nil {
+			ctxLogger.Error(stacktrace.Propagate(deleteErr, fmt.Sprintf("cannot delete the summary report [%s] which was not sent", report.ID)))
+		}
+		return false, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot send the summary report [%s] to user [%s]", report.ID, user.ID)))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("sent [%s] summary report [%s] to user [%s] with [%d] outgoing and [%d] incoming messages", report.Frequency, report.ID, user.ID, report.Outgoing, report.Incoming))
+	return true, nil
+}
+
+// Generate creates the entities.SummaryReport of the period of a schedule which starts at start from the statistics
+// of the messages which the API received in the period
+func (service *SummaryReportService) Generate(ctx context.Context, schedule *entities.SummaryReportSchedule, start time.Time) (*entities.SummaryReport, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	params := repositories.MessageStatisticsParams{From: start, To: schedule.PeriodEnd(start)}
+
+	counts, err := service.statisticsRepository.CountHourly(ctx, schedule.UserID, params)
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the messages of user [%s] from [%s] to [%s]", schedule.UserID, params.From, params.To)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	contacts, err := service.statisticsRepository.CountContacts(ctx, schedule.UserID, params, 1)
+	if err != nil {
+		msg := fmt.Sprintf("cannot count the messages of user [%s] by contact from [%s] to [%s]", schedule.UserID, params.From, params.To)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return entities.NewSummaryReport(schedule, start, counts, contacts), nil
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/emails"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubSummaryReportRepository stores the schedules and the reports in memory
+type stubSummaryReportRepository struct {
+	mutex     sync.Mutex
+	schedules map[entities.UserID]*entities.SummaryReportSchedule
+	reports   []*entities.SummaryReport
+}
+
+func (repository *stubSummaryReportRepository) SaveSchedule(_ context.Context, schedule *entities.SummaryReportSchedule) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.schedules[schedule.UserID] = schedule
+	return nil
+}
+
+func (repository *stubSummaryReportRepository) LoadSchedule(_ context.Context, userID entities.UserID) (*entities.SummaryReportSchedule, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if schedule, ok := repository.schedules[userID]; ok {
+		return schedule, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "schedule does not exist")
+}
+
+func (repository *stubSummaryReportRepository) DeleteSchedule(_ context.Context, userID entities.UserID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	delete(repository.schedules, userID)
+	return nil
+}
+
+func (repository *stubSummaryReportRepository) IndexEnabledSchedules(_ context.Context, after entities.UserID, limit int) ([]*entities.SummaryReportSchedule, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var schedules []*entities.SummaryReportSchedule
+	for _, schedule := range repository.schedules {
+		if schedule.Enabled && schedule.UserID > after {
+			schedules = append(schedules, schedule)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].UserID < schedules[j].UserID })
+	if len(schedules) > limit {
+		schedules = schedules[:limit]
+	}
+	return schedules, nil
+}
+
+func (repository *stubSummaryReportRepository) LoadLastReport(_ context.Context, userID entities.UserID, frequency entities.SummaryReportFrequency) (*entities.SummaryReport, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	var last *entities.SummaryReport
+	for _, report := range repository.reports {
+		if report.UserID == userID && report.Frequency == frequency && (last == nil || report.PeriodStart.After(last.PeriodStart)) {
+			last = report
+		}
+	}
+	if last == nil {
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "report does not exist")
+	}
+	return last, nil
+}
+
+func (repository *stubSummaryReportRepository) StoreReport(_ context.Context, report *entities.SummaryReport) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for _, stored := range repository.reports {
+		if stored.UserID == report.UserID && stored.Frequency == report.Frequency && stored.PeriodStart.Equal(report.PeriodStart) {
+			return false, nil
+		}
+	}
+	repository.reports = append(repository.reports, report)
+	return true, nil
+}
+
+func (repository *stubSummaryReportRepository) DeleteReport(_ context.Context, reportID uuid.UUID) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for i, report := range repository.reports {
+		if report.ID == reportID {
+			repository.reports = append(repository.reports[:i], repository.reports[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// stubSummaryReportStatisticsRepository counts the same messages in every period
+type stubSummaryReportStatisticsRepository struct {
+	repositories.MessageStatisticsRepository
+	counts   []*entities.MessageHourlyCount
+	contacts []*entities.MessageContactCount
+}
+
+func (repository *stubSummaryReportStatisticsRepository) CountHourly(_ context.Context, _ entities.UserID, _ repositories.MessageStatisticsParams) ([]*entities.MessageHourlyCount, error) {
+	return repository.counts, nil
+}
+
+func (repository *stubSummaryReportStatisticsRepository) CountContacts(_ context.Context, _ entities.UserID, _ repositories.MessageStatisticsParams, _ int) ([]*entities.MessageContactCount, error) {
+	return repository.contacts, nil
+}
+
+// fakeSummaryReportEmailFactory creates an email with the start of the period of a report
+type fakeSummaryReportEmailFactory struct {
+	emails.NotificationEmailFactory
+}
+
+func (factory *fakeSummaryReportEmailFactory) SummaryReport(user *entities.User, report *entities.SummaryReport) (*emails.Email, error) {
+	return &emails.Email{ToEmail: user.Email, Subject: report.PeriodStart.UTC().Format(time.RFC3339)}, nil
+}
+
+// failingMailer fails to send the emails while failing is true
+type failingMailer struct {
+	fakeMailer
+	failing bool
+}
+
+func (mailer *failingMailer) Send(ctx context.Context, mail *emails.Email) error {
+	if mailer.failing {
+		return errors.New("cannot connect to the SMTP server")
+	}
+	return mailer.fakeMailer.Send(ctx, mail)
+}
+
+func newTestSummaryReportService(user *entities.User, schedule *entities.SummaryReportSchedule, counts []*entities.MessageHourlyCount) (*SummaryReportService, *stubSummaryReportRepository, *failingMailer) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+
+	repository := &stubSummaryReportRepository{schedules: map[entities.UserID]*entities.SummaryReportSchedule{}}
+	if schedule != nil {
+		repository.schedules[schedule.UserID] = schedule
+	}
+
+	mailer := &failingMailer{}
+	service := NewSummaryReportService(
+		logger,
+		tracer,
+		repository,
+		&stubSummaryReportStatisticsRepository{counts: counts},
+		&stubNotificationUserRepository{user: user},
+		&fakeSummaryReportEmailFactory{},
+		mailer,
+	)
+	return service, repository, mailer
+}
+
+func TestSummaryReportService_SendDue(t *testing.T) {
+	user := &entities.User{ID: "user-id", Email: "name@email.com", Timezone: "Europe/Berlin"}
+	counts := []*entities.MessageHourlyCount{{Type: entities.MessageTypeMobileTerminated, Status: entities.MessageStatusDelivered, Count: 3}}
+	newSchedule := func(skipEmpty bool) *entities.SummaryReportSchedule {
+		return &entities.SummaryReportSchedule{
+			UserID:    user.ID,
+			Enabled:   true,
+			Frequency: entities.SummaryReportFrequencyDaily,
+			Hour:      8,
+			Timezone:  "Europe/Berlin",
+			SkipEmpty: skipEmpty,
+			CreatedAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	now := time.Date(2022, 6, 6, 7, 0, 0, 0, time.UTC)
+
+	t.Run("the report of a period is sent once when the scheduler runs again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, _, mailer := newTestSummaryReportService(user, newSchedule(true), counts)
+
+		// Arrange
+		sent, err := service.SendDue(context.Background(), now)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, sent)
+
+		// Act
+		sent, err = service.SendDue(context.Background(), now.Add(15*time.Minute))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+		assert.Equal(t, 1, len(mailer.emails))
+		assert.Equal(t, "2022-06-04T22:00:00Z", mailer.emails[0].Subject)
+	})
+
+	t.Run("the report which cannot be sent is sent when the scheduler runs again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, repository, mailer := newTestSummaryReportService(user, newSchedule(true), counts)
+
+		// Arrange
+		mailer.failing = true
+		_, err := service.SendDue(context.Background(), now)
+		assert.NotNil(t, err)
+		assert.Empty(t, repository.reports)
+		mailer.failing = false
+
+		// Act
+		sent, err := service.SendDue(context.Background(), now.Add(15*time.Minute))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, 1, len(mailer.emails))
+	})
+
+	t.Run("the periods which were missed while the scheduler was not running are sent oldest first", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, _, mailer := newTestSummaryReportService(user, newSchedule(true), counts)
+
+		// Arrange
+		_, err := service.SendDue(context.Background(), now.AddDate(0, 0, -3))
+		assert.Nil(t, err)
+
+		// Act
+		sent, err := service.SendDue(context.Background(), now)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 3, sent)
+		var subjects []string
+		for _, email := range mailer.emails {
+			subjects = append(subjects, email.Subject)
+		}
+		assert.Equal(t, []string{
+			"2022-06-01T22:00:00Z",
+			"2022-06-02T22:00:00Z",
+			"2022-06-03T22:00:00Z",
+			"2022-06-04T22:00:00Z",
+		}, subjects)
+	})
+
+	t.Run("the report of a period without messages is skipped", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, repository, mailer := newTestSummaryReportService(user, newSchedule(true), nil)
+
+		// Act
+		sent, err := service.SendDue(context.Background(), now)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, sent)
+		assert.Empty(t, mailer.emails)
+		assert.Equal(t, 1, len(repository.reports))
+		assert.True(t, repository.reports[0].Skipped)
+	})
+
+	t.Run("the report of a period without messages is sent when it is not skipped", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service, _, mailer := newTestSummaryReportService(user, newSchedule(false), nil)
+
+		// Act
+		sent, err := service.SendDue(context.Background(), now)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, 1, len(mailer.emails))
+	})
+}
+
+func TestSummaryReportService_UpsertSchedule(t *testing.T) {
+	t.Run("the timezone of the user is used when the timezone is empty", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		user := &entities.User{ID: "user-id", Email: "name@email.com", Timezone: "Africa/Douala"}
+		service, repository, _ := newTestSummaryReportService(user, nil, nil)
+
+		// Act
+		schedule, err := service.UpsertSchedule(context.Background(), &SummaryReportScheduleUpsertParams{
+			UserID:    user.ID,
+			Enabled:   true,
+			Frequency: entities.SummaryReportFrequencyWeekly,
+			Hour:      9,
+		})
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "Africa/Douala", schedule.Timezone)
+		assert.False(t, schedule.CreatedAt.IsZero())
+		assert.Equal(t, schedule, repository.schedules[user.ID])
+	})
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// teamInvitationTTL is the duration in which a team invitation can be accepted
+	teamInvitationTTL = 7 * 24 * time.Hour
+
+	teamInvitationTokenLength = 48
+
+	// teamInvitationInvalidMessage is the validation error when a team invitation does not exist, has expired or was sent to another email
+	teamInvitationInvalidMessage = "The invitation is invalid, expired or was sent to another email address"
+)
+
+// TeamService is responsible for managing the members of an entities.Team
+type TeamService struct {
+	service
+	logger     telemetry.Logger
+	tracer     telemetry.Tracer
+	repository repositories.TeamRepository
+}
+
+// NewTeamService creates a new TeamService
+func NewTeamService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.TeamRepository,
+) (s *TeamService) {
+	return &TeamService{
+		logger:     logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:     tracer,
+		repository: repository,
+	}
+}
+
+// Index fetches the entities.Team of a user starting with the personal team of the user
+func (service *TeamService) Index(ctx context.Context, userID entities.UserID, email string) ([]*entities.Team, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	teams, err := service.repository.Index(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch teams of user with ID [%s]", userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	personal := &entities.Team{ID: userID, Name: email, Role: entities.TeamRoleAdmin, IsPersonal: true}
+
+	ctxLogger.Info(fmt.Sprintf("fetched [%d] teams of user with ID [%s]", len(teams), userID))
+	return append([]*entities.Team{personal}, teams...), nil
+}
+
+// IndexMembers fetches the entities.TeamMember of a team
+func (service *TeamService) IndexMembers(ctx context.Context, teamID entities.UserID) ([]*entities.TeamMember, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	members, err := service.repository.IndexMembers(ctx, teamID)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch members of team with ID [%s]", teamID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return members, nil
+}
+
+// TeamInviteParams are parameters for inviting a user to a team
+type TeamInviteParams struct {
+	TeamID    entities.UserID
+	InvitedBy entities.UserID
+	Email     string
+	Role      entities.TeamRole
+}
+
+// Invite creates an entities.TeamInvitation, the plain text token is only available in the returned entities.TeamInvitation
+func (service *TeamService) Invite(ctx context.Context, params *TeamInviteParams) (*entities.TeamInvitation, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	token, err := service.generateToken()
+	if err != nil {
+		msg := fmt.Sprintf("cannot generate invitation token for team with ID [%s]", params.TeamID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	invitation := &entities.TeamInvitation{
+		ID:        uuid.New(),
+		TeamID:    params.TeamID,
+		InvitedBy: params.InvitedBy,
+		Email:     params.Email,
+		Role:      params.Role,
+		Token:     token,
+		TokenHash: entities.HashTeamInvitationToken(token),
+		ExpiresAt: time.Now().UTC().Add(teamInvitationTTL),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err = service.repository.StoreInvitation(ctx, invitation); err != nil {
+		msg := fmt.Sprintf("cannot save team invitation with ID [%s]", invitation.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("user [%s] invited [%s] to team [%s] with role [%s]", params.InvitedBy, params.Email, params.TeamID, params.Role))
+	return invitation, nil
+}
+
+// TeamJoinParams are parameters for accepting an entities.TeamInvitation
+type TeamJoinParams struct {
+	UserID entities.UserID
+	Email  string
+	Token  string
+}
+
+// Join adds a user to the team of an entities.TeamInvitation
+func (service *TeamService) Join(ctx context.Context, params *TeamJoinParams) (*entities.TeamMember, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	invitation, err := service.repository.LoadInvitation(ctx, entities.HashTeamInvitationToken(strings.TrimSpace(params.Token)))
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("user [%s] cannot join a team with an invalid invitation token", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("token", teamInvitationInvalidMessage), msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load team invitation for user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if invitation.TeamID == params.UserID || !invitation.CanBeAcceptedBy(params.Email, time.Now().UTC()) {
+		msg := fmt.Sprintf("user [%s] with email [%s] cannot accept team invitation with ID [%s]", params.UserID, params.Email, invitation.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("token", teamInvitationInvalidMessage), msg))
+	}
+
+	member := &entities.TeamMember{
+		ID:        uuid.New(),
+		TeamID:    invitation.TeamID,
+		UserID:    params.UserID,
+		Email:     params.Email,
+		Role:      invitation.Role,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	err = service.repository.AcceptInvitation(ctx, invitation, member)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("team invitation with ID [%s] has already been accepted", invitation.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("token", teamInvitationInvalidMessage), msg))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot accept team invitation with ID [%s] for user [%s]", invitation.ID, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("user [%s] joined team [%s] with role [%s]", member.UserID, member.TeamID, member.Role))
+	return member, nil
+}
+
+// DeleteMember removes a user from a team
+func (service *TeamService) DeleteMember(ctx context.Context, teamID entities.UserID, userID entities.UserID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	member, err := service.repository.LoadMember(ctx, teamID, userID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load member [%s] of team [%s]", userID, teamID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if err = service.repository.DeleteMember(ctx, member); err != nil {
+		msg := fmt.Sprintf("cannot delete member [%s] of team [%s]", userID, teamID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("removed user [%s] from team [%s]", userID, teamID))
+	return nil
+}
+
+func (service *TeamService) generateToken() (string, error) {
+	b := make([]byte, teamInvitationTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", stacktrace.Propagate(err, "cannot generate random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(b)[:teamInvitationTokenLength], nil
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telegram"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// telegramWebhookSecretLength is the length of the secret token which telegram sends with the updates of a bot
+const telegramWebhookSecretLength = 48
+
+// TelegramService is responsible for handling telegram integrations
+type TelegramService struct {
+	service
+	logger            telemetry.Logger
+	tracer            telemetry.Tracer
+	client            *telegram.Client
+	webhookURL        string
+	repository        repositories.TelegramRepository
+	messageRepository repositories.TelegramMessageRepository
+}
+
+// NewTelegramService creates a new TelegramService, the webhookURL is the public URL of the API which receives the
+// updates of the telegram bots
+func NewTelegramService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	client *telegram.Client,
+	webhookURL string,
+	repository repositories.TelegramRepository,
+	messageRepository repositories.TelegramMessageRepository,
+) (s *TelegramService) {
+	return &TelegramService{
+		logger:            logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:            tracer,
+		client:            client,
+		webhookURL:        strings.TrimRight(webhookURL, "/"),
+		repository:        repository,
+		messageRepository: messageRepository,
+	}
+}
+
+// Index fetches the entities.Telegram for an entities.UserID
+func (service *TelegramService) Index(ctx context.Context, userID entities.UserID, params repositories.IndexParams) ([]*entities.Telegram, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	telegramIntegrations, err := service.repository.Index(ctx, userID, params)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch telegram integrations with params [%+#v]", params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("fetched [%d] telegram integrations with prams [%+#v]", len(telegramIntegrations), params))
+	return telegramIntegrations, nil
+}
+
+// TelegramStoreParams are parameters for creating a new entities.Telegram
+type TelegramStoreParams struct {
+	UserID   entities.UserID
+	Name     string
+	Owner    string
+	BotToken string
+	ChatID   int64
+}
+
+// Store a new entities.Telegram and set the webhook of the telegram bot
+func (service *TelegramService) Store(ctx context.Context, params *TelegramStoreParams) (*entities.Telegram, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	telegramIntegration := &entities.Telegram{
+		ID:        uuid.New(),
+		UserID:    params.UserID,
+		Name:      params.Name,
+		Owner:     params.Owner,
+		BotToken:  params.BotToken,
+		ChatID:    params.ChatID,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	if err := service.setWebhook(ctx, telegramIntegration); err != nil {
+		msg := fmt.Sprintf("cannot set the webhook of telegram integration [%s]", telegramIntegration.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err := service.repository.Save(ctx, telegramIntegration); err != nil {
+		msg := fmt.Sprintf("cannot save telegram integration with id [%s]", telegramIntegration.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("telegram integration saved with id [%s] in the [%T]", telegramIntegration.ID, service.repository))
+	return telegramIntegration, nil
+}
+
+// TelegramUpdateParams are parameters for updating an entities.Telegram
+type TelegramUpdateParams struct {
+	UserID     entities.UserID
+	TelegramID uuid.UUID
+	Name       string
+	Owner      string
+	BotToken   string
+	ChatID     int64
+}
+
+// Update an entities.Telegram, the webhook is set again when the bot token changes
+func (service *TelegramService) Update(ctx context.Context, params *TelegramUpdateParams) (*entities.Telegram, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	telegramIntegration, err := service.repository.Load(ctx, params.UserID, params.TelegramID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integration with userID [%s] and telegramID [%s]", params.UserID, params.TelegramID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if telegramIntegration.BotToken != params.BotToken {
+		telegramIntegration.BotToken = params.BotToken
+		telegramIntegration.LastUpdateID = 0
+		if err = service.setWebhook(ctx, telegramIntegration); err != nil {
+			msg := fmt.Sprintf("cannot set the webhook of telegram integration [%s]", telegramIntegration.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	if telegramIntegration.ChatID != params.ChatID || telegramIntegration.Owner != params.Owner {
+		if err = service.messageRepository.DeleteAll(ctx, telegramIntegration.ID); err != nil {
+			msg := fmt.Sprintf("cannot delete the messages of telegram integration with id [%s]", telegramIntegration.ID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	telegramIntegration.Name = params.Name
+	telegramIntegration.Owner = params.Owner
+	telegramIntegration.ChatID = params.ChatID
+	telegramIntegration.UpdatedAt = time.Now().UTC()
+
+	if err = service.repository.Save(ctx, telegramIntegration); err != nil {
+		msg := fmt.Sprintf("cannot save telegram integration with id [%s] after update", telegramIntegration.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("telegram integration updated with id [%s] in the [%T]", telegramIntegration.ID, service.repository))
+	return telegramIntegration, nil
+}
+
+// Delete an entities.Telegram, its messages and the webhook of the telegram bot
+func (service *TelegramService) Delete(ctx context.Context, userID entities.UserID, telegramID uuid.UUID) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	telegramIntegration, err := service.repository.Load(ctx, userID, telegramID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integration with userID [%s] and telegramID [%s]", userID, telegramID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if _, err = service.client.Bot.DeleteWebhook(ctx, telegramIntegration.BotToken); err != nil {
+		msg := fmt.Sprintf("cannot delete the webhook of telegram integration [%s]", telegramID)
+		ctxLogger.Warn(stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.messageRepository.DeleteAll(ctx, telegramID); err != nil {
+		msg := fmt.Sprintf("cannot delete the messages of telegram integration with id [%s]", telegramID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.repository.Delete(ctx, userID, telegramID); err != nil {
+		msg := fmt.Sprintf("cannot delete telegram integration with id [%s] and userID [%s]", telegramID, userID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("deleted telegram integration with id [%s] and user id [%s]", telegramID, userID))
+	return nil
+}
+
+// setWebhook sets the webhook of the telegram bot of an entities.Telegram with a new secret token
+func (service *TelegramService) setWebhook(ctx context.Context, telegramIntegration *entities.Telegram) error {
+	if service.webhookURL == "" {
+		return stacktrace.NewError("the URL which receives the telegram updates is not configured")
+	}
+
+	secret, err := service.generateSecret()
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot generate the secret token of the telegram webhook")
+	}
+
+	request := &telegram.SetWebhookRequest{
+		URL:            fmt.Sprintf("%s/telegram/%s/updates", service.webhookURL, telegramIntegration.ID),
+		SecretToken:    secret,
+		AllowedUpdates: []string{"message"},
+	}
+	if _, err = service.client.Bot.SetWebhook(ctx, telegramIntegration.BotToken, request); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot set the telegram webhook to [%s]", request.URL))
+	}
+
+	telegramIntegration.WebhookSecret = secret
+	return nil
+}
+
+func (service *TelegramService) generateSecret() (string, error) {
+	b := make([]byte, telegramWebhookSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", stacktrace.Propagate(err, "cannot generate random bytes")
+	}
+	return base64.RawURLEncoding.EncodeToString(b)[:telegramWebhookSecretLength], nil
+}
+
+// HandleMessageReceived forwards a received message to the telegram integrations of the owner. The errors of
+// telegram are only logged so that a telegram outage never affects the received messages.
+func (service *TelegramService) HandleMessageReceived(ctx context.Context, event cloudevents.Event, payload *events.MessagePhoneReceivedPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	// a message which the owner sent to itself would be forwarded again after every reply
+	if payload.Contact == payload.Owner {
+		ctxLogger.Info(fmt.Sprintf("message [%s] was sent by the owner to itself, it is not forwarded to telegram", payload.MessageID))
+		return nil
+	}
+
+	telegramIntegrations, err := service.repository.FetchByOwner(ctx, payload.UserID, payload.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integrations for user with ID [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	if len(telegramIntegrations) == 0 {
+		ctxLogger.Info(fmt.Sprintf("user [%s] has no telegram integration for event [%s]", payload.UserID, event.Type()))
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, telegramIntegration := range telegramIntegrations {
+		wg.Add(1)
+		go func(telegramIntegration *entities.Telegram) {
+			defer wg.Done()
+			service.forwardMessage(ctx, event, payload, telegramIntegration)
+		}(telegramIntegration)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (service *TelegramService) forwardMessage(ctx context.Context, event cloudevents.Event, payload *events.MessagePhoneReceivedPayload, telegramIntegration *entities.Telegram) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	request := &telegram.SendMessageRequest{
+		ChatID: telegramIntegration.ChatID,
+		Text: fmt.Sprintf(
+			"✉ SMS from %s to %s\n\n%s\n\nReply to this message to send an SMS to %s",
+			service.getFormattedNumber(ctxLogger, payload.Contact),
+			service.getFormattedNumber(ctxLogger, payload.Owner),
+			displayContent(payload.Content, payload.IsEncrypted),
+			service.getFormattedNumber(ctxLogger, payload.Contact),
+		),
+	}
+
+	message, _, err := service.client.Bot.SendMessage(ctx, telegramIntegration.BotToken, request)
+	if err != nil {
+		msg := fmt.Sprintf("cannot forward [%s] event with ID [%s] to the chat of telegram integration [%s]", event.Type(), event.ID(), telegramIntegration.ID)
+		ctxLogger.Warn(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+		return
+	}
+
+	service.storeMessage(ctx, ctxLogger, telegramIntegration, message.MessageID, payload.MessageID, payload.Contact)
+	ctxLogger.Info(fmt.Sprintf("forwarded [%s] event with ID [%s] to telegram message [%d] of integration [%s]", event.Type(), event.ID(), message.MessageID, telegramIntegration.ID))
+}
+
+// storeMessage maps a message in the telegram chat to the contact so that the replies to the message are sent to the contact
+func (service *TelegramService) storeMessage(ctx context.Context, ctxLogger telemetry.Logger, telegramIntegration *entities.Telegram, telegramMessageID int64, messageID uuid.UUID, contact string) {
+	mapping := &entities.TelegramMessage{
+		TelegramID:        telegramIntegration.ID,
+		TelegramMessageID: telegramMessageID,
+		ChatID:            telegramIntegration.ChatID,
+		UserID:            telegramIntegration.UserID,
+		MessageID:         messageID,
+		Owner:             telegramIntegration.Owner,
+		Contact:           contact,
+		CreatedAt:         time.Now().UTC(),
+	}
+
+	if err := service.messageRepository.Store(ctx, mapping); err != nil {
+		msg := fmt.Sprintf("cannot store telegram message [%d] of integration [%s], the replies to it cannot be sent", telegramMessageID, telegramIntegration.ID)
+		ctxLogger.Error(stacktrace.Propagate(err, msg))
+	}
+}
+
+// TelegramReply is a reply in a telegram chat which is sent as an SMS to the Contact
+type TelegramReply struct {
+	Telegram *entities.Telegram
+	Message  *telegram.Message
+	Contact  string
+}
+
+// ResolveReply loads the contact of the SMS which a telegram update replies to. A nil TelegramReply is returned for
+// the updates which must not be sent e.g. the updates from bots, from another chat or which were already handled.
+func (service *TelegramService) ResolveReply(ctx context.Context, telegramID uuid.UUID, secret string, update *telegram.Update) (*TelegramReply, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	telegramIntegration, err := service.repository.LoadByID(ctx, telegramID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		msg := fmt.Sprintf("telegram integration [%s] does not exist", telegramID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeTelegramUnauthorized, msg))
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram integration [%s]", telegramID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if telegramIntegration.WebhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(telegramIntegration.WebhookSecret)) != 1 {
+		msg := fmt.Sprintf("the secret token of the update [%d] does not match the webhook of telegram integration [%s]", update.UpdateID, telegramID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeTelegramUnauthorized, msg))
+	}
+
+	message := update.Message
+	if message == nil || message.From == nil || message.From.IsBot || strings.TrimSpace(message.Text) == "" {
+		ctxLogger.Info(fmt.Sprintf("update [%d] of telegram integration [%s] is not a text message from a user", update.UpdateID, telegramID))
+		return nil, nil
+	}
+
+	if message.Chat.ID != telegramIntegration.ChatID {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("update [%d] from chat [%d] is not from the chat of telegram integration [%s]", update.UpdateID, message.Chat.ID, telegramID)))
+		return nil, nil
+	}
+
+	claimed, err := service.repository.ClaimUpdate(ctx, telegramID, update.UpdateID)
+	if err != nil {
+		msg := fmt.Sprintf("cannot claim update [%d] of telegram integration [%s]", update.UpdateID, telegramID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	if !claimed {
+		ctxLogger.Info(fmt.Sprintf("update [%d] of telegram integration [%s] was already handled", update.UpdateID, telegramID))
+		return nil, nil
+	}
+
+	if message.ReplyToMessage == nil {
+		service.Notify(ctx, telegramIntegration, message.MessageID, "Reply to a forwarded SMS to send an SMS to its sender")
+		return nil, nil
+	}
+
+	mapping, err := service.messageRepository.Load(ctx, telegramID, message.ReplyToMessage.MessageID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		service.Notify(ctx, telegramIntegration, message.MessageID, "The message you replied to is not a forwarded SMS")
+		return nil, nil
+	}
+	if err != nil {
+		msg := fmt.Sprintf("cannot load telegram message [%d] of integration [%s]", message.ReplyToMessage.MessageID, telegramID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return &TelegramReply{Telegram: telegramIntegration, Message: message, Contact: mapping.Contact}, nil
+}
+
+// HandleReplySent maps the reply to the contact so that the replies to it are sent to the same contact and confirms
+// that the SMS was sent in the telegram chat
+func (service *TelegramService) HandleReplySent(ctx context.Context, reply *TelegramReply, message *entities.Message) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	service.storeMessage(ctx, ctxLogger, reply.Telegram, reply.Message.MessageID, message.ID, reply.Contact)
+	service.Notify(ctx, reply.Telegram, reply.Message.MessageID, fmt.Sprintf("✅ SMS sent to %s", service.getFormattedNumber(ctxLogger, reply.Contact)))
+}
+
+// Notify replies to a message in the chat of an entities.Telegram, the errors are only logged
+func (service *TelegramService) Notify(ctx context.Context, telegramIntegration *entities.Telegram, replyToMessageID int64, text string) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	request := &telegram.SendMessageRequest{
+		ChatID:           telegramIntegration.ChatID,
+		Text:             text,
+		ReplyToMessageID: replyToMessageID,
+	}
+	if _, _, err := service.client.Bot.SendMessage(ctx, telegramIntegration.BotToken, request); err != nil {
+		msg := fmt.Sprintf("cannot reply to telegram message [%d] of integration [%s]", replyToMessageID, telegramIntegration.ID)
+		ctxLogger.Warn(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
+	}
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telegram"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubTelegramRepository stores the telegram integrations in memory
+type stubTelegramRepository struct {
+	repositories.TelegramRepository
+	mutex        sync.Mutex
+	integrations []*entities.Telegram
+}
+
+func (repository *stubTelegramRepository) FetchByOwner(_ context.Context, userID entities.UserID, owner string) ([]*entities.Telegram, error) {
+	var result []*entities.Telegram
+	for _, integration := range repository.integrations {
+		if integration.UserID == userID && integration.Owner == owner {
+			result = append(result, integration)
+		}
+	}
+	return result, nil
+}
+
+func (repository *stubTelegramRepository) LoadByID(_ context.Context, telegramID uuid.UUID) (*entities.Telegram, error) {
+	for _, integration := range repository.integrations {
+		if integration.ID == telegramID {
+			return integration, nil
+		}
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "telegram integration does not exist")
+}
+
+func (repository *stubTelegramRepository) ClaimUpdate(_ context.Context, telegramID uuid.UUID, updateID int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	for _, integration := range repository.integrations {
+		if integration.ID == telegramID && integration.LastUpdateID < updateID {
+			integration.LastUpdateID = updateID
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// stubTelegramMessageRepository stores the telegram messages in memory
+type stubTelegramMessageRepository struct {
+	mutex    sync.Mutex
+	messages map[string]*entities.TelegramMessage
+}
+
+func (repository *stubTelegramMessageRepository) Store(_ context.Context, message *entities.TelegramMessage) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	repository.messages[fmt.Sprintf("%s/%d", message.TelegramID, message.TelegramMessageID)] = message
+	return nil
+}
+
+func (repository *stubTelegramMessageRepository) Load(_ context.Context, telegramID uuid.UUID, telegramMessageID int64) (*entities.TelegramMessage, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+	if message, ok := repository.messages[fmt.Sprintf("%s/%d", telegramID, telegramMessageID)]; ok {
+		return message, nil
+	}
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "telegram message does not exist")
+}
+
+func (repository *stubTelegramMessageRepository) DeleteAll(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+// fakeTelegramServer records the messages which are sent by the bot
+type fakeTelegramServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	messages []telegram.SendMessageRequest
+}
+
+func newFakeTelegramServer(t *testing.T) *fakeTelegramServer {
+	server := &fakeTelegramServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		assert.True(t, strings.HasPrefix(r.URL.Path, "/botbot-token/"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		request := telegram.SendMessageRequest{}
+		assert.Nil(t, json.Unmarshal(body, &request))
+		server.messages = append(server.messages, request)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"ok":true,"result":{"message_id":%d,"chat":{"id":%d}}}`, 100+len(server.messages), request.ChatID)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestTelegramService(server *fakeTelegramServer, integration *entities.Telegram) (*TelegramService, *stubTelegramMessageRepository) {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	messageRepository := &stubTelegramMessageRepository{messages: map[string]*entities.TelegramMessage{}}
+	service := NewTelegramService(
+		logger,
+		tracer,
+		telegram.New(telegram.WithBaseURL(server.URL), telegram.WithHTTPClient(server.Client())),
+		"https://api.httpsms.com",
+		&stubTelegramRepository{integrations: []*entities.Telegram{integration}},
+		messageRepository,
+	)
+	return service, messageRepository
+}
+
+func newTestTelegramIntegration() *entities.Telegram {
+	return &entities.Telegram{
+		ID:            uuid.New(),
+		UserID:        "user-id",
+		Owner:         "+18005550199",
+		BotToken:      "bot-token",
+		ChatID:        12345,
+		WebhookSecret: "webhook-secret",
+	}
+}
+
+func newTestTelegramReplyUpdate(updateID int64, chatID int64, replyToMessageID int64) *telegram.Update {
+	return &telegram.Update{
+		UpdateID: updateID,
+		Message: &telegram.Message{
+			MessageID:      updateID + 1000,
+			From:           &telegram.User{ID: 1},
+			Chat:           telegram.Chat{ID: chatID},
+			Text:           "Hello back",
+			ReplyToMessage: &telegram.Message{MessageID: replyToMessageID},
+		},
+	}
+}
+
+func TestTelegramService_HandleMessageReceived(t *testing.T) {
+	t.Run("the message is forwarded to the chat and mapped to the contact", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, messageRepository := newTestTelegramService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+
+		// Act
+		err := service.HandleMessageReceived(context.Background(), event, payload)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Len(t, server.messages, 1)
+		assert.Equal(t, int64(12345), server.messages[0].ChatID)
+		assert.Equal(t, "✉ SMS from +1 800-555-0100 to +1 800-555-0199\n\nHello World\n\nReply to this message to send an SMS to +1 800-555-0100", server.messages[0].Text)
+
+		mapping, err := messageRepository.Load(context.Background(), integration.ID, 101)
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550100", mapping.Contact)
+		assert.Equal(t, payload.MessageID, mapping.MessageID)
+	})
+
+	t.Run("a message which the owner sent to itself is not forwarded", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		service, _ := newTestTelegramService(server, newTestTelegramIntegration())
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550199", "Hello World")
+
+		// Act
+		err := service.HandleMessageReceived(context.Background(), event, payload)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Empty(t, server.messages)
+	})
+}
+
+func TestTelegramService_ResolveReply(t *testing.T) {
+	t.Run("a reply to a forwarded message is resolved to its contact", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, _ := newTestTelegramService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+		assert.Nil(t, service.HandleMessageReceived(context.Background(), event, payload))
+
+		// Act
+		reply, err := service.ResolveReply(context.Background(), integration.ID, "webhook-secret", newTestTelegramReplyUpdate(1, 12345, 101))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550100", reply.Contact)
+		assert.Equal(t, "Hello back", reply.Message.Text)
+		assert.Equal(t, integration.ID, reply.Telegram.ID)
+	})
+
+	t.Run("an update without the secret token is unauthorized", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, _ := newTestTelegramService(server, integration)
+
+		// Act
+		reply, err := service.ResolveReply(context.Background(), integration.ID, "wrong-secret", newTestTelegramReplyUpdate(1, 12345, 101))
+
+		// Assert
+		assert.Nil(t, reply)
+		assert.Equal(t, ErrCodeTelegramUnauthorized, stacktrace.GetCode(err))
+	})
+
+	t.Run("an update from another chat is ignored", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, _ := newTestTelegramService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+		assert.Nil(t, service.HandleMessageReceived(context.Background(), event, payload))
+
+		// Act
+		reply, err := service.ResolveReply(context.Background(), integration.ID, "webhook-secret", newTestTelegramReplyUpdate(1, 99999, 101))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Nil(t, reply)
+		assert.Len(t, server.messages, 1)
+	})
+
+	t.Run("an update from a bot is ignored", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, _ := newTestTelegramService(server, integration)
+		update := newTestTelegramReplyUpdate(1, 12345, 101)
+		update.Message.From.IsBot = true
+
+		// Act
+		reply, err := service.ResolveReply(context.Background(), integration.ID, "webhook-secret", update)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Nil(t, reply)
+	})
+
+	t.Run("an update which is delivered twice is resolved once", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, _ := newTestTelegramService(server, integration)
+		event, payload := newTestMessagePhoneReceivedEvent(t, "+18005550100", "Hello World")
+		assert.Nil(t, service.HandleMessageReceived(context.Background(), event, payload))
+
+		// Act
+		first, err1 := service.ResolveReply(context.Background(), integration.ID, "webhook-secret", newTestTelegramReplyUpdate(7, 12345, 101))
+		second, err2 := service.ResolveReply(context.Background(), integration.ID, "webhook-secret", newTestTelegramReplyUpdate(7, 12345, 101))
+
+		// Assert
+		assert.Nil(t, err1)
+		assert.Nil(t, err2)
+		assert.NotNil(t, first)
+		assert.Nil(t, second)
+	})
+
+	t.Run("a message which is not a reply to a forwarded message is answered with a hint", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		server := newFakeTelegramServer(t)
+
+		// Arrange
+		integration := newTestTelegramIntegration()
+		service, _ := newTestTelegramService(server, integration)
+
+		// Act
+		reply, err := service.ResolveReply(context.Background(), integration.ID, "webhook-secret", newTestTelegramReplyUpdate(1, 12345, 555))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Nil(t, reply)
+		assert.Len(t, server.messages, 1)
+		assert.Equal(t, "The message you replied to is not a forwarded SMS", server.messages[0].Text)
+		assert.Equal(t, int64(1001), server.messages[0].ReplyToMessageID)
+	})
+}
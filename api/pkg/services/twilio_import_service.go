@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// twilioImportBatchSize is the number of imported entities.Message which are stored in one query
+	twilioImportBatchSize = 500
+
+	// twilioImportMaxErrors limits the number of row errors in the entities.TwilioImportResult, the other rows which
+	// could not be imported are only counted
+	twilioImportMaxErrors = 1000
+)
+
+// TwilioImportService imports the history of messages which were sent and received with Twilio
+type TwilioImportService struct {
+	service
+	logger        telemetry.Logger
+	tracer        telemetry.Tracer
+	repository    repositories.MessageRepository
+	threadService *MessageThreadService
+}
+
+// NewTwilioImportService creates a new TwilioImportService
+func NewTwilioImportService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	repository repositories.MessageRepository,
+	threadService *MessageThreadService,
+) (s *TwilioImportService) {
+	return &TwilioImportService{
+		logger:        logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:        tracer,
+		repository:    repository,
+		threadService: threadService,
+	}
+}
+
+// TwilioImportParams are the parameters for importing a Twilio export
+type TwilioImportParams struct {
+	UserID entities.UserID
+	// Owner is the phone number which owns all the imported messages, the Twilio phone number of each message is the
+	// owner when it is empty
+	Owner  string
+	Format twilio.ExportFormat
+	Export io.Reader
+}
+
+// Import stores the messages of a Twilio export as imported messages, no events are dispatched for the imported
+// messages so they are not sent to the phone, the webhooks or the integrations. The export is read one row at a time
+// and a message which was imported before has the same ID so it is skipped.
+func (service *TwilioImportService) Import(ctx context.Context, params TwilioImportParams) (*entities.TwilioImportResult, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	reader, err := twilio.NewExportReader(params.Format, params.Export)
+	if err != nil {
+		msg := fmt.Sprintf("cannot read the %s Twilio export of user [%s]", params.Format, params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("export", err.Error()), msg))
+	}
+
+	result := &entities.TwilioImportResult{Errors: []entities.TwilioImportRowError{}}
+	timestamp := time.Now().UTC()
+	batch := make([]*entities.Message, 0, twilioImportBatchSize)
+
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err == nil {
+			var message *entities.Message
+			if message, err = twilio.NewImportedMessage(params.UserID, params.Owner, record, timestamp); err == nil {
+				batch = append(batch, message)
+			}
+		}
+
+		if rowErr, ok := twilio.AsRowError(err); ok {
+			service.addRowError(result, rowErr)
+			continue
+		}
+
+		if err != nil {
+			msg := fmt.Sprintf("cannot read the %s Twilio export of user [%s] after [%d] imported messages", params.Format, params.UserID, result.Imported)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(NewValidationError("export", err.Error()), msg))
+		}
+
+		if len(batch) == twilioImportBatchSize {
+			if err = service.storeBatch(ctx, batch, result); err != nil {
+				return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot import messages of user [%s]", params.UserID)))
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if err = service.storeBatch(ctx, batch, result); err != nil {
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot import messages of user [%s]", params.UserID)))
+	}
+
+	ctxLogger.WithField(telemetry.LogFieldCount, result.Imported).Info(fmt.Sprintf(
+		"imported [%d] messages from the %s Twilio export of user [%s] with [%d] skipped and [%d] errored",
+		result.Imported,
+		params.Format,
+		params.UserID,
+		result.Skipped,
+		result.Errored,
+	))
+	return result, nil
+}
+
+func (service *TwilioImportService) addRowError(result *entities.TwilioImportResult, rowErr *twilio.RowError) {
+	result.Errored++
+	if len(result.Errors) < twilioImportMaxErrors {
+		result.Errors = append(result.Errors, entities.TwilioImportRowError{Row: rowErr.Row, SID: rowErr.SID, Reason: rowErr.Reason})
+	}
+}
+
+// storeBatch stores the imported messages and updates the thread of each owner and contact with the newest message
+func (service *TwilioImportService) storeBatch(ctx context.Context, messages []*entities.Message, result *entities.TwilioImportResult) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	outcomes, err := service.repository.StoreMany(ctx, messages)
+	if err != nil {
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot store [%d] imported messages", len(messages))))
+	}
+
+	newest := map[string]*entities.Message{}
+	keys := make([]string, 0, len(messages))
+	for index, message := range messages {
+		if outcomes[index] != repositories.StoreOutcomeInserted {
+			result.Skipped++
+			continue
+		}
+
+		result.Imported++
+		key := message.Owner + ":" + message.Contact
+		if current, ok := newest[key]; !ok || message.OrderTimestamp.After(current.OrderTimestamp) {
+			if !ok {
+				keys = append(keys, key)
+			}
+			newest[key] = message
+		}
+	}
+
+	for _, key := range keys {
+		message := newest[key]
+		err = service.threadService.ImportThread(ctx, MessageThreadUpdateParams{
+			Owner:     message.Owner,
+			Status:    message.Status,
+			Contact:   message.Contact,
+			Content:   message.Content,
+			UserID:    message.UserID,
+			MessageID: message.ID,
+			Timestamp: message.OrderTimestamp,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("cannot update the thread of owner [%s] and contact [%s] with imported message [%s]", message.Owner, telemetry.RedactPhoneNumber(message.Contact), message.ID)
+			ctxLogger.Error(stacktrace.Propagate(err, msg))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/repositories/memory"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+const twilioImportTestExport = `From,To,Body,Status,SentDate,ApiVersion,NumSegments,ErrorCode,AccountSid,Sid,Direction,Price,PriceUnit
++18005550199,+18005550100,"Hello, how are you?",delivered,2023-08-24T05:01:45-07:00,2010-04-01,1,0,AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1,SM1f0e8ae6ade43cb3c0ce4525424e404f,outbound-api,-0.00790,USD
++18005550100,+18005550199,I am fine thanks,received,2023-08-24T05:03:10-07:00,2010-04-01,1,0,AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1,SM2a7b9c1d3e5f4a6b8c0d2e4f6a8b0c1d,inbound,-0.00790,USD
++18005550199,+18005550101,Your code is 1234,undelivered,2023-08-24T05:05:00-07:00,2010-04-01,1,30003,AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1,SM3b8c0d2e4f6a8b0c1d3e5f7a9b1c3d5e,outbound-api,-0.00790,USD
++18005550199,+18005550102,Still waiting,queued,2023-08-24T05:06:00-07:00,2010-04-01,1,0,AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1,SM4c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7f,outbound-api,,USD
+`
+
+func newTestTwilioImportService(repository repositories.MessageRepository, threadRepository repositories.MessageThreadRepository) *TwilioImportService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewTwilioImportService(logger, tracer, repository, newTestMessageThreadService(threadRepository))
+}
+
+func TestTwilioImportService_Import(t *testing.T) {
+	userID := entities.UserID("WB7DRDWrJZRGbYrv2CKGkqbzvqdC")
+	params := func() TwilioImportParams {
+		return TwilioImportParams{UserID: userID, Format: twilio.ExportFormatCSV, Export: strings.NewReader(twilioImportTestExport)}
+	}
+
+	t.Run("the final messages are imported and the threads are created with the newest message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := memory.NewMessageRepository()
+		threadRepository := newStubMessageThreadRepository()
+		service := newTestTwilioImportService(repository, threadRepository)
+
+		// Act
+		result, err := service.Import(context.Background(), params())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 3, result.Imported)
+		assert.Equal(t, 0, result.Skipped)
+		assert.Equal(t, 1, result.Errored)
+		assert.Equal(t, 4, result.Errors[0].Row)
+		assert.Equal(t, "SM4c9d1e3f5a7b9c1d3e5f7a9b1c3d5e7f", result.Errors[0].SID)
+
+		message, err := repository.Load(context.Background(), userID, twilio.ImportedMessageID(userID, "SM2a7b9c1d3e5f4a6b8c0d2e4f6a8b0c1d"))
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusReceived), message.Status)
+		assert.NotNil(t, message.ImportedAt)
+
+		thread, err := threadRepository.LoadByOwnerContact(context.Background(), userID, "+18005550199", "+18005550100")
+		assert.Nil(t, err)
+		assert.Equal(t, message.ID, *thread.LastMessageID)
+		assert.Equal(t, 2, len(threadRepository.threads))
+	})
+
+	t.Run("the messages of an export which was imported before are skipped", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		repository := memory.NewMessageRepository()
+		service := newTestTwilioImportService(repository, newStubMessageThreadRepository())
+		_, err := service.Import(context.Background(), params())
+		assert.Nil(t, err)
+
+		// Act
+		result, err := service.Import(context.Background(), params())
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, result.Imported)
+		assert.Equal(t, 3, result.Skipped)
+		assert.Equal(t, 1, result.Errored)
+	})
+
+	t.Run("a thread with a newer message is not changed by the import", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		lastMessageID := uuid.New()
+		threadRepository := newStubMessageThreadRepository(entities.MessageThread{
+			ID:             uuid.New(),
+			UserID:         userID,
+			Owner:          "+18005550199",
+			Contact:        "+18005550100",
+			LastMessageID:  &lastMessageID,
+			OrderTimestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		service := newTestTwilioImportService(memory.NewMessageRepository(), threadRepository)
+
+		// Act
+		_, err := service.Import(context.Background(), params())
+
+		// Assert
+		assert.Nil(t, err)
+		thread, err := threadRepository.LoadByOwnerContact(context.Background(), userID, "+18005550199", "+18005550100")
+		assert.Nil(t, err)
+		assert.Equal(t, lastMessageID, *thread.LastMessageID)
+	})
+
+	t.Run("an export without a header is a validation error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		service := newTestTwilioImportService(memory.NewMessageRepository(), newStubMessageThreadRepository())
+
+		// Act
+		_, err := service.Import(context.Background(), TwilioImportParams{UserID: userID, Format: twilio.ExportFormatCSV, Export: strings.NewReader("")})
+
+		// Assert
+		_, ok := AsValidationError(err)
+		assert.True(t, ok)
+	})
+}
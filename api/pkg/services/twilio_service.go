@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/ids"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// TwilioService sends messages with the Twilio compatible API and sends the status callbacks of those messages
+type TwilioService struct {
+	service
+	logger         telemetry.Logger
+	tracer         telemetry.Tracer
+	client         *http.Client
+	ids            ids.Generator
+	messageService *MessageService
+	repository     repositories.TwilioStatusCallbackRepository
+}
+
+// NewTwilioService creates a new TwilioService
+func NewTwilioService(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	client *http.Client,
+	ids ids.Generator,
+	messageService *MessageService,
+	repository repositories.TwilioStatusCallbackRepository,
+) (s *TwilioService) {
+	return &TwilioService{
+		logger:         logger.WithService(fmt.Sprintf("%T", s)),
+		tracer:         tracer,
+		client:         client,
+		ids:            ids,
+		messageService: messageService,
+		repository:     repository,
+	}
+}
+
+// TwilioSendParams are the parameters for sending a message with the Twilio compatible API
+type TwilioSendParams struct {
+	MessageSendParams
+	// AccountSID is the Twilio account SID in the path of the request, it is sent back in the status callbacks
+	AccountSID string
+	// StatusCallback is the URL which receives the status callbacks of the message, it is optional
+	StatusCallback string
+}
+
+// Send a message with the MessageService, the status callback is stored before the message is sent so that the
+// listeners of the message can find it
+func (service *TwilioService) Send(ctx context.Context, params TwilioSendParams) (*entities.Message, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	if params.StatusCallback != "" {
+		params.MessageID = service.ids.New()
+		callback := &entities.TwilioStatusCallback{
+			MessageID:  params.MessageID,
+			UserID:     params.UserID,
+			AccountSID: params.AccountSID,
+			URL:        params.StatusCallback,
+			CreatedAt:  time.Now().UTC(),
+		}
+		if err := service.repository.Store(ctx, callback); err != nil {
+			msg := fmt.Sprintf("cannot store twilio status callback for message [%s] and user [%s]", params.MessageID, params.UserID)
+			return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+		}
+	}
+
+	message, err := service.messageService.SendMessage(ctx, params.MessageSendParams)
+	if err != nil {
+		if params.StatusCallback != "" {
+			service.deleteStatusCallback(ctx, ctxLogger, params.UserID, params.MessageID)
+		}
+		msg := fmt.Sprintf("cannot send twilio message for user [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return message, nil
+}
+
+// TwilioStatusCallbackParams are the parameters of the status callback of a message
+type TwilioStatusCallbackParams struct {
+	UserID    entities.UserID
+	MessageID uuid.UUID
+	Owner     string
+	Contact   string
+	Status    twilio.Status
+}
+
+// SendStatusCallback posts the twilio.StatusCallback of a message to its StatusCallback URL, the messages which were
+// not sent with a StatusCallback are ignored. The status callback is deleted after the final status of the message.
+func (service *TwilioService) SendStatusCallback(ctx context.Context, params TwilioStatusCallbackParams) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	callback, err := service.repository.Load(ctx, params.UserID, params.MessageID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		ctxLogger.Info(fmt.Sprintf("message [%s] of user [%s] has no twilio status callback", params.MessageID, params.UserID))
+		return nil
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("cannot load twilio status callback for message [%s] and user [%s]", params.MessageID, params.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	values := twilio.StatusCallback{
+		AccountSID: callback.AccountSID,
+		MessageID:  params.MessageID,
+		From:       params.Owner,
+		To:         params.Contact,
+		Status:     params.Status,
+	}.Values()
+
+	requestCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodPost, callback.URL, strings.NewReader(values.Encode()))
+	if err != nil {
+		msg := fmt.Sprintf("cannot create twilio status callback request to [%s] for message [%s]", callback.URL, params.MessageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := service.client.Do(request)
+	if err != nil {
+		msg := fmt.Sprintf("cannot send [%s] twilio status callback to [%s] for message [%s]", params.Status, callback.URL, params.MessageID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = response.Body.Close(); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, "cannot close response body"))
+	}
+
+	if response.StatusCode >= 400 {
+		msg := fmt.Sprintf("twilio status callback [%s] for message [%s] failed with response code [%d]", callback.URL, params.MessageID, response.StatusCode)
+		return service.tracer.WrapErrorSpan(span, stacktrace.NewError(msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("sent [%s] twilio status callback to [%s] for message [%s] with response code [%d]", params.Status, callback.URL, params.MessageID, response.StatusCode))
+
+	if params.Status == twilio.StatusDelivered || params.Status == twilio.StatusFailed {
+		service.deleteStatusCallback(ctx, ctxLogger, params.UserID, params.MessageID)
+	}
+
+	return nil
+}
+
+func (service *TwilioService) deleteStatusCallback(ctx context.Context, ctxLogger telemetry.Logger, userID entities.UserID, messageID uuid.UUID) {
+	if err := service.repository.Delete(ctx, userID, messageID); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot delete twilio status callback for message [%s] and user [%s]", messageID, userID)))
+	}
+}
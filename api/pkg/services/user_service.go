@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/NdoleStudio/httpsms/pkg/cache"
 	"github.com/NdoleStudio/httpsms/pkg/events"
 
 	"github.com/NdoleStudio/httpsms/pkg/emails"
@@ -26,8 +27,11 @@ type UserService struct {
 	emailFactory       emails.UserEmailFactory
 	mailer             emails.Mailer
 	repository         repositories.UserRepository
+	apiKeyRepository   repositories.APIKeyRepository
 	marketingService   *MarketingService
 	lemonsqueezyClient *lemonsqueezy.Client
+	cache              cache.Cache
+	dispatcher         *EventDispatcher
 }
 
 // NewUserService creates a new UserService
@@ -35,10 +39,13 @@ func NewUserService(
 	logger telemetry.Logger,
 	tracer telemetry.Tracer,
 	repository repositories.UserRepository,
+	apiKeyRepository repositories.APIKeyRepository,
 	mailer emails.Mailer,
 	emailFactory emails.UserEmailFactory,
 	marketingService *MarketingService,
 	lemonsqueezyClient *lemonsqueezy.Client,
+	cache cache.Cache,
+	dispatcher *EventDispatcher,
 ) (s *UserService) {
 	return &UserService{
 		logger:             logger.WithService(fmt.Sprintf("%T", s)),
@@ -47,7 +54,10 @@ func NewUserService(
 		marketingService:   marketingService,
 		emailFactory:       emailFactory,
 		repository:         repository,
+		apiKeyRepository:   apiKeyRepository,
 		lemonsqueezyClient: lemonsqueezyClient,
+		cache:              cache,
+		dispatcher:         dispatcher,
 	}
 }
 
@@ -83,10 +93,87 @@ func (service *UserService) GetByID(ctx context.Context, userID entities.UserID)
 	return user, nil
 }
 
+// Index fetches the entities.UserSummary of users with an email which matches the params
+func (service *UserService) Index(ctx context.Context, params repositories.IndexParams) ([]*entities.UserSummary, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	users, err := service.repository.Index(ctx, params)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch users with params [%+#v]", params)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	summaries, err := service.repository.Summaries(ctx, users)
+	if err != nil {
+		msg := fmt.Sprintf("could not fetch summaries of [%d] users", len(users))
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("fetched [%d] users with params [%+#v]", len(summaries), params))
+	return summaries, nil
+}
+
+// GetSummary fetches the entities.UserSummary of an entities.User
+func (service *UserService) GetSummary(ctx context.Context, userID entities.UserID) (*entities.UserSummary, error) {
+	ctx, span := service.tracer.Start(ctx)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("could not get [%T] with ID [%s]", user, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	return service.summary(ctx, user)
+}
+
+// Suspend an entities.User so that the API keys of the user are rejected, the user is reinstated when suspended is false
+func (service *UserService) Suspend(ctx context.Context, userID entities.UserID, suspended bool) (*entities.UserSummary, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("could not get [%T] with ID [%s]", user, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, stacktrace.GetCode(err), msg))
+	}
+
+	var suspendedAt *time.Time
+	if suspended {
+		timestamp := time.Now().UTC()
+		suspendedAt = &timestamp
+	}
+
+	if err = service.repository.Suspend(ctx, user, suspendedAt); err != nil {
+		msg := fmt.Sprintf("cannot set suspended [%t] for user with ID [%s]", suspended, user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.apiKeyRepository.EvictUser(ctx, user.ID); err != nil {
+		msg := fmt.Sprintf("cannot evict the api keys of user with ID [%s] from the cache", user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("set suspended [%t] for [%T] with ID [%s]", suspended, user, user.ID))
+	return service.summary(ctx, user)
+}
+
+func (service *UserService) summary(ctx context.Context, user *entities.User) (*entities.UserSummary, error) {
+	summaries, err := service.repository.Summaries(ctx, []*entities.User{user})
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("could not fetch summary of user with ID [%s]", user.ID))
+	}
+	return summaries[0], nil
+}
+
 // UserUpdateParams are parameters for updating an entities.User
 type UserUpdateParams struct {
-	Timezone      *time.Location
-	ActivePhoneID uuid.UUID
+	Timezone          *time.Location
+	ActivePhoneID     uuid.UUID
+	DailyMessageLimit *uint
+	// RequestDeliveryReports is unchanged when it is nil
+	RequestDeliveryReports *bool
 }
 
 // Update an entities.User
@@ -108,6 +195,12 @@ func (service *UserService) Update(ctx context.Context, authUser entities.AuthUs
 
 	user.Timezone = params.Timezone.String()
 	user.ActivePhoneID = &params.ActivePhoneID
+	if params.DailyMessageLimit != nil {
+		user.DailyMessageLimit = *params.DailyMessageLimit
+	}
+	if params.RequestDeliveryReports != nil {
+		user.RequestDeliveryReports = *params.RequestDeliveryReports
+	}
 
 	if err = service.repository.Update(ctx, user); err != nil {
 		msg := fmt.Sprintf("cannot save user with id [%s]", user.ID)
@@ -118,11 +211,54 @@ func (service *UserService) Update(ctx context.Context, authUser entities.AuthUs
 	return user, nil
 }
 
+// UserRotateAPIKeyParams are parameters for rotating the API key of an entities.User
+type UserRotateAPIKeyParams struct {
+	UserID entities.UserID
+	// GracePeriod is the duration in which the previous API key is still valid, the previous key is revoked immediately when it is 0
+	GracePeriod time.Duration
+	IPAddress   string
+	UserAgent   string
+	Source      string
+}
+
+// RotateAPIKey generates a new API key for an entities.User and revokes the previous API key after the grace period
+func (service *UserService) RotateAPIKey(ctx context.Context, params *UserRotateAPIKeyParams) (*entities.User, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.RotateAPIKey(ctx, params.UserID, params.GracePeriod)
+	if err != nil {
+		msg := fmt.Sprintf("cannot rotate API key for user with ID [%s]", params.UserID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	event, err := service.createEvent(ctx, events.UserAPIKeyRotated, params.Source, &events.UserAPIKeyRotatedPayload{
+		UserID:                  user.ID,
+		PreviousAPIKeyExpiresAt: user.PreviousAPIKeyExpiresAt,
+		IPAddress:               params.IPAddress,
+		UserAgent:               params.UserAgent,
+		Timestamp:               time.Now().UTC(),
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create [%s] event for user with ID [%s]", events.UserAPIKeyRotated, user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.dispatcher.Dispatch(ctx, event); err != nil {
+		msg := fmt.Sprintf("cannot dispatch [%s] event with ID [%s] for user with ID [%s]", event.Type(), event.ID(), user.ID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("rotated API key for [%T] with ID [%s] with grace period [%s]", user, user.ID, params.GracePeriod))
+	return user, nil
+}
+
 // UserNotificationUpdateParams are parameters for updating the notifications of a user
 type UserNotificationUpdateParams struct {
 	MessageStatusEnabled bool
 	WebhookEnabled       bool
 	HeartbeatEnabled     bool
+	HeartbeatQuietHours  uint
 }
 
 // UpdateNotificationSettings for an entities.User
@@ -138,6 +274,7 @@ func (service *UserService) UpdateNotificationSettings(ctx context.Context, user
 
 	user.NotificationWebhookEnabled = params.WebhookEnabled
 	user.NotificationHeartbeatEnabled = params.HeartbeatEnabled
+	user.NotificationHeartbeatQuietHours = params.HeartbeatQuietHours
 	user.NotificationMessageStatusEnabled = params.MessageStatusEnabled
 
 	if err = service.repository.Update(ctx, user); err != nil {
@@ -149,12 +286,104 @@ func (service *UserService) UpdateNotificationSettings(ctx context.Context, user
 	return user, nil
 }
 
+// UserFailoverUpdateParams are parameters for updating the failover settings of a user
+type UserFailoverUpdateParams struct {
+	Enabled     bool
+	PhoneNumber *string
+}
+
+// UpdateFailoverSettings for an entities.User
+func (service *UserService) UpdateFailoverSettings(ctx context.Context, userID entities.UserID, params *UserFailoverUpdateParams) (*entities.User, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("could not load [%T] with ID [%s]", user, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	user.FailoverEnabled = params.Enabled
+	user.FailoverPhoneNumber = params.PhoneNumber
+
+	if err = service.repository.Update(ctx, user); err != nil {
+		msg := fmt.Sprintf("cannot save user with id [%s] in [%T]", user.ID, service.repository)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("updated failover settings for [%T] with ID [%s] in the [%T]", user, user.ID, service.repository))
+	return user, nil
+}
+
+// UserRetentionUpdateParams are parameters for updating the data retention settings of a user
+type UserRetentionUpdateParams struct {
+	ReceivedDays uint
+	SentDays     uint
+}
+
+// UpdateRetentionSettings for an entities.User
+func (service *UserService) UpdateRetentionSettings(ctx context.Context, userID entities.UserID, params *UserRetentionUpdateParams) (*entities.User, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("could not load [%T] with ID [%s]", user, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	user.RetentionReceivedDays = params.ReceivedDays
+	user.RetentionSentDays = params.SentDays
+
+	if err = service.repository.Update(ctx, user); err != nil {
+		msg := fmt.Sprintf("cannot save user with id [%s] in [%T]", user.ID, service.repository)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("updated retention settings for [%T] with ID [%s] to [%d] received days and [%d] sent days", user, user.ID, params.ReceivedDays, params.SentDays))
+	return user, nil
+}
+
+// UserRetryPolicyUpdateParams are parameters for updating the retry policy of a user
+type UserRetryPolicyUpdateParams struct {
+	RetryPolicy entities.RetryPolicy
+}
+
+// UpdateRetryPolicy for an entities.User, the messages which were created before the update keep the previous policy
+func (service *UserService) UpdateRetryPolicy(ctx context.Context, userID entities.UserID, params *UserRetryPolicyUpdateParams) (*entities.User, error) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, userID)
+	if err != nil {
+		msg := fmt.Sprintf("could not load [%T] with ID [%s]", user, userID)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	user.RetryPolicy = params.RetryPolicy
+
+	if err = service.repository.Update(ctx, user); err != nil {
+		msg := fmt.Sprintf("cannot save user with id [%s] in [%T]", user.ID, service.repository)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("updated retry policy for [%T] with ID [%s] to [%+v]", user, user.ID, params.RetryPolicy))
+	return user, nil
+}
+
 // UserSendPhoneDeadEmailParams are parameters for notifying a user when a phone is dead
 type UserSendPhoneDeadEmailParams struct {
 	UserID                 entities.UserID
 	PhoneID                uuid.UUID
 	Owner                  string
 	LastHeartbeatTimestamp time.Time
+	Threshold              time.Duration
+}
+
+// Redacted returns a copy of the params which is safe to log
+func (params UserSendPhoneDeadEmailParams) Redacted() UserSendPhoneDeadEmailParams {
+	params.Owner = telemetry.RedactPhoneNumber(params.Owner)
+	return params
 }
 
 // SendPhoneDeadEmail sends an email to an entities.User when a phone is dead
@@ -175,7 +404,12 @@ func (service *UserService) SendPhoneDeadEmail(ctx context.Context, params *User
 		return nil
 	}
 
-	email, err := service.emailFactory.PhoneDead(user, params.LastHeartbeatTimestamp, params.Owner)
+	if _, err = service.cache.Get(ctx, service.phoneQuietCacheKey(user.ID, params.Owner)); err == nil {
+		ctxLogger.Info(fmt.Sprintf("[%s] email for user [%s] with owner [%s] is within the quiet period of [%d] hours", events.EventTypePhoneHeartbeatDead, params.UserID, params.Owner, user.NotificationHeartbeatQuietHours))
+		return nil
+	}
+
+	email, err := service.emailFactory.PhoneDead(user, params.LastHeartbeatTimestamp, params.Owner, params.Threshold)
 	if err != nil {
 		msg := fmt.Sprintf("cannot create phone dead email for user [%s]", params.UserID)
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
@@ -186,10 +420,150 @@ func (service *UserService) SendPhoneDeadEmail(ctx context.Context, params *User
 		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
 	}
 
+	service.setPhoneNotificationStatus(ctx, user.ID, params.Owner, events.EventTypePhoneHeartbeatDead)
+	if user.HeartbeatQuietPeriod() > 0 {
+		if err = service.cache.Set(ctx, service.phoneQuietCacheKey(user.ID, params.Owner), "", user.HeartbeatQuietPeriod()); err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot set quiet period for user [%s] with owner [%s]", user.ID, params.Owner)))
+		}
+	}
+
 	ctxLogger.Info(fmt.Sprintf("phone dead notification sent successfully to [%s] about [%s]", user.Email, params.Owner))
 	return nil
 }
 
+// UserSendPhoneAliveEmailParams are parameters for notifying a user when a phone is alive
+type UserSendPhoneAliveEmailParams struct {
+	UserID                 entities.UserID
+	PhoneID                uuid.UUID
+	Owner                  string
+	LastHeartbeatTimestamp time.Time
+}
+
+// Redacted returns a copy of the params which is safe to log
+func (params UserSendPhoneAliveEmailParams) Redacted() UserSendPhoneAliveEmailParams {
+	params.Owner = telemetry.RedactPhoneNumber(params.Owner)
+	return params
+}
+
+// SendPhoneAliveEmail sends an email to an entities.User when a phone which was dead is alive again
+func (service *UserService) SendPhoneAliveEmail(ctx context.Context, params *UserSendPhoneAliveEmailParams) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	status, err := service.cache.Get(ctx, service.phoneNotificationCacheKey(params.UserID, params.Owner))
+	if err != nil || status != events.EventTypePhoneHeartbeatDead {
+		ctxLogger.Info(fmt.Sprintf("[%s] email was not sent to user [%s] with owner [%s] so no [%s] email is needed", events.EventTypePhoneHeartbeatDead, params.UserID, params.Owner, events.EventTypePhoneHeartbeatAlive))
+		return nil
+	}
+
+	user, err := service.repository.Load(ctx, params.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("could not get [%T] with ID [%s]", user, params.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !user.NotificationHeartbeatEnabled {
+		ctxLogger.Info(fmt.Sprintf("[%s] email notifications disabled for user [%s] with owner [%s]", events.EventTypePhoneHeartbeatAlive, params.UserID, params.Owner))
+		return nil
+	}
+
+	email, err := service.emailFactory.PhoneAlive(user, params.LastHeartbeatTimestamp, params.Owner)
+	if err != nil {
+		msg := fmt.Sprintf("cannot create phone alive email for user [%s]", params.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.mailer.Send(ctx, email); err != nil {
+		msg := fmt.Sprintf("canot send phone alive notification to user [%s]", params.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	service.setPhoneNotificationStatus(ctx, user.ID, params.Owner, events.EventTypePhoneHeartbeatAlive)
+
+	ctxLogger.Info(fmt.Sprintf("phone alive notification sent successfully to [%s] about [%s]", user.Email, params.Owner))
+	return nil
+}
+
+// SendPhoneBatteryLowEmail sends an email to an entities.User when the battery of a phone is low
+func (service *UserService) SendPhoneBatteryLowEmail(ctx context.Context, payload *events.PhoneBatteryLowPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, payload.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("could not get [%T] with ID [%s]", user, payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !user.NotificationHeartbeatEnabled {
+		ctxLogger.Info(fmt.Sprintf("[%s] email notifications disabled for user [%s] with owner [%s]", events.EventTypePhoneBatteryLow, payload.UserID, payload.Owner))
+		return nil
+	}
+
+	email, err := service.emailFactory.PhoneBatteryLow(user, payload.Owner, payload.BatteryLevel)
+	if err != nil {
+		msg := fmt.Sprintf("cannot create phone battery low email for user [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.mailer.Send(ctx, email); err != nil {
+		msg := fmt.Sprintf("canot send phone battery low notification to user [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone battery low notification sent successfully to [%s] about [%s]", user.Email, payload.Owner))
+	return nil
+}
+
+// SendPhoneClockSkewedEmail sends an email to an entities.User when the clock of a phone is skewed
+func (service *UserService) SendPhoneClockSkewedEmail(ctx context.Context, payload *events.PhoneClockSkewedPayload) error {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	user, err := service.repository.Load(ctx, payload.UserID)
+	if err != nil {
+		msg := fmt.Sprintf("could not get [%T] with ID [%s]", user, payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if !user.NotificationHeartbeatEnabled {
+		ctxLogger.Info(fmt.Sprintf("[%s] email notifications disabled for user [%s] with owner [%s]", events.EventTypePhoneClockSkewed, payload.UserID, payload.Owner))
+		return nil
+	}
+
+	email, err := service.emailFactory.PhoneClockSkewed(user, payload.Owner, time.Duration(payload.ClockSkewMilliseconds)*time.Millisecond)
+	if err != nil {
+		msg := fmt.Sprintf("cannot create phone clock skewed email for user [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	if err = service.mailer.Send(ctx, email); err != nil {
+		msg := fmt.Sprintf("canot send phone clock skewed notification to user [%s]", payload.UserID)
+		return service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	ctxLogger.Info(fmt.Sprintf("phone clock skewed notification sent successfully to [%s] about [%s]", user.Email, payload.Owner))
+	return nil
+}
+
+func (service *UserService) setPhoneNotificationStatus(ctx context.Context, userID entities.UserID, owner string, status string) {
+	ctx, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	cacheKey := service.phoneNotificationCacheKey(userID, owner)
+	if err := service.cache.Set(ctx, cacheKey, status, 30*24*time.Hour); err != nil {
+		ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot set item in cache with key [%s] for owner [%s]", cacheKey, owner)))
+	}
+}
+
+func (service *UserService) phoneNotificationCacheKey(userID entities.UserID, owner string) string {
+	return fmt.Sprintf("email.phone.heartbeat.%s.%s", userID, owner)
+}
+
+func (service *UserService) phoneQuietCacheKey(userID entities.UserID, owner string) string {
+	return fmt.Sprintf("email.phone.heartbeat.quiet.%s.%s", userID, owner)
+}
+
 // StartSubscription starts a subscription for an entities.User
 func (service *UserService) StartSubscription(ctx context.Context, params *events.UserSubscriptionCreatedPayload) error {
 	ctx, span := service.tracer.Start(ctx)
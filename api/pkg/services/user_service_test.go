@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/emails"
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubUserRepository loads a single entities.User
+type stubUserRepository struct {
+	repositories.UserRepository
+	user *entities.User
+}
+
+func (repository *stubUserRepository) Load(_ context.Context, userID entities.UserID) (*entities.User, error) {
+	if repository.user == nil || repository.user.ID != userID {
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "user does not exist")
+	}
+	clone := *repository.user
+	return &clone, nil
+}
+
+// clockCache expires its items with a clock which is moved by the test instead of the wall clock
+type clockCache struct {
+	mutex   sync.Mutex
+	now     time.Time
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newClockCache(now time.Time) *clockCache {
+	return &clockCache{now: now, values: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (cache *clockCache) Set(_ context.Context, key string, value string, ttl time.Duration) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.values[key] = value
+	cache.expires[key] = cache.now.Add(ttl)
+	return nil
+}
+
+func (cache *clockCache) Get(_ context.Context, key string) (string, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	value, ok := cache.values[key]
+	if !ok || !cache.now.Before(cache.expires[key]) {
+		return "", stacktrace.NewError("no item found in cache with key [" + key + "]")
+	}
+	return value, nil
+}
+
+func (cache *clockCache) advance(duration time.Duration) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.now = cache.now.Add(duration)
+}
+
+// stubUserEmailFactory creates an emails.Email with the subject of the notification
+type stubUserEmailFactory struct {
+	emails.UserEmailFactory
+}
+
+func (factory *stubUserEmailFactory) PhoneDead(user *entities.User, _ time.Time, _ string, _ time.Duration) (*emails.Email, error) {
+	return &emails.Email{ToEmail: user.Email, Subject: "dead"}, nil
+}
+
+func (factory *stubUserEmailFactory) PhoneAlive(user *entities.User, _ time.Time, _ string) (*emails.Email, error) {
+	return &emails.Email{ToEmail: user.Email, Subject: "alive"}, nil
+}
+
+// capturingMailer stores the subjects of the emails which are sent
+type capturingMailer struct {
+	mutex    sync.Mutex
+	subjects []string
+}
+
+func (mailer *capturingMailer) Send(_ context.Context, mail *emails.Email) error {
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+	mailer.subjects = append(mailer.subjects, mail.Subject)
+	return nil
+}
+
+func newTestUserService(user *entities.User, cache *clockCache, mailer *capturingMailer) *UserService {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewUserService(logger, tracer, &stubUserRepository{user: user}, nil, mailer, &stubUserEmailFactory{}, nil, nil, cache, nil)
+}
+
+func TestUserService_SendPhoneDeadEmail(t *testing.T) {
+	newUser := func(quietHours uint) *entities.User {
+		return &entities.User{
+			ID:                              entities.UserID(uuid.NewString()),
+			Email:                           "name@email.com",
+			NotificationHeartbeatEnabled:    true,
+			NotificationHeartbeatQuietHours: quietHours,
+		}
+	}
+
+	dead := func(user *entities.User) *UserSendPhoneDeadEmailParams {
+		return &UserSendPhoneDeadEmailParams{UserID: user.ID, PhoneID: uuid.New(), Owner: "+18005550199", LastHeartbeatTimestamp: time.Now().UTC(), Threshold: time.Hour}
+	}
+
+	alive := func(user *entities.User) *UserSendPhoneAliveEmailParams {
+		return &UserSendPhoneAliveEmailParams{UserID: user.ID, PhoneID: uuid.New(), Owner: "+18005550199", LastHeartbeatTimestamp: time.Now().UTC()}
+	}
+
+	t.Run("a phone which goes offline again within the quiet period is notified once", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		user := newUser(2)
+		cache := newClockCache(time.Now().UTC())
+		mailer := &capturingMailer{}
+		service := newTestUserService(user, cache, mailer)
+
+		// Act
+		firstErr := service.SendPhoneDeadEmail(context.Background(), dead(user))
+		cache.advance(time.Hour)
+		secondErr := service.SendPhoneDeadEmail(context.Background(), dead(user))
+
+		// Assert
+		assert.Nil(t, firstErr)
+		assert.Nil(t, secondErr)
+		assert.Equal(t, []string{"dead"}, mailer.subjects)
+	})
+
+	t.Run("a phone which goes offline at the end of the quiet period is notified again", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		user := newUser(2)
+		cache := newClockCache(time.Now().UTC())
+		mailer := &capturingMailer{}
+		service := newTestUserService(user, cache, mailer)
+
+		// Act
+		assert.Nil(t, service.SendPhoneDeadEmail(context.Background(), dead(user)))
+
+		cache.advance(2*time.Hour - time.Nanosecond)
+		assert.Nil(t, service.SendPhoneDeadEmail(context.Background(), dead(user)))
+		subjectsInQuietPeriod := len(mailer.subjects)
+
+		cache.advance(time.Nanosecond)
+		err := service.SendPhoneDeadEmail(context.Background(), dead(user))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 1, subjectsInQuietPeriod)
+		assert.Equal(t, []string{"dead", "dead"}, mailer.subjects)
+	})
+
+	t.Run("the recovery of a phone is notified once after it was notified as offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		user := newUser(0)
+		cache := newClockCache(time.Now().UTC())
+		mailer := &capturingMailer{}
+		service := newTestUserService(user, cache, mailer)
+
+		// Act
+		assert.Nil(t, service.SendPhoneAliveEmail(context.Background(), alive(user)))
+		assert.Nil(t, service.SendPhoneDeadEmail(context.Background(), dead(user)))
+		assert.Nil(t, service.SendPhoneAliveEmail(context.Background(), alive(user)))
+		err := service.SendPhoneAliveEmail(context.Background(), alive(user))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"dead", "alive"}, mailer.subjects)
+	})
+
+	t.Run("a phone without a quiet period is notified every time it goes offline", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		user := newUser(0)
+		cache := newClockCache(time.Now().UTC())
+		mailer := &capturingMailer{}
+		service := newTestUserService(user, cache, mailer)
+
+		// Act
+		assert.Nil(t, service.SendPhoneDeadEmail(context.Background(), dead(user)))
+		assert.Nil(t, service.SendPhoneAliveEmail(context.Background(), alive(user)))
+		err := service.SendPhoneDeadEmail(context.Background(), dead(user))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"dead", "alive", "dead"}, mailer.subjects)
+	})
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+)
+
+// ValidationError is returned when the input of a request is invalid, the errors are keyed by the name of the field in the request
+type ValidationError struct {
+	Errors url.Values
+}
+
+// NewValidationError creates a ValidationError with a single error for the field
+func NewValidationError(field string, message string) *ValidationError {
+	return &ValidationError{
+		Errors: url.Values{field: []string{message}},
+	}
+}
+
+// Add adds an error for the field
+func (err *ValidationError) Add(field string, message string) *ValidationError {
+	if err.Errors == nil {
+		err.Errors = url.Values{}
+	}
+	err.Errors.Add(field, message)
+	return err
+}
+
+// Error returns the error message of the ValidationError
+func (err *ValidationError) Error() string {
+	fields := make([]string, 0, len(err.Errors))
+	for field := range err.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	messages := make([]string, 0, len(fields))
+	for _, field := range fields {
+		messages = append(messages, fmt.Sprintf("%s: %s", field, strings.Join(err.Errors[field], ", ")))
+	}
+
+	return fmt.Sprintf("validation errors [%s]", strings.Join(messages, "; "))
+}
+
+// AsValidationError returns the ValidationError which caused the error, an OwnerNotRegisteredError is a validation error of the "from" field
+func AsValidationError(err error) (*ValidationError, bool) {
+	switch cause := stacktrace.RootCause(err).(type) {
+	case *ValidationError:
+		return cause, true
+	case *OwnerNotRegisteredError:
+		return NewValidationError("from", cause.Error()), true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationError(t *testing.T) {
+	t.Run("errors of multiple fields are accumulated", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		err := NewValidationError("to", "The to field is required")
+
+		// Act
+		err.Add("content", "The content field is required").Add("to", "The to field must be a valid E.164 phone number")
+
+		// Assert
+		assert.Equal(t, []string{"The to field is required", "The to field must be a valid E.164 phone number"}, err.Errors["to"])
+		assert.Equal(t, []string{"The content field is required"}, err.Errors["content"])
+		assert.Equal(t, "validation errors [content: The content field is required; to: The to field is required, The to field must be a valid E.164 phone number]", err.Error())
+	})
+}
+
+func TestAsValidationError(t *testing.T) {
+	t.Run("the validation error is found in a propagated error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		err := stacktrace.Propagate(NewValidationError("token", "The invitation is invalid"), "cannot join team")
+
+		// Act
+		validationErr, ok := AsValidationError(err)
+
+		// Assert
+		assert.True(t, ok)
+		assert.Equal(t, []string{"The invitation is invalid"}, validationErr.Errors["token"])
+	})
+
+	t.Run("an owner which is not registered is a validation error of the from field", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		err := stacktrace.Propagate(&OwnerNotRegisteredError{Owner: "+18005550199"}, "cannot send message")
+
+		// Act
+		validationErr, ok := AsValidationError(err)
+
+		// Assert
+		assert.True(t, ok)
+		assert.Equal(t, []string{"the phone number [+18005550199] is not registered to your account"}, validationErr.Errors["from"])
+	})
+
+	t.Run("other errors are not validation errors", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		_, ok := AsValidationError(stacktrace.NewError("connection reset"))
+		_, okNil := AsValidationError(nil)
+
+		// Assert
+		assert.False(t, ok)
+		assert.False(t, okNil)
+	})
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// FlatWebhookPayload is the payload which is sent to a webhook with the entities.WebhookPayloadFormatFlat format. All
+// the fields are sent for every event so that no-code tools like Zapier, Make and n8n can map them, the fields which
+// don't apply to an event are empty.
+type FlatWebhookPayload struct {
+	EventID         string  `json:"event_id" example:"0b5b0d0a-5f2b-4c6e-9d3a-1c2f3e4d5a6b"`
+	EventType       string  `json:"event_type" example:"message.phone.received"`
+	UserID          string  `json:"user_id" example:"WB7DRDWrJZRGbYrv2CKGkqbzvqdC"`
+	PhoneNumber     string  `json:"phone_number" example:"+18005550199"`
+	PhoneID         string  `json:"phone_id" example:""`
+	MessageID       string  `json:"message_id" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	From            string  `json:"from" example:"+18005550100"`
+	To              string  `json:"to" example:"+18005550199"`
+	Content         string  `json:"content" example:"This is a sample text message"`
+	Status          string  `json:"status" example:"received"`
+	PreviousStatus  string  `json:"previous_status" example:""`
+	NewStatus       string  `json:"new_status" example:""`
+	TransitionedAt  string  `json:"transitioned_at" example:""`
+	AttemptCount    *uint   `json:"attempt_count" example:"1"`
+	SIM             string  `json:"sim" example:"SIM1"`
+	RequestID       string  `json:"request_id" example:""`
+	ErrorMessage    string  `json:"error_message" example:""`
+	BatteryLevel    *uint   `json:"battery_level" example:"15"`
+	LastHeartbeatAt *string `json:"last_heartbeat_at" example:"2022-06-05T14:26:02Z"`
+	Timestamp       string  `json:"timestamp" example:"2022-06-05T14:26:02Z"`
+	EventTimestamp  string  `json:"event_timestamp" example:"2022-06-05T14:26:03Z"`
+}
+
+// NewFlatWebhookPayload flattens the data of a cloudevents.Event which is sent to a webhook into a FlatWebhookPayload
+func NewFlatWebhookPayload(event cloudevents.Event) (*FlatWebhookPayload, error) {
+	payload := &FlatWebhookPayload{
+		EventID:        event.ID(),
+		EventType:      event.Type(),
+		EventTimestamp: flatTimestamp(event.Time()),
+	}
+
+	var err error
+	switch event.Type() {
+	case events.EventTypeMessagePhoneReceived:
+		err = flattenEvent(event, func(data *events.MessagePhoneReceivedPayload) {
+			payload.flattenMessage(data.UserID, data.MessageID, data.Owner, data.Contact, data.Content, data.SIM, data.Timestamp)
+			payload.From, payload.To = data.Contact, data.Owner
+			payload.Status = entities.MessageStatusReceived
+		})
+	case events.EventTypeMessagePhoneSent:
+		err = flattenEvent(event, func(data *events.MessagePhoneSentPayload) {
+			payload.flattenMessage(data.UserID, data.ID, data.Owner, data.Contact, data.Content, data.SIM, data.Timestamp)
+			payload.RequestID = flatString(data.RequestID)
+			payload.Status = entities.MessageStatusSent
+			payload.flattenTransition(data.MessageStatusTransition)
+		})
+	case events.EventTypeMessagePhoneDelivered:
+		err = flattenEvent(event, func(data *events.MessagePhoneDeliveredPayload) {
+			payload.flattenMessage(data.UserID, data.ID, data.Owner, data.Contact, data.Content, data.SIM, data.Timestamp)
+			payload.RequestID = flatString(data.RequestID)
+			payload.Status = entities.MessageStatusDelivered
+			payload.flattenTransition(data.MessageStatusTransition)
+		})
+	case events.EventTypeMessageSendFailed:
+		err = flattenEvent(event, func(data *events.MessageSendFailedPayload) {
+			payload.flattenMessage(data.UserID, data.ID, data.Owner, data.Contact, data.Content, data.SIM, data.Timestamp)
+			payload.RequestID = flatString(data.RequestID)
+			payload.ErrorMessage = data.ErrorMessage
+			payload.Status = entities.MessageStatusFailed
+			payload.flattenTransition(data.MessageStatusTransition)
+		})
+	case events.EventTypeMessageSendExpired:
+		err = flattenEvent(event, func(data *events.MessageSendExpiredPayload) {
+			payload.flattenMessage(data.UserID, data.MessageID, data.Owner, data.Contact, data.Content, data.SIM, data.Timestamp)
+			payload.RequestID = flatString(data.RequestID)
+			payload.Status = entities.MessageStatusExpired
+			payload.flattenTransition(data.MessageStatusTransition)
+		})
+	case events.EventTypeMessageFailover:
+		err = flattenEvent(event, func(data *events.MessageFailoverPayload) {
+			payload.flattenMessage(data.UserID, data.MessageID, data.Owner, data.Contact, data.Content, data.SIM, data.Timestamp)
+			payload.Status = entities.MessageStatusPending
+		})
+	case events.EventTypePhoneHeartbeatDead:
+		err = flattenEvent(event, func(data *events.PhoneHeartbeatDeadPayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "dead", data.Timestamp)
+			payload.LastHeartbeatAt = flatTimestampPointer(data.LastHeartbeatTimestamp)
+		})
+	case events.EventTypePhoneHeartbeatAlive:
+		err = flattenEvent(event, func(data *events.PhoneHeartbeatAlivePayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "alive", data.Timestamp)
+			payload.LastHeartbeatAt = flatTimestampPointer(data.LastHeartbeatTimestamp)
+		})
+	case events.EventTypePhoneBatteryLow:
+		err = flattenEvent(event, func(data *events.PhoneBatteryLowPayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "battery_low", data.Timestamp)
+			payload.BatteryLevel = &data.BatteryLevel
+		})
+	case events.EventTypePhonePaused:
+		err = flattenEvent(event, func(data *events.PhonePausedPayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "paused", data.Timestamp)
+		})
+	case events.EventTypePhoneResumed:
+		err = flattenEvent(event, func(data *events.PhoneResumedPayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "resumed", data.Timestamp)
+		})
+	case events.EventTypePhoneQuotaExhausted:
+		err = flattenEvent(event, func(data *events.PhoneQuotaExhaustedPayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "quota_exhausted", data.Timestamp)
+		})
+	case events.EventTypePhoneClockSkewed:
+		err = flattenEvent(event, func(data *events.PhoneClockSkewedPayload) {
+			payload.flattenPhone(data.UserID, data.PhoneID, data.Owner, "clock_skewed", data.Timestamp)
+		})
+	default:
+		return nil, stacktrace.NewError(fmt.Sprintf("cannot flatten event [%s] with ID [%s]", event.Type(), event.ID()))
+	}
+
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot flatten event [%s] with ID [%s]", event.Type(), event.ID()))
+	}
+
+	return payload, nil
+}
+
+func (payload *FlatWebhookPayload) flattenMessage(userID entities.UserID, messageID uuid.UUID, owner, contact, content string, sim entities.SIM, timestamp time.Time) {
+	payload.UserID = string(userID)
+	payload.PhoneNumber = owner
+	payload.MessageID = messageID.String()
+	payload.From = owner
+	payload.To = contact
+	payload.Content = content
+	payload.SIM = sim.String()
+	payload.Timestamp = flatTimestamp(timestamp)
+}
+
+func (payload *FlatWebhookPayload) flattenTransition(transition events.MessageStatusTransition) {
+	payload.PreviousStatus = string(transition.PreviousStatus)
+	payload.NewStatus = string(transition.NewStatus)
+	payload.TransitionedAt = flatTimestamp(transition.TransitionedAt)
+	payload.AttemptCount = &transition.AttemptCount
+}
+
+func (payload *FlatWebhookPayload) flattenPhone(userID entities.UserID, phoneID uuid.UUID, owner string, status string, timestamp time.Time) {
+	payload.UserID = string(userID)
+	payload.PhoneNumber = owner
+	payload.PhoneID = phoneID.String()
+	payload.Status = status
+	payload.Timestamp = flatTimestamp(timestamp)
+}
+
+// flattenEvent decodes the data of the event into T before flattening it
+func flattenEvent[T any](event cloudevents.Event, flatten func(data *T)) error {
+	data := new(T)
+	if err := event.DataAs(data); err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot decode event [%s] with ID [%s] into [%T]", event.Type(), event.ID(), data))
+	}
+	flatten(data)
+	return nil
+}
+
+func flatString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func flatTimestamp(timestamp time.Time) string {
+	if timestamp.IsZero() {
+		return ""
+	}
+	return timestamp.UTC().Format(time.RFC3339)
+}
+
+func flatTimestampPointer(timestamp time.Time) *string {
+	if timestamp.IsZero() {
+		return nil
+	}
+	value := flatTimestamp(timestamp)
+	return &value
+}
+
+// webhookSampleTimestamp is the time of the sample events which are sent to a webhook
+var webhookSampleTimestamp = time.Date(2022, time.June, 5, 14, 26, 2, 0, time.UTC)
+
+// webhookSamplePayloads are the payloads of the sample events for each event type which can be sent to a webhook
+var webhookSamplePayloads = map[string]any{
+	events.EventTypeMessagePhoneReceived: &events.MessagePhoneReceivedPayload{
+		MessageID: uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Owner:     "+18005550199",
+		Contact:   "+18005550100",
+		Timestamp: webhookSampleTimestamp,
+		Content:   "This is a sample text message",
+		SIM:       entities.SIM1,
+	},
+	events.EventTypeMessagePhoneSent: &events.MessagePhoneSentPayload{
+		ID:        uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		RequestID: webhookSampleRequestID(),
+		Owner:     "+18005550199",
+		Contact:   "+18005550100",
+		Timestamp: webhookSampleTimestamp,
+		Content:   "This is a sample text message",
+		SIM:       entities.SIM1,
+		MessageStatusTransition: events.MessageStatusTransition{
+			PreviousStatus: entities.MessageStatusSending,
+			NewStatus:      entities.MessageStatusSent,
+			TransitionedAt: webhookSampleTimestamp,
+			AttemptCount:   1,
+		},
+	},
+	events.EventTypeMessagePhoneDelivered: &events.MessagePhoneDeliveredPayload{
+		ID:        uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		Owner:     "+18005550199",
+		Contact:   "+18005550100",
+		RequestID: webhookSampleRequestID(),
+		UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Timestamp: webhookSampleTimestamp,
+		Content:   "This is a sample text message",
+		SIM:       entities.SIM1,
+		MessageStatusTransition: events.MessageStatusTransition{
+			PreviousStatus: entities.MessageStatusSent,
+			NewStatus:      entities.MessageStatusDelivered,
+			TransitionedAt: webhookSampleTimestamp,
+			AttemptCount:   1,
+		},
+	},
+	events.EventTypeMessageSendFailed: &events.MessageSendFailedPayload{
+		ID:           uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		ErrorMessage: "RESULT_ERROR_NO_SERVICE",
+		UserID:       "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Owner:        "+18005550199",
+		RequestID:    webhookSampleRequestID(),
+		Contact:      "+18005550100",
+		Timestamp:    webhookSampleTimestamp,
+		Content:      "This is a sample text message",
+		SIM:          entities.SIM1,
+		MessageStatusTransition: events.MessageStatusTransition{
+			PreviousStatus: entities.MessageStatusSending,
+			NewStatus:      entities.MessageStatusFailed,
+			TransitionedAt: webhookSampleTimestamp,
+			AttemptCount:   1,
+		},
+	},
+	events.EventTypeMessageSendExpired: &events.MessageSendExpiredPayload{
+		MessageID:        uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		Owner:            "+18005550199",
+		SendAttemptCount: 2,
+		IsFinal:          true,
+		RequestID:        webhookSampleRequestID(),
+		Contact:          "+18005550100",
+		UserID:           "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Timestamp:        webhookSampleTimestamp,
+		Content:          "This is a sample text message",
+		SIM:              entities.SIM1,
+		MessageStatusTransition: events.MessageStatusTransition{
+			PreviousStatus: entities.MessageStatusSending,
+			NewStatus:      entities.MessageStatusExpired,
+			TransitionedAt: webhookSampleTimestamp,
+			AttemptCount:   2,
+		},
+	},
+	events.EventTypeMessageFailover: &events.MessageFailoverPayload{
+		MessageID:     uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		UserID:        "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		OriginalOwner: "+18005550198",
+		Owner:         "+18005550199",
+		Contact:       "+18005550100",
+		Content:       "This is a sample text message",
+		SIM:           entities.SIM2,
+		Timestamp:     webhookSampleTimestamp,
+	},
+	events.EventTypePhoneHeartbeatDead: &events.PhoneHeartbeatDeadPayload{
+		PhoneID:                uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:                 "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		LastHeartbeatTimestamp: webhookSampleTimestamp.Add(-1 * time.Hour),
+		Timestamp:              webhookSampleTimestamp,
+		MonitorID:              uuid.MustParse("8f1e2d3c-4b5a-4697-8a8b-9c0d1e2f3a4b"),
+		Owner:                  "+18005550199",
+		Threshold:              time.Hour,
+	},
+	events.EventTypePhoneHeartbeatAlive: &events.PhoneHeartbeatAlivePayload{
+		PhoneID:                uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:                 "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		LastHeartbeatTimestamp: webhookSampleTimestamp,
+		Timestamp:              webhookSampleTimestamp,
+		MonitorID:              uuid.MustParse("8f1e2d3c-4b5a-4697-8a8b-9c0d1e2f3a4b"),
+		Owner:                  "+18005550199",
+		Threshold:              time.Hour,
+	},
+	events.EventTypePhoneBatteryLow: &events.PhoneBatteryLowPayload{
+		PhoneID:      uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:       "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Owner:        "+18005550199",
+		BatteryLevel: 15,
+		Threshold:    20,
+		Timestamp:    webhookSampleTimestamp,
+	},
+	events.EventTypePhonePaused: &events.PhonePausedPayload{
+		PhoneID:   uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Timestamp: webhookSampleTimestamp,
+		Owner:     "+18005550199",
+	},
+	events.EventTypePhoneResumed: &events.PhoneResumedPayload{
+		PhoneID:   uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:    "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Timestamp: webhookSampleTimestamp,
+		Owner:     "+18005550199",
+	},
+	events.EventTypePhoneQuotaExhausted: &events.PhoneQuotaExhaustedPayload{
+		PhoneID:        uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:         "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Owner:          "+18005550199",
+		DailySendLimit: 300,
+		ResetAt:        time.Date(2022, time.June, 6, 0, 0, 0, 0, time.UTC),
+		Timestamp:      webhookSampleTimestamp,
+	},
+	events.EventTypePhoneClockSkewed: &events.PhoneClockSkewedPayload{
+		PhoneID:               uuid.MustParse("4b6a7c1f-3d2e-4f8a-9b0c-5d6e7f8a9b0c"),
+		UserID:                "WB7DRDWrJZRGbYrv2CKGkqbzvqdC",
+		Owner:                 "+18005550199",
+		ClockSkewMilliseconds: -185000,
+		ThresholdMilliseconds: 120000,
+		Timestamp:             webhookSampleTimestamp,
+	},
+}
+
+func webhookSampleRequestID() *string {
+	requestID := "153554b5-ae44-44a0-8f4f-7bbac5657ad4"
+	return &requestID
+}
+
+// messageStatusTransitionPayload is the payload of an event with an events.MessageStatusTransition
+type messageStatusTransitionPayload interface {
+	SetExtensions(event *cloudevents.Event)
+}
+
+// NewWebhookSampleEvent creates a sample of an event which can be sent to a webhook, the sample has the same ID and
+// timestamps every time so that it can be used to configure no-code tools
+func NewWebhookSampleEvent(eventType string) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+
+	payload, ok := webhookSamplePayloads[eventType]
+	if !ok {
+		return event, stacktrace.NewError(fmt.Sprintf("there is no sample payload for the event [%s]", eventType))
+	}
+
+	event.SetSource("/v1/webhooks/samples")
+	event.SetType(eventType)
+	event.SetTime(webhookSampleTimestamp.Add(time.Second))
+	event.SetID("0b5b0d0a-5f2b-4c6e-9d3a-1c2f3e4d5a6b")
+
+	if err := event.SetData(cloudevents.ApplicationJSON, payload); err != nil {
+		return event, stacktrace.Propagate(err, fmt.Sprintf("cannot encode [%T] of sample event [%s] as JSON", payload, eventType))
+	}
+
+	if transition, ok := payload.(messageStatusTransitionPayload); ok {
+		transition.SetExtensions(&event)
+	}
+
+	return event, nil
+}
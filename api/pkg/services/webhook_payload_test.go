@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/events"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFlatWebhookPayload(t *testing.T) {
+	eventTypes := []string{
+		events.EventTypeMessagePhoneReceived,
+		events.EventTypeMessagePhoneSent,
+		events.EventTypeMessagePhoneDelivered,
+		events.EventTypeMessageSendFailed,
+		events.EventTypeMessageSendExpired,
+		events.EventTypeMessageFailover,
+		events.EventTypePhoneHeartbeatDead,
+		events.EventTypePhoneHeartbeatAlive,
+		events.EventTypePhoneBatteryLow,
+		events.EventTypePhonePaused,
+		events.EventTypePhoneResumed,
+		events.EventTypePhoneQuotaExhausted,
+		events.EventTypePhoneClockSkewed,
+	}
+
+	for _, eventType := range eventTypes {
+		eventType := eventType
+		t.Run(eventType, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			golden, err := os.ReadFile(filepath.Join("testdata", "webhook_payloads", eventType+".flat.json"))
+			assert.Nil(t, err)
+
+			// Arrange
+			event, err := NewWebhookSampleEvent(eventType)
+			assert.Nil(t, err)
+
+			// Act
+			payload, err := NewFlatWebhookPayload(event)
+
+			// Assert
+			assert.Nil(t, err)
+			content, err := json.Marshal(payload)
+			assert.Nil(t, err)
+			assert.JSONEq(t, strings.TrimSpace(string(golden)), string(content))
+		})
+	}
+
+	t.Run("unknown event type", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		event := cloudevents.NewEvent()
+		event.SetType(events.EventTypeMessageAPISent)
+
+		// Act
+		payload, err := NewFlatWebhookPayload(event)
+
+		// Assert
+		assert.Nil(t, payload)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("invalid event data", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		event := cloudevents.NewEvent()
+		event.SetType(events.EventTypeMessagePhoneReceived)
+		assert.Nil(t, event.SetData(cloudevents.ApplicationJSON, []string{"invalid"}))
+
+		// Act
+		payload, err := NewFlatWebhookPayload(event)
+
+		// Assert
+		assert.Nil(t, payload)
+		assert.NotNil(t, stacktrace.RootCause(err))
+	})
+}
+
+func TestNewWebhookSampleEvent(t *testing.T) {
+	eventTypes := []string{
+		events.EventTypeMessagePhoneSent,
+		events.EventTypeMessagePhoneDelivered,
+		events.EventTypeMessageSendFailed,
+		events.EventTypeMessageSendExpired,
+	}
+
+	for _, eventType := range eventTypes {
+		eventType := eventType
+		t.Run(eventType+" has the status transition", func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			golden, err := os.ReadFile(filepath.Join("testdata", "webhook_payloads", eventType+".cloudevent.json"))
+			assert.Nil(t, err)
+
+			// Act
+			event, err := NewWebhookSampleEvent(eventType)
+
+			// Assert
+			assert.Nil(t, err)
+			content, err := json.Marshal(event)
+			assert.Nil(t, err)
+			assert.JSONEq(t, strings.TrimSpace(string(golden)), string(content))
+		})
+	}
+}
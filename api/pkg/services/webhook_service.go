@@ -94,11 +94,12 @@ func (service *WebhookService) Delete(ctx context.Context, userID entities.UserI
 
 // WebhookStoreParams are parameters for creating a new entities.Webhook
 type WebhookStoreParams struct {
-	UserID       entities.UserID
-	SigningKey   string
-	URL          string
-	PhoneNumbers pq.StringArray
-	Events       pq.StringArray
+	UserID        entities.UserID
+	SigningKey    string
+	URL           string
+	PhoneNumbers  pq.StringArray
+	Events        pq.StringArray
+	PayloadFormat entities.WebhookPayloadFormat
 }
 
 // Store a new entities.Webhook
@@ -109,14 +110,15 @@ func (service *WebhookService) Store(ctx context.Context, params *WebhookStorePa
 	ctxLogger := service.tracer.CtxLogger(service.logger, span)
 
 	webhook := &entities.Webhook{
-		ID:           uuid.New(),
-		UserID:       params.UserID,
-		URL:          params.URL,
-		PhoneNumbers: params.PhoneNumbers,
-		SigningKey:   params.SigningKey,
-		Events:       params.Events,
-		CreatedAt:    time.Now().UTC(),
-		UpdatedAt:    time.Now().UTC(),
+		ID:            uuid.New(),
+		UserID:        params.UserID,
+		URL:           params.URL,
+		PhoneNumbers:  params.PhoneNumbers,
+		SigningKey:    params.SigningKey,
+		Events:        params.Events,
+		PayloadFormat: params.PayloadFormat,
+		CreatedAt:     time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
 	}
 
 	if err := service.repository.Save(ctx, webhook); err != nil {
@@ -130,12 +132,13 @@ func (service *WebhookService) Store(ctx context.Context, params *WebhookStorePa
 
 // WebhookUpdateParams are parameters for updating an entities.Webhook
 type WebhookUpdateParams struct {
-	UserID       entities.UserID
-	SigningKey   string
-	URL          string
-	Events       pq.StringArray
-	PhoneNumbers pq.StringArray
-	WebhookID    uuid.UUID
+	UserID        entities.UserID
+	SigningKey    string
+	URL           string
+	Events        pq.StringArray
+	PhoneNumbers  pq.StringArray
+	PayloadFormat entities.WebhookPayloadFormat
+	WebhookID     uuid.UUID
 }
 
 // Update an entities.Webhook
@@ -153,6 +156,7 @@ func (service *WebhookService) Update(ctx context.Context, params *WebhookUpdate
 	webhook.SigningKey = params.SigningKey
 	webhook.Events = params.Events
 	webhook.PhoneNumbers = params.PhoneNumbers
+	webhook.PayloadFormat = params.PayloadFormat
 
 	if err = service.repository.Save(ctx, webhook); err != nil {
 		msg := fmt.Sprintf("cannot save webhook with id [%s] after update", webhook.ID)
@@ -260,7 +264,31 @@ func (service *WebhookService) createRequest(ctx context.Context, event cloudeve
 	return request, nil
 }
 
+// Sample returns the payload of a sample event in the entities.WebhookPayloadFormat so that users can configure their
+// webhooks before the first event is sent
+func (service *WebhookService) Sample(ctx context.Context, eventType string, format entities.WebhookPayloadFormat) (any, error) {
+	_, span, ctxLogger := service.tracer.StartWithLogger(ctx, service.logger)
+	defer span.End()
+
+	event, err := NewWebhookSampleEvent(eventType)
+	if err != nil {
+		msg := fmt.Sprintf("cannot create sample event [%s] in the [%s] format", eventType, format)
+		return nil, service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return service.getPayload(ctxLogger, event, &entities.Webhook{PayloadFormat: format}), nil
+}
+
 func (service *WebhookService) getPayload(ctxLogger telemetry.Logger, event cloudevents.Event, webhook *entities.Webhook) any {
+	if webhook.PayloadFormat == entities.WebhookPayloadFormatFlat {
+		payload, err := NewFlatWebhookPayload(event)
+		if err != nil {
+			ctxLogger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot flatten event [%s] with ID [%s] for webhook [%s]", event.Type(), event.ID(), webhook.ID)))
+			return event
+		}
+		return payload
+	}
+
 	if event.Type() != events.EventTypeMessagePhoneReceived {
 		return event
 	}
@@ -296,7 +324,7 @@ func (service *WebhookService) getPayload(ctxLogger telemetry.Logger, event clou
 					},
 					{
 						"name":  "Content:",
-						"value": payload.Content,
+						"value": displayContent(payload.Content, payload.IsEncrypted),
 					},
 					{
 						"name":  "MessageID:",
@@ -350,7 +378,7 @@ func (service *WebhookService) handleWebhookSendFailed(ctx context.Context, even
 		}
 	}
 
-	event, err = service.createEvent(events.EventTypeWebhookSendFailed, event.Source(), payload)
+	event, err = service.createEvent(ctx, events.EventTypeWebhookSendFailed, event.Source(), payload)
 	if err != nil {
 		msg := fmt.Sprintf("cannot create event [%s] for user with id [%s]", events.EventTypeWebhookSendFailed, payload.UserID)
 		ctxLogger.Error(service.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg)))
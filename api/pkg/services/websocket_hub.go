@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/palantir/stacktrace"
+)
+
+// WebsocketMessage is the envelope of the messages which are sent to a WebsocketClient
+type WebsocketMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	Data any    `json:"data"`
+}
+
+// WebsocketClient is a websocket connection of an entities.User which is registered in the WebsocketHub
+type WebsocketClient struct {
+	UserID   entities.UserID
+	messages chan []byte
+}
+
+// Messages returns the channel of the encoded messages, it is closed when the client is unregistered or dropped by the WebsocketHub
+func (client *WebsocketClient) Messages() <-chan []byte {
+	return client.messages
+}
+
+// WebsocketHub fans out messages to the websocket connections of a user.
+// The clients are indexed by user so that a message is only delivered to the connections of its user,
+// and a client which cannot keep up is dropped so that a slow connection never blocks the event listeners.
+type WebsocketHub struct {
+	service
+	logger         telemetry.Logger
+	tracer         telemetry.Tracer
+	maxConnections int
+	bufferSize     int
+	mutex          sync.RWMutex
+	clients        map[entities.UserID]map[*WebsocketClient]struct{}
+	closed         bool
+}
+
+// NewWebsocketHub creates a new WebsocketHub
+func NewWebsocketHub(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	maxConnections int,
+	bufferSize int,
+) (hub *WebsocketHub) {
+	return &WebsocketHub{
+		logger:         logger.WithService(fmt.Sprintf("%T", hub)),
+		tracer:         tracer,
+		maxConnections: maxConnections,
+		bufferSize:     bufferSize,
+		clients:        map[entities.UserID]map[*WebsocketClient]struct{}{},
+	}
+}
+
+// Register adds a WebsocketClient for a user, Unregister must be called when the connection is closed
+func (hub *WebsocketHub) Register(userID entities.UserID) (*WebsocketClient, error) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.closed {
+		client := &WebsocketClient{UserID: userID, messages: make(chan []byte)}
+		close(client.messages)
+		return client, nil
+	}
+
+	if len(hub.clients[userID]) >= hub.maxConnections {
+		msg := fmt.Sprintf("user [%s] already has [%d] websocket connections", userID, len(hub.clients[userID]))
+		return nil, stacktrace.NewErrorWithCode(ErrCodeConnectionLimit, msg)
+	}
+
+	client := &WebsocketClient{
+		UserID:   userID,
+		messages: make(chan []byte, hub.bufferSize),
+	}
+
+	if _, ok := hub.clients[userID]; !ok {
+		hub.clients[userID] = map[*WebsocketClient]struct{}{}
+	}
+
+	hub.clients[userID][client] = struct{}{}
+	return client, nil
+}
+
+// Unregister removes a WebsocketClient and closes its channel, it is safe to call it for a dropped client
+func (hub *WebsocketHub) Unregister(client *WebsocketClient) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.remove(client)
+}
+
+// Close removes all the clients so that their connections are closed e.g. when the server is shutting down, the
+// clients which are registered after it is closed are already removed
+func (hub *WebsocketHub) Close() {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.closed = true
+	for _, clients := range hub.clients {
+		for client := range clients {
+			hub.remove(client)
+		}
+	}
+}
+
+// MaxConnections returns the maximum number of websocket connections of a user
+func (hub *WebsocketHub) MaxConnections() int {
+	return hub.maxConnections
+}
+
+// Connections returns the number of websocket connections of a user
+func (hub *WebsocketHub) Connections(userID entities.UserID) int {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	return len(hub.clients[userID])
+}
+
+// Publish sends a cloudevents.Event to the clients of the user in the payload, it is an events.EventListener
+func (hub *WebsocketHub) Publish(ctx context.Context, event cloudevents.Event) error {
+	ctx, span := hub.tracer.Start(ctx)
+	defer span.End()
+
+	payload := struct {
+		UserID entities.UserID `json:"user_id"`
+	}{}
+	if err := json.Unmarshal(event.Data(), &payload); err != nil {
+		msg := fmt.Sprintf("cannot decode the user of event [%s] with type [%s]", event.ID(), event.Type())
+		return hub.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return hub.Broadcast(ctx, payload.UserID, &WebsocketMessage{
+		Type: event.Type(),
+		ID:   event.ID(),
+		Data: json.RawMessage(event.Data()),
+	}, nil)
+}
+
+// Broadcast sends a WebsocketMessage to the clients of a user without blocking, the sender is skipped when it is not nil
+func (hub *WebsocketHub) Broadcast(ctx context.Context, userID entities.UserID, message *WebsocketMessage, sender *WebsocketClient) error {
+	_, span, ctxLogger := hub.tracer.StartWithLogger(ctx, hub.logger)
+	defer span.End()
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		msg := fmt.Sprintf("cannot marshal websocket message with type [%s] into JSON", message.Type)
+		return hub.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	var dropped []*WebsocketClient
+
+	hub.mutex.RLock()
+	for client := range hub.clients[userID] {
+		if client == sender {
+			continue
+		}
+
+		select {
+		case client.messages <- data:
+		default:
+			dropped = append(dropped, client)
+		}
+	}
+	hub.mutex.RUnlock()
+
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	for _, client := range dropped {
+		ctxLogger.Warn(stacktrace.NewError(fmt.Sprintf("dropping slow websocket client of user [%s] which cannot receive message with type [%s]", client.UserID, message.Type)))
+		hub.remove(client)
+	}
+	return nil
+}
+
+// Send sends a WebsocketMessage to a single client without blocking e.g. the reply to a command
+func (hub *WebsocketHub) Send(ctx context.Context, client *WebsocketClient, message *WebsocketMessage) error {
+	_, span := hub.tracer.Start(ctx)
+	defer span.End()
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		msg := fmt.Sprintf("cannot marshal websocket message with type [%s] into JSON", message.Type)
+		return hub.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if _, ok := hub.clients[client.UserID][client]; !ok {
+		return nil
+	}
+
+	select {
+	case client.messages <- data:
+	default:
+		hub.remove(client)
+	}
+	return nil
+}
+
+// remove deletes the client, the caller must hold the write lock so that no message is sent on the closed channel
+func (hub *WebsocketHub) remove(client *WebsocketClient) {
+	if _, ok := hub.clients[client.UserID][client]; !ok {
+		return
+	}
+
+	delete(hub.clients[client.UserID], client)
+	if len(hub.clients[client.UserID]) == 0 {
+		delete(hub.clients, client.UserID)
+	}
+	close(client.messages)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestWebsocketHub(maxConnections int, bufferSize int) *WebsocketHub {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewWebsocketHub(logger, tracer, maxConnections, bufferSize)
+}
+
+func newHubEvent(t assert.TestingT, userID entities.UserID) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(uuid.NewString())
+	event.SetType("message.phone.received")
+	event.SetSource("/v1/messages/receive")
+	event.SetTime(time.Now().UTC())
+	assert.Nil(t, event.SetData(cloudevents.ApplicationJSON, map[string]any{"user_id": userID}))
+	return event
+}
+
+func TestWebsocketHub_Register(t *testing.T) {
+	t.Run("connections of a user are limited", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub := newTestWebsocketHub(2, 10)
+
+		// Arrange
+		first, err := hub.Register("user-a")
+		assert.Nil(t, err)
+		_, err = hub.Register("user-a")
+		assert.Nil(t, err)
+
+		// Act
+		_, err = hub.Register("user-a")
+
+		// Assert
+		assert.Equal(t, ErrCodeConnectionLimit, stacktrace.GetCode(err))
+
+		_, err = hub.Register("user-b")
+		assert.Nil(t, err)
+
+		hub.Unregister(first)
+		_, err = hub.Register("user-a")
+		assert.Nil(t, err)
+	})
+}
+
+func TestWebsocketHub_Close(t *testing.T) {
+	t.Run("clients are removed when the hub is closed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub := newTestWebsocketHub(2, 10)
+
+		// Arrange
+		client, err := hub.Register("user-a")
+		assert.Nil(t, err)
+
+		// Act
+		hub.Close()
+		late, err := hub.Register("user-a")
+
+		// Assert
+		assert.Nil(t, err)
+		_, ok := <-client.Messages()
+		assert.False(t, ok)
+		_, ok = <-late.Messages()
+		assert.False(t, ok)
+		assert.Equal(t, 0, hub.Connections("user-a"))
+		hub.Unregister(late)
+	})
+}
+
+func TestWebsocketHub_Publish(t *testing.T) {
+	t.Run("events are only sent to the clients of the user", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub := newTestWebsocketHub(10, 10)
+
+		// Arrange
+		first, _ := hub.Register("user-a")
+		second, _ := hub.Register("user-a")
+		other, _ := hub.Register("user-b")
+		event := newHubEvent(t, "user-a")
+
+		// Act
+		err := hub.Publish(context.Background(), event)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(other.Messages()))
+		for _, client := range []*WebsocketClient{first, second} {
+			message := new(WebsocketMessage)
+			assert.Nil(t, json.Unmarshal(<-client.Messages(), message))
+			assert.Equal(t, event.ID(), message.ID)
+			assert.Equal(t, event.Type(), message.Type)
+		}
+	})
+
+	t.Run("slow client is dropped without blocking the other clients", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub := newTestWebsocketHub(10, 1)
+
+		// Arrange
+		slow, _ := hub.Register("user-a")
+		fast, _ := hub.Register("user-a")
+
+		// Act
+		for i := 0; i < 2; i++ {
+			assert.Nil(t, hub.Publish(context.Background(), newHubEvent(t, "user-a")))
+			if i == 0 {
+				<-fast.Messages()
+			}
+		}
+
+		// Assert
+		assert.Equal(t, 1, hub.Connections("user-a"))
+		<-slow.Messages()
+		_, ok := <-slow.Messages()
+		assert.False(t, ok)
+		assert.Equal(t, 1, len(fast.Messages()))
+
+		hub.Unregister(slow)
+		hub.Unregister(fast)
+		assert.Equal(t, 0, hub.Connections("user-a"))
+	})
+}
+
+func TestWebsocketHub_Broadcast(t *testing.T) {
+	t.Run("message is not sent back to the sender", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		hub := newTestWebsocketHub(10, 10)
+
+		// Arrange
+		sender, _ := hub.Register("user-a")
+		receiver, _ := hub.Register("user-a")
+
+		// Act
+		err := hub.Broadcast(context.Background(), "user-a", &WebsocketMessage{Type: "message-thread.typing"}, sender)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, 0, len(sender.Messages()))
+		assert.Equal(t, 1, len(receiver.Messages()))
+	})
+}
+
+// TestWebsocketHub_Load fans out events from concurrent publishers to thousands of clients which read at the same time
+func TestWebsocketHub_Load(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the load test of the websocket hub in short mode")
+	}
+
+	// Setup
+	const (
+		users          = 500
+		clientsPerUser = 10
+		eventsPerUser  = 50
+	)
+	hub := newTestWebsocketHub(clientsPerUser, eventsPerUser)
+
+	// Arrange
+	var received sync.WaitGroup
+	counts := make([]int, users*clientsPerUser)
+	for i := 0; i < users; i++ {
+		for j := 0; j < clientsPerUser; j++ {
+			client, err := hub.Register(entities.UserID(fmt.Sprintf("user-%d", i)))
+			assert.Nil(t, err)
+
+			received.Add(1)
+			go func(client *WebsocketClient, index int) {
+				defer received.Done()
+				for range client.Messages() {
+					if counts[index]++; counts[index] == eventsPerUser {
+						hub.Unregister(client)
+					}
+				}
+			}(client, i*clientsPerUser+j)
+		}
+	}
+
+	// Act
+	start := time.Now()
+	var published sync.WaitGroup
+	for i := 0; i < users; i++ {
+		published.Add(1)
+		go func(userID entities.UserID) {
+			defer published.Done()
+			for k := 0; k < eventsPerUser; k++ {
+				assert.Nil(t, hub.Publish(context.Background(), newHubEvent(t, userID)))
+			}
+		}(entities.UserID(fmt.Sprintf("user-%d", i)))
+	}
+	published.Wait()
+	received.Wait()
+
+	// Assert
+	t.Logf("delivered [%d] messages to [%d] clients in [%s]", users*clientsPerUser*eventsPerUser, users*clientsPerUser, time.Since(start))
+	for index, count := range counts {
+		assert.Equal(t, eventsPerUser, count, fmt.Sprintf("client [%d]", index))
+	}
+	assert.Equal(t, 0, hub.Connections("user-0"))
+}
+
+func BenchmarkWebsocketHub_Publish(b *testing.B) {
+	hub := newTestWebsocketHub(10, 64)
+
+	var clients sync.WaitGroup
+	for i := 0; i < 1000; i++ {
+		client, _ := hub.Register(entities.UserID(fmt.Sprintf("user-%d", i%100)))
+		clients.Add(1)
+		go func() {
+			defer clients.Done()
+			for range client.Messages() {
+			}
+		}()
+	}
+	event := newHubEvent(b, "user-1")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = hub.Publish(context.Background(), event)
+	}
+	b.StopTimer()
+
+	for i := 0; i < 100; i++ {
+		userID := entities.UserID(fmt.Sprintf("user-%d", i))
+		hub.mutex.Lock()
+		for client := range hub.clients[userID] {
+			hub.remove(client)
+		}
+		hub.mutex.Unlock()
+	}
+	clients.Wait()
+}
@@ -0,0 +1,37 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ChatService is the API client for posting messages with a bot token
+type ChatService service
+
+// PostMessage sends a message to a channel with the token of a bot which is a member of the channel.
+//
+// API Docs: https://api.slack.com/methods/chat.postMessage
+func (service *ChatService) PostMessage(ctx context.Context, token string, payload *Message) (*MessageResponse, *Response, error) {
+	request, err := service.client.newRequest(ctx, http.MethodPost, service.client.baseURL+"/chat.postMessage", token, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := service.client.do(request)
+	if err != nil {
+		return nil, response, err
+	}
+
+	message := new(MessageResponse)
+	if err = json.Unmarshal(*response.Body, message); err != nil {
+		return nil, response, err
+	}
+
+	if !message.OK {
+		return message, response, fmt.Errorf("cannot post message to channel [%s]: %s", payload.Channel, message.Error)
+	}
+
+	return message, response, nil
+}
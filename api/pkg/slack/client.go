@@ -0,0 +1,176 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type service struct {
+	client *Client
+}
+
+// Client is the slack API client.
+// Do not instantiate this client with Client{}. Use the New method instead.
+type Client struct {
+	httpClient    *http.Client
+	common        service
+	baseURL       string
+	maxRetries    int
+	maxRetryDelay time.Duration
+
+	Chat    *ChatService
+	Webhook *WebhookService
+}
+
+// New creates and returns a new slack.Client from a slice of slack.Option.
+func New(options ...Option) *Client {
+	config := defaultClientConfig()
+
+	for _, option := range options {
+		option.apply(config)
+	}
+
+	client := &Client{
+		httpClient:    config.httpClient,
+		baseURL:       config.baseURL,
+		maxRetries:    config.maxRetries,
+		maxRetryDelay: config.maxRetryDelay,
+	}
+
+	client.common.client = client
+
+	client.Chat = (*ChatService)(&client.common)
+	client.Webhook = (*WebhookService)(&client.common)
+
+	return client
+}
+
+// newRequest creates an API request to the url, the token is sent as a bearer token when it is not empty
+func (client *Client) newRequest(ctx context.Context, method, url string, token string, body any) (*http.Request, error) {
+	var buf io.ReadWriter
+	if body != nil {
+		buf = &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		err := enc.Encode(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// do carries out an HTTP request and returns a Response, the request is retried after the Retry-After header when
+// slack responds with 429 Too Many Requests
+//
+// API Docs: https://api.slack.com/apis/rate-limits
+func (client *Client) do(req *http.Request) (*Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%T cannot be nil", req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.send(req)
+		if resp == nil || resp.HTTPResponse.StatusCode != http.StatusTooManyRequests || attempt >= client.maxRetries {
+			return resp, err
+		}
+
+		delay := client.retryDelay(resp.HTTPResponse)
+		if delay > client.maxRetryDelay {
+			return resp, err
+		}
+
+		if req, err = client.rewind(req); err != nil {
+			return resp, err
+		}
+
+		select {
+		case <-req.Context().Done():
+			return resp, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send carries out a single HTTP request
+func (client *Client) send(req *http.Request) (*Response, error) {
+	httpResponse, err := client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = httpResponse.Body.Close() }()
+
+	resp, err := client.newResponse(httpResponse)
+	if err != nil {
+		return resp, err
+	}
+
+	_, err = io.Copy(io.Discard, httpResponse.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// rewind creates a copy of a request whose body can be sent again
+func (client *Client) rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return clone, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	clone.Body = body
+	return clone, nil
+}
+
+// retryDelay is the time to wait before retrying a rate limited request, slack sends it in seconds
+func (client *Client) retryDelay(httpResponse *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(httpResponse.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// newResponse converts an *http.Response to *Response
+func (client *Client) newResponse(httpResponse *http.Response) (*Response, error) {
+	if httpResponse == nil {
+		return nil, fmt.Errorf("%T cannot be nil", httpResponse)
+	}
+
+	resp := new(Response)
+	resp.HTTPResponse = httpResponse
+
+	buf, err := io.ReadAll(resp.HTTPResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &buf
+
+	return resp, resp.Error()
+}
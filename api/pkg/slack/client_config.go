@@ -0,0 +1,22 @@
+package slack
+
+import (
+	"net/http"
+	"time"
+)
+
+type clientConfig struct {
+	httpClient    *http.Client
+	baseURL       string
+	maxRetries    int
+	maxRetryDelay time.Duration
+}
+
+func defaultClientConfig() *clientConfig {
+	return &clientConfig{
+		httpClient:    http.DefaultClient,
+		baseURL:       "https://slack.com/api",
+		maxRetries:    3,
+		maxRetryDelay: 30 * time.Second,
+	}
+}
@@ -0,0 +1,56 @@
+package slack
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option is options for constructing a client
+type Option interface {
+	apply(config *clientConfig)
+}
+
+type clientOptionFunc func(config *clientConfig)
+
+func (fn clientOptionFunc) apply(config *clientConfig) {
+	fn(config)
+}
+
+// WithHTTPClient sets the underlying HTTP client used for API requests.
+// By default, http.DefaultClient is used.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return clientOptionFunc(func(config *clientConfig) {
+		if httpClient != nil {
+			config.httpClient = httpClient
+		}
+	})
+}
+
+// WithBaseURL set's the base url for the slack web API
+func WithBaseURL(baseURL string) Option {
+	return clientOptionFunc(func(config *clientConfig) {
+		if baseURL != "" {
+			config.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	})
+}
+
+// WithMaxRetries sets the number of times a request is retried when slack responds with 429 Too Many Requests
+func WithMaxRetries(maxRetries int) Option {
+	return clientOptionFunc(func(config *clientConfig) {
+		if maxRetries >= 0 {
+			config.maxRetries = maxRetries
+		}
+	})
+}
+
+// WithMaxRetryDelay sets the longest time to wait before retrying a request, a request whose Retry-After header is
+// longer is not retried
+func WithMaxRetryDelay(maxRetryDelay time.Duration) Option {
+	return clientOptionFunc(func(config *clientConfig) {
+		if maxRetryDelay >= 0 {
+			config.maxRetryDelay = maxRetryDelay
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package slack
+
+// Message is a message which is posted to a slack channel
+//
+// API Docs: https://api.slack.com/methods/chat.postMessage
+type Message struct {
+	// Channel is the ID or the name of the channel, it is ignored by incoming webhooks which post to a fixed channel
+	Channel string `json:"channel,omitempty"`
+
+	// Text is the fallback of the Blocks which is used in notifications
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks,omitempty"`
+
+	// ThreadTS is the timestamp of the parent message when the message is a reply in a thread
+	ThreadTS string `json:"thread_ts,omitempty"`
+}
+
+// Block is a layout block of a Message
+//
+// API Docs: https://api.slack.com/reference/block-kit/blocks
+type Block struct {
+	Type     string `json:"type"`
+	Text     *Text  `json:"text,omitempty"`
+	Fields   []Text `json:"fields,omitempty"`
+	Elements []Text `json:"elements,omitempty"`
+}
+
+// Text is a text object of a Block
+//
+// API Docs: https://api.slack.com/reference/block-kit/composition-objects#text
+type Text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// MessageResponse is the response of the web API after posting a Message
+type MessageResponse struct {
+	OK      bool   `json:"ok"`
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+	Error   string `json:"error"`
+}
@@ -0,0 +1,22 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+)
+
+// WebhookService is the API client for posting messages with incoming webhooks
+type WebhookService service
+
+// Send posts a message to the channel of an incoming webhook, incoming webhooks don't respond with the timestamp of
+// the message so the message cannot be the parent of a thread.
+//
+// API Docs: https://api.slack.com/messaging/webhooks
+func (service *WebhookService) Send(ctx context.Context, url string, payload *Message) (*Response, error) {
+	request, err := service.client.newRequest(ctx, http.MethodPost, url, "", payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.client.do(request)
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+// ErrCodeBlobNotFound is thrown when an object does not exist in the BlobStore
+const ErrCodeBlobNotFound = stacktrace.ErrorCode(3000)
+
+// ErrCodeSignedURLUnsupported is thrown when the BlobStore cannot create signed URLs e.g. the objects are files in a directory
+const ErrCodeSignedURLUnsupported = stacktrace.ErrorCode(3001)
+
+// SignedURLOptions are the options of a URL which gives temporary access to an object without credentials
+type SignedURLOptions struct {
+	// Method is the HTTP method of the request e.g. PUT to upload the object or GET to download it
+	Method string
+	// ContentType is the Content-Type header which must be sent when uploading the object
+	ContentType string
+	// Expires is the time after which the URL cannot be used
+	Expires time.Time
+}
+
+// BlobAttributes are the attributes of an object in the BlobStore
+type BlobAttributes struct {
+	Size        int64
+	ContentType string
+}
+
+// BlobStore stores objects e.g. the exported messages in an object storage bucket
+type BlobStore interface {
+	// Put writes the content of an object, an existing object at the path is replaced
+	Put(ctx context.Context, path string, content []byte) error
+
+	// Get reads the content of an object, it fails with ErrCodeBlobNotFound when the object does not exist
+	Get(ctx context.Context, path string) ([]byte, error)
+
+	// Attributes reads the size and the content type of an object, it fails with ErrCodeBlobNotFound when the object does not exist
+	Attributes(ctx context.Context, path string) (*BlobAttributes, error)
+
+	// Delete removes an object, it does not fail when the object does not exist
+	Delete(ctx context.Context, path string) error
+
+	// SignedURL creates a URL which uploads or downloads an object until it expires, it fails with
+	// ErrCodeSignedURLUnsupported when the BlobStore cannot sign URLs
+	SignedURL(ctx context.Context, path string, options SignedURLOptions) (string, error)
+}
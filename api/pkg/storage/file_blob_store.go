@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+// fileBlobStore stores objects as files in a directory, it is used when self-hosting without object storage
+type fileBlobStore struct {
+	tracer    telemetry.Tracer
+	directory string
+}
+
+// NewFileBlobStore creates a BlobStore which stores objects in a directory
+func NewFileBlobStore(tracer telemetry.Tracer, directory string) BlobStore {
+	return &fileBlobStore{
+		tracer:    tracer,
+		directory: directory,
+	}
+}
+
+// Put writes the content of an object to a temporary file which is renamed so that a partial object is never read
+func (store *fileBlobStore) Put(ctx context.Context, path string, content []byte) error {
+	_, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	filePath := filepath.Join(store.directory, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot create directory of object [%s]", path)))
+	}
+
+	if err := os.WriteFile(filePath+".tmp", content, 0o640); err != nil {
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot write object [%s]", path)))
+	}
+
+	if err := os.Rename(filePath+".tmp", filePath); err != nil {
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot rename object [%s]", path)))
+	}
+
+	return nil
+}
+
+// Get reads the content of an object from the directory
+func (store *fileBlobStore) Get(ctx context.Context, path string) ([]byte, error) {
+	_, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	content, err := os.ReadFile(filepath.Join(store.directory, filepath.FromSlash(path)))
+	if errors.Is(err, fs.ErrNotExist) {
+		msg := fmt.Sprintf("object [%s] does not exist", path)
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeBlobNotFound, msg))
+	}
+
+	if err != nil {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot read object [%s]", path)))
+	}
+
+	return content, nil
+}
+
+// Attributes reads the size of a file in the directory, the content type is detected from the content of the file
+func (store *fileBlobStore) Attributes(ctx context.Context, path string) (*BlobAttributes, error) {
+	_, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	file, err := os.Open(filepath.Join(store.directory, filepath.FromSlash(path)))
+	if errors.Is(err, fs.ErrNotExist) {
+		msg := fmt.Sprintf("object [%s] does not exist", path)
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeBlobNotFound, msg))
+	}
+
+	if err != nil {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot open object [%s]", path)))
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot stat object [%s]", path)))
+	}
+
+	// http.DetectContentType considers at most the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot read object [%s]", path)))
+	}
+
+	return &BlobAttributes{Size: info.Size(), ContentType: http.DetectContentType(head[:n])}, nil
+}
+
+// Delete removes a file from the directory
+func (store *fileBlobStore) Delete(ctx context.Context, path string) error {
+	_, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	err := os.Remove(filepath.Join(store.directory, filepath.FromSlash(path)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot delete object [%s]", path)))
+	}
+
+	return nil
+}
+
+// SignedURL is not supported because the files in the directory are not served over HTTP
+func (store *fileBlobStore) SignedURL(ctx context.Context, path string, options SignedURLOptions) (string, error) {
+	_, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	msg := fmt.Sprintf("cannot create signed [%s] URL of object [%s] in directory [%s]", options.Method, path, store.directory)
+	return "", store.tracer.WrapErrorSpan(span, stacktrace.NewErrorWithCode(ErrCodeSignedURLUnsupported, msg))
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+)
+
+// gcsBlobStore stores objects in a Google Cloud Storage bucket
+type gcsBlobStore struct {
+	tracer telemetry.Tracer
+	bucket *storage.BucketHandle
+}
+
+// NewGCSBlobStore creates a BlobStore for a Google Cloud Storage bucket.
+// The retention of the objects is configured with the retention policy of the bucket.
+func NewGCSBlobStore(tracer telemetry.Tracer, client *storage.Client, bucket string) BlobStore {
+	return &gcsBlobStore{
+		tracer: tracer,
+		bucket: client.Bucket(bucket),
+	}
+}
+
+// Put writes the content of an object to the bucket
+func (store *gcsBlobStore) Put(ctx context.Context, path string, content []byte) error {
+	ctx, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	writer := store.bucket.Object(path).NewWriter(ctx)
+	// the Content-Encoding is not set to gzip so that GCS returns the bytes which were written and the checksum matches
+	writer.ContentType = "application/octet-stream"
+
+	if _, err := writer.Write(content); err != nil {
+		_ = writer.Close()
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot write object [%s]", path)))
+	}
+
+	if err := writer.Close(); err != nil {
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot close writer of object [%s]", path)))
+	}
+
+	return nil
+}
+
+// Get reads the content of an object from the bucket
+func (store *gcsBlobStore) Get(ctx context.Context, path string) ([]byte, error) {
+	ctx, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	reader, err := store.bucket.Object(path).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		msg := fmt.Sprintf("object [%s] does not exist", path)
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeBlobNotFound, msg))
+	}
+
+	if err != nil {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot open reader of object [%s]", path)))
+	}
+	defer func() { _ = reader.Close() }()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot read object [%s]", path)))
+	}
+
+	return content, nil
+}
+
+// Attributes reads the size and the content type of an object in the bucket
+func (store *gcsBlobStore) Attributes(ctx context.Context, path string) (*BlobAttributes, error) {
+	ctx, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	attributes, err := store.bucket.Object(path).Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		msg := fmt.Sprintf("object [%s] does not exist", path)
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.PropagateWithCode(err, ErrCodeBlobNotFound, msg))
+	}
+
+	if err != nil {
+		return nil, store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot read attributes of object [%s]", path)))
+	}
+
+	return &BlobAttributes{Size: attributes.Size, ContentType: attributes.ContentType}, nil
+}
+
+// Delete removes an object from the bucket
+func (store *gcsBlobStore) Delete(ctx context.Context, path string) error {
+	ctx, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	err := store.bucket.Object(path).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("cannot delete object [%s]", path)))
+	}
+
+	return nil
+}
+
+// SignedURL creates a V4 signed URL of an object with the private key of the service account of the client
+func (store *gcsBlobStore) SignedURL(ctx context.Context, path string, options SignedURLOptions) (string, error) {
+	_, span := store.tracer.Start(ctx)
+	defer span.End()
+
+	url, err := store.bucket.SignedURL(path, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      options.Method,
+		ContentType: options.ContentType,
+		Expires:     options.Expires,
+	})
+	if err != nil {
+		msg := fmt.Sprintf("cannot create signed [%s] URL of object [%s]", options.Method, path)
+		return "", store.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, msg))
+	}
+
+	return url, nil
+}
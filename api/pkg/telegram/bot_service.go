@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"context"
+)
+
+// BotService is the API client for the methods of a telegram bot
+type BotService service
+
+// SendMessage sends a text message to a chat.
+//
+// API Docs: https://core.telegram.org/bots/api#sendmessage
+func (service *BotService) SendMessage(ctx context.Context, token string, payload *SendMessageRequest) (*Message, *Response, error) {
+	message := new(Message)
+	response, err := service.client.call(ctx, token, "sendMessage", payload, message)
+	if err != nil {
+		return nil, response, err
+	}
+	return message, response, nil
+}
+
+// SetWebhook sets the URL which receives the updates of the bot, the SecretToken is sent back in the
+// X-Telegram-Bot-Api-Secret-Token header of every update.
+//
+// API Docs: https://core.telegram.org/bots/api#setwebhook
+func (service *BotService) SetWebhook(ctx context.Context, token string, payload *SetWebhookRequest) (*Response, error) {
+	var result bool
+	return service.client.call(ctx, token, "setWebhook", payload, &result)
+}
+
+// DeleteWebhook removes the webhook of the bot.
+//
+// API Docs: https://core.telegram.org/bots/api#deletewebhook
+func (service *BotService) DeleteWebhook(ctx context.Context, token string) (*Response, error) {
+	var result bool
+	return service.client.call(ctx, token, "deleteWebhook", map[string]any{}, &result)
+}
+
+// GetMe returns the user of the bot, it is used to check the token of the bot.
+//
+// API Docs: https://core.telegram.org/bots/api#getme
+func (service *BotService) GetMe(ctx context.Context, token string) (*User, *Response, error) {
+	user := new(User)
+	response, err := service.client.call(ctx, token, "getMe", map[string]any{}, user)
+	if err != nil {
+		return nil, response, err
+	}
+	return user, response, nil
+}
@@ -0,0 +1,146 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type service struct {
+	client *Client
+}
+
+// Client is the telegram bot API client.
+// Do not instantiate this client with Client{}. Use the New method instead.
+type Client struct {
+	httpClient *http.Client
+	common     service
+	baseURL    string
+
+	Bot *BotService
+}
+
+// New creates and returns a new telegram.Client from a slice of telegram.Option.
+func New(options ...Option) *Client {
+	config := defaultClientConfig()
+
+	for _, option := range options {
+		option.apply(config)
+	}
+
+	client := &Client{
+		httpClient: config.httpClient,
+		baseURL:    config.baseURL,
+	}
+
+	client.common.client = client
+
+	client.Bot = (*BotService)(&client.common)
+
+	return client
+}
+
+// call calls a method of the bot API with the token of the bot and decodes the result of the method into result
+//
+// API Docs: https://core.telegram.org/bots/api#making-requests
+func (client *Client) call(ctx context.Context, token string, method string, body any, result any) (*Response, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(body); err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/%s", client.baseURL, token, method), buf)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.do(request)
+	if err != nil {
+		return response, client.apiError(response, err)
+	}
+
+	payload := &apiResponse{Result: result}
+	if err = json.Unmarshal(*response.Body, payload); err != nil {
+		return response, err
+	}
+
+	if !payload.OK {
+		return response, fmt.Errorf("cannot call telegram method [%s]: %s", method, payload.Description)
+	}
+
+	return response, nil
+}
+
+// apiError returns the description of the error in the body of the response
+func (client *Client) apiError(response *Response, err error) error {
+	if response == nil {
+		return err
+	}
+
+	payload := new(apiResponse)
+	if json.Unmarshal(*response.Body, payload) == nil && payload.Description != "" {
+		return fmt.Errorf("%d: %s", response.HTTPResponse.StatusCode, payload.Description)
+	}
+
+	return err
+}
+
+// do carries out an HTTP request and returns a Response
+func (client *Client) do(req *http.Request) (*Response, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%T cannot be nil", req)
+	}
+
+	httpResponse, err := client.httpClient.Do(req)
+
+	// the token of the bot is in the URL of the request so it is removed from the error
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return nil, fmt.Errorf("cannot %s telegram bot API: %w", urlErr.Op, urlErr.Err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = httpResponse.Body.Close() }()
+
+	resp, err := client.newResponse(httpResponse)
+	if err != nil {
+		return resp, err
+	}
+
+	_, err = io.Copy(io.Discard, httpResponse.Body)
+	if err != nil {
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// newResponse converts an *http.Response to *Response
+func (client *Client) newResponse(httpResponse *http.Response) (*Response, error) {
+	if httpResponse == nil {
+		return nil, fmt.Errorf("%T cannot be nil", httpResponse)
+	}
+
+	resp := new(Response)
+	resp.HTTPResponse = httpResponse
+
+	buf, err := io.ReadAll(resp.HTTPResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &buf
+
+	return resp, resp.Error()
+}
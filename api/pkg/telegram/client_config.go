@@ -0,0 +1,17 @@
+package telegram
+
+import (
+	"net/http"
+)
+
+type clientConfig struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+func defaultClientConfig() *clientConfig {
+	return &clientConfig{
+		httpClient: http.DefaultClient,
+		baseURL:    "https://api.telegram.org",
+	}
+}
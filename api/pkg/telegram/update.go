@@ -0,0 +1,59 @@
+package telegram
+
+// SecretTokenHeader is the header which contains the secret token of the webhook in the updates
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// apiResponse is the envelope of the responses of the bot API
+type apiResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	ErrorCode   int    `json:"error_code"`
+	Result      any    `json:"result"`
+}
+
+// Update is an incoming update of a bot, only the message updates are used
+//
+// API Docs: https://core.telegram.org/bots/api#update
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// Message is a message in a chat
+//
+// API Docs: https://core.telegram.org/bots/api#message
+type Message struct {
+	MessageID      int64    `json:"message_id"`
+	From           *User    `json:"from,omitempty"`
+	Chat           Chat     `json:"chat"`
+	Date           int64    `json:"date"`
+	Text           string   `json:"text"`
+	ReplyToMessage *Message `json:"reply_to_message,omitempty"`
+}
+
+// User is a telegram user or bot
+type User struct {
+	ID       int64  `json:"id"`
+	IsBot    bool   `json:"is_bot"`
+	Username string `json:"username,omitempty"`
+}
+
+// Chat is a private chat, group or channel
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// SendMessageRequest is the payload of BotService.SendMessage
+type SendMessageRequest struct {
+	ChatID           int64  `json:"chat_id"`
+	Text             string `json:"text"`
+	ReplyToMessageID int64  `json:"reply_to_message_id,omitempty"`
+}
+
+// SetWebhookRequest is the payload of BotService.SetWebhook
+type SetWebhookRequest struct {
+	URL            string   `json:"url"`
+	SecretToken    string   `json:"secret_token"`
+	AllowedUpdates []string `json:"allowed_updates"`
+}
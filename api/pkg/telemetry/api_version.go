@@ -0,0 +1,24 @@
+package telemetry
+
+import (
+	"context"
+)
+
+// APIVersionHeader is the HTTP response header with the version of the API which handled a request e.g. v1
+const APIVersionHeader = "API-Version"
+
+type apiVersionContextKey struct{}
+
+// WithAPIVersion stores the version of the API which handles a request in the context.Context
+func WithAPIVersion(ctx context.Context, version string) context.Context {
+	if version == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, apiVersionContextKey{}, version)
+}
+
+// APIVersion returns the version of the API in the context.Context or an empty string when the request is not versioned
+func APIVersion(ctx context.Context) string {
+	version, _ := ctx.Value(apiVersionContextKey{}).(string)
+	return version
+}
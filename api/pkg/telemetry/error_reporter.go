@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// ErrorReporterNone does not report errors
+	ErrorReporterNone = "none"
+
+	// ErrorReporterSentry reports errors to the project of the SENTRY_DSN
+	ErrorReporterSentry = "sentry"
+
+	// ErrorReporterGCP reports errors to GCP Error Reporting
+	ErrorReporterGCP = "gcp"
+)
+
+// ErrorReporter sends errors to an error tracking service so that failures are noticed when they happen
+type ErrorReporter interface {
+	// CaptureError reports an error without blocking the caller
+	CaptureError(ctx context.Context, report ErrorReport)
+}
+
+// ErrorReport is an error which is sent to an ErrorReporter
+type ErrorReport struct {
+	// Err is the error which is reported
+	Err error
+
+	// Tags are indexed by the error tracking service e.g. the trace_id
+	Tags map[string]string
+
+	// Fingerprint groups reports of the same failure together
+	Fingerprint []string
+}
+
+// Message is the first line of the root cause of the error
+func (report ErrorReport) Message() string {
+	return firstLine(stacktrace.RootCause(report.Err).Error())
+}
+
+// Class is the type of the root cause of the error e.g. *pgconn.PgError
+func (report ErrorReport) Class() string {
+	return fmt.Sprintf("%T", stacktrace.RootCause(report.Err))
+}
+
+// Stacktrace is the error with the file and line of every stacktrace.Propagate call
+func (report ErrorReport) Stacktrace() string {
+	return report.Err.Error()
+}
+
+// errorValuesPattern matches the values which are interpolated in error messages e.g. "message with ID [...]"
+var errorValuesPattern = regexp.MustCompile(`\[[^\]]*]`)
+
+// ErrorFingerprint groups errors by the message of the root cause and the signature of the code which failed e.g. the
+// listener of an event. Values in square brackets are removed from the message so that IDs do not split the group.
+func ErrorFingerprint(err error, signature string) []string {
+	message := firstLine(stacktrace.RootCause(err).Error())
+	return []string{signature, errorValuesPattern.ReplaceAllString(message, "[]")}
+}
+
+func firstLine(value string) string {
+	return strings.Split(value, "\n")[0]
+}
+
+type noopErrorReporter struct{}
+
+// NewNoopErrorReporter creates an ErrorReporter which drops the errors when error reporting is not configured
+func NewNoopErrorReporter() ErrorReporter {
+	return &noopErrorReporter{}
+}
+
+func (reporter *noopErrorReporter) CaptureError(_ context.Context, _ ErrorReport) {}
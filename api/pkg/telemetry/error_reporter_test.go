@@ -0,0 +1,135 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// capturingErrorReporter stores the reported errors
+type capturingErrorReporter struct {
+	mutex   sync.Mutex
+	reports []ErrorReport
+}
+
+func (reporter *capturingErrorReporter) CaptureError(_ context.Context, report ErrorReport) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.reports = append(reporter.reports, report)
+}
+
+func newNopLogger() Logger {
+	zl := zerolog.Nop()
+	return NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+}
+
+func TestErrorFingerprint(t *testing.T) {
+	t.Run("errors with different IDs in the message have the same fingerprint", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		first := stacktrace.Propagate(stacktrace.NewError("message with ID [32343a19-da5e-4b1b-a767-3298a73703cb] not found"), "cannot handle event")
+		second := stacktrace.Propagate(stacktrace.NewError("message with ID [8f9c71b8-b84e-4417-8408-a62274f65a08] not found"), "cannot handle event")
+
+		// Act
+		firstFingerprint := ErrorFingerprint(first, "message.phone.sent:MessageListener")
+		secondFingerprint := ErrorFingerprint(second, "message.phone.sent:MessageListener")
+
+		// Assert
+		assert.Equal(t, []string{"message.phone.sent:MessageListener", "message with ID [] not found"}, firstFingerprint)
+		assert.Equal(t, firstFingerprint, secondFingerprint)
+		assert.NotEqual(t, firstFingerprint, ErrorFingerprint(first, "message.phone.sent:WebhookListener"))
+	})
+}
+
+func TestOtelTracer_ReportError(t *testing.T) {
+	t.Run("error is reported with the trace and the fingerprint", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		reporter := &capturingErrorReporter{}
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+		tracer := NewOtelTracer("test", newNopLogger(), reporter)
+
+		// Arrange
+		ctx, span := provider.Tracer("test").Start(context.Background(), "EventDispatcher.Publish")
+		defer span.End()
+		err := stacktrace.PropagateWithCode(stacktrace.NewError("connection refused"), 2000, "cannot handle event")
+
+		// Act
+		tracer.ReportError(ctx, err, "message.phone.sent:MessageListener")
+		tracer.ReportError(ctx, nil, "message.phone.sent:MessageListener")
+
+		// Assert
+		assert.Equal(t, 1, len(reporter.reports))
+		report := reporter.reports[0]
+		assert.Equal(t, err, report.Err)
+		assert.Equal(t, "connection refused", report.Message())
+		assert.Equal(t, span.SpanContext().TraceID().String(), report.Tags[LogFieldTraceID])
+		assert.Equal(t, "message.phone.sent:MessageListener", report.Tags["signature"])
+		assert.Equal(t, "2000", report.Tags[string(AttributeErrorCode)])
+		assert.Equal(t, []string{"message.phone.sent:MessageListener", "connection refused"}, report.Fingerprint)
+	})
+}
+
+func TestSentryErrorReporter_CaptureError(t *testing.T) {
+	t.Run("error is sent to the store endpoint of the DSN", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		requests := make(chan *http.Request, 1)
+		events := make(chan map[string]interface{}, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			event := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&event)
+			requests <- r
+			events <- event
+		}))
+		defer server.Close()
+
+		reporter, err := NewSentryErrorReporter(newNopLogger(), server.Client(), strings.Replace(server.URL, "://", "://public@", 1)+"/42", "v1.0.0")
+		assert.Nil(t, err)
+
+		// Act
+		reporter.CaptureError(context.Background(), ErrorReport{
+			Err:         stacktrace.Propagate(stacktrace.NewError("connection refused"), "cannot handle event"),
+			Tags:        map[string]string{"signature": "message.phone.sent:MessageListener"},
+			Fingerprint: []string{"message.phone.sent:MessageListener", "connection refused"},
+		})
+
+		// Assert
+		select {
+		case request := <-requests:
+			event := <-events
+			assert.Equal(t, "/api/42/store/", request.URL.Path)
+			assert.Contains(t, request.Header.Get("X-Sentry-Auth"), "sentry_key=public")
+			assert.Equal(t, "connection refused", event["message"])
+			assert.Equal(t, []interface{}{"message.phone.sent:MessageListener", "connection refused"}, event["fingerprint"])
+			assert.Equal(t, "v1.0.0", event["release"])
+		case <-time.After(5 * time.Second):
+			t.Fatal("error was not sent to sentry")
+		}
+	})
+
+	t.Run("DSN without a project ID is invalid", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		_, err := NewSentryErrorReporter(newNopLogger(), http.DefaultClient, "https://public@o0.ingest.sentry.io/", "v1.0.0")
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
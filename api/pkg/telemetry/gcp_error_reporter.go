@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+// stacktraceLocationPattern matches the location which stacktrace adds to errors e.g. "--- at file.go:12 (Function) ---"
+var stacktraceLocationPattern = regexp.MustCompile(`--- at (.+):(\d+) \((.+)\) ---`)
+
+type gcpErrorReporter struct {
+	logger    Logger
+	client    *http.Client
+	projectID string
+	service   string
+	version   string
+}
+
+// NewGCPErrorReporter creates an ErrorReporter which sends errors to GCP Error Reporting. The client must be
+// authorized with the https://www.googleapis.com/auth/cloud-platform scope. GCP Error Reporting groups errors by the
+// location where they were created so the fingerprint is not sent.
+func NewGCPErrorReporter(logger Logger, client *http.Client, projectID string, service string, version string) ErrorReporter {
+	return &gcpErrorReporter{
+		logger:    logger.WithService(fmt.Sprintf("%T", &gcpErrorReporter{})),
+		client:    client,
+		projectID: projectID,
+		service:   service,
+		version:   version,
+	}
+}
+
+type gcpErrorEvent struct {
+	EventTime      string            `json:"eventTime"`
+	ServiceContext gcpServiceContext `json:"serviceContext"`
+	Message        string            `json:"message"`
+	Context        gcpErrorContext   `json:"context"`
+}
+
+type gcpServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+type gcpErrorContext struct {
+	ReportLocation *gcpReportLocation `json:"reportLocation,omitempty"`
+}
+
+type gcpReportLocation struct {
+	FilePath     string `json:"filePath"`
+	LineNumber   int    `json:"lineNumber"`
+	FunctionName string `json:"functionName"`
+}
+
+// CaptureError sends the error in the background so that an unreachable API does not slow down the caller
+func (reporter *gcpErrorReporter) CaptureError(_ context.Context, report ErrorReport) {
+	event := gcpErrorEvent{
+		EventTime:      time.Now().UTC().Format(time.RFC3339),
+		ServiceContext: gcpServiceContext{Service: reporter.service, Version: reporter.version},
+		Message:        report.Stacktrace(),
+		Context:        gcpErrorContext{ReportLocation: reportLocation(report.Err)},
+	}
+
+	go func() {
+		if err := reporter.send(event); err != nil {
+			reporter.logger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot report error [%s] to GCP error reporting", report.Message())))
+		}
+	}()
+}
+
+func (reporter *gcpErrorReporter) send(event gcpErrorEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot marshal [%T]", event))
+	}
+
+	endpoint := fmt.Sprintf("https://clouderrorreporting.googleapis.com/v1beta1/projects/%s/events:report", reporter.projectID)
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot create request to [%s]", endpoint))
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := reporter.client.Do(request)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot send error event to [%s]", endpoint))
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return stacktrace.NewError(fmt.Sprintf("GCP error reporting responded with status [%d]", response.StatusCode))
+	}
+	return nil
+}
+
+// reportLocation is the location of the root cause of the error. GCP Error Reporting requires the location because
+// the stacktrace of the error is not in the format of a go panic.
+func reportLocation(err error) *gcpReportLocation {
+	matches := stacktraceLocationPattern.FindAllStringSubmatch(err.Error(), -1)
+	if len(matches) == 0 {
+		return &gcpReportLocation{FunctionName: fmt.Sprintf("%T", stacktrace.RootCause(err))}
+	}
+
+	root := matches[len(matches)-1]
+	line, _ := strconv.Atoi(root[2])
+	return &gcpReportLocation{FilePath: root[1], LineNumber: line, FunctionName: root[3]}
+}
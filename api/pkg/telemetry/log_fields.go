@@ -0,0 +1,43 @@
+package telemetry
+
+// Keys of the structured log fields so that the same field has the same name in every log line
+const (
+	// LogFieldMessageID is the ID of an entities.Message
+	LogFieldMessageID = "message_id"
+
+	// LogFieldEventID is the ID of a cloud event
+	LogFieldEventID = "event_id"
+
+	// LogFieldEventType is the type of cloud event
+	LogFieldEventType = "event_type"
+
+	// LogFieldHandler is the name of the listener which handles a cloud event
+	LogFieldHandler = "handler"
+
+	// LogFieldOwner is the phone number which sends or receives a message
+	LogFieldOwner = "owner"
+
+	// LogFieldUserID is the ID of an entities.User
+	LogFieldUserID = "user_id"
+
+	// LogFieldStatus is the status of an entities.Message
+	LogFieldStatus = "status"
+
+	// LogFieldCount is the number of items which were fetched or updated
+	LogFieldCount = "count"
+
+	// LogFieldDuration is the time taken by an operation
+	LogFieldDuration = "duration"
+
+	// LogFieldTraceID is the ID of the trace of the span in the context logger
+	LogFieldTraceID = "trace_id"
+
+	// LogFieldSpanID is the ID of the span in the context logger
+	LogFieldSpanID = "span_id"
+
+	// LogFieldRequestID is the ID of the HTTP request which caused an operation, it is carried by cloud events
+	LogFieldRequestID = "request_id"
+
+	// LogFieldAPIVersion is the version of the API which handled the HTTP request e.g. v1
+	LogFieldAPIVersion = "api_version"
+)
@@ -15,6 +15,12 @@ type Logger interface {
 	// WithString creates a new structured logger instance with a string
 	WithString(key string, value string) Logger
 
+	// WithField creates a new structured logger instance with a field of any type e.g. a count or an ID
+	WithField(key string, value interface{}) Logger
+
+	// WithFields creates a new structured logger instance with multiple fields
+	WithFields(fields map[string]interface{}) Logger
+
 	// WithSpan creates a new structured logger instance for a spanContext
 	WithSpan(span trace.SpanContext) Logger
 
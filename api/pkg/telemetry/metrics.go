@@ -0,0 +1,259 @@
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	metricKindCounter   = "counter"
+	metricKindGauge     = "gauge"
+	metricKindHistogram = "histogram"
+)
+
+// MetricsContentType is the content type of the Prometheus text exposition format rendered by MetricsRegistry
+const MetricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsRegistry keeps the counters, gauges and histograms which are scraped by Prometheus
+type MetricsRegistry struct {
+	mutex    sync.Mutex
+	families map[string]*metricFamily
+}
+
+// NewMetricsRegistry creates an empty MetricsRegistry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{families: map[string]*metricFamily{}}
+}
+
+// Counter registers a counter, the existing counter is returned when the name is already registered
+func (registry *MetricsRegistry) Counter(name string, help string, labels ...string) *Counter {
+	return &Counter{family: registry.register(name, help, metricKindCounter, nil, labels)}
+}
+
+// Gauge registers a gauge, the existing gauge is returned when the name is already registered
+func (registry *MetricsRegistry) Gauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{family: registry.register(name, help, metricKindGauge, nil, labels)}
+}
+
+// Histogram registers a histogram with the upper bounds of its buckets in increasing order
+func (registry *MetricsRegistry) Histogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return &Histogram{family: registry.register(name, help, metricKindHistogram, buckets, labels)}
+}
+
+// WriteTo renders the metrics in the Prometheus text exposition format ordered by name
+func (registry *MetricsRegistry) WriteTo(w io.Writer) (int64, error) {
+	registry.mutex.Lock()
+	families := make([]*metricFamily, 0, len(registry.families))
+	for _, family := range registry.families {
+		families = append(families, family)
+	}
+	registry.mutex.Unlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].name < families[j].name
+	})
+
+	writer := &countingWriter{writer: bufio.NewWriter(w)}
+	for _, family := range families {
+		family.write(writer)
+	}
+
+	if err := writer.writer.Flush(); err != nil {
+		return writer.count, err
+	}
+	return writer.count, writer.err
+}
+
+func (registry *MetricsRegistry) register(name string, help string, kind string, buckets []float64, labels []string) *metricFamily {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if family, ok := registry.families[name]; ok && family.kind == kind {
+		return family
+	}
+
+	family := &metricFamily{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*metricSeries{},
+	}
+	registry.families[name] = family
+	return family
+}
+
+// Counter is a metric which only increases
+type Counter struct {
+	family *metricFamily
+}
+
+// Inc increases the counter with the label values by 1
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add increases the counter with the label values, negative values are ignored
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	if counter == nil || value < 0 {
+		return
+	}
+	counter.family.update(labelValues, func(series *metricSeries) {
+		series.value += value
+	})
+}
+
+// Gauge is a metric which can go up and down
+type Gauge struct {
+	family *metricFamily
+}
+
+// Set the value of the gauge with the label values
+func (gauge *Gauge) Set(value float64, labelValues ...string) {
+	if gauge == nil {
+		return
+	}
+	gauge.family.update(labelValues, func(series *metricSeries) {
+		series.value = value
+	})
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	family *metricFamily
+}
+
+// Observe adds a value to the histogram with the label values
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	if histogram == nil {
+		return
+	}
+	histogram.family.update(labelValues, func(series *metricSeries) {
+		if series.counts == nil {
+			series.counts = make([]uint64, len(histogram.family.buckets))
+		}
+		for index, bound := range histogram.family.buckets {
+			if value <= bound {
+				series.counts[index]++
+			}
+		}
+		series.sum += value
+		series.count++
+	})
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mutex  sync.Mutex
+	series map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// update changes the series of the label values, an update with the wrong number of label values is dropped
+func (family *metricFamily) update(labelValues []string, update func(series *metricSeries)) {
+	if len(labelValues) != len(family.labels) {
+		return
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	family.mutex.Lock()
+	defer family.mutex.Unlock()
+
+	series, ok := family.series[key]
+	if !ok {
+		series = &metricSeries{labelValues: append([]string(nil), labelValues...)}
+		family.series[key] = series
+	}
+	update(series)
+}
+
+func (family *metricFamily) write(writer *countingWriter) {
+	family.mutex.Lock()
+	defer family.mutex.Unlock()
+
+	writer.printf("# HELP %s %s\n", family.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(family.help))
+	writer.printf("# TYPE %s %s\n", family.name, family.kind)
+
+	keys := make([]string, 0, len(family.series))
+	for key := range family.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		series := family.series[key]
+		if family.kind != metricKindHistogram {
+			writer.printf("%s%s %s\n", family.name, family.labelPairs(series.labelValues, ""), formatFloat(series.value))
+			continue
+		}
+
+		for index, bound := range family.buckets {
+			writer.printf("%s_bucket%s %d\n", family.name, family.labelPairs(series.labelValues, formatFloat(bound)), series.counts[index])
+		}
+		writer.printf("%s_bucket%s %d\n", family.name, family.labelPairs(series.labelValues, "+Inf"), series.count)
+		writer.printf("%s_sum%s %s\n", family.name, family.labelPairs(series.labelValues, ""), formatFloat(series.sum))
+		writer.printf("%s_count%s %d\n", family.name, family.labelPairs(series.labelValues, ""), series.count)
+	}
+}
+
+// labelPairs renders the labels of a series, the le label of a histogram bucket is added when it is set
+func (family *metricFamily) labelPairs(labelValues []string, le string) string {
+	pairs := make([]string, 0, len(labelValues)+1)
+	for index, value := range labelValues {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, family.labels[index], labelValueReplacer.Replace(value)))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`le="%s"`, le))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelValueReplacer escapes a label value for the Prometheus text exposition format
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// countingWriter keeps the first error so that the metrics can be written without checking every write
+type countingWriter struct {
+	writer *bufio.Writer
+	count  int64
+	err    error
+}
+
+func (writer *countingWriter) printf(format string, args ...any) {
+	if writer.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(writer.writer, format, args...)
+	writer.count += int64(n)
+	writer.err = err
+}
@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsRegistry_WriteTo(t *testing.T) {
+	t.Run("metrics are rendered in the prometheus text format", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		registry := NewMetricsRegistry()
+
+		// Arrange
+		counter := registry.Counter("httpsms_messages_total", "Number of messages", "status", "direction")
+		gauge := registry.Gauge("httpsms_messages_queued", "Number of queued messages", "status")
+		histogram := registry.Histogram("httpsms_message_send_duration_seconds", "Send duration", []float64{1, 10}, "direction")
+
+		counter.Inc("sent", "outbound")
+		counter.Add(2, "sent", "outbound")
+		counter.Inc("received", "inbound")
+		gauge.Set(5, "pending")
+		histogram.Observe(0.5, "outbound")
+		histogram.Observe(5, "outbound")
+		histogram.Observe(50, "outbound")
+
+		buffer := new(bytes.Buffer)
+
+		// Act
+		_, err := registry.WriteTo(buffer)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, `# HELP httpsms_message_send_duration_seconds Send duration
+# TYPE httpsms_message_send_duration_seconds histogram
+httpsms_message_send_duration_seconds_bucket{direction="outbound",le="1"} 1
+httpsms_message_send_duration_seconds_bucket{direction="outbound",le="10"} 2
+httpsms_message_send_duration_seconds_bucket{direction="outbound",le="+Inf"} 3
+httpsms_message_send_duration_seconds_sum{direction="outbound"} 55.5
+httpsms_message_send_duration_seconds_count{direction="outbound"} 3
+# HELP httpsms_messages_queued Number of queued messages
+# TYPE httpsms_messages_queued gauge
+httpsms_messages_queued{status="pending"} 5
+# HELP httpsms_messages_total Number of messages
+# TYPE httpsms_messages_total counter
+httpsms_messages_total{status="received",direction="inbound"} 1
+httpsms_messages_total{status="sent",direction="outbound"} 3
+`, buffer.String())
+	})
+
+	t.Run("label values are escaped and invalid updates are dropped", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		registry := NewMetricsRegistry()
+
+		// Arrange
+		counter := registry.Counter("test_total", "Test counter", "route")
+		counter.Inc("/a\"b\\c\n")
+		counter.Inc("too", "many")
+		counter.Add(-1, "/a\"b\\c\n")
+
+		buffer := new(bytes.Buffer)
+
+		// Act
+		_, err := registry.WriteTo(buffer)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "# HELP test_total Test counter\n# TYPE test_total counter\ntest_total{route=\"/a\\\"b\\\\c\\n\"} 1\n", buffer.String())
+	})
+
+	t.Run("registering a metric twice returns the same metric", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		registry := NewMetricsRegistry()
+
+		// Arrange
+		registry.Counter("test_total", "Test counter").Inc()
+		registry.Counter("test_total", "Test counter").Inc()
+
+		buffer := new(bytes.Buffer)
+
+		// Act
+		_, err := registry.WriteTo(buffer)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Contains(t, buffer.String(), "test_total 2\n")
+	})
+}
@@ -2,10 +2,13 @@ package telemetry
 
 import (
 	"context"
+	"fmt"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/palantir/stacktrace"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
@@ -14,19 +17,26 @@ import (
 type otelTracer struct {
 	projectID string
 	logger    Logger
+	reporter  ErrorReporter
 }
 
-// NewOtelLogger creates a new Tracer
+// NewOtelLogger creates a new Tracer which does not report errors
 func NewOtelLogger(projectID string, logger Logger) Tracer {
+	return NewOtelTracer(projectID, logger, NewNoopErrorReporter())
+}
+
+// NewOtelTracer creates a new Tracer which sends errors to the ErrorReporter in ReportError
+func NewOtelTracer(projectID string, logger Logger, reporter ErrorReporter) Tracer {
 	return &otelTracer{
 		projectID: projectID,
 		logger:    logger,
+		reporter:  reporter,
 	}
 }
 
 func (tracer *otelTracer) StartFromFiberCtxWithLogger(c *fiber.Ctx, logger Logger, name ...string) (context.Context, trace.Span, Logger) {
 	ctx, span := tracer.StartFromFiberCtx(c, getName(name...))
-	return ctx, span, tracer.CtxLogger(logger, span)
+	return ctx, span, tracer.requestLogger(ctx, tracer.CtxLogger(logger, span))
 }
 
 func (tracer *otelTracer) StartFromFiberCtx(c *fiber.Ctx, name ...string) (context.Context, trace.Span) {
@@ -37,9 +47,20 @@ func (tracer *otelTracer) CtxLogger(logger Logger, span trace.Span) Logger {
 	return logger.WithSpan(span.SpanContext())
 }
 
+// requestLogger adds the ID of the request and the version of the API in the context.Context to the logger
+func (tracer *otelTracer) requestLogger(ctx context.Context, logger Logger) Logger {
+	if requestID := RequestID(ctx); requestID != "" {
+		logger = logger.WithField(LogFieldRequestID, requestID)
+	}
+	if version := APIVersion(ctx); version != "" {
+		logger = logger.WithField(LogFieldAPIVersion, version)
+	}
+	return logger
+}
+
 func (tracer *otelTracer) StartWithLogger(c context.Context, logger Logger, name ...string) (context.Context, trace.Span, Logger) {
 	ctx, span := tracer.Start(c, getName(name...))
-	return ctx, span, tracer.CtxLogger(logger, span)
+	return ctx, span, tracer.requestLogger(ctx, tracer.CtxLogger(logger, span))
 }
 
 func (tracer *otelTracer) Start(c context.Context, name ...string) (context.Context, trace.Span) {
@@ -65,10 +86,36 @@ func (tracer *otelTracer) WrapErrorSpan(span trace.Span, err error) error {
 
 	span.RecordError(err)
 	span.SetStatus(codes.Error, strings.Split(err.Error(), "\n")[0])
+	span.SetAttributes(ErrorAttributes(err)...)
 
 	return err
 }
 
+func (tracer *otelTracer) ReportError(ctx context.Context, err error, signature string) {
+	if err == nil {
+		return
+	}
+
+	spanContext := trace.SpanFromContext(ctx).SpanContext()
+	tags := map[string]string{
+		"signature":                 signature,
+		string(AttributeErrorClass): fmt.Sprintf("%T", stacktrace.RootCause(err)),
+	}
+	if spanContext.IsValid() {
+		tags[LogFieldTraceID] = spanContext.TraceID().String()
+		tags[LogFieldSpanID] = spanContext.SpanID().String()
+	}
+	if code := stacktrace.GetCode(err); code != stacktrace.NoCode {
+		tags[string(AttributeErrorCode)] = strconv.Itoa(int(code))
+	}
+
+	tracer.reporter.CaptureError(ctx, ErrorReport{
+		Err:         err,
+		Tags:        tags,
+		Fingerprint: ErrorFingerprint(err, signature),
+	})
+}
+
 func getName(name ...string) string {
 	if len(name) > 0 {
 		return name[0]
@@ -0,0 +1,94 @@
+package telemetry
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+	"unicode"
+)
+
+// phoneNumberVisibleDigits is the number of trailing digits of a phone number which are not masked
+const phoneNumberVisibleDigits = 4
+
+// redactionDisabled is set when message content and phone numbers are logged verbatim in local development
+var redactionDisabled int32
+
+// redactionKey keys the hash of redacted content so that the hash of a short content e.g. an OTP cannot be reversed by
+// hashing every possible value. The same content has the same hash until the process restarts.
+var redactionKey = newRedactionKey()
+
+func newRedactionKey() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}
+
+// SetRedaction enables or disables the redaction of message content and phone numbers in logs and error reports.
+// Redaction is enabled by default and it should only be disabled in local development.
+func SetRedaction(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&redactionDisabled, 0)
+		return
+	}
+	atomic.StoreInt32(&redactionDisabled, 1)
+}
+
+// RedactionEnabled is true when message content and phone numbers are redacted
+func RedactionEnabled() bool {
+	return atomic.LoadInt32(&redactionDisabled) == 0
+}
+
+// RedactContent replaces the content of a message with its length and a hash so that log lines of the same content
+// can be correlated without logging the content
+func RedactContent(content string) string {
+	if !RedactionEnabled() || content == "" {
+		return content
+	}
+
+	mac := hmac.New(sha256.New, redactionKey)
+	mac.Write([]byte(content))
+	return fmt.Sprintf("<redacted len=%d hash=%s>", len(content), hex.EncodeToString(mac.Sum(nil)[:4]))
+}
+
+// RedactPhoneNumber masks all the digits of a phone number except the last 4 digits e.g. +*******0199
+func RedactPhoneNumber(phoneNumber string) string {
+	if !RedactionEnabled() {
+		return phoneNumber
+	}
+
+	runes := []rune(phoneNumber)
+	visible := 0
+	for index := len(runes) - 1; index >= 0; index-- {
+		if !unicode.IsDigit(runes[index]) {
+			continue
+		}
+		if visible < phoneNumberVisibleDigits {
+			visible++
+			continue
+		}
+		runes[index] = '*'
+	}
+	return string(runes)
+}
+
+// RedactAPIKey replaces an API key with the first 8 characters of its SHA-256 hash so that the key can be found with
+// the prefix of the hash which is stored in the database. API keys are credentials so they are redacted even when the
+// redaction of message content and phone numbers is disabled.
+func RedactAPIKey(apiKey string) string {
+	return fmt.Sprintf("<api key sha256=%s>", hashPrefix(apiKey))
+}
+
+// RedactToken replaces a bearer token with the first 8 characters of its SHA-256 hash so that the failures of the same
+// token can be correlated in the logs without logging the credential
+func RedactToken(token string) string {
+	return fmt.Sprintf("<token sha256=%s>", hashPrefix(token))
+}
+
+// hashPrefix returns the first 8 characters of the hex encoded SHA-256 hash of a credential
+func hashPrefix(credential string) string {
+	hash := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(hash[:4])
+}
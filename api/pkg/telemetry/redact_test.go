@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactContent(t *testing.T) {
+	t.Run("content is replaced by its length and hash", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		redacted := RedactContent("Your OTP is 482910")
+
+		// Assert
+		assert.NotContains(t, redacted, "482910")
+		assert.True(t, strings.HasPrefix(redacted, "<redacted len=18 hash="))
+		assert.Equal(t, redacted, RedactContent("Your OTP is 482910"))
+		assert.NotEqual(t, redacted, RedactContent("Your OTP is 482911"))
+	})
+
+	t.Run("content is not redacted when redaction is disabled", func(t *testing.T) {
+		// Setup
+		// not parallel because the redaction setting is global
+
+		// Arrange
+		SetRedaction(false)
+		defer SetRedaction(true)
+
+		// Act
+		redacted := RedactContent("Your OTP is 482910")
+
+		// Assert
+		assert.Equal(t, "Your OTP is 482910", redacted)
+	})
+}
+
+func TestRedactPhoneNumber(t *testing.T) {
+	t.Run("all digits except the last 4 digits are masked", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		redacted := RedactPhoneNumber("+18005550199")
+
+		// Assert
+		assert.Equal(t, "+*******0199", redacted)
+		assert.Equal(t, "+* *** *** 0199", RedactPhoneNumber("+1 800 555 0199"))
+	})
+}
+
+func TestRedactAPIKey(t *testing.T) {
+	t.Run("the api key is replaced by the prefix of its hash even when redaction is disabled", func(t *testing.T) {
+		// Setup
+		// not parallel because the redaction setting is global
+
+		// Arrange
+		SetRedaction(false)
+		defer SetRedaction(true)
+		apiKey := "uk_Hf5lHn0ld7K9w2GxmD3rYtOb8cZpVqWs"
+
+		// Act
+		redacted := RedactAPIKey(apiKey)
+
+		// Assert
+		assert.NotContains(t, redacted, apiKey[3:])
+		assert.Equal(t, "<api key sha256=", redacted[:16])
+		assert.Equal(t, redacted, RedactAPIKey(apiKey))
+	})
+}
+
+func TestRedactToken(t *testing.T) {
+	t.Run("the token is replaced by the prefix of its hash", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		token := "eyJhbGciOiJSUzI1NiIsImtpZCI6IjFlOTczZWUwZTE2ZjdlZWY0ZjkyMWQ1MGRjNjFkNzBiMmVmZWZjMTkiLCJ0eXAiOiJKV1QifQ"
+
+		// Act
+		redacted := RedactToken(token)
+
+		// Assert
+		assert.NotContains(t, redacted, token[:16])
+		hash := sha256.Sum256([]byte(token))
+		assert.Equal(t, "<token sha256="+hex.EncodeToString(hash[:])[:8]+">", redacted)
+	})
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"context"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const (
+	// RequestIDHeader is the HTTP header which contains the ID of a request
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDExtension is the cloud event extension which carries the ID of the request which caused the event so
+	// that the ID survives the push queue
+	RequestIDExtension = "requestid"
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID stores the ID of a request in the context.Context
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestID returns the ID of the request in the context.Context or an empty string when there is no request ID
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// EventRequestID returns the ID of the request which caused the cloud event or an empty string when there is none
+func EventRequestID(event cloudevents.Event) string {
+	requestID, _ := event.Extensions()[RequestIDExtension].(string)
+	return requestID
+}
@@ -0,0 +1,126 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+type sentryErrorReporter struct {
+	logger   Logger
+	client   *http.Client
+	endpoint string
+	key      string
+	release  string
+}
+
+// NewSentryErrorReporter creates an ErrorReporter which sends errors to the store endpoint of the sentry project
+// in the dsn e.g. https://public@o0.ingest.sentry.io/0
+func NewSentryErrorReporter(logger Logger, client *http.Client, dsn string, release string) (ErrorReporter, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot parse the sentry DSN")
+	}
+
+	if parsed.User == nil || parsed.User.Username() == "" {
+		return nil, stacktrace.NewError("the sentry DSN does not contain a public key")
+	}
+
+	path := strings.Trim(parsed.Path, "/")
+	projectIndex := strings.LastIndex(path, "/")
+	projectID := path[projectIndex+1:]
+	if projectID == "" {
+		return nil, stacktrace.NewError("the sentry DSN does not contain a project ID")
+	}
+
+	prefix := ""
+	if projectIndex > 0 {
+		prefix = "/" + path[:projectIndex]
+	}
+
+	return &sentryErrorReporter{
+		logger:   logger.WithService(fmt.Sprintf("%T", &sentryErrorReporter{})),
+		client:   client,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", parsed.Scheme, parsed.Host, prefix, projectID),
+		key:      parsed.User.Username(),
+		release:  release,
+	}, nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Release     string            `json:"release,omitempty"`
+	Message     string            `json:"message"`
+	Exception   sentryExceptions  `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	Extra       map[string]string `json:"extra"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// CaptureError sends the error in the background so that an unreachable sentry does not slow down the caller
+func (reporter *sentryErrorReporter) CaptureError(_ context.Context, report ErrorReport) {
+	event := sentryEvent{
+		EventID:     strings.ReplaceAll(uuid.NewString(), "-", ""),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Level:       "error",
+		Platform:    "go",
+		Release:     reporter.release,
+		Message:     report.Message(),
+		Exception:   sentryExceptions{Values: []sentryException{{Type: report.Class(), Value: report.Message()}}},
+		Tags:        report.Tags,
+		Fingerprint: report.Fingerprint,
+		Extra:       map[string]string{"stacktrace": report.Stacktrace()},
+	}
+
+	go func() {
+		if err := reporter.send(event); err != nil {
+			reporter.logger.Warn(stacktrace.Propagate(err, fmt.Sprintf("cannot report error [%s] to sentry", event.Message)))
+		}
+	}()
+}
+
+func (reporter *sentryErrorReporter) send(event sentryEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot marshal [%T]", event))
+	}
+
+	request, err := http.NewRequest(http.MethodPost, reporter.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot create request to [%s]", reporter.endpoint))
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=httpsms/%s, sentry_key=%s", reporter.release, reporter.key))
+
+	response, err := reporter.client.Do(request)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot send event [%s] to [%s]", event.EventID, reporter.endpoint))
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return stacktrace.NewError(fmt.Sprintf("sentry responded with status [%d] for event [%s]", response.StatusCode, event.EventID))
+	}
+	return nil
+}
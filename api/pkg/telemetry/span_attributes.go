@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.18.0"
+)
+
+// Keys of the span attributes so that the same attribute has the same name on every span
+const (
+	// AttributeMessageID is the ID of an entities.Message
+	AttributeMessageID = attribute.Key("httpsms.message.id")
+
+	// AttributeOwnerHash is the hash of the phone number which sends or receives a message. The phone number is not
+	// added to spans because it is personal data, the hash of a known phone number can be computed with HashOwner.
+	AttributeOwnerHash = attribute.Key("httpsms.message.owner_hash")
+
+	// AttributeMessageStatus is the status of an entities.Message after it is updated
+	AttributeMessageStatus = attribute.Key("httpsms.message.status")
+
+	// AttributeMessagePreviousStatus is the status of an entities.Message before it is updated
+	AttributeMessagePreviousStatus = attribute.Key("httpsms.message.previous_status")
+
+	// AttributeErrorClass is the type of the root cause of an error e.g. *pgconn.PgError
+	AttributeErrorClass = attribute.Key("error.class")
+
+	// AttributeErrorCode is the stacktrace.ErrorCode of an error
+	AttributeErrorCode = attribute.Key("error.code")
+)
+
+// HashOwner hashes a phone number so that spans of the same owner can be found without storing the phone number
+func HashOwner(owner string) string {
+	hash := sha256.Sum256([]byte(owner))
+	return hex.EncodeToString(hash[:8])
+}
+
+// MessageAttributes are the attributes which identify a message
+func MessageAttributes(messageID uuid.UUID, owner string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{AttributeMessageID.String(messageID.String())}
+	if owner != "" {
+		attributes = append(attributes, AttributeOwnerHash.String(HashOwner(owner)))
+	}
+	return attributes
+}
+
+// StatusTransitionAttributes are the attributes of a message which changed status
+func StatusTransitionAttributes(previous string, current string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttributeMessagePreviousStatus.String(previous),
+		AttributeMessageStatus.String(current),
+	}
+}
+
+// EventAttributes are the attributes which identify a cloud event
+func EventAttributes(event cloudevents.Event) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.CloudeventsEventID(event.ID()),
+		semconv.CloudeventsEventType(event.Type()),
+		semconv.CloudeventsEventSource(event.Source()),
+	}
+}
+
+// ErrorAttributes are the attributes which classify an error
+func ErrorAttributes(err error) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{AttributeErrorClass.String(fmt.Sprintf("%T", stacktrace.RootCause(err)))}
+	if code := stacktrace.GetCode(err); code != stacktrace.NoCode {
+		attributes = append(attributes, AttributeErrorCode.Int(int(code)))
+	}
+	return attributes
+}
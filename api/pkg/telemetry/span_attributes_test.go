@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := map[attribute.Key]attribute.Value{}
+	for _, item := range span.Attributes() {
+		attributes[item.Key] = item.Value
+	}
+	return attributes
+}
+
+func TestOtelTracer_WrapErrorSpan(t *testing.T) {
+	t.Run("class and code of the error are added to the span", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		zl := zerolog.Nop()
+		tracer := NewOtelLogger("test", NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil))
+
+		// Arrange
+		_, span := provider.Tracer("test").Start(context.Background(), "MessageService.GetOutstanding")
+		err := stacktrace.PropagateWithCode(context.DeadlineExceeded, stacktrace.ErrorCode(1000), "cannot fetch outstanding message")
+
+		// Act
+		_ = tracer.WrapErrorSpan(span, err)
+		span.End()
+
+		// Assert
+		attributes := spanAttributes(recorder.Ended()[0])
+		assert.Equal(t, "context.deadlineExceededError", attributes[AttributeErrorClass].AsString())
+		assert.Equal(t, int64(1000), attributes[AttributeErrorCode].AsInt64())
+	})
+}
+
+func TestMessageAttributes(t *testing.T) {
+	t.Run("owner is hashed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		attributes := MessageAttributes([16]byte{1}, "+18005550199")
+
+		// Assert
+		assert.Equal(t, AttributeMessageID.String("01000000-0000-0000-0000-000000000000"), attributes[0])
+		assert.Equal(t, AttributeOwnerHash.String(HashOwner("+18005550199")), attributes[1])
+		assert.NotContains(t, attributes[1].Value.AsString(), "18005550199")
+		assert.Equal(t, HashOwner("+18005550199"), HashOwner("+18005550199"))
+		assert.NotEqual(t, HashOwner("+18005550199"), HashOwner("+18005550100"))
+	})
+}
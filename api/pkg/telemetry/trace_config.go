@@ -0,0 +1,217 @@
+package telemetry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceSamplerAlways samples every span
+	TraceSamplerAlways = "always"
+
+	// TraceSamplerNever drops every span
+	TraceSamplerNever = "never"
+
+	// TraceSamplerRatio samples a ratio of the traces
+	TraceSamplerRatio = "ratio"
+
+	// TraceSamplerParent samples a ratio of the root spans, child spans follow the decision of their parent
+	TraceSamplerParent = "parent"
+)
+
+const (
+	// TraceExporterUptrace exports spans to uptrace using the UPTRACE_DSN
+	TraceExporterUptrace = "uptrace"
+
+	// TraceExporterOTLP exports spans to the OTEL_EXPORTER_OTLP_ENDPOINT with gRPC
+	TraceExporterOTLP = "otlp"
+
+	// TraceExporterStdout writes spans to stdout for local development
+	TraceExporterStdout = "stdout"
+
+	// TraceExporterNone does not export spans
+	TraceExporterNone = "none"
+)
+
+// TraceConfig configures how spans are sampled and exported
+type TraceConfig struct {
+	// Sampler is one of TraceSamplerAlways, TraceSamplerNever, TraceSamplerRatio or TraceSamplerParent
+	Sampler string
+
+	// Ratio of the traces which are sampled by TraceSamplerRatio and TraceSamplerParent
+	Ratio float64
+
+	// RouteRatios overrides the Ratio for spans of a route e.g. "/v1/heartbeats" so that noisy endpoints can be
+	// sampled lower. The route is matched against the span name and the http.route attribute.
+	RouteRatios map[string]float64
+
+	// ExportErrors exports spans which end with an error status even when they are not sampled
+	ExportErrors bool
+
+	// Exporter is one of TraceExporterUptrace, TraceExporterOTLP, TraceExporterStdout or TraceExporterNone
+	Exporter string
+}
+
+// Validate checks that the sampler, the ratios and the exporter are supported
+func (config TraceConfig) Validate() error {
+	switch config.Sampler {
+	case TraceSamplerAlways, TraceSamplerNever, TraceSamplerRatio, TraceSamplerParent:
+	default:
+		return stacktrace.NewError(fmt.Sprintf("trace sampler [%s] is not supported", config.Sampler))
+	}
+
+	if config.Ratio < 0 || config.Ratio > 1 {
+		return stacktrace.NewError(fmt.Sprintf("trace sampler ratio [%f] is not between 0 and 1", config.Ratio))
+	}
+
+	for route, ratio := range config.RouteRatios {
+		if ratio < 0 || ratio > 1 {
+			return stacktrace.NewError(fmt.Sprintf("trace sampler ratio [%f] for route [%s] is not between 0 and 1", ratio, route))
+		}
+	}
+
+	switch config.Exporter {
+	case TraceExporterUptrace, TraceExporterOTLP, TraceExporterStdout, TraceExporterNone:
+	default:
+		return stacktrace.NewError(fmt.Sprintf("trace exporter [%s] is not supported", config.Exporter))
+	}
+
+	return nil
+}
+
+// NewSampler creates the sdktrace.Sampler of the config
+func (config TraceConfig) NewSampler() sdktrace.Sampler {
+	routes := make(map[string]sdktrace.Sampler, len(config.RouteRatios))
+	for route, ratio := range config.RouteRatios {
+		routes[route] = sdktrace.TraceIDRatioBased(ratio)
+	}
+
+	sampler := &routeSampler{
+		fallback:      config.rootSampler(),
+		routes:        routes,
+		recordDropped: config.ExportErrors,
+	}
+
+	if config.Sampler != TraceSamplerParent {
+		return sampler
+	}
+
+	notSampled := sdktrace.NeverSample()
+	if config.ExportErrors {
+		notSampled = &routeSampler{fallback: sdktrace.NeverSample(), recordDropped: true}
+	}
+
+	return sdktrace.ParentBased(
+		sampler,
+		sdktrace.WithLocalParentNotSampled(notSampled),
+		sdktrace.WithRemoteParentNotSampled(notSampled),
+	)
+}
+
+// NewSpanProcessor wraps the sdktrace.SpanProcessor which exports spans so that spans with an error are exported
+// when ExportErrors is set
+func (config TraceConfig) NewSpanProcessor(processor sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+	if !config.ExportErrors {
+		return processor
+	}
+	return &errorSpanProcessor{SpanProcessor: processor}
+}
+
+func (config TraceConfig) rootSampler() sdktrace.Sampler {
+	switch config.Sampler {
+	case TraceSamplerNever:
+		return sdktrace.NeverSample()
+	case TraceSamplerRatio, TraceSamplerParent:
+		return sdktrace.TraceIDRatioBased(config.Ratio)
+	default:
+		return sdktrace.AlwaysSample()
+	}
+}
+
+// ParseRouteRatios parses the ratios of routes in the format "/v1/heartbeats=0.01,/v1/messages/outstanding=0.1"
+func ParseRouteRatios(value string) (map[string]float64, error) {
+	ratios := map[string]float64{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		route, ratio, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, stacktrace.NewError(fmt.Sprintf("route ratio [%s] is not in the format route=ratio", pair))
+		}
+
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(ratio), 64)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot parse ratio [%s] of route [%s]", ratio, route))
+		}
+		ratios[strings.TrimSpace(route)] = parsed
+	}
+	return ratios, nil
+}
+
+// routeSampler samples spans with the sampler of their route. Dropped spans are recorded without being sampled when
+// recordDropped is set so that the errorSpanProcessor can export them if they fail.
+type routeSampler struct {
+	fallback      sdktrace.Sampler
+	routes        map[string]sdktrace.Sampler
+	recordDropped bool
+}
+
+func (sampler *routeSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := sampler.route(parameters).ShouldSample(parameters)
+	if result.Decision == sdktrace.Drop && sampler.recordDropped {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (sampler *routeSampler) Description() string {
+	return fmt.Sprintf("RouteSampler{%s,routes:%d}", sampler.fallback.Description(), len(sampler.routes))
+}
+
+func (sampler *routeSampler) route(parameters sdktrace.SamplingParameters) sdktrace.Sampler {
+	if route, ok := sampler.routes[parameters.Name]; ok {
+		return route
+	}
+
+	for _, attribute := range parameters.Attributes {
+		if attribute.Key != semconv.HTTPRouteKey {
+			continue
+		}
+		if route, ok := sampler.routes[attribute.Value.AsString()]; ok {
+			return route
+		}
+	}
+
+	return sampler.fallback
+}
+
+// errorSpanProcessor passes recorded spans which ended with an error to the exporter as sampled spans
+type errorSpanProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+func (processor *errorSpanProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	if !span.SpanContext().IsSampled() && span.Status().Code == codes.Error {
+		span = &sampledSpan{ReadOnlySpan: span}
+	}
+	processor.SpanProcessor.OnEnd(span)
+}
+
+// sampledSpan marks a sdktrace.ReadOnlySpan as sampled because the span processors only export sampled spans
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (span *sampledSpan) SpanContext() trace.SpanContext {
+	spanContext := span.ReadOnlySpan.SpanContext()
+	return spanContext.WithTraceFlags(spanContext.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,147 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracerProvider(t *testing.T, config TraceConfig) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(config.NewSampler()),
+		sdktrace.WithSpanProcessor(config.NewSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter))),
+	)
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+	return provider, exporter
+}
+
+func startSpans(provider *sdktrace.TracerProvider, name string, count int) {
+	for i := 0; i < count; i++ {
+		_, span := provider.Tracer("test").Start(context.Background(), name)
+		span.End()
+	}
+}
+
+func TestTraceConfig_NewSampler(t *testing.T) {
+	t.Run("ratio sampler drops spans", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider, exporter := newTestTracerProvider(t, TraceConfig{Sampler: TraceSamplerRatio, Ratio: 0.1})
+
+		// Act
+		startSpans(provider, "/v1/heartbeats", 1000)
+
+		// Assert
+		assert.Greater(t, len(exporter.GetSpans()), 0)
+		assert.Less(t, len(exporter.GetSpans()), 250)
+	})
+
+	t.Run("never sampler drops every span and always sampler keeps every span", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		neverProvider, neverExporter := newTestTracerProvider(t, TraceConfig{Sampler: TraceSamplerNever})
+		alwaysProvider, alwaysExporter := newTestTracerProvider(t, TraceConfig{Sampler: TraceSamplerAlways})
+
+		// Act
+		startSpans(neverProvider, "/v1/messages/send", 10)
+		startSpans(alwaysProvider, "/v1/messages/send", 10)
+
+		// Assert
+		assert.Equal(t, 0, len(neverExporter.GetSpans()))
+		assert.Equal(t, 10, len(alwaysExporter.GetSpans()))
+	})
+
+	t.Run("route ratios override the ratio of the sampler", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider, exporter := newTestTracerProvider(t, TraceConfig{
+			Sampler:     TraceSamplerRatio,
+			Ratio:       0,
+			RouteRatios: map[string]float64{"/v1/messages/send": 1},
+		})
+
+		// Act
+		startSpans(provider, "/v1/heartbeats", 10)
+		startSpans(provider, "/v1/messages/send", 10)
+
+		// Assert
+		assert.Equal(t, 10, len(exporter.GetSpans()))
+		for _, span := range exporter.GetSpans() {
+			assert.Equal(t, "/v1/messages/send", span.Name)
+		}
+	})
+
+	t.Run("child spans follow the decision of their parent with the parent sampler", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider, exporter := newTestTracerProvider(t, TraceConfig{Sampler: TraceSamplerParent, Ratio: 0})
+		parent := trace.ContextWithRemoteSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+			TraceFlags: trace.FlagsSampled,
+			Remote:     true,
+		}))
+
+		// Act
+		_, span := provider.Tracer("test").Start(parent, "/v1/messages/outstanding")
+		span.End()
+		startSpans(provider, "/v1/messages/outstanding", 10)
+
+		// Assert
+		assert.Equal(t, 1, len(exporter.GetSpans()))
+	})
+
+	t.Run("spans with an error are exported when they are not sampled", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		provider, exporter := newTestTracerProvider(t, TraceConfig{Sampler: TraceSamplerParent, Ratio: 0, ExportErrors: true})
+
+		// Arrange
+		ctx, root := provider.Tracer("test").Start(context.Background(), "/v1/messages/outstanding")
+		_, child := provider.Tracer("test").Start(ctx, "MessageService.GetOutstanding")
+
+		// Act
+		child.SetStatus(codes.Error, "cannot fetch outstanding message")
+		child.End()
+		root.End()
+		startSpans(provider, "/v1/heartbeats", 10)
+
+		// Assert
+		assert.Equal(t, 1, len(exporter.GetSpans()))
+		assert.Equal(t, "MessageService.GetOutstanding", exporter.GetSpans()[0].Name)
+		assert.True(t, exporter.GetSpans()[0].SpanContext.IsSampled())
+	})
+}
+
+func TestParseRouteRatios(t *testing.T) {
+	t.Run("route ratios are parsed", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		ratios, err := ParseRouteRatios("/v1/heartbeats=0.01, /v1/messages/send=1,")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]float64{"/v1/heartbeats": 0.01, "/v1/messages/send": 1}, ratios)
+	})
+
+	t.Run("invalid route ratio returns an error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		_, err := ParseRouteRatios("/v1/heartbeats")
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
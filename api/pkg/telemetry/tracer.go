@@ -29,9 +29,13 @@ type Tracer interface {
 	// CtxLogger creates a telemetry.Logger with spanContext attributes in the structured logger
 	CtxLogger(logger Logger, span trace.Span) Logger
 
-	// WrapErrorSpan sets a spanContext as error
+	// WrapErrorSpan sets a spanContext as error with the class and code of the error as attributes
 	WrapErrorSpan(span trace.Span, err error) error
 
+	// ReportError sends an error which is not propagated any further to the ErrorReporter. The signature identifies
+	// the code which failed e.g. the listener of an event and it is used to group reports of the same failure.
+	ReportError(ctx context.Context, err error, signature string)
+
 	// Span returns the trace.Span from context.Context
 	Span(ctx context.Context) trace.Span
 }
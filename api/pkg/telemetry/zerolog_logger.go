@@ -12,32 +12,34 @@ import (
 type zerologLogger struct {
 	zerolog     *zerodriver.Logger
 	spanContext *trace.SpanContext
-	fields      map[string]string
+	fields      map[string]interface{}
 	projectID   string
 	level       zerolog.Level
 }
 
 // NewZerologLogger creates a new instance of the zerolog logger
 func NewZerologLogger(projectID string, fields map[string]string, driver *zerodriver.Logger, span *trace.SpanContext) Logger {
-	logger := &zerologLogger{
+	values := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		values[key] = value
+	}
+
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	return newZerologLogger(projectID, values, driver, span)
+}
+
+func newZerologLogger(projectID string, fields map[string]interface{}, driver *zerodriver.Logger, span *trace.SpanContext) *zerologLogger {
+	return &zerologLogger{
 		zerolog:     driver,
 		fields:      fields,
 		projectID:   projectID,
 		spanContext: span,
 	}
-
-	zerolog.SetGlobalLevel(zerolog.TraceLevel)
-	return logger
 }
 
 // WithService creates a new structured zerolog logger instance with a service name
 func (logger *zerologLogger) WithService(service string) Logger {
-	return NewZerologLogger(
-		logger.projectID,
-		logger.addField(string(semconv.ServiceNameKey), service),
-		logger.zerolog,
-		logger.spanContext,
-	)
+	return logger.WithField(string(semconv.ServiceNameKey), service)
 }
 
 func (logger *zerologLogger) Printf(s string, i ...interface{}) {
@@ -46,12 +48,17 @@ func (logger *zerologLogger) Printf(s string, i ...interface{}) {
 
 // WithString creates a new structured zerolog logger instance with a key value pair
 func (logger *zerologLogger) WithString(key string, value string) Logger {
-	return NewZerologLogger(
-		logger.projectID,
-		logger.addField(key, value),
-		logger.zerolog,
-		logger.spanContext,
-	)
+	return logger.WithField(key, value)
+}
+
+// WithField creates a new structured zerolog logger instance with a field of any type
+func (logger *zerologLogger) WithField(key string, value interface{}) Logger {
+	return logger.WithFields(map[string]interface{}{key: value})
+}
+
+// WithFields creates a new structured zerolog logger instance with multiple fields
+func (logger *zerologLogger) WithFields(fields map[string]interface{}) Logger {
+	return newZerologLogger(logger.projectID, logger.addFields(fields), logger.zerolog, logger.spanContext)
 }
 
 // Info logs a new message with information level.
@@ -84,31 +91,31 @@ func (logger *zerologLogger) Error(err error) {
 	logger.decorateEvent(logger.zerolog.Error()).Err(err).Send()
 }
 
-// WithSpan adds a spanContext to a logger
+// WithSpan adds a spanContext to a logger, the trace and span IDs are also added as fields so that the log lines of a
+// request can be queried without the trace context of the log aggregator
 func (logger *zerologLogger) WithSpan(spanContext trace.SpanContext) Logger {
-	return NewZerologLogger(
-		logger.projectID,
-		logger.fields,
-		logger.zerolog,
-		&spanContext,
-	)
+	fields := logger.addFields(map[string]interface{}{
+		LogFieldTraceID: spanContext.TraceID().String(),
+		LogFieldSpanID:  spanContext.SpanID().String(),
+	})
+	return newZerologLogger(logger.projectID, fields, logger.zerolog, &spanContext)
 }
 
 func (logger *zerologLogger) decorateEvent(event *zerodriver.Event) *zerolog.Event {
 	if logger.spanContext != nil {
 		event.TraceContext(logger.spanContext.TraceID().String(), logger.spanContext.SpanID().String(), logger.spanContext.IsSampled(), logger.projectID)
 	}
-	for key, value := range logger.fields {
-		event.Str(key, value)
-	}
-	return event.Event
+	return event.Fields(logger.fields)
 }
 
-func (logger *zerologLogger) addField(key string, value string) map[string]string {
-	fields := map[string]string{}
-	for oldKey, oldValue := range logger.fields {
-		fields[oldKey] = oldValue
+// addFields copies the fields of the logger so that the fields of a parent logger are not changed by its children
+func (logger *zerologLogger) addFields(values map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(logger.fields)+len(values))
+	for key, value := range logger.fields {
+		fields[key] = value
+	}
+	for key, value := range values {
+		fields[key] = value
 	}
-	fields[key] = value
 	return fields
 }
@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestZerologLogger(buffer *bytes.Buffer) Logger {
+	zl := zerolog.New(buffer)
+	return NewZerologLogger("test", map[string]string{"pid": "1"}, &zerodriver.Logger{Logger: &zl}, nil)
+}
+
+func decodeLogLine(t *testing.T, buffer *bytes.Buffer) map[string]interface{} {
+	line := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &line))
+	return line
+}
+
+func TestZerologLogger_WithFields(t *testing.T) {
+	t.Run("fields are logged with their types", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		buffer := new(bytes.Buffer)
+
+		// Arrange
+		logger := newTestZerologLogger(buffer).
+			WithField(LogFieldCount, 12).
+			WithFields(map[string]interface{}{LogFieldOwner: "+18005550199", LogFieldMessageID: "32343a19-da5e-4b1b-a767-3298a73703cb"})
+
+		// Act
+		logger.Info("fetched outstanding messages")
+
+		// Assert
+		line := decodeLogLine(t, buffer)
+		assert.Equal(t, "fetched outstanding messages", line["message"])
+		assert.Equal(t, float64(12), line[LogFieldCount])
+		assert.Equal(t, "+18005550199", line[LogFieldOwner])
+		assert.Equal(t, "32343a19-da5e-4b1b-a767-3298a73703cb", line[LogFieldMessageID])
+		assert.Equal(t, "1", line["pid"])
+	})
+
+	t.Run("fields of a child logger are not added to its parent", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		buffer := new(bytes.Buffer)
+
+		// Arrange
+		logger := newTestZerologLogger(buffer)
+		logger.WithString(LogFieldEventID, "e6b2b1c2-6c6d-4b0a-9d4b-3c1c5c1e8f00")
+
+		// Act
+		logger.Info("dispatched event")
+
+		// Assert
+		line := decodeLogLine(t, buffer)
+		assert.NotContains(t, line, LogFieldEventID)
+	})
+
+	t.Run("trace and span IDs are logged as fields", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		buffer := new(bytes.Buffer)
+
+		// Arrange
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+			SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		})
+		logger := newTestZerologLogger(buffer).WithSpan(spanContext)
+
+		// Act
+		logger.Info("message saved")
+
+		// Assert
+		line := decodeLogLine(t, buffer)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line[LogFieldTraceID])
+		assert.Equal(t, "00f067aa0ba902b7", line[LogFieldSpanID])
+	})
+}
@@ -0,0 +1,72 @@
+package twilio
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// ErrorCodeInvalidParameter is returned when the request cannot be parsed
+	ErrorCodeInvalidParameter = 20001
+	// ErrorCodeAuthenticate is returned when the account SID and auth token are not valid
+	ErrorCodeAuthenticate = 20003
+	// ErrorCodeAccountNotActive is returned when the subscription of the user does not allow sending messages
+	ErrorCodeAccountNotActive = 20005
+	// ErrorCodeTooManyRequests is returned when the request exceeds a rate limit or the daily message limit
+	ErrorCodeTooManyRequests = 20429
+	// ErrorCodeInternal is returned when an unexpected error happens while handling the request
+	ErrorCodeInternal = 20500
+	// ErrorCodeInvalidTo is returned when the To parameter is not a valid phone number
+	ErrorCodeInvalidTo = 21211
+	// ErrorCodeInvalidFrom is returned when the From parameter is not a phone of the user
+	ErrorCodeInvalidFrom = 21212
+	// ErrorCodeBodyRequired is returned when the Body parameter is empty or too long
+	ErrorCodeBodyRequired = 21602
+	// ErrorCodeInvalidStatusCallback is returned when the StatusCallback parameter is not a valid URL
+	ErrorCodeInvalidStatusCallback = 21609
+)
+
+// Error is an error response of the Twilio REST API
+type Error struct {
+	Code     int    `json:"code" example:"21211"`
+	Message  string `json:"message" example:"The 'To' number 123 is not a valid phone number."`
+	MoreInfo string `json:"more_info" example:"https://www.twilio.com/docs/errors/21211"`
+	Status   int    `json:"status" example:"400"`
+}
+
+// NewError creates an Error with the HTTP status code and the Twilio error code
+func NewError(status int, code int, message string) Error {
+	return Error{
+		Code:     code,
+		Message:  message,
+		MoreInfo: fmt.Sprintf("https://www.twilio.com/docs/errors/%d", code),
+		Status:   status,
+	}
+}
+
+// validationErrorCodes are the Twilio error codes of the fields of a message which is sent
+var validationErrorCodes = []struct {
+	field string
+	code  int
+}{
+	{field: "to", code: ErrorCodeInvalidTo},
+	{field: "from", code: ErrorCodeInvalidFrom},
+	{field: "content", code: ErrorCodeBodyRequired},
+	{field: "status_callback", code: ErrorCodeInvalidStatusCallback},
+}
+
+// NewValidationError translates the validation errors of a message which is sent into the Error of the first invalid field
+func NewValidationError(errors url.Values) Error {
+	for _, item := range validationErrorCodes {
+		if message := errors.Get(item.field); message != "" {
+			return NewError(http.StatusBadRequest, item.code, message)
+		}
+	}
+
+	for _, messages := range errors {
+		return NewError(http.StatusBadRequest, ErrorCodeInvalidParameter, messages[0])
+	}
+
+	return NewError(http.StatusBadRequest, ErrorCodeInvalidParameter, "The request isn't properly formed")
+}
@@ -0,0 +1,25 @@
+package twilio
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewError(t *testing.T) {
+	t.Run("validation errors are translated into the error code of the field", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		content, err := json.Marshal(NewValidationError(map[string][]string{
+			"to":      {"The 'To' number 123 is not a valid phone number."},
+			"content": {"The content field is required"},
+		}))
+
+		// Assert
+		assert.Nil(t, err)
+		assert.JSONEq(t, fixture(t, "error_invalid_to.json"), string(content))
+	})
+}
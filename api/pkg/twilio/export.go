@@ -0,0 +1,425 @@
+package twilio
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/palantir/stacktrace"
+)
+
+// ExportFormat is the format of a file of messages which was exported from Twilio
+type ExportFormat string
+
+const (
+	// ExportFormatCSV is the CSV file which is downloaded from the message logs of the Twilio console
+	ExportFormatCSV = ExportFormat("csv")
+	// ExportFormatJSON is the response of the list messages endpoint of the Twilio REST API
+	ExportFormatJSON = ExportFormat("json")
+)
+
+// exportDateLayouts are the formats of the dates in the exports, the REST API uses RFC 2822 and the console uses ISO 8601
+var exportDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+}
+
+// importNamespace is the namespace of the IDs of the imported messages
+var importNamespace = uuid.MustParse("6f1d8c3e-2b4a-4f7e-9a51-0c7d3e8b2f64")
+
+// ExportRecord is a message in a file which was exported from Twilio
+type ExportRecord struct {
+	// Row is the position of the message in the export starting from 1, it does not include the header of a CSV file
+	Row          int
+	SID          string
+	From         string
+	To           string
+	Body         string
+	Status       Status
+	Direction    Direction
+	DateSent     string
+	DateCreated  string
+	NumSegments  string
+	ErrorCode    string
+	ErrorMessage string
+	Price        string
+	PriceUnit    string
+}
+
+// RowError is a row of an export which cannot be imported, the next row of the export can still be read
+type RowError struct {
+	Row    int
+	SID    string
+	Reason string
+}
+
+// Error returns the reason why the row cannot be imported
+func (err *RowError) Error() string {
+	return fmt.Sprintf("cannot import row [%d] with sid [%s]: %s", err.Row, err.SID, err.Reason)
+}
+
+// AsRowError checks if the cause of an error is a *RowError
+func AsRowError(err error) (*RowError, bool) {
+	var rowErr *RowError
+	if errors.As(stacktrace.RootCause(err), &rowErr) {
+		return rowErr, true
+	}
+	return nil, false
+}
+
+// ExportReader reads the messages of an export one at a time so that the whole file is never in memory
+type ExportReader interface {
+	// Next returns the next message of the export, the error is io.EOF when there are no more messages and a *RowError
+	// when the message cannot be parsed
+	Next() (*ExportRecord, error)
+}
+
+// NewExportReader creates an ExportReader for an export in the format
+func NewExportReader(format ExportFormat, reader io.Reader) (ExportReader, error) {
+	switch format {
+	case ExportFormatCSV:
+		return NewCSVExportReader(reader)
+	case ExportFormatJSON:
+		return NewJSONExportReader(reader)
+	default:
+		return nil, stacktrace.NewError(fmt.Sprintf("the export format [%s] is not supported", format))
+	}
+}
+
+// csvExportColumns are the columns of the CSV export which are read, the headers are matched without their case,
+// spaces and punctuation so that "Sent Date", "SentDate" and "date_sent" are the same column
+var csvExportColumns = map[string]func(record *ExportRecord, value string){
+	"sid":          func(record *ExportRecord, value string) { record.SID = value },
+	"messagesid":   func(record *ExportRecord, value string) { record.SID = value },
+	"from":         func(record *ExportRecord, value string) { record.From = value },
+	"to":           func(record *ExportRecord, value string) { record.To = value },
+	"body":         func(record *ExportRecord, value string) { record.Body = value },
+	"status":       func(record *ExportRecord, value string) { record.Status = Status(strings.ToLower(value)) },
+	"direction":    func(record *ExportRecord, value string) { record.Direction = Direction(strings.ToLower(value)) },
+	"sentdate":     func(record *ExportRecord, value string) { record.DateSent = value },
+	"datesent":     func(record *ExportRecord, value string) { record.DateSent = value },
+	"datecreated":  func(record *ExportRecord, value string) { record.DateCreated = value },
+	"createddate":  func(record *ExportRecord, value string) { record.DateCreated = value },
+	"numsegments":  func(record *ExportRecord, value string) { record.NumSegments = value },
+	"errorcode":    func(record *ExportRecord, value string) { record.ErrorCode = value },
+	"errormessage": func(record *ExportRecord, value string) { record.ErrorMessage = value },
+	"price":        func(record *ExportRecord, value string) { record.Price = value },
+	"priceunit":    func(record *ExportRecord, value string) { record.PriceUnit = value },
+}
+
+type csvExportReader struct {
+	reader  *csv.Reader
+	columns []func(record *ExportRecord, value string)
+	row     int
+}
+
+// NewCSVExportReader creates an ExportReader for the CSV file which is downloaded from the message logs of the Twilio console
+func NewCSVExportReader(reader io.Reader) (ExportReader, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.LazyQuotes = true
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot read the header of the CSV export")
+	}
+
+	result := &csvExportReader{reader: csvReader, columns: make([]func(*ExportRecord, string), len(header))}
+	hasSID := false
+	for index, name := range header {
+		key := normalizeColumn(name)
+		result.columns[index] = csvExportColumns[key]
+		hasSID = hasSID || key == "sid" || key == "messagesid"
+	}
+
+	if !hasSID {
+		return nil, stacktrace.NewError(fmt.Sprintf("the CSV export has no Sid column in the header [%s]", strings.Join(header, ",")))
+	}
+
+	return result, nil
+}
+
+// Next returns the next row of the CSV file
+func (reader *csvExportReader) Next() (*ExportRecord, error) {
+	values, err := reader.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+
+	reader.row++
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &RowError{Row: reader.row, Reason: parseErr.Err.Error()}
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot read row [%d] of the CSV export", reader.row))
+	}
+
+	record := &ExportRecord{Row: reader.row}
+	for index, value := range values {
+		if index < len(reader.columns) && reader.columns[index] != nil {
+			reader.columns[index](record, strings.TrimSpace(value))
+		}
+	}
+
+	return record, nil
+}
+
+func normalizeColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// jsonExportMessage is a message in the response of the list messages endpoint of the Twilio REST API, the error code
+// is a number and the other fields are strings
+type jsonExportMessage struct {
+	SID          string          `json:"sid"`
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Body         string          `json:"body"`
+	Status       string          `json:"status"`
+	Direction    string          `json:"direction"`
+	DateSent     *string         `json:"date_sent"`
+	DateCreated  *string         `json:"date_created"`
+	NumSegments  *string         `json:"num_segments"`
+	ErrorCode    json.RawMessage `json:"error_code"`
+	ErrorMessage *string         `json:"error_message"`
+	Price        *string         `json:"price"`
+	PriceUnit    *string         `json:"price_unit"`
+}
+
+type jsonExportReader struct {
+	decoder *json.Decoder
+	row     int
+}
+
+// NewJSONExportReader creates an ExportReader for the response of the list messages endpoint of the Twilio REST API,
+// the messages are decoded one at a time from the "messages" array of the response
+func NewJSONExportReader(reader io.Reader) (ExportReader, error) {
+	decoder := json.NewDecoder(reader)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, stacktrace.Propagate(err, "the JSON export is not an object")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot read a key of the JSON export")
+		}
+
+		if token == "messages" {
+			if err = expectDelim(decoder, '['); err != nil {
+				return nil, stacktrace.Propagate(err, "the messages of the JSON export are not an array")
+			}
+			return &jsonExportReader{decoder: decoder}, nil
+		}
+
+		// the other keys e.g. next_page_uri are skipped
+		var value json.RawMessage
+		if err = decoder.Decode(&value); err != nil {
+			return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot read the value of the key [%v] of the JSON export", token))
+		}
+	}
+
+	return nil, stacktrace.NewError("the JSON export has no messages")
+}
+
+// Next returns the next message of the messages array
+func (reader *jsonExportReader) Next() (*ExportRecord, error) {
+	if !reader.decoder.More() {
+		return nil, io.EOF
+	}
+
+	reader.row++
+	message := new(jsonExportMessage)
+	err := reader.decoder.Decode(message)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil, &RowError{Row: reader.row, SID: message.SID, Reason: fmt.Sprintf("the field [%s] is not a %s", typeErr.Field, typeErr.Type)}
+	}
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot decode message [%d] of the JSON export", reader.row))
+	}
+
+	errorCode := strings.Trim(string(message.ErrorCode), `"`)
+	return &ExportRecord{
+		Row:          reader.row,
+		SID:          message.SID,
+		From:         message.From,
+		To:           message.To,
+		Body:         message.Body,
+		Status:       Status(strings.ToLower(message.Status)),
+		Direction:    Direction(strings.ToLower(message.Direction)),
+		DateSent:     stringValue(message.DateSent),
+		DateCreated:  stringValue(message.DateCreated),
+		NumSegments:  stringValue(message.NumSegments),
+		ErrorCode:    stringValue(&errorCode),
+		ErrorMessage: stringValue(message.ErrorMessage),
+		Price:        stringValue(message.Price),
+		PriceUnit:    stringValue(message.PriceUnit),
+	}, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot read the token [%s]", delim))
+	}
+	if token != delim {
+		return stacktrace.NewError(fmt.Sprintf("expected token [%s] but got [%v]", delim, token))
+	}
+	return nil
+}
+
+func stringValue(value *string) string {
+	if value == nil || *value == "null" {
+		return ""
+	}
+	return strings.TrimSpace(*value)
+}
+
+// ImportedMessageID is the ID of the entities.Message which is imported from the message with the SID, it is the same
+// on every import so that a message which is imported again is skipped
+func ImportedMessageID(userID entities.UserID, sid string) uuid.UUID {
+	return uuid.NewSHA1(importNamespace, []byte(string(userID)+":"+sid))
+}
+
+// NewImportedMessage translates an ExportRecord into an entities.Message of the user, the owner of the message is the
+// phone number of the user which sent or received it unless the owner is set. The error is a *RowError when the record
+// cannot be imported.
+func NewImportedMessage(userID entities.UserID, owner string, record *ExportRecord, timestamp time.Time) (*entities.Message, error) {
+	rowErr := func(reason string) error {
+		return &RowError{Row: record.Row, SID: record.SID, Reason: reason}
+	}
+
+	if record.SID == "" {
+		return nil, rowErr("the sid is empty")
+	}
+	if record.From == "" || record.To == "" {
+		return nil, rowErr("the from or to phone number is empty")
+	}
+	if strings.Contains(record.From, ":") || strings.Contains(record.To, ":") {
+		return nil, rowErr(fmt.Sprintf("the channel of the message from [%s] to [%s] is not SMS", record.From, record.To))
+	}
+
+	date, err := parseExportDate(record.DateSent, record.DateCreated)
+	if err != nil {
+		return nil, rowErr(err.Error())
+	}
+
+	message := &entities.Message{
+		ID:                ImportedMessageID(userID, record.SID),
+		UserID:            userID,
+		Content:           record.Body,
+		SIM:               entities.SIM1,
+		RequestReceivedAt: date,
+		CreatedAt:         date,
+		UpdatedAt:         timestamp,
+		OrderTimestamp:    date,
+		MaxSendAttempts:   1,
+		Channel:           entities.MessageChannel(entities.FallbackProviderTypeTwilio),
+		ProviderMessageID: &record.SID,
+		Metadata:          entities.MessageMetadata{entities.MessageMetadataKeyTwilioSID: record.SID},
+		ImportedAt:        &timestamp,
+	}
+
+	if segments, err := strconv.Atoi(record.NumSegments); err == nil && segments > 0 {
+		message.Segments = uint(segments)
+	}
+
+	if price, err := strconv.ParseFloat(record.Price, 64); err == nil && record.PriceUnit != "" {
+		cost, currency := math.Abs(price), strings.ToUpper(record.PriceUnit)
+		message.Cost, message.CostCurrency = &cost, &currency
+	}
+
+	switch {
+	case isInbound(record.Direction):
+		message.Type = entities.MessageTypeMobileOriginated
+		message.Owner, message.Contact = record.To, record.From
+		message.Status = entities.MessageStatusReceived
+		message.ReceivedAt = &date
+	case isOutbound(record.Direction):
+		message.Type = entities.MessageTypeMobileTerminated
+		message.Owner, message.Contact = record.From, record.To
+		if err = setImportedStatus(message, record, date); err != nil {
+			return nil, rowErr(err.Error())
+		}
+	default:
+		return nil, rowErr(fmt.Sprintf("the direction [%s] is not supported", record.Direction))
+	}
+
+	if owner != "" {
+		message.Owner = owner
+	}
+
+	return message, nil
+}
+
+// setImportedStatus sets the status of an outgoing message, only the messages with a final status are imported
+func setImportedStatus(message *entities.Message, record *ExportRecord, date time.Time) error {
+	message.SendAttemptCount = 1
+	message.LastAttemptedAt = &date
+
+	switch record.Status {
+	case StatusDelivered, Status("read"):
+		message.Status = entities.MessageStatusDelivered
+		message.SentAt, message.DeliveredAt = &date, &date
+	case StatusSent:
+		message.Status = entities.MessageStatusSent
+		message.SentAt = &date
+	case StatusFailed, StatusUndelivered, StatusCanceled:
+		reason := record.ErrorMessage
+		if reason == "" && record.ErrorCode != "" {
+			reason = "TWILIO_ERROR_" + record.ErrorCode
+		}
+		if reason == "" {
+			reason = strings.ToUpper(string(record.Status))
+		}
+		message.Status = entities.MessageStatusFailed
+		message.FailedAt, message.FailureReason = &date, &reason
+	default:
+		return fmt.Errorf("the status [%s] of an outgoing message is not final", record.Status)
+	}
+	return nil
+}
+
+func isInbound(direction Direction) bool {
+	return direction == DirectionInbound || strings.HasPrefix(string(direction), "incoming")
+}
+
+func isOutbound(direction Direction) bool {
+	return strings.HasPrefix(string(direction), "outbound") || strings.HasPrefix(string(direction), "outgoing")
+}
+
+// parseExportDate parses the date when the message was sent, the date when it was created is used when it was not sent
+func parseExportDate(values ...string) (time.Time, error) {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		for _, layout := range exportDateLayouts {
+			if date, err := time.Parse(layout, value); err == nil {
+				return date.UTC(), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse the date [%s]", value)
+	}
+	return time.Time{}, errors.New("the message has no date")
+}
@@ -0,0 +1,201 @@
+package twilio
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func readExport(t *testing.T, format ExportFormat, name string) ([]*ExportRecord, []*RowError) {
+	file, err := os.Open(filepath.Join("testdata", name))
+	assert.Nil(t, err)
+	defer func() { _ = file.Close() }()
+
+	reader, err := NewExportReader(format, file)
+	assert.Nil(t, err)
+
+	var records []*ExportRecord
+	var rowErrors []*RowError
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records, rowErrors
+		}
+		if rowErr, ok := AsRowError(err); ok {
+			rowErrors = append(rowErrors, rowErr)
+			continue
+		}
+		assert.Nil(t, err)
+		records = append(records, record)
+	}
+}
+
+func TestNewExportReader(t *testing.T) {
+	t.Run("the rows of a CSV export are read with the columns of the header", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		records, rowErrors := readExport(t, ExportFormatCSV, "export_messages.csv")
+
+		// Assert
+		assert.Empty(t, rowErrors)
+		assert.Equal(t, 4, len(records))
+		assert.Equal(t, ExportRecord{
+			Row:          1,
+			SID:          "SM1f0e8ae6ade43cb3c0ce4525424e404f",
+			From:         "+18005550199",
+			To:           "+18005550100",
+			Body:         "Hello, how are you?",
+			Status:       StatusDelivered,
+			Direction:    DirectionOutboundAPI,
+			DateSent:     "2023-08-24T05:01:45-07:00",
+			NumSegments:  "1",
+			ErrorCode:    "0",
+			Price:        "-0.00790",
+			PriceUnit:    "USD",
+			ErrorMessage: "",
+		}, *records[0])
+		assert.Equal(t, DirectionInbound, records[1].Direction)
+		assert.Equal(t, "30003", records[2].ErrorCode)
+	})
+
+	t.Run("the messages of a JSON export are read and a message with an invalid field is a row error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		records, rowErrors := readExport(t, ExportFormatJSON, "export_messages.json")
+
+		// Assert
+		assert.Equal(t, 3, len(records))
+		assert.Equal(t, "SM1f0e8ae6ade43cb3c0ce4525424e404f", records[0].SID)
+		assert.Equal(t, "Thu, 24 Aug 2023 12:01:45 +0000", records[0].DateSent)
+		assert.Equal(t, "", records[0].ErrorCode)
+		assert.Equal(t, "30003", records[1].ErrorCode)
+		assert.Equal(t, "", records[1].DateSent)
+		assert.Equal(t, 4, records[2].Row)
+
+		assert.Equal(t, 1, len(rowErrors))
+		assert.Equal(t, 3, rowErrors[0].Row)
+		assert.Equal(t, "SM5d0e2f4a6b8c0d2e4f6a8b0c2d4e6f8a", rowErrors[0].SID)
+	})
+
+	t.Run("a CSV export without a sid column is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		_, err := NewExportReader(ExportFormatCSV, strings.NewReader("From,To,Body\n+18005550199,+18005550100,Hello\n"))
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+
+	t.Run("a JSON export without messages is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		_, err := NewExportReader(ExportFormatJSON, strings.NewReader(`{"page": 0, "next_page_uri": null}`))
+
+		// Assert
+		assert.NotNil(t, err)
+	})
+}
+
+func TestNewImportedMessage(t *testing.T) {
+	userID := entities.UserID("WB7DRDWrJZRGbYrv2CKGkqbzvqdC")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("a delivered outgoing message is owned by the sender", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		records, _ := readExport(t, ExportFormatJSON, "export_messages.json")
+
+		// Act
+		message, err := NewImportedMessage(userID, "", records[0], now)
+
+		// Assert
+		assert.Nil(t, err)
+		sentAt := time.Date(2023, 8, 24, 12, 1, 45, 0, time.UTC)
+		assert.Equal(t, ImportedMessageID(userID, "SM1f0e8ae6ade43cb3c0ce4525424e404f"), message.ID)
+		assert.Equal(t, "+18005550199", message.Owner)
+		assert.Equal(t, "+18005550100", message.Contact)
+		assert.Equal(t, entities.MessageType(entities.MessageTypeMobileTerminated), message.Type)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusDelivered), message.Status)
+		assert.Equal(t, sentAt, message.OrderTimestamp)
+		assert.Equal(t, &sentAt, message.DeliveredAt)
+		assert.Equal(t, uint(2), message.Segments)
+		assert.Equal(t, 0.0158, *message.Cost)
+		assert.Equal(t, "SM1f0e8ae6ade43cb3c0ce4525424e404f", message.Metadata[entities.MessageMetadataKeyTwilioSID])
+		assert.Equal(t, &now, message.ImportedAt)
+	})
+
+	t.Run("a failed message without a sent date uses the created date and the error message", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		records, _ := readExport(t, ExportFormatJSON, "export_messages.json")
+
+		// Act
+		message, err := NewImportedMessage(userID, "", records[1], now)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusFailed), message.Status)
+		assert.Equal(t, "Unreachable destination handset", *message.FailureReason)
+		assert.Equal(t, time.Date(2023, 8, 24, 12, 5, 0, 0, time.UTC), message.OrderTimestamp)
+		assert.Nil(t, message.Cost)
+	})
+
+	t.Run("a received message is owned by the recipient unless the owner is set", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		records, _ := readExport(t, ExportFormatCSV, "export_messages.csv")
+
+		// Act
+		message, err := NewImportedMessage(userID, "+18005550111", records[1], now)
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, "+18005550111", message.Owner)
+		assert.Equal(t, "+18005550100", message.Contact)
+		assert.Equal(t, entities.MessageStatus(entities.MessageStatusReceived), message.Status)
+		assert.Equal(t, time.Date(2023, 8, 24, 12, 3, 10, 0, time.UTC), *message.ReceivedAt)
+	})
+
+	t.Run("an outgoing message which is not final is a row error", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		records, _ := readExport(t, ExportFormatCSV, "export_messages.csv")
+
+		// Act
+		_, err := NewImportedMessage(userID, "", records[3], now)
+
+		// Assert
+		rowErr, ok := AsRowError(err)
+		assert.True(t, ok)
+		assert.Equal(t, 4, rowErr.Row)
+		assert.Contains(t, rowErr.Reason, "queued")
+	})
+
+	t.Run("the ID of a message is the same on every import", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Act
+		first := ImportedMessageID(userID, "SM1f0e8ae6ade43cb3c0ce4525424e404f")
+		second := ImportedMessageID(userID, "SM1f0e8ae6ade43cb3c0ce4525424e404f")
+		other := ImportedMessageID("3Ngh1zG0AoTNsT1vHBlF3Zvp4Ri1", "SM1f0e8ae6ade43cb3c0ce4525424e404f")
+
+		// Assert
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, first, other)
+	})
+}
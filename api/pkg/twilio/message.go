@@ -0,0 +1,98 @@
+package twilio
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// dateFormat is the RFC 2822 format of the dates of a Twilio message e.g. Thu, 24 Aug 2023 05:01:45 +0000
+const dateFormat = time.RFC1123Z
+
+// ErrorCodeUnknown is the error code of a message which the phone could not send
+const ErrorCodeUnknown = 30008
+
+// Message is an entities.Message in the shape of a Twilio message resource, the SID is the ID of the entities.Message
+type Message struct {
+	AccountSID          string            `json:"account_sid" example:"AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1"`
+	APIVersion          string            `json:"api_version" example:"2010-04-01"`
+	Body                string            `json:"body" example:"This is a sample text message"`
+	DateCreated         string            `json:"date_created" example:"Sun, 05 Jun 2022 11:26:02 +0000"`
+	DateSent            *string           `json:"date_sent" example:"Sun, 05 Jun 2022 11:26:09 +0000"`
+	DateUpdated         string            `json:"date_updated" example:"Sun, 05 Jun 2022 11:26:10 +0000"`
+	Direction           Direction         `json:"direction" example:"outbound-api"`
+	ErrorCode           *int              `json:"error_code" example:"30008"`
+	ErrorMessage        *string           `json:"error_message" example:"UNKNOWN"`
+	From                string            `json:"from" example:"+18005550199"`
+	MessagingServiceSID *string           `json:"messaging_service_sid"`
+	NumMedia            string            `json:"num_media" example:"0"`
+	NumSegments         string            `json:"num_segments" example:"1"`
+	Price               *string           `json:"price"`
+	PriceUnit           string            `json:"price_unit" example:"USD"`
+	SID                 string            `json:"sid" example:"32343a19-da5e-4b1b-a767-3298a73703cb"`
+	Status              Status            `json:"status" example:"queued"`
+	SubresourceURIs     map[string]string `json:"subresource_uris"`
+	To                  string            `json:"to" example:"+18005550100"`
+	URI                 string            `json:"uri" example:"/2010-04-01/Accounts/AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1/Messages/32343a19-da5e-4b1b-a767-3298a73703cb.json"`
+}
+
+// NewMessage translates an entities.Message into a Twilio Message of the account with the accountSID
+func NewMessage(accountSID string, message *entities.Message) Message {
+	from, to := message.Owner, message.Contact
+	if message.Type == entities.MessageTypeMobileOriginated {
+		from, to = message.Contact, message.Owner
+	}
+
+	uri := fmt.Sprintf("/%s/Accounts/%s/Messages/%s", APIVersion, accountSID, message.ID)
+	result := Message{
+		AccountSID:      accountSID,
+		APIVersion:      APIVersion,
+		Body:            message.Content,
+		DateCreated:     formatDate(message.CreatedAt),
+		DateSent:        formatDatePointer(message.SentAt),
+		DateUpdated:     formatDate(message.UpdatedAt),
+		Direction:       NewDirection(message.Type),
+		From:            from,
+		NumMedia:        "0",
+		NumSegments:     strconv.Itoa(Segments(message.Content)),
+		PriceUnit:       "USD",
+		SID:             message.ID.String(),
+		Status:          NewStatus(message.Status),
+		SubresourceURIs: map[string]string{"media": uri + "/Media.json"},
+		To:              to,
+		URI:             uri + ".json",
+	}
+
+	if message.ReceivedAt != nil {
+		result.DateSent = formatDatePointer(message.ReceivedAt)
+	}
+
+	if result.Status == StatusFailed {
+		code, reason := ErrorCodeUnknown, "UNKNOWN"
+		if message.FailureReason != nil && *message.FailureReason != "" {
+			reason = *message.FailureReason
+		}
+		result.ErrorCode, result.ErrorMessage = &code, &reason
+	}
+
+	return result
+}
+
+// Segments is the number of SMS segments which are needed to send the body
+func Segments(body string) int {
+	return entities.SegmentCount(body)
+}
+
+func formatDate(timestamp time.Time) string {
+	return timestamp.UTC().Format(dateFormat)
+}
+
+func formatDatePointer(timestamp *time.Time) *string {
+	if timestamp == nil {
+		return nil
+	}
+	result := formatDate(*timestamp)
+	return &result
+}
@@ -0,0 +1,149 @@
+package twilio
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAccountSID = "AC35ab4d9e1d0f4c9f8ab0c9f5a3e0f2d1"
+
+func fixture(t *testing.T, name string) string {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	assert.Nil(t, err)
+	return strings.TrimSpace(string(content))
+}
+
+func timestamp(value string) *time.Time {
+	result, _ := time.Parse(time.RFC3339, value)
+	return &result
+}
+
+func outboundMessage(status entities.MessageStatus) *entities.Message {
+	return &entities.Message{
+		ID:        uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+		Owner:     "+18005550199",
+		Contact:   "+18005550100",
+		Content:   "This is a sample text message",
+		Type:      entities.MessageTypeMobileTerminated,
+		Status:    status,
+		CreatedAt: *timestamp("2022-06-05T14:26:02+03:00"),
+		UpdatedAt: *timestamp("2022-06-05T14:26:02+03:00"),
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	delivered := outboundMessage(entities.MessageStatusDelivered)
+	delivered.SentAt = timestamp("2022-06-05T14:26:09+03:00")
+	delivered.UpdatedAt = *timestamp("2022-06-05T14:26:12+03:00")
+
+	failed := outboundMessage(entities.MessageStatusFailed)
+	failed.FailureReason = new(string)
+	*failed.FailureReason = "RESULT_ERROR_NO_SERVICE"
+	failed.UpdatedAt = *timestamp("2022-06-05T14:31:02+03:00")
+
+	tests := []struct {
+		fixture string
+		message *entities.Message
+	}{
+		{fixture: "message_queued.json", message: outboundMessage(entities.MessageStatusPending)},
+		{fixture: "message_delivered.json", message: delivered},
+		{fixture: "message_failed.json", message: failed},
+		{
+			fixture: "message_received.json",
+			message: &entities.Message{
+				ID:         uuid.MustParse("5b1a6c0e-2f3d-4a8b-9c7e-1d2f3a4b5c6d"),
+				Owner:      "+18005550199",
+				Contact:    "+18005550100",
+				Content:    "Hello 👋",
+				Type:       entities.MessageTypeMobileOriginated,
+				Status:     entities.MessageStatusReceived,
+				ReceivedAt: timestamp("2022-06-05T11:26:58Z"),
+				CreatedAt:  *timestamp("2022-06-05T11:27:00Z"),
+				UpdatedAt:  *timestamp("2022-06-05T11:27:00Z"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.fixture, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			content, err := json.Marshal(NewMessage(testAccountSID, test.message))
+
+			// Assert
+			assert.Nil(t, err)
+			assert.JSONEq(t, fixture(t, test.fixture), string(content))
+		})
+	}
+}
+
+func TestNewStatus(t *testing.T) {
+	tests := []struct {
+		status   entities.MessageStatus
+		expected Status
+	}{
+		{status: entities.MessageStatusPending, expected: StatusQueued},
+		{status: entities.MessageStatusScheduled, expected: StatusScheduled},
+		{status: entities.MessageStatusSending, expected: StatusSending},
+		{status: entities.MessageStatusSent, expected: StatusSent},
+		{status: entities.MessageStatusDelivered, expected: StatusDelivered},
+		{status: entities.MessageStatusFailed, expected: StatusFailed},
+		{status: entities.MessageStatusExpired, expected: StatusFailed},
+		{status: entities.MessageStatusReceived, expected: StatusReceived},
+		{status: entities.MessageStatusDeleted, expected: StatusCanceled},
+		{status: "unknown", expected: StatusQueued},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(string(test.status), func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			status := NewStatus(test.status)
+
+			// Assert
+			assert.Equal(t, test.expected, status)
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		segments int
+	}{
+		{name: "empty body", body: "", segments: 1},
+		{name: "single GSM segment", body: strings.Repeat("a", 160), segments: 1},
+		{name: "multiple GSM segments", body: strings.Repeat("a", 161), segments: 2},
+		{name: "single UCS-2 segment", body: strings.Repeat("é", 70), segments: 1},
+		{name: "multiple UCS-2 segments", body: strings.Repeat("é", 135), segments: 3},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Act
+			segments := Segments(test.body)
+
+			// Assert
+			assert.Equal(t, test.segments, segments)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// SignatureHeader is the header with the signature of a request which Twilio sends to a webhook
+const SignatureHeader = "X-Twilio-Signature"
+
+// Signature computes the signature of a form which Twilio posts to a URL, it is the base64 encoded HMAC-SHA1 of the
+// URL followed by the sorted names and values of the form signed with the auth token of the account
+func Signature(authToken string, url string, values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	builder.WriteString(url)
+	for _, key := range keys {
+		for _, value := range values[key] {
+			builder.WriteString(key)
+			builder.WriteString(value)
+		}
+	}
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(builder.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package twilio
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignature(t *testing.T) {
+	// Setup
+	t.Parallel()
+
+	// Arrange
+	values := url.Values{
+		"CallSid": []string{"CA1234567890ABCDE"},
+		"Caller":  []string{"+12349013030"},
+		"Digits":  []string{"1234"},
+		"From":    []string{"+12349013030"},
+		"To":      []string{"+18005551212"},
+	}
+
+	// Act
+	signature := Signature("12345", "https://mycompany.com/myapp.php?foo=1&bar=2", values)
+
+	// Assert
+	assert.Equal(t, "0/KCTR6DLpKmkAf8muzZqo1nDgQ=", signature)
+}
@@ -0,0 +1,38 @@
+package twilio
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// StatusCallback is the request which Twilio sends to the StatusCallback URL of a message when its status changes
+type StatusCallback struct {
+	AccountSID string
+	MessageID  uuid.UUID
+	From       string
+	To         string
+	Status     Status
+}
+
+// Values encodes the StatusCallback as the form which is posted to the StatusCallback URL, the error code is only
+// added to the form of a failed message
+func (callback StatusCallback) Values() url.Values {
+	values := url.Values{
+		"AccountSid":    []string{callback.AccountSID},
+		"ApiVersion":    []string{APIVersion},
+		"From":          []string{callback.From},
+		"MessageSid":    []string{callback.MessageID.String()},
+		"MessageStatus": []string{string(callback.Status)},
+		"SmsSid":        []string{callback.MessageID.String()},
+		"SmsStatus":     []string{string(callback.Status)},
+		"To":            []string{callback.To},
+	}
+
+	if callback.Status == StatusFailed {
+		values.Set("ErrorCode", strconv.Itoa(ErrorCodeUnknown))
+	}
+
+	return values
+}
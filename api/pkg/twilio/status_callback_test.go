@@ -0,0 +1,41 @@
+package twilio
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCallback_Values(t *testing.T) {
+	tests := []struct {
+		fixture string
+		status  Status
+	}{
+		{fixture: "status_callback_queued.txt", status: StatusQueued},
+		{fixture: "status_callback_sent.txt", status: StatusSent},
+		{fixture: "status_callback_delivered.txt", status: StatusDelivered},
+		{fixture: "status_callback_failed.txt", status: StatusFailed},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.fixture, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+			callback := StatusCallback{
+				AccountSID: testAccountSID,
+				MessageID:  uuid.MustParse("32343a19-da5e-4b1b-a767-3298a73703cb"),
+				From:       "+18005550199",
+				To:         "+18005550100",
+				Status:     test.status,
+			}
+
+			// Act
+			values := callback.Values()
+
+			// Assert
+			assert.Equal(t, fixture(t, test.fixture), values.Encode())
+		})
+	}
+}
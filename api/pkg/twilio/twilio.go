@@ -0,0 +1,75 @@
+// Package twilio translates between the entities of the API and the Twilio REST API so that the clients of Twilio can
+// send messages through the API without changing their code.
+package twilio
+
+import (
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+)
+
+// APIVersion is the version of the Twilio REST API which is emulated
+const APIVersion = "2010-04-01"
+
+// Status is the status of a Twilio message
+type Status string
+
+const (
+	// StatusQueued means the message is waiting to be sent by the phone
+	StatusQueued = Status("queued")
+	// StatusScheduled means the message is scheduled to be sent at a later time
+	StatusScheduled = Status("scheduled")
+	// StatusSending means the phone is sending the message
+	StatusSending = Status("sending")
+	// StatusSent means the phone sent the message
+	StatusSent = Status("sent")
+	// StatusDelivered means the message was delivered to the contact
+	StatusDelivered = Status("delivered")
+	// StatusFailed means the phone could not send the message
+	StatusFailed = Status("failed")
+	// StatusReceived means the message was received by the phone
+	StatusReceived = Status("received")
+	// StatusCanceled means the message was deleted before it was sent
+	StatusCanceled = Status("canceled")
+	// StatusAccepted means a Twilio messaging service accepted the message
+	StatusAccepted = Status("accepted")
+	// StatusUndelivered means the carrier could not deliver the message to the contact
+	StatusUndelivered = Status("undelivered")
+)
+
+// statuses translates the entities.MessageStatus into the Status of a Twilio message
+var statuses = map[entities.MessageStatus]Status{
+	entities.MessageStatusPending:   StatusQueued,
+	entities.MessageStatusScheduled: StatusScheduled,
+	entities.MessageStatusSending:   StatusSending,
+	entities.MessageStatusSent:      StatusSent,
+	entities.MessageStatusDelivered: StatusDelivered,
+	entities.MessageStatusFailed:    StatusFailed,
+	entities.MessageStatusExpired:   StatusFailed,
+	entities.MessageStatusReceived:  StatusReceived,
+	entities.MessageStatusDeleted:   StatusCanceled,
+}
+
+// NewStatus translates an entities.MessageStatus into the Status of a Twilio message, an unknown status is queued
+func NewStatus(status entities.MessageStatus) Status {
+	if result, ok := statuses[status]; ok {
+		return result
+	}
+	return StatusQueued
+}
+
+// Direction is the direction of a Twilio message
+type Direction string
+
+const (
+	// DirectionOutboundAPI is a message which was sent with the API
+	DirectionOutboundAPI = Direction("outbound-api")
+	// DirectionInbound is a message which was received by the phone
+	DirectionInbound = Direction("inbound")
+)
+
+// NewDirection translates an entities.MessageType into the Direction of a Twilio message
+func NewDirection(messageType entities.MessageType) Direction {
+	if messageType == entities.MessageTypeMobileOriginated {
+		return DirectionInbound
+	}
+	return DirectionOutboundAPI
+}
@@ -0,0 +1,72 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// APIKeyHandlerValidator validates models used in handlers.APIKeyHandler
+type APIKeyHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewAPIKeyHandlerValidator creates a new handlers.APIKeyHandler validator
+func NewAPIKeyHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *APIKeyHandlerValidator) {
+	return &APIKeyHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateIndex validates the requests.APIKeyIndex request
+func (validator *APIKeyHandlerValidator) ValidateIndex(_ context.Context, request requests.APIKeyIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+			"query": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateStore validates the requests.APIKeyStore request
+func (validator *APIKeyHandlerValidator) ValidateStore(_ context.Context, request requests.APIKeyStore) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"label": []string{
+				"required",
+				"min:1",
+				"max:100",
+			},
+			"scopes": []string{
+				"required",
+				apiKeyScopesRule,
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
@@ -0,0 +1,130 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// ContactGroupHandlerValidator validates models used in handlers.ContactGroupHandler
+type ContactGroupHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewContactGroupHandlerValidator creates a new handlers.ContactGroupHandler validator
+func NewContactGroupHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *ContactGroupHandlerValidator) {
+	return &ContactGroupHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateIndex validates the requests.ContactGroupIndex request
+func (validator *ContactGroupHandlerValidator) ValidateIndex(_ context.Context, request requests.ContactGroupIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+			"query": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateStore validates the requests.ContactGroupStore request
+func (validator *ContactGroupHandlerValidator) ValidateStore(_ context.Context, request requests.ContactGroupStore) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"name": []string{
+				"required",
+				"max:255",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateUpdate validates the requests.ContactGroupUpdate request
+func (validator *ContactGroupHandlerValidator) ValidateUpdate(_ context.Context, request requests.ContactGroupUpdate) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"contactGroupID": []string{
+				"required",
+				"uuid",
+			},
+			"name": []string{
+				"required",
+				"max:255",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateMemberIndex validates the requests.ContactGroupMemberIndex request
+func (validator *ContactGroupHandlerValidator) ValidateMemberIndex(_ context.Context, request requests.ContactGroupMemberIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"contactGroupID": []string{
+				"required",
+				"uuid",
+			},
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateMemberStore validates the requests.ContactGroupMemberStore request
+func (validator *ContactGroupHandlerValidator) ValidateMemberStore(_ context.Context, request requests.ContactGroupMemberStore) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"contactGroupID": []string{
+				"required",
+				"uuid",
+			},
+			"contact_ids": []string{
+				"required",
+				"min:1",
+				"max:500",
+				multipleUUIDRule,
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
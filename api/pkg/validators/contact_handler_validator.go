@@ -0,0 +1,219 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+const (
+	// contactSyncMaxBatchSize is the maximum number of entries of the address book in one sync request
+	contactSyncMaxBatchSize = 500
+
+	// contactNotesMaxLength is the maximum number of characters in the notes of a contact
+	contactNotesMaxLength = 2000
+
+	// contactLabelMaxLength is the maximum number of characters in a label of a contact
+	contactLabelMaxLength = 32
+
+	// contactLabelsMaxCount is the maximum number of labels on a contact
+	contactLabelsMaxCount = 20
+)
+
+// ContactHandlerValidator validates models used in handlers.ContactHandler
+type ContactHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewContactHandlerValidator creates a new handlers.ContactHandler validator
+func NewContactHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *ContactHandlerValidator) {
+	return &ContactHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateIndex validates the requests.ContactIndex request
+func (validator *ContactHandlerValidator) ValidateIndex(_ context.Context, request requests.ContactIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+			"query": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateStore validates the requests.ContactStore request
+func (validator *ContactHandlerValidator) ValidateStore(_ context.Context, request requests.ContactStore) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"phone_number": []string{
+				"required",
+				phoneNumberRule,
+			},
+			"name": []string{
+				"required",
+				"max:255",
+			},
+		},
+	}).ValidateStruct()
+	return validator.validateDND(result, request.DNDStart, request.DNDEnd, request.DNDTimezone)
+}
+
+// ValidateUpdate validates the requests.ContactUpdate request
+func (validator *ContactHandlerValidator) ValidateUpdate(_ context.Context, request requests.ContactUpdate) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"contactID": []string{
+				"required",
+				"uuid",
+			},
+			"name": []string{
+				"required",
+				"max:255",
+			},
+		},
+	}).ValidateStruct()
+	return validator.validateDND(result, request.DNDStart, request.DNDEnd, request.DNDTimezone)
+}
+
+// validateDND makes sure the do-not-disturb window of a contact is either empty or has a start, an end and a timezone
+func (validator *ContactHandlerValidator) validateDND(result url.Values, start string, end string, timezone string) url.Values {
+	if start == "" && end == "" && timezone == "" {
+		return result
+	}
+
+	if start == "" || end == "" || timezone == "" {
+		result.Add("dnd_start", "The dnd_start, dnd_end and dnd_timezone fields must all be set or all be empty")
+		return result
+	}
+
+	for field, value := range map[string]string{"dnd_start": start, "dnd_end": end} {
+		if _, err := time.Parse("15:04", value); err != nil {
+			result.Add(field, fmt.Sprintf("The %s field must be a time in the format HH:MM e.g. 18:00", field))
+		}
+	}
+
+	if start == end {
+		result.Add("dnd_end", "The dnd_end field must be different from the dnd_start field")
+	}
+
+	if _, err := time.LoadLocation(timezone); err != nil {
+		result.Add("dnd_timezone", fmt.Sprintf("The dnd_timezone field must be a valid timezone e.g. Europe/Berlin, [%s] is not a valid timezone", timezone))
+	}
+
+	return result
+}
+
+// ValidateNotesUpdate validates the requests.ContactNotesUpdate request
+func (validator *ContactHandlerValidator) ValidateNotesUpdate(_ context.Context, request requests.ContactNotesUpdate) url.Values {
+	return govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"contactID": []string{
+				"required",
+				"uuid",
+			},
+			"notes": []string{
+				fmt.Sprintf("max:%d", contactNotesMaxLength),
+			},
+		},
+	}).ValidateStruct()
+}
+
+// ValidateLabelsUpdate validates the requests.ContactLabelsUpdate request
+func (validator *ContactHandlerValidator) ValidateLabelsUpdate(_ context.Context, request requests.ContactLabelsUpdate) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"contactID": []string{
+				"required",
+				"uuid",
+			},
+		},
+	}).ValidateStruct()
+
+	if len(request.Labels) > contactLabelsMaxCount {
+		result.Add("labels", fmt.Sprintf("The labels field must not have more than %d labels", contactLabelsMaxCount))
+	}
+
+	for _, label := range request.Labels {
+		if utf8.RuneCountInString(label) > contactLabelMaxLength {
+			result.Add("labels", fmt.Sprintf("The label [%s] must not be longer than %d characters", label, contactLabelMaxLength))
+		}
+	}
+
+	return result
+}
+
+// ValidateLabelRename validates the requests.ContactLabelRename request
+func (validator *ContactHandlerValidator) ValidateLabelRename(_ context.Context, request requests.ContactLabelRename) url.Values {
+	return govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"label": []string{
+				"required",
+				fmt.Sprintf("max:%d", contactLabelMaxLength),
+			},
+			"name": []string{
+				"required",
+				fmt.Sprintf("max:%d", contactLabelMaxLength),
+			},
+		},
+	}).ValidateStruct()
+}
+
+// ValidateSync validates the requests.ContactSync request
+func (validator *ContactHandlerValidator) ValidateSync(_ context.Context, request requests.ContactSync) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+		},
+	}).ValidateStruct()
+
+	if len(request.Contacts) == 0 || len(request.Contacts) > contactSyncMaxBatchSize {
+		result.Add("contacts", fmt.Sprintf("The contacts field must have between 1 and %d entries, sync a larger address book in batches", contactSyncMaxBatchSize))
+	}
+
+	for index, contact := range request.Contacts {
+		if contact.PhoneNumber == "" || len(contact.PhoneNumber) > 50 {
+			result.Add("contacts", fmt.Sprintf("The phone_number of contact [%d] must be between 1 and 50 characters", index))
+		}
+		if len(contact.Name) > 255 {
+			result.Add("contacts", fmt.Sprintf("The name of contact [%d] must not be longer than 255 characters", index))
+		}
+	}
+
+	return result
+}
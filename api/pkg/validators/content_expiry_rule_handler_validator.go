@@ -0,0 +1,109 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// ContentExpiryRuleHandlerValidator validates models used in handlers.ContentExpiryRuleHandler
+type ContentExpiryRuleHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewContentExpiryRuleHandlerValidator creates a new handlers.ContentExpiryRuleHandler validator
+func NewContentExpiryRuleHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *ContentExpiryRuleHandlerValidator) {
+	return &ContentExpiryRuleHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateStore validates the requests.ContentExpiryRuleStore request
+func (validator *ContentExpiryRuleHandlerValidator) ValidateStore(_ context.Context, request requests.ContentExpiryRuleStore) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: validator.storeRules(),
+	}).ValidateStruct()
+	return validator.validateMatching(result, request)
+}
+
+// ValidateUpdate validates the requests.ContentExpiryRuleUpdate request
+func (validator *ContentExpiryRuleHandlerValidator) ValidateUpdate(_ context.Context, request requests.ContentExpiryRuleUpdate) url.Values {
+	rules := validator.storeRules()
+	rules["contentExpiryRuleID"] = []string{
+		"required",
+		"uuid",
+	}
+
+	result := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
+	}).ValidateStruct()
+	return validator.validateMatching(result, request.ContentExpiryRuleStore)
+}
+
+// ValidateDryRun validates the requests.ContentExpiryRuleDryRun request
+func (validator *ContentExpiryRuleHandlerValidator) ValidateDryRun(_ context.Context, request requests.ContentExpiryRuleDryRun) url.Values {
+	return govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+			"contact": []string{
+				"required",
+				"max:50",
+			},
+			"content": []string{
+				"required",
+				"max:2048",
+			},
+		},
+	}).ValidateStruct()
+}
+
+func (validator *ContentExpiryRuleHandlerValidator) storeRules() govalidator.MapData {
+	return govalidator.MapData{
+		"owner": []string{
+			"required",
+			phoneNumberRule,
+		},
+		"pattern": []string{
+			"max:200",
+		},
+		"contacts": []string{
+			"max:20",
+		},
+		"ttl_seconds": []string{
+			"required",
+			"min:60",
+			"max:2592000",
+		},
+	}
+}
+
+// validateMatching makes sure that the pattern is a valid regular expression and that a rule does not match every message
+func (validator *ContentExpiryRuleHandlerValidator) validateMatching(result url.Values, request requests.ContentExpiryRuleStore) url.Values {
+	if request.Pattern == "" && len(request.Contacts) == 0 {
+		result.Add("pattern", "The pattern field is required when the contacts field is empty, a rule cannot match every received message")
+		return result
+	}
+
+	if _, err := regexp.Compile(request.Pattern); err != nil {
+		result.Add("pattern", fmt.Sprintf("The pattern field must be a valid regular expression: %s", err.Error()))
+	}
+
+	return result
+}
@@ -0,0 +1,68 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// FallbackHandlerValidator validates models used in handlers.FallbackHandler
+type FallbackHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewFallbackHandlerValidator creates a new handlers.FallbackHandler validator
+func NewFallbackHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *FallbackHandlerValidator) {
+	return &FallbackHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateUpsert validates the requests.FallbackProviderUpsert request
+func (validator *FallbackHandlerValidator) ValidateUpsert(_ context.Context, request requests.FallbackProviderUpsert) url.Values {
+	rules := govalidator.MapData{
+		"type": []string{
+			"required",
+			"in:" + string(entities.FallbackProviderTypeTwilio) + "," + string(entities.FallbackProviderTypeHTTP),
+		},
+		"from": []string{
+			"required",
+			phoneNumberRule,
+		},
+	}
+
+	switch entities.FallbackProviderType(request.Type) {
+	case entities.FallbackProviderTypeTwilio:
+		rules["account_sid"] = []string{"required", "max:255"}
+		rules["auth_token"] = []string{"required", "max:255"}
+	case entities.FallbackProviderTypeHTTP:
+		rules["url"] = []string{"required", "url", "max:1000"}
+		rules["signing_key"] = []string{"required", "min:16", "max:255"}
+	}
+
+	result := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
+	}).ValidateStruct()
+
+	if request.OfflineAfterSeconds != 0 && (request.OfflineAfterSeconds < 60 || request.OfflineAfterSeconds > 604800) {
+		result.Add("offline_after_seconds", "The offline_after_seconds field must be 0 or between 60 and 604800")
+	}
+
+	if request.OfflineAfterSeconds == 0 && !request.FallbackOnExpired {
+		result.Add("fallback_on_expired", "The fallback_on_expired field must be true when offline_after_seconds is 0 otherwise the provider never sends a message")
+	}
+
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 
@@ -11,6 +12,9 @@ import (
 	"github.com/thedevsaddam/govalidator"
 )
 
+// heartbeatMaxTimeRange is the longest time range for which heartbeats can be fetched in a single request
+const heartbeatMaxTimeRange = 31 * 24 * time.Hour
+
 // HeartbeatHandlerValidator validates models used in handlers.HeartbeatHandler
 type HeartbeatHandlerValidator struct {
 	validator
@@ -54,7 +58,78 @@ func (validator *HeartbeatHandlerValidator) ValidateIndex(_ context.Context, req
 			},
 		},
 	})
-	return v.ValidateStruct()
+
+	result := v.ValidateStruct()
+	if len(result) > 0 {
+		return result
+	}
+
+	return validator.validateTimeRange(request.From, request.To, false)
+}
+
+// ValidateHourlyIndex validates the requests.HeartbeatHourlyIndex request
+func (validator *HeartbeatHandlerValidator) ValidateHourlyIndex(_ context.Context, request requests.HeartbeatHourlyIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+			"from": []string{
+				"required",
+			},
+			"to": []string{
+				"required",
+			},
+		},
+	})
+
+	result := v.ValidateStruct()
+	if len(result) > 0 {
+		return result
+	}
+
+	return validator.validateTimeRange(request.From, request.To, true)
+}
+
+// validateTimeRange makes sure the from and to RFC3339 timestamps form a time range which is not longer than heartbeatMaxTimeRange
+func (validator *HeartbeatHandlerValidator) validateTimeRange(from string, to string, required bool) url.Values {
+	result := url.Values{}
+
+	values := map[string]*time.Time{"from": nil, "to": nil}
+	for key, value := range map[string]string{"from": from, "to": to} {
+		if value == "" {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			result.Add(key, fmt.Sprintf("The %s field must be a valid RFC3339 timestamp e.g 2022-06-05T14:26:09+03:00", key))
+			continue
+		}
+		values[key] = &timestamp
+	}
+
+	if len(result) > 0 {
+		return result
+	}
+
+	if values["from"] == nil || values["to"] == nil {
+		if required || values["from"] != nil || values["to"] != nil {
+			result.Add("from", "The from and to fields must be set together")
+		}
+		return result
+	}
+
+	if !values["to"].After(*values["from"]) {
+		result.Add("to", "The to field must be after the from field")
+	}
+
+	if values["to"].Sub(*values["from"]) > heartbeatMaxTimeRange {
+		result.Add("from", fmt.Sprintf("The time range between the from and to fields cannot be more than %d days", int(heartbeatMaxTimeRange.Hours()/24)))
+	}
+
+	return result
 }
 
 // ValidateStore validates the requests.HeartbeatStore request
@@ -66,6 +141,10 @@ func (validator *HeartbeatHandlerValidator) ValidateStore(_ context.Context, req
 				"required",
 				phoneNumberRule,
 			},
+			"battery_level": []string{
+				"min:0",
+				"max:100",
+			},
 		},
 	})
 	return v.ValidateStruct()
@@ -0,0 +1,48 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// MediaHandlerValidator validates models used in handlers.MediaHandler
+type MediaHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewMediaHandlerValidator creates a new handlers.MediaHandler validator
+func NewMediaHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *MediaHandlerValidator) {
+	return &MediaHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateStore validates the requests.MediaStore request
+func (validator *MediaHandlerValidator) ValidateStore(_ context.Context, request requests.MediaStore) url.Values {
+	return govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"content_type": []string{
+				"required",
+				"in:" + strings.Join(entities.MediaContentTypes, ","),
+			},
+			"size": []string{
+				"required",
+				"min:1",
+			},
+		},
+	}).ValidateStruct()
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/NdoleStudio/httpsms/pkg/repositories"
 	"github.com/NdoleStudio/httpsms/pkg/services"
@@ -38,6 +41,29 @@ func NewMessageHandlerValidator(
 	}
 }
 
+const (
+	// messageIndexMaxMetadataFilters is the number of metadata filters which can be combined when fetching messages
+	messageIndexMaxMetadataFilters = 3
+
+	// messageMetadataValueMaxLength is the longest value of a metadata filter
+	messageMetadataValueMaxLength = 255
+
+	// messageMaxMetadata is the number of metadata keys which can be stored with a message
+	messageMaxMetadata = 10
+)
+
+// messageMetadataKeyPattern is a key of the metadata of a message e.g. twilio_sid
+var messageMetadataKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,40}$`)
+
+// messageContentMaxRule is the maximum length of the content of a message, the ciphertext of an encrypted content is
+// longer than the plaintext because it is encoded as text with the nonce of the cipher
+func messageContentMaxRule(isEncrypted bool) string {
+	if isEncrypted {
+		return "max:4096"
+	}
+	return "max:1024"
+}
+
 // ValidateMessageReceive validates the requests.MessageReceive request
 func (validator MessageHandlerValidator) ValidateMessageReceive(_ context.Context, request requests.MessageReceive) url.Values {
 	v := govalidator.New(govalidator.Options{
@@ -53,7 +79,10 @@ func (validator MessageHandlerValidator) ValidateMessageReceive(_ context.Contex
 			"content": []string{
 				"required",
 				"min:1",
-				"max:1024",
+				messageContentMaxRule(request.IsEncrypted),
+			},
+			"encryption_key_id": []string{
+				"max:255",
 			},
 			"sim": []string{
 				"required",
@@ -68,13 +97,16 @@ func (validator MessageHandlerValidator) ValidateMessageReceive(_ context.Contex
 	return v.ValidateStruct()
 }
 
-// ValidateMessageSend validates the requests.MessageSend request
+// ValidateMessageSend validates the requests.MessageSend request, the request is sanitized before it is validated but the errors reference the original input
 func (validator MessageHandlerValidator) ValidateMessageSend(ctx context.Context, userID entities.UserID, request requests.MessageSend) url.Values {
 	ctx, span := validator.tracer.Start(ctx)
 	defer span.End()
 
 	ctxLogger := validator.tracer.CtxLogger(validator.logger, span)
 
+	original := request
+	request.Sanitize()
+
 	v := govalidator.New(govalidator.Options{
 		Data: &request,
 		Rules: govalidator.MapData{
@@ -86,13 +118,31 @@ func (validator MessageHandlerValidator) ValidateMessageSend(ctx context.Context
 				"max:255",
 			},
 			"from": []string{
-				"required",
 				phoneNumberRule,
 			},
 			"content": []string{
 				"required",
 				"min:1",
-				"max:1024",
+				messageContentMaxRule(request.IsEncrypted),
+			},
+			"encryption_key_id": []string{
+				"max:255",
+			},
+			"sim": []string{
+				"in:" + strings.Join([]string{
+					string(entities.SIM1),
+					string(entities.SIM2),
+				}, ","),
+			},
+			"enforce_dnd": []string{
+				"in:" + strings.Join([]string{
+					string(services.DNDEnforcementDefer),
+					string(services.DNDEnforcementStrict),
+				}, ","),
+			},
+			"media_ids": []string{
+				fmt.Sprintf("max:%d", entities.MediaMaxPerMessage),
+				multipleUUIDRule,
 			},
 		},
 	})
@@ -102,60 +152,128 @@ func (validator MessageHandlerValidator) ValidateMessageSend(ctx context.Context
 		return result
 	}
 
-	_, err := validator.phoneService.Load(ctx, userID, request.From)
+	if request.RetryPolicy != nil {
+		if result = validator.validateRetryPolicy(result, "retry_policy.", *request.RetryPolicy); len(result) != 0 {
+			return result
+		}
+	}
+
+	if result = validator.validateMessageMetadata(result, request.Metadata); len(result) != 0 {
+		return result
+	}
+
+	if len(request.MediaIDs) > 0 && request.AllowFallback {
+		result.Add("allow_fallback", "the fallback provider cannot send media, send the message with media_ids without allow_fallback")
+		return result
+	}
+
+	if request.From == "" {
+		return validator.validateDefaultPhone(ctx, userID, request.SIM, result)
+	}
+
+	phone, err := validator.phoneService.Load(ctx, userID, request.From)
 	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
-		result.Add("from", fmt.Sprintf("no phone found with with 'from' number [%s]. install the android app on your phone to start sending messages", request.From))
+		result.Add("from", fmt.Sprintf("no phone found with with 'from' number [%s]. install the android app on your phone to start sending messages", original.From))
+		return result
 	}
 
 	if err != nil {
 		ctxLogger.Error(validator.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("could not load phone for user [%s] and phone [%s]", userID, request.From))))
-		result.Add("from", fmt.Sprintf("could not validate 'from' number [%s], please try again later", request.From))
+		result.Add("from", fmt.Sprintf("could not validate 'from' number [%s], please try again later", original.From))
+		return result
+	}
+
+	return validator.validatePhoneSIM(phone, request.SIM, result)
+}
+
+func (validator MessageHandlerValidator) validateDefaultPhone(ctx context.Context, userID entities.UserID, sim string, result url.Values) url.Values {
+	ctx, span := validator.tracer.Start(ctx)
+	defer span.End()
+
+	ctxLogger := validator.tracer.CtxLogger(validator.logger, span)
+
+	phone, err := validator.phoneService.LoadDefault(ctx, userID)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		result.Add("from", "the 'from' field is empty and you have no default phone. install the android app on your phone to start sending messages")
+		return result
+	}
+
+	if err != nil {
+		ctxLogger.Error(validator.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("could not load default phone for user [%s]", userID))))
+		result.Add("from", "could not load your default phone, please try again later")
+		return result
 	}
 
+	return validator.validatePhoneSIM(phone, sim, result)
+}
+
+// validatePhoneSIM makes sure the SIM used to send a message is registered on the phone
+func (validator MessageHandlerValidator) validatePhoneSIM(phone *entities.Phone, sim string, result url.Values) url.Values {
+	if sim == "" || phone.HasSIM(entities.SIM(sim)) {
+		return result
+	}
+
+	result.Add("sim", fmt.Sprintf("the SIM [%s] is not registered on the phone [%s], the valid options are [%s]", sim, phone.PhoneNumber, strings.Join(phone.RegisteredSIMs(), ", ")))
 	return result
 }
 
-// ValidateMessageBulkSend validates the requests.MessageBulkSend request
+// ValidateMessageBulkSend validates the requests.MessageBulkSend request, the request is sanitized before it is validated but the errors reference the original input
 func (validator MessageHandlerValidator) ValidateMessageBulkSend(ctx context.Context, userID entities.UserID, request requests.MessageBulkSend) url.Values {
 	ctx, span := validator.tracer.Start(ctx)
 	defer span.End()
 
 	ctxLogger := validator.tracer.CtxLogger(validator.logger, span)
 
-	v := govalidator.New(govalidator.Options{
-		Data: &request,
-		Rules: govalidator.MapData{
-			"to": []string{
-				"required",
-				"max:1000",
-				"min:1",
-				multipleContactPhoneNumberRule,
-			},
-			"from": []string{
-				"required",
-				phoneNumberRule,
-			},
-			"content": []string{
-				"required",
-				"min:1",
-				"max:1024",
-			},
+	original := request
+	request.Sanitize()
+
+	rules := govalidator.MapData{
+		"to": []string{
+			"required",
+			"max:1000",
+			"min:1",
+			multipleContactPhoneNumberRule,
+		},
+		"from": []string{
+			"required",
+			phoneNumberRule,
 		},
+		"content": []string{
+			"required",
+			"min:1",
+			"max:1024",
+		},
+	}
+
+	// the message is sent to the members of the group instead of the phone numbers in "to"
+	if request.GroupID != "" {
+		delete(rules, "to")
+		rules["group_id"] = []string{"uuid"}
+	}
+
+	v := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
 	})
 
 	result := v.ValidateStruct()
+	if request.GroupID != "" && len(request.To) != 0 {
+		result.Add("to", "The to field must be empty when the message is sent to a group with the group_id field")
+	}
+
 	if len(result) != 0 {
 		return result
 	}
 
 	_, err := validator.phoneService.Load(ctx, userID, request.From)
 	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
-		result.Add("from", fmt.Sprintf("no phone found with with 'from' number [%s]. Install the android app on your phone to start sending messages", request.From))
+		result.Add("from", fmt.Sprintf("no phone found with with 'from' number [%s]. Install the android app on your phone to start sending messages", original.From))
+		return result
 	}
 
 	if err != nil {
 		ctxLogger.Error(validator.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, fmt.Sprintf("could not load phone for user [%s] and phone [%s]", userID, request.From))))
-		result.Add("from", fmt.Sprintf("could not validate 'from' number [%s], please try again later", request.From))
+		result.Add("from", fmt.Sprintf("could not validate 'from' number [%s], please try again later", original.From))
 	}
 
 	return result
@@ -175,6 +293,20 @@ func (validator MessageHandlerValidator) ValidateMessageOutstanding(_ context.Co
 	return v.ValidateStruct()
 }
 
+// ValidateMessageQueueStats validates the requests.MessageQueueStats request
+func (validator MessageHandlerValidator) ValidateMessageQueueStats(_ context.Context, request requests.MessageQueueStats) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
 // ValidateMessageIndex validates the requests.MessageIndex request
 func (validator MessageHandlerValidator) ValidateMessageIndex(_ context.Context, request requests.MessageIndex) url.Values {
 	v := govalidator.New(govalidator.Options{
@@ -202,6 +334,167 @@ func (validator MessageHandlerValidator) ValidateMessageIndex(_ context.Context,
 				"required",
 				phoneNumberRule,
 			},
+			"status": []string{
+				fmt.Sprintf(
+					"in:%s,%s,%s,%s,%s,%s,%s,%s",
+					entities.MessageStatusPending,
+					entities.MessageStatusScheduled,
+					entities.MessageStatusSending,
+					entities.MessageStatusSent,
+					entities.MessageStatusReceived,
+					entities.MessageStatusFailed,
+					entities.MessageStatusDelivered,
+					entities.MessageStatusExpired,
+				),
+			},
+		},
+	})
+
+	result := v.ValidateStruct()
+	for key, value := range map[string]string{"after": request.After, "before": request.Before} {
+		if _, err := time.Parse(time.RFC3339, value); value != "" && err != nil {
+			result.Add(key, fmt.Sprintf("The %s field must be a valid RFC3339 timestamp e.g 2022-06-05T14:26:09+03:00", key))
+		}
+	}
+
+	return validator.validateMetadataFilters(result, request.Metadata)
+}
+
+// validateMetadataFilters makes sure that the metadata filters of requests.MessageIndex can be applied with the index
+// of the metadata, every filter is another containment condition so the number of filters is limited
+func (validator MessageHandlerValidator) validateMetadataFilters(result url.Values, metadata map[string]string) url.Values {
+	if len(metadata) > messageIndexMaxMetadataFilters {
+		result.Add("metadata", fmt.Sprintf("The metadata field cannot have more than %d filters", messageIndexMaxMetadataFilters))
+		return result
+	}
+
+	return validator.validateMetadataPairs(result, metadata)
+}
+
+// validateMessageMetadata makes sure that the metadata of requests.MessageSend can be found with the metadata filters of requests.MessageIndex
+func (validator MessageHandlerValidator) validateMessageMetadata(result url.Values, metadata map[string]string) url.Values {
+	if len(metadata) > messageMaxMetadata {
+		result.Add("metadata", fmt.Sprintf("The metadata field cannot have more than %d keys", messageMaxMetadata))
+		return result
+	}
+
+	return validator.validateMetadataPairs(result, metadata)
+}
+
+// validateMetadataPairs checks the keys and the values of the metadata of a message
+func (validator MessageHandlerValidator) validateMetadataPairs(result url.Values, metadata map[string]string) url.Values {
+	for key, value := range metadata {
+		if !messageMetadataKeyPattern.MatchString(key) {
+			result.Add("metadata", "The metadata keys must have at most 40 letters, numbers, dashes and underscores")
+			continue
+		}
+		if value == "" || utf8.RuneCountInString(value) > messageMetadataValueMaxLength {
+			result.Add("metadata", fmt.Sprintf("The metadata value of the key [%s] must have between 1 and %d characters", key, messageMetadataValueMaxLength))
+		}
+	}
+
+	return result
+}
+
+// ValidateMessageStatus validates the requests.MessageStatus request, at most 500 IDs can be fetched at once
+func (validator MessageHandlerValidator) ValidateMessageStatus(_ context.Context, request requests.MessageStatus) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"message_ids": []string{
+				"required",
+				"min:1",
+				"max:500",
+				multipleUUIDRule,
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateMessageUpdate validates the requests.MessageUpdate request
+func (validator MessageHandlerValidator) ValidateMessageUpdate(_ context.Context, request requests.MessageUpdate) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"messageID": []string{
+				"required",
+				"uuid",
+			},
+			"content": []string{
+				"required",
+				"min:1",
+				"max:1024",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateMessageForward validates the requests.MessageForward request
+func (validator MessageHandlerValidator) ValidateMessageForward(_ context.Context, request requests.MessageForward) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"messageID": []string{
+				"required",
+				"uuid",
+			},
+			"from": []string{
+				"required",
+				phoneNumberRule,
+			},
+			"to": []string{
+				"required",
+				contactPhoneNumberRule,
+			},
+			"prefix": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateMessageThreadReply validates the requests.MessageThreadReply request
+func (validator MessageHandlerValidator) ValidateMessageThreadReply(_ context.Context, request requests.MessageThreadReply) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"messageThreadID": []string{
+				"required",
+				"uuid",
+			},
+			"content": []string{
+				"required",
+				"min:1",
+				messageContentMaxRule(false),
+			},
+			"request_id": []string{
+				"max:255",
+			},
+			"sim": []string{
+				"in:" + strings.Join([]string{
+					string(entities.SIM1),
+					string(entities.SIM2),
+				}, ","),
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateMessageStream validates the requests.MessageStream request
+func (validator MessageHandlerValidator) ValidateMessageStream(_ context.Context, request requests.MessageStream) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				phoneNumberRule,
+			},
+			"last_event_id": []string{
+				"uuid",
+			},
 		},
 	})
 	return v.ValidateStruct()
@@ -0,0 +1,319 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/palantir/stacktrace"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubPhoneRepository does not have any entities.Phone except the default phone
+type stubPhoneRepository struct {
+	repositories.PhoneRepository
+	loaded       []string
+	defaultPhone *entities.Phone
+}
+
+func (repository *stubPhoneRepository) Load(_ context.Context, _ entities.UserID, phoneNumber string) (*entities.Phone, error) {
+	repository.loaded = append(repository.loaded, phoneNumber)
+	return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "phone does not exist")
+}
+
+func (repository *stubPhoneRepository) LoadDefault(_ context.Context, userID entities.UserID) (*entities.Phone, error) {
+	if repository.defaultPhone == nil || repository.defaultPhone.UserID != userID {
+		return nil, stacktrace.NewErrorWithCode(repositories.ErrCodeNotFound, "default phone does not exist")
+	}
+	return repository.defaultPhone, nil
+}
+
+func newTestMessageHandlerValidator(repository repositories.PhoneRepository) *MessageHandlerValidator {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	tracer := telemetry.NewOtelLogger("test", logger)
+	return NewMessageHandlerValidator(logger, tracer, services.NewPhoneService(logger, tracer, repository, nil, 0, services.PhoneClockConfig{}))
+}
+
+func TestMessageHandlerValidator_ValidateMessageSend(t *testing.T) {
+	t.Run("the errors of every invalid field are returned", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+
+		// Act
+		errors := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{To: "not a number", SIM: "SIM3"})
+
+		// Assert
+		assert.NotEmpty(t, errors["to"])
+		assert.NotEmpty(t, errors["content"])
+		assert.NotEmpty(t, errors["sim"])
+		assert.NotContains(t, errors, "from")
+	})
+
+	t.Run("the errors reference the original input instead of the sanitized input", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		repository := &stubPhoneRepository{}
+		validator := newTestMessageHandlerValidator(repository)
+
+		// Act
+		errors := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			From:    "18005550199",
+			To:      "+18005550100",
+			Content: "This is a sample text message",
+		})
+
+		// Assert
+		assert.Equal(t, []string{"+18005550199"}, repository.loaded)
+		assert.Len(t, errors["from"], 1)
+		assert.Contains(t, errors["from"][0], "[18005550199]")
+	})
+
+	t.Run("media cannot be sent by the fallback provider", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+
+		// Act
+		invalid := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:       "+18005550100",
+			Content:  "This is a sample text message",
+			MediaIDs: []string{uuid.NewString(), "not-a-uuid"},
+		})
+		fallback := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:            "+18005550100",
+			Content:       "This is a sample text message",
+			MediaIDs:      []string{uuid.NewString()},
+			AllowFallback: true,
+		})
+
+		// Assert
+		assert.NotEmpty(t, invalid["media_ids"])
+		assert.NotEmpty(t, fallback["allow_fallback"])
+		assert.NotContains(t, fallback, "from")
+	})
+
+	t.Run("the retry policy of a message must be within the bounds", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+
+		// Act
+		errors := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:      "+18005550100",
+			Content: "This is a sample text message",
+			RetryPolicy: &entities.RetryPolicy{
+				MaxAttempts:        50,
+				BackoffBaseSeconds: 600,
+				BackoffCapSeconds:  60,
+				BackoffMultiplier:  0.5,
+			},
+		})
+
+		// Assert
+		assert.NotEmpty(t, errors["retry_policy.max_attempts"])
+		assert.NotEmpty(t, errors["retry_policy.backoff_cap_seconds"])
+		assert.NotEmpty(t, errors["retry_policy.backoff_multiplier"])
+		assert.NotContains(t, errors, "retry_policy.backoff_base_seconds")
+		assert.NotContains(t, errors, "from")
+	})
+
+	t.Run("the metadata of a message must be searchable", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+		tooMany := map[string]string{}
+		for i := 0; i <= 10; i++ {
+			tooMany[fmt.Sprintf("key_%d", i)] = "value"
+		}
+
+		// Act
+		invalid := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:       "+18005550100",
+			Content:  "This is a sample text message",
+			Metadata: map[string]string{"order id": "1234", "order_id": ""},
+		})
+		exceeded := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:       "+18005550100",
+			Content:  "This is a sample text message",
+			Metadata: tooMany,
+		})
+
+		// Assert
+		assert.Len(t, invalid["metadata"], 2)
+		assert.Len(t, exceeded["metadata"], 1)
+		assert.NotContains(t, exceeded, "from")
+	})
+
+	t.Run("the default phone is used when the from field is empty", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		repository := &stubPhoneRepository{defaultPhone: &entities.Phone{
+			UserID:      "user-a",
+			PhoneNumber: "+18005550199",
+			SIMs:        []entities.PhoneSIM{{Slot: 0}},
+		}}
+		validator := newTestMessageHandlerValidator(repository)
+
+		// Act
+		valid := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:      "+18005550100",
+			Content: "This is a sample text message",
+		})
+		unregisteredSIM := validator.ValidateMessageSend(context.Background(), "user-a", requests.MessageSend{
+			To:      "+18005550100",
+			Content: "This is a sample text message",
+			SIM:     "SIM2",
+		})
+		withoutDefault := validator.ValidateMessageSend(context.Background(), "user-b", requests.MessageSend{
+			To:      "+18005550100",
+			Content: "This is a sample text message",
+		})
+
+		// Assert
+		assert.Empty(t, valid)
+		assert.Empty(t, repository.loaded)
+		assert.NotEmpty(t, unregisteredSIM["sim"])
+		assert.NotContains(t, unregisteredSIM, "from")
+		assert.Len(t, withoutDefault["from"], 1)
+		assert.Contains(t, withoutDefault["from"][0], "no default phone")
+	})
+}
+
+func TestMessageHandlerValidator_ValidateMessageReceive(t *testing.T) {
+	t.Run("the errors of every invalid field are returned", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+
+		// Arrange
+		validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+
+		// Act
+		errors := validator.ValidateMessageReceive(context.Background(), requests.MessageReceive{To: "invalid", SIM: "SIM1"})
+
+		// Assert
+		assert.Len(t, errors, 3)
+		assert.Contains(t, errors, "to")
+		assert.Contains(t, errors, "from")
+		assert.Contains(t, errors, "content")
+	})
+}
+
+func TestMessageHandlerValidator_ValidateMessageStatus(t *testing.T) {
+	ids := func(count int) []string {
+		result := make([]string, 0, count)
+		for i := 0; i < count; i++ {
+			result = append(result, uuid.NewString())
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		request requests.MessageStatus
+		valid   bool
+	}{
+		{name: "at most 500 IDs are valid", request: requests.MessageStatus{MessageIDs: ids(500)}, valid: true},
+		{name: "more than 500 IDs are invalid", request: requests.MessageStatus{MessageIDs: ids(501)}},
+		{name: "no IDs are invalid", request: requests.MessageStatus{MessageIDs: []string{}}},
+		{name: "an ID which is not a UUID is invalid", request: requests.MessageStatus{MessageIDs: append(ids(2), "not-a-uuid")}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Arrange
+			validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+
+			// Act
+			errors := validator.ValidateMessageStatus(context.Background(), test.request)
+
+			// Assert
+			if test.valid {
+				assert.Empty(t, errors)
+			} else {
+				assert.NotEmpty(t, errors["message_ids"])
+			}
+		})
+	}
+}
+
+func TestMessageHandlerValidator_ValidateMessageIndex(t *testing.T) {
+	index := func(metadata map[string]string) requests.MessageIndex {
+		request := requests.MessageIndex{
+			Owner:    "+18005550199",
+			Contact:  "+18005550100",
+			Status:   entities.MessageStatusDelivered,
+			After:    "2022-06-05T14:26:09+03:00",
+			Before:   "2022-06-06T14:26:09+03:00",
+			Metadata: metadata,
+		}
+		return request.Sanitize()
+	}
+
+	tests := []struct {
+		name    string
+		request requests.MessageIndex
+		field   string
+	}{
+		{name: "3 metadata filters are valid", request: index(map[string]string{"order_id": "42", "channel": "web", "twilio_sid": "SM123"})},
+		{name: "more than 3 metadata filters are invalid", request: index(map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}), field: "metadata"},
+		{name: "a metadata key with a bracket is invalid", request: index(map[string]string{"order[id]": "42"}), field: "metadata"},
+		{name: "an empty metadata value is invalid", request: index(map[string]string{"order_id": ""}), field: "metadata"},
+		{name: "an unknown status is invalid", request: func() requests.MessageIndex {
+			request := index(nil)
+			request.Status = "archived"
+			return request
+		}(), field: "status"},
+		{name: "a time which is not RFC3339 is invalid", request: func() requests.MessageIndex {
+			request := index(nil)
+			request.After = "2022-06-05"
+			return request
+		}(), field: "after"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// Setup
+			t.Parallel()
+
+			// Arrange
+			validator := newTestMessageHandlerValidator(&stubPhoneRepository{})
+
+			// Act
+			errors := validator.ValidateMessageIndex(context.Background(), test.request)
+
+			// Assert
+			if test.field == "" {
+				assert.Empty(t, errors)
+			} else {
+				assert.NotEmpty(t, errors[test.field])
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// MessageRateHandlerValidator validates models used in handlers.MessageRateHandler
+type MessageRateHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewMessageRateHandlerValidator creates a new handlers.MessageRateHandler validator
+func NewMessageRateHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *MessageRateHandlerValidator) {
+	return &MessageRateHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateStore validates the requests.MessageRateStore request
+func (validator *MessageRateHandlerValidator) ValidateStore(_ context.Context, request requests.MessageRateStore) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				phoneNumberRule,
+			},
+			"currency": []string{
+				"required",
+				"max:10",
+			},
+		},
+	}).ValidateStruct()
+	return validator.validateRate(result, request)
+}
+
+// ValidateUpdate validates the requests.MessageRateUpdate request
+func (validator *MessageRateHandlerValidator) ValidateUpdate(ctx context.Context, request requests.MessageRateUpdate) url.Values {
+	result := validator.ValidateStore(ctx, request.MessageRateStore)
+	for key, values := range validator.ValidateUUID(ctx, request.MessageRateID, "messageRateID") {
+		result[key] = append(result[key], values...)
+	}
+	return result
+}
+
+// validateRate makes sure the rate is not negative and the prefix and the effective_from time are valid
+func (validator *MessageRateHandlerValidator) validateRate(result url.Values, request requests.MessageRateStore) url.Values {
+	if request.Rate < 0 {
+		result.Add("rate", "The rate field must be a cost which is not negative e.g. 0.0075")
+	}
+
+	if _, err := time.Parse(time.RFC3339, request.EffectiveFrom); err != nil {
+		result.Add("effective_from", "The effective_from field must be a valid RFC3339 timestamp e.g 2022-06-01T00:00:00Z")
+	}
+
+	return validator.validatePhoneNumberPrefix(result, request.Prefix)
+}
+
+// ValidateCostIndex validates the requests.MessageCostIndex request
+func (validator *MessageRateHandlerValidator) ValidateCostIndex(_ context.Context, request requests.MessageCostIndex) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"request_id": []string{
+				"max:255",
+			},
+			"group_by": []string{
+				"in:request_id",
+			},
+		},
+	}).ValidateStruct()
+
+	timestamps := map[string]time.Time{}
+	for key, value := range map[string]string{"from": request.From, "to": request.To} {
+		if value == "" {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			result.Add(key, fmt.Sprintf("The %s field must be a valid RFC3339 timestamp e.g 2022-06-05T14:26:09+03:00", key))
+			continue
+		}
+		timestamps[key] = timestamp
+	}
+
+	if from, ok := timestamps["from"]; ok {
+		if to, ok := timestamps["to"]; ok && !to.After(from) {
+			result.Add("to", "The to field must be after the from field")
+		}
+	}
+
+	return result
+}
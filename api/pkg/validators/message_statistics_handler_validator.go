@@ -0,0 +1,123 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// messageTimeSeriesMaxRanges is the longest time range of a time series with each granularity so that a chart does not have too many buckets
+var messageTimeSeriesMaxRanges = map[entities.MessageStatisticsGranularity]time.Duration{
+	entities.MessageStatisticsGranularityHour: 31 * 24 * time.Hour,
+	entities.MessageStatisticsGranularityDay:  366 * 24 * time.Hour,
+}
+
+// messageLatencyMaxRange is the longest time range of the send latency of messages
+const messageLatencyMaxRange = 366 * 24 * time.Hour
+
+// MessageStatisticsHandlerValidator validates models used in handlers.MessageStatisticsHandler
+type MessageStatisticsHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewMessageStatisticsHandlerValidator creates a new handlers.MessageStatisticsHandler validator
+func NewMessageStatisticsHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *MessageStatisticsHandlerValidator) {
+	return &MessageStatisticsHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateTimeSeries validates the requests.MessageTimeSeries request
+func (validator *MessageStatisticsHandlerValidator) ValidateTimeSeries(_ context.Context, request requests.MessageTimeSeries) url.Values {
+	rules := govalidator.MapData{
+		"granularity": []string{
+			"required",
+			fmt.Sprintf("in:%s,%s", entities.MessageStatisticsGranularityHour, entities.MessageStatisticsGranularityDay),
+		},
+	}
+	if request.Owner != "" {
+		rules["owner"] = []string{phoneNumberRule}
+	}
+
+	result := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
+	}).ValidateStruct()
+
+	if _, err := time.LoadLocation(request.Timezone); err != nil {
+		result.Add("timezone", fmt.Sprintf("The timezone field must be a valid timezone e.g. Europe/Berlin, [%s] is not a valid timezone", request.Timezone))
+	}
+
+	limit, ok := messageTimeSeriesMaxRanges[entities.MessageStatisticsGranularity(request.Granularity)]
+	if !ok {
+		limit = messageLatencyMaxRange
+	}
+
+	return validator.validateTimeRange(result, request.From, request.To, limit)
+}
+
+// ValidateLatency validates the requests.MessageLatency request
+func (validator *MessageStatisticsHandlerValidator) ValidateLatency(_ context.Context, request requests.MessageLatency) url.Values {
+	rules := govalidator.MapData{
+		"threshold": []string{
+			"required",
+			"numeric",
+			"min:1",
+			"max:86400",
+		},
+	}
+	if request.Owner != "" {
+		rules["owner"] = []string{phoneNumberRule}
+	}
+
+	result := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
+	}).ValidateStruct()
+
+	if objective, err := strconv.ParseFloat(request.Objective, 64); err != nil || objective <= 0 || objective >= 1 {
+		result.Add("objective", fmt.Sprintf("The objective field must be a fraction between 0 and 1 e.g. 0.95, [%s] is not a valid objective", request.Objective))
+	}
+
+	return validator.validateTimeRange(result, request.From, request.To, messageLatencyMaxRange)
+}
+
+// validateTimeRange makes sure the from and to RFC3339 timestamps form a time range which is not longer than the limit
+func (validator *MessageStatisticsHandlerValidator) validateTimeRange(result url.Values, from string, to string, limit time.Duration) url.Values {
+	timestamps := map[string]time.Time{}
+	for key, value := range map[string]string{"from": from, "to": to} {
+		timestamp, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			result.Add(key, fmt.Sprintf("The %s field must be a valid RFC3339 timestamp e.g 2022-06-05T14:26:09+03:00", key))
+			continue
+		}
+		timestamps[key] = timestamp
+	}
+
+	if len(timestamps) != 2 {
+		return result
+	}
+
+	if !timestamps["to"].After(timestamps["from"]) {
+		result.Add("to", "The to field must be after the from field")
+	}
+
+	if timestamps["to"].Sub(timestamps["from"]) > limit {
+		result.Add("from", fmt.Sprintf("The time range between the from and to fields cannot be more than %d days", int(limit.Hours()/24)))
+	}
+
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
@@ -55,6 +56,9 @@ func (validator *MessageThreadHandlerValidator) ValidateMessageThreadIndex(_ con
 				"required",
 				phoneNumberRule,
 			},
+			"label": []string{
+				fmt.Sprintf("max:%d", contactLabelMaxLength),
+			},
 		},
 	})
 	return v.ValidateStruct()
@@ -74,3 +78,41 @@ func (validator *MessageThreadHandlerValidator) ValidateUpdate(_ context.Context
 
 	return v.ValidateStruct()
 }
+
+// ValidateMute validates the requests.MessageThreadMute request
+func (validator *MessageThreadHandlerValidator) ValidateMute(_ context.Context, request requests.MessageThreadMute) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"messageThreadID": []string{
+				"required",
+				"uuid",
+			},
+		},
+	})
+
+	result := v.ValidateStruct()
+	if request.MutedUntil != nil && !request.MutedUntil.After(time.Now().UTC()) {
+		result.Add("muted_until", "The muted_until field must be a time in the future")
+	}
+	return result
+}
+
+// ValidateWebsocketCommand validates the requests.WebsocketCommand which is received on a websocket connection
+func (validator *MessageThreadHandlerValidator) ValidateWebsocketCommand(_ context.Context, request requests.WebsocketCommand) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"type": []string{
+				"required",
+				fmt.Sprintf("in:%s,%s", requests.WebsocketCommandMarkThreadRead, requests.WebsocketCommandTyping),
+			},
+			"message_thread_id": []string{
+				"required",
+				"uuid",
+			},
+		},
+	})
+
+	return v.ValidateStruct()
+}
@@ -0,0 +1,61 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// NotificationPreferenceHandlerValidator validates models used in handlers.NotificationPreferenceHandler
+type NotificationPreferenceHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewNotificationPreferenceHandlerValidator creates a new handlers.NotificationPreferenceHandler validator
+func NewNotificationPreferenceHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *NotificationPreferenceHandlerValidator) {
+	return &NotificationPreferenceHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateUpsert validates the requests.NotificationPreferenceUpsert request
+func (validator *NotificationPreferenceHandlerValidator) ValidateUpsert(_ context.Context, request requests.NotificationPreferenceUpsert) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"threshold": []string{
+				"required",
+				"min:1",
+				"max:1000",
+			},
+		},
+	}).ValidateStruct()
+
+	if (request.QuietHoursStart == "") != (request.QuietHoursEnd == "") {
+		result.Add("quiet_hours_start", "The quiet_hours_start and quiet_hours_end fields must both be set or both be empty")
+		return result
+	}
+
+	for field, value := range map[string]string{"quiet_hours_start": request.QuietHoursStart, "quiet_hours_end": request.QuietHoursEnd} {
+		if _, err := time.Parse("15:04", value); value != "" && err != nil {
+			result.Add(field, fmt.Sprintf("The %s field must be a time in the format HH:MM e.g. 22:00", field))
+		}
+	}
+
+	if request.QuietHoursStart != "" && request.QuietHoursStart == request.QuietHoursEnd {
+		result.Add("quiet_hours_end", "The quiet_hours_end field must be different from the quiet_hours_start field")
+	}
+
+	return result
+}
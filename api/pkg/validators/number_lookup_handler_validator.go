@@ -0,0 +1,43 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// NumberLookupHandlerValidator validates models used in handlers.NumberLookupHandler
+type NumberLookupHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewNumberLookupHandlerValidator creates a new handlers.NumberLookupHandler validator
+func NewNumberLookupHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *NumberLookupHandlerValidator) {
+	return &NumberLookupHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateShow validates the requests.NumberLookupShow request
+func (validator *NumberLookupHandlerValidator) ValidateShow(_ context.Context, request requests.NumberLookupShow) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"phone_number": []string{
+				"required",
+				phoneNumberRule,
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
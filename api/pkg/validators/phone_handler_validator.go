@@ -5,14 +5,22 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/NdoleStudio/httpsms/pkg/entities"
 
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/nyaruka/phonenumbers"
 	"github.com/thedevsaddam/govalidator"
 )
 
+// phoneMaxSIMs is the number of SIM card slots which can be used to send messages i.e entities.SIM1 and entities.SIM2
+const phoneMaxSIMs = 2
+
+// phoneMaxDailySendLimit is the maximum number of messages which a phone can be allowed to send in a day
+const phoneMaxDailySendLimit = 100000
+
 // PhoneHandlerValidator validates models used in handlers.PhoneHandler
 type PhoneHandlerValidator struct {
 	validator
@@ -73,8 +81,8 @@ func (validator *PhoneHandlerValidator) ValidateUpsert(_ context.Context, reques
 				"max:60",
 			},
 			"max_send_attempts": []string{
-				"min:0",
-				"max:5",
+				"min:1",
+				"max:10",
 			},
 			"sim": []string{
 				"required",
@@ -84,6 +92,14 @@ func (validator *PhoneHandlerValidator) ValidateUpsert(_ context.Context, reques
 				"min:60",
 				"max:3600",
 			},
+			"heartbeat_dead_threshold_seconds": []string{
+				"min:960",
+				"max:86400",
+			},
+			"heartbeat_alert_cooldown_seconds": []string{
+				"min:60",
+				"max:604800",
+			},
 		},
 	})
 
@@ -96,6 +112,51 @@ func (validator *PhoneHandlerValidator) ValidateUpsert(_ context.Context, reques
 		result.Add("message_expiration_seconds", "message_expiration_seconds cannot be 0 when max_send_attempts is greater than 0")
 	}
 
+	if request.DailySendLimit != nil && *request.DailySendLimit > phoneMaxDailySendLimit {
+		result.Add("daily_send_limit", fmt.Sprintf("The daily_send_limit field cannot be greater than %d", phoneMaxDailySendLimit))
+	}
+
+	if request.DailySendLimitTimezone != nil && *request.DailySendLimitTimezone != "" {
+		if _, err := time.LoadLocation(*request.DailySendLimitTimezone); err != nil {
+			result.Add("daily_send_limit_timezone", fmt.Sprintf("The daily_send_limit_timezone field must be a valid timezone e.g. Africa/Douala, [%s] is not a valid timezone", *request.DailySendLimitTimezone))
+		}
+	}
+
+	if request.SIMs != nil {
+		result = validator.validateSIMs(*request.SIMs, result)
+	}
+
+	return result
+}
+
+func (validator *PhoneHandlerValidator) validateSIMs(sims []requests.PhoneUpsertSIM, result url.Values) url.Values {
+	if len(sims) > phoneMaxSIMs {
+		result.Add("sims", fmt.Sprintf("The sims field cannot contain more than %d SIM cards", phoneMaxSIMs))
+		return result
+	}
+
+	slots := map[uint]bool{}
+	for index, sim := range sims {
+		if sim.Slot >= phoneMaxSIMs {
+			result.Add("sims", fmt.Sprintf("The slot of the SIM card in index [%d] must be between 0 and %d", index, phoneMaxSIMs-1))
+		}
+
+		if slots[sim.Slot] {
+			result.Add("sims", fmt.Sprintf("The slot [%d] of the SIM card in index [%d] is duplicated", sim.Slot, index))
+		}
+		slots[sim.Slot] = true
+
+		if len(sim.CarrierName) > 100 {
+			result.Add("sims", fmt.Sprintf("The carrier name of the SIM card in index [%d] cannot be more than 100 characters", index))
+		}
+
+		if sim.PhoneNumber != "" {
+			if _, err := phonenumbers.Parse(sim.PhoneNumber, phonenumbers.UNKNOWN_REGION); err != nil {
+				result.Add("sims", fmt.Sprintf("The phone number of the SIM card in index [%d] must be a valid E.164 phone number", index))
+			}
+		}
+	}
+
 	return result
 }
 
@@ -108,6 +169,9 @@ func (validator *PhoneHandlerValidator) ValidateDelete(_ context.Context, reques
 				"required",
 				"uuid",
 			},
+			"keep_pending": []string{
+				"in:true,false",
+			},
 		},
 	})
 
@@ -0,0 +1,61 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// RoutingRuleHandlerValidator validates models used in handlers.RoutingRuleHandler
+type RoutingRuleHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewRoutingRuleHandlerValidator creates a new handlers.RoutingRuleHandler validator
+func NewRoutingRuleHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *RoutingRuleHandlerValidator) {
+	return &RoutingRuleHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateStore validates the requests.RoutingRuleStore request
+func (validator *RoutingRuleHandlerValidator) ValidateStore(_ context.Context, request requests.RoutingRuleStore) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+		},
+	}).ValidateStruct()
+	return validator.validatePhoneNumberPrefix(result, request.Prefix)
+}
+
+// ValidateUpdate validates the requests.RoutingRuleUpdate request
+func (validator *RoutingRuleHandlerValidator) ValidateUpdate(_ context.Context, request requests.RoutingRuleUpdate) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"routingRuleID": []string{
+				"required",
+				"uuid",
+			},
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+		},
+	}).ValidateStruct()
+	return validator.validatePhoneNumberPrefix(result, request.Prefix)
+}
@@ -0,0 +1,25 @@
+package validators
+
+import (
+	"fmt"
+
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+)
+
+// ShortLinkHandlerValidator validates models used in handlers.ShortLinkHandler
+type ShortLinkHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewShortLinkHandlerValidator creates a new handlers.ShortLinkHandler validator
+func NewShortLinkHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *ShortLinkHandlerValidator) {
+	return &ShortLinkHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
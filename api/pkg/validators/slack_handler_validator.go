@@ -0,0 +1,124 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// SlackHandlerValidator validates models used in handlers.SlackHandler
+type SlackHandlerValidator struct {
+	validator
+	logger       telemetry.Logger
+	tracer       telemetry.Tracer
+	phoneService *services.PhoneService
+}
+
+// NewSlackHandlerValidator creates a new handlers.SlackHandler validator
+func NewSlackHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	phoneService *services.PhoneService,
+) (v *SlackHandlerValidator) {
+	return &SlackHandlerValidator{
+		logger:       logger.WithService(fmt.Sprintf("%T", v)),
+		tracer:       tracer,
+		phoneService: phoneService,
+	}
+}
+
+// ValidateIndex validates the requests.SlackIndex request
+func (validator *SlackHandlerValidator) ValidateIndex(_ context.Context, request requests.SlackIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+			"query": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateStore validates the requests.SlackStore request
+func (validator *SlackHandlerValidator) ValidateStore(ctx context.Context, userID entities.UserID, request requests.SlackStore) url.Values {
+	return validator.validateSlack(ctx, userID, request, govalidator.MapData{})
+}
+
+// ValidateUpdate validates the requests.SlackUpdate request
+func (validator *SlackHandlerValidator) ValidateUpdate(ctx context.Context, userID entities.UserID, request requests.SlackUpdate) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"slackID": []string{
+				"required",
+				"uuid",
+			},
+		},
+	}).ValidateStruct()
+	if len(result) > 0 {
+		return result
+	}
+
+	return validator.validateSlack(ctx, userID, request.SlackStore, govalidator.MapData{})
+}
+
+func (validator *SlackHandlerValidator) validateSlack(ctx context.Context, userID entities.UserID, request requests.SlackStore, rules govalidator.MapData) url.Values {
+	ctx, span := validator.tracer.Start(ctx)
+	defer span.End()
+
+	rules["name"] = []string{"required", "min:1", "max:255"}
+	rules["owner"] = []string{"required", phoneNumberRule}
+	rules["channel"] = []string{"max:255"}
+	rules["bot_token"] = []string{"max:255"}
+	if request.WebhookURL != "" {
+		rules["webhook_url"] = []string{"url", "max:1000"}
+	}
+
+	v := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
+	})
+
+	result := v.ValidateStruct()
+	if len(result) > 0 {
+		return result
+	}
+
+	switch {
+	case request.WebhookURL == "" && request.BotToken == "":
+		result.Add("webhook_url", "The webhook_url field is required when the bot_token field is empty")
+	case request.WebhookURL != "" && request.BotToken != "":
+		result.Add("bot_token", "The bot_token field must be empty when the webhook_url field is set")
+	case request.BotToken != "" && request.Channel == "":
+		result.Add("channel", "The channel field is required when the bot_token field is set")
+	case request.ThreadByContact && request.BotToken == "":
+		result.Add("thread_by_contact", "The thread_by_contact field requires the bot_token field because slack incoming webhooks cannot reply in threads")
+	}
+
+	_, err := validator.phoneService.Load(ctx, userID, request.Owner)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		result.Add("owner", fmt.Sprintf("The phone number [%s] is not available in your account. Install the android app on your phone to post its messages to slack", request.Owner))
+	}
+
+	return result
+}
@@ -0,0 +1,53 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// SummaryReportHandlerValidator validates models used in handlers.SummaryReportHandler
+type SummaryReportHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewSummaryReportHandlerValidator creates a new handlers.SummaryReportHandler validator
+func NewSummaryReportHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *SummaryReportHandlerValidator) {
+	return &SummaryReportHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateUpsert validates the requests.SummaryReportScheduleUpsert request
+func (validator *SummaryReportHandlerValidator) ValidateUpsert(_ context.Context, request requests.SummaryReportScheduleUpsert) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"frequency": []string{
+				"required",
+				fmt.Sprintf("in:%s,%s", entities.SummaryReportFrequencyDaily, entities.SummaryReportFrequencyWeekly),
+			},
+			"hour": []string{
+				"max:23",
+			},
+		},
+	}).ValidateStruct()
+
+	if _, err := time.LoadLocation(request.Timezone); request.Timezone != "" && err != nil {
+		result.Add("timezone", fmt.Sprintf("The timezone field must be a valid timezone e.g. Europe/Berlin, [%s] is not a valid timezone", request.Timezone))
+	}
+
+	return result
+}
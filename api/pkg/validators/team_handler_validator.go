@@ -0,0 +1,63 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// TeamHandlerValidator validates models used in handlers.TeamHandler
+type TeamHandlerValidator struct {
+	validator
+	logger telemetry.Logger
+	tracer telemetry.Tracer
+}
+
+// NewTeamHandlerValidator creates a new handlers.TeamHandler validator
+func NewTeamHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+) (v *TeamHandlerValidator) {
+	return &TeamHandlerValidator{
+		logger: logger.WithService(fmt.Sprintf("%T", v)),
+		tracer: tracer,
+	}
+}
+
+// ValidateInvitationStore validates the requests.TeamInvitationStore request
+func (validator *TeamHandlerValidator) ValidateInvitationStore(_ context.Context, request requests.TeamInvitationStore) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"email": []string{
+				"required",
+				"email",
+				"max:255",
+			},
+			"role": []string{
+				"required",
+				fmt.Sprintf("in:%s,%s", entities.TeamRoleAdmin, entities.TeamRoleMember),
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateJoin validates the requests.TeamJoin request
+func (validator *TeamHandlerValidator) ValidateJoin(_ context.Context, request requests.TeamJoin) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"token": []string{
+				"required",
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
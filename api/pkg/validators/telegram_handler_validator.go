@@ -0,0 +1,131 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/repositories"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/services"
+	"github.com/NdoleStudio/httpsms/pkg/telegram"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/palantir/stacktrace"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// TelegramHandlerValidator validates models used in handlers.TelegramHandler
+type TelegramHandlerValidator struct {
+	validator
+	logger       telemetry.Logger
+	tracer       telemetry.Tracer
+	client       *telegram.Client
+	phoneService *services.PhoneService
+}
+
+// NewTelegramHandlerValidator creates a new handlers.TelegramHandler validator
+func NewTelegramHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	client *telegram.Client,
+	phoneService *services.PhoneService,
+) (v *TelegramHandlerValidator) {
+	return &TelegramHandlerValidator{
+		logger:       logger.WithService(fmt.Sprintf("%T", v)),
+		tracer:       tracer,
+		client:       client,
+		phoneService: phoneService,
+	}
+}
+
+// ValidateIndex validates the requests.TelegramIndex request
+func (validator *TelegramHandlerValidator) ValidateIndex(_ context.Context, request requests.TelegramIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+			"query": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateStore validates the requests.TelegramStore request
+func (validator *TelegramHandlerValidator) ValidateStore(ctx context.Context, userID entities.UserID, request requests.TelegramStore) url.Values {
+	return validator.validateTelegram(ctx, userID, request)
+}
+
+// ValidateUpdate validates the requests.TelegramUpdate request
+func (validator *TelegramHandlerValidator) ValidateUpdate(ctx context.Context, userID entities.UserID, request requests.TelegramUpdate) url.Values {
+	result := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"telegramID": []string{
+				"required",
+				"uuid",
+			},
+		},
+	}).ValidateStruct()
+	if len(result) > 0 {
+		return result
+	}
+
+	return validator.validateTelegram(ctx, userID, request.TelegramStore)
+}
+
+func (validator *TelegramHandlerValidator) validateTelegram(ctx context.Context, userID entities.UserID, request requests.TelegramStore) url.Values {
+	ctx, span, ctxLogger := validator.tracer.StartWithLogger(ctx, validator.logger)
+	defer span.End()
+
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"name": []string{
+				"required",
+				"min:1",
+				"max:255",
+			},
+			"owner": []string{
+				"required",
+				phoneNumberRule,
+			},
+			"bot_token": []string{
+				"required",
+				"max:255",
+			},
+		},
+	})
+
+	result := v.ValidateStruct()
+	if request.ChatID == 0 {
+		result.Add("chat_id", "The chat_id field is required")
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	if _, _, err := validator.client.Bot.GetMe(ctx, request.BotToken); err != nil {
+		ctxLogger.Warn(validator.tracer.WrapErrorSpan(span, stacktrace.Propagate(err, "cannot fetch the telegram bot with the bot token")))
+		result.Add("bot_token", "The bot_token field is not the token of a telegram bot, create a bot with @BotFather to get a token")
+	}
+
+	_, err := validator.phoneService.Load(ctx, userID, request.Owner)
+	if stacktrace.GetCode(err) == repositories.ErrCodeNotFound {
+		result.Add("owner", fmt.Sprintf("The phone number [%s] is not available in your account. Install the android app on your phone to forward its messages to telegram", request.Owner))
+	}
+
+	return result
+}
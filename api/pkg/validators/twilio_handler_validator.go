@@ -0,0 +1,78 @@
+package validators
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/requests"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/NdoleStudio/httpsms/pkg/twilio"
+	"github.com/thedevsaddam/govalidator"
+)
+
+// TwilioHandlerValidator validates models used in handlers.TwilioHandler and handlers.TwilioImportHandler
+type TwilioHandlerValidator struct {
+	validator
+	logger           telemetry.Logger
+	tracer           telemetry.Tracer
+	messageValidator *MessageHandlerValidator
+}
+
+// NewTwilioHandlerValidator creates a new handlers.TwilioHandler validator
+func NewTwilioHandlerValidator(
+	logger telemetry.Logger,
+	tracer telemetry.Tracer,
+	messageValidator *MessageHandlerValidator,
+) (v *TwilioHandlerValidator) {
+	return &TwilioHandlerValidator{
+		logger:           logger.WithService(fmt.Sprintf("%T", v)),
+		tracer:           tracer,
+		messageValidator: messageValidator,
+	}
+}
+
+// ValidateMessageSend validates the requests.TwilioMessageSend request like a requests.MessageSend
+func (validator *TwilioHandlerValidator) ValidateMessageSend(ctx context.Context, userID entities.UserID, request requests.TwilioMessageSend) url.Values {
+	ctx, span := validator.tracer.Start(ctx)
+	defer span.End()
+
+	request.Sanitize()
+
+	if request.StatusCallback != "" {
+		v := govalidator.New(govalidator.Options{
+			Data: &request,
+			Rules: govalidator.MapData{
+				"status_callback": []string{
+					"url",
+					"max:255",
+				},
+			},
+		})
+		if result := v.ValidateStruct(); len(result) != 0 {
+			return result
+		}
+	}
+
+	return validator.messageValidator.ValidateMessageSend(ctx, userID, request.ToMessageSend())
+}
+
+// ValidateImport validates the requests.TwilioImport request
+func (validator *TwilioHandlerValidator) ValidateImport(_ context.Context, request requests.TwilioImport) url.Values {
+	rules := govalidator.MapData{
+		"format": []string{
+			"required",
+			fmt.Sprintf("in:%s,%s", twilio.ExportFormatCSV, twilio.ExportFormatJSON),
+		},
+	}
+	if request.Owner != "" {
+		rules["owner"] = []string{phoneNumberRule}
+	}
+
+	v := govalidator.New(govalidator.Options{
+		Data:  &request,
+		Rules: rules,
+	})
+	return v.ValidateStruct()
+}
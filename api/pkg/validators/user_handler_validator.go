@@ -5,11 +5,23 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/requests"
 	"github.com/NdoleStudio/httpsms/pkg/telemetry"
 	"github.com/thedevsaddam/govalidator"
 )
 
+// userMaxDailyMessageLimit is the maximum value of entities.User.DailyMessageLimit
+const userMaxDailyMessageLimit = 1_000_000
+
+const (
+	// userMinRetentionDays is the shortest retention period so that recent messages cannot be deleted by mistake
+	userMinRetentionDays = 7
+
+	// userMaxRetentionDays is the longest retention period, messages are kept forever when the retention period is 0
+	userMaxRetentionDays = 3650
+)
+
 // UserHandlerValidator validates models used in handlers.UserHandler
 type UserHandlerValidator struct {
 	validator
@@ -40,5 +52,111 @@ func (validator *UserHandlerValidator) ValidateUpdate(_ context.Context, request
 		},
 	})
 
+	result := v.ValidateStruct()
+	if request.DailyMessageLimit != nil && *request.DailyMessageLimit > userMaxDailyMessageLimit {
+		result.Add("daily_message_limit", fmt.Sprintf("The daily_message_limit field must be between 0 and %d", userMaxDailyMessageLimit))
+	}
+
+	return result
+}
+
+// ValidateFailoverUpdate validates requests.UserFailoverUpdate
+func (validator *UserHandlerValidator) ValidateFailoverUpdate(_ context.Context, request requests.UserFailoverUpdate) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"phone_number": []string{
+				phoneNumberRule,
+			},
+		},
+	})
+
+	result := v.ValidateStruct()
+	if len(result) > 0 {
+		return result
+	}
+
+	if request.Enabled && request.PhoneNumber == "" {
+		result.Add("phone_number", "The phone_number field is required when failover is enabled")
+	}
+
+	return result
+}
+
+// ValidateRetentionUpdate validates requests.UserRetentionUpdate
+func (validator *UserHandlerValidator) ValidateRetentionUpdate(_ context.Context, request requests.UserRetentionUpdate) url.Values {
+	result := url.Values{}
+	for field, days := range map[string]uint{"received_days": request.ReceivedDays, "sent_days": request.SentDays} {
+		if days != 0 && (days < userMinRetentionDays || days > userMaxRetentionDays) {
+			result.Add(field, fmt.Sprintf("The %s field must be 0 to keep messages or between %d and %d", field, userMinRetentionDays, userMaxRetentionDays))
+		}
+	}
+	return result
+}
+
+// ValidateRetryPolicyUpdate validates requests.UserRetryPolicyUpdate
+func (validator *UserHandlerValidator) ValidateRetryPolicyUpdate(_ context.Context, request requests.UserRetryPolicyUpdate) url.Values {
+	return validator.validateRetryPolicy(url.Values{}, "", request.ToRetryPolicy())
+}
+
+// ValidateAPIKeyRotate validates requests.UserAPIKeyRotate
+func (validator *UserHandlerValidator) ValidateAPIKeyRotate(_ context.Context, request requests.UserAPIKeyRotate) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"grace_period_seconds": []string{
+				"min:0",
+				"max:86400",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateIndex validates the requests.UserIndex request
+func (validator *UserHandlerValidator) ValidateIndex(_ context.Context, request requests.UserIndex) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"limit": []string{
+				"required",
+				"numeric",
+				"min:1",
+				"max:100",
+			},
+			"skip": []string{
+				"required",
+				"numeric",
+				"min:0",
+			},
+			"query": []string{
+				"max:100",
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
+// ValidateSuspend validates that an administrator does not suspend their own account
+func (validator *UserHandlerValidator) ValidateSuspend(_ context.Context, authUser entities.AuthUser, userID string) url.Values {
+	result := url.Values{}
+	if entities.UserID(userID) == authUser.ID {
+		result.Add("userID", "you cannot suspend your own account")
+	}
+	return result
+}
+
+// ValidateNotificationUpdate validates requests.UserNotificationUpdate
+func (validator *UserHandlerValidator) ValidateNotificationUpdate(_ context.Context, request requests.UserNotificationUpdate) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"heartbeat_quiet_hours": []string{
+				"min:0",
+				"max:168",
+			},
+		},
+	})
+
 	return v.ValidateStruct()
 }
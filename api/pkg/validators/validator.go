@@ -6,19 +6,58 @@ import (
 	"net/url"
 	"regexp"
 
+	"github.com/NdoleStudio/httpsms/pkg/entities"
 	"github.com/NdoleStudio/httpsms/pkg/events"
 
+	"github.com/google/uuid"
 	"github.com/nyaruka/phonenumbers"
 	"github.com/thedevsaddam/govalidator"
 )
 
 type validator struct{}
 
+// phoneNumberPrefixPattern matches the start of a phone number in the E.164 format e.g. +44 or +1415
+var phoneNumberPrefixPattern = regexp.MustCompile(`^\+[1-9][0-9]{0,14}$`)
+
+// webhookEvents are the events which can be sent to an entities.Webhook
+var webhookEvents = map[string]bool{
+	events.EventTypeMessagePhoneReceived:  true,
+	events.EventTypeMessagePhoneSent:      true,
+	events.EventTypeMessagePhoneDelivered: true,
+	events.EventTypeMessageSendFailed:     true,
+	events.EventTypeMessageSendExpired:    true,
+	events.EventTypePhoneHeartbeatDead:    true,
+	events.EventTypePhoneHeartbeatAlive:   true,
+	events.EventTypePhoneBatteryLow:       true,
+	events.EventTypePhonePaused:           true,
+	events.EventTypePhoneResumed:          true,
+	events.EventTypePhoneQuotaExhausted:   true,
+	events.EventTypePhoneClockSkewed:      true,
+	events.EventTypeMessageFailover:       true,
+}
+
+const (
+	// retryPolicyMaxAttempts is the largest entities.RetryPolicy.MaxAttempts, it is the same as the limit of the phones
+	retryPolicyMaxAttempts = 10
+
+	// retryPolicyMaxBackoffBaseSeconds is the largest entities.RetryPolicy.BackoffBaseSeconds
+	retryPolicyMaxBackoffBaseSeconds = 60 * 60
+
+	// retryPolicyMaxBackoffCapSeconds is the largest entities.RetryPolicy.BackoffCapSeconds so that the retries of a message end within a few days
+	retryPolicyMaxBackoffCapSeconds = 12 * 60 * 60
+
+	// retryPolicyMaxBackoffMultiplier is the largest entities.RetryPolicy.BackoffMultiplier
+	retryPolicyMaxBackoffMultiplier = 10
+)
+
 const (
 	phoneNumberRule                = "phoneNumber"
 	contactPhoneNumberRule         = "contactPhoneNumber"
 	multipleContactPhoneNumberRule = "multipleContactPhoneNumber"
+	multipleUUIDRule               = "multipleUUID"
 	webhookEventsRule              = "webhookEvents"
+	webhookEventRule               = "webhookEvent"
+	apiKeyScopesRule               = "apiKeyScopes"
 )
 
 func init() {
@@ -68,6 +107,21 @@ func init() {
 		return nil
 	})
 
+	govalidator.AddCustomRule(multipleUUIDRule, func(field string, rule string, message string, value interface{}) error {
+		ids, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("The %s field must be an array of UUIDs", field)
+		}
+
+		for index, id := range ids {
+			if _, err := uuid.Parse(id); err != nil {
+				return fmt.Errorf("The %s field in index [%d] must be a valid UUID", field, index)
+			}
+		}
+
+		return nil
+	})
+
 	govalidator.AddCustomRule(webhookEventsRule, func(field string, rule string, message string, value interface{}) error {
 		input, ok := value.([]string)
 		if !ok {
@@ -78,22 +132,51 @@ func init() {
 			return fmt.Errorf("The %s field is an empty array", field)
 		}
 
-		validEvents := map[string]bool{
-			events.EventTypeMessagePhoneReceived:  true,
-			events.EventTypeMessagePhoneSent:      true,
-			events.EventTypeMessagePhoneDelivered: true,
-			events.EventTypeMessageSendFailed:     true,
-			events.EventTypeMessageSendExpired:    true,
-		}
-
 		for _, event := range input {
-			if _, ok := validEvents[event]; !ok {
+			if _, ok := webhookEvents[event]; !ok {
 				return fmt.Errorf("The %s field has an invalid event with name [%s]", field, event)
 			}
 		}
 
 		return nil
 	})
+
+	govalidator.AddCustomRule(webhookEventRule, func(field string, rule string, message string, value interface{}) error {
+		event, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("The %s field must be a string", field)
+		}
+
+		if _, ok = webhookEvents[event]; !ok {
+			return fmt.Errorf("The %s field has an invalid event with name [%s]", field, event)
+		}
+
+		return nil
+	})
+
+	govalidator.AddCustomRule(apiKeyScopesRule, func(field string, rule string, message string, value interface{}) error {
+		input, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("The %s field must be a string array", field)
+		}
+
+		if len(input) == 0 {
+			return fmt.Errorf("The %s field is an empty array", field)
+		}
+
+		validScopes := map[string]bool{}
+		for _, scope := range entities.APIKeyScopes() {
+			validScopes[scope.String()] = true
+		}
+
+		for _, scope := range input {
+			if _, ok := validScopes[scope]; !ok {
+				return fmt.Errorf("The %s field has an invalid scope with name [%s]", field, scope)
+			}
+		}
+
+		return nil
+	})
 }
 
 // ValidateUUID that the payload is a UUID
@@ -114,3 +197,37 @@ func (validator *validator) ValidateUUID(_ context.Context, ID string, name stri
 
 	return v.ValidateStruct()
 }
+
+// validatePhoneNumberPrefix makes sure that a prefix is either empty or the start of a phone number in the E.164 format
+func (validator *validator) validatePhoneNumberPrefix(result url.Values, prefix string) url.Values {
+	if prefix != "" && !phoneNumberPrefixPattern.MatchString(prefix) {
+		result.Add("prefix", fmt.Sprintf("The prefix field must be a country code or the start of a phone number e.g. +44, [%s] is not a valid prefix", prefix))
+	}
+	return result
+}
+
+// validateRetryPolicy keeps the fields of an entities.RetryPolicy which are set within safe bounds, a field which is 0 is
+// not set. The names of the fields in the errors start with the prefix e.g. retry_policy.
+func (validator *validator) validateRetryPolicy(result url.Values, prefix string, policy entities.RetryPolicy) url.Values {
+	if policy.MaxAttempts > retryPolicyMaxAttempts {
+		result.Add(prefix+"max_attempts", fmt.Sprintf("The %smax_attempts field must be 0 to use the attempts of the phone or between 1 and %d", prefix, retryPolicyMaxAttempts))
+	}
+
+	if policy.BackoffBaseSeconds > retryPolicyMaxBackoffBaseSeconds {
+		result.Add(prefix+"backoff_base_seconds", fmt.Sprintf("The %sbackoff_base_seconds field must be between 0 and %d", prefix, retryPolicyMaxBackoffBaseSeconds))
+	}
+
+	if policy.BackoffCapSeconds > retryPolicyMaxBackoffCapSeconds {
+		result.Add(prefix+"backoff_cap_seconds", fmt.Sprintf("The %sbackoff_cap_seconds field must be between 0 and %d", prefix, retryPolicyMaxBackoffCapSeconds))
+	}
+
+	if policy.BackoffCapSeconds != 0 && policy.BackoffCapSeconds < policy.BackoffBaseSeconds {
+		result.Add(prefix+"backoff_cap_seconds", fmt.Sprintf("The %sbackoff_cap_seconds field cannot be less than the %sbackoff_base_seconds field", prefix, prefix))
+	}
+
+	if policy.BackoffMultiplier != 0 && (policy.BackoffMultiplier < 1 || policy.BackoffMultiplier > retryPolicyMaxBackoffMultiplier) {
+		result.Add(prefix+"backoff_multiplier", fmt.Sprintf("The %sbackoff_multiplier field must be 0 to use the default or between 1 and %d", prefix, retryPolicyMaxBackoffMultiplier))
+	}
+
+	return result
+}
@@ -61,6 +61,24 @@ func (validator *WebhookHandlerValidator) ValidateIndex(_ context.Context, reque
 	return v.ValidateStruct()
 }
 
+// ValidateSample validates the requests.WebhookSample request
+func (validator *WebhookHandlerValidator) ValidateSample(_ context.Context, request requests.WebhookSample) url.Values {
+	v := govalidator.New(govalidator.Options{
+		Data: &request,
+		Rules: govalidator.MapData{
+			"event": []string{
+				"required",
+				webhookEventRule,
+			},
+			"payload_format": []string{
+				"required",
+				fmt.Sprintf("in:%s,%s", entities.WebhookPayloadFormatRaw, entities.WebhookPayloadFormatFlat),
+			},
+		},
+	})
+	return v.ValidateStruct()
+}
+
 // ValidateStore validates the requests.WebhookStore request
 func (validator *WebhookHandlerValidator) ValidateStore(ctx context.Context, userID entities.UserID, request requests.WebhookStore) url.Values {
 	ctx, span := validator.tracer.Start(ctx)
@@ -86,6 +104,10 @@ func (validator *WebhookHandlerValidator) ValidateStore(ctx context.Context, use
 				"required",
 				multipleContactPhoneNumberRule,
 			},
+			"payload_format": []string{
+				"required",
+				fmt.Sprintf("in:%s,%s", entities.WebhookPayloadFormatRaw, entities.WebhookPayloadFormatFlat),
+			},
 		},
 	})
 
@@ -132,6 +154,10 @@ func (validator *WebhookHandlerValidator) ValidateUpdate(ctx context.Context, us
 				"required",
 				multipleContactPhoneNumberRule,
 			},
+			"payload_format": []string{
+				"required",
+				fmt.Sprintf("in:%s,%s", entities.WebhookPayloadFormatRaw, entities.WebhookPayloadFormatFlat),
+			},
 		},
 	})
 
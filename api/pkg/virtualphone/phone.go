@@ -0,0 +1,296 @@
+// Package virtualphone simulates the android app so that the outbound flow of messages can be exercised without a
+// phone. It only uses the public HTTP API so it can also drive integration tests against a running server.
+package virtualphone
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/carlmjohnson/requests"
+	"github.com/palantir/stacktrace"
+)
+
+// pageSize is the number of threads and messages fetched with one request
+const pageSize = 100
+
+// Config configures a Phone
+type Config struct {
+	// BaseURL is the URL of the API e.g. http://localhost:8000
+	BaseURL string
+
+	// APIKey is the API key of the user who owns the phone
+	APIKey string
+
+	// Owner is the phone number of the phone
+	Owner string
+
+	// SIM is the SIM card which receives the inbound messages
+	SIM entities.SIM
+
+	// PollInterval is the time between two checks for outstanding messages
+	PollInterval time.Duration
+
+	// FailureRate is the probability between 0 and 1 that a message fails instead of being sent
+	FailureRate float64
+
+	// MinDelay and MaxDelay bound the random time it takes to send a message
+	MinDelay time.Duration
+	MaxDelay time.Duration
+
+	// Deliver also emits the DELIVERED event after a message is sent
+	Deliver bool
+
+	// Seed makes the failures and delays reproducible
+	Seed int64
+}
+
+// Phone polls the API for the outstanding entities.Message of an owner and emits the phone events like the android app
+type Phone struct {
+	logger telemetry.Logger
+	config Config
+	client *http.Client
+
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// New creates a new Phone
+func New(logger telemetry.Logger, client *http.Client, config Config) (p *Phone) {
+	if config.SIM == "" {
+		config.SIM = entities.SIM1
+	}
+
+	return &Phone{
+		logger: logger.WithService(fmt.Sprintf("%T", p)),
+		config: config,
+		client: client,
+		random: rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Run polls for outstanding messages until the context is cancelled
+func (phone *Phone) Run(ctx context.Context) error {
+	ticker := time.NewTicker(phone.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		count, err := phone.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			phone.logger.Error(stacktrace.Propagate(err, fmt.Sprintf("cannot poll outstanding messages for owner [%s]", phone.config.Owner)))
+		}
+		if count > 0 {
+			phone.logger.Info(fmt.Sprintf("virtual phone [%s] handled [%d] messages", phone.config.Owner, count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll sends a heartbeat and handles every outstanding message once, it returns the number of messages which were sent or failed
+func (phone *Phone) Poll(ctx context.Context) (int, error) {
+	if err := phone.heartbeat(ctx); err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot store heartbeat for owner [%s]", phone.config.Owner))
+	}
+
+	messages, err := phone.outstanding(ctx)
+	if err != nil {
+		return 0, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch outstanding messages for owner [%s]", phone.config.Owner))
+	}
+
+	count := 0
+	for _, message := range messages {
+		handled, err := phone.send(ctx, message)
+		if err != nil {
+			return count, stacktrace.Propagate(err, fmt.Sprintf("cannot send message with ID [%s]", message.ID))
+		}
+		if handled {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Receive synthesizes an inbound message from a contact like the android app does when it receives an SMS
+func (phone *Phone) Receive(ctx context.Context, contact string, content string) (*entities.Message, error) {
+	response := new(responses.MessageResponse)
+	err := phone.request("/v1/messages/receive").
+		BodyJSON(map[string]any{
+			"from":      contact,
+			"to":        phone.config.Owner,
+			"content":   content,
+			"sim":       phone.config.SIM,
+			"timestamp": time.Now().UTC(),
+		}).
+		ToJSON(response).
+		Fetch(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot receive message from [%s] on [%s]", contact, phone.config.Owner))
+	}
+
+	return &response.Data, nil
+}
+
+// send fetches an outstanding message which emits the message.phone.sending event, waits for the send delay and
+// emits the SENT or FAILED event. It returns false when the message was already fetched by another phone.
+func (phone *Phone) send(ctx context.Context, message entities.Message) (bool, error) {
+	err := phone.request("/v1/messages/outstanding").
+		Param("message_id", message.ID.String()).
+		Fetch(ctx)
+	if requests.HasStatusErr(err, http.StatusNotFound, http.StatusTooManyRequests) {
+		return false, nil
+	}
+	if err != nil {
+		return false, stacktrace.Propagate(err, "cannot fetch outstanding message")
+	}
+
+	delay, failed := phone.outcome()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(delay):
+	}
+
+	if failed {
+		return true, phone.event(ctx, message, entities.MessageEventNameFailed, "virtual phone failure")
+	}
+
+	if err = phone.event(ctx, message, entities.MessageEventNameSent, ""); err != nil || !phone.config.Deliver {
+		return true, err
+	}
+
+	return true, phone.event(ctx, message, entities.MessageEventNameDelivered, "")
+}
+
+// outcome picks the send delay and whether the message fails
+func (phone *Phone) outcome() (time.Duration, bool) {
+	phone.mutex.Lock()
+	defer phone.mutex.Unlock()
+
+	delay := phone.config.MinDelay
+	if phone.config.MaxDelay > phone.config.MinDelay {
+		delay += time.Duration(phone.random.Int63n(int64(phone.config.MaxDelay - phone.config.MinDelay)))
+	}
+
+	return delay, phone.random.Float64() < phone.config.FailureRate
+}
+
+// event posts a phone event for a message
+func (phone *Phone) event(ctx context.Context, message entities.Message, name entities.MessageEventName, reason string) error {
+	payload := map[string]any{
+		"event_name": name,
+		"timestamp":  time.Now().UTC(),
+	}
+	if reason != "" {
+		payload["reason"] = reason
+	}
+
+	err := phone.request(fmt.Sprintf("/v1/messages/%s/events", message.ID)).BodyJSON(payload).Fetch(ctx)
+	if err != nil {
+		return stacktrace.Propagate(err, fmt.Sprintf("cannot emit [%s] event for message with ID [%s]", name, message.ID))
+	}
+	return nil
+}
+
+// heartbeat tells the API that the phone is online
+func (phone *Phone) heartbeat(ctx context.Context) error {
+	return phone.request("/v1/heartbeats").
+		BodyJSON(map[string]any{"owner": phone.config.Owner, "charging": true}).
+		Fetch(ctx)
+}
+
+// outstanding finds the messages of the owner which the android app would be notified to send. The API has no
+// endpoint which lists them so every thread of the owner is searched.
+func (phone *Phone) outstanding(ctx context.Context) ([]entities.Message, error) {
+	threads, err := phone.threads(ctx)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot fetch message threads")
+	}
+
+	var outstanding []entities.Message
+	for _, thread := range threads {
+		for skip := 0; ; skip += pageSize {
+			response := new(responses.MessagesResponse)
+			err = phone.request("/v1/messages").
+				Param("owner", phone.config.Owner).
+				Param("contact", thread.Contact).
+				ParamInt("skip", skip).
+				ParamInt("limit", pageSize).
+				ToJSON(response).
+				Fetch(ctx)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch messages with contact [%s]", thread.Contact))
+			}
+
+			for _, message := range response.Data {
+				if phone.isOutstanding(message) {
+					outstanding = append(outstanding, message)
+				}
+			}
+
+			if len(response.Data) < pageSize {
+				break
+			}
+		}
+	}
+
+	return outstanding, nil
+}
+
+// isOutstanding checks if the android app would be notified to send a message
+func (phone *Phone) isOutstanding(message entities.Message) bool {
+	if message.Type != entities.MessageTypeMobileTerminated {
+		return false
+	}
+
+	if message.IsScheduled() {
+		return message.NotificationScheduledAt == nil || !message.NotificationScheduledAt.After(time.Now().UTC())
+	}
+
+	return message.IsPending()
+}
+
+// threads fetches the archived and the active entities.MessageThread of the owner
+func (phone *Phone) threads(ctx context.Context) ([]entities.MessageThread, error) {
+	var threads []entities.MessageThread
+	for _, archived := range []string{"false", "true"} {
+		for skip := 0; ; skip += pageSize {
+			response := new(responses.MessageThreadsResponse)
+			err := phone.request("/v1/message-threads").
+				Param("owner", phone.config.Owner).
+				Param("is_archived", archived).
+				ParamInt("skip", skip).
+				ParamInt("limit", pageSize).
+				ToJSON(response).
+				Fetch(ctx)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, fmt.Sprintf("cannot fetch message threads with is_archived [%s]", archived))
+			}
+
+			threads = append(threads, response.Data...)
+			if len(response.Data) < pageSize {
+				break
+			}
+		}
+	}
+	return threads, nil
+}
+
+func (phone *Phone) request(path string) *requests.Builder {
+	return requests.
+		URL(phone.config.BaseURL).
+		Path(path).
+		Client(phone.client).
+		Header("x-api-key", phone.config.APIKey)
+}
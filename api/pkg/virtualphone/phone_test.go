@@ -0,0 +1,234 @@
+package virtualphone
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NdoleStudio/httpsms/pkg/entities"
+	"github.com/NdoleStudio/httpsms/pkg/responses"
+	"github.com/NdoleStudio/httpsms/pkg/telemetry"
+	"github.com/carlmjohnson/requests"
+	"github.com/google/uuid"
+	"github.com/hirosassa/zerodriver"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAPIKey = "test-api-key"
+
+// fakeAPI implements the endpoints of the API which are used by the Phone
+type fakeAPI struct {
+	mutex    sync.Mutex
+	messages map[uuid.UUID]*entities.Message
+}
+
+func newFakeAPI(t *testing.T) string {
+	api := &fakeAPI{messages: map[uuid.UUID]*entities.Message{}}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func (api *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("x-api-key") != testAPIKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	var payload map[string]string
+	if r.Method == http.MethodPost {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}
+
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/heartbeats":
+		api.respond(w, nil)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/send":
+		api.respond(w, api.store(payload["from"], payload["to"], payload["content"], entities.MessageTypeMobileTerminated, entities.MessageStatusPending))
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/receive":
+		api.respond(w, api.store(payload["to"], payload["from"], payload["content"], entities.MessageTypeMobileOriginated, entities.MessageStatusReceived))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/message-threads":
+		api.respond(w, api.threads(query.Get("owner"), query.Get("is_archived")))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/messages":
+		api.respond(w, api.index(query.Get("owner"), query.Get("contact")))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/outstanding":
+		message, ok := api.messages[uuid.MustParse(query.Get("message_id"))]
+		if !ok || !message.IsPending() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		message.Status = entities.MessageStatusSending
+		api.respond(w, message)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/events"):
+		message := api.messages[uuid.MustParse(strings.Split(r.URL.Path, "/")[3])]
+		message.Status = map[string]entities.MessageStatus{
+			string(entities.MessageEventNameSent):      entities.MessageStatusSent,
+			string(entities.MessageEventNameFailed):    entities.MessageStatusFailed,
+			string(entities.MessageEventNameDelivered): entities.MessageStatusDelivered,
+		}[payload["event_name"]]
+		api.respond(w, message)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (api *fakeAPI) store(owner string, contact string, content string, messageType entities.MessageType, status entities.MessageStatus) *entities.Message {
+	message := &entities.Message{
+		ID:      uuid.New(),
+		Owner:   owner,
+		Contact: contact,
+		Content: content,
+		Type:    messageType,
+		Status:  status,
+	}
+	api.messages[message.ID] = message
+	return message
+}
+
+func (api *fakeAPI) threads(owner string, archived string) []entities.MessageThread {
+	contacts := map[string]bool{}
+	threads := make([]entities.MessageThread, 0)
+	for _, message := range api.messages {
+		if message.Owner == owner && archived == "false" && !contacts[message.Contact] {
+			contacts[message.Contact] = true
+			threads = append(threads, entities.MessageThread{Owner: owner, Contact: message.Contact})
+		}
+	}
+	return threads
+}
+
+func (api *fakeAPI) index(owner string, contact string) []entities.Message {
+	messages := make([]entities.Message, 0)
+	for _, message := range api.messages {
+		if message.Owner == owner && message.Contact == contact {
+			messages = append(messages, *message)
+		}
+	}
+	return messages
+}
+
+func (api *fakeAPI) respond(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": "success", "message": "ok", "data": data})
+}
+
+func newTestPhone(baseURL string, apiKey string, owner string, failureRate float64) *Phone {
+	zl := zerolog.Nop()
+	logger := telemetry.NewZerologLogger("test", map[string]string{}, &zerodriver.Logger{Logger: &zl}, nil)
+	return New(logger, nil, Config{
+		BaseURL:      baseURL,
+		APIKey:       apiKey,
+		Owner:        owner,
+		PollInterval: 100 * time.Millisecond,
+		FailureRate:  failureRate,
+		MaxDelay:     50 * time.Millisecond,
+	})
+}
+
+// sendScenario sends a message with the API while the phone is running and waits until it reaches the expected status
+func sendScenario(t *testing.T, phone *Phone, contact string, expected entities.MessageStatus) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- phone.Run(ctx) }()
+
+	sent := new(responses.MessageResponse)
+	err := phone.request("/v1/messages/send").
+		BodyJSON(map[string]string{"from": phone.config.Owner, "to": contact, "content": "virtual phone scenario " + uuid.NewString()}).
+		ToJSON(sent).
+		Fetch(ctx)
+	assert.Nil(t, err)
+
+	status := sent.Data.Status
+	for status != expected && ctx.Err() == nil {
+		time.Sleep(100 * time.Millisecond)
+
+		messages := new(responses.MessagesResponse)
+		if err = phone.request("/v1/messages").Param("owner", phone.config.Owner).Param("contact", contact).ToJSON(messages).Fetch(ctx); err != nil {
+			continue
+		}
+
+		for _, message := range messages.Data {
+			if message.ID == sent.Data.ID {
+				status = message.Status
+			}
+		}
+	}
+
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, expected, status)
+}
+
+func TestPhone_Scenario(t *testing.T) {
+	t.Run("sent message reaches the sent status", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		phone := newTestPhone(newFakeAPI(t), testAPIKey, "+18005550199", 0)
+
+		// Act + Assert
+		sendScenario(t, phone, "+18005550100", entities.MessageStatusSent)
+	})
+
+	t.Run("message fails when the failure rate is 1", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		phone := newTestPhone(newFakeAPI(t), testAPIKey, "+18005550199", 1)
+
+		// Act + Assert
+		sendScenario(t, phone, "+18005550100", entities.MessageStatusFailed)
+	})
+
+	t.Run("sent message reaches the sent status on a running API", func(t *testing.T) {
+		// Setup
+		baseURL := os.Getenv("HTTPSMS_URL_TEST")
+		if baseURL == "" {
+			t.Skip("HTTPSMS_URL_TEST is not set")
+		}
+		phone := newTestPhone(baseURL, os.Getenv("HTTPSMS_KEY_TEST"), os.Getenv("HTTPSMS_FROM_TEST"), 0)
+
+		// Act + Assert
+		sendScenario(t, phone, os.Getenv("HTTPSMS_TO_TEST"), entities.MessageStatusSent)
+	})
+}
+
+func TestPhone_Receive(t *testing.T) {
+	t.Run("inbound message is received by the owner", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		phone := newTestPhone(newFakeAPI(t), testAPIKey, "+18005550199", 0)
+
+		// Act
+		message, err := phone.Receive(context.Background(), "+18005550100", "hello world")
+
+		// Assert
+		assert.Nil(t, err)
+		assert.Equal(t, entities.MessageStatusReceived, message.Status)
+		assert.Equal(t, "+18005550199", message.Owner)
+		assert.Equal(t, "+18005550100", message.Contact)
+	})
+
+	t.Run("request without a valid API key is rejected", func(t *testing.T) {
+		// Setup
+		t.Parallel()
+		phone := newTestPhone(newFakeAPI(t), "invalid", "+18005550199", 0)
+
+		// Act
+		_, err := phone.Receive(context.Background(), "+18005550100", "hello world")
+
+		// Assert
+		assert.True(t, requests.HasStatusErr(err, http.StatusUnauthorized))
+	})
+}